@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/spf13/cobra"
+)
+
+// The db commands use the server's flags so they open the same stores as a
+// server started with the same flags.
+var dbStringFlags = map[string]stringFlag{
+	DataDirFlag: {
+		description: "Path to the server's data directory. Queued events are always stored in it," +
+			" as are locks and pull request statuses when --" + DBBackendFlag + " is " + db.BoltDBBackend + " or " + db.SQLiteBackend + ".",
+		defaultValue: DefaultDataDir,
+	},
+	DBBackendFlag: {
+		description:  "Backend the server stores locks and pull request statuses in. Accepts " + db.BoltDBBackend + ", " + db.SQLiteBackend + " or " + db.PostgresBackend + ".",
+		defaultValue: DefaultDBBackend,
+	},
+	PostgresURLFlag: {
+		description: "URL of the Postgres database when --" + DBBackendFlag + "=" + db.PostgresBackend + ".",
+		secret:      true,
+	},
+}
+
+// DBCmd exports and imports the server's state, ex. to migrate it to another
+// backend.
+type DBCmd struct {
+	// Stdin is read by import when the path is -. It defaults to os.Stdin.
+	Stdin io.Reader
+}
+
+// dbConfig is the server's stores as set by the db flags.
+type dbConfig struct {
+	dataDir     string
+	backend     string
+	postgresURL string
+}
+
+// Init returns the runnable cobra command.
+func (d *DBCmd) Init() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "db",
+		Short: "Export and import the server's state",
+	}
+	c.AddCommand(d.exportCmd())
+	c.AddCommand(d.importCmd())
+	return c
+}
+
+func (d *DBCmd) exportCmd() *cobra.Command {
+	var cfg dbConfig
+	c := &cobra.Command{
+		Use:   "export [flags] [path/to/state.json]",
+		Short: "Export locks, pull request statuses and queued events to JSON",
+		Long: `Export locks, pull request statuses and queued events to a JSON file, or to stdout if no file is given.
+The server must be stopped first.`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := ""
+			if len(args) == 1 {
+				path = args[0]
+			}
+			err := d.export(cmd, cfg, path)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "\033[31mError: %s\033[39m\n", err.Error())
+			}
+			return err
+		},
+	}
+	d.addFlags(c, &cfg)
+	return c
+}
+
+func (d *DBCmd) importCmd() *cobra.Command {
+	var cfg dbConfig
+	c := &cobra.Command{
+		Use:   "import [flags] path/to/state.json",
+		Short: "Import locks, pull request statuses and queued events from JSON",
+		Long: `Import locks, pull request statuses and queued events exported with atlantis db export.
+Use - to read from stdin. The server must be stopped first.
+Nothing is overwritten: locks of projects that are already locked, statuses of pull requests
+that already have one and events that are already queued are skipped.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := d.importState(cmd, cfg, args[0])
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "\033[31mError: %s\033[39m\n", err.Error())
+			}
+			return err
+		},
+	}
+	d.addFlags(c, &cfg)
+	return c
+}
+
+func (d *DBCmd) addFlags(c *cobra.Command, cfg *dbConfig) {
+	c.SetUsageTemplate(usageTmpl(dbStringFlags, nil, nil))
+	c.Flags().StringVar(&cfg.dataDir, DataDirFlag, DefaultDataDir, dbStringFlags[DataDirFlag].description)
+	c.Flags().StringVar(&cfg.backend, DBBackendFlag, DefaultDBBackend, dbStringFlags[DBBackendFlag].description)
+	c.Flags().StringVar(&cfg.postgresURL, PostgresURLFlag, "", dbStringFlags[PostgresURLFlag].description)
+}
+
+func (d *DBCmd) export(cmd *cobra.Command, cfg dbConfig, path string) error {
+	store, queue, closeStores, err := d.openStores(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStores()
+
+	state, err := db.ExportState(store, queue, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "creating %s", path)
+		}
+		defer f.Close() // nolint: errcheck
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(state); err != nil {
+		return errors.Wrap(err, "writing state")
+	}
+	if path != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "exported %d locks, %d pull statuses and %d queued events to %s\n",
+			len(state.Locks), len(state.PullStatuses), len(state.QueuedEvents), path)
+	}
+	return nil
+}
+
+func (d *DBCmd) importState(cmd *cobra.Command, cfg dbConfig, path string) error {
+	in := d.Stdin
+	if in == nil {
+		in = os.Stdin
+	}
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return errors.Wrapf(err, "opening %s", path)
+		}
+		defer f.Close() // nolint: errcheck
+		in = f
+	}
+	var state db.State
+	if err := json.NewDecoder(in).Decode(&state); err != nil {
+		return errors.Wrap(err, "parsing state")
+	}
+
+	store, queue, closeStores, err := d.openStores(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStores()
+
+	result, err := db.ImportState(state, store, queue)
+	if err != nil {
+		return errors.Wrapf(err, "importing state after having %s", result)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), result.String())
+	return nil
+}
+
+// openStores opens the store of locks and pull statuses and the BoltDB that
+// events are queued in like the server does. closeStores closes both.
+func (d *DBCmd) openStores(cfg dbConfig) (store db.Store, queue *db.BoltDB, closeStores func(), err error) {
+	dataDir, err := homedir.Expand(cfg.dataDir)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "expanding --%s", DataDirFlag)
+	}
+	switch cfg.backend {
+	case db.BoltDBBackend, db.SQLiteBackend:
+		if cfg.postgresURL != "" {
+			return nil, nil, nil, fmt.Errorf("--%s can only be used with --%s=%s", PostgresURLFlag, DBBackendFlag, db.PostgresBackend)
+		}
+	case db.PostgresBackend:
+		if cfg.postgresURL == "" {
+			return nil, nil, nil, fmt.Errorf("--%s must be set when using --%s=%s", PostgresURLFlag, DBBackendFlag, db.PostgresBackend)
+		}
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid --%s: not one of %s, %s or %s", DBBackendFlag, db.BoltDBBackend, db.SQLiteBackend, db.PostgresBackend)
+	}
+
+	// BoltDB returns an error if the server has it open so this also checks
+	// that the server is stopped.
+	queue, err = db.New(dataDir)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "opening the data dir, is the server stopped?")
+	}
+	switch cfg.backend {
+	case db.SQLiteBackend:
+		sqliteDB, err := db.NewSQLite(dataDir)
+		if err != nil {
+			queue.Close() // nolint: errcheck
+			return nil, nil, nil, errors.Wrap(err, "opening SQLite database")
+		}
+		return sqliteDB, queue, func() {
+			sqliteDB.Close() // nolint: errcheck
+			queue.Close()    // nolint: errcheck
+		}, nil
+	case db.PostgresBackend:
+		postgresDB, err := db.NewPostgres(cfg.postgresURL)
+		if err != nil {
+			queue.Close() // nolint: errcheck
+			return nil, nil, nil, errors.Wrap(err, "connecting to Postgres")
+		}
+		return postgresDB, queue, func() {
+			postgresDB.Close() // nolint: errcheck
+			queue.Close()      // nolint: errcheck
+		}, nil
+	default:
+		return queue, queue, func() {
+			queue.Close() // nolint: errcheck
+		}, nil
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func runDB(t *testing.T, args ...string) (string, error) {
+	c := (&DBCmd{}).Init()
+	var out bytes.Buffer
+	c.SetOutput(&out)
+	c.SetArgs(args)
+	err := c.Execute()
+	return out.String(), err
+}
+
+// Test migrating the state of a server from BoltDB to SQLite.
+func TestDB_ExportImport(t *testing.T) {
+	srcDir, cleanupSrc := TempDir(t)
+	defer cleanupSrc()
+	boltDB, err := db.New(srcDir)
+	Ok(t, err)
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo:   models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}},
+	}
+	_, _, err = boltDB.TryLock(models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "."),
+		Workspace: "default",
+		Pull:      pull,
+		Time:      time.Now(),
+	})
+	Ok(t, err)
+	_, err = boltDB.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+	_, err = boltDB.PutQueuedEvent(models.QueuedEvent{QueuedAt: time.Now(), Payload: []byte("{}")})
+	Ok(t, err)
+
+	// The export fails while the server has the data dir open.
+	_, err = runDB(t, "export", "--data-dir", srcDir)
+	Assert(t, err != nil, "expected error while the data dir is open")
+	Ok(t, boltDB.Close())
+
+	statePath := filepath.Join(srcDir, "state.json")
+	out, err := runDB(t, "export", "--data-dir", srcDir, statePath)
+	Ok(t, err)
+	Equals(t, "exported 1 locks, 1 pull statuses and 1 queued events to "+statePath+"\n", out)
+
+	dstDir, cleanupDst := TempDir(t)
+	defer cleanupDst()
+	out, err = runDB(t, "import", "--data-dir", dstDir, "--db-backend", db.SQLiteBackend, statePath)
+	Ok(t, err)
+	Equals(t, "imported 1 locks (0 skipped), 1 pull statuses (0 skipped) and 1 queued events (0 skipped)\n", out)
+
+	out, err = runDB(t, "import", "--data-dir", dstDir, "--db-backend", db.SQLiteBackend, statePath)
+	Ok(t, err)
+	Equals(t, "imported 0 locks (1 skipped), 0 pull statuses (1 skipped) and 0 queued events (1 skipped)\n", out)
+}
+
+func TestDB_InvalidBackend(t *testing.T) {
+	_, err := runDB(t, "export", "--db-backend", "redis")
+	ErrEquals(t, "invalid --db-backend: not one of boltdb, sqlite or postgres", err)
+}
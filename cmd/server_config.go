@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// WebhooksKey is the config file key for server.UserConfig.Webhooks. It has
+// no flag since it's a list.
+const WebhooksKey = "webhooks"
+
+// The sources a server setting can come from, from highest to lowest
+// precedence.
+const (
+	FlagSettingSource       = "flag"
+	EnvSettingSource        = "env"
+	ConfigFileSettingSource = "config-file"
+	DefaultSettingSource    = "default"
+)
+
+// RedactedSettingValue replaces the values of secret flags in the server's
+// settings.
+const RedactedSettingValue = "<redacted>"
+
+// envVarRegex matches ${VAR} and ${VAR:-default} references in config file
+// values. $${VAR} is escaped and becomes ${VAR}.
+var envVarRegex = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// readConfigFile reads the server config file at path into s.Viper.
+// YAML and JSON files can group flags under nested keys, ex. gh: {user: bob}
+// sets --gh-user, and reference env vars in their values. Other formats are
+// read by viper as-is.
+func (s *ServerCmd) readConfigFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		s.Viper.SetConfigFile(path)
+		return s.Viper.ReadInConfig()
+	}
+
+	contents, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	cfg, err := parseServerConfig(contents)
+	if err != nil {
+		return err
+	}
+	flat, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	s.Viper.SetConfigType("yaml")
+	return s.Viper.ReadConfig(bytes.NewReader(flat))
+}
+
+// parseServerConfig parses the contents of a server config file into a map
+// from flag name to value. Env var references are replaced and each value is
+// checked against the type of its flag.
+func parseServerConfig(contents []byte) (map[string]interface{}, error) {
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return nil, err
+	}
+	flat := make(map[string]interface{})
+	var errs []string
+	flattenServerConfig("", raw, flat, &errs)
+
+	var keys []string
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := interpolateEnv(key, flat[key])
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		value, err = serverConfigValue(key, value)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		flat[key] = value
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+	return flat, nil
+}
+
+// flattenServerConfig adds the values in m to flat. Nested maps that aren't
+// themselves a setting are joined to their parent key with a dash.
+func flattenServerConfig(prefix string, m map[interface{}]interface{}, flat map[string]interface{}, errs *[]string) {
+	for k, v := range m {
+		key := prefix + fmt.Sprint(k)
+		if nested, ok := v.(map[interface{}]interface{}); ok && !isServerConfigKey(key) {
+			flattenServerConfig(key+"-", nested, flat, errs)
+			continue
+		}
+		if _, ok := flat[key]; ok {
+			*errs = append(*errs, fmt.Sprintf("%q is set more than once", key))
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+func isServerConfigKey(key string) bool {
+	_, isString := stringFlags[key]
+	_, isInt := intFlags[key]
+	_, isBool := boolFlags[key]
+	return isString || isInt || isBool || key == WebhooksKey
+}
+
+// serverConfigValue checks value against the type of the setting key and
+// converts it to that type.
+func serverConfigValue(key string, value interface{}) (interface{}, error) {
+	if !isServerConfigKey(key) {
+		return nil, fmt.Errorf("%q is not a valid key", key)
+	}
+	if key == WebhooksKey {
+		if _, ok := value.([]interface{}); !ok && value != nil {
+			return nil, fmt.Errorf("%q must be a list", key)
+		}
+		return value, nil
+	}
+	switch value.(type) {
+	case []interface{}, map[interface{}]interface{}:
+		return nil, fmt.Errorf("%q must be a single value", key)
+	}
+	str := fmt.Sprint(value)
+	if _, ok := intFlags[key]; ok {
+		if _, isInt := value.(int); isInt {
+			return value, nil
+		}
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("%q must be an integer, got %q", key, str)
+		}
+		return i, nil
+	}
+	if _, ok := boolFlags[key]; ok {
+		if _, isBool := value.(bool); isBool {
+			return value, nil
+		}
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, fmt.Errorf("%q must be true or false, got %q", key, str)
+		}
+		return b, nil
+	}
+	if value == nil {
+		return "", nil
+	}
+	return str, nil
+}
+
+// interpolateEnv replaces the env var references in the strings in value.
+// It errors if a reference without a default is to an unset env var.
+func interpolateEnv(key string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+		replaced := envVarRegex.ReplaceAllStringFunc(v, func(ref string) string {
+			match := envVarRegex.FindStringSubmatch(ref)
+			if match[1] != "" {
+				return ref[1:]
+			}
+			if val, ok := os.LookupEnv(match[2]); ok {
+				return val
+			}
+			if match[3] != "" {
+				return strings.TrimPrefix(match[3], ":-")
+			}
+			if err == nil {
+				err = fmt.Errorf("%q references env var %s which isn't set", key, match[2])
+			}
+			return ref
+		})
+		return replaced, err
+	case []interface{}:
+		for i, elem := range v {
+			replaced, err := interpolateEnv(key, elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = replaced
+		}
+	case map[interface{}]interface{}:
+		for k, elem := range v {
+			replaced, err := interpolateEnv(key, elem)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = replaced
+		}
+	}
+	return value, nil
+}
+
+// settings returns each of the server's settings, where it was set and its
+// value after defaults are applied. Secrets are redacted.
+func (s *ServerCmd) settings(userConfig server.UserConfig) []server.Setting {
+	var settings []server.Setting
+	v := reflect.ValueOf(userConfig)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("mapstructure")
+		if !isServerConfigKey(name) {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if stringFlags[name].secret && value != "" {
+			value = RedactedSettingValue
+		}
+		settings = append(settings, server.Setting{
+			Name:   name,
+			Value:  value,
+			Source: s.settingSource(name),
+		})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings
+}
+
+// settingSource returns where the setting name was set, in the same order
+// of precedence viper uses.
+func (s *ServerCmd) settingSource(name string) string {
+	if s.flags != nil {
+		if f := s.flags.Lookup(name); f != nil && f.Changed {
+			return FlagSettingSource
+		}
+	}
+	if _, ok := os.LookupEnv("ATLANTIS_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))); ok {
+		return EnvSettingSource
+	}
+	if s.Viper.InConfig(name) {
+		return ConfigFileSettingSource
+	}
+	return DefaultSettingSource
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -33,10 +34,15 @@ import (
 // Used for testing.
 var passedConfig server.UserConfig
 
+// passedServerConfig is set to whatever server.Config ended up being passed
+// to NewServer.
+var passedServerConfig server.Config
+
 type ServerCreatorMock struct{}
 
 func (s *ServerCreatorMock) NewServer(userConfig server.UserConfig, config server.Config) (ServerStarter, error) {
 	passedConfig = userConfig
+	passedServerConfig = config
 	return &ServerStarterMock{}, nil
 }
 
@@ -49,47 +55,174 @@ func (s *ServerStarterMock) Start() error {
 // Adding a new flag? Add it to this slice for testing in alphabetical
 // order.
 var testFlags = map[string]interface{}{
-	ADTokenFlag:                "ad-token",
-	ADUserFlag:                 "ad-user",
-	ADWebhookPasswordFlag:      "ad-wh-pass",
-	ADWebhookUserFlag:          "ad-wh-user",
-	AtlantisURLFlag:            "url",
-	AllowForkPRsFlag:           true,
-	AllowRepoConfigFlag:        true,
-	AutomergeFlag:              true,
-	BitbucketBaseURLFlag:       "https://bitbucket-base-url.com",
-	BitbucketTokenFlag:         "bitbucket-token",
-	BitbucketUserFlag:          "bitbucket-user",
-	BitbucketWebhookSecretFlag: "bitbucket-secret",
-	CheckoutStrategyFlag:       "merge",
-	DataDirFlag:                "/path",
-	DefaultTFVersionFlag:       "v0.11.0",
-	DisableApplyAllFlag:        true,
-	DisableMarkdownFoldingFlag: true,
-	GHHostnameFlag:             "ghhostname",
-	GHTokenFlag:                "token",
-	GHUserFlag:                 "user",
-	GHWebhookSecretFlag:        "secret",
-	GitlabHostnameFlag:         "gitlab-hostname",
-	GitlabTokenFlag:            "gitlab-token",
-	GitlabUserFlag:             "gitlab-user",
-	GitlabWebhookSecretFlag:    "gitlab-secret",
-	LogLevelFlag:               "debug",
-	PortFlag:                   8181,
-	RepoWhitelistFlag:          "github.com/runatlantis/atlantis",
-	RequireApprovalFlag:        true,
-	RequireMergeableFlag:       true,
-	SilenceForkPRErrorsFlag:    true,
-	SilenceWhitelistErrorsFlag: true,
-	SilenceVCSStatusNoPlans:    true,
-	SlackTokenFlag:             "slack-token",
-	SSLCertFileFlag:            "cert-file",
-	SSLKeyFileFlag:             "key-file",
-	TFDownloadURLFlag:          "https://my-hostname.com",
-	TFEHostnameFlag:            "my-hostname",
-	TFETokenFlag:               "my-token",
-	VCSStatusName:              "my-status",
-	WriteGitCredsFlag:          true,
+	ADHostnameFlag:              "ad-hostname",
+	ADTokenFlag:                 "ad-token",
+	ADUserFlag:                  "ad-user",
+	ADWebhookPasswordFlag:       "ad-wh-pass",
+	ADWebhookUserFlag:           "ad-wh-user",
+	APITokensFlag:               `[{"name": "worker", "token": "secret", "scopes": ["workers:run"]}]`,
+	ApplyTimeoutFlag:            "1h",
+	ArtifactBucketFlag:          "atlantis-artifacts",
+	ArtifactS3EndpointFlag:      "https://minio.example.com",
+	ArtifactURLExpiryFlag:       "48h",
+	AtlantisURLFlag:             "url",
+	AllowDraftPRsFlag:           true,
+	AllowForkPRsFlag:            true,
+	AuditLogFlag:                true,
+	AuditSyslogAddrFlag:         "udp://syslog.example.com:514",
+	AuditWebhookURLFlag:         "https://audit.example.com",
+	AuthorizerTokenFlag:         "authorizer-token",
+	AuthorizerURLFlag:           "https://authorizer.example.com",
+	AllowRepoConfigFlag:         true,
+	AllowedExtraArgsFlag:        "-target,-var",
+	AutomergeFlag:               true,
+	AutoplanDebounceWindowFlag:  "30s",
+	AutoplanModulesFlag:         true,
+	AutoRegisterReposFlag:       "github.com/myorg/*",
+	BitbucketBaseURLFlag:        "https://bitbucket-base-url.com",
+	BitbucketInsightsFlag:       true,
+	BitbucketTokenFlag:          "bitbucket-token",
+	BitbucketUserFlag:           "bitbucket-user",
+	BitbucketWebhookSecretFlag:  "bitbucket-secret",
+	CheckoutDepthFlag:           50,
+	CheckoutStrategyFlag:        "merge",
+	CommentReactionsFlag:        true,
+	CommentRateLimitFlag:        5,
+	CommentRateLimitWindowFlag:  "15m",
+	CommentTemplatesDirFlag:     "/etc/atlantis/comments",
+	CloneCacheFlag:              true,
+	CloneCacheFetchIntervalFlag: "5m",
+	CloneCacheMaxAgeFlag:        "24h",
+	DataDirFlag:                 "/path",
+	DBBackendFlag:               "postgres",
+	DefaultTFVersionFlag:        "v0.11.0",
+	DeniedExtraArgsFlag:         "-lock=false",
+	DisableApplyAllFlag:         true,
+	DisableMarkdownFoldingFlag:  true,
+	DrainTimeoutFlag:            "10m",
+	EventBusTopicFlag:           "atlantis-events",
+	EventBusURLFlag:             "nats://nats.example.com:4222",
+	EventQueueMaxDepthFlag:      100,
+	EventQueueWorkersFlag:       4,
+	EventsCircuitBreakerFlag:    "15m",
+	EventsPortFlag:              8282,
+	EventsRateLimitPerIPFlag:    100,
+	EventsRateLimitPerRepoFlag:  20,
+	EventsSSLCertFileFlag:       "events-cert-file",
+	EventsSSLKeyFileFlag:        "events-key-file",
+	EventsTrustedProxiesFlag:    "10.0.0.0/8",
+	EventsURLFlag:               "https://atlantis-events.example.com",
+	ExecutionBackendFlag:        "kubernetes-job",
+	FailureReportChannelFlag:    "atlantis-failures",
+	FailureReportIntervalFlag:   "24h",
+	FeatureFlagsFileFlag:        "/path/to/flags.yaml",
+	GHHostnameFlag:              "ghhostname",
+	GHTokenFlag:                 "token",
+	GHUserFlag:                  "user",
+	GHWebhookSecretFlag:         "secret",
+	GitlabDiscussionsFlag:       true,
+	GitlabHostnameFlag:          "gitlab-hostname",
+	GitlabTokenFlag:             "gitlab-token",
+	GitlabUserFlag:              "gitlab-user",
+	GitlabWebhookSecretFlag:     "gitlab-secret",
+	GRPCPortFlag:                8383,
+	LoadShedMaxQueueDepthFlag:   10,
+	LoadShedMinFreeDiskMBFlag:   1024,
+	LoadShedMinFreeMemMBFlag:    512,
+	LockingModeFlag:             "on_apply",
+	LockingOutageModeFlag:       "queue",
+	LockingOutageWaitFlag:       "5m",
+	LogLevelFlag:                "debug",
+	MergeQueueModeFlag:          "apply-before-merge",
+	MergeQueueVerifyPlanFlag:    true,
+	ModifiedFilesSourceFlag:     "git",
+	NoSensitiveDiskWritesFlag:   false,
+	OutboundCABundleFileFlag:    "/etc/ssl/internal-ca.pem",
+	OutboundClientCertFileFlag:  "/etc/ssl/atlantis.crt",
+	OutboundClientKeyFileFlag:   "/etc/ssl/atlantis.key",
+	IncrementalFetchFlag:        true,
+	InitTimeoutFlag:             "10m",
+	InvalidateOnPushFlag:        "modified",
+	K8sJobCPUFlag:               "500m",
+	K8sJobDataVolumeClaimFlag:   "atlantis-data",
+	K8sJobImageFlag:             "runatlantis/atlantis",
+	K8sJobMemoryFlag:            "1Gi",
+	K8sJobNamespaceFlag:         "atlantis-jobs",
+	K8sJobServiceAccountFlag:    "atlantis-jobs",
+	PlanEncryptionKeyFlag:       "6368616e676520746869732070617373776f726420746f206120736563726574",
+	PlanOutputDestinationFlag:   "s3",
+	PlanOutputMaxBytesFlag:      20000,
+	PlanTimeoutFlag:             "30m",
+	PortFlag:                    8181,
+	PostgresURLFlag:             "postgres://atlantis@localhost/atlantis",
+	PrePlanChecksFlag:           true,
+	PrewarmWorkingDirFlag:       true,
+	PullStatusCheckFlag:         true,
+	RedactEnvVarsFlag:           "MY_SECRET,OTHER_SECRET",
+	RemoteWorkersFlag:           true,
+	RepoWhitelistFlag:           "github.com/runatlantis/atlantis",
+	RepoWhitelistOrgsFlag:       "github.com/runatlantis",
+	RepoWhitelistOrgsSyncFlag:   "5m",
+	RepoWhitelistOrgsTopicFlag:  "atlantis-enabled",
+	ReposDirFlag:                "/dev/shm/atlantis",
+	RequestReviewersFlag:        true,
+	ResultsExportDirFlag:        "/tmp/results",
+	ResultsExportFormatFlag:     "junit",
+	ResultsExportURLFlag:        "https://results.example.com",
+	RequireApprovalFlag:         true,
+	RequireMergeableFlag:        true,
+	ReusePlansFlag:              true,
+	RunTimeoutFlag:              "5m",
+	ScanSecretsFlag:             true,
+	SecretScannerCommandFlag:    "gitleaks-wrapper",
+	SelfCheckAtlantisURLFlag:    true,
+	SilenceForkPRErrorsFlag:     true,
+	SilenceNoChangesFlag:        "summarize",
+	SilenceWhitelistErrorsFlag:  true,
+	SilenceVCSStatusNoPlans:     true,
+	SlackApprovalsChannelFlag:   "atlantis-approvals",
+	SlackSigningSecretFlag:      "slack-signing-secret",
+	SlackTokenFlag:              "slack-token",
+	SlackUsersFlag:              "U012AB3CD:alice",
+	SMTPAddrFlag:                "smtp.example.com:587",
+	SMTPFromFlag:                "atlantis@example.com",
+	SMTPPasswordFlag:            "smtp-password",
+	SMTPUsernameFlag:            "smtp-username",
+	SSLCertFileFlag:             "cert-file",
+	SSLKeyFileFlag:              "key-file",
+	StalePlanAgeFlag:            "48h",
+	StalePlanCheckScheduleFlag:  "0 2 * * *",
+	StatsdHostFlag:              "localhost",
+	StatsdPortFlag:              8126,
+	StatsdPrefixFlag:            "ci.atlantis.",
+	TracingOTLPEndpointFlag:     "http://localhost:4318",
+	TracingServiceNameFlag:      "atlantis-ci",
+	StatusProgressIntervalFlag:  "1m",
+	StructuredPlanDiffFlag:      true,
+	SummaryCommentFlag:          true,
+	TFDistributionFlag:          "opentofu",
+	TFDownloadChecksumFlag:      "skip",
+	TFDownloadURLFlag:           "https://my-hostname.com",
+	TFLocalBinDirFlag:           "/opt/terraform",
+	TFPluginCacheVersionsFlag:   3,
+	TFEHostnameFlag:             "my-hostname",
+	TFETokenFlag:                "my-token",
+	VaultAddrFlag:               "https://vault.example.com:8200",
+	VaultTokenFlag:              "vault-token",
+	VCSStatusModeFlag:           "both",
+	VCSStatusName:               "my-status",
+	WebBasicAuthFlag:            false,
+	WebOIDCAllowedDomainsFlag:   "example.com",
+	WebOIDCClientIDFlag:         "client-id",
+	WebOIDCClientSecretFlag:     "client-secret",
+	WebOIDCIssuerURLFlag:        "https://accounts.example.com",
+	WebPasswordFlag:             "web-password",
+	WebUsernameFlag:             "web-username",
+	WebhookArchiveEndpointFlag:  "https://minio.example.com",
+	WebhookArchiveRetentionFlag: 30,
+	WebhookArchiveURLFlag:       "s3://bucket/webhooks",
+	WebhookDedupWindowFlag:      "5m",
+	WriteGitCredsFlag:           true,
 }
 
 func TestExecute_Defaults(t *testing.T) {
@@ -155,6 +288,10 @@ func TestExecute_ConfigFile(t *testing.T) {
 	t.Log("Should use all the values from the config file.")
 	var cfgContents string
 	for flag, value := range testFlags {
+		// Strings are quoted so JSON values aren't parsed as YAML lists.
+		if str, ok := value.(string); ok {
+			value = strconv.Quote(str)
+		}
 		cfgContents += fmt.Sprintf("%s: %v\n", flag, value)
 	}
 	tmpFile := tempFile(t, cfgContents)
@@ -233,6 +370,96 @@ func TestExecute_InvalidConfig(t *testing.T) {
 	Assert(t, strings.Contains(err.Error(), "unmarshal errors"), "should be an unmarshal error")
 }
 
+func TestExecute_ConfigFileNestedKeysAndEnv(t *testing.T) {
+	os.Setenv("ATLANTIS_TEST_GH_TOKEN", "secret-token") // nolint: errcheck
+	defer os.Unsetenv("ATLANTIS_TEST_GH_TOKEN")         // nolint: errcheck
+	os.Setenv("ATLANTIS_LOG_LEVEL", "warn")             // nolint: errcheck
+	defer os.Unsetenv("ATLANTIS_LOG_LEVEL")             // nolint: errcheck
+	tmpFile := tempFile(t, `
+gh:
+  user: bot
+  token: ${ATLANTIS_TEST_GH_TOKEN}
+  webhook-secret: $${NOT_INTERPOLATED}
+repo-whitelist: "*"
+port: ${ATLANTIS_TEST_UNSET_PORT:-4242}
+require-approval: "true"
+log-level: debug
+`)
+	defer os.Remove(tmpFile) // nolint: errcheck
+	c := setup(map[string]interface{}{
+		ConfigFlag: tmpFile,
+	})
+	Ok(t, c.Execute())
+	Equals(t, "bot", passedConfig.GithubUser)
+	Equals(t, "secret-token", passedConfig.GithubToken)
+	Equals(t, "${NOT_INTERPOLATED}", passedConfig.GithubWebhookSecret)
+	Equals(t, 4242, passedConfig.Port)
+	Equals(t, true, passedConfig.RequireApproval)
+	// Env vars take precedence over the config file.
+	Equals(t, "warn", passedConfig.LogLevel)
+
+	Equals(t, tmpFile, passedServerConfig.ConfigFile)
+	settings := make(map[string]server.Setting)
+	for _, s := range passedServerConfig.Settings {
+		settings[s.Name] = s
+	}
+	Equals(t, server.Setting{Name: GHUserFlag, Value: "bot", Source: ConfigFileSettingSource}, settings[GHUserFlag])
+	Equals(t, server.Setting{Name: GHTokenFlag, Value: RedactedSettingValue, Source: ConfigFileSettingSource}, settings[GHTokenFlag])
+	Equals(t, server.Setting{Name: LogLevelFlag, Value: "warn", Source: EnvSettingSource}, settings[LogLevelFlag])
+	Equals(t, server.Setting{Name: DataDirFlag, Value: passedConfig.DataDir, Source: DefaultSettingSource}, settings[DataDirFlag])
+	Equals(t, server.Setting{Name: GitlabTokenFlag, Value: "", Source: DefaultSettingSource}, settings[GitlabTokenFlag])
+}
+
+func TestExecute_ConfigFileErrors(t *testing.T) {
+	cases := map[string]struct {
+		contents string
+		expErr   string
+	}{
+		"unknown key": {
+			"gh-user: bot\nunknown: true\n",
+			`"unknown" is not a valid key`,
+		},
+		"unknown nested key": {
+			"gh:\n  usr: bot\n",
+			`"gh-usr" is not a valid key`,
+		},
+		"unset env var": {
+			"gh-token: ${ATLANTIS_TEST_UNSET_TOKEN}\n",
+			`"gh-token" references env var ATLANTIS_TEST_UNSET_TOKEN which isn't set`,
+		},
+		"int": {
+			"port: abc\n",
+			`"port" must be an integer, got "abc"`,
+		},
+		"bool": {
+			"require-approval: maybe\n",
+			`"require-approval" must be true or false, got "maybe"`,
+		},
+		"list": {
+			"gh-user: [a, b]\n",
+			`"gh-user" must be a single value`,
+		},
+		"set twice": {
+			"gh-user: a\ngh:\n  user: b\n",
+			`"gh-user" is set more than once`,
+		},
+		"multiple errors": {
+			"a: 1\nb: 2\n",
+			`"a" is not a valid key, "b" is not a valid key`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmpFile := tempFile(t, c.contents)
+			defer os.Remove(tmpFile) // nolint: errcheck
+			cmd := setupWithDefaults(map[string]interface{}{
+				ConfigFlag: tmpFile,
+			})
+			ErrEquals(t, "invalid config: reading "+tmpFile+": "+c.expErr, cmd.Execute())
+		})
+	}
+}
+
 func TestExecute_RequireRepoWhitelist(t *testing.T) {
 	t.Log("If no repo whitelist set should error.")
 	c := setup(map[string]interface{}{
@@ -244,6 +471,35 @@ func TestExecute_RequireRepoWhitelist(t *testing.T) {
 	Equals(t, "--repo-whitelist must be set for security purposes", err.Error())
 }
 
+func TestExecute_RepoWhitelistOrgs(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:            "user",
+		GHTokenFlag:           "token",
+		RepoWhitelistOrgsFlag: "github.com/runatlantis",
+	})
+	Ok(t, c.Execute())
+	Equals(t, DefaultRepoWhitelistOrgsSync, passedConfig.RepoWhitelistOrgsSyncInterval)
+
+	for expErr, flags := range map[string]map[string]interface{}{
+		"--repo-whitelist-orgs cannot contain ://, should be hostnames only": {
+			RepoWhitelistOrgsFlag: "https://github.com/runatlantis",
+		},
+		"--repo-whitelist-orgs must be set when using --repo-whitelist-orgs-topic": {
+			RepoWhitelistFlag:          "*",
+			RepoWhitelistOrgsTopicFlag: "atlantis-enabled",
+		},
+		"invalid --repo-whitelist-orgs-sync-interval: must be a positive duration, ex. 10m": {
+			RepoWhitelistOrgsFlag:     "github.com/runatlantis",
+			RepoWhitelistOrgsSyncFlag: "0s",
+		},
+	} {
+		flags[GHUserFlag] = "user"
+		flags[GHTokenFlag] = "token"
+		c := setup(flags)
+		ErrEquals(t, expErr, c.Execute())
+	}
+}
+
 // Should error if the repo whitelist contained a scheme.
 func TestExecute_RepoWhitelistScheme(t *testing.T) {
 	c := setup(map[string]interface{}{
@@ -297,6 +553,269 @@ func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	ErrEquals(t, "invalid checkout strategy: not one of branch or merge", err)
 }
 
+func TestExecute_ValidateInvalidateOnPush(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		InvalidateOnPushFlag: "changed",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --invalidate-on-push: not one of all or modified", err)
+}
+
+func TestExecute_ValidateResultsExport(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:              "user",
+		GHTokenFlag:             "token",
+		RepoWhitelistFlag:       "*",
+		ResultsExportFormatFlag: "xml",
+	})
+	ErrEquals(t, "invalid --results-export-format: not one of sarif or junit", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:           "user",
+		GHTokenFlag:          "token",
+		RepoWhitelistFlag:    "*",
+		ResultsExportURLFlag: "results.example.com",
+	})
+	ErrEquals(t, `--results-export-url must have http:// or https://, got "results.example.com"`, c.Execute())
+}
+
+func TestExecute_ValidateModifiedFilesSource(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		ModifiedFilesSourceFlag: "api",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --modified-files-source: not one of vcs or git", err)
+}
+
+func TestExecute_ValidateMergeQueueMode(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		MergeQueueModeFlag: "apply-on-merge",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --merge-queue-mode: not one of apply-before-merge or apply-after-merge", err)
+}
+
+func TestExecute_MergeQueueVerifyPlanWithoutMode(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		MergeQueueVerifyPlanFlag: true,
+	})
+	err := c.Execute()
+	ErrEquals(t, "--merge-queue-mode must be set when using --merge-queue-verify-plan", err)
+}
+
+func TestExecute_ValidateStalePlanCheckSchedule(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		CheckoutStrategyFlag:       "merge",
+		StalePlanCheckScheduleFlag: "0 2 * *",
+	})
+	err := c.Execute()
+	ErrEquals(t, `invalid --stale-plan-check-schedule: "0 2 * *" must have 5 fields: minute hour day-of-month month day-of-week`, err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		StalePlanCheckScheduleFlag: "0 2 * * *",
+	})
+	err = c.Execute()
+	ErrEquals(t, "--checkout-strategy=merge must be set when using --stale-plan-check-schedule", err)
+}
+
+func TestExecute_ValidateSilenceNoChanges(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		SilenceNoChangesFlag: "quiet",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --silence-no-changes: not one of off, hide or summarize", err)
+}
+
+func TestExecute_ValidatePlanOutputDestination(t *testing.T) {
+	cases := []struct {
+		flags  map[string]interface{}
+		expErr string
+	}{
+		{
+			map[string]interface{}{PlanOutputDestinationFlag: "slack"},
+			"invalid --plan-output-destination: not one of ui, s3 or gist",
+		},
+		{
+			map[string]interface{}{PlanOutputDestinationFlag: "s3"},
+			"--plan-output-destination=s3 requires --plan-output-max-bytes",
+		},
+		{
+			map[string]interface{}{PlanOutputDestinationFlag: "s3", PlanOutputMaxBytesFlag: 20000},
+			"--plan-output-destination=s3 requires --artifact-bucket",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expErr, func(t *testing.T) {
+			err := setupWithDefaults(c.flags).Execute()
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+
+	c := setup(map[string]interface{}{
+		GitlabUserFlag:            "user",
+		GitlabTokenFlag:           "token",
+		RepoWhitelistFlag:         "*",
+		PlanOutputDestinationFlag: "gist",
+		PlanOutputMaxBytesFlag:    20000,
+	})
+	ErrEquals(t, "--plan-output-destination=gist requires --gh-user", c.Execute())
+}
+
+func TestExecute_ValidateLockingMode(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LockingModeFlag: "on_merge",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --locking-mode: not one of on_plan or on_apply", err)
+}
+
+func TestExecute_ValidateLockingOutage(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LockingOutageModeFlag: "retry",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --locking-outage-mode: not one of fail or queue", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		LockingOutageWaitFlag: "0s",
+	})
+	err = c.Execute()
+	ErrEquals(t, "invalid --locking-outage-wait: must be a positive duration, ex. 10m", err)
+}
+
+func TestExecute_ValidateVCSStatusMode(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		VCSStatusModeFlag: "per-pull",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --vcs-status-mode: not one of aggregate, project or both", err)
+}
+
+func TestExecute_ValidateExecutionBackend(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"invalid backend",
+			map[string]interface{}{
+				ExecutionBackendFlag: "docker",
+			},
+			"invalid --execution-backend: not one of local or kubernetes-job",
+		},
+		{
+			"kubernetes-job without image",
+			map[string]interface{}{
+				ExecutionBackendFlag:      "kubernetes-job",
+				K8sJobDataVolumeClaimFlag: "atlantis-data",
+			},
+			"--k8s-job-image and --k8s-job-data-volume-claim are required with --execution-backend=kubernetes-job",
+		},
+		{
+			"kubernetes-job without volume claim",
+			map[string]interface{}{
+				ExecutionBackendFlag: "kubernetes-job",
+				K8sJobImageFlag:      "runatlantis/atlantis",
+			},
+			"--k8s-job-image and --k8s-job-data-volume-claim are required with --execution-backend=kubernetes-job",
+		},
+		{
+			"kubernetes-job",
+			map[string]interface{}{
+				ExecutionBackendFlag:      "kubernetes-job",
+				K8sJobImageFlag:           "runatlantis/atlantis",
+				K8sJobDataVolumeClaimFlag: "atlantis-data",
+			},
+			"",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := setupWithDefaults(testCase.flags).Execute()
+			if testCase.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, testCase.expErr, err)
+			}
+		})
+	}
+}
+
+func TestExecute_ValidateDBBackend(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"invalid backend",
+			map[string]interface{}{
+				DBBackendFlag: "mysql",
+			},
+			"invalid --db-backend: not one of boltdb, sqlite or postgres",
+		},
+		{
+			"postgres without url",
+			map[string]interface{}{
+				DBBackendFlag: "postgres",
+			},
+			"--postgres-url must be set when using --db-backend=postgres",
+		},
+		{
+			"url with boltdb",
+			map[string]interface{}{
+				PostgresURLFlag: "postgres://localhost/atlantis",
+			},
+			"--postgres-url can only be used with --db-backend=postgres",
+		},
+		{
+			"url with sqlite",
+			map[string]interface{}{
+				DBBackendFlag:   "sqlite",
+				PostgresURLFlag: "postgres://localhost/atlantis",
+			},
+			"--postgres-url can only be used with --db-backend=postgres",
+		},
+		{
+			"sqlite",
+			map[string]interface{}{
+				DBBackendFlag: "sqlite",
+			},
+			"",
+		},
+		{
+			"postgres with url",
+			map[string]interface{}{
+				DBBackendFlag:   "postgres",
+				PostgresURLFlag: "postgres://localhost/atlantis",
+			},
+			"",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range testCase.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if testCase.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, testCase.expErr, err)
+			}
+		})
+	}
+}
+
 func TestExecute_ValidateSSLConfig(t *testing.T) {
 	expErr := "--ssl-key-file and --ssl-cert-file are both required for ssl"
 	cases := []struct {
@@ -594,60 +1113,853 @@ func TestExecute_BitbucketCloudWithWebhookSecret(t *testing.T) {
 	ErrEquals(t, "--bitbucket-webhook-secret cannot be specified for Bitbucket Cloud because it is not supported by Bitbucket", err)
 }
 
-// Base URL must have a scheme.
-func TestExecute_BitbucketServerBaseURLScheme(t *testing.T) {
+func TestExecute_ValidateRedactPatterns(t *testing.T) {
 	c := setup(map[string]interface{}{
-		BitbucketUserFlag:    "user",
-		BitbucketTokenFlag:   "token",
-		RepoWhitelistFlag:    "*",
-		BitbucketBaseURLFlag: "mydomain.com",
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "*",
+		RedactPatternsFlag: `["("]`,
 	})
-	ErrEquals(t, "--bitbucket-base-url must have http:// or https://, got \"mydomain.com\"", c.Execute())
+	ErrContains(t, `invalid --redact-patterns: compiling redact pattern "("`, c.Execute())
 
 	c = setup(map[string]interface{}{
-		BitbucketUserFlag:    "user",
-		BitbucketTokenFlag:   "token",
-		RepoWhitelistFlag:    "*",
-		BitbucketBaseURLFlag: "://mydomain.com",
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "*",
+		RedactPatternsFlag: `["password=(?P<secret>\\S+)"]`,
 	})
-	ErrEquals(t, "error parsing --bitbucket-webhook-secret flag value \"://mydomain.com\": parse \"://mydomain.com\": missing protocol scheme", c.Execute())
+	Ok(t, c.Execute())
 }
 
-// Port should be retained on base url.
-func TestExecute_BitbucketServerBaseURLPort(t *testing.T) {
+func TestExecute_ValidateAutoRegisterRepos(t *testing.T) {
 	c := setup(map[string]interface{}{
-		BitbucketUserFlag:    "user",
-		BitbucketTokenFlag:   "token",
-		RepoWhitelistFlag:    "*",
-		BitbucketBaseURLFlag: "http://mydomain.com:7990",
+		GHUserFlag:            "user",
+		GHTokenFlag:           "token",
+		AutoRegisterReposFlag: "!github.com/myorg/sandbox-*",
+	})
+	ErrEquals(t, `invalid --auto-register-repos: auto-register rules "!github.com/myorg/sandbox-*" must include at least one repo`, c.Execute())
+
+	// The whitelist isn't required if repos are auto-registered.
+	c = setup(map[string]interface{}{
+		GHUserFlag:            "user",
+		GHTokenFlag:           "token",
+		AutoRegisterReposFlag: "github.com/myorg/*,!github.com/myorg/sandbox-*",
 	})
 	Ok(t, c.Execute())
-	Equals(t, "http://mydomain.com:7990", passedConfig.BitbucketBaseURL)
 }
 
-// Can't use both --repo-config and --repo-config-json.
-func TestExecute_RepoCfgFlags(t *testing.T) {
+func TestExecute_ValidateEventsPort(t *testing.T) {
+	cases := []struct {
+		flags  map[string]interface{}
+		expErr string
+	}{
+		{
+			map[string]interface{}{
+				EventsPortFlag: 4141,
+			},
+			"--events-port must be different from --port",
+		},
+		{
+			map[string]interface{}{
+				GRPCPortFlag: 4141,
+			},
+			"--grpc-port must be different from --port and --events-port",
+		},
+		{
+			map[string]interface{}{
+				EventsPortFlag:        4142,
+				EventsSSLCertFileFlag: "cert",
+			},
+			"--events-ssl-key-file and --events-ssl-cert-file are both required for ssl",
+		},
+		{
+			map[string]interface{}{
+				EventsSSLCertFileFlag: "cert",
+				EventsSSLKeyFileFlag:  "key",
+			},
+			"--events-ssl-cert-file can only be used with --events-port",
+		},
+		{
+			map[string]interface{}{
+				EventsURLFlag: "https://atlantis-events.example.com",
+			},
+			"--events-url can only be used with --events-port",
+		},
+		{
+			map[string]interface{}{
+				EventsPortFlag:        4142,
+				EventsSSLCertFileFlag: "cert",
+				EventsSSLKeyFileFlag:  "key",
+				EventsURLFlag:         "https://atlantis-events.example.com",
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expErr, func(t *testing.T) {
+			c.flags[GHUserFlag] = "user"
+			c.flags[GHTokenFlag] = "token"
+			c.flags[RepoWhitelistFlag] = "*"
+			err := setup(c.flags).Execute()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestExecute_ValidateLoadShed(t *testing.T) {
 	c := setup(map[string]interface{}{
-		GHUserFlag:         "user",
-		GHTokenFlag:        "token",
-		RepoWhitelistFlag:  "github.com",
-		RepoConfigFlag:     "repos.yaml",
-		RepoConfigJSONFlag: "{}",
+		GHUserFlag:                "user",
+		GHTokenFlag:               "token",
+		RepoWhitelistFlag:         "*",
+		LoadShedMinFreeDiskMBFlag: -1,
 	})
-	err := c.Execute()
-	ErrEquals(t, "cannot use --repo-config and --repo-config-json at the same time", err)
+	ErrEquals(t, "--load-shed-min-free-disk-mb cannot be negative", c.Execute())
 }
 
-// Can't use both --tfe-hostname flag without --tfe-token.
-func TestExecute_TFEHostnameOnly(t *testing.T) {
+func TestExecute_ValidateEventQueue(t *testing.T) {
 	c := setup(map[string]interface{}{
-		GHUserFlag:        "user",
-		GHTokenFlag:       "token",
-		RepoWhitelistFlag: "github.com",
-		TFEHostnameFlag:   "not-app.terraform.io",
+		GHUserFlag:            "user",
+		GHTokenFlag:           "token",
+		RepoWhitelistFlag:     "*",
+		EventQueueWorkersFlag: -1,
 	})
-	err := c.Execute()
-	ErrEquals(t, "if setting --tfe-hostname, must set --tfe-token", err)
+	ErrEquals(t, "--event-queue-workers cannot be negative", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:             "user",
+		GHTokenFlag:            "token",
+		RepoWhitelistFlag:      "*",
+		EventQueueMaxDepthFlag: 10,
+	})
+	ErrEquals(t, "--event-queue-max-depth can only be used with --event-queue-workers", c.Execute())
+}
+
+func TestExecute_ValidateWebhookDedupWindow(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:             "user",
+		GHTokenFlag:            "token",
+		RepoWhitelistFlag:      "*",
+		WebhookDedupWindowFlag: "-1m",
+	})
+	ErrEquals(t, "invalid --webhook-dedup-window: must be a duration, ex. 10m", c.Execute())
+}
+
+func TestExecute_ValidateAutoplanDebounceWindow(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                 "user",
+		GHTokenFlag:                "token",
+		RepoWhitelistFlag:          "*",
+		AutoplanDebounceWindowFlag: "soon",
+	})
+	ErrEquals(t, "invalid --autoplan-debounce-window: must be a duration, ex. 30s", c.Execute())
+}
+
+func TestExecute_ValidateEventsRateLimit(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		EventsRateLimitPerIPFlag: -1,
+	})
+	ErrEquals(t, "--events-rate-limit-per-ip cannot be negative", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		EventsCircuitBreakerFlag: "15m",
+	})
+	ErrEquals(t, "--events-circuit-breaker-cooldown can only be used with --events-rate-limit-per-repo", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:                 "user",
+		GHTokenFlag:                "token",
+		RepoWhitelistFlag:          "*",
+		EventsRateLimitPerRepoFlag: 10,
+		EventsCircuitBreakerFlag:   "0s",
+	})
+	ErrEquals(t, "invalid --events-circuit-breaker-cooldown: must be a positive duration, ex. 15m", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		EventsTrustedProxiesFlag: "10.0.0.0/8",
+	})
+	ErrEquals(t, "--events-trusted-proxies can only be used with --events-rate-limit-per-ip", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		EventsRateLimitPerIPFlag: 10,
+		EventsTrustedProxiesFlag: "10.0.0.0/8, 10.0.0.1",
+	})
+	ErrEquals(t, `invalid --events-trusted-proxies: "10.0.0.1" must be a CIDR, ex. 10.0.0.0/8`, c.Execute())
+}
+
+func TestExecute_ValidatePlanEncryptionKey(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:            "user",
+		GHTokenFlag:           "token",
+		RepoWhitelistFlag:     "*",
+		PlanEncryptionKeyFlag: "passphrase",
+	})
+	ErrEquals(t, "invalid --plan-encryption-key: plan encryption key must be 32 bytes encoded as hex or base64, ex. from `openssl rand -hex 32`", c.Execute())
+}
+
+func TestExecute_ValidateDrainTimeout(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		DrainTimeoutFlag:  "-1m",
+	})
+	ErrEquals(t, "invalid --drain-timeout: must be a duration, ex. 10m", c.Execute())
+}
+
+func TestExecute_ValidateStepTimeouts(t *testing.T) {
+	for _, flag := range []string{InitTimeoutFlag, PlanTimeoutFlag, ApplyTimeoutFlag, RunTimeoutFlag} {
+		t.Run(flag, func(t *testing.T) {
+			c := setup(map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+				flag:              "0s",
+			})
+			ErrEquals(t, "invalid --"+flag+": must be a positive duration, ex. 30m", c.Execute())
+		})
+	}
+}
+
+func TestExecute_ValidateOutboundClientCert(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                 "user",
+		GHTokenFlag:                "token",
+		RepoWhitelistFlag:          "*",
+		OutboundClientCertFileFlag: "/etc/ssl/atlantis.crt",
+	})
+	ErrEquals(t, "--outbound-client-cert-file and --outbound-client-key-file must be set together", c.Execute())
+}
+
+func TestExecute_ValidateTFDownload(t *testing.T) {
+	cases := []struct {
+		flags  map[string]interface{}
+		expErr string
+	}{
+		{
+			map[string]interface{}{TFDistributionFlag: "tofu"},
+			"invalid --tf-distribution: not one of terraform or opentofu",
+		},
+		{
+			map[string]interface{}{TFDownloadChecksumFlag: "none"},
+			"invalid --tf-download-checksum: not one of verify or skip",
+		},
+	}
+	for _, c := range cases {
+		flags := map[string]interface{}{
+			GHUserFlag:        "user",
+			GHTokenFlag:       "token",
+			RepoWhitelistFlag: "*",
+		}
+		for k, v := range c.flags {
+			flags[k] = v
+		}
+		ErrEquals(t, c.expErr, setup(flags).Execute())
+	}
+}
+
+func TestExecute_OpenTofuDownloadURL(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "*",
+		TFDistributionFlag: "opentofu",
+	})
+	Ok(t, c.Execute())
+	Equals(t, DefaultOpenTofuDownloadURL, passedConfig.TFDownloadURL)
+}
+
+func TestExecute_ValidateTracingOTLPEndpoint(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:              "user",
+		GHTokenFlag:             "token",
+		RepoWhitelistFlag:       "*",
+		TracingOTLPEndpointFlag: "localhost:4318",
+	})
+	ErrEquals(t, "--tracing-otlp-endpoint must have http:// or https://, got \"localhost:4318\"", c.Execute())
+}
+
+func TestExecute_ValidateWebhookSecrets(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "*",
+		WebhookSecretsFlag: `[{"vcs": "github"}]`,
+	})
+	ErrEquals(t, "invalid --webhook-secrets: webhook secret 0: secret is required", c.Execute())
+
+	c = setup(map[string]interface{}{
+		BitbucketUserFlag:  "user",
+		BitbucketTokenFlag: "token",
+		RepoWhitelistFlag:  "*",
+		WebhookSecretsFlag: `[{"vcs": "bitbucket", "secret": "my secret"}]`,
+	})
+	ErrEquals(t, "--webhook-secrets cannot contain bitbucket secrets for Bitbucket Cloud because it is not supported by Bitbucket", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "*",
+		WebhookSecretsFlag: `[{"vcs": "github", "secret": "new"}, {"vcs": "github", "secret": "old"}]`,
+	})
+	Ok(t, c.Execute())
+}
+
+// Base URL must have a scheme.
+func TestExecute_BitbucketServerBaseURLScheme(t *testing.T) {
+	c := setup(map[string]interface{}{
+		BitbucketUserFlag:    "user",
+		BitbucketTokenFlag:   "token",
+		RepoWhitelistFlag:    "*",
+		BitbucketBaseURLFlag: "mydomain.com",
+	})
+	ErrEquals(t, "--bitbucket-base-url must have http:// or https://, got \"mydomain.com\"", c.Execute())
+
+	c = setup(map[string]interface{}{
+		BitbucketUserFlag:    "user",
+		BitbucketTokenFlag:   "token",
+		RepoWhitelistFlag:    "*",
+		BitbucketBaseURLFlag: "://mydomain.com",
+	})
+	ErrEquals(t, "error parsing --bitbucket-webhook-secret flag value \"://mydomain.com\": parse \"://mydomain.com\": missing protocol scheme", c.Execute())
+}
+
+// Port should be retained on base url.
+func TestExecute_BitbucketServerBaseURLPort(t *testing.T) {
+	c := setup(map[string]interface{}{
+		BitbucketUserFlag:    "user",
+		BitbucketTokenFlag:   "token",
+		RepoWhitelistFlag:    "*",
+		BitbucketBaseURLFlag: "http://mydomain.com:7990",
+	})
+	Ok(t, c.Execute())
+	Equals(t, "http://mydomain.com:7990", passedConfig.BitbucketBaseURL)
+}
+
+func TestExecute_ValidateAPITokens(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		APITokensFlag:     `[{"name": "ci", "token": "secret", "scopes": ["locks:write"]}]`,
+	})
+	ErrEquals(t, "invalid --api-tokens: API token \"ci\": invalid scope \"locks:write\", must be one of locks:read, locks:delete, plan:trigger, apply:trigger, tokens:manage, audit:read, metrics:read, repos:manage, plans:read, workers:run, config:reload, config:read, flags:manage, loglevel:manage, pulls:read, commands:cancel, events:manage", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		APITokensFlag:     `[{"name": "ci", "token": "secret", "scopes": ["locks:read"]}]`,
+	})
+	Ok(t, c.Execute())
+	Equals(t, `[{"name": "ci", "token": "secret", "scopes": ["locks:read"]}]`, passedConfig.APITokens)
+}
+
+func TestExecute_RemoteWorkersRequireToken(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		RemoteWorkersFlag: true,
+		APITokensFlag:     `[{"name": "ci", "token": "secret", "scopes": ["locks:read"]}]`,
+	})
+	ErrEquals(t, "--remote-workers requires a token with the workers:run scope in --api-tokens", c.Execute())
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		RemoteWorkersFlag: true,
+		APITokensFlag:     `[{"name": "worker", "token": "secret", "scopes": ["workers:run"]}]`,
+	})
+	Ok(t, c.Execute())
+}
+
+func TestExecute_ValidateArtifactURLExpiry(t *testing.T) {
+	for _, expiry := range []string{"0s", "-1h", "200h", "tomorrow"} {
+		c := setup(map[string]interface{}{
+			GHUserFlag:            "user",
+			GHTokenFlag:           "token",
+			RepoWhitelistFlag:     "*",
+			ArtifactURLExpiryFlag: expiry,
+		})
+		ErrEquals(t, "invalid --artifact-url-expiry: must be a positive duration of at most 168h, ex. 24h", c.Execute())
+	}
+}
+
+func TestExecute_ValidateWebAuthConfig(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"basic auth without password",
+			map[string]interface{}{
+				WebBasicAuthFlag: true,
+				WebUsernameFlag:  "user",
+			},
+			"--web-username and --web-password must be set when using --web-basic-auth",
+		},
+		{
+			"basic auth and oidc",
+			map[string]interface{}{
+				WebBasicAuthFlag:        true,
+				WebUsernameFlag:         "user",
+				WebPasswordFlag:         "pass",
+				WebOIDCIssuerURLFlag:    "https://accounts.example.com",
+				WebOIDCClientIDFlag:     "id",
+				WebOIDCClientSecretFlag: "secret",
+			},
+			"cannot use --web-basic-auth and --web-oidc-issuer-url at the same time",
+		},
+		{
+			"oidc without client secret",
+			map[string]interface{}{
+				WebOIDCIssuerURLFlag: "https://accounts.example.com",
+				WebOIDCClientIDFlag:  "id",
+			},
+			"--web-oidc-client-id and --web-oidc-client-secret must be set when using --web-oidc-issuer-url",
+		},
+		{
+			"oidc without https",
+			map[string]interface{}{
+				WebOIDCIssuerURLFlag:    "http://accounts.example.com",
+				WebOIDCClientIDFlag:     "id",
+				WebOIDCClientSecretFlag: "secret",
+			},
+			"--web-oidc-issuer-url must have https://, got \"http://accounts.example.com\"",
+		},
+		{
+			"basic auth",
+			map[string]interface{}{
+				WebBasicAuthFlag: true,
+				WebUsernameFlag:  "user",
+				WebPasswordFlag:  "pass",
+			},
+			"",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range testCase.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if testCase.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, testCase.expErr, err)
+			}
+		})
+	}
+}
+
+func TestExecute_ValidateAuditLogConfig(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"syslog without audit log",
+			map[string]interface{}{
+				AuditSyslogAddrFlag: "local",
+			},
+			"--audit-log must be set when using --audit-syslog-addr",
+		},
+		{
+			"webhook without audit log",
+			map[string]interface{}{
+				AuditWebhookURLFlag: "https://audit.example.com",
+			},
+			"--audit-log must be set when using --audit-webhook-url",
+		},
+		{
+			"webhook without scheme",
+			map[string]interface{}{
+				AuditLogFlag:        true,
+				AuditWebhookURLFlag: "audit.example.com",
+			},
+			"--audit-webhook-url must have http:// or https://, got \"audit.example.com\"",
+		},
+		{
+			"audit log with sinks",
+			map[string]interface{}{
+				AuditLogFlag:        true,
+				AuditSyslogAddrFlag: "local",
+				AuditWebhookURLFlag: "https://audit.example.com",
+			},
+			"",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range testCase.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if testCase.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, testCase.expErr, err)
+			}
+		})
+	}
+}
+
+func TestExecute_SecretScannerCommandRequiresScanSecrets(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:               "user",
+		GHTokenFlag:              "token",
+		RepoWhitelistFlag:        "*",
+		SecretScannerCommandFlag: "gitleaks-wrapper",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--scan-secrets must be set when using --secret-scanner-command", err)
+}
+
+func TestExecute_NoSensitiveDiskWritesWithResultsExportDir(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                "user",
+		GHTokenFlag:               "token",
+		RepoWhitelistFlag:         "*",
+		NoSensitiveDiskWritesFlag: true,
+		ResultsExportDirFlag:      "/tmp/results",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--results-export-dir can't be used with --no-sensitive-disk-writes", err)
+}
+
+func TestExecute_ExpandHomeInReposDir(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "*",
+		ReposDirFlag:      "~/repos",
+	})
+	err := c.Execute()
+	Ok(t, err)
+
+	home, err := homedir.Dir()
+	Ok(t, err)
+	Equals(t, home+"/repos", passedConfig.ReposDir)
+}
+
+func TestExecute_ValidateSMTP(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"addr without from",
+			map[string]interface{}{
+				SMTPAddrFlag: "smtp.example.com:587",
+			},
+			"--smtp-from must be set when using --smtp-addr",
+		},
+		{
+			"addr without port",
+			map[string]interface{}{
+				SMTPAddrFlag: "smtp.example.com",
+				SMTPFromFlag: "atlantis@example.com",
+			},
+			"invalid --smtp-addr: must be of the form host:port: address smtp.example.com: missing port in address",
+		},
+		{
+			"username without addr",
+			map[string]interface{}{
+				SMTPUsernameFlag: "user",
+			},
+			"--smtp-addr must be set when using --smtp-from, --smtp-username or --smtp-password",
+		},
+		{
+			"valid",
+			map[string]interface{}{
+				SMTPAddrFlag:     "smtp.example.com:587",
+				SMTPFromFlag:     "atlantis@example.com",
+				SMTPUsernameFlag: "user",
+				SMTPPasswordFlag: "pass",
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range c.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestExecute_ValidateCommentRateLimit(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                 "user",
+		GHTokenFlag:                "token",
+		RepoWhitelistFlag:          "*",
+		CommentRateLimitFlag:       5,
+		CommentRateLimitWindowFlag: "0s",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --comment-rate-limit-window: must be a positive duration, ex. 10m", err)
+
+	c = setup(map[string]interface{}{
+		GHUserFlag:           "user",
+		GHTokenFlag:          "token",
+		RepoWhitelistFlag:    "*",
+		CommentRateLimitFlag: -1,
+	})
+	err = c.Execute()
+	ErrEquals(t, "--comment-rate-limit cannot be negative", err)
+}
+
+func TestExecute_ValidateFailureReport(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"channel without slack token",
+			map[string]interface{}{
+				FailureReportChannelFlag: "atlantis-failures",
+			},
+			"--slack-token must be set when using --failure-report-channel",
+		},
+		{
+			"invalid interval",
+			map[string]interface{}{
+				FailureReportIntervalFlag: "0s",
+			},
+			"invalid --failure-report-interval: must be a positive duration, ex. 168h",
+		},
+		{
+			"valid",
+			map[string]interface{}{
+				FailureReportChannelFlag:  "atlantis-failures",
+				FailureReportIntervalFlag: "24h",
+				SlackTokenFlag:            "token",
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range c.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestExecute_ValidateSlackApprovals(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"channel without signing secret",
+			map[string]interface{}{
+				SlackApprovalsChannelFlag: "atlantis-approvals",
+				SlackTokenFlag:            "token",
+				SlackUsersFlag:            "U012AB3CD:alice",
+			},
+			"--slack-signing-secret must be set when using --slack-approvals-channel",
+		},
+		{
+			"channel without users",
+			map[string]interface{}{
+				SlackApprovalsChannelFlag: "atlantis-approvals",
+				SlackTokenFlag:            "token",
+				SlackSigningSecretFlag:    "secret",
+			},
+			"--slack-users must be set when using --slack-approvals-channel",
+		},
+		{
+			"invalid users",
+			map[string]interface{}{
+				SlackApprovalsChannelFlag: "atlantis-approvals",
+				SlackTokenFlag:            "token",
+				SlackSigningSecretFlag:    "secret",
+				SlackUsersFlag:            "U012AB3CD:alice,bob",
+			},
+			"invalid --slack-users: \"bob\" must be of the form {slack user ID}:{VCS username}",
+		},
+		{
+			"valid",
+			map[string]interface{}{
+				SlackApprovalsChannelFlag: "atlantis-approvals",
+				SlackTokenFlag:            "token",
+				SlackSigningSecretFlag:    "secret",
+				SlackUsersFlag:            "U012AB3CD:alice, U045EF6GH:bob",
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			flags := map[string]interface{}{
+				GHUserFlag:        "user",
+				GHTokenFlag:       "token",
+				RepoWhitelistFlag: "*",
+			}
+			for k, v := range c.flags {
+				flags[k] = v
+			}
+			err := setup(flags).Execute()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+// Can't use both --repo-config and --repo-config-json.
+func TestExecute_RepoCfgFlags(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:         "user",
+		GHTokenFlag:        "token",
+		RepoWhitelistFlag:  "github.com",
+		RepoConfigFlag:     "repos.yaml",
+		RepoConfigJSONFlag: "{}",
+	})
+	err := c.Execute()
+	ErrEquals(t, "cannot use --repo-config and --repo-config-json at the same time", err)
+}
+
+// Can't watch the repo config if there's no file.
+func TestExecute_RepoConfigWatchWithoutFile(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:          "user",
+		GHTokenFlag:         "token",
+		RepoWhitelistFlag:   "github.com",
+		RepoConfigWatchFlag: true,
+	})
+	err := c.Execute()
+	ErrEquals(t, "--repo-config must be set when using --repo-config-watch", err)
+}
+
+// Can't use both --tfe-hostname flag without --tfe-token.
+func TestExecute_TFEHostnameOnly(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "github.com",
+		TFEHostnameFlag:   "not-app.terraform.io",
+	})
+	err := c.Execute()
+	ErrEquals(t, "if setting --tfe-hostname, must set --tfe-token", err)
+}
+
+// Can't use --vault-addr without --vault-token.
+func TestExecute_VaultAddrOnly(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "github.com",
+		VaultAddrFlag:     "https://vault.example.com:8200",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--vault-addr and --vault-token must be set together", err)
+}
+
+func TestExecute_ValidateEventBusURL(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoWhitelistFlag: "github.com",
+		EventBusURLFlag:   "amqp://rabbitmq.example.com",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --event-bus-url: \"amqp://rabbitmq.example.com\" must start with nats://, sqs://, kafka+http:// or kafka+https://", err)
+}
+
+func TestExecute_ValidateWebhookArchive(t *testing.T) {
+	cases := []struct {
+		flags  map[string]interface{}
+		expErr string
+	}{
+		{
+			map[string]interface{}{WebhookArchiveURLFlag: "bucket/webhooks"},
+			"invalid --webhook-archive-url: \"bucket/webhooks\" must start with s3:// or gs://",
+		},
+		{
+			map[string]interface{}{WebhookArchiveURLFlag: "gs://bucket", WebhookArchiveRetentionFlag: -1},
+			"--webhook-archive-retention-days cannot be negative",
+		},
+		{
+			map[string]interface{}{WebhookArchiveURLFlag: "gs://bucket/webhooks", WebhookArchiveRetentionFlag: 0},
+			"",
+		},
+	}
+	for _, c := range cases {
+		flags := map[string]interface{}{
+			GHUserFlag:        "user",
+			GHTokenFlag:       "token",
+			RepoWhitelistFlag: "github.com",
+		}
+		for k, v := range c.flags {
+			flags[k] = v
+		}
+		err := setup(flags).Execute()
+		if c.expErr != "" {
+			ErrEquals(t, c.expErr, err)
+		} else {
+			Ok(t, err)
+		}
+	}
 }
 
 func setup(flags map[string]interface{}) *cobra.Command {
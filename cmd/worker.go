@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/artifacts"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// To add a new worker flag, add it to workerFlags. Flags shared with the
+// server use the server's flag constants.
+const (
+	WorkerServerURLFlag = "server-url"
+	WorkerTokenFlag     = "token"
+)
+
+var workerFlags = map[string]stringFlag{
+	ArtifactBucketFlag:     stringFlags[ArtifactBucketFlag],
+	ArtifactS3EndpointFlag: stringFlags[ArtifactS3EndpointFlag],
+	ArtifactURLExpiryFlag:  stringFlags[ArtifactURLExpiryFlag],
+	DataDirFlag: {
+		description:  "Path to directory to clone repos in and download terraform binaries to. Clones are deleted once each job is done.",
+		defaultValue: DefaultDataDir,
+	},
+	DefaultTFVersionFlag: {
+		description: "Terraform version to default to (ex. v0.12.0). Will download if not yet on disk." +
+			" If not set, Atlantis uses the terraform binary in its PATH.",
+	},
+	LogLevelFlag: {
+		description:  "Log level. Either debug, info, warn, or error.",
+		defaultValue: DefaultLogLevel,
+	},
+	WorkerServerURLFlag: {
+		description: "URL of the Atlantis server to claim jobs from, ex. https://atlantis.example.com. The server must be started with --" + RemoteWorkersFlag + ".",
+	},
+	TFDistributionFlag:     stringFlags[TFDistributionFlag],
+	TFDownloadChecksumFlag: stringFlags[TFDownloadChecksumFlag],
+	TFDownloadURLFlag:      stringFlags[TFDownloadURLFlag],
+	TFLocalBinDirFlag:      stringFlags[TFLocalBinDirFlag],
+	TFEHostnameFlag: {
+		description:  "Hostname of your Terraform Enterprise installation. If using Terraform Cloud no need to set.",
+		defaultValue: DefaultTFEHostname,
+	},
+	TFETokenFlag: {
+		description: "API token for Terraform Cloud/Enterprise. Used by projects that use the remote backend.",
+	},
+	VaultAddrFlag:  stringFlags[VaultAddrFlag],
+	VaultTokenFlag: stringFlags[VaultTokenFlag],
+	WorkerTokenFlag: {
+		description: "API token with the " + server.WorkersRunScope + " scope, used to claim jobs and report their results." +
+			" Should be specified via the ATLANTIS_TOKEN environment variable.",
+	},
+}
+
+// WorkerCmd runs a worker that claims plans and applies from an Atlantis
+// server started with --remote-workers and runs them.
+type WorkerCmd struct {
+	WorkerCreator WorkerCreator
+	Viper         *viper.Viper
+	Logger        *logging.SimpleLogger
+}
+
+// WorkerCreator creates workers.
+// It's an abstraction to help us test.
+type WorkerCreator interface {
+	NewWorker(config workers.AgentConfig, logger *logging.SimpleLogger) (WorkerStarter, error)
+}
+
+// WorkerStarter is for starting up a worker.
+// It's an abstraction to help us test.
+type WorkerStarter interface {
+	Start() error
+}
+
+// DefaultWorkerCreator is the concrete implementation of WorkerCreator.
+type DefaultWorkerCreator struct{}
+
+// NewWorker returns the real worker agent.
+func (d *DefaultWorkerCreator) NewWorker(config workers.AgentConfig, logger *logging.SimpleLogger) (WorkerStarter, error) {
+	return workers.NewAgent(config, logger)
+}
+
+// Init returns the runnable cobra command.
+func (w *WorkerCmd) Init() *cobra.Command {
+	c := &cobra.Command{
+		Use:           "worker",
+		Short:         "Start an atlantis worker",
+		Long:          `Start a worker that runs plans and applies for an atlantis server started with --` + RemoteWorkersFlag + `.`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := w.run()
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "\033[31mError: %s\033[39m\n\n", err.Error())
+			}
+			return err
+		},
+	}
+
+	// Like the server, accept env vars prefixed with ATLANTIS_ instead of
+	// flags.
+	w.Viper.SetEnvPrefix("ATLANTIS")
+	w.Viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	w.Viper.AutomaticEnv()
+
+	c.SetUsageTemplate(usageTmpl(workerFlags, nil, nil))
+	for name, f := range workerFlags {
+		usage := f.description
+		if f.defaultValue != "" {
+			usage = fmt.Sprintf("%s (default %q)", usage, f.defaultValue)
+		}
+		c.Flags().String(name, "", usage+"\n")
+		w.Viper.BindPFlag(name, c.Flags().Lookup(name)) // nolint: errcheck
+	}
+	return c
+}
+
+func (w *WorkerCmd) run() error {
+	get := func(name string) string {
+		if v := w.Viper.GetString(name); v != "" {
+			return v
+		}
+		return workerFlags[name].defaultValue
+	}
+	if get(WorkerServerURLFlag) == "" || get(WorkerTokenFlag) == "" {
+		return fmt.Errorf("--%s and --%s must be set", WorkerServerURLFlag, WorkerTokenFlag)
+	}
+	if get(TFEHostnameFlag) != DefaultTFEHostname && get(TFETokenFlag) == "" {
+		return fmt.Errorf("if setting --%s, must set --%s", TFEHostnameFlag, TFETokenFlag)
+	}
+	if err := validateTFDownload(get(TFDistributionFlag), get(TFDownloadChecksumFlag)); err != nil {
+		return err
+	}
+	tfDownloadURL := w.Viper.GetString(TFDownloadURLFlag)
+	if tfDownloadURL == "" {
+		tfDownloadURL = defaultTFDownloadURL(get(TFDistributionFlag))
+	}
+	if (get(VaultAddrFlag) == "") != (get(VaultTokenFlag) == "") {
+		return fmt.Errorf("--%s and --%s must be set together", VaultAddrFlag, VaultTokenFlag)
+	}
+	artifactURLExpiry, err := time.ParseDuration(get(ArtifactURLExpiryFlag))
+	if err != nil || artifactURLExpiry <= 0 || artifactURLExpiry > artifacts.MaxURLExpiry {
+		return fmt.Errorf("invalid --%s: must be a positive duration of at most 168h, ex. 24h", ArtifactURLExpiryFlag)
+	}
+	dataDir, err := absDataDir(get(DataDirFlag))
+	if err != nil {
+		return err
+	}
+	w.Logger.SetLevel(server.UserConfig{LogLevel: get(LogLevelFlag)}.ToLogLevel())
+
+	worker, err := w.WorkerCreator.NewWorker(workers.AgentConfig{
+		ServerURL:            get(WorkerServerURLFlag),
+		Token:                get(WorkerTokenFlag),
+		DataDir:              dataDir,
+		DefaultTFVersion:     get(DefaultTFVersionFlag),
+		DefaultTFVersionFlag: DefaultTFVersionFlag,
+		TFDownload: terraform.DownloadConfig{
+			URL:          tfDownloadURL,
+			Distribution: get(TFDistributionFlag),
+			Checksum:     get(TFDownloadChecksumFlag),
+			LocalBinDir:  get(TFLocalBinDirFlag),
+		},
+		TFEToken:           get(TFETokenFlag),
+		TFEHostname:        get(TFEHostnameFlag),
+		ArtifactBucket:     get(ArtifactBucketFlag),
+		ArtifactS3Endpoint: get(ArtifactS3EndpointFlag),
+		ArtifactURLExpiry:  artifactURLExpiry,
+		VaultAddr:          get(VaultAddrFlag),
+		VaultToken:         get(VaultTokenFlag),
+	}, w.Logger)
+	if err != nil {
+		return errors.Wrap(err, "initializing worker")
+	}
+	return worker.Start()
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+	"github.com/spf13/viper"
+)
+
+var passedWorkerConfig workers.AgentConfig
+
+type WorkerCreatorMock struct{}
+
+func (w *WorkerCreatorMock) NewWorker(config workers.AgentConfig, logger *logging.SimpleLogger) (WorkerStarter, error) {
+	passedWorkerConfig = config
+	return &ServerStarterMock{}, nil
+}
+
+func setupWorker(flags map[string]interface{}) *WorkerCmd {
+	vipr := viper.New()
+	for k, v := range flags {
+		vipr.Set(k, v)
+	}
+	return &WorkerCmd{
+		WorkerCreator: &WorkerCreatorMock{},
+		Viper:         vipr,
+		Logger:        logging.NewNoopLogger(),
+	}
+}
+
+func TestWorker_RequiresServerURLAndToken(t *testing.T) {
+	for _, flags := range []map[string]interface{}{
+		{},
+		{WorkerServerURLFlag: "https://atlantis.example.com"},
+		{WorkerTokenFlag: "token"},
+	} {
+		c := setupWorker(flags).Init()
+		c.SetOutput(ioutil.Discard)
+		err := c.Execute()
+		ErrEquals(t, "--server-url and --token must be set", err)
+	}
+}
+
+func TestWorker_Defaults(t *testing.T) {
+	c := setupWorker(map[string]interface{}{
+		WorkerServerURLFlag: "https://atlantis.example.com",
+		WorkerTokenFlag:     "token",
+	}).Init()
+	Ok(t, c.Execute())
+
+	Equals(t, "https://atlantis.example.com", passedWorkerConfig.ServerURL)
+	Equals(t, "token", passedWorkerConfig.Token)
+	Assert(t, filepath.IsAbs(passedWorkerConfig.DataDir), "expected data dir to be absolute, got %q", passedWorkerConfig.DataDir)
+	Equals(t, filepath.Base(DefaultDataDir), filepath.Base(passedWorkerConfig.DataDir))
+	Equals(t, terraform.DownloadConfig{
+		URL:          DefaultTFDownloadURL,
+		Distribution: terraform.TerraformDistribution,
+		Checksum:     terraform.ChecksumVerify,
+	}, passedWorkerConfig.TFDownload)
+	Equals(t, DefaultTFEHostname, passedWorkerConfig.TFEHostname)
+	Equals(t, DefaultTFVersionFlag, passedWorkerConfig.DefaultTFVersionFlag)
+	Equals(t, 24*time.Hour, passedWorkerConfig.ArtifactURLExpiry)
+}
+
+func TestWorker_Flags(t *testing.T) {
+	c := setupWorker(map[string]interface{}{
+		ArtifactBucketFlag:     "atlantis-artifacts",
+		ArtifactS3EndpointFlag: "https://minio.example.com",
+		ArtifactURLExpiryFlag:  "48h",
+		DataDirFlag:            "/path",
+		DefaultTFVersionFlag:   "v0.12.24",
+		LogLevelFlag:           "debug",
+		WorkerServerURLFlag:    "https://atlantis.example.com",
+		TFDistributionFlag:     "opentofu",
+		TFDownloadChecksumFlag: "skip",
+		TFDownloadURLFlag:      "https://mirror.example.com",
+		TFLocalBinDirFlag:      "/opt/terraform",
+		TFEHostnameFlag:        "tfe.example.com",
+		TFETokenFlag:           "tfe-token",
+		VaultAddrFlag:          "https://vault.example.com:8200",
+		VaultTokenFlag:         "vault-token",
+		WorkerTokenFlag:        "token",
+	}).Init()
+	Ok(t, c.Execute())
+
+	Equals(t, workers.AgentConfig{
+		ServerURL:            "https://atlantis.example.com",
+		Token:                "token",
+		DataDir:              "/path",
+		DefaultTFVersion:     "v0.12.24",
+		DefaultTFVersionFlag: DefaultTFVersionFlag,
+		TFDownload: terraform.DownloadConfig{
+			URL:          "https://mirror.example.com",
+			Distribution: terraform.OpenTofuDistribution,
+			Checksum:     terraform.ChecksumSkip,
+			LocalBinDir:  "/opt/terraform",
+		},
+		TFEToken:           "tfe-token",
+		TFEHostname:        "tfe.example.com",
+		ArtifactBucket:     "atlantis-artifacts",
+		ArtifactS3Endpoint: "https://minio.example.com",
+		ArtifactURLExpiry:  48 * time.Hour,
+		VaultAddr:          "https://vault.example.com:8200",
+		VaultToken:         "vault-token",
+	}, passedWorkerConfig)
+}
+
+func TestWorker_OpenTofuDownloadURL(t *testing.T) {
+	c := setupWorker(map[string]interface{}{
+		WorkerServerURLFlag: "https://atlantis.example.com",
+		WorkerTokenFlag:     "token",
+		TFDistributionFlag:  "opentofu",
+	}).Init()
+	Ok(t, c.Execute())
+	Equals(t, DefaultOpenTofuDownloadURL, passedWorkerConfig.TFDownload.URL)
+}
+
+func TestWorker_TFEHostnameRequiresToken(t *testing.T) {
+	c := setupWorker(map[string]interface{}{
+		WorkerServerURLFlag: "https://atlantis.example.com",
+		WorkerTokenFlag:     "token",
+		TFEHostnameFlag:     "tfe.example.com",
+	}).Init()
+	c.SetOutput(ioutil.Discard)
+	ErrEquals(t, "if setting --tfe-hostname, must set --tfe-token", c.Execute())
+}
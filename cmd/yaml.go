@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/spf13/cobra"
+)
+
+// To add a new yaml validate flag, add it to yamlValidateStringFlags or
+// yamlValidateBoolFlags. Flags shared with the server use the server's flag
+// constants.
+const (
+	YAMLRepoIDFlag = "repo-id"
+)
+
+var yamlValidateStringFlags = map[string]stringFlag{
+	RepoConfigFlag: {
+		description: "Path to the server-side repo config file the server is started with. If set, the atlantis.yaml file is also checked" +
+			" against it, ex. that the workflows it references are defined and the keys it sets are allowed.",
+	},
+	YAMLRepoIDFlag: {
+		description: "ID of the repo the atlantis.yaml file is in, ex. github.com/runatlantis/atlantis." +
+			" It's used to find which repos in --" + RepoConfigFlag + " apply to the file.",
+	},
+}
+
+var yamlValidateBoolFlags = map[string]boolFlag{
+	AllowRepoConfigFlag: {
+		description:  "Validate the file as if the server was started with --" + AllowRepoConfigFlag + ".",
+		defaultValue: false,
+	},
+}
+
+// YAMLCmd checks Atlantis config files.
+type YAMLCmd struct{}
+
+// Init returns the runnable cobra command.
+func (y *YAMLCmd) Init() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "yaml",
+		Short: "Check Atlantis config files",
+	}
+	c.AddCommand(y.validateCmd())
+	return c
+}
+
+func (y *YAMLCmd) validateCmd() *cobra.Command {
+	var repoConfig, repoID string
+	var allowRepoConfig bool
+	c := &cobra.Command{
+		Use:   "validate [flags] path/to/atlantis.yaml",
+		Short: "Validate an atlantis.yaml file",
+		Long: `Validate an atlantis.yaml file with the same parser and validation as the server.
+Each error is printed with its line if it's known. Exits with 1 if the file is invalid.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := y.validate(cmd, args[0], repoConfig, repoID, allowRepoConfig)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "\033[31mError: %s\033[39m\n", err.Error())
+			}
+			return err
+		},
+	}
+	c.SetUsageTemplate(usageTmpl(yamlValidateStringFlags, nil, yamlValidateBoolFlags))
+	c.Flags().StringVar(&repoConfig, RepoConfigFlag, "", yamlValidateStringFlags[RepoConfigFlag].description)
+	c.Flags().StringVar(&repoID, YAMLRepoIDFlag, "", yamlValidateStringFlags[YAMLRepoIDFlag].description)
+	c.Flags().BoolVar(&allowRepoConfig, AllowRepoConfigFlag, false, yamlValidateBoolFlags[AllowRepoConfigFlag].description)
+	return c
+}
+
+// validate prints the errors in the atlantis.yaml file at path. It returns an
+// error if there were any.
+func (y *YAMLCmd) validate(cmd *cobra.Command, path string, repoConfig string, repoID string, allowRepoConfig bool) error {
+	validator := &yaml.ParserValidator{}
+	// The apply requirement flags don't change what's valid.
+	globalCfg := valid.NewGlobalCfg(allowRepoConfig, false, false)
+	if repoConfig != "" {
+		var err error
+		globalCfg, err = validator.ParseGlobalCfg(repoConfig, globalCfg)
+		if err != nil {
+			return errors.Wrapf(err, "parsing %s file", repoConfig)
+		}
+	}
+
+	cfgErrs, err := validator.ValidateRepoCfgFile(path, globalCfg, repoID)
+	if err != nil {
+		return err
+	}
+	if len(cfgErrs) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+		return nil
+	}
+	for _, cfgErr := range cfgErrs {
+		if cfgErr.Line > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s:%d: %s\n", path, cfgErr.Line, y.withoutLine(cfgErr))
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", path, cfgErr.Error())
+		}
+	}
+	return fmt.Errorf("found %d error(s) in %s", len(cfgErrs), path)
+}
+
+// withoutLine returns the error message of cfgErr without its line since
+// it's printed with the path.
+func (y *YAMLCmd) withoutLine(cfgErr yaml.ConfigError) string {
+	cfgErr.Line = 0
+	return cfgErr.Error()
+}
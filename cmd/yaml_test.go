@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func runYAMLValidate(t *testing.T, args ...string) (string, error) {
+	c := (&YAMLCmd{}).Init()
+	var out bytes.Buffer
+	c.SetOutput(&out)
+	c.SetArgs(append([]string{"validate"}, args...))
+	err := c.Execute()
+	return out.String(), err
+}
+
+func TestYAMLValidate_Valid(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "atlantis.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte("version: 3\nprojects:\n- dir: .\n"), 0600))
+
+	out, err := runYAMLValidate(t, path)
+	Ok(t, err)
+	Equals(t, path+" is valid\n", out)
+}
+
+func TestYAMLValidate_Invalid(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "atlantis.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte("version: 3\nprojects:\n- dir: ../mod\n  workflow: custom\n"), 0600))
+
+	out, err := runYAMLValidate(t, path)
+	ErrEquals(t, "found 1 error(s) in "+path, err)
+	Equals(t, path+":3: projects[0].dir: cannot contain '..'\n\x1b[31mError: found 1 error(s) in "+path+"\x1b[39m\n", out)
+}
+
+// Test that workflows referenced by the atlantis.yaml file are looked up in
+// the server-side repo config.
+func TestYAMLValidate_RepoConfig(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "atlantis.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte("version: 3\nprojects:\n- dir: .\n  workflow: custom\n"), 0600))
+	repoConfig := filepath.Join(tmp, "repos.yaml")
+	Ok(t, ioutil.WriteFile(repoConfig, []byte(`repos:
+- id: github.com/owner/repo
+  allowed_overrides: [workflow]
+workflows:
+  other: {}
+`), 0600))
+
+	out, err := runYAMLValidate(t, "--repo-config", repoConfig, "--repo-id", "github.com/owner/repo", path)
+	ErrEquals(t, "found 1 error(s) in "+path, err)
+	Equals(t, path+": workflow \"custom\" is not defined anywhere\n\x1b[31mError: found 1 error(s) in "+path+"\x1b[39m\n", out)
+
+	Ok(t, ioutil.WriteFile(repoConfig, []byte(`repos:
+- id: github.com/owner/repo
+  allowed_overrides: [workflow]
+workflows:
+  custom: {}
+`), 0600))
+	_, err = runYAMLValidate(t, "--repo-config", repoConfig, "--repo-id", "github.com/owner/repo", path)
+	Ok(t, err)
+}
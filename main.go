@@ -33,10 +33,20 @@ func main() {
 		AtlantisVersion: atlantisVersion,
 		Logger:          logging.NewSimpleLogger("cmd", false, logging.Info),
 	}
+	worker := &cmd.WorkerCmd{
+		WorkerCreator: &cmd.DefaultWorkerCreator{},
+		Viper:         viper.New(),
+		Logger:        logging.NewSimpleLogger("worker", false, logging.Info),
+	}
 	version := &cmd.VersionCmd{AtlantisVersion: atlantisVersion}
 	testdrive := &cmd.TestdriveCmd{}
+	yaml := &cmd.YAMLCmd{}
+	dbCmd := &cmd.DBCmd{}
 	cmd.RootCmd.AddCommand(server.Init())
+	cmd.RootCmd.AddCommand(worker.Init())
 	cmd.RootCmd.AddCommand(version.Init())
 	cmd.RootCmd.AddCommand(testdrive.Init())
+	cmd.RootCmd.AddCommand(yaml.Init())
+	cmd.RootCmd.AddCommand(dbCmd.Init())
 	cmd.Execute()
 }
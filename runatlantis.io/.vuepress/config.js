@@ -54,6 +54,7 @@ module.exports = {
                         'webhook-secrets',
                         'deployment',
                         'configuring-webhooks',
+                        'onboarding-repos',
                         'provider-credentials'
                     ]
                 },
@@ -70,7 +71,8 @@ module.exports = {
                         'apply-requirements',
                         'checkout-strategy',
                         'terraform-versions',
-                        'terraform-cloud'
+                        'terraform-cloud',
+                        'metrics'
                     ]
                 },
                 {
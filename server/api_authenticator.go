@@ -0,0 +1,260 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// API scopes that tokens can be granted.
+const (
+	// LocksReadScope allows viewing locks.
+	LocksReadScope = "locks:read"
+	// LocksDeleteScope allows deleting locks, which also discards their plans.
+	LocksDeleteScope = "locks:delete"
+	// PlanTriggerScope allows triggering plans.
+	PlanTriggerScope = "plan:trigger"
+	// ApplyTriggerScope allows triggering applies.
+	ApplyTriggerScope = "apply:trigger"
+	// TokensManageScope allows creating, listing and deleting API tokens.
+	TokensManageScope = "tokens:manage"
+	// AuditReadScope allows exporting the audit log.
+	AuditReadScope = "audit:read"
+	// MetricsReadScope allows scraping metrics.
+	MetricsReadScope = "metrics:read"
+	// ReposManageScope allows onboarding and listing onboarded repos.
+	ReposManageScope = "repos:manage"
+	// PlansReadScope allows viewing the full output of truncated plans.
+	PlansReadScope = "plans:read"
+	// WorkersRunScope allows claiming jobs and reporting their results, which
+	// is what remote workers do.
+	WorkersRunScope = "workers:run"
+	// ConfigReloadScope allows reloading the server-side repo config.
+	ConfigReloadScope = "config:reload"
+	// ConfigReadScope allows viewing the server-side repo config that
+	// applies to repos.
+	ConfigReadScope = "config:read"
+	// FeatureFlagsManageScope allows listing, setting and deleting feature
+	// flags.
+	FeatureFlagsManageScope = "flags:manage"
+	// LogLevelManageScope allows viewing and changing the server's log
+	// levels.
+	LogLevelManageScope = "loglevel:manage"
+	// PullsReadScope allows viewing the open pull requests and the status of
+	// their projects.
+	PullsReadScope = "pulls:read"
+	// CommandsCancelScope allows cancelling the commands running on pull
+	// requests.
+	CommandsCancelScope = "commands:cancel"
+	// EventsManageScope allows viewing and replaying the webhook requests
+	// that failed to be handled. They include the webhooks' payloads.
+	EventsManageScope = "events:manage"
+)
+
+// ValidAPIScopes are all the scopes that can be granted to API tokens.
+var ValidAPIScopes = []string{LocksReadScope, LocksDeleteScope, PlanTriggerScope, ApplyTriggerScope, TokensManageScope, AuditReadScope, MetricsReadScope, ReposManageScope, PlansReadScope, WorkersRunScope, ConfigReloadScope, ConfigReadScope, FeatureFlagsManageScope, LogLevelManageScope, PullsReadScope, CommandsCancelScope, EventsManageScope}
+
+// tokenRequiredScopes are the scopes whose routes are rejected instead of
+// allowed if no API tokens exist and web UI authentication is disabled, since
+// they change how Atlantis behaves, ex. which repos it accepts webhooks from.
+// Deleting locks and cancelling commands are still allowed because the web UI
+// has always let anyone do them.
+var tokenRequiredScopes = map[string]bool{
+	PlanTriggerScope:        true,
+	ApplyTriggerScope:       true,
+	TokensManageScope:       true,
+	ReposManageScope:        true,
+	ConfigReloadScope:       true,
+	FeatureFlagsManageScope: true,
+	LogLevelManageScope:     true,
+	EventsManageScope:       true,
+}
+
+// APIAuthenticator enforces that requests to the API have a token with the
+// right scope. Tokens come from the --api-tokens flag or are created via the
+// API and stored in the DB.
+type APIAuthenticator struct {
+	// StaticTokens are the tokens from the --api-tokens flag.
+	StaticTokens []models.APIToken
+	DB           *db.BoltDB
+	// WebAuthenticator, if set, is used to authenticate requests that don't
+	// have an API token, ex. from users of the web UI.
+	WebAuthenticator WebAuthenticator
+	Logger           *logging.SimpleLogger
+}
+
+// staticAPITokenConfig is the format of each token in the --api-tokens flag.
+type staticAPITokenConfig struct {
+	Name   string   `json:"name"`
+	Token  string   `json:"token"`
+	Scopes []string `json:"scopes"`
+}
+
+// ParseAPITokens parses the JSON value of the --api-tokens flag, ex.
+// [{"name": "ci", "token": "secret", "scopes": ["locks:read"]}].
+func ParseAPITokens(tokensJSON string) ([]models.APIToken, error) {
+	if tokensJSON == "" {
+		return nil, nil
+	}
+	var configs []staticAPITokenConfig
+	if err := json.Unmarshal([]byte(tokensJSON), &configs); err != nil {
+		return nil, errors.Wrap(err, "parsing API tokens")
+	}
+	var tokens []models.APIToken
+	names := make(map[string]bool)
+	for i, c := range configs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("API token %d: name is required", i)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("API token %q: name must be unique", c.Name)
+		}
+		names[c.Name] = true
+		if c.Token == "" {
+			return nil, fmt.Errorf("API token %q: token is required", c.Name)
+		}
+		if err := ValidateAPIScopes(c.Scopes); err != nil {
+			return nil, errors.Wrapf(err, "API token %q", c.Name)
+		}
+		tokens = append(tokens, models.APIToken{
+			Name:   c.Name,
+			Hash:   HashAPIToken(c.Token),
+			Scopes: c.Scopes,
+		})
+	}
+	return tokens, nil
+}
+
+// ValidateAPIScopes returns an error if any of scopes isn't valid.
+func ValidateAPIScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		valid := false
+		for _, v := range ValidAPIScopes {
+			if s == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid scope %q, must be one of %s", s, strings.Join(ValidAPIScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// HashAPIToken returns the hash of token that we store.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Wrap returns a handler that calls next only if the request is allowed to
+// use scope. Requests with an API token in the Authorization header must have
+// a token granted scope. Other requests are passed to the web authenticator.
+// If there's no web authenticator and no API tokens exist then requests are
+// allowed so that Atlantis works as it did before tokens were introduced,
+// except for the tokenRequiredScopes. Otherwise anyone who can reach Atlantis
+// could, ex. create a token for themselves or onboard repos.
+func (a *APIAuthenticator) Wrap(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := a.tokens()
+		if err != nil {
+			a.Logger.Err("getting API tokens: %s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if raw := bearerToken(r); raw != "" {
+			token := findAPIToken(tokens, raw)
+			if token == nil {
+				http.Error(w, "Invalid API token", http.StatusUnauthorized)
+				return
+			}
+			if !token.HasScope(scope) {
+				a.Logger.Warn("rejecting request to %s from API token %q because it doesn't have scope %q", r.URL.Path, token.Name, scope)
+				http.Error(w, fmt.Sprintf("API token %q does not have scope %q", token.Name, scope), http.StatusForbidden)
+				return
+			}
+			next(w, withRequestUser(r, "api-token:"+token.Name))
+			return
+		}
+
+		if a.WebAuthenticator != nil {
+			a.WebAuthenticator.Wrap(next)(w, r)
+			return
+		}
+		if len(tokens) > 0 {
+			http.Error(w, "An API token is required", http.StatusUnauthorized)
+			return
+		}
+		if tokenRequiredScopes[scope] {
+			a.Logger.Warn("rejecting request to %s because scope %q can't be used without --api-tokens or web UI authentication", r.URL.Path, scope)
+			http.Error(w, fmt.Sprintf("Scope %q can only be used once --api-tokens or web UI authentication is configured", scope), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireToken returns a handler that calls next only if the request has an
+// API token granted scope. Unlike Wrap, requests without a token are never
+// passed to the web authenticator or allowed, ex. for remote workers, which
+// are sent credentials.
+func (a *APIAuthenticator) RequireToken(scope string, next http.HandlerFunc) http.HandlerFunc {
+	wrapped := a.Wrap(scope, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if bearerToken(r) == "" {
+			http.Error(w, "An API token is required", http.StatusUnauthorized)
+			return
+		}
+		wrapped(w, r)
+	}
+}
+
+// tokens returns all the static and stored tokens.
+func (a *APIAuthenticator) tokens() ([]models.APIToken, error) {
+	tokens := a.StaticTokens
+	if a.DB != nil {
+		stored, err := a.DB.ListAPITokens()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(append([]models.APIToken{}, tokens...), stored...)
+	}
+	return tokens, nil
+}
+
+// findAPIToken returns the token in tokens that matches raw or nil if
+// none match.
+func findAPIToken(tokens []models.APIToken, raw string) *models.APIToken {
+	hash := []byte(HashAPIToken(raw))
+	for i := range tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(tokens[i].Hash)) == 1 {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+// bearerToken returns the token from the request's Authorization header or
+// an empty string if there isn't one.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
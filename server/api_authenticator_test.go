@@ -0,0 +1,280 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseAPITokens(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		exp         []models.APIToken
+		expErr      string
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			"",
+		},
+		{
+			"valid",
+			`[{"name": "ci", "token": "secret", "scopes": ["locks:read", "locks:delete"]}]`,
+			[]models.APIToken{
+				{
+					Name:   "ci",
+					Hash:   server.HashAPIToken("secret"),
+					Scopes: []string{"locks:read", "locks:delete"},
+				},
+			},
+			"",
+		},
+		{
+			"invalid json",
+			`{`,
+			nil,
+			"parsing API tokens: unexpected end of JSON input",
+		},
+		{
+			"missing name",
+			`[{"token": "secret", "scopes": ["locks:read"]}]`,
+			nil,
+			"API token 0: name is required",
+		},
+		{
+			"duplicate name",
+			`[{"name": "ci", "token": "a", "scopes": ["locks:read"]}, {"name": "ci", "token": "b", "scopes": ["locks:read"]}]`,
+			nil,
+			`API token "ci": name must be unique`,
+		},
+		{
+			"missing token",
+			`[{"name": "ci", "scopes": ["locks:read"]}]`,
+			nil,
+			`API token "ci": token is required`,
+		},
+		{
+			"no scopes",
+			`[{"name": "ci", "token": "secret"}]`,
+			nil,
+			`API token "ci": at least one scope is required`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tokens, err := server.ParseAPITokens(c.input)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, tokens)
+		})
+	}
+}
+
+func TestAPIAuthenticator_Wrap(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	_, err = boltDB.CreateAPIToken(models.APIToken{
+		Name:   "stored",
+		Hash:   server.HashAPIToken("stored-secret"),
+		Scopes: []string{server.LocksDeleteScope},
+	})
+	Ok(t, err)
+
+	a := &server.APIAuthenticator{
+		StaticTokens: []models.APIToken{
+			{
+				Name:   "static",
+				Hash:   server.HashAPIToken("static-secret"),
+				Scopes: []string{server.LocksReadScope},
+			},
+		},
+		DB:     boltDB,
+		Logger: logging.NewNoopLogger(),
+	}
+
+	cases := []struct {
+		description string
+		authHeader  string
+		scope       string
+		expCode     int
+	}{
+		{"no token", "", server.LocksReadScope, http.StatusUnauthorized},
+		{"invalid token", "Bearer wrong", server.LocksReadScope, http.StatusUnauthorized},
+		{"static token with scope", "Bearer static-secret", server.LocksReadScope, http.StatusOK},
+		{"static token without scope", "Bearer static-secret", server.LocksDeleteScope, http.StatusForbidden},
+		{"stored token with scope", "bearer stored-secret", server.LocksDeleteScope, http.StatusOK},
+		{"stored token without scope", "Bearer stored-secret", server.TokensManageScope, http.StatusForbidden},
+		{"basic auth header", "Basic dXNlcjpwYXNz", server.LocksReadScope, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/locks", nil)
+			if c.authHeader != "" {
+				req.Header.Set("Authorization", c.authHeader)
+			}
+			w := httptest.NewRecorder()
+			a.Wrap(c.scope, okHandler)(w, req)
+			Equals(t, c.expCode, w.Code)
+		})
+	}
+}
+
+func TestAPIAuthenticator_WebAuthFallback(t *testing.T) {
+	a := &server.APIAuthenticator{
+		StaticTokens: []models.APIToken{
+			{
+				Name:   "static",
+				Hash:   server.HashAPIToken("static-secret"),
+				Scopes: []string{server.LocksReadScope},
+			},
+		},
+		WebAuthenticator: &server.BasicWebAuthenticator{Username: "user", Password: "pass"},
+		Logger:           logging.NewNoopLogger(),
+	}
+
+	// Requests without a token are authenticated by the web authenticator.
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("user", "pass")
+	w := httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+
+	// Tokens still work.
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer static-secret")
+	w = httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+}
+
+func TestAPIAuthenticator_NoTokens(t *testing.T) {
+	// If no tokens are configured then we don't require them so existing
+	// installations keep working.
+	a := &server.APIAuthenticator{Logger: logging.NewNoopLogger()}
+	req, _ := http.NewRequest("DELETE", "/locks?id=abc", nil)
+	w := httptest.NewRecorder()
+	a.Wrap(server.LocksDeleteScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	// Routes that change how Atlantis behaves are rejected though, otherwise
+	// anyone could, ex. create a token or onboard a repo.
+	for _, c := range []struct {
+		method string
+		path   string
+		scope  string
+	}{
+		{"POST", "/api/tokens", server.TokensManageScope},
+		{"POST", "/api/repos", server.ReposManageScope},
+		{"DELETE", "/api/repos/owner/repo", server.ReposManageScope},
+		{"POST", "/api/reload-config", server.ConfigReloadScope},
+		{"PUT", "/api/feature-flags/flag", server.FeatureFlagsManageScope},
+		{"PUT", "/api/loglevel", server.LogLevelManageScope},
+		{"POST", "/api/events/1/replay", server.EventsManageScope},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req, _ := http.NewRequest(c.method, c.path, nil)
+			w := httptest.NewRecorder()
+			a.Wrap(c.scope, okHandler)(w, req)
+			Equals(t, http.StatusForbidden, w.Code)
+			Equals(t, fmt.Sprintf("Scope %q can only be used once --api-tokens or web UI authentication is configured\n", c.scope), w.Body.String())
+		})
+	}
+}
+
+func TestAPIAuthenticator_StoredTokensOnly(t *testing.T) {
+	// Tokens created through the API require a token even if --api-tokens
+	// isn't set.
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	_, err = boltDB.CreateAPIToken(models.APIToken{
+		Name:   "stored",
+		Hash:   server.HashAPIToken("stored-secret"),
+		Scopes: []string{server.LocksReadScope},
+	})
+	Ok(t, err)
+	a := &server.APIAuthenticator{DB: boltDB, Logger: logging.NewNoopLogger()}
+
+	req, _ := http.NewRequest("GET", "/api/locks", nil)
+	w := httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/locks", nil)
+	req.Header.Set("Authorization", "Bearer stored-secret")
+	w = httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/locks", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/tokens", nil)
+	req.Header.Set("Authorization", "Bearer stored-secret")
+	w = httptest.NewRecorder()
+	a.Wrap(server.TokensManageScope, okHandler)(w, req)
+	Equals(t, http.StatusForbidden, w.Code)
+}
+
+func TestAPIAuthenticator_RequireToken(t *testing.T) {
+	a := &server.APIAuthenticator{
+		StaticTokens: []models.APIToken{
+			{
+				Name:   "worker",
+				Hash:   server.HashAPIToken("worker-secret"),
+				Scopes: []string{server.WorkersRunScope},
+			},
+		},
+		WebAuthenticator: &server.BasicWebAuthenticator{Username: "user", Password: "pass"},
+		Logger:           logging.NewNoopLogger(),
+	}
+
+	// Logging in to the UI isn't enough.
+	req, _ := http.NewRequest("POST", "/api/workers/jobs/claim", nil)
+	req.SetBasicAuth("user", "pass")
+	w := httptest.NewRecorder()
+	a.RequireToken(server.WorkersRunScope, okHandler)(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/workers/jobs/claim", nil)
+	req.Header.Set("Authorization", "Bearer worker-secret")
+	w = httptest.NewRecorder()
+	a.RequireToken(server.WorkersRunScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/locks", nil)
+	req.Header.Set("Authorization", "Bearer worker-secret")
+	w = httptest.NewRecorder()
+	a.RequireToken(server.LocksDeleteScope, okHandler)(w, req)
+	Equals(t, http.StatusForbidden, w.Code)
+
+	// Without any auth configured, a token is still required.
+	a = &server.APIAuthenticator{Logger: logging.NewNoopLogger()}
+	req, _ = http.NewRequest("POST", "/api/workers/jobs/claim", nil)
+	w = httptest.NewRecorder()
+	a.RequireToken(server.WorkersRunScope, okHandler)(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+}
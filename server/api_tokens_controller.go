@@ -0,0 +1,142 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// apiTokenPrefix is prepended to generated tokens so they're easy to spot,
+// ex. by secret scanners.
+const apiTokenPrefix = "atl_"
+
+// APITokensController handles requests to manage API tokens.
+type APITokensController struct {
+	// StaticTokens are the tokens from the --api-tokens flag. They can't be
+	// created or deleted via the API.
+	StaticTokens []models.APIToken
+	DB           *db.BoltDB
+	Logger       *logging.SimpleLogger
+}
+
+// APITokenData is the API representation of a token. The token itself is
+// only returned when it's created.
+type APITokenData struct {
+	Name      string     `json:"name"`
+	Token     string     `json:"token,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// Static is true if the token was configured via the --api-tokens flag.
+	Static bool `json:"static"`
+}
+
+// ListTokens is the GET /api/tokens route.
+func (a *APITokensController) ListTokens(w http.ResponseWriter, _ *http.Request) {
+	stored, err := a.DB.ListAPITokens()
+	if err != nil {
+		respond(w, a.Logger, logging.Error, http.StatusInternalServerError, "Failed listing API tokens: %s", err)
+		return
+	}
+	tokens := []APITokenData{}
+	for _, t := range a.StaticTokens {
+		tokens = append(tokens, APITokenData{Name: t.Name, Scopes: t.Scopes, Static: true})
+	}
+	for _, t := range stored {
+		createdAt := t.CreatedAt
+		tokens = append(tokens, APITokenData{Name: t.Name, Scopes: t.Scopes, CreatedAt: &createdAt})
+	}
+	a.respondJSON(w, http.StatusOK, tokens)
+}
+
+// CreateToken is the POST /api/tokens route. It generates a new token with the
+// requested name and scopes.
+func (a *APITokensController) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+		return
+	}
+	if req.Name == "" {
+		respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "name is required")
+		return
+	}
+	if err := ValidateAPIScopes(req.Scopes); err != nil {
+		respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "Invalid scopes: %s", err)
+		return
+	}
+	for _, t := range a.StaticTokens {
+		if t.Name == req.Name {
+			respond(w, a.Logger, logging.Warn, http.StatusConflict, "API token %q already exists", req.Name)
+			return
+		}
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		respond(w, a.Logger, logging.Error, http.StatusInternalServerError, "Failed generating API token: %s", err)
+		return
+	}
+	raw := apiTokenPrefix + hex.EncodeToString(tokenBytes)
+	token := models.APIToken{
+		Name:      req.Name,
+		Hash:      HashAPIToken(raw),
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	created, err := a.DB.CreateAPIToken(token)
+	if err != nil {
+		respond(w, a.Logger, logging.Error, http.StatusInternalServerError, "Failed creating API token: %s", err)
+		return
+	}
+	if !created {
+		respond(w, a.Logger, logging.Warn, http.StatusConflict, "API token %q already exists", req.Name)
+		return
+	}
+	a.Logger.Info("created API token %q with scopes %v", token.Name, token.Scopes)
+	a.respondJSON(w, http.StatusCreated, APITokenData{
+		Name:      token.Name,
+		Token:     raw,
+		Scopes:    token.Scopes,
+		CreatedAt: &token.CreatedAt,
+	})
+}
+
+// DeleteToken is the DELETE /api/tokens/{name} route.
+func (a *APITokensController) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	for _, t := range a.StaticTokens {
+		if t.Name == name {
+			respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "API token %q was configured via flag and can't be deleted via the API", name)
+			return
+		}
+	}
+	deleted, err := a.DB.DeleteAPIToken(name)
+	if err != nil {
+		respond(w, a.Logger, logging.Error, http.StatusInternalServerError, "Failed deleting API token: %s", err)
+		return
+	}
+	if !deleted {
+		respond(w, a.Logger, logging.Info, http.StatusNotFound, "No API token named %q", name)
+		return
+	}
+	respond(w, a.Logger, logging.Info, http.StatusOK, "Deleted API token %q", name)
+}
+
+// respondJSON writes data as JSON.
+func (a *APITokensController) respondJSON(w http.ResponseWriter, responseCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(responseCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		a.Logger.Err("writing response: %s", err)
+	}
+}
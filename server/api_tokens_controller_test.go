@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newAPITokensController(t *testing.T) (*server.APITokensController, func()) {
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	return &server.APITokensController{
+		StaticTokens: []models.APIToken{
+			{Name: "admin", Hash: server.HashAPIToken("admin-secret"), Scopes: []string{server.TokensManageScope}},
+		},
+		DB:     boltDB,
+		Logger: logging.NewNoopLogger(),
+	}, cleanup
+}
+
+func TestAPITokensController_CreateListDelete(t *testing.T) {
+	c, cleanup := newAPITokensController(t)
+	defer cleanup()
+
+	// Create.
+	req, _ := http.NewRequest("POST", "/api/tokens", bytes.NewBufferString(`{"name": "ci", "scopes": ["locks:read"]}`))
+	w := httptest.NewRecorder()
+	c.CreateToken(w, req)
+	Equals(t, http.StatusCreated, w.Code)
+	var created server.APITokenData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &created))
+	Equals(t, "ci", created.Name)
+	Equals(t, []string{"locks:read"}, created.Scopes)
+	Assert(t, strings.HasPrefix(created.Token, "atl_"), "expected token to have atl_ prefix, got %q", created.Token)
+
+	// The generated token should authenticate.
+	a := &server.APIAuthenticator{DB: c.DB, Logger: logging.NewNoopLogger()}
+	req, _ = http.NewRequest("GET", "/api/locks", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+	w = httptest.NewRecorder()
+	a.Wrap(server.LocksReadScope, okHandler)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	// Creating it again should conflict.
+	req, _ = http.NewRequest("POST", "/api/tokens", bytes.NewBufferString(`{"name": "ci", "scopes": ["locks:read"]}`))
+	w = httptest.NewRecorder()
+	c.CreateToken(w, req)
+	Equals(t, http.StatusConflict, w.Code)
+
+	// List doesn't include the tokens themselves.
+	req, _ = http.NewRequest("GET", "/api/tokens", nil)
+	w = httptest.NewRecorder()
+	c.ListTokens(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var tokens []server.APITokenData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	Equals(t, 2, len(tokens))
+	Equals(t, "admin", tokens[0].Name)
+	Equals(t, true, tokens[0].Static)
+	Equals(t, "ci", tokens[1].Name)
+	Equals(t, "", tokens[1].Token)
+
+	// Delete.
+	req, _ = http.NewRequest("DELETE", "/api/tokens/ci", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "ci"})
+	w = httptest.NewRecorder()
+	c.DeleteToken(w, req)
+	responseContains(t, w, http.StatusOK, `Deleted API token "ci"`)
+
+	req, _ = http.NewRequest("DELETE", "/api/tokens/ci", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "ci"})
+	w = httptest.NewRecorder()
+	c.DeleteToken(w, req)
+	responseContains(t, w, http.StatusNotFound, `No API token named "ci"`)
+}
+
+func TestAPITokensController_CreateInvalid(t *testing.T) {
+	c, cleanup := newAPITokensController(t)
+	defer cleanup()
+
+	cases := []struct {
+		description string
+		body        string
+		expCode     int
+		expBody     string
+	}{
+		{"invalid json", `{`, http.StatusBadRequest, "Invalid request body"},
+		{"no name", `{"scopes": ["locks:read"]}`, http.StatusBadRequest, "name is required"},
+		{"invalid scope", `{"name": "ci", "scopes": ["locks:write"]}`, http.StatusBadRequest, `invalid scope "locks:write"`},
+		{"static name", `{"name": "admin", "scopes": ["locks:read"]}`, http.StatusConflict, `API token "admin" already exists`},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/tokens", bytes.NewBufferString(testCase.body))
+			w := httptest.NewRecorder()
+			c.CreateToken(w, req)
+			responseContains(t, w, testCase.expCode, testCase.expBody)
+		})
+	}
+}
+
+func TestAPITokensController_DeleteStatic(t *testing.T) {
+	c, cleanup := newAPITokensController(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest("DELETE", "/api/tokens/admin", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "admin"})
+	w := httptest.NewRecorder()
+	c.DeleteToken(w, req)
+	responseContains(t, w, http.StatusBadRequest, "can't be deleted via the API")
+}
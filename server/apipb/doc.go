@@ -0,0 +1,5 @@
+// Package apipb contains the protobuf messages and the gRPC service of the
+// Atlantis gRPC API, generated from atlantis.proto.
+package apipb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. atlantis.proto
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// auditCSVHeader is the header row of CSV audit log exports. Columns are in
+// the same order as csvRecord.
+var auditCSVHeader = []string{"id", "time", "action", "user", "repo", "pull_num", "project", "dir", "workspace", "result", "error", "duration_ms", "project_id"}
+
+// AuditController handles requests to export the audit log.
+type AuditController struct {
+	DB     *db.BoltDB
+	Logger *logging.SimpleLogger
+}
+
+// ExportAuditLog is the GET /api/audit route. It returns the audit log as JSON
+// or, if the format query param is "csv", as CSV. The since query param, an
+// RFC 3339 timestamp, limits the export to events at or after that time.
+func (a *AuditController) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "Invalid since %q, must be an RFC 3339 timestamp, ex. 2020-01-02T15:04:05Z", sinceStr)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respond(w, a.Logger, logging.Warn, http.StatusBadRequest, "Invalid format %q, must be json or csv", format)
+		return
+	}
+
+	auditEvents, err := a.DB.ListAuditEvents(since)
+	if err != nil {
+		respond(w, a.Logger, logging.Error, http.StatusInternalServerError, "Failed listing audit events: %s", err)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="atlantis-audit.csv"`)
+		w.WriteHeader(http.StatusOK)
+		writer := csv.NewWriter(w)
+		records := [][]string{auditCSVHeader}
+		for _, e := range auditEvents {
+			records = append(records, csvRecord(e))
+		}
+		if err := writer.WriteAll(records); err != nil {
+			a.Logger.Err("writing response: %s", err)
+		}
+		return
+	}
+
+	if auditEvents == nil {
+		auditEvents = []models.AuditEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(auditEvents); err != nil {
+		a.Logger.Err("writing response: %s", err)
+	}
+}
+
+func csvRecord(e models.AuditEvent) []string {
+	return []string{
+		strconv.FormatUint(e.ID, 10),
+		e.Time.Format(time.RFC3339),
+		string(e.Action),
+		e.User,
+		e.RepoFullName,
+		strconv.Itoa(e.PullNum),
+		e.ProjectName,
+		e.RepoRelDir,
+		e.Workspace,
+		string(e.Result),
+		e.Error,
+		strconv.FormatInt(e.DurationMS, 10),
+		e.ProjectID,
+	}
+}
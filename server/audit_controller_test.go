@@ -0,0 +1,109 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newAuditController(t *testing.T) (*server.AuditController, func()) {
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err = boltDB.AppendAuditEvent(models.AuditEvent{
+		Time:         start,
+		Action:       models.PlanAuditAction,
+		User:         "lkysow",
+		RepoFullName: "owner/repo",
+		PullNum:      1,
+		RepoRelDir:   ".",
+		Workspace:    "default",
+		Result:       models.SuccessAuditResult,
+		DurationMS:   1200,
+	})
+	Ok(t, err)
+	_, err = boltDB.AppendAuditEvent(models.AuditEvent{
+		Time:         start.Add(time.Hour),
+		Action:       models.ApplyAuditAction,
+		User:         "lkysow",
+		RepoFullName: "owner/repo",
+		PullNum:      1,
+		ProjectName:  "proj",
+		RepoRelDir:   "dir",
+		Workspace:    "default",
+		Result:       models.ErrorAuditResult,
+		Error:        "exit status 1",
+		DurationMS:   3400,
+	})
+	Ok(t, err)
+	return &server.AuditController{DB: boltDB, Logger: logging.NewNoopLogger()}, cleanup
+}
+
+func TestExportAuditLog_JSON(t *testing.T) {
+	a, cleanup := newAuditController(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/audit", nil)
+	w := httptest.NewRecorder()
+	a.ExportAuditLog(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "application/json", w.Header().Get("Content-Type"))
+	var auditEvents []models.AuditEvent
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &auditEvents))
+	Equals(t, 2, len(auditEvents))
+	Equals(t, models.PlanAuditAction, auditEvents[0].Action)
+	Equals(t, models.ApplyAuditAction, auditEvents[1].Action)
+}
+
+func TestExportAuditLog_Since(t *testing.T) {
+	a, cleanup := newAuditController(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/audit?since=2020-01-02T04:00:00Z", nil)
+	w := httptest.NewRecorder()
+	a.ExportAuditLog(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var auditEvents []models.AuditEvent
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &auditEvents))
+	Equals(t, 1, len(auditEvents))
+	Equals(t, uint64(2), auditEvents[0].ID)
+}
+
+func TestExportAuditLog_CSV(t *testing.T) {
+	a, cleanup := newAuditController(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/audit?format=csv", nil)
+	w := httptest.NewRecorder()
+	a.ExportAuditLog(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "text/csv", w.Header().Get("Content-Type"))
+	Equals(t, `id,time,action,user,repo,pull_num,project,dir,workspace,result,error,duration_ms,project_id
+1,2020-01-02T03:04:05Z,plan,lkysow,owner/repo,1,,.,default,success,,1200,
+2,2020-01-02T04:04:05Z,apply,lkysow,owner/repo,1,proj,dir,default,error,exit status 1,3400,
+`, w.Body.String())
+}
+
+func TestExportAuditLog_InvalidParams(t *testing.T) {
+	a, cleanup := newAuditController(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/audit?format=xml", nil)
+	w := httptest.NewRecorder()
+	a.ExportAuditLog(w, req)
+	responseContains(t, w, http.StatusBadRequest, `Invalid format "xml", must be json or csv`)
+
+	req, _ = http.NewRequest("GET", "/api/audit?since=yesterday", nil)
+	w = httptest.NewRecorder()
+	a.ExportAuditLog(w, req)
+	responseContains(t, w, http.StatusBadRequest, `Invalid since "yesterday"`)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// CommandsController cancels the commands running on pull requests from the
+// API and the UI.
+type CommandsController struct {
+	RunningCommands *events.RunningCommands
+	DB              db.PullStatusStore
+	Locker          locking.Locker
+	VCSClient       vcs.Client
+	Logger          *logging.SimpleLogger
+	// GlobalCfgStore, if set, holds the server-side repo config. Users who
+	// belong to its tenants can only cancel the commands of their tenants'
+	// repos.
+	GlobalCfgStore *events.GlobalCfgStore
+}
+
+// CancelCommandsResponse is the response of the DELETE /api/commands route.
+type CancelCommandsResponse struct {
+	Cancelled []events.RunningCommand `json:"cancelled"`
+}
+
+// CancelCommands is the DELETE /api/commands route. It cancels the commands
+// running on the pull request in the repo and pull query params, ex.
+// repo=owner/repo&pull=1, like the cancel comment command. The dir, workspace
+// and project query params select the projects like the -d, -w and -p flags.
+// It comments on the pull request with the cancelled commands and returns
+// them as JSON.
+func (c *CommandsController) CancelCommands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	repo := query.Get("repo")
+	if repo == "" {
+		respond(w, c.Logger, logging.Warn, http.StatusBadRequest, "repo query param must be set, ex. owner/repo")
+		return
+	}
+	pullNum, err := strconv.Atoi(query.Get("pull"))
+	if err != nil || pullNum <= 0 {
+		respond(w, c.Logger, logging.Warn, http.StatusBadRequest, "pull query param must be a pull request number, got %q", query.Get("pull"))
+		return
+	}
+	sel := events.DiscardSelector{
+		RepoRelDir:  query.Get("dir"),
+		Workspace:   query.Get("workspace"),
+		ProjectName: query.Get("project"),
+	}
+	if strings.HasPrefix(sel.RepoRelDir, "/") || strings.Contains(sel.RepoRelDir, "..") {
+		respond(w, c.Logger, logging.Warn, http.StatusBadRequest, "invalid dir %q, must be relative to the repo root and can't contain ..", sel.RepoRelDir)
+		return
+	}
+
+	if visible := repoScope(c.GlobalCfgStore, r); visible != nil {
+		for _, rc := range c.RunningCommands.List() {
+			if rc.Pull == pullNum && strings.EqualFold(rc.Repo, repo) && !visible(rc.RepoID()) {
+				respond(w, c.Logger, logging.Info, http.StatusNotFound, "No pull request %s#%d found", repo, pullNum)
+				return
+			}
+		}
+	}
+
+	user := RequestUser(r)
+	cancelled := c.RunningCommands.Cancel(repo, pullNum, sel, user)
+	for _, rc := range cancelled {
+		c.Logger.Info("cancelled %s on %s#%d started by %s", rc.Command, rc.Repo, rc.Pull, rc.User)
+	}
+	if len(cancelled) > 0 {
+		// The first plan of a pull request can be cancelled before Atlantis
+		// has stored the pull request, in which case there's nowhere to
+		// comment. The cancelled commands still comment their results.
+		pull, ok, err := findKnownPull(c.DB, c.Locker, repo, pullNum)
+		if err != nil {
+			c.Logger.Err("finding pull request to comment on: %s", err)
+		} else if ok {
+			if err := c.VCSClient.CreateComment(pull.BaseRepo, pull.Num, events.CancelComment(cancelled, user, "the Atlantis API")); err != nil {
+				c.Logger.Err("unable to comment on pull request: %s", err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if cancelled == nil {
+		cancelled = []events.RunningCommand{}
+	}
+	if err := json.NewEncoder(w).Encode(CancelCommandsResponse{Cancelled: cancelled}); err != nil {
+		c.Logger.Err("writing response: %s", err)
+	}
+}
@@ -0,0 +1,80 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCancelCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	pull := models.PullRequest{Num: 1, BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, ProjectName: "app", RepoRelDir: "app", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+
+	running := &events.RunningCommands{}
+	ctx := &events.CommandContext{BaseRepo: repo, Pull: pull, User: models.User{Username: "user"}}
+	defer running.Start(ctx, "apply")()
+	running.SetProject(ctx, models.ProjectCommandContext{ProjectName: "app", RepoRelDir: "app", Workspace: "default"})
+	vcsClient := vcsmocks.NewMockClient()
+	cc := server.CommandsController{
+		RunningCommands: running,
+		DB:              store,
+		Locker:          lockmocks.NewMockLocker(),
+		VCSClient:       vcsClient,
+		Logger:          logging.NewNoopLogger(),
+	}
+
+	for query, expCode := range map[string]int{
+		"":                                http.StatusBadRequest,
+		"?repo=owner/repo&pull=abc":       http.StatusBadRequest,
+		"?repo=owner/repo&pull=1&dir=../": http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest("DELETE", "/api/commands"+query, nil)
+		w := httptest.NewRecorder()
+		cc.CancelCommands(w, req)
+		Equals(t, expCode, w.Code)
+	}
+
+	// Nothing runs on the db project.
+	req, _ := http.NewRequest("DELETE", "/api/commands?repo=owner/repo&pull=1&project=db", nil)
+	w := httptest.NewRecorder()
+	cc.CancelCommands(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "{\"cancelled\":[]}\n", w.Body.String())
+
+	req, _ = http.NewRequest("DELETE", "/api/commands?repo=OWNER/repo&pull=1&project=app", nil)
+	w = httptest.NewRecorder()
+	cc.CancelCommands(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var resp server.CancelCommandsResponse
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	Equals(t, 1, len(resp.Cancelled))
+	Equals(t, "apply", resp.Cancelled[0].Command)
+	Equals(t, "project: app", resp.Cancelled[0].Project)
+	vcsClient.VerifyWasCalledOnce().CreateComment(repo, 1, events.CancelComment(resp.Cancelled, "", "the Atlantis API"))
+	select {
+	case <-ctx.Done:
+	default:
+		t.Fatal("exp command to be cancelled")
+	}
+}
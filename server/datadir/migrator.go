@@ -0,0 +1,238 @@
+// Package datadir versions the layout of Atlantis' data dir and migrates it
+// between releases.
+package datadir
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// VersionFilename is the name of the file in the data dir that stores
+	// the layout version.
+	VersionFilename = "layout-version"
+	// BackupsDirName is the name of the dir in the data dir where backups are
+	// stored before migrating.
+	BackupsDirName = "backups"
+	// dbFilename is the name of the BoltDB file. It must match the name used
+	// by the db package.
+	dbFilename = "atlantis.db"
+	// reposDirName is the dir that repos are cloned into and where plans are
+	// stored. It must match the name used by events.FileWorkspace.
+	reposDirName = "repos"
+	// planfileExt is the extension of generated planfiles.
+	planfileExt = ".tfplan"
+)
+
+// Migration migrates the data dir from the previous layout version to Version.
+type Migration struct {
+	// Version is the layout version after this migration is run.
+	Version int
+	// Description is logged when the migration runs.
+	Description string
+	// Migrate does the migration. It's called with the absolute path to the
+	// data dir.
+	Migrate func(dataDir string) error
+}
+
+// Migrations are all the migrations in the order they must be run. When a
+// release changes how data is stored, ex. the BoltDB bucket format or where
+// plans are saved, it must append a migration here.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "record layout version of data dirs created before versioning",
+		// Layout version 1 is the layout used before versioning was added so
+		// there's nothing to change.
+		Migrate: func(string) error { return nil },
+	},
+}
+
+// CurrentVersion is the layout version this release of Atlantis uses.
+var CurrentVersion = Migrations[len(Migrations)-1].Version
+
+// Migrator migrates the data dir to the current layout version.
+type Migrator struct {
+	DataDir    string
+	Migrations []Migration
+	Logger     *logging.SimpleLogger
+}
+
+// NewMigrator returns a Migrator that runs the default Migrations.
+func NewMigrator(dataDir string, logger *logging.SimpleLogger) *Migrator {
+	return &Migrator{
+		DataDir:    dataDir,
+		Migrations: Migrations,
+		Logger:     logger,
+	}
+}
+
+// Run migrates the data dir to the latest version in m.Migrations. Before any
+// migrations are run, the BoltDB file and all planfiles are backed up into
+// the backups dir so they can be restored if a migration fails.
+// It returns an error if the data dir was written by a newer version of
+// Atlantis since we don't know how to read it.
+func (m *Migrator) Run() error {
+	if len(m.Migrations) == 0 {
+		return nil
+	}
+	latest := m.Migrations[len(m.Migrations)-1].Version
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == latest {
+		return nil
+	}
+	if version > latest {
+		return fmt.Errorf("data dir %q has layout version %d but this version of Atlantis only supports up to version %d: downgrading isn't supported, restore a backup from %q or use a newer version of Atlantis",
+			m.DataDir, version, latest, filepath.Join(m.DataDir, BackupsDirName))
+	}
+
+	// If this is a new data dir there's nothing to migrate.
+	isNew, err := m.isNew()
+	if err != nil {
+		return err
+	}
+	if isNew {
+		m.Logger.Debug("initializing data dir %q with layout version %d", m.DataDir, latest)
+		return m.writeVersion(latest)
+	}
+
+	backupDir, err := m.backup(version)
+	if err != nil {
+		return errors.Wrap(err, "backing up data dir before migrating")
+	}
+	m.Logger.Info("backed up data dir to %q before migrating from layout version %d to %d", backupDir, version, latest)
+
+	for _, migration := range m.Migrations {
+		if migration.Version <= version {
+			continue
+		}
+		m.Logger.Info("migrating data dir to layout version %d: %s", migration.Version, migration.Description)
+		if err := migration.Migrate(m.DataDir); err != nil {
+			return errors.Wrapf(err, "migrating data dir to layout version %d, a backup of the data dir before migrating is in %q", migration.Version, backupDir)
+		}
+		// Write the version after each migration so that if a later one
+		// fails we don't re-run the earlier ones.
+		if err := m.writeVersion(migration.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version returns the current layout version of the data dir. Data dirs from
+// before versioning was added have version 0.
+func (m *Migrator) Version() (int, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(m.DataDir, VersionFilename)) // nolint: gosec
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "reading data dir layout version")
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing data dir layout version from %q", filepath.Join(m.DataDir, VersionFilename))
+	}
+	return version, nil
+}
+
+// isNew returns true if the data dir doesn't have any data we'd need to
+// migrate.
+func (m *Migrator) isNew() (bool, error) {
+	for _, name := range []string{dbFilename, reposDirName} {
+		_, err := os.Stat(filepath.Join(m.DataDir, name))
+		if err == nil {
+			return false, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, errors.Wrapf(err, "checking for %s in data dir", name)
+		}
+	}
+	return true, nil
+}
+
+func (m *Migrator) writeVersion(version int) error {
+	if err := os.MkdirAll(m.DataDir, 0700); err != nil {
+		return errors.Wrap(err, "creating data dir")
+	}
+	if err := ioutil.WriteFile(filepath.Join(m.DataDir, VersionFilename), []byte(fmt.Sprintf("%d\n", version)), 0600); err != nil {
+		return errors.Wrap(err, "writing data dir layout version")
+	}
+	return nil
+}
+
+// backup copies the BoltDB file and all planfiles into a new dir under the
+// backups dir and returns its path. We don't back up the rest of the repos
+// dir because it can be very large and can be re-cloned.
+func (m *Migrator) backup(version int) (string, error) {
+	backupDir := filepath.Join(m.DataDir, BackupsDirName, fmt.Sprintf("layout-v%d-%s", version, time.Now().UTC().Format("20060102T150405Z")))
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+
+	dbPath := filepath.Join(m.DataDir, dbFilename)
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := copyFile(dbPath, filepath.Join(backupDir, dbFilename)); err != nil {
+			return "", err
+		}
+	}
+
+	reposDir := filepath.Join(m.DataDir, reposDirName)
+	err := filepath.Walk(reposDir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == reposDir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Skip .terraform dirs since they're big and re-created by init.
+			if info.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != planfileExt {
+			return nil
+		}
+		rel, err := filepath.Rel(m.DataDir, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(backupDir, rel))
+	})
+	return backupDir, err
+}
+
+func copyFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	in, err := os.Open(src) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() // nolint: errcheck
+		return errors.Wrapf(err, "copying %q", src)
+	}
+	return out.Close()
+}
@@ -0,0 +1,163 @@
+package datadir_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/datadir"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// recordingMigrations returns migrations up to version that append their
+// version to ran when they're run.
+func recordingMigrations(version int, ran *[]int) []datadir.Migration {
+	var migrations []datadir.Migration
+	for i := 1; i <= version; i++ {
+		v := i
+		migrations = append(migrations, datadir.Migration{
+			Version:     v,
+			Description: "test",
+			Migrate: func(string) error {
+				*ran = append(*ran, v)
+				return nil
+			},
+		})
+	}
+	return migrations
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	Ok(t, os.MkdirAll(filepath.Dir(path), 0700))
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+}
+
+func readFile(t *testing.T, path string) string {
+	contents, err := ioutil.ReadFile(path)
+	Ok(t, err)
+	return string(contents)
+}
+
+// Should record the latest version and not run any migrations on a new data
+// dir.
+func TestRun_NewDataDir(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	dataDir := filepath.Join(tmp, "data")
+
+	var ran []int
+	m := &datadir.Migrator{DataDir: dataDir, Migrations: recordingMigrations(3, &ran), Logger: logging.NewNoopLogger()}
+	Ok(t, m.Run())
+	Equals(t, 0, len(ran))
+	version, err := m.Version()
+	Ok(t, err)
+	Equals(t, 3, version)
+	_, err = os.Stat(filepath.Join(dataDir, datadir.BackupsDirName))
+	Assert(t, os.IsNotExist(err), "expected no backups dir")
+}
+
+// Should run all migrations on a data dir from before versioning and back it
+// up first.
+func TestRun_UnversionedDataDir(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, "atlantis.db"), "db")
+	writeFile(t, filepath.Join(tmp, "repos", "owner", "repo", "1", "default", "dir", "default.tfplan"), "plan")
+	writeFile(t, filepath.Join(tmp, "repos", "owner", "repo", "1", "default", "dir", "main.tf"), "tf")
+	writeFile(t, filepath.Join(tmp, "repos", "owner", "repo", "1", "default", "dir", ".terraform", "x.tfplan"), "ignored")
+
+	var ran []int
+	m := &datadir.Migrator{DataDir: tmp, Migrations: recordingMigrations(2, &ran), Logger: logging.NewNoopLogger()}
+	Ok(t, m.Run())
+	Equals(t, []int{1, 2}, ran)
+	version, err := m.Version()
+	Ok(t, err)
+	Equals(t, 2, version)
+
+	backups, err := ioutil.ReadDir(filepath.Join(tmp, datadir.BackupsDirName))
+	Ok(t, err)
+	Equals(t, 1, len(backups))
+	backupDir := filepath.Join(tmp, datadir.BackupsDirName, backups[0].Name())
+	Equals(t, "db", readFile(t, filepath.Join(backupDir, "atlantis.db")))
+	Equals(t, "plan", readFile(t, filepath.Join(backupDir, "repos", "owner", "repo", "1", "default", "dir", "default.tfplan")))
+	_, err = os.Stat(filepath.Join(backupDir, "repos", "owner", "repo", "1", "default", "dir", "main.tf"))
+	Assert(t, os.IsNotExist(err), "expected non-planfiles to not be backed up")
+	_, err = os.Stat(filepath.Join(backupDir, "repos", "owner", "repo", "1", "default", "dir", ".terraform"))
+	Assert(t, os.IsNotExist(err), "expected .terraform dirs to not be backed up")
+}
+
+// Should only run migrations newer than the current version.
+func TestRun_PartiallyMigrated(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, "atlantis.db"), "db")
+	writeFile(t, filepath.Join(tmp, datadir.VersionFilename), "2\n")
+
+	var ran []int
+	m := &datadir.Migrator{DataDir: tmp, Migrations: recordingMigrations(4, &ran), Logger: logging.NewNoopLogger()}
+	Ok(t, m.Run())
+	Equals(t, []int{3, 4}, ran)
+	Equals(t, "4\n", readFile(t, filepath.Join(tmp, datadir.VersionFilename)))
+}
+
+// Should do nothing if already at the latest version.
+func TestRun_UpToDate(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, "atlantis.db"), "db")
+	writeFile(t, filepath.Join(tmp, datadir.VersionFilename), "2\n")
+
+	var ran []int
+	m := &datadir.Migrator{DataDir: tmp, Migrations: recordingMigrations(2, &ran), Logger: logging.NewNoopLogger()}
+	Ok(t, m.Run())
+	Equals(t, 0, len(ran))
+	_, err := os.Stat(filepath.Join(tmp, datadir.BackupsDirName))
+	Assert(t, os.IsNotExist(err), "expected no backups dir")
+}
+
+// Should error if the data dir is from a newer version of Atlantis.
+func TestRun_NewerVersion(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, datadir.VersionFilename), "3\n")
+
+	var ran []int
+	m := &datadir.Migrator{DataDir: tmp, Migrations: recordingMigrations(2, &ran), Logger: logging.NewNoopLogger()}
+	err := m.Run()
+	ErrContains(t, "has layout version 3 but this version of Atlantis only supports up to version 2", err)
+}
+
+// Should stop at a failing migration and record the last successful version.
+func TestRun_MigrationFails(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, "atlantis.db"), "db")
+
+	var ran []int
+	migrations := recordingMigrations(1, &ran)
+	migrations = append(migrations, datadir.Migration{
+		Version:     2,
+		Description: "fails",
+		Migrate:     func(string) error { return errors.New("boom") },
+	})
+	m := &datadir.Migrator{DataDir: tmp, Migrations: migrations, Logger: logging.NewNoopLogger()}
+	err := m.Run()
+	ErrContains(t, "migrating data dir to layout version 2", err)
+	ErrContains(t, "boom", err)
+	Equals(t, []int{1}, ran)
+	version, err := m.Version()
+	Ok(t, err)
+	Equals(t, 1, version)
+}
+
+func TestRun_InvalidVersionFile(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(tmp, datadir.VersionFilename), "abc")
+
+	m := datadir.NewMigrator(tmp, logging.NewNoopLogger())
+	ErrContains(t, "parsing data dir layout version", m.Run())
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// EffectiveConfigController shows the server-side repo config that applies to
+// a repo so users can debug which settings it gets without reading the
+// server's logs.
+type EffectiveConfigController struct {
+	AtlantisVersion         string
+	AtlantisURL             *url.URL
+	Store                   *events.GlobalCfgStore
+	EffectiveConfigTemplate TemplateWriter
+	Logger                  *logging.SimpleLogger
+}
+
+// GetConfig is the GET /api/repos/{repo}/config route. It returns the
+// effective config of the repo with ID {repo}, ex. github.com/owner/repo, as
+// JSON.
+func (e *EffectiveConfigController) GetConfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["repo"]
+	if _, _, err := splitRepoID(id); err != nil {
+		respond(w, e.Logger, logging.Warn, http.StatusBadRequest, "Invalid repo: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(e.Store.Get().EffectiveCfg(id)); err != nil {
+		e.Logger.Err("writing response: %s", err)
+	}
+}
+
+// GetConfigPage is the GET /repo-config?repo={repo} route. It renders the
+// effective config of the repo. Without a repo it renders a form to pick one.
+func (e *EffectiveConfigController) GetConfigPage(w http.ResponseWriter, r *http.Request) {
+	data := EffectiveConfigData{
+		AtlantisVersion: e.AtlantisVersion,
+		CleanedBasePath: e.AtlantisURL.Path,
+	}
+	if id := r.URL.Query().Get("repo"); id != "" {
+		data.RepoID = id
+		if _, _, err := splitRepoID(id); err != nil {
+			data.Error = err.Error()
+		} else {
+			cfg := e.Store.Get().EffectiveCfg(id)
+			data.Config = &cfg
+		}
+	}
+	if err := e.EffectiveConfigTemplate.Execute(w, data); err != nil {
+		e.Logger.Err(err.Error())
+	}
+}
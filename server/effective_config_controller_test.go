@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newEffectiveConfigController(t *testing.T, tmpl server.TemplateWriter) *server.EffectiveConfigController {
+	atlantisURL, err := url.Parse("https://example.com/basepath")
+	Ok(t, err)
+	return &server.EffectiveConfigController{
+		AtlantisVersion:         "1300135",
+		AtlantisURL:             atlantisURL,
+		Store:                   events.NewGlobalCfgStore(valid.NewGlobalCfg(false, true, false)),
+		EffectiveConfigTemplate: tmpl,
+		Logger:                  logging.NewNoopLogger(),
+	}
+}
+
+func TestEffectiveConfigController_GetConfig(t *testing.T) {
+	c := newEffectiveConfigController(t, nil)
+
+	req, _ := http.NewRequest("GET", "/api/repos/owner/repo/config", nil)
+	req = mux.SetURLVars(req, map[string]string{"repo": "owner/repo"})
+	w := httptest.NewRecorder()
+	c.GetConfig(w, req)
+	responseContains(t, w, http.StatusBadRequest, `Invalid repo: "owner/repo" must be in the format {hostname}/{owner}/{repo}`)
+
+	req = mux.SetURLVars(req, map[string]string{"repo": "github.com/owner/repo"})
+	w = httptest.NewRecorder()
+	c.GetConfig(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "application/json", w.Header().Get("Content-Type"))
+	var cfg struct {
+		Repo              string `json:"repo"`
+		ApplyRequirements struct {
+			Value  []string `json:"value"`
+			Source string   `json:"source"`
+		} `json:"apply_requirements"`
+		Workflows []struct {
+			Name string `json:"name"`
+		} `json:"workflows"`
+	}
+	Ok(t, json.NewDecoder(w.Body).Decode(&cfg))
+	Equals(t, "github.com/owner/repo", cfg.Repo)
+	Equals(t, []string{"mergeable"}, cfg.ApplyRequirements.Value)
+	Equals(t, "server defaults", cfg.ApplyRequirements.Source)
+	Equals(t, 1, len(cfg.Workflows))
+	Equals(t, "default", cfg.Workflows[0].Name)
+}
+
+func TestEffectiveConfigController_GetConfigPage(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	c := newEffectiveConfigController(t, tmpl)
+
+	req, _ := http.NewRequest("GET", "/repo-config", nil)
+	w := httptest.NewRecorder()
+	c.GetConfigPage(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.EffectiveConfigData{
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/basepath",
+	})
+
+	req, _ = http.NewRequest("GET", "/repo-config?repo=owner/repo", nil)
+	w = httptest.NewRecorder()
+	c.GetConfigPage(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.EffectiveConfigData{
+		RepoID:          "owner/repo",
+		Error:           `"owner/repo" must be in the format {hostname}/{owner}/{repo}`,
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/basepath",
+	})
+
+	req, _ = http.NewRequest("GET", "/repo-config?repo=github.com/owner/repo", nil)
+	w = httptest.NewRecorder()
+	c.GetConfigPage(w, req)
+	cfg := valid.NewGlobalCfg(false, true, false).EffectiveCfg("github.com/owner/repo")
+	tmpl.VerifyWasCalledOnce().Execute(w, server.EffectiveConfigData{
+		RepoID:          "github.com/owner/repo",
+		Config:          &cfg,
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/basepath",
+	})
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// AfterMergeApplier applies the projects of pull requests once they're merged
+// into the default branch, for repos that are applied after merge in the
+// server-side repo config or by a merge queue in ApplyAfterMergeMode. The
+// results are commented on the merged pull request and sent to the repo's
+// apply_after_merge notify targets.
+type AfterMergeApplier struct {
+	// GlobalCfgStore holds the server-side repo config that decides which
+	// repos are applied after merge.
+	GlobalCfgStore        *GlobalCfgStore
+	VCSClient             vcs.Client
+	DB                    db.PullStatusStore
+	ProjectCommandBuilder ProjectCommandBuilder
+	ProjectCommandRunner  ProjectCommandRunner
+	MarkdownRenderer      *MarkdownRenderer
+	Locker                locking.Locker
+	WorkingDir            WorkingDir
+	Logger                logging.SimpleLogging
+}
+
+// Enabled returns true if repo is applied after merge in the server-side repo
+// config.
+func (a *AfterMergeApplier) Enabled(repo models.Repo) bool {
+	return a.GlobalCfgStore != nil && a.GlobalCfgStore.Get().ApplyAfterMerge(repo.ID()) != nil
+}
+
+// MergedProjects returns the projects that pull planned so they can be
+// applied once it's merged, or nil if it didn't plan any. It must be called
+// before pull is cleaned up.
+func (a *AfterMergeApplier) MergedProjects(pull models.PullRequest) ([]models.ProjectStatus, error) {
+	ps, err := a.DB.GetPullStatus(pull)
+	if err != nil || ps == nil {
+		return nil, err
+	}
+	return ps.Projects, nil
+}
+
+// ApplyMerged plans and applies projects, which pull planned, on mergeCommit
+// once pull is merged into the default branch of baseRepo and comments the
+// results on pull. Projects whose plans don't change anything aren't applied.
+// Applies are also sent to the repo's apply_after_merge notify targets.
+func (a *AfterMergeApplier) ApplyMerged(baseRepo models.Repo, pull models.PullRequest, mergeCommit string, user models.User, projects []models.ProjectStatus) {
+	log := a.Logger.NewLogger(fmt.Sprintf("%s#%d", baseRepo.FullName, pull.Num), true, a.Logger.GetLevel())
+	if len(projects) == 0 {
+		log.Info("not applying after merge since no projects were planned")
+		return
+	}
+	// The default branch is checked out since the pull request's branch
+	// could be deleted once it's merged.
+	merged := pull
+	merged.HeadBranch = pull.BaseBranch
+	merged.HeadCommit = mergeCommit
+	ctx := &CommandContext{
+		BaseRepo: baseRepo,
+		HeadRepo: baseRepo,
+		Pull:     merged,
+		User:     user,
+		Log:      log,
+	}
+	// The locks the plans take and the clone are deleted afterwards, like
+	// they are when pull requests are closed.
+	defer func() {
+		if _, err := a.Locker.UnlockByPull(baseRepo.FullName, pull.Num); err != nil {
+			log.Err("unable to delete locks after applying merged pull request: %s", err)
+		}
+		if err := a.WorkingDir.Delete(baseRepo, merged); err != nil {
+			log.Err("unable to delete working dir after applying merged pull request: %s", err)
+		}
+	}()
+
+	var planResults, applyResults []models.ProjectResult
+	for _, p := range projects {
+		pCmds, err := a.ProjectCommandBuilder.BuildPlanCommands(ctx, NewCommentCommand(p.RepoRelDir, nil, models.PlanCommand, false, p.Workspace, p.ProjectName))
+		if err != nil {
+			a.comment(log, baseRepo, pull.Num, CommandResult{Error: err}, models.PlanCommand)
+			return
+		}
+		for _, pCmd := range pCmds {
+			res := a.ProjectCommandRunner.Plan(pCmd)
+			if res.PlanSuccess == nil || !res.PlanSuccess.HasChanges() {
+				planResults = append(planResults, res)
+				continue
+			}
+			// The pull request's apply requirements can't be met anymore
+			// now that it's merged.
+			pCmd.ApplyRequirements = nil
+			if pCmd.ApplyAfterMerge != nil {
+				pCmd.Notify = mergeNotify(pCmd.Notify, pCmd.ApplyAfterMerge.Notify)
+				pCmd.ApplyAfterMerge = nil
+			}
+			applyResults = append(applyResults, a.ProjectCommandRunner.Apply(pCmd))
+		}
+	}
+	if len(planResults) > 0 {
+		a.comment(log, baseRepo, pull.Num, CommandResult{ProjectResults: planResults}, models.PlanCommand)
+	}
+	if len(applyResults) > 0 {
+		a.comment(log, baseRepo, pull.Num, CommandResult{ProjectResults: applyResults}, models.ApplyCommand)
+	}
+}
+
+func (a *AfterMergeApplier) comment(log *logging.SimpleLogger, repo models.Repo, pullNum int, res CommandResult, cmdName models.CommandName) {
+	comment := "**Atlantis ran this after the pull request was merged.**\n\n" + a.MarkdownRenderer.Render(res, cmdName, log.History.String(), false, repo.VCSHost.Type)
+	if err := a.VCSClient.CreateComment(repo, pullNum, comment); err != nil {
+		log.Err("unable to comment: %s", err)
+	}
+}
+
+// mergeNotify returns the notify targets of both n and other without
+// duplicates.
+func mergeNotify(n valid.Notify, other valid.Notify) valid.Notify {
+	return valid.Notify{
+		SlackChannels: appendMissing(n.SlackChannels, other.SlackChannels),
+		Webhooks:      appendMissing(n.Webhooks, other.Webhooks),
+		Emails:        appendMissing(n.Emails, other.Emails),
+	}
+}
+
+func appendMissing(s []string, other []string) []string {
+	for _, o := range other {
+		found := false
+		for _, v := range s {
+			if v == o {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, o)
+		}
+	}
+	return s
+}
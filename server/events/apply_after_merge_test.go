@@ -0,0 +1,87 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAfterMergeApplier_Enabled(t *testing.T) {
+	globalCfg := valid.NewGlobalCfg(false, false, false)
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		ID:              fixtures.GithubRepo.ID(),
+		ApplyAfterMerge: &valid.ApplyAfterMerge{Enabled: true},
+	})
+	applier := &events.AfterMergeApplier{GlobalCfgStore: events.NewGlobalCfgStore(globalCfg)}
+	Assert(t, applier.Enabled(fixtures.GithubRepo), "exp repo to be applied after merge")
+	Assert(t, !applier.Enabled(fixtures.GitlabRepo), "exp other repos to be applied from pull requests")
+	Assert(t, !(&events.AfterMergeApplier{}).Enabled(fixtures.GithubRepo), "exp disabled without config")
+}
+
+func TestAfterMergeApplier_ApplyMerged(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	builder := mocks.NewMockProjectCommandBuilder()
+	runner := mocks.NewMockProjectCommandRunner()
+	locker := lockmocks.NewMockLocker()
+	applier := &events.AfterMergeApplier{
+		VCSClient:             vcsClient,
+		ProjectCommandBuilder: builder,
+		ProjectCommandRunner:  runner,
+		MarkdownRenderer:      &events.MarkdownRenderer{},
+		Locker:                locker,
+		WorkingDir:            mocks.NewMockWorkingDir(),
+		Logger:                logging.NewNoopLogger(),
+	}
+	afterMerge := &valid.ApplyAfterMerge{Enabled: true, Notify: valid.Notify{SlackChannels: []string{"infra", "team"}}}
+	When(builder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{
+			{
+				BaseRepo:          fixtures.GithubRepo,
+				RepoRelDir:        "changed",
+				Workspace:         "default",
+				ApplyRequirements: []string{"approved"},
+				ApplyAfterMerge:   afterMerge,
+				Notify:            valid.Notify{SlackChannels: []string{"team"}},
+			},
+			{BaseRepo: fixtures.GithubRepo, RepoRelDir: "unchanged", Workspace: "default", ApplyAfterMerge: afterMerge},
+		}, nil)
+	When(runner.Plan(matchers.AnyModelsProjectCommandContext())).Then(func(params []Param) ReturnValues {
+		ctx := params[0].(models.ProjectCommandContext)
+		output := "No changes."
+		if ctx.RepoRelDir == "changed" {
+			output = "Plan: 1 to add"
+		}
+		return ReturnValues{models.ProjectResult{RepoRelDir: ctx.RepoRelDir, Workspace: ctx.Workspace, PlanSuccess: &models.PlanSuccess{TerraformOutput: output}}}
+	})
+	When(runner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{RepoRelDir: "changed", Workspace: "default", ApplySuccess: "applied"})
+
+	applier.ApplyMerged(fixtures.GithubRepo, fixtures.Pull, "merge-sha", fixtures.User, []models.ProjectStatus{{RepoRelDir: "dir", Workspace: "default"}})
+
+	// Only the project with changes is applied, without the pull request's
+	// apply requirements, and its results are also sent to the repo's
+	// apply_after_merge notify targets.
+	applied := runner.VerifyWasCalledOnce().Apply(matchers.AnyModelsProjectCommandContext()).GetCapturedArguments()
+	Equals(t, "changed", applied.RepoRelDir)
+	Equals(t, 0, len(applied.ApplyRequirements))
+	Assert(t, applied.ApplyAfterMerge == nil, "exp project to be applied")
+	Equals(t, []string{"team", "infra"}, applied.Notify.SlackChannels)
+	vcsClient.VerifyWasCalled(Times(2)).CreateComment(vcsmatchers.AnyModelsRepo(), EqInt(fixtures.Pull.Num), AnyString())
+
+	// The default branch is planned at the merge commit.
+	ctx, _ := builder.VerifyWasCalledOnce().BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, "merge-sha", ctx.Pull.HeadCommit)
+	Equals(t, fixtures.Pull.BaseBranch, ctx.Pull.HeadBranch)
+	locker.VerifyWasCalledOnce().UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)
+}
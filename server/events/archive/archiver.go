@@ -0,0 +1,245 @@
+// Package archive archives the raw webhook payloads Atlantis receives to
+// object storage so it's possible to prove which events were delivered and to
+// replay them.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// dateLayout is the layout of the date segment of keys. Keys sort by the
+	// day they were received in so old payloads can be deleted without
+	// listing newer ones.
+	dateLayout = "2006/01/02"
+	// queueSize is how many payloads can be waiting to be uploaded before
+	// new ones are dropped.
+	queueSize = 1000
+	// pruneInterval is how often payloads older than the retention are
+	// deleted.
+	pruneInterval = 24 * time.Hour
+)
+
+// DeliveryIDHeaders are the headers that VCS hosts set to the unique ID of
+// each webhook delivery.
+var DeliveryIDHeaders = []string{
+	"X-GitHub-Delivery",
+	"X-Gitlab-Event-UUID",
+	"X-Request-UUID",
+	"X-Request-ID",
+	"Request-Id",
+}
+
+// redactedHeaders are dropped from archived payloads because they contain
+// webhook secrets or credentials rather than signatures of the payload.
+var redactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"X-Gitlab-Token",
+}
+
+// Store is the object storage that payloads are archived to.
+type Store interface {
+	// Put writes body to key.
+	Put(key string, body []byte) error
+	// Walk calls fn with the keys under prefix in lexicographic order until
+	// it returns false.
+	Walk(prefix string, fn func(key string) bool) error
+	// Delete deletes key.
+	Delete(key string) error
+}
+
+// Payload is what's archived for each webhook request. It's stored as gzipped
+// JSON.
+type Payload struct {
+	ReceivedAt time.Time   `json:"received_at"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	RemoteAddr string      `json:"remote_addr"`
+	Header     http.Header `json:"header"`
+	// Body is the request's body exactly as it was received so signatures
+	// can be verified again.
+	Body string `json:"body"`
+}
+
+// Archiver uploads webhook payloads in the background so archiving doesn't
+// slow down or fail webhook requests. A nil *Archiver doesn't archive
+// anything.
+type Archiver struct {
+	Store Store
+	// Prefix is prepended to every key, ex. atlantis/webhooks.
+	Prefix string
+	// RetentionDays is how many days payloads are kept for. If 0, they're
+	// never deleted.
+	RetentionDays int
+	Logger        logging.SimpleLogging
+	Now           func() time.Time
+
+	queue chan archiveRequest
+}
+
+type archiveRequest struct {
+	key     string
+	payload Payload
+}
+
+// NewArchiver returns an archiver that uploads to store. Start must be called
+// for payloads to be uploaded.
+func NewArchiver(store Store, prefix string, retentionDays int, logger logging.SimpleLogging) *Archiver {
+	return &Archiver{
+		Store:         store,
+		Prefix:        strings.Trim(prefix, "/"),
+		RetentionDays: retentionDays,
+		Logger:        logger,
+		queue:         make(chan archiveRequest, queueSize),
+	}
+}
+
+// Start uploads queued payloads and deletes expired ones until the process
+// exits.
+func (a *Archiver) Start() {
+	go func() {
+		for req := range a.queue {
+			if err := a.upload(req.key, req.payload); err != nil {
+				a.Logger.Err("archiving webhook payload to %s: %s", req.key, err)
+			}
+		}
+	}()
+	if a.RetentionDays <= 0 {
+		return
+	}
+	for {
+		if err := a.Prune(); err != nil {
+			a.Logger.Err("deleting expired webhook payloads: %s", err)
+		}
+		time.Sleep(pruneInterval)
+	}
+}
+
+// Archive queues r, whose body is body, to be uploaded and returns the key it
+// will be uploaded to. If too many payloads are queued, it's dropped and an
+// empty key is returned.
+func (a *Archiver) Archive(r *http.Request, body []byte) string {
+	if a == nil {
+		return ""
+	}
+	receivedAt := a.now().UTC()
+	header := r.Header.Clone()
+	for _, h := range redactedHeaders {
+		header.Del(h)
+	}
+	key := a.key(receivedAt, deliveryID(r.Header))
+	req := archiveRequest{
+		key: key,
+		payload: Payload{
+			ReceivedAt: receivedAt,
+			Method:     r.Method,
+			URL:        r.URL.String(),
+			RemoteAddr: r.RemoteAddr,
+			Header:     header,
+			Body:       string(body),
+		},
+	}
+	select {
+	case a.queue <- req:
+		return key
+	default:
+		a.Logger.Warn("dropping webhook payload because %d are already waiting to be archived", queueSize)
+		return ""
+	}
+}
+
+// Prune deletes the payloads that were received more than RetentionDays days
+// ago.
+func (a *Archiver) Prune() error {
+	if a == nil || a.RetentionDays <= 0 {
+		return nil
+	}
+	cutoff := a.now().UTC().AddDate(0, 0, -a.RetentionDays).Format(dateLayout)
+	var expired []string
+	err := a.Store.Walk(a.join(""), func(key string) bool {
+		date := strings.TrimPrefix(key, a.join(""))
+		if len(date) < len(dateLayout) || date[:len(dateLayout)] >= cutoff {
+			// Keys are sorted by date so the rest aren't expired either.
+			return false
+		}
+		expired = append(expired, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err := a.Store.Delete(key); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		a.Logger.Info("deleted %d webhook payloads received before %s", len(expired), cutoff)
+	}
+	return nil
+}
+
+func (a *Archiver) upload(key string, payload Payload) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(payload); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return a.Store.Put(key, buf.Bytes())
+}
+
+// key returns the key of a payload received at receivedAt, ex.
+// atlantis/2020/01/10/150405.000000000-<delivery ID>.json.gz.
+func (a *Archiver) key(receivedAt time.Time, id string) string {
+	if id == "" {
+		b := make([]byte, 8)
+		rand.Read(b) // nolint: errcheck
+		id = hex.EncodeToString(b)
+	}
+	return a.join(fmt.Sprintf("%s/%s-%s.json.gz", receivedAt.Format(dateLayout), receivedAt.Format("150405.000000000"), id))
+}
+
+// join returns key with the prefix prepended.
+func (a *Archiver) join(key string) string {
+	if a.Prefix == "" {
+		return key
+	}
+	return a.Prefix + "/" + key
+}
+
+func (a *Archiver) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// deliveryID returns the ID of the webhook delivery from header, made safe to
+// use in keys, or an empty string if there isn't one.
+func deliveryID(header http.Header) string {
+	for _, h := range DeliveryIDHeaders {
+		if id := header.Get(h); id != "" {
+			return strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+					return r
+				}
+				return -1
+			}, id)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/archive"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// memStore is a Store that keeps objects in memory.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	walked  int
+}
+
+func newMemStore(keys ...string) *memStore {
+	m := &memStore{objects: make(map[string][]byte)}
+	for _, k := range keys {
+		m.objects[k] = nil
+	}
+	return m
+}
+
+func (m *memStore) Put(key string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = body
+	return nil
+}
+
+func (m *memStore) Walk(prefix string, fn func(key string) bool) error {
+	for _, k := range m.keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		m.walked++
+		if !fn(k) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memStore) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for k := range m.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestArchiver_Archive(t *testing.T) {
+	store := newMemStore()
+	a := archive.NewArchiver(store, "/atlantis/webhooks/", 0, logging.NewNoopLogger())
+	now := time.Date(2020, 1, 10, 15, 4, 5, 0, time.UTC)
+	a.Now = func() time.Time { return now }
+	a.Start()
+
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(`{"action":"opened"}`))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Set("X-Hub-Signature", "sha1=abc")
+	req.Header.Set("X-Gitlab-Token", "secret")
+	req.Header.Set("Authorization", "Basic secret")
+	key := a.Archive(req, []byte(`{"action":"opened"}`))
+	Equals(t, "atlantis/webhooks/2020/01/10/150405.000000000-72d3162e-cc78-11e3-81ab-4c9367dc0958.json.gz", key)
+
+	var body []byte
+	for i := 0; i < 100 && body == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		store.mu.Lock()
+		body = store.objects[key]
+		store.mu.Unlock()
+	}
+	Assert(t, body != nil, "expected payload to be uploaded")
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	Ok(t, err)
+	var payload archive.Payload
+	Ok(t, json.NewDecoder(gz).Decode(&payload))
+	Equals(t, archive.Payload{
+		ReceivedAt: now,
+		Method:     "POST",
+		URL:        "/events",
+		RemoteAddr: "192.0.2.1:1234",
+		Header: http.Header{
+			"X-Github-Delivery": {"72d3162e-cc78-11e3-81ab-4c9367dc0958"},
+			"X-Hub-Signature":   {"sha1=abc"},
+		},
+		Body: `{"action":"opened"}`,
+	}, payload)
+}
+
+func TestArchiver_ArchiveWithoutDeliveryID(t *testing.T) {
+	a := archive.NewArchiver(newMemStore(), "", 0, logging.NewNoopLogger())
+	a.Now = func() time.Time { return time.Date(2020, 1, 10, 15, 4, 5, 0, time.UTC) }
+	req, _ := http.NewRequest("POST", "/events", nil)
+	key := a.Archive(req, nil)
+	Assert(t, strings.HasPrefix(key, "2020/01/10/150405.000000000-"), "unexpected key %s", key)
+	Assert(t, strings.HasSuffix(key, ".json.gz"), "unexpected key %s", key)
+	Assert(t, key != a.Archive(req, nil), "expected keys to be unique")
+}
+
+func TestArchiver_Prune(t *testing.T) {
+	store := newMemStore(
+		"webhooks/2019/12/01/000000.000000000-a.json.gz",
+		"webhooks/2020/01/02/235959.000000000-b.json.gz",
+		"webhooks/2020/01/03/000000.000000000-c.json.gz",
+		"webhooks/2020/01/10/000000.000000000-d.json.gz",
+		"other/2019/01/01/000000.000000000-e.json.gz",
+	)
+	a := archive.NewArchiver(store, "webhooks", 7, logging.NewNoopLogger())
+	a.Now = func() time.Time { return time.Date(2020, 1, 10, 15, 4, 5, 0, time.UTC) }
+	Ok(t, a.Prune())
+	Equals(t, []string{
+		"other/2019/01/01/000000.000000000-e.json.gz",
+		"webhooks/2020/01/03/000000.000000000-c.json.gz",
+		"webhooks/2020/01/10/000000.000000000-d.json.gz",
+	}, store.keys())
+	// Listing should stop at the first payload that isn't expired.
+	Equals(t, 3, store.walked)
+}
+
+func TestArchiver_Nil(t *testing.T) {
+	var a *archive.Archiver
+	req, _ := http.NewRequest("POST", "/events", nil)
+	Equals(t, "", a.Archive(req, nil))
+	Ok(t, a.Prune())
+}
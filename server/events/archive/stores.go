@@ -0,0 +1,212 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsScope is the OAuth scope needed to write and delete objects.
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// ParseURL returns the scheme, bucket and prefix of archiveURL, which must be
+// either s3://<bucket>/<prefix> or gs://<bucket>/<prefix>.
+func ParseURL(archiveURL string) (scheme string, bucket string, prefix string, err error) {
+	u, err := url.Parse(archiveURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" {
+		return "", "", "", fmt.Errorf("%q must start with s3:// or gs://", archiveURL)
+	}
+	if u.Host == "" {
+		return "", "", "", fmt.Errorf("%q has no bucket", archiveURL)
+	}
+	return u.Scheme, u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// NewStore returns the store for archiveURL and the prefix that keys must
+// start with. See ParseURL. If s3Endpoint is set, it's used instead of AWS's,
+// ex. for MinIO.
+func NewStore(archiveURL string, s3Endpoint string) (Store, string, error) {
+	scheme, bucket, prefix, err := ParseURL(archiveURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if scheme == "gs" {
+		store, err := NewGCSStore(bucket)
+		return store, prefix, err
+	}
+	store, err := NewS3Store(bucket, s3Endpoint)
+	return store, prefix, err
+}
+
+// S3Store stores payloads in an S3 bucket.
+type S3Store struct {
+	Client *s3.S3
+	Bucket string
+}
+
+// NewS3Store returns a store for bucket. Credentials and the region are read
+// like the AWS CLI reads them, ex. from AWS_REGION.
+func NewS3Store(bucket string, endpoint string) (*S3Store, error) {
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS session")
+	}
+	return &S3Store{Client: s3.New(sess), Bucket: bucket}, nil
+}
+
+// Put implements Store.
+func (s *S3Store) Put(key string, body []byte) error {
+	_, err := s.Client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/gzip"),
+	})
+	return errors.Wrapf(err, "uploading to s3://%s/%s", s.Bucket, key)
+}
+
+// Walk implements Store.
+func (s *S3Store) Walk(prefix string, fn func(key string) bool) error {
+	err := s.Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			if !fn(aws.StringValue(obj.Key)) {
+				return false
+			}
+		}
+		return true
+	})
+	return errors.Wrapf(err, "listing s3://%s/%s", s.Bucket, prefix)
+}
+
+// Delete implements Store.
+func (s *S3Store) Delete(key string) error {
+	_, err := s.Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return errors.Wrapf(err, "deleting s3://%s/%s", s.Bucket, key)
+}
+
+// GCSStore stores payloads in a Google Cloud Storage bucket with its JSON API.
+type GCSStore struct {
+	// Client adds Google Cloud credentials to requests.
+	Client *http.Client
+	Bucket string
+	// BaseURL is the API's URL, which is stubbed in tests.
+	BaseURL string
+}
+
+// NewGCSStore returns a store for bucket that uses the application default
+// credentials.
+func NewGCSStore(bucket string) (*GCSStore, error) {
+	client, err := google.DefaultClient(context.Background(), gcsScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding Google Cloud credentials")
+	}
+	return &GCSStore{
+		Client:  client,
+		Bucket:  bucket,
+		BaseURL: "https://storage.googleapis.com",
+	}, nil
+}
+
+// Put implements Store.
+func (g *GCSStore) Put(key string, body []byte) error {
+	reqURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", g.BaseURL, url.PathEscape(g.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequest("POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	_, err = g.do(req)
+	return errors.Wrapf(err, "uploading to gs://%s/%s", g.Bucket, key)
+}
+
+// Walk implements Store.
+func (g *GCSStore) Walk(prefix string, fn func(key string) bool) error {
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/storage/v1/b/%s/o?%s", g.BaseURL, url.PathEscape(g.Bucket), query.Encode()), nil)
+		if err != nil {
+			return err
+		}
+		body, err := g.do(req)
+		if err != nil {
+			return errors.Wrapf(err, "listing gs://%s/%s", g.Bucket, prefix)
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return errors.Wrapf(err, "parsing listing of gs://%s/%s", g.Bucket, prefix)
+		}
+		for _, item := range page.Items {
+			if !fn(item.Name) {
+				return nil
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Delete implements Store.
+func (g *GCSStore) Delete(key string) error {
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/storage/v1/b/%s/o/%s", g.BaseURL, url.PathEscape(g.Bucket), url.PathEscape(key)), nil)
+	if err != nil {
+		return err
+	}
+	_, err = g.do(req)
+	return errors.Wrapf(err, "deleting gs://%s/%s", g.Bucket, key)
+}
+
+// do sends req and returns the response's body or an error if it wasn't
+// successful.
+func (g *GCSStore) do(req *http.Request) ([]byte, error) {
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
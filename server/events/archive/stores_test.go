@@ -0,0 +1,95 @@
+package archive_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/archive"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseURL(t *testing.T) {
+	cases := []struct {
+		url       string
+		expScheme string
+		expBucket string
+		expPrefix string
+		expErr    string
+	}{
+		{url: "s3://bucket", expScheme: "s3", expBucket: "bucket"},
+		{url: "s3://bucket/atlantis/webhooks/", expScheme: "s3", expBucket: "bucket", expPrefix: "atlantis/webhooks"},
+		{url: "gs://bucket/webhooks", expScheme: "gs", expBucket: "bucket", expPrefix: "webhooks"},
+		{url: "https://bucket/webhooks", expErr: "\"https://bucket/webhooks\" must start with s3:// or gs://"},
+		{url: "s3:///webhooks", expErr: "\"s3:///webhooks\" has no bucket"},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			scheme, bucket, prefix, err := archive.ParseURL(c.url)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expScheme, scheme)
+			Equals(t, c.expBucket, bucket)
+			Equals(t, c.expPrefix, prefix)
+		})
+	}
+}
+
+func TestGCSStore(t *testing.T) {
+	var requests []string
+	var uploaded string
+	gcs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.Method == "POST":
+			b, _ := ioutil.ReadAll(r.Body)
+			uploaded = string(b)
+			fmt.Fprint(w, `{}`)
+		case r.Method == "GET" && r.URL.Query().Get("pageToken") == "":
+			fmt.Fprint(w, `{"items": [{"name": "webhooks/a"}, {"name": "webhooks/b"}], "nextPageToken": "next"}`)
+		case r.Method == "GET":
+			fmt.Fprint(w, `{"items": [{"name": "webhooks/c"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": "not found"}`)
+		}
+	}))
+	defer gcs.Close()
+	store := archive.GCSStore{Client: http.DefaultClient, Bucket: "bucket", BaseURL: gcs.URL}
+
+	Ok(t, store.Put("webhooks/2020/01/10/a.json.gz", []byte("payload")))
+	Equals(t, "payload", uploaded)
+
+	var keys []string
+	Ok(t, store.Walk("webhooks/", func(key string) bool {
+		keys = append(keys, key)
+		return true
+	}))
+	Equals(t, []string{"webhooks/a", "webhooks/b", "webhooks/c"}, keys)
+
+	// Walking stops when fn returns false, without fetching the next page.
+	keys = nil
+	Ok(t, store.Walk("webhooks/", func(key string) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	Equals(t, []string{"webhooks/a"}, keys)
+
+	Ok(t, store.Delete("webhooks/a"))
+	ErrEquals(t, "deleting gs://bucket/webhooks/missing: 404 Not Found: {\"error\": \"not found\"}", store.Delete("webhooks/missing"))
+
+	Equals(t, []string{
+		"POST /upload/storage/v1/b/bucket/o?uploadType=media&name=webhooks%2F2020%2F01%2F10%2Fa.json.gz",
+		"GET /storage/v1/b/bucket/o?fields=items%28name%29%2CnextPageToken&prefix=webhooks%2F",
+		"GET /storage/v1/b/bucket/o?fields=items%28name%29%2CnextPageToken&pageToken=next&prefix=webhooks%2F",
+		"GET /storage/v1/b/bucket/o?fields=items%28name%29%2CnextPageToken&prefix=webhooks%2F",
+		"DELETE /storage/v1/b/bucket/o/webhooks%2Fa",
+		"DELETE /storage/v1/b/bucket/o/webhooks%2Fmissing",
+	}, requests)
+}
@@ -0,0 +1,81 @@
+// Package artifacts uploads the artifacts of upload_artifact steps, ex. plan
+// JSON or scan reports, to object storage.
+package artifacts
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// MaxURLExpiry is the longest S3 allows pre-signed URLs to be valid for.
+const MaxURLExpiry = 7 * 24 * time.Hour
+
+// S3Store uploads artifacts to an S3 bucket and links to them with pre-signed
+// URLs, so the bucket doesn't need to be public.
+type S3Store struct {
+	Client *s3.S3
+	Bucket string
+	// URLExpiry is how long the URLs to artifacts are valid for.
+	URLExpiry time.Duration
+}
+
+// NewS3Store returns a store that uploads to bucket. Credentials and the
+// region are read like the AWS CLI reads them, ex. from AWS_REGION. If
+// endpoint is set, it's used instead of AWS's, ex. for MinIO.
+func NewS3Store(bucket string, endpoint string, urlExpiry time.Duration) (*S3Store, error) {
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS session")
+	}
+	return &S3Store{
+		Client:    s3.New(sess),
+		Bucket:    bucket,
+		URLExpiry: urlExpiry,
+	}, nil
+}
+
+// Upload uploads the file at path to key and returns a pre-signed URL to
+// download it.
+func (s *S3Store) Upload(key string, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() // nolint: errcheck
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if _, err := s.Client.PutObject(input); err != nil {
+		return "", errors.Wrapf(err, "uploading to s3://%s/%s", s.Bucket, key)
+	}
+
+	req, _ := s.Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	url, err := req.Presign(s.URLExpiry)
+	if err != nil {
+		return "", errors.Wrapf(err, "signing URL to s3://%s/%s", s.Bucket, key)
+	}
+	return url, nil
+}
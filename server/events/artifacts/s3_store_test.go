@@ -0,0 +1,62 @@
+package artifacts_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/runatlantis/atlantis/server/events/artifacts"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestS3Store_Upload(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "plan.json")
+	Ok(t, ioutil.WriteFile(path, []byte(`{"format_version": "0.1"}`), 0600))
+
+	var method, reqPath, contentType, body string
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		reqPath = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer s3.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithCredentials(credentials.NewStaticCredentials("access-key", "secret-key", "")).
+		WithRegion("us-east-1").
+		WithEndpoint(s3.URL).
+		WithS3ForcePathStyle(true))
+	Ok(t, err)
+	store := artifacts.S3Store{
+		Client:    awss3.New(sess),
+		Bucket:    "bucket",
+		URLExpiry: time.Hour,
+	}
+	signedURL, err := store.Upload("owner/repo/1/plan-json/plan.json", path)
+	Ok(t, err)
+
+	Equals(t, "PUT", method)
+	Equals(t, "/bucket/owner/repo/1/plan-json/plan.json", reqPath)
+	Equals(t, "application/json", contentType)
+	Equals(t, `{"format_version": "0.1"}`, body)
+
+	parsed, err := url.Parse(signedURL)
+	Ok(t, err)
+	Equals(t, s3.URL, parsed.Scheme+"://"+parsed.Host)
+	Equals(t, "/bucket/owner/repo/1/plan-json/plan.json", parsed.Path)
+	Equals(t, "3600", parsed.Query().Get("X-Amz-Expires"))
+	Assert(t, strings.HasPrefix(parsed.Query().Get("X-Amz-Credential"), "access-key/"), "expected URL to be signed, got %s", signedURL)
+}
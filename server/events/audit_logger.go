@@ -0,0 +1,149 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_audit_logger.go AuditLogger
+
+// AuditLogger records commands and lock operations for auditing.
+type AuditLogger interface {
+	// Record adds event to the audit log.
+	Record(event models.AuditEvent)
+}
+
+// AuditSink is somewhere audit events are sent in addition to the audit
+// store, ex. syslog.
+type AuditSink interface {
+	Send(event models.AuditEvent) error
+}
+
+// DefaultAuditLogger stores audit events in the DB and sends them to Sinks.
+type DefaultAuditLogger struct {
+	DB     *db.BoltDB
+	Sinks  []AuditSink
+	Logger logging.SimpleLogging
+}
+
+// Record stores event and then sends it to each sink. Errors are logged
+// rather than returned because failing to audit shouldn't fail the command
+// that's already run.
+func (a *DefaultAuditLogger) Record(event models.AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	stored, err := a.DB.AppendAuditEvent(event)
+	if err != nil {
+		a.Logger.Err("unable to store audit event %s for %s#%d: %s", event.Action, event.RepoFullName, event.PullNum, err)
+		stored = event
+	}
+	for _, sink := range a.Sinks {
+		if err := sink.Send(stored); err != nil {
+			a.Logger.Err("unable to send audit event %d to sink: %s", stored.ID, err)
+		}
+	}
+}
+
+// NewProjectAuditEvent returns the audit event for running cmdName on the
+// project in ctx.
+func NewProjectAuditEvent(ctx models.ProjectCommandContext, cmdName models.CommandName, res models.ProjectResult, duration time.Duration) models.AuditEvent {
+	action := models.PlanAuditAction
+	if cmdName == models.ApplyCommand {
+		action = models.ApplyAuditAction
+	}
+	event := models.AuditEvent{
+		Time:         time.Now().UTC(),
+		Action:       action,
+		User:         ctx.User.Username,
+		RepoFullName: ctx.BaseRepo.FullName,
+		PullNum:      ctx.Pull.Num,
+		ProjectName:  ctx.ProjectName,
+		RepoRelDir:   ctx.RepoRelDir,
+		Workspace:    ctx.Workspace,
+		Result:       models.SuccessAuditResult,
+		DurationMS:   int64(duration / time.Millisecond),
+		ProjectID:    ctx.ProjectID(),
+	}
+	if res.Error != nil {
+		event.Result = models.ErrorAuditResult
+		event.Error = res.Error.Error()
+	} else if res.Failure != "" {
+		event.Result = models.FailureAuditResult
+		event.Error = res.Failure
+	}
+	return event
+}
+
+// SyslogAuditSink writes audit events to syslog as JSON.
+type SyslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogAuditSink connects to the syslog server at addr, ex.
+// "udp://syslog.example.com:514". If addr is "local" it connects to the local
+// syslog daemon.
+func NewSyslogAuditSink(addr string) (*SyslogAuditSink, error) {
+	network, raddr := "", ""
+	if addr != "local" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing syslog address %q", addr)
+		}
+		if u.Scheme != "udp" && u.Scheme != "tcp" {
+			return nil, fmt.Errorf("syslog address %q must use the udp or tcp scheme or be \"local\"", addr)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_AUTH, "atlantis")
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to syslog")
+	}
+	return &SyslogAuditSink{writer: writer}, nil
+}
+
+// Send writes event to syslog.
+func (s *SyslogAuditSink) Send(event models.AuditEvent) error {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(serialized))
+}
+
+// WebhookAuditSink POSTs audit events as JSON to URL.
+type WebhookAuditSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts event to the webhook.
+func (w *WebhookAuditSink) Send(event models.AuditEvent) error {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(serialized))
+	if err != nil {
+		return errors.Wrap(err, "posting audit event")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting audit event: webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package events_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDefaultAuditLogger_Record(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+
+	var received []models.AuditEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "application/json", r.Header.Get("Content-Type"))
+		var event models.AuditEvent
+		Ok(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	auditLogger := &events.DefaultAuditLogger{
+		DB:     boltDB,
+		Sinks:  []events.AuditSink{&events.WebhookAuditSink{URL: server.URL}},
+		Logger: logging.NewNoopLogger(),
+	}
+	auditLogger.Record(models.AuditEvent{
+		Action:       models.DeleteLockAuditAction,
+		User:         "lkysow",
+		RepoFullName: "owner/repo",
+		PullNum:      1,
+		RepoRelDir:   ".",
+		Workspace:    "default",
+		Result:       models.SuccessAuditResult,
+	})
+
+	stored, err := boltDB.ListAuditEvents(time.Time{})
+	Ok(t, err)
+	Equals(t, 1, len(stored))
+	Equals(t, uint64(1), stored[0].ID)
+	Assert(t, !stored[0].Time.IsZero(), "expected time to be set")
+
+	// The sink should get the event with its ID.
+	Equals(t, 1, len(received))
+	Equals(t, uint64(1), received[0].ID)
+	Equals(t, "lkysow", received[0].User)
+}
+
+func TestWebhookAuditSink_ErrStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	sink := &events.WebhookAuditSink{URL: server.URL}
+	ErrEquals(t, "posting audit event: webhook responded with status 500", sink.Send(models.AuditEvent{}))
+}
+
+func TestNewSyslogAuditSink_InvalidAddr(t *testing.T) {
+	_, err := events.NewSyslogAuditSink("http://syslog.example.com")
+	ErrEquals(t, `syslog address "http://syslog.example.com" must use the udp or tcp scheme or be "local"`, err)
+}
+
+func TestNewProjectAuditEvent(t *testing.T) {
+	ctx := models.ProjectCommandContext{
+		BaseRepo:    fixtures.GithubRepo,
+		Pull:        fixtures.Pull,
+		User:        fixtures.User,
+		ProjectName: "proj",
+		RepoRelDir:  "dir",
+		Workspace:   "default",
+	}
+	cases := []struct {
+		description string
+		cmdName     models.CommandName
+		res         models.ProjectResult
+		expAction   models.AuditAction
+		expResult   models.AuditResult
+		expErr      string
+	}{
+		{
+			"plan success",
+			models.PlanCommand,
+			models.ProjectResult{PlanSuccess: &models.PlanSuccess{}},
+			models.PlanAuditAction,
+			models.SuccessAuditResult,
+			"",
+		},
+		{
+			"apply failure",
+			models.ApplyCommand,
+			models.ProjectResult{Failure: "Pull request must be approved"},
+			models.ApplyAuditAction,
+			models.FailureAuditResult,
+			"Pull request must be approved",
+		},
+		{
+			"apply error",
+			models.ApplyCommand,
+			models.ProjectResult{Error: errors.New("err")},
+			models.ApplyAuditAction,
+			models.ErrorAuditResult,
+			"err",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			event := events.NewProjectAuditEvent(ctx, c.cmdName, c.res, 1500*time.Millisecond)
+			Equals(t, c.expAction, event.Action)
+			Equals(t, c.expResult, event.Result)
+			Equals(t, c.expErr, event.Error)
+			Equals(t, int64(1500), event.DurationMS)
+			Equals(t, fixtures.User.Username, event.User)
+			Equals(t, fixtures.GithubRepo.FullName, event.RepoFullName)
+			Equals(t, fixtures.Pull.Num, event.PullNum)
+			Equals(t, "proj", event.ProjectName)
+			Equals(t, "dir", event.RepoRelDir)
+			Equals(t, "default", event.Workspace)
+		})
+	}
+}
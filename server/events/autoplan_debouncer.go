@@ -0,0 +1,112 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// AutoplanDebouncer delays autoplans until no new commits have been pushed
+// to their pull request for Window so that a burst of pushes is only planned
+// once, for its last commit. Autoplans of commits that are superseded by a
+// newer push are skipped if they haven't started or cancelled if they're
+// running. A nil *AutoplanDebouncer runs every autoplan straight away.
+type AutoplanDebouncer struct {
+	// Window is how long autoplans wait for newer commits.
+	Window time.Duration
+	Now    func() time.Time
+
+	mu    sync.Mutex
+	pulls map[string]*debouncedCommit
+}
+
+// debouncedCommit is the latest commit pushed to a pull request.
+type debouncedCommit struct {
+	sha      string
+	pushedAt time.Time
+	// superseded is closed once a newer commit is pushed.
+	superseded chan struct{}
+}
+
+// Push records that pull's head commit was pushed. The autoplan of the
+// commit that was pushed before it is cancelled.
+func (d *AutoplanDebouncer) Push(repo models.Repo, pull models.PullRequest) {
+	if d == nil || d.Window <= 0 || pull.HeadCommit == "" {
+		return
+	}
+	now := d.now()
+	key := autoplanDedupPrefix(repo, pull.Num)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pulls == nil {
+		d.pulls = make(map[string]*debouncedCommit)
+	}
+	if prev, ok := d.pulls[key]; ok {
+		if prev.sha == pull.HeadCommit {
+			return
+		}
+		close(prev.superseded)
+	}
+	d.pulls[key] = &debouncedCommit{
+		sha:        pull.HeadCommit,
+		pushedAt:   now,
+		superseded: make(chan struct{}),
+	}
+}
+
+// Wait blocks until no newer commit has been pushed to pull for Window. It
+// returns false if pull's head commit was superseded in the meantime and
+// shouldn't be autoplanned. Otherwise it returns a channel that's closed once
+// the commit is superseded, which cancels its autoplan, and a function that
+// must be called once the autoplan has finished.
+func (d *AutoplanDebouncer) Wait(repo models.Repo, pull models.PullRequest) (<-chan struct{}, func(), bool) {
+	if d == nil || d.Window <= 0 || pull.HeadCommit == "" {
+		return nil, func() {}, true
+	}
+	key := autoplanDedupPrefix(repo, pull.Num)
+	for {
+		d.mu.Lock()
+		if d.pulls == nil {
+			d.pulls = make(map[string]*debouncedCommit)
+		}
+		commit, ok := d.pulls[key]
+		if !ok {
+			// The push wasn't recorded, ex. because the autoplan was queued
+			// before the server restarted, so there's nothing to wait for.
+			commit = &debouncedCommit{sha: pull.HeadCommit, superseded: make(chan struct{})}
+			d.pulls[key] = commit
+		}
+		d.mu.Unlock()
+		if commit.sha != pull.HeadCommit {
+			return nil, nil, false
+		}
+
+		wait := commit.pushedAt.Add(d.Window).Sub(d.now())
+		if wait <= 0 {
+			return commit.superseded, func() { d.finish(key, commit) }, true
+		}
+		select {
+		case <-commit.superseded:
+			return nil, nil, false
+		case <-time.After(wait):
+		}
+	}
+}
+
+// finish forgets commit unless it was superseded so the map doesn't grow
+// forever.
+func (d *AutoplanDebouncer) finish(key string, commit *debouncedCommit) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pulls[key] == commit {
+		delete(d.pulls, key)
+	}
+}
+
+func (d *AutoplanDebouncer) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
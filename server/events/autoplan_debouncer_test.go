@@ -0,0 +1,90 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAutoplanDebouncer_Nil(t *testing.T) {
+	var debouncer *events.AutoplanDebouncer
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull := models.PullRequest{Num: 1, HeadCommit: "abc"}
+	debouncer.Push(repo, pull)
+	done, finish, ok := debouncer.Wait(repo, pull)
+	Equals(t, true, ok)
+	Assert(t, done == nil, "exp autoplans to not be cancellable")
+	finish()
+}
+
+func TestAutoplanDebouncer_Wait(t *testing.T) {
+	debouncer := &events.AutoplanDebouncer{Window: 100 * time.Millisecond}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	first := models.PullRequest{Num: 1, HeadCommit: "abc"}
+	second := models.PullRequest{Num: 1, HeadCommit: "def"}
+	other := models.PullRequest{Num: 2, HeadCommit: "abc"}
+
+	debouncer.Push(repo, first)
+	debouncer.Push(repo, other)
+	firstOK := make(chan bool)
+	go func() {
+		_, _, ok := debouncer.Wait(repo, first)
+		firstOK <- ok
+	}()
+	time.Sleep(20 * time.Millisecond)
+	debouncer.Push(repo, second)
+	Equals(t, false, <-firstOK)
+
+	start := time.Now()
+	done, finish, ok := debouncer.Wait(repo, second)
+	Equals(t, true, ok)
+	Assert(t, time.Since(start) >= 50*time.Millisecond, "exp to wait for the window")
+	finish()
+
+	// Other pull requests aren't superseded.
+	_, finish, ok = debouncer.Wait(repo, other)
+	Equals(t, true, ok)
+	finish()
+
+	// Finished autoplans aren't cancelled by later pushes.
+	debouncer.Push(repo, models.PullRequest{Num: 1, HeadCommit: "ghi"})
+	select {
+	case <-done:
+		t.Fatal("exp finished autoplan to not be cancelled")
+	default:
+	}
+}
+
+func TestAutoplanDebouncer_CancelRunning(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	debouncer := &events.AutoplanDebouncer{
+		Window: time.Minute,
+		Now:    func() time.Time { return now },
+	}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull := models.PullRequest{Num: 1, HeadCommit: "abc"}
+
+	debouncer.Push(repo, pull)
+	now = now.Add(time.Minute)
+	done, finish, ok := debouncer.Wait(repo, pull)
+	Equals(t, true, ok)
+	defer finish()
+
+	// Duplicate pushes of the same commit don't cancel it.
+	debouncer.Push(repo, pull)
+	select {
+	case <-done:
+		t.Fatal("exp autoplan to not be cancelled by a duplicate push")
+	default:
+	}
+
+	debouncer.Push(repo, models.PullRequest{Num: 1, HeadCommit: "def"})
+	select {
+	case <-done:
+	default:
+		t.Fatal("exp autoplan to be cancelled by a newer commit")
+	}
+}
@@ -0,0 +1,280 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// CloneCacheDir is the directory under the data dir that repo mirrors are
+// cloned into. Mirrors are at {CloneCacheDir}/{repo ID}/{generation}.git
+// where the generation is the time the mirror was created.
+const CloneCacheDir = "clone-cache"
+
+// The results counted by CloneCache.Requests.
+const (
+	cloneCacheHit    = "hit"
+	cloneCacheFetch  = "fetch"
+	cloneCacheCreate = "create"
+	cloneCacheError  = "error"
+)
+
+// CloneCache keeps a mirror clone of each repo that pull request clones use
+// as a reference so that they only download the objects the mirror doesn't
+// have and read the mirror's objects from disk instead of copying them.
+//
+// A mirror is fetched at most once per FetchInterval. Since pull request
+// clones depend on the mirror's objects, objects are never deleted from a
+// mirror. Instead, once a mirror is older than MaxAge, or can't be fetched, a
+// new generation of it is cloned for new pull request clones to use. Older
+// generations are deleted once no clone references them anymore, ex. after
+// their pull requests are closed.
+type CloneCache struct {
+	DataDir string
+	// ReposDir is FileWorkspace's ReposDir, where the clones that reference
+	// mirrors are.
+	ReposDir string
+	// FetchInterval is how often mirrors are fetched. Clones in between use
+	// the mirror as is and fetch newer commits themselves.
+	FetchInterval time.Duration
+	// MaxAge is how old a mirror can get before a new one is cloned. If 0,
+	// mirrors are only re-cloned if they can't be fetched.
+	MaxAge time.Duration
+	// Requests, if set, counts the times each repo's mirror was requested by
+	// the result: hit if it was used as is, fetch if it was fetched first,
+	// create if a new mirror was cloned and error if it couldn't be used.
+	Requests *metrics.Counter
+	Now      func() time.Time
+
+	mu      sync.Mutex
+	mirrors map[string]*repoMirror
+}
+
+// repoMirror is the state of a repo's mirror.
+type repoMirror struct {
+	mu sync.Mutex
+	// loaded is true once the latest generation on disk has been looked up.
+	loaded bool
+	// dir is the current generation's dir or empty if there isn't one.
+	dir     string
+	created time.Time
+	fetched time.Time
+	// inUse counts the clones currently using each generation's dir so it
+	// isn't deleted before the clone has written its reference to it.
+	inUse map[string]int
+}
+
+// Reference returns the dir of repo's mirror after cloning or fetching it
+// from cloneURL if necessary. The returned func must be called once the clone
+// that references the mirror is done.
+func (c *CloneCache) Reference(log *logging.SimpleLogger, repo models.Repo, cloneURL string) (string, func(), error) {
+	m := c.mirror(repo.ID())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.loaded {
+		m.dir, m.created = c.latestGeneration(repo)
+		m.loaded = true
+	}
+	now := c.now()
+	result := cloneCacheHit
+	switch {
+	case m.dir == "":
+		result = cloneCacheCreate
+	case c.MaxAge > 0 && now.Sub(m.created) >= c.MaxAge:
+		log.Info("re-cloning mirror of %s since it's older than %s", repo.FullName, c.MaxAge)
+		result = cloneCacheCreate
+	case now.Sub(m.fetched) >= c.FetchInterval:
+		if err := c.git(repo, m.dir, "fetch", "--quiet", "--prune", cloneURL, "+refs/*:refs/*"); err != nil {
+			log.Warn("re-cloning mirror of %s since it couldn't be fetched: %s", repo.FullName, err)
+			result = cloneCacheCreate
+		} else {
+			m.fetched = now
+			result = cloneCacheFetch
+		}
+	}
+	if result == cloneCacheCreate {
+		dir, err := c.create(log, repo, cloneURL, now)
+		if err != nil {
+			c.count(repo, cloneCacheError)
+			return "", nil, err
+		}
+		m.dir, m.created, m.fetched = dir, now, now
+	}
+	if result != cloneCacheHit {
+		c.prune(log, repo, m)
+	}
+	c.count(repo, result)
+
+	dir := m.dir
+	m.inUse[dir]++
+	release := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inUse[dir]--
+		if m.inUse[dir] <= 0 {
+			delete(m.inUse, dir)
+		}
+	}
+	return dir, release, nil
+}
+
+// create clones a new generation of repo's mirror.
+func (c *CloneCache) create(log *logging.SimpleLogger, repo models.Repo, cloneURL string, now time.Time) (string, error) {
+	repoDir := c.repoDir(repo)
+	if err := os.MkdirAll(repoDir, 0700); err != nil {
+		return "", errors.Wrap(err, "creating clone cache dir")
+	}
+	dir := filepath.Join(repoDir, fmt.Sprintf("%d.git", now.UnixNano()))
+	log.Info("cloning mirror of %s into %q", repo.FullName, dir)
+	if err := c.git(repo, repoDir, "clone", "--quiet", "--mirror", cloneURL, dir); err != nil {
+		os.RemoveAll(dir) // nolint: errcheck
+		return "", err
+	}
+	// Objects that are no longer referenced by the mirror's refs can still be
+	// used by pull request clones so they must never be garbage collected.
+	if err := c.git(repo, dir, "config", "gc.auto", "0"); err != nil {
+		os.RemoveAll(dir) // nolint: errcheck
+		return "", err
+	}
+	return dir, nil
+}
+
+// prune deletes the generations of repo's mirror other than the current one
+// that aren't used by any clone.
+func (c *CloneCache) prune(log *logging.SimpleLogger, repo models.Repo, m *repoMirror) {
+	generations, err := c.generations(repo)
+	if err != nil {
+		log.Warn("unable to list mirrors of %s: %s", repo.FullName, err)
+		return
+	}
+	// Clones record the objects dirs they reference in their alternates file.
+	alternates, _ := filepath.Glob(filepath.Join(ReposDir(c.DataDir, c.ReposDir), repo.FullName, "*", "*", ".git", "objects", "info", "alternates"))
+	var references []string
+	for _, path := range alternates {
+		contents, err := ioutil.ReadFile(path) // nolint: gosec
+		if err != nil {
+			log.Warn("not deleting old mirrors of %s, unable to read %q: %s", repo.FullName, path, err)
+			return
+		}
+		references = append(references, string(contents))
+	}
+
+	for _, dir := range generations {
+		if dir == m.dir || m.inUse[dir] > 0 || referenced(references, dir) {
+			continue
+		}
+		log.Info("deleting old mirror %q", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warn("unable to delete old mirror %q: %s", dir, err)
+		}
+	}
+}
+
+// referenced returns true if any of the alternates files' contents reference
+// the objects in the mirror in dir.
+func referenced(alternates []string, dir string) bool {
+	objectsDir := filepath.Join(dir, "objects")
+	for _, contents := range alternates {
+		for _, line := range strings.Split(contents, "\n") {
+			if filepath.Clean(strings.TrimSpace(line)) == objectsDir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// latestGeneration returns the dir and creation time of the newest mirror of
+// repo on disk, or an empty dir if there isn't one.
+func (c *CloneCache) latestGeneration(repo models.Repo) (string, time.Time) {
+	generations, err := c.generations(repo)
+	if err != nil || len(generations) == 0 {
+		return "", time.Time{}
+	}
+	latest := generations[len(generations)-1]
+	nanos, _ := strconv.ParseInt(strings.TrimSuffix(filepath.Base(latest), ".git"), 10, 64)
+	return latest, time.Unix(0, nanos)
+}
+
+// generations returns the dirs of all the mirrors of repo on disk, oldest
+// first.
+func (c *CloneCache) generations(repo models.Repo) ([]string, error) {
+	entries, err := ioutil.ReadDir(c.repoDir(repo))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// ReadDir sorts by name and the names are timestamps of the same length
+	// so they're sorted by age.
+	var dirs []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !strings.HasSuffix(name, ".git") {
+			continue
+		}
+		if _, err := strconv.ParseInt(strings.TrimSuffix(name, ".git"), 10, 64); err != nil {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(c.repoDir(repo), name))
+	}
+	return dirs, nil
+}
+
+// git runs git with args in dir.
+func (c *CloneCache) git(repo models.Repo, dir string, args ...string) error {
+	cmd := exec.Command("git", args...) // nolint: gosec
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		sanitize := func(s string) string {
+			return strings.Replace(s, repo.CloneURL, repo.SanitizedCloneURL, -1)
+		}
+		return fmt.Errorf("running git %s: %s: %s", sanitize(strings.Join(args, " ")), sanitize(string(out)), err)
+	}
+	return nil
+}
+
+func (c *CloneCache) mirror(repoID string) *repoMirror {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mirrors == nil {
+		c.mirrors = make(map[string]*repoMirror)
+	}
+	m, ok := c.mirrors[repoID]
+	if !ok {
+		m = &repoMirror{inUse: make(map[string]int)}
+		c.mirrors[repoID] = m
+	}
+	return m
+}
+
+func (c *CloneCache) repoDir(repo models.Repo) string {
+	return filepath.Join(c.DataDir, CloneCacheDir, repo.ID())
+}
+
+func (c *CloneCache) count(repo models.Repo, result string) {
+	if c.Requests != nil {
+		c.Requests.Inc(repo.FullName, result)
+	}
+}
+
+func (c *CloneCache) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
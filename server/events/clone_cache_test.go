@@ -0,0 +1,173 @@
+package events_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var cacheRepo = models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+
+func cloneCacheRequests(t *testing.T, registry *metrics.Registry) map[string]float64 {
+	families, err := registry.Gather()
+	Ok(t, err)
+	counts := make(map[string]float64)
+	for _, s := range families[0].Samples {
+		counts[s.Labels[1]] = s.Value
+	}
+	return counts
+}
+
+func TestCloneCache_Reference(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	now := time.Unix(1600000000, 0)
+	registry := metrics.NewRegistry()
+	cache := &events.CloneCache{
+		DataDir:       dataDir,
+		FetchInterval: time.Minute,
+		Requests:      registry.NewCounter("atlantis_clone_cache_requests", "", "repo", "result"),
+		Now:           func() time.Time { return now },
+	}
+	log := logging.NewNoopLogger()
+	cloneURL := fmt.Sprintf("file://%s", repoDir)
+
+	mirror, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Equals(t, filepath.Join(dataDir, events.CloneCacheDir, "github.com", "owner", "repo", fmt.Sprintf("%d.git", now.UnixNano())), mirror)
+	initialCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+	Equals(t, initialCommit, runCmd(t, mirror, "git", "rev-parse", "master"))
+
+	// Within the fetch interval the mirror is used as is.
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", "second commit")
+	now = now.Add(30 * time.Second)
+	_, release, err = cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Equals(t, initialCommit, runCmd(t, mirror, "git", "rev-parse", "master"))
+
+	// After it, the mirror is fetched.
+	now = now.Add(time.Minute)
+	fetched, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Equals(t, mirror, fetched)
+	Equals(t, runCmd(t, repoDir, "git", "rev-parse", "HEAD"), runCmd(t, mirror, "git", "rev-parse", "master"))
+
+	Equals(t, map[string]float64{"create": 1, "hit": 1, "fetch": 1}, cloneCacheRequests(t, registry))
+}
+
+// Test that old mirrors are replaced after MaxAge and deleted once no clones
+// reference them.
+func TestCloneCache_MaxAge(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	now := time.Unix(1600000000, 0)
+	cache := &events.CloneCache{
+		DataDir:       dataDir,
+		FetchInterval: time.Minute,
+		MaxAge:        time.Hour,
+		Now:           func() time.Time { return now },
+	}
+	log := logging.NewNoopLogger()
+	cloneURL := fmt.Sprintf("file://%s", repoDir)
+
+	first, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	// A pull request's clone references the first mirror.
+	pullClone := filepath.Join(dataDir, events.WorkingDirPrefix, "owner", "repo", "1", "default")
+	runCmd(t, dataDir, "git", "clone", "--reference", first, cloneURL, pullClone)
+
+	now = now.Add(time.Hour)
+	second, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Assert(t, first != second, "expected a new mirror after the max age")
+	_, err = os.Stat(first)
+	Ok(t, err)
+
+	// Once the pull request's clone is deleted, the first mirror is deleted
+	// the next time the mirror is fetched.
+	Ok(t, os.RemoveAll(pullClone))
+	now = now.Add(time.Minute)
+	_, release, err = cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	_, err = os.Stat(first)
+	Assert(t, os.IsNotExist(err), "expected old mirror to be deleted, got %v", err)
+	_, err = os.Stat(second)
+	Ok(t, err)
+}
+
+// Test that a mirror that can't be fetched is replaced.
+func TestCloneCache_ReplacesBrokenMirror(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	now := time.Unix(1600000000, 0)
+	cache := &events.CloneCache{
+		DataDir: dataDir,
+		Now:     func() time.Time { return now },
+	}
+	log := logging.NewNoopLogger()
+	cloneURL := fmt.Sprintf("file://%s", repoDir)
+
+	first, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Ok(t, ioutil.WriteFile(filepath.Join(first, "HEAD"), []byte("garbage"), 0600))
+
+	now = now.Add(time.Second)
+	second, release, err := cache.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Assert(t, first != second, "expected a new mirror")
+	Equals(t, runCmd(t, repoDir, "git", "rev-parse", "HEAD"), runCmd(t, second, "git", "rev-parse", "master"))
+	_, err = os.Stat(first)
+	Assert(t, os.IsNotExist(err), "expected broken mirror to be deleted, got %v", err)
+}
+
+// Test that the latest mirror on disk is used after a restart.
+func TestCloneCache_ReusesMirrorOnDisk(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	log := logging.NewNoopLogger()
+	cloneURL := fmt.Sprintf("file://%s", repoDir)
+	first, release, err := (&events.CloneCache{DataDir: dataDir}).Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+
+	registry := metrics.NewRegistry()
+	restarted := &events.CloneCache{
+		DataDir:  dataDir,
+		Requests: registry.NewCounter("atlantis_clone_cache_requests", "", "repo", "result"),
+	}
+	second, release, err := restarted.Reference(log, cacheRepo, cloneURL)
+	Ok(t, err)
+	release()
+	Equals(t, first, second)
+	Equals(t, map[string]float64{"fetch": 1}, cloneCacheRequests(t, registry))
+	Equals(t, "0\n", runCmd(t, second, "git", "config", "gc.auto"))
+}
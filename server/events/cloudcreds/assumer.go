@@ -0,0 +1,165 @@
+// Package cloudcreds assumes the cloud identities that projects are
+// configured with in the server-side repo config, so each project's steps
+// run with short-lived credentials instead of the server's own.
+package cloudcreds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCPIAMCredentialsURL is the URL of Google Cloud's IAM credentials API, which
+// generates the access tokens of impersonated service accounts.
+const GCPIAMCredentialsURL = "https://iamcredentials.googleapis.com"
+
+// gcpCloudPlatformScope is the scope of the tokens we generate. What they can
+// do is limited by the service account's roles.
+const gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Assumer assumes AWS IAM roles and impersonates Google Cloud service accounts
+// with the server's own credentials. The server's identity needs to be
+// allowed to do so, ex. by the role's trust policy or the service account's
+// roles/iam.serviceAccountTokenCreator binding.
+type Assumer struct {
+	// STS assumes AWS roles. If nil, a client that uses the server's AWS
+	// credentials is created the first time a role is assumed.
+	STS stsiface.STSAPI
+	// GCPTokenSource is the server's own Google Cloud credentials. If nil,
+	// the application default credentials are used.
+	GCPTokenSource oauth2.TokenSource
+	// GCPURL is the URL of the IAM credentials API. If empty,
+	// GCPIAMCredentialsURL is used.
+	GCPURL string
+
+	mu sync.Mutex
+}
+
+// Assume returns the env vars that Terraform and the cloud CLIs read
+// credentials from.
+func (a *Assumer) Assume(ctx models.ProjectCommandContext, cred valid.CloudCredential) (map[string]string, error) {
+	envs := make(map[string]string)
+	if cred.AWSRoleARN != "" {
+		if err := a.assumeAWSRole(ctx, cred, envs); err != nil {
+			return nil, err
+		}
+	}
+	if cred.GCPServiceAccount != "" {
+		if err := a.impersonateGCPServiceAccount(ctx, cred, envs); err != nil {
+			return nil, err
+		}
+	}
+	return envs, nil
+}
+
+func (a *Assumer) assumeAWSRole(ctx models.ProjectCommandContext, cred valid.CloudCredential, envs map[string]string) error {
+	client, err := a.stsClient()
+	if err != nil {
+		return err
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn: aws.String(cred.AWSRoleARN),
+		// The session name is in CloudTrail so it's the project's ID, which
+		// can be looked up in the Atlantis audit log.
+		RoleSessionName: aws.String("atlantis-" + ctx.ProjectID()),
+		DurationSeconds: aws.Int64(int64(cred.Duration.Seconds())),
+	}
+	if cred.AWSExternalID != "" {
+		input.ExternalId = aws.String(cred.AWSExternalID)
+	}
+	out, err := client.AssumeRole(input)
+	if err != nil {
+		return errors.Wrapf(err, "assuming AWS role %s", cred.AWSRoleARN)
+	}
+	envs["AWS_ACCESS_KEY_ID"] = aws.StringValue(out.Credentials.AccessKeyId)
+	envs["AWS_SECRET_ACCESS_KEY"] = aws.StringValue(out.Credentials.SecretAccessKey)
+	envs["AWS_SESSION_TOKEN"] = aws.StringValue(out.Credentials.SessionToken)
+	ctx.Log.Info("assumed AWS role %s until %s", cred.AWSRoleARN, aws.TimeValue(out.Credentials.Expiration))
+	return nil
+}
+
+func (a *Assumer) stsClient() (stsiface.STSAPI, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.STS == nil {
+		sess, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "creating AWS session")
+		}
+		a.STS = sts.New(sess)
+	}
+	return a.STS, nil
+}
+
+func (a *Assumer) impersonateGCPServiceAccount(ctx models.ProjectCommandContext, cred valid.CloudCredential, envs map[string]string) error {
+	tokenSource, err := a.gcpTokenSource()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"scope":    []string{gcpCloudPlatformScope},
+		"lifetime": fmt.Sprintf("%ds", int64(cred.Duration.Seconds())),
+	})
+	if err != nil {
+		return err
+	}
+	baseURL := a.GCPURL
+	if baseURL == "" {
+		baseURL = GCPIAMCredentialsURL
+	}
+	reqURL := fmt.Sprintf("%s/v1/projects/-/serviceAccounts/%s:generateAccessToken", baseURL, url.PathEscape(cred.GCPServiceAccount))
+	resp, err := oauth2.NewClient(context.Background(), tokenSource).Post(reqURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "impersonating Google Cloud service account %s", cred.GCPServiceAccount)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "impersonating Google Cloud service account %s", cred.GCPServiceAccount)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("impersonating Google Cloud service account %s: %s: %s", cred.GCPServiceAccount, resp.Status, bytes.TrimSpace(respBody))
+	}
+	var token struct {
+		AccessToken string `json:"accessToken"`
+		ExpireTime  string `json:"expireTime"`
+	}
+	if err := json.Unmarshal(respBody, &token); err != nil {
+		return errors.Wrapf(err, "parsing access token of Google Cloud service account %s", cred.GCPServiceAccount)
+	}
+	// The Google provider reads the first and gcloud the second.
+	envs["GOOGLE_OAUTH_ACCESS_TOKEN"] = token.AccessToken
+	envs["CLOUDSDK_AUTH_ACCESS_TOKEN"] = token.AccessToken
+	ctx.Log.Info("impersonated Google Cloud service account %s until %s", cred.GCPServiceAccount, token.ExpireTime)
+	return nil
+}
+
+func (a *Assumer) gcpTokenSource() (oauth2.TokenSource, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.GCPTokenSource == nil {
+		tokenSource, err := google.DefaultTokenSource(context.Background(), gcpCloudPlatformScope)
+		if err != nil {
+			return nil, errors.Wrap(err, "finding Google Cloud credentials")
+		}
+		a.GCPTokenSource = tokenSource
+	}
+	return a.GCPTokenSource, nil
+}
@@ -0,0 +1,121 @@
+package cloudcreds_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/runatlantis/atlantis/server/events/cloudcreds"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+	"golang.org/x/oauth2"
+)
+
+// fakeSTS records the roles that are assumed.
+type fakeSTS struct {
+	stsiface.STSAPI
+	inputs []*sts.AssumeRoleInput
+	err    error
+}
+
+func (f *fakeSTS) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, input)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("ASIAEXAMPLE"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("session"),
+			Expiration:      aws.Time(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}, nil
+}
+
+func projectCtx() models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		RepoRelDir: "path",
+		Workspace:  "default",
+		Log:        logging.NewNoopLogger(),
+	}
+}
+
+func TestAssumer_AWS(t *testing.T) {
+	fake := &fakeSTS{}
+	a := &cloudcreds.Assumer{STS: fake}
+	envs, err := a.Assume(projectCtx(), valid.CloudCredential{
+		AWSRoleARN:    "arn:aws:iam::111111111111:role/atlantis",
+		AWSExternalID: "external",
+		Duration:      30 * time.Minute,
+	})
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "ASIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "session",
+	}, envs)
+	Equals(t, []*sts.AssumeRoleInput{{
+		RoleArn:         aws.String("arn:aws:iam::111111111111:role/atlantis"),
+		RoleSessionName: aws.String("atlantis-445faa5827ef29e9"),
+		DurationSeconds: aws.Int64(1800),
+		ExternalId:      aws.String("external"),
+	}}, fake.inputs)
+
+	fake.err = errors.New("AccessDenied")
+	_, err = a.Assume(projectCtx(), valid.CloudCredential{AWSRoleARN: "arn:aws:iam::111111111111:role/atlantis", Duration: time.Hour})
+	ErrEquals(t, "assuming AWS role arn:aws:iam::111111111111:role/atlantis: AccessDenied", err)
+}
+
+func TestAssumer_GCP(t *testing.T) {
+	var gotAuth, gotPath string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotPath = r.URL.EscapedPath()
+		Ok(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		if gotBody["lifetime"] == "60s" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintln(w, `{"error": "denied"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"accessToken": "ya29.token", "expireTime": "2020-01-01T00:00:00Z"}`)
+	}))
+	defer server.Close()
+
+	a := &cloudcreds.Assumer{
+		GCPTokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "server-token"}),
+		GCPURL:         server.URL,
+	}
+	envs, err := a.Assume(projectCtx(), valid.CloudCredential{
+		GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com",
+		Duration:          time.Hour,
+	})
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"GOOGLE_OAUTH_ACCESS_TOKEN":  "ya29.token",
+		"CLOUDSDK_AUTH_ACCESS_TOKEN": "ya29.token",
+	}, envs)
+	Equals(t, "Bearer server-token", gotAuth)
+	Equals(t, "/v1/projects/-/serviceAccounts/atlantis@project.iam.gserviceaccount.com:generateAccessToken", gotPath)
+	Equals(t, map[string]interface{}{
+		"scope":    []interface{}{"https://www.googleapis.com/auth/cloud-platform"},
+		"lifetime": "3600s",
+	}, gotBody)
+
+	_, err = a.Assume(projectCtx(), valid.CloudCredential{
+		GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com",
+		Duration:          time.Minute,
+	})
+	ErrEquals(t, `impersonating Google Cloud service account atlantis@project.iam.gserviceaccount.com: 403 Forbidden: {"error": "denied"}`, err)
+}
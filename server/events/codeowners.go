@@ -0,0 +1,138 @@
+package events
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CodeownersPaths are where we look for a CODEOWNERS file, relative to the
+// repo root, in order. They're the locations GitHub and GitLab support.
+var CodeownersPaths = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+}
+
+// Codeowners are the rules of a CODEOWNERS file.
+type Codeowners struct {
+	// Path is the path of the file relative to the repo root.
+	Path  string
+	rules []codeownersRule
+}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// FindCodeowners reads the first CODEOWNERS file in CodeownersPaths from the
+// repo cloned to repoDir. It returns nil if the repo doesn't have one.
+func FindCodeowners(repoDir string) (*Codeowners, error) {
+	for _, relPath := range CodeownersPaths {
+		f, err := os.Open(filepath.Join(repoDir, relPath))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening %s", relPath)
+		}
+		defer f.Close() // nolint: errcheck
+		c, err := ParseCodeowners(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", relPath)
+		}
+		c.Path = relPath
+		return c, nil
+	}
+	return nil, nil
+}
+
+// ParseCodeowners parses a CODEOWNERS file. Each line is a path pattern
+// followed by its owners, ex. "/production/ @alice @org/ops". GitLab section
+// headers, ex. "[Terraform]", are ignored so every section's rules are used
+// as if they were in one list.
+func ParseCodeowners(r io.Reader) (*Codeowners, error) {
+	c := &Codeowners{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		pattern, err := codeownersPatternRegex(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing pattern %q", fields[0])
+		}
+		var owners []string
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, strings.TrimPrefix(owner, "@"))
+		}
+		c.rules = append(c.rules, codeownersRule{pattern: pattern, owners: owners})
+	}
+	return c, errors.Wrap(scanner.Err(), "reading")
+}
+
+// Owners returns the owners of the file at relPath, relative to the repo
+// root, without their @ prefix. The last rule that matches the file wins. It
+// returns nil if the file doesn't have owners.
+func (c *Codeowners) Owners(relPath string) []string {
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+	var owners []string
+	for _, rule := range c.rules {
+		if rule.pattern.MatchString(relPath) {
+			owners = rule.owners
+		}
+	}
+	return owners
+}
+
+// codeownersPatternRegex converts a CODEOWNERS pattern, which uses gitignore
+// syntax, to a regex that matches the paths of the files it applies to.
+func codeownersPatternRegex(pattern string) (*regexp.Regexp, error) {
+	// Patterns that start with or contain a slash are relative to the repo
+	// root. Others match at any depth.
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	// A pattern that matches a directory applies to everything in it.
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
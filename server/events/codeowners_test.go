@@ -0,0 +1,74 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCodeowners_Owners(t *testing.T) {
+	codeowners, err := events.ParseCodeowners(strings.NewReader(`
+# Everything is owned by the platform team unless a later rule matches.
+*                 @org/platform
+*.md              @docs-writer
+
+[Terraform]
+/production/      @alice @org/ops # Production needs ops.
+staging/*.tf      @bob
+modules/**/iam    @security@example.com
+tfvars            @carol
+`))
+	Ok(t, err)
+
+	cases := []struct {
+		path      string
+		expOwners []string
+	}{
+		{"main.tf", []string{"org/platform"}},
+		{"README.md", []string{"docs-writer"}},
+		{"docs/guide.md", []string{"docs-writer"}},
+		{"production/main.tf", []string{"alice", "org/ops"}},
+		{"production/nested/main.tf", []string{"alice", "org/ops"}},
+		{"other/production/main.tf", []string{"org/platform"}},
+		{"staging/main.tf", []string{"bob"}},
+		{"staging/nested/main.tf", []string{"org/platform"}},
+		{"modules/iam/main.tf", []string{"security@example.com"}},
+		{"modules/a/b/iam/main.tf", []string{"security@example.com"}},
+		{"envs/tfvars/prod.tfvars", []string{"carol"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			Equals(t, c.expOwners, codeowners.Owners(c.path))
+		})
+	}
+}
+
+func TestCodeowners_OwnersNoMatch(t *testing.T) {
+	codeowners, err := events.ParseCodeowners(strings.NewReader("/production/ @alice\n"))
+	Ok(t, err)
+	Equals(t, []string(nil), codeowners.Owners("staging/main.tf"))
+}
+
+func TestFindCodeowners(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	codeowners, err := events.FindCodeowners(tmp)
+	Ok(t, err)
+	Assert(t, codeowners == nil, "expected no code owners when there's no file")
+
+	// Files in .github take precedence over the root.
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "CODEOWNERS"), []byte("* @root\n"), 0600))
+	Ok(t, os.Mkdir(filepath.Join(tmp, ".github"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, ".github", "CODEOWNERS"), []byte("* @github\n"), 0600))
+
+	codeowners, err = events.FindCodeowners(tmp)
+	Ok(t, err)
+	Equals(t, ".github/CODEOWNERS", codeowners.Path)
+	Equals(t, []string{"github"}, codeowners.Owners("main.tf"))
+}
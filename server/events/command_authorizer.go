@@ -0,0 +1,126 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// CommandAuthorizationRequest is POSTed to the command authorizer before a
+// command is run for a project.
+type CommandAuthorizationRequest struct {
+	Command   string `json:"command"`
+	User      string `json:"user"`
+	Repo      string `json:"repo"`
+	Pull      int    `json:"pull"`
+	PullURL   string `json:"pull_url"`
+	Author    string `json:"author"`
+	HeadSHA   string `json:"head_sha"`
+	Branch    string `json:"branch"`
+	Base      string `json:"base_branch"`
+	Project   string `json:"project"`
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+}
+
+// CommandAuthorizationResponse is the command authorizer's decision.
+type CommandAuthorizationResponse struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+// CommandAuthorizer asks an external endpoint, ex. a change management
+// system, whether commands can be run before they are. Commands are denied if
+// the endpoint can't be reached or doesn't respond with a decision.
+type CommandAuthorizer struct {
+	URL string
+	// Token, if set, is sent in the Authorization header as a bearer token.
+	Token  string
+	Client *http.Client
+}
+
+// Authorize returns whether cmdName can be run for the project in ctx and
+// the reason the authorizer gave.
+func (a *CommandAuthorizer) Authorize(ctx models.ProjectCommandContext, cmdName models.CommandName) (bool, string, error) {
+	serialized, err := json.Marshal(CommandAuthorizationRequest{
+		Command:   commandDisplayName(ctx, cmdName),
+		User:      ctx.User.Username,
+		Repo:      ctx.BaseRepo.FullName,
+		Pull:      ctx.Pull.Num,
+		PullURL:   ctx.Pull.URL,
+		Author:    ctx.Pull.Author,
+		HeadSHA:   ctx.Pull.HeadCommit,
+		Branch:    ctx.Pull.HeadBranch,
+		Base:      ctx.Pull.BaseBranch,
+		Project:   ctx.ProjectName,
+		Dir:       ctx.RepoRelDir,
+		Workspace: ctx.Workspace,
+	})
+	if err != nil {
+		return false, "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.URL, bytes.NewReader(serialized))
+	if err != nil {
+		return false, "", errors.Wrap(err, "creating authorization request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, "", errors.Wrap(err, "posting authorization request")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, "", fmt.Errorf("command authorizer responded with status %d: %s", resp.StatusCode, body)
+	}
+	var decision CommandAuthorizationResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&decision); err != nil {
+		return false, "", errors.Wrap(err, "parsing command authorizer response")
+	}
+	return decision.Allow, decision.Reason, nil
+}
+
+// commandDisplayName returns the name of cmdName as it's commented, ex.
+// "plan" or the name of a custom command.
+func commandDisplayName(ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	if cmdName == models.CustomCommand && ctx.CustomCommand != nil {
+		return ctx.CustomCommand.Name
+	}
+	return cmdName.String()
+}
+
+// authorizationFailure returns why cmdName can't be run for the project in
+// ctx because the command authorizer denied it, or an empty string if it can
+// or there's no authorizer.
+func (p *DefaultProjectCommandRunner) authorizationFailure(ctx models.ProjectCommandContext, cmdName models.CommandName) (string, error) {
+	if p.Authorizer == nil {
+		return "", nil
+	}
+	allowed, reason, err := p.Authorizer.Authorize(ctx, cmdName)
+	if err != nil {
+		return "", errors.Wrap(err, "authorizing command")
+	}
+	if allowed {
+		return "", nil
+	}
+	ctx.Log.Info("command authorizer denied %s by %s: %s", commandDisplayName(ctx, cmdName), ctx.User.Username, reason)
+	failure := fmt.Sprintf("The command authorizer denied @%s's `%s`.", ctx.User.Username, commandDisplayName(ctx, cmdName))
+	if reason != "" {
+		failure += fmt.Sprintf(" Reason: %s", reason)
+	}
+	return failure, nil
+}
@@ -0,0 +1,142 @@
+package events_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommandAuthorizer_Authorize(t *testing.T) {
+	var got events.CommandAuthorizationRequest
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		Ok(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"allow": false, "reason": "CHG0001234 isn't approved yet."}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	authorizer := &events.CommandAuthorizer{URL: server.URL, Token: "token"}
+	ctx := models.ProjectCommandContext{
+		User:        models.User{Username: "alice"},
+		BaseRepo:    models.Repo{FullName: "owner/repo"},
+		Pull:        models.PullRequest{Num: 1, URL: "https://github.com/owner/repo/pull/1", Author: "bob", HeadCommit: "abc123", HeadBranch: "branch", BaseBranch: "master"},
+		ProjectName: "production",
+		RepoRelDir:  "production",
+		Workspace:   "default",
+	}
+	allowed, reason, err := authorizer.Authorize(ctx, models.ApplyCommand)
+	Ok(t, err)
+	Equals(t, false, allowed)
+	Equals(t, "CHG0001234 isn't approved yet.", reason)
+	Equals(t, "Bearer token", gotAuth)
+	Equals(t, events.CommandAuthorizationRequest{
+		Command:   "apply",
+		User:      "alice",
+		Repo:      "owner/repo",
+		Pull:      1,
+		PullURL:   "https://github.com/owner/repo/pull/1",
+		Author:    "bob",
+		HeadSHA:   "abc123",
+		Branch:    "branch",
+		Base:      "master",
+		Project:   "production",
+		Dir:       "production",
+		Workspace: "default",
+	}, got)
+
+	ctx.CustomCommand = &valid.CustomCommand{Name: "drift"}
+	_, _, err = authorizer.Authorize(ctx, models.CustomCommand)
+	Ok(t, err)
+	Equals(t, "drift", got.Command)
+}
+
+func TestCommandAuthorizer_AuthorizeErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		status      int
+		body        string
+		expErr      string
+	}{
+		{
+			"error status",
+			http.StatusInternalServerError,
+			"oops",
+			"command authorizer responded with status 500: oops",
+		},
+		{
+			"invalid response",
+			http.StatusOK,
+			"allow",
+			"parsing command authorizer response: invalid character 'a' looking for beginning of value",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				w.Write([]byte(c.body)) // nolint: errcheck
+			}))
+			defer server.Close()
+
+			authorizer := &events.CommandAuthorizer{URL: server.URL}
+			allowed, _, err := authorizer.Authorize(models.ProjectCommandContext{}, models.PlanCommand)
+			ErrEquals(t, c.expErr, err)
+			Equals(t, false, allowed)
+		})
+	}
+}
+
+func TestDefaultProjectCommandRunner_Authorizer(t *testing.T) {
+	RegisterMockTestingT(t)
+	allow := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(events.CommandAuthorizationResponse{Allow: allow, Reason: "Outside the change window."}) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := events.DefaultProjectCommandRunner{
+		ApplyStepRunner:  mockApply,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+		Authorizer:       &events.CommandAuthorizer{URL: server.URL},
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("apply", nil)
+
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		Steps:      []valid.Step{{StepName: "apply"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		User:       models.User{Username: "alice"},
+	}
+	res := runner.Apply(ctx)
+	Equals(t, "The command authorizer denied @alice's `apply`. Reason: Outside the change window.", res.Failure)
+	mockApply.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+
+	allow = true
+	res = runner.Apply(ctx)
+	Equals(t, "", res.Failure)
+	Equals(t, "apply", res.ApplySuccess)
+
+	// Commands fail if the authorizer can't be asked.
+	server.Close()
+	res = runner.Apply(ctx)
+	Assert(t, res.Error != nil, "exp error")
+}
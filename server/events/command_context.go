@@ -16,6 +16,7 @@ package events
 import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
 )
 
 // CommandContext represents the context of a command that should be executed
@@ -37,4 +38,14 @@ type CommandContext struct {
 	// set our own build statuses which can affect mergeability if users have
 	// required the Atlantis status to be successful prior to merging.
 	PullMergeable bool
+	// CommentID is the ID of the comment that triggered this command or 0 if
+	// it wasn't triggered by a comment, ex. autoplan.
+	CommentID int64
+	// Span, if set, is the span of the command. Its work, ex. cloning and
+	// running each project, is traced as its children.
+	Span *tracing.Span
+	// Done, if set, is closed once the command is cancelled, ex. because a
+	// newer commit was pushed to Pull. Its running steps are stopped and the
+	// rest of its projects are skipped.
+	Done <-chan struct{}
 }
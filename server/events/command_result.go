@@ -24,6 +24,23 @@ type CommandResult struct {
 	// deleted. This happens if automerging is enabled and one project has an
 	// error since automerging requires all plans to succeed.
 	PlansDeleted bool
+	// SuggestedReviewers are the owners of the projects that were run, not
+	// including the pull request's author.
+	SuggestedReviewers []string
+	// CustomCommand is the name of the custom command that was run, if any.
+	CustomCommand string
+	// NoChangesResults are the results of plans without changes that were
+	// removed from ProjectResults so they're not commented.
+	NoChangesResults []models.ProjectResult
+	// SummarizeNoChanges is true if the projects in NoChangesResults should
+	// be listed on a single line.
+	SummarizeNoChanges bool
+	// KeptPlans are the projects whose plans were kept because the commits
+	// pushed since they were planned didn't modify them.
+	KeptPlans []models.ProjectStatus
+	// ReusedPlans are the projects that weren't planned again because
+	// nothing changed since they were planned.
+	ReusedPlans []models.ProjectStatus
 }
 
 // HasErrors returns true if there were any errors during the execution,
@@ -15,15 +15,26 @@ package events
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v28/github"
 	"github.com/mcdafydd/go-azuredevops/azuredevops"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/eventbus"
+	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 	"github.com/runatlantis/atlantis/server/recovery"
+	"github.com/runatlantis/atlantis/server/tracing"
 	gitlab "github.com/xanzy/go-gitlab"
 )
 
@@ -54,6 +65,33 @@ type AzureDevopsPullGetter interface {
 	GetPullRequest(repo models.Repo, pullNum int) (*azuredevops.GitPullRequest, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_reviewer_requester.go ReviewerRequester
+
+// ReviewerRequester requests reviews on pull requests.
+type ReviewerRequester interface {
+	// RequestReviewers requests reviews of pull from reviewers.
+	RequestReviewers(repo models.Repo, pull models.PullRequest, reviewers []string) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_insights_reporter.go InsightsReporter
+
+// InsightsReporter creates reports on commits that are shown alongside the
+// pull request's diff.
+type InsightsReporter interface {
+	// CreateInsightsReport creates the report with key on the head commit of
+	// pull, replacing any existing report with the same key.
+	CreateInsightsReport(repo models.Repo, pull models.PullRequest, key string, report models.InsightsReport) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_comment_reactor.go CommentReactor
+
+// CommentReactor reacts to comments with emoji.
+type CommentReactor interface {
+	// ReactToComment adds the emoji for reaction to the comment with
+	// commentID on the pull request.
+	ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction models.CommentReaction) error
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_gitlab_merge_request_getter.go GitlabMergeRequestGetter
 
 // GitlabMergeRequestGetter makes API calls to get merge requests.
@@ -62,12 +100,26 @@ type GitlabMergeRequestGetter interface {
 	GetMergeRequest(repoFullName string, pullNum int) (*gitlab.MergeRequest, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_pull_head_repo_getter.go PullHeadRepoGetter
+
+// PullHeadRepoGetter makes API calls to get the repo that pull requests'
+// changes are from.
+type PullHeadRepoGetter interface {
+	// GetPullHeadRepo gets the full name and clone URL of the head repo of
+	// the pull request with id pullNum for the repo.
+	GetPullHeadRepo(repo models.Repo, pullNum int) (fullName string, cloneURL string, err error)
+}
+
 // DefaultCommandRunner is the first step when processing a comment command.
 type DefaultCommandRunner struct {
 	VCSClient                vcs.Client
 	GithubPullGetter         GithubPullGetter
 	AzureDevopsPullGetter    AzureDevopsPullGetter
 	GitlabMergeRequestGetter GitlabMergeRequestGetter
+	// PullHeadRepoGetters look up the head repos of GitLab and Bitbucket pull
+	// requests, by VCS host type, when commands aren't run from comment
+	// webhooks, which include them.
+	PullHeadRepoGetters map[models.VCSHostType]PullHeadRepoGetter
 	CommitStatusUpdater      CommitStatusUpdater
 	DisableApplyAll          bool
 	EventParser              EventParsing
@@ -90,32 +142,166 @@ type DefaultCommandRunner struct {
 	// SilenceVCSStatusNoPlans is whether autoplan should set commit status if no plans
 	// are found
 	SilenceVCSStatusNoPlans bool
-	ProjectCommandBuilder   ProjectCommandBuilder
-	ProjectCommandRunner    ProjectCommandRunner
+	// SilenceNoChanges is how the results of plans without changes are
+	// posted, one of the valid.SilenceNoChanges* constants, unless a repo's
+	// atlantis.yaml overrides it. If empty, they're posted like any other.
+	SilenceNoChanges      string
+	ProjectCommandBuilder ProjectCommandBuilder
+	ProjectCommandRunner  ProjectCommandRunner
 	// GlobalAutomerge is true if we should automatically merge pull requests if all
 	// plans have been successfully applied. This is set via a CLI flag.
 	GlobalAutomerge   bool
 	PendingPlanFinder PendingPlanFinder
 	WorkingDir        WorkingDir
-	DB                *db.BoltDB
+	DB                db.PullStatusStore
+	// WorkingDirPrewarmer, if set, is used after autoplan to initialize
+	// the projects that weren't autoplanned so later plan commands are
+	// faster.
+	WorkingDirPrewarmer WorkingDirPrewarmer
+	// AuditLogger, if set, records each project that's planned or applied.
+	AuditLogger AuditLogger
+	// SecretScanner, if set, scans the pull request's modified files before
+	// planning. If it finds any secrets, plan fails for every project.
+	SecretScanner SecretScanner
+	// ReviewerRequester, if set, is used to request reviews from the owners
+	// of the projects that are planned in GitHub pull requests. Owners are
+	// always suggested in the plan comment.
+	ReviewerRequester ReviewerRequester
+	// GlobalCfg is the server-side repo config. It's used to ignore pull
+	// requests whose base branch isn't allowed.
+	GlobalCfg valid.GlobalCfg
+	// GlobalCfgStore, if set, holds the server-side repo config instead of
+	// GlobalCfg so it can be reloaded.
+	GlobalCfgStore *GlobalCfgStore
+	// LoadShedder, if set, counts the commands in flight and is used to
+	// reject new autoplans when the server is overloaded.
+	LoadShedder *LoadShedder
+	// RunningCommands, if set, tracks the commands that are in progress and
+	// the project each is running on.
+	RunningCommands *RunningCommands
+	// AutoplanDebouncer, if set, delays autoplans until their pull request
+	// stops being pushed to and cancels the autoplans of superseded commits.
+	AutoplanDebouncer *AutoplanDebouncer
+	// ProjectCommitStatuses is true if a commit status should be set for
+	// each project that's planned or applied, in addition to any aggregate
+	// status set by the CommitStatusUpdater.
+	ProjectCommitStatuses bool
+	// StatusProgressInterval is how often the pending commit statuses of
+	// running plans and applies are updated with their progress. If 0,
+	// progress isn't reported.
+	StatusProgressInterval time.Duration
+	// FailureTracker, if set, classifies and counts the errors of each
+	// command.
+	FailureTracker *FailureTracker
+	// ProjectCommands and ProjectCommandDuration, if set, count each project
+	// that's planned or applied and how long it took by repo, command and
+	// result: success, failure or error.
+	ProjectCommands        *metrics.Counter
+	ProjectCommandDuration *metrics.Timer
+	// CommentRateLimiter, if set, limits how many comment commands each
+	// user can run on a pull request.
+	CommentRateLimiter *CommentRateLimiter
+	// LockingHealth, if set, is checked before commands are run so they
+	// don't fail halfway through when the locking backend is unavailable.
+	LockingHealth *locking.HealthChecker
+	// LockingOutageWait is how long commands are held for the locking
+	// backend to recover before they're rejected. If 0, they're rejected
+	// right away.
+	LockingOutageWait time.Duration
+	// SummaryComment is true if a comment summarizing every project in the
+	// pull request should be posted after autoplan or a plan of all
+	// projects.
+	SummaryComment bool
+	// InsightsReporter, if set, is used to create a report of each
+	// project's plan on Bitbucket Server pull requests.
+	InsightsReporter InsightsReporter
+	// CommentReactors, if set, react to comment commands on the VCS hosts
+	// they're keyed by to show the command was received, started and
+	// whether it succeeded, before the output is commented.
+	CommentReactors map[models.VCSHostType]CommentReactor
+	// Locker, if set, is used by the status command to list the locks held
+	// on each project.
+	Locker locking.Locker
+	// PullStatusCheck is true if a status should be set that only succeeds
+	// once every project in the pull request has been applied or has no
+	// changes, so branch protection can prevent merging pull requests that
+	// are only partly applied.
+	PullStatusCheck bool
+	// PlanInvalidator, if set, is used when new commits are pushed to only
+	// discard the plans of the projects they modify. Otherwise every plan
+	// is discarded.
+	PlanInvalidator *PlanInvalidator
+	// PlanDiscarder, if set, runs the discard command.
+	PlanDiscarder *PlanDiscarder
+	// ReusePlans is true if projects aren't planned again when their plans
+	// were made from the pull request's current head and base commits with
+	// the same flags.
+	ReusePlans bool
+	// Tracer, if set, records a trace of each command: building the project
+	// commands, including cloning, each project's steps and the VCS API
+	// calls made while it runs.
+	Tracer *tracing.Tracer
+	// EventPublisher, if set, publishes an event when each project starts
+	// and finishes being planned or applied and when secrets stop it from
+	// being planned.
+	EventPublisher *eventbus.Publisher
+	// QueuedApplies, if set, runs the applies of projects that were queued
+	// until their apply windows open. If nil, they aren't run.
+	QueuedApplies *QueuedApplies
+	// ResultsExporter, if set, exports the results of each plan command as
+	// SARIF or JUnit XML.
+	ResultsExporter *ResultsExporter
+	// SlackApprovals, if set, posts the plans of each plan command to Slack
+	// so they can be approved and applied from there.
+	SlackApprovals *SlackApprovals
 }
 
 // RunAutoplanCommand runs plan when a pull request is opened or updated.
 func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
 	log := c.buildLogger(baseRepo.FullName, pull.Num)
 	defer c.logPanics(baseRepo, pull.Num, log)
+	span := c.Tracer.StartPull("autoplan", baseRepo.FullName, pull.Num, tracing.String("user", user.Username))
+	defer span.End()
 	ctx := &CommandContext{
 		User:     user,
 		Log:      log,
 		Pull:     pull,
 		HeadRepo: headRepo,
 		BaseRepo: baseRepo,
+		Span:     span,
+	}
+	if !c.globalCfg().BranchMatches(baseRepo.ID(), pull.BaseBranch) {
+		log.Info("ignoring pull request into branch %q because it isn't allowed by the server-side repo config's %s key", pull.BaseBranch, valid.BranchKey)
+		return
+	}
+	superseded, finish, ok := c.AutoplanDebouncer.Wait(baseRepo, pull)
+	if !ok {
+		log.Info("not autoplanning commit %s because a newer commit was pushed", pull.HeadCommit)
+		return
 	}
+	defer finish()
+	ctx.Done = superseded
 	if !c.validateCtxAndComment(ctx) {
 		return
 	}
+	if reasons := c.LoadShedder.Check(); len(reasons) > 0 {
+		log.Warn("not autoplanning because the server is overloaded: %s", strings.Join(reasons, ", "))
+		if err := c.VCSClient.CreateComment(baseRepo, pull.Num, loadShedComment); err != nil {
+			log.Err("unable to comment on pull request: %s", err)
+		}
+		return
+	}
+	defer c.LoadShedder.Track()()
+	defer c.RunningCommands.Start(ctx, "autoplan")()
+	if !c.lockingBackendAvailable(ctx) {
+		return
+	}
+	// The status has to be read before the new commits are planned.
+	prevStatus := c.prevPullStatus(ctx)
 
+	buildSpan := span.Start("build project commands")
 	projectCmds, err := c.ProjectCommandBuilder.BuildAutoplanCommands(ctx)
+	endSpan(buildSpan, err)
 	if err != nil {
 		if statusErr := c.CommitStatusUpdater.UpdateCombined(ctx.BaseRepo, ctx.Pull, models.FailedCommitStatus, models.PlanCommand); statusErr != nil {
 			ctx.Log.Warn("unable to update commit status: %s", statusErr)
@@ -124,7 +310,12 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		c.updatePull(ctx, AutoplanCommand{}, CommandResult{Error: err})
 		return
 	}
-	if len(projectCmds) == 0 {
+	var keptPlans, reusedPlans []models.ProjectStatus
+	if prevStatus != nil {
+		projectCmds, keptPlans = c.invalidatePlans(ctx, *prevStatus, projectCmds)
+		projectCmds, reusedPlans = c.reusePlans(ctx, *prevStatus, projectCmds)
+	}
+	if len(projectCmds) == 0 && len(keptPlans) == 0 && len(reusedPlans) == 0 {
 		log.Info("determined there was no project to run plan in")
 		if !c.SilenceVCSStatusNoPlans {
 			// If there were no projects modified, we set a successful commit status
@@ -134,7 +325,9 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 			if err := c.CommitStatusUpdater.UpdateCombinedCount(baseRepo, pull, models.SuccessCommitStatus, models.PlanCommand, 0, 0); err != nil {
 				ctx.Log.Warn("unable to update commit status: %s", err)
 			}
+			c.updatePullCommitStatus(ctx, models.PullStatus{})
 		}
+		c.prewarm(ctx)
 		return
 	}
 
@@ -143,19 +336,72 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
 
-	result := c.runProjectCmds(projectCmds, models.PlanCommand)
+	result := c.runPlanCmds(ctx, projectCmds)
+	if isDone(superseded) {
+		// The newer commit's autoplan comments and sets the statuses.
+		log.Info("cancelled autoplan of commit %s because a newer commit was pushed", pull.HeadCommit)
+		return
+	}
+	result.SuggestedReviewers = c.suggestReviewers(ctx, projectCmds)
 	if c.automergeEnabled(ctx, projectCmds) && result.HasErrors() {
 		ctx.Log.Info("deleting plans because there were errors and automerge requires all plans succeed")
 		c.deletePlans(ctx)
 		result.PlansDeleted = true
+	} else {
+		result.KeptPlans = keptPlans
+		result.ReusedPlans = reusedPlans
 	}
-	c.updatePull(ctx, AutoplanCommand{}, result)
-	pullStatus, err := c.updateDB(ctx, ctx.Pull, result.ProjectResults)
+	c.updatePull(ctx, AutoplanCommand{}, c.silenceNoChanges(result, projectCmds))
+	c.exportResults(ctx, models.PlanCommand, result)
+	c.postSlackApprovals(ctx, result)
+	dbResults := append(result.ProjectResults, c.keptPlanResults(ctx, append(result.KeptPlans, result.ReusedPlans...))...)
+	pullStatus, err := c.updateDB(ctx, ctx.Pull, dbResults)
 	if err != nil {
 		c.Logger.Err("writing results: %s", err)
 	}
 
 	c.updateCommitStatus(ctx, models.PlanCommand, pullStatus)
+	c.updatePullCommitStatus(ctx, pullStatus)
+	c.postSummary(ctx, result, pullStatus)
+	c.prewarm(ctx)
+}
+
+// lockingBackendAvailable returns true if the locking backend is healthy or
+// recovers within LockingOutageWait. Otherwise it comments why the command
+// wasn't run and returns false.
+func (c *DefaultCommandRunner) lockingBackendAvailable(ctx *CommandContext) bool {
+	if c.LockingHealth.Status().Healthy {
+		return true
+	}
+	if c.LockingOutageWait > 0 {
+		ctx.Log.Info("waiting up to %s for the locking backend to recover", c.LockingOutageWait)
+	}
+	err := c.LockingHealth.WaitHealthy(c.LockingOutageWait)
+	if err == nil {
+		return true
+	}
+	ctx.Log.Err("not running command because the locking backend is unavailable: %s", err)
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, lockingOutageComment); err != nil {
+		ctx.Log.Err("unable to comment on pull request: %s", err)
+	}
+	return false
+}
+
+// globalCfg returns the current server-side repo config.
+func (c *DefaultCommandRunner) globalCfg() valid.GlobalCfg {
+	if c.GlobalCfgStore != nil {
+		return c.GlobalCfgStore.Get()
+	}
+	return c.GlobalCfg
+}
+
+// prewarm gets the working directories ready for later plan commands if
+// pre-warming is enabled.
+func (c *DefaultCommandRunner) prewarm(ctx *CommandContext) {
+	if c.WorkingDirPrewarmer == nil {
+		return
+	}
+	c.WorkingDirPrewarmer.Prewarm(ctx)
 }
 
 // RunCommentCommand executes the command.
@@ -166,9 +412,29 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
 	log := c.buildLogger(baseRepo.FullName, pullNum)
 	defer c.logPanics(baseRepo, pullNum, log)
+	defer c.LoadShedder.Track()()
+	span := c.Tracer.StartPull("comment command", baseRepo.FullName, pullNum, tracing.String("user", user.Username))
+	defer span.End()
+
+	if res := c.CommentRateLimiter.Allow(baseRepo, pullNum, user); !res.Allowed {
+		log.Warn("ignoring %s command from %s because they've exceeded the comment command rate limit", cmd.Name.String(), user.Username)
+		if res.Notify {
+			if err := c.VCSClient.CreateComment(baseRepo, pullNum, c.CommentRateLimiter.RejectionComment(user, res)); err != nil {
+				log.Err("unable to comment on pull request: %s", err)
+			}
+		}
+		return
+	}
+	var commentID int64
+	if cmd != nil {
+		commentID = cmd.CommentID
+		span.SetAttributes(tracing.String("command", cmd.Name.String()))
+	}
+	c.react(baseRepo, pullNum, commentID, models.ReceivedReaction, log)
 
 	if c.DisableApplyAll && cmd.Name == models.ApplyCommand && !cmd.IsForSpecificProject() {
 		log.Info("ignoring apply command without flags since apply all is disabled")
+		c.react(baseRepo, pullNum, commentID, models.FailedReaction, log)
 		if err := c.VCSClient.CreateComment(baseRepo, pullNum, applyAllDisabledComment); err != nil {
 			log.Err("unable to comment on pull request: %s", err)
 		}
@@ -187,12 +453,18 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		pull, headRepo, err = c.getGithubData(baseRepo, pullNum)
 	case models.Gitlab:
 		pull, err = c.getGitlabData(baseRepo, pullNum)
+		if err == nil && maybeHeadRepo == nil {
+			headRepo, err = c.getHeadRepo(baseRepo, pullNum)
+		}
 	case models.BitbucketCloud, models.BitbucketServer:
 		if maybePull == nil {
 			err = errors.New("pull request should not be nil–this is a bug")
 			break
 		}
 		pull = *maybePull
+		if maybeHeadRepo == nil {
+			headRepo, err = c.getHeadRepo(baseRepo, pullNum)
+		}
 	case models.AzureDevops:
 		pull, headRepo, err = c.getAzureDevopsData(baseRepo, pullNum)
 	default:
@@ -200,19 +472,60 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	}
 	if err != nil {
 		log.Err(err.Error())
+		c.react(baseRepo, pullNum, commentID, models.FailedReaction, log)
 		if commentErr := c.VCSClient.CreateComment(baseRepo, pullNum, fmt.Sprintf("`Error: %s`", err)); commentErr != nil {
 			log.Err("unable to comment: %s", commentErr)
 		}
 		return
 	}
 	ctx := &CommandContext{
-		User:     user,
-		Log:      log,
-		Pull:     pull,
-		HeadRepo: headRepo,
-		BaseRepo: baseRepo,
+		User:      user,
+		Log:       log,
+		Pull:      pull,
+		HeadRepo:  headRepo,
+		BaseRepo:  baseRepo,
+		CommentID: commentID,
+		Span:      span,
+	}
+	if !c.globalCfg().BranchMatches(baseRepo.ID(), pull.BaseBranch) {
+		log.Info("command was run on a pull request into branch %q which isn't allowed by the server-side repo config's %s key", pull.BaseBranch, valid.BranchKey)
+		c.react(baseRepo, pullNum, commentID, models.FailedReaction, log)
+		if err := c.VCSClient.CreateComment(baseRepo, pullNum, fmt.Sprintf("Atlantis commands can't be run on pull requests into branch `%s`. The allowed branches are set by the `%s` key in the server-side repo config.", pull.BaseBranch, valid.BranchKey)); err != nil {
+			log.Err("unable to comment: %s", err)
+		}
+		return
 	}
 	if !c.validateCtxAndComment(ctx) {
+		c.react(baseRepo, pullNum, commentID, models.FailedReaction, log)
+		return
+	}
+	// Cancel isn't tracked as a running command so it doesn't cancel
+	// itself, and it doesn't need the locking backend.
+	if cmd.Name == models.CancelCommand {
+		c.runCancel(ctx, cmd)
+		return
+	}
+	if !c.lockingBackendAvailable(ctx) {
+		c.react(baseRepo, pullNum, commentID, models.FailedReaction, log)
+		return
+	}
+	c.react(baseRepo, pullNum, commentID, models.StartedReaction, log)
+	defer c.RunningCommands.Start(ctx, cmd.Name.String())()
+
+	if cmd.Name == models.DiffCommand {
+		c.runDiff(ctx, cmd)
+		return
+	}
+	if cmd.Name == models.CustomCommand {
+		c.runCustomCommand(ctx, cmd)
+		return
+	}
+	if cmd.Name == models.StatusCommand {
+		c.runStatus(ctx)
+		return
+	}
+	if cmd.Name == models.DiscardCommand {
+		c.runDiscard(ctx, cmd)
 		return
 	}
 
@@ -237,6 +550,7 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	}
 
 	var projectCmds []models.ProjectCommandContext
+	buildSpan := span.Start("build project commands")
 	switch cmd.Name {
 	case models.PlanCommand:
 		projectCmds, err = c.ProjectCommandBuilder.BuildPlanCommands(ctx, cmd)
@@ -246,6 +560,7 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		ctx.Log.Err("failed to determine desired command, neither plan nor apply")
 		return
 	}
+	endSpan(buildSpan, err)
 	if err != nil {
 		if statusErr := c.CommitStatusUpdater.UpdateCombined(ctx.BaseRepo, ctx.Pull, models.FailedCommitStatus, cmd.CommandName()); statusErr != nil {
 			ctx.Log.Warn("unable to update commit status: %s", statusErr)
@@ -254,30 +569,250 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		return
 	}
 
-	result := c.runProjectCmds(projectCmds, cmd.Name)
+	var result CommandResult
+	if cmd.Name == models.PlanCommand {
+		var reusedPlans []models.ProjectStatus
+		if prevStatus := c.prevPullStatus(ctx); prevStatus != nil {
+			projectCmds, reusedPlans = c.reusePlans(ctx, *prevStatus, projectCmds)
+		}
+		result = c.runPlanCmds(ctx, projectCmds)
+		result.SuggestedReviewers = c.suggestReviewers(ctx, projectCmds)
+		result.ReusedPlans = reusedPlans
+	} else {
+		result = c.runProjectCmds(ctx, projectCmds, cmd.Name)
+	}
 	if cmd.Name == models.PlanCommand && c.automergeEnabled(ctx, projectCmds) && result.HasErrors() {
 		ctx.Log.Info("deleting plans because there were errors and automerge requires all plans succeed")
 		c.deletePlans(ctx)
 		result.PlansDeleted = true
 	}
-	c.updatePull(
-		ctx,
-		cmd,
-		result)
+	if cmd.Name == models.PlanCommand {
+		c.updatePull(ctx, cmd, c.silenceNoChanges(result, projectCmds))
+		c.exportResults(ctx, models.PlanCommand, result)
+		c.postSlackApprovals(ctx, result)
+	} else {
+		c.updatePull(ctx, cmd, result)
+		c.queueApplies(ctx, cmd, result)
+	}
 
-	pullStatus, err := c.updateDB(ctx, pull, result.ProjectResults)
+	pullStatus, err := c.updateDB(ctx, pull, append(result.ProjectResults, c.keptPlanResults(ctx, result.ReusedPlans)...))
 	if err != nil {
 		c.Logger.Err("writing results: %s", err)
 		return
 	}
 
 	c.updateCommitStatus(ctx, cmd.Name, pullStatus)
+	c.updatePullCommitStatus(ctx, pullStatus)
+	if cmd.Name == models.PlanCommand && !cmd.IsForSpecificProject() {
+		c.postSummary(ctx, result, pullStatus)
+	}
 
 	if cmd.Name == models.ApplyCommand && c.automergeEnabled(ctx, projectCmds) {
 		c.automerge(ctx, pullStatus)
 	}
 }
 
+// queueApplies queues the applies in res that were outside their projects'
+// apply windows so they're run as the user who requested them once the
+// windows open.
+func (c *DefaultCommandRunner) queueApplies(ctx *CommandContext, cmd *CommentCommand, res CommandResult) {
+	for _, r := range res.ProjectResults {
+		if r.ApplyQueuedUntil.IsZero() {
+			continue
+		}
+		if c.QueuedApplies == nil {
+			ctx.Log.Warn("not queueing apply of dir %q workspace %q since queued applies aren't enabled", r.RepoRelDir, r.Workspace)
+			continue
+		}
+		// Projects with names are applied with -p since it can't be used
+		// with -d or -w.
+		queued := NewCommentCommand(r.RepoRelDir, cmd.Flags, models.ApplyCommand, cmd.Verbose, r.Workspace, "")
+		if r.ProjectName != "" {
+			queued = NewCommentCommand("", cmd.Flags, models.ApplyCommand, cmd.Verbose, "", r.ProjectName)
+		}
+		queued.ConfirmDestroy = cmd.ConfirmDestroy
+		queued.VarFile = cmd.VarFile
+		baseRepo, headRepo, pull, user := ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.User
+		ctx.Log.Info("queueing apply of dir %q workspace %q until %s", r.RepoRelDir, r.Workspace, r.ApplyQueuedUntil)
+		c.QueuedApplies.Queue(QueuedApplyKey(pull, r.RepoRelDir, r.Workspace), r.ApplyQueuedUntil, func() {
+			c.RunCommentCommand(baseRepo, &headRepo, &pull, user, pull.Num, queued)
+		})
+	}
+}
+
+// runDiff plans the project in cmd in each of its workspaces and comments how
+// the plans differ. Diffs don't change which plans can be applied so commit
+// statuses and the pull request's status in the DB aren't updated.
+func (c *DefaultCommandRunner) runDiff(ctx *CommandContext, cmd *CommentCommand) {
+	var projectCmds []models.ProjectCommandContext
+	for _, workspace := range cmd.Workspaces {
+		planCmd := NewCommentCommand(cmd.RepoRelDir, cmd.Flags, models.PlanCommand, cmd.Verbose, "", cmd.ProjectName)
+		if cmd.ProjectName == "" {
+			// Projects are configured with a single workspace so we only
+			// check that the workspace is allowed for dirs.
+			planCmd.Workspace = workspace
+		}
+		pCmds, err := c.ProjectCommandBuilder.BuildPlanCommands(ctx, planCmd)
+		if err != nil {
+			c.updatePull(ctx, cmd, CommandResult{Error: err})
+			return
+		}
+		pCmd := pCmds[0]
+		pCmd.Workspace = workspace
+		projectCmds = append(projectCmds, pCmd)
+	}
+
+	res := c.ProjectCommandRunner.Diff(projectCmds)
+	c.updatePull(ctx, cmd, CommandResult{ProjectResults: []models.ProjectResult{res}})
+}
+
+// runCustomCommand runs the steps of the custom command in cmd for the
+// projects that plan would be run for. Like diffs, custom commands don't
+// change which plans can be applied so the pull request's status in the DB
+// isn't updated. They set their own commit status instead.
+func (c *DefaultCommandRunner) runCustomCommand(ctx *CommandContext, cmd *CommentCommand) {
+	custom, ok := c.globalCfg().Commands[cmd.CustomCommand]
+	if !ok {
+		// The server-side repo config could have been reloaded since the
+		// comment was parsed.
+		c.updatePull(ctx, cmd, CommandResult{Error: fmt.Errorf("custom command %q isn't configured", cmd.CustomCommand)})
+		return
+	}
+	if err := c.CommitStatusUpdater.UpdateCustomCommand(ctx.BaseRepo, ctx.Pull, models.PendingCommitStatus, custom.Name); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+
+	planCmd := NewCommentCommand(cmd.RepoRelDir, cmd.Flags, models.PlanCommand, cmd.Verbose, cmd.Workspace, cmd.ProjectName)
+	projectCmds, err := c.ProjectCommandBuilder.BuildPlanCommands(ctx, planCmd)
+	result := CommandResult{CustomCommand: custom.Name}
+	if err != nil {
+		result.Error = err
+	} else {
+		for _, pCmd := range projectCmds {
+			pCmd.CustomCommand = &custom
+			result.ProjectResults = append(result.ProjectResults, c.ProjectCommandRunner.Custom(pCmd))
+		}
+	}
+	c.updatePull(ctx, cmd, result)
+
+	status := models.SuccessCommitStatus
+	if result.HasErrors() {
+		status = models.FailedCommitStatus
+	}
+	if err := c.CommitStatusUpdater.UpdateCustomCommand(ctx.BaseRepo, ctx.Pull, status, custom.Name); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+}
+
+// runStatus comments the status of each project in the pull request: the
+// projects plan would be run for and any others planned by earlier commands,
+// whether they've been planned and applied, and who holds their locks. It
+// doesn't plan anything so no state is changed.
+func (c *DefaultCommandRunner) runStatus(ctx *CommandContext) {
+	report := StatusReport{PullNum: ctx.Pull.Num}
+	// index maps from each project's dir and workspace to its place in
+	// report.Projects.
+	index := make(map[string]int)
+	add := func(projectName string, repoRelDir string, workspace string) int {
+		key := repoRelDir + "/" + workspace
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(report.Projects)
+		report.Projects = append(report.Projects, ProjectStatusReport{
+			ProjectName: projectName,
+			RepoRelDir:  repoRelDir,
+			Workspace:   workspace,
+		})
+		return index[key]
+	}
+
+	projectCmds, err := c.ProjectCommandBuilder.BuildPlanCommands(ctx, &CommentCommand{Name: models.PlanCommand})
+	if err != nil {
+		ctx.Log.Warn("unable to determine the projects plan would be run for: %s", err)
+		report.DetectErr = err
+	}
+	for _, pCmd := range projectCmds {
+		add(pCmd.ProjectName, pCmd.RepoRelDir, pCmd.Workspace)
+	}
+
+	pullStatus, err := c.DB.GetPullStatus(ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to get pull request status: %s", err)
+	}
+	// Statuses from an earlier commit are for plans that have been discarded.
+	if pullStatus != nil && pullStatus.Pull.HeadCommit == ctx.Pull.HeadCommit {
+		for _, p := range pullStatus.Projects {
+			status := p.Status
+			report.Projects[add(p.ProjectName, p.RepoRelDir, p.Workspace)].Status = &status
+		}
+	}
+
+	if c.Locker != nil {
+		locks, err := c.Locker.List()
+		if err != nil {
+			ctx.Log.Warn("unable to list locks: %s", err)
+		}
+		for _, lock := range locks {
+			i, ok := index[lock.Project.Path+"/"+lock.Workspace]
+			if !ok || lock.Project.RepoFullName != ctx.BaseRepo.FullName {
+				continue
+			}
+			lock := lock
+			report.Projects[i].Lock = &lock
+		}
+	}
+
+	c.react(ctx.BaseRepo, ctx.Pull.Num, ctx.CommentID, models.SucceededReaction, ctx.Log)
+	comment := c.MarkdownRenderer.RenderStatus(report)
+	for _, part := range c.MarkdownRenderer.SplitComment(comment, ctx.BaseRepo.VCSHost.Type) {
+		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, part); err != nil {
+			ctx.Log.Err("unable to comment status: %s", err)
+			return
+		}
+	}
+}
+
+// runDiscard discards the plans of the projects selected by cmd's flags and
+// releases their locks, then comments which plans were discarded.
+func (c *DefaultCommandRunner) runDiscard(ctx *CommandContext, cmd *CommentCommand) {
+	if c.PlanDiscarder == nil {
+		c.updatePull(ctx, cmd, CommandResult{Error: errors.New("discarding plans isn't enabled")})
+		return
+	}
+	sel := DiscardSelector{RepoRelDir: cmd.RepoRelDir, Workspace: cmd.Workspace, ProjectName: cmd.ProjectName}
+	plans, err := c.PlanDiscarder.Discard(ctx.Log, ctx.Pull, ctx.User.Username, sel)
+	if err != nil {
+		c.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	c.react(ctx.BaseRepo, ctx.Pull.Num, ctx.CommentID, models.SucceededReaction, ctx.Log)
+	comment := DiscardComment(plans, ctx.User.Username, "a comment")
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+}
+
+// runCancel cancels the commands running on the projects selected by cmd's
+// flags, then comments which commands were cancelled. The cancelled commands
+// comment their own results once they've stopped.
+func (c *DefaultCommandRunner) runCancel(ctx *CommandContext, cmd *CommentCommand) {
+	sel := DiscardSelector{RepoRelDir: cmd.RepoRelDir, Workspace: cmd.Workspace, ProjectName: cmd.ProjectName}
+	cancelled := c.RunningCommands.Cancel(ctx.BaseRepo.FullName, ctx.Pull.Num, sel, ctx.User.Username)
+	reaction := models.SucceededReaction
+	if len(cancelled) == 0 {
+		reaction = models.FailedReaction
+	}
+	for _, rc := range cancelled {
+		ctx.Log.Info("cancelled %s started by %s", rc.Command, rc.User)
+	}
+	c.react(ctx.BaseRepo, ctx.Pull.Num, ctx.CommentID, reaction, ctx.Log)
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, CancelComment(cancelled, ctx.User.Username, "a comment")); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+}
+
 func (c *DefaultCommandRunner) updateCommitStatus(ctx *CommandContext, cmd models.CommandName, pullStatus models.PullStatus) {
 	var numSuccess int
 	var status models.CommitStatus
@@ -310,6 +845,32 @@ func (c *DefaultCommandRunner) updateCommitStatus(ctx *CommandContext, cmd model
 	}
 }
 
+// updatePullCommitStatus sets the status that only succeeds once every
+// project in pullStatus has been applied or has no changes, if enabled.
+func (c *DefaultCommandRunner) updatePullCommitStatus(ctx *CommandContext, pullStatus models.PullStatus) {
+	if !c.PullStatusCheck {
+		return
+	}
+	var numDone, numErrored int
+	for _, p := range pullStatus.Projects {
+		switch {
+		case p.Status == models.AppliedPlanStatus, p.Status == models.PlannedPlanStatus && p.NoChanges:
+			numDone++
+		case p.Status == models.ErroredPlanStatus, p.Status == models.ErroredApplyStatus:
+			numErrored++
+		}
+	}
+	status := models.SuccessCommitStatus
+	if numErrored > 0 {
+		status = models.FailedCommitStatus
+	} else if numDone < len(pullStatus.Projects) {
+		status = models.PendingCommitStatus
+	}
+	if err := c.CommitStatusUpdater.UpdatePullStatus(ctx.BaseRepo, ctx.Pull, status, numDone, len(pullStatus.Projects)); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+}
+
 func (c *DefaultCommandRunner) automerge(ctx *CommandContext, pullStatus models.PullStatus) {
 	// We only automerge if all projects have been successfully applied.
 	for _, p := range pullStatus.Projects {
@@ -339,21 +900,235 @@ func (c *DefaultCommandRunner) automerge(ctx *CommandContext, pullStatus models.
 	}
 }
 
-func (c *DefaultCommandRunner) runProjectCmds(cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+// suggestReviewers returns the owners of the projects in cmds, not including
+// the pull request's author, and requests their reviews if configured to.
+func (c *DefaultCommandRunner) suggestReviewers(ctx *CommandContext, cmds []models.ProjectCommandContext) []string {
+	var reviewers []string
+	seen := make(map[string]bool)
+	for _, cmd := range cmds {
+		for _, owner := range cmd.Owners {
+			key := strings.ToLower(owner)
+			if seen[key] || strings.EqualFold(owner, ctx.Pull.Author) {
+				continue
+			}
+			seen[key] = true
+			reviewers = append(reviewers, owner)
+		}
+	}
+	if len(reviewers) == 0 || c.ReviewerRequester == nil || ctx.BaseRepo.VCSHost.Type != models.Github {
+		return reviewers
+	}
+	ctx.Log.Info("requesting reviews from %s", strings.Join(reviewers, ", "))
+	if err := c.ReviewerRequester.RequestReviewers(ctx.BaseRepo, ctx.Pull, reviewers); err != nil {
+		ctx.Log.Warn("unable to request reviews: %s", err)
+	}
+	return reviewers
+}
+
+// runPlanCmds runs plan for cmds unless the secret scanner finds secrets in
+// the pull request's modified files, in which case each project fails without
+// being planned so the secrets never make it into state.
+func (c *DefaultCommandRunner) runPlanCmds(ctx *CommandContext, cmds []models.ProjectCommandContext) CommandResult {
+	if c.SecretScanner == nil || len(cmds) == 0 {
+		return c.runProjectCmds(ctx, cmds, models.PlanCommand)
+	}
+	findings, err := c.scanForSecrets(ctx, cmds[0].Workspace)
+	if err == nil && len(findings) == 0 {
+		return c.runProjectCmds(ctx, cmds, models.PlanCommand)
+	}
+
+	var failure string
+	if err != nil {
+		err = errors.Wrap(err, "scanning for secrets")
+	} else {
+		ctx.Log.Warn("not planning since %d possible secrets were found", len(findings))
+		failure = RenderSecretFindings(findings)
+	}
+	var results []models.ProjectResult
+	for _, pCmd := range cmds {
+		res := models.ProjectResult{
+			Command:     models.PlanCommand,
+			Error:       err,
+			Failure:     failure,
+			RepoRelDir:  pCmd.RepoRelDir,
+			Workspace:   pCmd.Workspace,
+			ProjectName: pCmd.ProjectName,
+		}
+		if c.AuditLogger != nil {
+			c.AuditLogger.Record(NewProjectAuditEvent(pCmd, models.PlanCommand, res, 0))
+		}
+		if failure != "" {
+			c.EventPublisher.Publish(eventbus.NewProjectResultEvent(eventbus.PolicyFailedEvent, pCmd, res, 0))
+		}
+		c.updateProjectStatus(ctx, pCmd, models.PlanCommand, res)
+		results = append(results, res)
+	}
+	return CommandResult{ProjectResults: results}
+}
+
+// scanForSecrets scans the pull request's modified files in the working dir
+// for workspace, which has already been cloned by the project command builder.
+func (c *DefaultCommandRunner) scanForSecrets(ctx *CommandContext, workspace string) ([]SecretFinding, error) {
+	repoDir, err := c.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, workspace)
+	if err != nil {
+		return nil, err
+	}
+	modifiedFiles, err := c.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting modified files")
+	}
+	ctx.Log.Debug("scanning %d modified files for secrets", len(modifiedFiles))
+	return c.SecretScanner.Scan(ctx.Log, repoDir, modifiedFiles)
+}
+
+func (c *DefaultCommandRunner) runProjectCmds(ctx *CommandContext, cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+	// If plans without changes don't get statuses, we can't know which
+	// projects will get one until they're planned.
+	projectStatusesUpFront := c.ProjectCommitStatuses &&
+		!(cmdName == models.PlanCommand && len(cmds) > 0 && c.silenceNoChangesMode(cmds[0]) != valid.SilenceNoChangesOff)
+	if projectStatusesUpFront {
+		// Set every project to pending up front so required checks for
+		// projects later in the list don't look like they're missing.
+		for _, pCmd := range cmds {
+			if err := c.CommitStatusUpdater.UpdateProject(pCmd, cmdName, models.PendingCommitStatus, ""); err != nil {
+				ctx.Log.Warn("unable to update commit status: %s", err)
+			}
+		}
+	}
+	progress := startStatusProgress(c.CommitStatusUpdater, ctx, cmdName, len(cmds), projectStatusesUpFront, c.StatusProgressInterval)
+	defer progress.Stop()
 	var results []models.ProjectResult
 	for _, pCmd := range cmds {
+		if isDone(ctx.Done) {
+			ctx.Log.Info("skipping the rest of the projects because the command was cancelled")
+			break
+		}
+		progress.Next(pCmd)
+		c.RunningCommands.SetProject(ctx, pCmd)
+		pCmd.Span = ctx.Span.Start("project "+cmdName.String(),
+			tracing.String("dir", pCmd.RepoRelDir),
+			tracing.String("workspace", pCmd.Workspace),
+			tracing.String("project", pCmd.ProjectName))
 		var res models.ProjectResult
+		start := time.Now()
+		c.EventPublisher.Publish(eventbus.NewProjectEvent(startedEventType(cmdName), pCmd))
 		switch cmdName {
 		case models.PlanCommand:
 			res = c.ProjectCommandRunner.Plan(pCmd)
 		case models.ApplyCommand:
 			res = c.ProjectCommandRunner.Apply(pCmd)
 		}
+		progress.Finished()
+		if res.Failure != "" {
+			pCmd.Span.SetAttributes(tracing.String("failure", res.Failure))
+		}
+		endSpan(pCmd.Span, res.Error)
+		duration := time.Since(start)
+		if c.AuditLogger != nil {
+			c.AuditLogger.Record(NewProjectAuditEvent(pCmd, cmdName, res, duration))
+		}
+		c.EventPublisher.Publish(eventbus.NewProjectResultEvent(finishedEventType(cmdName), pCmd, res, duration))
+		c.recordProjectMetrics(pCmd, cmdName, res, duration)
+		c.updateProjectStatus(ctx, pCmd, cmdName, res)
+		c.createInsightsReport(ctx, pCmd, cmdName, res)
 		results = append(results, res)
 	}
 	return CommandResult{ProjectResults: results}
 }
 
+// startedEventType returns the type of event published when a project starts
+// running cmdName.
+func startedEventType(cmdName models.CommandName) eventbus.EventType {
+	if cmdName == models.ApplyCommand {
+		return eventbus.ApplyStartedEvent
+	}
+	return eventbus.PlanStartedEvent
+}
+
+// finishedEventType returns the type of event published with the result of
+// running cmdName on a project.
+func finishedEventType(cmdName models.CommandName) eventbus.EventType {
+	if cmdName == models.ApplyCommand {
+		return eventbus.ApplyFinishedEvent
+	}
+	return eventbus.PlanFinishedEvent
+}
+
+// endSpan marks span as failed with err, if set, and ends it.
+func endSpan(span *tracing.Span, err error) {
+	span.SetError(err)
+	span.End()
+}
+
+// recordProjectMetrics counts running cmdName on the project in pCmd and how
+// long it took.
+func (c *DefaultCommandRunner) recordProjectMetrics(pCmd models.ProjectCommandContext, cmdName models.CommandName, res models.ProjectResult, duration time.Duration) {
+	result := "success"
+	if res.Error != nil {
+		result = "error"
+	} else if res.Failure != "" {
+		result = "failure"
+	}
+	if c.ProjectCommands != nil {
+		c.ProjectCommands.Inc(pCmd.BaseRepo.FullName, pCmd.Tenant, cmdName.String(), result)
+	}
+	if c.ProjectCommandDuration != nil {
+		c.ProjectCommandDuration.Observe(duration, pCmd.BaseRepo.FullName, pCmd.Tenant, cmdName.String(), result)
+	}
+}
+
+// updateProjectStatus sets the commit status of the project in pCmd to the
+// result of running cmdName if per-project statuses are enabled. The status
+// links to the project's output or lock so failures can be looked into from
+// the VCS host.
+func (c *DefaultCommandRunner) updateProjectStatus(ctx *CommandContext, pCmd models.ProjectCommandContext, cmdName models.CommandName, res models.ProjectResult) {
+	if !c.ProjectCommitStatuses {
+		return
+	}
+	if cmdName == models.PlanCommand && res.PlanSuccess != nil && !res.PlanSuccess.HasChanges() && c.silenceNoChangesMode(pCmd) != valid.SilenceNoChangesOff {
+		return
+	}
+	status := models.SuccessCommitStatus
+	if res.Error != nil || res.Failure != "" {
+		status = models.FailedCommitStatus
+	}
+	if err := c.CommitStatusUpdater.UpdateProject(pCmd, cmdName, status, res.StatusURL()); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+}
+
+// createInsightsReport creates a report of the plan of the project in pCmd so
+// reviewers see it in Bitbucket Server's diff view, if configured to.
+func (c *DefaultCommandRunner) createInsightsReport(ctx *CommandContext, pCmd models.ProjectCommandContext, cmdName models.CommandName, res models.ProjectResult) {
+	if c.InsightsReporter == nil || cmdName != models.PlanCommand || ctx.BaseRepo.VCSHost.Type != models.BitbucketServer {
+		return
+	}
+	if err := c.InsightsReporter.CreateInsightsReport(ctx.BaseRepo, ctx.Pull, InsightsReportKey(pCmd, cmdName), NewPlanInsightsReport(pCmd, res)); err != nil {
+		ctx.Log.Warn("unable to create insights report: %s", err)
+	}
+}
+
+// exportResults exports the results of running cmdName, if configured to.
+func (c *DefaultCommandRunner) exportResults(ctx *CommandContext, cmdName models.CommandName, res CommandResult) {
+	if c.ResultsExporter == nil {
+		return
+	}
+	if err := c.ResultsExporter.Export(ctx, cmdName, res.ProjectResults); err != nil {
+		ctx.Log.Warn("unable to export results: %s", err)
+	}
+}
+
+// postSlackApprovals posts the plans in res to Slack for approval, if
+// configured to.
+func (c *DefaultCommandRunner) postSlackApprovals(ctx *CommandContext, res CommandResult) {
+	if c.SlackApprovals == nil {
+		return
+	}
+	if err := c.SlackApprovals.PostPlans(ctx, res.ProjectResults); err != nil {
+		ctx.Log.Warn("unable to post plans to slack for approval: %s", err)
+	}
+}
+
 func (c *DefaultCommandRunner) getGithubData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
 	if c.GithubPullGetter == nil {
 		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support GitHub")
@@ -381,6 +1156,25 @@ func (c *DefaultCommandRunner) getGitlabData(baseRepo models.Repo, pullNum int)
 	return pull, nil
 }
 
+// getHeadRepo looks up the head repo of the pull request, which is cloned
+// with the same credentials as baseRepo.
+func (c *DefaultCommandRunner) getHeadRepo(baseRepo models.Repo, pullNum int) (models.Repo, error) {
+	getter, ok := c.PullHeadRepoGetters[baseRepo.VCSHost.Type]
+	if !ok {
+		return models.Repo{}, fmt.Errorf("Atlantis not configured to look up the head repos of %s pull requests", baseRepo.VCSHost.Type.String())
+	}
+	fullName, cloneURL, err := getter.GetPullHeadRepo(baseRepo, pullNum)
+	if err != nil {
+		return models.Repo{}, errors.Wrap(err, "making pull request API call to get its head repo")
+	}
+	baseCloneURL, err := url.Parse(baseRepo.CloneURL)
+	if err != nil {
+		return models.Repo{}, errors.Wrap(err, "parsing base repo clone URL")
+	}
+	token, _ := baseCloneURL.User.Password()
+	return models.NewRepo(baseRepo.VCSHost.Type, fullName, cloneURL, baseCloneURL.User.Username(), token)
+}
+
 func (c *DefaultCommandRunner) getAzureDevopsData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
 	if c.AzureDevopsPullGetter == nil {
 		return models.PullRequest{}, models.Repo{}, errors.New("atlantis not configured to support Azure DevOps")
@@ -430,19 +1224,93 @@ func (c *DefaultCommandRunner) updatePull(ctx *CommandContext, command PullComma
 	} else if res.Failure != "" {
 		ctx.Log.Warn(res.Failure)
 	}
+	c.FailureTracker.Record(ctx.BaseRepo, command.CommandName(), res)
+	if res.HasErrors() {
+		c.react(ctx.BaseRepo, ctx.Pull.Num, ctx.CommentID, models.FailedReaction, ctx.Log)
+	} else {
+		c.react(ctx.BaseRepo, ctx.Pull.Num, ctx.CommentID, models.SucceededReaction, ctx.Log)
+	}
 
 	// HidePrevPlanComments will hide old comments left from previous plan runs to reduce
 	// clutter in a pull/merge request. This will not delete the comment, since the
 	// comment trail may be useful in auditing or backtracing problems.
-	if c.HidePrevPlanComments {
+	// Diffs and custom commands don't replace any plans so the plan comments
+	// are still current.
+	if c.HidePrevPlanComments && command.CommandName() != models.DiffCommand && command.CommandName() != models.CustomCommand {
 		if err := c.VCSClient.HidePrevPlanComments(ctx.BaseRepo, ctx.Pull.Num); err != nil {
 			ctx.Log.Err("unable to hide old comments: %s", err)
 		}
 	}
 
-	comment := c.MarkdownRenderer.Render(res, command.CommandName(), ctx.Log.History.String(), command.IsVerbose(), ctx.BaseRepo.VCSHost.Type)
-	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
-		ctx.Log.Err("unable to comment: %s", err)
+	for _, commentRes := range splitSeparateComments(res) {
+		comment := c.MarkdownRenderer.Render(commentRes, command.CommandName(), ctx.Log.History.String(), command.IsVerbose(), ctx.BaseRepo.VCSHost.Type)
+		if comment == "" {
+			// Every project's result was silenced.
+			continue
+		}
+		meta := NewCommentMetadata(command.CommandName(), ctx.BaseRepo.FullName, ctx.Pull.HeadCommit, commentRes)
+		for _, part := range c.MarkdownRenderer.SplitCommentWithMetadata(comment, ctx.BaseRepo.VCSHost.Type, meta) {
+			if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, part); err != nil {
+				ctx.Log.Err("unable to comment: %s", err)
+				return
+			}
+		}
+	}
+}
+
+// splitSeparateComments splits the project results that are commented on
+// their own out of res. It returns the results to comment, one per comment.
+// The rest of res is only commented if anything is left in it.
+func splitSeparateComments(res CommandResult) []CommandResult {
+	var together []models.ProjectResult
+	var separate []CommandResult
+	for _, pr := range res.ProjectResults {
+		if !pr.CommentSeparately {
+			together = append(together, pr)
+			continue
+		}
+		separate = append(separate, CommandResult{
+			ProjectResults: []models.ProjectResult{pr},
+			PlansDeleted:   res.PlansDeleted,
+			CustomCommand:  res.CustomCommand,
+		})
+	}
+	if len(separate) == 0 {
+		return []CommandResult{res}
+	}
+	res.ProjectResults = together
+	if len(together) == 0 && len(res.NoChangesResults) == 0 && len(res.KeptPlans) == 0 && len(res.ReusedPlans) == 0 && res.Error == nil && res.Failure == "" {
+		separate[0].SuggestedReviewers = res.SuggestedReviewers
+		return separate
+	}
+	return append([]CommandResult{res}, separate...)
+}
+
+// react adds reaction to the comment with commentID if the VCS host supports
+// reactions. Reactions are best effort so errors are only logged.
+func (c *DefaultCommandRunner) react(repo models.Repo, pullNum int, commentID int64, reaction models.CommentReaction, log *logging.SimpleLogger) {
+	reactor, ok := c.CommentReactors[repo.VCSHost.Type]
+	if !ok || commentID == 0 {
+		return
+	}
+	if err := reactor.ReactToComment(repo, pullNum, commentID, reaction); err != nil {
+		log.Warn("unable to react to comment: %s", err)
+	}
+}
+
+// postSummary comments a summary of every project in the pull request, if
+// enabled. It isn't posted for a single project since its plan comment
+// already says everything the summary would.
+func (c *DefaultCommandRunner) postSummary(ctx *CommandContext, res CommandResult, pullStatus models.PullStatus) {
+	if !c.SummaryComment || len(pullStatus.Projects) < 2 {
+		return
+	}
+	comment := c.MarkdownRenderer.RenderSummary(res, pullStatus)
+	for _, part := range c.MarkdownRenderer.SplitComment(comment, ctx.BaseRepo.VCSHost.Type) {
+		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, part); err != nil {
+			ctx.Log.Err("unable to comment summary: %s", err)
+			return
+		}
 	}
 }
 
@@ -488,6 +1356,143 @@ func (c *DefaultCommandRunner) updateDB(ctx *CommandContext, pull models.PullReq
 	return c.DB.UpdatePullWithResults(pull, filtered)
 }
 
+// prevPullStatus returns the pull request's status from before this command
+// if plans are only discarded for the projects new commits modify, or if
+// plans are reused. Otherwise it returns nil.
+func (c *DefaultCommandRunner) prevPullStatus(ctx *CommandContext) *models.PullStatus {
+	if (c.PlanInvalidator == nil && !c.ReusePlans) || c.DB == nil {
+		return nil
+	}
+	status, err := c.DB.GetPullStatus(ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to get pull request status: %s", err)
+		return nil
+	}
+	return status
+}
+
+// invalidatePlans discards the plans in prev that were invalidated by the
+// commits pushed since. It returns the commands in cmds that still need to
+// be run and the plans that were kept.
+func (c *DefaultCommandRunner) invalidatePlans(ctx *CommandContext, prev models.PullStatus, cmds []models.ProjectCommandContext) ([]models.ProjectCommandContext, []models.ProjectStatus) {
+	if prev.Pull.HeadCommit == ctx.Pull.HeadCommit || c.PlanInvalidator == nil {
+		return cmds, nil
+	}
+	remaining, kept, err := c.PlanInvalidator.Invalidate(ctx, prev, cmds)
+	if err != nil {
+		ctx.Log.Warn("discarding every plan because the plans the new commits invalidated couldn't be determined: %s", err)
+		c.deletePlans(ctx)
+		return cmds, nil
+	}
+	return remaining, kept
+}
+
+// reusePlans returns cmds without the projects whose plans in prev, the
+// pull request's status before this command, were made from the current head
+// and base commits with the same flags so they don't need to be planned
+// again. It also returns the statuses of the reused plans.
+func (c *DefaultCommandRunner) reusePlans(ctx *CommandContext, prev models.PullStatus, cmds []models.ProjectCommandContext) ([]models.ProjectCommandContext, []models.ProjectStatus) {
+	if !c.ReusePlans || len(cmds) == 0 || prev.Pull.HeadCommit != ctx.Pull.HeadCommit {
+		return cmds, nil
+	}
+	planned := make(map[string]models.ProjectStatus)
+	for _, ps := range prev.Projects {
+		if ps.Status == models.PlannedPlanStatus && !ps.PlanFingerprint.IsEmpty() {
+			planned[projectStatusKey(ps.RepoRelDir, ps.Workspace, ps.ProjectName)] = ps
+		}
+	}
+	if len(planned) == 0 {
+		return cmds, nil
+	}
+	// The builder already cloned the default workspace so the base branch
+	// is fetched there.
+	baseCommit, err := c.WorkingDir.GetBaseBranchCommit(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, DefaultWorkspace)
+	if err != nil {
+		ctx.Log.Warn("planning every project since the base branch's commit couldn't be determined: %s", err)
+		return cmds, nil
+	}
+
+	var remaining []models.ProjectCommandContext
+	var reused []models.ProjectStatus
+	for _, cmd := range cmds {
+		ps, ok := planned[projectStatusKey(cmd.RepoRelDir, cmd.Workspace, cmd.ProjectName)]
+		if !ok || ps.PlanFingerprint != planFingerprint(cmd, baseCommit) || !c.planExists(ctx, ps) {
+			remaining = append(remaining, cmd)
+			continue
+		}
+		ctx.Log.Info("reusing plan for dir %q workspace %q because nothing changed since it was planned", ps.RepoRelDir, ps.Workspace)
+		reused = append(reused, ps)
+	}
+	return remaining, reused
+}
+
+// planExists returns true if the planfile of the project of ps is in its
+// workspace's clone.
+func (c *DefaultCommandRunner) planExists(ctx *CommandContext, ps models.ProjectStatus) bool {
+	repoDir, err := c.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ps.Workspace)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(repoDir, ps.RepoRelDir, runtime.GetPlanFilename(ps.Workspace, ps.ProjectName)))
+	return err == nil
+}
+
+// keptPlanResults returns the results that save the plans of statuses, which
+// were kept or reused instead of planned again, as planned for the pull
+// request's current commit. The projects' commit statuses are updated too.
+func (c *DefaultCommandRunner) keptPlanResults(ctx *CommandContext, statuses []models.ProjectStatus) []models.ProjectResult {
+	var results []models.ProjectResult
+	for _, ps := range statuses {
+		res := keptPlanResult(ps)
+		results = append(results, res)
+		c.updateProjectStatus(ctx, models.ProjectCommandContext{
+			BaseRepo:    ctx.BaseRepo,
+			Pull:        ctx.Pull,
+			RepoRelDir:  ps.RepoRelDir,
+			Workspace:   ps.Workspace,
+			ProjectName: ps.ProjectName,
+		}, models.PlanCommand, res)
+	}
+	return results
+}
+
+// silenceNoChangesMode returns how the result of planning the project in pCmd
+// is posted if it has no changes.
+func (c *DefaultCommandRunner) silenceNoChangesMode(pCmd models.ProjectCommandContext) string {
+	if pCmd.SilenceNoChanges != "" {
+		return pCmd.SilenceNoChanges
+	}
+	if c.SilenceNoChanges != "" {
+		return c.SilenceNoChanges
+	}
+	return valid.SilenceNoChangesOff
+}
+
+// silenceNoChanges returns result without the plans that have no changes if
+// the projects in projectCmds are configured to silence them. It's only used
+// for the comment so the plans are still saved.
+func (c *DefaultCommandRunner) silenceNoChanges(result CommandResult, projectCmds []models.ProjectCommandContext) CommandResult {
+	if len(projectCmds) == 0 {
+		return result
+	}
+	// Every project is in the same repo so they have the same setting.
+	mode := c.silenceNoChangesMode(projectCmds[0])
+	if mode == valid.SilenceNoChangesOff {
+		return result
+	}
+	var kept []models.ProjectResult
+	for _, r := range result.ProjectResults {
+		if r.Error == nil && r.Failure == "" && r.PlanSuccess != nil && !r.PlanSuccess.HasChanges() {
+			result.NoChangesResults = append(result.NoChangesResults, r)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	result.ProjectResults = kept
+	result.SummarizeNoChanges = mode == valid.SilenceNoChangesSummarize
+	return result
+}
+
 // automergeEnabled returns true if automerging is enabled in this context.
 func (c *DefaultCommandRunner) automergeEnabled(ctx *CommandContext, projectCmds []models.ProjectCommandContext) bool {
 	// If the global automerge is set, we always automerge.
@@ -500,6 +1505,16 @@ func (c *DefaultCommandRunner) automergeEnabled(ctx *CommandContext, projectCmds
 // merges the PR.
 var automergeComment = `Automatically merging because all plans have been successfully applied.`
 
+// loadShedComment is posted when autoplan is skipped because the server is
+// overloaded.
+var loadShedComment = "Atlantis is overloaded so it didn't autoplan this pull request." +
+	" Try again later by commenting `atlantis plan`."
+
+// lockingOutageComment is posted when a command isn't run because the locking
+// backend is unavailable.
+var lockingOutageComment = "Atlantis can't reach the database it stores locks in so it didn't run this command." +
+	" Nothing was planned or applied. Try again once it recovers."
+
 // applyAllDisabledComment is posted when apply all commands (i.e. "atlantis apply")
 // are disabled and an apply all command is issued.
 var applyAllDisabledComment = "**Error:** Running `atlantis apply` without flags is disabled." +
@@ -2,6 +2,7 @@ package events
 
 import (
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/models"
 	. "github.com/runatlantis/atlantis/testing"
@@ -144,3 +145,45 @@ func (m *MockCSU) UpdateCombined(repo models.Repo, pull models.PullRequest, stat
 func (m *MockCSU) UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error {
 	return nil
 }
+func (m *MockCSU) UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) error {
+	return nil
+}
+func (m *MockCSU) UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) error {
+	return nil
+}
+func (m *MockCSU) UpdateCustomCommand(repo models.Repo, pull models.PullRequest, status models.CommitStatus, name string) error {
+	return nil
+}
+
+func (m *MockCSU) UpdatePullStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDone int, numTotal int) error {
+	return nil
+}
+
+func (m *MockCSU) UpdateDiscarded(lock models.ProjectLock) error {
+	return nil
+}
+
+func TestSplitSeparateComments(t *testing.T) {
+	together := models.ProjectResult{RepoRelDir: "a", Workspace: "default"}
+	staging := models.ProjectResult{RepoRelDir: "b", Workspace: "staging", CommentSeparately: true}
+	prod := models.ProjectResult{RepoRelDir: "b", Workspace: "prod", CommentSeparately: true}
+
+	// Nothing to split.
+	res := CommandResult{ProjectResults: []models.ProjectResult{together}}
+	Equals(t, []CommandResult{res}, splitSeparateComments(res))
+
+	// The rest of the results get their own comment first.
+	res = CommandResult{ProjectResults: []models.ProjectResult{staging, together, prod}, PlansDeleted: true}
+	Equals(t, []CommandResult{
+		{ProjectResults: []models.ProjectResult{together}, PlansDeleted: true},
+		{ProjectResults: []models.ProjectResult{staging}, PlansDeleted: true},
+		{ProjectResults: []models.ProjectResult{prod}, PlansDeleted: true},
+	}, splitSeparateComments(res))
+
+	// An empty rest isn't commented but its suggested reviewers are kept.
+	res = CommandResult{ProjectResults: []models.ProjectResult{staging, prod}, SuggestedReviewers: []string{"alice"}}
+	Equals(t, []CommandResult{
+		{ProjectResults: []models.ProjectResult{staging}, SuggestedReviewers: []string{"alice"}},
+		{ProjectResults: []models.ProjectResult{prod}},
+	}, splitSeparateComments(res))
+}
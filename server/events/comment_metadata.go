@@ -0,0 +1,133 @@
+package events
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+)
+
+const (
+	// CommentMetadataVersion is the version of the CommentMetadata format.
+	// It's incremented if fields are removed or change meaning so parsers can
+	// tell which format a comment uses.
+	CommentMetadataVersion = 1
+
+	// The statuses of commands and projects in CommentMetadata.
+	SuccessMetadataStatus = "success"
+	FailureMetadataStatus = "failure"
+	ErrorMetadataStatus   = "error"
+
+	// commentMetadataPrefix and commentMetadataSuffix wrap the metadata so
+	// it's hidden when the comment is rendered.
+	commentMetadataPrefix = "<!-- atlantis-metadata: "
+	commentMetadataSuffix = " -->"
+)
+
+// CommentMetadata is embedded as JSON in an HTML comment at the end of every
+// command result comment so automation can parse the results without
+// scraping the markdown.
+type CommentMetadata struct {
+	Version int    `json:"version"`
+	Command string `json:"command"`
+	// Status is success if the command and every project succeeded, failure
+	// if the command or a project couldn't run, ex. because the project was
+	// locked, and error if the command or a project errored.
+	Status     string `json:"status"`
+	HeadCommit string `json:"head_commit,omitempty"`
+	// Part and Parts are set when the results are split across comments.
+	// Part starts at 1.
+	Part     int               `json:"part,omitempty"`
+	Parts    int               `json:"parts,omitempty"`
+	Projects []ProjectMetadata `json:"projects,omitempty"`
+}
+
+// ProjectMetadata is the result of a command for a single project.
+type ProjectMetadata struct {
+	// ID is the project's stable ID, which doesn't change if it's renamed.
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Status    string `json:"status"`
+	// LockID is the ID of the project's lock, if plan locked it.
+	LockID string `json:"lock_id,omitempty"`
+	// PlanFile is the path to the planfile, relative to the root of the
+	// repo, for successful plans that weren't deleted.
+	PlanFile string `json:"plan_file,omitempty"`
+}
+
+// NewCommentMetadata returns the metadata for the results of cmdName in the
+// repo repoFullName.
+func NewCommentMetadata(cmdName models.CommandName, repoFullName string, headCommit string, res CommandResult) CommentMetadata {
+	meta := CommentMetadata{
+		Version:    CommentMetadataVersion,
+		Command:    cmdName.String(),
+		Status:     SuccessMetadataStatus,
+		HeadCommit: headCommit,
+	}
+	if res.Error != nil {
+		meta.Status = ErrorMetadataStatus
+	} else if res.Failure != "" {
+		meta.Status = FailureMetadataStatus
+	}
+	for _, r := range res.ProjectResults {
+		project := ProjectMetadata{
+			ID:        models.ProjectID(repoFullName, r.RepoRelDir, r.Workspace),
+			Name:      r.ProjectName,
+			Dir:       r.RepoRelDir,
+			Workspace: r.Workspace,
+			Status:    SuccessMetadataStatus,
+		}
+		switch {
+		case r.Error != nil:
+			project.Status = ErrorMetadataStatus
+		case r.Failure != "":
+			project.Status = FailureMetadataStatus
+		case r.PlanSuccess != nil:
+			project.LockID = r.PlanSuccess.LockID
+			if !res.PlansDeleted {
+				project.PlanFile = path.Join(r.RepoRelDir, runtime.GetPlanFilename(r.Workspace, r.ProjectName))
+			}
+		}
+		// Errors take precedence over failures.
+		if project.Status == ErrorMetadataStatus || (project.Status == FailureMetadataStatus && meta.Status == SuccessMetadataStatus) {
+			meta.Status = project.Status
+		}
+		meta.Projects = append(meta.Projects, project)
+	}
+	return meta
+}
+
+// ParseCommentMetadata returns the metadata embedded in comment and true, or
+// false if it doesn't have any.
+func ParseCommentMetadata(comment string) (CommentMetadata, bool) {
+	var meta CommentMetadata
+	start := strings.LastIndex(comment, commentMetadataPrefix)
+	if start == -1 {
+		return meta, false
+	}
+	rest := comment[start+len(commentMetadataPrefix):]
+	end := strings.Index(rest, commentMetadataSuffix)
+	if end == -1 {
+		return meta, false
+	}
+	if err := json.Unmarshal([]byte(rest[:end]), &meta); err != nil {
+		return meta, false
+	}
+	return meta, true
+}
+
+// render returns the metadata as a hidden HTML comment.
+func (c CommentMetadata) render() string {
+	// json.Marshal escapes <, > and & so the JSON can't end the HTML comment
+	// early.
+	data, err := json.Marshal(c)
+	if err != nil {
+		// This can't happen since all the fields can be marshalled.
+		return ""
+	}
+	return commentMetadataPrefix + string(data) + commentMetadataSuffix
+}
@@ -0,0 +1,151 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewCommentMetadata(t *testing.T) {
+	cases := []struct {
+		description string
+		res         events.CommandResult
+		exp         events.CommentMetadata
+	}{
+		{
+			"command error",
+			events.CommandResult{Error: errors.New("err")},
+			events.CommentMetadata{Version: 1, Command: "plan", Status: "error", HeadCommit: "sha"},
+		},
+		{
+			"command failure",
+			events.CommandResult{Failure: "failure"},
+			events.CommentMetadata{Version: 1, Command: "plan", Status: "failure", HeadCommit: "sha"},
+		},
+		{
+			"projects",
+			events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{
+						RepoRelDir:  "dir1",
+						Workspace:   "default",
+						PlanSuccess: &models.PlanSuccess{LockID: "owner/repo/dir1/default"},
+					},
+					{
+						RepoRelDir:  "dir2",
+						Workspace:   "staging",
+						ProjectName: "my/project",
+						PlanSuccess: &models.PlanSuccess{},
+					},
+					{
+						RepoRelDir: "dir3",
+						Workspace:  "default",
+						Failure:    "locked",
+					},
+				},
+			},
+			events.CommentMetadata{
+				Version:    1,
+				Command:    "plan",
+				Status:     "failure",
+				HeadCommit: "sha",
+				Projects: []events.ProjectMetadata{
+					{ID: models.ProjectID("owner/repo", "dir1", "default"), Dir: "dir1", Workspace: "default", Status: "success", LockID: "owner/repo/dir1/default", PlanFile: "dir1/default.tfplan"},
+					{ID: models.ProjectID("owner/repo", "dir2", "staging"), Name: "my/project", Dir: "dir2", Workspace: "staging", Status: "success", PlanFile: "dir2/my::project-staging.tfplan"},
+					{ID: models.ProjectID("owner/repo", "dir3", "default"), Dir: "dir3", Workspace: "default", Status: "failure"},
+				},
+			},
+		},
+		{
+			"errors take precedence over failures",
+			events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{RepoRelDir: ".", Workspace: "a", Error: errors.New("err")},
+					{RepoRelDir: ".", Workspace: "b", Failure: "failure"},
+				},
+			},
+			events.CommentMetadata{
+				Version:    1,
+				Command:    "plan",
+				Status:     "error",
+				HeadCommit: "sha",
+				Projects: []events.ProjectMetadata{
+					{ID: models.ProjectID("owner/repo", ".", "a"), Dir: ".", Workspace: "a", Status: "error"},
+					{ID: models.ProjectID("owner/repo", ".", "b"), Dir: ".", Workspace: "b", Status: "failure"},
+				},
+			},
+		},
+		{
+			"plans deleted",
+			events.CommandResult{
+				PlansDeleted: true,
+				ProjectResults: []models.ProjectResult{
+					{RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+				},
+			},
+			events.CommentMetadata{
+				Version:    1,
+				Command:    "plan",
+				Status:     "success",
+				HeadCommit: "sha",
+				Projects: []events.ProjectMetadata{
+					{ID: models.ProjectID("owner/repo", ".", "default"), Dir: ".", Workspace: "default", Status: "success"},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, events.NewCommentMetadata(models.PlanCommand, "owner/repo", "sha", c.res))
+		})
+	}
+}
+
+func TestSplitCommentWithMetadata(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	meta := events.CommentMetadata{
+		Version: 1,
+		Command: "apply",
+		Status:  "success",
+		Projects: []events.ProjectMetadata{
+			// The JSON shouldn't be able to end the HTML comment.
+			{Dir: "-->", Workspace: "default", Status: "success"},
+		},
+	}
+
+	parts := mr.SplitCommentWithMetadata("Ran Apply", models.Github, meta)
+	Equals(t, 1, len(parts))
+	Equals(t, `Ran Apply
+
+<!-- atlantis-metadata: {"version":1,"command":"apply","status":"success","projects":[{"dir":"--\u003e","workspace":"default","status":"success"}]} -->`, parts[0])
+	parsed, ok := events.ParseCommentMetadata(parts[0])
+	Equals(t, true, ok)
+	Equals(t, meta, parsed)
+
+	comment := "Ran Apply\n\n```diff\n" + strings.Repeat("+ resource line\n", 5000) + "```\n"
+	parts = mr.SplitCommentWithMetadata(comment, models.BitbucketServer, meta)
+	Equals(t, 3, len(parts))
+	for i, p := range parts {
+		Assert(t, len(p) <= 32768, "part %d was %d chars", i, len(p))
+		parsed, ok := events.ParseCommentMetadata(p)
+		Equals(t, true, ok)
+		Equals(t, i+1, parsed.Part)
+		Equals(t, 3, parsed.Parts)
+		Equals(t, meta.Projects, parsed.Projects)
+	}
+}
+
+func TestParseCommentMetadata_Missing(t *testing.T) {
+	for _, comment := range []string{
+		"Ran Plan",
+		"<!-- atlantis-metadata: {",
+		"<!-- atlantis-metadata: not json -->",
+	} {
+		_, ok := events.ParseCommentMetadata(comment)
+		Equals(t, false, ok)
+	}
+}
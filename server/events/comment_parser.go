@@ -19,24 +19,33 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/flynn-archive/go-shlex"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/spf13/pflag"
 )
 
 const (
-	workspaceFlagLong  = "workspace"
-	workspaceFlagShort = "w"
-	dirFlagLong        = "dir"
-	dirFlagShort       = "d"
-	projectFlagLong    = "project"
-	projectFlagShort   = "p"
-	verboseFlagLong    = "verbose"
-	verboseFlagShort   = ""
-	atlantisExecutable = "atlantis"
+	workspaceFlagLong      = "workspace"
+	workspaceFlagShort     = "w"
+	dirFlagLong            = "dir"
+	dirFlagShort           = "d"
+	projectFlagLong        = "project"
+	projectFlagShort       = "p"
+	verboseFlagLong        = "verbose"
+	verboseFlagShort       = ""
+	workspacesFlagLong     = "workspaces"
+	destroyFlagLong        = "destroy"
+	confirmDestroyFlagLong = "confirm-destroy"
+	breakGlassFlagLong     = "break-glass"
+	overrideGuardrailsLong = "override-guardrails"
+	varFileFlagLong        = "var-file"
+	discardFlagLong        = "discard"
+	atlantisExecutable     = "atlantis"
 )
 
 // multiLineRegex is used to ignore multi-line comments since those aren't valid
@@ -53,6 +62,9 @@ type CommentParsing interface {
 	// Parse attempts to parse a pull request comment to see if it's an Atlantis
 	// command.
 	Parse(comment string, vcsHost models.VCSHostType) CommentParseResult
+	// HelpComment returns the atlantis help comment for the repo with id
+	// repoID, including its help sections from the server-side repo config.
+	HelpComment(repoID string) string
 }
 
 // CommentBuilder builds comment commands that can be used on pull requests.
@@ -69,6 +81,17 @@ type CommentParser struct {
 	GitlabUser      string
 	BitbucketUser   string
 	AzureDevopsUser string
+	// GlobalCfgStore holds the server-side repo config whose custom
+	// commands can also be run. If nil, there are no custom commands.
+	GlobalCfgStore *GlobalCfgStore
+	// AllowedExtraArgs, if not empty, are the only terraform flags that can
+	// be passed after --, ex. "-target". DeniedExtraArgs can never be passed.
+	// An entry with a value, ex. "-lock=false", only matches that value.
+	AllowedExtraArgs []string
+	DeniedExtraArgs  []string
+	// Templates renders the comments that respond to commands that aren't
+	// run, ex. atlantis help. If nil, the default comments are used.
+	Templates *CommentTemplates
 }
 
 // CommentParseResult describes the result of parsing a comment as a command.
@@ -79,6 +102,10 @@ type CommentParseResult struct {
 	// CommentResponse is set when we should respond immediately to the command
 	// for example for atlantis help.
 	CommentResponse string
+	// Help is true if CommentResponse is the help comment. It doesn't have
+	// the repo's help sections since the repo isn't known when parsing, so
+	// it should be replaced with HelpComment's.
+	Help bool
 	// Ignore is set to true when we should just ignore this comment.
 	Ignore bool
 }
@@ -88,7 +115,8 @@ type CommentParseResult struct {
 // Valid commands contain:
 // - The initial "executable" name, 'run' or 'atlantis' or '@GithubUser'
 //   where GithubUser is the API user Atlantis is running as.
-// - Then a command, either 'plan', 'apply', or 'help'.
+// - Then a command, either 'plan', 'apply', 'diff', 'status', 'discard',
+//   'cancel', 'help' or a custom command from the server-side repo config.
 // - Then optional flags, then an optional separator '--' followed by optional
 //   extra flags to be appended to the terraform plan/apply command.
 //
@@ -98,6 +126,10 @@ type CommentParseResult struct {
 // - @GithubUser plan -w staging
 // - atlantis plan -w staging -d dir --verbose
 // - atlantis plan --verbose -- -key=value -key2 value2
+// - atlantis diff -p project --workspaces staging,prod
+// - atlantis status
+// - atlantis discard -p project
+// - atlantis cancel
 //
 func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) CommentParseResult {
 	if multiLineRegex.MatchString(comment) {
@@ -113,7 +145,7 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 
 	// Helpfully warn the user if they're using "terraform" instead of "atlantis"
 	if args[0] == "terraform" {
-		return CommentParseResult{CommentResponse: DidYouMeanAtlantisComment}
+		return CommentParseResult{CommentResponse: e.Templates.DidYouMean()}
 	}
 
 	// Atlantis can be invoked using the name of the VCS host user we're
@@ -138,7 +170,7 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	// parser.
 	args, err := shlex.Split(comment)
 	if err != nil {
-		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError parsing command: %s\n```", err)}
+		return CommentParseResult{CommentResponse: e.Templates.ParseError(err.Error())}
 	}
 	if len(args) < 1 {
 		return CommentParseResult{Ignore: true}
@@ -147,24 +179,34 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	// If they've just typed the name of the executable then give them the help
 	// output.
 	if len(args) == 1 {
-		return CommentParseResult{CommentResponse: HelpComment}
+		return CommentParseResult{CommentResponse: e.HelpComment(""), Help: true}
 	}
 	command := args[1]
 
 	// Help output.
 	if e.stringInSlice(command, []string{"help", "-h", "--help"}) {
-		return CommentParseResult{CommentResponse: HelpComment}
+		return CommentParseResult{CommentResponse: e.HelpComment(""), Help: true}
 	}
 
-	// Need to have a plan or apply at this point.
-	if !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String()}) {
-		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError: unknown command %q.\nRun 'atlantis --help' for usage.\n```", command)}
+	// Need to have a plan, apply, diff, status, discard, cancel or custom
+	// command at this point.
+	customCommands := e.customCommands()
+	_, isCustom := customCommands[command]
+	if !isCustom && !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String(), models.DiffCommand.String(), models.StatusCommand.String(), models.DiscardCommand.String(), models.CancelCommand.String()}) {
+		return CommentParseResult{CommentResponse: e.Templates.UnknownCommand(command)}
 	}
 
 	var workspace string
 	var dir string
 	var project string
 	var verbose bool
+	var workspaces []string
+	var destroy bool
+	var confirmDestroy bool
+	var breakGlass bool
+	var overrideGuardrails bool
+	var varFile string
+	var discard bool
 	var flagSet *pflag.FlagSet
 	var name models.CommandName
 
@@ -176,25 +218,68 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.SetOutput(ioutil.Discard)
 		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before planning.")
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run plan in relative to root of repo, ex. 'child/dir'.")
-		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run plan for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run plan for. Refers to the name of the project configured in %s. Can be a comma-separated list or glob of names, ex. 'app,db' or 'network-*'. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+		flagSet.BoolVar(&destroy, destroyFlagLong, false, "Plan to destroy every resource in the project. Applying the plan requires --"+confirmDestroyFlagLong+".")
+		flagSet.StringVar(&varFile, varFileFlagLong, "", fmt.Sprintf("Plan with this .tfvars file. Must be one of the project's var_files in %s.", yaml.AtlantisYAMLFilename))
+		flagSet.BoolVar(&discard, discardFlagLong, false, "Discard the plans and release their locks instead.")
 	case models.ApplyCommand.String():
 		name = models.ApplyCommand
 		flagSet = pflag.NewFlagSet(models.ApplyCommand.String(), pflag.ContinueOnError)
 		flagSet.SetOutput(ioutil.Discard)
 		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Apply the plan for this Terraform workspace.")
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Apply the plan for this directory, relative to root of repo, ex. 'child/dir'.")
-		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Apply the plan for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Apply the plan for this project. Refers to the name of the project configured in %s. Can be a comma-separated list or glob of names, ex. 'app,db' or 'network-*'. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+		flagSet.BoolVar(&confirmDestroy, confirmDestroyFlagLong, false, "Confirm that destroy plans from 'atlantis plan --"+destroyFlagLong+"' should be applied.")
+		flagSet.StringVar(&varFile, varFileFlagLong, "", "Only apply plans made with this .tfvars file.")
+		flagSet.BoolVar(&breakGlass, breakGlassFlagLong, false, "Apply outside the project's apply windows. Only allowed for break_glass users.")
+		flagSet.BoolVar(&overrideGuardrails, overrideGuardrailsLong, false, "Apply plans that exceed the project's guardrails. Only allowed for override users.")
+	case models.DiffCommand.String():
+		name = models.DiffCommand
+		flagSet = pflag.NewFlagSet(models.DiffCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringSliceVar(&workspaces, workspacesFlagLong, nil, "The two Terraform workspaces to plan and compare, ex. 'staging,prod'.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to compare relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to compare. Refers to the name of the project configured in %s. Cannot be used at same time as dir flag.", yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case models.DiscardCommand.String():
+		name = models.DiscardCommand
+		flagSet = pflag.NewFlagSet(models.DiscardCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Discard the plans in this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Discard the plans in this directory, relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Discard the plan of this project. Refers to the name of the project configured in %s. Can be a comma-separated list or glob of names, ex. 'app,db' or 'network-*'. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+	case models.CancelCommand.String():
+		name = models.CancelCommand
+		flagSet = pflag.NewFlagSet(models.CancelCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Cancel the command running in this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Cancel the command running in this directory, relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Cancel the command running on this project. Refers to the name of the project configured in %s. Can be a comma-separated list or glob of names, ex. 'app,db' or 'network-*'. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+	case models.StatusCommand.String():
+		// Status always lists every project so it doesn't take any flags.
+		name = models.StatusCommand
+		flagSet = pflag.NewFlagSet(models.StatusCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
 	default:
-		return CommentParseResult{CommentResponse: fmt.Sprintf("Error: unknown command %q – this is a bug", command)}
+		// Unknown commands were rejected above so this is a custom command.
+		// They take the same flags as plan. Their extra args are available
+		// to their steps in $COMMENT_ARGS.
+		name = models.CustomCommand
+		flagSet = pflag.NewFlagSet(command, pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", fmt.Sprintf("Switch to this Terraform workspace before running %s.", command))
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", fmt.Sprintf("Which directory to run %s in relative to root of repo, ex. 'child/dir'.", command))
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run %s for. Refers to the name of the project configured in %s. Can be a comma-separated list or glob of names, ex. 'app,db' or 'network-*'. Cannot be used at same time as workspace or dir flags.", command, yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
 	}
 
 	// Now parse the flags.
 	// It's safe to use [2:] because we know there's at least 2 elements in args.
 	err = flagSet.Parse(args[2:])
 	if err == pflag.ErrHelp {
-		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nUsage of %s:\n%s\n```", command, flagSet.FlagUsagesWrapped(usagesCols))}
+		return CommentParseResult{CommentResponse: e.Templates.CommandUsage(command, flagSet.FlagUsagesWrapped(usagesCols))}
 	}
 	if err != nil {
 		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
@@ -215,6 +300,27 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		extraArgs = flagSet.Args()[flagSet.ArgsLenAtDash():]
 	}
 
+	// atlantis plan --discard is the same as atlantis discard.
+	if discard {
+		if destroy || varFile != "" || verbose {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("--%s can't be used with other flags than -%s, -%s and -%s", discardFlagLong, dirFlagShort, workspaceFlagShort, projectFlagShort), command, flagSet)}
+		}
+		name = models.DiscardCommand
+	}
+
+	if name == models.StatusCommand || name == models.DiscardCommand || name == models.CancelCommand {
+		if len(extraArgs) > 0 {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("%s doesn't run terraform so it can't be passed terraform flags", name.String()), command, flagSet)}
+		}
+		if name == models.StatusCommand {
+			return CommentParseResult{Command: NewCommentCommand("", nil, name, false, "", "")}
+		}
+	}
+
+	if err := e.validateExtraArgs(extraArgs); err != nil {
+		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
+	}
+
 	dir, err = e.validateDir(dir)
 	if err != nil {
 		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
@@ -237,9 +343,126 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: e.errMarkdown(err, command, flagSet)}
 	}
 
-	return CommentParseResult{
-		Command: NewCommentCommand(dir, extraArgs, name, verbose, workspace, project),
+	// Projects can be selected with lists and globs, ex. -p 'network-*,app'.
+	// They're expanded by the ProjectCommandBuilder since the projects in
+	// the pull request aren't known yet.
+	if err := validateProjectPattern(project); project != "" && err != nil {
+		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
+	}
+	if name == models.DiffCommand && isProjectPattern(project) {
+		err := fmt.Sprintf("%s compares a single project so -%s/--%s can't be a list or glob", command, projectFlagShort, projectFlagLong)
+		return CommentParseResult{CommentResponse: e.errMarkdown(err, command, flagSet)}
+	}
+
+	if name == models.DiffCommand {
+		if len(workspaces) != 2 {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("--%s must be set to two workspaces, ex. --%s staging,prod", workspacesFlagLong, workspacesFlagLong), command, flagSet)}
+		}
+		for _, w := range workspaces {
+			if w == "" || w != url.PathEscape(w) || strings.Contains(w, "..") {
+				return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid workspace: %q", w), command, flagSet)}
+			}
+		}
+		if workspaces[0] == workspaces[1] {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("--%s must be set to two different workspaces", workspacesFlagLong), command, flagSet)}
+		}
+		// Diff always compares a single project so it defaults to the root
+		// dir like plan does when it's given flags.
+		if project == "" && dir == "" {
+			dir = DefaultRepoRelDir
+		}
+		cmd := NewCommentCommand(dir, extraArgs, name, verbose, "", project)
+		cmd.Workspaces = workspaces
+		return CommentParseResult{Command: cmd}
+	}
+
+	cmd := NewCommentCommand(dir, extraArgs, name, verbose, workspace, project)
+	if name == models.CustomCommand {
+		cmd.CustomCommand = command
+	}
+	cmd.Destroy = destroy
+	cmd.ConfirmDestroy = confirmDestroy
+	cmd.BreakGlass = breakGlass
+	cmd.OverrideGuardrails = overrideGuardrails
+	cmd.VarFile = varFile
+	return CommentParseResult{Command: cmd}
+}
+
+// validateExtraArgs returns an error if extraArgs has a flag that's denied or
+// isn't allowed. Args that don't start with a - are flag values so they
+// aren't checked.
+func (e *CommentParser) validateExtraArgs(extraArgs []string) error {
+	for _, arg := range extraArgs {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if matchesExtraArg(e.DeniedExtraArgs, arg) {
+			return fmt.Errorf("flag %q isn't allowed on this Atlantis server", arg)
+		}
+		if len(e.AllowedExtraArgs) > 0 && !matchesExtraArg(e.AllowedExtraArgs, arg) {
+			return fmt.Errorf("flag %q isn't allowed on this Atlantis server, only %s can be passed after --", arg, strings.Join(e.AllowedExtraArgs, ", "))
+		}
+	}
+	return nil
+}
+
+// matchesExtraArg returns true if arg is one of the flags in entries. Flags
+// match with one or two leading dashes, and an entry without a value matches
+// the flag with any value.
+func matchesExtraArg(entries []string, arg string) bool {
+	argName, argValue, argHasValue := splitFlag(arg)
+	for _, entry := range entries {
+		name, value, hasValue := splitFlag(entry)
+		if name == argName && (!hasValue || (argHasValue && value == argValue)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFlag splits a flag like --name=value into its name and value.
+func splitFlag(flag string) (name string, value string, hasValue bool) {
+	flag = strings.TrimLeft(flag, "-")
+	if i := strings.Index(flag, "="); i != -1 {
+		return flag[:i], flag[i+1:], true
+	}
+	return flag, "", false
+}
+
+// customCommands returns the custom commands in the server-side repo config.
+func (e *CommentParser) customCommands() map[string]valid.CustomCommand {
+	if e.GlobalCfgStore == nil {
+		return nil
 	}
+	return e.GlobalCfgStore.Get().Commands
+}
+
+// HelpComment returns the help comment with the custom commands added to its
+// list of commands and the help sections of the repo with id repoID. If
+// repoID is empty, no help sections are added.
+func (e *CommentParser) HelpComment(repoID string) string {
+	var data HelpData
+	if repoID != "" && e.GlobalCfgStore != nil {
+		data.Sections = e.GlobalCfgStore.Get().HelpSections(repoID)
+	}
+	commands := e.customCommands()
+	var names []string
+	width := 0
+	for name := range commands {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data.CustomCommands = append(data.CustomCommands, HelpCommand{
+			Name:        name,
+			Description: commands[name].Description,
+			Usage:       strings.TrimRight(fmt.Sprintf("  %-*s  %s", width, name, commands[name].Description), " "),
+		})
+	}
+	return e.Templates.Help(data)
 }
 
 // BuildPlanComment builds a plan comment for the specified args.
@@ -264,6 +487,17 @@ func (e *CommentParser) BuildApplyComment(repoRelDir string, workspace string, p
 	return fmt.Sprintf("%s %s%s", atlantisExecutable, models.ApplyCommand.String(), flags)
 }
 
+// withCommandFlag adds the boolean flag --flag to comment, a command built by
+// BuildPlanComment or BuildApplyComment, ex. "atlantis plan -d dir" becomes
+// "atlantis plan --destroy -d dir".
+func withCommandFlag(comment string, command models.CommandName, flag string) string {
+	prefix := fmt.Sprintf("%s %s", atlantisExecutable, command.String())
+	if !strings.HasPrefix(comment, prefix) {
+		return comment
+	}
+	return fmt.Sprintf("%s --%s%s", prefix, flag, strings.TrimPrefix(comment, prefix))
+}
+
 func (e *CommentParser) buildFlags(repoRelDir string, workspace string, project string) string {
 	// Add quotes if dir has spaces.
 	if strings.Contains(repoRelDir, " ") {
@@ -319,41 +553,13 @@ func (e *CommentParser) stringInSlice(a string, list []string) bool {
 }
 
 func (e *CommentParser) errMarkdown(errMsg string, command string, flagSet *pflag.FlagSet) string {
-	return fmt.Sprintf("```\nError: %s.\nUsage of %s:\n%s```", errMsg, command, flagSet.FlagUsagesWrapped(usagesCols))
+	return e.Templates.CommandError(command, errMsg, flagSet.FlagUsagesWrapped(usagesCols))
 }
 
 // HelpComment is the comment we add to the pull request when someone runs
-// `atlantis help`.
-var HelpComment = "```cmake\n" +
-	`atlantis
-Terraform Pull Request Automation
-
-Usage:
-  atlantis <command> [options] -- [terraform options]
-
-Examples:
-  # run plan in the root directory passing the -target flag to terraform
-  atlantis plan -d . -- -target=resource
-
-  # apply all unapplied plans from this pull request
-  atlantis apply
-
-  # apply the plan for the root directory and staging workspace
-  atlantis apply -d . -w staging
-
-Commands:
-  plan   Runs 'terraform plan' for the changes in this pull request.
-         To plan a specific project, use the -d, -w and -p flags.
-  apply  Runs 'terraform apply' on all unapplied plans from this pull request.
-         To only apply a specific plan, use the -d, -w and -p flags.
-  help   View help.
-
-Flags:
-  -h, --help   help for atlantis
-
-Use "atlantis [command] --help" for more information about a command.` +
-	"\n```"
+// `atlantis help` and there are no custom commands or comment templates.
+var HelpComment = DefaultCommentTemplates().Help(HelpData{})
 
 // DidYouMeanAtlantisComment is the comment we add to the pull request when
 // someone runs a command with terraform instead of atlantis.
-var DidYouMeanAtlantisComment = "Did you mean to use `atlantis` instead of `terraform`?"
+var DidYouMeanAtlantisComment = DefaultCommentTemplates().DidYouMean()
@@ -20,6 +20,7 @@ import (
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -57,9 +58,29 @@ func TestParse_HelpResponse(t *testing.T) {
 	for _, c := range helpComments {
 		r := commentParser.Parse(c, models.Github)
 		Equals(t, events.HelpComment, r.CommentResponse)
+		Assert(t, r.Help, "expected Help to be true for comment %q", c)
 	}
 }
 
+func TestHelpComment_Sections(t *testing.T) {
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos, valid.Repo{
+		ID: "github.com/owner/repo",
+		HelpSections: []valid.HelpSection{
+			{Title: "Support", Text: "Ask in #atlantis."},
+			{Text: "See the runbook."},
+		},
+	})
+	parser := events.CommentParser{
+		GithubUser:     "github-user",
+		GlobalCfgStore: events.NewGlobalCfgStore(global),
+	}
+
+	Equals(t, events.HelpComment+"\n\n**Support**\n\nAsk in #atlantis.\n\nSee the runbook.", parser.HelpComment("github.com/owner/repo"))
+	Equals(t, events.HelpComment, parser.HelpComment("github.com/owner/other"))
+	Equals(t, events.HelpComment, parser.HelpComment(""))
+}
+
 func TestParse_UnusedArguments(t *testing.T) {
 	t.Log("if there are unused flags we return an error")
 	cases := []struct {
@@ -169,6 +190,8 @@ func TestParse_SubcommandUsage(t *testing.T) {
 		"atlantis plan --help",
 		"atlantis apply -h",
 		"atlantis apply --help",
+		"atlantis diff -h",
+		"atlantis diff --help",
 	}
 	for _, c := range comments {
 		r := commentParser.Parse(c, models.Github)
@@ -203,6 +226,10 @@ func TestParse_InvalidFlags(t *testing.T) {
 			"atlantis apply --abc",
 			"Error: unknown flag: --abc",
 		},
+		{
+			"atlantis diff -w staging --workspaces staging,prod",
+			"Error: unknown shorthand flag: 'w' in -w",
+		},
 	}
 	for _, c := range cases {
 		r := commentParser.Parse(c.comment, models.Github)
@@ -295,6 +322,325 @@ func TestParse_UsingProjectAtSameTimeAsWorkspaceOrDir(t *testing.T) {
 	}
 }
 
+func TestParse_ProjectPatterns(t *testing.T) {
+	for _, c := range []struct {
+		comment    string
+		expProject string
+	}{
+		{"atlantis plan -p 'network-*'", "network-*"},
+		{"atlantis plan -p app,db", "app,db"},
+		{"atlantis apply -p 'app, network-[ab]'", "app, network-[ab]"},
+	} {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, c.expProject, r.Command.ProjectName)
+		})
+	}
+
+	for comment, expErr := range map[string]string{
+		"atlantis plan -p app,":                              `Error: invalid project "app,": cannot contain empty project names`,
+		"atlantis apply -p 'network-['":                      `Error: invalid project "network-[": syntax error in pattern`,
+		"atlantis diff -p 'app-*' --workspaces staging,prod": "Error: diff compares a single project so -p/--project can't be a list or glob",
+	} {
+		t.Run(comment, func(t *testing.T) {
+			r := commentParser.Parse(comment, models.Github)
+			Assert(t, strings.Contains(r.CommentResponse, expErr),
+				"For comment %q expected CommentResponse %q to contain %q", comment, r.CommentResponse, expErr)
+		})
+	}
+}
+
+func TestParse_Discard(t *testing.T) {
+	for _, c := range []struct {
+		comment      string
+		expDir       string
+		expWorkspace string
+		expProject   string
+	}{
+		{"atlantis discard", "", "", ""},
+		{"atlantis discard -d dir -w staging", "dir", "staging", ""},
+		{"atlantis discard -p 'network-*'", "", "", "network-*"},
+		{"atlantis plan --discard -d dir -w staging", "dir", "staging", ""},
+		{"atlantis plan --discard -p app,db", "", "", "app,db"},
+	} {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.DiscardCommand, r.Command.Name)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, c.expWorkspace, r.Command.Workspace)
+			Equals(t, c.expProject, r.Command.ProjectName)
+		})
+	}
+
+	for comment, expErr := range map[string]string{
+		"atlantis plan --discard --destroy": "Error: --discard can't be used with other flags than -d, -w and -p",
+		"atlantis discard -- -target=a":     "Error: discard doesn't run terraform",
+		"atlantis discard --verbose":        "Error: unknown flag: --verbose",
+	} {
+		t.Run(comment, func(t *testing.T) {
+			r := commentParser.Parse(comment, models.Github)
+			Assert(t, strings.Contains(r.CommentResponse, expErr),
+				"For comment %q expected CommentResponse %q to contain %q", comment, r.CommentResponse, expErr)
+		})
+	}
+}
+
+func TestParse_Cancel(t *testing.T) {
+	for _, c := range []struct {
+		comment      string
+		expDir       string
+		expWorkspace string
+		expProject   string
+	}{
+		{"atlantis cancel", "", "", ""},
+		{"atlantis cancel -d dir -w staging", "dir", "staging", ""},
+		{"atlantis cancel -p 'network-*'", "", "", "network-*"},
+	} {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.CancelCommand, r.Command.Name)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, c.expWorkspace, r.Command.Workspace)
+			Equals(t, c.expProject, r.Command.ProjectName)
+		})
+	}
+
+	for comment, expErr := range map[string]string{
+		"atlantis cancel -- -target=a": "Error: cancel doesn't run terraform",
+		"atlantis cancel --verbose":    "Error: unknown flag: --verbose",
+		"atlantis cancel -p app -d .":  "Error: cannot use -p/--project at same time as -d/--dir or -w/--workspace",
+	} {
+		t.Run(comment, func(t *testing.T) {
+			r := commentParser.Parse(comment, models.Github)
+			Assert(t, strings.Contains(r.CommentResponse, expErr),
+				"For comment %q expected CommentResponse %q to contain %q", comment, r.CommentResponse, expErr)
+		})
+	}
+}
+
+func TestParse_VarFile(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -d dir --var-file staging.tfvars", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, "staging.tfvars", r.Command.VarFile)
+
+	r = commentParser.Parse("atlantis apply -d dir --var-file=staging.tfvars", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, "staging.tfvars", r.Command.VarFile)
+
+	r = commentParser.Parse("atlantis plan -d dir", models.Github)
+	Equals(t, "", r.Command.VarFile)
+}
+
+func TestParse_OverrideGuardrails(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p app --override-guardrails", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, true, r.Command.OverrideGuardrails)
+
+	r = commentParser.Parse("atlantis apply -p app", models.Github)
+	Equals(t, false, r.Command.OverrideGuardrails)
+
+	r = commentParser.Parse("atlantis plan --override-guardrails", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --override-guardrails"), "exp unknown flag error, got %s", r.CommentResponse)
+}
+
+func TestParse_BreakGlass(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p app --break-glass", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, true, r.Command.BreakGlass)
+
+	r = commentParser.Parse("atlantis apply -p app", models.Github)
+	Equals(t, false, r.Command.BreakGlass)
+
+	// Plans don't need to be outside apply windows.
+	r = commentParser.Parse("atlantis plan --break-glass", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --break-glass"), "exp unknown flag error, got %s", r.CommentResponse)
+}
+
+func TestParse_ExtraArgsAllowlist(t *testing.T) {
+	parser := events.CommentParser{
+		GithubUser:       "github-user",
+		AllowedExtraArgs: []string{"-target", "-var", "-lock=true"},
+		DeniedExtraArgs:  []string{"-lock=false", "-state"},
+	}
+	cases := []struct {
+		comment string
+		expErr  string
+	}{
+		{"atlantis plan -- -target=aws_instance.a -var foo=bar", ""},
+		{"atlantis plan -- --target aws_instance.a", ""},
+		{"atlantis plan -- -lock=true", ""},
+		{"atlantis plan -- -var x=-1", ""},
+		{"atlantis apply -- -lock=false", `Error: flag "-lock=false" isn't allowed on this Atlantis server.`},
+		{"atlantis plan -- -state=other.tfstate", `Error: flag "-state=other.tfstate" isn't allowed on this Atlantis server.`},
+		{"atlantis plan -- -lock", `Error: flag "-lock" isn't allowed on this Atlantis server, only -target, -var, -lock=true can be passed after --.`},
+		{"atlantis plan -- -target a -refresh=false", `Error: flag "-refresh=false" isn't allowed on this Atlantis server, only -target, -var, -lock=true can be passed after --.`},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := parser.Parse(c.comment, models.Github)
+			if c.expErr == "" {
+				Equals(t, "", r.CommentResponse)
+				Assert(t, r.Command != nil, "expected command to be parsed")
+				return
+			}
+			Assert(t, strings.Contains(r.CommentResponse, c.expErr),
+				"For comment %q expected CommentResponse %q to contain %q", c.comment, r.CommentResponse, c.expErr)
+		})
+	}
+
+	// Without lists, any flag can be passed.
+	r := commentParser.Parse("atlantis plan -- -lock=false", models.Github)
+	Equals(t, []string{"-lock=false"}, r.Command.Flags)
+}
+
+func TestParse_Diff(t *testing.T) {
+	cases := []struct {
+		comment       string
+		expDir        string
+		expProject    string
+		expWorkspaces []string
+		expFlags      []string
+	}{
+		{
+			"atlantis diff --workspaces staging,prod",
+			".",
+			"",
+			[]string{"staging", "prod"},
+			nil,
+		},
+		{
+			"atlantis diff -d dir/ --workspaces staging --workspaces prod",
+			"dir",
+			"",
+			[]string{"staging", "prod"},
+			nil,
+		},
+		{
+			"atlantis diff -p project --workspaces staging,prod -- -var=a=b",
+			"",
+			"project",
+			[]string{"staging", "prod"},
+			[]string{"-var=a=b"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.DiffCommand, r.Command.Name)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, "", r.Command.Workspace)
+			Equals(t, c.expProject, r.Command.ProjectName)
+			Equals(t, c.expWorkspaces, r.Command.Workspaces)
+			Equals(t, c.expFlags, r.Command.Flags)
+		})
+	}
+}
+
+func TestParse_DiffInvalidWorkspaces(t *testing.T) {
+	cases := map[string]string{
+		"atlantis diff":                                    "Error: --workspaces must be set to two workspaces",
+		"atlantis diff --workspaces staging":               "Error: --workspaces must be set to two workspaces",
+		"atlantis diff --workspaces staging,prod,dev":      "Error: --workspaces must be set to two workspaces",
+		"atlantis diff --workspaces staging,staging":       "Error: --workspaces must be set to two different workspaces",
+		"atlantis diff --workspaces staging,../etc/passwd": `Error: invalid workspace: "../etc/passwd"`,
+		"atlantis diff --workspaces staging,":              `Error: invalid workspace: ""`,
+		"atlantis diff -d dir -p project --workspaces a,b": "Error: cannot use -p/--project at same time as -d/--dir",
+	}
+	for c, exp := range cases {
+		t.Run(c, func(t *testing.T) {
+			r := commentParser.Parse(c, models.Github)
+			Assert(t, r.Command == nil, "expected no command")
+			Assert(t, strings.Contains(r.CommentResponse, exp),
+				"For comment %q expected CommentResponse %q to contain %q", c, r.CommentResponse, exp)
+		})
+	}
+}
+
+func TestParse_Status(t *testing.T) {
+	r := commentParser.Parse("atlantis status", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.StatusCommand, r.Command.Name)
+	Equals(t, "", r.Command.RepoRelDir)
+	Equals(t, "", r.Command.Workspace)
+
+	cases := map[string]string{
+		"atlantis status -d dir":      "Error: unknown shorthand flag: 'd' in -d",
+		"atlantis status arg":         "Error: unknown argument(s) – arg",
+		"atlantis status -- -var=a=b": "Error: status doesn't run terraform so it can't be passed terraform flags",
+	}
+	for c, exp := range cases {
+		t.Run(c, func(t *testing.T) {
+			r := commentParser.Parse(c, models.Github)
+			Assert(t, r.Command == nil, "expected no command")
+			Assert(t, strings.Contains(r.CommentResponse, exp),
+				"For comment %q expected CommentResponse %q to contain %q", c, r.CommentResponse, exp)
+		})
+	}
+}
+
+func TestParse_Destroy(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -d dir --destroy -- -var=a=b", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.PlanCommand, r.Command.Name)
+	Equals(t, true, r.Command.Destroy)
+	Equals(t, []string{"-var=a=b"}, r.Command.Flags)
+
+	r = commentParser.Parse("atlantis apply -d dir --confirm-destroy", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.ApplyCommand, r.Command.Name)
+	Equals(t, true, r.Command.ConfirmDestroy)
+	Equals(t, false, r.Command.Destroy)
+
+	// Each flag is only valid for its command.
+	r = commentParser.Parse("atlantis apply --destroy", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "Error: unknown flag: --destroy"), "unexpected response %q", r.CommentResponse)
+	r = commentParser.Parse("atlantis plan --confirm-destroy", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "Error: unknown flag: --confirm-destroy"), "unexpected response %q", r.CommentResponse)
+}
+
+func TestParse_CustomCommand(t *testing.T) {
+	parser := events.CommentParser{
+		GithubUser: "github-user",
+		GlobalCfgStore: events.NewGlobalCfgStore(valid.GlobalCfg{
+			Commands: map[string]valid.CustomCommand{
+				"lint":     {Name: "lint", Description: "Runs tflint."},
+				"docs-gen": {Name: "docs-gen"},
+			},
+		}),
+	}
+
+	r := parser.Parse("atlantis lint -d dir -w staging -- --strict", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{
+		RepoRelDir:    "dir",
+		Flags:         []string{"--strict"},
+		Name:          models.CustomCommand,
+		Workspace:     "staging",
+		CustomCommand: "lint",
+	}, r.Command)
+
+	r = parser.Parse("atlantis docs-gen -p project --verbose", models.Github)
+	Equals(t, "docs-gen", r.Command.CustomCommand)
+	Equals(t, "project", r.Command.ProjectName)
+	Equals(t, true, r.Command.Verbose)
+
+	r = parser.Parse("atlantis lint --workspaces a,b", models.Github)
+	Assert(t, r.Command == nil, "expected no command")
+	Assert(t, strings.Contains(r.CommentResponse, "Error: unknown flag: --workspaces.\nUsage of lint:"), "unexpected response %q", r.CommentResponse)
+
+	r = parser.Parse("atlantis unknown", models.Github)
+	Equals(t, "```\nError: unknown command \"unknown\".\nRun 'atlantis --help' for usage.\n```", r.CommentResponse)
+
+	// Custom commands are only listed in the help if they're configured.
+	r = parser.Parse("atlantis help", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "  help    View help.\n\nCustom Commands:\n  docs-gen\n  lint      Runs tflint.\n\nFlags:"), "unexpected help %q", r.CommentResponse)
+	Equals(t, events.HelpComment, commentParser.Parse("atlantis help", models.Github).CommentResponse)
+}
+
 func TestParse_Parsing(t *testing.T) {
 	cases := []struct {
 		flags        string
@@ -675,21 +1021,37 @@ func TestParse_VCSUsername(t *testing.T) {
 }
 
 var PlanUsage = `Usage of plan:
+      --destroy            Plan to destroy every resource in the project. Applying
+                           the plan requires --confirm-destroy.
   -d, --dir string         Which directory to run plan in relative to root of repo,
                            ex. 'child/dir'.
+      --discard            Discard the plans and release their locks instead.
   -p, --project string     Which project to run plan for. Refers to the name of the
-                           project configured in atlantis.yaml. Cannot be used at
-                           same time as workspace or dir flags.
+                           project configured in atlantis.yaml. Can be a
+                           comma-separated list or glob of names, ex. 'app,db' or
+                           'network-*'. Cannot be used at same time as workspace or
+                           dir flags.
+      --var-file string    Plan with this .tfvars file. Must be one of the project's
+                           var_files in atlantis.yaml.
       --verbose            Append Atlantis log to comment.
   -w, --workspace string   Switch to this Terraform workspace before planning.
 `
 
 var ApplyUsage = `Usage of apply:
-  -d, --dir string         Apply the plan for this directory, relative to root of
-                           repo, ex. 'child/dir'.
-  -p, --project string     Apply the plan for this project. Refers to the name of
-                           the project configured in atlantis.yaml. Cannot be used
-                           at same time as workspace or dir flags.
-      --verbose            Append Atlantis log to comment.
-  -w, --workspace string   Apply the plan for this Terraform workspace.
+      --break-glass           Apply outside the project's apply windows. Only
+                              allowed for break_glass users.
+      --confirm-destroy       Confirm that destroy plans from 'atlantis plan
+                              --destroy' should be applied.
+  -d, --dir string            Apply the plan for this directory, relative to root of
+                              repo, ex. 'child/dir'.
+      --override-guardrails   Apply plans that exceed the project's guardrails. Only
+                              allowed for override users.
+  -p, --project string        Apply the plan for this project. Refers to the name of
+                              the project configured in atlantis.yaml. Can be a
+                              comma-separated list or glob of names, ex. 'app,db' or
+                              'network-*'. Cannot be used at same time as workspace
+                              or dir flags.
+      --var-file string       Only apply plans made with this .tfvars file.
+      --verbose               Append Atlantis log to comment.
+  -w, --workspace string      Apply the plan for this Terraform workspace.
 `
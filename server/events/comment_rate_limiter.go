@@ -0,0 +1,135 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// CommentRateLimiter limits how many comment commands each user can run on a
+// pull request in a window of time. It protects the server and the VCS host
+// from bots or users that comment commands in a loop. A nil
+// *CommentRateLimiter allows every command.
+type CommentRateLimiter struct {
+	// MaxCommands is the most commands a user can run on a pull request in
+	// Window.
+	MaxCommands int
+	Window      time.Duration
+	// Limited, if set, counts the commands that were rejected by repo.
+	Limited *metrics.Counter
+	// Now is stubbed in tests.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	users     map[string]*commentRateLimit
+	lastSweep time.Time
+}
+
+// commentRateLimit is the commands one user ran on one pull request.
+type commentRateLimit struct {
+	// times are when the commands in the current window were run, oldest
+	// first.
+	times []time.Time
+	// notified is true if the user was already told they're limited in the
+	// current window so we don't add to the storm with our own comments.
+	notified bool
+}
+
+// CommentRateLimitResult is the result of checking a command against the
+// limit.
+type CommentRateLimitResult struct {
+	// Allowed is true if the command can run.
+	Allowed bool
+	// Notify is true if the command was rejected and the user should be told
+	// why. It's only true for the first rejection in a window.
+	Notify bool
+	// RetryAfter is how long until the user can run another command.
+	RetryAfter time.Duration
+}
+
+// Allow records that user ran a command on pull pullNum of repo and returns
+// whether it's within the limit. Rejected commands don't count towards the
+// limit.
+func (r *CommentRateLimiter) Allow(repo models.Repo, pullNum int, user models.User) CommentRateLimitResult {
+	if r == nil || r.MaxCommands <= 0 {
+		return CommentRateLimitResult{Allowed: true}
+	}
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.users == nil {
+		r.users = make(map[string]*commentRateLimit)
+	}
+	r.sweep(now)
+
+	key := repo.FullName + "#" + strconv.Itoa(pullNum) + "#" + user.Username
+	limit, ok := r.users[key]
+	if !ok {
+		limit = &commentRateLimit{}
+		r.users[key] = limit
+	}
+	limit.expire(now.Add(-r.Window))
+	if len(limit.times) < r.MaxCommands {
+		limit.times = append(limit.times, now)
+		return CommentRateLimitResult{Allowed: true}
+	}
+
+	if r.Limited != nil {
+		r.Limited.Inc(repo.FullName)
+	}
+	res := CommentRateLimitResult{
+		Notify:     !limit.notified,
+		RetryAfter: limit.times[0].Add(r.Window).Sub(now),
+	}
+	limit.notified = true
+	return res
+}
+
+// RejectionComment returns the comment that tells user why their command
+// wasn't run.
+func (r *CommentRateLimiter) RejectionComment(user models.User, res CommentRateLimitResult) string {
+	return fmt.Sprintf("**Error:** @%s has run %d commands on this pull request in the last %s, which is the most allowed."+
+		" Further commands will be ignored without a comment until the limit resets in %s.",
+		user.Username, r.MaxCommands, r.Window, res.RetryAfter.Round(time.Second))
+}
+
+// expire forgets the commands run before cutoff. Once they're all forgotten
+// the user is notified again if they're limited.
+func (l *commentRateLimit) expire(cutoff time.Time) {
+	i := 0
+	for i < len(l.times) && !l.times[i].After(cutoff) {
+		i++
+	}
+	l.times = l.times[i:]
+	if len(l.times) == 0 {
+		l.notified = false
+	}
+}
+
+// sweep forgets the users that haven't run a command in the last window so
+// the map doesn't grow forever. It runs at most once per window. r.mu must
+// be held.
+func (r *CommentRateLimiter) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < r.Window {
+		return
+	}
+	r.lastSweep = now
+	cutoff := now.Add(-r.Window)
+	for key, limit := range r.users {
+		limit.expire(cutoff)
+		if len(limit.times) == 0 {
+			delete(r.users, key)
+		}
+	}
+}
+
+func (r *CommentRateLimiter) now() time.Time {
+	if r.Now == nil {
+		return time.Now()
+	}
+	return r.Now()
+}
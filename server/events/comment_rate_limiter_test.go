@@ -0,0 +1,59 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommentRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	registry := metrics.NewRegistry()
+	limiter := &events.CommentRateLimiter{
+		MaxCommands: 2,
+		Window:      10 * time.Minute,
+		Limited:     registry.NewCounter("atlantis_comment_commands_rate_limited", "", "repo"),
+		Now:         func() time.Time { return now },
+	}
+	repo := models.Repo{FullName: "owner/repo"}
+	bot := models.User{Username: "bot"}
+	human := models.User{Username: "human"}
+
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, bot))
+	now = now.Add(time.Minute)
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, bot))
+	now = now.Add(time.Minute)
+	// Only the first rejection in a window notifies the user.
+	Equals(t, events.CommentRateLimitResult{Notify: true, RetryAfter: 8 * time.Minute}, limiter.Allow(repo, 1, bot))
+	Equals(t, events.CommentRateLimitResult{RetryAfter: 8 * time.Minute}, limiter.Allow(repo, 1, bot))
+
+	// Other users and pull requests have their own limits.
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, human))
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 2, bot))
+
+	// Once the first command is outside the window, another is allowed.
+	now = now.Add(8 * time.Minute)
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, bot))
+	Equals(t, events.CommentRateLimitResult{RetryAfter: time.Minute}, limiter.Allow(repo, 1, bot))
+
+	// Once all commands are outside the window, the user is notified again.
+	now = now.Add(time.Hour)
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, bot))
+	Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(repo, 1, bot))
+	Equals(t, events.CommentRateLimitResult{Notify: true, RetryAfter: 10 * time.Minute}, limiter.Allow(repo, 1, bot))
+
+	families, err := registry.Gather()
+	Ok(t, err)
+	Equals(t, []metrics.Sample{{Labels: []string{"owner/repo"}, Value: 4}}, families[0].Samples)
+}
+
+func TestCommentRateLimiter_Disabled(t *testing.T) {
+	var limiter *events.CommentRateLimiter
+	for i := 0; i < 100; i++ {
+		Equals(t, events.CommentRateLimitResult{Allowed: true}, limiter.Allow(models.Repo{}, 1, models.User{}))
+	}
+}
@@ -0,0 +1,257 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// The names of the comment templates. Each can be overridden with a
+// <name>.tmpl file in the comment templates dir.
+const (
+	HelpTemplate           = "help"
+	DidYouMeanTemplate     = "did-you-mean"
+	UnknownCommandTemplate = "unknown-command"
+	ParseErrorTemplate     = "parse-error"
+	CommandErrorTemplate   = "command-error"
+	CommandUsageTemplate   = "command-usage"
+	NotWhitelistedTemplate = "not-whitelisted"
+)
+
+// commentTemplateExt is the extension of comment template files.
+const commentTemplateExt = ".tmpl"
+
+// HelpData is the data of the help template.
+type HelpData struct {
+	// CustomCommands are the custom commands from the server-side repo
+	// config, sorted by name.
+	CustomCommands []HelpCommand
+	// Sections are the repo's help sections from the server-side repo
+	// config. They're empty if the repo isn't known.
+	Sections []valid.HelpSection
+}
+
+// HelpCommand is a custom command in the help template.
+type HelpCommand struct {
+	Name        string
+	Description string
+	// Usage is the command's line in the list of commands with its name
+	// padded so the descriptions of all the commands line up.
+	Usage string
+}
+
+// CommentErrorData is the data of the templates of comments about commands
+// that can't be run.
+type CommentErrorData struct {
+	// Command is the command that was run, ex. plan.
+	Command string
+	// Error is why the command can't be run.
+	Error string
+	// Usage is the command's flags and their descriptions.
+	Usage string
+}
+
+var defaultCommentTemplates = map[string]string{
+	HelpTemplate: "```cmake\n" +
+		`atlantis
+Terraform Pull Request Automation
+
+Usage:
+  atlantis <command> [options] -- [terraform options]
+
+Examples:
+  # run plan in the root directory passing the -target flag to terraform
+  atlantis plan -d . -- -target=resource
+
+  # apply all unapplied plans from this pull request
+  atlantis apply
+
+  # apply the plan for the root directory and staging workspace
+  atlantis apply -d . -w staging
+
+  # plan to destroy every resource in the root directory
+  atlantis plan -d . --destroy
+
+  # compare the plans for the root directory in the staging and prod workspaces
+  atlantis diff -d . --workspaces staging,prod
+
+  # list each project's plan and apply status and locks
+  atlantis status
+
+  # discard the plans of the network-* projects and release their locks
+  atlantis discard -p 'network-*'
+
+  # cancel the plans and applies running on this pull request
+  atlantis cancel
+
+Commands:
+  plan    Runs 'terraform plan' for the changes in this pull request.
+          To plan a specific project, use the -d, -w and -p flags.
+  apply   Runs 'terraform apply' on all unapplied plans from this pull request.
+          To only apply a specific plan, use the -d, -w and -p flags.
+  diff    Plans a project in two workspaces and compares the plans.
+          Use the --workspaces flag to choose the workspaces.
+  status  Lists the projects in this pull request, whether they've been
+          planned and applied and which pull requests hold their locks.
+  discard Discards the plans of this pull request and releases their locks.
+          To only discard specific plans, use the -d, -w and -p flags.
+  cancel  Cancels the plans and applies running on this pull request.
+          To only cancel the command running on a specific project, use the
+          -d, -w and -p flags.
+  help    View help.
+{{if .CustomCommands}}
+Custom Commands:
+{{range .CustomCommands}}{{.Usage}}
+{{end}}{{end}}
+Flags:
+  -h, --help   help for atlantis
+
+Use "atlantis [command] --help" for more information about a command.` +
+		"\n```" +
+		"{{range .Sections}}\n\n{{if .Title}}**{{.Title}}**\n\n{{end}}{{.Text}}{{end}}",
+	DidYouMeanTemplate:     "Did you mean to use `atlantis` instead of `terraform`?",
+	UnknownCommandTemplate: "```\nError: unknown command {{printf \"%q\" .Command}}.\nRun 'atlantis --help' for usage.\n```",
+	ParseErrorTemplate:     "```\nError parsing command: {{.Error}}\n```",
+	CommandErrorTemplate:   "```\nError: {{.Error}}.\nUsage of {{.Command}}:\n{{.Usage}}```",
+	CommandUsageTemplate:   "```\nUsage of {{.Command}}:\n{{.Usage}}\n```",
+	NotWhitelistedTemplate: "```\nError: This repo is not whitelisted for Atlantis.\n```",
+}
+
+// sampleCommentTemplateData is the data loaded templates are executed with to
+// check they only use fields that exist.
+var sampleCommentTemplateData = map[string]interface{}{
+	HelpTemplate: HelpData{
+		CustomCommands: []HelpCommand{{Name: "lint", Description: "Runs tflint.", Usage: "  lint  Runs tflint."}},
+		Sections:       []valid.HelpSection{{Title: "Support", Text: "Ask in #atlantis."}},
+	},
+	UnknownCommandTemplate: CommentErrorData{Command: "pln"},
+	ParseErrorTemplate:     CommentErrorData{Error: "EOF found when expecting closing quote"},
+	CommandErrorTemplate:   CommentErrorData{Command: "plan", Error: "unknown flag: --foo", Usage: "  -d, --dir string   Which directory to run plan in.\n"},
+	CommandUsageTemplate:   CommentErrorData{Command: "plan", Usage: "  -d, --dir string   Which directory to run plan in.\n"},
+}
+
+// CommentTemplates renders the comments Atlantis responds to commands with
+// when it doesn't run them, ex. the atlantis help output. Their text can be
+// replaced, ex. to translate it or link to internal docs.
+// A nil CommentTemplates renders the default comments.
+type CommentTemplates struct {
+	templates map[string]*template.Template
+}
+
+// DefaultCommentTemplates returns the templates of the default comments.
+func DefaultCommentTemplates() *CommentTemplates {
+	c := &CommentTemplates{templates: make(map[string]*template.Template)}
+	for name, text := range defaultCommentTemplates {
+		c.templates[name] = template.Must(newCommentTemplate(name).Parse(text))
+	}
+	return c
+}
+
+// LoadCommentTemplates returns the default templates overridden by the
+// <name>.tmpl files in dir, ex. help.tmpl. It returns an error if dir has a
+// .tmpl file that isn't the name of a template or a template that doesn't
+// parse or execute.
+func LoadCommentTemplates(dir string) (*CommentTemplates, error) {
+	c := DefaultCommentTemplates()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+commentTemplateExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), commentTemplateExt)
+		if _, ok := defaultCommentTemplates[name]; !ok {
+			return nil, fmt.Errorf("%s isn't a comment template, valid names are %s", path, strings.Join(commentTemplateFiles(), ", "))
+		}
+		text, err := ioutil.ReadFile(path) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", path)
+		}
+		tmpl, err := newCommentTemplate(name).Parse(string(text))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", path)
+		}
+		if err := tmpl.Execute(ioutil.Discard, sampleCommentTemplateData[name]); err != nil {
+			return nil, errors.Wrapf(err, "executing %s", path)
+		}
+		c.templates[name] = tmpl
+	}
+	return c, nil
+}
+
+// Help renders the atlantis help comment.
+func (c *CommentTemplates) Help(data HelpData) string {
+	return c.render(HelpTemplate, data)
+}
+
+// DidYouMean renders the comment for commands run with terraform instead of
+// atlantis.
+func (c *CommentTemplates) DidYouMean() string {
+	return c.render(DidYouMeanTemplate, nil)
+}
+
+// UnknownCommand renders the comment for a command that doesn't exist.
+func (c *CommentTemplates) UnknownCommand(command string) string {
+	return c.render(UnknownCommandTemplate, CommentErrorData{Command: command})
+}
+
+// ParseError renders the comment for a command that can't be split into
+// arguments, ex. because of an unclosed quote.
+func (c *CommentTemplates) ParseError(err string) string {
+	return c.render(ParseErrorTemplate, CommentErrorData{Error: err})
+}
+
+// CommandError renders the comment for a command with invalid flags.
+func (c *CommentTemplates) CommandError(command string, err string, usage string) string {
+	return c.render(CommandErrorTemplate, CommentErrorData{Command: command, Error: err, Usage: usage})
+}
+
+// CommandUsage renders the comment for a command run with --help.
+func (c *CommentTemplates) CommandUsage(command string, usage string) string {
+	return c.render(CommandUsageTemplate, CommentErrorData{Command: command, Usage: usage})
+}
+
+// NotWhitelisted renders the comment for commands in repos that aren't
+// whitelisted.
+func (c *CommentTemplates) NotWhitelisted() string {
+	return c.render(NotWhitelistedTemplate, nil)
+}
+
+// render renders the template name. If it fails, ex. because a loaded
+// template indexes a nil field, the default template is rendered instead.
+func (c *CommentTemplates) render(name string, data interface{}) string {
+	if c != nil {
+		if tmpl, ok := c.templates[name]; ok {
+			buf := &bytes.Buffer{}
+			if err := tmpl.Execute(buf, data); err == nil {
+				return buf.String()
+			}
+		}
+	}
+	buf := &bytes.Buffer{}
+	// The default templates are tested so they can't fail.
+	template.Must(newCommentTemplate(name).Parse(defaultCommentTemplates[name])).Execute(buf, data) // nolint: errcheck
+	return buf.String()
+}
+
+func newCommentTemplate(name string) *template.Template {
+	return template.New(name).Funcs(sprig.TxtFuncMap())
+}
+
+// commentTemplateFiles returns the names of the comment template files,
+// sorted.
+func commentTemplateFiles() []string {
+	var files []string
+	for name := range defaultCommentTemplates {
+		files = append(files, name+commentTemplateExt)
+	}
+	sort.Strings(files)
+	return files
+}
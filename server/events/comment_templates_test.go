@@ -0,0 +1,92 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommentTemplates_Defaults(t *testing.T) {
+	var nilTemplates *events.CommentTemplates
+	for _, templates := range []*events.CommentTemplates{events.DefaultCommentTemplates(), nilTemplates} {
+		Equals(t, events.HelpComment, templates.Help(events.HelpData{}))
+		Equals(t, events.DidYouMeanAtlantisComment, templates.DidYouMean())
+		Equals(t, "```\nError: unknown command \"pln\".\nRun 'atlantis --help' for usage.\n```", templates.UnknownCommand("pln"))
+		Equals(t, "```\nError parsing command: EOF\n```", templates.ParseError("EOF"))
+		Equals(t, "```\nError: unknown flag: --foo.\nUsage of plan:\n  -d string\n```", templates.CommandError("plan", "unknown flag: --foo", "  -d string\n"))
+		Equals(t, "```\nUsage of plan:\n  -d string\n\n```", templates.CommandUsage("plan", "  -d string\n"))
+		Equals(t, "```\nError: This repo is not whitelisted for Atlantis.\n```", templates.NotWhitelisted())
+	}
+}
+
+func TestLoadCommentTemplates(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "help.tmpl"), []byte(`Hilfe:{{range .CustomCommands}} {{.Name}}{{end}}{{range .Sections}} {{.Title | upper}}{{end}}`), 0600))
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "unknown-command.tmpl"), []byte(`Unbekannter Befehl {{.Command}}`), 0600))
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "README.md"), []byte(`not a template`), 0600))
+
+	templates, err := events.LoadCommentTemplates(tmp)
+	Ok(t, err)
+	Equals(t, "Hilfe: lint SUPPORT", templates.Help(events.HelpData{
+		CustomCommands: []events.HelpCommand{{Name: "lint"}},
+		Sections:       []valid.HelpSection{{Title: "Support"}},
+	}))
+	Equals(t, "Unbekannter Befehl pln", templates.UnknownCommand("pln"))
+	// Templates without a file keep their default.
+	Equals(t, events.DidYouMeanAtlantisComment, templates.DidYouMean())
+}
+
+func TestLoadCommentTemplates_Errors(t *testing.T) {
+	cases := []struct {
+		description string
+		file        string
+		text        string
+		expErr      string
+	}{
+		{
+			"unknown template",
+			"halp.tmpl",
+			"help",
+			"halp.tmpl isn't a comment template, valid names are command-error.tmpl, command-usage.tmpl, did-you-mean.tmpl, help.tmpl, not-whitelisted.tmpl, parse-error.tmpl, unknown-command.tmpl",
+		},
+		{
+			"template doesn't parse",
+			"help.tmpl",
+			"{{range .Sections}}",
+			"parsing",
+		},
+		{
+			"template uses a field that doesn't exist",
+			"unknown-command.tmpl",
+			"{{.Comand}}",
+			"executing",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			Ok(t, ioutil.WriteFile(filepath.Join(tmp, c.file), []byte(c.text), 0600))
+			_, err := events.LoadCommentTemplates(tmp)
+			ErrContains(t, c.expErr, err)
+		})
+	}
+}
+
+func TestCommentTemplates_FallsBackToDefault(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	// Indexing a nil section passes the check with the sample data but fails
+	// when the repo has no sections.
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "help.tmpl"), []byte(`{{(index .Sections 0).Title}}`), 0600))
+	templates, err := events.LoadCommentTemplates(tmp)
+	Ok(t, err)
+	Equals(t, "Support", templates.Help(events.HelpData{Sections: []valid.HelpSection{{Title: "Support"}}}))
+	Assert(t, strings.HasPrefix(templates.Help(events.HelpData{}), "```cmake\natlantis\n"), "expected the default help")
+}
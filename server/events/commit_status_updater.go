@@ -16,11 +16,24 @@ package events
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 )
 
+const (
+	// AggregateCommitStatusMode sets a single status per command, ex.
+	// atlantis/plan, that represents all the projects in the pull request.
+	AggregateCommitStatusMode = "aggregate"
+	// ProjectCommitStatusMode sets a status per project, ex.
+	// atlantis/plan: project1, instead of the aggregate status.
+	ProjectCommitStatusMode = "project"
+	// BothCommitStatusMode sets the aggregate status and a status per
+	// project.
+	BothCommitStatusMode = "both"
+)
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_commit_status_updater.go CommitStatusUpdater
 
 // CommitStatusUpdater updates the status of a commit with the VCS host. We set
@@ -35,6 +48,23 @@ type CommitStatusUpdater interface {
 	// UpdateProject sets the commit status for the project represented by
 	// ctx.
 	UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error
+	// UpdateCombinedProgress updates the pending combined status to show
+	// that the current project out of numTotal is running and how long the
+	// command has been running for.
+	UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) error
+	// UpdateProjectProgress updates the pending status of the project
+	// represented by ctx to show how long it's been running for.
+	UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) error
+	// UpdateCustomCommand updates the status of the custom command name, ex.
+	// atlantis/lint, on the head commit of pull.
+	UpdateCustomCommand(repo models.Repo, pull models.PullRequest, status models.CommitStatus, name string) error
+	// UpdatePullStatus updates the atlantis/status status of the head commit
+	// of pull to reflect that numDone out of numTotal projects have been
+	// applied or don't need to be.
+	UpdatePullStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDone int, numTotal int) error
+	// UpdateDiscarded fails the plan status of the pull request that held
+	// lock to show that its plan was discarded and must be run again.
+	UpdateDiscarded(lock models.ProjectLock) error
 }
 
 // DefaultCommitStatusUpdater implements CommitStatusUpdater.
@@ -42,9 +72,15 @@ type DefaultCommitStatusUpdater struct {
 	Client vcs.Client
 	// StatusName is the name used to identify Atlantis when creating PR statuses.
 	StatusName string
+	// SkipCombined is true if only per-project statuses should be set, in
+	// which case UpdateCombined and UpdateCombinedCount are no-ops.
+	SkipCombined bool
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateCombined(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName) error {
+	if d.SkipCombined {
+		return nil
+	}
 	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
 	var descripWords string
 	switch status {
@@ -59,7 +95,56 @@ func (d *DefaultCommitStatusUpdater) UpdateCombined(repo models.Repo, pull model
 	return d.Client.UpdateStatus(repo, pull, status, src, descrip, "")
 }
 
+// UpdateCustomCommand sets the status of the custom command name. It's set
+// even if SkipCombined is true since custom commands don't have per-project
+// statuses.
+func (d *DefaultCommitStatusUpdater) UpdateCustomCommand(repo models.Repo, pull models.PullRequest, status models.CommitStatus, name string) error {
+	src := fmt.Sprintf("%s/%s", d.StatusName, name)
+	var descripWords string
+	switch status {
+	case models.PendingCommitStatus:
+		descripWords = "in progress..."
+	case models.FailedCommitStatus:
+		descripWords = "failed."
+	case models.SuccessCommitStatus:
+		descripWords = "succeeded."
+	}
+	descrip := fmt.Sprintf("%s %s", strings.Title(name), descripWords)
+	return d.Client.UpdateStatus(repo, pull, status, src, descrip, "")
+}
+
+// UpdatePullStatus sets the status that reflects whether every project in
+// the pull request has been applied. Like custom command statuses, it's set
+// even if SkipCombined is true.
+func (d *DefaultCommitStatusUpdater) UpdatePullStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDone int, numTotal int) error {
+	src := fmt.Sprintf("%s/%s", d.StatusName, models.StatusCommand.String())
+	var descrip string
+	switch status {
+	case models.FailedCommitStatus:
+		descrip = fmt.Sprintf("%d/%d projects applied, fix the failed projects.", numDone, numTotal)
+	default:
+		descrip = fmt.Sprintf("%d/%d projects applied or unchanged.", numDone, numTotal)
+	}
+	return d.Client.UpdateStatus(repo, pull, status, src, descrip, "")
+}
+
+// UpdateDiscarded fails the combined plan status or, if SkipCombined is
+// true, the project's plan status. Locks don't store the project's name so
+// the project's status is the one named after its dir and workspace.
+func (d *DefaultCommitStatusUpdater) UpdateDiscarded(lock models.ProjectLock) error {
+	projectID := fmt.Sprintf("%s/%s", lock.Project.Path, lock.Workspace)
+	src := fmt.Sprintf("%s/%s", d.StatusName, models.PlanCommand.String())
+	if d.SkipCombined {
+		src = fmt.Sprintf("%s: %s", src, projectID)
+	}
+	descrip := fmt.Sprintf("Plan for %s discarded, run plan again.", projectID)
+	return d.Client.UpdateStatus(lock.Pull.BaseRepo, lock.Pull, models.FailedCommitStatus, src, descrip, "")
+}
+
 func (d *DefaultCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName, numSuccess int, numTotal int) error {
+	if d.SkipCombined {
+		return nil
+	}
 	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
 	cmdVerb := "planned"
 	if command == models.ApplyCommand {
@@ -84,5 +169,48 @@ func (d *DefaultCommitStatusUpdater) UpdateProject(ctx models.ProjectCommandCont
 		descripWords = "succeeded."
 	}
 	descrip := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), descripWords)
-	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, descrip, url)
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, withProjectID(ctx, descrip), url)
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) error {
+	if d.SkipCombined {
+		return nil
+	}
+	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
+	cmdVerb := "Planning"
+	if command == models.ApplyCommand {
+		cmdVerb = "Applying"
+	}
+	descrip := fmt.Sprintf("%s %d/%d projects, running %s...", cmdVerb, current, numTotal, fmtElapsed(elapsed))
+	return d.Client.UpdateStatus(repo, pull, models.PendingCommitStatus, src, descrip, "")
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) error {
+	projectID := ctx.ProjectName
+	if projectID == "" {
+		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
+	}
+	src := fmt.Sprintf("%s/%s: %s", d.StatusName, cmdName.String(), projectID)
+	descrip := fmt.Sprintf("%s running %s...", strings.Title(cmdName.String()), fmtElapsed(elapsed))
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, models.PendingCommitStatus, src, withProjectID(ctx, descrip), "")
+}
+
+// withProjectID adds the project's stable ID to the status description
+// descrip. The status's name uses the project's name, which can change, so
+// automation can use the ID to track the project instead.
+func withProjectID(ctx models.ProjectCommandContext, descrip string) string {
+	return fmt.Sprintf("%s Project ID: %s", descrip, ctx.ProjectID())
+}
+
+// fmtElapsed formats d for status descriptions which are short so we only
+// show seconds for the first minute, ex. 45s, 6m or 1h5m.
+func fmtElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	d = d.Truncate(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
 }
@@ -16,6 +16,7 @@ package events_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
@@ -76,6 +77,16 @@ func TestUpdateCombined(t *testing.T) {
 	}
 }
 
+func TestUpdateCombined_SkipCombined(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", SkipCombined: true}
+	Ok(t, s.UpdateCombined(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, models.PlanCommand))
+	Ok(t, s.UpdateCombinedCount(models.Repo{}, models.PullRequest{}, models.SuccessCommitStatus, models.PlanCommand, 1, 1))
+	client.VerifyWasCalled(Never()).UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, "atlantis/plan", "Plan in progress...", "")
+	client.VerifyWasCalled(Never()).UpdateStatus(models.Repo{}, models.PullRequest{}, models.SuccessCommitStatus, "atlantis/plan", "1/1 projects planned successfully.", "")
+}
+
 func TestUpdateCombinedCount(t *testing.T) {
 	cases := []struct {
 		status     models.CommitStatus
@@ -179,7 +190,8 @@ func TestDefaultCommitStatusUpdater_UpdateProjectSrc(t *testing.T) {
 				models.PendingCommitStatus,
 				"url")
 			Ok(t, err)
-			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, c.expSrc, "Plan in progress...", "url")
+			expDescrip := "Plan in progress... Project ID: " + models.ProjectID("", c.repoRelDir, c.workspace)
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, c.expSrc, expDescrip, "url")
 		})
 	}
 }
@@ -236,7 +248,7 @@ func TestDefaultCommitStatusUpdater_UpdateProject(t *testing.T) {
 				c.status,
 				"url")
 			Ok(t, err)
-			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, c.status, fmt.Sprintf("atlantis/%s: ./default", c.cmd.String()), c.expDescrip, "url")
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, c.status, fmt.Sprintf("atlantis/%s: ./default", c.cmd.String()), c.expDescrip+" Project ID: 2aaeaf00c5e1cf70", "url")
 		})
 	}
 }
@@ -255,5 +267,146 @@ func TestDefaultCommitStatusUpdater_UpdateProjectCustomStatusName(t *testing.T)
 		"url")
 	Ok(t, err)
 	client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{},
-		models.SuccessCommitStatus, "custom/apply: ./default", "Apply succeeded.", "url")
+		models.SuccessCommitStatus, "custom/apply: ./default", "Apply succeeded. Project ID: 2aaeaf00c5e1cf70", "url")
+}
+
+func TestDefaultCommitStatusUpdater_UpdateCustomCommand(t *testing.T) {
+	cases := []struct {
+		status     models.CommitStatus
+		expDescrip string
+	}{
+		{
+			status:     models.PendingCommitStatus,
+			expDescrip: "Lint in progress...",
+		},
+		{
+			status:     models.FailedCommitStatus,
+			expDescrip: "Lint failed.",
+		},
+		{
+			status:     models.SuccessCommitStatus,
+			expDescrip: "Lint succeeded.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expDescrip, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockClient()
+			// Custom command statuses are set even if combined statuses are
+			// skipped.
+			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", SkipCombined: true}
+			err := s.UpdateCustomCommand(models.Repo{}, models.PullRequest{}, c.status, "lint")
+			Ok(t, err)
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, c.status, "atlantis/lint", c.expDescrip, "")
+		})
+	}
+}
+
+func TestDefaultCommitStatusUpdater_UpdatePullStatus(t *testing.T) {
+	cases := []struct {
+		status     models.CommitStatus
+		numDone    int
+		expDescrip string
+	}{
+		{
+			status:     models.PendingCommitStatus,
+			numDone:    1,
+			expDescrip: "1/3 projects applied or unchanged.",
+		},
+		{
+			status:     models.FailedCommitStatus,
+			numDone:    2,
+			expDescrip: "2/3 projects applied, fix the failed projects.",
+		},
+		{
+			status:     models.SuccessCommitStatus,
+			numDone:    3,
+			expDescrip: "3/3 projects applied or unchanged.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expDescrip, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockClient()
+			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis-dev", SkipCombined: true}
+			err := s.UpdatePullStatus(models.Repo{}, models.PullRequest{}, c.status, c.numDone, 3)
+			Ok(t, err)
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, c.status, "atlantis-dev/status", c.expDescrip, "")
+		})
+	}
+}
+
+func TestDefaultCommitStatusUpdater_UpdateDiscarded(t *testing.T) {
+	lock := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "dir"),
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 1},
+	}
+	cases := []struct {
+		skipCombined bool
+		expSrc       string
+	}{
+		{
+			skipCombined: false,
+			expSrc:       "atlantis/plan",
+		},
+		{
+			skipCombined: true,
+			expSrc:       "atlantis/plan: dir/default",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expSrc, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockClient()
+			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", SkipCombined: c.skipCombined}
+			err := s.UpdateDiscarded(lock)
+			Ok(t, err)
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, lock.Pull, models.FailedCommitStatus, c.expSrc, "Plan for dir/default discarded, run plan again.", "")
+		})
+	}
+}
+
+func TestDefaultCommitStatusUpdater_UpdateCombinedProgress(t *testing.T) {
+	RegisterMockTestingT(t)
+	cases := []struct {
+		cmd        models.CommandName
+		elapsed    time.Duration
+		expDescrip string
+	}{
+		{models.PlanCommand, 45 * time.Second, "Planning 4/12 projects, running 45s..."},
+		{models.ApplyCommand, 6*time.Minute + 30*time.Second, "Applying 4/12 projects, running 6m..."},
+		{models.PlanCommand, 65 * time.Minute, "Planning 4/12 projects, running 1h5m..."},
+	}
+	for _, c := range cases {
+		t.Run(c.expDescrip, func(t *testing.T) {
+			client := mocks.NewMockClient()
+			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis"}
+			Ok(t, s.UpdateCombinedProgress(models.Repo{}, models.PullRequest{}, c.cmd, 4, 12, c.elapsed))
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, fmt.Sprintf("atlantis/%s", c.cmd.String()), c.expDescrip, "")
+		})
+	}
+}
+
+func TestDefaultCommitStatusUpdater_UpdateCombinedProgressSkipCombined(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", SkipCombined: true}
+	Ok(t, s.UpdateCombinedProgress(models.Repo{}, models.PullRequest{}, models.PlanCommand, 1, 2, time.Minute))
+	client.VerifyWasCalled(Never()).UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, "atlantis/plan", "Planning 1/2 projects, running 1m...", "")
+}
+
+func TestDefaultCommitStatusUpdater_UpdateProjectProgress(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis"}
+	err := s.UpdateProjectProgress(models.ProjectCommandContext{
+		RepoRelDir: ".",
+		Workspace:  "default",
+	}, models.ApplyCommand, 6*time.Minute)
+	Ok(t, err)
+	client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, "atlantis/apply: ./default", "Apply running 6m... Project ID: 2aaeaf00c5e1cf70", "")
 }
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// ConcurrencyLimiter limits how many Terraform operations can run at the same
+// time for projects in the same concurrency group. This is used to prevent
+// many projects that share a set of credentials, ex. a single AWS account,
+// from hitting that account's API rate limits when they're all planned at
+// once.
+//
+// It also enforces the max_concurrent and max_concurrent_per_project limits
+// of each repo's concurrency config so one busy repo can't take all of the
+// server's capacity. Operations waiting for a slot get it in order of their
+// repo's priority, then in the order they started waiting.
+type ConcurrencyLimiter struct {
+	mu sync.Mutex
+	// groups maps from the group name to its semaphore. The map is never
+	// written to after construction.
+	groups map[string]*prioritySemaphore
+	// repos and projects map from a repo or project to its semaphore. They're
+	// created when first needed and deleted once they're unused since their
+	// limits come from the repo config which can be reloaded.
+	repos    map[string]*prioritySemaphore
+	projects map[string]*prioritySemaphore
+	// seq orders waiters with the same priority.
+	seq uint64
+}
+
+// prioritySemaphore lets up to limit holders hold it at once. It's guarded by
+// its ConcurrencyLimiter's mu.
+type prioritySemaphore struct {
+	limit   int
+	held    int
+	waiters []semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// NewConcurrencyLimiter returns a limiter for groups.
+func NewConcurrencyLimiter(groups map[string]valid.ConcurrencyGroup) *ConcurrencyLimiter {
+	semaphores := make(map[string]*prioritySemaphore)
+	for name, g := range groups {
+		semaphores[name] = &prioritySemaphore{limit: g.MaxConcurrent}
+	}
+	return &ConcurrencyLimiter{
+		groups:   semaphores,
+		repos:    make(map[string]*prioritySemaphore),
+		projects: make(map[string]*prioritySemaphore),
+	}
+}
+
+// Acquire blocks until an operation can run for the project of ctx within its
+// project's, repo's and concurrency group's limits. It returns a function
+// that must be called to release the slots once the operation is complete.
+// If none of the limits are set, Acquire returns immediately.
+func (c *ConcurrencyLimiter) Acquire(ctx models.ProjectCommandContext) func() {
+	if c == nil {
+		return func() {}
+	}
+	priority := ctx.Concurrency.Priority
+	var releases []func()
+	// The slots are always acquired in the same order so two operations
+	// can't each hold a slot the other is waiting for.
+	if limit := ctx.Concurrency.MaxConcurrentPerProject; limit > 0 {
+		key := fmt.Sprintf("%s/%s/%s", ctx.BaseRepo.FullName, ctx.RepoRelDir, ctx.Workspace)
+		releases = append(releases, c.acquireDynamic(ctx.Log, c.projects, key, "project", limit, priority))
+	}
+	if limit := ctx.Concurrency.MaxConcurrent; limit > 0 {
+		releases = append(releases, c.acquireDynamic(ctx.Log, c.repos, ctx.BaseRepo.FullName, "repo", limit, priority))
+	}
+	if group := ctx.ConcurrencyGroup; group != "" {
+		if sem, ok := c.groups[group]; ok {
+			c.acquire(ctx.Log, sem, fmt.Sprintf("concurrency group %q", group), priority)
+			releases = append(releases, func() { c.release(sem) })
+		} else {
+			ctx.Log.Warn("concurrency group %q is not configured, not limiting", group)
+		}
+	}
+	return func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+}
+
+// acquireDynamic acquires the semaphore for key in semaphores, creating it
+// with limit if it doesn't exist. The semaphore is deleted once it's released
+// by every holder so changes to limit take effect once it's unused.
+func (c *ConcurrencyLimiter) acquireDynamic(log *logging.SimpleLogger, semaphores map[string]*prioritySemaphore, key string, kind string, limit int, priority int) func() {
+	c.mu.Lock()
+	sem, ok := semaphores[key]
+	if !ok {
+		sem = &prioritySemaphore{limit: limit}
+		semaphores[key] = sem
+	}
+	c.mu.Unlock()
+
+	c.acquire(log, sem, fmt.Sprintf("%s %q", kind, key), priority)
+	return func() {
+		c.release(sem)
+		c.mu.Lock()
+		if sem.held == 0 && len(sem.waiters) == 0 && semaphores[key] == sem {
+			delete(semaphores, key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// acquire blocks until sem can be held. description describes sem in logs.
+func (c *ConcurrencyLimiter) acquire(log *logging.SimpleLogger, sem *prioritySemaphore, description string, priority int) {
+	c.mu.Lock()
+	if sem.held < sem.limit && len(sem.waiters) == 0 {
+		sem.held++
+		c.mu.Unlock()
+		return
+	}
+	c.seq++
+	w := semaphoreWaiter{priority: priority, seq: c.seq, ready: make(chan struct{})}
+	sem.waiters = append(sem.waiters, w)
+	sort.Slice(sem.waiters, func(i, j int) bool {
+		if sem.waiters[i].priority != sem.waiters[j].priority {
+			return sem.waiters[i].priority > sem.waiters[j].priority
+		}
+		return sem.waiters[i].seq < sem.waiters[j].seq
+	})
+	limit := sem.limit
+	c.mu.Unlock()
+
+	log.Info("%s is at its limit of %d, waiting for a running operation to complete", description, limit)
+	start := time.Now()
+	<-w.ready
+	log.Info("waited %s for %s", time.Since(start).Round(time.Millisecond), description)
+}
+
+// release releases a hold on sem and hands it to the next waiter, if any.
+func (c *ConcurrencyLimiter) release(sem *prioritySemaphore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sem.held--
+	for sem.held < sem.limit && len(sem.waiters) > 0 {
+		next := sem.waiters[0]
+		sem.waiters = sem.waiters[1:]
+		sem.held++
+		close(next.ready)
+	}
+}
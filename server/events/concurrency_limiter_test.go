@@ -0,0 +1,153 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+func TestConcurrencyLimiter_NilLimiter(t *testing.T) {
+	var limiter *events.ConcurrencyLimiter
+	release := limiter.Acquire(groupCtx("group"))
+	release()
+}
+
+func TestConcurrencyLimiter_UnknownGroup(t *testing.T) {
+	limiter := events.NewConcurrencyLimiter(nil)
+	t.Log("unconfigured groups should never block")
+	limiter.Acquire(groupCtx("unknown"))
+	limiter.Acquire(groupCtx("unknown"))
+	limiter.Acquire(groupCtx(""))
+}
+
+func TestConcurrencyLimiter_BlocksAtLimit(t *testing.T) {
+	limiter := events.NewConcurrencyLimiter(map[string]valid.ConcurrencyGroup{
+		"aws-prod": {Name: "aws-prod", MaxConcurrent: 1},
+	})
+
+	release := limiter.Acquire(groupCtx("aws-prod"))
+
+	t.Log("other groups shouldn't be affected")
+	limiter.Acquire(groupCtx("other"))
+
+	acquired := make(chan struct{})
+	go func() {
+		limiter.Acquire(groupCtx("aws-prod"))()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second acquire should have blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second acquire should have succeeded after release")
+	}
+
+	t.Log("after both are released we should be able to acquire again")
+	release = limiter.Acquire(groupCtx("aws-prod"))
+	release()
+}
+
+func TestConcurrencyLimiter_RepoAndProjectLimits(t *testing.T) {
+	limiter := events.NewConcurrencyLimiter(nil)
+	concurrency := valid.Concurrency{MaxConcurrent: 2, MaxConcurrentPerProject: 1}
+	app := projectCtx("owner/monorepo", "app", concurrency)
+	vpc := projectCtx("owner/monorepo", "vpc", concurrency)
+
+	releaseApp := limiter.Acquire(app)
+	t.Log("other projects of the repo shouldn't be affected by the project limit")
+	releaseVPC := limiter.Acquire(vpc)
+	t.Log("other repos shouldn't be affected by the repo limit")
+	limiter.Acquire(projectCtx("owner/small", "app", concurrency))()
+
+	projectAcquired := acquireAsync(limiter, app)
+	assertBlocked(t, projectAcquired)
+	repoAcquired := acquireAsync(limiter, projectCtx("owner/monorepo", "dns", concurrency))
+	assertBlocked(t, repoAcquired)
+
+	releaseVPC()
+	receiveRelease(t, repoAcquired)()
+	assertBlocked(t, projectAcquired)
+	releaseApp()
+	receiveRelease(t, projectAcquired)()
+}
+
+func TestConcurrencyLimiter_HigherPrioritiesFirst(t *testing.T) {
+	limiter := events.NewConcurrencyLimiter(map[string]valid.ConcurrencyGroup{
+		"aws-prod": {Name: "aws-prod", MaxConcurrent: 1},
+	})
+	release := limiter.Acquire(groupCtx("aws-prod"))
+
+	monorepo := groupCtx("aws-prod")
+	monorepo.Concurrency.Priority = -1
+	monorepoAcquired := acquireAsync(limiter, monorepo)
+	assertBlocked(t, monorepoAcquired)
+	small := groupCtx("aws-prod")
+	small.Concurrency.Priority = 10
+	smallAcquired := acquireAsync(limiter, small)
+	assertBlocked(t, smallAcquired)
+
+	release()
+	releaseSmall := receiveRelease(t, smallAcquired)
+	assertBlocked(t, monorepoAcquired)
+	releaseSmall()
+	receiveRelease(t, monorepoAcquired)()
+}
+
+func groupCtx(group string) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log:              logging.NewNoopLogger(),
+		BaseRepo:         models.Repo{FullName: "owner/repo"},
+		ConcurrencyGroup: group,
+	}
+}
+
+func projectCtx(repoFullName string, dir string, concurrency valid.Concurrency) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log:         logging.NewNoopLogger(),
+		BaseRepo:    models.Repo{FullName: repoFullName},
+		RepoRelDir:  dir,
+		Workspace:   "default",
+		Concurrency: concurrency,
+	}
+}
+
+// acquireAsync acquires ctx's slots in a goroutine and sends the function
+// that releases them once they're acquired.
+func acquireAsync(limiter *events.ConcurrencyLimiter, ctx models.ProjectCommandContext) chan func() {
+	acquired := make(chan func(), 1)
+	go func() {
+		acquired <- limiter.Acquire(ctx)
+	}()
+	return acquired
+}
+
+func assertBlocked(t *testing.T, acquired chan func()) {
+	t.Helper()
+	select {
+	case <-acquired:
+		t.Fatal("acquire should have blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func receiveRelease(t *testing.T, acquired chan func()) func() {
+	t.Helper()
+	select {
+	case release := <-acquired:
+		return release
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for acquire")
+		return nil
+	}
+}
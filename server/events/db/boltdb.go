@@ -3,6 +3,7 @@ package db
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -23,9 +24,16 @@ type BoltDB struct {
 }
 
 const (
-	locksBucketName  = "runLocks"
-	pullsBucketName  = "pulls"
-	pullKeySeparator = "::"
+	locksBucketName        = "runLocks"
+	pullsBucketName        = "pulls"
+	apiTokensBucketName    = "apiTokens"
+	auditBucketName        = "auditLog"
+	onboardedBucketName    = "onboardedRepos"
+	planOutputsBucketName  = "planOutputs"
+	featureFlagsBucketName = "featureFlags"
+	eventQueueBucketName   = "eventQueue"
+	failedEventsBucketName = "failedEvents"
+	pullKeySeparator       = "::"
 )
 
 // New returns a valid locker. We need to be able to write to dataDir
@@ -50,6 +58,27 @@ func New(dataDir string) (*BoltDB, error) {
 		if _, err = tx.CreateBucketIfNotExists([]byte(pullsBucketName)); err != nil {
 			return errors.Wrapf(err, "creating bucket %q", pullsBucketName)
 		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(apiTokensBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", apiTokensBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(auditBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", auditBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(onboardedBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", onboardedBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(planOutputsBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", planOutputsBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(featureFlagsBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", featureFlagsBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(eventQueueBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", eventQueueBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(failedEventsBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", failedEventsBucketName)
+		}
 		return nil
 	})
 	if err != nil {
@@ -59,6 +88,12 @@ func New(dataDir string) (*BoltDB, error) {
 	return &BoltDB{db: db, locksBucketName: []byte(locksBucketName), pullsBucketName: []byte(pullsBucketName)}, nil
 }
 
+// Close closes the database file so it can be opened again, ex. by another
+// process.
+func (b *BoltDB) Close() error {
+	return b.db.Close()
+}
+
 // NewWithDB is used for testing.
 func NewWithDB(db *bolt.DB, bucket string) (*BoltDB, error) {
 	return &BoltDB{db: db, locksBucketName: []byte(bucket), pullsBucketName: []byte(pullsBucketName)}, nil
@@ -147,7 +182,7 @@ func (b *BoltDB) List() ([]models.ProjectLock, error) {
 	for k, v := range locksBytes {
 		var lock models.ProjectLock
 		if err := json.Unmarshal(v, &lock); err != nil {
-			return locks, errors.Wrap(err, fmt.Sprintf("failed to deserialize lock at key %q", string(k)))
+			return locks, errors.Wrap(err, fmt.Sprintf("failed to deserialize lock at index %d", k))
 		}
 		locks = append(locks, lock)
 	}
@@ -167,7 +202,8 @@ func (b *BoltDB) UnlockByPull(repoFullName string, pullNum int) ([]models.Projec
 			if err := json.Unmarshal(v, &lock); err != nil {
 				return errors.Wrapf(err, "deserializing lock at key %q", string(k))
 			}
-			if lock.Pull.Num == pullNum {
+			// Other repos can start with repoFullName, ex. owner/repo2.
+			if lock.Project.RepoFullName == repoFullName && lock.Pull.Num == pullNum {
 				locks = append(locks, lock)
 			}
 		}
@@ -230,48 +266,7 @@ func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []mod
 			return err
 		}
 
-		// If there is no pull OR if the pull we have is out of date, we
-		// just write a new pull.
-		if currStatus == nil || currStatus.Pull.HeadCommit != pull.HeadCommit {
-			var statuses []models.ProjectStatus
-			for _, r := range newResults {
-				statuses = append(statuses, b.projectResultToProject(r))
-			}
-			newStatus = models.PullStatus{
-				Pull:     pull,
-				Projects: statuses,
-			}
-		} else {
-			// If there's an existing pull at the right commit then we have to
-			// merge our project results with the existing ones. We do a merge
-			// because it's possible a user is just applying a single project
-			// in this command and so we don't want to delete our data about
-			// other projects that aren't affected by this command.
-			newStatus = *currStatus
-			for _, res := range newResults {
-				// First, check if we should update any existing projects.
-				updatedExisting := false
-				for i := range newStatus.Projects {
-					// NOTE: We're using a reference here because we are
-					// in-place updating its Status field.
-					proj := &newStatus.Projects[i]
-					if res.Workspace == proj.Workspace &&
-						res.RepoRelDir == proj.RepoRelDir &&
-						res.ProjectName == proj.ProjectName {
-
-						proj.Status = res.PlanStatus()
-						updatedExisting = true
-						break
-					}
-				}
-
-				if !updatedExisting {
-					// If we didn't update an existing project, then we need to
-					// add this because it's a new one.
-					newStatus.Projects = append(newStatus.Projects, b.projectResultToProject(res))
-				}
-			}
-		}
+		newStatus = MergePullStatus(currStatus, pull, newResults)
 
 		// Now, we overwrite the key with our new status.
 		return b.writePullToBucket(bucket, key, newStatus)
@@ -279,6 +274,18 @@ func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []mod
 	return newStatus, errors.Wrap(err, "DB transaction failed")
 }
 
+// PutPullStatus stores status, replacing the status of its pull request.
+func (b *BoltDB) PutPullStatus(status models.PullStatus) error {
+	key, err := b.pullKey(status.Pull)
+	if err != nil {
+		return err
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return b.writePullToBucket(tx.Bucket(b.pullsBucketName), key, status)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
 // GetPullStatus returns the status for pull.
 // If there is no status, returns a nil pointer.
 func (b *BoltDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
@@ -296,6 +303,27 @@ func (b *BoltDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, err
 	return s, errors.Wrap(err, "DB transaction failed")
 }
 
+// ListPullStatuses returns the statuses of every pull request.
+func (b *BoltDB) ListPullStatuses() ([]models.PullStatus, error) {
+	var statuses []models.PullStatus
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.pullsBucketName).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var s models.PullStatus
+			if err := json.Unmarshal(v, &s); err != nil {
+				return errors.Wrapf(err, "deserializing pull at %q with contents %q", k, v)
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	SortPullStatuses(statuses)
+	return statuses, nil
+}
+
 // DeletePullStatus deletes the status for pull.
 func (b *BoltDB) DeletePullStatus(pull models.PullRequest) error {
 	key, err := b.pullKey(pull)
@@ -325,25 +353,371 @@ func (b *BoltDB) DeleteProjectStatus(pull models.PullRequest, workspace string,
 		if currStatusPtr == nil {
 			return nil
 		}
-		currStatus := *currStatusPtr
+		return b.writePullToBucket(bucket, key, WithoutProjectStatus(*currStatusPtr, workspace, repoRelDir))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// CreateAPIToken saves token. It returns false if a token with the same name
+// already exists.
+func (b *BoltDB) CreateAPIToken(token models.APIToken) (bool, error) {
+	var created bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiTokensBucketName))
+		if bucket.Get([]byte(token.Name)) != nil {
+			return nil
+		}
+		serialized, err := json.Marshal(token)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		created = true
+		return bucket.Put([]byte(token.Name), serialized)
+	})
+	return created, errors.Wrap(err, "DB transaction failed")
+}
+
+// ListAPITokens returns all the API tokens.
+func (b *BoltDB) ListAPITokens() ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiTokensBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var token models.APIToken
+			if err := json.Unmarshal(v, &token); err != nil {
+				return errors.Wrapf(err, "deserializing API token %q", string(k))
+			}
+			tokens = append(tokens, token)
+			return nil
+		})
+	})
+	return tokens, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteAPIToken deletes the API token named name. It returns false if no
+// token with that name exists.
+func (b *BoltDB) DeleteAPIToken(name string) (bool, error) {
+	var deleted bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiTokensBucketName))
+		if bucket.Get([]byte(name)) == nil {
+			return nil
+		}
+		deleted = true
+		return bucket.Delete([]byte(name))
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
+// PutFeatureFlag saves flag, replacing any existing flag with the same name.
+func (b *BoltDB) PutFeatureFlag(flag models.FeatureFlag) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(featureFlagsBucketName))
+		serialized, err := json.Marshal(flag)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put([]byte(flag.Name), serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListFeatureFlags returns all the feature flags.
+func (b *BoltDB) ListFeatureFlags() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(featureFlagsBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var flag models.FeatureFlag
+			if err := json.Unmarshal(v, &flag); err != nil {
+				return errors.Wrapf(err, "deserializing feature flag %q", string(k))
+			}
+			flags = append(flags, flag)
+			return nil
+		})
+	})
+	return flags, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteFeatureFlag deletes the feature flag named name. It returns false if
+// no flag with that name exists.
+func (b *BoltDB) DeleteFeatureFlag(name string) (bool, error) {
+	var deleted bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(featureFlagsBucketName))
+		if bucket.Get([]byte(name)) == nil {
+			return nil
+		}
+		deleted = true
+		return bucket.Delete([]byte(name))
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
+// PutOnboardedRepo saves repo, replacing any existing repo with the same ID.
+func (b *BoltDB) PutOnboardedRepo(repo models.OnboardedRepo) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(onboardedBucketName))
+		serialized, err := json.Marshal(repo)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put([]byte(strings.ToLower(repo.ID)), serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListOnboardedRepos returns all the onboarded repos.
+func (b *BoltDB) ListOnboardedRepos() ([]models.OnboardedRepo, error) {
+	var repos []models.OnboardedRepo
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(onboardedBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var repo models.OnboardedRepo
+			if err := json.Unmarshal(v, &repo); err != nil {
+				return errors.Wrapf(err, "deserializing onboarded repo %q", string(k))
+			}
+			repos = append(repos, repo)
+			return nil
+		})
+	})
+	return repos, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteOnboardedRepo deletes the onboarded repo with id. It returns false if
+// the repo wasn't onboarded. It doesn't delete the repo's webhook.
+func (b *BoltDB) DeleteOnboardedRepo(id string) (bool, error) {
+	var deleted bool
+	key := []byte(strings.ToLower(id))
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(onboardedBucketName))
+		if bucket.Get(key) == nil {
+			return nil
+		}
+		deleted = true
+		return bucket.Delete(key)
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
+// AppendAuditEvent adds event to the end of the audit log and returns it with
+// its ID set. Audit events can't be modified or deleted.
+func (b *BoltDB) AppendAuditEvent(event models.AuditEvent) (models.AuditEvent, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditBucketName))
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return errors.Wrap(err, "generating id")
+		}
+		event.ID = id
+		serialized, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put(sequenceKey(id), serialized)
+	})
+	return event, errors.Wrap(err, "DB transaction failed")
+}
+
+// ListAuditEvents returns the audit events that happened at or after since,
+// oldest first. If since is the zero time all events are returned.
+func (b *BoltDB) ListAuditEvents(since time.Time) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var event models.AuditEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return errors.Wrapf(err, "deserializing audit event %d", binary.BigEndian.Uint64(k))
+			}
+			if !event.Time.Before(since) {
+				events = append(events, event)
+			}
+			return nil
+		})
+	})
+	return events, errors.Wrap(err, "DB transaction failed")
+}
+
+// PutPlanOutput saves output, replacing the output of any previous plan, or
+// apply if output is of an apply, of the same project in the same pull
+// request. It returns the ID to get the output with.
+func (b *BoltDB) PutPlanOutput(output models.PlanOutput) (string, error) {
+	pullKey, err := b.pullKey(output.Pull)
+	if err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%s%s%s%s%s", pullKey, pullKeySeparator, output.Workspace, pullKeySeparator, path.Clean(output.RepoRelDir))
+	if output.Apply {
+		// Applies get their own ID so they don't replace the output of the
+		// plan that the plan's comment links to.
+		id += pullKeySeparator + "apply"
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(planOutputsBucketName))
+		serialized, err := json.Marshal(output)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put([]byte(id), serialized)
+	})
+	return id, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetPlanOutput returns the plan output with id or nil if there isn't one.
+func (b *BoltDB) GetPlanOutput(id string) (*models.PlanOutput, error) {
+	var output *models.PlanOutput
+	err := b.db.View(func(tx *bolt.Tx) error {
+		serialized := tx.Bucket([]byte(planOutputsBucketName)).Get([]byte(id))
+		if serialized == nil {
+			return nil
+		}
+		output = &models.PlanOutput{}
+		return errors.Wrapf(json.Unmarshal(serialized, output), "deserializing plan output %q", id)
+	})
+	return output, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeletePlanOutputs deletes the plan outputs of every project in pull.
+func (b *BoltDB) DeletePlanOutputs(pull models.PullRequest) error {
+	pullKey, err := b.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	prefix := append(pullKey, []byte(pullKeySeparator)...)
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(planOutputsBucketName)).Cursor()
+		// Seek again after each delete since deleting while iterating
+		// can skip keys.
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// PutQueuedEvent stores event, replacing the stored event with the same ID.
+// If event's ID is 0 it's assigned the next ID. It returns event with its ID
+// set.
+func (b *BoltDB) PutQueuedEvent(event models.QueuedEvent) (models.QueuedEvent, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(eventQueueBucketName))
+		if event.ID == 0 {
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return errors.Wrap(err, "generating id")
+			}
+			event.ID = id
+		}
+		serialized, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put(sequenceKey(event.ID), serialized)
+	})
+	return event, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteQueuedEvent deletes the queued event with id. It's not an error if
+// there isn't one.
+func (b *BoltDB) DeleteQueuedEvent(id uint64) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(eventQueueBucketName)).Delete(sequenceKey(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
 
-		// Create a new projectStatuses array without the ones we want to
-		// delete.
-		var newProjects []models.ProjectStatus
-		for _, p := range currStatus.Projects {
-			if p.Workspace == workspace && p.RepoRelDir == repoRelDir {
-				continue
+// ListQueuedEvents returns the stored queued events, oldest first.
+func (b *BoltDB) ListQueuedEvents() ([]models.QueuedEvent, error) {
+	var events []models.QueuedEvent
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(eventQueueBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var event models.QueuedEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return errors.Wrapf(err, "deserializing queued event %d", binary.BigEndian.Uint64(k))
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	return events, errors.Wrap(err, "DB transaction failed")
+}
+
+// PutFailedEvent stores event, replacing the stored event with the same ID.
+// If event's ID is 0 it's assigned the next ID. It returns event with its ID
+// set.
+func (b *BoltDB) PutFailedEvent(event models.FailedEvent) (models.FailedEvent, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(failedEventsBucketName))
+		if event.ID == 0 {
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return errors.Wrap(err, "generating id")
 			}
-			newProjects = append(newProjects, p)
+			event.ID = id
 		}
+		serialized, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		return bucket.Put(sequenceKey(event.ID), serialized)
+	})
+	return event, errors.Wrap(err, "DB transaction failed")
+}
 
-		// Overwrite the old pull status.
-		currStatus.Projects = newProjects
-		return b.writePullToBucket(bucket, key, currStatus)
+// GetFailedEvent returns the failed event with id or nil if there isn't one.
+func (b *BoltDB) GetFailedEvent(id uint64) (*models.FailedEvent, error) {
+	var event *models.FailedEvent
+	err := b.db.View(func(tx *bolt.Tx) error {
+		serialized := tx.Bucket([]byte(failedEventsBucketName)).Get(sequenceKey(id))
+		if serialized == nil {
+			return nil
+		}
+		event = &models.FailedEvent{}
+		return errors.Wrapf(json.Unmarshal(serialized, event), "deserializing failed event %d", id)
+	})
+	return event, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteFailedEvent deletes the failed event with id. It's not an error if
+// there isn't one.
+func (b *BoltDB) DeleteFailedEvent(id uint64) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(failedEventsBucketName)).Delete(sequenceKey(id))
 	})
 	return errors.Wrap(err, "DB transaction failed")
 }
 
+// ListFailedEvents returns the stored failed events, oldest first.
+func (b *BoltDB) ListFailedEvents() ([]models.FailedEvent, error) {
+	var events []models.FailedEvent
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(failedEventsBucketName))
+		return bucket.ForEach(func(k, v []byte) error {
+			var event models.FailedEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return errors.Wrapf(err, "deserializing failed event %d", binary.BigEndian.Uint64(k))
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	return events, errors.Wrap(err, "DB transaction failed")
+}
+
+// sequenceKey returns the key for the audit, queued or failed event with id.
+// Keys are big endian so that BoltDB's byte ordering matches the order events
+// were added.
+func sequenceKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
 func (b *BoltDB) pullKey(pull models.PullRequest) ([]byte, error) {
 	hostname := pull.BaseRepo.VCSHost.Hostname
 	if strings.Contains(hostname, pullKeySeparator) {
@@ -382,12 +756,3 @@ func (b *BoltDB) writePullToBucket(bucket *bolt.Bucket, key []byte, pull models.
 	}
 	return bucket.Put(key, serialized)
 }
-
-func (b *BoltDB) projectResultToProject(p models.ProjectResult) models.ProjectStatus {
-	return models.ProjectStatus{
-		Workspace:   p.Workspace,
-		RepoRelDir:  p.RepoRelDir,
-		ProjectName: p.ProjectName,
-		Status:      p.PlanStatus(),
-	}
-}
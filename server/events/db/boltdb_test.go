@@ -14,12 +14,14 @@
 package db_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/db/dbtest"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -43,6 +45,12 @@ var lock = models.ProjectLock{
 	Time:      time.Now(),
 }
 
+func TestBoltDB_Store(t *testing.T) {
+	dbtest.TestStore(t, func(t *testing.T) (db.Store, func()) {
+		return newTestDB2(t)
+	})
+}
+
 func TestListNoLocks(t *testing.T) {
 	t.Log("listing locks when there are none should return an empty list")
 	db, b := newTestDB()
@@ -404,6 +412,44 @@ func TestPullStatus_UpdateGet(t *testing.T) {
 	}, status.Projects)
 }
 
+// Test that statuses are listed by repo and pull number.
+func TestPullStatus_List(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	statuses, err := b.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	repo := models.Repo{
+		FullName: "runatlantis/atlantis",
+		VCSHost:  models.VCSHost{Hostname: "github.com"},
+	}
+	otherRepo := models.Repo{
+		FullName: "runatlantis/helm-charts",
+		VCSHost:  models.VCSHost{Hostname: "github.com"},
+	}
+	for _, pull := range []models.PullRequest{
+		{Num: 10, BaseRepo: repo},
+		{Num: 1, BaseRepo: otherRepo},
+		{Num: 9, BaseRepo: repo},
+	} {
+		_, err := b.UpdatePullWithResults(pull, []models.ProjectResult{
+			{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		})
+		Ok(t, err)
+	}
+
+	statuses, err = b.ListPullStatuses()
+	Ok(t, err)
+	var pulls []string
+	for _, s := range statuses {
+		pulls = append(pulls, fmt.Sprintf("%s#%d", s.Pull.BaseRepo.FullName, s.Pull.Num))
+		Equals(t, 1, len(s.Projects))
+	}
+	Equals(t, []string{"runatlantis/atlantis#9", "runatlantis/atlantis#10", "runatlantis/helm-charts#1"}, pulls)
+}
+
 // Test we can create a status, delete it, and then we shouldn't be able to get
 // it.
 func TestPullStatus_UpdateDeleteGet(t *testing.T) {
@@ -623,6 +669,7 @@ func TestPullStatus_UpdateMerge(t *testing.T) {
 					LockURL:         "lock-url",
 					RePlanCmd:       "plan command",
 					ApplyCmd:        "apply command",
+					Fingerprint:     models.PlanFingerprint{HeadCommit: "sha", BaseCommit: "base-sha"},
 				},
 			},
 		})
@@ -672,9 +719,10 @@ func TestPullStatus_UpdateMerge(t *testing.T) {
 				Status:      models.ErroredApplyStatus,
 			},
 			{
-				RepoRelDir: "staythesame",
-				Workspace:  "default",
-				Status:     models.PlannedPlanStatus,
+				RepoRelDir:      "staythesame",
+				Workspace:       "default",
+				Status:          models.PlannedPlanStatus,
+				PlanFingerprint: models.PlanFingerprint{HeadCommit: "sha", BaseCommit: "base-sha"},
 			},
 			{
 				RepoRelDir: "newresult",
@@ -686,6 +734,167 @@ func TestPullStatus_UpdateMerge(t *testing.T) {
 }
 
 // newTestDB returns a TestDB using a temporary path.
+func TestAPITokens_CreateListDelete(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	tokens, err := b.ListAPITokens()
+	Ok(t, err)
+	Equals(t, 0, len(tokens))
+
+	token := models.APIToken{
+		Name:      "ci",
+		Hash:      "abc123",
+		Scopes:    []string{"locks:read"},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	created, err := b.CreateAPIToken(token)
+	Ok(t, err)
+	Equals(t, true, created)
+
+	// Names must be unique.
+	created, err = b.CreateAPIToken(models.APIToken{Name: "ci", Hash: "def456"})
+	Ok(t, err)
+	Equals(t, false, created)
+
+	tokens, err = b.ListAPITokens()
+	Ok(t, err)
+	Equals(t, []models.APIToken{token}, tokens)
+
+	deleted, err := b.DeleteAPIToken("ci")
+	Ok(t, err)
+	Equals(t, true, deleted)
+	deleted, err = b.DeleteAPIToken("ci")
+	Ok(t, err)
+	Equals(t, false, deleted)
+
+	tokens, err = b.ListAPITokens()
+	Ok(t, err)
+	Equals(t, 0, len(tokens))
+}
+
+func TestOnboardedRepos_PutListDelete(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	repos, err := b.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+
+	repo := models.OnboardedRepo{
+		ID:          "github.com/owner/repo",
+		WebhookID:   "1",
+		OnboardedBy: "api-token:ci",
+		OnboardedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	Ok(t, b.PutOnboardedRepo(repo))
+
+	// Onboarding again replaces the repo, even if the case differs.
+	repo.ID = "github.com/Owner/Repo"
+	repo.WebhookID = "2"
+	Ok(t, b.PutOnboardedRepo(repo))
+	repos, err = b.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, []models.OnboardedRepo{repo}, repos)
+
+	deleted, err := b.DeleteOnboardedRepo("github.com/owner/repo")
+	Ok(t, err)
+	Equals(t, true, deleted)
+	deleted, err = b.DeleteOnboardedRepo("github.com/owner/repo")
+	Ok(t, err)
+	Equals(t, false, deleted)
+
+	repos, err = b.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+}
+
+func TestFeatureFlags_PutListDelete(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	flags, err := b.ListFeatureFlags()
+	Ok(t, err)
+	Equals(t, 0, len(flags))
+
+	flag := models.FeatureFlag{
+		Name:  "parallel-apply",
+		Repos: []string{"github.com/owner/repo"},
+	}
+	Ok(t, b.PutFeatureFlag(flag))
+
+	// Putting a flag with the same name replaces it.
+	flag.Repos = nil
+	flag.Percentage = 10
+	Ok(t, b.PutFeatureFlag(flag))
+	flags, err = b.ListFeatureFlags()
+	Ok(t, err)
+	Equals(t, []models.FeatureFlag{flag}, flags)
+
+	deleted, err := b.DeleteFeatureFlag("parallel-apply")
+	Ok(t, err)
+	Equals(t, true, deleted)
+	deleted, err = b.DeleteFeatureFlag("parallel-apply")
+	Ok(t, err)
+	Equals(t, false, deleted)
+}
+
+func TestPlanOutputs_PutGetDelete(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull1 := models.PullRequest{Num: 1, BaseRepo: repo}
+	pull10 := models.PullRequest{Num: 10, BaseRepo: repo}
+
+	output := models.PlanOutput{
+		Pull:       pull1,
+		RepoRelDir: "./dir",
+		Workspace:  "default",
+		Output:     "first",
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	id, err := b.PutPlanOutput(output)
+	Ok(t, err)
+	Equals(t, "github.com::owner/repo::1::default::dir", id)
+
+	// Planning the same project again replaces the output.
+	output.Output = "second"
+	id2, err := b.PutPlanOutput(output)
+	Ok(t, err)
+	Equals(t, id, id2)
+	got, err := b.GetPlanOutput(id)
+	Ok(t, err)
+	Equals(t, &output, got)
+
+	// Applies don't replace the plan's output.
+	applyOutput := output
+	applyOutput.Output = "apply"
+	applyOutput.Apply = true
+	applyID, err := b.PutPlanOutput(applyOutput)
+	Ok(t, err)
+	Equals(t, "github.com::owner/repo::1::default::dir::apply", applyID)
+	got, err = b.GetPlanOutput(id)
+	Ok(t, err)
+	Equals(t, &output, got)
+
+	other := output
+	other.Pull = pull10
+	otherID, err := b.PutPlanOutput(other)
+	Ok(t, err)
+
+	// Deleting pull 1's outputs shouldn't delete pull 10's.
+	Ok(t, b.DeletePlanOutputs(pull1))
+	got, err = b.GetPlanOutput(id)
+	Ok(t, err)
+	Assert(t, got == nil, "exp output to be deleted")
+	got, err = b.GetPlanOutput(applyID)
+	Ok(t, err)
+	Assert(t, got == nil, "exp apply output to be deleted")
+	got, err = b.GetPlanOutput(otherID)
+	Ok(t, err)
+	Equals(t, &other, got)
+}
+
 func newTestDB() (*bolt.DB, *db.BoltDB) {
 	// Retrieve a temporary path.
 	f, err := ioutil.TempFile("", "")
@@ -725,3 +934,97 @@ func cleanupDB(db *bolt.DB) {
 	os.Remove(db.Path()) // nolint: errcheck
 	db.Close()           // nolint: errcheck
 }
+
+func TestAuditEvents_AppendList(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	events, err := b.ListAuditEvents(time.Time{})
+	Ok(t, err)
+	Equals(t, 0, len(events))
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		stored, err := b.AppendAuditEvent(models.AuditEvent{
+			Time:         start.Add(time.Duration(i) * time.Hour),
+			Action:       models.ApplyAuditAction,
+			User:         "lkysow",
+			RepoFullName: "owner/repo",
+			PullNum:      i,
+			Result:       models.SuccessAuditResult,
+		})
+		Ok(t, err)
+		Equals(t, uint64(i+1), stored.ID)
+	}
+
+	events, err = b.ListAuditEvents(time.Time{})
+	Ok(t, err)
+	Equals(t, 3, len(events))
+	for i, e := range events {
+		Equals(t, uint64(i+1), e.ID)
+		Equals(t, i, e.PullNum)
+	}
+
+	// Should only return events at or after since.
+	events, err = b.ListAuditEvents(start.Add(time.Hour))
+	Ok(t, err)
+	Equals(t, 2, len(events))
+	Equals(t, uint64(2), events[0].ID)
+}
+
+func TestQueuedEvents_PutDeleteList(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		stored, err := b.PutQueuedEvent(models.QueuedEvent{Payload: []byte{byte(i)}})
+		Ok(t, err)
+		Equals(t, uint64(i+1), stored.ID)
+	}
+
+	// Putting an event with an ID should replace it.
+	_, err := b.PutQueuedEvent(models.QueuedEvent{ID: 2, Attempts: 1, Payload: []byte{1}})
+	Ok(t, err)
+	Ok(t, b.DeleteQueuedEvent(1))
+	// Deleting an event that doesn't exist isn't an error.
+	Ok(t, b.DeleteQueuedEvent(10))
+
+	events, err := b.ListQueuedEvents()
+	Ok(t, err)
+	Equals(t, []models.QueuedEvent{
+		{ID: 2, Attempts: 1, Payload: []byte{1}},
+		{ID: 3, Payload: []byte{2}},
+	}, events)
+}
+
+func TestFailedEvents_PutGetDeleteList(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		stored, err := b.PutFailedEvent(models.FailedEvent{Status: 400, Body: []byte{byte(i)}})
+		Ok(t, err)
+		Equals(t, uint64(i+1), stored.ID)
+	}
+
+	// Putting an event with an ID should replace it.
+	_, err := b.PutFailedEvent(models.FailedEvent{ID: 2, Status: 500, Replays: 1, Body: []byte{1}})
+	Ok(t, err)
+	event, err := b.GetFailedEvent(2)
+	Ok(t, err)
+	Equals(t, &models.FailedEvent{ID: 2, Status: 500, Replays: 1, Body: []byte{1}}, event)
+
+	Ok(t, b.DeleteFailedEvent(1))
+	// Deleting an event that doesn't exist isn't an error.
+	Ok(t, b.DeleteFailedEvent(10))
+	event, err = b.GetFailedEvent(1)
+	Ok(t, err)
+	Assert(t, event == nil, "exp deleted event to be nil")
+
+	events, err := b.ListFailedEvents()
+	Ok(t, err)
+	Equals(t, []models.FailedEvent{
+		{ID: 2, Status: 500, Replays: 1, Body: []byte{1}},
+		{ID: 3, Status: 400, Body: []byte{2}},
+	}, events)
+}
@@ -0,0 +1,359 @@
+// Package dbtest checks that implementations of db.Store follow its
+// contract. Custom stores should be tested with it:
+//
+//	func TestMyStore(t *testing.T) {
+//		dbtest.TestStore(t, func(t *testing.T) (db.Store, func()) {
+//			s := NewMyStore()
+//			return s, func() { s.Close() }
+//		})
+//	}
+package dbtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// NewStoreFunc returns an empty store and a function that cleans it up.
+type NewStoreFunc func(t *testing.T) (db.Store, func())
+
+// TestStore runs the conformance tests against stores made by newStore. Each
+// test gets its own store.
+func TestStore(t *testing.T, newStore NewStoreFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s db.Store)
+	}{
+		{"Locking", testLocking},
+		{"UnlockByPull", testUnlockByPull},
+		{"ConcurrentTryLock", testConcurrentTryLock},
+		{"PullStatus", testPullStatus},
+		{"ListPullStatuses", testListPullStatuses},
+		{"PutPullStatus", testPutPullStatus},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, cleanup := newStore(t)
+			defer cleanup()
+			test.test(t, s)
+		})
+	}
+}
+
+var project = models.NewProject("owner/repo", "parent/child")
+
+func newLock(project models.Project, workspace string, pullNum int) models.ProjectLock {
+	return models.ProjectLock{
+		Project:   project,
+		Workspace: workspace,
+		Pull:      models.PullRequest{Num: pullNum, BaseRepo: models.Repo{FullName: project.RepoFullName}},
+		User:      models.User{Username: "lkysow"},
+		// Stores might not keep the monotonic clock or sub-second precision.
+		Time: time.Now().Truncate(time.Second),
+	}
+}
+
+// equalLocks fails t if the locks aren't the same. Their times are compared
+// with Equal since stores can change their location.
+func equalLocks(t *testing.T, exp models.ProjectLock, act models.ProjectLock) {
+	t.Helper()
+	Assert(t, exp.Time.Equal(act.Time), "exp lock time %s, got %s", exp.Time, act.Time)
+	act.Time = exp.Time
+	Equals(t, exp, act)
+}
+
+func testLocking(t *testing.T, s db.Store) {
+	locks, err := s.List()
+	Ok(t, err)
+	Equals(t, 0, len(locks))
+	l, err := s.GetLock(project, "default")
+	Ok(t, err)
+	Assert(t, l == nil, "exp no lock, got %v", l)
+
+	lock := newLock(project, "default", 1)
+	acquired, currLock, err := s.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	equalLocks(t, lock, currLock)
+
+	// Any other lock of the same project and workspace, even from the same
+	// pull request, gets the existing lock back.
+	for _, pullNum := range []int{1, 2} {
+		acquired, currLock, err = s.TryLock(newLock(project, "default", pullNum))
+		Ok(t, err)
+		Equals(t, false, acquired)
+		equalLocks(t, lock, currLock)
+	}
+
+	// Other workspaces and projects can be locked.
+	stagingLock := newLock(project, "staging", 2)
+	acquired, _, err = s.TryLock(stagingLock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	otherProject := models.NewProject("owner/repo", "parent")
+	acquired, _, err = s.TryLock(newLock(otherProject, "default", 2))
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	locks, err = s.List()
+	Ok(t, err)
+	Equals(t, 3, len(locks))
+	l, err = s.GetLock(project, "staging")
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	equalLocks(t, stagingLock, *l)
+
+	l, err = s.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l != nil, "exp unlocked lock to be returned")
+	equalLocks(t, stagingLock, *l)
+	l, err = s.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l == nil, "exp nil lock when there's nothing to unlock, got %v", l)
+	l, err = s.GetLock(project, "staging")
+	Ok(t, err)
+	Assert(t, l == nil, "exp no lock, got %v", l)
+
+	// Unlocked projects can be locked again.
+	acquired, _, err = s.TryLock(stagingLock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+}
+
+func testUnlockByPull(t *testing.T, s db.Store) {
+	locks, err := s.UnlockByPull("owner/repo", 1)
+	Ok(t, err)
+	Equals(t, 0, len(locks))
+
+	for _, lock := range []models.ProjectLock{
+		newLock(project, "default", 1),
+		newLock(project, "staging", 1),
+		newLock(models.NewProject("owner/repo", "other"), "default", 2),
+		// Repos whose names start with the repo's name must be kept.
+		newLock(models.NewProject("owner/repo2", "parent/child"), "default", 1),
+	} {
+		acquired, _, err := s.TryLock(lock)
+		Ok(t, err)
+		Equals(t, true, acquired)
+	}
+
+	locks, err = s.UnlockByPull("owner/repo", 1)
+	Ok(t, err)
+	Equals(t, 2, len(locks))
+	for _, lock := range locks {
+		Equals(t, project, lock.Project)
+		Equals(t, 1, lock.Pull.Num)
+	}
+	locks, err = s.List()
+	Ok(t, err)
+	Equals(t, 2, len(locks))
+	for _, lock := range locks {
+		Assert(t, lock.Project.RepoFullName != "owner/repo" || lock.Pull.Num != 1, "exp lock %v to be deleted", lock)
+	}
+
+	locks, err = s.UnlockByPull("owner/repo", 1)
+	Ok(t, err)
+	Equals(t, 0, len(locks))
+}
+
+func testConcurrentTryLock(t *testing.T, s db.Store) {
+	const n = 10
+	var wg sync.WaitGroup
+	acquired := make(chan int, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(pullNum int) {
+			defer wg.Done()
+			ok, _, err := s.TryLock(newLock(project, "default", pullNum))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if ok {
+				acquired <- pullNum
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(acquired)
+	close(errs)
+	for err := range errs {
+		Ok(t, err)
+	}
+	var winners []int
+	for pullNum := range acquired {
+		winners = append(winners, pullNum)
+	}
+	Equals(t, 1, len(winners))
+	l, err := s.GetLock(project, "default")
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	Equals(t, winners[0], l.Pull.Num)
+}
+
+func testPullStatus(t *testing.T, s db.Store) {
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost:  models.VCSHost{Hostname: "github.com"},
+		},
+	}
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp no status, got %v", status)
+	// Deleting what doesn't exist isn't an error.
+	Ok(t, s.DeleteProjectStatus(pull, "default", "."))
+	Ok(t, s.DeletePullStatus(pull))
+
+	fingerprint := models.PlanFingerprint{HeadCommit: "sha", BaseCommit: "base", Args: "-target=a"}
+	newStatus, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add", Fingerprint: fingerprint}},
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", ProjectName: "staging", Error: errors.New("err")},
+	})
+	Ok(t, err)
+	expProjects := []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus, PlanFingerprint: fingerprint},
+		{RepoRelDir: "staging", Workspace: "default", ProjectName: "staging", Status: models.ErroredPlanStatus},
+	}
+	Equals(t, expProjects, newStatus.Projects)
+	Equals(t, pull, newStatus.Pull)
+
+	// Results at the same commit are merged.
+	newStatus, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.ApplyCommand, RepoRelDir: ".", Workspace: "default", ApplySuccess: "success!"},
+		{Command: models.PlanCommand, RepoRelDir: "prod", Workspace: "default", PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes."}},
+	})
+	Ok(t, err)
+	expProjects = []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.AppliedPlanStatus},
+		{RepoRelDir: "staging", Workspace: "default", ProjectName: "staging", Status: models.ErroredPlanStatus},
+		{RepoRelDir: "prod", Workspace: "default", Status: models.PlannedPlanStatus, NoChanges: true},
+	}
+	Equals(t, expProjects, newStatus.Projects)
+
+	// Changing what's returned doesn't change what's stored.
+	newStatus.Projects[0].Status = models.ErroredApplyStatus
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status != nil, "exp status")
+	Equals(t, models.PullStatus{Pull: pull, Projects: expProjects}, *status)
+
+	// Statuses are found by the pull request's repo and number, not its
+	// commit.
+	newCommitPull := pull
+	newCommitPull.HeadCommit = "newsha"
+	status, err = s.GetPullStatus(newCommitPull)
+	Ok(t, err)
+	Assert(t, status != nil, "exp status")
+	Equals(t, "sha", status.Pull.HeadCommit)
+
+	Ok(t, s.DeleteProjectStatus(pull, "default", "staging"))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{expProjects[0], expProjects[2]}, status.Projects)
+
+	// Results at a new commit replace the old ones.
+	newStatus, err = s.UpdatePullWithResults(newCommitPull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add"}},
+	})
+	Ok(t, err)
+	expProjects = []models.ProjectStatus{
+		{RepoRelDir: "staging", Workspace: "default", Status: models.PlannedPlanStatus},
+	}
+	Equals(t, expProjects, newStatus.Projects)
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, models.PullStatus{Pull: newCommitPull, Projects: expProjects}, *status)
+
+	Ok(t, s.DeletePullStatus(pull))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp status to be deleted, got %v", status)
+}
+
+func testListPullStatuses(t *testing.T, s db.Store) {
+	statuses, err := s.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	pull := func(hostname string, repo string, num int) models.PullRequest {
+		return models.PullRequest{
+			Num:        num,
+			HeadCommit: fmt.Sprintf("sha%d", num),
+			BaseRepo: models.Repo{
+				FullName: repo,
+				VCSHost:  models.VCSHost{Hostname: hostname},
+			},
+		}
+	}
+	// The pulls are in the order they must be listed in.
+	pulls := []models.PullRequest{
+		pull("github.com", "owner/a", 2),
+		pull("github.com", "owner/a", 10),
+		pull("github.com", "owner/b", 1),
+		pull("gitlab.com", "owner/a", 1),
+	}
+	for _, i := range []int{3, 1, 2, 0} {
+		_, err := s.UpdatePullWithResults(pulls[i], []models.ProjectResult{
+			{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		})
+		Ok(t, err)
+	}
+
+	statuses, err = s.ListPullStatuses()
+	Ok(t, err)
+	var listed []models.PullRequest
+	for _, status := range statuses {
+		listed = append(listed, status.Pull)
+	}
+	Equals(t, pulls, listed)
+
+	Ok(t, s.DeletePullStatus(pulls[1]))
+	statuses, err = s.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 3, len(statuses))
+}
+
+// testPutPullStatus is skipped for stores that don't implement
+// db.PullStatusPutter, which is optional.
+func testPutPullStatus(t *testing.T, s db.Store) {
+	putter, ok := s.(db.PullStatusPutter)
+	if !ok {
+		t.Skip("store doesn't implement db.PullStatusPutter")
+	}
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost:  models.VCSHost{Hostname: "github.com"},
+		},
+	}
+	status := models.PullStatus{
+		Pull: pull,
+		Projects: []models.ProjectStatus{
+			{RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus, PlanFingerprint: models.PlanFingerprint{HeadCommit: "sha"}},
+		},
+	}
+	Ok(t, putter.PutPullStatus(status))
+	got, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, &status, got)
+
+	// The status is replaced, not merged.
+	status.Projects = []models.ProjectStatus{{RepoRelDir: "staging", Workspace: "default", Status: models.AppliedPlanStatus}}
+	Ok(t, putter.PutPullStatus(status))
+	got, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, &status, got)
+}
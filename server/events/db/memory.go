@@ -0,0 +1,176 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// MemoryDB stores locks and pull statuses in memory. Everything is lost when
+// the process exits so it's meant for tests and as a reference for custom
+// Store implementations.
+type MemoryDB struct {
+	mu sync.Mutex
+	// locks are keyed by memoryLockKey.
+	locks map[string]models.ProjectLock
+	// pulls are keyed by memoryPullKey.
+	pulls map[string]models.PullStatus
+}
+
+// NewMemory returns an empty MemoryDB.
+func NewMemory() *MemoryDB {
+	return &MemoryDB{
+		locks: make(map[string]models.ProjectLock),
+		pulls: make(map[string]models.PullStatus),
+	}
+}
+
+// TryLock acquires newLock if its project and workspace aren't locked.
+// Otherwise it returns false and the lock that's holding them.
+func (m *MemoryDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryLockKey(newLock.Project, newLock.Workspace)
+	if currLock, ok := m.locks[key]; ok {
+		return false, currLock, nil
+	}
+	m.locks[key] = newLock
+	return true, newLock, nil
+}
+
+// Unlock deletes the lock of project and workspace and returns it, or a nil
+// pointer if there's none.
+func (m *MemoryDB) Unlock(project models.Project, workspace string) (*models.ProjectLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryLockKey(project, workspace)
+	lock, ok := m.locks[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.locks, key)
+	return &lock, nil
+}
+
+// List lists all current locks.
+func (m *MemoryDB) List() ([]models.ProjectLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var locks []models.ProjectLock
+	for _, lock := range m.locks {
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// UnlockByPull deletes all locks associated with that pull request and
+// returns them.
+func (m *MemoryDB) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var locks []models.ProjectLock
+	for key, lock := range m.locks {
+		if lock.Project.RepoFullName == repoFullName && lock.Pull.Num == pullNum {
+			locks = append(locks, lock)
+			delete(m.locks, key)
+		}
+	}
+	return locks, nil
+}
+
+// GetLock returns the lock for that project and workspace, or a nil pointer
+// if there's none.
+func (m *MemoryDB) GetLock(project models.Project, workspace string) (*models.ProjectLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[memoryLockKey(project, workspace)]
+	if !ok {
+		return nil, nil
+	}
+	return &lock, nil
+}
+
+// UpdatePullWithResults updates pull's status with the latest project results.
+// It returns the new PullStatus object.
+func (m *MemoryDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryPullKey(pull)
+	var currStatus *models.PullStatus
+	if status, ok := m.pulls[key]; ok {
+		currStatus = &status
+	}
+	newStatus := copyPullStatus(MergePullStatus(currStatus, pull, newResults))
+	m.pulls[key] = newStatus
+	return copyPullStatus(newStatus), nil
+}
+
+// PutPullStatus stores status, replacing the status of its pull request.
+func (m *MemoryDB) PutPullStatus(status models.PullStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pulls[memoryPullKey(status.Pull)] = copyPullStatus(status)
+	return nil
+}
+
+// GetPullStatus returns the status for pull.
+// If there is no status, returns a nil pointer.
+func (m *MemoryDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status, ok := m.pulls[memoryPullKey(pull)]
+	if !ok {
+		return nil, nil
+	}
+	status = copyPullStatus(status)
+	return &status, nil
+}
+
+// ListPullStatuses returns the statuses of every pull request.
+func (m *MemoryDB) ListPullStatuses() ([]models.PullStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var statuses []models.PullStatus
+	for _, status := range m.pulls {
+		statuses = append(statuses, copyPullStatus(status))
+	}
+	SortPullStatuses(statuses)
+	return statuses, nil
+}
+
+// DeletePullStatus deletes the status for pull.
+func (m *MemoryDB) DeletePullStatus(pull models.PullRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pulls, memoryPullKey(pull))
+	return nil
+}
+
+// DeleteProjectStatus deletes all project statuses under pull that match
+// workspace and repoRelDir.
+func (m *MemoryDB) DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryPullKey(pull)
+	if status, ok := m.pulls[key]; ok {
+		m.pulls[key] = WithoutProjectStatus(status, workspace, repoRelDir)
+	}
+	return nil
+}
+
+func memoryLockKey(p models.Project, workspace string) string {
+	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+}
+
+func memoryPullKey(pull models.PullRequest) string {
+	return fmt.Sprintf("%s::%s::%d", pull.BaseRepo.VCSHost.Hostname, pull.BaseRepo.FullName, pull.Num)
+}
+
+// copyPullStatus copies status so callers can't change the stored projects.
+func copyPullStatus(status models.PullStatus) models.PullStatus {
+	if status.Projects != nil {
+		status.Projects = append([]models.ProjectStatus(nil), status.Projects...)
+	}
+	return status
+}
@@ -0,0 +1,14 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/db/dbtest"
+)
+
+func TestMemoryDB_Store(t *testing.T) {
+	dbtest.TestStore(t, func(t *testing.T) (db.Store, func()) {
+		return db.NewMemory(), func() {}
+	})
+}
@@ -0,0 +1,184 @@
+package db_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/db/dbtest"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// postgresURLEnvVar is the env var that must be set to the URL of a
+// Postgres database to run the Postgres tests. The tests drop and recreate
+// the Atlantis tables so don't point this at a database with data you need.
+const postgresURLEnvVar = "ATLANTIS_TEST_POSTGRES_URL"
+
+func newTestPostgres(t *testing.T) *db.PostgresDB {
+	url := os.Getenv(postgresURLEnvVar)
+	if url == "" {
+		t.Skipf("%s not set", postgresURLEnvVar)
+	}
+	conn, err := sql.Open("postgres", url)
+	Ok(t, err)
+	_, err = conn.Exec("DROP TABLE IF EXISTS atlantis_locks, atlantis_pulls, atlantis_schema_migrations")
+	Ok(t, err)
+	Ok(t, conn.Close())
+
+	p, err := db.NewPostgres(url)
+	Ok(t, err)
+	return p
+}
+
+func TestPostgres_Migrate(t *testing.T) {
+	p := newTestPostgres(t)
+	defer p.Close() // nolint: errcheck
+	version, err := p.SchemaVersion()
+	Ok(t, err)
+	Equals(t, 1, version)
+
+	// Running the migrations again should be a no-op.
+	p2, err := db.NewPostgres(os.Getenv(postgresURLEnvVar))
+	Ok(t, err)
+	defer p2.Close() // nolint: errcheck
+	version, err = p2.SchemaVersion()
+	Ok(t, err)
+	Equals(t, 1, version)
+}
+
+func TestPostgres_Store(t *testing.T) {
+	dbtest.TestStore(t, func(t *testing.T) (db.Store, func()) {
+		p := newTestPostgres(t)
+		return p, func() {
+			p.Close() // nolint: errcheck
+		}
+	})
+}
+
+func TestPostgres_Locking(t *testing.T) {
+	p := newTestPostgres(t)
+	defer p.Close() // nolint: errcheck
+
+	ls, err := p.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+
+	acquired, currLock, err := p.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	Equals(t, lock, currLock)
+
+	// A different pull should get the existing lock back.
+	newLock := lock
+	newLock.Pull.Num = pullNum + 1
+	acquired, currLock, err = p.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum, currLock.Pull.Num)
+
+	// A different workspace can be locked.
+	otherWorkspace := lock
+	otherWorkspace.Workspace = "staging"
+	acquired, _, err = p.TryLock(otherWorkspace)
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	ls, err = p.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+
+	l, err := p.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "expected lock")
+	Equals(t, lock.Time.Unix(), l.Time.Unix())
+
+	l, err = p.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l != nil, "expected unlocked lock to be returned")
+	Equals(t, "staging", l.Workspace)
+	l, err = p.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l == nil, "expected nil lock when there's nothing to unlock")
+
+	// UnlockByPull should only delete locks for the repo and pull.
+	otherRepo := lock
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	_, _, err = p.TryLock(otherRepo)
+	Ok(t, err)
+	ls, err = p.UnlockByPull(project.RepoFullName, pullNum)
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+	Equals(t, project, ls[0].Project)
+	ls, err = p.List()
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+	Equals(t, "owner/repo2", ls[0].Project.RepoFullName)
+}
+
+func TestPostgres_PullStatus(t *testing.T) {
+	p := newTestPostgres(t)
+	defer p.Close() // nolint: errcheck
+
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost:  models.VCSHost{Hostname: "github.com"},
+		},
+	}
+	s, err := p.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, s == nil, "expected no status")
+
+	_, err = p.UpdatePullWithResults(pull, []models.ProjectResult{
+		{RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		{RepoRelDir: "staging", Workspace: "default", Error: os.ErrNotExist},
+	})
+	Ok(t, err)
+
+	// Results at the same commit should be merged.
+	status, err := p.UpdatePullWithResults(pull, []models.ProjectResult{
+		{RepoRelDir: ".", Workspace: "default", ApplySuccess: "success!"},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.AppliedPlanStatus},
+		{RepoRelDir: "staging", Workspace: "default", Status: models.ErroredPlanStatus},
+	}, status.Projects)
+
+	s, err = p.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, s != nil, "expected status")
+	Equals(t, status.Projects, s.Projects)
+	Equals(t, "sha", s.Pull.HeadCommit)
+
+	Ok(t, p.DeleteProjectStatus(pull, "default", "staging"))
+	s, err = p.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.AppliedPlanStatus},
+	}, s.Projects)
+
+	// Results at a new commit should replace the old ones.
+	pull.HeadCommit = "newsha"
+	status, err = p.UpdatePullWithResults(pull, []models.ProjectResult{
+		{RepoRelDir: "staging", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: "staging", Workspace: "default", Status: models.PlannedPlanStatus},
+	}, status.Projects)
+
+	statuses, err := p.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 1, len(statuses))
+	Equals(t, "newsha", statuses[0].Pull.HeadCommit)
+
+	Ok(t, p.DeletePullStatus(pull))
+	s, err = p.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, s == nil, "expected status to be deleted")
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"sort"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// PullStatusStore stores the status of each pull request's projects. It's
+// implemented by BoltDB, PostgresDB, SQLiteDB and MemoryDB. Pull requests are
+// identified by their repo's VCS host and full name and their number.
+// Implementations must be safe for concurrent use.
+type PullStatusStore interface {
+	// UpdatePullWithResults updates pull's status with the latest project
+	// results as MergePullStatus does. It returns the new PullStatus object.
+	UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error)
+	// GetPullStatus returns the status for pull. If there is no status, it
+	// returns a nil pointer.
+	GetPullStatus(pull models.PullRequest) (*models.PullStatus, error)
+	// ListPullStatuses returns the statuses of every pull request, which are
+	// deleted once pull requests are closed, sorted by VCS host, repo and
+	// pull request number.
+	ListPullStatuses() ([]models.PullStatus, error)
+	// DeletePullStatus deletes the status for pull.
+	DeletePullStatus(pull models.PullRequest) error
+	// DeleteProjectStatus deletes all project statuses under pull that match
+	// workspace and repoRelDir.
+	DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error
+}
+
+// MergePullStatus returns the status of pull after newResults are applied to
+// currStatus, which can be nil if there is no status yet. Results for pull's
+// head commit are merged into its projects but results for a new commit
+// replace them.
+func MergePullStatus(currStatus *models.PullStatus, pull models.PullRequest, newResults []models.ProjectResult) models.PullStatus {
+	// If there is no pull OR if the pull we have is out of date, we
+	// just write a new pull.
+	if currStatus == nil || currStatus.Pull.HeadCommit != pull.HeadCommit {
+		var statuses []models.ProjectStatus
+		for _, r := range newResults {
+			statuses = append(statuses, projectResultToProject(r))
+		}
+		return models.PullStatus{
+			Pull:     pull,
+			Projects: statuses,
+		}
+	}
+
+	// If there's an existing pull at the right commit then we have to
+	// merge our project results with the existing ones. We do a merge
+	// because it's possible a user is just applying a single project
+	// in this command and so we don't want to delete our data about
+	// other projects that aren't affected by this command.
+	newStatus := *currStatus
+	for _, res := range newResults {
+		// First, check if we should update any existing projects.
+		updatedExisting := false
+		for i := range newStatus.Projects {
+			// NOTE: We're using a reference here because we are
+			// in-place updating its Status field.
+			proj := &newStatus.Projects[i]
+			if res.Workspace == proj.Workspace &&
+				res.RepoRelDir == proj.RepoRelDir &&
+				res.ProjectName == proj.ProjectName {
+
+				proj.Status = res.PlanStatus()
+				proj.NoChanges = noChanges(res)
+				proj.PlanFingerprint = planFingerprint(res)
+				updatedExisting = true
+				break
+			}
+		}
+
+		if !updatedExisting {
+			// If we didn't update an existing project, then we need to
+			// add this because it's a new one.
+			newStatus.Projects = append(newStatus.Projects, projectResultToProject(res))
+		}
+	}
+	return newStatus
+}
+
+// SortPullStatuses sorts statuses by VCS host, repo and pull request number
+// as ListPullStatuses must.
+func SortPullStatuses(statuses []models.PullStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i].Pull, statuses[j].Pull
+		if a.BaseRepo.VCSHost.Hostname != b.BaseRepo.VCSHost.Hostname {
+			return a.BaseRepo.VCSHost.Hostname < b.BaseRepo.VCSHost.Hostname
+		}
+		if a.BaseRepo.FullName != b.BaseRepo.FullName {
+			return a.BaseRepo.FullName < b.BaseRepo.FullName
+		}
+		return a.Num < b.Num
+	})
+}
+
+// WithoutProjectStatus returns currStatus without the projects that match
+// workspace and repoRelDir.
+func WithoutProjectStatus(currStatus models.PullStatus, workspace string, repoRelDir string) models.PullStatus {
+	var newProjects []models.ProjectStatus
+	for _, p := range currStatus.Projects {
+		if p.Workspace == workspace && p.RepoRelDir == repoRelDir {
+			continue
+		}
+		newProjects = append(newProjects, p)
+	}
+	currStatus.Projects = newProjects
+	return currStatus
+}
+
+func projectResultToProject(p models.ProjectResult) models.ProjectStatus {
+	return models.ProjectStatus{
+		Workspace:       p.Workspace,
+		RepoRelDir:      p.RepoRelDir,
+		ProjectName:     p.ProjectName,
+		Status:          p.PlanStatus(),
+		NoChanges:       noChanges(p),
+		PlanFingerprint: planFingerprint(p),
+	}
+}
+
+// planFingerprint returns what p's plan was made from if p is a successful
+// plan.
+func planFingerprint(p models.ProjectResult) models.PlanFingerprint {
+	if p.PlanSuccess == nil {
+		return models.PlanFingerprint{}
+	}
+	return p.PlanSuccess.Fingerprint
+}
+
+// noChanges returns true if p is a successful plan that doesn't change
+// anything.
+func noChanges(p models.ProjectResult) bool {
+	return p.PlanSuccess != nil && !p.PlanSuccess.HasChanges()
+}
@@ -0,0 +1,377 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	// Registers the sqlite driver with database/sql.
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteBackend stores locks and pull statuses in a SQLite database in the
+// data dir.
+const SQLiteBackend = "sqlite"
+
+// SQLiteFile is the name of the SQLite database in the data dir.
+const SQLiteFile = "atlantis.sqlite"
+
+// sqliteMigrations are run in order when Atlantis starts. Like
+// postgresMigrations, once released a migration must never be changed.
+var sqliteMigrations = []schemaMigration{
+	{
+		Version:     1,
+		Description: "create locks, pulls and project results tables",
+		SQL: `
+CREATE TABLE atlantis_locks (
+	repo_full_name TEXT NOT NULL,
+	path TEXT NOT NULL,
+	workspace TEXT NOT NULL,
+	pull_num INTEGER NOT NULL,
+	lock TEXT NOT NULL,
+	PRIMARY KEY (repo_full_name, path, workspace)
+);
+CREATE INDEX atlantis_locks_pull_idx ON atlantis_locks (repo_full_name, pull_num);
+CREATE TABLE atlantis_pulls (
+	vcs_hostname TEXT NOT NULL,
+	repo_full_name TEXT NOT NULL,
+	pull_num INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (vcs_hostname, repo_full_name, pull_num)
+);
+CREATE TABLE atlantis_project_results (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	vcs_hostname TEXT NOT NULL,
+	repo_full_name TEXT NOT NULL,
+	pull_num INTEGER NOT NULL,
+	head_commit TEXT NOT NULL,
+	path TEXT NOT NULL,
+	workspace TEXT NOT NULL,
+	project_name TEXT NOT NULL,
+	status TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX atlantis_project_results_pull_idx ON atlantis_project_results (repo_full_name, pull_num);`,
+	},
+}
+
+// SQLiteDB stores locks and pull statuses in a SQLite database so that
+// single-node installs can query them with SQL without running a database
+// server. Unlike BoltDB and PostgresDB it also keeps the result of every
+// project that's run in the atlantis_project_results table, which isn't read
+// by Atlantis but can be queried for history and analytics. It implements
+// Store.
+type SQLiteDB struct {
+	db *sql.DB
+	// mu serializes writes. SQLite only allows one writer at a time so
+	// letting transactions race would only make them fail with
+	// SQLITE_BUSY.
+	mu sync.Mutex
+}
+
+// NewSQLite opens or creates the SQLite database in dataDir and migrates its
+// schema to the latest version. The driver is pure Go so this works in
+// binaries built with CGO_ENABLED=0.
+func NewSQLite(dataDir string) (*SQLiteDB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.Join(dataDir, SQLiteFile))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening sqlite database")
+	}
+	if err := db.Ping(); err != nil {
+		db.Close() // nolint: errcheck
+		return nil, errors.Wrap(err, "opening sqlite database")
+	}
+	s := &SQLiteDB{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close() // nolint: errcheck
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *SQLiteDB) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks that the database can be read. It implements locking.Pinger.
+func (s *SQLiteDB) Ping() error {
+	var one int
+	return errors.Wrap(s.db.QueryRow("SELECT 1").Scan(&one), "reading sqlite database")
+}
+
+// SchemaVersion returns the version of the last migration that was run.
+func (s *SQLiteDB) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM atlantis_schema_migrations").Scan(&version)
+	return version, errors.Wrap(err, "getting sqlite schema version")
+}
+
+// migrate runs the migrations that haven't been run yet. Each migration runs
+// in its own transaction along with recording its version.
+func (s *SQLiteDB) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS atlantis_schema_migrations (
+	version INTEGER PRIMARY KEY,
+	description TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return errors.Wrap(err, "creating sqlite schema migrations table")
+	}
+
+	for _, migration := range sqliteMigrations {
+		err := s.inTx(func(tx *sql.Tx) error {
+			var applied bool
+			if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM atlantis_schema_migrations WHERE version = ?)", migration.Version).Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+			if _, err := tx.Exec(migration.SQL); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO atlantis_schema_migrations (version, description) VALUES (?, ?)", migration.Version, migration.Description)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "migrating sqlite schema to version %d (%s)", migration.Version, migration.Description)
+		}
+	}
+	return nil
+}
+
+// TryLock attempts to create a new lock. If the lock is
+// acquired, it will return true and the lock returned will be newLock.
+// If the lock is not acquired, it will return false and the current
+// lock that is preventing this lock from being acquired.
+func (s *SQLiteDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
+	serialized, err := json.Marshal(newLock)
+	if err != nil {
+		return false, newLock, errors.Wrap(err, "serializing lock")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.db.Exec(`INSERT INTO atlantis_locks (repo_full_name, path, workspace, pull_num, lock)
+VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		newLock.Project.RepoFullName, newLock.Project.Path, newLock.Workspace, newLock.Pull.Num, string(serialized))
+	if err != nil {
+		return false, newLock, errors.Wrap(err, "DB transaction failed")
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, newLock, errors.Wrap(err, "DB transaction failed")
+	}
+	if inserted == 1 {
+		return true, newLock, nil
+	}
+
+	// Otherwise the lock fails, return to caller the run that's holding the
+	// lock. Since writes are serialized it can't have been deleted since
+	// the insert.
+	currLock, err := s.GetLock(newLock.Project, newLock.Workspace)
+	if err != nil {
+		return false, newLock, err
+	}
+	if currLock == nil {
+		return false, newLock, fmt.Errorf("DB transaction failed: lock for %s/%s/%s conflicted but doesn't exist", newLock.Project.RepoFullName, newLock.Project.Path, newLock.Workspace)
+	}
+	return false, *currLock, nil
+}
+
+// Unlock attempts to unlock the project and workspace.
+// If there is no lock, then it will return a nil pointer.
+// If there is a lock, then it will delete it, and then return a pointer
+// to the deleted lock.
+func (s *SQLiteDB) Unlock(project models.Project, workspace string) (*models.ProjectLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var serialized []byte
+	err := s.db.QueryRow("DELETE FROM atlantis_locks WHERE repo_full_name = ? AND path = ? AND workspace = ? RETURNING lock",
+		project.RepoFullName, project.Path, workspace).Scan(&serialized)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	var lock models.ProjectLock
+	if err := json.Unmarshal(serialized, &lock); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize lock")
+	}
+	return &lock, nil
+}
+
+// List lists all current locks.
+func (s *SQLiteDB) List() ([]models.ProjectLock, error) {
+	rows, err := s.db.Query("SELECT lock FROM atlantis_locks ORDER BY repo_full_name, path, workspace")
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	return scanLocks(rows)
+}
+
+// UnlockByPull deletes all locks associated with that pull request and returns them.
+func (s *SQLiteDB) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.Query("DELETE FROM atlantis_locks WHERE repo_full_name = ? AND pull_num = ? RETURNING lock", repoFullName, pullNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unlocking locks for %s#%d", repoFullName, pullNum)
+	}
+	return scanLocks(rows)
+}
+
+// GetLock returns a pointer to the lock for that project and workspace.
+// If there is no lock, it returns a nil pointer.
+func (s *SQLiteDB) GetLock(project models.Project, workspace string) (*models.ProjectLock, error) {
+	var serialized []byte
+	err := s.db.QueryRow("SELECT lock FROM atlantis_locks WHERE repo_full_name = ? AND path = ? AND workspace = ?",
+		project.RepoFullName, project.Path, workspace).Scan(&serialized)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting lock data")
+	}
+	var lock models.ProjectLock
+	if err := json.Unmarshal(serialized, &lock); err != nil {
+		return nil, errors.Wrapf(err, "deserializing lock for %s/%s/%s", project.RepoFullName, project.Path, workspace)
+	}
+	// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+	lock.Time = lock.Time.Local()
+	return &lock, nil
+}
+
+// UpdatePullWithResults updates pull's status with the latest project results
+// and records them in the project results history.
+// It returns the new PullStatus object.
+func (s *SQLiteDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var newStatus models.PullStatus
+	err := s.inTx(func(tx *sql.Tx) error {
+		currStatus, err := s.getPullStatus(tx, pull)
+		if err != nil {
+			return err
+		}
+		newStatus = MergePullStatus(currStatus, pull, newResults)
+		if err := s.writePullStatus(tx, newStatus); err != nil {
+			return err
+		}
+		for _, r := range newResults {
+			if _, err := tx.Exec(`INSERT INTO atlantis_project_results
+(vcs_hostname, repo_full_name, pull_num, head_commit, path, workspace, project_name, status)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				pull.BaseRepo.VCSHost.Hostname, pull.BaseRepo.FullName, pull.Num, pull.HeadCommit,
+				r.RepoRelDir, r.Workspace, r.ProjectName, r.PlanStatus().String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return newStatus, errors.Wrap(err, "DB transaction failed")
+}
+
+// PutPullStatus stores status, replacing the status of its pull request. The
+// results history isn't changed.
+func (s *SQLiteDB) PutPullStatus(status models.PullStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.inTx(func(tx *sql.Tx) error {
+		return s.writePullStatus(tx, status)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// GetPullStatus returns the status for pull.
+// If there is no status, returns a nil pointer.
+func (s *SQLiteDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
+	status, err := s.getPullStatus(s.db, pull)
+	return status, errors.Wrap(err, "DB transaction failed")
+}
+
+// ListPullStatuses returns the statuses of every pull request.
+func (s *SQLiteDB) ListPullStatuses() ([]models.PullStatus, error) {
+	rows, err := s.db.Query("SELECT status FROM atlantis_pulls ORDER BY vcs_hostname, repo_full_name, pull_num")
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	return scanPullStatuses(rows)
+}
+
+// DeletePullStatus deletes the status for pull. The pull's project results
+// history is kept.
+func (s *SQLiteDB) DeletePullStatus(pull models.PullRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec("DELETE FROM atlantis_pulls WHERE vcs_hostname = ? AND repo_full_name = ? AND pull_num = ?",
+		pull.BaseRepo.VCSHost.Hostname, pull.BaseRepo.FullName, pull.Num)
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteProjectStatus deletes all project statuses under pull that match
+// workspace and repoRelDir.
+func (s *SQLiteDB) DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.inTx(func(tx *sql.Tx) error {
+		currStatus, err := s.getPullStatus(tx, pull)
+		if err != nil {
+			return err
+		}
+		if currStatus == nil {
+			return nil
+		}
+		return s.writePullStatus(tx, WithoutProjectStatus(*currStatus, workspace, repoRelDir))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// inTx runs f in a transaction, committing if it returns nil and rolling back
+// otherwise.
+func (s *SQLiteDB) inTx(f func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDB) getPullStatus(q queryRower, pull models.PullRequest) (*models.PullStatus, error) {
+	var serialized []byte
+	err := q.QueryRow("SELECT status FROM atlantis_pulls WHERE vcs_hostname = ? AND repo_full_name = ? AND pull_num = ?",
+		pull.BaseRepo.VCSHost.Hostname, pull.BaseRepo.FullName, pull.Num).Scan(&serialized)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status models.PullStatus
+	if err := json.Unmarshal(serialized, &status); err != nil {
+		return nil, errors.Wrapf(err, "deserializing pull %s with contents %q", pullLockKey(pull), serialized)
+	}
+	return &status, nil
+}
+
+func (s *SQLiteDB) writePullStatus(tx *sql.Tx, status models.PullStatus) error {
+	serialized, err := json.Marshal(status)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	_, err = tx.Exec(`INSERT INTO atlantis_pulls (vcs_hostname, repo_full_name, pull_num, status)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (vcs_hostname, repo_full_name, pull_num) DO UPDATE SET status = excluded.status, updated_at = CURRENT_TIMESTAMP`,
+		status.Pull.BaseRepo.VCSHost.Hostname, status.Pull.BaseRepo.FullName, status.Pull.Num, string(serialized))
+	return err
+}
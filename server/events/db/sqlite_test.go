@@ -0,0 +1,194 @@
+package db_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/db/dbtest"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newTestSQLite(t *testing.T) (*db.SQLiteDB, string, func()) {
+	dataDir, cleanup := TempDir(t)
+	s, err := db.NewSQLite(dataDir)
+	Ok(t, err)
+	return s, dataDir, func() {
+		s.Close() // nolint: errcheck
+		cleanup()
+	}
+}
+
+func TestSQLite_Migrate(t *testing.T) {
+	s, dataDir, cleanup := newTestSQLite(t)
+	defer cleanup()
+	version, err := s.SchemaVersion()
+	Ok(t, err)
+	Equals(t, 1, version)
+
+	// Running the migrations again should be a no-op.
+	s2, err := db.NewSQLite(dataDir)
+	Ok(t, err)
+	defer s2.Close() // nolint: errcheck
+	version, err = s2.SchemaVersion()
+	Ok(t, err)
+	Equals(t, 1, version)
+}
+
+func TestSQLite_Store(t *testing.T) {
+	dbtest.TestStore(t, func(t *testing.T) (db.Store, func()) {
+		s, _, cleanup := newTestSQLite(t)
+		return s, cleanup
+	})
+}
+
+func TestSQLite_Locking(t *testing.T) {
+	s, _, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+
+	acquired, currLock, err := s.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	Equals(t, lock, currLock)
+
+	// A different pull should get the existing lock back.
+	newLock := lock
+	newLock.Pull.Num = pullNum + 1
+	acquired, currLock, err = s.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum, currLock.Pull.Num)
+
+	// A different workspace can be locked.
+	otherWorkspace := lock
+	otherWorkspace.Workspace = "staging"
+	acquired, _, err = s.TryLock(otherWorkspace)
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	ls, err = s.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+
+	l, err := s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "expected lock")
+	Equals(t, lock.Time.Unix(), l.Time.Unix())
+
+	l, err = s.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l != nil, "expected unlocked lock to be returned")
+	Equals(t, "staging", l.Workspace)
+	l, err = s.Unlock(project, "staging")
+	Ok(t, err)
+	Assert(t, l == nil, "expected nil lock when there's nothing to unlock")
+
+	// UnlockByPull should only delete locks for the repo and pull.
+	otherRepo := lock
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	_, _, err = s.TryLock(otherRepo)
+	Ok(t, err)
+	ls, err = s.UnlockByPull(project.RepoFullName, pullNum)
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+	Equals(t, project, ls[0].Project)
+	ls, err = s.List()
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+	Equals(t, "owner/repo2", ls[0].Project.RepoFullName)
+}
+
+func TestSQLite_PullStatus(t *testing.T) {
+	s, dataDir, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost:  models.VCSHost{Hostname: "github.com"},
+		},
+	}
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "expected no status")
+
+	_, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", Error: os.ErrNotExist},
+	})
+	Ok(t, err)
+
+	// Results at the same commit should be merged.
+	newStatus, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.ApplyCommand, RepoRelDir: ".", Workspace: "default", ApplySuccess: "success!"},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.AppliedPlanStatus},
+		{RepoRelDir: "staging", Workspace: "default", Status: models.ErroredPlanStatus},
+	}, newStatus.Projects)
+
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status != nil, "expected status")
+	Equals(t, newStatus.Projects, status.Projects)
+	Equals(t, "sha", status.Pull.HeadCommit)
+
+	Ok(t, s.DeleteProjectStatus(pull, "default", "staging"))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: ".", Workspace: "default", Status: models.AppliedPlanStatus},
+	}, status.Projects)
+
+	// Results at a new commit should replace the old ones.
+	pull.HeadCommit = "newsha"
+	newStatus, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{RepoRelDir: "staging", Workspace: "default", Status: models.PlannedPlanStatus},
+	}, newStatus.Projects)
+
+	statuses, err := s.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 1, len(statuses))
+	Equals(t, "newsha", statuses[0].Pull.HeadCommit)
+
+	Ok(t, s.DeletePullStatus(pull))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "expected status to be deleted")
+
+	// Every result should be kept in the history, even after the pull's
+	// status is deleted.
+	conn, err := sql.Open("sqlite", filepath.Join(dataDir, db.SQLiteFile))
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	rows, err := conn.Query("SELECT head_commit, path, status FROM atlantis_project_results ORDER BY id")
+	Ok(t, err)
+	defer rows.Close() // nolint: errcheck
+	var history [][3]string
+	for rows.Next() {
+		var r [3]string
+		Ok(t, rows.Scan(&r[0], &r[1], &r[2]))
+		history = append(history, r)
+	}
+	Ok(t, rows.Err())
+	Equals(t, [][3]string{
+		{"sha", ".", "planned"},
+		{"sha", "staging", "plan_errored"},
+		{"sha", ".", "applied"},
+		{"newsha", "staging", "planned"},
+	}, history)
+}
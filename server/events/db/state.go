@@ -0,0 +1,178 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// StateVersion is the version of the State format that ExportState returns.
+// ImportState rejects other versions.
+const StateVersion = 1
+
+// State is the server state that's exported from one backend and imported
+// into another, ex. to migrate from BoltDB to Postgres without losing the
+// state of open pull requests.
+type State struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Locks, PullStatuses and QueuedEvents are serialized like BoltDB stores
+	// them.
+	Locks        []models.ProjectLock `json:"locks"`
+	PullStatuses []models.PullStatus  `json:"pull_statuses"`
+	QueuedEvents []models.QueuedEvent `json:"queued_events"`
+}
+
+// PullStatusPutter is implemented by the stores that pull statuses can be
+// imported into as they were exported. BoltDB, PostgresDB, SQLiteDB and
+// MemoryDB implement it.
+type PullStatusPutter interface {
+	// PutPullStatus stores status, replacing the status of its pull
+	// request.
+	PutPullStatus(status models.PullStatus) error
+}
+
+// QueuedEventStore stores the events queued by the event queue. It's
+// implemented by BoltDB.
+type QueuedEventStore interface {
+	// PutQueuedEvent stores event and returns it with its ID set.
+	PutQueuedEvent(event models.QueuedEvent) (models.QueuedEvent, error)
+	// ListQueuedEvents returns the stored events, oldest first.
+	ListQueuedEvents() ([]models.QueuedEvent, error)
+}
+
+var (
+	_ PullStatusPutter = &BoltDB{}
+	_ PullStatusPutter = &PostgresDB{}
+	_ PullStatusPutter = &SQLiteDB{}
+	_ PullStatusPutter = &MemoryDB{}
+	_ QueuedEventStore = &BoltDB{}
+)
+
+// ImportResult counts what ImportState imported and skipped.
+type ImportResult struct {
+	Locks               int
+	SkippedLocks        int
+	PullStatuses        int
+	SkippedPullStatuses int
+	QueuedEvents        int
+	SkippedQueuedEvents int
+}
+
+// String describes the result for people, ex. "imported 2 locks (1 skipped),
+// 3 pull statuses (0 skipped) and 0 queued events (0 skipped)".
+func (r ImportResult) String() string {
+	return fmt.Sprintf("imported %d locks (%d skipped), %d pull statuses (%d skipped) and %d queued events (%d skipped)",
+		r.Locks, r.SkippedLocks, r.PullStatuses, r.SkippedPullStatuses, r.QueuedEvents, r.SkippedQueuedEvents)
+}
+
+// ExportState returns the locks and pull statuses in store and the events
+// queued in queue, which can be nil if events aren't queued.
+func ExportState(store Store, queue QueuedEventStore, now time.Time) (State, error) {
+	state := State{
+		Version:      StateVersion,
+		ExportedAt:   now,
+		Locks:        []models.ProjectLock{},
+		PullStatuses: []models.PullStatus{},
+		QueuedEvents: []models.QueuedEvent{},
+	}
+	locks, err := store.List()
+	if err != nil {
+		return State{}, errors.Wrap(err, "listing locks")
+	}
+	state.Locks = append(state.Locks, locks...)
+	statuses, err := store.ListPullStatuses()
+	if err != nil {
+		return State{}, errors.Wrap(err, "listing pull statuses")
+	}
+	state.PullStatuses = append(state.PullStatuses, statuses...)
+	if queue != nil {
+		events, err := queue.ListQueuedEvents()
+		if err != nil {
+			return State{}, errors.Wrap(err, "listing queued events")
+		}
+		state.QueuedEvents = append(state.QueuedEvents, events...)
+	}
+	return state, nil
+}
+
+// ImportState imports state into store and queue, which can be nil if state
+// has no queued events. It doesn't overwrite anything so importing twice
+// doesn't change the stores: locks of projects that are already locked, pull
+// statuses of pull requests that already have one and queued events that are
+// already queued are skipped. Queued events get new IDs so they're handled
+// after the events that are already queued.
+func ImportState(state State, store Store, queue QueuedEventStore) (ImportResult, error) {
+	var result ImportResult
+	if state.Version != StateVersion {
+		return result, fmt.Errorf("unsupported state version %d, expected %d", state.Version, StateVersion)
+	}
+	putter, ok := store.(PullStatusPutter)
+	if !ok && len(state.PullStatuses) > 0 {
+		return result, errors.New("store doesn't support importing pull statuses")
+	}
+	if queue == nil && len(state.QueuedEvents) > 0 {
+		return result, errors.New("there's no store to import queued events into")
+	}
+
+	for _, lock := range state.Locks {
+		acquired, _, err := store.TryLock(lock)
+		if err != nil {
+			return result, errors.Wrapf(err, "importing lock of %s", lock.ProjectID())
+		}
+		if acquired {
+			result.Locks++
+		} else {
+			result.SkippedLocks++
+		}
+	}
+
+	for _, status := range state.PullStatuses {
+		curr, err := store.GetPullStatus(status.Pull)
+		if err != nil {
+			return result, errors.Wrapf(err, "getting status of %s#%d", status.Pull.BaseRepo.FullName, status.Pull.Num)
+		}
+		if curr != nil {
+			result.SkippedPullStatuses++
+			continue
+		}
+		if err := putter.PutPullStatus(status); err != nil {
+			return result, errors.Wrapf(err, "importing status of %s#%d", status.Pull.BaseRepo.FullName, status.Pull.Num)
+		}
+		result.PullStatuses++
+	}
+
+	if len(state.QueuedEvents) == 0 {
+		return result, nil
+	}
+	queued, err := queue.ListQueuedEvents()
+	if err != nil {
+		return result, errors.Wrap(err, "listing queued events")
+	}
+	for _, event := range state.QueuedEvents {
+		if isQueued(queued, event) {
+			result.SkippedQueuedEvents++
+			continue
+		}
+		event.ID = 0
+		if _, err := queue.PutQueuedEvent(event); err != nil {
+			return result, errors.Wrap(err, "importing queued event")
+		}
+		result.QueuedEvents++
+	}
+	return result, nil
+}
+
+// isQueued returns true if an event that was queued at the same time with
+// the same payload as event is in queued.
+func isQueued(queued []models.QueuedEvent, event models.QueuedEvent) bool {
+	for _, q := range queued {
+		if q.QueuedAt.Equal(event.QueuedAt) && bytes.Equal(q.Payload, event.Payload) {
+			return true
+		}
+	}
+	return false
+}
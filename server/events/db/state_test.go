@@ -0,0 +1,82 @@
+package db_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestExportImportState(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	src, err := db.New(tmp)
+	Ok(t, err)
+	defer src.Close() // nolint: errcheck
+
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull := models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: repo}
+	lock := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "."),
+		Workspace: "default",
+		Pull:      pull,
+		User:      models.User{Username: "lkysow"},
+		Time:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	_, _, err = src.TryLock(lock)
+	Ok(t, err)
+	_, err = src.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{Fingerprint: models.PlanFingerprint{HeadCommit: "sha"}}},
+	})
+	Ok(t, err)
+	_, err = src.PutQueuedEvent(models.QueuedEvent{QueuedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Payload: []byte(`{"PullNum":1}`)})
+	Ok(t, err)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	state, err := db.ExportState(src, src, now)
+	Ok(t, err)
+	Equals(t, db.StateVersion, state.Version)
+	Equals(t, now, state.ExportedAt)
+	Equals(t, 1, len(state.Locks))
+	Equals(t, 1, len(state.PullStatuses))
+	Equals(t, 1, len(state.QueuedEvents))
+
+	// The state is imported from JSON like the db import command does.
+	serialized, err := json.Marshal(state)
+	Ok(t, err)
+	var imported db.State
+	Ok(t, json.Unmarshal(serialized, &imported))
+
+	dstDir, cleanupDst := TempDir(t)
+	defer cleanupDst()
+	queue, err := db.New(dstDir)
+	Ok(t, err)
+	defer queue.Close() // nolint: errcheck
+	dst := db.NewMemory()
+	result, err := db.ImportState(imported, dst, queue)
+	Ok(t, err)
+	Equals(t, db.ImportResult{Locks: 1, PullStatuses: 1, QueuedEvents: 1}, result)
+
+	exported, err := db.ExportState(dst, queue, now)
+	Ok(t, err)
+	Equals(t, state.Locks, exported.Locks)
+	Equals(t, state.PullStatuses, exported.PullStatuses)
+	Equals(t, state.QueuedEvents[0].Payload, exported.QueuedEvents[0].Payload)
+
+	// Importing again doesn't change anything.
+	result, err = db.ImportState(imported, dst, queue)
+	Ok(t, err)
+	Equals(t, db.ImportResult{SkippedLocks: 1, SkippedPullStatuses: 1, SkippedQueuedEvents: 1}, result)
+	Equals(t, "imported 0 locks (1 skipped), 0 pull statuses (1 skipped) and 0 queued events (1 skipped)", result.String())
+}
+
+func TestImportState_Errors(t *testing.T) {
+	_, err := db.ImportState(db.State{Version: 2}, db.NewMemory(), nil)
+	ErrEquals(t, "unsupported state version 2, expected 1", err)
+
+	_, err = db.ImportState(db.State{Version: db.StateVersion, QueuedEvents: []models.QueuedEvent{{ID: 1}}}, db.NewMemory(), nil)
+	ErrEquals(t, "there's no store to import queued events into", err)
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// LockStore stores the locks that stop pull requests from planning and
+// applying the same project at the same time. It's the locking.Backend of
+// the server's locking client. Locks are identified by the repo and path of
+// their project and their workspace.
+//
+// Implementations must be safe for concurrent use and TryLock must be atomic:
+// of the calls that race to lock the same project and workspace, only one can
+// acquire it. The dbtest package checks the rest of the contract.
+type LockStore interface {
+	// TryLock acquires newLock if its project and workspace aren't locked
+	// and returns true and newLock. Otherwise it returns false and the lock
+	// that's holding them, even if it's for the same pull request.
+	TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error)
+	// Unlock deletes the lock of project and workspace and returns it. It
+	// returns a nil pointer if they weren't locked.
+	Unlock(project models.Project, workspace string) (*models.ProjectLock, error)
+	// List returns every lock in any order.
+	List() ([]models.ProjectLock, error)
+	// GetLock returns the lock of project and workspace or a nil pointer if
+	// they aren't locked.
+	GetLock(project models.Project, workspace string) (*models.ProjectLock, error)
+	// UnlockByPull deletes the locks of pull request pullNum in the repo
+	// repoFullName and returns them.
+	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
+}
+
+// Store stores the locks and pull statuses that --db-backend decides where to
+// keep. Custom backends can be used by implementing it and setting the
+// server's Config.Store.
+type Store interface {
+	LockStore
+	PullStatusStore
+}
+
+var (
+	_ Store = &BoltDB{}
+	_ Store = &PostgresDB{}
+	_ Store = &SQLiteDB{}
+	_ Store = &MemoryDB{}
+)
@@ -78,9 +78,38 @@ type CommentCommand struct {
 	// If empty then the comment specified no workspace.
 	Workspace string
 	// ProjectName is the name of a project to run the command on. It refers to a
-	// project specified in an atlantis.yaml file.
+	// project specified in an atlantis.yaml file. For plan and apply it can
+	// also be a comma-separated list of names and globs, ex. network-*,app.
 	// If empty then the comment specified no project.
 	ProjectName string
+	// Workspaces are the workspaces whose plans are compared by the diff
+	// command.
+	Workspaces []string
+	// CustomCommand is the name of the custom command to run if Name is
+	// models.CustomCommand.
+	CustomCommand string
+	// Destroy is true if a plan should destroy every resource in the
+	// projects, ex. atlantis plan --destroy.
+	Destroy bool
+	// ConfirmDestroy is true if an apply should apply destroy plans, ex.
+	// atlantis apply --confirm-destroy.
+	ConfirmDestroy bool
+	// BreakGlass is true if an apply should run outside the apply windows,
+	// ex. atlantis apply --break-glass.
+	BreakGlass bool
+	// OverrideGuardrails is true if an apply should apply plans that exceed
+	// the guardrails, ex. atlantis apply --override-guardrails.
+	OverrideGuardrails bool
+	// SlackApprover is the VCS username of the user who approved the apply
+	// in Slack. It counts as their approval of the pull request.
+	SlackApprover string
+	// VarFile is the .tfvars file selected with --var-file, ex. atlantis
+	// plan --var-file staging.tfvars. It must be one of the project's
+	// var_files.
+	VarFile string
+	// CommentID is the ID of the comment the command came from. It's used to
+	// react to the comment. If 0, the VCS host doesn't support reactions.
+	CommentID int64
 }
 
 // IsForSpecificProject returns true if the command is for a specific dir, workspace
@@ -271,6 +300,10 @@ type EventParser struct {
 	BitbucketServerURL string
 	AzureDevopsToken   string
 	AzureDevopsUser    string
+	// AllowDraftPRs is true if draft pull requests, and GitLab's work in
+	// progress merge requests, should be autoplanned. If false they're
+	// ignored until they're marked ready for review, apart from being closed.
+	AllowDraftPRs bool
 }
 
 // GetBitbucketCloudPullEventType returns the type of the pull request
@@ -417,26 +450,21 @@ func (e *EventParser) ParseGithubPullEvent(pullEvent *github.PullRequestEvent) (
 		return
 	}
 
-	if pullEvent.GetPullRequest().GetDraft() {
-		// if the PR is in draft state we don't care about the action type
-		// we can set the type to Other and ignore the PR
+	switch pullEvent.GetAction() {
+	case "opened":
+		pullEventType = models.OpenedPullEvent
+	case "ready_for_review":
+		// when an author takes a PR out of 'draft' state a 'ready_for_review'
+		// event is triggered. We want atlantis to treat this as a freshly opened PR
+		pullEventType = models.OpenedPullEvent
+	case "synchronize":
+		pullEventType = models.UpdatedPullEvent
+	case "closed":
+		pullEventType = models.ClosedPullEvent
+	default:
 		pullEventType = models.OtherPullEvent
-	} else {
-		switch pullEvent.GetAction() {
-		case "opened":
-			pullEventType = models.OpenedPullEvent
-		case "ready_for_review":
-			// when an author takes a PR out of 'draft' state a 'ready_for_review'
-			// event is triggered. We want atlantis to treat this as a freshly opened PR
-			pullEventType = models.OpenedPullEvent
-		case "synchronize":
-			pullEventType = models.UpdatedPullEvent
-		case "closed":
-			pullEventType = models.ClosedPullEvent
-		default:
-			pullEventType = models.OtherPullEvent
-		}
 	}
+	pullEventType = e.ignoreDraft(pullEvent.GetPullRequest().GetDraft(), pullEventType)
 	user = models.User{Username: senderUsername}
 	return
 }
@@ -546,12 +574,15 @@ func (e *EventParser) ParseGitlabMergeRequestEvent(event gitlab.MergeEvent) (pul
 	case "open":
 		eventType = models.OpenedPullEvent
 	case "update":
+		// Marking a merge request as ready, i.e. no longer a work in
+		// progress, is an update so it's autoplanned.
 		eventType = models.UpdatedPullEvent
 	case "merge", "close":
 		eventType = models.ClosedPullEvent
 	default:
 		eventType = models.OtherPullEvent
 	}
+	eventType = e.ignoreDraft(event.ObjectAttributes.WorkInProgress, eventType)
 
 	user = models.User{
 		Username: event.User.Username,
@@ -742,10 +773,21 @@ func (e *EventParser) ParseAzureDevopsPullEvent(event azuredevops.Event) (pull m
 	default:
 		pullEventType = models.OtherPullEvent
 	}
+	pullEventType = e.ignoreDraft(pullResource.GetIsDraft(), pullEventType)
 	user = models.User{Username: senderUsername}
 	return
 }
 
+// ignoreDraft returns OtherPullEvent for events on draft pull requests so
+// they're not autoplanned, unless AllowDraftPRs is set. Closed events are
+// kept so the pull request's locks and plans are still cleaned up.
+func (e *EventParser) ignoreDraft(draft bool, eventType models.PullRequestEventType) models.PullRequestEventType {
+	if !draft || e.AllowDraftPRs || eventType == models.ClosedPullEvent {
+		return eventType
+	}
+	return models.OtherPullEvent
+}
+
 // ParseAzureDevopsPull parses the response from the Azure DevOps API endpoint (not
 // from a webhook) that returns a pull request.
 // See EventParsing for return value docs.
@@ -815,38 +857,64 @@ func (e *EventParser) ParseAzureDevopsPull(pull *azuredevops.GitPullRequest) (pu
 // ParseAzureDevopsRepo parses the response from the Azure DevOps API endpoint that
 // returns a repo into the Atlantis model.
 // If the event payload doesn't contain a parent repository reference, extract the owner
-// name from the URL. The URL will match one of two different formats:
+// name from the URL. The URL will match one of three different formats:
 //
 // https://runatlantis.visualstudio.com/project/_git/repo
 // https://dev.azure.com/runatlantis/project/_git/repo
+// https://azuredevops.example.com/tfs/runatlantis/project/_git/repo
+//
+// The last is Azure DevOps Server where the owner is the collection. The /tfs
+// virtual directory is optional.
 //
 // See EventParsing for return value docs.
 func (e *EventParser) ParseAzureDevopsRepo(adRepo *azuredevops.GitRepository) (models.Repo, error) {
 	teamProject := adRepo.GetProject()
 	parent := adRepo.GetParentRepository()
+	uri, err := url.Parse(adRepo.GetWebURL())
+	if err != nil {
+		return models.Repo{}, err
+	}
+	collectionURL, collection := azureDevopsServerCollection(uri)
 	owner := ""
 	if parent != nil {
 		owner = parent.GetName()
+	} else if strings.Contains(uri.Host, "visualstudio.com") {
+		owner = strings.Split(uri.Host, ".")[0]
+	} else if strings.Contains(uri.Host, "dev.azure.com") {
+		owner = strings.Split(uri.Path, "/")[1]
 	} else {
-		uri, err := url.Parse(adRepo.GetWebURL())
-		if err != nil {
-			return models.Repo{}, err
-		}
-		if strings.Contains(uri.Host, "visualstudio.com") {
-			owner = strings.Split(uri.Host, ".")[0]
-		} else if strings.Contains(uri.Host, "dev.azure.com") {
-			owner = strings.Split(uri.Path, "/")[1]
-		} else {
-			owner = ""
-		}
+		owner = collection
 	}
 
 	// Construct our own clone URL so we always get the new dev.azure.com
 	// hostname for now.
 	// https://docs.microsoft.com/en-us/azure/devops/release-notes/2018/sep-10-azure-devops-launch#switch-existing-organizations-to-use-the-new-domain-name-url
+	// Azure DevOps Server repos are cloned from the server.
 	project := teamProject.GetName()
 	repo := adRepo.GetName()
 	cloneURL := fmt.Sprintf("https://dev.azure.com/%s/%s/_git/%s", owner, project, repo)
+	if collectionURL != "" {
+		cloneURL = fmt.Sprintf("%s/%s/_git/%s", collectionURL, project, repo)
+	}
 	fullName := fmt.Sprintf("%s/%s/%s", owner, project, repo)
 	return models.NewRepo(models.AzureDevops, fullName, cloneURL, e.AzureDevopsUser, e.AzureDevopsToken)
 }
+
+// azureDevopsServerCollection returns the URL of the collection that the repo
+// with webURL is in and its name if webURL is an Azure DevOps Server URL, ex.
+// https://azuredevops.example.com/tfs/collection/project/_git/repo. It returns
+// empty strings for Azure DevOps Services URLs.
+func azureDevopsServerCollection(webURL *url.URL) (string, string) {
+	if webURL.Host == "" || strings.Contains(webURL.Host, "dev.azure.com") || strings.Contains(webURL.Host, "visualstudio.com") {
+		return "", ""
+	}
+	segments := strings.Split(strings.Trim(webURL.Path, "/"), "/")
+	for i, segment := range segments {
+		// The collection comes before the project, which comes before _git.
+		if segment == "_git" && i >= 2 {
+			collectionPath := strings.Join(segments[:i-1], "/")
+			return fmt.Sprintf("%s://%s/%s", webURL.Scheme, webURL.Host, collectionPath), segments[i-2]
+		}
+	}
+	return "", ""
+}
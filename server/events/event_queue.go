@@ -0,0 +1,239 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// DefaultEventQueueMaxAttempts is how many times handling a queued event is
+// started before it's dropped.
+const DefaultEventQueueMaxAttempts = 3
+
+// ErrEventQueueFull is returned when queueing an event while the queue
+// already holds its max depth of events.
+var ErrEventQueueFull = errors.New("event queue is full")
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_event_queue_store.go EventQueueStore
+
+// EventQueueStore stores the events in an EventQueue so they survive
+// restarts.
+type EventQueueStore interface {
+	// PutQueuedEvent stores event and returns it with its ID set.
+	PutQueuedEvent(event models.QueuedEvent) (models.QueuedEvent, error)
+	// DeleteQueuedEvent deletes the stored event with id.
+	DeleteQueuedEvent(id uint64) error
+	// ListQueuedEvents returns the stored events, oldest first.
+	ListQueuedEvents() ([]models.QueuedEvent, error)
+}
+
+// queuedCommand is the payload of a queued event. It holds the arguments of
+// the CommandRunner method that handles the event.
+type queuedCommand struct {
+	Autoplan bool
+	BaseRepo models.Repo
+	HeadRepo *models.Repo
+	Pull     *models.PullRequest
+	User     models.User
+	PullNum  int
+	Command  *CommentCommand
+}
+
+// EventQueue sits between the events controller and the command runner so
+// webhooks can be responded to as soon as their event is queued. Events are
+// stored until they've been handled so events that were queued or being
+// handled when the server stopped are handled again once it starts. Workers
+// events are handled at a time, in order of their repo's priority and then
+// in the order they were queued.
+type EventQueue struct {
+	Runner CommandRunner
+	Store  EventQueueStore
+	// Workers is how many events are handled at once.
+	Workers int
+	// MaxDepth is the most events that can be queued, including the ones
+	// being handled, before new events are rejected so the VCS host can
+	// deliver them again later. 0 means there's no limit.
+	MaxDepth int
+	// MaxAttempts is how many times handling an event is started before
+	// it's dropped, ex. because the server keeps crashing while handling
+	// it.
+	MaxAttempts int
+	Logger      logging.SimpleLogging
+	// Depth, if set, is set to the number of queued events.
+	Depth *metrics.Gauge
+	// Priority, if set, returns the priority of the events of repo so
+	// events of small repos aren't stuck behind those of a busy monorepo.
+	// If nil, all events have the same priority.
+	Priority func(repo models.Repo) int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []models.QueuedEvent
+	running int
+}
+
+// Start queues the stored events and starts the workers that handle them.
+// It must be called before any events are queued.
+func (q *EventQueue) Start() error {
+	q.cond = sync.NewCond(&q.mu)
+	stored, err := q.Store.ListQueuedEvents()
+	if err != nil {
+		return errors.Wrap(err, "listing queued events")
+	}
+	q.mu.Lock()
+	for _, event := range stored {
+		if event.Attempts >= q.MaxAttempts {
+			q.Logger.Err("dropping queued event %d after %d attempts to handle it", event.ID, event.Attempts)
+			if err := q.Store.DeleteQueuedEvent(event.ID); err != nil {
+				q.Logger.Err("unable to delete queued event %d: %s", event.ID, err)
+			}
+			continue
+		}
+		q.pending = append(q.pending, event)
+	}
+	if len(q.pending) > 0 {
+		q.Logger.Info("handling %d events that were queued before the server restarted", len(q.pending))
+	}
+	q.updateDepth()
+	q.mu.Unlock()
+
+	for i := 0; i < q.Workers; i++ {
+		go q.work()
+	}
+	return nil
+}
+
+// QueueAutoplan queues running autoplan on pull.
+func (q *EventQueue) QueueAutoplan(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) error {
+	return q.queue(queuedCommand{
+		Autoplan: true,
+		BaseRepo: baseRepo,
+		HeadRepo: &headRepo,
+		Pull:     &pull,
+		User:     user,
+		PullNum:  pull.Num,
+	})
+}
+
+// QueueCommentCommand queues running cmd, which was commented on pull request
+// pullNum.
+func (q *EventQueue) QueueCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) error {
+	return q.queue(queuedCommand{
+		BaseRepo: baseRepo,
+		HeadRepo: maybeHeadRepo,
+		Pull:     maybePull,
+		User:     user,
+		PullNum:  pullNum,
+		Command:  cmd,
+	})
+}
+
+// Len returns the number of queued events, including the ones being
+// handled.
+func (q *EventQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending) + q.running
+}
+
+func (q *EventQueue) queue(cmd queuedCommand) error {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "serializing event")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.MaxDepth > 0 && len(q.pending)+q.running >= q.MaxDepth {
+		return ErrEventQueueFull
+	}
+	var priority int
+	if q.Priority != nil {
+		priority = q.Priority(cmd.BaseRepo)
+	}
+	event, err := q.Store.PutQueuedEvent(models.QueuedEvent{
+		QueuedAt: time.Now().UTC(),
+		Payload:  payload,
+		Priority: priority,
+	})
+	if err != nil {
+		return errors.Wrap(err, "storing event")
+	}
+	q.pending = append(q.pending, event)
+	q.updateDepth()
+	q.cond.Signal()
+	return nil
+}
+
+// work handles queued events forever.
+func (q *EventQueue) work() {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 {
+			q.cond.Wait()
+		}
+		event := q.next()
+		q.running++
+		q.mu.Unlock()
+
+		q.handle(event)
+
+		q.mu.Lock()
+		q.running--
+		q.updateDepth()
+		q.mu.Unlock()
+	}
+}
+
+// next removes and returns the first pending event with the highest
+// priority. q.mu must be held and there must be pending events.
+func (q *EventQueue) next() models.QueuedEvent {
+	i := 0
+	for j, event := range q.pending {
+		if event.Priority > q.pending[i].Priority {
+			i = j
+		}
+	}
+	event := q.pending[i]
+	q.pending = append(q.pending[:i], q.pending[i+1:]...)
+	return event
+}
+
+// handle runs the command of event and then deletes it from the store. The
+// attempt is stored first so an event that crashes the server isn't handled
+// more than MaxAttempts times.
+func (q *EventQueue) handle(event models.QueuedEvent) {
+	event.Attempts++
+	if _, err := q.Store.PutQueuedEvent(event); err != nil {
+		q.Logger.Warn("unable to store attempt to handle queued event %d: %s", event.ID, err)
+	}
+	defer func() {
+		if err := q.Store.DeleteQueuedEvent(event.ID); err != nil {
+			q.Logger.Err("unable to delete queued event %d: %s", event.ID, err)
+		}
+	}()
+
+	var cmd queuedCommand
+	if err := json.Unmarshal(event.Payload, &cmd); err != nil {
+		q.Logger.Err("dropping queued event %d: deserializing: %s", event.ID, err)
+		return
+	}
+	q.Logger.Debug("handling queued event %d for %s#%d after %s", event.ID, cmd.BaseRepo.FullName, cmd.PullNum, time.Since(event.QueuedAt).Round(time.Millisecond))
+	if cmd.Autoplan {
+		q.Runner.RunAutoplanCommand(cmd.BaseRepo, *cmd.HeadRepo, *cmd.Pull, cmd.User)
+		return
+	}
+	q.Runner.RunCommentCommand(cmd.BaseRepo, cmd.HeadRepo, cmd.Pull, cmd.User, cmd.PullNum, cmd.Command)
+}
+
+// updateDepth sets the Depth gauge. q.mu must be held.
+func (q *EventQueue) updateDepth() {
+	if q.Depth != nil {
+		q.Depth.Set(float64(len(q.pending) + q.running))
+	}
+}
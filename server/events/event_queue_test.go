@@ -0,0 +1,194 @@
+package events_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// blockingRunner reports each command it runs on ran and then waits for
+// release.
+type blockingRunner struct {
+	ran     chan string
+	release chan struct{}
+}
+
+func (b *blockingRunner) RunCommentCommand(baseRepo models.Repo, _ *models.Repo, _ *models.PullRequest, _ models.User, pullNum int, cmd *events.CommentCommand) {
+	b.ran <- fmt.Sprintf("%s#%d %s", baseRepo.FullName, pullNum, cmd.Name.String())
+	<-b.release
+}
+
+func (b *blockingRunner) RunAutoplanCommand(baseRepo models.Repo, _ models.Repo, pull models.PullRequest, _ models.User) {
+	b.ran <- fmt.Sprintf("%s#%d autoplan", baseRepo.FullName, pull.Num)
+	<-b.release
+}
+
+func TestEventQueue_HandlesEventsInOrder(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	runner := &blockingRunner{ran: make(chan string), release: make(chan struct{})}
+	q := &events.EventQueue{
+		Runner:      runner,
+		Store:       boltDB,
+		Workers:     1,
+		MaxAttempts: events.DefaultEventQueueMaxAttempts,
+		Logger:      logging.NewNoopLogger(),
+	}
+	Ok(t, q.Start())
+
+	repo := models.Repo{FullName: "owner/repo"}
+	Ok(t, q.QueueAutoplan(repo, repo, models.PullRequest{Num: 1}, models.User{}))
+	Ok(t, q.QueueCommentCommand(repo, nil, nil, models.User{}, 2, &events.CommentCommand{Name: models.ApplyCommand}))
+
+	Equals(t, "owner/repo#1 autoplan", receive(t, runner.ran))
+	Equals(t, 2, q.Len())
+	stored, err := boltDB.ListQueuedEvents()
+	Ok(t, err)
+	Equals(t, 2, len(stored))
+	Equals(t, 1, stored[0].Attempts)
+	Equals(t, 0, stored[1].Attempts)
+
+	runner.release <- struct{}{}
+	Equals(t, "owner/repo#2 apply", receive(t, runner.ran))
+	runner.release <- struct{}{}
+	waitFor(t, func() bool { return q.Len() == 0 })
+	stored, err = boltDB.ListQueuedEvents()
+	Ok(t, err)
+	Equals(t, 0, len(stored))
+}
+
+func TestEventQueue_HandlesHigherPrioritiesFirst(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	runner := &blockingRunner{ran: make(chan string), release: make(chan struct{})}
+	q := &events.EventQueue{
+		Runner:      runner,
+		Store:       boltDB,
+		Workers:     1,
+		MaxAttempts: events.DefaultEventQueueMaxAttempts,
+		Logger:      logging.NewNoopLogger(),
+		Priority: func(repo models.Repo) int {
+			if repo.FullName == "owner/small" {
+				return 10
+			}
+			return 0
+		},
+	}
+	Ok(t, q.Start())
+
+	monorepo := models.Repo{FullName: "owner/monorepo"}
+	small := models.Repo{FullName: "owner/small"}
+	Ok(t, q.QueueAutoplan(monorepo, monorepo, models.PullRequest{Num: 1}, models.User{}))
+	Equals(t, "owner/monorepo#1 autoplan", receive(t, runner.ran))
+	Ok(t, q.QueueAutoplan(monorepo, monorepo, models.PullRequest{Num: 2}, models.User{}))
+	Ok(t, q.QueueAutoplan(monorepo, monorepo, models.PullRequest{Num: 3}, models.User{}))
+	Ok(t, q.QueueAutoplan(small, small, models.PullRequest{Num: 4}, models.User{}))
+
+	runner.release <- struct{}{}
+	Equals(t, "owner/small#4 autoplan", receive(t, runner.ran))
+	runner.release <- struct{}{}
+	Equals(t, "owner/monorepo#2 autoplan", receive(t, runner.ran))
+	runner.release <- struct{}{}
+	Equals(t, "owner/monorepo#3 autoplan", receive(t, runner.ran))
+	runner.release <- struct{}{}
+	waitFor(t, func() bool { return q.Len() == 0 })
+}
+
+func TestEventQueue_RejectsEventsOverMaxDepth(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	runner := &blockingRunner{ran: make(chan string), release: make(chan struct{})}
+	q := &events.EventQueue{
+		Runner:      runner,
+		Store:       boltDB,
+		Workers:     1,
+		MaxDepth:    2,
+		MaxAttempts: events.DefaultEventQueueMaxAttempts,
+		Logger:      logging.NewNoopLogger(),
+	}
+	Ok(t, q.Start())
+
+	repo := models.Repo{FullName: "owner/repo"}
+	Ok(t, q.QueueAutoplan(repo, repo, models.PullRequest{Num: 1}, models.User{}))
+	receive(t, runner.ran)
+	Ok(t, q.QueueAutoplan(repo, repo, models.PullRequest{Num: 2}, models.User{}))
+	Equals(t, events.ErrEventQueueFull, q.QueueAutoplan(repo, repo, models.PullRequest{Num: 3}, models.User{}))
+
+	runner.release <- struct{}{}
+	receive(t, runner.ran)
+	Ok(t, q.QueueAutoplan(repo, repo, models.PullRequest{Num: 3}, models.User{}))
+	runner.release <- struct{}{}
+	Equals(t, "owner/repo#3 autoplan", receive(t, runner.ran))
+	runner.release <- struct{}{}
+}
+
+func TestEventQueue_StartHandlesStoredEvents(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	repo := models.Repo{FullName: "owner/repo"}
+
+	// Queue events with a queue that has no workers, as if the server
+	// stopped before handling them.
+	stopped := &events.EventQueue{Store: boltDB, MaxAttempts: 2, Logger: logging.NewNoopLogger()}
+	Ok(t, stopped.Start())
+	Ok(t, stopped.QueueAutoplan(repo, repo, models.PullRequest{Num: 1}, models.User{}))
+	Ok(t, stopped.QueueAutoplan(repo, repo, models.PullRequest{Num: 2}, models.User{}))
+	queued, err := boltDB.ListQueuedEvents()
+	Ok(t, err)
+	// The first event has crashed the server each time it was handled.
+	queued[0].Attempts = 2
+	_, err = boltDB.PutQueuedEvent(queued[0])
+	Ok(t, err)
+
+	runner := &blockingRunner{ran: make(chan string), release: make(chan struct{})}
+	q := &events.EventQueue{
+		Runner:      runner,
+		Store:       boltDB,
+		Workers:     1,
+		MaxAttempts: 2,
+		Logger:      logging.NewNoopLogger(),
+	}
+	Ok(t, q.Start())
+	Equals(t, "owner/repo#2 autoplan", receive(t, runner.ran))
+	runner.release <- struct{}{}
+	waitFor(t, func() bool { return q.Len() == 0 })
+	stored, err := boltDB.ListQueuedEvents()
+	Ok(t, err)
+	Equals(t, 0, len(stored))
+}
+
+func receive(t *testing.T, ch chan string) string {
+	t.Helper()
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a command to run")
+		return ""
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// eventRateLimitWindow is the window that EventRateLimiter's limits apply to.
+const eventRateLimitWindow = time.Minute
+
+// The kinds of rejections counted by EventRateLimiter.Limited.
+const (
+	ipEventRateLimit     = "ip"
+	repoEventRateLimit   = "repo"
+	circuitBreakerOpened = "circuit_breaker"
+)
+
+// EventRateLimiter limits how many webhooks each IP can send and how many
+// events each repo can trigger per minute. It protects the server from
+// misbehaving clients and from repos that generate pathological volumes of
+// events, ex. a bot pushing in a loop. A nil *EventRateLimiter allows every
+// event.
+type EventRateLimiter struct {
+	// PerIP is the most webhook requests an IP can send per minute. 0
+	// disables the limit.
+	PerIP int
+	// PerRepo is the most events a repo can trigger per minute. 0 disables
+	// the limit.
+	PerRepo int
+	// BreakerCooldown, if set, opens a repo's circuit breaker once it
+	// exceeds PerRepo: all of the repo's events are rejected until the
+	// cooldown is over, even if they'd be within the limit.
+	BreakerCooldown time.Duration
+	// Limited, if set, counts the rejected events by kind: ip, repo or
+	// circuit_breaker.
+	Limited *metrics.Counter
+	Logger  logging.SimpleLogging
+	Now     func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*eventRateWindow
+	breakers  map[string]time.Time
+	lastSweep time.Time
+}
+
+// eventRateWindow is the events one IP or repo sent in the last window.
+type eventRateWindow struct {
+	// times are when the events were sent, oldest first.
+	times []time.Time
+	// warned is true if we already warned that the IP or repo is limited in
+	// the current window so the log isn't flooded.
+	warned bool
+}
+
+// AllowIP records a webhook request from ip and returns whether it's within
+// the limit.
+func (l *EventRateLimiter) AllowIP(ip string) bool {
+	if l == nil || l.PerIP <= 0 {
+		return true
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.allow(now, ipEventRateLimit, ip, l.PerIP)
+}
+
+// AllowRepo records an event for the repo repoFullName and returns whether
+// it's within the limit and the repo's circuit breaker is closed.
+func (l *EventRateLimiter) AllowRepo(repoFullName string) bool {
+	if l == nil || l.PerRepo <= 0 {
+		return true
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until, ok := l.breakers[repoFullName]; ok && now.Before(until) {
+		l.count(circuitBreakerOpened)
+		return false
+	}
+	if l.allow(now, repoEventRateLimit, repoFullName, l.PerRepo) {
+		return true
+	}
+	if l.BreakerCooldown > 0 {
+		l.breakers[repoFullName] = now.Add(l.BreakerCooldown)
+		l.Logger.Warn("pausing events for repo %s for %s because it triggered more than %d events in %s", repoFullName, l.BreakerCooldown, l.PerRepo, eventRateLimitWindow)
+	}
+	return false
+}
+
+// allow records an event for key and returns whether there were fewer than
+// max events for key in the last window. Rejected events don't count towards
+// the limit. l.mu must be held.
+func (l *EventRateLimiter) allow(now time.Time, kind string, key string, max int) bool {
+	if l.windows == nil {
+		l.windows = make(map[string]*eventRateWindow)
+		l.breakers = make(map[string]time.Time)
+	}
+	l.sweep(now)
+
+	w, ok := l.windows[kind+":"+key]
+	if !ok {
+		w = &eventRateWindow{}
+		l.windows[kind+":"+key] = w
+	}
+	w.expire(now.Add(-eventRateLimitWindow))
+	if len(w.times) < max {
+		w.times = append(w.times, now)
+		return true
+	}
+
+	l.count(kind)
+	if !w.warned {
+		l.Logger.Warn("rate limiting events from %s %s because it sent more than %d in %s", kind, key, max, eventRateLimitWindow)
+		w.warned = true
+	}
+	return false
+}
+
+func (l *EventRateLimiter) count(kind string) {
+	if l.Limited != nil {
+		l.Limited.Inc(kind)
+	}
+}
+
+// expire forgets the events sent before cutoff.
+func (w *eventRateWindow) expire(cutoff time.Time) {
+	i := 0
+	for i < len(w.times) && !w.times[i].After(cutoff) {
+		i++
+	}
+	w.times = w.times[i:]
+	if len(w.times) == 0 {
+		w.warned = false
+	}
+}
+
+// sweep forgets the IPs and repos that haven't sent events in the last window
+// and the circuit breakers that have closed so the maps don't grow forever.
+// It runs at most once per window. l.mu must be held.
+func (l *EventRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < eventRateLimitWindow {
+		return
+	}
+	l.lastSweep = now
+	cutoff := now.Add(-eventRateLimitWindow)
+	for key, w := range l.windows {
+		w.expire(cutoff)
+		if len(w.times) == 0 {
+			delete(l.windows, key)
+		}
+	}
+	for repo, until := range l.breakers {
+		if !now.Before(until) {
+			delete(l.breakers, repo)
+		}
+	}
+}
+
+func (l *EventRateLimiter) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
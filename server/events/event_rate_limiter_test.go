@@ -0,0 +1,72 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestEventRateLimiter_AllowIP(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	limiter := &events.EventRateLimiter{
+		PerIP:  2,
+		Logger: logging.NewNoopLogger(),
+		Now:    func() time.Time { return now },
+	}
+
+	Equals(t, true, limiter.AllowIP("10.0.0.1"))
+	Equals(t, true, limiter.AllowIP("10.0.0.1"))
+	Equals(t, false, limiter.AllowIP("10.0.0.1"))
+	// Other IPs have their own limits.
+	Equals(t, true, limiter.AllowIP("10.0.0.2"))
+	// The repo limit is disabled.
+	Equals(t, true, limiter.AllowRepo("owner/repo"))
+
+	// Once the first request is outside the window, another is allowed.
+	now = now.Add(time.Minute)
+	Equals(t, true, limiter.AllowIP("10.0.0.1"))
+}
+
+func TestEventRateLimiter_AllowRepo(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	registry := metrics.NewRegistry()
+	limiter := &events.EventRateLimiter{
+		PerRepo:         2,
+		BreakerCooldown: 10 * time.Minute,
+		Limited:         registry.NewCounter("atlantis_events_rate_limited", "", "kind"),
+		Logger:          logging.NewNoopLogger(),
+		Now:             func() time.Time { return now },
+	}
+
+	Equals(t, true, limiter.AllowRepo("owner/repo"))
+	Equals(t, true, limiter.AllowRepo("owner/repo"))
+	// Exceeding the limit opens the circuit breaker.
+	Equals(t, false, limiter.AllowRepo("owner/repo"))
+	Equals(t, true, limiter.AllowRepo("owner/other"))
+
+	// The repo's events are rejected until the cooldown is over even though
+	// they'd be within the limit.
+	now = now.Add(5 * time.Minute)
+	Equals(t, false, limiter.AllowRepo("owner/repo"))
+	now = now.Add(5 * time.Minute)
+	Equals(t, true, limiter.AllowRepo("owner/repo"))
+
+	families, err := registry.Gather()
+	Ok(t, err)
+	Equals(t, []metrics.Sample{
+		{Labels: []string{"circuit_breaker"}, Value: 1},
+		{Labels: []string{"repo"}, Value: 1},
+	}, families[0].Samples)
+}
+
+func TestEventRateLimiter_Disabled(t *testing.T) {
+	var limiter *events.EventRateLimiter
+	for i := 0; i < 100; i++ {
+		Equals(t, true, limiter.AllowIP("10.0.0.1"))
+		Equals(t, true, limiter.AllowRepo("owner/repo"))
+	}
+}
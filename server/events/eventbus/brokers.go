@@ -0,0 +1,284 @@
+package eventbus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/pkg/errors"
+)
+
+// sendTimeout is how long sending a single event can take.
+const sendTimeout = 10 * time.Second
+
+// ValidateURL returns an error if brokerURL isn't one NewBroker supports.
+func ValidateURL(brokerURL string) error {
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "nats", "sqs", "kafka+http", "kafka+https":
+	default:
+		return fmt.Errorf("%q must start with nats://, sqs://, kafka+http:// or kafka+https://", brokerURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", brokerURL)
+	}
+	if u.Scheme == "sqs" && strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("%q has no queue", brokerURL)
+	}
+	return nil
+}
+
+// NewBroker returns the broker for brokerURL. nats://[user:password@]<host>:<port>
+// publishes to the topic subject, sqs://<queue URL without https://> sends
+// messages to an SQS queue and kafka+http(s)://<host>:<port> produces to the
+// topic with a Kafka REST proxy.
+func NewBroker(brokerURL string, topic string) (Broker, error) {
+	if err := ValidateURL(brokerURL); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(brokerURL)
+	switch u.Scheme {
+	case "nats":
+		broker := &NATSBroker{Addr: u.Host, Subject: topic}
+		if u.User != nil {
+			broker.User = u.User.Username()
+			broker.Password, _ = u.User.Password()
+		}
+		return broker, nil
+	case "sqs":
+		return NewSQSBroker("https://" + u.Host + u.Path)
+	default:
+		return &KafkaRESTBroker{
+			URL:    strings.TrimPrefix(u.Scheme, "kafka+") + "://" + u.Host + strings.TrimRight(u.Path, "/"),
+			Topic:  topic,
+			Client: &http.Client{Timeout: sendTimeout},
+		}, nil
+	}
+}
+
+// NATSBroker publishes events to a NATS subject. It speaks NATS's text
+// protocol directly and keeps its connection open between events.
+type NATSBroker struct {
+	Addr     string
+	Subject  string
+	User     string
+	Password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Send implements Broker. If publishing fails, the connection is closed and
+// the next event reconnects.
+func (n *NATSBroker) Send(payload []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return errors.Wrapf(err, "connecting to nats server %s", n.Addr)
+		}
+	}
+	if err := n.publish(payload); err != nil {
+		n.conn.Close() // nolint: errcheck
+		n.conn = nil
+		return errors.Wrapf(err, "publishing to nats subject %s", n.Subject)
+	}
+	return nil
+}
+
+func (n *NATSBroker) connect() error {
+	conn, err := net.DialTimeout("tcp", n.Addr, sendTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout)) // nolint: errcheck
+	reader := bufio.NewReader(conn)
+	// The server greets each client with its INFO before anything else.
+	if line, err := reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close() // nolint: errcheck
+		if err == nil {
+			err = fmt.Errorf("expected INFO but got %q", strings.TrimSpace(line))
+		}
+		return err
+	}
+	options, _ := json.Marshal(map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "atlantis",
+		"user":     n.User,
+		"pass":     n.Password,
+	})
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", options); err != nil {
+		conn.Close() // nolint: errcheck
+		return err
+	}
+	n.conn = conn
+	n.reader = reader
+	return nil
+}
+
+// publish sends payload followed by a PING so that the server's PONG confirms
+// it processed the message, or its -ERR says why it didn't.
+func (n *NATSBroker) publish(payload []byte) error {
+	n.conn.SetDeadline(time.Now().Add(sendTimeout)) // nolint: errcheck
+	if _, err := fmt.Fprintf(n.conn, "PUB %s %d\r\n%s\r\nPING\r\n", n.Subject, len(payload), payload); err != nil {
+		return err
+	}
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server responded %s", line)
+		}
+	}
+}
+
+// SQSBroker sends events as messages to an SQS queue with its query API.
+type SQSBroker struct {
+	QueueURL    string
+	Region      string
+	Credentials *credentials.Credentials
+	Client      *http.Client
+}
+
+// NewSQSBroker returns a broker for the queue at queueURL, ex.
+// https://sqs.us-east-1.amazonaws.com/123456789012/atlantis. Credentials are
+// read like the AWS CLI reads them. The region is read from the queue URL or,
+// if it doesn't have one, like the AWS CLI reads it.
+func NewSQSBroker(queueURL string) (*SQSBroker, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS session")
+	}
+	region := ""
+	if sess.Config.Region != nil {
+		region = *sess.Config.Region
+	}
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	if parts := strings.Split(u.Hostname(), "."); len(parts) > 2 && parts[0] == "sqs" {
+		region = parts[1]
+	}
+	if region == "" {
+		return nil, fmt.Errorf("unable to determine the region of queue %s, set AWS_REGION", queueURL)
+	}
+	return &SQSBroker{
+		QueueURL:    queueURL,
+		Region:      region,
+		Credentials: sess.Config.Credentials,
+		Client:      &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// Send implements Broker.
+func (s *SQSBroker) Send(payload []byte) error {
+	body := url.Values{
+		"Action":      {"SendMessage"},
+		"Version":     {"2012-11-05"},
+		"MessageBody": {string(payload)},
+	}.Encode()
+	req, err := http.NewRequest("POST", s.QueueURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := v4.NewSigner(s.Credentials).Sign(req, strings.NewReader(body), "sqs", s.Region, time.Now()); err != nil {
+		return errors.Wrap(err, "signing request")
+	}
+	_, err = do(s.Client, req, "sending message to "+s.QueueURL)
+	return err
+}
+
+// KafkaRESTBroker produces events to a Kafka topic with a Kafka REST proxy's
+// v2 API.
+type KafkaRESTBroker struct {
+	// URL is the proxy's URL, ex. https://kafka-rest.example.com:8082.
+	URL    string
+	Topic  string
+	Client *http.Client
+}
+
+// Send implements Broker.
+func (k *KafkaRESTBroker) Send(payload []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]json.RawMessage{{"value": payload}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/topics/%s", k.URL, url.PathEscape(k.Topic)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	action := "producing to kafka topic " + k.Topic
+	respBody, err := do(k.Client, req, action)
+	if err != nil {
+		return err
+	}
+	// The proxy responds 200 even if Kafka rejected the record so its offset
+	// has to be checked too.
+	var produced struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(respBody, &produced); err != nil {
+		return errors.Wrapf(err, "%s: parsing response", action)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("%s: error code %d: %s", action, *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
+
+// do sends req and returns the response's body, or an error describing action
+// if it fails or the response isn't a 2xx.
+func do(client *http.Client, req *http.Request, action string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, action)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: reading response", action)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: responded with status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,42 @@
+package eventbus
+
+import (
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// Locker wraps a locking.Locker and publishes an event whenever a lock is
+// acquired or released through it, no matter which part of Atlantis did it.
+type Locker struct {
+	locking.Locker
+	Publisher *Publisher
+}
+
+// TryLock implements locking.Locker.
+func (l *Locker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+	resp, err := l.Locker.TryLock(p, workspace, pull, user)
+	if err == nil && resp.LockAcquired {
+		l.Publisher.Publish(NewLockEvent(LockAcquiredEvent, resp.CurrLock))
+	}
+	return resp, err
+}
+
+// Unlock implements locking.Locker.
+func (l *Locker) Unlock(key string) (*models.ProjectLock, error) {
+	lock, err := l.Locker.Unlock(key)
+	if err == nil && lock != nil {
+		l.Publisher.Publish(NewLockEvent(LockReleasedEvent, *lock))
+	}
+	return lock, err
+}
+
+// UnlockByPull implements locking.Locker.
+func (l *Locker) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	locks, err := l.Locker.UnlockByPull(repoFullName, pullNum)
+	if err == nil {
+		for _, lock := range locks {
+			l.Publisher.Publish(NewLockEvent(LockReleasedEvent, lock))
+		}
+	}
+	return locks, err
+}
@@ -0,0 +1,174 @@
+// Package eventbus publishes structured events about what Atlantis is doing,
+// ex. plans finishing and locks being released, to a message broker so other
+// systems can consume them without scraping pull request comments.
+package eventbus
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// queueSize is how many events can be waiting to be sent before new ones are
+// dropped.
+const queueSize = 1000
+
+// EventType is the kind of activity an event describes.
+type EventType string
+
+const (
+	// PlanStartedEvent is published when a project starts being planned.
+	PlanStartedEvent EventType = "plan_started"
+	// PlanFinishedEvent is published when a project's plan finishes,
+	// successfully or not.
+	PlanFinishedEvent EventType = "plan_finished"
+	// ApplyStartedEvent is published when a project starts being applied.
+	ApplyStartedEvent EventType = "apply_started"
+	// ApplyFinishedEvent is published with the result of applying a project.
+	ApplyFinishedEvent EventType = "apply_finished"
+	// LockAcquiredEvent is published when a pull request locks a project.
+	LockAcquiredEvent EventType = "lock_acquired"
+	// LockReleasedEvent is published when a project's lock is deleted, ex.
+	// because its pull request was closed.
+	LockReleasedEvent EventType = "lock_released"
+	// PolicyFailedEvent is published when a project isn't planned because
+	// a check Atlantis enforces failed, ex. secrets were found.
+	PolicyFailedEvent EventType = "policy_failed"
+)
+
+// Result is the outcome of a finished command.
+type Result string
+
+const (
+	// SuccessResult means the command succeeded.
+	SuccessResult Result = "success"
+	// FailureResult means the command ran but didn't succeed, ex. because
+	// apply requirements weren't met.
+	FailureResult Result = "failure"
+	// ErrorResult means the command errored.
+	ErrorResult Result = "error"
+)
+
+// Event is what's published to the broker as JSON.
+type Event struct {
+	Type         EventType `json:"type"`
+	Time         time.Time `json:"time"`
+	RepoFullName string    `json:"repo"`
+	PullNum      int       `json:"pull_num"`
+	// User is who ran the command or, for lock events, who holds the lock.
+	User        string `json:"user,omitempty"`
+	ProjectName string `json:"project,omitempty"`
+	ProjectID   string `json:"project_id,omitempty"`
+	RepoRelDir  string `json:"dir"`
+	Workspace   string `json:"workspace"`
+	// Result is only set for finished events.
+	Result Result `json:"result,omitempty"`
+	// Error is the error or failure message if the command didn't succeed.
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// NewProjectEvent returns an event of type eventType for the project in ctx.
+func NewProjectEvent(eventType EventType, ctx models.ProjectCommandContext) Event {
+	return Event{
+		Type:         eventType,
+		Time:         time.Now().UTC(),
+		RepoFullName: ctx.BaseRepo.FullName,
+		PullNum:      ctx.Pull.Num,
+		User:         ctx.User.Username,
+		ProjectName:  ctx.ProjectName,
+		ProjectID:    ctx.ProjectID(),
+		RepoRelDir:   ctx.RepoRelDir,
+		Workspace:    ctx.Workspace,
+	}
+}
+
+// NewProjectResultEvent returns an event of type eventType for the project in
+// ctx that finished with res after duration.
+func NewProjectResultEvent(eventType EventType, ctx models.ProjectCommandContext, res models.ProjectResult, duration time.Duration) Event {
+	event := NewProjectEvent(eventType, ctx)
+	event.Result = SuccessResult
+	event.DurationMS = int64(duration / time.Millisecond)
+	if res.Error != nil {
+		event.Result = ErrorResult
+		event.Error = res.Error.Error()
+	} else if res.Failure != "" {
+		event.Result = FailureResult
+		event.Error = res.Failure
+	}
+	return event
+}
+
+// NewLockEvent returns an event of type eventType for lock.
+func NewLockEvent(eventType EventType, lock models.ProjectLock) Event {
+	return Event{
+		Type:         eventType,
+		Time:         time.Now().UTC(),
+		RepoFullName: lock.Project.RepoFullName,
+		PullNum:      lock.Pull.Num,
+		User:         lock.User.Username,
+		ProjectID:    lock.ProjectID(),
+		RepoRelDir:   lock.Project.Path,
+		Workspace:    lock.Workspace,
+	}
+}
+
+// Broker is the message broker that events are sent to.
+type Broker interface {
+	// Send delivers payload, which is a JSON encoded event.
+	Send(payload []byte) error
+}
+
+// Publisher sends events to a broker in the background so publishing doesn't
+// slow down or fail commands. A nil *Publisher doesn't publish anything.
+type Publisher struct {
+	Broker Broker
+	Logger logging.SimpleLogging
+
+	queue chan Event
+}
+
+// NewPublisher returns a publisher that sends to broker. Start must be called
+// for events to be sent.
+func NewPublisher(broker Broker, logger logging.SimpleLogging) *Publisher {
+	return &Publisher{
+		Broker: broker,
+		Logger: logger,
+		queue:  make(chan Event, queueSize),
+	}
+}
+
+// Start sends queued events until the process exits.
+func (p *Publisher) Start() {
+	for event := range p.queue {
+		if err := p.send(event); err != nil {
+			p.Logger.Err("publishing %s event for %s#%d: %s", event.Type, event.RepoFullName, event.PullNum, err)
+		}
+	}
+}
+
+// Publish queues event to be sent. If too many events are queued, it's
+// dropped.
+func (p *Publisher) Publish(event Event) {
+	if p == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.Logger.Warn("dropping %s event because %d are already waiting to be published", event.Type, queueSize)
+	}
+}
+
+func (p *Publisher) send(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.Broker.Send(payload)
+}
@@ -0,0 +1,151 @@
+package eventbus_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/eventbus"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// memBroker is a Broker that keeps the events it's sent in memory.
+type memBroker struct {
+	mu     sync.Mutex
+	events []eventbus.Event
+}
+
+func (m *memBroker) Send(payload []byte) error {
+	var event eventbus.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+// waitForEvents returns the events sent to m once there are n of them.
+func (m *memBroker) waitForEvents(t *testing.T, n int) []eventbus.Event {
+	for i := 0; i < 100; i++ {
+		m.mu.Lock()
+		events := m.events
+		m.mu.Unlock()
+		if len(events) >= n {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d events", n)
+	return nil
+}
+
+var projectCtx = models.ProjectCommandContext{
+	BaseRepo:    models.Repo{FullName: "owner/repo"},
+	Pull:        models.PullRequest{Num: 1},
+	User:        models.User{Username: "alice"},
+	ProjectName: "prod",
+	RepoRelDir:  "infra/prod",
+	Workspace:   "default",
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	broker := &memBroker{}
+	p := eventbus.NewPublisher(broker, logging.NewNoopLogger())
+	go p.Start()
+
+	p.Publish(eventbus.NewProjectEvent(eventbus.PlanStartedEvent, projectCtx))
+	p.Publish(eventbus.NewProjectResultEvent(eventbus.PlanFinishedEvent, projectCtx, models.ProjectResult{Failure: "locked"}, 2*time.Second))
+	p.Publish(eventbus.NewProjectResultEvent(eventbus.ApplyFinishedEvent, projectCtx, models.ProjectResult{Error: errors.New("boom")}, 0))
+	p.Publish(eventbus.Event{Type: eventbus.LockReleasedEvent})
+
+	events := broker.waitForEvents(t, 4)
+	Equals(t, eventbus.PlanStartedEvent, events[0].Type)
+	Equals(t, "owner/repo", events[0].RepoFullName)
+	Equals(t, 1, events[0].PullNum)
+	Equals(t, "alice", events[0].User)
+	Equals(t, "prod", events[0].ProjectName)
+	Equals(t, projectCtx.ProjectID(), events[0].ProjectID)
+	Equals(t, "infra/prod", events[0].RepoRelDir)
+	Equals(t, eventbus.Result(""), events[0].Result)
+
+	Equals(t, eventbus.PlanFinishedEvent, events[1].Type)
+	Equals(t, eventbus.FailureResult, events[1].Result)
+	Equals(t, "locked", events[1].Error)
+	Equals(t, int64(2000), events[1].DurationMS)
+
+	Equals(t, eventbus.ErrorResult, events[2].Result)
+	Equals(t, "boom", events[2].Error)
+
+	Assert(t, !events[3].Time.IsZero(), "exp time to be set")
+}
+
+func TestPublisher_PublishNil(t *testing.T) {
+	var p *eventbus.Publisher
+	p.Publish(eventbus.Event{Type: eventbus.PlanStartedEvent})
+}
+
+// memLocker is a locking.Locker that always acquires locks and releases the
+// locks it's given.
+type memLocker struct {
+	locking.Locker
+	locks []models.ProjectLock
+}
+
+func (m *memLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+	lock := models.ProjectLock{Project: p, Workspace: workspace, Pull: pull, User: user}
+	return locking.TryLockResponse{LockAcquired: p.Path != "held", CurrLock: lock}, nil
+}
+
+func (m *memLocker) Unlock(key string) (*models.ProjectLock, error) {
+	if len(m.locks) == 0 {
+		return nil, nil
+	}
+	return &m.locks[0], nil
+}
+
+func (m *memLocker) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	return m.locks, nil
+}
+
+func TestLocker(t *testing.T) {
+	broker := &memBroker{}
+	p := eventbus.NewPublisher(broker, logging.NewNoopLogger())
+	go p.Start()
+	lock := models.ProjectLock{
+		Project:   models.Project{RepoFullName: "owner/repo", Path: "infra"},
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 2},
+		User:      models.User{Username: "bob"},
+	}
+	underlying := &memLocker{}
+	l := &eventbus.Locker{Locker: underlying, Publisher: p}
+
+	_, err := l.TryLock(lock.Project, lock.Workspace, lock.Pull, lock.User)
+	Ok(t, err)
+	// Locks that aren't acquired and unlocking nothing aren't published.
+	_, err = l.TryLock(models.Project{RepoFullName: "owner/repo", Path: "held"}, "default", lock.Pull, lock.User)
+	Ok(t, err)
+	_, err = l.Unlock("owner/repo/infra/default")
+	Ok(t, err)
+	underlying.locks = []models.ProjectLock{lock, lock}
+	_, err = l.UnlockByPull("owner/repo", 2)
+	Ok(t, err)
+
+	events := broker.waitForEvents(t, 3)
+	Equals(t, 3, len(events))
+	Equals(t, eventbus.LockAcquiredEvent, events[0].Type)
+	Equals(t, "owner/repo", events[0].RepoFullName)
+	Equals(t, "infra", events[0].RepoRelDir)
+	Equals(t, "default", events[0].Workspace)
+	Equals(t, 2, events[0].PullNum)
+	Equals(t, "bob", events[0].User)
+	Equals(t, eventbus.LockReleasedEvent, events[1].Type)
+	Equals(t, eventbus.LockReleasedEvent, events[2].Type)
+}
@@ -0,0 +1,44 @@
+// Package execution runs the commands of project steps, ex. terraform plan
+// and custom run steps, either on the Atlantis server or elsewhere.
+package execution
+
+import (
+	"os/exec"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// The execution backends that can be configured.
+const (
+	LocalBackendName         = "local"
+	KubernetesJobBackendName = "kubernetes-job"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_backend.go Backend
+
+// Backend executes commands.
+type Backend interface {
+	// CombinedOutput runs cmd and returns its combined stdout and stderr.
+	// cmd's Args, Dir and Env describe the command to run. Errors from
+	// commands that exit with a non-zero status start with "exit status".
+	// Commands that run for longer than their Timeout are stopped and a
+	// *TimeoutError is returned. Commands that are Cancelled are stopped and
+	// ErrCancelled is returned.
+	CombinedOutput(log *logging.SimpleLogger, cmd *exec.Cmd) ([]byte, error)
+}
+
+// LocalBackend runs commands as subprocesses of the Atlantis server.
+type LocalBackend struct{}
+
+// CombinedOutput runs cmd on the server.
+func (l *LocalBackend) CombinedOutput(log *logging.SimpleLogger, cmd *exec.Cmd) ([]byte, error) {
+	return localCombinedOutput(cmd)
+}
+
+// CombinedOutput runs cmd with backend, or on the server if backend is nil.
+func CombinedOutput(backend Backend, log *logging.SimpleLogger, cmd *exec.Cmd) ([]byte, error) {
+	if backend == nil {
+		return localCombinedOutput(cmd)
+	}
+	return backend.CombinedOutput(log, cmd)
+}
@@ -0,0 +1,59 @@
+package execution
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CancelEnvVar is set in the environment of commands that can be cancelled.
+// Its value is the key returned by RegisterCancel.
+const CancelEnvVar = "ATLANTIS_CANCEL_KEY"
+
+// ErrCancelled is returned for commands that were stopped because they were
+// cancelled, ex. because a newer commit was pushed to the pull request they
+// were planning.
+var ErrCancelled = errors.New("cancelled")
+
+var cancels = struct {
+	sync.Mutex
+	next int
+	done map[string]<-chan struct{}
+}{done: make(map[string]<-chan struct{})}
+
+// RegisterCancel returns a key that commands are cancelled with once done is
+// closed. It's set in their environment as CancelEnvVar since commands can
+// only carry strings to backends. The returned function unregisters the key
+// and must be called once the commands have finished.
+func RegisterCancel(done <-chan struct{}) (string, func()) {
+	cancels.Lock()
+	defer cancels.Unlock()
+	cancels.next++
+	key := strconv.Itoa(cancels.next)
+	cancels.done[key] = done
+	return key, func() {
+		cancels.Lock()
+		defer cancels.Unlock()
+		delete(cancels.done, key)
+	}
+}
+
+// Cancelled returns a channel that's closed once cmd is cancelled, or nil if
+// it can't be cancelled.
+func Cancelled(cmd *exec.Cmd) <-chan struct{} {
+	var key string
+	for _, kv := range cmd.Env {
+		if strings.HasPrefix(kv, CancelEnvVar+"=") {
+			// Later values override earlier ones like they do for exec.
+			key = strings.TrimPrefix(kv, CancelEnvVar+"=")
+		}
+	}
+	if key == "" {
+		return nil
+	}
+	cancels.Lock()
+	defer cancels.Unlock()
+	return cancels.done[key]
+}
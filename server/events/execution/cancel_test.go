@@ -0,0 +1,49 @@
+package execution_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCancelled(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "true")
+	Assert(t, execution.Cancelled(cmd) == nil, "exp commands without a key to not be cancellable")
+
+	done := make(chan struct{})
+	key, unregister := execution.RegisterCancel(done)
+	cmd.Env = []string{execution.CancelEnvVar + "=" + key}
+	Assert(t, execution.Cancelled(cmd) != nil, "exp command to be cancellable")
+
+	unregister()
+	Assert(t, execution.Cancelled(cmd) == nil, "exp unregistered keys to not be cancellable")
+}
+
+func TestLocalBackend_CombinedOutputCancelled(t *testing.T) {
+	done := make(chan struct{})
+	key, unregister := execution.RegisterCancel(done)
+	defer unregister()
+	cmd := exec.Command("sh", "-c", "echo started; sleep 10")
+	cmd.Env = []string{execution.CancelEnvVar + "=" + key}
+	time.AfterFunc(100*time.Millisecond, func() { close(done) })
+
+	start := time.Now()
+	out, err := execution.CombinedOutput(&execution.LocalBackend{}, logging.NewNoopLogger(), cmd)
+	Equals(t, execution.ErrCancelled, err)
+	Equals(t, "started\n", string(out))
+	Assert(t, time.Since(start) < 5*time.Second, "exp command to be stopped")
+}
+
+func TestLocalBackend_CombinedOutputNotCancelled(t *testing.T) {
+	key, unregister := execution.RegisterCancel(make(chan struct{}))
+	defer unregister()
+	cmd := exec.Command("sh", "-c", "echo hi")
+	cmd.Env = []string{execution.CancelEnvVar + "=" + key}
+	out, err := execution.CombinedOutput(nil, logging.NewNoopLogger(), cmd)
+	Ok(t, err)
+	Equals(t, "hi\n", string(out))
+}
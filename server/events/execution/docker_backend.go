@@ -0,0 +1,125 @@
+package execution
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// ContainerImageEnvVar is set in the environment of commands whose workflow
+// sets an image. Backends that run commands in containers use it as the
+// image to run.
+const ContainerImageEnvVar = "ATLANTIS_CONTAINER_IMAGE"
+
+// containerEnvExcludes are the variables from the command's environment that
+// aren't set in containers since they describe the server, not the image.
+var containerEnvExcludes = map[string]bool{
+	"HOME":     true,
+	"HOSTNAME": true,
+	"PATH":     true,
+}
+
+// ContainerImage returns the image cmd should run in, or an empty string if
+// its workflow didn't set one.
+func ContainerImage(cmd *exec.Cmd) string {
+	var image string
+	for _, kv := range cmd.Env {
+		if strings.HasPrefix(kv, ContainerImageEnvVar+"=") {
+			// Later values override earlier ones like they do for exec.
+			image = strings.TrimPrefix(kv, ContainerImageEnvVar+"=")
+		}
+	}
+	return image
+}
+
+// DockerBackend runs commands whose workflow sets an image in a Docker
+// container of that image and all other commands with Backend. The data dir
+// is mounted in containers at the same path so commands see the repos
+// Atlantis cloned and the Terraform versions it downloaded at the same paths
+// as on the server. Containers run as the server's user and get the
+// command's environment, except for PATH, HOME and HOSTNAME.
+type DockerBackend struct {
+	// Backend runs commands that don't set an image. If nil, they're run on
+	// the server.
+	Backend Backend
+	// DockerPath is the path to the docker binary. Defaults to docker in the
+	// PATH.
+	DockerPath string
+	// DataDir is the Atlantis data dir.
+	DataDir string
+	// TerraformPath, if set, is the path to the Terraform binary in the
+	// server's PATH. It's mounted in containers at the same path since
+	// it isn't in the data dir.
+	TerraformPath string
+}
+
+// LocalTerraformPath returns the path to the Terraform binary in the server's
+// PATH, or an empty string if there isn't one.
+func LocalTerraformPath() string {
+	path, err := exec.LookPath("terraform")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// CombinedOutput runs cmd in a container if its workflow sets an image.
+func (d *DockerBackend) CombinedOutput(log *logging.SimpleLogger, cmd *exec.Cmd) ([]byte, error) {
+	image := ContainerImage(cmd)
+	if image == "" {
+		return CombinedOutput(d.Backend, log, cmd)
+	}
+	log.Debug("running %q in a %s container", strings.Join(cmd.Args, " "), image)
+	// Stopping the docker client stops the container too since it proxies
+	// signals to it and the container is removed once it exits.
+	return localCombinedOutput(d.dockerCmd(image, cmd))
+}
+
+// dockerCmd returns the docker run command that runs cmd in image.
+func (d *DockerBackend) dockerCmd(image string, cmd *exec.Cmd) *exec.Cmd {
+	args := []string{
+		"run",
+		"--rm",
+		// Run as the same user as the server so files written to the data
+		// dir can be read by both.
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--volume", fmt.Sprintf("%s:%s", d.DataDir, d.DataDir),
+	}
+	if d.TerraformPath != "" {
+		args = append(args, "--volume", fmt.Sprintf("%s:%s:ro", d.TerraformPath, d.TerraformPath))
+	}
+	if cmd.Dir != "" {
+		args = append(args, "--workdir", cmd.Dir)
+	}
+	// We only pass the names of the variables so docker reads their values
+	// from its own environment and they aren't visible in the process list.
+	names := make(map[string]bool)
+	for _, kv := range cmd.Env {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if !containerEnvExcludes[name] {
+			names[name] = true
+		}
+	}
+	var sortedNames []string
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	for _, name := range sortedNames {
+		args = append(args, "--env", name)
+	}
+	args = append(args, image)
+	args = append(args, cmd.Args...)
+
+	dockerPath := d.DockerPath
+	if dockerPath == "" {
+		dockerPath = "docker"
+	}
+	docker := exec.Command(dockerPath, args...) // #nosec
+	docker.Env = cmd.Env
+	return docker
+}
@@ -0,0 +1,69 @@
+package execution_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeDocker writes a fake docker binary that prints its args and the value
+// of SECRET from its environment.
+func fakeDocker(t *testing.T, dir string) string {
+	docker := filepath.Join(dir, "docker")
+	Ok(t, ioutil.WriteFile(docker, []byte("#!/bin/sh\necho \"$@\"\necho \"SECRET=$SECRET\"\n"), 0700)) // nolint: gosec
+	return docker
+}
+
+func TestDockerBackend_CombinedOutput(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	backend := &execution.DockerBackend{
+		DockerPath:    fakeDocker(t, tmp),
+		DataDir:       "/atlantis-data",
+		TerraformPath: "/usr/local/bin/terraform",
+	}
+
+	cmd := exec.Command("sh", "-c", "terragrunt plan")
+	cmd.Dir = "/atlantis-data/repos/owner/repo/1/default"
+	cmd.Env = []string{
+		"PATH=/usr/bin",
+		"SECRET=hunter2",
+		"WORKSPACE=default",
+		execution.ContainerImageEnvVar + "=alpine/terragrunt",
+	}
+	out, err := backend.CombinedOutput(logging.NewNoopLogger(), cmd)
+	Ok(t, err)
+	// Values aren't in the args, docker reads them from its environment.
+	Equals(t, fmt.Sprintf("run --rm --user %d:%d --volume /atlantis-data:/atlantis-data"+
+		" --volume /usr/local/bin/terraform:/usr/local/bin/terraform:ro"+
+		" --workdir /atlantis-data/repos/owner/repo/1/default"+
+		" --env ATLANTIS_CONTAINER_IMAGE --env SECRET --env WORKSPACE"+
+		" alpine/terragrunt sh -c terragrunt plan\nSECRET=hunter2\n", os.Getuid(), os.Getgid()), string(out))
+}
+
+func TestDockerBackend_CombinedOutputNoImage(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	backend := &execution.DockerBackend{
+		DockerPath: fakeDocker(t, tmp),
+		DataDir:    "/atlantis-data",
+	}
+
+	out, err := backend.CombinedOutput(logging.NewNoopLogger(), exec.Command("sh", "-c", "echo hi"))
+	Ok(t, err)
+	Equals(t, "hi\n", string(out))
+}
+
+func TestContainerImage(t *testing.T) {
+	cmd := exec.Command("sh")
+	Equals(t, "", execution.ContainerImage(cmd))
+	cmd.Env = []string{"ATLANTIS_CONTAINER_IMAGE=a", "ATLANTIS_CONTAINER_IMAGE_X=c", "ATLANTIS_CONTAINER_IMAGE=b"}
+	Equals(t, "b", execution.ContainerImage(cmd))
+}
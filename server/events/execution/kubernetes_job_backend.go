@@ -0,0 +1,436 @@
+package execution
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// serviceAccountDir is where Kubernetes mounts the credentials of the pod's
+// service account.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+const (
+	defaultJobPollInterval = 2 * time.Second
+	defaultJobTimeout      = 2 * time.Hour
+	// jobTTLSeconds is how long finished jobs are kept if we fail to delete
+	// them.
+	jobTTLSeconds = 3600
+	// dataVolumeName is the name of the volume the data dir is mounted from.
+	dataVolumeName = "atlantis-data"
+)
+
+// KubernetesJobBackend runs each command as a Kubernetes Job so commands,
+// including custom run steps, are isolated from the Atlantis server and can
+// be scheduled on other nodes. The Atlantis data dir must be on a volume that
+// can be mounted by the server and jobs at the same time, ex. a
+// ReadWriteMany PersistentVolumeClaim, since jobs run in the repos Atlantis
+// cloned into it. Jobs don't inherit the server's environment variables,
+// only the ones that are set for the command, so credentials should come
+// from the jobs' service account.
+type KubernetesJobBackend struct {
+	HTTPClient *http.Client
+	// BaseURL is the URL of the Kubernetes API server.
+	BaseURL string
+	// Token is the bearer token used to authenticate to the API server.
+	Token     string
+	Namespace string
+	// Image is the container image jobs run. It must have sh and any tools
+	// used by custom run steps.
+	Image string
+	// ServiceAccount is the service account jobs run as. If empty, the
+	// namespace's default service account is used.
+	ServiceAccount string
+	// CPU and Memory are the resources requested by and limited to for each
+	// job, ex. 500m and 1Gi. If empty, they're not set.
+	CPU    string
+	Memory string
+	// DataDir is the Atlantis data dir. It's mounted in jobs at the same path
+	// from the PersistentVolumeClaim named DataVolumeClaim.
+	DataDir         string
+	DataVolumeClaim string
+	// PollInterval is how often jobs are checked while we wait for them.
+	// Defaults to 2s.
+	PollInterval time.Duration
+	// Timeout is how long we wait for a job before giving up. Defaults to 2h.
+	Timeout time.Duration
+}
+
+// NewInClusterKubernetesJobBackend returns a backend that authenticates to
+// the Kubernetes API server with the service account of the pod Atlantis is
+// running in. If namespace is empty, jobs are created in the pod's namespace.
+func NewInClusterKubernetesJobBackend(namespace string) (*KubernetesJobBackend, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading service account token")
+	}
+	caCert, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading service account CA certificate")
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("parsing service account CA certificate")
+	}
+	if namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, errors.Wrap(err, "reading service account namespace")
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+	return &KubernetesJobBackend{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12},
+			},
+		},
+		BaseURL:   "https://" + net.JoinHostPort(host, port),
+		Token:     strings.TrimSpace(string(token)),
+		Namespace: namespace,
+	}, nil
+}
+
+// CombinedOutput runs cmd as a job and returns its logs once it finishes.
+// The logs are also written to the server's log as they're streamed.
+func (k *KubernetesJobBackend) CombinedOutput(log *logging.SimpleLogger, cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	deadline := start.Add(k.timeout())
+	stepTimeout := Timeout(cmd)
+	if stepTimeout > 0 && stepTimeout+KillGracePeriod < k.timeout() {
+		// Kubernetes stops the job once it's run for stepTimeout so we only
+		// wait a little longer for it to be stopped.
+		deadline = start.Add(stepTimeout + KillGracePeriod)
+	}
+	cancelled := Cancelled(cmd)
+	out, err := k.runJob(log, cmd, deadline, cancelled)
+	if err != nil && isClosed(cancelled) {
+		return out, ErrCancelled
+	}
+	if err != nil && stepTimeout > 0 && time.Since(start) >= stepTimeout {
+		return out, &TimeoutError{Timeout: stepTimeout}
+	}
+	return out, err
+}
+
+// isClosed returns true if c is closed.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// runJob runs cmd as a job and waits for it until deadline or until
+// cancelled is closed. The job is deleted either way, which stops it.
+func (k *KubernetesJobBackend) runJob(log *logging.SimpleLogger, cmd *exec.Cmd, deadline time.Time, cancelled <-chan struct{}) ([]byte, error) {
+	jobName, err := k.createJob(cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating job")
+	}
+	log.Info("running %q in job %s/%s", strings.Join(cmd.Args, " "), k.Namespace, jobName)
+	defer func() {
+		path := fmt.Sprintf("/apis/batch/v1/namespaces/%s/jobs/%s?propagationPolicy=Background", k.Namespace, jobName)
+		if err := k.makeRequest("DELETE", path, nil, nil); err != nil {
+			log.Warn("failed to delete job %s: %s", jobName, err)
+		}
+	}()
+
+	podName, err := k.waitForPod(jobName, deadline, cancelled, func(p pod) bool { return p.Status.Phase != "Pending" })
+	if err != nil {
+		return nil, err
+	}
+
+	// Following the logs returns once the container exits.
+	var out bytes.Buffer
+	logsPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log?follow=true", k.Namespace, podName)
+	logs, err := k.stream(logsPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "streaming logs of job %s", jobName)
+	}
+	streamed := make(chan struct{})
+	defer close(streamed)
+	go func() {
+		// Closing the logs stops following them.
+		select {
+		case <-cancelled:
+			logs.Close() // nolint: errcheck
+		case <-streamed:
+		}
+	}()
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		log.Debug("[job %s] %s", jobName, scanner.Text())
+		out.WriteString(scanner.Text() + "\n")
+	}
+	logs.Close() // nolint: errcheck
+	if err := scanner.Err(); err != nil {
+		return out.Bytes(), errors.Wrapf(err, "streaming logs of job %s", jobName)
+	}
+
+	var finished pod
+	_, err = k.waitForPod(jobName, deadline, cancelled, func(p pod) bool {
+		finished = p
+		return p.Status.Phase == "Succeeded" || p.Status.Phase == "Failed"
+	})
+	if err != nil {
+		return out.Bytes(), err
+	}
+	for _, status := range finished.Status.ContainerStatuses {
+		if status.State.Terminated != nil && status.State.Terminated.ExitCode != 0 {
+			return out.Bytes(), fmt.Errorf("exit status %d", status.State.Terminated.ExitCode)
+		}
+	}
+	if finished.Status.Phase == "Failed" {
+		return out.Bytes(), fmt.Errorf("job %s failed: %s", jobName, finished.Status.Message)
+	}
+	return out.Bytes(), nil
+}
+
+type pod struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Phase             string `json:"phase"`
+		Message           string `json:"message"`
+		ContainerStatuses []struct {
+			State struct {
+				Terminated *struct {
+					ExitCode int `json:"exitCode"`
+				} `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// createJob creates a job that runs cmd and returns its name.
+func (k *KubernetesJobBackend) createJob(cmd *exec.Cmd) (string, error) {
+	// Workflows can override the image their commands run in.
+	image := k.Image
+	if workflowImage := ContainerImage(cmd); workflowImage != "" {
+		image = workflowImage
+	}
+	container := map[string]interface{}{
+		"name":       "run",
+		"image":      image,
+		"command":    cmd.Args,
+		"workingDir": cmd.Dir,
+		"env":        jobEnv(cmd.Env),
+	}
+	resources := map[string]string{}
+	if k.CPU != "" {
+		resources["cpu"] = k.CPU
+	}
+	if k.Memory != "" {
+		resources["memory"] = k.Memory
+	}
+	if len(resources) > 0 {
+		container["resources"] = map[string]interface{}{"requests": resources, "limits": resources}
+	}
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+		// Run as the same user as the server so files written to the data
+		// dir can be read by both.
+		"securityContext": map[string]interface{}{
+			"runAsUser":  os.Getuid(),
+			"runAsGroup": os.Getgid(),
+		},
+	}
+	if k.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = k.ServiceAccount
+	}
+	if k.DataVolumeClaim != "" {
+		container["volumeMounts"] = []interface{}{
+			map[string]interface{}{"name": dataVolumeName, "mountPath": k.DataDir},
+		}
+		podSpec["volumes"] = []interface{}{
+			map[string]interface{}{
+				"name":                  dataVolumeName,
+				"persistentVolumeClaim": map[string]interface{}{"claimName": k.DataVolumeClaim},
+			},
+		}
+	}
+	labels := map[string]string{"app.kubernetes.io/managed-by": "atlantis"}
+	job := map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]interface{}{
+			"generateName": "atlantis-",
+			"namespace":    k.Namespace,
+			"labels":       labels,
+		},
+		"spec": map[string]interface{}{
+			"backoffLimit":            0,
+			"ttlSecondsAfterFinished": jobTTLSeconds,
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+	if timeout := Timeout(cmd); timeout > 0 {
+		jobSpec := job["spec"].(map[string]interface{})
+		jobSpec["activeDeadlineSeconds"] = int64(math.Ceil(timeout.Seconds()))
+	}
+	var created struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := k.makeRequest("POST", fmt.Sprintf("/apis/batch/v1/namespaces/%s/jobs", k.Namespace), job, &created); err != nil {
+		return "", err
+	}
+	return created.Metadata.Name, nil
+}
+
+// waitForPod polls the pod of the job named jobName until done returns true
+// and returns its name. It gives up once cancelled is closed.
+func (k *KubernetesJobBackend) waitForPod(jobName string, deadline time.Time, cancelled <-chan struct{}, done func(pod) bool) (string, error) {
+	query := url.Values{}
+	query.Set("labelSelector", "job-name="+jobName)
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods?%s", k.Namespace, query.Encode())
+	for time.Now().Before(deadline) {
+		var pods struct {
+			Items []pod `json:"items"`
+		}
+		if err := k.makeRequest("GET", path, nil, &pods); err != nil {
+			return "", errors.Wrapf(err, "getting pod of job %s", jobName)
+		}
+		if len(pods.Items) > 0 && done(pods.Items[0]) {
+			return pods.Items[0].Metadata.Name, nil
+		}
+		select {
+		case <-cancelled:
+			return "", ErrCancelled
+		case <-time.After(k.pollInterval()):
+		}
+	}
+	return "", fmt.Errorf("timed out after %s waiting for job %s", k.timeout(), jobName)
+}
+
+func (k *KubernetesJobBackend) makeRequest(method string, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "encoding request")
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	resp, err := k.do(method, path, reqBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if out == nil {
+		return nil
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading response from request %q", method+" "+path)
+	}
+	return errors.Wrapf(json.Unmarshal(respBody, out), "parsing response from request %q", method+" "+path)
+}
+
+// stream returns the body of a GET request to path, which must be closed.
+func (k *KubernetesJobBackend) stream(path string) (io.ReadCloser, error) {
+	resp, err := k.do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (k *KubernetesJobBackend) do(method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, k.BaseURL+path, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Authorization", "Bearer "+k.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := k.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close() // nolint: errcheck
+		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", method+" "+path, resp.StatusCode, string(respBody))
+	}
+	return resp, nil
+}
+
+func (k *KubernetesJobBackend) pollInterval() time.Duration {
+	if k.PollInterval == 0 {
+		return defaultJobPollInterval
+	}
+	return k.PollInterval
+}
+
+func (k *KubernetesJobBackend) timeout() time.Duration {
+	if k.Timeout == 0 {
+		return defaultJobTimeout
+	}
+	return k.Timeout
+}
+
+// jobEnv returns the env vars in env that aren't inherited from the server,
+// sorted by name. If a var is set more than once, the last value is used like
+// it is for local commands.
+func jobEnv(env []string) []interface{} {
+	serverEnv := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		serverEnv[kv] = true
+	}
+	vars := make(map[string]string)
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if serverEnv[kv] {
+			// A later duplicate may still override an earlier value.
+			delete(vars, parts[0])
+			continue
+		}
+		vars[parts[0]] = parts[1]
+	}
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var envVars []interface{}
+	for _, name := range names {
+		envVars = append(envVars, map[string]string{"name": name, "value": vars[name]})
+	}
+	return envVars
+}
@@ -0,0 +1,201 @@
+package execution_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeKubernetes is a fake of the parts of the Kubernetes API we use to run
+// jobs.
+type fakeKubernetes struct {
+	mu sync.Mutex
+	// phases are the phases the job's pod goes through each time it's
+	// fetched.
+	phases   []string
+	exitCode int
+	logs     string
+
+	job     map[string]interface{}
+	podGets int
+	deleted bool
+}
+
+func (f *fakeKubernetes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/apis/batch/v1/namespaces/atlantis/jobs":
+		json.NewDecoder(r.Body).Decode(&f.job) // nolint: errcheck
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"metadata": map[string]string{"name": "atlantis-abc"}}) // nolint: errcheck
+	case r.Method == "GET" && r.URL.Path == "/api/v1/namespaces/atlantis/pods":
+		if r.URL.Query().Get("labelSelector") != "job-name=atlantis-abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		phase := f.phases[len(f.phases)-1]
+		if f.podGets < len(f.phases) {
+			phase = f.phases[f.podGets]
+		}
+		f.podGets++
+		status := map[string]interface{}{"phase": phase}
+		if phase == "Succeeded" || phase == "Failed" {
+			status["containerStatuses"] = []interface{}{
+				map[string]interface{}{"state": map[string]interface{}{"terminated": map[string]interface{}{"exitCode": f.exitCode}}},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{ // nolint: errcheck
+			"items": []interface{}{
+				map[string]interface{}{"metadata": map[string]string{"name": "atlantis-abc-xyz"}, "status": status},
+			},
+		})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/namespaces/atlantis/pods/atlantis-abc-xyz/log":
+		fmt.Fprint(w, f.logs) // nolint: errcheck
+	case r.Method == "DELETE" && r.URL.Path == "/apis/batch/v1/namespaces/atlantis/jobs/atlantis-abc":
+		f.deleted = r.URL.Query().Get("propagationPolicy") == "Background"
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func setupKubernetes(t *testing.T, f *fakeKubernetes) (*execution.KubernetesJobBackend, func()) {
+	server := httptest.NewServer(f)
+	return &execution.KubernetesJobBackend{
+		HTTPClient:      server.Client(),
+		BaseURL:         server.URL,
+		Token:           "token",
+		Namespace:       "atlantis",
+		Image:           "runatlantis/atlantis",
+		ServiceAccount:  "atlantis-jobs",
+		CPU:             "500m",
+		Memory:          "1Gi",
+		DataDir:         "/atlantis-data",
+		DataVolumeClaim: "atlantis-data",
+		PollInterval:    1,
+	}, server.Close
+}
+
+func TestKubernetesJobBackend_CombinedOutput(t *testing.T) {
+	f := &fakeKubernetes{
+		phases: []string{"Pending", "Running", "Running", "Succeeded"},
+		logs:   "line1\nline2\n",
+	}
+	backend, cleanup := setupKubernetes(t, f)
+	defer cleanup()
+
+	cmd := exec.Command("sh", "-c", "terraform plan")
+	cmd.Dir = "/atlantis-data/repos/owner/repo/1/default"
+	cmd.Env = append(os.Environ(), "WORKSPACE=default", "DIR=/atlantis-data/repos/owner/repo/1/default", "WORKSPACE=staging")
+	out, err := backend.CombinedOutput(logging.NewNoopLogger(), cmd)
+	Ok(t, err)
+	Equals(t, "line1\nline2\n", string(out))
+	Assert(t, f.deleted, "expected job to be deleted")
+
+	spec := f.job["spec"].(map[string]interface{})
+	Equals(t, float64(0), spec["backoffLimit"])
+	podSpec := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	Equals(t, "Never", podSpec["restartPolicy"])
+	Equals(t, "atlantis-jobs", podSpec["serviceAccountName"])
+	Equals(t, []interface{}{
+		map[string]interface{}{"name": "atlantis-data", "persistentVolumeClaim": map[string]interface{}{"claimName": "atlantis-data"}},
+	}, podSpec["volumes"])
+	container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	Equals(t, "runatlantis/atlantis", container["image"])
+	Equals(t, []interface{}{"sh", "-c", "terraform plan"}, container["command"])
+	Equals(t, "/atlantis-data/repos/owner/repo/1/default", container["workingDir"])
+	Equals(t, []interface{}{
+		map[string]interface{}{"name": "atlantis-data", "mountPath": "/atlantis-data"},
+	}, container["volumeMounts"])
+	resources := map[string]interface{}{"cpu": "500m", "memory": "1Gi"}
+	Equals(t, map[string]interface{}{"requests": resources, "limits": resources}, container["resources"])
+	// The server's env vars aren't passed to the job and the last value of
+	// duplicated vars wins.
+	Equals(t, []interface{}{
+		map[string]interface{}{"name": "DIR", "value": "/atlantis-data/repos/owner/repo/1/default"},
+		map[string]interface{}{"name": "WORKSPACE", "value": "staging"},
+	}, container["env"])
+}
+
+func TestKubernetesJobBackend_CombinedOutputWorkflowImage(t *testing.T) {
+	f := &fakeKubernetes{
+		phases: []string{"Succeeded"},
+	}
+	backend, cleanup := setupKubernetes(t, f)
+	defer cleanup()
+
+	cmd := exec.Command("sh", "-c", "terragrunt plan")
+	cmd.Env = []string{execution.ContainerImageEnvVar + "=alpine/terragrunt"}
+	_, err := backend.CombinedOutput(logging.NewNoopLogger(), cmd)
+	Ok(t, err)
+	podSpec := f.job["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	Equals(t, "alpine/terragrunt", container["image"])
+}
+
+func TestKubernetesJobBackend_CombinedOutputNonZeroExit(t *testing.T) {
+	f := &fakeKubernetes{
+		phases:   []string{"Running", "Failed"},
+		exitCode: 3,
+		logs:     "Error: oops\n",
+	}
+	backend, cleanup := setupKubernetes(t, f)
+	defer cleanup()
+
+	out, err := backend.CombinedOutput(logging.NewNoopLogger(), exec.Command("sh", "-c", "exit 3"))
+	ErrEquals(t, "exit status 3", err)
+	Equals(t, "Error: oops\n", string(out))
+	Assert(t, f.deleted, "expected job to be deleted")
+}
+
+func TestKubernetesJobBackend_CombinedOutputTimeout(t *testing.T) {
+	f := &fakeKubernetes{
+		phases: []string{"Pending"},
+	}
+	backend, cleanup := setupKubernetes(t, f)
+	defer cleanup()
+	backend.Timeout = 10 * backend.PollInterval
+
+	_, err := backend.CombinedOutput(logging.NewNoopLogger(), exec.Command("sh", "-c", "true"))
+	Assert(t, err != nil && strings.HasPrefix(err.Error(), "timed out after"), "expected timeout error, got %v", err)
+	Assert(t, f.deleted, "expected job to be deleted")
+}
+
+func TestKubernetesJobBackend_CombinedOutputStepTimeout(t *testing.T) {
+	f := &fakeKubernetes{
+		phases:   []string{"Running", "Failed"},
+		exitCode: 137,
+	}
+	backend, cleanup := setupKubernetes(t, f)
+	defer cleanup()
+
+	cmd := exec.Command("sh", "-c", "sleep 10")
+	cmd.Env = []string{execution.TimeoutEnvVar + "=1ns"}
+	_, err := backend.CombinedOutput(logging.NewNoopLogger(), cmd)
+	ErrEquals(t, "timed out after 1ns", err)
+	Equals(t, float64(1), f.job["spec"].(map[string]interface{})["activeDeadlineSeconds"])
+	Assert(t, f.deleted, "expected job to be deleted")
+}
+
+func TestLocalBackend_CombinedOutput(t *testing.T) {
+	out, err := execution.CombinedOutput(&execution.LocalBackend{}, logging.NewNoopLogger(), exec.Command("sh", "-c", "echo hi"))
+	Ok(t, err)
+	Equals(t, "hi\n", string(out))
+
+	_, err = execution.CombinedOutput(nil, logging.NewNoopLogger(), exec.Command("sh", "-c", "exit 2"))
+	ErrEquals(t, "exit status 2", err)
+}
@@ -0,0 +1,133 @@
+package execution
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// TimeoutEnvVar is set in the environment of commands that have a timeout,
+// ex. 30m. Backends stop commands that run for longer and return a
+// *TimeoutError.
+const TimeoutEnvVar = "ATLANTIS_STEP_TIMEOUT"
+
+// KillGracePeriod is how long commands that timed out have to exit after
+// they're interrupted before they're killed. Terraform uses it to stop
+// gracefully and release its state lock.
+var KillGracePeriod = 30 * time.Second
+
+// TimeoutError is returned for commands that ran for longer than their
+// timeout and were stopped.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (t *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", t.Timeout)
+}
+
+// Timeout returns how long cmd can run for, or 0 if it has no timeout.
+func Timeout(cmd *exec.Cmd) time.Duration {
+	var timeout time.Duration
+	for _, kv := range cmd.Env {
+		if strings.HasPrefix(kv, TimeoutEnvVar+"=") {
+			// Later values override earlier ones like they do for exec.
+			// Invalid values are ignored since they're validated when the
+			// config is parsed.
+			timeout, _ = time.ParseDuration(strings.TrimPrefix(kv, TimeoutEnvVar+"="))
+		}
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// PrepareStop gets cmd ready to be stopped by WatchStop if it has a timeout
+// or can be cancelled. It must be called before cmd is started. cmd is run in
+// its own process group so the commands it starts, ex. terraform from sh -c,
+// are stopped with it.
+func PrepareStop(cmd *exec.Cmd) {
+	if Timeout(cmd) == 0 && Cancelled(cmd) == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// WatchStop interrupts the process group of the started cmd once it's run
+// for longer than its timeout or it's cancelled, and kills it if it's still
+// running after KillGracePeriod. The returned function must be called once
+// cmd has exited. It returns a *TimeoutError or ErrCancelled if cmd was
+// stopped and nil otherwise.
+func WatchStop(cmd *exec.Cmd) func() error {
+	timeout := Timeout(cmd)
+	cancelled := Cancelled(cmd)
+	if (timeout == 0 && cancelled == nil) || cmd.Process == nil {
+		return func() error { return nil }
+	}
+	// Nil channels never fire so commands without a timeout are only
+	// stopped if they're cancelled and the other way around.
+	var timer *time.Timer
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer = time.NewTimer(timeout)
+		timedOut = timer.C
+	}
+	pgid := cmd.Process.Pid
+	exited := make(chan struct{})
+	stopped := make(chan error, 1)
+	go func() {
+		if timer != nil {
+			defer timer.Stop()
+		}
+		var err error
+		select {
+		case <-exited:
+			stopped <- nil
+			return
+		case <-timedOut:
+			err = &TimeoutError{Timeout: timeout}
+		case <-cancelled:
+			err = ErrCancelled
+		}
+		// A negative pid signals the whole process group.
+		syscall.Kill(-pgid, syscall.SIGINT) // nolint: errcheck
+		select {
+		case <-exited:
+		case <-time.After(KillGracePeriod):
+			syscall.Kill(-pgid, syscall.SIGKILL) // nolint: errcheck
+		}
+		stopped <- err
+	}()
+	return func() error {
+		close(exited)
+		return <-stopped
+	}
+}
+
+// localCombinedOutput runs cmd on the server like cmd.CombinedOutput but
+// stops it once it's run for longer than its timeout or it's cancelled.
+func localCombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if Timeout(cmd) == 0 && Cancelled(cmd) == nil {
+		return cmd.CombinedOutput()
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	PrepareStop(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stop := WatchStop(cmd)
+	err := cmd.Wait()
+	if stopErr := stop(); stopErr != nil {
+		return out.Bytes(), stopErr
+	}
+	return out.Bytes(), err
+}
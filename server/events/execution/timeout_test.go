@@ -0,0 +1,55 @@
+package execution_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTimeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "true")
+	Equals(t, time.Duration(0), execution.Timeout(cmd))
+
+	cmd.Env = []string{execution.TimeoutEnvVar + "=5m", execution.TimeoutEnvVar + "=30m"}
+	Equals(t, 30*time.Minute, execution.Timeout(cmd))
+
+	cmd.Env = []string{execution.TimeoutEnvVar + "=notaduration"}
+	Equals(t, time.Duration(0), execution.Timeout(cmd))
+}
+
+func TestLocalBackend_CombinedOutputTimeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo started; sleep 10")
+	cmd.Env = []string{execution.TimeoutEnvVar + "=100ms"}
+	start := time.Now()
+	out, err := execution.CombinedOutput(&execution.LocalBackend{}, logging.NewNoopLogger(), cmd)
+	ErrEquals(t, "timed out after 100ms", err)
+	_, ok := err.(*execution.TimeoutError)
+	Assert(t, ok, "exp a *TimeoutError, got %T", err)
+	Equals(t, "started\n", string(out))
+	Assert(t, time.Since(start) < 5*time.Second, "exp command to be stopped")
+}
+
+func TestLocalBackend_CombinedOutputTimeoutKill(t *testing.T) {
+	defer func(grace time.Duration) { execution.KillGracePeriod = grace }(execution.KillGracePeriod)
+	execution.KillGracePeriod = 100 * time.Millisecond
+
+	// Commands that ignore the interrupt are killed after the grace period.
+	cmd := exec.Command("sh", "-c", "trap '' INT; sleep 10")
+	cmd.Env = []string{execution.TimeoutEnvVar + "=100ms"}
+	start := time.Now()
+	_, err := execution.CombinedOutput(nil, logging.NewNoopLogger(), cmd)
+	ErrEquals(t, "timed out after 100ms", err)
+	Assert(t, time.Since(start) < 5*time.Second, "exp command to be killed")
+}
+
+func TestLocalBackend_CombinedOutputWithinTimeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo hi")
+	cmd.Env = []string{execution.TimeoutEnvVar + "=1m"}
+	out, err := execution.CombinedOutput(nil, logging.NewNoopLogger(), cmd)
+	Ok(t, err)
+	Equals(t, "hi\n", string(out))
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultMaxFailedEvents is how many failed webhook requests are kept before
+// the oldest are deleted.
+const DefaultMaxFailedEvents = 100
+
+// FailedEventStore stores the webhook requests that failed to be handled so
+// they can be inspected and replayed.
+type FailedEventStore interface {
+	// PutFailedEvent stores event and returns it with its ID set.
+	PutFailedEvent(event models.FailedEvent) (models.FailedEvent, error)
+	// GetFailedEvent returns the stored event with id or nil if there isn't
+	// one.
+	GetFailedEvent(id uint64) (*models.FailedEvent, error)
+	// DeleteFailedEvent deletes the stored event with id.
+	DeleteFailedEvent(id uint64) error
+	// ListFailedEvents returns the stored events, oldest first.
+	ListFailedEvents() ([]models.FailedEvent, error)
+}
+
+// FailedEvents is the dead-letter store of webhook requests that failed to
+// be handled, ex. because a secret or the repo allowlist was misconfigured.
+// Operators can replay them once the problem is fixed instead of having to
+// redeliver them from the VCS host.
+type FailedEvents struct {
+	Store FailedEventStore
+	// Max is how many events are kept. Once there are more, the oldest are
+	// deleted so a flood of bad requests can't fill the disk. 0 means there's
+	// no limit.
+	Max    int
+	Logger logging.SimpleLogging
+}
+
+// Record stores event and returns it with its ID set. It deletes the oldest
+// events beyond Max.
+func (f *FailedEvents) Record(event models.FailedEvent) (models.FailedEvent, error) {
+	stored, err := f.Store.PutFailedEvent(event)
+	if err != nil {
+		return stored, errors.Wrap(err, "storing failed event")
+	}
+	if f.Max <= 0 {
+		return stored, nil
+	}
+	all, err := f.Store.ListFailedEvents()
+	if err != nil {
+		return stored, errors.Wrap(err, "listing failed events")
+	}
+	for i := 0; i < len(all)-f.Max; i++ {
+		if err := f.Store.DeleteFailedEvent(all[i].ID); err != nil {
+			return stored, errors.Wrapf(err, "deleting failed event %d", all[i].ID)
+		}
+		f.Logger.Debug("deleted failed event %d since there are more than %d", all[i].ID, f.Max)
+	}
+	return stored, nil
+}
+
+// List returns the stored events, oldest first.
+func (f *FailedEvents) List() ([]models.FailedEvent, error) {
+	return f.Store.ListFailedEvents()
+}
+
+// Get returns the stored event with id or nil if there isn't one.
+func (f *FailedEvents) Get(id uint64) (*models.FailedEvent, error) {
+	return f.Store.GetFailedEvent(id)
+}
+
+// Replayed updates the stored event after it's been replayed. If the replay
+// succeeded the event is deleted, otherwise its status and response are
+// replaced with the replay's.
+func (f *FailedEvents) Replayed(event models.FailedEvent, status int, response string) error {
+	if status < 400 {
+		return f.Store.DeleteFailedEvent(event.ID)
+	}
+	event.Status = status
+	event.Response = response
+	event.Replays++
+	_, err := f.Store.PutFailedEvent(event)
+	return err
+}
@@ -0,0 +1,54 @@
+package events_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFailedEvents_RecordDeletesOldest(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	f := &events.FailedEvents{Store: boltDB, Max: 2, Logger: logging.NewNoopLogger()}
+	for i := 0; i < 3; i++ {
+		event, err := f.Record(models.FailedEvent{Status: http.StatusBadRequest})
+		Ok(t, err)
+		Equals(t, uint64(i+1), event.ID)
+	}
+	stored, err := f.List()
+	Ok(t, err)
+	Equals(t, 2, len(stored))
+	Equals(t, uint64(2), stored[0].ID)
+	Equals(t, uint64(3), stored[1].ID)
+}
+
+func TestFailedEvents_Replayed(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	f := &events.FailedEvents{Store: boltDB, Logger: logging.NewNoopLogger()}
+	event, err := f.Record(models.FailedEvent{Status: http.StatusBadRequest, Response: "bad signature"})
+	Ok(t, err)
+
+	// A failed replay keeps the event with the new response.
+	Ok(t, f.Replayed(event, http.StatusServiceUnavailable, "queue full"))
+	stored, err := f.Get(event.ID)
+	Ok(t, err)
+	Equals(t, http.StatusServiceUnavailable, stored.Status)
+	Equals(t, "queue full", stored.Response)
+	Equals(t, 1, stored.Replays)
+
+	// A successful replay deletes it.
+	Ok(t, f.Replayed(*stored, http.StatusOK, "Processing..."))
+	stored, err = f.Get(event.ID)
+	Ok(t, err)
+	Assert(t, stored == nil, "exp event to be deleted")
+}
@@ -0,0 +1,215 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// FailureClass is the likely cause of a command's error.
+type FailureClass string
+
+const (
+	// ConfigFailure is an error in the repo's Atlantis config, ex. an
+	// invalid atlantis.yaml or a project name that doesn't exist.
+	ConfigFailure FailureClass = "config"
+	// TerraformFailure is an error from Terraform or a custom step, ex. an
+	// invalid resource or a failed apply. It's the class of errors that
+	// don't match any other class.
+	TerraformFailure FailureClass = "terraform"
+	// ProviderAuthFailure is a Terraform provider that couldn't authenticate
+	// with its cloud, ex. expired AWS credentials.
+	ProviderAuthFailure FailureClass = "provider_auth"
+	// InfraFailure is an error talking to the VCS host or running git, or
+	// a problem with the Atlantis server itself, ex. a full disk.
+	InfraFailure FailureClass = "infra"
+)
+
+// failureClassTitles are the names of the classes in reports.
+var failureClassTitles = map[FailureClass]string{
+	ConfigFailure:       "Atlantis config errors",
+	TerraformFailure:    "Terraform errors",
+	ProviderAuthFailure: "Provider authentication errors",
+	InfraFailure:        "Infrastructure and VCS errors",
+}
+
+// failureClassPatterns match the errors of each class. They're checked in
+// order since errors usually include Terraform's output, ex. a provider auth
+// error is also a Terraform error.
+var failureClassPatterns = []struct {
+	class   FailureClass
+	pattern *regexp.Regexp
+}{
+	{ProviderAuthFailure, regexp.MustCompile(`(?i)no valid credential sources|NoCredentialProviders|InvalidClientTokenId|ExpiredToken|SignatureDoesNotMatch|UnrecognizedClientException|AccessDenied|UnauthorizedOperation|could not find default credentials|oauth2: cannot fetch token|AADSTS\d+|Unable to locate credentials|error configuring Terraform \S+ Provider`)},
+	{InfraFailure, regexp.MustCompile(`(?i)running git |acquiring lock|getting modified files|has not been cloned|no space left on device|too many open files|connection refused|connection reset|i/o timeout|dial tcp|TLS handshake|context deadline exceeded|Downloading terraform Version|\b(GET|POST|PUT|PATCH|DELETE) https?://\S+: 5\d\d`)},
+	{ConfigFailure, regexp.MustCompile(`(?i)atlantis\.yaml|workspace_var_file is set|does not support workspaces|isn't configured on this server|not allowed by the server-side repo config`)},
+}
+
+// ClassifyFailure returns the likely class of err.
+func ClassifyFailure(err error) FailureClass {
+	msg := err.Error()
+	for _, p := range failureClassPatterns {
+		if p.pattern.MatchString(msg) {
+			return p.class
+		}
+	}
+	return TerraformFailure
+}
+
+// FailureTracker classifies the errors of commands and counts them by class
+// so platform teams know which kind of failure to fix first. Failures that
+// are expected, ex. an apply of a pull request that wasn't approved, aren't
+// counted.
+type FailureTracker struct {
+	// Failures, if set, counts the errors by repo, command and class.
+	Failures *metrics.Counter
+	// SlackClient and SlackChannel, if set, are where ReportEvery posts
+	// its summaries.
+	SlackClient  webhooks.SlackClient
+	SlackChannel string
+	Now          func() time.Time
+
+	mu      sync.Mutex
+	since   time.Time
+	byClass map[FailureClass]int
+	byRepo  map[string]int
+}
+
+// FailureReport is a summary of the failures in a period.
+type FailureReport struct {
+	Since   time.Time
+	Until   time.Time
+	ByClass map[FailureClass]int
+	ByRepo  map[string]int
+}
+
+// Record counts the errors of res, the result of running cmdName on repo.
+func (f *FailureTracker) Record(repo models.Repo, cmdName models.CommandName, res CommandResult) {
+	if f == nil {
+		return
+	}
+	var errs []error
+	if res.Error != nil {
+		errs = append(errs, res.Error)
+	}
+	for _, p := range res.ProjectResults {
+		if p.Error != nil {
+			errs = append(errs, p.Error)
+		}
+	}
+	if len(errs) == 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	for _, err := range errs {
+		class := ClassifyFailure(err)
+		f.byClass[class]++
+		f.byRepo[repo.FullName]++
+		if f.Failures != nil {
+			f.Failures.Inc(repo.FullName, cmdName.String(), string(class))
+		}
+	}
+}
+
+// Report returns the failures since the last report and starts a new
+// period.
+func (f *FailureTracker) Report() FailureReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	report := FailureReport{
+		Since:   f.since,
+		Until:   f.now(),
+		ByClass: f.byClass,
+		ByRepo:  f.byRepo,
+	}
+	f.since = report.Until
+	f.byClass = make(map[FailureClass]int)
+	f.byRepo = make(map[string]int)
+	return report
+}
+
+// ReportEvery posts a report to SlackChannel every interval. It never
+// returns. Counts aren't persisted so the first report after a restart only
+// covers the time since the restart.
+func (f *FailureTracker) ReportEvery(log *logging.SimpleLogger, interval time.Duration) {
+	f.mu.Lock()
+	f.init()
+	f.mu.Unlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		report := f.Report()
+		if err := f.SlackClient.PostText(f.SlackChannel, report.String()); err != nil {
+			log.Err("unable to post failure report to slack channel %q: %s", f.SlackChannel, err)
+		}
+	}
+}
+
+// String renders the report in Slack's markdown.
+func (r FailureReport) String() string {
+	var b bytes.Buffer
+	total := 0
+	for _, n := range r.ByClass {
+		total += n
+	}
+	dateFmt := "Jan 2 15:04 MST"
+	fmt.Fprintf(&b, "*Atlantis failures from %s to %s: %d*\n", r.Since.Format(dateFmt), r.Until.Format(dateFmt), total)
+	if total == 0 {
+		return b.String()
+	}
+
+	for _, class := range []FailureClass{TerraformFailure, ProviderAuthFailure, ConfigFailure, InfraFailure} {
+		if n := r.ByClass[class]; n > 0 {
+			fmt.Fprintf(&b, "• %s: %d\n", failureClassTitles[class], n)
+		}
+	}
+
+	var repos []string
+	for repo := range r.ByRepo {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if r.ByRepo[repos[i]] != r.ByRepo[repos[j]] {
+			return r.ByRepo[repos[i]] > r.ByRepo[repos[j]]
+		}
+		return repos[i] < repos[j]
+	})
+	const maxRepos = 5
+	if len(repos) > maxRepos {
+		repos = repos[:maxRepos]
+	}
+	b.WriteString("\n*Repos with the most failures:*\n")
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "• %s: %d\n", repo, r.ByRepo[repo])
+	}
+	return b.String()
+}
+
+// init starts the first period. f.mu must be held.
+func (f *FailureTracker) init() {
+	if f.byClass != nil {
+		return
+	}
+	f.since = f.now()
+	f.byClass = make(map[FailureClass]int)
+	f.byRepo = make(map[string]int)
+}
+
+func (f *FailureTracker) now() time.Time {
+	if f.Now == nil {
+		return time.Now()
+	}
+	return f.Now()
+}
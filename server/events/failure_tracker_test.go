@@ -0,0 +1,144 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		err string
+		exp events.FailureClass
+	}{
+		{
+			"running \"terraform plan\" in \"/data/repos/owner/repo/1/default\": exit status 1\nError: Invalid reference",
+			events.TerraformFailure,
+		},
+		{
+			"exit status 1\nError: error configuring Terraform AWS Provider: no valid credential sources for Terraform AWS Provider found.",
+			events.ProviderAuthFailure,
+		},
+		{
+			"exit status 1\nError: Error refreshing state: ExpiredToken: The security token included in the request is expired",
+			events.ProviderAuthFailure,
+		},
+		{
+			"exit status 1\nError: google: could not find default credentials.",
+			events.ProviderAuthFailure,
+		},
+		{
+			"parsing atlantis.yaml: version: is required",
+			events.ConfigFailure,
+		},
+		{
+			"no project with name \"prod\" is defined in atlantis.yaml",
+			events.ConfigFailure,
+		},
+		{
+			"running git clone --no-single-branch https://github.com/owner/repo.git /data/repos/owner/repo/1/default: fatal: unable to access",
+			events.InfraFailure,
+		},
+		{
+			"getting modified files: GET https://api.github.com/repos/owner/repo/pulls/1/files: 502 Bad Gateway",
+			events.InfraFailure,
+		},
+		{
+			"write /data/repos/owner/repo/1/default/.terraform/plugins: no space left on device",
+			events.InfraFailure,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.err, func(t *testing.T) {
+			Equals(t, c.exp, events.ClassifyFailure(errors.New(c.err)))
+		})
+	}
+}
+
+func TestFailureTracker_Record(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	registry := metrics.NewRegistry()
+	tracker := &events.FailureTracker{
+		Failures: registry.NewCounter("atlantis_command_failures", "", "repo", "command", "class"),
+		Now:      func() time.Time { return now },
+	}
+	repo := models.Repo{FullName: "owner/repo"}
+	other := models.Repo{FullName: "owner/other"}
+
+	tracker.Record(repo, models.PlanCommand, events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{Error: errors.New("exit status 1\nError: Invalid reference")},
+			{Error: errors.New("exit status 1\nExpiredToken: The security token included in the request is expired")},
+			// Expected failures aren't counted.
+			{Failure: "This project is currently locked by an unapplied plan from pull #2."},
+			{PlanSuccess: &models.PlanSuccess{}},
+		},
+	})
+	tracker.Record(other, models.ApplyCommand, events.CommandResult{Error: errors.New("parsing atlantis.yaml: version: is required")})
+	tracker.Record(other, models.PlanCommand, events.CommandResult{Failure: "Atlantis commands can't be run on fork pull requests."})
+
+	families, err := registry.Gather()
+	Ok(t, err)
+	Equals(t, []metrics.Sample{
+		{Labels: []string{"owner/other", "apply", "config"}, Value: 1},
+		{Labels: []string{"owner/repo", "plan", "provider_auth"}, Value: 1},
+		{Labels: []string{"owner/repo", "plan", "terraform"}, Value: 1},
+	}, families[0].Samples)
+
+	now = now.Add(7 * 24 * time.Hour)
+	report := tracker.Report()
+	Equals(t, events.FailureReport{
+		Since: time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+		Until: now,
+		ByClass: map[events.FailureClass]int{
+			events.TerraformFailure:    1,
+			events.ProviderAuthFailure: 1,
+			events.ConfigFailure:       1,
+		},
+		ByRepo: map[string]int{
+			"owner/repo":  2,
+			"owner/other": 1,
+		},
+	}, report)
+
+	// The next report starts where the last one ended.
+	Equals(t, events.FailureReport{
+		Since:   now,
+		Until:   now,
+		ByClass: map[events.FailureClass]int{},
+		ByRepo:  map[string]int{},
+	}, tracker.Report())
+}
+
+func TestFailureReport_String(t *testing.T) {
+	report := events.FailureReport{
+		Since: time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+		Until: time.Date(2020, 6, 8, 9, 0, 0, 0, time.UTC),
+		ByClass: map[events.FailureClass]int{
+			events.TerraformFailure: 4,
+			events.InfraFailure:     1,
+		},
+		ByRepo: map[string]int{
+			"owner/b": 2,
+			"owner/a": 2,
+			"owner/c": 1,
+		},
+	}
+	Equals(t, `*Atlantis failures from Jun 1 09:00 UTC to Jun 8 09:00 UTC: 5*
+• Terraform errors: 4
+• Infrastructure and VCS errors: 1
+
+*Repos with the most failures:*
+• owner/a: 2
+• owner/b: 2
+• owner/c: 1
+`, report.String())
+
+	report.ByClass = map[events.FailureClass]int{}
+	Equals(t, "*Atlantis failures from Jun 1 09:00 UTC to Jun 8 09:00 UTC: 0*\n", report.String())
+}
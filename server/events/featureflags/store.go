@@ -0,0 +1,249 @@
+// Package featureflags enables risky new behaviors for a subset of repos so
+// they can be rolled out gradually. Flags are read from a file and can be
+// changed via the API while the server is running.
+package featureflags
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The sources a flag can be configured from. Flags set via the API override
+// flags with the same name in the file.
+const (
+	FileSource = "file"
+	APISource  = "api"
+)
+
+// nameRegex matches valid flag names.
+var nameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// DB stores the flags set via the API.
+type DB interface {
+	PutFeatureFlag(flag models.FeatureFlag) error
+	ListFeatureFlags() ([]models.FeatureFlag, error)
+	DeleteFeatureFlag(name string) (bool, error)
+}
+
+// Status is a flag and where it was set.
+type Status struct {
+	models.FeatureFlag
+	Source string
+}
+
+// Store holds the feature flags. A nil *Store has every flag disabled so
+// callers don't need to check whether flags are configured.
+type Store struct {
+	db DB
+
+	mu   sync.RWMutex
+	file map[string]flag
+	api  map[string]flag
+}
+
+// flag is a FeatureFlag with its repo regexes compiled.
+type flag struct {
+	models.FeatureFlag
+	ids     []string
+	regexes []*regexp.Regexp
+}
+
+// rawFile is the format of the flags file.
+type rawFile struct {
+	Flags map[string]rawFlag `yaml:"flags"`
+}
+
+type rawFlag struct {
+	Enabled    bool     `yaml:"enabled"`
+	Repos      []string `yaml:"repos"`
+	Percentage int      `yaml:"percentage"`
+}
+
+// NewStore returns a store with the flags in the file at path, if path isn't
+// empty, and the flags previously set via the API and saved in db.
+func NewStore(path string, db DB) (*Store, error) {
+	s := &Store{
+		db:   db,
+		file: make(map[string]flag),
+		api:  make(map[string]flag),
+	}
+	if path != "" {
+		contents, err := ioutil.ReadFile(path) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading feature flags file %s", path)
+		}
+		flags, err := ParseFile(contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing feature flags file %s", path)
+		}
+		for _, f := range flags {
+			// Already validated by ParseFile.
+			s.file[f.Name], _ = compile(f)
+		}
+	}
+	stored, err := db.ListFeatureFlags()
+	if err != nil {
+		return nil, errors.Wrap(err, "loading feature flags")
+	}
+	for _, f := range stored {
+		compiled, err := compile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading feature flag %q", f.Name)
+		}
+		s.api[f.Name] = compiled
+	}
+	return s, nil
+}
+
+// ParseFile parses the contents of a flags file, which maps each flag's name
+// to its settings under the flags key.
+func ParseFile(contents []byte) ([]models.FeatureFlag, error) {
+	var raw rawFile
+	if err := yaml.UnmarshalStrict(contents, &raw); err != nil {
+		return nil, err
+	}
+	var flags []models.FeatureFlag
+	for name, r := range raw.Flags {
+		f := models.FeatureFlag{
+			Name:       name,
+			Enabled:    r.Enabled,
+			Repos:      r.Repos,
+			Percentage: r.Percentage,
+		}
+		if err := Validate(f); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// Validate returns an error if f isn't a valid flag.
+func Validate(f models.FeatureFlag) error {
+	_, err := compile(f)
+	return err
+}
+
+func compile(f models.FeatureFlag) (flag, error) {
+	c := flag{FeatureFlag: f}
+	if !nameRegex.MatchString(f.Name) {
+		return c, fmt.Errorf("flag name %q must start with a lowercase letter or number and only contain lowercase letters, numbers, '-' and '_'", f.Name)
+	}
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return c, fmt.Errorf("flag %q: percentage must be between 0 and 100, got %d", f.Name, f.Percentage)
+	}
+	for _, id := range f.Repos {
+		if len(id) > 1 && strings.HasPrefix(id, "/") && strings.HasSuffix(id, "/") {
+			regex, err := regexp.Compile(id[1 : len(id)-1])
+			if err != nil {
+				return c, errors.Wrapf(err, "flag %q: parsing repo %s", f.Name, id)
+			}
+			c.regexes = append(c.regexes, regex)
+			continue
+		}
+		c.ids = append(c.ids, id)
+	}
+	return c, nil
+}
+
+// Enabled returns true if the flag name is enabled for the repo with ID
+// repoID, ex. github.com/owner/repo. Unknown flags are disabled.
+func (s *Store) Enabled(name string, repoID string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	f, ok := s.api[name]
+	if !ok {
+		f, ok = s.file[name]
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return f.enabledFor(repoID)
+}
+
+func (f flag) enabledFor(repoID string) bool {
+	if f.Enabled {
+		return true
+	}
+	for _, id := range f.ids {
+		if id == repoID {
+			return true
+		}
+	}
+	for _, regex := range f.regexes {
+		if regex.MatchString(repoID) {
+			return true
+		}
+	}
+	return f.Percentage > 0 && bucket(f.Name, repoID) < f.Percentage
+}
+
+// bucket deterministically assigns repoID a number from 0 to 99 for the flag
+// name so raising a flag's percentage only ever adds repos.
+func bucket(name string, repoID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + repoID)) // nolint: errcheck
+	return int(h.Sum32() % 100)
+}
+
+// List returns the flags that are in effect, sorted by name.
+func (s *Store) List() []Status {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var statuses []Status
+	for name, f := range s.file {
+		if _, ok := s.api[name]; !ok {
+			statuses = append(statuses, Status{FeatureFlag: f.FeatureFlag, Source: FileSource})
+		}
+	}
+	for _, f := range s.api {
+		statuses = append(statuses, Status{FeatureFlag: f.FeatureFlag, Source: APISource})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Set saves f, overriding any flag with the same name in the file.
+func (s *Store) Set(f models.FeatureFlag) error {
+	compiled, err := compile(f)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.PutFeatureFlag(f); err != nil {
+		return err
+	}
+	s.api[f.Name] = compiled
+	return nil
+}
+
+// Delete deletes the flag name that was set via the API. If the file has a
+// flag with the same name, it's used again. It returns false if no flag with
+// that name was set via the API.
+func (s *Store) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted, err := s.db.DeleteFeatureFlag(name)
+	if err != nil {
+		return false, err
+	}
+	delete(s.api, name)
+	return deleted, nil
+}
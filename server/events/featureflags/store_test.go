@@ -0,0 +1,240 @@
+package featureflags_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/featureflags"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// memDB is an in-memory featureflags.DB.
+type memDB struct {
+	flags map[string]models.FeatureFlag
+}
+
+func newMemDB(flags ...models.FeatureFlag) *memDB {
+	m := &memDB{flags: make(map[string]models.FeatureFlag)}
+	for _, f := range flags {
+		m.flags[f.Name] = f
+	}
+	return m
+}
+
+func (m *memDB) PutFeatureFlag(flag models.FeatureFlag) error {
+	m.flags[flag.Name] = flag
+	return nil
+}
+
+func (m *memDB) ListFeatureFlags() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	for _, f := range m.flags {
+		flags = append(flags, f)
+	}
+	return flags, nil
+}
+
+func (m *memDB) DeleteFeatureFlag(name string) (bool, error) {
+	_, ok := m.flags[name]
+	delete(m.flags, name)
+	return ok, nil
+}
+
+func writeFlagsFile(t *testing.T, contents string) (string, func()) {
+	tmp, cleanup := TempDir(t)
+	path := filepath.Join(tmp, "flags.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path, cleanup
+}
+
+func TestParseFile(t *testing.T) {
+	cases := []struct {
+		description string
+		contents    string
+		exp         []models.FeatureFlag
+		expErr      string
+	}{
+		{
+			description: "empty",
+			contents:    "",
+			exp:         nil,
+		},
+		{
+			description: "flags",
+			contents: `
+flags:
+  parallel-apply:
+    repos: [github.com/owner/repo, /github.com\/other\/.*/]
+    percentage: 10
+  new-renderer:
+    enabled: true
+`,
+			exp: []models.FeatureFlag{
+				{Name: "new-renderer", Enabled: true},
+				{Name: "parallel-apply", Repos: []string{"github.com/owner/repo", `/github.com\/other\/.*/`}, Percentage: 10},
+			},
+		},
+		{
+			description: "unknown key",
+			contents: `
+flags:
+  parallel-apply:
+    enable: true
+`,
+			expErr: "yaml: unmarshal errors:\n  line 4: field enable not found in type featureflags.rawFlag",
+		},
+		{
+			description: "invalid name",
+			contents: `
+flags:
+  Parallel:
+    enabled: true
+`,
+			expErr: `flag name "Parallel" must start with a lowercase letter or number and only contain lowercase letters, numbers, '-' and '_'`,
+		},
+		{
+			description: "invalid percentage",
+			contents: `
+flags:
+  parallel-apply:
+    percentage: 101
+`,
+			expErr: `flag "parallel-apply": percentage must be between 0 and 100, got 101`,
+		},
+		{
+			description: "invalid regex",
+			contents: `
+flags:
+  parallel-apply:
+    repos: [/(/]
+`,
+			expErr: "flag \"parallel-apply\": parsing repo /(/: error parsing regexp: missing closing ): `(`",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			flags, err := featureflags.ParseFile([]byte(c.contents))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, flags)
+		})
+	}
+}
+
+func TestStore_Enabled(t *testing.T) {
+	path, cleanup := writeFlagsFile(t, `
+flags:
+  everywhere:
+    enabled: true
+  by-id:
+    repos: [github.com/owner/repo]
+  by-regex:
+    repos: [/github.com\/owner\/.*/]
+`)
+	defer cleanup()
+	s, err := featureflags.NewStore(path, newMemDB())
+	Ok(t, err)
+
+	Equals(t, true, s.Enabled("everywhere", "github.com/owner/repo"))
+	Equals(t, true, s.Enabled("by-id", "github.com/owner/repo"))
+	Equals(t, false, s.Enabled("by-id", "github.com/owner/other"))
+	Equals(t, true, s.Enabled("by-regex", "github.com/owner/other"))
+	Equals(t, false, s.Enabled("by-regex", "gitlab.com/owner/other"))
+	Equals(t, false, s.Enabled("unknown", "github.com/owner/repo"))
+}
+
+func TestStore_EnabledNil(t *testing.T) {
+	var s *featureflags.Store
+	Equals(t, false, s.Enabled("everywhere", "github.com/owner/repo"))
+	Equals(t, 0, len(s.List()))
+}
+
+// Raising a flag's percentage should only add repos.
+func TestStore_EnabledPercentage(t *testing.T) {
+	s, err := featureflags.NewStore("", newMemDB())
+	Ok(t, err)
+
+	var repos []string
+	for i := 0; i < 1000; i++ {
+		repos = append(repos, fmt.Sprintf("github.com/owner/repo%d", i))
+	}
+	enabledAt := func(percentage int) map[string]bool {
+		Ok(t, s.Set(models.FeatureFlag{Name: "rollout", Percentage: percentage}))
+		enabled := make(map[string]bool)
+		for _, r := range repos {
+			if s.Enabled("rollout", r) {
+				enabled[r] = true
+			}
+		}
+		return enabled
+	}
+
+	Equals(t, 0, len(enabledAt(0)))
+	ten := enabledAt(10)
+	Assert(t, len(ten) > 50 && len(ten) < 150, "exp about 100 repos enabled at 10%%, got %d", len(ten))
+	fifty := enabledAt(50)
+	Assert(t, len(fifty) > 400 && len(fifty) < 600, "exp about 500 repos enabled at 50%%, got %d", len(fifty))
+	for r := range ten {
+		Assert(t, fifty[r], "exp %s to stay enabled", r)
+	}
+	Equals(t, len(repos), len(enabledAt(100)))
+}
+
+func TestStore_SetOverridesFile(t *testing.T) {
+	path, cleanup := writeFlagsFile(t, `
+flags:
+  parallel-apply:
+    enabled: true
+  new-renderer:
+    percentage: 5
+`)
+	defer cleanup()
+	db := newMemDB(models.FeatureFlag{Name: "from-db", Repos: []string{"github.com/owner/repo"}})
+	s, err := featureflags.NewStore(path, db)
+	Ok(t, err)
+	Equals(t, []featureflags.Status{
+		{FeatureFlag: models.FeatureFlag{Name: "from-db", Repos: []string{"github.com/owner/repo"}}, Source: featureflags.APISource},
+		{FeatureFlag: models.FeatureFlag{Name: "new-renderer", Percentage: 5}, Source: featureflags.FileSource},
+		{FeatureFlag: models.FeatureFlag{Name: "parallel-apply", Enabled: true}, Source: featureflags.FileSource},
+	}, s.List())
+
+	// Disable it via the API.
+	Ok(t, s.Set(models.FeatureFlag{Name: "parallel-apply"}))
+	Equals(t, false, s.Enabled("parallel-apply", "github.com/owner/repo"))
+	Equals(t, models.FeatureFlag{Name: "parallel-apply"}, db.flags["parallel-apply"])
+	Equals(t, featureflags.Status{FeatureFlag: models.FeatureFlag{Name: "parallel-apply"}, Source: featureflags.APISource}, s.List()[2])
+
+	// Deleting it goes back to the file's flag.
+	deleted, err := s.Delete("parallel-apply")
+	Ok(t, err)
+	Equals(t, true, deleted)
+	Equals(t, true, s.Enabled("parallel-apply", "github.com/owner/repo"))
+	deleted, err = s.Delete("parallel-apply")
+	Ok(t, err)
+	Equals(t, false, deleted)
+}
+
+func TestStore_SetInvalid(t *testing.T) {
+	db := newMemDB()
+	s, err := featureflags.NewStore("", db)
+	Ok(t, err)
+	err = s.Set(models.FeatureFlag{Name: "rollout", Percentage: -1})
+	ErrEquals(t, `flag "rollout": percentage must be between 0 and 100, got -1`, err)
+	Equals(t, 0, len(db.flags))
+}
+
+func TestNewStore_FileErrors(t *testing.T) {
+	_, err := featureflags.NewStore("/does/not/exist.yaml", newMemDB())
+	ErrEquals(t, "reading feature flags file /does/not/exist.yaml: open /does/not/exist.yaml: no such file or directory", err)
+
+	path, cleanup := writeFlagsFile(t, "flags:\n  Bad:\n    enabled: true\n")
+	defer cleanup()
+	_, err = featureflags.NewStore(path, newMemDB())
+	ErrEquals(t, fmt.Sprintf(`parsing feature flags file %s: flag name "Bad" must start with a lowercase letter or number and only contain lowercase letters, numbers, '-' and '_'`, path), err)
+}
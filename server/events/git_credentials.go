@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// gitCredentialEnvs writes creds to a temporary directory and returns the
+// environment variables that make git, and so terraform init, use them, the
+// secrets that must be redacted from the steps' output and a func that
+// removes the directory. It must be called once the steps have run.
+//
+// SSH keys are used through an ssh config file set in GIT_SSH_COMMAND so
+// each host gets its own key. Tokens are added to HTTPS URLs with
+// url.<base>.insteadOf git config set with GIT_CONFIG_COUNT so they aren't
+// written to disk.
+func gitCredentialEnvs(creds []valid.GitCredential) (map[string]string, []string, func(), error) {
+	noop := func() {}
+	if len(creds) == 0 {
+		return nil, nil, noop, nil
+	}
+	dir, err := ioutil.TempDir("", "atlantis-git-credentials")
+	if err != nil {
+		return nil, nil, noop, errors.Wrap(err, "creating git credentials dir")
+	}
+	cleanup := func() {
+		os.RemoveAll(dir) // nolint: errcheck
+	}
+
+	envs := make(map[string]string)
+	var secrets []string
+	var sshConfig strings.Builder
+	// Keep the git config that's already set in the server's environment.
+	configCount, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	for i, c := range creds {
+		switch {
+		case c.SSHKeyFromEnv != "" || c.SSHKeyFile != "":
+			keyFile := c.SSHKeyFile
+			if c.SSHKeyFromEnv != "" {
+				key, ok := os.LookupEnv(c.SSHKeyFromEnv)
+				if !ok {
+					cleanup()
+					return nil, nil, noop, fmt.Errorf("ssh key for %s is read from %s but it isn't set in the server's environment", c.Host, c.SSHKeyFromEnv)
+				}
+				secrets = append(secrets, key)
+				// ssh rejects keys that don't end with a newline.
+				if !strings.HasSuffix(key, "\n") {
+					key += "\n"
+				}
+				keyFile = filepath.Join(dir, fmt.Sprintf("key-%d", i))
+				if err := ioutil.WriteFile(keyFile, []byte(key), 0600); err != nil {
+					cleanup()
+					return nil, nil, noop, errors.Wrapf(err, "writing ssh key for %s", c.Host)
+				}
+			}
+			// ssh matches hosts without their port.
+			host := strings.SplitN(c.Host, ":", 2)[0]
+			fmt.Fprintf(&sshConfig, "Host %s\n  IdentityFile %q\n  IdentitiesOnly yes\n", host, keyFile)
+		case c.TokenFromEnv != "":
+			token, ok := os.LookupEnv(c.TokenFromEnv)
+			if !ok {
+				cleanup()
+				return nil, nil, noop, fmt.Errorf("token for %s is read from %s but it isn't set in the server's environment", c.Host, c.TokenFromEnv)
+			}
+			secrets = append(secrets, token)
+			// The token is escaped in the URL so that form is redacted too.
+			if escaped := strings.TrimPrefix(url.UserPassword("", token).String(), ":"); escaped != token {
+				secrets = append(secrets, escaped)
+			}
+			userinfo := url.UserPassword(c.Username, token).String()
+			envs[fmt.Sprintf("GIT_CONFIG_KEY_%d", configCount)] = fmt.Sprintf("url.https://%s@%s/.insteadOf", userinfo, c.Host)
+			envs[fmt.Sprintf("GIT_CONFIG_VALUE_%d", configCount)] = "https://" + c.Host + "/"
+			configCount++
+		}
+	}
+
+	if sshConfig.Len() > 0 {
+		// The user's own ssh config is still used for other hosts.
+		sshConfig.WriteString("Match all\n  Include ~/.ssh/config\n")
+		configFile := filepath.Join(dir, "ssh_config")
+		if err := ioutil.WriteFile(configFile, []byte(sshConfig.String()), 0600); err != nil {
+			cleanup()
+			return nil, nil, noop, errors.Wrap(err, "writing ssh config")
+		}
+		envs["GIT_SSH_COMMAND"] = fmt.Sprintf("ssh -F '%s'", configFile)
+	}
+	if configCount > 0 {
+		envs["GIT_CONFIG_COUNT"] = strconv.Itoa(configCount)
+	}
+	return envs, secrets, cleanup, nil
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// GlobalCfgStore holds the server-side repo config so it can be reloaded
+// while the server is running. Commands that are already running keep using
+// the config they started with.
+type GlobalCfgStore struct {
+	mu  sync.RWMutex
+	cfg valid.GlobalCfg
+}
+
+// NewGlobalCfgStore returns a store that holds cfg.
+func NewGlobalCfgStore(cfg valid.GlobalCfg) *GlobalCfgStore {
+	return &GlobalCfgStore{cfg: cfg}
+}
+
+// Get returns the current config.
+func (s *GlobalCfgStore) Get() valid.GlobalCfg {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Set replaces the current config with cfg.
+func (s *GlobalCfgStore) Set(cfg valid.GlobalCfg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
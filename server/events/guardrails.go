@@ -0,0 +1,118 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// GuardrailViolations returns how the plan summarized by summary exceeds
+// guardrails, or nil if it doesn't or guardrails is nil. If the plan couldn't
+// be summarized it can't be checked so that's a violation too.
+func GuardrailViolations(guardrails *valid.Guardrails, summary *models.PlanSummary) []string {
+	if guardrails == nil {
+		return nil
+	}
+	if summary == nil {
+		return []string{"The plan couldn't be summarized so it can't be checked against the guardrails."}
+	}
+	deletes := len(summary.Replaces) + len(summary.Deletes)
+	changes := len(summary.Creates) + len(summary.Updates) + deletes
+	var violations []string
+	for _, r := range guardrails.Rules {
+		if r.MaxDeletes != nil && deletes > *r.MaxDeletes {
+			violations = append(violations, fmt.Sprintf("Deletes %d resources, more than the %d allowed.", deletes, *r.MaxDeletes))
+		}
+		if r.MaxChanges != nil && changes > *r.MaxChanges {
+			violations = append(violations, fmt.Sprintf("Changes %d resources, more than the %d allowed.", changes, *r.MaxChanges))
+		}
+		for _, changed := range []struct {
+			action string
+			addrs  []string
+		}{
+			{models.UpdateAction, summary.Updates},
+			{models.ReplaceAction, summary.Replaces},
+			{models.DeleteAction, summary.Deletes},
+		} {
+			for _, addr := range changed.addrs {
+				if pattern := r.Denies(addr); pattern != "" {
+					violations = append(violations, fmt.Sprintf("Would %s `%s`, which matches the denied pattern `%s`.", changed.action, addr, pattern))
+				}
+			}
+		}
+	}
+	// Rules can overlap so the same violation is only reported once.
+	var unique []string
+	seen := make(map[string]bool)
+	for _, v := range violations {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// guardrailsMarkerPath returns the path of the file that records how the
+// project's plan exceeds its guardrails. It's next to the planfile so it's
+// deleted with the clone.
+func guardrailsMarkerPath(ctx models.ProjectCommandContext, absPath string) string {
+	return filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)+".guardrails")
+}
+
+// markGuardrailViolations records how the project's plan exceeds its
+// guardrails so it can't be applied without an override, or removes the
+// record of a previous plan's violations.
+func markGuardrailViolations(ctx models.ProjectCommandContext, absPath string, violations []string) error {
+	marker := guardrailsMarkerPath(ctx, absPath)
+	if len(violations) > 0 {
+		return errors.Wrap(ioutil.WriteFile(marker, []byte(strings.Join(violations, "\n")), 0600), "recording guardrail violations")
+	}
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "removing guardrail violations record")
+	}
+	return nil
+}
+
+// guardrailsFailure returns why the project's plan can't be applied because
+// it exceeds its guardrails, or an empty string if it can.
+func (p *DefaultProjectCommandRunner) guardrailsFailure(ctx models.ProjectCommandContext, absPath string) (string, error) {
+	contents, err := ioutil.ReadFile(guardrailsMarkerPath(ctx, absPath))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "reading guardrail violations")
+	}
+	var override []string
+	if ctx.Guardrails != nil {
+		override = ctx.Guardrails.Override
+	}
+	if ctx.OverrideGuardrails {
+		allowed, err := p.ownerApproved(ctx.BaseRepo, override, []string{ctx.User.Username}, make(map[string]bool)) // nolint: vetshadow
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return fmt.Sprintf("@%s isn't allowed to apply plans that exceed this project's guardrails with `--%s`.", ctx.User.Username, overrideGuardrailsLong), nil
+		}
+		ctx.Log.Warn("%s is applying a plan that exceeds the guardrails with --%s", ctx.User.Username, overrideGuardrailsLong)
+		return "", nil
+	}
+
+	failure := "This plan exceeds the project's guardrails:\n"
+	for _, v := range strings.Split(string(contents), "\n") {
+		failure += fmt.Sprintf("* %s\n", v)
+	}
+	if len(override) > 0 {
+		failure += fmt.Sprintf("\nOne of %s can apply it anyway by commenting:\n* `%s`", strings.Join(override, ", "), withCommandFlag(ctx.ApplyCmd, models.ApplyCommand, overrideGuardrailsLong))
+	}
+	return strings.TrimSuffix(failure, "\n"), nil
+}
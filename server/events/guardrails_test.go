@@ -0,0 +1,67 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGuardrailViolations(t *testing.T) {
+	zero, two := 0, 2
+	summary := &models.PlanSummary{
+		Creates:  []string{"aws_route53_zone.new", "aws_instance.web[1]"},
+		Updates:  []string{"aws_route53_zone.main"},
+		Replaces: []string{"aws_instance.web[0]"},
+	}
+
+	cases := []struct {
+		description string
+		guardrails  *valid.Guardrails
+		summary     *models.PlanSummary
+		exp         []string
+	}{
+		{
+			description: "no guardrails",
+			summary:     summary,
+		},
+		{
+			description: "no summary",
+			guardrails:  &valid.Guardrails{Rules: []valid.GuardrailRule{{MaxChanges: &two}}},
+			exp:         []string{"The plan couldn't be summarized so it can't be checked against the guardrails."},
+		},
+		{
+			description: "within limits",
+			guardrails:  &valid.Guardrails{Rules: []valid.GuardrailRule{{Deny: []string{"aws_db_instance.*"}}}},
+			summary:     summary,
+		},
+		{
+			description: "replaces count as deletes",
+			guardrails:  &valid.Guardrails{Rules: []valid.GuardrailRule{{MaxDeletes: &zero, MaxChanges: &two}}},
+			summary:     summary,
+			exp: []string{
+				"Deletes 1 resources, more than the 0 allowed.",
+				"Changes 4 resources, more than the 2 allowed.",
+			},
+		},
+		{
+			description: "denied resources can be created",
+			guardrails:  &valid.Guardrails{Rules: []valid.GuardrailRule{{Deny: []string{"aws_route53_zone.*"}}, {Deny: []string{"aws_route53_zone.main"}}}},
+			summary:     summary,
+			exp:         []string{"Would update `aws_route53_zone.main`, which matches the denied pattern `aws_route53_zone.*`.", "Would update `aws_route53_zone.main`, which matches the denied pattern `aws_route53_zone.main`."},
+		},
+		{
+			description: "overlapping rules",
+			guardrails:  &valid.Guardrails{Rules: []valid.GuardrailRule{{MaxDeletes: &zero, Dir: "production"}, {MaxDeletes: &zero}}},
+			summary:     summary,
+			exp:         []string{"Deletes 1 resources, more than the 0 allowed."},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, events.GuardrailViolations(c.guardrails, c.summary))
+		})
+	}
+}
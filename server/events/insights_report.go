@@ -0,0 +1,70 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// planCountsRegex matches the line terraform prints at the end of a plan that
+// has changes.
+var planCountsRegex = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy\.`)
+
+// InsightsReportKey returns the key of the report of cmdName for the project
+// in ctx. Each project has its own report that's replaced when the command is
+// run again.
+func InsightsReportKey(ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	return fmt.Sprintf("atlantis-%s-%s", cmdName.String(), ctx.ProjectID())
+}
+
+// NewPlanInsightsReport returns a report of the plan of the project in ctx
+// that had result res. It counts the resources the plan will change from its
+// structured summary or, if it doesn't have one, from the plan's output.
+func NewPlanInsightsReport(ctx models.ProjectCommandContext, res models.ProjectResult) models.InsightsReport {
+	project := fmt.Sprintf("dir: %s workspace: %s", ctx.RepoRelDir, ctx.Workspace)
+	if ctx.ProjectName != "" {
+		project = fmt.Sprintf("project: %s %s", ctx.ProjectName, project)
+	}
+	report := models.InsightsReport{
+		Title:  fmt.Sprintf("Atlantis plan: %s", project),
+		Passed: res.Error == nil && res.Failure == "",
+	}
+	switch {
+	case res.Error != nil:
+		report.Details = fmt.Sprintf("Plan errored: %s", res.Error)
+	case res.Failure != "":
+		report.Details = fmt.Sprintf("Plan failed: %s", res.Failure)
+	case res.PlanSuccess != nil:
+		report.Link = res.PlanSuccess.FullOutputURL
+		if summary := res.PlanSuccess.Summary; summary != nil {
+			report.Details = summaryChanges(summary) + "."
+			report.Data = []models.InsightsData{
+				{Title: "To add", Value: len(summary.Creates)},
+				{Title: "To change", Value: len(summary.Updates)},
+				{Title: "To replace", Value: len(summary.Replaces)},
+				{Title: "To destroy", Value: len(summary.Deletes)},
+			}
+		} else if counts := planCountsRegex.FindStringSubmatch(res.PlanSuccess.TerraformOutput); counts != nil {
+			report.Details = strings.TrimPrefix(counts[0], "Plan: ")
+			report.Data = []models.InsightsData{
+				{Title: "To add", Value: atoi(counts[1])},
+				{Title: "To change", Value: atoi(counts[2])},
+				{Title: "To destroy", Value: atoi(counts[3])},
+			}
+		} else if strings.Contains(res.PlanSuccess.TerraformOutput, "No changes.") {
+			report.Details = "No changes."
+		} else {
+			report.Details = "Plan succeeded."
+		}
+	}
+	return report
+}
+
+// atoi converts s, which must be all digits, to an int.
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
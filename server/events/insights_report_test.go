@@ -0,0 +1,87 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewPlanInsightsReport(t *testing.T) {
+	ctx := models.ProjectCommandContext{RepoRelDir: "dir", Workspace: "default", ProjectName: "proj"}
+	cases := []struct {
+		description string
+		res         models.ProjectResult
+		exp         models.InsightsReport
+	}{
+		{
+			description: "error",
+			res:         models.ProjectResult{Error: errors.New("exit status 1")},
+			exp: models.InsightsReport{
+				Title:   "Atlantis plan: project: proj dir: dir workspace: default",
+				Details: "Plan errored: exit status 1",
+			},
+		},
+		{
+			description: "failure",
+			res:         models.ProjectResult{Failure: "locked"},
+			exp: models.InsightsReport{
+				Title:   "Atlantis plan: project: proj dir: dir workspace: default",
+				Details: "Plan failed: locked",
+			},
+		},
+		{
+			description: "summary",
+			res: models.ProjectResult{PlanSuccess: &models.PlanSuccess{
+				FullOutputURL: "https://atlantis/plan-outputs/1",
+				Summary:       &models.PlanSummary{Creates: []string{"a", "b"}, Replaces: []string{"c"}},
+			}},
+			exp: models.InsightsReport{
+				Title:   "Atlantis plan: project: proj dir: dir workspace: default",
+				Details: "2 to add, 0 to change, 1 to replace, 0 to destroy.",
+				Passed:  true,
+				Data: []models.InsightsData{
+					{Title: "To add", Value: 2},
+					{Title: "To change", Value: 0},
+					{Title: "To replace", Value: 1},
+					{Title: "To destroy", Value: 0},
+				},
+				Link: "https://atlantis/plan-outputs/1",
+			},
+		},
+		{
+			description: "counts from output",
+			res: models.ProjectResult{PlanSuccess: &models.PlanSuccess{
+				TerraformOutput: "+ null_resource.a\n\nPlan: 1 to add, 3 to change, 0 to destroy.",
+			}},
+			exp: models.InsightsReport{
+				Title:   "Atlantis plan: project: proj dir: dir workspace: default",
+				Details: "1 to add, 3 to change, 0 to destroy.",
+				Passed:  true,
+				Data: []models.InsightsData{
+					{Title: "To add", Value: 1},
+					{Title: "To change", Value: 3},
+					{Title: "To destroy", Value: 0},
+				},
+			},
+		},
+		{
+			description: "no changes",
+			res: models.ProjectResult{PlanSuccess: &models.PlanSuccess{
+				TerraformOutput: "No changes. Infrastructure is up-to-date.",
+			}},
+			exp: models.InsightsReport{
+				Title:   "Atlantis plan: project: proj dir: dir workspace: default",
+				Details: "No changes.",
+				Passed:  true,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, events.NewPlanInsightsReport(ctx, c.res))
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobLogRetention is how long the logs of a finished job are kept if no
+// other job of its project starts.
+const JobLogRetention = time.Hour
+
+// JobLogLine is the output of one step of a job.
+type JobLogLine struct {
+	// Step is the name of the step, ex. init or plan.
+	Step   string
+	Output string
+	Time   time.Time
+}
+
+// JobLogs keeps the output of the latest job of each project, i.e. the steps
+// run for a plan, apply or custom command, so it can be streamed while the
+// job runs. Logs are only kept in memory.
+type JobLogs struct {
+	mu   sync.Mutex
+	jobs map[string]*jobLog
+	// started is closed and replaced whenever a job starts.
+	started chan struct{}
+}
+
+type jobLog struct {
+	lines      []JobLogLine
+	finishedAt time.Time
+	// changed is closed and replaced whenever a line is added or the job
+	// finishes.
+	changed chan struct{}
+}
+
+// JobKey returns the key of the jobs of the project in repoRelDir and
+// workspace in pull request pullNum of the repo repoFullName.
+func JobKey(repoFullName string, pullNum int, repoRelDir string, workspace string) string {
+	return fmt.Sprintf("%s#%d/%s/%s", repoFullName, pullNum, repoRelDir, workspace)
+}
+
+// Start starts a new job for key, replacing the logs of its previous job.
+// It's safe to call on a nil JobLogs, as are Append and Finish.
+func (j *JobLogs) Start(key string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.init()
+	now := time.Now()
+	for k, job := range j.jobs {
+		if !job.finishedAt.IsZero() && now.Sub(job.finishedAt) > JobLogRetention {
+			delete(j.jobs, k)
+		}
+	}
+	if prev, ok := j.jobs[key]; ok && prev.finishedAt.IsZero() {
+		// Wake up anyone following the previous job so they see it's over.
+		prev.finishedAt = now
+		close(prev.changed)
+	}
+	j.jobs[key] = &jobLog{changed: make(chan struct{})}
+	close(j.started)
+	j.started = make(chan struct{})
+}
+
+// Append adds line to the running job of key. It's a no-op if there isn't one.
+func (j *JobLogs) Append(key string, line JobLogLine) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[key]
+	if !ok || !job.finishedAt.IsZero() {
+		return
+	}
+	job.lines = append(job.lines, line)
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+// Finish marks the running job of key as finished.
+func (j *JobLogs) Finish(key string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[key]
+	if !ok || !job.finishedAt.IsZero() {
+		return
+	}
+	job.finishedAt = time.Now()
+	close(job.changed)
+}
+
+// Follow calls send with each line of the latest job of key and then with
+// each line that's added until the job finishes. If there's no job yet or
+// next is true, it waits for the next job to start first. It returns early
+// with ctx's error if ctx is done and with send's error if send fails.
+func (j *JobLogs) Follow(ctx context.Context, key string, next bool, send func(JobLogLine) error) error {
+	j.mu.Lock()
+	j.init()
+	job := j.jobs[key]
+	prev := job
+	for job == nil || (next && job == prev) {
+		started := j.started
+		j.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-started:
+		}
+		j.mu.Lock()
+		job = j.jobs[key]
+	}
+
+	sent := 0
+	for {
+		lines := job.lines[sent:]
+		finished := !job.finishedAt.IsZero()
+		changed := job.changed
+		j.mu.Unlock()
+
+		for _, line := range lines {
+			if err := send(line); err != nil {
+				return err
+			}
+		}
+		sent += len(lines)
+		if finished {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+		j.mu.Lock()
+	}
+}
+
+// init initializes the zero value. j.mu must be held.
+func (j *JobLogs) init() {
+	if j.jobs == nil {
+		j.jobs = make(map[string]*jobLog)
+		j.started = make(chan struct{})
+	}
+}
@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestJobLogs_Follow(t *testing.T) {
+	logs := &events.JobLogs{}
+	key := events.JobKey("owner/repo", 1, ".", "default")
+	Equals(t, "owner/repo#1/./default", key)
+
+	logs.Start(key)
+	logs.Append(key, events.JobLogLine{Step: "init", Output: "first init"})
+	logs.Finish(key)
+	t.Log("lines added after a job finishes are ignored")
+	logs.Append(key, events.JobLogLine{Step: "plan", Output: "ignored"})
+
+	var lines []string
+	send := func(line events.JobLogLine) error {
+		lines = append(lines, line.Output)
+		return nil
+	}
+	Ok(t, logs.Follow(context.Background(), key, false, send))
+	Equals(t, []string{"first init"}, lines)
+
+	t.Log("with next, the finished job is skipped and the next one is followed until it finishes")
+	lines = nil
+	done := make(chan error)
+	go func() {
+		done <- logs.Follow(context.Background(), key, true, send)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	logs.Start(key)
+	logs.Append(key, events.JobLogLine{Step: "init", Output: "second init"})
+	logs.Append(key, events.JobLogLine{Step: "plan", Output: "second plan"})
+	logs.Finish(key)
+	select {
+	case err := <-done:
+		Ok(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out following job")
+	}
+	Equals(t, []string{"second init", "second plan"}, lines)
+}
+
+func TestJobLogs_FollowCanceled(t *testing.T) {
+	logs := &events.JobLogs{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := logs.Follow(ctx, "owner/repo#1/./default", false, func(events.JobLogLine) error { return nil })
+	Equals(t, context.Canceled, err)
+
+	t.Log("a nil JobLogs ignores jobs")
+	var nilLogs *events.JobLogs
+	nilLogs.Start("key")
+	nilLogs.Append("key", events.JobLogLine{})
+	nilLogs.Finish("key")
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// memInfoPath is where the kernel reports memory usage. It only exists on
+// Linux so the memory threshold is ignored on other platforms.
+const memInfoPath = "/proc/meminfo"
+
+// LoadShedder rejects new autoplans when the server is running low on disk or
+// memory or is already running too many commands. It protects the commands
+// that are in flight, ex. applies, from failing because the server took on
+// more work than it can finish. A nil *LoadShedder never sheds load.
+type LoadShedder struct {
+	// DataDir is the directory whose filesystem's free space is checked.
+	DataDir string
+	// MinFreeDiskBytes is the least free disk space in DataDir before we
+	// shed load. 0 disables the check.
+	MinFreeDiskBytes uint64
+	// MinFreeMemoryBytes is the least available memory before we shed load.
+	// 0 disables the check.
+	MinFreeMemoryBytes uint64
+	// MaxQueueDepth is the most commands that can be in flight before we
+	// shed load. 0 disables the check.
+	MaxQueueDepth int
+	// FreeDiskBytes and FreeMemoryBytes return the free disk space in DataDir
+	// and the available memory. They're fields so they can be set in tests
+	// and default to reading them from the OS.
+	FreeDiskBytes   func(dir string) (uint64, error)
+	FreeMemoryBytes func() (uint64, error)
+
+	inFlight int64
+}
+
+// Track records that a command is in flight. The returned func must be called
+// when the command finishes.
+func (l *LoadShedder) Track() func() {
+	if l == nil {
+		return func() {}
+	}
+	atomic.AddInt64(&l.inFlight, 1)
+	return func() {
+		atomic.AddInt64(&l.inFlight, -1)
+	}
+}
+
+// InFlight returns the number of commands in flight.
+func (l *LoadShedder) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&l.inFlight))
+}
+
+// Check returns the reasons the server is degraded. If it returns no reasons
+// the server can take on more work. Errors reading disk or memory usage
+// don't shed load since we'd otherwise reject all work on platforms we can't
+// measure.
+func (l *LoadShedder) Check() []string {
+	if l == nil {
+		return nil
+	}
+	var reasons []string
+	if l.MinFreeDiskBytes > 0 {
+		free, err := l.freeDiskBytes()
+		if err == nil && free < l.MinFreeDiskBytes {
+			reasons = append(reasons, fmt.Sprintf("free disk space %s is below %s", formatMB(free), formatMB(l.MinFreeDiskBytes)))
+		}
+	}
+	if l.MinFreeMemoryBytes > 0 {
+		free, err := l.freeMemoryBytes()
+		if err == nil && free < l.MinFreeMemoryBytes {
+			reasons = append(reasons, fmt.Sprintf("available memory %s is below %s", formatMB(free), formatMB(l.MinFreeMemoryBytes)))
+		}
+	}
+	if l.MaxQueueDepth > 0 {
+		if inFlight := l.InFlight(); inFlight >= l.MaxQueueDepth {
+			reasons = append(reasons, fmt.Sprintf("%d commands are in flight, the most allowed is %d", inFlight, l.MaxQueueDepth))
+		}
+	}
+	return reasons
+}
+
+func (l *LoadShedder) freeDiskBytes() (uint64, error) {
+	if l.FreeDiskBytes != nil {
+		return l.FreeDiskBytes(l.DataDir)
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(l.DataDir, &stat); err != nil {
+		return 0, errors.Wrapf(err, "checking free disk space in %s", l.DataDir)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+func (l *LoadShedder) freeMemoryBytes() (uint64, error) {
+	if l.FreeMemoryBytes != nil {
+		return l.FreeMemoryBytes()
+	}
+	f, err := os.Open(memInfoPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close() // nolint: errcheck
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The line looks like "MemAvailable:    1234567 kB".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "parsing %s", memInfoPath)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrapf(err, "reading %s", memInfoPath)
+	}
+	return 0, fmt.Errorf("MemAvailable not found in %s", memInfoPath)
+}
+
+func formatMB(bytes uint64) string {
+	return fmt.Sprintf("%dMB", bytes/1024/1024)
+}
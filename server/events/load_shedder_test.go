@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestLoadShedder_Check(t *testing.T) {
+	const mb = 1024 * 1024
+	l := &events.LoadShedder{
+		DataDir:            "/data",
+		MinFreeDiskBytes:   100 * mb,
+		MinFreeMemoryBytes: 200 * mb,
+		MaxQueueDepth:      2,
+		FreeDiskBytes: func(dir string) (uint64, error) {
+			Equals(t, "/data", dir)
+			return 500 * mb, nil
+		},
+		FreeMemoryBytes: func() (uint64, error) { return 500 * mb, nil },
+	}
+	Equals(t, 0, len(l.Check()))
+
+	done1 := l.Track()
+	done2 := l.Track()
+	Equals(t, 2, l.InFlight())
+	Equals(t, []string{"2 commands are in flight, the most allowed is 2"}, l.Check())
+	done1()
+	done2()
+	Equals(t, 0, l.InFlight())
+
+	l.FreeDiskBytes = func(string) (uint64, error) { return 50 * mb, nil }
+	l.FreeMemoryBytes = func() (uint64, error) { return 150 * mb, nil }
+	Equals(t, []string{
+		"free disk space 50MB is below 100MB",
+		"available memory 150MB is below 200MB",
+	}, l.Check())
+
+	// Errors measuring usage shouldn't shed load.
+	l.FreeDiskBytes = func(string) (uint64, error) { return 0, errors.New("err") }
+	l.FreeMemoryBytes = func() (uint64, error) { return 0, errors.New("err") }
+	Equals(t, 0, len(l.Check()))
+}
+
+func TestLoadShedder_Nil(t *testing.T) {
+	var l *events.LoadShedder
+	l.Track()()
+	Equals(t, 0, l.InFlight())
+	Equals(t, 0, len(l.Check()))
+}
+
+func TestLoadShedder_OS(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	// Make the thresholds impossible to meet so we know usage was measured.
+	l := &events.LoadShedder{
+		DataDir:          tmp,
+		MinFreeDiskBytes: 1 << 62,
+	}
+	Equals(t, 1, len(l.Check()))
+}
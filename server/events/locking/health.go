@@ -0,0 +1,148 @@
+package locking
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// DefaultHealthCheckInterval is how often the backend is checked.
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultHealthCheckTimeout is how long a check can take before the
+	// backend is considered unavailable.
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+// Pinger is implemented by backends that can check their connection more
+// cheaply than listing every lock.
+type Pinger interface {
+	Ping() error
+}
+
+// HealthStatus is the result of the last health check.
+type HealthStatus struct {
+	Healthy bool `json:"healthy"`
+	// Error is why the backend is unavailable. It's empty if it's healthy.
+	Error string `json:"error,omitempty"`
+	// Since is when the backend became healthy or unavailable.
+	Since time.Time `json:"since"`
+	// FailedChecks is how many checks have failed since the server started.
+	FailedChecks int `json:"failed_checks"`
+}
+
+// HealthChecker periodically checks that the locking backend can be reached
+// so commands can be rejected or held before they start during an outage
+// instead of failing halfway through with storage errors. A nil
+// *HealthChecker is always healthy.
+type HealthChecker struct {
+	Backend  Backend
+	Interval time.Duration
+	Timeout  time.Duration
+	Now      func() time.Time
+
+	mu     sync.Mutex
+	status HealthStatus
+}
+
+// NewHealthChecker returns a checker for backend that assumes it's healthy
+// until it's first checked.
+func NewHealthChecker(backend Backend) *HealthChecker {
+	return &HealthChecker{
+		Backend:  backend,
+		Interval: DefaultHealthCheckInterval,
+		Timeout:  DefaultHealthCheckTimeout,
+		status:   HealthStatus{Healthy: true, Since: time.Now()},
+	}
+}
+
+// Start checks the backend every Interval until the process exits and logs
+// when it becomes unavailable or recovers.
+func (h *HealthChecker) Start(logger logging.SimpleLogging) {
+	for range time.Tick(h.Interval) {
+		wasHealthy := h.Status().Healthy
+		err := h.Check()
+		switch {
+		case err != nil && wasHealthy:
+			logger.Err("locking backend is unavailable, commands will be held or rejected until it recovers: %s", err)
+		case err == nil && !wasHealthy:
+			logger.Info("locking backend recovered")
+		}
+	}
+}
+
+// Check checks the backend now, records the result and returns why it's
+// unavailable or nil if it's healthy.
+func (h *HealthChecker) Check() error {
+	if h == nil {
+		return nil
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		if pinger, ok := h.Backend.(Pinger); ok {
+			errCh <- pinger.Ping()
+			return
+		}
+		_, err := h.Backend.List()
+		errCh <- err
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-time.After(h.Timeout):
+		err = fmt.Errorf("timed out after %s", h.Timeout)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	healthy := err == nil
+	if healthy != h.status.Healthy {
+		h.status.Healthy = healthy
+		h.status.Since = h.now()
+	}
+	h.status.Error = ""
+	if err != nil {
+		h.status.Error = err.Error()
+		h.status.FailedChecks++
+	}
+	return err
+}
+
+// Status returns the result of the last check.
+func (h *HealthChecker) Status() HealthStatus {
+	if h == nil {
+		return HealthStatus{Healthy: true}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// WaitHealthy returns nil once the backend is healthy or why it's unavailable
+// if it's still down after wait. The backend is checked again each Interval
+// while waiting rather than waiting for the background checks.
+func (h *HealthChecker) WaitHealthy(wait time.Duration) error {
+	if h == nil || h.Status().Healthy {
+		return nil
+	}
+	deadline := h.now().Add(wait)
+	for {
+		err := h.Check()
+		if err == nil {
+			return nil
+		}
+		if !h.now().Add(h.Interval).Before(deadline) {
+			return err
+		}
+		time.Sleep(h.Interval)
+	}
+}
+
+func (h *HealthChecker) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,106 @@
+package locking_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// pingBackend is a backend whose Ping returns err.
+type pingBackend struct {
+	locking.Backend
+	err   error
+	block chan struct{}
+}
+
+func (p *pingBackend) Ping() error {
+	if p.block != nil {
+		<-p.block
+	}
+	return p.err
+}
+
+// listBackend is a backend without Ping whose List returns err.
+type listBackend struct {
+	locking.Backend
+	err error
+}
+
+func (l *listBackend) List() ([]models.ProjectLock, error) {
+	return nil, l.err
+}
+
+func TestHealthChecker_Check(t *testing.T) {
+	backend := &pingBackend{}
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	h := locking.NewHealthChecker(backend)
+	h.Now = func() time.Time { return now }
+	Equals(t, true, h.Status().Healthy)
+
+	backend.err = errors.New("connection refused")
+	ErrEquals(t, "connection refused", h.Check())
+	Equals(t, locking.HealthStatus{
+		Healthy:      false,
+		Error:        "connection refused",
+		Since:        now,
+		FailedChecks: 1,
+	}, h.Status())
+
+	// Since shouldn't change while the backend stays unavailable.
+	h.Now = func() time.Time { return now.Add(time.Minute) }
+	ErrEquals(t, "connection refused", h.Check())
+	Equals(t, now, h.Status().Since)
+	Equals(t, 2, h.Status().FailedChecks)
+
+	backend.err = nil
+	Ok(t, h.Check())
+	Equals(t, locking.HealthStatus{
+		Healthy:      true,
+		Since:        now.Add(time.Minute),
+		FailedChecks: 2,
+	}, h.Status())
+}
+
+func TestHealthChecker_CheckListsWithoutPing(t *testing.T) {
+	backend := &listBackend{err: errors.New("bolt closed")}
+	h := locking.NewHealthChecker(backend)
+	ErrEquals(t, "bolt closed", h.Check())
+	backend.err = nil
+	Ok(t, h.Check())
+}
+
+func TestHealthChecker_CheckTimeout(t *testing.T) {
+	backend := &pingBackend{block: make(chan struct{})}
+	defer close(backend.block)
+	h := locking.NewHealthChecker(backend)
+	h.Timeout = 10 * time.Millisecond
+	ErrEquals(t, "timed out after 10ms", h.Check())
+	Equals(t, false, h.Status().Healthy)
+}
+
+func TestHealthChecker_WaitHealthy(t *testing.T) {
+	backend := &pingBackend{err: errors.New("connection refused")}
+	h := locking.NewHealthChecker(backend)
+	h.Interval = time.Millisecond
+
+	// Healthy checkers shouldn't wait.
+	Ok(t, h.WaitHealthy(time.Hour))
+
+	Assert(t, h.Check() != nil, "expected check to fail")
+	ErrEquals(t, "connection refused", h.WaitHealthy(5*time.Millisecond))
+
+	backend.err = nil
+	Ok(t, h.WaitHealthy(time.Hour))
+	Equals(t, true, h.Status().Healthy)
+}
+
+func TestHealthChecker_Nil(t *testing.T) {
+	var h *locking.HealthChecker
+	Ok(t, h.Check())
+	Ok(t, h.WaitHealthy(time.Hour))
+	Equals(t, true, h.Status().Healthy)
+}
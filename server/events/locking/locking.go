@@ -135,6 +135,12 @@ func (c *Client) GetLock(key string) (*models.ProjectLock, error) {
 }
 
 func (c *Client) key(p models.Project, workspace string) string {
+	return LockKey(p, workspace)
+}
+
+// LockKey returns the key of the lock on project p in workspace, as used by
+// GetLock and Unlock.
+func LockKey(p models.Project, workspace string) string {
 	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
 }
 
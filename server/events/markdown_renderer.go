@@ -16,6 +16,7 @@ package events
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
 	"text/template"
 
@@ -26,11 +27,29 @@ import (
 const (
 	planCommandTitle  = "Plan"
 	applyCommandTitle = "Apply"
+	diffCommandTitle  = "Diff"
 	// maxUnwrappedLines is the maximum number of lines the Terraform output
 	// can be before we wrap it in an expandable template.
 	maxUnwrappedLines = 12
+	// continuationReserve is the number of chars we reserve in each split
+	// comment for the continuation header and footer.
+	continuationReserve = 200
 )
 
+// maxCommentLengths is the maximum number of chars allowed in a single comment
+// for each VCS host. If a host isn't in this map then it has no limit that
+// we know of.
+var maxCommentLengths = map[models.VCSHostType]int{
+	models.Github:          65536,
+	models.Gitlab:          1000000,
+	models.BitbucketServer: 32768,
+	models.AzureDevops:     65536,
+}
+
+// resourceHeaderRegex matches the line Terraform outputs before each resource
+// in a plan, ex. "  # aws_instance.web will be created".
+var resourceHeaderRegex = regexp.MustCompile(`^\s*# (\S+ (?:will|must) be .+)$`)
+
 // MarkdownRenderer renders responses as markdown.
 type MarkdownRenderer struct {
 	// GitlabSupportsCommonMark is true if the version of GitLab we're
@@ -48,6 +67,8 @@ type commonData struct {
 	Log             string
 	PlansDeleted    bool
 	DisableApplyAll bool
+	// CustomCommand is true if the results are from a custom command.
+	CustomCommand bool
 }
 
 // errData is data about an error response.
@@ -73,6 +94,25 @@ type planSuccessData struct {
 	PlanWasDeleted bool
 }
 
+type diffSuccessData struct {
+	models.DiffSuccess
+	// Fold is true if each plan's output should be collapsible.
+	Fold bool
+}
+
+type planSuccessResourcesData struct {
+	planSuccessData
+	Sections []planSection
+}
+
+// planSection is a part of the plan output. If Name is set then it's the
+// section for a single resource, otherwise it's output outside of any
+// resource, ex. "Plan: 1 to add, 0 to change, 0 to destroy."
+type planSection struct {
+	Name string
+	Body string
+}
+
 type projectResultTmplData struct {
 	Workspace   string
 	RepoRelDir  string
@@ -84,12 +124,16 @@ type projectResultTmplData struct {
 // nolint: interfacer
 func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName, log string, verbose bool, vcsHost models.VCSHostType) string {
 	commandStr := strings.Title(cmdName.String())
+	if res.CustomCommand != "" {
+		commandStr = strings.Title(res.CustomCommand)
+	}
 	common := commonData{
 		Command:         commandStr,
 		Verbose:         verbose,
 		Log:             log,
 		PlansDeleted:    res.PlansDeleted,
 		DisableApplyAll: m.DisableApplyAll,
+		CustomCommand:   res.CustomCommand != "",
 	}
 	if res.Error != nil {
 		return m.renderTemplate(unwrappedErrWithLogTmpl, errData{res.Error.Error(), common})
@@ -97,7 +141,38 @@ func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName,
 	if res.Failure != "" {
 		return m.renderTemplate(failureWithLogTmpl, failureData{res.Failure, common})
 	}
-	return m.renderProjectResults(res.ProjectResults, common, vcsHost)
+	var rendered string
+	if len(res.ProjectResults) > 0 || (len(res.NoChangesResults) == 0 && len(res.KeptPlans) == 0 && len(res.ReusedPlans) == 0) {
+		rendered = m.renderProjectResults(res.ProjectResults, common, vcsHost)
+	}
+	if res.SummarizeNoChanges && len(res.NoChangesResults) > 0 {
+		var names []string
+		for _, r := range res.NoChangesResults {
+			names = append(names, shortProjectName(r.ProjectName, r.RepoRelDir, r.Workspace))
+		}
+		rendered += m.renderTemplate(noChangesTmpl, names)
+	}
+	if len(res.KeptPlans) > 0 {
+		var names []string
+		for _, ps := range res.KeptPlans {
+			names = append(names, shortProjectName(ps.ProjectName, ps.RepoRelDir, ps.Workspace))
+		}
+		rendered += m.renderTemplate(keptPlansTmpl, names)
+	}
+	if len(res.ReusedPlans) > 0 {
+		var names []string
+		for _, ps := range res.ReusedPlans {
+			names = append(names, shortProjectName(ps.ProjectName, ps.RepoRelDir, ps.Workspace))
+		}
+		rendered += m.renderTemplate(reusedPlansTmpl, names)
+	}
+	if rendered == "" {
+		return ""
+	}
+	if len(res.SuggestedReviewers) > 0 {
+		rendered += m.renderTemplate(suggestedReviewersTmpl, res.SuggestedReviewers)
+	}
+	return rendered
 }
 
 func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult, common commonData, vcsHost models.VCSHostType) string {
@@ -131,12 +206,33 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 				Failure: result.Failure,
 			})
 		} else if result.PlanSuccess != nil {
-			if m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
-				resultData.Rendered = m.renderTemplate(planSuccessWrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted})
+			if result.PlanSuccess.Summary != nil {
+				tmpl := planSummaryUnwrappedTmpl
+				if m.supportsFolding(vcsHost) {
+					tmpl = planSummaryWrappedTmpl
+				}
+				resultData.Rendered = m.renderTemplate(tmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted})
+			} else if m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
+				data := planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted}
+				if sections := splitPlanByResource(result.PlanSuccess.TerraformOutput); sections != nil {
+					resultData.Rendered = m.renderTemplate(planSuccessResourcesTmpl, planSuccessResourcesData{planSuccessData: data, Sections: sections})
+				} else {
+					resultData.Rendered = m.renderTemplate(planSuccessWrappedTmpl, data)
+				}
 			} else {
 				resultData.Rendered = m.renderTemplate(planSuccessUnwrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted})
 			}
 			numPlanSuccesses++
+		} else if result.Command == models.CustomCommand {
+			if result.CustomOutput == "" {
+				resultData.Rendered = customNoOutput
+			} else if m.shouldUseWrappedTmpl(vcsHost, result.CustomOutput) {
+				resultData.Rendered = m.renderTemplate(applyWrappedSuccessTmpl, struct{ Output string }{result.CustomOutput})
+			} else {
+				resultData.Rendered = m.renderTemplate(applyUnwrappedSuccessTmpl, struct{ Output string }{result.CustomOutput})
+			}
+		} else if result.DiffSuccess != nil {
+			resultData.Rendered = m.renderTemplate(diffSuccessTmpl, diffSuccessData{DiffSuccess: *result.DiffSuccess, Fold: m.supportsFolding(vcsHost)})
 		} else if result.ApplySuccess != "" {
 			if m.shouldUseWrappedTmpl(vcsHost, result.ApplySuccess) {
 				resultData.Rendered = m.renderTemplate(applyWrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
@@ -158,6 +254,13 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 		tmpl = singleProjectPlanUnsuccessfulTmpl
 	case len(resultsTmplData) == 1 && common.Command == applyCommandTitle:
 		tmpl = singleProjectApplyTmpl
+	case common.Command == diffCommandTitle:
+		tmpl = singleProjectDiffTmpl
+	case len(resultsTmplData) == 1 && common.CustomCommand:
+		// Custom commands' output is rendered like apply output.
+		tmpl = singleProjectApplyTmpl
+	case common.CustomCommand:
+		tmpl = multiProjectApplyTmpl
 	case common.Command == planCommandTitle:
 		tmpl = multiProjectPlanTmpl
 	case common.Command == applyCommandTitle:
@@ -168,11 +271,191 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 	return m.renderTemplate(tmpl, resultData{resultsTmplData, common})
 }
 
+// summaryData is the data for the summary comment posted after all of a pull
+// request's projects are planned.
+type summaryData struct {
+	Rows            []summaryRow
+	Total           int
+	NeedApply       int
+	Errored         int
+	DisableApplyAll bool
+}
+
+// summaryRow is a project in the summary comment.
+type summaryRow struct {
+	Project string
+	Result  string
+	Changes string
+	Next    string
+}
+
+// RenderSummary renders a table of every project in the pull request with its
+// result, the changes it will make and what needs to be done next. The
+// projects in res were just planned. Those only in pullStatus are from
+// earlier commands so only their status is known.
+func (m *MarkdownRenderer) RenderSummary(res CommandResult, pullStatus models.PullStatus) string {
+	data := summaryData{DisableApplyAll: m.DisableApplyAll}
+	ran := make(map[string]bool)
+	for _, r := range res.ProjectResults {
+		ran[r.RepoRelDir+"/"+r.Workspace] = true
+		row := summaryRow{Project: summaryProjectName(r.ProjectName, r.RepoRelDir, r.Workspace)}
+		switch {
+		case r.Error != nil || r.Failure != "":
+			row.Result = ":x: Plan failed"
+			row.Next = "Fix the error and plan again"
+			data.Errored++
+		case r.PlanSuccess != nil:
+			row.Result = ":white_check_mark: Planned"
+			row.Changes = summaryChanges(r.PlanSuccess.Summary)
+			switch {
+			case res.PlansDeleted:
+				row.Next = "Plan again once the failed projects are fixed"
+			case r.PlanSuccess.PlanOnly:
+				row.Next = "Apply outside of Atlantis"
+			default:
+				row.Next = fmt.Sprintf("`%s`", r.PlanSuccess.ApplyCmd)
+				data.NeedApply++
+			}
+		default:
+			continue
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	for _, p := range pullStatus.Projects {
+		if ran[p.RepoRelDir+"/"+p.Workspace] {
+			continue
+		}
+		row := summaryRow{Project: summaryProjectName(p.ProjectName, p.RepoRelDir, p.Workspace)}
+		switch p.Status {
+		case models.ErroredPlanStatus:
+			row.Result = ":x: Plan failed"
+			row.Next = "Fix the error and plan again"
+			data.Errored++
+		case models.PlannedPlanStatus:
+			row.Result = ":white_check_mark: Planned"
+			row.Next = "Apply"
+			data.NeedApply++
+		case models.ErroredApplyStatus:
+			row.Result = ":x: Apply failed"
+			row.Next = "Fix the error and apply again"
+			data.Errored++
+		case models.AppliedPlanStatus:
+			row.Result = ":heavy_check_mark: Applied"
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	data.Total = len(data.Rows)
+	return m.renderTemplate(summaryTmpl, data)
+}
+
+// statusData is the data for the comment posted by the status command.
+type statusData struct {
+	Rows            []statusRow
+	Total           int
+	NeedPlan        int
+	NeedApply       int
+	WaitingOnLocks  int
+	DetectErr       string
+	DisableApplyAll bool
+}
+
+// statusRow is a project in the status comment.
+type statusRow struct {
+	Project string
+	Plan    string
+	Apply   string
+	Lock    string
+}
+
+// RenderStatus renders a table of every project in report with whether it's
+// been planned and applied and which pull request holds its lock.
+func (m *MarkdownRenderer) RenderStatus(report StatusReport) string {
+	data := statusData{
+		Total:           len(report.Projects),
+		NeedPlan:        report.NeedPlan(),
+		NeedApply:       report.NeedApply(),
+		WaitingOnLocks:  report.WaitingOnLocks(),
+		DisableApplyAll: m.DisableApplyAll,
+	}
+	if report.DetectErr != nil {
+		data.DetectErr = report.DetectErr.Error()
+	}
+	for _, p := range report.Projects {
+		row := statusRow{
+			Project: summaryProjectName(p.ProjectName, p.RepoRelDir, p.Workspace),
+			Plan:    "Not planned",
+		}
+		if p.Status != nil {
+			switch *p.Status {
+			case models.ErroredPlanStatus:
+				row.Plan = ":x: Failed"
+			case models.PlannedPlanStatus:
+				row.Plan = ":white_check_mark: Planned"
+				row.Apply = "Not applied"
+			case models.ErroredApplyStatus:
+				row.Plan = ":white_check_mark: Planned"
+				row.Apply = ":x: Failed"
+			case models.AppliedPlanStatus:
+				row.Plan = ":white_check_mark: Planned"
+				row.Apply = ":heavy_check_mark: Applied"
+			}
+		}
+		if p.Lock != nil {
+			if p.Lock.Pull.Num == report.PullNum {
+				row.Lock = "Held by this pull request"
+			} else {
+				row.Lock = fmt.Sprintf(":lock: Waiting on #%d by %s", p.Lock.Pull.Num, p.Lock.User.Username)
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return m.renderTemplate(statusTmpl, data)
+}
+
+// summaryProjectName identifies a project in the summary comment.
+func summaryProjectName(projectName string, repoRelDir string, workspace string) string {
+	if projectName != "" {
+		return fmt.Sprintf("project: `%s` dir: `%s` workspace: `%s`", projectName, repoRelDir, workspace)
+	}
+	return fmt.Sprintf("dir: `%s` workspace: `%s`", repoRelDir, workspace)
+}
+
+// shortProjectName names a project on a single line. The workspace is only
+// included if it isn't the default.
+func shortProjectName(projectName string, repoRelDir string, workspace string) string {
+	name := fmt.Sprintf("`%s`", repoRelDir)
+	if projectName != "" {
+		name = fmt.Sprintf("`%s`", projectName)
+	}
+	if workspace != DefaultWorkspace {
+		name += fmt.Sprintf(" (workspace `%s`)", workspace)
+	}
+	return name
+}
+
+// summaryChanges counts the changes in a plan for the summary comment.
+func summaryChanges(summary *models.PlanSummary) string {
+	if summary == nil {
+		return "Unknown"
+	}
+	if !summary.HasChanges() {
+		return "No changes"
+	}
+	return fmt.Sprintf("%d to add, %d to change, %d to replace, %d to destroy",
+		len(summary.Creates), len(summary.Updates), len(summary.Replaces), len(summary.Deletes))
+}
+
 // shouldUseWrappedTmpl returns true if we should use the wrapped markdown
 // templates that collapse the output to make the comment smaller on initial
 // load. Some VCS providers or versions of VCS providers don't support this
 // syntax.
 func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, output string) bool {
+	return m.supportsFolding(vcsHost) && strings.Count(output, "\n") > maxUnwrappedLines
+}
+
+// supportsFolding returns true if the collapsible <details> markdown syntax
+// can be used for vcsHost.
+func (m *MarkdownRenderer) supportsFolding(vcsHost models.VCSHostType) bool {
 	if m.DisableMarkdownFolding {
 		return false
 	}
@@ -185,8 +468,185 @@ func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, outp
 	if vcsHost == models.Gitlab && !m.GitlabSupportsCommonMark {
 		return false
 	}
+	return true
+}
 
-	return strings.Count(output, "\n") > maxUnwrappedLines
+// SplitComment splits comment into multiple comments if it's longer than the
+// maximum comment length for vcsHost. Comments are split on line boundaries.
+// If a split happens inside a code block or a collapsible section, the block
+// is closed at the end of the comment and re-opened at the start of the next
+// one so that each comment renders properly on its own. Each comment is
+// numbered so users can tell they're continuations.
+func (m *MarkdownRenderer) SplitComment(comment string, vcsHost models.VCSHostType) []string {
+	return m.splitComment(comment, vcsHost, 0)
+}
+
+// SplitCommentWithMetadata splits comment like SplitComment and appends meta
+// to each part as a hidden HTML comment. See CommentMetadata.
+func (m *MarkdownRenderer) SplitCommentWithMetadata(comment string, vcsHost models.VCSHostType, meta CommentMetadata) []string {
+	// Reserve room for the metadata with the longest part numbers it could
+	// have.
+	longest := meta
+	longest.Part = len(comment)
+	longest.Parts = len(comment)
+	parts := m.splitComment(comment, vcsHost, len(longest.render())+2)
+	for i := range parts {
+		if len(parts) > 1 {
+			meta.Part = i + 1
+			meta.Parts = len(parts)
+		}
+		parts[i] += "\n\n" + meta.render()
+	}
+	return parts
+}
+
+// splitComment splits comment so each part, plus reserve chars, fits in a
+// comment on vcsHost.
+func (m *MarkdownRenderer) splitComment(comment string, vcsHost models.VCSHostType, reserve int) []string {
+	maxSize, ok := maxCommentLengths[vcsHost]
+	if !ok || len(comment)+reserve <= maxSize {
+		return []string{comment}
+	}
+	// Leave room for the continuation header and footer.
+	maxSize -= continuationReserve + reserve
+
+	var lines []string
+	for _, line := range strings.SplitAfter(comment, "\n") {
+		// Lines that couldn't fit in a comment even on their own are split
+		// into pieces.
+		for len(line) > maxSize/2 {
+			lines = append(lines, line[:maxSize/2])
+			line = line[maxSize/2:]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var parts []string
+	var curr strings.Builder
+	var state markdownBlockState
+	for _, line := range lines {
+		next := state.after(line)
+		if curr.Len() > 0 && curr.Len()+len(line)+len(next.closing())+1 > maxSize {
+			part := curr.String()
+			if !strings.HasSuffix(part, "\n") {
+				part += "\n"
+			}
+			parts = append(parts, part+state.closing())
+			curr.Reset()
+			curr.WriteString(state.opening())
+		}
+		curr.WriteString(line)
+		state = next
+	}
+	parts = append(parts, curr.String())
+
+	for i := range parts {
+		if i > 0 {
+			parts[i] = fmt.Sprintf("Continued from previous comment (part %d of %d).\n\n", i+1, len(parts)) + parts[i]
+		}
+		if i < len(parts)-1 {
+			parts[i] += fmt.Sprintf("\n**Warning**: Output length greater than max comment size. Continued in next comment (part %d of %d).", i+2, len(parts))
+		}
+	}
+	return parts
+}
+
+// markdownBlockState tracks the code block and collapsible sections that are
+// open at a given line of a markdown comment.
+type markdownBlockState struct {
+	// fence is the line that opened the current code block, ex. "```diff\n",
+	// or empty if we're not in a code block.
+	fence string
+	// details are the lines that opened the currently open collapsible
+	// sections, outermost first.
+	details []string
+}
+
+// after returns the state after line.
+func (s markdownBlockState) after(line string) markdownBlockState {
+	if strings.HasPrefix(line, "```") {
+		if s.fence == "" {
+			s.fence = line
+		} else {
+			s.fence = ""
+		}
+		return s
+	}
+	if s.fence != "" {
+		return s
+	}
+	if strings.Contains(line, "<details>") {
+		s.details = append(append([]string{}, s.details...), line)
+	}
+	if strings.Contains(line, "</details>") && len(s.details) > 0 {
+		s.details = s.details[:len(s.details)-1]
+	}
+	return s
+}
+
+// closing returns the markdown needed to close all open blocks.
+func (s markdownBlockState) closing() string {
+	var closing string
+	if s.fence != "" {
+		closing += "```\n"
+	}
+	for range s.details {
+		closing += "</details>\n"
+	}
+	return closing
+}
+
+// opening returns the markdown needed to re-open all open blocks.
+func (s markdownBlockState) opening() string {
+	var opening string
+	for _, d := range s.details {
+		opening += d + "\n"
+	}
+	if s.fence != "" {
+		opening += s.fence
+	}
+	return opening
+}
+
+// splitPlanByResource splits the plan output into a section per resource so
+// each resource can be rendered in its own collapsible section. If the output
+// doesn't contain any resources it returns nil.
+func splitPlanByResource(output string) []planSection {
+	var sections []planSection
+	curr := planSection{}
+	foundResource := false
+	for _, line := range strings.Split(output, "\n") {
+		match := resourceHeaderRegex.FindStringSubmatch(line)
+		switch {
+		case match != nil:
+			foundResource = true
+			sections = appendPlanSection(sections, curr)
+			curr = planSection{Name: match[1], Body: line}
+		case curr.Name != "" && strings.HasPrefix(line, "Plan:"):
+			// The summary line ends the last resource.
+			sections = appendPlanSection(sections, curr)
+			curr = planSection{Body: line}
+		case curr.Body == "" && curr.Name == "":
+			curr.Body = line
+		default:
+			curr.Body += "\n" + line
+		}
+	}
+	if !foundResource {
+		return nil
+	}
+	return appendPlanSection(sections, curr)
+}
+
+// appendPlanSection appends s to sections unless it's empty.
+func appendPlanSection(sections []planSection, s planSection) []planSection {
+	s.Body = strings.Trim(s.Body, "\n")
+	if s.Body == "" {
+		return sections
+	}
+	return append(sections, s)
 }
 
 func (m *MarkdownRenderer) renderTemplate(tmpl *template.Template, data interface{}) string {
@@ -209,6 +669,8 @@ var singleProjectPlanSuccessTmpl = template.Must(template.New("").Parse(
 var singleProjectPlanUnsuccessfulTmpl = template.Must(template.New("").Parse(
 	"{{$result := index .Results 0}}Ran {{.Command}} for dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n" +
 		"{{$result.Rendered}}\n" + logTmpl))
+var singleProjectDiffTmpl = template.Must(template.New("").Parse(
+	"{{$result := index .Results 0}}Ran {{.Command}} for {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspaces: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" + logTmpl))
 var multiProjectPlanTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
 	"Ran {{.Command}} for {{ len .Results }} projects:\n\n" +
 		"{{ range $result := .Results }}" +
@@ -245,13 +707,87 @@ var planSuccessWrappedTmpl = template.Must(template.New("").Parse(
 		"</details>" +
 		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
 
+var planSuccessResourcesTmpl = template.Must(template.New("").Parse(
+	"<details><summary>Show Output</summary>\n\n" +
+		"{{ range .Sections }}{{ if .Name }}<details><summary>{{ .Name }}</summary>\n\n" +
+		"```diff\n" +
+		"{{ .Body }}\n" +
+		"```\n" +
+		"</details>\n\n" +
+		"{{ else }}```diff\n" +
+		"{{ .Body }}\n" +
+		"```\n\n" +
+		"{{ end }}{{ end }}" +
+		planNextSteps + "\n" +
+		"</details>" +
+		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+
+var planSummaryUnwrappedTmpl = template.Must(template.New("").Parse(
+	planSummary +
+		"```diff\n" +
+		"{{.TerraformOutput}}\n" +
+		"```\n\n" + planNextSteps +
+		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+
+var planSummaryWrappedTmpl = template.Must(template.New("").Parse(
+	planSummary +
+		"<details><summary>Show Output</summary>\n\n" +
+		"```diff\n" +
+		"{{.TerraformOutput}}\n" +
+		"```\n" +
+		"</details>\n\n" +
+		planNextSteps +
+		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+
+// planSummary renders the structured plan summary as a diff so resources are
+// colored by the action that will be taken on them.
+var planSummary = "{{ with .Summary }}{{ if .HasChanges }}" +
+	"**Plan:** {{ len .Creates }} to add, {{ len .Updates }} to change, {{ len .Replaces }} to replace, {{ len .Deletes }} to destroy.\n\n" +
+	"```diff\n" +
+	"{{ range .Creates }}+ {{ . }}\n{{ end }}" +
+	"{{ range .Updates }}! {{ . }}\n{{ end }}" +
+	"{{ range .Replaces }}-/+ {{ . }}\n{{ end }}" +
+	"{{ range .Deletes }}- {{ . }}\n{{ end }}" +
+	"```\n\n" +
+	"{{ else }}**Plan:** No changes.\n\n{{ end }}{{ end }}"
+
 // planNextSteps are instructions appended after successful plans as to what
-// to do next.
-var planNextSteps = "{{ if .PlanWasDeleted }}This plan was not saved because one or more projects failed and automerge requires all plans pass.{{ else }}* :arrow_forward: To **apply** this plan, comment:\n" +
-	"    * `{{.ApplyCmd}}`\n" +
-	"* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n" +
+// to do next. If the plan's output was truncated, they start with a link to
+// the full output.
+var planNextSteps = "{{ if .FullOutputURL }}:scissors: This plan's output was too long so parts of it are hidden. View the full output [here]({{.FullOutputURL}}).\n\n{{ end }}" +
+	"{{ if .Destroy }}:warning: This is a **destroy** plan. Applying it destroys every resource in this project so it must be confirmed with `--confirm-destroy`.\n\n{{ end }}" +
+	"{{ with .GuardrailViolations }}:no_entry: This plan exceeds the project's guardrails so it can't be applied without `--override-guardrails`:\n{{ range . }}* {{ . }}\n{{ end }}\n{{ end }}" +
+	"{{ if .VarFile }}:page_facing_up: Planned with variables from `{{.VarFile}}`.\n\n{{ end }}" +
+	"{{ if .PlanWasDeleted }}This plan was not saved because one or more projects failed and automerge requires all plans pass.{{ else }}{{ if .PlanOnly }}* :no_entry_sign: This project is plan only so it must be applied outside of Atlantis.\n{{ else }}* :arrow_forward: To **apply** this plan, comment:\n" +
+	"    * `{{.ApplyCmd}}`\n{{ end }}" +
+	"{{ if .LockURL }}* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n{{ end }}" +
 	"* :repeat: To **plan** this project again, comment:\n" +
 	"    * `{{.RePlanCmd}}`{{end}}"
+
+// diffSuccessTmpl renders a table of the resources the plans take different
+// actions on, followed by the output of each plan.
+var diffSuccessTmpl = template.Must(template.New("").Parse(
+	"{{ $plans := .Plans }}{{ if .Summarized }}{{ with .Differences }}" +
+		"**Differences:**\n\n" +
+		"| Resource |{{ range $plans }} `{{ .Workspace }}` |{{ end }}\n" +
+		"|---|{{ range $plans }}---|{{ end }}\n" +
+		"{{ range . }}| `{{ .Address }}` |{{ range .Actions }} {{ if . }}{{ . }}{{ else }}no change{{ end }} |{{ end }}\n{{ end }}\n" +
+		"{{ else }}**Differences:** None, the plans make the same changes in each workspace.\n\n{{ end }}" +
+		"{{ else }}**Differences:** Unknown, the plans couldn't be summarized. Compare their output below.\n\n{{ end }}" +
+		"{{ range $plans }}{{ if $.Fold }}<details><summary>Show Output for `{{ .Workspace }}`</summary>\n\n" +
+		"```diff\n" +
+		"{{ .TerraformOutput }}\n" +
+		"```\n" +
+		"</details>\n\n" +
+		"{{ else }}**Output for `{{ .Workspace }}`:**\n" +
+		"```diff\n" +
+		"{{ .TerraformOutput }}\n" +
+		"```\n\n" +
+		"{{ end }}{{ end }}" +
+		"These plans were only made to be compared so they can't be applied."))
+
+// customNoOutput is rendered for custom commands that succeed without output.
+var customNoOutput = "Succeeded with no output."
 var applyUnwrappedSuccessTmpl = template.Must(template.New("").Parse(
 	"```diff\n" +
 		"{{.Output}}\n" +
@@ -277,4 +813,34 @@ var wrappedErrTmpl = template.Must(template.New("").Parse(wrappedErrTmplText))
 var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}"
 var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
 var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
+var noChangesTmpl = template.Must(template.New("").Parse(
+	"\n**No changes** in {{ len . }} {{ if eq (len .) 1 }}project{{ else }}projects{{ end }}: {{ range $i, $n := . }}{{ if $i }}, {{ end }}{{ $n }}{{ end }}\n"))
+var keptPlansTmpl = template.Must(template.New("").Parse(
+	"\n**Kept plans** of {{ len . }} {{ if eq (len .) 1 }}project{{ else }}projects{{ end }} the new commits didn't modify: {{ range $i, $n := . }}{{ if $i }}, {{ end }}{{ $n }}{{ end }}\n"))
+var reusedPlansTmpl = template.Must(template.New("").Parse(
+	"\n**Reused plans** of {{ len . }} {{ if eq (len .) 1 }}project{{ else }}projects{{ end }} that nothing changed in since they were planned: {{ range $i, $n := . }}{{ if $i }}, {{ end }}{{ $n }}{{ end }}\n"))
+var suggestedReviewersTmpl = template.Must(template.New("").Parse(
+	"\n**Suggested reviewers** based on the projects in this pull request: {{ range $i, $r := . }}{{ if $i }}, {{ end }}@{{ $r }}{{ end }}\n"))
+var statusTmpl = template.Must(template.New("").Parse(
+	"### Pull Request Status\n\n" +
+		"{{ if .DetectErr }}:warning: Couldn't determine which projects plan would be run for so only the projects planned by earlier commands are listed: `{{ .DetectErr }}`\n\n{{ end }}" +
+		"{{ if .Rows }}| Project | Plan | Apply | Lock |\n" +
+		"|---------|------|-------|------|\n" +
+		"{{ range .Rows }}| {{ .Project }} | {{ .Plan }} | {{ .Apply }} | {{ .Lock }} |\n{{ end }}\n" +
+		"{{ if .NeedPlan }}:arrow_forward: **{{ .NeedPlan }} of {{ .Total }} projects need to be planned.** To plan them, comment `atlantis plan`.\n{{ end }}" +
+		"{{ if .NeedApply }}:arrow_forward: **{{ .NeedApply }} of {{ .Total }} projects need to be applied.**" +
+		"{{ if not .DisableApplyAll }} To apply them all, comment `atlantis apply`.{{ end }}\n{{ end }}" +
+		"{{ if .WaitingOnLocks }}:lock: **{{ .WaitingOnLocks }} of {{ .Total }} projects are locked by other pull requests.** They can't be planned until those pull requests are merged or their locks are deleted.\n{{ end }}" +
+		"{{ if not (or .NeedPlan .NeedApply) }}:white_check_mark: **All projects have been applied.**\n{{ end }}" +
+		"{{ else }}No projects were found in this pull request.\n{{ end }}"))
+
+var summaryTmpl = template.Must(template.New("").Parse(
+	"### Plan Summary\n\n" +
+		"| Project | Result | Changes | Next |\n" +
+		"|---------|--------|---------|------|\n" +
+		"{{ range .Rows }}| {{ .Project }} | {{ .Result }} | {{ .Changes }} | {{ .Next }} |\n{{ end }}\n" +
+		"{{ if .Errored }}:x: **{{ .Errored }} of {{ .Total }} projects failed.** Fix the errors and comment `atlantis plan` to plan them again.\n{{ end }}" +
+		"{{ if .NeedApply }}:arrow_forward: **{{ .NeedApply }} of {{ .Total }} projects need to be applied.**" +
+		"{{ if not .DisableApplyAll }} To apply them all, comment `atlantis apply`.{{ end }}\n" +
+		"{{ else if not .Errored }}:white_check_mark: **No projects need to be applied.**\n{{ end }}"))
 var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
@@ -156,6 +156,102 @@ $$$
 * :repeat: To **plan** this project again, comment:
     * $atlantis plan -d path -w workspace$
 
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`,
+		},
+		{
+			"single successful plan without a lock",
+			models.PlanCommand,
+			[]models.ProjectResult{
+				{
+					PlanSuccess: &models.PlanSuccess{
+						TerraformOutput: "terraform-output",
+						RePlanCmd:       "atlantis plan -d path -w workspace",
+						ApplyCmd:        "atlantis apply -d path -w workspace",
+					},
+					Workspace:  "workspace",
+					RepoRelDir: "path",
+				},
+			},
+			models.Github,
+			`Ran Plan for dir: $path$ workspace: $workspace$
+
+$$$diff
+terraform-output
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d path -w workspace$
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d path -w workspace$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`,
+		},
+		{
+			"single successful plan with truncated output",
+			models.PlanCommand,
+			[]models.ProjectResult{
+				{
+					PlanSuccess: &models.PlanSuccess{
+						TerraformOutput: "terraform-output",
+						FullOutputURL:   "full-output-url",
+						RePlanCmd:       "atlantis plan -d path -w workspace",
+						ApplyCmd:        "atlantis apply -d path -w workspace",
+					},
+					Workspace:  "workspace",
+					RepoRelDir: "path",
+				},
+			},
+			models.Github,
+			`Ran Plan for dir: $path$ workspace: $workspace$
+
+$$$diff
+terraform-output
+$$$
+
+:scissors: This plan's output was too long so parts of it are hidden. View the full output [here](full-output-url).
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d path -w workspace$
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d path -w workspace$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`,
+		},
+		{
+			"single successful plan only plan",
+			models.PlanCommand,
+			[]models.ProjectResult{
+				{
+					PlanSuccess: &models.PlanSuccess{
+						TerraformOutput: "terraform-output",
+						RePlanCmd:       "atlantis plan -d path -w workspace",
+						ApplyCmd:        "atlantis apply -d path -w workspace",
+						PlanOnly:        true,
+					},
+					Workspace:  "workspace",
+					RepoRelDir: "path",
+				},
+			},
+			models.Github,
+			`Ran Plan for dir: $path$ workspace: $workspace$
+
+$$$diff
+terraform-output
+$$$
+
+* :no_entry_sign: This project is plan only so it must be applied outside of Atlantis.
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d path -w workspace$
+
 ---
 * :fast_forward: To **apply** all unapplied plans from this pull request, comment:
     * $atlantis apply$
@@ -1254,3 +1350,670 @@ This plan was not saved because one or more projects failed and automerge requir
 		})
 	}
 }
+
+// Test that large plans are rendered with a collapsible section per resource.
+func TestRenderProjectResults_PlanResourceSections(t *testing.T) {
+	output := `An execution plan has been generated and is shown below.
+Resource actions are indicated with the following symbols:
+  + create
+  - destroy
+
+Terraform will perform the following actions:
+
+  # null_resource.a will be created
+  + resource "null_resource" "a" {
+      + id = (known after apply)
+    }
+
+  # module.mod.null_resource.b[0] must be replaced
+-/+ resource "null_resource" "b" {
+      ~ id = "123" -> (known after apply)
+    }
+
+Plan: 2 to add, 0 to change, 1 to destroy.`
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: output,
+					LockURL:         "lock-url",
+					RePlanCmd:       "atlantis plan -d .",
+					ApplyCmd:        "atlantis apply -d .",
+				},
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+<details><summary>Show Output</summary>
+
+$$$diff
+An execution plan has been generated and is shown below.
+Resource actions are indicated with the following symbols:
+  + create
+  - destroy
+
+Terraform will perform the following actions:
+$$$
+
+<details><summary>null_resource.a will be created</summary>
+
+$$$diff
+  # null_resource.a will be created
+  + resource "null_resource" "a" {
+      + id = (known after apply)
+    }
+$$$
+</details>
+
+<details><summary>module.mod.null_resource.b[0] must be replaced</summary>
+
+$$$diff
+  # module.mod.null_resource.b[0] must be replaced
+-/+ resource "null_resource" "b" {
+      ~ id = "123" -> (known after apply)
+    }
+$$$
+</details>
+
+$$$diff
+Plan: 2 to add, 0 to change, 1 to destroy.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d .$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d .$
+</details>
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_PlanSummary(t *testing.T) {
+	cases := []struct {
+		Description string
+		VCSHost     models.VCSHostType
+		Summary     models.PlanSummary
+		Exp         string
+	}{
+		{
+			"folding supported",
+			models.Github,
+			models.PlanSummary{
+				Creates:  []string{"null_resource.a"},
+				Updates:  []string{"null_resource.b"},
+				Replaces: []string{"module.mod.null_resource.c[0]"},
+				Deletes:  []string{"null_resource.d", "null_resource.e"},
+			},
+			`Ran Plan for dir: $.$ workspace: $default$
+
+**Plan:** 1 to add, 1 to change, 1 to replace, 2 to destroy.
+
+$$$diff
++ null_resource.a
+! null_resource.b
+-/+ module.mod.null_resource.c[0]
+- null_resource.d
+- null_resource.e
+$$$
+
+<details><summary>Show Output</summary>
+
+$$$diff
+terraform-output
+$$$
+</details>
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d .$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d .$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`,
+		},
+		{
+			"folding not supported",
+			models.BitbucketCloud,
+			models.PlanSummary{},
+			`Ran Plan for dir: $.$ workspace: $default$
+
+**Plan:** No changes.
+
+$$$diff
+terraform-output
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d .$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d .$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			summary := c.Summary
+			mr := events.MarkdownRenderer{}
+			rendered := mr.Render(events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{
+						RepoRelDir: ".",
+						Workspace:  "default",
+						PlanSuccess: &models.PlanSuccess{
+							TerraformOutput: "terraform-output",
+							LockURL:         "lock-url",
+							RePlanCmd:       "atlantis plan -d .",
+							ApplyCmd:        "atlantis apply -d .",
+							Summary:         &summary,
+						},
+					},
+				},
+			}, models.PlanCommand, "log", false, c.VCSHost)
+			Equals(t, strings.Replace(c.Exp, "$", "`", -1), rendered)
+		})
+	}
+}
+
+func TestRenderProjectResults_Diff(t *testing.T) {
+	cases := []struct {
+		Description string
+		VCSHost     models.VCSHostType
+		Diff        models.DiffSuccess
+		Exp         string
+	}{
+		{
+			"differences with folding",
+			models.Github,
+			models.DiffSuccess{
+				Plans: []models.WorkspacePlan{
+					{
+						Workspace:       "staging",
+						TerraformOutput: "staging-output",
+						Summary:         &models.PlanSummary{Creates: []string{"null_resource.a"}, Updates: []string{"null_resource.b"}},
+					},
+					{
+						Workspace:       "prod",
+						TerraformOutput: "prod-output",
+						Summary:         &models.PlanSummary{Updates: []string{"null_resource.b"}},
+					},
+				},
+			},
+			`Ran Diff for project: $proj$ dir: $.$ workspaces: $staging,prod$
+
+**Differences:**
+
+| Resource | $staging$ | $prod$ |
+|---|---|---|
+| $null_resource.a$ | create | no change |
+
+<details><summary>Show Output for $staging$</summary>
+
+$$$diff
+staging-output
+$$$
+</details>
+
+<details><summary>Show Output for $prod$</summary>
+
+$$$diff
+prod-output
+$$$
+</details>
+
+These plans were only made to be compared so they can't be applied.
+
+`,
+		},
+		{
+			"no differences without folding",
+			models.BitbucketCloud,
+			models.DiffSuccess{
+				Plans: []models.WorkspacePlan{
+					{Workspace: "staging", TerraformOutput: "staging-output", Summary: &models.PlanSummary{}},
+					{Workspace: "prod", TerraformOutput: "prod-output", Summary: &models.PlanSummary{}},
+				},
+			},
+			`Ran Diff for project: $proj$ dir: $.$ workspaces: $staging,prod$
+
+**Differences:** None, the plans make the same changes in each workspace.
+
+**Output for $staging$:**
+$$$diff
+staging-output
+$$$
+
+**Output for $prod$:**
+$$$diff
+prod-output
+$$$
+
+These plans were only made to be compared so they can't be applied.
+
+`,
+		},
+		{
+			"not summarized",
+			models.BitbucketCloud,
+			models.DiffSuccess{
+				Plans: []models.WorkspacePlan{
+					{Workspace: "staging", TerraformOutput: "staging-output"},
+					{Workspace: "prod", TerraformOutput: "prod-output"},
+				},
+			},
+			`Ran Diff for project: $proj$ dir: $.$ workspaces: $staging,prod$
+
+**Differences:** Unknown, the plans couldn't be summarized. Compare their output below.
+
+**Output for $staging$:**
+$$$diff
+staging-output
+$$$
+
+**Output for $prod$:**
+$$$diff
+prod-output
+$$$
+
+These plans were only made to be compared so they can't be applied.
+
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			diff := c.Diff
+			mr := events.MarkdownRenderer{}
+			rendered := mr.Render(events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{
+						RepoRelDir:  ".",
+						Workspace:   "staging,prod",
+						ProjectName: "proj",
+						DiffSuccess: &diff,
+					},
+				},
+			}, models.DiffCommand, "log", false, c.VCSHost)
+			Equals(t, strings.Replace(c.Exp, "$", "`", -1), rendered)
+		})
+	}
+}
+
+func TestRenderProjectResults_CustomCommand(t *testing.T) {
+	cases := []struct {
+		Description string
+		Results     []models.ProjectResult
+		Exp         string
+	}{
+		{
+			"single project",
+			[]models.ProjectResult{
+				{
+					Command:      models.CustomCommand,
+					RepoRelDir:   ".",
+					Workspace:    "default",
+					CustomOutput: "no issues",
+				},
+			},
+			`Ran Lint for dir: $.$ workspace: $default$
+
+$$$diff
+no issues
+$$$
+
+`,
+		},
+		{
+			"no output",
+			[]models.ProjectResult{
+				{
+					Command:    models.CustomCommand,
+					RepoRelDir: ".",
+					Workspace:  "default",
+				},
+			},
+			`Ran Lint for dir: $.$ workspace: $default$
+
+Succeeded with no output.
+
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			mr := events.MarkdownRenderer{}
+			rendered := mr.Render(events.CommandResult{
+				ProjectResults: c.Results,
+				CustomCommand:  "lint",
+			}, models.CustomCommand, "", false, models.Github)
+			Equals(t, strings.Replace(c.Exp, "$", "`", -1), rendered)
+		})
+	}
+}
+
+func TestRenderProjectResults_SuggestedReviewers(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					RePlanCmd:       "atlantis plan -d .",
+					ApplyCmd:        "atlantis apply -d .",
+				},
+			},
+		},
+		SuggestedReviewers: []string{"alice", "org/team"},
+	}, models.PlanCommand, "log", false, models.Github)
+	Assert(t, strings.HasSuffix(rendered, "\n**Suggested reviewers** based on the projects in this pull request: @alice, @org/team\n"), "unexpected rendering %q", rendered)
+}
+
+func TestRenderProjectResults_NoChanges(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	noChanges := []models.ProjectResult{
+		{
+			RepoRelDir:  "dir1",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes."},
+		},
+		{
+			RepoRelDir:  "dir2",
+			Workspace:   "staging",
+			ProjectName: "proj2",
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes."},
+		},
+	}
+
+	t.Log("if every result is hidden nothing should be rendered")
+	rendered := mr.Render(events.CommandResult{NoChangesResults: noChanges}, models.PlanCommand, "log", false, models.Github)
+	Equals(t, "", rendered)
+
+	t.Log("if they're summarized they should be listed on one line")
+	rendered = mr.Render(events.CommandResult{NoChangesResults: noChanges, SummarizeNoChanges: true}, models.PlanCommand, "log", false, models.Github)
+	Equals(t, "\n**No changes** in 2 projects: `dir1`, `proj2` (workspace `staging`)\n", rendered)
+}
+
+func TestRenderProjectResults_GuardrailViolations(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: "dir1",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput:     "Plan: 0 to add, 0 to change, 1 to destroy.",
+					ApplyCmd:            "atlantis apply -d dir1",
+					RePlanCmd:           "atlantis plan -d dir1",
+					GuardrailViolations: []string{"Deletes 1 resources, more than the 0 allowed."},
+				},
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	Assert(t, strings.Contains(rendered, ":no_entry: This plan exceeds the project's guardrails so it can't be applied without `--override-guardrails`:\n* Deletes 1 resources, more than the 0 allowed.\n\n"), "exp guardrail violations, got %s", rendered)
+}
+
+func TestRenderProjectResults_KeptPlans(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		KeptPlans: []models.ProjectStatus{
+			{RepoRelDir: "dir1", Workspace: "default", Status: models.PlannedPlanStatus},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	Equals(t, "\n**Kept plans** of 1 project the new commits didn't modify: `dir1`\n", rendered)
+}
+
+func TestRenderProjectResults_ReusedPlans(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ReusedPlans: []models.ProjectStatus{
+			{RepoRelDir: "dir1", Workspace: "default", Status: models.PlannedPlanStatus},
+			{RepoRelDir: "dir2", Workspace: "staging", ProjectName: "proj2", Status: models.PlannedPlanStatus},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	Equals(t, "\n**Reused plans** of 2 projects that nothing changed in since they were planned: `dir1`, `proj2` (workspace `staging`)\n", rendered)
+}
+
+func TestRenderSummary(t *testing.T) {
+	cases := []struct {
+		Description     string
+		Res             events.CommandResult
+		PullStatus      models.PullStatus
+		DisableApplyAll bool
+		Exp             string
+	}{
+		{
+			"projects from earlier commands",
+			events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{
+						RepoRelDir: "dir1",
+						Workspace:  "default",
+						PlanSuccess: &models.PlanSuccess{
+							ApplyCmd: "atlantis apply -d dir1",
+							Summary:  &models.PlanSummary{},
+						},
+					},
+					{
+						RepoRelDir:  "dir2",
+						Workspace:   "default",
+						PlanSuccess: &models.PlanSuccess{PlanOnly: true},
+					},
+				},
+			},
+			models.PullStatus{
+				Projects: []models.ProjectStatus{
+					{RepoRelDir: "dir1", Workspace: "default", Status: models.PlannedPlanStatus},
+					{RepoRelDir: "dir2", Workspace: "default", Status: models.PlannedPlanStatus},
+					{RepoRelDir: "dir3", Workspace: "default", ProjectName: "proj3", Status: models.AppliedPlanStatus},
+					{RepoRelDir: "dir4", Workspace: "staging", Status: models.PlannedPlanStatus},
+					{RepoRelDir: "dir5", Workspace: "default", Status: models.ErroredApplyStatus},
+				},
+			},
+			true,
+			`### Plan Summary
+
+| Project | Result | Changes | Next |
+|---------|--------|---------|------|
+| dir: $dir1$ workspace: $default$ | :white_check_mark: Planned | No changes | $atlantis apply -d dir1$ |
+| dir: $dir2$ workspace: $default$ | :white_check_mark: Planned | Unknown | Apply outside of Atlantis |
+| project: $proj3$ dir: $dir3$ workspace: $default$ | :heavy_check_mark: Applied |  |  |
+| dir: $dir4$ workspace: $staging$ | :white_check_mark: Planned |  | Apply |
+| dir: $dir5$ workspace: $default$ | :x: Apply failed |  | Fix the error and apply again |
+
+:x: **1 of 5 projects failed.** Fix the errors and comment $atlantis plan$ to plan them again.
+:arrow_forward: **2 of 5 projects need to be applied.**
+`,
+		},
+		{
+			"plans deleted",
+			events.CommandResult{
+				ProjectResults: []models.ProjectResult{
+					{
+						RepoRelDir:  "dir1",
+						Workspace:   "default",
+						PlanSuccess: &models.PlanSuccess{ApplyCmd: "atlantis apply -d dir1"},
+					},
+					{
+						RepoRelDir: "dir2",
+						Workspace:  "default",
+						Failure:    "locked",
+					},
+				},
+				PlansDeleted: true,
+			},
+			models.PullStatus{},
+			false,
+			`### Plan Summary
+
+| Project | Result | Changes | Next |
+|---------|--------|---------|------|
+| dir: $dir1$ workspace: $default$ | :white_check_mark: Planned | Unknown | Plan again once the failed projects are fixed |
+| dir: $dir2$ workspace: $default$ | :x: Plan failed |  | Fix the error and plan again |
+
+:x: **1 of 2 projects failed.** Fix the errors and comment $atlantis plan$ to plan them again.
+`,
+		},
+		{
+			"nothing to apply",
+			events.CommandResult{},
+			models.PullStatus{
+				Projects: []models.ProjectStatus{
+					{RepoRelDir: "dir1", Workspace: "default", Status: models.AppliedPlanStatus},
+					{RepoRelDir: "dir2", Workspace: "default", Status: models.AppliedPlanStatus},
+				},
+			},
+			false,
+			`### Plan Summary
+
+| Project | Result | Changes | Next |
+|---------|--------|---------|------|
+| dir: $dir1$ workspace: $default$ | :heavy_check_mark: Applied |  |  |
+| dir: $dir2$ workspace: $default$ | :heavy_check_mark: Applied |  |  |
+
+:white_check_mark: **No projects need to be applied.**
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			mr := events.MarkdownRenderer{DisableApplyAll: c.DisableApplyAll}
+			rendered := mr.RenderSummary(c.Res, c.PullStatus)
+			Equals(t, strings.Replace(c.Exp, "$", "`", -1), rendered)
+		})
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	planned := models.PlannedPlanStatus
+	applied := models.AppliedPlanStatus
+	errored := models.ErroredPlanStatus
+	cases := []struct {
+		Description string
+		Report      events.StatusReport
+		Exp         string
+	}{
+		{
+			"projects in each state",
+			events.StatusReport{
+				PullNum: 1,
+				Projects: []events.ProjectStatusReport{
+					{RepoRelDir: "dir1", Workspace: "default"},
+					{RepoRelDir: "dir2", Workspace: "default", Status: &planned, Lock: &models.ProjectLock{Pull: models.PullRequest{Num: 1}}},
+					{RepoRelDir: "dir3", Workspace: "default", ProjectName: "proj3", Status: &applied},
+					{RepoRelDir: "dir4", Workspace: "staging", Status: &errored},
+					{RepoRelDir: "dir5", Workspace: "default", Lock: &models.ProjectLock{Pull: models.PullRequest{Num: 2}, User: models.User{Username: "alice"}}},
+				},
+			},
+			`### Pull Request Status
+
+| Project | Plan | Apply | Lock |
+|---------|------|-------|------|
+| dir: $dir1$ workspace: $default$ | Not planned |  |  |
+| dir: $dir2$ workspace: $default$ | :white_check_mark: Planned | Not applied | Held by this pull request |
+| project: $proj3$ dir: $dir3$ workspace: $default$ | :white_check_mark: Planned | :heavy_check_mark: Applied |  |
+| dir: $dir4$ workspace: $staging$ | :x: Failed |  |  |
+| dir: $dir5$ workspace: $default$ | Not planned |  | :lock: Waiting on #2 by alice |
+
+:arrow_forward: **3 of 5 projects need to be planned.** To plan them, comment $atlantis plan$.
+:arrow_forward: **1 of 5 projects need to be applied.** To apply them all, comment $atlantis apply$.
+:lock: **1 of 5 projects are locked by other pull requests.** They can't be planned until those pull requests are merged or their locks are deleted.
+`,
+		},
+		{
+			"all applied",
+			events.StatusReport{
+				Projects: []events.ProjectStatusReport{
+					{RepoRelDir: "dir1", Workspace: "default", Status: &applied},
+				},
+			},
+			`### Pull Request Status
+
+| Project | Plan | Apply | Lock |
+|---------|------|-------|------|
+| dir: $dir1$ workspace: $default$ | :white_check_mark: Planned | :heavy_check_mark: Applied |  |
+
+:white_check_mark: **All projects have been applied.**
+`,
+		},
+		{
+			"projects not detected",
+			events.StatusReport{
+				DetectErr: errors.New("workspace is locked"),
+			},
+			`### Pull Request Status
+
+:warning: Couldn't determine which projects plan would be run for so only the projects planned by earlier commands are listed: $workspace is locked$
+
+No projects were found in this pull request.
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			mr := events.MarkdownRenderer{}
+			Equals(t, strings.Replace(c.Exp, "$", "`", -1), mr.RenderStatus(c.Report))
+		})
+	}
+}
+
+func TestSplitComment_UnderMax(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	comment := strings.Repeat("line\n", 100)
+	Equals(t, []string{comment}, mr.SplitComment(comment, models.Github))
+}
+
+func TestSplitComment_NoLimit(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	comment := strings.Repeat("line\n", 100000)
+	Equals(t, []string{comment}, mr.SplitComment(comment, models.BitbucketCloud))
+}
+
+// Test that when we split a comment, code blocks and collapsible sections
+// are closed and re-opened and that each comment fits.
+func TestSplitComment_ClosesAndReopensBlocks(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	comment := "Ran Plan\n\n<details><summary>Show Output</summary>\n\n```diff\n" +
+		strings.Repeat("+ resource line\n", 5000) +
+		"```\n</details>\n"
+	parts := mr.SplitComment(comment, models.BitbucketServer)
+	Equals(t, 3, len(parts))
+
+	for i, p := range parts {
+		Assert(t, len(p) <= 32768, "part %d was %d chars", i, len(p))
+		Equals(t, 0, strings.Count(p, "```")%2)
+		Equals(t, strings.Count(p, "<details>"), strings.Count(p, "</details>"))
+	}
+	Assert(t, strings.HasPrefix(parts[0], "Ran Plan\n\n<details><summary>Show Output</summary>\n\n```diff\n"), "first part should start with the original comment")
+	Assert(t, strings.HasSuffix(parts[0], "Continued in next comment (part 2 of 3)."), "exp first part to be numbered, got %q", parts[0][len(parts[0])-100:])
+	Assert(t, strings.HasPrefix(parts[1], "Continued from previous comment (part 2 of 3).\n\n<details><summary>Show Output</summary>\n\n```diff\n"), "exp second part to re-open blocks, got %q", parts[1][:100])
+	Assert(t, strings.HasPrefix(parts[2], "Continued from previous comment (part 3 of 3)."), "exp last part to be numbered")
+	Assert(t, strings.HasSuffix(parts[2], "```\n</details>\n"), "exp last part to end with the original comment")
+
+	// No content should be lost.
+	Equals(t, 5000, strings.Count(strings.Join(parts, ""), "+ resource line\n"))
+}
@@ -0,0 +1,273 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Merge queue modes. They decide when the projects of pull requests that are
+// merged by a GitHub merge queue are applied.
+const (
+	// ApplyBeforeMergeMode applies projects from pull requests, like
+	// Atlantis always does, so pull requests can only enter the merge
+	// queue once all their projects are applied.
+	ApplyBeforeMergeMode = "apply-before-merge"
+	// ApplyAfterMergeMode applies projects once pull requests are merged to
+	// the default branch so pull requests can enter the merge queue once all
+	// their projects are planned.
+	ApplyAfterMergeMode = "apply-after-merge"
+)
+
+// MergeGroup is a group of pull requests in a GitHub merge queue that's
+// checked together before it's merged.
+type MergeGroup struct {
+	// HeadSHA is the commit of the base branch with the group's pull requests
+	// merged in.
+	HeadSHA string
+	// HeadRef is the temporary branch of HeadSHA, ex.
+	// gh-readonly-queue/main/pr-123-{sha}.
+	HeadRef string
+	// BaseRef is the branch the group is merged into, ex. main.
+	BaseRef string
+	// PullNum is the number of the last pull request in the group, which is
+	// the one that entered the queue.
+	PullNum int
+}
+
+// mergeGroupPullRegex matches the pull request number in the head refs of
+// merge groups.
+var mergeGroupPullRegex = regexp.MustCompile(`/pr-(\d+)-[0-9a-f]+$`)
+
+// githubMergeGroupEvent is GitHub's merge_group webhook, which the GitHub
+// client doesn't support.
+type githubMergeGroupEvent struct {
+	Action     string `json:"action"`
+	MergeGroup struct {
+		HeadSHA string `json:"head_sha"`
+		HeadRef string `json:"head_ref"`
+		BaseRef string `json:"base_ref"`
+	} `json:"merge_group"`
+	Repo   *github.Repository `json:"repository"`
+	Sender *github.User       `json:"sender"`
+}
+
+// ParseGithubMergeGroupEvent parses the payload of a GitHub merge_group
+// webhook. action is checks_requested when the group is created and needs to
+// be checked, or destroyed once it's merged or removed from the queue.
+func ParseGithubMergeGroupEvent(parser EventParsing, payload []byte) (action string, group MergeGroup, baseRepo models.Repo, user models.User, err error) {
+	var event githubMergeGroupEvent
+	if err = json.Unmarshal(payload, &event); err != nil {
+		err = errors.Wrap(err, "parsing merge_group event")
+		return
+	}
+	if event.MergeGroup.HeadSHA == "" {
+		err = errors.New("merge_group.head_sha is null")
+		return
+	}
+	match := mergeGroupPullRegex.FindStringSubmatch(event.MergeGroup.HeadRef)
+	if match == nil {
+		err = fmt.Errorf("merge_group.head_ref %q doesn't contain a pull request number", event.MergeGroup.HeadRef)
+		return
+	}
+	pullNum, _ := strconv.Atoi(match[1])
+	if event.Repo == nil {
+		err = errors.New("repository is null")
+		return
+	}
+	baseRepo, err = parser.ParseGithubRepo(event.Repo)
+	if err != nil {
+		return
+	}
+	if event.Sender.GetLogin() == "" {
+		err = errors.New("sender.login is null")
+		return
+	}
+	return event.Action, MergeGroup{
+		HeadSHA: event.MergeGroup.HeadSHA,
+		HeadRef: event.MergeGroup.HeadRef,
+		BaseRef: strings.TrimPrefix(event.MergeGroup.BaseRef, "refs/heads/"),
+		PullNum: pullNum,
+	}, baseRepo, models.User{Username: event.Sender.GetLogin()}, nil
+}
+
+// MergeQueue supports repos that merge pull requests with GitHub merge
+// queues. GitHub requires the checks that protect the base branch to pass on
+// the commits of merge groups too so it sets Atlantis' plan and apply
+// statuses on them, and applies pull requests after they're merged in
+// ApplyAfterMergeMode.
+type MergeQueue struct {
+	// Mode is ApplyBeforeMergeMode or ApplyAfterMergeMode.
+	Mode string
+	// VerifyPlan is true if the projects of pull requests are planned again
+	// on the commit of their merge group before their statuses are set. In
+	// ApplyBeforeMergeMode, their plans must not change anything since
+	// they're already applied.
+	VerifyPlan bool
+	// StatusName is the name used to identify Atlantis' commit statuses.
+	StatusName            string
+	VCSClient             vcs.Client
+	GithubPullGetter      GithubPullGetter
+	EventParser           EventParsing
+	DB                    db.PullStatusStore
+	ProjectCommandBuilder ProjectCommandBuilder
+	ProjectCommandRunner  ProjectCommandRunner
+	Logger                logging.SimpleLogging
+	// AfterMergeApplier, if set, decides which repos are applied after
+	// merge even if Mode is ApplyBeforeMergeMode.
+	AfterMergeApplier *AfterMergeApplier
+}
+
+// ChecksRequested sets Atlantis' statuses on the commit of group, which is
+// merged into baseRepo once the checks pass. If they fail, it comments why on
+// the pull request that entered the queue.
+func (m *MergeQueue) ChecksRequested(baseRepo models.Repo, group MergeGroup, user models.User) {
+	log := m.Logger.NewLogger(fmt.Sprintf("%s#%d", baseRepo.FullName, group.PullNum), true, m.Logger.GetLevel())
+	ghPull, err := m.GithubPullGetter.GetPullRequest(baseRepo, group.PullNum)
+	if err != nil {
+		log.Err("making pull request API call to GitHub: %s", err)
+		return
+	}
+	pull, headRepo, err := m.parsePull(ghPull)
+	if err != nil {
+		log.Err("extracting required fields from pull request: %s", err)
+		return
+	}
+	groupPull := pull
+	groupPull.HeadCommit = group.HeadSHA
+	groupPull.HeadBranch = group.HeadRef
+	m.updateStatuses(log, baseRepo, groupPull, models.PendingCommitStatus, "Checking merge group...")
+
+	ctx := &CommandContext{
+		BaseRepo: baseRepo,
+		HeadRepo: headRepo,
+		Pull:     pull,
+		User:     user,
+		Log:      log,
+	}
+	failure, err := m.checkMergeGroup(ctx, groupPull)
+	if err != nil {
+		log.Err("checking merge group: %s", err)
+		failure = fmt.Sprintf("Atlantis couldn't check the merge group: %s", err)
+	}
+	if failure == "" {
+		m.updateStatuses(log, baseRepo, groupPull, models.SuccessCommitStatus, "Merge group is ready to merge.")
+		return
+	}
+	m.updateStatuses(log, baseRepo, groupPull, models.FailedCommitStatus, "Merge group isn't ready to merge.")
+	comment := fmt.Sprintf("**Atlantis removed this pull request from the merge queue.**\n\n%s", failure)
+	if err := m.VCSClient.CreateComment(baseRepo, pull.Num, comment); err != nil {
+		log.Err("unable to comment: %s", err)
+	}
+}
+
+// checkMergeGroup returns why the merge group with the commit of groupPull
+// can't be merged, or an empty string if it can.
+func (m *MergeQueue) checkMergeGroup(ctx *CommandContext, groupPull models.PullRequest) (string, error) {
+	ps, err := m.DB.GetPullStatus(ctx.Pull)
+	if err != nil {
+		return "", errors.Wrap(err, "getting pull request status")
+	}
+	if ps == nil || len(ps.Projects) == 0 {
+		return "", nil
+	}
+	if ps.Pull.HeadCommit != ctx.Pull.HeadCommit {
+		return fmt.Sprintf("Its projects were planned on commit %s but its head is %s. Plan them again.", ps.Pull.HeadCommit, ctx.Pull.HeadCommit), nil
+	}
+	mode := m.mode(ctx.BaseRepo)
+	var notReady []string
+	for _, p := range ps.Projects {
+		switch {
+		case mode == ApplyAfterMergeMode && p.Status == models.PlannedPlanStatus:
+		case p.Status == models.AppliedPlanStatus, p.Status == models.PlannedPlanStatus && p.NoChanges:
+		default:
+			notReady = append(notReady, fmt.Sprintf("* dir: `%s` workspace: `%s` is %s", p.RepoRelDir, p.Workspace, p.Status.String()))
+		}
+	}
+	if len(notReady) > 0 {
+		want := "applied"
+		if mode == ApplyAfterMergeMode {
+			want = "planned"
+		}
+		return fmt.Sprintf("Its projects must be %s before it's merged:\n%s", want, strings.Join(notReady, "\n")), nil
+	}
+	if !m.VerifyPlan {
+		return "", nil
+	}
+	return m.verifyPlans(ctx, groupPull, ps.Projects)
+}
+
+// verifyPlans plans projects on the commit of groupPull. It returns why their
+// plans show that the merge group can't be merged, or an empty string if it
+// can.
+func (m *MergeQueue) verifyPlans(ctx *CommandContext, groupPull models.PullRequest, projects []models.ProjectStatus) (string, error) {
+	var failures []string
+	for _, p := range projects {
+		pCmds, err := m.ProjectCommandBuilder.BuildPlanCommands(ctx, NewCommentCommand(p.RepoRelDir, nil, models.PlanCommand, false, p.Workspace, p.ProjectName))
+		if err != nil {
+			return "", err
+		}
+		for _, pCmd := range pCmds {
+			// Projects in Terraform Cloud can't be planned in another
+			// working dir.
+			if pCmd.TerraformCloud != nil {
+				continue
+			}
+			// The plan is made in its own working dir like diffs so the
+			// pull request's plans aren't replaced.
+			pCmd.Pull = groupPull
+			pCmd.HeadRepo = ctx.BaseRepo
+			res := m.ProjectCommandRunner.Diff([]models.ProjectCommandContext{pCmd})
+			project := fmt.Sprintf("dir: `%s` workspace: `%s`", pCmd.RepoRelDir, pCmd.Workspace)
+			switch {
+			case res.Error != nil:
+				failures = append(failures, fmt.Sprintf("* %s failed to plan:\n```\n%s\n```", project, strings.TrimSpace(res.Error.Error())))
+			case res.Failure != "":
+				failures = append(failures, fmt.Sprintf("* %s failed to plan: %s", project, res.Failure))
+			case m.mode(ctx.BaseRepo) == ApplyBeforeMergeMode:
+				plan := res.DiffSuccess.Plans[0]
+				if (models.PlanSuccess{TerraformOutput: plan.TerraformOutput, Summary: plan.Summary}).HasChanges() {
+					failures = append(failures, fmt.Sprintf("* %s still has changes even though it was applied:\n```diff\n%s\n```", project, strings.TrimSpace(plan.TerraformOutput)))
+				}
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("Its projects were planned again on the merge group's commit %s:\n%s", groupPull.HeadCommit, strings.Join(failures, "\n")), nil
+}
+
+// mode returns the mode of repo. Repos that are applied after merge in the
+// server-side repo config always use ApplyAfterMergeMode.
+func (m *MergeQueue) mode(repo models.Repo) string {
+	if m.AfterMergeApplier != nil && m.AfterMergeApplier.Enabled(repo) {
+		return ApplyAfterMergeMode
+	}
+	return m.Mode
+}
+
+// updateStatuses sets the combined plan and apply statuses of pull's head
+// commit since those are the ones that are required to merge.
+func (m *MergeQueue) updateStatuses(log *logging.SimpleLogger, repo models.Repo, pull models.PullRequest, status models.CommitStatus, description string) {
+	for _, cmdName := range []models.CommandName{models.PlanCommand, models.ApplyCommand} {
+		src := fmt.Sprintf("%s/%s", m.StatusName, cmdName.String())
+		if err := m.VCSClient.UpdateStatus(repo, pull, status, src, description, ""); err != nil {
+			log.Warn("unable to update commit status: %s", err)
+		}
+	}
+}
+
+func (m *MergeQueue) parsePull(ghPull *github.PullRequest) (models.PullRequest, models.Repo, error) {
+	pull, _, headRepo, err := m.EventParser.ParseGithubPull(ghPull)
+	return pull, headRepo, err
+}
@@ -0,0 +1,168 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v28/github"
+	. "github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseGithubMergeGroupEvent(t *testing.T) {
+	parser := &events.EventParser{GithubUser: "user", GithubToken: "token"}
+	payload := `{
+  "action": "checks_requested",
+  "merge_group": {
+    "head_sha": "abc123",
+    "head_ref": "refs/heads/gh-readonly-queue/main/pr-12-0123456789abcdef",
+    "base_ref": "refs/heads/main"
+  },
+  "repository": {"full_name": "runatlantis/atlantis", "clone_url": "https://github.com/runatlantis/atlantis.git"},
+  "sender": {"login": "lkysow"}
+}`
+	action, group, repo, user, err := events.ParseGithubMergeGroupEvent(parser, []byte(payload))
+	Ok(t, err)
+	Equals(t, "checks_requested", action)
+	Equals(t, events.MergeGroup{
+		HeadSHA: "abc123",
+		HeadRef: "refs/heads/gh-readonly-queue/main/pr-12-0123456789abcdef",
+		BaseRef: "main",
+		PullNum: 12,
+	}, group)
+	Equals(t, "runatlantis/atlantis", repo.FullName)
+	Equals(t, models.User{Username: "lkysow"}, user)
+
+	_, _, _, _, err = events.ParseGithubMergeGroupEvent(parser, []byte(strings.Replace(payload, "pr-12-", "", 1)))
+	ErrEquals(t, `merge_group.head_ref "refs/heads/gh-readonly-queue/main/0123456789abcdef" doesn't contain a pull request number`, err)
+}
+
+func TestMergeQueue_ChecksRequested(t *testing.T) {
+	group := events.MergeGroup{HeadSHA: "merge-sha", HeadRef: "gh-readonly-queue/main/pr-1-abc", BaseRef: "main", PullNum: fixtures.Pull.Num}
+	cases := []struct {
+		description string
+		mode        string
+		result      models.ProjectResult
+		expSuccess  bool
+	}{
+		{
+			"applied projects can be merged before merge",
+			events.ApplyBeforeMergeMode,
+			models.ProjectResult{Command: models.ApplyCommand, ApplySuccess: "applied"},
+			true,
+		},
+		{
+			"planned projects can't be merged before merge",
+			events.ApplyBeforeMergeMode,
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add"}},
+			false,
+		},
+		{
+			"planned projects without changes can be merged before merge",
+			events.ApplyBeforeMergeMode,
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes."}},
+			true,
+		},
+		{
+			"planned projects can be merged after merge",
+			events.ApplyAfterMergeMode,
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add"}},
+			true,
+		},
+		{
+			"errored projects can't be merged after merge",
+			events.ApplyAfterMergeMode,
+			models.ProjectResult{Command: models.PlanCommand, Error: errors.New("plan failed")},
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			mq, vcsClient, cleanup := setupMergeQueue(t, c.mode)
+			defer cleanup()
+			c.result.RepoRelDir = "dir"
+			c.result.Workspace = "default"
+			_, err := mq.DB.(*db.BoltDB).UpdatePullWithResults(fixtures.Pull, []models.ProjectResult{c.result})
+			Ok(t, err)
+
+			mq.ChecksRequested(fixtures.GithubRepo, group, fixtures.User)
+
+			groupPull := fixtures.Pull
+			groupPull.HeadCommit = group.HeadSHA
+			groupPull.HeadBranch = group.HeadRef
+			status, description := models.FailedCommitStatus, "Merge group isn't ready to merge."
+			if c.expSuccess {
+				status, description = models.SuccessCommitStatus, "Merge group is ready to merge."
+			}
+			for _, src := range []string{"atlantis/plan", "atlantis/apply"} {
+				vcsClient.VerifyWasCalledOnce().UpdateStatus(fixtures.GithubRepo, groupPull, models.PendingCommitStatus, src, "Checking merge group...", "")
+				vcsClient.VerifyWasCalledOnce().UpdateStatus(fixtures.GithubRepo, groupPull, status, src, description, "")
+			}
+			if c.expSuccess {
+				vcsClient.VerifyWasCalled(Never()).CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString())
+			} else {
+				vcsClient.VerifyWasCalledOnce().CreateComment(vcsmatchers.AnyModelsRepo(), EqInt(fixtures.Pull.Num), AnyString())
+			}
+		})
+	}
+}
+
+func TestMergeQueue_ChecksRequested_VerifyPlan(t *testing.T) {
+	group := events.MergeGroup{HeadSHA: "merge-sha", HeadRef: "gh-readonly-queue/main/pr-1-abc", BaseRef: "main", PullNum: fixtures.Pull.Num}
+	mq, vcsClient, cleanup := setupMergeQueue(t, events.ApplyBeforeMergeMode)
+	defer cleanup()
+	mq.VerifyPlan = true
+	_, err := mq.DB.(*db.BoltDB).UpdatePullWithResults(fixtures.Pull, []models.ProjectResult{{RepoRelDir: "dir", Workspace: "default", Command: models.ApplyCommand, ApplySuccess: "applied"}})
+	Ok(t, err)
+	builder := mq.ProjectCommandBuilder.(*mocks.MockProjectCommandBuilder)
+	When(builder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{{BaseRepo: fixtures.GithubRepo, Pull: fixtures.Pull, RepoRelDir: "dir", Workspace: "default"}}, nil)
+	runner := mq.ProjectCommandRunner.(*mocks.MockProjectCommandRunner)
+	When(runner.Diff(matchers.AnySliceOfModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		DiffSuccess: &models.DiffSuccess{Plans: []models.WorkspacePlan{{Workspace: "default", TerraformOutput: "Plan: 1 to add"}}},
+	})
+
+	mq.ChecksRequested(fixtures.GithubRepo, group, fixtures.User)
+
+	// The plan is made on the merge group's commit.
+	ctxs := runner.VerifyWasCalledOnce().Diff(matchers.AnySliceOfModelsProjectCommandContext()).GetCapturedArguments()
+	Equals(t, "merge-sha", ctxs[0].Pull.HeadCommit)
+	Equals(t, fixtures.GithubRepo, ctxs[0].HeadRepo)
+	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "still has changes even though it was applied"), "exp comment about changes, got %q", comment)
+}
+
+func setupMergeQueue(t *testing.T, mode string) (*events.MergeQueue, *vcsmocks.MockClient, func()) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+
+	pullGetter := mocks.NewMockGithubPullGetter()
+	parser := mocks.NewMockEventParsing()
+	ghPull := &github.PullRequest{}
+	When(pullGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(ghPull, nil)
+	When(parser.ParseGithubPull(ghPull)).ThenReturn(fixtures.Pull, fixtures.GithubRepo, fixtures.GithubRepo, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	return &events.MergeQueue{
+		Mode:                  mode,
+		StatusName:            "atlantis",
+		VCSClient:             vcsClient,
+		GithubPullGetter:      pullGetter,
+		EventParser:           parser,
+		DB:                    boltDB,
+		ProjectCommandBuilder: mocks.NewMockProjectCommandBuilder(),
+		ProjectCommandRunner:  mocks.NewMockProjectCommandRunner(),
+		Logger:                logging.NewNoopLogger(),
+	}, vcsClient, cleanup
+}
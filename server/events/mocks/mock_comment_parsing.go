@@ -41,6 +41,21 @@ func (mock *MockCommentParsing) Parse(comment string, vcsHost models.VCSHostType
 	return ret0
 }
 
+func (mock *MockCommentParsing) HelpComment(repoID string) string {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommentParsing().")
+	}
+	params := []pegomock.Param{repoID}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("HelpComment", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem()})
+	var ret0 string
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(string)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockCommentParsing) VerifyWasCalledOnce() *VerifierMockCommentParsing {
 	return &VerifierMockCommentParsing{
 		mock:                   mock,
@@ -108,3 +123,30 @@ func (c *MockCommentParsing_Parse_OngoingVerification) GetAllCapturedArguments()
 	}
 	return
 }
+
+func (verifier *VerifierMockCommentParsing) HelpComment(repoID string) *MockCommentParsing_HelpComment_OngoingVerification {
+	params := []pegomock.Param{repoID}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "HelpComment", params, verifier.timeout)
+	return &MockCommentParsing_HelpComment_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommentParsing_HelpComment_OngoingVerification struct {
+	mock              *MockCommentParsing
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommentParsing_HelpComment_OngoingVerification) GetCapturedArguments() string {
+	repoID := c.GetAllCapturedArguments()
+	return repoID[len(repoID)-1]
+}
+
+func (c *MockCommentParsing_HelpComment_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
+}
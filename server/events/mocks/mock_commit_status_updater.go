@@ -70,6 +70,81 @@ func (mock *MockCommitStatusUpdater) UpdateProject(ctx models.ProjectCommandCont
 	return ret0
 }
 
+func (mock *MockCommitStatusUpdater) UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{repo, pull, command, current, numTotal, elapsed}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateCombinedProgress", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockCommitStatusUpdater) UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{ctx, cmdName, elapsed}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateProjectProgress", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockCommitStatusUpdater) UpdateCustomCommand(repo models.Repo, pull models.PullRequest, status models.CommitStatus, name string) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{repo, pull, status, name}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateCustomCommand", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockCommitStatusUpdater) UpdateDiscarded(lock models.ProjectLock) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{lock}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateDiscarded", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockCommitStatusUpdater) UpdatePullStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDone int, numTotal int) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{repo, pull, status, numDone, numTotal}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdatePullStatus", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockCommitStatusUpdater) VerifyWasCalledOnce() *VerifierMockCommitStatusUpdater {
 	return &VerifierMockCommitStatusUpdater{
 		mock:                   mock,
@@ -126,19 +201,19 @@ func (c *MockCommitStatusUpdater_UpdateCombined_OngoingVerification) GetCaptured
 func (c *MockCommitStatusUpdater_UpdateCombined_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []models.CommandName) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]models.Repo, len(params[0]))
+		_param0 = make([]models.Repo, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(models.Repo)
 		}
-		_param1 = make([]models.PullRequest, len(params[1]))
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
 		for u, param := range params[1] {
 			_param1[u] = param.(models.PullRequest)
 		}
-		_param2 = make([]models.CommitStatus, len(params[2]))
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
 		for u, param := range params[2] {
 			_param2[u] = param.(models.CommitStatus)
 		}
-		_param3 = make([]models.CommandName, len(params[3]))
+		_param3 = make([]models.CommandName, len(c.methodInvocations))
 		for u, param := range params[3] {
 			_param3[u] = param.(models.CommandName)
 		}
@@ -165,27 +240,27 @@ func (c *MockCommitStatusUpdater_UpdateCombinedCount_OngoingVerification) GetCap
 func (c *MockCommitStatusUpdater_UpdateCombinedCount_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []models.CommandName, _param4 []int, _param5 []int) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]models.Repo, len(params[0]))
+		_param0 = make([]models.Repo, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(models.Repo)
 		}
-		_param1 = make([]models.PullRequest, len(params[1]))
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
 		for u, param := range params[1] {
 			_param1[u] = param.(models.PullRequest)
 		}
-		_param2 = make([]models.CommitStatus, len(params[2]))
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
 		for u, param := range params[2] {
 			_param2[u] = param.(models.CommitStatus)
 		}
-		_param3 = make([]models.CommandName, len(params[3]))
+		_param3 = make([]models.CommandName, len(c.methodInvocations))
 		for u, param := range params[3] {
 			_param3[u] = param.(models.CommandName)
 		}
-		_param4 = make([]int, len(params[4]))
+		_param4 = make([]int, len(c.methodInvocations))
 		for u, param := range params[4] {
 			_param4[u] = param.(int)
 		}
-		_param5 = make([]int, len(params[5]))
+		_param5 = make([]int, len(c.methodInvocations))
 		for u, param := range params[5] {
 			_param5[u] = param.(int)
 		}
@@ -212,22 +287,213 @@ func (c *MockCommitStatusUpdater_UpdateProject_OngoingVerification) GetCapturedA
 func (c *MockCommitStatusUpdater_UpdateProject_OngoingVerification) GetAllCapturedArguments() (_param0 []models.ProjectCommandContext, _param1 []models.CommandName, _param2 []models.CommitStatus, _param3 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]models.ProjectCommandContext, len(params[0]))
+		_param0 = make([]models.ProjectCommandContext, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(models.ProjectCommandContext)
 		}
-		_param1 = make([]models.CommandName, len(params[1]))
+		_param1 = make([]models.CommandName, len(c.methodInvocations))
 		for u, param := range params[1] {
 			_param1[u] = param.(models.CommandName)
 		}
-		_param2 = make([]models.CommitStatus, len(params[2]))
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
 		for u, param := range params[2] {
 			_param2[u] = param.(models.CommitStatus)
 		}
-		_param3 = make([]string, len(params[3]))
+		_param3 = make([]string, len(c.methodInvocations))
 		for u, param := range params[3] {
 			_param3[u] = param.(string)
 		}
 	}
 	return
 }
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) *MockCommitStatusUpdater_UpdateCombinedProgress_OngoingVerification {
+	params := []pegomock.Param{repo, pull, command, current, numTotal, elapsed}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateCombinedProgress", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateCombinedProgress_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateCombinedProgress_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateCombinedProgress_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest, models.CommandName, int, int, time.Duration) {
+	repo, pull, command, current, numTotal, elapsed := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pull[len(pull)-1], command[len(command)-1], current[len(current)-1], numTotal[len(numTotal)-1], elapsed[len(elapsed)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateCombinedProgress_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommandName, _param3 []int, _param4 []int, _param5 []time.Duration) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+		_param2 = make([]models.CommandName, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.CommandName)
+		}
+		_param3 = make([]int, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(int)
+		}
+		_param4 = make([]int, len(c.methodInvocations))
+		for u, param := range params[4] {
+			_param4[u] = param.(int)
+		}
+		_param5 = make([]time.Duration, len(c.methodInvocations))
+		for u, param := range params[5] {
+			_param5[u] = param.(time.Duration)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) *MockCommitStatusUpdater_UpdateProjectProgress_OngoingVerification {
+	params := []pegomock.Param{ctx, cmdName, elapsed}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateProjectProgress", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateProjectProgress_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateProjectProgress_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateProjectProgress_OngoingVerification) GetCapturedArguments() (models.ProjectCommandContext, models.CommandName, time.Duration) {
+	ctx, cmdName, elapsed := c.GetAllCapturedArguments()
+	return ctx[len(ctx)-1], cmdName[len(cmdName)-1], elapsed[len(elapsed)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateProjectProgress_OngoingVerification) GetAllCapturedArguments() (_param0 []models.ProjectCommandContext, _param1 []models.CommandName, _param2 []time.Duration) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.ProjectCommandContext, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.ProjectCommandContext)
+		}
+		_param1 = make([]models.CommandName, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.CommandName)
+		}
+		_param2 = make([]time.Duration, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(time.Duration)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdateCustomCommand(repo models.Repo, pull models.PullRequest, status models.CommitStatus, name string) *MockCommitStatusUpdater_UpdateCustomCommand_OngoingVerification {
+	params := []pegomock.Param{repo, pull, status, name}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateCustomCommand", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateCustomCommand_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateCustomCommand_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateCustomCommand_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest, models.CommitStatus, string) {
+	repo, pull, status, name := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pull[len(pull)-1], status[len(status)-1], name[len(name)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateCustomCommand_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.CommitStatus)
+		}
+		_param3 = make([]string, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(string)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdateDiscarded(lock models.ProjectLock) *MockCommitStatusUpdater_UpdateDiscarded_OngoingVerification {
+	params := []pegomock.Param{lock}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateDiscarded", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateDiscarded_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateDiscarded_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateDiscarded_OngoingVerification) GetCapturedArguments() models.ProjectLock {
+	lock := c.GetAllCapturedArguments()
+	return lock[len(lock)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateDiscarded_OngoingVerification) GetAllCapturedArguments() (_param0 []models.ProjectLock) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.ProjectLock, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.ProjectLock)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdatePullStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDone int, numTotal int) *MockCommitStatusUpdater_UpdatePullStatus_OngoingVerification {
+	params := []pegomock.Param{repo, pull, status, numDone, numTotal}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdatePullStatus", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdatePullStatus_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdatePullStatus_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdatePullStatus_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest, models.CommitStatus, int, int) {
+	repo, pull, status, numDone, numTotal := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pull[len(pull)-1], status[len(status)-1], numDone[len(numDone)-1], numTotal[len(numTotal)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdatePullStatus_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []int, _param4 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.CommitStatus)
+		}
+		_param3 = make([]int, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(int)
+		}
+		_param4 = make([]int, len(c.methodInvocations))
+		for u, param := range params[4] {
+			_param4[u] = param.(int)
+		}
+	}
+	return
+}
@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"net/url"
 	paths "path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -218,6 +220,165 @@ type ProjectLock struct {
 	Time time.Time
 }
 
+// APIToken is a token that can be used to authenticate to the API.
+type APIToken struct {
+	// Name identifies the token.
+	Name string
+	// Hash is the hex-encoded SHA256 hash of the token. We never store the
+	// token itself.
+	Hash string
+	// Scopes are the API scopes the token is allowed to use,
+	// ex. "locks:read".
+	Scopes []string
+	// CreatedAt is when the token was created.
+	CreatedAt time.Time
+}
+
+// HasScope returns true if the token is allowed to use scope.
+func (a APIToken) HasScope(scope string) bool {
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// OnboardedRepo is a repo that was onboarded via the API. Onboarded repos are
+// whitelisted in addition to the repos matched by --repo-whitelist.
+type OnboardedRepo struct {
+	// ID is the repo's hostname and full name, ex. github.com/owner/repo.
+	ID string
+	// WebhookID is the ID of the webhook Atlantis created or found on the
+	// repo.
+	WebhookID string
+	// OnboardedBy is who onboarded the repo, ex. "api-token:ci".
+	OnboardedBy string
+	// OnboardedAt is when the repo was onboarded.
+	OnboardedAt time.Time
+}
+
+// FeatureFlag enables a new behavior for some repos before it's enabled for
+// all of them.
+type FeatureFlag struct {
+	// Name identifies the flag, ex. "parallel-apply".
+	Name string
+	// Enabled is true if the flag is enabled for every repo.
+	Enabled bool
+	// Repos are the IDs of the repos the flag is enabled for, ex.
+	// github.com/owner/repo. IDs wrapped in slashes are regexes.
+	Repos []string
+	// Percentage is the percent of all repos the flag is enabled for, from 0
+	// to 100.
+	Percentage int
+}
+
+// PlanOutput is the full output of a plan whose output was truncated in its
+// pull request comment.
+type PlanOutput struct {
+	Pull        PullRequest
+	ProjectName string
+	RepoRelDir  string
+	Workspace   string
+	Output      string
+	CreatedAt   time.Time
+	// Apply is true if Output is the output of an apply rather than of a
+	// plan.
+	Apply bool
+}
+
+// QueuedEvent is a webhook event that's waiting to be handled, or is being
+// handled, by the command runner. It's stored so it survives restarts.
+type QueuedEvent struct {
+	// ID is assigned when the event is first stored. IDs increase with each
+	// event.
+	ID       uint64
+	QueuedAt time.Time
+	// Attempts is how many times handling the event has started. It's more
+	// than 1 if the server stopped while handling it.
+	Attempts int
+	// Payload is the serialized command the event runs.
+	Payload []byte
+	// Priority is the priority of the event's repo when it was queued.
+	// Events with higher priorities are handled first.
+	Priority int
+}
+
+// FailedEvent is a webhook request that failed to be handled, ex. because
+// the server was misconfigured. It's stored so it can be replayed once the
+// problem is fixed.
+type FailedEvent struct {
+	// ID is assigned when the event is first stored. IDs increase with each
+	// event.
+	ID         uint64
+	ReceivedAt time.Time
+	RemoteAddr string
+	Header     map[string][]string
+	Body       []byte
+	// Status is the HTTP status code of the last response to the request.
+	Status int
+	// Response is the start of the body of the last response.
+	Response string
+	// Replays is how many times the request has been replayed.
+	Replays int
+}
+
+// AuditAction is an action recorded in the audit log.
+type AuditAction string
+
+const (
+	// PlanAuditAction is recorded when a project is planned.
+	PlanAuditAction AuditAction = "plan"
+	// ApplyAuditAction is recorded when a project is applied.
+	ApplyAuditAction AuditAction = "apply"
+	// UnlockAuditAction is recorded when Atlantis releases a lock because
+	// its pull request was closed.
+	UnlockAuditAction AuditAction = "unlock"
+	// DeleteLockAuditAction is recorded when a lock is deleted via the UI or
+	// API.
+	DeleteLockAuditAction AuditAction = "delete_lock"
+	// DiscardPlanAuditAction is recorded when a project's plan is discarded
+	// via the discard command or API.
+	DiscardPlanAuditAction AuditAction = "discard_plan"
+)
+
+// AuditResult is the outcome of an audited action.
+type AuditResult string
+
+const (
+	// SuccessAuditResult means the action succeeded.
+	SuccessAuditResult AuditResult = "success"
+	// FailureAuditResult means the action ran but didn't succeed, ex. because
+	// apply requirements weren't met.
+	FailureAuditResult AuditResult = "failure"
+	// ErrorAuditResult means the action errored.
+	ErrorAuditResult AuditResult = "error"
+)
+
+// AuditEvent is an entry in the audit log.
+type AuditEvent struct {
+	// ID is assigned when the event is stored. IDs increase with each event.
+	ID     uint64      `json:"id"`
+	Time   time.Time   `json:"time"`
+	Action AuditAction `json:"action"`
+	// User is who performed the action. It's the VCS username for commands,
+	// the web or API token user for lock deletions and empty for actions
+	// Atlantis performs itself.
+	User         string      `json:"user"`
+	RepoFullName string      `json:"repo"`
+	PullNum      int         `json:"pull_num"`
+	ProjectName  string      `json:"project,omitempty"`
+	RepoRelDir   string      `json:"dir"`
+	Workspace    string      `json:"workspace"`
+	Result       AuditResult `json:"result"`
+	// Error is the error or failure message if the action didn't succeed.
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	// ProjectID is the project's stable ID. It's empty for actions that
+	// aren't for a project and for events recorded before IDs were added.
+	ProjectID string `json:"project_id,omitempty"`
+}
+
 // Project represents a Terraform project. Since there may be multiple
 // Terraform projects in a single repo we also include Path to the project
 // root relative to the repo root.
@@ -305,6 +466,22 @@ type ProjectCommandContext struct {
 	// ApplyRequirements is the list of requirements that must be satisfied
 	// before we will run the apply stage.
 	ApplyRequirements []string
+	// Approvals, if set, are the rules of the approved apply requirement,
+	// from the server-side repo config. If nil, any approval is enough.
+	Approvals *valid.Approvals
+	// SecurityScan, if set, is how security_scan steps scan the project, from
+	// the server-side repo config. If nil, valid.DefaultSecurityScan is used.
+	SecurityScan *valid.SecurityScan
+	// ApplyWindows, if set, are when this project can be applied, from the
+	// server-side repo config. If nil, it can be applied at any time.
+	ApplyWindows *valid.ApplyWindows
+	// ApplyAfterMerge, if set, is how this project is applied once its pull
+	// request is merged into the default branch, from the server-side repo
+	// config. It can't be applied from pull requests.
+	ApplyAfterMerge *valid.ApplyAfterMerge
+	// Guardrails, if set, limit what this project's plans can change, from
+	// the server-side repo config.
+	Guardrails *valid.Guardrails
 	// AutoplanEnabled is true if automerge is enabled for the repo that this
 	// project is in.
 	AutomergeEnabled bool
@@ -312,17 +489,71 @@ type ProjectCommandContext struct {
 	AutoplanEnabled bool
 	// BaseRepo is the repository that the pull request will be merged into.
 	BaseRepo Repo
+	// BreakGlass is true if the apply comment had the --break-glass flag so
+	// the project can be applied outside its apply windows.
+	BreakGlass bool
+	// CloudCredential, if set, is the cloud identity that's assumed before
+	// this project's steps are run, from the server-side repo config.
+	CloudCredential *valid.CloudCredential
+	// CustomCommand is set if this is the context for a custom comment
+	// command. Steps are then the command's steps.
+	CustomCommand *valid.CustomCommand
+	// ConcurrencyGroup is the name of the concurrency group that limits how
+	// many Terraform operations can run at once for this project. If empty,
+	// this project isn't limited.
+	ConcurrencyGroup string
+	// Concurrency is how many Terraform operations can run at once for this
+	// project's repo and for this project, and the priority they wait with,
+	// from the server-side repo config.
+	Concurrency valid.Concurrency
+	// Timeouts are how long this project's steps can run for, from the
+	// server-side repo config. Steps that set their own timeout use it
+	// instead.
+	Timeouts valid.Timeouts
+	// Tenant is the name of the tenant this project's repo belongs to, from
+	// the server-side repo config, or an empty string if it doesn't belong
+	// to any.
+	Tenant string
+	// ContainerImage is the container image this project's steps run in,
+	// from its workflow. If empty, they run wherever the execution backend
+	// runs commands.
+	ContainerImage string
+	// ConfirmDestroy is true if the apply comment had the --confirm-destroy
+	// flag so destroy plans can be applied.
+	ConfirmDestroy bool
+	// Destroy is true if this is a plan from atlantis plan --destroy, which
+	// plans to destroy every resource in the project.
+	Destroy bool
 	// EscapedCommentArgs are the extra arguments that were added to the atlantis
 	// command, ex. atlantis plan -- -target=resource. We then escape them
 	// by adding a \ before each character so that they can be used within
 	// sh -c safely, i.e. sh -c "terraform plan $(touch bad)".
 	EscapedCommentArgs []string
+	// EnvVars are the environment variables that are set for this project's
+	// steps, from the server-side repo config.
+	EnvVars []valid.EnvVar
+	// GitCredentials are the credentials that this project's steps fetch
+	// private module sources with, from the server-side repo config.
+	GitCredentials []valid.GitCredential
 	// HeadRepo is the repository that is getting merged into the BaseRepo.
 	// If the pull request branch is from the same repository then HeadRepo will
 	// be the same as BaseRepo.
 	HeadRepo Repo
 	// Log is a logger that's been set up for this context.
 	Log *logging.SimpleLogger
+	// Owners are the users or teams that own this project, from the repo's
+	// atlantis.yaml file.
+	Owners []string
+	// OverrideGuardrails is true if the apply comment had the
+	// --override-guardrails flag so plans that exceed the project's
+	// guardrails can be applied.
+	OverrideGuardrails bool
+	// Notify is where this project's events are sent, from the repo's
+	// atlantis.yaml file.
+	Notify valid.Notify
+	// PlanOnly is true if this project can be planned but not applied, from
+	// the repo's atlantis.yaml file.
+	PlanOnly bool
 	// PullMergeable is true if the pull request for this project is able to be merged.
 	PullMergeable bool
 	// Pull is the pull request we're responding to.
@@ -338,13 +569,43 @@ type ProjectCommandContext struct {
 	RePlanCmd string
 	// RepoRelDir is the directory of this project relative to the repo root.
 	RepoRelDir string
+	// SilenceNoChanges is how the result of this project's plan is posted if
+	// it has no changes, from the repo's atlantis.yaml file. If empty, the
+	// server's setting is used.
+	SilenceNoChanges string
+	// SlackApprover is the VCS username of the user who approved the apply in
+	// Slack. It counts as their approval of the pull request.
+	SlackApprover string
+	// Span, if set, is the span of running the command on this project.
+	// Its steps are traced as its children.
+	Span *tracing.Span
+	// Done, if set, is closed once the command is cancelled. Its running
+	// step is stopped and the rest of its steps are skipped.
+	Done <-chan struct{}
+	// TerraformCloud, if set, selects the Terraform Cloud workspace this
+	// project is planned and applied in instead of running Steps.
+	TerraformCloud *valid.TerraformCloud
 	// Steps are the sequence of commands we need to run for this project and this
 	// stage.
 	Steps []valid.Step
+	// Targets are the resource addresses of the -target flags of the apply
+	// comment, ex. atlantis apply -- -target=aws_instance.web. If set, only
+	// they are applied by planning the project again with them first.
+	Targets []string
+	// PlanSteps are the project's plan steps if this is a partial apply with
+	// Targets. They're run to plan the project again before it's applied.
+	PlanSteps []valid.Step
+	// StateLockRetry, if set, is how the init, plan and apply steps are
+	// retried when another run holds the Terraform state lock, from the
+	// project's workflow.
+	StateLockRetry *valid.StateLockRetry
 	// TerraformVersion is the version of terraform we should use when executing
 	// commands for this project. This can be set to nil in which case we will
 	// use the default Atlantis terraform version.
 	TerraformVersion *version.Version
+	// TerraformDistribution is the distribution of terraform, ex. opentofu,
+	// to run for this project. If empty, the server's distribution is used.
+	TerraformDistribution string
 	// User is the user that triggered this command.
 	User User
 	// Verbose is true when the user would like verbose output.
@@ -352,6 +613,20 @@ type ProjectCommandContext struct {
 	// Workspace is the Terraform workspace this project is in. It will always
 	// be set.
 	Workspace string
+	// WorkspaceVarFile is true if the project's <workspace>.tfvars file must
+	// exist and is passed to plans with -var-file, from the repo's
+	// atlantis.yaml file.
+	WorkspaceVarFile bool
+	// DiscoverWorkspaces is true if the project is planned in each of its
+	// existing workspaces, from the repo's atlantis.yaml file. The plan of
+	// each workspace is commented separately.
+	DiscoverWorkspaces bool
+	// VarFiles are the .tfvars files that can be selected with --var-file,
+	// from the repo's atlantis.yaml file.
+	VarFiles []string
+	// VarFile is the .tfvars file selected with --var-file. It's passed to
+	// plans with -var-file. If empty, no file was selected.
+	VarFile string
 }
 
 // SplitRepoFullName splits a repo full name up into its owner and repo
@@ -379,6 +654,35 @@ type ProjectResult struct {
 	PlanSuccess  *PlanSuccess
 	ApplySuccess string
 	ProjectName  string
+	// DiffSuccess is set for successful diff commands.
+	DiffSuccess *DiffSuccess
+	// CustomOutput is the output of a successful custom command.
+	CustomOutput string
+	// CommentSeparately is true if the result should be commented on its
+	// own instead of with the results of the other projects.
+	CommentSeparately bool
+	// ApplyQueuedUntil is set if the apply was outside the project's apply
+	// windows and was queued until this time, when the next window opens.
+	ApplyQueuedUntil time.Time
+	// OutputURL is the URL of the page with the output of the failed plan or
+	// the apply if it was saved.
+	OutputURL string
+}
+
+// StatusURL returns the URL that the project's commit status links to: the
+// page with its output or, for successful plans, the full output of a
+// truncated plan or the plan's lock. It's empty if there's no page.
+func (p ProjectResult) StatusURL() string {
+	if p.OutputURL != "" {
+		return p.OutputURL
+	}
+	if p.PlanSuccess == nil {
+		return ""
+	}
+	if p.PlanSuccess.FullOutputURL != "" {
+		return p.PlanSuccess.FullOutputURL
+	}
+	return p.PlanSuccess.LockURL
 }
 
 // CommitStatus returns the vcs commit status of this project result.
@@ -418,7 +722,11 @@ func (p ProjectResult) PlanStatus() ProjectPlanStatus {
 
 // IsSuccessful returns true if this project result had no errors.
 func (p ProjectResult) IsSuccessful() bool {
-	return p.PlanSuccess != nil || p.ApplySuccess != ""
+	if p.Command == CustomCommand {
+		// Custom commands can succeed without any output.
+		return p.Error == nil && p.Failure == ""
+	}
+	return p.PlanSuccess != nil || p.ApplySuccess != "" || p.DiffSuccess != nil
 }
 
 // PlanSuccess is the result of a successful plan.
@@ -427,14 +735,211 @@ type PlanSuccess struct {
 	TerraformOutput string
 	// LockURL is the full URL to the lock held by this plan.
 	LockURL string
+	// LockID is the ID of the lock held by this plan. It's empty if plan
+	// doesn't lock projects.
+	LockID string
+	// FullOutputURL is the URL to view the full output of this plan if
+	// TerraformOutput was truncated.
+	FullOutputURL string
 	// RePlanCmd is the command that users should run to re-plan this project.
 	RePlanCmd string
 	// ApplyCmd is the command that users should run to apply this plan.
 	ApplyCmd string
+	// PlanOnly is true if the project can't be applied by Atlantis.
+	PlanOnly bool
 	// HasDiverged is true if we're using the checkout merge strategy and the
 	// branch we're merging into has been updated since we cloned and merged
 	// it.
 	HasDiverged bool
+	// Destroy is true if this is a destroy plan. It can only be applied with
+	// the --confirm-destroy flag.
+	Destroy bool
+	// Summary is a structured summary of the changes in this plan. It is nil
+	// if we couldn't generate a summary, ex. because the Terraform version
+	// doesn't support `terraform show -json`.
+	Summary *PlanSummary
+	// VarFile is the .tfvars file selected with --var-file for this plan or
+	// an empty string if none was.
+	VarFile string
+	// Fingerprint identifies what the plan was made from. It's empty if that
+	// couldn't be determined.
+	Fingerprint PlanFingerprint
+	// SecurityFindings are the issues found by the workflow's security_scan
+	// steps, if it has any.
+	SecurityFindings []SecurityFinding
+	// GuardrailViolations are how the plan exceeds the project's guardrails.
+	// It can't be applied without --override-guardrails if there are any.
+	GuardrailViolations []string
+}
+
+// SecurityFinding is a single issue found by a security scanner.
+type SecurityFinding struct {
+	RuleID      string
+	Severity    string
+	Description string
+	// File is relative to the project's dir. It's empty for findings in the
+	// plan.
+	File string
+	Line int
+	// Resource is the address of the resource the finding is in, if the
+	// scanner reports it.
+	Resource string
+}
+
+// PlanFingerprint identifies what a plan was made from so the plan can be
+// reused instead of planning the project again if none of it changed.
+type PlanFingerprint struct {
+	// HeadCommit is the pull request's head commit.
+	HeadCommit string
+	// BaseCommit is the commit of the base branch that was merged into the
+	// head branch. It's empty if the head branch was planned without
+	// merging since the base branch doesn't affect the plan.
+	BaseCommit string
+	// Args are the flags that the plan was run with, ex. from the comment.
+	Args string
+}
+
+// IsEmpty returns true if it's not known what the plan was made from.
+func (f PlanFingerprint) IsEmpty() bool {
+	return f.HeadCommit == ""
+}
+
+// HasChanges returns true if applying the plan would change any resources.
+// If the plan couldn't be summarized, Terraform's output is checked instead.
+func (p PlanSuccess) HasChanges() bool {
+	if p.Summary != nil {
+		return p.Summary.HasChanges()
+	}
+	return !strings.Contains(p.TerraformOutput, "No changes.")
+}
+
+// InsightsReport summarizes the result of a command for a commit so it can be
+// shown alongside the pull request's diff, ex. in Bitbucket Server's Code
+// Insights.
+type InsightsReport struct {
+	Title   string
+	Details string
+	// Passed is false if the command failed.
+	Passed bool
+	// Data are the numbers to show in the report, ex. how many resources
+	// will be added.
+	Data []InsightsData
+	// Link, if set, is where the report links to for more details.
+	Link string
+}
+
+// InsightsData is a number shown in an InsightsReport.
+type InsightsData struct {
+	Title string
+	Value int
+}
+
+// PlanSummary is a structured summary of a plan generated from the output of
+// `terraform show -json`. Each field holds the addresses of the resources
+// that will have that action taken on them.
+type PlanSummary struct {
+	Creates  []string
+	Updates  []string
+	Replaces []string
+	Deletes  []string
+}
+
+// HasChanges returns true if the plan will change any resources.
+func (p PlanSummary) HasChanges() bool {
+	return len(p.Creates)+len(p.Updates)+len(p.Replaces)+len(p.Deletes) > 0
+}
+
+// The actions a plan can take on a resource, as used in ResourceDiff.
+const (
+	CreateAction  = "create"
+	UpdateAction  = "update"
+	ReplaceAction = "replace"
+	DeleteAction  = "delete"
+)
+
+// actions returns the action the plan will take on each resource it changes.
+func (p PlanSummary) actions() map[string]string {
+	actions := make(map[string]string)
+	for action, addrs := range map[string][]string{
+		CreateAction:  p.Creates,
+		UpdateAction:  p.Updates,
+		ReplaceAction: p.Replaces,
+		DeleteAction:  p.Deletes,
+	} {
+		for _, addr := range addrs {
+			actions[addr] = action
+		}
+	}
+	return actions
+}
+
+// DiffSuccess is the result of successfully comparing the plans of a project
+// in different workspaces.
+type DiffSuccess struct {
+	// Plans are the plans for each workspace in the order they were compared.
+	Plans []WorkspacePlan
+}
+
+// WorkspacePlan is the plan of a project in a single workspace.
+type WorkspacePlan struct {
+	Workspace       string
+	TerraformOutput string
+	// Summary is nil if the plan couldn't be summarized.
+	Summary *PlanSummary
+}
+
+// ResourceDiff is a resource that the compared plans take different actions
+// on.
+type ResourceDiff struct {
+	Address string
+	// Actions are the actions each plan will take on the resource, in the
+	// same order as the plans. They're empty if the plan won't change it.
+	Actions []string
+}
+
+// Summarized returns true if every plan has a summary so the plans can be
+// compared.
+func (d DiffSuccess) Summarized() bool {
+	for _, plan := range d.Plans {
+		if plan.Summary == nil {
+			return false
+		}
+	}
+	return len(d.Plans) > 0
+}
+
+// Differences returns the resources that the plans take different actions on,
+// sorted by address. It returns nil if any of the plans aren't summarized.
+func (d DiffSuccess) Differences() []ResourceDiff {
+	if !d.Summarized() {
+		return nil
+	}
+	var planActions []map[string]string
+	addrs := make(map[string]bool)
+	for _, plan := range d.Plans {
+		actions := plan.Summary.actions()
+		for addr := range actions {
+			addrs[addr] = true
+		}
+		planActions = append(planActions, actions)
+	}
+
+	var diffs []ResourceDiff
+	for addr := range addrs {
+		diff := ResourceDiff{Address: addr}
+		same := true
+		for _, actions := range planActions {
+			diff.Actions = append(diff.Actions, actions[addr])
+			if actions[addr] != diff.Actions[0] {
+				same = false
+			}
+		}
+		if !same {
+			diffs = append(diffs, diff)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Address < diffs[j].Address })
+	return diffs
 }
 
 // PullStatus is the current status of a pull request that is in progress.
@@ -463,6 +968,12 @@ type ProjectStatus struct {
 	ProjectName string
 	// Status is the status of where this project is at in the planning cycle.
 	Status ProjectPlanStatus
+	// NoChanges is true if the project was planned and its plan doesn't
+	// change anything so it doesn't need to be applied.
+	NoChanges bool
+	// PlanFingerprint identifies what the project's plan was made from if
+	// it was planned.
+	PlanFingerprint PlanFingerprint
 }
 
 // ProjectPlanStatus is the status of where this project is at in the planning
@@ -508,6 +1019,21 @@ const (
 	ApplyCommand CommandName = iota
 	// PlanCommand is a command to run terraform plan.
 	PlanCommand
+	// DiffCommand is a command to compare the plans of a project in two
+	// workspaces.
+	DiffCommand
+	// CustomCommand is a command configured in the server-side repo config
+	// that runs its own steps. Its name is in CommentCommand.CustomCommand.
+	CustomCommand
+	// StatusCommand is a command to list the status of each project in the
+	// pull request.
+	StatusCommand
+	// DiscardCommand is a command to discard the plans of projects in the
+	// pull request and release their locks.
+	DiscardCommand
+	// CancelCommand is a command to cancel the plans and applies that are
+	// running on the pull request.
+	CancelCommand
 	// Adding more? Don't forget to update String() below
 )
 
@@ -518,6 +1044,31 @@ func (c CommandName) String() string {
 		return "apply"
 	case PlanCommand:
 		return "plan"
+	case DiffCommand:
+		return "diff"
+	case CustomCommand:
+		return "custom"
+	case StatusCommand:
+		return "status"
+	case DiscardCommand:
+		return "discard"
+	case CancelCommand:
+		return "cancel"
 	}
 	return ""
 }
+
+// CommentReaction is a reaction added to a comment command to show how far
+// along the command is. Each VCS client picks the emoji it uses.
+type CommentReaction int
+
+const (
+	// ReceivedReaction means the command was received.
+	ReceivedReaction CommentReaction = iota
+	// StartedReaction means the command started running.
+	StartedReaction
+	// SucceededReaction means the command finished without errors.
+	SucceededReaction
+	// FailedReaction means the command failed.
+	FailedReaction
+)
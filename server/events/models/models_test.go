@@ -360,6 +360,12 @@ func TestProjectResult_IsSuccessful(t *testing.T) {
 			},
 			true,
 		},
+		"diff success": {
+			models.ProjectResult{
+				DiffSuccess: &models.DiffSuccess{},
+			},
+			true,
+		},
 		"failure": {
 			models.ProjectResult{
 				Failure: "failure",
@@ -381,6 +387,70 @@ func TestProjectResult_IsSuccessful(t *testing.T) {
 	}
 }
 
+func TestDiffSuccess_Differences(t *testing.T) {
+	diff := models.DiffSuccess{
+		Plans: []models.WorkspacePlan{
+			{
+				Workspace: "staging",
+				Summary: &models.PlanSummary{
+					Creates:  []string{"aws_instance.b", "aws_instance.same"},
+					Updates:  []string{"aws_instance.a"},
+					Replaces: []string{"aws_instance.c"},
+				},
+			},
+			{
+				Workspace: "prod",
+				Summary: &models.PlanSummary{
+					Creates: []string{"aws_instance.same"},
+					Updates: []string{"aws_instance.a"},
+					Deletes: []string{"aws_instance.c", "aws_instance.d"},
+				},
+			},
+		},
+	}
+	Assert(t, diff.Summarized(), "expected plans to be summarized")
+	Equals(t, []models.ResourceDiff{
+		{Address: "aws_instance.b", Actions: []string{models.CreateAction, ""}},
+		{Address: "aws_instance.c", Actions: []string{models.ReplaceAction, models.DeleteAction}},
+		{Address: "aws_instance.d", Actions: []string{"", models.DeleteAction}},
+	}, diff.Differences())
+}
+
+func TestDiffSuccess_DifferencesNone(t *testing.T) {
+	diff := models.DiffSuccess{
+		Plans: []models.WorkspacePlan{
+			{Workspace: "staging", Summary: &models.PlanSummary{Creates: []string{"aws_instance.a"}}},
+			{Workspace: "prod", Summary: &models.PlanSummary{Creates: []string{"aws_instance.a"}}},
+		},
+	}
+	Equals(t, 0, len(diff.Differences()))
+}
+
+func TestDiffSuccess_DifferencesNotSummarized(t *testing.T) {
+	diff := models.DiffSuccess{
+		Plans: []models.WorkspacePlan{
+			{Workspace: "staging", Summary: &models.PlanSummary{Creates: []string{"aws_instance.a"}}},
+			{Workspace: "prod"},
+		},
+	}
+	Assert(t, !diff.Summarized(), "expected plans to not be summarized")
+	Assert(t, diff.Differences() == nil, "expected no differences")
+}
+
+func TestPlanSuccess_HasChanges(t *testing.T) {
+	Assert(t, models.PlanSuccess{Summary: &models.PlanSummary{Deletes: []string{"aws_instance.a"}}}.HasChanges(), "expected summarized changes")
+	Assert(t, !models.PlanSuccess{Summary: &models.PlanSummary{}, TerraformOutput: "Plan: 1 to add"}.HasChanges(), "expected the summary to be used over the output")
+	Assert(t, models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."}.HasChanges(), "expected changes in the output")
+	Assert(t, !models.PlanSuccess{TerraformOutput: "No changes. Infrastructure is up-to-date."}.HasChanges(), "expected no changes in the output")
+}
+
+func TestProjectResult_StatusURL(t *testing.T) {
+	Equals(t, "", models.ProjectResult{Failure: "failure"}.StatusURL())
+	Equals(t, "output", models.ProjectResult{Failure: "failure", OutputURL: "output"}.StatusURL())
+	Equals(t, "lock", models.ProjectResult{PlanSuccess: &models.PlanSuccess{LockURL: "lock"}}.StatusURL())
+	Equals(t, "full", models.ProjectResult{PlanSuccess: &models.PlanSuccess{LockURL: "lock", FullOutputURL: "full"}}.StatusURL())
+}
+
 func TestProjectResult_PlanStatus(t *testing.T) {
 	cases := []struct {
 		p         models.ProjectResult
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// projectIDLen is the number of hex characters in project IDs. 16 characters
+// is 64 bits of the hash, which won't collide across any number of projects
+// a server has.
+const projectIDLen = 16
+
+// ProjectID returns the stable ID of the project at repoRelDir in
+// repoFullName that runs in workspace. It's a hash of them so it doesn't
+// change when the project's name in atlantis.yaml changes, unlike the names
+// we show users. External automation can use it to track projects across
+// statuses, locks, the API and artifacts.
+func ProjectID(repoFullName string, repoRelDir string, workspace string) string {
+	// NewProject cleans the dir so "dir/" and "./dir" have the same ID.
+	project := NewProject(repoFullName, repoRelDir)
+	sum := sha256.Sum256([]byte(project.RepoFullName + "\x00" + project.Path + "\x00" + workspace))
+	return hex.EncodeToString(sum[:])[:projectIDLen]
+}
+
+// ProjectID returns the stable ID of the project this context is for.
+func (p ProjectCommandContext) ProjectID() string {
+	return ProjectID(p.BaseRepo.FullName, p.RepoRelDir, p.Workspace)
+}
+
+// ProjectID returns the stable ID of the locked project.
+func (l ProjectLock) ProjectID() string {
+	return ProjectID(l.Project.RepoFullName, l.Project.Path, l.Workspace)
+}
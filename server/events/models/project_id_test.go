@@ -0,0 +1,38 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestProjectID(t *testing.T) {
+	// The ID is used by external automation so it must never change.
+	Equals(t, "445faa5827ef29e9", models.ProjectID("owner/repo", "path", "default"))
+
+	// Different ways of writing the same dir have the same ID.
+	Equals(t, "445faa5827ef29e9", models.ProjectID("owner/repo", "./path/", "default"))
+
+	Assert(t, models.ProjectID("owner/repo", "path", "staging") != models.ProjectID("owner/repo", "path", "default"),
+		"exp workspace to change the ID")
+	Assert(t, models.ProjectID("owner/other", "path", "default") != models.ProjectID("owner/repo", "path", "default"),
+		"exp repo to change the ID")
+}
+
+func TestProjectCommandContext_ProjectID(t *testing.T) {
+	ctx := models.ProjectCommandContext{
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		RepoRelDir: "path",
+		Workspace:  "default",
+	}
+	Equals(t, "445faa5827ef29e9", ctx.ProjectID())
+}
+
+func TestProjectLock_ProjectID(t *testing.T) {
+	lock := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "path"),
+		Workspace: "default",
+	}
+	Equals(t, "445faa5827ef29e9", lock.ProjectID())
+}
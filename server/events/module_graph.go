@@ -0,0 +1,120 @@
+package events
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/pkg/errors"
+)
+
+// ModuleGraph maps the Terraform modules in a repo to the modules that call
+// them via local source paths, ex. source = "../modules/vpc". It's used to
+// find the projects that use a module when the module is modified.
+type ModuleGraph struct {
+	// callers maps the dir of each module that's called to the dirs of the
+	// modules that call it. Dirs are relative to the repo root.
+	callers map[string][]string
+}
+
+// BuildModuleGraph parses every Terraform module in absRepoDir and returns
+// the graph of their local module calls. Modules that can't be parsed are
+// skipped since they'll fail when they're planned anyway.
+func BuildModuleGraph(absRepoDir string) (*ModuleGraph, error) {
+	g := &ModuleGraph{callers: make(map[string][]string)}
+	err := filepath.Walk(absRepoDir, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		// Skip .git, .terraform and other hidden dirs. .terraform contains
+		// copies of modules that would otherwise look like callers.
+		if absPath != absRepoDir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !tfconfig.IsModuleDir(absPath) {
+			return nil
+		}
+		relDir, err := filepath.Rel(absRepoDir, absPath)
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+		module, _ := tfconfig.LoadModule(absPath)
+		if module == nil {
+			return nil
+		}
+		for _, call := range module.ModuleCalls {
+			if !isLocalModuleSource(call.Source) {
+				continue
+			}
+			calledDir := path.Join(relDir, call.Source)
+			// Ignore modules outside of the repo.
+			if calledDir == ".." || strings.HasPrefix(calledDir, "../") {
+				continue
+			}
+			g.callers[calledDir] = append(g.callers[calledDir], relDir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "building module graph for %s", absRepoDir)
+	}
+	return g, nil
+}
+
+// IsCalled returns true if the module at dir is called by another module in
+// the repo. dir is relative to the repo root.
+func (g *ModuleGraph) IsCalled(dir string) bool {
+	_, ok := g.callers[path.Clean(dir)]
+	return ok
+}
+
+// Dependents returns the dirs of the modules that directly or indirectly call
+// a module that contains one of modifiedFiles. Files are relative to the
+// repo root. The dirs are sorted and don't include the modified modules
+// themselves unless they call each other.
+func (g *ModuleGraph) Dependents(modifiedFiles []string) []string {
+	var queue []string
+	for _, f := range modifiedFiles {
+		// A file in a subdirectory of a module, ex. a template, modifies
+		// the module.
+		for dir := path.Dir(f); ; dir = path.Dir(dir) {
+			if g.IsCalled(dir) {
+				queue = append(queue, dir)
+				break
+			}
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var dependents []string
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, caller := range g.callers[dir] {
+			if seen[caller] {
+				continue
+			}
+			seen[caller] = true
+			dependents = append(dependents, caller)
+			queue = append(queue, caller)
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// isLocalModuleSource returns true if source is a path in the same repo as
+// opposed to a registry or remote module.
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
@@ -0,0 +1,90 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestModuleGraph_Dependents(t *testing.T) {
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": `module "vpc" { source = "../modules/vpc" }`,
+		},
+		"project2": map[string]interface{}{
+			"main.tf": `
+module "app" { source = "../modules/app" }
+module "remote" { source = "terraform-aws-modules/vpc/aws" }
+`,
+		},
+		"project3": map[string]interface{}{
+			"main.tf": `module "outside" { source = "../../other-repo" }`,
+		},
+		"modules": map[string]interface{}{
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+				"templates": map[string]interface{}{
+					"userdata.tpl": nil,
+				},
+			},
+			"app": map[string]interface{}{
+				"main.tf": `module "vpc" { source = "./../vpc" }`,
+			},
+			"unused": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+		".terraform": map[string]interface{}{
+			"modules": map[string]interface{}{
+				"copy": map[string]interface{}{
+					"main.tf": `module "vpc" { source = "../../../modules/vpc" }`,
+				},
+			},
+		},
+	})
+	defer cleanup()
+
+	graph, err := events.BuildModuleGraph(tmpDir)
+	Ok(t, err)
+
+	cases := []struct {
+		description   string
+		modifiedFiles []string
+		exp           []string
+	}{
+		{
+			description:   "module called directly and indirectly",
+			modifiedFiles: []string{"modules/vpc/main.tf"},
+			exp:           []string{"modules/app", "project1", "project2"},
+		},
+		{
+			description:   "file in module subdir",
+			modifiedFiles: []string{"modules/vpc/templates/userdata.tpl"},
+			exp:           []string{"modules/app", "project1", "project2"},
+		},
+		{
+			description:   "module called once",
+			modifiedFiles: []string{"modules/app/main.tf"},
+			exp:           []string{"project2"},
+		},
+		{
+			description:   "unused module",
+			modifiedFiles: []string{"modules/unused/main.tf"},
+			exp:           nil,
+		},
+		{
+			description:   "project modified",
+			modifiedFiles: []string{"project1/main.tf", "README.md"},
+			exp:           nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, graph.Dependents(c.modifiedFiles))
+		})
+	}
+
+	Equals(t, true, graph.IsCalled("modules/app"))
+	Equals(t, false, graph.IsCalled("project2"))
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// OrgRepoLister lists the repos of VCS organizations or groups.
+type OrgRepoLister interface {
+	// ListOrgRepos returns the full names, ex. owner/repo, of the repos in
+	// org that aren't archived. If topic is set, only the repos with that
+	// topic are returned.
+	ListOrgRepos(org string, topic string) ([]string, error)
+}
+
+// OrgRepoSet is a set of repos synced from VCS organizations.
+type OrgRepoSet interface {
+	// Contains returns true if candidate, ex. github.com/owner/repo, is in
+	// the set.
+	Contains(candidate string) bool
+}
+
+// OrgRepoSyncer periodically syncs the repos of VCS organizations or groups
+// so they're whitelisted without listing each one and new repos are
+// whitelisted without redeploying the server.
+type OrgRepoSyncer struct {
+	// Topic, if set, only syncs repos with this topic, ex. atlantis-enabled.
+	Topic  string
+	Logger logging.SimpleLogging
+
+	orgs []orgRef
+	// listers are keyed by VCS hostname.
+	listers map[string]OrgRepoLister
+
+	mu sync.RWMutex
+	// repos are the lowercased IDs, ex. github.com/owner/repo, of each org's
+	// repos, keyed by org index. An org's repos are kept if it can't be
+	// synced.
+	repos map[int]map[string]bool
+}
+
+// orgRef is an organization on a VCS host.
+type orgRef struct {
+	hostname string
+	name     string
+}
+
+func (o orgRef) String() string {
+	return o.hostname + "/" + o.name
+}
+
+// NewOrgRepoSyncer returns a syncer for orgs, a comma separated list of
+// {hostname}/{org}, ex. github.com/myorg. GitLab subgroups can be used, ex.
+// gitlab.com/mygroup/subgroup. listers are keyed by VCS hostname and each
+// org's host must have one.
+func NewOrgRepoSyncer(orgs string, topic string, listers map[string]OrgRepoLister, logger logging.SimpleLogging) (*OrgRepoSyncer, error) {
+	s := &OrgRepoSyncer{
+		Topic:   topic,
+		Logger:  logger,
+		listers: listers,
+		repos:   make(map[int]map[string]bool),
+	}
+	for _, org := range strings.Split(orgs, ",") {
+		org = strings.TrimSpace(org)
+		if org == "" {
+			continue
+		}
+		parts := strings.SplitN(org, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.Trim(parts[1], "/") == "" || strings.Contains(org, "*") {
+			return nil, fmt.Errorf("org %q must be of the form {hostname}/{org}, ex. github.com/myorg", org)
+		}
+		if _, ok := listers[parts[0]]; !ok {
+			return nil, fmt.Errorf("org %q is on %s but listing repos is only supported for the GitHub and GitLab hosts Atlantis is configured with", org, parts[0])
+		}
+		s.orgs = append(s.orgs, orgRef{hostname: parts[0], name: strings.Trim(parts[1], "/")})
+	}
+	if len(s.orgs) == 0 {
+		return nil, fmt.Errorf("no orgs in %q", orgs)
+	}
+	return s, nil
+}
+
+// Sync lists the repos of every org. If an org's repos can't be listed, the
+// repos from its last sync are kept and an error is returned once the other
+// orgs are synced.
+func (s *OrgRepoSyncer) Sync() error {
+	var errs []string
+	for i, org := range s.orgs {
+		names, err := s.listers[org.hostname].ListOrgRepos(org.name, s.Topic)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("listing repos of %s: %s", org, err))
+			continue
+		}
+		repos := make(map[string]bool)
+		for _, name := range names {
+			repos[strings.ToLower(org.hostname+"/"+name)] = true
+		}
+		s.mu.Lock()
+		s.repos[i] = repos
+		s.mu.Unlock()
+		s.Logger.Debug("synced %d repos of %s", len(repos), org)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SyncEvery syncs the repos now and then every interval until the process
+// exits.
+func (s *OrgRepoSyncer) SyncEvery(interval time.Duration) {
+	for {
+		if err := s.Sync(); err != nil {
+			s.Logger.Err("syncing whitelisted repos: %s", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Contains returns true if candidate, ex. github.com/owner/repo, was in one of
+// the orgs when they were last synced.
+func (s *OrgRepoSyncer) Contains(candidate string) bool {
+	candidate = strings.ToLower(candidate)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, repos := range s.repos {
+		if repos[candidate] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeOrgRepoLister returns the repos of each org, or err if it's set.
+type fakeOrgRepoLister struct {
+	repos  map[string][]string
+	err    error
+	topics []string
+}
+
+func (f *fakeOrgRepoLister) ListOrgRepos(org string, topic string) ([]string, error) {
+	f.topics = append(f.topics, topic)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.repos[org], nil
+}
+
+func TestNewOrgRepoSyncer_Errs(t *testing.T) {
+	listers := map[string]events.OrgRepoLister{"github.com": &fakeOrgRepoLister{}}
+	for orgs, expErr := range map[string]string{
+		"":                               `no orgs in ""`,
+		"github.com":                     `org "github.com" must be of the form {hostname}/{org}, ex. github.com/myorg`,
+		"github.com/*":                   `org "github.com/*" must be of the form {hostname}/{org}, ex. github.com/myorg`,
+		"github.com/ok,gitlab.com/group": `org "gitlab.com/group" is on gitlab.com but listing repos is only supported for the GitHub and GitLab hosts Atlantis is configured with`,
+	} {
+		_, err := events.NewOrgRepoSyncer(orgs, "", listers, logging.NewNoopLogger())
+		ErrEquals(t, expErr, err)
+	}
+}
+
+func TestOrgRepoSyncer_Sync(t *testing.T) {
+	github := &fakeOrgRepoLister{repos: map[string][]string{
+		"owner": {"owner/App", "owner/infra"},
+	}}
+	gitlab := &fakeOrgRepoLister{repos: map[string][]string{
+		"group/sub": {"group/sub/app"},
+	}}
+	syncer, err := events.NewOrgRepoSyncer("github.com/owner, gitlab.com/group/sub/", "atlantis-enabled", map[string]events.OrgRepoLister{
+		"github.com": github,
+		"gitlab.com": gitlab,
+	}, logging.NewNoopLogger())
+	Ok(t, err)
+	Equals(t, false, syncer.Contains("github.com/owner/app"))
+
+	Ok(t, syncer.Sync())
+	Equals(t, []string{"atlantis-enabled"}, github.topics)
+	Equals(t, true, syncer.Contains("github.com/owner/app"))
+	Equals(t, true, syncer.Contains("github.com/Owner/Infra"))
+	Equals(t, true, syncer.Contains("gitlab.com/group/sub/app"))
+	Equals(t, false, syncer.Contains("gitlab.com/owner/app"))
+	Equals(t, false, syncer.Contains("github.com/owner/other"))
+
+	// Repos added to and removed from the org are picked up by the next sync.
+	github.repos["owner"] = []string{"owner/other"}
+	Ok(t, syncer.Sync())
+	Equals(t, false, syncer.Contains("github.com/owner/app"))
+	Equals(t, true, syncer.Contains("github.com/owner/other"))
+
+	// If an org can't be listed its repos from the last sync are kept.
+	gitlab.err = errors.New("unauthorized")
+	github.repos["owner"] = nil
+	ErrEquals(t, "listing repos of gitlab.com/group/sub: unauthorized", syncer.Sync())
+	Equals(t, true, syncer.Contains("gitlab.com/group/sub/app"))
+	Equals(t, false, syncer.Contains("github.com/owner/other"))
+}
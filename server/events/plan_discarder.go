@@ -0,0 +1,211 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PlanDiscarder throws away the plans of a pull request's projects and
+// releases their locks without closing the pull request. It's used by the
+// discard comment command and the DELETE /api/plans route.
+type PlanDiscarder struct {
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	DB               db.PullStatusStore
+	Locker           locking.Locker
+	// CommitStatusUpdater, if set, fails the plan status of each project
+	// whose plan is discarded.
+	CommitStatusUpdater CommitStatusUpdater
+	// AuditLogger, if set, records each plan that's discarded.
+	AuditLogger AuditLogger
+}
+
+// DiscardSelector selects the projects whose plans are discarded, or whose
+// running commands are cancelled. Empty fields match every project.
+type DiscardSelector struct {
+	RepoRelDir string
+	Workspace  string
+	// ProjectName can be a list of names and globs like the -p flag, ex.
+	// network-*,app.
+	ProjectName string
+}
+
+// DiscardedPlan is a project whose plan was discarded.
+type DiscardedPlan struct {
+	ProjectName string `json:"project,omitempty"`
+	RepoRelDir  string `json:"dir"`
+	Workspace   string `json:"workspace"`
+	// LockReleased is true if the pull request held the project's lock and
+	// it was released.
+	LockReleased bool `json:"lock_released"`
+}
+
+// matches returns true if the project in repoRelDir and workspace named
+// projectName is selected by s.
+func (s DiscardSelector) matches(projectName string, repoRelDir string, workspace string) bool {
+	if s.RepoRelDir != "" && filepath.Clean(s.RepoRelDir) != filepath.Clean(repoRelDir) {
+		return false
+	}
+	if s.Workspace != "" && s.Workspace != workspace {
+		return false
+	}
+	if s.ProjectName == "" {
+		return true
+	}
+	for _, pattern := range splitProjectPatterns(s.ProjectName) {
+		if ok, _ := path.Match(pattern, projectName); ok && projectName != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Discard discards the plans of the projects of pull selected by sel on
+// behalf of user. A project has a plan to discard if it has an unapplied
+// status or pull holds its lock. Its planfile and status are deleted, its
+// lock is released and its plan commit status is failed so it must be
+// planned again before it can be applied. It returns the discarded plans,
+// which are empty if none were selected.
+func (d *PlanDiscarder) Discard(log *logging.SimpleLogger, pull models.PullRequest, user string, sel DiscardSelector) ([]DiscardedPlan, error) {
+	var discarded []DiscardedPlan
+	// index maps from each project's dir and workspace to its place in
+	// discarded so locks are matched to the project's status.
+	index := make(map[string]int)
+	add := func(projectName string, repoRelDir string, workspace string) {
+		key := filepath.Clean(repoRelDir) + "|" + workspace
+		if _, ok := index[key]; ok {
+			return
+		}
+		index[key] = len(discarded)
+		discarded = append(discarded, DiscardedPlan{ProjectName: projectName, RepoRelDir: repoRelDir, Workspace: workspace})
+	}
+
+	pullStatus, err := d.DB.GetPullStatus(pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting pull request status")
+	}
+	if pullStatus != nil {
+		for _, ps := range pullStatus.Projects {
+			if ps.Status != models.AppliedPlanStatus && sel.matches(ps.ProjectName, ps.RepoRelDir, ps.Workspace) {
+				add(ps.ProjectName, ps.RepoRelDir, ps.Workspace)
+			}
+		}
+	}
+
+	locks, err := d.Locker.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing locks")
+	}
+	// Locks are sorted so the discarded plans are always in the same order.
+	var keys []string
+	for key := range locks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	held := make(map[string]string)
+	for _, key := range keys {
+		lock := locks[key]
+		if lock.Pull.Num != pull.Num || lock.Project.RepoFullName != pull.BaseRepo.FullName {
+			continue
+		}
+		// Locks don't store the project's name so they're only selected by
+		// name through their project's status.
+		statusKey := filepath.Clean(lock.Project.Path) + "|" + lock.Workspace
+		if _, ok := index[statusKey]; !ok && (sel.ProjectName != "" || !sel.matches("", lock.Project.Path, lock.Workspace)) {
+			continue
+		}
+		add("", lock.Project.Path, lock.Workspace)
+		held[statusKey] = key
+	}
+
+	for i, plan := range discarded {
+		if err := d.deletePlan(pull, plan); err != nil {
+			return nil, err
+		}
+		lock := models.ProjectLock{
+			Project:   models.NewProject(pull.BaseRepo.FullName, plan.RepoRelDir),
+			Workspace: plan.Workspace,
+			Pull:      pull,
+		}
+		if key, ok := held[filepath.Clean(plan.RepoRelDir)+"|"+plan.Workspace]; ok {
+			if _, err := d.Locker.Unlock(key); err != nil {
+				return nil, errors.Wrapf(err, "releasing lock %q", key)
+			}
+			discarded[i].LockReleased = true
+		}
+		if d.CommitStatusUpdater != nil {
+			if err := d.CommitStatusUpdater.UpdateDiscarded(lock); err != nil {
+				log.Warn("unable to update commit status: %s", err)
+			}
+		}
+		if d.AuditLogger != nil {
+			d.AuditLogger.Record(models.AuditEvent{
+				Action:       models.DiscardPlanAuditAction,
+				User:         user,
+				RepoFullName: pull.BaseRepo.FullName,
+				PullNum:      pull.Num,
+				ProjectName:  plan.ProjectName,
+				RepoRelDir:   plan.RepoRelDir,
+				Workspace:    plan.Workspace,
+				Result:       models.SuccessAuditResult,
+				ProjectID:    lock.ProjectID(),
+			})
+		}
+		log.Info("discarded plan for dir %q workspace %q", plan.RepoRelDir, plan.Workspace)
+	}
+	return discarded, nil
+}
+
+// deletePlan deletes the planfile and status of plan. The workspace is
+// locked so the plan isn't deleted while it's being applied.
+func (d *PlanDiscarder) deletePlan(pull models.PullRequest, plan DiscardedPlan) error {
+	unlock, err := d.WorkingDirLocker.TryLock(pull.BaseRepo.FullName, pull.Num, plan.Workspace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if repoDir, err := d.WorkingDir.GetWorkingDir(pull.BaseRepo, pull, plan.Workspace); err == nil {
+		planPath := filepath.Join(repoDir, plan.RepoRelDir, runtime.GetPlanFilename(plan.Workspace, plan.ProjectName))
+		if err := os.Remove(planPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "deleting plan at %s", planPath)
+		}
+	}
+	return errors.Wrap(d.DB.DeleteProjectStatus(pull, plan.Workspace, plan.RepoRelDir), "deleting project status")
+}
+
+// DiscardComment returns the comment posted on the pull request once user
+// discarded plans via via, ex. "the Atlantis API".
+func DiscardComment(plans []DiscardedPlan, user string, via string) string {
+	if len(plans) == 0 {
+		return "There are no plans to discard."
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Discarded** the plans of these projects via %s", via)
+	if user != "" {
+		fmt.Fprintf(&b, " by `%s`", user)
+	}
+	b.WriteString(":\n\n")
+	for _, p := range plans {
+		fmt.Fprintf(&b, "* dir: `%s` workspace: `%s`", p.RepoRelDir, p.Workspace)
+		if p.ProjectName != "" {
+			fmt.Fprintf(&b, " project: `%s`", p.ProjectName)
+		}
+		if p.LockReleased {
+			b.WriteString(" (lock released)")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nTo `apply` these projects you must run `plan` again.")
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	lockmatchers "github.com/runatlantis/atlantis/server/events/locking/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestPlanDiscarder_Discard(t *testing.T) {
+	cases := []struct {
+		description string
+		sel         events.DiscardSelector
+		exp         []events.DiscardedPlan
+		// expRemaining are the dirs whose statuses are left.
+		expRemaining []string
+	}{
+		{
+			description: "every plan is discarded",
+			exp: []events.DiscardedPlan{
+				{ProjectName: "app", RepoRelDir: "dir1", Workspace: "default", LockReleased: true},
+				{ProjectName: "network-a", RepoRelDir: "dir2", Workspace: "default"},
+				{RepoRelDir: "dir4", Workspace: "default", LockReleased: true},
+			},
+			expRemaining: []string{"dir3"},
+		},
+		{
+			description:  "projects are selected by pattern",
+			sel:          events.DiscardSelector{ProjectName: "network-*,app"},
+			exp:          []events.DiscardedPlan{{ProjectName: "app", RepoRelDir: "dir1", Workspace: "default", LockReleased: true}, {ProjectName: "network-a", RepoRelDir: "dir2", Workspace: "default"}},
+			expRemaining: []string{"dir3"},
+		},
+		{
+			description:  "locked projects without a status are selected by dir",
+			sel:          events.DiscardSelector{RepoRelDir: "./dir4", Workspace: "default"},
+			exp:          []events.DiscardedPlan{{RepoRelDir: "dir4", Workspace: "default", LockReleased: true}},
+			expRemaining: []string{"dir1", "dir2", "dir3"},
+		},
+		{
+			description:  "nothing matches",
+			sel:          events.DiscardSelector{Workspace: "staging"},
+			expRemaining: []string{"dir1", "dir2", "dir3"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			repoDir := filepath.Join(tmp, "repo")
+			planfiles := map[string]string{
+				"dir1": "app-default.tfplan",
+				"dir2": "network-a-default.tfplan",
+				"dir4": "default.tfplan",
+			}
+			for dir, planfile := range planfiles {
+				Ok(t, os.MkdirAll(filepath.Join(repoDir, dir), 0700))
+				Ok(t, ioutil.WriteFile(filepath.Join(repoDir, dir, planfile), nil, 0600))
+			}
+
+			store, err := db.New(tmp)
+			Ok(t, err)
+			repo := models.Repo{FullName: "owner/repo"}
+			pull := models.PullRequest{Num: 1, BaseRepo: repo}
+			_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+				{Command: models.PlanCommand, ProjectName: "app", RepoRelDir: "dir1", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+				{Command: models.PlanCommand, ProjectName: "network-a", RepoRelDir: "dir2", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+				{Command: models.ApplyCommand, ProjectName: "network-b", RepoRelDir: "dir3", Workspace: "default", ApplySuccess: "applied"},
+			})
+			Ok(t, err)
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+			locker := lockmocks.NewMockLocker()
+			When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+				"owner/repo/dir1/default": {Project: models.NewProject("owner/repo", "dir1"), Workspace: "default", Pull: pull},
+				"owner/repo/dir4/default": {Project: models.NewProject("owner/repo", "dir4"), Workspace: "default", Pull: pull},
+				"owner/repo/dir5/default": {Project: models.NewProject("owner/repo", "dir5"), Workspace: "default", Pull: models.PullRequest{Num: 2, BaseRepo: repo}},
+			}, nil)
+			commitStatusUpdater := mocks.NewMockCommitStatusUpdater()
+			discarder := events.PlanDiscarder{
+				WorkingDir:          workingDir,
+				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+				DB:                  store,
+				Locker:              locker,
+				CommitStatusUpdater: commitStatusUpdater,
+			}
+
+			plans, err := discarder.Discard(logging.NewNoopLogger(), pull, "user", c.sel)
+			Ok(t, err)
+			Equals(t, c.exp, plans)
+
+			discarded := make(map[string]bool)
+			for _, p := range c.exp {
+				discarded[p.RepoRelDir] = true
+				if p.LockReleased {
+					locker.VerifyWasCalledOnce().Unlock("owner/repo/" + p.RepoRelDir + "/default")
+				}
+			}
+			locker.VerifyWasCalled(Never()).Unlock("owner/repo/dir5/default")
+			commitStatusUpdater.VerifyWasCalled(Times(len(c.exp))).UpdateDiscarded(lockmatchers.AnyModelsProjectLock())
+			for dir, planfile := range planfiles {
+				_, err := os.Stat(filepath.Join(repoDir, dir, planfile))
+				Equals(t, discarded[dir], os.IsNotExist(err))
+			}
+			status, err := store.GetPullStatus(pull)
+			Ok(t, err)
+			var remaining []string
+			for _, p := range status.Projects {
+				remaining = append(remaining, p.RepoRelDir)
+			}
+			Equals(t, c.expRemaining, remaining)
+		})
+	}
+}
+
+func TestDiscardComment(t *testing.T) {
+	Equals(t, "There are no plans to discard.", events.DiscardComment(nil, "user", "a comment"))
+	Equals(t, "**Discarded** the plans of these projects via the Atlantis API by `api-token:ci`:\n\n"+
+		"* dir: `dir1` workspace: `default` project: `app` (lock released)\n"+
+		"* dir: `dir2` workspace: `staging`\n"+
+		"\nTo `apply` these projects you must run `plan` again.",
+		events.DiscardComment([]events.DiscardedPlan{
+			{ProjectName: "app", RepoRelDir: "dir1", Workspace: "default", LockReleased: true},
+			{RepoRelDir: "dir2", Workspace: "staging"},
+		}, "api-token:ci", "the Atlantis API"))
+}
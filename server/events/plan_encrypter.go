@@ -0,0 +1,143 @@
+package events
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+)
+
+// encryptedPlanHeader starts planfiles that are encrypted so they can be told
+// apart from plans made before encryption was enabled.
+const encryptedPlanHeader = "atlantis-encrypted-plan-v1\n"
+
+// PlanEncrypter encrypts planfiles at rest, between plan and apply, since
+// they can contain secrets in clear text. Planfiles are decrypted in place
+// just before they're applied.
+type PlanEncrypter struct {
+	aead cipher.AEAD
+}
+
+// NewPlanEncrypter returns a PlanEncrypter that encrypts planfiles with
+// AES-256-GCM using key, which must be 32 random bytes encoded as hex or
+// base64.
+func NewPlanEncrypter(key string) (*PlanEncrypter, error) {
+	if key == "" {
+		return nil, errors.New("plan encryption key can't be empty")
+	}
+	raw, err := decodePlanEncryptionKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &PlanEncrypter{aead: aead}, nil
+}
+
+// Encrypt encrypts the planfile of ctx's project at planPath in place. It
+// does nothing if there's no planfile or it's already encrypted.
+func (e *PlanEncrypter) Encrypt(ctx models.ProjectCommandContext, planPath string) error {
+	contents, err := ioutil.ReadFile(planPath) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "reading planfile")
+	}
+	if bytes.HasPrefix(contents, []byte(encryptedPlanHeader)) {
+		return nil
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.Wrap(err, "generating nonce")
+	}
+	encrypted := append([]byte(encryptedPlanHeader), nonce...)
+	encrypted = e.aead.Seal(encrypted, nonce, contents, planAdditionalData(ctx, planPath))
+	return errors.Wrap(replaceFile(planPath, encrypted), "encrypting planfile")
+}
+
+// Decrypt decrypts the planfile of ctx's project at planPath in place. It
+// does nothing if there's no planfile or it isn't encrypted.
+func (e *PlanEncrypter) Decrypt(ctx models.ProjectCommandContext, planPath string) error {
+	contents, err := ioutil.ReadFile(planPath) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "reading planfile")
+	}
+	if !bytes.HasPrefix(contents, []byte(encryptedPlanHeader)) {
+		return nil
+	}
+	contents = contents[len(encryptedPlanHeader):]
+	if len(contents) < e.aead.NonceSize() {
+		return errors.New("decrypting planfile: too short")
+	}
+	nonce, ciphertext := contents[:e.aead.NonceSize()], contents[e.aead.NonceSize():]
+	decrypted, err := e.aead.Open(nil, nonce, ciphertext, planAdditionalData(ctx, planPath))
+	if err != nil {
+		return errors.Wrap(err, "decrypting planfile, was it encrypted with a different key?")
+	}
+	return errors.Wrap(replaceFile(planPath, decrypted), "decrypting planfile")
+}
+
+// planAdditionalData returns the data that's authenticated along with the
+// planfile of ctx's project so plans can't be swapped between projects, pull
+// requests or repos. The planfile's name is included since it has the
+// project's name.
+func planAdditionalData(ctx models.ProjectCommandContext, planPath string) []byte {
+	return []byte(fmt.Sprintf("%s\n%d\n%s\n%s\n%s", ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.RepoRelDir, ctx.Workspace, filepath.Base(planPath)))
+}
+
+// decodePlanEncryptionKey decodes key from hex or base64. Passphrases aren't
+// accepted since a key derived from one is only as hard to guess as the
+// passphrase.
+func decodePlanEncryptionKey(key string) ([]byte, error) {
+	if raw, err := hex.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("plan encryption key must be 32 bytes encoded as hex or base64, ex. from `openssl rand -hex 32`")
+}
+
+// replaceFile replaces the contents of path by renaming a new file over it
+// so it's never left half written.
+func replaceFile(path string, contents []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close() // nolint: errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// planPath returns the path of the project's planfile.
+func planPath(ctx models.ProjectCommandContext, absPath string) string {
+	return filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+}
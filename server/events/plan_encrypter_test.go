@@ -0,0 +1,127 @@
+package events_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// planEncryptionKey is a 32 byte key encoded as hex for tests.
+const planEncryptionKey = "6368616e676520746869732070617373776f726420746f206120736563726574"
+
+// planCtx is the project whose planfiles are encrypted in tests.
+var planCtx = models.ProjectCommandContext{
+	BaseRepo:   models.Repo{FullName: "owner/repo"},
+	Pull:       models.PullRequest{Num: 1},
+	RepoRelDir: "dir",
+	Workspace:  "default",
+}
+
+func TestPlanEncrypter_EncryptDecrypt(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("password = hunter2"), 0600))
+
+	encrypter, err := events.NewPlanEncrypter(planEncryptionKey)
+	Ok(t, err)
+	Ok(t, encrypter.Encrypt(planCtx, planPath))
+	encrypted, err := ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Assert(t, !bytes.Contains(encrypted, []byte("hunter2")), "exp planfile to be encrypted, got %q", encrypted)
+
+	// Encrypting again does nothing.
+	Ok(t, encrypter.Encrypt(planCtx, planPath))
+	again, err := ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, encrypted, again)
+
+	Ok(t, encrypter.Decrypt(planCtx, planPath))
+	decrypted, err := ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, "password = hunter2", string(decrypted))
+
+	// Decrypting plans that aren't encrypted does nothing.
+	Ok(t, encrypter.Decrypt(planCtx, planPath))
+	decrypted, err = ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, "password = hunter2", string(decrypted))
+}
+
+func TestPlanEncrypter_NoPlanfile(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+
+	encrypter, err := events.NewPlanEncrypter(planEncryptionKey)
+	Ok(t, err)
+	Ok(t, encrypter.Encrypt(planCtx, planPath))
+	Ok(t, encrypter.Decrypt(planCtx, planPath))
+	_, err = os.Stat(planPath)
+	Assert(t, os.IsNotExist(err), "exp no planfile")
+}
+
+func TestPlanEncrypter_DecryptErrors(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("plan"), 0600))
+	encrypter, err := events.NewPlanEncrypter(planEncryptionKey)
+	Ok(t, err)
+	Ok(t, encrypter.Encrypt(planCtx, planPath))
+
+	other, err := events.NewPlanEncrypter("c3VwZXJzZWNyZXRrZXl0aGF0aXMzMmJ5dGVzbG9uZyE=")
+	Ok(t, err)
+	ErrEquals(t, "decrypting planfile, was it encrypted with a different key?: cipher: message authentication failed", other.Decrypt(planCtx, planPath))
+
+	// Plans can't be swapped between projects, even if their planfiles have
+	// the same name.
+	otherRepo := planCtx
+	otherRepo.BaseRepo.FullName = "owner/other-repo"
+	otherPull := planCtx
+	otherPull.Pull.Num = 2
+	otherDir := planCtx
+	otherDir.RepoRelDir = "other-dir"
+	otherWorkspace := planCtx
+	otherWorkspace.Workspace = "staging"
+	for _, ctx := range []models.ProjectCommandContext{otherRepo, otherPull, otherDir, otherWorkspace} {
+		ErrEquals(t, "decrypting planfile, was it encrypted with a different key?: cipher: message authentication failed", encrypter.Decrypt(ctx, planPath))
+	}
+	otherPlanPath := filepath.Join(tmp, "staging-default.tfplan")
+	Ok(t, os.Rename(planPath, otherPlanPath))
+	ErrEquals(t, "decrypting planfile, was it encrypted with a different key?: cipher: message authentication failed", encrypter.Decrypt(planCtx, otherPlanPath))
+
+	_, err = events.NewPlanEncrypter("")
+	ErrEquals(t, "plan encryption key can't be empty", err)
+}
+
+func TestNewPlanEncrypter_Key(t *testing.T) {
+	cases := []struct {
+		key    string
+		expErr string
+	}{
+		{planEncryptionKey, ""},
+		// base64 of 32 bytes.
+		{"c3VwZXJzZWNyZXRrZXl0aGF0aXMzMmJ5dGVzbG9uZyE=", ""},
+		// Passphrases aren't accepted.
+		{"correct horse battery staple", "plan encryption key must be 32 bytes encoded as hex or base64, ex. from `openssl rand -hex 32`"},
+		// 16 bytes.
+		{"00112233445566778899aabbccddeeff", "plan encryption key must be 32 bytes encoded as hex or base64, ex. from `openssl rand -hex 32`"},
+	}
+	for _, c := range cases {
+		t.Run(c.key, func(t *testing.T) {
+			_, err := events.NewPlanEncrypter(c.key)
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+const (
+	// InvalidateAllOnPush discards every plan of a pull request when new
+	// commits are pushed to it. It's the default.
+	InvalidateAllOnPush = "all"
+	// InvalidateModifiedOnPush only discards the plans and locks of the
+	// projects that the new commits modify.
+	InvalidateModifiedOnPush = "modified"
+)
+
+// PlanInvalidator decides which of a pull request's plans are still valid
+// after new commits are pushed to it. The plans of projects the commits
+// modified are discarded along with their locks and the others are kept so
+// they don't need to be planned again.
+type PlanInvalidator struct {
+	WorkingDir      WorkingDir
+	ParserValidator *yaml.ParserValidator
+	ProjectFinder   ProjectFinder
+	GlobalCfgStore  *GlobalCfgStore
+	// Locker, if set, is used to discard the locks of the projects whose
+	// plans are discarded, unless they're about to be planned again.
+	Locker locking.Locker
+}
+
+// Invalidate discards the plans in prev, the status of the pull request
+// before the new commits were pushed, of the projects that the commits
+// modified. cmds are the commands autoplan built for the new commits. It
+// returns cmds without the projects whose plans were kept, and the statuses
+// of the kept plans. If it returns an error it couldn't tell which projects
+// were modified so the caller should discard every plan.
+func (p *PlanInvalidator) Invalidate(ctx *CommandContext, prev models.PullStatus, cmds []models.ProjectCommandContext) ([]models.ProjectCommandContext, []models.ProjectStatus, error) {
+	modifiedFiles, err := p.WorkingDir.GetModifiedFilesSince(ctx.BaseRepo, ctx.Pull, DefaultWorkspace, prev.Pull.HeadCommit)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "finding the files modified by the new commits")
+	}
+	ctx.Log.Debug("%d files were modified since commit %q", len(modifiedFiles), prev.Pull.HeadCommit)
+	isModified, err := p.modifiedFunc(ctx, modifiedFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replanned := make(map[string]bool)
+	for _, cmd := range cmds {
+		replanned[projectStatusKey(cmd.RepoRelDir, cmd.Workspace, cmd.ProjectName)] = true
+	}
+	kept := make(map[string]bool)
+	var keptStatuses []models.ProjectStatus
+	for _, ps := range prev.Projects {
+		if ps.Status != models.PlannedPlanStatus {
+			continue
+		}
+		key := projectStatusKey(ps.RepoRelDir, ps.Workspace, ps.ProjectName)
+		// If the workspace's clone or the planfile is gone there's no plan
+		// to keep.
+		planPath, err := p.planPath(ctx, ps)
+		if err == nil && !isModified(ps) {
+			if _, err := os.Stat(planPath); err == nil {
+				ctx.Log.Info("keeping plan for dir %q workspace %q because the new commits didn't modify it", ps.RepoRelDir, ps.Workspace)
+				kept[key] = true
+				keptStatuses = append(keptStatuses, ps)
+				continue
+			}
+		}
+
+		ctx.Log.Info("discarding plan for dir %q workspace %q", ps.RepoRelDir, ps.Workspace)
+		if planPath != "" {
+			if err := os.Remove(planPath); err != nil && !os.IsNotExist(err) {
+				return nil, nil, errors.Wrapf(err, "deleting plan at %s", planPath)
+			}
+		}
+		if !replanned[key] {
+			p.unlock(ctx, ps)
+		}
+	}
+
+	var remaining []models.ProjectCommandContext
+	for _, cmd := range cmds {
+		if !kept[projectStatusKey(cmd.RepoRelDir, cmd.Workspace, cmd.ProjectName)] {
+			remaining = append(remaining, cmd)
+		}
+	}
+	return remaining, keptStatuses, nil
+}
+
+// modifiedFunc returns a function that returns true if a project's plan is
+// affected by modifiedFiles. A project is modified if the files match its
+// when_modified config, or would be autoplanned if there's no atlantis.yaml,
+// or if they modify a module it uses. Every project is modified if the
+// atlantis.yaml file is.
+func (p *PlanInvalidator) modifiedFunc(ctx *CommandContext, modifiedFiles []string) (func(models.ProjectStatus) bool, error) {
+	for _, f := range modifiedFiles {
+		if f == yaml.AtlantisYAMLFilename {
+			ctx.Log.Info("discarding every plan because %s was modified", yaml.AtlantisYAMLFilename)
+			return func(models.ProjectStatus) bool { return true }, nil
+		}
+	}
+
+	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, DefaultWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := BuildModuleGraph(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	dependents := make(map[string]bool)
+	for _, dir := range graph.Dependents(modifiedFiles) {
+		dependents[dir] = true
+	}
+	hasRepoCfg, err := p.ParserValidator.HasRepoCfg(repoDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
+	}
+
+	if !hasRepoCfg {
+		modifiedProjects, err := determineProjectsWithoutRepoCfg(ctx.Log, p.ProjectFinder, p.GlobalCfgStore.Get(), modifiedFiles, ctx.BaseRepo, repoDir)
+		if err != nil {
+			return nil, err
+		}
+		modifiedDirs := make(map[string]bool)
+		for _, mp := range modifiedProjects {
+			modifiedDirs[filepath.Clean(mp.Path)] = true
+		}
+		return func(ps models.ProjectStatus) bool {
+			dir := filepath.Clean(ps.RepoRelDir)
+			return modifiedDirs[dir] || dependents[filepath.ToSlash(dir)]
+		}, nil
+	}
+
+	repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfgStore.Get(), ctx.BaseRepo.ID())
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", yaml.AtlantisYAMLFilename)
+	}
+	return func(ps models.ProjectStatus) bool {
+		if dependents[filepath.ToSlash(filepath.Clean(ps.RepoRelDir))] {
+			return true
+		}
+		for _, project := range repoCfg.FindProjectsByDirWorkspace(ps.RepoRelDir, ps.Workspace) {
+			if project.GetName() != ps.ProjectName {
+				continue
+			}
+			matching, err := p.ProjectFinder.DetermineProjectsViaConfig(ctx.Log, modifiedFiles, valid.RepoCfg{Projects: []valid.Project{project}}, repoDir)
+			if err != nil {
+				ctx.Log.Warn("unable to check if project at dir %q workspace %q was modified: %s", ps.RepoRelDir, ps.Workspace, err)
+				return true
+			}
+			// Projects whose dir was deleted aren't matched but their plans
+			// can't be applied.
+			_, statErr := os.Stat(filepath.Join(repoDir, ps.RepoRelDir))
+			return len(matching) > 0 || statErr != nil
+		}
+		// The project was removed from the config.
+		return true
+	}, nil
+}
+
+// planPath returns the path to the planfile of the project of ps.
+func (p *PlanInvalidator) planPath(ctx *CommandContext, ps models.ProjectStatus) (string, error) {
+	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ps.Workspace)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(repoDir, ps.RepoRelDir, runtime.GetPlanFilename(ps.Workspace, ps.ProjectName)), nil
+}
+
+// unlock discards the lock on the project of ps if it's held by this pull
+// request.
+func (p *PlanInvalidator) unlock(ctx *CommandContext, ps models.ProjectStatus) {
+	if p.Locker == nil {
+		return
+	}
+	key := locking.LockKey(models.NewProject(ctx.BaseRepo.FullName, ps.RepoRelDir), ps.Workspace)
+	lock, err := p.Locker.GetLock(key)
+	if err != nil {
+		ctx.Log.Warn("unable to get lock %q: %s", key, err)
+		return
+	}
+	if lock == nil || lock.Pull.Num != ctx.Pull.Num {
+		return
+	}
+	if _, err := p.Locker.Unlock(key); err != nil {
+		ctx.Log.Warn("unable to discard lock %q: %s", key, err)
+		return
+	}
+	ctx.Log.Info("discarded lock %q because its plan was discarded", key)
+}
+
+// keptPlanResult returns a result for the kept plan of ps so it's saved as
+// planned for the new commit.
+func keptPlanResult(ps models.ProjectStatus) models.ProjectResult {
+	planSuccess := &models.PlanSuccess{Fingerprint: ps.PlanFingerprint}
+	if ps.NoChanges {
+		planSuccess.Summary = &models.PlanSummary{}
+	}
+	return models.ProjectResult{
+		Command:     models.PlanCommand,
+		RepoRelDir:  ps.RepoRelDir,
+		Workspace:   ps.Workspace,
+		ProjectName: ps.ProjectName,
+		PlanSuccess: planSuccess,
+	}
+}
+
+// projectStatusKey identifies a project by its dir, workspace and name.
+func projectStatusKey(repoRelDir string, workspace string, projectName string) string {
+	return filepath.Clean(repoRelDir) + "|" + workspace + "|" + projectName
+}
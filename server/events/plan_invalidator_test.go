@@ -0,0 +1,153 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestPlanInvalidator_Invalidate(t *testing.T) {
+	repoCfg := `
+version: 3
+projects:
+- dir: dir1
+  autoplan:
+    when_modified: ["*.tf", "../shared/*.tfvars"]
+- dir: dir2
+`
+	cases := []struct {
+		description   string
+		repoCfg       string
+		modifiedFiles []string
+		// replanned are the dirs autoplan built commands for.
+		replanned    []string
+		expRemaining []string
+		expKept      []string
+		expUnlocked  []string
+	}{
+		{
+			description:   "only the modified project is discarded",
+			modifiedFiles: []string{"dir1/main.tf"},
+			replanned:     []string{"dir1", "dir2"},
+			expRemaining:  []string{"dir1"},
+			expKept:       []string{"dir2"},
+		},
+		{
+			description:   "modified projects that aren't planned again are unlocked",
+			modifiedFiles: []string{"dir1/main.tf"},
+			expKept:       []string{"dir2"},
+			expUnlocked:   []string{"dir1"},
+		},
+		{
+			description:   "projects using a modified module are discarded",
+			modifiedFiles: []string{"modules/vpc/main.tf"},
+			replanned:     []string{"dir1", "dir2"},
+			expRemaining:  []string{"dir2"},
+			expKept:       []string{"dir1"},
+		},
+		{
+			description:   "when_modified is used",
+			repoCfg:       repoCfg,
+			modifiedFiles: []string{"shared/common.tfvars"},
+			replanned:     []string{"dir1", "dir2"},
+			expRemaining:  []string{"dir1"},
+			expKept:       []string{"dir2"},
+		},
+		{
+			description:   "modifying atlantis.yaml discards everything",
+			repoCfg:       repoCfg,
+			modifiedFiles: []string{"atlantis.yaml"},
+			replanned:     []string{"dir1", "dir2"},
+			expRemaining:  []string{"dir1", "dir2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			repoDir, cleanup := TempDir(t)
+			defer cleanup()
+			for _, dir := range []string{"dir1", "dir2", "modules/vpc", "shared"} {
+				Ok(t, os.MkdirAll(filepath.Join(repoDir, dir), 0700))
+			}
+			Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "dir1", "main.tf"), nil, 0600))
+			Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "dir2", "main.tf"), []byte(`module "vpc" { source = "../modules/vpc" }`), 0600))
+			Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "modules", "vpc", "main.tf"), nil, 0600))
+			for _, dir := range []string{"dir1", "dir2"} {
+				Ok(t, ioutil.WriteFile(filepath.Join(repoDir, dir, "default.tfplan"), nil, 0600))
+			}
+			if c.repoCfg != "" {
+				Ok(t, ioutil.WriteFile(filepath.Join(repoDir, yaml.AtlantisYAMLFilename), []byte(c.repoCfg), 0600))
+			}
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+			When(workingDir.GetModifiedFilesSince(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString("default"), EqString("old-commit"))).ThenReturn(c.modifiedFiles, nil)
+			locker := lockmocks.NewMockLocker()
+			When(locker.GetLock(AnyString())).ThenReturn(&models.ProjectLock{Pull: models.PullRequest{Num: 1}}, nil)
+			invalidator := events.PlanInvalidator{
+				WorkingDir:      workingDir,
+				ParserValidator: &yaml.ParserValidator{},
+				ProjectFinder:   &events.DefaultProjectFinder{},
+				GlobalCfgStore:  events.NewGlobalCfgStore(valid.NewGlobalCfg(false, false, false)),
+				Locker:          locker,
+			}
+
+			ctx := &events.CommandContext{
+				BaseRepo: models.Repo{FullName: "owner/repo"},
+				Pull:     models.PullRequest{Num: 1, HeadCommit: "new-commit"},
+				Log:      logging.NewNoopLogger(),
+			}
+			prev := models.PullStatus{
+				Pull: models.PullRequest{Num: 1, HeadCommit: "old-commit"},
+				Projects: []models.ProjectStatus{
+					{RepoRelDir: "dir1", Workspace: "default", Status: models.PlannedPlanStatus},
+					{RepoRelDir: "dir2", Workspace: "default", Status: models.PlannedPlanStatus},
+				},
+			}
+			var cmds []models.ProjectCommandContext
+			for _, dir := range c.replanned {
+				cmds = append(cmds, models.ProjectCommandContext{RepoRelDir: dir, Workspace: "default"})
+			}
+
+			remaining, kept, err := invalidator.Invalidate(ctx, prev, cmds)
+			Ok(t, err)
+
+			var remainingDirs []string
+			for _, cmd := range remaining {
+				remainingDirs = append(remainingDirs, cmd.RepoRelDir)
+			}
+			Equals(t, c.expRemaining, remainingDirs)
+			var keptDirs []string
+			for _, ps := range kept {
+				keptDirs = append(keptDirs, ps.RepoRelDir)
+			}
+			Equals(t, c.expKept, keptDirs)
+			for _, dir := range []string{"dir1", "dir2"} {
+				_, err := os.Stat(filepath.Join(repoDir, dir, "default.tfplan"))
+				isKept := false
+				for _, k := range keptDirs {
+					isKept = isKept || k == dir
+				}
+				Equals(t, isKept, err == nil)
+			}
+			for _, dir := range c.expUnlocked {
+				locker.VerifyWasCalledOnce().Unlock("owner/repo/" + dir + "/default")
+			}
+			if len(c.expUnlocked) == 0 {
+				locker.VerifyWasCalled(Never()).Unlock(AnyString())
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+)
+
+// The destinations the full output of truncated plans can be uploaded to.
+const (
+	// PlanOutputDestinationUI stores the full output in the database so it
+	// can be viewed in the Atlantis UI. It's the default.
+	PlanOutputDestinationUI = "ui"
+	// PlanOutputDestinationS3 uploads the full output to the artifact bucket
+	// and links to it with a pre-signed URL.
+	PlanOutputDestinationS3 = "s3"
+	// PlanOutputDestinationGist uploads the full output to a secret GitHub
+	// gist.
+	PlanOutputDestinationGist = "gist"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_output_store.go PlanOutputStore
+
+// PlanOutputStore stores the full output of plans whose output is truncated
+// in their comment so it can be viewed in the UI.
+type PlanOutputStore interface {
+	// PutPlanOutput saves output and returns the ID to view it with.
+	PutPlanOutput(output models.PlanOutput) (string, error)
+	// DeletePlanOutputs deletes the plan outputs of every project in pull.
+	DeletePlanOutputs(pull models.PullRequest) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_output_url_generator.go PlanOutputURLGenerator
+
+// PlanOutputURLGenerator generates URLs to view the full output of plans.
+type PlanOutputURLGenerator interface {
+	// GeneratePlanOutputURL returns the URL to view the plan output with id.
+	GeneratePlanOutputURL(id string) string
+}
+
+// PlanOutputUploader uploads the full output of plans whose output is
+// truncated somewhere other than the Atlantis UI.
+type PlanOutputUploader interface {
+	// UploadPlanOutput uploads output, the full output of the plan of the
+	// project in ctx, and returns the URL to view it.
+	UploadPlanOutput(ctx models.ProjectCommandContext, output string) (string, error)
+}
+
+// ArtifactPlanOutputUploader uploads plan output to artifact storage next to
+// the project's other artifacts, ex.
+// owner/repo/1/abc123/445faa5827ef29e9/plan-output/plan.txt.
+type ArtifactPlanOutputUploader struct {
+	Uploader runtime.ArtifactUploader
+}
+
+// UploadPlanOutput uploads output and returns the URL to download it.
+func (a *ArtifactPlanOutputUploader) UploadPlanOutput(ctx models.ProjectCommandContext, output string) (string, error) {
+	f, err := ioutil.TempFile("", "plan-output-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name()) // nolint: errcheck
+	_, err = f.WriteString(output)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "writing plan output")
+	}
+	return a.Uploader.Upload(runtime.ArtifactKey(ctx, "plan-output", "plan.txt"), f.Name())
+}
+
+// GistCreator creates GitHub gists. It's implemented by vcs.GithubClient.
+type GistCreator interface {
+	// CreateGist creates a secret gist with a file called filename and
+	// returns the URL to view it.
+	CreateGist(description string, filename string, content string) (string, error)
+}
+
+// GistPlanOutputUploader uploads plan output to secret GitHub gists. Secret
+// gists aren't listed but anyone with their URL can view them.
+type GistPlanOutputUploader struct {
+	Client GistCreator
+}
+
+// UploadPlanOutput uploads output to a new gist and returns its URL.
+func (g *GistPlanOutputUploader) UploadPlanOutput(ctx models.ProjectCommandContext, output string) (string, error) {
+	description := fmt.Sprintf("Plan of %s in workspace %s for %s#%d at %s",
+		ctx.RepoRelDir, ctx.Workspace, ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Pull.HeadCommit)
+	if ctx.ProjectName != "" {
+		description = fmt.Sprintf("Plan of project %s for %s#%d at %s",
+			ctx.ProjectName, ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Pull.HeadCommit)
+	}
+	return g.Client.CreateGist(description, "plan.txt", output)
+}
+
+// refreshLineRegex matches the lines Terraform outputs while it refreshes
+// state. They're removed from plans by the plan step but can still be in the
+// output of custom workflows.
+var refreshLineRegex = regexp.MustCompile(`: (Refreshing state\.\.\.|Reading\.\.\.|Read complete after .*)( \[id=.*\])?$`)
+
+// destructiveResourceRegex matches the headers of resources that will be
+// destroyed or replaced.
+var destructiveResourceRegex = regexp.MustCompile(`(will be destroyed|must be replaced|will be replaced)`)
+
+// The levels of detail each resource in a truncated plan is shown with.
+const (
+	fullResource = iota
+	headerOnlyResource
+	omittedResource
+)
+
+// TruncatePlanOutput shortens output to at most maxBytes if it's longer.
+// Rather than cutting the output off, it removes the least important parts
+// first so the summary, the resources that will be destroyed or replaced,
+// and any errors or warnings are kept. First, lines from refreshing state are
+// removed. Then resources that won't be destroyed are reduced to their header
+// line, ex. "# aws_instance.web will be created", starting from the end,
+// followed by resources that will be destroyed. Then resources are left out
+// entirely, starting with the ones that won't be destroyed. If that's still
+// too long, the output is cut on a line boundary.
+func TruncatePlanOutput(output string, maxBytes int) string {
+	if len(output) <= maxBytes {
+		return output
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if !refreshLineRegex.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	output = strings.Join(lines, "\n")
+	if len(output) <= maxBytes {
+		return output
+	}
+
+	sections := splitPlanByResource(output)
+	if sections == nil {
+		return cutPlanOutput(output, "", maxBytes)
+	}
+
+	// Each pass reduces the level of detail of one kind of resource, one
+	// resource at a time from the end, until the output fits. Omitted
+	// resources are counted as if each needs its own "hidden" line so the
+	// size is never underestimated.
+	levels := make([]int, len(sections))
+	size := 0
+	for _, s := range sections {
+		size += len(s.Body) + 2
+	}
+	omittedSize := len(omittedResourcesLine(len(sections))) + 2
+	sectionSize := func(s planSection, level int) int {
+		if level == omittedResource {
+			return omittedSize
+		}
+		return len(renderPlanSection(s, level)) + 2
+	}
+	passes := []struct {
+		destructive bool
+		level       int
+	}{
+		{false, headerOnlyResource},
+		{true, headerOnlyResource},
+		{false, omittedResource},
+		{true, omittedResource},
+	}
+	for _, pass := range passes {
+		for i := len(sections) - 1; i >= 0 && size > maxBytes; i-- {
+			s := sections[i]
+			if s.Name == "" || levels[i] >= pass.level || destructiveResourceRegex.MatchString(s.Name) != pass.destructive {
+				continue
+			}
+			// Hiding a resource's lines can take more room than they do.
+			if saved := sectionSize(s, levels[i]) - sectionSize(s, pass.level); saved > 0 {
+				size -= saved
+				levels[i] = pass.level
+			}
+		}
+	}
+
+	var parts []string
+	omitted := 0
+	for i, s := range sections {
+		if levels[i] == omittedResource {
+			omitted++
+			continue
+		}
+		if omitted > 0 {
+			parts = append(parts, omittedResourcesLine(omitted))
+			omitted = 0
+		}
+		parts = append(parts, renderPlanSection(s, levels[i]))
+	}
+	if omitted > 0 {
+		parts = append(parts, omittedResourcesLine(omitted))
+	}
+	truncated := strings.Join(parts, "\n\n")
+	if len(truncated) <= maxBytes {
+		return truncated
+	}
+
+	// Keep the summary, which is the last section if it isn't a resource.
+	var tail string
+	if last := sections[len(sections)-1]; last.Name == "" {
+		tail = last.Body
+		truncated = strings.TrimSuffix(truncated, last.Body)
+	}
+	return cutPlanOutput(truncated, tail, maxBytes)
+}
+
+// renderPlanSection renders s at level, which must not be omittedResource.
+func renderPlanSection(s planSection, level int) string {
+	switch level {
+	case headerOnlyResource:
+		header := strings.SplitN(s.Body, "\n", 2)[0]
+		if header == s.Body {
+			return header
+		}
+		return fmt.Sprintf("%s\n  # (%d lines hidden)", header, strings.Count(s.Body, "\n"))
+	default:
+		return s.Body
+	}
+}
+
+func omittedResourcesLine(count int) string {
+	if count == 1 {
+		return "# (1 resource hidden)"
+	}
+	return fmt.Sprintf("# (%d resources hidden)", count)
+}
+
+// cutPlanOutput cuts output on a line boundary so that it and tail fit in
+// maxBytes, then appends tail.
+func cutPlanOutput(output string, tail string, maxBytes int) string {
+	const marker = "# (output truncated)"
+	budget := maxBytes - len(marker) - 1
+	if tail != "" {
+		budget -= len(tail) + 2
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	if len(output) > budget {
+		output = output[:budget]
+		if i := strings.LastIndex(output, "\n"); i >= 0 {
+			output = output[:i]
+		} else {
+			output = ""
+		}
+	}
+	output = strings.TrimRight(output, "\n")
+	if output != "" {
+		output += "\n"
+	}
+	output += marker
+	if tail != "" {
+		output += "\n\n" + tail
+	}
+	return output
+}
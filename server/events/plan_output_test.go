@@ -0,0 +1,206 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const truncatePlan = `An execution plan has been generated and is shown below.
+
+Terraform will perform the following actions:
+
+  # aws_instance.new will be created
++ resource "aws_instance" "new" {
+    + ami  = "ami-123"
+    + tags = {
+        + "Name" = "new"
+      }
+  }
+
+  # aws_instance.old will be destroyed
+- resource "aws_instance" "old" {
+    - ami = "ami-456" -> null
+  }
+
+  # aws_instance.web will be updated in-place
+~ resource "aws_instance" "web" {
+    ~ instance_type = "t2.micro" -> "t2.small"
+  }
+
+Plan: 1 to add, 1 to change, 1 to destroy.`
+
+func TestTruncatePlanOutput(t *testing.T) {
+	cases := []struct {
+		description string
+		output      string
+		maxBytes    int
+		exp         string
+	}{
+		{
+			"fits",
+			truncatePlan,
+			len(truncatePlan),
+			truncatePlan,
+		},
+		{
+			"refresh lines removed",
+			"aws_instance.web: Refreshing state... [id=i-123]\n" + truncatePlan,
+			len(truncatePlan),
+			truncatePlan,
+		},
+		{
+			"non-destructive resources reduced to headers from the end",
+			truncatePlan,
+			len(truncatePlan) - 1,
+			`An execution plan has been generated and is shown below.
+
+Terraform will perform the following actions:
+
+  # aws_instance.new will be created
++ resource "aws_instance" "new" {
+    + ami  = "ami-123"
+    + tags = {
+        + "Name" = "new"
+      }
+  }
+
+  # aws_instance.old will be destroyed
+- resource "aws_instance" "old" {
+    - ami = "ami-456" -> null
+  }
+
+  # aws_instance.web will be updated in-place
+  # (3 lines hidden)
+
+Plan: 1 to add, 1 to change, 1 to destroy.`,
+		},
+		{
+			"destroys kept in full",
+			truncatePlan,
+			385,
+			`An execution plan has been generated and is shown below.
+
+Terraform will perform the following actions:
+
+  # aws_instance.new will be created
+  # (6 lines hidden)
+
+  # aws_instance.old will be destroyed
+- resource "aws_instance" "old" {
+    - ami = "ami-456" -> null
+  }
+
+  # aws_instance.web will be updated in-place
+  # (3 lines hidden)
+
+Plan: 1 to add, 1 to change, 1 to destroy.`,
+		},
+		{
+			"resources hidden",
+			truncatePlan,
+			260,
+			`An execution plan has been generated and is shown below.
+
+Terraform will perform the following actions:
+
+# (1 resource hidden)
+
+  # aws_instance.old will be destroyed
+  # (3 lines hidden)
+
+# (1 resource hidden)
+
+Plan: 1 to add, 1 to change, 1 to destroy.`,
+		},
+		{
+			"cut keeps summary",
+			truncatePlan,
+			130,
+			`An execution plan has been generated and is shown below.
+# (output truncated)
+
+Plan: 1 to add, 1 to change, 1 to destroy.`,
+		},
+		{
+			"no resources",
+			"line1\nline2\nline3\nline4\nline5",
+			27,
+			"line1\n# (output truncated)",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			act := events.TruncatePlanOutput(c.output, c.maxBytes)
+			Equals(t, c.exp, act)
+			if len(c.output) > c.maxBytes {
+				Assert(t, len(act) <= c.maxBytes, "expected at most %d bytes, got %d", c.maxBytes, len(act))
+			}
+			Assert(t, !strings.Contains(act, "Refreshing state"), "expected refresh lines to be removed")
+		})
+	}
+}
+
+// fakeArtifactUploader records the key and content of the file it uploads.
+type fakeArtifactUploader struct {
+	key     string
+	content string
+}
+
+func (f *fakeArtifactUploader) Upload(key string, path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	f.key = key
+	f.content = string(content)
+	return "https://bucket.s3.amazonaws.com/" + key + "?X-Amz-Signature=sig", err
+}
+
+type fakeGistCreator struct {
+	description string
+	filename    string
+	content     string
+}
+
+func (f *fakeGistCreator) CreateGist(description string, filename string, content string) (string, error) {
+	f.description = description
+	f.filename = filename
+	f.content = content
+	return "https://gist.github.com/abc123", nil
+}
+
+func TestPlanOutputUploaders(t *testing.T) {
+	ctx := models.ProjectCommandContext{
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		Pull:       models.PullRequest{Num: 1, HeadCommit: "abc123"},
+		RepoRelDir: "staging",
+		Workspace:  "default",
+	}
+
+	t.Run("artifact storage", func(t *testing.T) {
+		uploader := &fakeArtifactUploader{}
+		url, err := (&events.ArtifactPlanOutputUploader{Uploader: uploader}).UploadPlanOutput(ctx, truncatePlan)
+		Ok(t, err)
+		Equals(t, "owner/repo/1/abc123/"+ctx.ProjectID()+"/plan-output/plan.txt", uploader.key)
+		Equals(t, truncatePlan, uploader.content)
+		Equals(t, "https://bucket.s3.amazonaws.com/"+uploader.key+"?X-Amz-Signature=sig", url)
+	})
+
+	t.Run("gist", func(t *testing.T) {
+		creator := &fakeGistCreator{}
+		url, err := (&events.GistPlanOutputUploader{Client: creator}).UploadPlanOutput(ctx, truncatePlan)
+		Ok(t, err)
+		Equals(t, "https://gist.github.com/abc123", url)
+		Equals(t, "Plan of staging in workspace default for owner/repo#1 at abc123", creator.description)
+		Equals(t, "plan.txt", creator.filename)
+		Equals(t, truncatePlan, creator.content)
+
+		projectCtx := ctx
+		projectCtx.ProjectName = "staging"
+		_, err = (&events.GistPlanOutputUploader{Client: creator}).UploadPlanOutput(projectCtx, truncatePlan)
+		Ok(t, err)
+		Equals(t, "Plan of project staging for owner/repo#1 at abc123", creator.description)
+	})
+}
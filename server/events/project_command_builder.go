@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -14,6 +15,9 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
 )
 
 const (
@@ -44,6 +48,24 @@ type ProjectCommandBuilder interface {
 	BuildApplyCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_workspace_lister.go WorkspaceLister
+
+// WorkspaceLister lists the Terraform workspaces of projects.
+type WorkspaceLister interface {
+	// List returns the names of the existing workspaces of the project at
+	// path. If tfVersion is nil, the server's default version is used.
+	List(log *logging.SimpleLogger, path string, tfVersion *version.Version) ([]string, error)
+}
+
+const (
+	// VCSModifiedFilesSource lists the files modified by pull requests with
+	// the VCS host's API. It's the default.
+	VCSModifiedFilesSource = "vcs"
+	// GitModifiedFilesSource lists the files modified by pull requests by
+	// diffing their clone against the merge base with their base branch.
+	GitModifiedFilesSource = "git"
+)
+
 // DefaultProjectCommandBuilder implements ProjectCommandBuilder.
 // This class combines the data from the comment and any atlantis.yaml file or
 // Atlantis server config and then generates a set of contexts.
@@ -56,6 +78,31 @@ type DefaultProjectCommandBuilder struct {
 	GlobalCfg         valid.GlobalCfg
 	PendingPlanFinder *DefaultPendingPlanFinder
 	CommentBuilder    CommentBuilder
+	// AutoplanModules is true if projects that use a modified module via a
+	// local source path, ex. source = "../modules/vpc", should be planned
+	// even if they weren't modified themselves.
+	AutoplanModules bool
+	// GlobalCfgStore, if set, holds the server-side repo config instead of
+	// GlobalCfg so it can be reloaded.
+	GlobalCfgStore *GlobalCfgStore
+	// PrePlanChecks is true if fmt_check and validate steps should run before
+	// the plan step of every workflow that doesn't already run them.
+	PrePlanChecks bool
+	// WorkspaceLister lists the workspaces of projects that discover them. If
+	// nil, planning those projects fails.
+	WorkspaceLister WorkspaceLister
+	// ModifiedFilesSource is how the files modified by pull requests are
+	// listed, one of the *ModifiedFilesSource constants. If empty, the VCS
+	// host's API is used.
+	ModifiedFilesSource string
+}
+
+// globalCfg returns the current server-side repo config.
+func (p *DefaultProjectCommandBuilder) globalCfg() valid.GlobalCfg {
+	if p.GlobalCfgStore != nil {
+		return p.GlobalCfgStore.Get()
+	}
+	return p.GlobalCfg
 }
 
 // See ProjectCommandBuilder.BuildAutoplanCommands.
@@ -77,20 +124,105 @@ func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *CommandContext
 
 // See ProjectCommandBuilder.BuildPlanCommands.
 func (p *DefaultProjectCommandBuilder) BuildPlanCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	var pccs []models.ProjectCommandContext
 	if !cmd.IsForSpecificProject() {
-		return p.buildPlanAllCommands(ctx, cmd.Flags, cmd.Verbose)
+		var err error
+		pccs, err = p.buildPlanAllCommands(ctx, cmd.Flags, cmd.Verbose)
+		if err != nil {
+			return nil, err
+		}
+	} else if isProjectPattern(cmd.ProjectName) {
+		var err error
+		pccs, err = buildProjectPatternCommands(ctx, cmd, func() ([]models.ProjectCommandContext, error) {
+			return p.buildPlanAllCommands(ctx, cmd.Flags, cmd.Verbose)
+		}, p.buildProjectPlanCommand)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		pcc, err := p.buildProjectPlanCommand(ctx, cmd)
+		if err != nil {
+			return []models.ProjectCommandContext{pcc}, err
+		}
+		pccs = []models.ProjectCommandContext{pcc}
+	}
+	if cmd.Destroy {
+		// Destroy plans are re-planned as destroy plans and can only be
+		// applied once confirmed.
+		for i := range pccs {
+			pccs[i].Destroy = true
+			pccs[i].RePlanCmd = withCommandFlag(pccs[i].RePlanCmd, models.PlanCommand, destroyFlagLong)
+			pccs[i].ApplyCmd = withCommandFlag(pccs[i].ApplyCmd, models.ApplyCommand, confirmDestroyFlagLong)
+		}
+	}
+	if err := selectVarFile(pccs, cmd.VarFile); err != nil {
+		return nil, err
 	}
-	pcc, err := p.buildProjectPlanCommand(ctx, cmd)
-	return []models.ProjectCommandContext{pcc}, err
+	return pccs, nil
 }
 
 // See ProjectCommandBuilder.BuildApplyCommands.
 func (p *DefaultProjectCommandBuilder) BuildApplyCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	var pccs []models.ProjectCommandContext
 	if !cmd.IsForSpecificProject() {
-		return p.buildApplyAllCommands(ctx, cmd)
+		var err error
+		pccs, err = p.buildApplyAllCommands(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+	} else if isProjectPattern(cmd.ProjectName) {
+		var err error
+		pccs, err = buildProjectPatternCommands(ctx, cmd, func() ([]models.ProjectCommandContext, error) {
+			return p.buildApplyAllCommands(ctx, cmd)
+		}, p.buildProjectApplyCommand)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		pac, err := p.buildProjectApplyCommand(ctx, cmd)
+		if err != nil {
+			return []models.ProjectCommandContext{pac}, err
+		}
+		pccs = []models.ProjectCommandContext{pac}
+	}
+	for i := range pccs {
+		pccs[i].ConfirmDestroy = cmd.ConfirmDestroy
+		pccs[i].BreakGlass = cmd.BreakGlass
+		pccs[i].OverrideGuardrails = cmd.OverrideGuardrails
+		pccs[i].SlackApprover = cmd.SlackApprover
 	}
-	pac, err := p.buildProjectApplyCommand(ctx, cmd)
-	return []models.ProjectCommandContext{pac}, err
+	if err := selectVarFile(pccs, cmd.VarFile); err != nil {
+		return nil, err
+	}
+	return pccs, nil
+}
+
+// selectVarFile selects varFile, from the --var-file flag, for each of pccs.
+// It errors if varFile isn't one of the var_files of every project so
+// comments can't pass arbitrary files to Terraform.
+func selectVarFile(pccs []models.ProjectCommandContext, varFile string) error {
+	if varFile == "" {
+		return nil
+	}
+	for i := range pccs {
+		allowed := false
+		for _, f := range pccs[i].VarFiles {
+			if f == varFile {
+				allowed = true
+			}
+		}
+		if !allowed {
+			if len(pccs[i].VarFiles) == 0 {
+				return fmt.Errorf("--%s can't be used for project at dir %q workspace %q since it has no var_files in %s", varFileFlagLong, pccs[i].RepoRelDir, pccs[i].Workspace, yaml.AtlantisYAMLFilename)
+			}
+			return fmt.Errorf("%q isn't one of the var_files of project at dir %q workspace %q: must be one of %s", varFile, pccs[i].RepoRelDir, pccs[i].Workspace, strings.Join(pccs[i].VarFiles, ", "))
+		}
+		pccs[i].VarFile = varFile
+		flag := fmt.Sprintf("%s %s", varFileFlagLong, varFile)
+		pccs[i].RePlanCmd = withCommandFlag(pccs[i].RePlanCmd, models.PlanCommand, flag)
+		pccs[i].ApplyCmd = withCommandFlag(pccs[i].ApplyCmd, models.ApplyCommand, flag)
+	}
+	return nil
 }
 
 // buildPlanAllCommands builds plan contexts for all projects we determine were
@@ -106,17 +238,17 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 	ctx.Log.Debug("got workspace lock")
 	defer unlockFn()
 
-	// We'll need the list of modified files.
-	modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull)
+	repoDir, err := p.clone(ctx, workspace)
 	if err != nil {
 		return nil, err
 	}
-	ctx.Log.Debug("%d files were modified in this pull request", len(modifiedFiles))
 
-	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workspace)
+	// We'll need the list of modified files.
+	modifiedFiles, err := p.modifiedFiles(ctx, workspace)
 	if err != nil {
 		return nil, err
 	}
+	ctx.Log.Debug("%d files were modified in this pull request", len(modifiedFiles))
 
 	// Parse config file if it exists.
 	hasRepoCfg, err := p.ParserValidator.HasRepoCfg(repoDir)
@@ -124,11 +256,14 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 		return nil, errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
 	}
 
+	// The config is read once so the whole command uses the same config even
+	// if it's reloaded in the meantime.
+	globalCfg := p.globalCfg()
 	var projCtxs []models.ProjectCommandContext
 	if hasRepoCfg {
 		// If there's a repo cfg then we'll use it to figure out which projects
 		// should be planed.
-		repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.BaseRepo.ID())
+		repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, globalCfg, ctx.BaseRepo.ID())
 		if err != nil {
 			return nil, errors.Wrapf(err, "parsing %s", yaml.AtlantisYAMLFilename)
 		}
@@ -138,27 +273,166 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 			return nil, err
 		}
 		ctx.Log.Info("%d projects are to be planned based on their when_modified config", len(matchingProjects))
+		if p.AutoplanModules {
+			matchingProjects, err = p.addModuleDependentProjects(ctx, modifiedFiles, repoCfg.Projects, matchingProjects, repoDir)
+			if err != nil {
+				return nil, err
+			}
+		}
+		matchingProjects, err = p.expandDiscoveredWorkspaces(ctx, matchingProjects, repoCfg.Projects, repoDir)
+		if err != nil {
+			return nil, err
+		}
 		for _, mp := range matchingProjects {
 			ctx.Log.Debug("determining config for project at dir: %q workspace: %q", mp.Dir, mp.Workspace)
-			mergedCfg := p.GlobalCfg.MergeProjectCfg(ctx.Log, ctx.BaseRepo.ID(), mp, repoCfg)
+			mergedCfg := globalCfg.MergeProjectCfg(ctx.Log, ctx.BaseRepo.ID(), mp, repoCfg)
 			projCtxs = append(projCtxs, p.buildCtx(ctx, models.PlanCommand, mergedCfg, commentFlags, repoCfg.Automerge, verbose, repoDir))
 		}
 	} else {
 		// If there is no config file, then we'll plan each project that
 		// our algorithm determines was modified.
 		ctx.Log.Info("found no %s file", yaml.AtlantisYAMLFilename)
-		modifiedProjects := p.ProjectFinder.DetermineProjects(ctx.Log, modifiedFiles, ctx.BaseRepo.FullName, repoDir)
+		modifiedProjects, err := determineProjectsWithoutRepoCfg(ctx.Log, p.ProjectFinder, globalCfg, modifiedFiles, ctx.BaseRepo, repoDir)
+		if err != nil {
+			return nil, err
+		}
 		ctx.Log.Info("automatically determined that there were %d projects modified in this pull request: %s", len(modifiedProjects), modifiedProjects)
+		if p.AutoplanModules {
+			modifiedProjects, err = p.addModuleDependentDirs(ctx, modifiedFiles, modifiedProjects, repoDir)
+			if err != nil {
+				return nil, err
+			}
+		}
 		for _, mp := range modifiedProjects {
 			ctx.Log.Debug("determining config for project at dir: %q", mp.Path)
-			pCfg := p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.BaseRepo.ID(), mp.Path, DefaultWorkspace)
+			pCfg := globalCfg.DefaultProjCfg(ctx.Log, ctx.BaseRepo.ID(), mp.Path, DefaultWorkspace)
 			projCtxs = append(projCtxs, p.buildCtx(ctx, models.PlanCommand, pCfg, commentFlags, DefaultAutomergeEnabled, verbose, repoDir))
 		}
 	}
 
+	sortProjectCmds(projCtxs)
 	return projCtxs, nil
 }
 
+// modifiedFiles returns the files modified by the pull request of ctx. When
+// they're listed with git, they're diffed in the clone for workspace.
+func (p *DefaultProjectCommandBuilder) modifiedFiles(ctx *CommandContext, workspace string) ([]string, error) {
+	if p.ModifiedFilesSource == GitModifiedFilesSource {
+		files, err := p.WorkingDir.GetMergeBaseModifiedFiles(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workspace)
+		return files, errors.Wrap(err, "listing modified files with git")
+	}
+	return p.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull)
+}
+
+// sortProjectCmds sorts cmds by dir, workspace and then name so the projects
+// of commands, and the output and statuses for them, are always in the same
+// order no matter which files were modified first.
+func sortProjectCmds(cmds []models.ProjectCommandContext) {
+	sort.SliceStable(cmds, func(i, j int) bool {
+		if cmds[i].RepoRelDir != cmds[j].RepoRelDir {
+			return cmds[i].RepoRelDir < cmds[j].RepoRelDir
+		}
+		if cmds[i].Workspace != cmds[j].Workspace {
+			return cmds[i].Workspace < cmds[j].Workspace
+		}
+		return cmds[i].ProjectName < cmds[j].ProjectName
+	})
+}
+
+// expandDiscoveredWorkspaces replaces each of projects that discovers its
+// workspaces with a copy of it in every workspace it has. Workspaces that are
+// configured as their own project in configured are left to that project.
+func (p *DefaultProjectCommandBuilder) expandDiscoveredWorkspaces(ctx *CommandContext, projects []valid.Project, configured []valid.Project, repoDir string) ([]valid.Project, error) {
+	var expanded []valid.Project
+	for _, project := range projects {
+		if !project.DiscoverWorkspaces {
+			expanded = append(expanded, project)
+			continue
+		}
+		if p.WorkspaceLister == nil {
+			return nil, fmt.Errorf("project at dir %q discovers its workspaces but this server can't list workspaces", project.Dir)
+		}
+		workspaces, err := p.WorkspaceLister.List(ctx.Log, filepath.Join(repoDir, project.Dir), project.TerraformVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "discovering workspaces of project at dir %q", project.Dir)
+		}
+		ctx.Log.Info("discovered workspaces %s of project at dir %q", strings.Join(workspaces, ", "), project.Dir)
+		for _, workspace := range workspaces {
+			if isConfiguredWorkspace(configured, project.Dir, workspace) {
+				continue
+			}
+			project.Workspace = workspace
+			expanded = append(expanded, project)
+		}
+	}
+	return expanded, nil
+}
+
+// isConfiguredWorkspace returns true if projects has a project in dir and
+// workspace that doesn't discover its workspaces.
+func isConfiguredWorkspace(projects []valid.Project, dir string, workspace string) bool {
+	for _, p := range projects {
+		if !p.DiscoverWorkspaces && p.Dir == dir && p.Workspace == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// addModuleDependentProjects adds the projects from the repo config that use
+// a modified module to matched, unless they're already in it.
+func (p *DefaultProjectCommandBuilder) addModuleDependentProjects(ctx *CommandContext, modifiedFiles []string, projects []valid.Project, matched []valid.Project, repoDir string) ([]valid.Project, error) {
+	graph, err := BuildModuleGraph(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	dependents := make(map[string]bool)
+	for _, dir := range graph.Dependents(modifiedFiles) {
+		dependents[dir] = true
+	}
+	for _, project := range projects {
+		if !dependents[filepath.ToSlash(filepath.Clean(project.Dir))] || containsProject(matched, project) {
+			continue
+		}
+		ctx.Log.Info("planning project at dir %q workspace %q because it uses a modified module", project.Dir, project.Workspace)
+		matched = append(matched, project)
+	}
+	return matched, nil
+}
+
+// addModuleDependentDirs adds the root modules, i.e. modules that aren't called
+// by other modules, that use a modified module to modified, unless they're
+// already in it.
+func (p *DefaultProjectCommandBuilder) addModuleDependentDirs(ctx *CommandContext, modifiedFiles []string, modified []models.Project, repoDir string) ([]models.Project, error) {
+	graph, err := BuildModuleGraph(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool)
+	for _, mp := range modified {
+		existing[mp.Path] = true
+	}
+	for _, dir := range graph.Dependents(modifiedFiles) {
+		if existing[dir] || graph.IsCalled(dir) {
+			continue
+		}
+		ctx.Log.Info("planning project at dir %q because it uses a modified module", dir)
+		modified = append(modified, models.NewProject(ctx.BaseRepo.FullName, dir))
+	}
+	return modified, nil
+}
+
+// containsProject returns true if projects contains a project with the same
+// name, dir and workspace as project.
+func containsProject(projects []valid.Project, project valid.Project) bool {
+	for _, p := range projects {
+		if p.GetName() == project.GetName() && filepath.Clean(p.Dir) == filepath.Clean(project.Dir) && p.Workspace == project.Workspace {
+			return true
+		}
+	}
+	return false
+}
+
 // buildProjectPlanCommand builds a plan context for a single project.
 // cmd must be for only one project.
 func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandContext, cmd *CommentCommand) (models.ProjectCommandContext, error) {
@@ -176,7 +450,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandConte
 	defer unlockFn()
 
 	ctx.Log.Debug("cloning repository")
-	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workspace)
+	repoDir, err := p.clone(ctx, workspace)
 	if err != nil {
 		return pcc, err
 	}
@@ -189,6 +463,15 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandConte
 	return p.buildProjectCommandCtx(ctx, models.PlanCommand, cmd.ProjectName, cmd.Flags, repoDir, repoRelDir, workspace, cmd.Verbose)
 }
 
+// clone clones the pull request into workspace's working dir and returns
+// its path.
+func (p *DefaultProjectCommandBuilder) clone(ctx *CommandContext, workspace string) (string, error) {
+	span := ctx.Span.Start("clone", tracing.String("workspace", workspace))
+	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workspace)
+	endSpan(span, err)
+	return repoDir, err
+}
+
 // buildApplyAllCommands builds apply contexts for every project that has
 // pending plans in this ctx.
 func (p *DefaultProjectCommandBuilder) buildApplyAllCommands(ctx *CommandContext, commentCmd *CommentCommand) ([]models.ProjectCommandContext, error) {
@@ -216,8 +499,15 @@ func (p *DefaultProjectCommandBuilder) buildApplyAllCommands(ctx *CommandContext
 		if err != nil {
 			return nil, errors.Wrapf(err, "building command for dir %q", plan.RepoRelDir)
 		}
+		// Plan only projects are applied elsewhere so we don't fail apply all
+		// because of them.
+		if cmd.PlanOnly {
+			ctx.Log.Info("skipping plan only project in dir %q workspace %q", plan.RepoRelDir, plan.Workspace)
+			continue
+		}
 		cmds = append(cmds, cmd)
 	}
+	sortProjectCmds(cmds)
 	return cmds, nil
 }
 
@@ -261,7 +551,8 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(
 	workspace string,
 	verbose bool) (models.ProjectCommandContext, error) {
 
-	projCfgPtr, repoCfgPtr, err := p.getCfg(ctx, projectName, repoRelDir, workspace, repoDir)
+	globalCfg := p.globalCfg()
+	projCfgPtr, repoCfgPtr, err := p.getCfg(ctx, globalCfg, projectName, repoRelDir, workspace, repoDir)
 	if err != nil {
 		return models.ProjectCommandContext{}, err
 	}
@@ -273,9 +564,9 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(
 		// with both project name and dir/workspace.
 		repoRelDir = projCfg.RepoRelDir
 		workspace = projCfg.Workspace
-		projCfg = p.GlobalCfg.MergeProjectCfg(ctx.Log, ctx.BaseRepo.ID(), *projCfgPtr, *repoCfgPtr)
+		projCfg = globalCfg.MergeProjectCfg(ctx.Log, ctx.BaseRepo.ID(), *projCfgPtr, *repoCfgPtr)
 	} else {
-		projCfg = p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.BaseRepo.ID(), repoRelDir, workspace)
+		projCfg = globalCfg.DefaultProjCfg(ctx.Log, ctx.BaseRepo.ID(), repoRelDir, workspace)
 	}
 
 	if err := p.validateWorkspaceAllowed(repoCfgPtr, repoRelDir, workspace); err != nil {
@@ -291,7 +582,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(
 
 // getCfg returns the atlantis.yaml config (if it exists) for this project. If
 // there is no config, then projectCfg and repoCfg will be nil.
-func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName string, dir string, workspace string, repoDir string) (projectCfg *valid.Project, repoCfg *valid.RepoCfg, err error) {
+func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, globalCfg valid.GlobalCfg, projectName string, dir string, workspace string, repoDir string) (projectCfg *valid.Project, repoCfg *valid.RepoCfg, err error) {
 	hasConfigFile, err := p.ParserValidator.HasRepoCfg(repoDir)
 	if err != nil {
 		err = errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
@@ -306,7 +597,7 @@ func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName s
 	}
 
 	var repoConfig valid.RepoCfg
-	repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.BaseRepo.ID())
+	repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, globalCfg, ctx.BaseRepo.ID())
 	if err != nil {
 		return
 	}
@@ -355,7 +646,7 @@ func (p *DefaultProjectCommandBuilder) validateWorkspaceAllowed(repoCfg *valid.R
 
 	var configuredSpaces []string
 	for _, p := range projects {
-		if p.Workspace == workspace {
+		if p.Workspace == workspace || p.DiscoverWorkspaces {
 			return nil
 		}
 		configuredSpaces = append(configuredSpaces, p.Workspace)
@@ -378,12 +669,24 @@ func (p *DefaultProjectCommandBuilder) buildCtx(ctx *CommandContext,
 	verbose bool,
 	absRepoDir string) models.ProjectCommandContext {
 
+	planSteps := projCfg.Workflow.Plan.Steps
+	if p.PrePlanChecks {
+		planSteps = withPrePlanChecks(planSteps)
+	}
 	var steps []valid.Step
+	var targets []string
+	var targetPlanSteps []valid.Step
 	switch cmd {
 	case models.PlanCommand:
-		steps = projCfg.Workflow.Plan.Steps
+		steps = planSteps
 	case models.ApplyCommand:
 		steps = projCfg.Workflow.Apply.Steps
+		// Plans can't be applied with -target so the project is planned
+		// again with the targets before it's applied instead.
+		targets, commentArgs = splitTargetArgs(commentArgs)
+		if len(targets) > 0 {
+			targetPlanSteps = planSteps
+		}
 	}
 
 	// If TerraformVersion not defined in config file look for a
@@ -391,31 +694,109 @@ func (p *DefaultProjectCommandBuilder) buildCtx(ctx *CommandContext,
 	if projCfg.TerraformVersion == nil {
 		projCfg.TerraformVersion = p.getTfVersion(ctx, filepath.Join(absRepoDir, projCfg.RepoRelDir))
 	}
+	// Otherwise use the repo's default version from the server-side config.
+	if projCfg.TerraformVersion == nil {
+		projCfg.TerraformVersion = projCfg.DefaultTerraformVersion
+	}
 
 	return models.ProjectCommandContext{
 		ApplyCmd:           p.CommentBuilder.BuildApplyComment(projCfg.RepoRelDir, projCfg.Workspace, projCfg.Name),
 		BaseRepo:           ctx.BaseRepo,
-		EscapedCommentArgs: p.escapeArgs(commentArgs),
+		CloudCredential:    projCfg.CloudCredential,
+		ConcurrencyGroup:   projCfg.ConcurrencyGroup,
+		Concurrency:        projCfg.Concurrency,
+		Timeouts:           projCfg.Timeouts,
+		Tenant:             projCfg.Tenant,
+		Done:               ctx.Done,
+		ContainerImage:     projCfg.Workflow.Image,
+		EscapedCommentArgs: escapeArgs(commentArgs),
+		EnvVars:            projCfg.EnvVars,
+		GitCredentials:     projCfg.GitCredentials,
 		AutomergeEnabled:   automergeEnabled,
 		AutoplanEnabled:    projCfg.AutoplanEnabled,
 		Steps:              steps,
+		Targets:            targets,
+		PlanSteps:          targetPlanSteps,
+		StateLockRetry:     projCfg.Workflow.StateLockRetry,
 		HeadRepo:           ctx.HeadRepo,
 		Log:                ctx.Log,
+		Owners:             projCfg.Owners,
+		Notify:             projCfg.Notify,
+		PlanOnly:           projCfg.PlanOnly,
+		TerraformCloud:     projCfg.TerraformCloud,
+		WorkspaceVarFile:   projCfg.WorkspaceVarFile,
+		DiscoverWorkspaces: projCfg.DiscoverWorkspaces,
+		VarFiles:           projCfg.VarFiles,
 		PullMergeable:      ctx.PullMergeable,
 		Pull:               ctx.Pull,
 		ProjectName:        projCfg.Name,
 		ApplyRequirements:  projCfg.ApplyRequirements,
+		Approvals:          projCfg.Approvals,
+		SecurityScan:       projCfg.SecurityScan,
+		ApplyWindows:       projCfg.ApplyWindows,
+		ApplyAfterMerge:    projCfg.ApplyAfterMerge,
+		Guardrails:         projCfg.Guardrails,
 		RePlanCmd:          p.CommentBuilder.BuildPlanComment(projCfg.RepoRelDir, projCfg.Workspace, projCfg.Name, commentArgs),
 		RepoRelDir:         projCfg.RepoRelDir,
+		SilenceNoChanges:   projCfg.SilenceNoChanges,
 		RepoConfigVersion:  projCfg.RepoCfgVersion,
 		TerraformVersion:   projCfg.TerraformVersion,
 		User:               ctx.User,
 		Verbose:            verbose,
 		Workspace:          projCfg.Workspace,
+
+		TerraformDistribution: projCfg.TerraformDistribution,
+	}
+}
+
+// withPrePlanChecks returns steps with fmt_check and validate steps added
+// before the first plan step so that style and syntax problems fail fast.
+// Workflows that already run either check, or that don't have a plan step,
+// are returned as is.
+func withPrePlanChecks(steps []valid.Step) []valid.Step {
+	for _, step := range steps {
+		if step.StepName == raw.FmtCheckStepName || step.StepName == raw.ValidateStepName {
+			return steps
+		}
+	}
+	for i, step := range steps {
+		if step.StepName == raw.PlanStepName {
+			checked := make([]valid.Step, 0, len(steps)+2)
+			checked = append(checked, steps[:i]...)
+			checked = append(checked, valid.Step{StepName: raw.FmtCheckStepName}, valid.Step{StepName: raw.ValidateStepName})
+			return append(checked, steps[i:]...)
+		}
+	}
+	return steps
+}
+
+// splitTargetArgs splits the -target flags, ex. -target=aws_instance.web or
+// -target aws_instance.web, out of args. It returns their resource addresses
+// and the rest of args.
+func splitTargetArgs(args []string) (targets []string, rest []string) {
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := splitFlag(args[i])
+		if !strings.HasPrefix(args[i], "-") || name != "target" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if value == "" {
+			// Left for apply to reject.
+			rest = append(rest, args[i])
+			continue
+		}
+		targets = append(targets, value)
 	}
+	return targets, rest
 }
 
-func (p *DefaultProjectCommandBuilder) escapeArgs(args []string) []string {
+// escapeArgs escapes every character of args so they're passed to commands
+// as is when they're run in a shell.
+func escapeArgs(args []string) []string {
 	var escaped []string
 	for _, arg := range args {
 		var escapedArg string
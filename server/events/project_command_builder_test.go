@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
+	version "github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/matchers"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
+	tfmatchers "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
 	"github.com/runatlantis/atlantis/server/events/yaml"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -160,6 +163,99 @@ projects:
 	}
 }
 
+func TestDefaultProjectCommandBuilder_BuildAutoplanCommands_Modules(t *testing.T) {
+	cases := []struct {
+		Description     string
+		AtlantisYAML    string
+		AutoplanModules bool
+		expDirs         []string
+	}{
+		{
+			Description:     "no atlantis.yaml",
+			AutoplanModules: true,
+			expDirs:         []string{"project1", "project2"},
+		},
+		{
+			Description: "no atlantis.yaml and disabled",
+			expDirs:     nil,
+		},
+		{
+			Description: "atlantis.yaml",
+			AtlantisYAML: `
+version: 3
+projects:
+- dir: project1
+- dir: project2
+  autoplan:
+    when_modified: ["*.tf", "../modules/**/*.tf"]
+- dir: project3
+`,
+			AutoplanModules: true,
+			// project2 matches its when_modified and project1 uses the
+			// modified module but they're sorted by dir.
+			expDirs: []string{"project1", "project2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": `module "vpc" { source = "../modules/vpc" }`,
+				},
+				"project2": map[string]interface{}{
+					"main.tf": `module "app" { source = "../modules/app" }`,
+				},
+				"project3": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"modules": map[string]interface{}{
+					"vpc": map[string]interface{}{
+						"main.tf": nil,
+					},
+					"app": map[string]interface{}{
+						"main.tf": `module "vpc" { source = "../vpc" }`,
+					},
+				},
+			})
+			defer cleanup()
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"modules/vpc/main.tf"}, nil)
+			if c.AtlantisYAML != "" {
+				err := ioutil.WriteFile(filepath.Join(tmpDir, yaml.AtlantisYAMLFilename), []byte(c.AtlantisYAML), 0600)
+				Ok(t, err)
+			}
+
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+				WorkingDir:        workingDir,
+				ParserValidator:   &yaml.ParserValidator{},
+				VCSClient:         vcsClient,
+				ProjectFinder:     &events.DefaultProjectFinder{},
+				PendingPlanFinder: &events.DefaultPendingPlanFinder{},
+				CommentBuilder:    &events.CommentParser{},
+				GlobalCfg:         valid.NewGlobalCfg(false, false, false),
+				AutoplanModules:   c.AutoplanModules,
+			}
+
+			ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{
+				PullMergeable: true,
+				Log:           logging.NewNoopLogger(),
+			})
+			Ok(t, err)
+			var actDirs []string
+			for _, actCtx := range ctxs {
+				actDirs = append(actDirs, actCtx.RepoRelDir)
+			}
+			Equals(t, c.expDirs, actDirs)
+		})
+	}
+}
+
 // Test building a plan and apply command for one project.
 func TestDefaultProjectCommandBuilder_BuildSinglePlanApplyCommand(t *testing.T) {
 	cases := []struct {
@@ -172,6 +268,8 @@ func TestDefaultProjectCommandBuilder_BuildSinglePlanApplyCommand(t *testing.T)
 		ExpProjectName string
 		ExpErr         string
 		ExpApplyReqs   []string
+		// ExpContainerImage is the image from the project's workflow.
+		ExpContainerImage string
 	}{
 		{
 			Description: "no atlantis.yaml",
@@ -320,6 +418,27 @@ projects:
 `,
 			ExpErr: "must specify project name: more than one project defined in atlantis.yaml matched dir: \".\" workspace: \"myworkspace\"",
 		},
+		{
+			Description: "atlantis.yaml with workflow image",
+			Cmd: events.CommentCommand{
+				RepoRelDir: ".",
+				Name:       models.PlanCommand,
+				Workspace:  "default",
+			},
+			AtlantisYAML: `
+version: 3
+projects:
+- dir: .
+  workflow: terragrunt
+workflows:
+  terragrunt:
+    image: alpine/terragrunt
+`,
+			ExpWorkspace:      "default",
+			ExpDir:            ".",
+			ExpApplyReqs:      []string{},
+			ExpContainerImage: "alpine/terragrunt",
+		},
 		{
 			Description: "atlantis.yaml with project flag not matching",
 			Cmd: events.CommentCommand{
@@ -387,6 +506,7 @@ projects:
 				Equals(t, c.ExpCommentArgs, actCtx.EscapedCommentArgs)
 				Equals(t, c.ExpProjectName, actCtx.ProjectName)
 				Equals(t, c.ExpApplyReqs, actCtx.ApplyRequirements)
+				Equals(t, c.ExpContainerImage, actCtx.ContainerImage)
 			})
 		}
 	}
@@ -586,14 +706,66 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 	Equals(t, 4, len(ctxs))
 	Equals(t, "project1", ctxs[0].RepoRelDir)
 	Equals(t, "workspace1", ctxs[0].Workspace)
-	Equals(t, "project2", ctxs[1].RepoRelDir)
-	Equals(t, "workspace1", ctxs[1].Workspace)
-	Equals(t, "project1", ctxs[2].RepoRelDir)
-	Equals(t, "workspace2", ctxs[2].Workspace)
+	Equals(t, "project1", ctxs[1].RepoRelDir)
+	Equals(t, "workspace2", ctxs[1].Workspace)
+	Equals(t, "project2", ctxs[2].RepoRelDir)
+	Equals(t, "workspace1", ctxs[2].Workspace)
 	Equals(t, "project2", ctxs[3].RepoRelDir)
 	Equals(t, "workspace2", ctxs[3].Workspace)
 }
 
+// Test that apply all skips plan only projects.
+func TestDefaultProjectCommandBuilder_BuildMultiApply_PlanOnly(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"default": map[string]interface{}{
+			"project1": map[string]interface{}{
+				"main.tf":        nil,
+				"default.tfplan": nil,
+			},
+			"project2": map[string]interface{}{
+				"main.tf":        nil,
+				"default.tfplan": nil,
+			},
+			yaml.AtlantisYAMLFilename: `
+version: 3
+projects:
+- dir: project1
+- dir: project2
+  plan_only: true
+`,
+		},
+	})
+	defer cleanup()
+	runCmd(t, filepath.Join(tmpDir, "default"), "git", "init")
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetPullDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest())).
+		ThenReturn(tmpDir, nil)
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		WorkingDir:        workingDir,
+		ParserValidator:   &yaml.ParserValidator{},
+		VCSClient:         nil,
+		ProjectFinder:     &events.DefaultProjectFinder{},
+		PendingPlanFinder: &events.DefaultPendingPlanFinder{},
+		CommentBuilder:    &events.CommentParser{},
+		GlobalCfg:         valid.NewGlobalCfg(false, false, false),
+	}
+
+	ctxs, err := builder.BuildApplyCommands(
+		&events.CommandContext{Log: logging.NewNoopLogger()},
+		&events.CommentCommand{
+			Name: models.ApplyCommand,
+		})
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "project1", ctxs[0].RepoRelDir)
+}
+
 // Test that if a directory has a list of workspaces configured then we don't
 // allow plans for other workspace names.
 func TestDefaultProjectCommandBuilder_WrongWorkspaceName(t *testing.T) {
@@ -657,6 +829,384 @@ projects:
 	ErrEquals(t, "running commands in workspace \"notconfigured\" is not allowed because this directory is only configured for the following workspaces: default, staging", err)
 }
 
+// Test that destroy plans are re-planned as destroy plans and that their
+// apply command confirms the destroy.
+func TestDefaultProjectCommandBuilder_Destroy(t *testing.T) {
+	RegisterMockTestingT(t)
+	workingDir := mocks.NewMockWorkingDir()
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+	})
+	defer cleanup()
+	When(workingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+	When(workingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		ParserValidator:  &yaml.ParserValidator{},
+		ProjectFinder:    &events.DefaultProjectFinder{},
+		CommentBuilder:   &events.CommentParser{},
+		GlobalCfg:        valid.NewGlobalCfg(true, false, false),
+	}
+	ctx := &events.CommandContext{Log: logging.NewNoopLogger()}
+
+	planCmd := events.NewCommentCommand(".", []string{"-var=a=b"}, models.PlanCommand, false, "", "")
+	planCmd.Destroy = true
+	pccs, err := builder.BuildPlanCommands(ctx, planCmd)
+	Ok(t, err)
+	Equals(t, 1, len(pccs))
+	Equals(t, true, pccs[0].Destroy)
+	Equals(t, "atlantis plan --destroy -d . -- -var=a=b", pccs[0].RePlanCmd)
+	Equals(t, "atlantis apply --confirm-destroy -d .", pccs[0].ApplyCmd)
+
+	applyCmd := events.NewCommentCommand(".", nil, models.ApplyCommand, false, "", "")
+	applyCmd.ConfirmDestroy = true
+	pccs, err = builder.BuildApplyCommands(ctx, applyCmd)
+	Ok(t, err)
+	Equals(t, true, pccs[0].ConfirmDestroy)
+	Equals(t, false, pccs[0].Destroy)
+	Equals(t, "atlantis apply -d .", pccs[0].ApplyCmd)
+}
+
+// Test that -target flags on apply are split out of the extra args so the
+// project is planned again with them.
+func TestDefaultProjectCommandBuilder_PartialApply(t *testing.T) {
+	RegisterMockTestingT(t)
+	workingDir := mocks.NewMockWorkingDir()
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+	})
+	defer cleanup()
+	When(workingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+	When(workingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		ParserValidator:  &yaml.ParserValidator{},
+		ProjectFinder:    &events.DefaultProjectFinder{},
+		CommentBuilder:   &events.CommentParser{},
+		GlobalCfg:        valid.NewGlobalCfg(true, false, false),
+	}
+	ctx := &events.CommandContext{Log: logging.NewNoopLogger()}
+
+	applyCmd := events.NewCommentCommand(".", []string{"-target=aws_instance.web", "-lock-timeout=5m", "--target", `module.db.aws_db_instance.main["a"]`}, models.ApplyCommand, false, "", "")
+	pccs, err := builder.BuildApplyCommands(ctx, applyCmd)
+	Ok(t, err)
+	Equals(t, 1, len(pccs))
+	Equals(t, []string{"aws_instance.web", `module.db.aws_db_instance.main["a"]`}, pccs[0].Targets)
+	Equals(t, []string{`\-\l\o\c\k\-\t\i\m\e\o\u\t\=\5\m`}, pccs[0].EscapedCommentArgs)
+	Equals(t, valid.NewGlobalCfg(true, false, false).Workflows["default"].Plan.Steps, pccs[0].PlanSteps)
+
+	// Plan steps are only set for partial applies.
+	pccs, err = builder.BuildApplyCommands(ctx, events.NewCommentCommand(".", nil, models.ApplyCommand, false, "", ""))
+	Ok(t, err)
+	Equals(t, 0, len(pccs[0].Targets))
+	Equals(t, 0, len(pccs[0].PlanSteps))
+}
+
+// Test that fmt_check and validate steps are added before the plan step of
+// workflows that don't already run them when pre-plan checks are enabled.
+func TestDefaultProjectCommandBuilder_PrePlanChecks(t *testing.T) {
+	cases := []struct {
+		description  string
+		atlantisYAML string
+		expSteps     []string
+	}{
+		{
+			description: "default workflow",
+			expSteps:    []string{"init", "fmt_check", "validate", "plan"},
+		},
+		{
+			description: "workflow that already validates",
+			atlantisYAML: `
+version: 3
+projects:
+- dir: .
+  workflow: custom
+workflows:
+  custom:
+    plan:
+      steps: [init, validate, plan]
+`,
+			expSteps: []string{"init", "validate", "plan"},
+		},
+		{
+			description: "workflow without a plan step",
+			atlantisYAML: `
+version: 3
+projects:
+- dir: .
+  workflow: custom
+workflows:
+  custom:
+    plan:
+      steps: [{run: ./plan.sh}]
+`,
+			expSteps: []string{"run"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			files := map[string]interface{}{"main.tf": nil}
+			if c.atlantisYAML != "" {
+				files["atlantis.yaml"] = c.atlantisYAML
+			}
+			tmpDir, cleanup := DirStructure(t, files)
+			defer cleanup()
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString())).ThenReturn(tmpDir, nil)
+
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				WorkingDir:       workingDir,
+				ParserValidator:  &yaml.ParserValidator{},
+				ProjectFinder:    &events.DefaultProjectFinder{},
+				CommentBuilder:   &events.CommentParser{},
+				GlobalCfg:        valid.NewGlobalCfg(true, false, false),
+				PrePlanChecks:    true,
+			}
+			pccs, err := builder.BuildPlanCommands(
+				&events.CommandContext{Log: logging.NewNoopLogger()},
+				events.NewCommentCommand(".", nil, models.PlanCommand, false, "", ""))
+			Ok(t, err)
+			Equals(t, 1, len(pccs))
+			var steps []string
+			for _, step := range pccs[0].Steps {
+				steps = append(steps, step.StepName)
+			}
+			Equals(t, c.expSteps, steps)
+		})
+	}
+}
+
+// Test that projects that discover their workspaces are planned in each of
+// their workspaces, except those configured as their own project.
+func TestDefaultProjectCommandBuilder_DiscoverWorkspaces(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+		"atlantis.yaml": `
+version: 3
+projects:
+- dir: .
+  discover_workspaces: true
+- dir: .
+  workspace: prod
+  name: prod
+`,
+	})
+	defer cleanup()
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"main.tf"}, nil)
+	lister := mocks.NewMockWorkspaceLister()
+	When(lister.List(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), tfmatchers.AnyPtrToGoVersionVersion())).
+		ThenReturn([]string{"default", "staging", "prod"}, nil)
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		WorkingDir:        workingDir,
+		ParserValidator:   &yaml.ParserValidator{},
+		VCSClient:         vcsClient,
+		ProjectFinder:     &events.DefaultProjectFinder{},
+		PendingPlanFinder: &events.DefaultPendingPlanFinder{},
+		CommentBuilder:    &events.CommentParser{},
+		GlobalCfg:         valid.NewGlobalCfg(false, false, false),
+		WorkspaceLister:   lister,
+	}
+
+	ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{Log: logging.NewNoopLogger()})
+	Ok(t, err)
+	Equals(t, 3, len(ctxs))
+	for i, exp := range []struct {
+		workspace string
+		discover  bool
+	}{
+		{"default", true},
+		{"prod", false},
+		{"staging", true},
+	} {
+		Equals(t, exp.workspace, ctxs[i].Workspace)
+		Equals(t, exp.discover, ctxs[i].DiscoverWorkspaces)
+	}
+	lister.VerifyWasCalledOnce().List(matchers.AnyPtrToLoggingSimpleLogger(), EqString(tmpDir), tfmatchers.AnyPtrToGoVersionVersion())
+
+	// A discovered workspace can be planned on its own.
+	ctxs, err = builder.BuildPlanCommands(
+		&events.CommandContext{Log: logging.NewNoopLogger()},
+		events.NewCommentCommand(".", nil, models.PlanCommand, false, "staging", ""))
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "staging", ctxs[0].Workspace)
+	Equals(t, true, ctxs[0].DiscoverWorkspaces)
+}
+
+// Test that with the git source, modified files are listed by diffing the
+// clone instead of with the VCS host's API.
+func TestDefaultProjectCommandBuilder_GitModifiedFilesSource(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{"main.tf": nil},
+		"project2": map[string]interface{}{"main.tf": nil},
+	})
+	defer cleanup()
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+	When(workingDir.GetMergeBaseModifiedFiles(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn([]string{"project2/main.tf"}, nil)
+	vcsClient := vcsmocks.NewMockClient()
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+		WorkingDir:          workingDir,
+		ParserValidator:     &yaml.ParserValidator{},
+		VCSClient:           vcsClient,
+		ProjectFinder:       &events.DefaultProjectFinder{},
+		PendingPlanFinder:   &events.DefaultPendingPlanFinder{},
+		CommentBuilder:      &events.CommentParser{},
+		GlobalCfg:           valid.NewGlobalCfg(false, false, false),
+		ModifiedFilesSource: events.GitModifiedFilesSource,
+	}
+
+	ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{Log: logging.NewNoopLogger()})
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "project2", ctxs[0].RepoRelDir)
+	workingDir.VerifyWasCalledOnce().GetMergeBaseModifiedFiles(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString(events.DefaultWorkspace))
+	vcsClient.VerifyWasCalled(Never()).GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+}
+
+func TestDefaultProjectCommandBuilder_Autodiscover(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"live": map[string]interface{}{
+			"prod": map[string]interface{}{
+				"vpc": map[string]interface{}{"main.tf": nil},
+			},
+		},
+		"sandbox": map[string]interface{}{"main.tf": nil},
+	})
+	defer cleanup()
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"live/prod/vpc/main.tf", "sandbox/main.tf"}, nil)
+	globalCfg := valid.NewGlobalCfg(false, false, false)
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		IDRegex:      regexp.MustCompile(".*"),
+		Autodiscover: &valid.Autodiscover{Include: []string{"live/**"}, ProjectMarkers: valid.DefaultProjectMarkers},
+	})
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		WorkingDir:        workingDir,
+		ParserValidator:   &yaml.ParserValidator{},
+		VCSClient:         vcsClient,
+		ProjectFinder:     &events.DefaultProjectFinder{},
+		PendingPlanFinder: &events.DefaultPendingPlanFinder{},
+		CommentBuilder:    &events.CommentParser{},
+		GlobalCfg:         globalCfg,
+	}
+
+	ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{Log: logging.NewNoopLogger()})
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "live/prod/vpc", ctxs[0].RepoRelDir)
+}
+
+func TestDefaultProjectCommandBuilder_VarFile(t *testing.T) {
+	cases := []struct {
+		description string
+		cmd         events.CommentCommand
+		expVarFile  string
+		expApplyCmd string
+		expErr      string
+	}{
+		{
+			description: "no var file",
+			cmd:         events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "project1"},
+			expApplyCmd: "atlantis apply -d project1",
+		},
+		{
+			description: "allowed var file",
+			cmd:         events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "project1", VarFile: "production.tfvars"},
+			expVarFile:  "production.tfvars",
+			expApplyCmd: "atlantis apply --var-file production.tfvars -d project1",
+		},
+		{
+			description: "var file that isn't allowed",
+			cmd:         events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "project1", VarFile: "other.tfvars"},
+			expErr:      `"other.tfvars" isn't one of the var_files of project at dir "project1" workspace "default": must be one of staging.tfvars, production.tfvars`,
+		},
+		{
+			description: "project without var files",
+			cmd:         events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "project2", VarFile: "production.tfvars"},
+			expErr:      `--var-file can't be used for project at dir "project2" workspace "default" since it has no var_files in atlantis.yaml`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+				"project1": map[string]interface{}{"main.tf": nil},
+				"project2": map[string]interface{}{"main.tf": nil},
+				"atlantis.yaml": `
+version: 3
+projects:
+- dir: project1
+  var_files: [staging.tfvars, production.tfvars]
+- dir: project2
+`,
+			})
+			defer cleanup()
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				WorkingDir:       workingDir,
+				ParserValidator:  &yaml.ParserValidator{},
+				ProjectFinder:    &events.DefaultProjectFinder{},
+				CommentBuilder:   &events.CommentParser{},
+				GlobalCfg:        valid.NewGlobalCfg(false, false, false),
+			}
+			ctxs, err := builder.BuildPlanCommands(&events.CommandContext{Log: logging.NewNoopLogger()}, &c.cmd)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(ctxs))
+			Equals(t, c.expVarFile, ctxs[0].VarFile)
+			Equals(t, c.expApplyCmd, ctxs[0].ApplyCmd)
+		})
+	}
+}
+
 // Test that extra comment args are escaped.
 func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 	cases := []struct {
@@ -887,3 +1437,135 @@ projects:
 		})
 	}
 }
+
+// Test that -p can select projects with lists and globs. Globs only match the
+// projects detected in the pull request.
+func TestDefaultProjectCommandBuilder_BuildPlanCommandsProjectPatterns(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"network": map[string]interface{}{
+			"a": map[string]interface{}{"main.tf": nil},
+			"b": map[string]interface{}{"main.tf": nil},
+		},
+		"app": map[string]interface{}{"main.tf": nil},
+		"db":  map[string]interface{}{"main.tf": nil},
+		yaml.AtlantisYAMLFilename: `
+version: 3
+projects:
+- name: network-a
+  dir: network/a
+- name: network-b
+  dir: network/b
+- name: app
+  dir: app
+- name: db
+  dir: db
+`,
+	})
+	defer cleanup()
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"network/a/main.tf", "network/b/main.tf", "db/main.tf"}, nil)
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		VCSClient:        vcsClient,
+		ParserValidator:  &yaml.ParserValidator{},
+		ProjectFinder:    &events.DefaultProjectFinder{},
+		CommentBuilder:   &events.CommentParser{},
+		GlobalCfg:        valid.NewGlobalCfg(true, false, false),
+	}
+
+	cases := map[string][]string{
+		"network-*":        {"network-a", "network-b"},
+		"network-*,app":    {"app", "network-a", "network-b"},
+		"app, db":          {"app", "db"},
+		"network-a,net*-?": {"network-a", "network-b"},
+		"network-[b]":      {"network-b"},
+	}
+	for project, expNames := range cases {
+		t.Run(project, func(t *testing.T) {
+			actCtxs, err := builder.BuildPlanCommands(&events.CommandContext{}, &events.CommentCommand{
+				Name:        models.PlanCommand,
+				ProjectName: project,
+			})
+			Ok(t, err)
+			var names []string
+			for _, actCtx := range actCtxs {
+				names = append(names, actCtx.ProjectName)
+			}
+			Equals(t, expNames, names)
+		})
+	}
+
+	_, err := builder.BuildPlanCommands(&events.CommandContext{}, &events.CommentCommand{
+		Name:        models.PlanCommand,
+		ProjectName: "app*",
+	})
+	ErrEquals(t, `no project matching "app*" was found in this pull request`, err)
+}
+
+// Test that the repo's default Terraform version from the server-side config
+// is used by projects that don't require a version and that projects run with
+// the repo's distribution.
+func TestDefaultProjectCommandBuilder_RepoTerraformDefault(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": "terraform {\n  required_version = \"0.12.8\"\n}\n",
+		},
+		"project2": map[string]interface{}{
+			"main.tf": nil,
+		},
+	})
+	defer cleanup()
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"project1/main.tf", "project2/main.tf"}, nil)
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+	When(workingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).ThenReturn(tmpDir, nil)
+
+	globalCfg := valid.NewGlobalCfg(true, false, false)
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		IDRegex:                 regexp.MustCompile(".*"),
+		DefaultTerraformVersion: version.Must(version.NewVersion("0.13.7")),
+		TerraformDistribution:   "opentofu",
+	})
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		VCSClient:        vcsClient,
+		ParserValidator:  &yaml.ParserValidator{},
+		ProjectFinder:    &events.DefaultProjectFinder{},
+		CommentBuilder:   &events.CommentParser{},
+		GlobalCfg:        globalCfg,
+	}
+
+	actCtxs, err := builder.BuildPlanCommands(
+		&events.CommandContext{},
+		&events.CommentCommand{
+			Name: models.PlanCommand,
+		})
+	Ok(t, err)
+	Equals(t, 2, len(actCtxs))
+	versions := make(map[string]string)
+	for _, actCtx := range actCtxs {
+		versions[actCtx.RepoRelDir] = actCtx.TerraformVersion.String()
+		Equals(t, "opentofu", actCtx.TerraformDistribution)
+	}
+	Equals(t, map[string]string{"project1": "0.12.8", "project2": "0.13.7"}, versions)
+}
@@ -15,11 +15,14 @@ package events
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/execution"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
@@ -70,6 +73,93 @@ type EnvStepRunner interface {
 	Run(ctx models.ProjectCommandContext, cmd string, value string, path string, envs map[string]string) (string, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_multi_env_step_runner.go MultiEnvStepRunner
+
+// MultiEnvStepRunner runs multienv steps.
+type MultiEnvStepRunner interface {
+	// Run cmd in path and return the environment variables it printed.
+	Run(ctx models.ProjectCommandContext, cmd string, path string, envs map[string]string) (map[string]string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_artifact_step_runner.go ArtifactStepRunner
+
+// ArtifactStepRunner runs upload_artifact steps.
+type ArtifactStepRunner interface {
+	// Run uploads the file at relPath, relative to the project at path, as
+	// the artifact called name.
+	Run(ctx models.ProjectCommandContext, name string, relPath string, path string) (string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_diagram_step_runner.go DiagramStepRunner
+
+// DiagramStepRunner runs diagram steps.
+type DiagramStepRunner interface {
+	// Run generates a diagram of the project at path with tool and uploads
+	// it.
+	Run(ctx models.ProjectCommandContext, tool string, path string) (string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_security_scan_step_runner.go SecurityScanStepRunner
+
+// SecurityScanStepRunner runs security_scan steps.
+type SecurityScanStepRunner interface {
+	// Run scans the project at path with the scanner in ctx's security
+	// scan config and returns its findings.
+	Run(ctx models.ProjectCommandContext, path string) (string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_summarizer.go PlanSummarizer
+
+// PlanSummarizer generates structured summaries of plans.
+type PlanSummarizer interface {
+	// Summarize returns a summary of the plan for the project at path or nil
+	// if a summary can't be generated.
+	Summarize(ctx models.ProjectCommandContext, path string) (*models.PlanSummary, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_terraform_cloud_runner.go TerraformCloudRunner
+
+// TerraformCloudRunner plans and applies projects in Terraform Cloud
+// workspaces.
+type TerraformCloudRunner interface {
+	// Plan runs a speculative plan of the project at path.
+	Plan(ctx models.ProjectCommandContext, path string) (string, error)
+	// Apply runs and confirms an apply of the project at path.
+	Apply(ctx models.ProjectCommandContext, path string) (string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_cloud_credential_assumer.go CloudCredentialAssumer
+
+// CloudCredentialAssumer gets short-lived credentials for the cloud identity
+// a project is configured with.
+type CloudCredentialAssumer interface {
+	// Assume returns the env vars that give the project's steps the
+	// credentials of cred. Their values are secret.
+	Assume(ctx models.ProjectCommandContext, cred valid.CloudCredential) (map[string]string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_secrets_provider.go SecretsProvider
+
+// SecretsProvider fetches the secrets that env steps read from a secrets
+// manager, ex. Vault.
+type SecretsProvider interface {
+	// Secret returns the value of the secret at ref, ex.
+	// secret/data/db#password.
+	Secret(ctx models.ProjectCommandContext, ref string) (string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_worker_dispatcher.go WorkerDispatcher
+
+// WorkerDispatcher runs the steps of projects on remote workers.
+type WorkerDispatcher interface {
+	// Plan runs ctx's plan steps on a worker and saves the generated planfile
+	// to the project at path.
+	Plan(ctx models.ProjectCommandContext, path string) ([]string, error)
+	// Apply runs ctx's apply steps on a worker with the planfile saved in the
+	// project at path.
+	Apply(ctx models.ProjectCommandContext, path string) ([]string, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_webhooks_sender.go WebhooksSender
 
 // WebhooksSender sends webhook.
@@ -87,26 +177,108 @@ type ProjectCommandRunner interface {
 	Plan(ctx models.ProjectCommandContext) models.ProjectResult
 	// Apply runs terraform apply for the project described by ctx.
 	Apply(ctx models.ProjectCommandContext) models.ProjectResult
+	// Diff plans the project described by each of ctxs, which only differ
+	// by workspace, and compares the plans.
+	Diff(ctxs []models.ProjectCommandContext) models.ProjectResult
+	// Custom runs the steps of the custom command in ctx for its project.
+	Custom(ctx models.ProjectCommandContext) models.ProjectResult
 }
 
 // DefaultProjectCommandRunner implements ProjectCommandRunner.
 type DefaultProjectCommandRunner struct {
-	Locker              ProjectLocker
-	LockURLGenerator    LockURLGenerator
-	InitStepRunner      StepRunner
-	PlanStepRunner      StepRunner
-	ApplyStepRunner     StepRunner
-	RunStepRunner       CustomStepRunner
-	EnvStepRunner       EnvStepRunner
-	PullApprovedChecker runtime.PullApprovedChecker
-	WorkingDir          WorkingDir
-	Webhooks            WebhooksSender
-	WorkingDirLocker    WorkingDirLocker
+	Locker                ProjectLocker
+	LockURLGenerator      LockURLGenerator
+	InitStepRunner        StepRunner
+	PlanStepRunner        StepRunner
+	ApplyStepRunner       StepRunner
+	RunStepRunner         CustomStepRunner
+	EnvStepRunner         EnvStepRunner
+	MultiEnvStepRunner    MultiEnvStepRunner
+	ArtifactStepRunner    ArtifactStepRunner
+	DiagramStepRunner     DiagramStepRunner
+	PullApprovedChecker   runtime.PullApprovedChecker
+	PullUndivergedChecker runtime.PullUndivergedChecker
+	// TeamMembershipChecker, if set, is used to check if approvers are
+	// members of the teams that are code owners. If nil, teams never match.
+	TeamMembershipChecker runtime.TeamMembershipChecker
+	WorkingDir            WorkingDir
+	Webhooks              WebhooksSender
+	WorkingDirLocker      WorkingDirLocker
+	// ConcurrencyLimiter limits how many steps can run at once for projects
+	// in the same concurrency group or repo and for each project. If nil,
+	// steps are never limited.
+	ConcurrencyLimiter *ConcurrencyLimiter
+	// JobLogs, if set, gets the output of each step as it completes so it
+	// can be streamed. Steps run on remote workers aren't streamed.
+	JobLogs *JobLogs
+	// PlanSummarizer, if set, is used to add a structured summary to
+	// successful plans.
+	PlanSummarizer PlanSummarizer
+	// Redactor scrubs secrets from the output of each step. If nil, nothing
+	// is redacted.
+	Redactor *Redactor
+	// LockOnApply is true if projects are locked when they're applied rather
+	// than when they're planned, so pull requests can plan the same project
+	// at the same time.
+	LockOnApply bool
+	// PlanOutputMaxBytes is the most bytes of plan output to comment. Longer
+	// output is truncated with TruncatePlanOutput. 0 means no limit.
+	PlanOutputMaxBytes int
+	// PlanOutputStore and PlanOutputURLGenerator, if set, are used to link
+	// to the full output of truncated plans and, from commit statuses, to the
+	// output of failed plans and of applies.
+	PlanOutputStore        PlanOutputStore
+	PlanOutputURLGenerator PlanOutputURLGenerator
+	// PlanOutputUploader, if set, uploads the full output of truncated plans
+	// instead of PlanOutputStore, ex. to S3.
+	PlanOutputUploader PlanOutputUploader
+	// TerraformCloudRunner plans and applies projects that are configured
+	// with terraform_cloud instead of running their workflow's steps.
+	TerraformCloudRunner TerraformCloudRunner
+	// WorkerDispatcher, if set, runs the steps of projects on remote workers
+	// instead of on this server.
+	WorkerDispatcher WorkerDispatcher
+	// CloudCredentialAssumer assumes the cloud identity of projects that are
+	// configured with one before their steps are run.
+	CloudCredentialAssumer CloudCredentialAssumer
+	// SecretsProvider fetches the secrets of env steps that read them from
+	// Vault. If nil, those steps fail.
+	SecretsProvider SecretsProvider
+	// SecurityScanStepRunner runs security_scan steps. Findings it marks as
+	// blocking stop the plan from being applied.
+	SecurityScanStepRunner SecurityScanStepRunner
+	// FmtCheckStepRunner and ValidateStepRunner run fmt_check and validate
+	// steps, which fail the plan early if the project's files aren't
+	// formatted or its configuration isn't valid.
+	FmtCheckStepRunner StepRunner
+	ValidateStepRunner StepRunner
+	// PlanEncrypter, if set, encrypts planfiles between plan and apply.
+	PlanEncrypter *PlanEncrypter
+	// Authorizer, if set, is asked whether each command can be run for a
+	// project before it is. Commands it denies fail with its reason.
+	Authorizer *CommandAuthorizer
+	// DefaultTimeouts are how long init, plan, apply and run steps can run
+	// for if neither they nor their repo's config set a timeout. 0 means
+	// they can run for as long as they need.
+	DefaultTimeouts valid.Timeouts
+	// Now is checked against the apply windows of projects. If nil, time.Now
+	// is used.
+	Now func() time.Time
 }
 
 // Plan runs terraform plan for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) models.ProjectResult {
-	planSuccess, failure, err := p.doPlan(ctx)
+	var planSuccess *models.PlanSuccess
+	failure, err := p.authorizationFailure(ctx, models.PlanCommand)
+	if failure == "" && err == nil {
+		planSuccess, failure, err = p.doPlan(ctx)
+	}
+	var outputURL string
+	if err != nil {
+		outputURL = p.storeOutput(ctx, false, err.Error())
+	} else if failure != "" {
+		outputURL = p.storeOutput(ctx, false, failure)
+	}
 	return models.ProjectResult{
 		Command:     models.PlanCommand,
 		PlanSuccess: planSuccess,
@@ -115,33 +287,298 @@ func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) mod
 		RepoRelDir:  ctx.RepoRelDir,
 		Workspace:   ctx.Workspace,
 		ProjectName: ctx.ProjectName,
+		// Each workspace of projects that discover them is commented
+		// separately since there can be many.
+		CommentSeparately: ctx.DiscoverWorkspaces,
+		OutputURL:         outputURL,
 	}
 }
 
-// Apply runs terraform apply for the project described by ctx.
-func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
-	applyOut, failure, err := p.doApply(ctx)
+// diffWorkingDirPrefix is prefixed to the workspace of the working dirs that
+// diffs are planned in. Commas can't be used in workspace names so they can't
+// clash with the working dirs of plans.
+const diffWorkingDirPrefix = "diff,"
+
+// Diff plans the project described by each of ctxs and compares the plans.
+// The plans are made in their own working dirs that are deleted afterwards so
+// they can't be applied and don't replace any plans that are waiting to be
+// applied.
+func (p *DefaultProjectCommandRunner) Diff(ctxs []models.ProjectCommandContext) models.ProjectResult {
+	var workspaces []string
+	for _, ctx := range ctxs {
+		workspaces = append(workspaces, ctx.Workspace)
+	}
+	res := models.ProjectResult{
+		Command:     models.DiffCommand,
+		RepoRelDir:  ctxs[0].RepoRelDir,
+		Workspace:   strings.Join(workspaces, ","),
+		ProjectName: ctxs[0].ProjectName,
+	}
+	if ctxs[0].TerraformCloud != nil {
+		res.Failure = "Projects that run in Terraform Cloud can't be diffed."
+		return res
+	}
+	res.Failure, res.Error = p.authorizationFailure(ctxs[0], models.DiffCommand)
+	if res.Failure != "" || res.Error != nil {
+		return res
+	}
+
+	diff := &models.DiffSuccess{}
+	for _, ctx := range ctxs {
+		plan, err := p.doDiffPlan(ctx)
+		if err != nil {
+			res.Error = errors.Wrapf(err, "planning workspace %q", ctx.Workspace)
+			return res
+		}
+		diff.Plans = append(diff.Plans, plan)
+	}
+	res.DiffSuccess = diff
+	return res
+}
+
+// doDiffPlan plans the project in ctx in a temporary working dir.
+func (p *DefaultProjectCommandRunner) doDiffPlan(ctx models.ProjectCommandContext) (models.WorkspacePlan, error) {
+	plan := models.WorkspacePlan{Workspace: ctx.Workspace}
+	workingDirKey := diffWorkingDirPrefix + ctx.Workspace
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workingDirKey)
+	if err != nil {
+		return plan, err
+	}
+	defer unlockFn()
+
+	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workingDirKey)
+	defer func() {
+		if err := p.WorkingDir.DeleteForWorkspace(ctx.BaseRepo, ctx.Pull, workingDirKey); err != nil {
+			ctx.Log.Warn("unable to delete diff working dir: %s", err)
+		}
+	}()
+	if err != nil {
+		return plan, err
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err := os.Stat(projAbsPath); os.IsNotExist(err) {
+		return plan, DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	outputs, err := p.runPlanSteps(ctx, projAbsPath)
+	if err != nil {
+		return plan, fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+	plan.TerraformOutput = strings.Join(outputs, "\n")
+	if p.PlanOutputMaxBytes > 0 && len(plan.TerraformOutput) > p.PlanOutputMaxBytes {
+		plan.TerraformOutput = TruncatePlanOutput(plan.TerraformOutput, p.PlanOutputMaxBytes)
+	}
+	// See doPlan for why plans run on workers aren't summarized.
+	if p.PlanSummarizer != nil && p.WorkerDispatcher == nil {
+		plan.Summary, err = p.PlanSummarizer.Summarize(ctx, projAbsPath)
+		if err != nil {
+			ctx.Log.Warn("unable to summarize plan: %s", err)
+		}
+	}
+	return plan, nil
+}
+
+// customWorkingDirPrefix is prefixed to the workspace of the working dirs
+// that custom commands are run in. Like diffWorkingDirPrefix, it can't clash
+// with the working dirs of plans.
+const customWorkingDirPrefix = "custom,"
+
+// Custom runs the steps of the custom command in ctx for its project. The
+// steps run in their own working dir that's deleted afterwards so they can't
+// change the plans that are waiting to be applied.
+func (p *DefaultProjectCommandRunner) Custom(ctx models.ProjectCommandContext) models.ProjectResult {
+	var out string
+	failure, err := p.authorizationFailure(ctx, models.CustomCommand)
+	if failure == "" && err == nil {
+		out, failure, err = p.doCustom(ctx)
+	}
 	return models.ProjectResult{
-		Command:      models.ApplyCommand,
-		Failure:      failure,
+		Command:      models.CustomCommand,
+		CustomOutput: out,
 		Error:        err,
-		ApplySuccess: applyOut,
+		Failure:      failure,
 		RepoRelDir:   ctx.RepoRelDir,
 		Workspace:    ctx.Workspace,
 		ProjectName:  ctx.ProjectName,
 	}
 }
 
-func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*models.PlanSuccess, string, error) {
-	// Acquire Atlantis lock for this repo/dir/workspace.
-	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir))
+func (p *DefaultProjectCommandRunner) doCustom(ctx models.ProjectCommandContext) (string, string, error) {
+	if ctx.CustomCommand == nil {
+		return "", "", errors.New("project isn't configured with a custom command–this is a bug")
+	}
+	if ctx.TerraformCloud != nil {
+		return "", "Projects that run in Terraform Cloud can't run custom commands.", nil
+	}
+	// Like plans, the lock is kept if the command succeeds.
+	lockAttempt := &TryLockResponse{
+		LockAcquired: true,
+		UnlockFn:     func() error { return nil },
+	}
+	if ctx.CustomCommand.Lock {
+		var err error
+		lockAttempt, err = p.lockProject(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		if !lockAttempt.LockAcquired {
+			return "", lockAttempt.LockFailureReason, nil
+		}
+	}
+	unlockOnErr := func() {
+		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+			ctx.Log.Err("error unlocking state after %s error: %v", ctx.CustomCommand.Name, unlockErr)
+		}
+	}
+
+	workingDirKey := customWorkingDirPrefix + ctx.Workspace
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workingDirKey)
+	if err != nil {
+		unlockOnErr()
+		return "", "", err
+	}
+	defer unlockFn()
+
+	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, workingDirKey)
+	defer func() {
+		if err := p.WorkingDir.DeleteForWorkspace(ctx.BaseRepo, ctx.Pull, workingDirKey); err != nil {
+			ctx.Log.Warn("unable to delete %s working dir: %s", ctx.CustomCommand.Name, err)
+		}
+	}()
 	if err != nil {
-		return nil, "", errors.Wrap(err, "acquiring lock")
+		unlockOnErr()
+		return "", "", err
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err := os.Stat(projAbsPath); os.IsNotExist(err) {
+		unlockOnErr()
+		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	outputs, err := p.runSteps(ctx.CustomCommand.Steps, ctx, projAbsPath)
+	if err != nil {
+		unlockOnErr()
+		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+	return strings.Join(outputs, "\n"), "", nil
+}
+
+// PlanOnlyApplyFailure is the failure returned when applying a project that's
+// set to plan_only in the repo's atlantis.yaml.
+const PlanOnlyApplyFailure = "This project is set to plan only so Atlantis won't apply it. Apply it with the pipeline that owns its applies instead."
+
+// ApplyAfterMergeFailure is the failure returned when applying a project from
+// a pull request in a repo that's applied after pull requests are merged.
+const ApplyAfterMergeFailure = "This repo's projects are applied by Atlantis once pull requests are merged into the default branch, not from pull requests. Merge this pull request to apply it."
+
+// Apply runs terraform apply for the project described by ctx.
+func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
+	res := p.apply(ctx)
+	switch {
+	case res.Error != nil:
+		res.OutputURL = p.storeOutput(ctx, true, res.Error.Error())
+	case res.Failure != "":
+		res.OutputURL = p.storeOutput(ctx, true, res.Failure)
+	default:
+		res.OutputURL = p.storeOutput(ctx, true, res.ApplySuccess)
+	}
+	return res
+}
+
+func (p *DefaultProjectCommandRunner) apply(ctx models.ProjectCommandContext) models.ProjectResult {
+	res := models.ProjectResult{
+		Command:     models.ApplyCommand,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		ProjectName: ctx.ProjectName,
+	}
+	res.Failure, res.Error = p.authorizationFailure(ctx, models.ApplyCommand)
+	if res.Failure != "" || res.Error != nil {
+		return res
+	}
+	if ctx.ApplyAfterMerge != nil && !ctx.PlanOnly {
+		res.Failure = ApplyAfterMergeFailure
+		return res
+	}
+	if !ctx.PlanOnly {
+		res.Failure, res.ApplyQueuedUntil, res.Error = p.applyWindowFailure(ctx)
+		if res.Failure != "" || res.Error != nil {
+			return res
+		}
+	}
+	res.ApplySuccess, res.Failure, res.Error = p.doApply(ctx)
+	return res
+}
+
+// applyWindowFailure returns why the project can't be applied now because
+// its apply windows are closed, or an empty string if it can. If the apply
+// is queued, it also returns when it's queued until.
+func (p *DefaultProjectCommandRunner) applyWindowFailure(ctx models.ProjectCommandContext) (string, time.Time, error) {
+	windows := ctx.ApplyWindows
+	if windows == nil {
+		return "", time.Time{}, nil
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	t := now()
+	if windows.IsOpen(t) {
+		return "", time.Time{}, nil
+	}
+	if ctx.BreakGlass {
+		allowed, err := p.ownerApproved(ctx.BaseRepo, windows.BreakGlass, []string{ctx.User.Username}, make(map[string]bool))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if !allowed {
+			return fmt.Sprintf("@%s isn't allowed to apply outside this project's apply windows with `--%s`.", ctx.User.Username, breakGlassFlagLong), time.Time{}, nil
+		}
+		ctx.Log.Warn("%s is applying outside the apply windows with --%s", ctx.User.Username, breakGlassFlagLong)
+		return "", time.Time{}, nil
+	}
+
+	var schedules []string
+	for _, w := range windows.Windows {
+		schedules = append(schedules, fmt.Sprintf("`%s` (%s)", w.Schedule, w.Location))
+	}
+	failure := fmt.Sprintf("This project can only be applied during its apply windows: %s.", strings.Join(schedules, ", "))
+	next, ok := windows.NextOpen(t)
+	queued := ok && windows.Outside == valid.QueueOutsideApplyWindows
+	switch {
+	case !ok:
+		failure += " None of them open in the next year."
+	case queued:
+		failure += fmt.Sprintf(" The apply is queued until the next window opens at %s.", next.Format("2006-01-02 15:04 MST"))
+	default:
+		failure += fmt.Sprintf(" The next window opens at %s.", next.Format("2006-01-02 15:04 MST"))
+	}
+	if len(windows.BreakGlass) > 0 {
+		failure += fmt.Sprintf(" In an emergency, one of %s can apply it now by commenting:\n* `%s`", strings.Join(windows.BreakGlass, ", "), withCommandFlag(ctx.ApplyCmd, models.ApplyCommand, breakGlassFlagLong))
+	}
+	if !queued {
+		return failure, time.Time{}, nil
+	}
+	return failure, next, nil
+}
+
+func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*models.PlanSuccess, string, error) {
+	// Acquire Atlantis lock for this repo/dir/workspace unless it's acquired
+	// by apply instead.
+	lockAttempt := &TryLockResponse{
+		LockAcquired: true,
+		UnlockFn:     func() error { return nil },
 	}
-	if !lockAttempt.LockAcquired {
-		return nil, lockAttempt.LockFailureReason, nil
+	if !p.LockOnApply {
+		var err error
+		lockAttempt, err = p.lockProject(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if !lockAttempt.LockAcquired {
+			return nil, lockAttempt.LockFailureReason, nil
+		}
 	}
-	ctx.Log.Debug("acquired lock for project")
 
 	// Acquire internal lock for the directory we're going to operate in.
 	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
@@ -159,61 +596,453 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 		return nil, "", cloneErr
 	}
 	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
-	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
+	if _, err := os.Stat(projAbsPath); os.IsNotExist(err) {
 		return nil, "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, projAbsPath)
+	// The findings of a previous plan's security scan are removed so they
+	// aren't reported if the workflow no longer scans the project.
+	if err := os.Remove(runtime.SecurityFindingsPath(ctx, projAbsPath)); err != nil && !os.IsNotExist(err) {
+		ctx.Log.Warn("unable to remove previous security findings: %s", err)
+	}
+	outputs, err := p.runPlanSteps(ctx, projAbsPath)
 	if err != nil {
+		removeCancelledPlan(ctx, projAbsPath)
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
 		return nil, "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
 	}
+	findings, err := runtime.ReadSecurityFindings(ctx, projAbsPath)
+	if err != nil {
+		ctx.Log.Warn("unable to read security findings: %s", err)
+	}
+	err = markDestroyPlan(ctx, projAbsPath)
+	if err == nil {
+		err = markVarFile(ctx, projAbsPath)
+	}
+	if err != nil {
+		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
+		}
+		return nil, "", err
+	}
+
+	var summary *models.PlanSummary
+	// Plans in Terraform Cloud aren't saved locally so they can't be
+	// summarized, and plans run on workers are summarized by the server's
+	// terraform binary so they're skipped too.
+	if p.PlanSummarizer != nil && ctx.TerraformCloud == nil && p.WorkerDispatcher == nil {
+		summary, err = p.PlanSummarizer.Summarize(ctx, projAbsPath)
+		if err != nil {
+			ctx.Log.Warn("unable to summarize plan: %s", err)
+		}
+	}
+	violations := GuardrailViolations(ctx.Guardrails, summary)
+	err = markGuardrailViolations(ctx, projAbsPath, violations)
+	// The plan is encrypted last since it's read to summarize it.
+	if err == nil && p.PlanEncrypter != nil {
+		err = p.PlanEncrypter.Encrypt(ctx, planPath(ctx, projAbsPath))
+	}
+	if err != nil {
+		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
+		}
+		return nil, "", err
+	}
 
+	var lockURL string
+	if lockAttempt.LockKey != "" {
+		lockURL = p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey)
+	}
+	output := strings.Join(outputs, "\n")
+	var fullOutputURL string
+	if p.PlanOutputMaxBytes > 0 && len(output) > p.PlanOutputMaxBytes {
+		fullOutputURL = p.storePlanOutput(ctx, output)
+		output = TruncatePlanOutput(output, p.PlanOutputMaxBytes)
+	}
 	return &models.PlanSuccess{
-		LockURL:         p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey),
-		TerraformOutput: strings.Join(outputs, "\n"),
-		RePlanCmd:       ctx.RePlanCmd,
-		ApplyCmd:        ctx.ApplyCmd,
-		HasDiverged:     hasDiverged,
+		LockURL:          lockURL,
+		LockID:           lockAttempt.LockKey,
+		FullOutputURL:    fullOutputURL,
+		TerraformOutput:  output,
+		RePlanCmd:        ctx.RePlanCmd,
+		ApplyCmd:         ctx.ApplyCmd,
+		PlanOnly:         ctx.PlanOnly,
+		HasDiverged:      hasDiverged,
+		Destroy:          ctx.Destroy,
+		Summary:          summary,
+		VarFile:          ctx.VarFile,
+		Fingerprint:      p.fingerprintPlan(ctx),
+		SecurityFindings: findings,
+
+		GuardrailViolations: violations,
 	}, "", nil
 }
 
+// fingerprintPlan returns what the plan of ctx was made from so it can be
+// reused. It's empty if that can't be determined or if the plan isn't saved
+// locally.
+func (p *DefaultProjectCommandRunner) fingerprintPlan(ctx models.ProjectCommandContext) models.PlanFingerprint {
+	if ctx.TerraformCloud != nil {
+		return models.PlanFingerprint{}
+	}
+	baseCommit, err := p.WorkingDir.GetMergedBaseCommit(ctx.BaseRepo, ctx.Pull, ctx.Workspace)
+	if err != nil {
+		ctx.Log.Warn("unable to fingerprint plan so it won't be reused: %s", err)
+		return models.PlanFingerprint{}
+	}
+	return planFingerprint(ctx, baseCommit)
+}
+
+// planFingerprint returns the fingerprint of planning the project of ctx with
+// the base branch at baseCommit, which is empty if it isn't merged.
+func planFingerprint(ctx models.ProjectCommandContext, baseCommit string) models.PlanFingerprint {
+	args := append([]string{}, ctx.EscapedCommentArgs...)
+	if ctx.Destroy {
+		args = append(args, "--"+destroyFlagLong)
+	}
+	if ctx.VarFile != "" {
+		args = append(args, "--"+varFileFlagLong, ctx.VarFile)
+	}
+	return models.PlanFingerprint{
+		HeadCommit: ctx.Pull.HeadCommit,
+		BaseCommit: baseCommit,
+		Args:       strings.Join(args, " "),
+	}
+}
+
+// destroyMarkerPath returns the path of the file that marks the project's plan
+// as a destroy plan. It's next to the planfile so it's deleted with the
+// clone.
+func destroyMarkerPath(ctx models.ProjectCommandContext, absPath string) string {
+	return filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)+".destroy")
+}
+
+// markDestroyPlan marks the project's plan as a destroy plan if it is one so
+// it can't be applied without confirmation, or removes the mark left by a
+// previous destroy plan. It's only called after a successful plan since a
+// failed plan may leave the previous planfile behind.
+func markDestroyPlan(ctx models.ProjectCommandContext, absPath string) error {
+	marker := destroyMarkerPath(ctx, absPath)
+	if ctx.Destroy {
+		return errors.Wrap(ioutil.WriteFile(marker, nil, 0600), "marking destroy plan")
+	}
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "removing destroy plan mark")
+	}
+	return nil
+}
+
+// varFileMarkerPath returns the path of the file that records the .tfvars
+// file selected with --var-file for the project's plan.
+func varFileMarkerPath(ctx models.ProjectCommandContext, absPath string) string {
+	return filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)+".varfile")
+}
+
+// markVarFile records the .tfvars file the project was planned with so it's
+// applied with the same one, or removes the record of a previous plan's file.
+func markVarFile(ctx models.ProjectCommandContext, absPath string) error {
+	marker := varFileMarkerPath(ctx, absPath)
+	if ctx.VarFile != "" {
+		return errors.Wrap(ioutil.WriteFile(marker, []byte(ctx.VarFile), 0600), "recording var file")
+	}
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "removing var file record")
+	}
+	return nil
+}
+
+// storePlanOutput saves the full output of a plan that's going to be
+// truncated and returns the URL to view it, or an empty string if it can't be
+// saved.
+func (p *DefaultProjectCommandRunner) storePlanOutput(ctx models.ProjectCommandContext, output string) string {
+	if p.PlanOutputUploader != nil {
+		url, err := p.PlanOutputUploader.UploadPlanOutput(ctx, output)
+		if err != nil {
+			ctx.Log.Warn("unable to upload full plan output: %s", err)
+			return ""
+		}
+		return url
+	}
+	return p.storeOutput(ctx, false, output)
+}
+
+// storeOutput saves the output of the plan, or the apply if apply is true, of
+// the project in ctx in PlanOutputStore so it can be viewed in the UI. It
+// returns the URL to view it or an empty string if it can't be saved.
+func (p *DefaultProjectCommandRunner) storeOutput(ctx models.ProjectCommandContext, apply bool, output string) string {
+	if p.PlanOutputStore == nil || p.PlanOutputURLGenerator == nil {
+		return ""
+	}
+	id, err := p.PlanOutputStore.PutPlanOutput(models.PlanOutput{
+		Pull:        ctx.Pull,
+		ProjectName: ctx.ProjectName,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		Output:      output,
+		CreatedAt:   time.Now().UTC(),
+		Apply:       apply,
+	})
+	if err != nil {
+		ctx.Log.Warn("unable to save output: %s", err)
+		return ""
+	}
+	return p.PlanOutputURLGenerator.GeneratePlanOutputURL(id)
+}
+
+// lockProject acquires the Atlantis lock for the project in ctx.
+func (p *DefaultProjectCommandRunner) lockProject(ctx models.ProjectCommandContext) (*TryLockResponse, error) {
+	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir))
+	if err != nil {
+		return nil, errors.Wrap(err, "acquiring lock")
+	}
+	if lockAttempt.LockAcquired {
+		ctx.Log.Debug("acquired lock for project")
+	}
+	return lockAttempt, nil
+}
+
+// runPlanSteps plans the project at absPath in Terraform Cloud if it's
+// configured to use it, or else runs its plan steps, on a worker if workers
+// are enabled.
+func (p *DefaultProjectCommandRunner) runPlanSteps(ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	if ctx.TerraformCloud == nil && p.WorkerDispatcher != nil {
+		return p.dispatchSteps(ctx, absPath, p.WorkerDispatcher.Plan)
+	}
+	if ctx.TerraformCloud == nil {
+		return p.runSteps(ctx.Steps, ctx, absPath)
+	}
+	if p.TerraformCloudRunner == nil {
+		return nil, errors.New("Terraform Cloud isn't configured on this server")
+	}
+	out, err := p.TerraformCloudRunner.Plan(ctx, absPath)
+	return []string{p.Redactor.Redact(out)}, err
+}
+
+// runApplySteps applies the project at absPath in Terraform Cloud if it's
+// configured to use it, or else runs its apply steps, on a worker if workers
+// are enabled.
+func (p *DefaultProjectCommandRunner) runApplySteps(ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	if ctx.TerraformCloud == nil && p.WorkerDispatcher != nil {
+		return p.dispatchSteps(ctx, absPath, p.WorkerDispatcher.Apply)
+	}
+	if ctx.TerraformCloud == nil {
+		return p.runSteps(ctx.Steps, ctx, absPath)
+	}
+	if p.TerraformCloudRunner == nil {
+		return nil, errors.New("Terraform Cloud isn't configured on this server")
+	}
+	out, err := p.TerraformCloudRunner.Apply(ctx, absPath)
+	return []string{p.Redactor.Redact(out)}, err
+}
+
+// dispatchSteps runs ctx's steps on a worker with dispatch. Concurrency limits
+// are still enforced by this server.
+func (p *DefaultProjectCommandRunner) dispatchSteps(ctx models.ProjectCommandContext, absPath string, dispatch func(models.ProjectCommandContext, string) ([]string, error)) ([]string, error) {
+	release := p.ConcurrencyLimiter.Acquire(ctx)
+	defer release()
+
+	outputs, err := dispatch(ctx, absPath)
+	for i, out := range outputs {
+		outputs[i] = p.Redactor.Redact(out)
+	}
+	if err != nil {
+		if redacted := p.Redactor.Redact(err.Error()); redacted != err.Error() {
+			err = errors.New(redacted)
+		}
+	}
+	return outputs, err
+}
+
+// RunSteps runs ctx's steps for the project at absPath. Workers use it to run
+// the jobs dispatched to them.
+func (p *DefaultProjectCommandRunner) RunSteps(ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	return p.runSteps(ctx.Steps, ctx, absPath)
+}
+
 func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	release := p.ConcurrencyLimiter.Acquire(ctx)
+	defer release()
+	jobKey := JobKey(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.RepoRelDir, ctx.Workspace)
+	p.JobLogs.Start(jobKey)
+	defer p.JobLogs.Finish(jobKey)
+
 	var outputs []string
 	envs := make(map[string]string)
+	if ctx.ContainerImage != "" {
+		// Execution backends run commands in the image set in this env var.
+		envs[execution.ContainerImageEnvVar] = ctx.ContainerImage
+	}
+	if ctx.Done != nil {
+		// Execution backends stop commands once they're cancelled.
+		cancelKey, unregister := execution.RegisterCancel(ctx.Done)
+		defer unregister()
+		envs[execution.CancelEnvVar] = cancelKey
+	}
+	// Values read from the server's environment are likely secrets so
+	// they're redacted like the cloud credentials.
+	var secrets []string
+	for _, e := range ctx.EnvVars {
+		if e.FromEnv == "" {
+			envs[e.Name] = e.Value
+			continue
+		}
+		value, ok := os.LookupEnv(e.FromEnv)
+		if !ok {
+			return nil, fmt.Errorf("env var %s is read from %s but it isn't set in the server's environment", e.Name, e.FromEnv)
+		}
+		envs[e.Name] = value
+		secrets = append(secrets, value)
+	}
+	gitEnvs, gitSecrets, cleanupGitCredentials, err := gitCredentialEnvs(ctx.GitCredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupGitCredentials()
+	for k, v := range gitEnvs {
+		envs[k] = v
+	}
+	secrets = append(secrets, gitSecrets...)
+	// The credentials are assumed after waiting for the concurrency group so
+	// they don't expire while waiting.
+	if ctx.CloudCredential != nil {
+		if p.CloudCredentialAssumer == nil {
+			return nil, errors.New("project is configured with cloud_credentials but assuming them isn't supported by this server")
+		}
+		credEnvs, err := p.CloudCredentialAssumer.Assume(ctx, *ctx.CloudCredential)
+		if err != nil {
+			return nil, errors.Wrapf(err, "assuming cloud credentials %s", ctx.CloudCredential)
+		}
+		for k, v := range credEnvs {
+			envs[k] = v
+			secrets = append(secrets, v)
+		}
+	}
 	for _, step := range steps {
+		if isDone(ctx.Done) {
+			return outputs, fmt.Errorf("%s step was skipped because the command was cancelled", step.StepName)
+		}
+		span := ctx.Span.Start("step " + step.StepName)
 		var out string
 		var err error
+		timeout := p.stepTimeout(step, ctx)
 		switch step.StepName {
 		case "init":
-			out, err = p.InitStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+			out, err = p.InitStepRunner.Run(ctx, step.ExtraArgs, absPath, withTimeout(envs, timeout))
 		case "plan":
-			out, err = p.PlanStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+			out, err = p.PlanStepRunner.Run(ctx, step.ExtraArgs, absPath, withTimeout(envs, timeout))
 		case "apply":
-			out, err = p.ApplyStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+			out, err = p.ApplyStepRunner.Run(ctx, step.ExtraArgs, absPath, withTimeout(envs, timeout))
 		case "run":
-			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath, envs)
+			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath, withTimeout(envs, timeout))
 		case "env":
-			out, err = p.EnvStepRunner.Run(ctx, step.RunCommand, step.EnvVarValue, absPath, envs)
+			if step.EnvVarSecret != "" {
+				out, err = p.fetchSecret(ctx, step.EnvVarSecret)
+				secrets = append(secrets, out)
+			} else {
+				out, err = p.EnvStepRunner.Run(ctx, step.RunCommand, step.EnvVarValue, absPath, envs)
+			}
 			envs[step.EnvVarName] = out
 			// We reset out to the empty string because we don't want it to
 			// be printed to the PR, it's solely to set the environment variable.
 			out = ""
+		case "multienv":
+			var vars map[string]string
+			vars, err = p.MultiEnvStepRunner.Run(ctx, step.RunCommand, absPath, envs)
+			for k, v := range vars {
+				envs[k] = v
+			}
+		case "upload_artifact":
+			out, err = p.ArtifactStepRunner.Run(ctx, step.ArtifactName, step.ArtifactPath, absPath)
+		case "diagram":
+			out, err = p.DiagramStepRunner.Run(ctx, step.DiagramTool, absPath)
+		case "security_scan":
+			out, err = p.SecurityScanStepRunner.Run(ctx, absPath)
+		case "fmt_check":
+			out, err = p.FmtCheckStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+		case "validate":
+			out, err = p.ValidateStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
 		}
 
+		out = p.Redactor.RedactWithSecrets(out, envs, secrets)
 		if out != "" {
 			outputs = append(outputs, out)
 		}
 		if err != nil {
+			if _, ok := errors.Cause(err).(*execution.TimeoutError); ok {
+				err = fmt.Errorf("%s step was stopped because it ran for longer than its timeout of %s. Its timeout can be raised in its workflow, the timeouts of the server-side repo config or with --%s-timeout.\n\n%s", step.StepName, timeout, step.StepName, err)
+			} else if errors.Cause(err) == execution.ErrCancelled {
+				err = fmt.Errorf("%s step was stopped because the command was cancelled", step.StepName)
+			}
+			// Errors usually include the step's output.
+			if redacted := p.Redactor.RedactWithSecrets(err.Error(), envs, secrets); redacted != err.Error() {
+				err = errors.New(redacted)
+			}
+			p.JobLogs.Append(jobKey, JobLogLine{Step: step.StepName, Output: err.Error(), Time: time.Now()})
+			endSpan(span, err)
 			return outputs, err
 		}
+		p.JobLogs.Append(jobKey, JobLogLine{Step: step.StepName, Output: out, Time: time.Now()})
+		span.End()
 	}
 	return outputs, nil
 }
 
+// stepTimeout returns how long step can run for. The step's own timeout is
+// used first, then its repo's and then the server's. 0 means there's no
+// timeout.
+func (p *DefaultProjectCommandRunner) stepTimeout(step valid.Step, ctx models.ProjectCommandContext) time.Duration {
+	if step.Timeout > 0 {
+		return step.Timeout
+	}
+	if timeout := ctx.Timeouts.For(step.StepName); timeout > 0 {
+		return timeout
+	}
+	return p.DefaultTimeouts.For(step.StepName)
+}
+
+// withTimeout returns a copy of envs that tells execution backends to stop
+// commands once they've run for longer than timeout. envs is returned as is
+// if there's no timeout.
+func withTimeout(envs map[string]string, timeout time.Duration) map[string]string {
+	if timeout == 0 {
+		return envs
+	}
+	stepEnvs := make(map[string]string, len(envs)+1)
+	for k, v := range envs {
+		stepEnvs[k] = v
+	}
+	stepEnvs[execution.TimeoutEnvVar] = timeout.String()
+	return stepEnvs
+}
+
+// isDone returns true if done is closed. A nil done is never closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// fetchSecret returns the value of the secret at ref for an env step.
+func (p *DefaultProjectCommandRunner) fetchSecret(ctx models.ProjectCommandContext, ref string) (string, error) {
+	if p.SecretsProvider == nil {
+		return "", fmt.Errorf("env step reads secret %s from vault but this server isn't configured with a vault address", ref)
+	}
+	value, err := p.SecretsProvider.Secret(ctx, ref)
+	return value, errors.Wrapf(err, "fetching secret %s", ref)
+}
+
 func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext) (applyOut string, failure string, err error) {
+	if ctx.PlanOnly {
+		return "", PlanOnlyApplyFailure, nil
+	}
+	if len(ctx.Targets) > 0 && ctx.TerraformCloud != nil {
+		return "", "Projects in Terraform Cloud can't be partially applied with `-target`. Plan them with `-target` instead.", nil
+	}
 	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -229,17 +1058,71 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 	for _, req := range ctx.ApplyRequirements {
 		switch req {
 		case raw.ApprovedApplyRequirement:
-			approved, err := p.PullApprovedChecker.PullIsApproved(ctx.BaseRepo, ctx.Pull) // nolint: vetshadow
+			approvalFailure, err := p.approvalFailure(ctx, repoDir) // nolint: vetshadow
 			if err != nil {
-				return "", "", errors.Wrap(err, "checking if pull request was approved")
+				return "", "", err
 			}
-			if !approved {
-				return "", "Pull request must be approved by at least one person other than the author before running apply.", nil
+			if approvalFailure != "" {
+				return "", approvalFailure, nil
 			}
 		case raw.MergeableApplyRequirement:
 			if !ctx.PullMergeable {
 				return "", "Pull request must be mergeable before running apply.", nil
 			}
+		case raw.UndivergedApplyRequirement:
+			undiverged, err := p.PullUndivergedChecker.PullIsUndiverged(ctx.BaseRepo, ctx.Pull) // nolint: vetshadow
+			if err != nil {
+				return "", "", errors.Wrap(err, "checking if pull request is behind its base branch")
+			}
+			if !undiverged {
+				return "", "Pull request must be up to date with its base branch before running apply. Merge or rebase the base branch and plan again.", nil
+			}
+		}
+	}
+	if blocked, err := ioutil.ReadFile(runtime.SecurityScanMarkerPath(ctx, absPath)); err == nil {
+		return "", string(blocked), nil
+	}
+	// Partial applies are checked against the guardrails once the project is
+	// planned again with its targets.
+	if len(ctx.Targets) == 0 {
+		guardrailsFailure, err := p.guardrailsFailure(ctx, absPath) // nolint: vetshadow
+		if err != nil {
+			return "", "", err
+		}
+		if guardrailsFailure != "" {
+			return "", guardrailsFailure, nil
+		}
+	}
+	if _, err := os.Stat(destroyMarkerPath(ctx, absPath)); err == nil {
+		if !ctx.ConfirmDestroy {
+			return "", fmt.Sprintf("This is a destroy plan so applying it destroys every resource in the project. To confirm, comment:\n* `%s`", withCommandFlag(ctx.ApplyCmd, models.ApplyCommand, confirmDestroyFlagLong)), nil
+		}
+		ctx.Destroy = true
+	}
+	// Plans are applied with the .tfvars file they were planned with. If
+	// --var-file is set on apply, it must match so the wrong plan isn't
+	// applied by mistake.
+	plannedVarFile := ""
+	if contents, err := ioutil.ReadFile(varFileMarkerPath(ctx, absPath)); err == nil { // nolint: vetshadow
+		plannedVarFile = string(contents)
+	}
+	if ctx.VarFile != "" && ctx.VarFile != plannedVarFile {
+		if plannedVarFile == "" {
+			return "", fmt.Sprintf("This plan wasn't made with `--%s %s`. Plan again with it before applying.", varFileFlagLong, ctx.VarFile), nil
+		}
+		return "", fmt.Sprintf("This plan was made with `--%s %s`, not `--%s %s`. Plan again with `--%s %s` before applying.", varFileFlagLong, plannedVarFile, varFileFlagLong, ctx.VarFile, varFileFlagLong, ctx.VarFile), nil
+	}
+	ctx.VarFile = plannedVarFile
+	if p.LockOnApply {
+		// The lock is kept after apply, like it is after plan when projects
+		// are locked on plan, so other pull requests can't apply the project
+		// until this one is merged or closed.
+		lockAttempt, err := p.lockProject(ctx) // nolint: vetshadow
+		if err != nil {
+			return "", "", err
+		}
+		if !lockAttempt.LockAcquired {
+			return "", lockAttempt.LockFailureReason, nil
 		}
 	}
 	// Acquire internal lock for the directory we're going to operate in.
@@ -248,8 +1131,48 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 		return "", "", err
 	}
 	defer unlockFn()
+	if p.PlanEncrypter != nil {
+		if err = p.PlanEncrypter.Decrypt(ctx, planPath(ctx, absPath)); err != nil {
+			return "", "", err
+		}
+		// Plans that weren't applied, ex. because the apply failed, are
+		// encrypted again.
+		defer func() {
+			if encryptErr := p.PlanEncrypter.Encrypt(ctx, planPath(ctx, absPath)); encryptErr != nil {
+				ctx.Log.Err("unable to encrypt planfile again: %s", encryptErr)
+			}
+		}()
+	}
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
+	var planOutputs []string
+	if len(ctx.Targets) > 0 {
+		var targetFailure string
+		planOutputs, targetFailure, err = p.planTargets(ctx, absPath)
+		if err != nil || targetFailure != "" {
+			// The targeted plan replaced the project's plan so neither can be
+			// applied anymore.
+			if removeErr := os.Remove(planPath(ctx, absPath)); removeErr != nil && !os.IsNotExist(removeErr) {
+				ctx.Log.Warn("unable to discard targeted plan: %s", removeErr)
+			}
+			if targetFailure != "" {
+				targetFailure += "\n\nThe project's plan was discarded so plan it again before applying."
+			}
+			return "", targetFailure, err
+		}
+	}
+
+	outputs, err := p.runApplySteps(ctx, absPath)
+	outputs = append(planOutputs, outputs...)
+	if len(ctx.Targets) > 0 && err == nil {
+		outputs = append(outputs, "Only the targeted resources were applied. Plan the project again to apply the rest of its changes.")
+	}
+	// The apply step deletes the planfile once it's applied but workflows
+	// that apply with run steps might not so it's deleted here too.
+	if err == nil {
+		if removeErr := os.Remove(planPath(ctx, absPath)); removeErr != nil && !os.IsNotExist(removeErr) {
+			ctx.Log.Warn("unable to delete applied planfile: %s", removeErr)
+		}
+	}
 	p.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
 		Workspace: ctx.Workspace,
 		User:      ctx.User,
@@ -257,9 +1180,177 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 		Pull:      ctx.Pull,
 		Success:   err == nil,
 		Directory: ctx.RepoRelDir,
+		Notify:    ctx.Notify,
 	})
 	if err != nil {
+		removeCancelledPlan(ctx, absPath)
 		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
 	}
 	return strings.Join(outputs, "\n"), "", nil
 }
+
+// removeCancelledPlan removes the project's planfile if its command was
+// cancelled. A cancelled plan might have left an earlier plan behind and a
+// cancelled apply might have applied part of its plan so neither can be
+// applied.
+func removeCancelledPlan(ctx models.ProjectCommandContext, absPath string) {
+	if !isDone(ctx.Done) {
+		return
+	}
+	if err := os.Remove(planPath(ctx, absPath)); err != nil && !os.IsNotExist(err) {
+		ctx.Log.Warn("unable to remove planfile of cancelled command: %s", err)
+	}
+}
+
+// planTargets plans the project again with only its Targets before it's
+// partially applied and checks the new plan like plan does. It returns the
+// plan's output, or why the new plan can't be applied.
+func (p *DefaultProjectCommandRunner) planTargets(ctx models.ProjectCommandContext, absPath string) ([]string, string, error) {
+	var args []string
+	for _, t := range ctx.Targets {
+		args = append(args, "-target="+t)
+	}
+	planCtx := ctx
+	planCtx.Steps = ctx.PlanSteps
+	planCtx.EscapedCommentArgs = escapeArgs(args)
+	ctx.Log.Info("planning again with %s before applying", strings.Join(args, " "))
+	outputs, err := p.runPlanSteps(planCtx, absPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("planning with %s: %s\n%s", strings.Join(args, " "), err, strings.Join(outputs, "\n"))
+	}
+	if blocked, err := ioutil.ReadFile(runtime.SecurityScanMarkerPath(ctx, absPath)); err == nil { // nolint: vetshadow
+		return nil, string(blocked), nil
+	}
+	if ctx.Guardrails != nil {
+		var summary *models.PlanSummary
+		if p.PlanSummarizer != nil && p.WorkerDispatcher == nil {
+			summary, err = p.PlanSummarizer.Summarize(planCtx, absPath)
+			if err != nil {
+				ctx.Log.Warn("unable to summarize plan: %s", err)
+			}
+		}
+		if err = markGuardrailViolations(ctx, absPath, GuardrailViolations(ctx.Guardrails, summary)); err != nil {
+			return nil, "", err
+		}
+		failure, err := p.guardrailsFailure(ctx, absPath) // nolint: vetshadow
+		if err != nil || failure != "" {
+			return nil, failure, err
+		}
+	}
+	return append([]string{fmt.Sprintf("Planned again with %s:", strings.Join(args, " "))}, outputs...), "", nil
+}
+
+// approvalFailure returns why the pull request doesn't meet the approved apply
+// requirement or an empty string if it does. repoDir is where the repo is
+// cloned, which is where its CODEOWNERS file is read from.
+func (p *DefaultProjectCommandRunner) approvalFailure(ctx models.ProjectCommandContext, repoDir string) (string, error) {
+	// The author can't approve their own pull request from Slack either.
+	slackApprover := ""
+	if ctx.SlackApprover != "" && !strings.EqualFold(ctx.SlackApprover, ctx.Pull.Author) {
+		slackApprover = ctx.SlackApprover
+	}
+	if ctx.Approvals == nil || ctx.Approvals.IsDefault() {
+		if slackApprover != "" {
+			return "", nil
+		}
+		approved, err := p.PullApprovedChecker.PullIsApproved(ctx.BaseRepo, ctx.Pull)
+		if err != nil {
+			return "", errors.Wrap(err, "checking if pull request was approved")
+		}
+		if !approved {
+			return "Pull request must be approved by at least one person other than the author before running apply.", nil
+		}
+		return "", nil
+	}
+
+	approvers, err := p.PullApprovedChecker.GetPullApprovers(ctx.BaseRepo, ctx.Pull)
+	if err != nil {
+		return "", errors.Wrap(err, "getting pull request approvers")
+	}
+	if slackApprover != "" && !containsFold(approvers, slackApprover) {
+		approvers = append(approvers, slackApprover)
+	}
+	if len(approvers) < ctx.Approvals.Count {
+		return fmt.Sprintf("Pull request must be approved by at least %d people other than the author before running apply. It has %d approvals.", ctx.Approvals.Count, len(approvers)), nil
+	}
+	if !ctx.Approvals.Codeowners {
+		return "", nil
+	}
+
+	codeowners, err := FindCodeowners(repoDir)
+	if err != nil {
+		return "", errors.Wrap(err, "reading code owners")
+	}
+	if codeowners == nil {
+		return "", nil
+	}
+	files, err := p.PullApprovedChecker.GetModifiedFiles(ctx.BaseRepo, ctx.Pull)
+	if err != nil {
+		return "", errors.Wrap(err, "getting modified files")
+	}
+	var unapproved []string
+	teamMembers := make(map[string]bool)
+	for _, file := range files {
+		// The file is read from the pull request's branch so it could give
+		// the author ownership of everything.
+		if file == codeowners.Path {
+			return fmt.Sprintf("Pull request modifies `%s` so its code owners can't be checked. Modify it in a separate pull request.", codeowners.Path), nil
+		}
+		owners := codeowners.Owners(file)
+		if len(owners) == 0 {
+			continue
+		}
+		approved, err := p.ownerApproved(ctx.BaseRepo, owners, approvers, teamMembers)
+		if err != nil {
+			return "", err
+		}
+		if !approved {
+			unapproved = append(unapproved, file)
+		}
+	}
+	if len(unapproved) > 0 {
+		return fmt.Sprintf("Pull request must be approved by a code owner of each modified file before running apply. Waiting on approvals for:\n* `%s`", strings.Join(unapproved, "`\n* `")), nil
+	}
+	return "", nil
+}
+
+// ownerApproved returns true if one of approvers is one of owners or a member
+// of one of the teams in owners. teamMembers caches team membership checks
+// and is keyed by team and user.
+func (p *DefaultProjectCommandRunner) ownerApproved(repo models.Repo, owners []string, approvers []string, teamMembers map[string]bool) (bool, error) {
+	for _, owner := range owners {
+		isTeam := strings.Contains(owner, "/")
+		for _, approver := range approvers {
+			if strings.EqualFold(owner, approver) {
+				return true, nil
+			}
+			if !isTeam || p.TeamMembershipChecker == nil {
+				continue
+			}
+			key := strings.ToLower(owner + "#" + approver)
+			member, ok := teamMembers[key]
+			if !ok {
+				var err error
+				member, err = p.TeamMembershipChecker.IsTeamMember(repo, owner, approver)
+				if err != nil {
+					return false, errors.Wrap(err, "checking team membership")
+				}
+				teamMembers[key] = member
+			}
+			if member {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// containsFold returns true if s is in slice, ignoring case.
+func containsFold(slice []string, s string) bool {
+	for _, e := range slice {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
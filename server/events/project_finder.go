@@ -38,6 +38,12 @@ type ProjectFinder interface {
 	// based on modifiedFiles and the repo's config.
 	// absRepoDir is the path to the cloned repo on disk.
 	DetermineProjectsViaConfig(log *logging.SimpleLogger, modifiedFiles []string, config valid.RepoCfg, absRepoDir string) ([]valid.Project, error)
+	// DetermineProjectsViaAutodiscover returns the list of projects that
+	// were modified based on modifiedFiles and the server-side autodiscover
+	// config of repos without an atlantis.yaml. The list will be
+	// de-duplicated.
+	// absRepoDir is the path to the cloned repo on disk.
+	DetermineProjectsViaAutodiscover(log *logging.SimpleLogger, modifiedFiles []string, repoFullName string, absRepoDir string, autodiscover valid.Autodiscover) ([]models.Project, error)
 }
 
 // DefaultProjectFinder implements ProjectFinder.
@@ -130,6 +136,83 @@ func (p *DefaultProjectFinder) DetermineProjectsViaConfig(log *logging.SimpleLog
 	return projects, nil
 }
 
+// See ProjectFinder.DetermineProjectsViaAutodiscover.
+func (p *DefaultProjectFinder) DetermineProjectsViaAutodiscover(log *logging.SimpleLogger, modifiedFiles []string, repoFullName string, absRepoDir string, autodiscover valid.Autodiscover) ([]models.Project, error) {
+	include, err := fileutils.NewPatternMatcher(autodiscover.Include)
+	if err != nil {
+		return nil, errors.Wrapf(err, "matching dirs with include patterns: %v", autodiscover.Include)
+	}
+	exclude, err := fileutils.NewPatternMatcher(autodiscover.Exclude)
+	if err != nil {
+		return nil, errors.Wrapf(err, "matching dirs with exclude patterns: %v", autodiscover.Exclude)
+	}
+
+	// Each modified file belongs to the closest directory above it that
+	// can be a project and has a project marker in it.
+	var dirs []string
+	for _, file := range p.filterToTerraform(modifiedFiles) {
+		for dir := path.Dir(file); ; dir = path.Dir(dir) {
+			isProject := (len(autodiscover.Include) == 0 || dirMatches(include, dir)) &&
+				!dirMatches(exclude, dir) &&
+				hasProjectMarker(filepath.Join(absRepoDir, dir), autodiscover.ProjectMarkers)
+			if isProject {
+				log.Debug("file %q is in autodiscovered project at dir %q", file, dir)
+				dirs = append(dirs, dir)
+				break
+			}
+			if dir == "." {
+				log.Debug("file %q isn't in any autodiscovered project", file)
+				break
+			}
+		}
+	}
+
+	var projects []models.Project
+	for _, dir := range p.unique(dirs) {
+		projects = append(projects, models.NewProject(repoFullName, dir))
+	}
+	log.Info("autodiscovered %d modified project(s) at path(s): %v", len(projects), strings.Join(p.unique(dirs), ", "))
+	return projects, nil
+}
+
+// determineProjectsWithoutRepoCfg returns the projects modified by
+// modifiedFiles in repo, which has no atlantis.yaml. They're autodiscovered
+// if the server-side config has autodiscover rules for repo.
+func determineProjectsWithoutRepoCfg(log *logging.SimpleLogger, finder ProjectFinder, globalCfg valid.GlobalCfg, modifiedFiles []string, repo models.Repo, repoDir string) ([]models.Project, error) {
+	if autodiscover := globalCfg.Autodiscover(repo.ID()); autodiscover != nil {
+		log.Debug("autodiscovering projects with %s", autodiscover)
+		return finder.DetermineProjectsViaAutodiscover(log, modifiedFiles, repo.FullName, repoDir, *autodiscover)
+	}
+	return finder.DetermineProjects(log, modifiedFiles, repo.FullName, repoDir), nil
+}
+
+// dirMatches returns true if dir, relative to the repo root, or one of its
+// parents matches pm.
+func dirMatches(pm *fileutils.PatternMatcher, dir string) bool {
+	for ; dir != "."; dir = path.Dir(dir) {
+		// Matches only returns an error if the patterns are malformed,
+		// which NewPatternMatcher already checked.
+		if match, _ := pm.Matches(dir); match {
+			return true
+		}
+	}
+	return false
+}
+
+// hasProjectMarker returns true if absDir has a file that matches one of
+// markers.
+func hasProjectMarker(absDir string, markers []string) bool {
+	for _, marker := range markers {
+		matches, _ := filepath.Glob(filepath.Join(absDir, marker))
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // filterToTerraform filters non-terraform files from files.
 func (p *DefaultProjectFinder) filterToTerraform(files []string) []string {
 	var filtered []string
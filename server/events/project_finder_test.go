@@ -458,3 +458,134 @@ func TestDefaultProjectFinder_DetermineProjectsViaConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultProjectFinder_DetermineProjectsViaAutodiscover(t *testing.T) {
+	// Create dir structure:
+	// main.tf
+	// live/
+	//   modules/
+	//     network/
+	//       main.tf
+	//   prod/
+	//     eu/
+	//       app/
+	//         terragrunt.hcl
+	//       vpc/
+	//         main.tf
+	//         policies/
+	//           s3.json
+	// sandbox/
+	//   main.tf
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+		"live": map[string]interface{}{
+			"modules": map[string]interface{}{
+				"network": map[string]interface{}{
+					"main.tf": nil,
+				},
+			},
+			"prod": map[string]interface{}{
+				"eu": map[string]interface{}{
+					"app": map[string]interface{}{
+						"terragrunt.hcl": nil,
+					},
+					"vpc": map[string]interface{}{
+						"main.tf": nil,
+						"policies": map[string]interface{}{
+							"s3.json": nil,
+						},
+					},
+				},
+			},
+		},
+		"sandbox": map[string]interface{}{
+			"main.tf": nil,
+		},
+	})
+	defer cleanup()
+
+	modified := []string{
+		"README.md",
+		"live/modules/network/main.tf",
+		"live/prod/eu/app/terragrunt.hcl",
+		"live/prod/eu/vpc/main.tf",
+		// Deleted files belong to the closest project above them.
+		"live/prod/eu/vpc/policies/old.tf",
+		"live/prod/eu/vpc/terraform.tfstate",
+		"sandbox/main.tf",
+	}
+	cases := []struct {
+		description  string
+		autodiscover valid.Autodiscover
+		modified     []string
+		expProjPaths []string
+	}{
+		{
+			description: "defaults",
+			autodiscover: valid.Autodiscover{
+				Exclude:        valid.DefaultAutodiscoverExclude,
+				ProjectMarkers: valid.DefaultProjectMarkers,
+			},
+			modified: modified,
+			// The excluded module's file belongs to the root project.
+			expProjPaths: []string{".", "live/prod/eu/app", "live/prod/eu/vpc", "sandbox"},
+		},
+		{
+			description: "include",
+			autodiscover: valid.Autodiscover{
+				Include:        []string{"live/prod"},
+				Exclude:        valid.DefaultAutodiscoverExclude,
+				ProjectMarkers: valid.DefaultProjectMarkers,
+			},
+			modified:     modified,
+			expProjPaths: []string{"live/prod/eu/app", "live/prod/eu/vpc"},
+		},
+		{
+			description: "include with wildcards",
+			autodiscover: valid.Autodiscover{
+				Include:        []string{"**/app", "sandbox"},
+				ProjectMarkers: valid.DefaultProjectMarkers,
+			},
+			modified:     modified,
+			expProjPaths: []string{"live/prod/eu/app", "sandbox"},
+		},
+		{
+			description: "exclude",
+			autodiscover: valid.Autodiscover{
+				Exclude:        []string{"live/prod/*/vpc", "sandbox"},
+				ProjectMarkers: valid.DefaultProjectMarkers,
+			},
+			modified:     modified,
+			expProjPaths: []string{"live/modules/network", "live/prod/eu/app", "."},
+		},
+		{
+			description: "project markers",
+			autodiscover: valid.Autodiscover{
+				ProjectMarkers: []string{"terragrunt.hcl"},
+			},
+			modified:     modified,
+			expProjPaths: []string{"live/prod/eu/app"},
+		},
+		{
+			description: "no terraform files",
+			autodiscover: valid.Autodiscover{
+				ProjectMarkers: valid.DefaultProjectMarkers,
+			},
+			modified:     []string{"README.md", "live/prod/eu/vpc/policies/s3.json"},
+			expProjPaths: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			pf := events.DefaultProjectFinder{}
+			projects, err := pf.DetermineProjectsViaAutodiscover(logging.NewNoopLogger(), c.modified, modifiedRepo, tmpDir, c.autodiscover)
+			Ok(t, err)
+			var paths []string
+			for _, p := range projects {
+				Equals(t, modifiedRepo, p.RepoFullName)
+				paths = append(paths, p.Path)
+			}
+			Equals(t, c.expProjPaths, paths)
+		})
+	}
+}
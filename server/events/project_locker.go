@@ -22,6 +22,21 @@ import (
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
+const (
+	// LockOnPlanMode locks projects when they're planned. It's the default.
+	LockOnPlanMode = "on_plan"
+	// LockOnApplyMode locks projects when they're applied so pull requests
+	// can plan the same project at the same time.
+	LockOnApplyMode = "on_apply"
+
+	// FailLockingOutageMode rejects commands while the locking backend is
+	// unavailable. It's the default.
+	FailLockingOutageMode = "fail"
+	// QueueLockingOutageMode holds commands while the locking backend is
+	// unavailable and runs them once it recovers.
+	QueueLockingOutageMode = "queue"
+)
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_lock.go ProjectLocker
 
 // ProjectLocker locks this project against other plans being run until this
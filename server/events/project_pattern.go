@@ -0,0 +1,113 @@
+package events
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// projectPatternSeparator separates the project names and globs of a -p flag,
+// ex. atlantis plan -p app,db.
+const projectPatternSeparator = ","
+
+// projectGlobChars are the characters that make a project pattern a glob,
+// ex. network-*. Globs use path.Match syntax.
+const projectGlobChars = "*?["
+
+// splitProjectPatterns returns the project names and globs in project, the
+// value of a -p flag.
+func splitProjectPatterns(project string) []string {
+	var patterns []string
+	for _, p := range strings.Split(project, projectPatternSeparator) {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// isProjectGlob returns true if pattern is a glob instead of a project name.
+func isProjectGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, projectGlobChars)
+}
+
+// isProjectPattern returns true if project, the value of a -p flag, selects
+// projects with globs or lists more than one project.
+func isProjectPattern(project string) bool {
+	return strings.Contains(project, projectPatternSeparator) || isProjectGlob(project)
+}
+
+// validateProjectPattern returns an error if project, the value of a -p
+// flag, has an empty entry or a glob that isn't valid.
+func validateProjectPattern(project string) error {
+	for _, p := range strings.Split(project, projectPatternSeparator) {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return fmt.Errorf("invalid project %q: cannot contain empty project names", project)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid project %q: %s", p, err)
+		}
+	}
+	return nil
+}
+
+// buildProjectPatternCommands builds a context for each project selected by
+// the -p flag of cmd, ex. -p 'network-*,app'. Names are built with buildOne
+// like -p name is. Globs are matched against the names of the projects
+// detected in the pull request, which are built with buildAll, and must
+// match at least one of them.
+func buildProjectPatternCommands(
+	ctx *CommandContext,
+	cmd *CommentCommand,
+	buildAll func() ([]models.ProjectCommandContext, error),
+	buildOne func(*CommandContext, *CommentCommand) (models.ProjectCommandContext, error)) ([]models.ProjectCommandContext, error) {
+
+	var pccs []models.ProjectCommandContext
+	added := make(map[string]bool)
+	add := func(pcc models.ProjectCommandContext) {
+		if !added[pcc.ProjectName] {
+			added[pcc.ProjectName] = true
+			pccs = append(pccs, pcc)
+		}
+	}
+
+	var detected []models.ProjectCommandContext
+	detectedBuilt := false
+	for _, pattern := range splitProjectPatterns(cmd.ProjectName) {
+		if !isProjectGlob(pattern) {
+			single := *cmd
+			single.ProjectName = pattern
+			pcc, err := buildOne(ctx, &single)
+			if err != nil {
+				return nil, err
+			}
+			add(pcc)
+			continue
+		}
+
+		if !detectedBuilt {
+			var err error
+			detected, err = buildAll()
+			if err != nil {
+				return nil, err
+			}
+			detectedBuilt = true
+		}
+		matched := false
+		for _, pcc := range detected {
+			// Only projects with names in atlantis.yaml can be selected.
+			if ok, _ := path.Match(pattern, pcc.ProjectName); ok && pcc.ProjectName != "" {
+				add(pcc)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no project matching %q was found in this pull request", pattern)
+		}
+	}
+	sortProjectCmds(pccs)
+	return pccs, nil
+}
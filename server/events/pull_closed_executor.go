@@ -46,7 +46,12 @@ type PullClosedExecutor struct {
 	VCSClient  vcs.Client
 	WorkingDir WorkingDir
 	Logger     logging.SimpleLogging
-	DB         *db.BoltDB
+	DB         db.PullStatusStore
+	// AuditLogger, if set, records each lock that's released.
+	AuditLogger AuditLogger
+	// PlanOutputStore, if set, is used to delete the full output of the
+	// pull request's truncated plans.
+	PlanOutputStore PlanOutputStore
 }
 
 type templatedProject struct {
@@ -73,10 +78,29 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 		return errors.Wrap(err, "cleaning up locks")
 	}
 
+	if p.AuditLogger != nil {
+		for _, l := range locks {
+			p.AuditLogger.Record(models.AuditEvent{
+				Action:       models.UnlockAuditAction,
+				RepoFullName: repo.FullName,
+				PullNum:      pull.Num,
+				RepoRelDir:   l.Project.Path,
+				Workspace:    l.Workspace,
+				Result:       models.SuccessAuditResult,
+				ProjectID:    l.ProjectID(),
+			})
+		}
+	}
+
 	// Delete pull from DB.
 	if err := p.DB.DeletePullStatus(pull); err != nil {
 		p.Logger.Err("deleting pull from db: %s", err)
 	}
+	if p.PlanOutputStore != nil {
+		if err := p.PlanOutputStore.DeletePlanOutputs(pull); err != nil {
+			p.Logger.Err("deleting plan outputs from db: %s", err)
+		}
+	}
 
 	// If there are no locks then there's no need to comment.
 	if len(locks) == 0 {
@@ -15,6 +15,10 @@ package events_test
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events/db"
@@ -171,3 +175,70 @@ func TestCleanUpPullComments(t *testing.T) {
 		}()
 	}
 }
+
+func TestCleanUpPullAuditLog(t *testing.T) {
+	t.Log("each released lock should be recorded in the audit log")
+	RegisterMockTestingT(t)
+	w := mocks.NewMockWorkingDir()
+	l := lockmocks.NewMockLocker()
+	cp := vcsmocks.NewMockClient()
+	auditLogger := mocks.NewMockAuditLogger()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := db.New(tmp)
+	Ok(t, err)
+	pce := events.PullClosedExecutor{
+		Locker:      l,
+		VCSClient:   cp,
+		WorkingDir:  w,
+		DB:          db,
+		AuditLogger: auditLogger,
+	}
+	When(l.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn([]models.ProjectLock{
+		{
+			Project:   models.NewProject(fixtures.GithubRepo.FullName, "path"),
+			Workspace: "default",
+		},
+	}, nil)
+	Ok(t, pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull))
+	auditLogger.VerifyWasCalledOnce().Record(models.AuditEvent{
+		Action:       models.UnlockAuditAction,
+		RepoFullName: fixtures.GithubRepo.FullName,
+		PullNum:      fixtures.Pull.Num,
+		RepoRelDir:   "path",
+		Workspace:    "default",
+		Result:       models.SuccessAuditResult,
+		ProjectID:    "693e3767c96458a4",
+	})
+}
+
+func TestCleanUpPullEncryptedPlans(t *testing.T) {
+	t.Log("encrypted planfiles should be deleted when the pull request is closed, including from --repos-dir")
+	RegisterMockTestingT(t)
+	l := lockmocks.NewMockLocker()
+	dataDir, cleanupDataDir := TempDir(t)
+	defer cleanupDataDir()
+	reposDir, cleanupReposDir := TempDir(t)
+	defer cleanupReposDir()
+	db, err := db.New(dataDir)
+	Ok(t, err)
+	pce := events.PullClosedExecutor{
+		Locker:     l,
+		VCSClient:  vcsmocks.NewMockClient(),
+		WorkingDir: &events.FileWorkspace{DataDir: dataDir, ReposDir: reposDir},
+		DB:         db,
+	}
+
+	pullDir := filepath.Join(reposDir, fixtures.GithubRepo.FullName, strconv.Itoa(fixtures.Pull.Num))
+	planPath := filepath.Join(pullDir, "default", "dir", "default.tfplan")
+	Ok(t, os.MkdirAll(filepath.Dir(planPath), 0700))
+	Ok(t, ioutil.WriteFile(planPath, []byte("secret plan"), 0600))
+	encrypter, err := events.NewPlanEncrypter(planEncryptionKey)
+	Ok(t, err)
+	Ok(t, encrypter.Encrypt(models.ProjectCommandContext{BaseRepo: fixtures.GithubRepo, Pull: fixtures.Pull, RepoRelDir: "dir", Workspace: "default"}, planPath))
+
+	When(l.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn(nil, nil)
+	Ok(t, pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull))
+	_, err = os.Stat(pullDir)
+	Assert(t, os.IsNotExist(err), "exp pull's working dir and planfiles to be deleted")
+}
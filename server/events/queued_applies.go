@@ -0,0 +1,67 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// QueuedApplies runs applies that were requested outside their project's
+// apply windows once the next window opens. Queued applies are only kept in
+// memory so they're lost if Atlantis restarts.
+type QueuedApplies struct {
+	Now func() time.Time
+	// AfterFunc is stubbed in tests. If nil, time.AfterFunc is used.
+	AfterFunc func(d time.Duration, f func()) *time.Timer
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// QueuedApplyKey returns the key of the queued apply of the project in
+// repoRelDir and workspace in pull.
+func QueuedApplyKey(pull models.PullRequest, repoRelDir string, workspace string) string {
+	return fmt.Sprintf("%s#%d/%s/%s", pull.BaseRepo.FullName, pull.Num, repoRelDir, workspace)
+}
+
+// Queue runs apply at the time at. If an apply with the same key is already
+// queued, it's replaced so each project is only applied once.
+func (q *QueuedApplies) Queue(key string, at time.Time, apply func()) {
+	now := time.Now
+	if q.Now != nil {
+		now = q.Now
+	}
+	afterFunc := time.AfterFunc
+	if q.AfterFunc != nil {
+		afterFunc = q.AfterFunc
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.timers == nil {
+		q.timers = make(map[string]*time.Timer)
+	}
+	if t, ok := q.timers[key]; ok {
+		t.Stop()
+	}
+	var timer *time.Timer
+	timer = afterFunc(at.Sub(now()), func() {
+		q.mu.Lock()
+		// Only forget the apply if it wasn't replaced while it was waiting.
+		if q.timers[key] == timer {
+			delete(q.timers, key)
+		}
+		q.mu.Unlock()
+		apply()
+	})
+	q.timers[key] = timer
+}
+
+// Len returns how many applies are queued.
+func (q *QueuedApplies) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.timers)
+}
@@ -0,0 +1,41 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestQueuedApplies_Queue(t *testing.T) {
+	now := time.Date(2020, 6, 5, 18, 0, 0, 0, time.UTC)
+	var delays []time.Duration
+	var funcs []func()
+	q := events.QueuedApplies{
+		Now: func() time.Time { return now },
+		AfterFunc: func(d time.Duration, f func()) *time.Timer {
+			delays = append(delays, d)
+			funcs = append(funcs, f)
+			// A timer that never fires so f is only run by the test.
+			return time.AfterFunc(time.Hour, func() {})
+		},
+	}
+	pull := models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "owner/repo"}}
+	key := events.QueuedApplyKey(pull, "production", "default")
+	Equals(t, "owner/repo#1/production/default", key)
+
+	var applied []string
+	q.Queue(key, now.Add(time.Hour), func() { applied = append(applied, "first") })
+	q.Queue(key, now.Add(2*time.Hour), func() { applied = append(applied, "second") })
+	q.Queue(events.QueuedApplyKey(pull, "staging", "default"), now.Add(time.Minute), func() { applied = append(applied, "staging") })
+	Equals(t, []time.Duration{time.Hour, 2 * time.Hour, time.Minute}, delays)
+	Equals(t, 2, q.Len())
+
+	// The replaced apply's timer is stopped so only the latest one runs.
+	funcs[2]()
+	funcs[1]()
+	Equals(t, []string{"staging", "second"}, applied)
+	Equals(t, 0, q.Len())
+}
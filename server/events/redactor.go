@@ -0,0 +1,182 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedactedPlaceholder replaces secrets that have been redacted.
+const RedactedPlaceholder = "<redacted>"
+
+// minRedactedValueLength is the length of the shortest env var value we'll
+// redact. Shorter values, ex. "1" or "true", are unlikely to be secrets and
+// redacting them would mangle unrelated output.
+const minRedactedValueLength = 4
+
+// RedactSecretGroup is the name of the regex group whose match is redacted.
+// If a pattern doesn't have a group with this name, the whole match is
+// redacted.
+const RedactSecretGroup = "secret"
+
+// DefaultRedactPatterns match common credentials. They're always redacted in
+// addition to the patterns from --redact-patterns.
+var DefaultRedactPatterns = []*regexp.Regexp{
+	// AWS access key IDs.
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// AWS secret access keys. They don't have a prefix so we only match them
+	// next to their name.
+	regexp.MustCompile(`(?i)aws_?secret_?(access_?)?key["']?\s*[=:]\s*["']?(?P<secret>[0-9a-zA-Z/+]{40})\b`),
+	// Private keys, ex. from a tls_private_key resource.
+	regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`),
+	// GitHub tokens.
+	regexp.MustCompile(`\bgh[pousr]_[0-9a-zA-Z]{36}\b`),
+	// GitLab personal access tokens.
+	regexp.MustCompile(`\bglpat-[0-9a-zA-Z_\-]{20}\b`),
+	// Slack tokens.
+	regexp.MustCompile(`\bxox[baprs]-[0-9a-zA-Z-]{10,72}\b`),
+	// Atlantis API tokens.
+	regexp.MustCompile(`\b` + apiTokenPattern + `\b`),
+}
+
+// Redactor scrubs secrets from command output before it's logged or
+// commented back to the pull request. A nil *Redactor doesn't redact
+// anything.
+type Redactor struct {
+	// EnvVarNames are the names of env vars whose values are redacted. Values
+	// are read from Atlantis's environment when the Redactor is created and
+	// from the env vars set by env steps.
+	EnvVarNames []string
+	// Patterns match secrets to redact.
+	Patterns []*regexp.Regexp
+
+	values []string
+}
+
+// NewRedactor returns a Redactor that redacts values, ex. Atlantis's own VCS
+// tokens, the values of envVarNames and matches of patterns as well as the
+// DefaultRedactPatterns.
+func NewRedactor(envVarNames []string, values []string, patterns []*regexp.Regexp) *Redactor {
+	r := &Redactor{
+		EnvVarNames: envVarNames,
+		Patterns:    append(append([]*regexp.Regexp{}, DefaultRedactPatterns...), patterns...),
+	}
+	for _, v := range values {
+		r.addValue(v)
+	}
+	for _, name := range envVarNames {
+		r.addValue(os.Getenv(name))
+	}
+	return r
+}
+
+// ParseRedactPatterns parses the JSON value of the --redact-patterns flag, ex.
+// ["password=(?P<secret>\\S+)"].
+func ParseRedactPatterns(patternsJSON string) ([]*regexp.Regexp, error) {
+	if patternsJSON == "" {
+		return nil, nil
+	}
+	var patterns []string
+	if err := json.Unmarshal([]byte(patternsJSON), &patterns); err != nil {
+		return nil, errors.Wrap(err, "parsing redact patterns")
+	}
+	var regexes []*regexp.Regexp
+	for _, p := range patterns {
+		if p == "" {
+			return nil, errors.New("redact patterns cannot be empty")
+		}
+		regex, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compiling redact pattern %q", p)
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, nil
+}
+
+// Redact returns s with any secrets replaced by RedactedPlaceholder.
+func (r *Redactor) Redact(s string) string {
+	return r.RedactWithEnv(s, nil)
+}
+
+// RedactWithEnv is like Redact but it also redacts the values of envs, which
+// are the env vars set by env steps, whose names are in EnvVarNames.
+func (r *Redactor) RedactWithEnv(s string, envs map[string]string) string {
+	return r.RedactWithSecrets(s, envs, nil)
+}
+
+// RedactWithSecrets is like RedactWithEnv but it also redacts secrets, ex.
+// short-lived credentials that only exist while a command runs.
+func (r *Redactor) RedactWithSecrets(s string, envs map[string]string, secrets []string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	// Copy the values since the Redactor is used concurrently.
+	values := append([]string{}, r.values...)
+	for _, v := range secrets {
+		if len(v) >= minRedactedValueLength {
+			values = append(values, v)
+		}
+	}
+	for _, name := range r.EnvVarNames {
+		if v, ok := envs[name]; ok && len(v) >= minRedactedValueLength {
+			values = append(values, v)
+		}
+	}
+	// Replace longer values first so a value that contains another value is
+	// completely redacted.
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, v := range values {
+		s = strings.Replace(s, v, RedactedPlaceholder, -1)
+	}
+	for _, p := range r.Patterns {
+		s = redactPattern(s, p)
+	}
+	return s
+}
+
+func (r *Redactor) addValue(v string) {
+	v = strings.TrimSpace(v)
+	if len(v) < minRedactedValueLength {
+		return
+	}
+	for _, existing := range r.values {
+		if existing == v {
+			return
+		}
+	}
+	r.values = append(r.values, v)
+}
+
+// redactPattern replaces the matches of p in s. If p has a RedactSecretGroup
+// group only that part of the match is replaced.
+func redactPattern(s string, p *regexp.Regexp) string {
+	group := -1
+	for i, name := range p.SubexpNames() {
+		if name == RedactSecretGroup {
+			group = i
+		}
+	}
+	if group == -1 {
+		return p.ReplaceAllLiteralString(s, RedactedPlaceholder)
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range p.FindAllStringSubmatchIndex(s, -1) {
+		start, end := match[2*group], match[2*group+1]
+		if start == -1 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(RedactedPlaceholder)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
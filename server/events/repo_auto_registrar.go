@@ -0,0 +1,96 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// AutoRegisteredBy is the OnboardedBy of repos that were registered
+// automatically when their first webhook arrived.
+const AutoRegisteredBy = "webhook"
+
+// autoRegisterExcludePrefix marks auto-register rules that exclude repos.
+const autoRegisterExcludePrefix = "!"
+
+// OnboardedRepoWriter saves onboarded repos.
+type OnboardedRepoWriter interface {
+	PutOnboardedRepo(repo models.OnboardedRepo) error
+}
+
+// RepoAutoRegistrar registers repos as their webhooks arrive. It's used with
+// organization or group webhooks so repos don't need their own webhook or to
+// be listed in the repo whitelist.
+type RepoAutoRegistrar struct {
+	Store  OnboardedRepoWriter
+	Logger logging.SimpleLogging
+	Now    func() time.Time
+
+	include *RepoWhitelistChecker
+	exclude *RepoWhitelistChecker
+}
+
+// NewRepoAutoRegistrar returns a RepoAutoRegistrar for rules, a comma
+// separated list in the same format as the repo whitelist. Rules prefixed
+// with ! exclude the repos they match, ex.
+// "github.com/myorg/*,!github.com/myorg/sandbox-*".
+func NewRepoAutoRegistrar(rules string, store OnboardedRepoWriter, logger logging.SimpleLogging) (*RepoAutoRegistrar, error) {
+	var include, exclude []string
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "" {
+			continue
+		}
+		if strings.HasPrefix(rule, autoRegisterExcludePrefix) {
+			exclude = append(exclude, strings.TrimPrefix(rule, autoRegisterExcludePrefix))
+		} else {
+			include = append(include, rule)
+		}
+	}
+	if len(include) == 0 {
+		return nil, fmt.Errorf("auto-register rules %q must include at least one repo", rules)
+	}
+	includeChecker, err := NewRepoWhitelistChecker(strings.Join(include, ","))
+	if err != nil {
+		return nil, err
+	}
+	excludeChecker, err := NewRepoWhitelistChecker(strings.Join(exclude, ","))
+	if err != nil {
+		return nil, err
+	}
+	return &RepoAutoRegistrar{
+		Store:   store,
+		Logger:  logger,
+		include: includeChecker,
+		exclude: excludeChecker,
+	}, nil
+}
+
+// Register registers the repo if it matches the rules and returns true if it
+// did. If the repo can't be saved it's still allowed to be used for this
+// event.
+func (r *RepoAutoRegistrar) Register(repoFullName string, vcsHostname string) bool {
+	if !r.include.IsWhitelisted(repoFullName, vcsHostname) || r.exclude.IsWhitelisted(repoFullName, vcsHostname) {
+		return false
+	}
+	repo := models.OnboardedRepo{
+		ID:          fmt.Sprintf("%s/%s", vcsHostname, repoFullName),
+		OnboardedBy: AutoRegisteredBy,
+		OnboardedAt: r.now().UTC(),
+	}
+	if err := r.Store.PutOnboardedRepo(repo); err != nil {
+		r.Logger.Err("unable to register repo %s: %s", repo.ID, err)
+		return true
+	}
+	r.Logger.Info("auto-registered repo %s", repo.ID)
+	return true
+}
+
+func (r *RepoAutoRegistrar) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
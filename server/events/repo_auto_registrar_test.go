@@ -0,0 +1,53 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type recordingRepoWriter struct {
+	repos []models.OnboardedRepo
+	err   error
+}
+
+func (r *recordingRepoWriter) PutOnboardedRepo(repo models.OnboardedRepo) error {
+	r.repos = append(r.repos, repo)
+	return r.err
+}
+
+func TestRepoAutoRegistrar_Register(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	store := &recordingRepoWriter{}
+	r, err := events.NewRepoAutoRegistrar("github.com/myorg/*,!github.com/myorg/sandbox-*", store, logging.NewNoopLogger())
+	Ok(t, err)
+	r.Now = func() time.Time { return now }
+
+	Equals(t, true, r.Register("myorg/repo", "github.com"))
+	Equals(t, false, r.Register("myorg/sandbox-repo", "github.com"))
+	Equals(t, false, r.Register("otherorg/repo", "github.com"))
+	Equals(t, false, r.Register("myorg/repo", "gitlab.com"))
+	Equals(t, []models.OnboardedRepo{
+		{
+			ID:          "github.com/myorg/repo",
+			OnboardedBy: events.AutoRegisteredBy,
+			OnboardedAt: now,
+		},
+	}, store.repos)
+
+	// If we can't save the repo we still allow the event.
+	store.err = errors.New("db error")
+	Equals(t, true, r.Register("myorg/other", "github.com"))
+}
+
+func TestNewRepoAutoRegistrar_Errors(t *testing.T) {
+	_, err := events.NewRepoAutoRegistrar("!github.com/myorg/*", nil, nil)
+	ErrEquals(t, `auto-register rules "!github.com/myorg/*" must include at least one repo`, err)
+	_, err = events.NewRepoAutoRegistrar("https://github.com/myorg/*", nil, nil)
+	ErrEquals(t, `whitelist "https://github.com/myorg/*" contained ://`, err)
+}
@@ -16,6 +16,8 @@ package events
 import (
 	"fmt"
 	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 // Wildcard matches 0-n of all characters except commas.
@@ -24,7 +26,22 @@ const Wildcard = "*"
 // RepoWhitelistChecker implements checking if repos are whitelisted to be used with
 // this Atlantis.
 type RepoWhitelistChecker struct {
-	rules []string
+	// OnboardedRepos, if set, lists the repos onboarded via the API. They're
+	// whitelisted in addition to the repos matched by the whitelist rules.
+	OnboardedRepos OnboardedRepoLister
+	// OrgRepos, if set, are the repos synced from VCS organizations. They're
+	// whitelisted in addition to the repos matched by the whitelist rules.
+	OrgRepos OrgRepoSet
+	// Tenants, if set, holds the server-side repo config. The repos of its
+	// tenants are whitelisted in addition to the repos matched by the
+	// whitelist rules.
+	Tenants *GlobalCfgStore
+	rules   []string
+}
+
+// OnboardedRepoLister lists the repos onboarded via the API.
+type OnboardedRepoLister interface {
+	ListOnboardedRepos() ([]models.OnboardedRepo, error)
 }
 
 // NewRepoWhitelistChecker constructs a new checker and validates that the
@@ -50,6 +67,30 @@ func (r *RepoWhitelistChecker) IsWhitelisted(repoFullName string, vcsHostname st
 			return true
 		}
 	}
+	if r.OrgRepos != nil && r.OrgRepos.Contains(candidate) {
+		return true
+	}
+	if r.Tenants != nil && r.Tenants.Get().TenantFor(candidate) != "" {
+		return true
+	}
+	return r.isOnboarded(candidate)
+}
+
+// isOnboarded returns true if candidate was onboarded via the API. If the
+// onboarded repos can't be listed we fail closed.
+func (r *RepoWhitelistChecker) isOnboarded(candidate string) bool {
+	if r.OnboardedRepos == nil {
+		return false
+	}
+	repos, err := r.OnboardedRepos.ListOnboardedRepos()
+	if err != nil {
+		return false
+	}
+	for _, repo := range repos {
+		if strings.EqualFold(repo.ID, candidate) {
+			return true
+		}
+	}
 	return false
 }
 
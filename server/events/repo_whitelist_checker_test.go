@@ -14,9 +14,13 @@
 package events_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -209,3 +213,58 @@ func TestRepoWhitelistChecker_ContainsSchema(t *testing.T) {
 		})
 	}
 }
+
+type staticOnboardedRepos struct {
+	repos []models.OnboardedRepo
+	err   error
+}
+
+func (s staticOnboardedRepos) ListOnboardedRepos() ([]models.OnboardedRepo, error) {
+	return s.repos, s.err
+}
+
+func TestRepoWhitelistChecker_OnboardedRepos(t *testing.T) {
+	w, err := events.NewRepoWhitelistChecker("github.com/owner/other")
+	Ok(t, err)
+	w.OnboardedRepos = staticOnboardedRepos{
+		repos: []models.OnboardedRepo{{ID: "github.com/Owner/Repo"}},
+	}
+	Equals(t, true, w.IsWhitelisted("owner/repo", "github.com"))
+	Equals(t, true, w.IsWhitelisted("owner/other", "github.com"))
+	Equals(t, false, w.IsWhitelisted("owner/repo", "gitlab.com"))
+
+	// If we can't list the onboarded repos we fail closed.
+	w.OnboardedRepos = staticOnboardedRepos{err: errors.New("db error")}
+	Equals(t, false, w.IsWhitelisted("owner/repo", "github.com"))
+	Equals(t, true, w.IsWhitelisted("owner/other", "github.com"))
+}
+
+func TestRepoWhitelistChecker_OrgRepos(t *testing.T) {
+	w, err := events.NewRepoWhitelistChecker("")
+	Ok(t, err)
+	Equals(t, false, w.IsWhitelisted("owner/repo", "github.com"))
+	syncer, err := events.NewOrgRepoSyncer("github.com/owner", "", map[string]events.OrgRepoLister{
+		"github.com": &fakeOrgRepoLister{repos: map[string][]string{"owner": {"owner/repo"}}},
+	}, logging.NewNoopLogger())
+	Ok(t, err)
+	Ok(t, syncer.Sync())
+	w.OrgRepos = syncer
+	Equals(t, true, w.IsWhitelisted("owner/repo", "github.com"))
+	Equals(t, false, w.IsWhitelisted("owner/other", "github.com"))
+}
+
+func TestRepoWhitelistChecker_Tenants(t *testing.T) {
+	w, err := events.NewRepoWhitelistChecker("github.com/owner/*")
+	Ok(t, err)
+	w.Tenants = events.NewGlobalCfgStore(valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{ID: "github.com/team-a/infra", Tenant: "team-a"},
+			{ID: "github.com/team-b/infra"},
+		},
+	})
+	Equals(t, true, w.IsWhitelisted("owner/repo", "github.com"))
+	Equals(t, true, w.IsWhitelisted("team-a/infra", "github.com"))
+	Equals(t, false, w.IsWhitelisted("team-a/infra", "gitlab.com"))
+	// Repos configs outside of tenants don't whitelist repos.
+	Equals(t, false, w.IsWhitelisted("team-b/infra", "github.com"))
+}
@@ -0,0 +1,356 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+const (
+	// SARIFResultsFormat exports results as SARIF 2.1.0, which code scanning
+	// dashboards ingest.
+	SARIFResultsFormat = "sarif"
+	// JUnitResultsFormat exports results as JUnit XML, which CI systems show
+	// in their test summaries.
+	JUnitResultsFormat = "junit"
+
+	// planRuleID is the SARIF rule of the results for plans.
+	planRuleID = "atlantis/plan"
+)
+
+// ResultsExporter exports the results of each plan command, the plans'
+// summaries and their security scans' findings, as SARIF or JUnit XML so
+// dashboards and CI summaries can show them.
+type ResultsExporter struct {
+	// Format is SARIFResultsFormat or JUnitResultsFormat.
+	Format string
+	// Dir, if set, is the directory reports are written to at
+	// {owner}/{repo}/{pull}/{time}-{command}.{sarif|xml}.
+	Dir string
+	// URL, if set, is the endpoint reports are POSTed to. The repo, pull
+	// request and head commit are sent in X-Atlantis-* headers.
+	URL             string
+	Client          *http.Client
+	AtlantisVersion string
+}
+
+// Export exports the results of running cmdName on the pull request of ctx.
+func (e *ResultsExporter) Export(ctx *CommandContext, cmdName models.CommandName, results []models.ProjectResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	var report []byte
+	var err error
+	if e.Format == JUnitResultsFormat {
+		report, err = NewJUnitReport(cmdName, results)
+	} else {
+		report, err = NewSARIFReport(ctx.Pull, cmdName, results, e.AtlantisVersion)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "creating %s report", e.Format)
+	}
+
+	if e.Dir != "" {
+		file := filepath.Join(e.Dir, filepath.FromSlash(ctx.BaseRepo.FullName), strconv.Itoa(ctx.Pull.Num),
+			fmt.Sprintf("%s-%s.%s", time.Now().UTC().Format("20060102T150405Z"), cmdName.String(), e.extension()))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return errors.Wrap(err, "creating report dir")
+		}
+		if err := ioutil.WriteFile(file, report, 0600); err != nil {
+			return errors.Wrap(err, "writing report")
+		}
+		ctx.Log.Debug("wrote %s report to %s", e.Format, file)
+	}
+	if e.URL != "" {
+		if err := e.post(ctx, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ResultsExporter) extension() string {
+	if e.Format == JUnitResultsFormat {
+		return "xml"
+	}
+	return "sarif"
+}
+
+// post sends report to the URL.
+func (e *ResultsExporter) post(ctx *CommandContext, report []byte) error {
+	req, err := http.NewRequest("POST", e.URL, bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	if e.Format == JUnitResultsFormat {
+		req.Header.Set("Content-Type", "application/xml")
+	} else {
+		req.Header.Set("Content-Type", "application/sarif+json")
+	}
+	req.Header.Set("X-Atlantis-Repo", ctx.BaseRepo.FullName)
+	req.Header.Set("X-Atlantis-Pull", strconv.Itoa(ctx.Pull.Num))
+	req.Header.Set("X-Atlantis-Commit", ctx.Pull.HeadCommit)
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "posting report")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("posting report: responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// resultProject describes the project of res for reports.
+func resultProject(res models.ProjectResult) string {
+	project := fmt.Sprintf("dir: %s workspace: %s", res.RepoRelDir, res.Workspace)
+	if res.ProjectName != "" {
+		project = fmt.Sprintf("project: %s %s", res.ProjectName, project)
+	}
+	return project
+}
+
+// resultDetails describes the outcome of res, ex. the changes its plan makes.
+func resultDetails(res models.ProjectResult) string {
+	return NewPlanInsightsReport(models.ProjectCommandContext{
+		RepoRelDir:  res.RepoRelDir,
+		Workspace:   res.Workspace,
+		ProjectName: res.ProjectName,
+	}, res).Details
+}
+
+// resultFindings returns the security findings of res.
+func resultFindings(res models.ProjectResult) []models.SecurityFinding {
+	if res.PlanSuccess == nil {
+		return nil
+	}
+	return res.PlanSuccess.SecurityFindings
+}
+
+// findingPath returns the path of f relative to the repo root. Findings in
+// the plan are reported on the project's dir.
+func findingPath(res models.ProjectResult, f models.SecurityFinding) string {
+	return path.Join(filepath.ToSlash(res.RepoRelDir), filepath.ToSlash(f.File))
+}
+
+// sarifLog is the subset of SARIF 2.1.0 that we export.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool              sarifTool              `json:"tool"`
+	AutomationDetails sarifAutomationDetails `json:"automationDetails"`
+	Results           []sarifResult          `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// NewSARIFReport returns a SARIF report of results. Each plan is a result of
+// the atlantis/plan rule and each security finding is a result of its own
+// rule.
+func NewSARIFReport(pull models.PullRequest, cmdName models.CommandName, results []models.ProjectResult, atlantisVersion string) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Atlantis",
+			Version:        atlantisVersion,
+			InformationURI: "https://www.runatlantis.io",
+			Rules:          []sarifRule{{ID: planRuleID, ShortDescription: sarifMessage{Text: "Terraform plan"}}},
+		}},
+		// Code scanning dashboards group the results of each run by this
+		// ID so one pull request's results replace its previous ones.
+		AutomationDetails: sarifAutomationDetails{ID: fmt.Sprintf("atlantis/%s/%s/%d/", cmdName.String(), pull.BaseRepo.FullName, pull.Num)},
+		Results:           []sarifResult{},
+	}
+	rules := map[string]bool{planRuleID: true}
+	for _, res := range results {
+		level := "note"
+		if !res.IsSuccessful() {
+			level = "error"
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    planRuleID,
+			Level:     level,
+			Message:   sarifMessage{Text: fmt.Sprintf("%s %s: %s", strings.Title(cmdName.String()), resultProject(res), resultDetails(res))},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(res.RepoRelDir)}}}},
+		})
+		for _, f := range resultFindings(res) {
+			if !rules[f.RuleID] {
+				rules[f.RuleID] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: f.RuleID, ShortDescription: sarifMessage{Text: f.Description}})
+			}
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: findingPath(res, f)}}}
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line}
+			}
+			if f.Resource != "" {
+				loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: f.Resource}}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    f.RuleID,
+				Level:     sarifLevel(f.Severity),
+				Message:   sarifMessage{Text: fmt.Sprintf("%s (%s)", f.Description, resultProject(res))},
+				Locations: []sarifLocation{loc},
+			})
+		}
+	}
+	return json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
+
+// sarifLevel returns the SARIF level of a finding with severity.
+func sarifLevel(severity string) string {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL", "HIGH":
+		return "error"
+	case "MEDIUM":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// junitTestSuites is the subset of JUnit XML that we export.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReport returns a JUnit XML report of results. Each project is a test
+// suite with a test case for its plan, which fails if the plan did, and a
+// failed test case for each security finding.
+func NewJUnitReport(cmdName models.CommandName, results []models.ProjectResult) ([]byte, error) {
+	suites := junitTestSuites{Name: "atlantis " + cmdName.String()}
+	for _, res := range results {
+		suite := junitTestSuite{Name: resultProject(res)}
+		tc := junitTestCase{Name: cmdName.String(), ClassName: suite.Name}
+		switch {
+		case res.Error != nil:
+			tc.Error = &junitProblem{Message: fmt.Sprintf("%s errored", strings.Title(cmdName.String())), Text: res.Error.Error()}
+			suite.Errors++
+		case res.Failure != "":
+			tc.Failure = &junitProblem{Message: fmt.Sprintf("%s failed", strings.Title(cmdName.String())), Text: res.Failure}
+			suite.Failures++
+		default:
+			tc.SystemOut = resultDetails(res)
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		for _, f := range resultFindings(res) {
+			location := findingPath(res, f)
+			if f.Line > 0 {
+				location = fmt.Sprintf("%s:%d", location, f.Line)
+			}
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      fmt.Sprintf("%s %s", f.RuleID, location),
+				ClassName: suite.Name,
+				Failure:   &junitProblem{Message: f.Description, Type: strings.ToUpper(f.Severity), Text: f.Resource},
+			})
+			suite.Failures++
+		}
+		suite.Tests = len(suite.TestCases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
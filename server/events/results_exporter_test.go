@@ -0,0 +1,208 @@
+package events_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var exportPull = models.PullRequest{
+	Num:        7,
+	HeadCommit: "abc123",
+	BaseRepo:   models.Repo{FullName: "owner/repo"},
+}
+
+var exportResults = []models.ProjectResult{
+	{
+		Command:     models.PlanCommand,
+		ProjectName: "app",
+		RepoRelDir:  "app",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{
+			TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy.",
+			SecurityFindings: []models.SecurityFinding{
+				{RuleID: "CKV_AWS_19", Severity: "HIGH", Description: "Unencrypted bucket", File: "main.tf", Line: 3, Resource: "aws_s3_bucket.logs"},
+			},
+		},
+	},
+	{
+		Command:    models.PlanCommand,
+		RepoRelDir: "db",
+		Workspace:  "staging",
+		Error:      errors.New("init failed"),
+	},
+}
+
+func TestNewSARIFReport(t *testing.T) {
+	out, err := events.NewSARIFReport(exportPull, models.PlanCommand, exportResults, "0.15.0")
+	Ok(t, err)
+
+	var report struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Version string `json:"version"`
+					Rules   []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			AutomationDetails struct {
+				ID string `json:"id"`
+			} `json:"automationDetails"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+					LogicalLocations []struct {
+						FullyQualifiedName string `json:"fullyQualifiedName"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	Ok(t, json.Unmarshal(out, &report))
+	Equals(t, "2.1.0", report.Version)
+	Equals(t, 1, len(report.Runs))
+	run := report.Runs[0]
+	Equals(t, "0.15.0", run.Tool.Driver.Version)
+	Equals(t, 2, len(run.Tool.Driver.Rules))
+	Equals(t, "CKV_AWS_19", run.Tool.Driver.Rules[1].ID)
+	Equals(t, "atlantis/plan/owner/repo/7/", run.AutomationDetails.ID)
+	Equals(t, 3, len(run.Results))
+
+	plan := run.Results[0]
+	Equals(t, "atlantis/plan", plan.RuleID)
+	Equals(t, "note", plan.Level)
+	Equals(t, "Plan project: app dir: app workspace: default: 1 to add, 0 to change, 0 to destroy.", plan.Message.Text)
+
+	finding := run.Results[1]
+	Equals(t, "CKV_AWS_19", finding.RuleID)
+	Equals(t, "error", finding.Level)
+	Equals(t, "app/main.tf", finding.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	Equals(t, 3, finding.Locations[0].PhysicalLocation.Region.StartLine)
+	Equals(t, "aws_s3_bucket.logs", finding.Locations[0].LogicalLocations[0].FullyQualifiedName)
+
+	errored := run.Results[2]
+	Equals(t, "error", errored.Level)
+	Equals(t, "Plan dir: db workspace: staging: Plan errored: init failed", errored.Message.Text)
+}
+
+func TestNewJUnitReport(t *testing.T) {
+	out, err := events.NewJUnitReport(models.PlanCommand, exportResults)
+	Ok(t, err)
+	Assert(t, strings.HasPrefix(string(out), xml.Header), "exp XML header, got %q", out)
+
+	var report struct {
+		Name     string `xml:"name,attr"`
+		Tests    int    `xml:"tests,attr"`
+		Failures int    `xml:"failures,attr"`
+		Errors   int    `xml:"errors,attr"`
+		Suites   []struct {
+			Name      string `xml:"name,attr"`
+			TestCases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Type    string `xml:"type,attr"`
+				} `xml:"failure"`
+				Error *struct {
+					Text string `xml:",chardata"`
+				} `xml:"error"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	Ok(t, xml.Unmarshal(out, &report))
+	Equals(t, "atlantis plan", report.Name)
+	Equals(t, 3, report.Tests)
+	Equals(t, 1, report.Failures)
+	Equals(t, 1, report.Errors)
+	Equals(t, 2, len(report.Suites))
+
+	app := report.Suites[0]
+	Equals(t, "project: app dir: app workspace: default", app.Name)
+	Equals(t, 2, len(app.TestCases))
+	Equals(t, "1 to add, 0 to change, 0 to destroy.", app.TestCases[0].SystemOut)
+	Equals(t, "CKV_AWS_19 app/main.tf:3", app.TestCases[1].Name)
+	Equals(t, "Unencrypted bucket", app.TestCases[1].Failure.Message)
+	Equals(t, "HIGH", app.TestCases[1].Failure.Type)
+
+	db := report.Suites[1]
+	Equals(t, "init failed", db.TestCases[0].Error.Text)
+}
+
+func TestResultsExporter_Export(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	var posted []byte
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		posted, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	exporter := events.ResultsExporter{
+		Format: events.JUnitResultsFormat,
+		Dir:    tmp,
+		URL:    srv.URL,
+		Client: srv.Client(),
+	}
+	ctx := &events.CommandContext{
+		BaseRepo: exportPull.BaseRepo,
+		Pull:     exportPull,
+		Log:      logging.NewNoopLogger(),
+	}
+	Ok(t, exporter.Export(ctx, models.PlanCommand, exportResults))
+
+	files, err := filepath.Glob(filepath.Join(tmp, "owner", "repo", "7", "*-plan.xml"))
+	Ok(t, err)
+	Equals(t, 1, len(files))
+	written, err := ioutil.ReadFile(files[0]) // nolint: gosec
+	Ok(t, err)
+	Equals(t, string(written), string(posted))
+	Equals(t, "application/xml", headers.Get("Content-Type"))
+	Equals(t, "owner/repo", headers.Get("X-Atlantis-Repo"))
+	Equals(t, "7", headers.Get("X-Atlantis-Pull"))
+	Equals(t, "abc123", headers.Get("X-Atlantis-Commit"))
+
+	// Nothing is exported if there are no results.
+	posted = nil
+	Ok(t, exporter.Export(ctx, models.PlanCommand, nil))
+	Assert(t, posted == nil, "exp no report to be posted")
+}
+
+func TestResultsExporter_ExportErrStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad report", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	exporter := events.ResultsExporter{Format: events.SARIFResultsFormat, URL: srv.URL, Client: srv.Client()}
+	ctx := &events.CommandContext{BaseRepo: exportPull.BaseRepo, Pull: exportPull, Log: logging.NewNoopLogger()}
+	ErrEquals(t, "posting report: responded with status 400: bad report", exporter.Export(ctx, models.PlanCommand, exportResults))
+}
@@ -0,0 +1,209 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// RunningCommand is a command that's in progress, as listed by /api/pulls.
+type RunningCommand struct {
+	Repo      string    `json:"repo"`
+	Pull      int       `json:"pull"`
+	Command   string    `json:"command"`
+	User      string    `json:"user"`
+	StartedAt time.Time `json:"started_at"`
+	// Project is the project the command is currently running on, ex.
+	// "dir: infra/prod, workspace: default", or empty if it's not running on
+	// one yet.
+	Project string `json:"project,omitempty"`
+	// CancelledBy is the user who cancelled the command, or empty if it
+	// hasn't been cancelled.
+	CancelledBy string `json:"cancelled_by,omitempty"`
+
+	// repoID is the id of the repo, ex. github.com/owner/repo.
+	repoID string
+	// The project the command is running on, matched by Cancel.
+	projectName string
+	repoRelDir  string
+	workspace   string
+	// cancel is closed once the command is cancelled.
+	cancel chan struct{}
+}
+
+// RepoID returns the id of the command's repo, ex. github.com/owner/repo.
+func (c RunningCommand) RepoID() string {
+	return c.repoID
+}
+
+// RunningCommands tracks the commands that are in progress so operators can
+// see what a server is doing before restarting it, and cancels them. A nil
+// *RunningCommands tracks nothing.
+type RunningCommands struct {
+	mu       sync.Mutex
+	commands map[*CommandContext]*RunningCommand
+}
+
+// Start records that command started running in ctx and sets ctx.Done so
+// it can be cancelled with Cancel. If ctx.Done was already set, the command
+// is still cancelled once it's closed. The returned func must be called when
+// the command finishes.
+func (r *RunningCommands) Start(ctx *CommandContext, command string) func() {
+	if r == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.commands == nil {
+		r.commands = make(map[*CommandContext]*RunningCommand)
+	}
+	cmd := &RunningCommand{
+		Repo:      ctx.BaseRepo.FullName,
+		Pull:      ctx.Pull.Num,
+		Command:   command,
+		User:      ctx.User.Username,
+		StartedAt: time.Now(),
+		repoID:    ctx.BaseRepo.ID(),
+		cancel:    make(chan struct{}),
+	}
+	r.commands[ctx] = cmd
+	finished := make(chan struct{})
+	if done := ctx.Done; done != nil {
+		go func() {
+			select {
+			case <-done:
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				r.cancelLocked(cmd, "")
+			case <-finished:
+			}
+		}()
+	}
+	ctx.Done = cmd.cancel
+	return func() {
+		close(finished)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.commands, ctx)
+	}
+}
+
+// SetProject records that the command running in ctx is now running on the
+// project in pCmd.
+func (r *RunningCommands) SetProject(ctx *CommandContext, pCmd models.ProjectCommandContext) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cmd, ok := r.commands[ctx]; ok {
+		cmd.projectName = pCmd.ProjectName
+		cmd.repoRelDir = pCmd.RepoRelDir
+		cmd.workspace = pCmd.Workspace
+		if pCmd.ProjectName != "" {
+			cmd.Project = "project: " + pCmd.ProjectName
+		} else {
+			cmd.Project = "dir: " + pCmd.RepoRelDir + ", workspace: " + pCmd.Workspace
+		}
+	}
+}
+
+// Cancel cancels the commands running on pull request pullNum in repo, ex.
+// owner/repo, that aren't already cancelled. If sel selects projects, only
+// commands that are currently running on one of them are cancelled. Their
+// running steps are stopped and the rest of their projects are skipped. It
+// returns the cancelled commands, oldest first.
+func (r *RunningCommands) Cancel(repo string, pullNum int, sel DiscardSelector, user string) []RunningCommand {
+	if r == nil {
+		return nil
+	}
+	selectsProjects := sel != DiscardSelector{}
+	r.mu.Lock()
+	var cancelled []RunningCommand
+	for _, cmd := range r.commands {
+		if cmd.Pull != pullNum || !strings.EqualFold(cmd.Repo, repo) || isDone(cmd.cancel) {
+			continue
+		}
+		if selectsProjects && (cmd.Project == "" || !sel.matches(cmd.projectName, cmd.repoRelDir, cmd.workspace)) {
+			continue
+		}
+		r.cancelLocked(cmd, user)
+		cancelled = append(cancelled, *cmd)
+	}
+	r.mu.Unlock()
+	sortRunningCommands(cancelled)
+	return cancelled
+}
+
+// cancelLocked cancels cmd if it isn't already cancelled. r.mu must be held.
+func (r *RunningCommands) cancelLocked(cmd *RunningCommand, user string) {
+	select {
+	case <-cmd.cancel:
+		return
+	default:
+	}
+	cmd.CancelledBy = user
+	close(cmd.cancel)
+}
+
+// List returns the running commands, oldest first.
+func (r *RunningCommands) List() []RunningCommand {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	var list []RunningCommand
+	for _, cmd := range r.commands {
+		list = append(list, *cmd)
+	}
+	r.mu.Unlock()
+	sortRunningCommands(list)
+	return list
+}
+
+// sortRunningCommands sorts list oldest first.
+func sortRunningCommands(list []RunningCommand) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].StartedAt.Equal(list[j].StartedAt) {
+			return list[i].Repo+list[i].Command < list[j].Repo+list[j].Command
+		}
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+}
+
+// Len returns the number of running commands.
+func (r *RunningCommands) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.commands)
+}
+
+// CancelComment returns the comment posted on a pull request once cancelled
+// commands were cancelled by user via, ex. "a comment".
+func CancelComment(cancelled []RunningCommand, user string, via string) string {
+	if len(cancelled) == 0 {
+		return "There are no running commands to cancel."
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Cancelled** these commands via %s", via)
+	if user != "" {
+		fmt.Fprintf(&b, " by `%s`", user)
+	}
+	b.WriteString(":\n\n")
+	for _, cmd := range cancelled {
+		fmt.Fprintf(&b, "* `%s` started by `%s`", cmd.Command, cmd.User)
+		if cmd.Project != "" {
+			fmt.Fprintf(&b, " while running on %s", cmd.Project)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nTheir running steps are stopped and their results are commented once they have. Cancelled plans must be run again before they can be applied.")
+	return b.String()
+}
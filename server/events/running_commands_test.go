@@ -0,0 +1,76 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRunningCommands_Cancel(t *testing.T) {
+	running := &events.RunningCommands{}
+	repo := models.Repo{FullName: "owner/repo"}
+	newCtx := func(pullNum int) *events.CommandContext {
+		return &events.CommandContext{
+			BaseRepo: repo,
+			Pull:     models.PullRequest{Num: pullNum},
+			User:     models.User{Username: "user"},
+		}
+	}
+	app := newCtx(1)
+	defer running.Start(app, "plan")()
+	running.SetProject(app, models.ProjectCommandContext{ProjectName: "app", RepoRelDir: "app", Workspace: "default"})
+	db := newCtx(1)
+	defer running.Start(db, "apply")()
+	running.SetProject(db, models.ProjectCommandContext{RepoRelDir: "db", Workspace: "default"})
+	other := newCtx(2)
+	defer running.Start(other, "plan")()
+
+	// Commands are only cancelled if they're running on a selected project.
+	cancelled := running.Cancel("OWNER/repo", 1, events.DiscardSelector{ProjectName: "app"}, "admin")
+	Equals(t, 1, len(cancelled))
+	Equals(t, "plan", cancelled[0].Command)
+	Equals(t, "admin", cancelled[0].CancelledBy)
+	Assert(t, isClosed(app.Done), "exp app command to be cancelled")
+	Assert(t, !isClosed(db.Done), "exp db command to keep running")
+
+	// Commands that were already cancelled aren't cancelled again.
+	cancelled = running.Cancel("owner/repo", 1, events.DiscardSelector{}, "admin")
+	Equals(t, 1, len(cancelled))
+	Equals(t, "apply", cancelled[0].Command)
+	Assert(t, isClosed(db.Done), "exp db command to be cancelled")
+	Equals(t, 0, len(running.Cancel("owner/repo", 1, events.DiscardSelector{}, "admin")))
+	Assert(t, !isClosed(other.Done), "exp other pull request's command to keep running")
+
+	// Commands that are cancelled through the Done they were started with
+	// are cancelled too.
+	done := make(chan struct{})
+	autoplan := newCtx(3)
+	autoplan.Done = done
+	defer running.Start(autoplan, "autoplan")()
+	close(done)
+	<-autoplan.Done
+	Equals(t, 0, len(running.Cancel("owner/repo", 3, events.DiscardSelector{}, "admin")))
+}
+
+func TestCancelComment(t *testing.T) {
+	Equals(t, "There are no running commands to cancel.", events.CancelComment(nil, "admin", "a comment"))
+	Equals(t, "**Cancelled** these commands via a comment by `admin`:\n\n"+
+		"* `plan` started by `user` while running on project: app\n"+
+		"* `autoplan` started by `user`\n"+
+		"\nTheir running steps are stopped and their results are commented once they have. Cancelled plans must be run again before they can be applied.",
+		events.CancelComment([]events.RunningCommand{
+			{Command: "plan", User: "user", Project: "project: app"},
+			{Command: "autoplan", User: "user"},
+		}, "admin", "a comment"))
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -18,6 +18,7 @@ import (
 // ApplyStepRunner runs `terraform apply`.
 type ApplyStepRunner struct {
 	TerraformExecutor   TerraformExec
+	DefaultTFVersion    *version.Version
 	CommitStatusUpdater StatusUpdater
 	AsyncTFExec         AsyncTFExec
 }
@@ -27,6 +28,15 @@ func (a *ApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []stri
 		return "", errors.New("cannot run apply with -target because we are applying an already generated plan. Instead, run -target with atlantis plan")
 	}
 
+	tfVersion := a.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	extraArgs, err := AdaptArgs(ctx.Log, tfVersion, "apply", extraArgs)
+	if err != nil {
+		return "", err
+	}
+
 	planPath := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
 	contents, err := ioutil.ReadFile(planPath)
 	if os.IsNotExist(err) {
@@ -38,16 +48,23 @@ func (a *ApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []stri
 
 	var out string
 	if a.isRemotePlan(contents) {
-		args := append(append([]string{"apply", "-input=false", "-no-color"}, extraArgs...), ctx.EscapedCommentArgs...)
-		out, err = a.runRemoteApply(ctx, args, path, planPath, ctx.TerraformVersion, envs)
+		// Remote applies re-run the plan so they need the same variables.
+		// Local applies don't since they're saved in the planfile.
+		varFileArgs, varFileErr := VarFileArgs(ctx, path)
+		if varFileErr != nil {
+			return "", varFileErr
+		}
+		args := append(append(append(append([]string{"apply", "-input=false", "-no-color"}, destroyArgs(ctx)...), varFileArgs...), extraArgs...), ctx.EscapedCommentArgs...)
+		var runURL string
+		out, runURL, err = a.runRemoteApply(ctx, args, path, planPath, ctx.TerraformVersion, envs)
 		if err == nil {
-			out = a.cleanRemoteApplyOutput(out)
+			out = withRunURL(a.cleanRemoteApplyOutput(out), runURL)
 		}
 	} else {
 		// NOTE: we need to quote the plan path because Bitbucket Server can
 		// have spaces in its repo owner names which is part of the path.
 		args := append(append(append([]string{"apply", "-input=false", "-no-color"}, extraArgs...), ctx.EscapedCommentArgs...), fmt.Sprintf("%q", planPath))
-		out, err = a.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, args, envs, ctx.TerraformVersion, ctx.Workspace)
+		out, err = tfExecFor(a.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, args, envs, ctx.TerraformVersion, ctx.Workspace)
 	}
 
 	// If the apply was successful, delete the plan.
@@ -65,8 +82,7 @@ func (a *ApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []stri
 func (a *ApplyStepRunner) isRemotePlan(planContents []byte) bool {
 	// We add a header to plans generated by the remote backend so we can
 	// detect that they're remote in the apply phase.
-	remoteOpsHeaderBytes := []byte(remoteOpsHeader)
-	return bytes.Equal(planContents[:len(remoteOpsHeaderBytes)], remoteOpsHeaderBytes)
+	return bytes.HasPrefix(planContents, []byte(remoteOpsHeader))
 }
 
 func (a *ApplyStepRunner) hasTargetFlag(ctx models.ProjectCommandContext, extraArgs []string) bool {
@@ -115,19 +131,20 @@ func (a *ApplyStepRunner) cleanRemoteApplyOutput(out string) string {
 // We need to do this because remote plan doesn't support -out, so we do a
 // manual diff.
 // It also writes "yes" or "no" to the process to confirm the apply.
+// It returns the output and the run url.
 func (a *ApplyStepRunner) runRemoteApply(
 	ctx models.ProjectCommandContext,
 	applyArgs []string,
 	path string,
 	absPlanPath string,
 	tfVersion *version.Version,
-	envs map[string]string) (string, error) {
+	envs map[string]string) (string, string, error) {
 
 	// The planfile contents are needed to ensure that the plan didn't change
 	// between plan and apply phases.
 	planfileBytes, err := ioutil.ReadFile(absPlanPath)
 	if err != nil {
-		return "", errors.Wrap(err, "reading planfile")
+		return "", "", errors.Wrap(err, "reading planfile")
 	}
 
 	// updateStatusF will update the commit status and log any error.
@@ -139,7 +156,7 @@ func (a *ApplyStepRunner) runRemoteApply(
 
 	// Start the async command execution.
 	ctx.Log.Debug("starting async tf remote operation")
-	inCh, outCh := a.AsyncTFExec.RunCommandAsync(ctx.Log, filepath.Clean(path), applyArgs, envs, tfVersion, ctx.Workspace)
+	inCh, outCh := asyncTFExecFor(a.AsyncTFExec, ctx).RunCommandAsync(ctx.Log, filepath.Clean(path), applyArgs, envs, tfVersion, ctx.Workspace)
 	var lines []string
 	nextLineIsRunURL := false
 	var runURL string
@@ -150,6 +167,7 @@ func (a *ApplyStepRunner) runRemoteApply(
 			err = line.Err
 			break
 		}
+		ctx.Log.Debug("[remote run] %s", line.Line)
 		lines = append(lines, line.Line)
 
 		// Here we're checking for the run url and updating the status
@@ -190,7 +208,7 @@ func (a *ApplyStepRunner) runRemoteApply(
 		updateStatusF(models.FailedCommitStatus, runURL)
 		// The output isn't important if the plans don't match so we just
 		// discard it.
-		return "", planChangedErr
+		return "", runURL, planChangedErr
 	}
 
 	if err != nil {
@@ -198,7 +216,7 @@ func (a *ApplyStepRunner) runRemoteApply(
 	} else {
 		updateStatusF(models.SuccessCommitStatus, runURL)
 	}
-	return output, err
+	return output, runURL, err
 }
 
 // remotePlanChanged checks if the plan generated during the plan phase matches
@@ -206,18 +224,13 @@ func (a *ApplyStepRunner) runRemoteApply(
 // If the plans don't match, it returns an error with a diff of the two plans
 // that can be printed to the pull request.
 func (a *ApplyStepRunner) remotePlanChanged(planfileContents string, applyOut string) error {
-	// The plan is between the refresh separator...
-	planStartIdx := strings.Index(applyOut, refreshSeparator)
-	if planStartIdx < 0 {
-		return fmt.Errorf("Couldn't find refresh separator when parsing apply output:\n%q", applyOut)
-	}
-
-	// ...and the prompt to execute the plan.
-	planEndIdx := strings.Index(applyOut, "Do you want to perform these actions in workspace \"")
+	// The plan starts after the refresh and ends at the prompt to execute it.
+	currPlan := remotePlanOutput(applyOut)
+	planEndIdx := strings.Index(currPlan, remotePlanEndLine)
 	if planEndIdx < 0 {
 		return fmt.Errorf("Couldn't find plan end when parsing apply output:\n%q", applyOut)
 	}
-	currPlan := strings.TrimSpace(applyOut[planStartIdx+len(refreshSeparator) : planEndIdx])
+	currPlan = strings.TrimSpace(currPlan[:planEndIdx])
 
 	// Ensure we strip the remoteOpsHeader from the plan contents so the
 	// comparison is fair. We add this header in the plan phase so we can
@@ -257,7 +257,9 @@ null_resource.dir2[1]: Destroying... (ID: 8554368366766418126)
 null_resource.dir2[1]: Destruction complete after 0s
 
 Apply complete! Resources: 0 added, 0 changed, 1 destroyed.
-`, output)
+
+
+Terraform Cloud run: https://app.terraform.io/app/lkysow-enterprises/atlantis-tfe-test-dir2/runs/run-PiDsRYKGcerTttV2`, output)
 
 	Equals(t, []string{"apply", "-input=false", "-no-color", "extra", "args", "comment", "args"}, tfExec.CalledArgs)
 	_, err = os.Stat(planPath)
@@ -342,6 +344,52 @@ To resolve, re-run plan.`, err)
 	Ok(t, err)
 }
 
+// Test that remote applies work with the output of newer versions of
+// Terraform that don't print the refresh separator before the plan.
+func TestRun_RemoteApply_NoRefreshSeparator(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmpDir, "default.tfplan")
+	planFileContents := `Terraform used the selected providers to generate the following execution
+plan. Resource actions are indicated with the following symbols:
+  - destroy
+
+Terraform will perform the following actions:
+
+  # null_resource.hi will be destroyed
+  - resource "null_resource" "hi" {
+      - id = "217661332516885645" -> null
+    }
+
+Plan: 0 to add, 0 to change, 1 to destroy.`
+	Ok(t, ioutil.WriteFile(planPath, []byte("Atlantis: this plan was created by remote ops\n"+planFileContents), 0600))
+
+	RegisterMockTestingT(t)
+	tfExec := &remoteApplyMock{LinesToSend: fmt.Sprintf(cloudPreConfirmOutFmt, planFileContents) + cloudPostConfirmOut, DoneCh: make(chan bool)}
+	o := runtime.ApplyStepRunner{
+		AsyncTFExec:         tfExec,
+		CommitStatusUpdater: mocks2.NewMockCommitStatusUpdater(),
+	}
+	tfVersion, _ := version.NewVersion("1.1.0")
+	output, err := o.Run(models.ProjectCommandContext{
+		Workspace:        "default",
+		RepoRelDir:       ".",
+		TerraformVersion: tfVersion,
+	}, nil, tmpDir, map[string]string(nil))
+	<-tfExec.DoneCh
+
+	Ok(t, err)
+	Equals(t, "yes\n", tfExec.PassedInput)
+	Equals(t, `
+null_resource.hi: Destroying... [id=217661332516885645]
+null_resource.hi: Destruction complete after 0s
+
+Apply complete! Resources: 0 added, 0 changed, 1 destroyed.
+
+
+Terraform Cloud run: https://app.terraform.io/app/acme/networking/runs/run-VdBe1zXnRaUe8zKq`, output)
+}
+
 type remoteApplyMock struct {
 	// LinesToSend will be sent on the channel.
 	LinesToSend string
@@ -439,3 +487,35 @@ var noConfirmationOut = `
 
 Error: Apply discarded.
 `
+
+var cloudPreConfirmOutFmt = `Running apply in Terraform Cloud. Output will stream here. Pressing Ctrl-C
+will cancel the remote apply if it's still pending. If the apply started it
+will stop streaming the logs, but will not stop the apply running remotely.
+
+Preparing the remote apply...
+
+To view this run in a browser, visit:
+https://app.terraform.io/app/acme/networking/runs/run-VdBe1zXnRaUe8zKq
+
+Waiting for the plan to start...
+
+Terraform v1.1.0
+on linux_amd64
+Initializing plugins and modules...
+null_resource.hi: Refreshing state... [id=217661332516885645]
+
+%s
+
+Do you want to perform these actions in workspace "networking"?
+  Terraform will perform the actions described above.
+  Only 'yes' will be accepted to approve.
+
+  Enter a value: `
+
+var cloudPostConfirmOut = `
+
+null_resource.hi: Destroying... [id=217661332516885645]
+null_resource.hi: Destruction complete after 0s
+
+Apply complete! Resources: 0 added, 0 changed, 1 destroyed.
+`
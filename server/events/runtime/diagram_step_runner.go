@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+const (
+	// TerraformDiagramTool generates diagrams with `terraform graph`.
+	TerraformDiagramTool = "terraform"
+	// InframapDiagramTool generates diagrams with inframap
+	// (https://github.com/cycloidio/inframap).
+	InframapDiagramTool = "inframap"
+
+	// diagramArtifactName is the artifact name diagrams are uploaded as.
+	diagramArtifactName = "diagram"
+)
+
+// DiagramStepRunner runs diagram steps. They generate a graph of the
+// project's resources and upload it as an artifact so it can be linked to
+// from the pull request.
+type DiagramStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+	// Uploader is nil if artifact storage isn't configured.
+	Uploader ArtifactUploader
+	// DotPath is the path to graphviz's dot binary, used to render diagrams
+	// as SVG. If it's empty, the diagram's DOT source is uploaded instead.
+	DotPath string
+	// InframapPath is the path to the inframap binary. If it's empty,
+	// inframap is looked up in the PATH.
+	InframapPath string
+}
+
+// Run generates a diagram of the project at path with tool and uploads it.
+// Its output links to the uploaded diagram.
+func (d *DiagramStepRunner) Run(ctx models.ProjectCommandContext, tool string, path string) (string, error) {
+	if d.Uploader == nil {
+		return "", errors.New("can't upload diagram because artifact storage isn't configured on this Atlantis server")
+	}
+
+	var dot string
+	var err error
+	switch tool {
+	case InframapDiagramTool:
+		dot, err = d.inframapGraph(path)
+	default:
+		dot, err = d.terraformGraph(ctx, path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := ioutil.TempDir("", "atlantis-diagram")
+	if err != nil {
+		return "", errors.Wrap(err, "creating temp dir")
+	}
+	defer os.RemoveAll(tmp) // nolint: errcheck
+
+	filename, contents, err := d.render(dot)
+	if err != nil {
+		return "", err
+	}
+	diagramPath := filepath.Join(tmp, filename)
+	if err := ioutil.WriteFile(diagramPath, contents, 0600); err != nil {
+		return "", errors.Wrap(err, "writing diagram")
+	}
+	url, err := d.Uploader.Upload(ArtifactKey(ctx, diagramArtifactName, filename), diagramPath)
+	if err != nil {
+		return "", errors.Wrap(err, "uploading diagram")
+	}
+	return fmt.Sprintf("Uploaded diagram: %s", url), nil
+}
+
+// terraformGraph returns the DOT graph of the changes in the project's
+// planfile.
+func (d *DiagramStepRunner) terraformGraph(ctx models.ProjectCommandContext, path string) (string, error) {
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	contents, err := ioutil.ReadFile(planFile) // nolint: gosec
+	if os.IsNotExist(err) {
+		return "", errors.New("no planfile found, diagram steps must run after a plan step")
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "reading planfile")
+	}
+	if bytes.HasPrefix(contents, []byte(remoteOpsHeader)) {
+		return "", errors.New("can't generate a diagram of a plan created by remote ops")
+	}
+
+	tfVersion := d.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	out, err := tfExecFor(d.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, filepath.Clean(path), []string{"graph", planFile}, nil, tfVersion, ctx.Workspace)
+	if err != nil {
+		return "", errors.Wrapf(err, "running terraform graph: %s", out)
+	}
+	return trimToDigraph(out)
+}
+
+// inframapGraph returns the DOT graph inframap generates from the project's
+// configuration.
+func (d *DiagramStepRunner) inframapGraph(path string) (string, error) {
+	inframap := d.InframapPath
+	if inframap == "" {
+		inframap = InframapDiagramTool
+	}
+	cmd := exec.Command(inframap, "generate", "--hcl", ".") // nolint: gosec
+	cmd.Dir = path
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "running inframap: %s", stderr.String())
+	}
+	return trimToDigraph(string(out))
+}
+
+// render renders dot as SVG if dot is installed. It returns the filename the
+// diagram should be uploaded as and its contents.
+func (d *DiagramStepRunner) render(dot string) (string, []byte, error) {
+	if d.DotPath == "" {
+		return "diagram.dot", []byte(dot), nil
+	}
+	cmd := exec.Command(d.DotPath, "-Tsvg") // nolint: gosec
+	cmd.Stdin = strings.NewReader(dot)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "rendering diagram: %s", stderr.String())
+	}
+	return "diagram.svg", out, nil
+}
+
+// FindDot returns the path to graphviz's dot binary or an empty string if it
+// isn't in the PATH.
+func FindDot() string {
+	path, err := exec.LookPath("dot")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// trimToDigraph strips anything, ex. warnings, before the start of the DOT
+// graph in out.
+func trimToDigraph(out string) (string, error) {
+	start := strings.Index(out, "digraph")
+	if start < 0 {
+		return "", errors.New("no graph found in output")
+	}
+	if strings.HasSuffix(out[:start], "strict ") {
+		start -= len("strict ")
+	}
+	return out[start:], nil
+}
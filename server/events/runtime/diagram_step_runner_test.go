@@ -0,0 +1,114 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// recordingUploader records the key and contents of the last upload since
+// diagrams are written to temp files that are deleted after uploading.
+type recordingUploader struct {
+	key      string
+	contents string
+}
+
+func (r *recordingUploader) Upload(key string, path string) (string, error) {
+	contents, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return "", err
+	}
+	r.key = key
+	r.contents = string(contents)
+	return "https://bucket.s3.amazonaws.com/signed", nil
+}
+
+func TestDiagramStepRunner_RunTerraform(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "staging.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("planfile"), 0600))
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("Warning: something\n\ndigraph {\n}\n", nil)
+	uploader := &recordingUploader{}
+	r := &runtime.DiagramStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+		Uploader:          uploader,
+	}
+
+	ctx := uploadArtifactCtx()
+	out, err := r.Run(ctx, "", tmp)
+	Ok(t, err)
+	Equals(t, "Uploaded diagram: https://bucket.s3.amazonaws.com/signed", out)
+	Equals(t, "owner/repo/2/abc123/dfc912cc838b5615/diagram/diagram.dot", uploader.key)
+	Equals(t, "digraph {\n}\n", uploader.contents)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx.Log, tmp, []string{"graph", planPath}, nil, tfVersion, "staging")
+}
+
+func TestDiagramStepRunner_RunRendersSVG(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "staging.tfplan"), []byte("planfile"), 0600))
+	dot := filepath.Join(tmp, "dot")
+	Ok(t, ioutil.WriteFile(dot, []byte("#!/bin/sh\necho \"<svg>$1\"\ncat\n"), 0700)) // nolint: gosec
+
+	terraform := mocks.NewMockClient()
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("digraph {\n}\n", nil)
+	uploader := &recordingUploader{}
+	r := &runtime.DiagramStepRunner{
+		TerraformExecutor: terraform,
+		Uploader:          uploader,
+		DotPath:           dot,
+	}
+
+	_, err := r.Run(uploadArtifactCtx(), "terraform", tmp)
+	Ok(t, err)
+	Equals(t, "owner/repo/2/abc123/dfc912cc838b5615/diagram/diagram.svg", uploader.key)
+	Equals(t, "<svg>-Tsvg\ndigraph {\n}\n", uploader.contents)
+}
+
+func TestDiagramStepRunner_RunInframap(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	inframap := filepath.Join(tmp, "inframap")
+	Ok(t, ioutil.WriteFile(inframap, []byte("#!/bin/sh\necho \"strict digraph G { $*; }\"\n"), 0700)) // nolint: gosec
+
+	uploader := &recordingUploader{}
+	r := &runtime.DiagramStepRunner{
+		Uploader:     uploader,
+		InframapPath: inframap,
+	}
+
+	_, err := r.Run(uploadArtifactCtx(), "inframap", tmp)
+	Ok(t, err)
+	Equals(t, "strict digraph G { generate --hcl .; }\n", uploader.contents)
+}
+
+func TestDiagramStepRunner_RunNoPlanfile(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	r := &runtime.DiagramStepRunner{Uploader: &recordingUploader{}}
+	_, err := r.Run(uploadArtifactCtx(), "", tmp)
+	ErrEquals(t, "no planfile found, diagram steps must run after a plan step", err)
+}
+
+func TestDiagramStepRunner_RunNotConfigured(t *testing.T) {
+	r := &runtime.DiagramStepRunner{}
+	_, err := r.Run(uploadArtifactCtx(), "", "/tmp")
+	ErrEquals(t, "can't upload diagram because artifact storage isn't configured on this Atlantis server", err)
+}
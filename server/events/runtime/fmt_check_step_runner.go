@@ -0,0 +1,33 @@
+package runtime
+
+import (
+	"fmt"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// FmtCheckStepRunner runs `terraform fmt -check` so that unformatted files fail
+// the plan before it's run.
+type FmtCheckStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run checks the formatting of the Terraform files in path. Extra args, ex.
+// -recursive, are appended to the command. If any file isn't formatted it
+// returns an error with the diff that terraform fmt would apply.
+func (f *FmtCheckStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := f.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	args := append([]string{"fmt", "-check", "-diff"}, extraArgs...)
+	out, err := tfExecFor(f.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, args, envs, tfVersion, ctx.Workspace)
+	// If there's no output terraform itself failed, ex. it couldn't be
+	// downloaded, so there's nothing more specific to say.
+	if err != nil && out != "" {
+		return "", fmt.Errorf("terraform fmt found files that aren't formatted or can't be parsed. Run `terraform fmt` and push the changes:\n\n%s", out)
+	}
+	return "", err
+}
@@ -0,0 +1,52 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFmtCheckStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	r := runtime.FmtCheckStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("", nil)
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}
+
+	out, err := r.Run(ctx, []string{"-recursive"}, "/path", map[string]string(nil))
+	Ok(t, err)
+	Equals(t, "", out)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx.Log, "/path", []string{"fmt", "-check", "-diff", "-recursive"}, map[string]string(nil), tfVersion, "default")
+}
+
+func TestFmtCheckStepRunner_RunUnformatted(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	r := runtime.FmtCheckStepRunner{TerraformExecutor: terraform}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}
+
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("main.tf\n-  a =  1\n+  a = 1\n", errors.New("exit status 3"))
+	_, err := r.Run(ctx, nil, "/path", nil)
+	ErrEquals(t, "terraform fmt found files that aren't formatted or can't be parsed. Run `terraform fmt` and push the changes:\n\nmain.tf\n-  a =  1\n+  a = 1\n", err)
+
+	// Errors running terraform itself are returned as is.
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("", errors.New("downloading terraform"))
+	_, err = r.Run(ctx, nil, "/path", nil)
+	ErrEquals(t, "downloading terraform", err)
+}
@@ -16,15 +16,17 @@ func (i *InitStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	if ctx.TerraformVersion != nil {
 		tfVersion = ctx.TerraformVersion
 	}
-	terraformInitCmd := append([]string{"init", "-input=false", "-no-color", "-upgrade"}, extraArgs...)
-
-	// If we're running < 0.9 we have to use `terraform get` instead of `init`.
-	if MustConstraint("< 0.9.0").Check(tfVersion) {
+	initArgs := InitArgs(tfVersion)
+	if initArgs[0] == "get" {
 		ctx.Log.Info("running terraform version %s so will use `get` instead of `init`", tfVersion)
-		terraformInitCmd = append([]string{"get", "-no-color", "-upgrade"}, extraArgs...)
 	}
+	extraArgs, err := AdaptArgs(ctx.Log, tfVersion, initArgs[0], extraArgs)
+	if err != nil {
+		return "", err
+	}
+	terraformInitCmd := append(initArgs, extraArgs...)
 
-	out, err := i.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, terraformInitCmd, envs, tfVersion, ctx.Workspace)
+	out, err := tfExecFor(i.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, terraformInitCmd, envs, tfVersion, ctx.Workspace)
 	// Only include the init output if there was an error. Otherwise it's
 	// unnecessary and lengthens the comment.
 	if err != nil {
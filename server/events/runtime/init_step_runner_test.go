@@ -11,6 +11,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
 	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -88,3 +89,26 @@ func TestRun_ShowInitOutputOnError(t *testing.T) {
 	ErrEquals(t, "error", err)
 	Equals(t, "output", output)
 }
+
+func TestRun_DropsUnsupportedInitArgs(t *testing.T) {
+	// Flags removed in newer versions should be dropped so one workflow
+	// works for all versions.
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("output", nil)
+
+	tfVersion, _ := version.NewVersion("0.15.0")
+	iso := runtime.InitStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	logger := logging.NewNoopLogger()
+	_, err := iso.Run(models.ProjectCommandContext{
+		Log:        logger,
+		Workspace:  "workspace",
+		RepoRelDir: ".",
+	}, []string{"-get-plugins=false", "-backend=false"}, "/path", map[string]string(nil))
+	Ok(t, err)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", []string{"init", "-input=false", "-no-color", "-upgrade", "-backend=false"}, map[string]string(nil), tfVersion, "workspace")
+}
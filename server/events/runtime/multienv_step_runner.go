@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// envVarNameRegex matches valid environment variable names.
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MultiEnvStepRunner runs multienv steps, which set many environment
+// variables from the output of a single command.
+type MultiEnvStepRunner struct {
+	RunStepRunner *RunStepRunner
+}
+
+// Run runs command and returns the environment variables it printed. Each
+// line of output must be NAME=value. Blank lines are ignored.
+func (r *MultiEnvStepRunner) Run(ctx models.ProjectCommandContext, command string, path string, envs map[string]string) (map[string]string, error) {
+	res, err := r.RunStepRunner.Run(ctx, command, path, envs)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMultiEnvOutput(res)
+}
+
+// ParseMultiEnvOutput parses the NAME=value lines printed by a multienv
+// step's command. Values can contain '='.
+func ParseMultiEnvOutput(out string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, line := range strings.Split(out, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || !envVarNameRegex.MatchString(parts[0]) {
+			// Don't include the line since it could be a secret.
+			return nil, fmt.Errorf("multienv command output line %d must be NAME=value", i+1)
+		}
+		vars[parts[0]] = parts[1]
+	}
+	return vars, nil
+}
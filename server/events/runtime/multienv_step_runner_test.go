@@ -0,0 +1,58 @@
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseMultiEnvOutput(t *testing.T) {
+	cases := []struct {
+		description string
+		out         string
+		exp         map[string]string
+		expErr      string
+	}{
+		{
+			description: "empty",
+			out:         "",
+			exp:         map[string]string{},
+		},
+		{
+			description: "multiple vars",
+			out:         "AWS_ACCESS_KEY_ID=id\nAWS_SECRET_ACCESS_KEY=se=cret\n\nEMPTY=\r\n",
+			exp: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "id",
+				"AWS_SECRET_ACCESS_KEY": "se=cret",
+				"EMPTY":                 "",
+			},
+		},
+		{
+			description: "later vars win",
+			out:         "A=1\nA=2\n",
+			exp:         map[string]string{"A": "2"},
+		},
+		{
+			description: "no equals",
+			out:         "A=1\nsecret-value\n",
+			expErr:      "multienv command output line 2 must be NAME=value",
+		},
+		{
+			description: "invalid name",
+			out:         "1A=1\n",
+			expErr:      "multienv command output line 1 must be NAME=value",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vars, err := runtime.ParseMultiEnvOutput(c.out)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, vars)
+		})
+	}
+}
@@ -38,6 +38,17 @@ func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	if ctx.TerraformVersion != nil {
 		tfVersion = ctx.TerraformVersion
 	}
+	extraArgs, err := AdaptArgs(ctx.Log, tfVersion, "plan", extraArgs)
+	if err != nil {
+		return "", err
+	}
+	varFileArgs, err := VarFileArgs(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	// They're added before the extra args so that -var and -var-file flags
+	// from the workflow or comment override them.
+	extraArgs = append(varFileArgs, extraArgs...)
 
 	// We only need to switch workspaces in version 0.9.*. In older versions,
 	// there is no such thing as a workspace so we don't need to do anything.
@@ -46,8 +57,14 @@ func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	}
 
 	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	// Remote operations don't support saving the plan so there's no point
+	// running a plan with -out that we know will fail.
+	if UsesRemoteBackend(path, envs) {
+		ctx.Log.Debug("detected that this project is using a remote or cloud backend")
+		return p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
+	}
 	planCmd := p.buildPlanCmd(ctx, extraArgs, path, tfVersion, planFile)
-	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, filepath.Clean(path), planCmd, envs, tfVersion, ctx.Workspace)
+	output, err := tfExecFor(p.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, filepath.Clean(path), planCmd, envs, tfVersion, ctx.Workspace)
 	if p.isRemoteOpsErr(output, err) {
 		ctx.Log.Debug("detected that this project is using TFE remote ops")
 		return p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
@@ -64,7 +81,7 @@ func (p *PlanStepRunner) isRemoteOpsErr(output string, err error) bool {
 	if err == nil {
 		return false
 	}
-	return strings.Contains(output, remoteOpsErr01114) || strings.Contains(output, remoteOpsErr012)
+	return strings.Contains(output, remoteOpsErr01114) || strings.Contains(output, remoteOpsErr012) || strings.Contains(output, remoteOpsErrPrefix)
 }
 
 // remotePlan runs a terraform plan command compatible with TFE remote
@@ -72,11 +89,12 @@ func (p *PlanStepRunner) isRemoteOpsErr(output string, err error) bool {
 func (p *PlanStepRunner) remotePlan(ctx models.ProjectCommandContext, extraArgs []string, path string, tfVersion *version.Version, planFile string, envs map[string]string) (string, error) {
 	argList := [][]string{
 		{"plan", "-input=false", "-refresh", "-no-color"},
+		destroyArgs(ctx),
 		extraArgs,
 		ctx.EscapedCommentArgs,
 	}
 	args := p.flatten(argList)
-	output, err := p.runRemotePlan(ctx, args, path, tfVersion, envs)
+	output, runURL, err := p.runRemotePlan(ctx, args, path, tfVersion, envs)
 	if err != nil {
 		return output, err
 	}
@@ -89,11 +107,7 @@ func (p *PlanStepRunner) remotePlan(ctx models.ProjectCommandContext, extraArgs
 	// plan. To ensure that what gets applied is the plan we printed to the PR,
 	// during the apply phase, we diff the output we stored in the fake
 	// planfile with the pending apply output.
-	planOutput := output
-	sepIdx := strings.Index(planOutput, refreshSeparator)
-	if sepIdx > -1 {
-		planOutput = planOutput[sepIdx+len(refreshSeparator):]
-	}
+	planOutput := remotePlanOutput(output)
 
 	// We also prepend our own remote ops header to the file so during apply we
 	// know this is a remote apply.
@@ -102,35 +116,62 @@ func (p *PlanStepRunner) remotePlan(ctx models.ProjectCommandContext, extraArgs
 		return output, errors.Wrap(err, "unable to create planfile for remote ops")
 	}
 
-	return p.fmtPlanOutput(output), nil
+	return withRunURL(p.fmtPlanOutput(planOutput), runURL), nil
+}
+
+// VarFileArgs returns the -var-file flags for the project's <workspace>.tfvars
+// file if the project sets workspace_var_file, and for the file selected with
+// --var-file. It errors if a file doesn't exist so a typo in a workspace name
+// doesn't silently plan without its variables.
+func VarFileArgs(ctx models.ProjectCommandContext, path string) ([]string, error) {
+	var args []string
+	if ctx.WorkspaceVarFile {
+		varFile := ctx.Workspace + ".tfvars"
+		if err := checkVarFile(path, varFile); err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				return nil, fmt.Errorf("workspace_var_file is set but %q doesn't exist in %q", varFile, ctx.RepoRelDir)
+			}
+			return nil, err
+		}
+		args = append(args, "-var-file", varFile)
+	}
+	if ctx.VarFile != "" {
+		if err := checkVarFile(path, ctx.VarFile); err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				return nil, fmt.Errorf("--var-file %q doesn't exist in %q", ctx.VarFile, ctx.RepoRelDir)
+			}
+			return nil, err
+		}
+		args = append(args, "-var-file", ctx.VarFile)
+	}
+	return args, nil
+}
+
+// checkVarFile returns an error if varFile doesn't exist in path.
+func checkVarFile(path string, varFile string) error {
+	_, err := os.Stat(filepath.Join(path, varFile))
+	return errors.Wrapf(err, "checking for %s", varFile)
 }
 
 // switchWorkspace changes the terraform workspace if necessary and will create
 // it if it doesn't exist. It handles differences between versions.
 func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path string, tfVersion *version.Version, envs map[string]string) error {
-	// In versions less than 0.9 there is no support for workspaces.
-	noWorkspaceSupport := MustConstraint("<0.9").Check(tfVersion)
+	workspaceCmd := WorkspaceSubcommand(tfVersion)
 	// If the user tried to set a specific workspace in the comment but their
 	// version of TF doesn't support workspaces then error out.
-	if noWorkspaceSupport && ctx.Workspace != defaultWorkspace {
+	if workspaceCmd == "" && ctx.Workspace != defaultWorkspace {
 		return fmt.Errorf("terraform version %s does not support workspaces", tfVersion)
 	}
-	if noWorkspaceSupport {
+	if workspaceCmd == "" {
 		return nil
 	}
-
-	// In version 0.9.* the workspace command was called env.
-	workspaceCmd := "workspace"
-	runningZeroPointNine := MustConstraint(">=0.9,<0.10").Check(tfVersion)
-	if runningZeroPointNine {
-		workspaceCmd = "env"
-	}
+	runningZeroPointNine := workspaceCmd == "env"
 
 	// Use `workspace show` to find out what workspace we're in now. If we're
 	// already in the right workspace then no need to switch. This will save us
 	// about ten seconds. This command is only available in > 0.10.
 	if !runningZeroPointNine {
-		workspaceShowOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "show"}, envs, tfVersion, ctx.Workspace)
+		workspaceShowOutput, err := tfExecFor(p.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "show"}, envs, tfVersion, ctx.Workspace)
 		if err != nil {
 			return err
 		}
@@ -145,11 +186,11 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 	// To do this we can either select and catch the error or use list and then
 	// look for the workspace. Both commands take the same amount of time so
 	// that's why we're running select here.
-	_, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
+	_, err := tfExecFor(p.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
 	if err != nil {
 		// If terraform workspace select fails we run terraform workspace
 		// new to create a new workspace automatically.
-		out, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
+		out, err := tfExecFor(p.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
 		if err != nil {
 			return fmt.Errorf("%s: %s", err, out)
 		}
@@ -174,6 +215,7 @@ func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArg
 		// NOTE: we need to quote the plan filename because Bitbucket Server can
 		// have spaces in its repo owner names.
 		{"plan", "-input=false", "-refresh", "-no-color", "-out", fmt.Sprintf("%q", planFile)},
+		destroyArgs(ctx),
 		tfVars,
 		extraArgs,
 		ctx.EscapedCommentArgs,
@@ -183,6 +225,15 @@ func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArg
 	return p.flatten(argList)
 }
 
+// destroyArgs returns the flag that makes terraform plan destroy every
+// resource if this is a destroy plan.
+func destroyArgs(ctx models.ProjectCommandContext) []string {
+	if ctx.Destroy {
+		return []string{"-destroy"}
+	}
+	return nil
+}
+
 // tfVars returns a list of "-var", "key=value" pairs that identify who and which
 // repo this command is running for. This can be used for naming the
 // session name in AWS which will identify in CloudTrail the source of
@@ -245,7 +296,8 @@ func (p *PlanStepRunner) fmtPlanOutput(output string) string {
 // backend. It watches the command output for the run url to be printed, and
 // then updates the commit status with a link to the run url.
 // The run url is a link to the Terraform Enterprise UI where the output
-// from the in-progress command can be viewed.
+// from the in-progress command can be viewed. It's returned along with the
+// output.
 // cmdArgs is the args to terraform to execute.
 // path is the path to where we need to execute.
 func (p *PlanStepRunner) runRemotePlan(
@@ -253,7 +305,7 @@ func (p *PlanStepRunner) runRemotePlan(
 	cmdArgs []string,
 	path string,
 	tfVersion *version.Version,
-	envs map[string]string) (string, error) {
+	envs map[string]string) (string, string, error) {
 
 	// updateStatusF will update the commit status and log any error.
 	updateStatusF := func(status models.CommitStatus, url string) {
@@ -264,7 +316,7 @@ func (p *PlanStepRunner) runRemotePlan(
 
 	// Start the async command execution.
 	ctx.Log.Debug("starting async tf remote operation")
-	_, outCh := p.AsyncTFExec.RunCommandAsync(ctx.Log, filepath.Clean(path), cmdArgs, envs, tfVersion, ctx.Workspace)
+	_, outCh := asyncTFExecFor(p.AsyncTFExec, ctx).RunCommandAsync(ctx.Log, filepath.Clean(path), cmdArgs, envs, tfVersion, ctx.Workspace)
 	var lines []string
 	nextLineIsRunURL := false
 	var runURL string
@@ -275,6 +327,7 @@ func (p *PlanStepRunner) runRemotePlan(
 			err = line.Err
 			break
 		}
+		ctx.Log.Debug("[remote run] %s", line.Line)
 		lines = append(lines, line.Line)
 
 		// Here we're checking for the run url and updating the status
@@ -296,7 +349,7 @@ func (p *PlanStepRunner) runRemotePlan(
 	} else {
 		updateStatusF(models.SuccessCommitStatus, runURL)
 	}
-	return output, err
+	return output, runURL, err
 }
 
 var vTwelveAndUp = MustConstraint(">=0.12-a")
@@ -320,6 +373,12 @@ locally at this time.
 
 `
 
+// remoteOpsErrPrefix is the start of the error terraform plan returns if this
+// project is using remote operations. It's wrapped differently depending on the
+// version and whether the remote backend or a cloud block is used so we only
+// match the first line.
+var remoteOpsErrPrefix = "Error: Saving a generated plan is currently not supported"
+
 // remoteOpsHeader is the header we add to the planfile if this plan was
 // generated using TFE remote operations.
 var remoteOpsHeader = "Atlantis: this plan was created by remote ops\n"
@@ -444,6 +444,137 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	Equals(t, "output", output)
 }
 
+func TestRun_Destroy(t *testing.T) {
+	// Test that destroy plans are run with -destroy.
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	tfVersion, _ := version.NewVersion("0.12.0")
+	logger := logging.NewNoopLogger()
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+
+	expPlanArgs := []string{"plan",
+		"-input=false",
+		"-refresh",
+		"-no-color",
+		"-out",
+		fmt.Sprintf("%q", filepath.Join(tmpDir, "default.tfplan")),
+		"-destroy",
+		"extra",
+		"args",
+		"comment",
+		"args",
+	}
+	When(terraform.RunCommandWithVersion(logger, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")).ThenReturn("output", nil)
+
+	output, err := s.Run(models.ProjectCommandContext{
+		Log:                logger,
+		Workspace:          "default",
+		RepoRelDir:         ".",
+		EscapedCommentArgs: []string{"comment", "args"},
+		Destroy:            true,
+	}, []string{"extra", "args"}, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")
+	Equals(t, "output", output)
+}
+
+func TestRun_AddsWorkspaceVarFile(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "staging.tfvars"), nil, 0600))
+
+	tfVersion, _ := version.NewVersion("0.12.0")
+	logger := logging.NewNoopLogger()
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("output", nil)
+
+	_, err := s.Run(models.ProjectCommandContext{
+		Log:                logger,
+		Workspace:          "staging",
+		WorkspaceVarFile:   true,
+		RepoRelDir:         ".",
+		EscapedCommentArgs: []string{"comment", "args"},
+	}, []string{"extra", "args"}, tmpDir, map[string]string(nil))
+	Ok(t, err)
+
+	// The var file comes before the extra args so they can override it.
+	expPlanArgs := []string{"plan",
+		"-input=false",
+		"-refresh",
+		"-no-color",
+		"-out",
+		fmt.Sprintf("%q", filepath.Join(tmpDir, "staging.tfplan")),
+		"-var-file",
+		"staging.tfvars",
+		"extra",
+		"args",
+		"comment",
+		"args",
+	}
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "staging")
+}
+
+func TestRun_WorkspaceVarFileMissing(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	tfVersion, _ := version.NewVersion("0.12.0")
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	_, err := s.Run(models.ProjectCommandContext{
+		Log:              logging.NewNoopLogger(),
+		Workspace:        "staging",
+		WorkspaceVarFile: true,
+		RepoRelDir:       "project",
+	}, nil, tmpDir, map[string]string(nil))
+	ErrEquals(t, `workspace_var_file is set but "staging.tfvars" doesn't exist in "project"`, err)
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())
+}
+
+func TestVarFileArgs(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "staging.tfvars"), nil, 0600))
+	Ok(t, os.Mkdir(filepath.Join(tmpDir, "env"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "env", "production.tfvars"), nil, 0600))
+
+	args, err := runtime.VarFileArgs(models.ProjectCommandContext{Workspace: "staging"}, tmpDir)
+	Ok(t, err)
+	Equals(t, []string(nil), args)
+
+	// The selected var file comes after the workspace's so it overrides it.
+	args, err = runtime.VarFileArgs(models.ProjectCommandContext{
+		Workspace:        "staging",
+		WorkspaceVarFile: true,
+		VarFile:          "env/production.tfvars",
+	}, tmpDir)
+	Ok(t, err)
+	Equals(t, []string{"-var-file", "staging.tfvars", "-var-file", "env/production.tfvars"}, args)
+
+	_, err = runtime.VarFileArgs(models.ProjectCommandContext{
+		Workspace:  "default",
+		RepoRelDir: "project",
+		VarFile:    "missing.tfvars",
+	}, tmpDir)
+	ErrEquals(t, `--var-file "missing.tfvars" doesn't exist in "project"`, err)
+}
+
 func TestRun_UsesDiffPathForProject(t *testing.T) {
 	// Test that if running for a project, uses a different path for the plan
 	// file.
@@ -679,6 +810,12 @@ plan locally at this time.
 The "remote" backend does not support saving the generated execution plan
 locally at this time.
 
+`,
+		"cloud block error": `Error: Saving a generated plan is currently not supported
+
+Terraform Cloud does not support saving the generated execution plan locally
+at this time.
+
 `,
 	}
 	for name, remoteOpsErr := range cases {
@@ -764,7 +901,9 @@ Terraform will perform the following actions:
 - null_resource.hi[1]
 
 
-Plan: 0 to add, 0 to change, 1 to destroy.`, output)
+Plan: 0 to add, 0 to change, 1 to destroy.
+
+Terraform Cloud run: https://app.terraform.io/app/lkysow-enterprises/atlantis-tfe-test/runs/run-is4oVvJfrkud1KvE`, output)
 
 			expRemotePlanArgs := []string{"plan", "-input=false", "-refresh", "-no-color", "extra", "args", "comment", "args"}
 			Equals(t, expRemotePlanArgs, asyncTf.CalledArgs)
@@ -793,6 +932,86 @@ Plan: 0 to add, 0 to change, 1 to destroy.`, string(bytes))
 	}
 }
 
+// Test that projects initialized with a cloud block are planned remotely
+// without first trying to save the plan, and that the plan is parsed from
+// output that doesn't have the refresh separator.
+func TestRun_RemoteBackendDetected(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	asyncTf := &remotePlanMock{LinesToSend: cloudPlanOutput}
+	tfVersion, _ := version.NewVersion("1.1.0")
+	s := runtime.PlanStepRunner{
+		TerraformExecutor:   terraform,
+		DefaultTFVersion:    tfVersion,
+		AsyncTFExec:         asyncTf,
+		CommitStatusUpdater: mocks2.NewMockCommitStatusUpdater(),
+	}
+	absProjectPath, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.Mkdir(filepath.Join(absProjectPath, ".terraform"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(absProjectPath, ".terraform", "terraform.tfstate"), []byte(`{"version": 3, "backend": {"type": "cloud"}}`), 0600))
+	When(terraform.RunCommandWithVersion(nil, absProjectPath, []string{"workspace", "show"}, map[string]string(nil), tfVersion, "default")).
+		ThenReturn("default\n", nil)
+
+	output, err := s.Run(models.ProjectCommandContext{
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}, nil, absProjectPath, map[string]string(nil))
+	Ok(t, err)
+	Equals(t, `Terraform used the selected providers to generate the following execution
+plan. Resource actions are indicated with the following symbols:
+- destroy
+
+Terraform will perform the following actions:
+
+  # null_resource.hi will be destroyed
+- resource "null_resource" "hi" {
+      - id = "217661332516885645" -> null
+    }
+
+Plan: 0 to add, 0 to change, 1 to destroy.
+
+Terraform Cloud run: https://app.terraform.io/app/acme/networking/runs/run-CZcmD7eagjhyX0vN`, output)
+	Equals(t, []string{"plan", "-input=false", "-refresh", "-no-color"}, asyncTf.CalledArgs)
+	// Only workspace show should have run locally.
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())
+
+	contents, err := ioutil.ReadFile(filepath.Join(absProjectPath, "default.tfplan"))
+	Ok(t, err)
+	Assert(t, strings.HasPrefix(string(contents), "Atlantis: this plan was created by remote ops\nTerraform used the selected providers"), "unexpected planfile contents %q", string(contents))
+}
+
+func TestUsesRemoteBackend(t *testing.T) {
+	cases := []struct {
+		description string
+		dataDir     string
+		envs        map[string]string
+		state       string
+		exp         bool
+	}{
+		{"remote backend", ".terraform", nil, `{"backend": {"type": "remote"}}`, true},
+		{"cloud block", ".terraform", nil, `{"backend": {"type": "cloud"}}`, true},
+		{"s3 backend", ".terraform", nil, `{"backend": {"type": "s3"}}`, false},
+		{"no backend", ".terraform", nil, `{"version": 3}`, false},
+		{"invalid json", ".terraform", nil, `{`, false},
+		{"TF_DATA_DIR", "data", map[string]string{"TF_DATA_DIR": "data"}, `{"backend": {"type": "remote"}}`, true},
+		{"ignores default dir with TF_DATA_DIR", ".terraform", map[string]string{"TF_DATA_DIR": "data"}, `{"backend": {"type": "remote"}}`, false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			Ok(t, os.Mkdir(filepath.Join(tmp, c.dataDir), 0700))
+			Ok(t, ioutil.WriteFile(filepath.Join(tmp, c.dataDir, "terraform.tfstate"), []byte(c.state), 0600))
+			Equals(t, c.exp, runtime.UsesRemoteBackend(tmp, c.envs))
+		})
+	}
+
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Equals(t, false, runtime.UsesRemoteBackend(tmp, nil))
+}
+
 type remotePlanMock struct {
 	// LinesToSend will be sent on the channel.
 	LinesToSend string
@@ -860,3 +1079,31 @@ Terraform will perform the following actions:
 
 
 Plan: 0 to add, 0 to change, 1 to destroy.`
+
+var cloudPlanOutput = `Running plan in Terraform Cloud. Output will stream here. Pressing Ctrl-C
+will stop streaming the logs, but will not stop the plan running remotely.
+
+Preparing the remote plan...
+
+To view this run in a browser, visit:
+https://app.terraform.io/app/acme/networking/runs/run-CZcmD7eagjhyX0vN
+
+Waiting for the plan to start...
+
+Terraform v1.1.0
+on linux_amd64
+Initializing plugins and modules...
+null_resource.hi: Refreshing state... [id=217661332516885645]
+
+Terraform used the selected providers to generate the following execution
+plan. Resource actions are indicated with the following symbols:
+  - destroy
+
+Terraform will perform the following actions:
+
+  # null_resource.hi will be destroyed
+  - resource "null_resource" "hi" {
+      - id = "217661332516885645" -> null
+    }
+
+Plan: 0 to add, 0 to change, 1 to destroy.`
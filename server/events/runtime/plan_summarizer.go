@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// PlanSummarizer summarizes a plan using `terraform show -json`.
+type PlanSummarizer struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// showJSONOutput is the subset of the `terraform show -json` output that we
+// use.
+type showJSONOutput struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// Summarize returns a summary of the plan for the project in path. It returns
+// nil if a summary can't be generated, ex. because there's no planfile or
+// the Terraform version is too old to support `terraform show -json`.
+func (p *PlanSummarizer) Summarize(ctx models.ProjectCommandContext, path string) (*models.PlanSummary, error) {
+	tfVersion := p.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	if !SupportsShowJSON(tfVersion) {
+		ctx.Log.Debug("not summarizing plan because terraform version %s doesn't support show -json", tfVersion)
+		return nil, nil
+	}
+
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	contents, err := ioutil.ReadFile(planFile) // nolint: gosec
+	if os.IsNotExist(err) {
+		ctx.Log.Debug("not summarizing plan because no planfile exists at %q", planFile)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading planfile")
+	}
+	if bytes.HasPrefix(contents, []byte(remoteOpsHeader)) {
+		// Remote ops plans aren't real planfiles.
+		return nil, nil
+	}
+
+	out, err := tfExecFor(p.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, filepath.Clean(path), []string{"show", "-json", planFile}, nil, tfVersion, ctx.Workspace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "running terraform show: %s", out)
+	}
+	return ParseShowJSON(out)
+}
+
+// ParseShowJSON parses the output of `terraform show -json` into a summary.
+func ParseShowJSON(out string) (*models.PlanSummary, error) {
+	// The output might be preceded by warnings since we get both stdout and
+	// stderr so we start parsing from the first brace.
+	start := strings.Index(out, "{")
+	if start < 0 {
+		return nil, errors.New("no JSON found in terraform show output")
+	}
+	var parsed showJSONOutput
+	if err := json.NewDecoder(strings.NewReader(out[start:])).Decode(&parsed); err != nil {
+		return nil, errors.Wrap(err, "parsing terraform show output")
+	}
+
+	summary := &models.PlanSummary{}
+	for _, rc := range parsed.ResourceChanges {
+		switch strings.Join(rc.Change.Actions, ",") {
+		case "create":
+			summary.Creates = append(summary.Creates, rc.Address)
+		case "update":
+			summary.Updates = append(summary.Updates, rc.Address)
+		case "delete,create", "create,delete":
+			summary.Replaces = append(summary.Replaces, rc.Address)
+		case "delete":
+			summary.Deletes = append(summary.Deletes, rc.Address)
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,105 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseShowJSON(t *testing.T) {
+	out := `Warning: something
+
+{"format_version":"0.1","resource_changes":[
+{"address":"null_resource.create","change":{"actions":["create"]}},
+{"address":"null_resource.noop","change":{"actions":["no-op"]}},
+{"address":"data.null_data_source.read","change":{"actions":["read"]}},
+{"address":"null_resource.update","change":{"actions":["update"]}},
+{"address":"null_resource.replace1","change":{"actions":["delete","create"]}},
+{"address":"null_resource.replace2","change":{"actions":["create","delete"]}},
+{"address":"null_resource.delete","change":{"actions":["delete"]}}
+]}`
+	summary, err := runtime.ParseShowJSON(out)
+	Ok(t, err)
+	Equals(t, &models.PlanSummary{
+		Creates:  []string{"null_resource.create"},
+		Updates:  []string{"null_resource.update"},
+		Replaces: []string{"null_resource.replace1", "null_resource.replace2"},
+		Deletes:  []string{"null_resource.delete"},
+	}, summary)
+}
+
+func TestParseShowJSON_Invalid(t *testing.T) {
+	_, err := runtime.ParseShowJSON("Error: no planfile")
+	ErrEquals(t, "no JSON found in terraform show output", err)
+}
+
+func TestSummarize(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("planfile"), 0600))
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	s := runtime.PlanSummarizer{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	logger := logging.NewNoopLogger()
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn(`{"resource_changes":[{"address":"null_resource.a","change":{"actions":["create"]}}]}`, nil)
+
+	summary, err := s.Summarize(models.ProjectCommandContext{
+		Log:       logger,
+		Workspace: "default",
+	}, tmp)
+	Ok(t, err)
+	Equals(t, &models.PlanSummary{Creates: []string{"null_resource.a"}}, summary)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmp, []string{"show", "-json", planPath}, nil, tfVersion, "default")
+}
+
+func TestSummarize_Skipped(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	oldVersion, _ := version.NewVersion("0.11.14")
+	s := runtime.PlanSummarizer{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	ctx := models.ProjectCommandContext{
+		Log:       logging.NewNoopLogger(),
+		Workspace: "default",
+	}
+
+	t.Run("no planfile", func(t *testing.T) {
+		summary, err := s.Summarize(ctx, tmp)
+		Ok(t, err)
+		Assert(t, summary == nil, "expected nil summary")
+	})
+
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("planfile"), 0600))
+	t.Run("old terraform version", func(t *testing.T) {
+		oldCtx := ctx
+		oldCtx.TerraformVersion = oldVersion
+		summary, err := s.Summarize(oldCtx, tmp)
+		Ok(t, err)
+		Assert(t, summary == nil, "expected nil summary")
+	})
+
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())
+}
@@ -8,4 +8,20 @@ import (
 
 type PullApprovedChecker interface {
 	PullIsApproved(baseRepo models.Repo, pull models.PullRequest) (bool, error)
+	// GetPullApprovers returns the usernames of the users other than the
+	// author whose approvals of pull are current.
+	GetPullApprovers(baseRepo models.Repo, pull models.PullRequest) ([]string, error)
+	// GetModifiedFiles returns the paths of the files pull modifies, relative
+	// to the repo root. They're checked against the repo's code owners.
+	GetModifiedFiles(baseRepo models.Repo, pull models.PullRequest) ([]string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_team_membership_checker.go TeamMembershipChecker
+
+// TeamMembershipChecker checks if users are members of the teams that are
+// code owners.
+type TeamMembershipChecker interface {
+	// IsTeamMember returns true if user is an active member of team, of the
+	// form org/team.
+	IsTeamMember(baseRepo models.Repo, team string, user string) (bool, error)
 }
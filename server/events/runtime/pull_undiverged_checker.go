@@ -0,0 +1,11 @@
+package runtime
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_pull_undiverged_checker.go PullUndivergedChecker
+
+type PullUndivergedChecker interface {
+	PullIsUndiverged(baseRepo models.Repo, pull models.PullRequest) (bool, error)
+}
@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// remoteBackendTypes are the backends that run plans and applies remotely in
+// Terraform Cloud or Terraform Enterprise.
+var remoteBackendTypes = map[string]bool{
+	"remote": true,
+	"cloud":  true,
+}
+
+// remotePlanStartLines are the lines that start the plan in the output of
+// remote operations. Newer versions of Terraform don't print refreshSeparator
+// so we look for these instead.
+var remotePlanStartLines = []string{
+	"An execution plan has been generated and is shown below.",
+	"Terraform used the selected providers to generate the following execution",
+	"No changes.",
+}
+
+// remotePlanEndLine is printed by remote applies right after the plan.
+const remotePlanEndLine = "Do you want to perform these actions"
+
+// backendState is the part of the .terraform/terraform.tfstate file that
+// terraform init writes that we need.
+type backendState struct {
+	Backend *struct {
+		Type string `json:"type"`
+	} `json:"backend"`
+}
+
+// UsesRemoteBackend returns true if the project at path was initialized with
+// a backend that runs operations in Terraform Cloud or Terraform Enterprise,
+// ex. the remote backend or a cloud block. It respects TF_DATA_DIR if it's set
+// in envs or the server's environment.
+func UsesRemoteBackend(path string, envs map[string]string) bool {
+	dataDir, ok := envs["TF_DATA_DIR"]
+	if !ok {
+		dataDir = os.Getenv("TF_DATA_DIR")
+	}
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(path, dataDir)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dataDir, "terraform.tfstate")) // nolint: gosec
+	if err != nil {
+		return false
+	}
+	var state backendState
+	if err := json.Unmarshal(contents, &state); err != nil || state.Backend == nil {
+		return false
+	}
+	return remoteBackendTypes[state.Backend.Type]
+}
+
+// remotePlanOutput returns the plan in the output of a remote operation
+// without what's printed before it, like the link to the run and the refresh.
+func remotePlanOutput(output string) string {
+	if idx := strings.Index(output, refreshSeparator); idx > -1 {
+		return output[idx+len(refreshSeparator):]
+	}
+	start := -1
+	for _, line := range remotePlanStartLines {
+		if idx := strings.Index(output, line); idx > -1 && (start < 0 || idx < start) {
+			start = idx
+		}
+	}
+	if start < 0 {
+		return output
+	}
+	return output[start:]
+}
+
+// withRunURL appends the link to the remote run to output so it's shown in
+// the pull request comment.
+func withRunURL(output string, runURL string) string {
+	if runURL == "" {
+		return output
+	}
+	return fmt.Sprintf("%s\n\nTerraform Cloud run: %s", output, runURL)
+}
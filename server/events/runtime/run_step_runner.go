@@ -8,6 +8,8 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/execution"
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -17,6 +19,8 @@ type RunStepRunner struct {
 	DefaultTFVersion  *version.Version
 	// TerraformBinDir is the directory where Atlantis downloads Terraform binaries.
 	TerraformBinDir string
+	// ExecutionBackend runs the commands. If nil, they're run on the server.
+	ExecutionBackend execution.Backend
 }
 
 func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command string, path string, envs map[string]string) (string, error) {
@@ -25,7 +29,7 @@ func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command string, pa
 		tfVersion = ctx.TerraformVersion
 	}
 
-	err := r.TerraformExecutor.EnsureVersion(ctx.Log, tfVersion)
+	err := tfExecFor(r.TerraformExecutor, ctx).EnsureVersion(ctx.Log, tfVersion)
 	if err != nil {
 		err = fmt.Errorf("%s: Downloading terraform Version %s", err, tfVersion.String())
 		ctx.Log.Debug("error: %s", err)
@@ -63,8 +67,15 @@ func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command string, pa
 		finalEnvVars = append(finalEnvVars, fmt.Sprintf("%s=%s", key, val))
 	}
 	cmd.Env = finalEnvVars
-	out, err := cmd.CombinedOutput()
+	out, err := execution.CombinedOutput(r.ExecutionBackend, ctx.Log, cmd)
 
+	if _, ok := err.(*execution.TimeoutError); ok || err == execution.ErrCancelled {
+		// The error is kept so timeouts and cancellations can be told apart
+		// from failures.
+		err = errors.Wrapf(err, "running %q in %q: \n%s", command, path, out)
+		ctx.Log.Debug("error: %s", err)
+		return "", err
+	}
 	if err != nil {
 		err = fmt.Errorf("%s: running %q in %q: \n%s", err, command, path, out)
 		ctx.Log.Debug("error: %s", err)
@@ -8,6 +8,8 @@ import (
 
 	version "github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
+	executionmocks "github.com/runatlantis/atlantis/server/events/execution/mocks"
+	executionmatchers "github.com/runatlantis/atlantis/server/events/execution/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
@@ -155,3 +157,32 @@ func TestRunStepRunner_Run(t *testing.T) {
 		})
 	}
 }
+
+// Test that commands are run with the execution backend.
+func TestRunStepRunner_RunExecutionBackend(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.EnsureVersion(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyPtrToGoVersionVersion())).
+		ThenReturn(nil)
+	backend := executionmocks.NewMockBackend()
+	When(backend.CombinedOutput(matchers.AnyPtrToLoggingSimpleLogger(), executionmatchers.AnyPtrToExecCmd())).
+		ThenReturn([]byte("output"), nil)
+	defaultVersion, _ := version.NewVersion("0.8")
+
+	r := runtime.RunStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  defaultVersion,
+		TerraformBinDir:   "/bin/dir",
+		ExecutionBackend:  backend,
+	}
+	ctx := models.ProjectCommandContext{
+		Log:       logging.NewNoopLogger(),
+		Workspace: "default",
+	}
+	out, err := r.Run(ctx, "echo hi", "/path", nil)
+	Ok(t, err)
+	Equals(t, "output", out)
+	_, cmd := backend.VerifyWasCalledOnce().CombinedOutput(matchers.AnyPtrToLoggingSimpleLogger(), executionmatchers.AnyPtrToExecCmd()).GetCapturedArguments()
+	Equals(t, []string{"sh", "-c", "echo hi"}, cmd.Args)
+	Equals(t, "/path", cmd.Dir)
+}
@@ -42,6 +42,25 @@ type AsyncTFExec interface {
 	RunCommandAsync(log *logging.SimpleLogger, path string, args []string, envs map[string]string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line)
 }
 
+// tfExecFor returns the executor that runs ctx's distribution of Terraform,
+// ex. opentofu for repos that set terraform_distribution in the server-side
+// repo config. Executors other than the terraform client, ex. mocks, are
+// returned as is.
+func tfExecFor(exec TerraformExec, ctx models.ProjectCommandContext) TerraformExec {
+	if c, ok := exec.(*terraform.DefaultClient); ok {
+		return c.ForDistribution(ctx.TerraformDistribution)
+	}
+	return exec
+}
+
+// asyncTFExecFor is tfExecFor for AsyncTFExec.
+func asyncTFExecFor(exec AsyncTFExec, ctx models.ProjectCommandContext) AsyncTFExec {
+	if c, ok := exec.(*terraform.DefaultClient); ok {
+		return c.ForDistribution(ctx.TerraformDistribution)
+	}
+	return exec
+}
+
 // StatusUpdater brings the interface from CommitStatusUpdater into this package
 // without causing circular imports.
 type StatusUpdater interface {
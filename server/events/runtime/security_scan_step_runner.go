@@ -0,0 +1,331 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// SecurityFinding is a single issue found by a security scanner.
+type SecurityFinding = models.SecurityFinding
+
+// SecurityScanStepRunner runs security_scan steps. They scan the project with
+// tfsec or Checkov and list the findings in the plan's output. If the repo's
+// config sets a block_severity, findings of that severity or higher mark the
+// plan so it can't be applied.
+type SecurityScanStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+	// TfsecPath and CheckovPath are the paths to the scanners' binaries. If
+	// they're empty, the scanners are looked up in the PATH.
+	TfsecPath   string
+	CheckovPath string
+	// TempDir, if set, is where plans' JSON is written for Checkov instead
+	// of the system's temp dir, ex. so it's only written to a tmpfs mount.
+	TempDir string
+}
+
+// SecurityScanMarkerPath returns the path of the file that marks the project's
+// plan as blocked by security findings. Its contents are why. It's next to the
+// planfile so it's deleted with the clone.
+func SecurityScanMarkerPath(ctx models.ProjectCommandContext, path string) string {
+	return filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName)+".security")
+}
+
+// SecurityFindingsPath returns the path of the file the findings of the
+// project's last security scan are saved to as JSON so they're added to the
+// plan's result. It's next to the planfile so it's deleted with the clone.
+func SecurityFindingsPath(ctx models.ProjectCommandContext, path string) string {
+	return filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName)+".findings.json")
+}
+
+// ReadSecurityFindings returns the findings saved by the project's last
+// security scan. It returns nil if the project wasn't scanned.
+func ReadSecurityFindings(ctx models.ProjectCommandContext, path string) ([]SecurityFinding, error) {
+	contents, err := ioutil.ReadFile(SecurityFindingsPath(ctx, path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading security findings")
+	}
+	var findings []SecurityFinding
+	if err := json.Unmarshal(contents, &findings); err != nil {
+		return nil, errors.Wrap(err, "parsing security findings")
+	}
+	return findings, nil
+}
+
+// Run scans the project at path and returns its findings.
+func (s *SecurityScanStepRunner) Run(ctx models.ProjectCommandContext, path string) (string, error) {
+	cfg := valid.DefaultSecurityScan
+	if ctx.SecurityScan != nil {
+		cfg = *ctx.SecurityScan
+	}
+
+	var findings []SecurityFinding
+	var err error
+	switch {
+	case cfg.Tool == valid.CheckovSecurityScanTool && cfg.Target == valid.PlanSecurityScanTarget:
+		findings, err = s.checkovPlan(ctx, path)
+	case cfg.Tool == valid.CheckovSecurityScanTool:
+		findings, err = s.checkov(path, "-d", ".")
+	default:
+		findings, err = s.tfsec(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return valid.SeverityRank(findings[i].Severity) > valid.SeverityRank(findings[j].Severity)
+	})
+
+	var blocking int
+	for _, f := range findings {
+		if cfg.Blocks(f.Severity) {
+			blocking++
+		}
+	}
+	marker := SecurityScanMarkerPath(ctx, path)
+	if blocking > 0 {
+		failure := fmt.Sprintf("The %s security scan found %d issue(s) with severity %s or higher. Fix them and plan again to apply.", cfg.Tool, blocking, cfg.BlockSeverity)
+		if err := ioutil.WriteFile(marker, []byte(failure), 0600); err != nil {
+			return "", errors.Wrap(err, "marking plan as blocked by security findings")
+		}
+	} else if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "removing security findings mark")
+	}
+	saved, err := json.Marshal(findings)
+	if err == nil {
+		err = ioutil.WriteFile(SecurityFindingsPath(ctx, path), saved, 0600)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "saving security findings")
+	}
+	return RenderSecurityFindings(cfg, findings, blocking), nil
+}
+
+// RenderSecurityFindings describes findings, blocking of which block apply,
+// for the plan's output.
+func RenderSecurityFindings(cfg valid.SecurityScan, findings []SecurityFinding, blocking int) string {
+	if len(findings) == 0 {
+		return fmt.Sprintf("Security scan (%s): no issues found.", cfg.Tool)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Security scan (%s): %d issue(s) found", cfg.Tool, len(findings))
+	if blocking > 0 {
+		fmt.Fprintf(&buf, ", %d with severity %s or higher block apply", blocking, cfg.BlockSeverity)
+	}
+	buf.WriteString(".\n")
+	for _, f := range findings {
+		severity := strings.ToUpper(f.Severity)
+		if severity == "" {
+			severity = "UNKNOWN"
+		}
+		fmt.Fprintf(&buf, "\n[%s] %s: %s", severity, f.RuleID, f.Description)
+		switch {
+		case f.File != "" && f.Line > 0:
+			fmt.Fprintf(&buf, " (%s:%d)", f.File, f.Line)
+		case f.File != "":
+			fmt.Fprintf(&buf, " (%s)", f.File)
+		case f.Resource != "":
+			fmt.Fprintf(&buf, " (%s)", f.Resource)
+		}
+	}
+	return buf.String()
+}
+
+// tfsecOutput is the subset of tfsec's JSON output that we use.
+type tfsecOutput struct {
+	Results []struct {
+		RuleID          string `json:"rule_id"`
+		LongID          string `json:"long_id"`
+		RuleDescription string `json:"rule_description"`
+		Description     string `json:"description"`
+		Severity        string `json:"severity"`
+		Resource        string `json:"resource"`
+		Location        struct {
+			Filename  string `json:"filename"`
+			StartLine int    `json:"start_line"`
+		} `json:"location"`
+	} `json:"results"`
+}
+
+// tfsec scans the project's Terraform files with tfsec.
+func (s *SecurityScanStepRunner) tfsec(path string) ([]SecurityFinding, error) {
+	out, err := runScanner(s.TfsecPath, valid.TfsecSecurityScanTool, path, ".", "--format", "json", "--no-color", "--soft-fail")
+	if err != nil {
+		return nil, err
+	}
+	return ParseTfsecOutput(out, path)
+}
+
+// ParseTfsecOutput parses tfsec's JSON output of a scan of the project at
+// path.
+func ParseTfsecOutput(out []byte, path string) ([]SecurityFinding, error) {
+	var parsed tfsecOutput
+	if err := json.Unmarshal(jsonStart(out), &parsed); err != nil {
+		return nil, errors.Wrap(err, "parsing tfsec output")
+	}
+	var findings []SecurityFinding
+	for _, r := range parsed.Results {
+		id := r.LongID
+		if id == "" {
+			id = r.RuleID
+		}
+		description := r.Description
+		if description == "" {
+			description = r.RuleDescription
+		}
+		findings = append(findings, SecurityFinding{
+			RuleID:      id,
+			Severity:    r.Severity,
+			Description: description,
+			File:        relToProject(path, r.Location.Filename),
+			Line:        r.Location.StartLine,
+			Resource:    r.Resource,
+		})
+	}
+	return findings, nil
+}
+
+// checkovReport is the subset of a Checkov report in its JSON output that we
+// use. Checkov outputs a list of reports if it ran more than one framework.
+type checkovReport struct {
+	Results struct {
+		FailedChecks []struct {
+			CheckID       string `json:"check_id"`
+			CheckName     string `json:"check_name"`
+			Severity      string `json:"severity"`
+			FilePath      string `json:"file_path"`
+			FileLineRange []int  `json:"file_line_range"`
+			Resource      string `json:"resource"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+// checkov scans the project with Checkov with args selecting what to scan.
+func (s *SecurityScanStepRunner) checkov(path string, args ...string) ([]SecurityFinding, error) {
+	args = append(args, "-o", "json", "--quiet", "--soft-fail", "--compact")
+	out, err := runScanner(s.CheckovPath, valid.CheckovSecurityScanTool, path, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCheckovOutput(out)
+}
+
+// checkovPlan scans the project's plan as JSON with Checkov.
+func (s *SecurityScanStepRunner) checkovPlan(ctx models.ProjectCommandContext, path string) ([]SecurityFinding, error) {
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	contents, err := ioutil.ReadFile(planFile) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil, errors.New("no planfile found, security_scan steps that scan the plan must run after a plan step")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading planfile")
+	}
+	if bytes.HasPrefix(contents, []byte(remoteOpsHeader)) {
+		return nil, errors.New("can't scan a plan created by remote ops")
+	}
+	tfVersion := s.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	out, err := tfExecFor(s.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, filepath.Clean(path), []string{"show", "-json", planFile}, nil, tfVersion, ctx.Workspace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "running terraform show: %s", out)
+	}
+
+	tmp, err := ioutil.TempDir(s.TempDir, "atlantis-security-scan")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating temp dir")
+	}
+	defer os.RemoveAll(tmp) // nolint: errcheck
+	planJSON := filepath.Join(tmp, "tfplan.json")
+	if err := ioutil.WriteFile(planJSON, jsonStart([]byte(out)), 0600); err != nil {
+		return nil, errors.Wrap(err, "writing plan JSON")
+	}
+	return s.checkov(path, "-f", planJSON, "--framework", "terraform_plan")
+}
+
+// ParseCheckovOutput parses Checkov's JSON output.
+func ParseCheckovOutput(out []byte) ([]SecurityFinding, error) {
+	out = jsonStart(out)
+	var reports []checkovReport
+	if bytes.HasPrefix(out, []byte("[")) {
+		if err := json.Unmarshal(out, &reports); err != nil {
+			return nil, errors.Wrap(err, "parsing checkov output")
+		}
+	} else {
+		var report checkovReport
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, errors.Wrap(err, "parsing checkov output")
+		}
+		reports = append(reports, report)
+	}
+	var findings []SecurityFinding
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			f := SecurityFinding{
+				RuleID:      c.CheckID,
+				Severity:    c.Severity,
+				Description: c.CheckName,
+				Resource:    c.Resource,
+			}
+			// Checks of the plan have the plan JSON as their file.
+			if !strings.HasSuffix(c.FilePath, ".json") {
+				f.File = strings.TrimPrefix(c.FilePath, "/")
+				if len(c.FileLineRange) > 0 {
+					f.Line = c.FileLineRange[0]
+				}
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// runScanner runs the scanner called name, at binPath if it's set, in path and
+// returns its stdout.
+func runScanner(binPath string, name string, path string, args ...string) ([]byte, error) {
+	if binPath == "" {
+		binPath = name
+	}
+	cmd := exec.Command(binPath, args...) // nolint: gosec
+	cmd.Dir = path
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "running %s: %s", name, stderr.String())
+	}
+	return out, nil
+}
+
+// jsonStart strips anything, ex. warnings, before the JSON in out.
+func jsonStart(out []byte) []byte {
+	if i := bytes.IndexAny(out, "[{"); i > 0 {
+		return out[i:]
+	}
+	return out
+}
+
+// relToProject returns file, which tfsec reports as an absolute path, relative
+// to the project at path.
+func relToProject(path string, file string) string {
+	if rel, err := filepath.Rel(path, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
@@ -0,0 +1,213 @@
+package runtime_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const tfsecOutput = `{
+  "results": [
+    {
+      "rule_id": "AWS002",
+      "long_id": "aws-s3-enable-bucket-logging",
+      "description": "Resource 'aws_s3_bucket.logs' does not have logging enabled.",
+      "severity": "MEDIUM",
+      "resource": "aws_s3_bucket.logs",
+      "location": {"filename": "%s/main.tf", "start_line": 1}
+    },
+    {
+      "rule_id": "AWS017",
+      "long_id": "aws-s3-enable-bucket-encryption",
+      "rule_description": "Unencrypted S3 bucket.",
+      "severity": "HIGH",
+      "resource": "aws_s3_bucket.logs",
+      "location": {"filename": "%s/main.tf", "start_line": 3}
+    }
+  ]
+}`
+
+const checkovOutput = `[
+  {
+    "check_type": "terraform",
+    "results": {
+      "failed_checks": [
+        {
+          "check_id": "CKV_AWS_18",
+          "check_name": "Ensure the S3 bucket has access logging enabled",
+          "severity": null,
+          "file_path": "/modules/s3/main.tf",
+          "file_line_range": [4, 12],
+          "resource": "aws_s3_bucket.logs"
+        }
+      ]
+    }
+  },
+  {
+    "check_type": "terraform_plan",
+    "results": {
+      "failed_checks": [
+        {
+          "check_id": "CKV_AWS_19",
+          "check_name": "Ensure all data stored in the S3 bucket is securely encrypted at rest",
+          "severity": "CRITICAL",
+          "file_path": "/tfplan.json",
+          "file_line_range": [0, 0],
+          "resource": "aws_s3_bucket.logs"
+        }
+      ]
+    }
+  }
+]`
+
+func TestParseTfsecOutput(t *testing.T) {
+	findings, err := runtime.ParseTfsecOutput([]byte("WARNING: tfsec is deprecated\n"+sprintfTwice(tfsecOutput, "/repo/dir")), "/repo/dir")
+	Ok(t, err)
+	Equals(t, []runtime.SecurityFinding{
+		{
+			RuleID:      "aws-s3-enable-bucket-logging",
+			Severity:    "MEDIUM",
+			Description: "Resource 'aws_s3_bucket.logs' does not have logging enabled.",
+			File:        "main.tf",
+			Line:        1,
+			Resource:    "aws_s3_bucket.logs",
+		},
+		{
+			RuleID:      "aws-s3-enable-bucket-encryption",
+			Severity:    "HIGH",
+			Description: "Unencrypted S3 bucket.",
+			File:        "main.tf",
+			Line:        3,
+			Resource:    "aws_s3_bucket.logs",
+		},
+	}, findings)
+}
+
+func TestParseCheckovOutput(t *testing.T) {
+	findings, err := runtime.ParseCheckovOutput([]byte(checkovOutput))
+	Ok(t, err)
+	Equals(t, []runtime.SecurityFinding{
+		{
+			RuleID:      "CKV_AWS_18",
+			Description: "Ensure the S3 bucket has access logging enabled",
+			File:        "modules/s3/main.tf",
+			Line:        4,
+			Resource:    "aws_s3_bucket.logs",
+		},
+		{
+			RuleID:      "CKV_AWS_19",
+			Severity:    "CRITICAL",
+			Description: "Ensure all data stored in the S3 bucket is securely encrypted at rest",
+			Resource:    "aws_s3_bucket.logs",
+		},
+	}, findings)
+
+	// Checkov outputs a single report if it ran one framework.
+	findings, err = runtime.ParseCheckovOutput([]byte(`{"check_type": "terraform", "results": {"failed_checks": []}}`))
+	Ok(t, err)
+	Equals(t, 0, len(findings))
+}
+
+func TestSecurityScanStepRunner_Run(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	tfsec := filepath.Join(tmp, "tfsec")
+	Ok(t, ioutil.WriteFile(tfsec, []byte("#!/bin/sh\ncat <<EOF\n"+sprintfTwice(tfsecOutput, tmp)+"\nEOF\n"), 0700)) // nolint: gosec
+	r := &runtime.SecurityScanStepRunner{TfsecPath: tfsec}
+	ctx := uploadArtifactCtx()
+	marker := runtime.SecurityScanMarkerPath(ctx, tmp)
+
+	// Without a block_severity, findings are only listed.
+	out, err := r.Run(ctx, tmp)
+	Ok(t, err)
+	Equals(t, `Security scan (tfsec): 2 issue(s) found.
+
+[HIGH] aws-s3-enable-bucket-encryption: Unencrypted S3 bucket. (main.tf:3)
+[MEDIUM] aws-s3-enable-bucket-logging: Resource 'aws_s3_bucket.logs' does not have logging enabled. (main.tf:1)`, out)
+	_, err = os.Stat(marker)
+	Assert(t, os.IsNotExist(err), "exp no marker, got %v", err)
+	findings, err := runtime.ReadSecurityFindings(ctx, tmp)
+	Ok(t, err)
+	Equals(t, 2, len(findings))
+	Equals(t, runtime.SecurityFinding{RuleID: "aws-s3-enable-bucket-encryption", Severity: "HIGH", Description: "Unencrypted S3 bucket.", File: "main.tf", Line: 3, Resource: "aws_s3_bucket.logs"}, findings[0])
+
+	ctx.SecurityScan = &valid.SecurityScan{Tool: "tfsec", Target: "dir", BlockSeverity: "HIGH"}
+	out, err = r.Run(ctx, tmp)
+	Ok(t, err)
+	Equals(t, `Security scan (tfsec): 2 issue(s) found, 1 with severity HIGH or higher block apply.
+
+[HIGH] aws-s3-enable-bucket-encryption: Unencrypted S3 bucket. (main.tf:3)
+[MEDIUM] aws-s3-enable-bucket-logging: Resource 'aws_s3_bucket.logs' does not have logging enabled. (main.tf:1)`, out)
+	contents, err := ioutil.ReadFile(marker) // nolint: gosec
+	Ok(t, err)
+	Equals(t, "The tfsec security scan found 1 issue(s) with severity HIGH or higher. Fix them and plan again to apply.", string(contents))
+
+	// Planning again without blocking findings unblocks apply.
+	ctx.SecurityScan.BlockSeverity = "CRITICAL"
+	_, err = r.Run(ctx, tmp)
+	Ok(t, err)
+	_, err = os.Stat(marker)
+	Assert(t, os.IsNotExist(err), "exp marker to be removed, got %v", err)
+}
+
+func TestSecurityScanStepRunner_RunCheckovPlan(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "staging.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("planfile"), 0600))
+	// The fake checkov fails unless it's given the plan as JSON.
+	checkov := filepath.Join(tmp, "checkov")
+	Ok(t, ioutil.WriteFile(checkov, []byte(`#!/bin/sh
+grep -q format_version "$2" || exit 1
+echo '{"results": {"failed_checks": [{"check_id": "CKV_AWS_19", "check_name": "Unencrypted", "severity": "CRITICAL", "file_path": "/tfplan.json", "resource": "aws_s3_bucket.logs"}]}}'
+`), 0700)) // nolint: gosec
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn(`{"format_version": "0.1"}`, nil)
+	r := &runtime.SecurityScanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+		CheckovPath:       checkov,
+	}
+	ctx := uploadArtifactCtx()
+	ctx.SecurityScan = &valid.SecurityScan{Tool: "checkov", Target: "plan", BlockSeverity: "CRITICAL"}
+
+	out, err := r.Run(ctx, tmp)
+	Ok(t, err)
+	Equals(t, `Security scan (checkov): 1 issue(s) found, 1 with severity CRITICAL or higher block apply.
+
+[CRITICAL] CKV_AWS_19: Unencrypted (aws_s3_bucket.logs)`, out)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx.Log, tmp, []string{"show", "-json", planPath}, nil, tfVersion, "staging")
+	_, err = os.Stat(runtime.SecurityScanMarkerPath(ctx, tmp))
+	Ok(t, err)
+}
+
+func TestSecurityScanStepRunner_RunCheckovPlanNoPlanfile(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	r := &runtime.SecurityScanStepRunner{}
+	ctx := uploadArtifactCtx()
+	ctx.SecurityScan = &valid.SecurityScan{Tool: "checkov", Target: "plan"}
+
+	_, err := r.Run(ctx, tmp)
+	ErrEquals(t, "no planfile found, security_scan steps that scan the plan must run after a plan step", err)
+}
+
+// sprintfTwice fills both of format's %s with s.
+func sprintfTwice(format string, s string) string {
+	return fmt.Sprintf(format, s, s)
+}
@@ -0,0 +1,151 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// argRule describes a flag that isn't supported by some versions of
+// Terraform and what to do when it's used with one of them.
+type argRule struct {
+	// Subcommands are the terraform subcommands the flag applies to.
+	Subcommands []string
+	// Flag is the flag, ex. "-get-plugins".
+	Flag string
+	// TakesValue is true if the flag's value can be passed as the next
+	// argument, ex. "-replace addr", instead of only via "=".
+	TakesValue bool
+	// Unsupported are the versions that don't support the flag.
+	Unsupported version.Constraints
+	// Drop is true if the flag can be safely removed when it's unsupported.
+	// Otherwise running the command is an error since dropping the flag would
+	// change what the command does.
+	Drop bool
+	// Reason explains why the flag isn't supported.
+	Reason string
+}
+
+// argRules are the flags that differ between the Terraform versions we
+// support. Workflows can use these flags and projects pinned to versions that
+// don't support them will still work, as long as the flag can be dropped.
+var argRules = []argRule{
+	{
+		Subcommands: []string{"init"},
+		Flag:        "-get-plugins",
+		Unsupported: MustConstraint(">= 0.15.0"),
+		Drop:        true,
+		Reason:      "removed in terraform 0.15, plugins are always installed",
+	},
+	{
+		Subcommands: []string{"init"},
+		Flag:        "-verify-plugins",
+		Unsupported: MustConstraint(">= 0.15.0"),
+		Drop:        true,
+		Reason:      "removed in terraform 0.15, plugins are always verified",
+	},
+	{
+		Subcommands: []string{"plan", "apply"},
+		Flag:        "-json",
+		Unsupported: MustConstraint("< 0.15.3"),
+		Drop:        true,
+		Reason:      "machine readable output requires terraform 0.15.3 or later",
+	},
+	{
+		Subcommands: []string{"plan", "apply"},
+		Flag:        "-replace",
+		TakesValue:  true,
+		Unsupported: MustConstraint("< 0.15.2"),
+		Reason:      "requires terraform 0.15.2 or later, use `terraform taint` instead",
+	},
+	{
+		Subcommands: []string{"plan", "apply"},
+		Flag:        "-refresh-only",
+		Unsupported: MustConstraint("< 0.15.4"),
+		Reason:      "requires terraform 0.15.4 or later, use `terraform refresh` instead",
+	},
+}
+
+// AdaptArgs returns args, the arguments to the terraform subcommand,
+// adapted to run with tfVersion. Flags the version doesn't support are
+// dropped if that's safe, otherwise an error is returned. This lets one
+// workflow be used by projects pinned to different versions.
+func AdaptArgs(log *logging.SimpleLogger, tfVersion *version.Version, subcommand string, args []string) ([]string, error) {
+	if tfVersion == nil {
+		return args, nil
+	}
+	var adapted []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		rule := findArgRule(subcommand, arg)
+		if rule == nil || !rule.Unsupported.Check(tfVersion) {
+			adapted = append(adapted, arg)
+			continue
+		}
+		if !rule.Drop {
+			return nil, fmt.Errorf("terraform %s %s is not supported by terraform %s: %s", subcommand, rule.Flag, tfVersion, rule.Reason)
+		}
+		log.Warn("dropping %s from terraform %s since it's not supported by terraform %s: %s", rule.Flag, subcommand, tfVersion, rule.Reason)
+		// Also drop the flag's value if it was passed as the next argument.
+		if rule.TakesValue && arg == rule.Flag && i+1 < len(args) {
+			i++
+		}
+	}
+	// Since we only ever drop args, if nothing was dropped return args as is.
+	if len(adapted) == len(args) {
+		return args, nil
+	}
+	return adapted, nil
+}
+
+// findArgRule returns the rule for arg when used with subcommand or nil if
+// there isn't one.
+func findArgRule(subcommand string, arg string) *argRule {
+	flag := strings.SplitN(arg, "=", 2)[0]
+	// Terraform accepts flags with one or two dashes.
+	if strings.HasPrefix(flag, "--") {
+		flag = flag[1:]
+	}
+	for i := range argRules {
+		if argRules[i].Flag != flag {
+			continue
+		}
+		for _, s := range argRules[i].Subcommands {
+			if s == subcommand {
+				return &argRules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// InitArgs returns the command used to initialize a project with tfVersion.
+func InitArgs(tfVersion *version.Version) []string {
+	// Before 0.9 there was no `init` for modules so we use `get`.
+	if MustConstraint("< 0.9.0").Check(tfVersion) {
+		return []string{"get", "-no-color", "-upgrade"}
+	}
+	return []string{"init", "-input=false", "-no-color", "-upgrade"}
+}
+
+// WorkspaceSubcommand returns the subcommand used to manage workspaces in
+// tfVersion. It returns an empty string if tfVersion doesn't support
+// workspaces.
+func WorkspaceSubcommand(tfVersion *version.Version) string {
+	switch {
+	case MustConstraint("< 0.9").Check(tfVersion):
+		return ""
+	case MustConstraint(">= 0.9, < 0.10").Check(tfVersion):
+		// In version 0.9.* the workspace command was called env.
+		return "env"
+	default:
+		return "workspace"
+	}
+}
+
+// SupportsShowJSON returns true if tfVersion supports `terraform show -json`.
+func SupportsShowJSON(tfVersion *version.Version) bool {
+	return MustConstraint(">= 0.12.0").Check(tfVersion)
+}
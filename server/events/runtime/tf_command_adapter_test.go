@@ -0,0 +1,128 @@
+package runtime_test
+
+import (
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAdaptArgs(t *testing.T) {
+	cases := []struct {
+		description string
+		version     string
+		subcommand  string
+		args        []string
+		exp         []string
+		expErr      string
+	}{
+		{
+			"no rules apply",
+			"0.13.5",
+			"plan",
+			[]string{"-lock-timeout=5m", "-var", "a=b"},
+			[]string{"-lock-timeout=5m", "-var", "a=b"},
+			"",
+		},
+		{
+			"removed flag is dropped on new versions",
+			"1.0.0",
+			"init",
+			[]string{"-get-plugins=false", "-verify-plugins", "false", "-backend=false"},
+			// -verify-plugins doesn't take a value as the next arg so
+			// "false" is left as is.
+			[]string{"false", "-backend=false"},
+			"",
+		},
+		{
+			"removed flag is kept on old versions",
+			"0.14.11",
+			"init",
+			[]string{"-get-plugins=false"},
+			[]string{"-get-plugins=false"},
+			"",
+		},
+		{
+			"flag for another subcommand is kept",
+			"1.0.0",
+			"plan",
+			[]string{"-get-plugins=false"},
+			[]string{"-get-plugins=false"},
+			"",
+		},
+		{
+			"new flag is dropped on old versions",
+			"0.13.5",
+			"plan",
+			[]string{"-json", "-lock=false"},
+			[]string{"-lock=false"},
+			"",
+		},
+		{
+			"new flag with two dashes is dropped on old versions",
+			"0.13.5",
+			"apply",
+			[]string{"--json"},
+			nil,
+			"",
+		},
+		{
+			"new flag is kept on new versions",
+			"0.15.3",
+			"plan",
+			[]string{"-json"},
+			[]string{"-json"},
+			"",
+		},
+		{
+			"new flag that changes the plan errors on old versions",
+			"0.14.0",
+			"plan",
+			[]string{"-replace", "aws_instance.a"},
+			nil,
+			"terraform plan -replace is not supported by terraform 0.14.0: requires terraform 0.15.2 or later, use `terraform taint` instead",
+		},
+		{
+			"refresh only on old versions",
+			"0.15.3",
+			"apply",
+			[]string{"-refresh-only"},
+			nil,
+			"terraform apply -refresh-only is not supported by terraform 0.15.3: requires terraform 0.15.4 or later, use `terraform refresh` instead",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			v, err := version.NewVersion(c.version)
+			Ok(t, err)
+			args, err := runtime.AdaptArgs(logging.NewNoopLogger(), v, c.subcommand, c.args)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, args)
+		})
+	}
+}
+
+func TestWorkspaceSubcommand(t *testing.T) {
+	for v, exp := range map[string]string{
+		"0.8.8":  "",
+		"0.9.11": "env",
+		"0.10.0": "workspace",
+		"0.13.5": "workspace",
+		"1.0.0":  "workspace",
+	} {
+		t.Run(v, func(t *testing.T) {
+			Equals(t, exp, runtime.WorkspaceSubcommand(version.Must(version.NewVersion(v))))
+		})
+	}
+}
+
+func TestInitArgs(t *testing.T) {
+	Equals(t, []string{"get", "-no-color", "-upgrade"}, runtime.InitArgs(version.Must(version.NewVersion("0.8.8"))))
+	Equals(t, []string{"init", "-input=false", "-no-color", "-upgrade"}, runtime.InitArgs(version.Must(version.NewVersion("0.13.5"))))
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_artifact_uploader.go ArtifactUploader
+
+// ArtifactUploader uploads artifacts to object storage.
+type ArtifactUploader interface {
+	// Upload uploads the file at path to key and returns a URL to download
+	// it.
+	Upload(key string, path string) (string, error)
+}
+
+// UploadArtifactStepRunner runs upload_artifact steps.
+type UploadArtifactStepRunner struct {
+	// Uploader is nil if artifact storage isn't configured.
+	Uploader ArtifactUploader
+}
+
+// Run uploads the file at relPath, relative to the project at path, as the
+// artifact called name. Its output links to the uploaded artifact.
+func (u *UploadArtifactStepRunner) Run(ctx models.ProjectCommandContext, name string, relPath string, path string) (string, error) {
+	if u.Uploader == nil {
+		return "", fmt.Errorf("can't upload artifact %q because artifact storage isn't configured on this Atlantis server", name)
+	}
+	artifactPath := filepath.Join(path, relPath)
+	if _, err := os.Stat(artifactPath); err != nil {
+		return "", errors.Wrapf(err, "finding artifact %q", name)
+	}
+	url, err := u.Uploader.Upload(ArtifactKey(ctx, name, relPath), artifactPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "uploading artifact %q", name)
+	}
+	return fmt.Sprintf("Uploaded artifact %q: %s", name, url), nil
+}
+
+// ArtifactKey returns the key the artifact called name, from the file at
+// relPath, is uploaded to. Keys include the commit so the artifacts of
+// earlier commits aren't overwritten and the project's stable ID so they can
+// be found after the project is renamed, ex.
+// owner/repo/1/abc123/445faa5827ef29e9/plan-json/plan.json.
+func ArtifactKey(ctx models.ProjectCommandContext, name string, relPath string) string {
+	return path.Join(
+		ctx.BaseRepo.FullName,
+		strconv.Itoa(ctx.Pull.Num),
+		ctx.Pull.HeadCommit,
+		ctx.ProjectID(),
+		name,
+		filepath.Base(relPath),
+	)
+}
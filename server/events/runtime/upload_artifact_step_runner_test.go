@@ -0,0 +1,77 @@
+package runtime_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/runtime/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func uploadArtifactCtx() models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		Pull:       models.PullRequest{Num: 2, HeadCommit: "abc123"},
+		RepoRelDir: "dir/sub",
+		Workspace:  "staging",
+	}
+}
+
+func TestUploadArtifactStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	artifactPath := filepath.Join(tmp, "out", "plan.json")
+	Ok(t, os.Mkdir(filepath.Join(tmp, "out"), 0700))
+	Ok(t, ioutil.WriteFile(artifactPath, []byte("{}"), 0600))
+
+	uploader := mocks.NewMockArtifactUploader()
+	When(uploader.Upload("owner/repo/2/abc123/dfc912cc838b5615/plan-json/plan.json", artifactPath)).
+		ThenReturn("https://bucket.s3.amazonaws.com/signed", nil)
+	r := &runtime.UploadArtifactStepRunner{Uploader: uploader}
+
+	out, err := r.Run(uploadArtifactCtx(), "plan-json", "out/plan.json", tmp)
+	Ok(t, err)
+	Equals(t, `Uploaded artifact "plan-json": https://bucket.s3.amazonaws.com/signed`, out)
+}
+
+func TestUploadArtifactStepRunner_RunMissingFile(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	uploader := mocks.NewMockArtifactUploader()
+	r := &runtime.UploadArtifactStepRunner{Uploader: uploader}
+
+	_, err := r.Run(uploadArtifactCtx(), "plan-json", "plan.json", tmp)
+	Assert(t, err != nil && strings.HasPrefix(err.Error(), `finding artifact "plan-json"`), "expected missing file error, got %v", err)
+	uploader.VerifyWasCalled(Never()).Upload(AnyString(), AnyString())
+}
+
+func TestUploadArtifactStepRunner_RunUploadErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "plan.json"), nil, 0600))
+	uploader := mocks.NewMockArtifactUploader()
+	When(uploader.Upload(AnyString(), AnyString())).ThenReturn("", errors.New("access denied"))
+	r := &runtime.UploadArtifactStepRunner{Uploader: uploader}
+
+	_, err := r.Run(uploadArtifactCtx(), "plan-json", "plan.json", tmp)
+	ErrEquals(t, `uploading artifact "plan-json": access denied`, err)
+}
+
+func TestUploadArtifactStepRunner_RunNotConfigured(t *testing.T) {
+	r := &runtime.UploadArtifactStepRunner{}
+	_, err := r.Run(uploadArtifactCtx(), "plan-json", "plan.json", "/tmp")
+	ErrEquals(t, `can't upload artifact "plan-json" because artifact storage isn't configured on this Atlantis server`, err)
+}
+
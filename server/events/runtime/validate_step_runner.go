@@ -0,0 +1,32 @@
+package runtime
+
+import (
+	"fmt"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ValidateStepRunner runs `terraform validate` so that invalid configuration
+// fails the plan before it's run. It must come after the init step.
+type ValidateStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run validates the project at path. Extra args are appended to the command.
+// If the configuration isn't valid it returns an error with terraform's
+// output.
+func (v *ValidateStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := v.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	args := append([]string{"validate", "-no-color"}, extraArgs...)
+	out, err := tfExecFor(v.TerraformExecutor, ctx).RunCommandWithVersion(ctx.Log, path, args, envs, tfVersion, ctx.Workspace)
+	// Only include the output if there was an error, like init.
+	if err != nil && out != "" {
+		return "", fmt.Errorf("terraform validate found errors in the configuration. Fix them and push the changes:\n\n%s", out)
+	}
+	return "", err
+}
@@ -0,0 +1,48 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	defaultVersion, _ := version.NewVersion("0.12.24")
+	projVersion, _ := version.NewVersion("0.13.0")
+	r := runtime.ValidateStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  defaultVersion,
+	}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("Success! The configuration is valid.\n", nil)
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default", TerraformVersion: projVersion}
+	envs := map[string]string{"TF_VAR_a": "b"}
+
+	out, err := r.Run(ctx, nil, "/path", envs)
+	Ok(t, err)
+	// The output isn't commented unless there are errors.
+	Equals(t, "", out)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx.Log, "/path", []string{"validate", "-no-color"}, envs, projVersion, "default")
+}
+
+func TestValidateStepRunner_RunInvalid(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	r := runtime.ValidateStepRunner{TerraformExecutor: terraform}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("Error: Reference to undeclared input variable\n", errors.New("exit status 1"))
+
+	_, err := r.Run(models.ProjectCommandContext{Log: logging.NewNoopLogger()}, nil, "/path", nil)
+	ErrEquals(t, "terraform validate found errors in the configuration. Fix them and push the changes:\n\nError: Reference to undeclared input variable\n", err)
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// WorkspaceLister lists the existing Terraform workspaces of projects with
+// `terraform workspace list`.
+type WorkspaceLister struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// List returns the names of the workspaces of the project at path. The
+// project is initialized first since its workspaces are stored in its
+// backend. If tfVersion is nil, the default version is used.
+func (w *WorkspaceLister) List(log *logging.SimpleLogger, path string, tfVersion *version.Version) ([]string, error) {
+	if tfVersion == nil {
+		tfVersion = w.DefaultTFVersion
+	}
+	subcommand := WorkspaceSubcommand(tfVersion)
+	if subcommand == "" {
+		return nil, fmt.Errorf("terraform version %s does not support workspaces", tfVersion)
+	}
+	if out, err := w.TerraformExecutor.RunCommandWithVersion(log, path, InitArgs(tfVersion), nil, tfVersion, defaultWorkspace); err != nil {
+		return nil, errors.Wrapf(err, "initializing: %s", out)
+	}
+	out, err := w.TerraformExecutor.RunCommandWithVersion(log, path, []string{subcommand, "list"}, nil, tfVersion, defaultWorkspace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing workspaces: %s", out)
+	}
+	return ParseWorkspaceList(out), nil
+}
+
+// ParseWorkspaceList returns the workspace names in the output of
+// `terraform workspace list`, which lists one per line and marks the selected
+// one with a *.
+func ParseWorkspaceList(out string) []string {
+	var workspaces []string
+	for _, line := range strings.Split(out, "\n") {
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if name != "" {
+			workspaces = append(workspaces, name)
+		}
+	}
+	return workspaces
+}
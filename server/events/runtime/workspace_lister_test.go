@@ -0,0 +1,70 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseWorkspaceList(t *testing.T) {
+	cases := []struct {
+		out string
+		exp []string
+	}{
+		{"", nil},
+		{"* default\n", []string{"default"}},
+		{"  default\n* staging\n  prod\n\n", []string{"default", "staging", "prod"}},
+	}
+	for _, c := range cases {
+		t.Run(c.out, func(t *testing.T) {
+			Equals(t, c.exp, runtime.ParseWorkspaceList(c.out))
+		})
+	}
+}
+
+func TestWorkspaceLister_List(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	l := runtime.WorkspaceLister{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	logger := logging.NewNoopLogger()
+	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "list"}, map[string]string(nil), tfVersion, "default")).
+		ThenReturn("* default\n  staging\n", nil)
+
+	workspaces, err := l.List(logger, "/path", nil)
+	Ok(t, err)
+	Equals(t, []string{"default", "staging"}, workspaces)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", []string{"init", "-input=false", "-no-color", "-upgrade"}, map[string]string(nil), tfVersion, "default")
+}
+
+func TestWorkspaceLister_ListInitErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.24")
+	l := runtime.WorkspaceLister{TerraformExecutor: terraform}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("backend error", errors.New("exit status 1"))
+
+	_, err := l.List(logging.NewNoopLogger(), "/path", tfVersion)
+	ErrEquals(t, "initializing: backend error: exit status 1", err)
+}
+
+func TestWorkspaceLister_ListUnsupportedVersion(t *testing.T) {
+	RegisterMockTestingT(t)
+	tfVersion, _ := version.NewVersion("0.8.8")
+	l := runtime.WorkspaceLister{TerraformExecutor: mocks.NewMockClient()}
+
+	_, err := l.List(logging.NewNoopLogger(), "/path", tfVersion)
+	ErrEquals(t, "terraform version 0.8.8 does not support workspaces", err)
+}
@@ -0,0 +1,255 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// SecretAllowComment can be added to a line to stop it from being reported by
+// the PatternSecretScanner, ex. for test fixtures.
+const SecretAllowComment = "atlantis:allow-secret"
+
+// maxScannedFileSize is the largest file we'll scan. Larger files are almost
+// always generated or binary and scanning them would slow down every plan.
+const maxScannedFileSize = 1024 * 1024
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_secret_scanner.go SecretScanner
+
+// SecretScanner scans files for credentials so they can be caught before
+// they're planned and end up in Terraform state.
+type SecretScanner interface {
+	// Scan scans files, which are relative to repoDir, and returns any
+	// secrets it finds. Files that don't exist, ex. because they were deleted
+	// by the pull request, are skipped.
+	Scan(log *logging.SimpleLogger, repoDir string, files []string) ([]SecretFinding, error)
+}
+
+// SecretFinding is a possible secret found by a SecretScanner. It never
+// contains the secret itself since it's commented back to the pull request.
+type SecretFinding struct {
+	// File is the path of the file relative to the repo root. It can be empty
+	// if the scanner doesn't report which file the secret is in.
+	File string
+	// Line is the 1-based line number of the secret or 0 if unknown.
+	Line int
+	// Description describes what was found, ex. "AWS access key ID".
+	Description string
+}
+
+// String returns the finding formatted for a pull request comment.
+func (s SecretFinding) String() string {
+	switch {
+	case s.File == "":
+		return s.Description
+	case s.Line == 0:
+		return fmt.Sprintf("`%s`: %s", s.File, s.Description)
+	default:
+		return fmt.Sprintf("`%s:%d`: %s", s.File, s.Line, s.Description)
+	}
+}
+
+// SecretRule is a pattern the PatternSecretScanner looks for.
+type SecretRule struct {
+	Description string
+	Regex       *regexp.Regexp
+}
+
+// DefaultSecretRules are the rules used by the built-in scanner. They're
+// modelled on gitleaks' default rules and favour precision over recall since
+// each match blocks planning.
+var DefaultSecretRules = []SecretRule{
+	{
+		Description: "AWS access key ID",
+		Regex:       regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	{
+		Description: "AWS secret access key",
+		Regex:       regexp.MustCompile(`(?i)aws_?secret_?(access_?)?key\s*[=:]\s*["']?[0-9a-zA-Z/+]{40}\b`),
+	},
+	{
+		Description: "private key",
+		Regex:       regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`),
+	},
+	{
+		Description: "GitHub token",
+		Regex:       regexp.MustCompile(`\bgh[pousr]_[0-9a-zA-Z]{36}\b`),
+	},
+	{
+		Description: "GitLab personal access token",
+		Regex:       regexp.MustCompile(`\bglpat-[0-9a-zA-Z_\-]{20}\b`),
+	},
+	{
+		Description: "Slack token",
+		Regex:       regexp.MustCompile(`\bxox[baprs]-[0-9a-zA-Z-]{10,72}\b`),
+	},
+	{
+		Description: "Google Cloud service account key",
+		Regex:       regexp.MustCompile(`"type"\s*:\s*"service_account"`),
+	},
+	{
+		Description: "Azure storage account key",
+		Regex:       regexp.MustCompile(`AccountKey=[0-9a-zA-Z/+]{86}==`),
+	},
+	{
+		Description: "Atlantis API token",
+		Regex:       regexp.MustCompile(`\b` + apiTokenPattern + `\b`),
+	},
+	{
+		// Matches hardcoded values for password/secret/token arguments but
+		// not interpolations like "${var.password}".
+		Description: "hardcoded password or token",
+		Regex:       regexp.MustCompile(`(?i)\b[a-z_]*(password|passwd|secret|token)\s*=\s*"[^"$\s]{8,}"`),
+	},
+}
+
+// apiTokenPattern matches tokens generated by the API tokens controller.
+const apiTokenPattern = `atl_[0-9a-f]{64}`
+
+// PatternSecretScanner is the built-in SecretScanner. It matches each line of
+// each file against Rules.
+type PatternSecretScanner struct {
+	Rules []SecretRule
+}
+
+// NewPatternSecretScanner returns a PatternSecretScanner that uses the
+// DefaultSecretRules.
+func NewPatternSecretScanner() *PatternSecretScanner {
+	return &PatternSecretScanner{Rules: DefaultSecretRules}
+}
+
+// Scan implements SecretScanner.
+func (p *PatternSecretScanner) Scan(log *logging.SimpleLogger, repoDir string, files []string) ([]SecretFinding, error) {
+	var findings []SecretFinding
+	for _, file := range files {
+		absPath := filepath.Join(repoDir, file)
+		info, err := os.Stat(absPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "scanning %q for secrets", file)
+		}
+		if info.IsDir() {
+			continue
+		}
+		if info.Size() > maxScannedFileSize {
+			log.Debug("not scanning %q for secrets since it's larger than %d bytes", file, maxScannedFileSize)
+			continue
+		}
+		contents, err := ioutil.ReadFile(absPath) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "scanning %q for secrets", file)
+		}
+		// Skip binary files.
+		if bytes.IndexByte(contents, 0) != -1 {
+			continue
+		}
+		findings = append(findings, p.scanContents(file, contents)...)
+	}
+	return findings, nil
+}
+
+func (p *PatternSecretScanner) scanContents(file string, contents []byte) []SecretFinding {
+	var findings []SecretFinding
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScannedFileSize)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.Contains(line, SecretAllowComment) {
+			continue
+		}
+		for _, rule := range p.Rules {
+			if rule.Regex.MatchString(line) {
+				findings = append(findings, SecretFinding{File: file, Line: lineNum, Description: rule.Description})
+				// Only report each line once.
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// CommandSecretScanner runs an external scanner, ex. a wrapper around
+// gitleaks. Command is run with sh -c in the repo dir with the files to scan
+// as its arguments. Exit code 0 means no secrets were found and exit code 1
+// means secrets were found, in which case each line of output is a finding.
+// Lines of the form "file:line: description" are parsed into their parts.
+// Any other exit code is an error.
+type CommandSecretScanner struct {
+	Command string
+}
+
+// commandFindingRegex matches output lines of the form "file:line: description".
+var commandFindingRegex = regexp.MustCompile(`^([^:\s][^:]*):(\d+):\s*(.+)$`)
+
+// Scan implements SecretScanner.
+func (c *CommandSecretScanner) Scan(log *logging.SimpleLogger, repoDir string, files []string) ([]SecretFinding, error) {
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(repoDir, file)); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) == 0 {
+		return nil, nil
+	}
+
+	// The first argument after the command becomes $0 so the files are
+	// available as "$@".
+	args := append([]string{"-c", c.Command, "atlantis-secret-scanner"}, existing...)
+	cmd := exec.Command("sh", args...) // #nosec
+	cmd.Dir = repoDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		log.Debug("secret scanner %q found no secrets", c.Command)
+		return nil, nil
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 1 {
+		return nil, fmt.Errorf("%s: running secret scanner %q: \n%s", err, c.Command, out)
+	}
+
+	var findings []SecretFinding
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		finding := SecretFinding{Description: line}
+		if match := commandFindingRegex.FindStringSubmatch(line); match != nil {
+			lineNum, _ := strconv.Atoi(match[2]) // nolint: errcheck
+			finding = SecretFinding{File: match[1], Line: lineNum, Description: match[3]}
+		}
+		findings = append(findings, finding)
+	}
+	// The scanner said it found secrets so make sure we don't treat no
+	// output as no findings.
+	if len(findings) == 0 {
+		findings = append(findings, SecretFinding{Description: fmt.Sprintf("secret scanner %q reported secrets but had no output", c.Command)})
+	}
+	return findings, nil
+}
+
+// RenderSecretFindings returns the plan failure for findings.
+func RenderSecretFindings(findings []SecretFinding) string {
+	var b strings.Builder
+	b.WriteString("Possible secrets were found in this pull request's modified files so plan was not run. Remove them, rotate them if they're real credentials, and then re-run plan.\n")
+	for _, f := range findings {
+		b.WriteString("\n* ")
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
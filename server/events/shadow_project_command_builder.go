@@ -0,0 +1,289 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// DefaultShadowDiffsLimit is how many comparisons ShadowDiffStore keeps by
+// default.
+const DefaultShadowDiffsLimit = 200
+
+// ShadowProjectCommandBuilder builds the project commands of plans with a
+// candidate server-side repo config alongside the active one and records how
+// they differ, so sweeping config changes can be validated on real pull
+// requests before they're rolled out. Only the commands built with the active
+// config are returned and errors from the candidate config are only recorded.
+// Apply commands are built from the active config only.
+type ShadowProjectCommandBuilder struct {
+	// ProjectCommandBuilder builds commands with the active config.
+	ProjectCommandBuilder
+	// Shadow builds commands with the candidate config.
+	Shadow ProjectCommandBuilder
+	Diffs  *ShadowDiffStore
+}
+
+// See ProjectCommandBuilder.BuildAutoplanCommands.
+func (s *ShadowProjectCommandBuilder) BuildAutoplanCommands(ctx *CommandContext) ([]models.ProjectCommandContext, error) {
+	cmds, err := s.ProjectCommandBuilder.BuildAutoplanCommands(ctx)
+	if err != nil {
+		return cmds, err
+	}
+	// The candidate's commands are built before we return so they don't hold
+	// the workspace lock while the active commands run.
+	shadowCmds, shadowErr := s.Shadow.BuildAutoplanCommands(ctx)
+	s.record(ctx, "autoplan", cmds, shadowCmds, shadowErr)
+	return cmds, nil
+}
+
+// See ProjectCommandBuilder.BuildPlanCommands.
+func (s *ShadowProjectCommandBuilder) BuildPlanCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error) {
+	cmds, err := s.ProjectCommandBuilder.BuildPlanCommands(ctx, comment)
+	if err != nil {
+		return cmds, err
+	}
+	shadowCmds, shadowErr := s.Shadow.BuildPlanCommands(ctx, comment)
+	s.record(ctx, "plan", cmds, shadowCmds, shadowErr)
+	return cmds, nil
+}
+
+// record compares the commands built with each config, logs the differences
+// and stores them.
+func (s *ShadowProjectCommandBuilder) record(ctx *CommandContext, command string, active []models.ProjectCommandContext, shadow []models.ProjectCommandContext, shadowErr error) {
+	var diff ShadowDiff
+	if shadowErr != nil {
+		diff.Error = shadowErr.Error()
+	} else {
+		diff = DiffProjectCommands(active, shadow)
+	}
+	diff.Repo = ctx.BaseRepo.FullName
+	diff.Pull = ctx.Pull.Num
+	diff.Command = command
+	diff.Time = time.Now()
+	s.Diffs.Add(diff)
+
+	switch {
+	case shadowErr != nil:
+		ctx.Log.Warn("shadow repo config failed to build the %s commands: %s", command, shadowErr)
+	case diff.Identical:
+		ctx.Log.Debug("shadow repo config builds the same %s commands", command)
+	default:
+		ctx.Log.Info("shadow repo config builds different %s commands: %s", command, diff.Summary())
+	}
+}
+
+// ShadowDiff is how the project commands built for a pull request with the
+// candidate repo config differ from those built with the active config.
+type ShadowDiff struct {
+	Repo    string    `json:"repo"`
+	Pull    int       `json:"pull"`
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+	// Identical is true if both configs build the same commands.
+	Identical bool `json:"identical"`
+	// Added are the projects only the candidate config builds commands for.
+	Added []ShadowProject `json:"added,omitempty"`
+	// Removed are the projects only the active config builds commands for.
+	Removed []ShadowProject `json:"removed,omitempty"`
+	// Changed are the projects whose commands are built differently.
+	Changed []ShadowProjectDiff `json:"changed,omitempty"`
+	// Error is set if the candidate config failed to build the commands.
+	Error string `json:"error,omitempty"`
+}
+
+// Summary returns a one line description of the differences for logs.
+func (d ShadowDiff) Summary() string {
+	var parts []string
+	for _, p := range d.Added {
+		parts = append(parts, fmt.Sprintf("added %s", p))
+	}
+	for _, p := range d.Removed {
+		parts = append(parts, fmt.Sprintf("removed %s", p))
+	}
+	for _, p := range d.Changed {
+		var fields []string
+		for _, f := range p.Fields {
+			fields = append(fields, f.Field)
+		}
+		parts = append(parts, fmt.Sprintf("changed %s: %s", p.ShadowProject, strings.Join(fields, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ShadowProject identifies the project of a command.
+type ShadowProject struct {
+	ProjectName string `json:"project_name,omitempty"`
+	RepoRelDir  string `json:"dir"`
+	Workspace   string `json:"workspace"`
+}
+
+func (p ShadowProject) String() string {
+	if p.ProjectName != "" {
+		return fmt.Sprintf("project %q (dir %q, workspace %q)", p.ProjectName, p.RepoRelDir, p.Workspace)
+	}
+	return fmt.Sprintf("dir %q workspace %q", p.RepoRelDir, p.Workspace)
+}
+
+// ShadowProjectDiff are the settings that differ for a project.
+type ShadowProjectDiff struct {
+	ShadowProject
+	Fields []ShadowFieldDiff `json:"fields"`
+}
+
+// ShadowFieldDiff is a setting that differs. Active and Shadow are empty for
+// settings that may contain secrets, ex. env vars.
+type ShadowFieldDiff struct {
+	Field  string `json:"field"`
+	Active string `json:"active,omitempty"`
+	Shadow string `json:"shadow,omitempty"`
+}
+
+// shadowFields are the settings of project commands that come from the repo
+// configs and are compared.
+var shadowFields = []struct {
+	name  string
+	value func(pcc models.ProjectCommandContext) interface{}
+	// secret is true if the values may contain secrets so they aren't
+	// reported.
+	secret bool
+}{
+	{name: "apply_requirements", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.ApplyRequirements }},
+	{name: "approvals", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Approvals }},
+	{name: "apply_windows", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.ApplyWindows }},
+	{name: "automerge", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.AutomergeEnabled }},
+	{name: "autoplan", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.AutoplanEnabled }},
+	{name: "cloud_credential", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.CloudCredential }, secret: true},
+	{name: "concurrency", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Concurrency }},
+	{name: "concurrency_group", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.ConcurrencyGroup }},
+	{name: "container_image", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.ContainerImage }},
+	{name: "custom_command", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.CustomCommand }},
+	{name: "env_vars", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.EnvVars }, secret: true},
+	{name: "git_credentials", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.GitCredentials }, secret: true},
+	{name: "guardrails", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Guardrails }},
+	{name: "notify", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Notify }},
+	{name: "owners", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Owners }},
+	{name: "plan_only", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.PlanOnly }},
+	{name: "security_scan", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.SecurityScan }},
+	{name: "silence_no_changes", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.SilenceNoChanges }},
+	{name: "state_lock_retry", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.StateLockRetry }},
+	// Steps can set env vars to secrets.
+	{name: "steps", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.Steps }, secret: true},
+	{name: "terraform_cloud", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.TerraformCloud }, secret: true},
+	{name: "terraform_distribution", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.TerraformDistribution }},
+	{name: "terraform_version", value: func(pcc models.ProjectCommandContext) interface{} {
+		if pcc.TerraformVersion == nil {
+			return ""
+		}
+		return pcc.TerraformVersion.String()
+	}},
+	{name: "var_files", value: func(pcc models.ProjectCommandContext) interface{} { return pcc.VarFiles }},
+}
+
+// DiffProjectCommands compares the commands built with the active config to
+// those built with the candidate config.
+func DiffProjectCommands(active []models.ProjectCommandContext, shadow []models.ProjectCommandContext) ShadowDiff {
+	shadowByProject := make(map[ShadowProject]models.ProjectCommandContext)
+	for _, pcc := range shadow {
+		shadowByProject[shadowProject(pcc)] = pcc
+	}
+	var diff ShadowDiff
+	seen := make(map[ShadowProject]bool)
+	for _, a := range active {
+		project := shadowProject(a)
+		seen[project] = true
+		s, ok := shadowByProject[project]
+		if !ok {
+			diff.Removed = append(diff.Removed, project)
+			continue
+		}
+		var fields []ShadowFieldDiff
+		for _, f := range shadowFields {
+			av, sv := f.value(a), f.value(s)
+			if reflect.DeepEqual(av, sv) {
+				continue
+			}
+			fd := ShadowFieldDiff{Field: f.name}
+			if !f.secret {
+				fd.Active = formatShadowValue(av)
+				fd.Shadow = formatShadowValue(sv)
+			}
+			fields = append(fields, fd)
+		}
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, ShadowProjectDiff{ShadowProject: project, Fields: fields})
+		}
+	}
+	for _, s := range shadow {
+		if project := shadowProject(s); !seen[project] {
+			diff.Added = append(diff.Added, project)
+		}
+	}
+	diff.Identical = len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+	return diff
+}
+
+func shadowProject(pcc models.ProjectCommandContext) ShadowProject {
+	return ShadowProject{ProjectName: pcc.ProjectName, RepoRelDir: pcc.RepoRelDir, Workspace: pcc.Workspace}
+}
+
+// formatShadowValue formats v for the diff, following pointers so their
+// values rather than addresses are shown.
+func formatShadowValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "<none>"
+		}
+		v = rv.Elem().Interface()
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// ShadowDiffStore keeps the most recent comparisons in memory.
+type ShadowDiffStore struct {
+	// Limit is how many comparisons are kept. If 0, DefaultShadowDiffsLimit
+	// are kept.
+	Limit int
+
+	mu    sync.Mutex
+	diffs []ShadowDiff
+}
+
+// Add stores diff, dropping the oldest comparison if the store is full.
+func (s *ShadowDiffStore) Add(diff ShadowDiff) {
+	limit := s.Limit
+	if limit == 0 {
+		limit = DefaultShadowDiffsLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.diffs = append(s.diffs, diff)
+	if len(s.diffs) > limit {
+		s.diffs = s.diffs[len(s.diffs)-limit:]
+	}
+}
+
+// List returns the stored comparisons, newest first. If repo is set, only the
+// comparisons of its pull requests are returned. If changedOnly is true, the
+// comparisons where both configs build the same commands are left out.
+func (s *ShadowDiffStore) List(repo string, changedOnly bool) []ShadowDiff {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	diffs := []ShadowDiff{}
+	for i := len(s.diffs) - 1; i >= 0; i-- {
+		d := s.diffs[i]
+		if repo != "" && !strings.EqualFold(d.Repo, repo) {
+			continue
+		}
+		if changedOnly && d.Identical {
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
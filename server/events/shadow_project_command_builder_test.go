@@ -0,0 +1,116 @@
+package events_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDiffProjectCommands(t *testing.T) {
+	v12 := version.Must(version.NewVersion("0.12.0"))
+	v13 := version.Must(version.NewVersion("0.13.0"))
+	active := []models.ProjectCommandContext{
+		{RepoRelDir: "app", Workspace: "default", ApplyRequirements: []string{"approved"}, TerraformVersion: v12, EnvVars: []valid.EnvVar{{Name: "TOKEN", Value: "old"}}},
+		{RepoRelDir: "db", Workspace: "default", ProjectName: "db"},
+		{RepoRelDir: "same", Workspace: "default", AutoplanEnabled: true},
+	}
+	shadow := []models.ProjectCommandContext{
+		{RepoRelDir: "app", Workspace: "default", ApplyRequirements: []string{"approved", "mergeable"}, TerraformVersion: v13, EnvVars: []valid.EnvVar{{Name: "TOKEN", Value: "new"}}},
+		{RepoRelDir: "same", Workspace: "default", AutoplanEnabled: true},
+		{RepoRelDir: "network", Workspace: "staging"},
+	}
+
+	diff := events.DiffProjectCommands(active, shadow)
+	Equals(t, events.ShadowDiff{
+		Added:   []events.ShadowProject{{RepoRelDir: "network", Workspace: "staging"}},
+		Removed: []events.ShadowProject{{ProjectName: "db", RepoRelDir: "db", Workspace: "default"}},
+		Changed: []events.ShadowProjectDiff{{
+			ShadowProject: events.ShadowProject{RepoRelDir: "app", Workspace: "default"},
+			Fields: []events.ShadowFieldDiff{
+				{Field: "apply_requirements", Active: "[approved]", Shadow: "[approved mergeable]"},
+				{Field: "env_vars"},
+				{Field: "terraform_version", Active: "0.12.0", Shadow: "0.13.0"},
+			},
+		}},
+	}, diff)
+	Equals(t, `added dir "network" workspace "staging"; removed project "db" (dir "db", workspace "default"); changed dir "app" workspace "default": apply_requirements, env_vars, terraform_version`, diff.Summary())
+
+	Equals(t, true, events.DiffProjectCommands(active, active).Identical)
+}
+
+func TestShadowProjectCommandBuilder(t *testing.T) {
+	RegisterMockTestingT(t)
+	active := mocks.NewMockProjectCommandBuilder()
+	shadow := mocks.NewMockProjectCommandBuilder()
+	diffs := &events.ShadowDiffStore{}
+	builder := events.ShadowProjectCommandBuilder{
+		ProjectCommandBuilder: active,
+		Shadow:                shadow,
+		Diffs:                 diffs,
+	}
+	ctx := &events.CommandContext{
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+		Pull:     models.PullRequest{Num: 1},
+		Log:      logging.NewNoopLogger(),
+	}
+	cmd := &events.CommentCommand{Name: models.PlanCommand}
+	activeCmds := []models.ProjectCommandContext{{RepoRelDir: "app", Workspace: "default"}}
+
+	When(active.BuildAutoplanCommands(ctx)).ThenReturn(activeCmds, nil)
+	When(shadow.BuildAutoplanCommands(ctx)).ThenReturn(activeCmds, nil)
+	cmds, err := builder.BuildAutoplanCommands(ctx)
+	Ok(t, err)
+	Equals(t, activeCmds, cmds)
+
+	// Only the active config's commands are returned even if the shadow
+	// config fails.
+	When(active.BuildPlanCommands(ctx, cmd)).ThenReturn(activeCmds, nil)
+	When(shadow.BuildPlanCommands(ctx, cmd)).ThenReturn(nil, errors.New("invalid workflow"))
+	cmds, err = builder.BuildPlanCommands(ctx, cmd)
+	Ok(t, err)
+	Equals(t, activeCmds, cmds)
+
+	// Nothing is compared if the active config fails.
+	When(active.BuildPlanCommands(ctx, cmd)).ThenReturn(nil, errors.New("clone failed"))
+	_, err = builder.BuildPlanCommands(ctx, cmd)
+	ErrEquals(t, "clone failed", err)
+	shadow.VerifyWasCalledOnce().BuildPlanCommands(ctx, cmd)
+
+	// Apply commands are only built with the active config.
+	_, err = builder.BuildApplyCommands(ctx, cmd)
+	Ok(t, err)
+	shadow.VerifyWasCalled(Never()).BuildApplyCommands(ctx, cmd)
+
+	recorded := diffs.List("", false)
+	Equals(t, 2, len(recorded))
+	Equals(t, "plan", recorded[0].Command)
+	Equals(t, "invalid workflow", recorded[0].Error)
+	Equals(t, false, recorded[0].Identical)
+	Equals(t, "autoplan", recorded[1].Command)
+	Equals(t, "owner/repo", recorded[1].Repo)
+	Equals(t, 1, recorded[1].Pull)
+	Equals(t, true, recorded[1].Identical)
+	Equals(t, 1, len(diffs.List("", true)))
+	Equals(t, 0, len(diffs.List("owner/other", false)))
+}
+
+func TestShadowDiffStore_Limit(t *testing.T) {
+	diffs := &events.ShadowDiffStore{Limit: 2}
+	for i := 1; i <= 3; i++ {
+		diffs.Add(events.ShadowDiff{Repo: fmt.Sprintf("owner/repo%d", i)})
+	}
+	var repos []string
+	for _, d := range diffs.List("", false) {
+		repos = append(repos, d.Repo)
+	}
+	Equals(t, []string{"owner/repo3", "owner/repo2"}, repos)
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+)
+
+// SlackApprovals posts the plans of pull requests to a Slack channel with an
+// "Approve & Apply" button so approvers who live in Slack can apply them.
+// Clicking the button approves the pull request as the clicker's VCS user and
+// applies its plans as if that user had commented atlantis apply, so every
+// apply requirement is still checked.
+type SlackApprovals struct {
+	Client  webhooks.SlackClient
+	Channel string
+	// Users maps Slack user IDs to the VCS usernames they approve and apply
+	// as. Slack users that aren't mapped can't approve.
+	Users map[string]string
+}
+
+// SlackApproval identifies the plans of a pull request in the value of an
+// approval's button. Commit is the head commit they were planned at so
+// clicking the button after new commits were pushed doesn't apply plans the
+// approver hasn't seen.
+type SlackApproval struct {
+	Repo   string `json:"repo"`
+	Pull   int    `json:"pull"`
+	Commit string `json:"commit"`
+}
+
+// ParseSlackApproval parses the value of an approval's button.
+func ParseSlackApproval(value string) (SlackApproval, error) {
+	var approval SlackApproval
+	if err := json.Unmarshal([]byte(value), &approval); err != nil {
+		return approval, fmt.Errorf("parsing approval %q: %s", value, err)
+	}
+	if approval.Repo == "" || approval.Pull <= 0 {
+		return approval, fmt.Errorf("approval %q is missing its repo or pull request", value)
+	}
+	return approval, nil
+}
+
+// ParseSlackUsers parses users, a comma separated list of
+// {slack user ID}:{VCS username}, ex. U012AB3CD:alice,U045EF6GH:bob.
+func ParseSlackUsers(users string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, u := range strings.Split(users, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		parts := strings.SplitN(u, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q must be of the form {slack user ID}:{VCS username}", u)
+		}
+		parsed[parts[0]] = parts[1]
+	}
+	return parsed, nil
+}
+
+// VCSUser returns the VCS username of the Slack user with ID slackUserID.
+func (s *SlackApprovals) VCSUser(slackUserID string) (string, bool) {
+	user, ok := s.Users[slackUserID]
+	return user, ok
+}
+
+// PostPlans posts the plans in results that can be applied, i.e. that have
+// changes, to the channel. Nothing is posted if there are none.
+func (s *SlackApprovals) PostPlans(ctx *CommandContext, results []models.ProjectResult) error {
+	var projects []string
+	for _, res := range results {
+		if res.PlanSuccess == nil || res.PlanSuccess.PlanOnly || !res.PlanSuccess.HasChanges() {
+			continue
+		}
+		projects = append(projects, fmt.Sprintf("%s: %s", resultProject(res), resultDetails(res)))
+	}
+	if len(projects) == 0 {
+		return nil
+	}
+	value, err := json.Marshal(SlackApproval{Repo: ctx.BaseRepo.FullName, Pull: ctx.Pull.Num, Commit: ctx.Pull.HeadCommit})
+	if err != nil {
+		return err
+	}
+	return s.Client.PostApprovalRequest(s.Channel, webhooks.ApprovalRequest{
+		Repo:     ctx.BaseRepo,
+		Pull:     ctx.Pull,
+		User:     ctx.User,
+		Projects: projects,
+		Value:    string(value),
+	})
+}
@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseSlackUsers(t *testing.T) {
+	users, err := events.ParseSlackUsers("U012AB3CD:alice, U045EF6GH:bob,")
+	Ok(t, err)
+	Equals(t, map[string]string{"U012AB3CD": "alice", "U045EF6GH": "bob"}, users)
+
+	_, err = events.ParseSlackUsers("U012AB3CD:")
+	ErrEquals(t, `"U012AB3CD:" must be of the form {slack user ID}:{VCS username}`, err)
+}
+
+func TestParseSlackApproval(t *testing.T) {
+	approval, err := events.ParseSlackApproval(`{"repo":"owner/repo","pull":7,"commit":"abc123"}`)
+	Ok(t, err)
+	Equals(t, events.SlackApproval{Repo: "owner/repo", Pull: 7, Commit: "abc123"}, approval)
+
+	_, err = events.ParseSlackApproval(`{"repo":"owner/repo"}`)
+	ErrEquals(t, `approval "{\"repo\":\"owner/repo\"}" is missing its repo or pull request`, err)
+	_, err = events.ParseSlackApproval("owner/repo#7")
+	Assert(t, err != nil, "exp error parsing approval that isn't JSON")
+}
+
+func TestSlackApprovals_PostPlans(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	approvals := events.SlackApprovals{Client: client, Channel: "approvals"}
+	ctx := &events.CommandContext{
+		BaseRepo: exportPull.BaseRepo,
+		Pull:     exportPull,
+		User:     models.User{Username: "carol"},
+		Log:      logging.NewNoopLogger(),
+	}
+
+	noChanges := models.ProjectResult{RepoRelDir: "db", Workspace: "default", PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes. Infrastructure is up-to-date."}}
+	planOnly := models.ProjectResult{RepoRelDir: "audit", Workspace: "default", PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy.", PlanOnly: true}}
+	failed := models.ProjectResult{RepoRelDir: "net", Workspace: "default", Failure: "failed"}
+
+	// Nothing is posted without plans that can be applied.
+	Ok(t, approvals.PostPlans(ctx, []models.ProjectResult{noChanges, planOnly, failed}))
+	client.VerifyWasCalled(Never()).PostApprovalRequest(AnyString(), matchers.AnyWebhooksApprovalRequest())
+
+	Ok(t, approvals.PostPlans(ctx, append([]models.ProjectResult{exportResults[0]}, noChanges)))
+	client.VerifyWasCalledOnce().PostApprovalRequest("approvals", webhooks.ApprovalRequest{
+		Repo:     exportPull.BaseRepo,
+		Pull:     exportPull,
+		User:     models.User{Username: "carol"},
+		Projects: []string{"project: app dir: app workspace: default: 1 to add, 0 to change, 0 to destroy."},
+		Value:    `{"repo":"owner/repo","pull":7,"commit":"abc123"}`,
+	})
+}
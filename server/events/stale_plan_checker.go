@@ -0,0 +1,210 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// StalePlanChecker finds the plans of open pull requests that went stale
+// because the base branch changed their projects after they were planned,
+// ex. because other pull requests were applied and merged, and comments on
+// the pull requests so reviewers don't approve outdated plans. Only plans
+// made with the merge checkout strategy are checked since they're the only
+// ones that include the base branch.
+type StalePlanChecker struct {
+	// StalePlanAge is how old a plan has to be before it's checked.
+	StalePlanAge     time.Duration
+	DB               db.PullStatusStore
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	VCSClient        vcs.Client
+	Logger           logging.SimpleLogging
+
+	// notified are the plans that were already commented on. They're
+	// commented on again once they're planned again and go stale.
+	notified map[string]bool
+}
+
+// stalePlan is a plan that the base branch changed the project of.
+type stalePlan struct {
+	status models.ProjectStatus
+	// files are the files in the project's dir that were changed.
+	files []string
+}
+
+// CheckOn checks every pull request whenever schedule matches. It never
+// returns.
+func (s *StalePlanChecker) CheckOn(schedule valid.Schedule) {
+	for {
+		next, ok := schedule.Next(time.Now())
+		if !ok {
+			s.Logger.Warn("not checking for stale plans since schedule %q doesn't match in the next year", schedule)
+			return
+		}
+		time.Sleep(time.Until(next))
+		s.Check()
+	}
+}
+
+// Check checks the plans of every pull request and comments on the pull
+// requests that have stale plans.
+func (s *StalePlanChecker) Check() {
+	statuses, err := s.DB.ListPullStatuses()
+	if err != nil {
+		s.Logger.Err("unable to list pull requests to check for stale plans: %s", err)
+		return
+	}
+	notified := make(map[string]bool)
+	for _, ps := range statuses {
+		log := s.Logger.NewLogger(fmt.Sprintf("%s#%d", ps.Pull.BaseRepo.FullName, ps.Pull.Num), true, s.Logger.GetLevel())
+		stale, err := s.stalePlans(log, ps)
+		if err != nil {
+			log.Warn("unable to check for stale plans: %s", err)
+		}
+		var unnotified []stalePlan
+		for _, plan := range stale {
+			key := stalePlanKey(ps.Pull, plan.status)
+			if !s.notified[key] {
+				unnotified = append(unnotified, plan)
+			}
+			notified[key] = true
+		}
+		if len(unnotified) == 0 {
+			continue
+		}
+		log.Info("commenting about %d stale plans", len(unnotified))
+		if err := s.VCSClient.CreateComment(ps.Pull.BaseRepo, ps.Pull.Num, stalePlansComment(ps.Pull, unnotified)); err != nil {
+			log.Err("unable to comment: %s", err)
+		}
+	}
+	// Plans that aren't stale anymore, ex. because they were planned again,
+	// are forgotten.
+	s.notified = notified
+}
+
+// stalePlans returns the plans of ps that are older than StalePlanAge and
+// whose projects were changed on the base branch since they were planned.
+func (s *StalePlanChecker) stalePlans(log *logging.SimpleLogger, ps models.PullStatus) ([]stalePlan, error) {
+	pull := ps.Pull
+	// The base branch is fetched into each workspace's clone so it's only
+	// fetched once per workspace.
+	byWorkspace := make(map[string][]models.ProjectStatus)
+	for _, p := range ps.Projects {
+		if p.Status != models.PlannedPlanStatus || p.PlanFingerprint.BaseCommit == "" || !s.planIsOld(pull, p) {
+			continue
+		}
+		byWorkspace[p.Workspace] = append(byWorkspace[p.Workspace], p)
+	}
+	var workspaces []string
+	for workspace := range byWorkspace {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	var stale []stalePlan
+	for _, workspace := range workspaces {
+		unlockFn, err := s.WorkingDirLocker.TryLock(pull.BaseRepo.FullName, pull.Num, workspace)
+		if err != nil {
+			// A command is running in the workspace so its plans might
+			// change anyway.
+			log.Debug("not checking workspace %q for stale plans: %s", workspace, err)
+			continue
+		}
+		workspaceStale, err := s.staleWorkspacePlans(log, pull, workspace, byWorkspace[workspace])
+		unlockFn()
+		if err != nil {
+			return stale, errors.Wrapf(err, "checking workspace %q", workspace)
+		}
+		stale = append(stale, workspaceStale...)
+	}
+	return stale, nil
+}
+
+// staleWorkspacePlans returns which of projects, which were all planned in
+// workspace, are stale.
+func (s *StalePlanChecker) staleWorkspacePlans(log *logging.SimpleLogger, pull models.PullRequest, workspace string, projects []models.ProjectStatus) ([]stalePlan, error) {
+	// The head repo is only used for credentials when fetching the base
+	// branch.
+	baseCommit, err := s.WorkingDir.GetBaseBranchCommit(log, pull.BaseRepo, pull.BaseRepo, pull, workspace)
+	if err != nil {
+		return nil, err
+	}
+	var stale []stalePlan
+	for _, p := range projects {
+		if baseCommit == "" || baseCommit == p.PlanFingerprint.BaseCommit {
+			continue
+		}
+		files, err := s.WorkingDir.GetBaseBranchModifiedFiles(pull.BaseRepo, pull, workspace, p.PlanFingerprint.BaseCommit)
+		if err != nil {
+			return stale, err
+		}
+		var projectFiles []string
+		for _, file := range files {
+			if p.RepoRelDir == "." || strings.HasPrefix(file, p.RepoRelDir+"/") {
+				projectFiles = append(projectFiles, file)
+			}
+		}
+		if len(projectFiles) > 0 {
+			stale = append(stale, stalePlan{status: p, files: projectFiles})
+		}
+	}
+	return stale, nil
+}
+
+// planIsOld returns true if the planfile of p is older than StalePlanAge.
+func (s *StalePlanChecker) planIsOld(pull models.PullRequest, p models.ProjectStatus) bool {
+	repoDir, err := s.WorkingDir.GetWorkingDir(pull.BaseRepo, pull, p.Workspace)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(repoDir, p.RepoRelDir, runtime.GetPlanFilename(p.Workspace, p.ProjectName)))
+	if err != nil {
+		return false
+	}
+	return time.Since(info.ModTime()) >= s.StalePlanAge
+}
+
+// stalePlanKey identifies the plan of p in pull. It includes the commits the
+// plan was made on so plans that are made again are different.
+func stalePlanKey(pull models.PullRequest, p models.ProjectStatus) string {
+	return fmt.Sprintf("%s#%d/%s/%s/%s@%s,%s", pull.BaseRepo.FullName, pull.Num, p.RepoRelDir, p.Workspace, p.ProjectName, p.PlanFingerprint.HeadCommit, p.PlanFingerprint.BaseCommit)
+}
+
+// maxStalePlanFiles is how many of the changed files of a stale plan are
+// listed in comments.
+const maxStalePlanFiles = 5
+
+func stalePlansComment(pull models.PullRequest, stale []stalePlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Some plans of this pull request are stale.** Their projects changed on the `%s` branch since they were planned, ex. because other pull requests were applied and merged, so they might not show what applying them will do:\n", pull.BaseBranch)
+	for _, plan := range stale {
+		p := plan.status
+		fmt.Fprintf(&b, "* dir: `%s` workspace: `%s`", p.RepoRelDir, p.Workspace)
+		if p.ProjectName != "" {
+			fmt.Fprintf(&b, " project: `%s`", p.ProjectName)
+		}
+		files := plan.files
+		if len(files) > maxStalePlanFiles {
+			files = files[:maxStalePlanFiles]
+		}
+		fmt.Fprintf(&b, ", `%s` changed", strings.Join(files, "`, `"))
+		if more := len(plan.files) - len(files); more > 0 {
+			fmt.Fprintf(&b, " and %d more files", more)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nRun `atlantis plan` again before approving or applying them.")
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestStalePlanChecker_Check(t *testing.T) {
+	cases := []struct {
+		description string
+		planAge     time.Duration
+		baseCommit  string
+		baseFiles   []string
+		expStale    bool
+	}{
+		{
+			"plans whose projects changed on the base branch are stale",
+			48 * time.Hour,
+			"new-base",
+			[]string{"README.md", "dir/main.tf"},
+			true,
+		},
+		{
+			"plans of projects that didn't change on the base branch aren't stale",
+			48 * time.Hour,
+			"new-base",
+			[]string{"README.md", "dir2/main.tf"},
+			false,
+		},
+		{
+			"plans on the current base branch aren't stale",
+			48 * time.Hour,
+			"old-base",
+			nil,
+			false,
+		},
+		{
+			"new plans aren't checked",
+			time.Hour,
+			"new-base",
+			[]string{"dir/main.tf"},
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			checker, workingDir, vcsClient, cleanup := setupStalePlanChecker(t, c.planAge)
+			defer cleanup()
+			When(workingDir.GetBaseBranchCommit(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(c.baseCommit, nil)
+			When(workingDir.GetBaseBranchModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), EqString("old-base"))).ThenReturn(c.baseFiles, nil)
+
+			checker.Check()
+
+			if !c.expStale {
+				vcsClient.VerifyWasCalled(Never()).CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString())
+				return
+			}
+			_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(vcsmatchers.AnyModelsRepo(), EqInt(fixtures.Pull.Num), AnyString()).GetCapturedArguments()
+			Assert(t, strings.Contains(comment, "* dir: `dir` workspace: `default`, `dir/main.tf` changed\n"), "exp stale project in comment, got %q", comment)
+
+			// The plan is only commented on once.
+			checker.Check()
+			vcsClient.VerifyWasCalledOnce().CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString())
+		})
+	}
+}
+
+func setupStalePlanChecker(t *testing.T, planAge time.Duration) (*events.StalePlanChecker, *mocks.MockWorkingDir, *vcsmocks.MockClient, func()) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	_, err = boltDB.UpdatePullWithResults(fixtures.Pull, []models.ProjectResult{{
+		RepoRelDir: "dir",
+		Workspace:  "default",
+		Command:    models.PlanCommand,
+		PlanSuccess: &models.PlanSuccess{
+			TerraformOutput: "Plan: 1 to add",
+			Fingerprint:     models.PlanFingerprint{HeadCommit: fixtures.Pull.HeadCommit, BaseCommit: "old-base"},
+		},
+	}})
+	Ok(t, err)
+
+	repoDir := filepath.Join(tmp, "repo")
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "dir"), 0700))
+	planfile := filepath.Join(repoDir, "dir", "default.tfplan")
+	Ok(t, ioutil.WriteFile(planfile, nil, 0600))
+	planned := time.Now().Add(-planAge)
+	Ok(t, os.Chtimes(planfile, planned, planned))
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString("default"))).ThenReturn(repoDir, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	return &events.StalePlanChecker{
+		StalePlanAge:     24 * time.Hour,
+		DB:               boltDB,
+		WorkingDir:       workingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		VCSClient:        vcsClient,
+		Logger:           logging.NewNoopLogger(),
+	}, workingDir, vcsClient, cleanup
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// stateLockedRegex matches the output of Terraform commands that failed
+// because another run holds the state lock.
+var stateLockedRegex = regexp.MustCompile(`Error acquiring the state lock`)
+
+// stateLockInfoRegex matches the fields of the Lock Info block Terraform
+// prints when the state is locked, ex. "  Who:       user@host".
+var stateLockInfoRegex = regexp.MustCompile(`(?m)^\s*(ID|Path|Operation|Who|Version|Created):[ \t]*(\S.*?)\s*$`)
+
+// StateLockInfo is the lock held on the Terraform state by another run, from
+// the Lock Info Terraform prints when it can't acquire it.
+type StateLockInfo struct {
+	ID        string
+	Path      string
+	Operation string
+	Who       string
+	Version   string
+	Created   string
+}
+
+// StateLockedErr is returned by StateLockRetryStepRunner when a step still
+// can't acquire the Terraform state lock once it's out of attempts.
+type StateLockedErr struct {
+	Lock     StateLockInfo
+	Attempts int
+}
+
+func (s StateLockedErr) Error() string {
+	var b strings.Builder
+	b.WriteString("the Terraform state is locked by ")
+	if s.Lock.Who != "" {
+		b.WriteString(s.Lock.Who)
+	} else {
+		b.WriteString("another run")
+	}
+	var details []string
+	if s.Lock.Operation != "" {
+		details = append(details, "operation "+s.Lock.Operation)
+	}
+	if s.Lock.Created != "" {
+		details = append(details, "since "+s.Lock.Created)
+	}
+	if s.Lock.ID != "" {
+		details = append(details, "lock ID "+s.Lock.ID)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+	if s.Attempts > 1 {
+		fmt.Fprintf(&b, ", gave up after %d attempts", s.Attempts)
+	}
+	b.WriteString(". Try again once the other run finishes")
+	if s.Lock.ID != "" {
+		fmt.Fprintf(&b, " or, if the lock is stale, remove it with: terraform force-unlock %s", s.Lock.ID)
+	}
+	return b.String()
+}
+
+// ParseStateLockInfo returns the lock in the output of a Terraform command
+// and true if the command failed because the state was locked.
+func ParseStateLockInfo(output string) (StateLockInfo, bool) {
+	if !stateLockedRegex.MatchString(output) {
+		return StateLockInfo{}, false
+	}
+	var lock StateLockInfo
+	if i := strings.Index(output, "Lock Info:"); i >= 0 {
+		for _, m := range stateLockInfoRegex.FindAllStringSubmatch(output[i:], -1) {
+			switch m[1] {
+			case "ID":
+				lock.ID = m[2]
+			case "Path":
+				lock.Path = m[2]
+			case "Operation":
+				lock.Operation = m[2]
+			case "Who":
+				lock.Who = m[2]
+			case "Version":
+				lock.Version = m[2]
+			case "Created":
+				lock.Created = m[2]
+			}
+		}
+	}
+	return lock, true
+}
+
+// StateLockRetryStepRunner wraps a StepRunner and, if the step fails because
+// another run holds the Terraform state lock, retries it with jittered
+// exponential backoff as configured by the project's workflow. If the state
+// is still locked, it returns a StateLockedErr describing the lock instead
+// of Terraform's output.
+type StateLockRetryStepRunner struct {
+	StepRunner StepRunner
+}
+
+// Run runs the wrapped step, retrying it while the state is locked.
+func (s *StateLockRetryStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	maxAttempts := 1
+	var backoff time.Duration
+	if ctx.StateLockRetry != nil {
+		maxAttempts = ctx.StateLockRetry.MaxAttempts
+		backoff = ctx.StateLockRetry.Backoff
+	}
+	retries := newStepRetries(ctx, envs)
+	attempt := 1
+	for {
+		out, err := s.StepRunner.Run(ctx, extraArgs, path, retries.envs())
+		if err == nil {
+			return out, nil
+		}
+		lock, locked := ParseStateLockInfo(out + err.Error())
+		if !locked {
+			return out, err
+		}
+		if attempt >= maxAttempts {
+			return "", StateLockedErr{Lock: lock, Attempts: attempt}
+		}
+		wait := retryBackoff(backoff, attempt)
+		ctx.Log.Warn("the Terraform state is locked by %q, retrying in %s (attempt %d/%d)", lock.Who, wait, attempt+1, maxAttempts)
+		if err := retries.wait(wait); err != nil {
+			return "", err
+		}
+		attempt++
+	}
+}
@@ -0,0 +1,134 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var stateLockedOutput = `
+Error: Error acquiring the state lock
+
+Error message: ConditionalCheckFailedException: The conditional request failed
+Lock Info:
+  ID:        4d4b1ee5-9c5b-c5b3-6e0e-1d8b5f2f1f6a
+  Path:      bucket/env/terraform.tfstate
+  Operation: OperationTypeApply
+  Who:       runner@ci-host
+  Version:   0.12.24
+  Created:   2020-05-01 12:00:00.123 +0000 UTC
+  Info:
+
+Terraform acquires a state lock to protect the state from being written
+by multiple users at the same time.
+`
+
+func TestParseStateLockInfo(t *testing.T) {
+	lock, locked := events.ParseStateLockInfo(stateLockedOutput)
+	Equals(t, true, locked)
+	Equals(t, events.StateLockInfo{
+		ID:        "4d4b1ee5-9c5b-c5b3-6e0e-1d8b5f2f1f6a",
+		Path:      "bucket/env/terraform.tfstate",
+		Operation: "OperationTypeApply",
+		Who:       "runner@ci-host",
+		Version:   "0.12.24",
+		Created:   "2020-05-01 12:00:00.123 +0000 UTC",
+	}, lock)
+
+	_, locked = events.ParseStateLockInfo("Error: invalid resource")
+	Equals(t, false, locked)
+}
+
+func TestStateLockRetryStepRunner_RetriesUntilUnlocked(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.StateLockRetryStepRunner{StepRunner: mockStep}
+	ctx := models.ProjectCommandContext{
+		Log:            logging.NewNoopLogger(),
+		StateLockRetry: &valid.StateLockRetry{MaxAttempts: 3, Backoff: time.Millisecond},
+	}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn(stateLockedOutput, errors.New("exit status 1")).
+		ThenReturn("output", nil)
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	Ok(t, err)
+	Equals(t, "output", out)
+	mockStep.VerifyWasCalled(Times(2)).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestStateLockRetryStepRunner_GivesUp(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.StateLockRetryStepRunner{StepRunner: mockStep}
+	ctx := models.ProjectCommandContext{
+		Log:            logging.NewNoopLogger(),
+		StateLockRetry: &valid.StateLockRetry{MaxAttempts: 2, Backoff: time.Millisecond},
+	}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn(stateLockedOutput, errors.New("exit status 1"))
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	Equals(t, "", out)
+	ErrEquals(t, "the Terraform state is locked by runner@ci-host (operation OperationTypeApply, since 2020-05-01 12:00:00.123 +0000 UTC, lock ID 4d4b1ee5-9c5b-c5b3-6e0e-1d8b5f2f1f6a), gave up after 2 attempts. Try again once the other run finishes or, if the lock is stale, remove it with: terraform force-unlock 4d4b1ee5-9c5b-c5b3-6e0e-1d8b5f2f1f6a", err)
+	mockStep.VerifyWasCalled(Times(2)).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestStateLockRetryStepRunner_NotRetriedWithoutConfig(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.StateLockRetryStepRunner{StepRunner: mockStep}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Error acquiring the state lock", errors.New("exit status 1"))
+
+	_, err := runner.Run(ctx, nil, "path", nil)
+	ErrEquals(t, "the Terraform state is locked by another run. Try again once the other run finishes", err)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestStateLockRetryStepRunner_OtherErrorsNotRetried(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.StateLockRetryStepRunner{StepRunner: mockStep}
+	ctx := models.ProjectCommandContext{
+		Log:            logging.NewNoopLogger(),
+		StateLockRetry: &valid.StateLockRetry{MaxAttempts: 3, Backoff: time.Millisecond},
+	}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: invalid resource", errors.New("exit status 1"))
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	ErrEquals(t, "exit status 1", err)
+	Equals(t, "Error: invalid resource", out)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestStateLockRetryStepRunner_BackoffCancelled(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.StateLockRetryStepRunner{StepRunner: mockStep}
+	done := make(chan struct{})
+	close(done)
+	ctx := models.ProjectCommandContext{
+		Log:            logging.NewNoopLogger(),
+		StateLockRetry: &valid.StateLockRetry{MaxAttempts: 3, Backoff: time.Hour},
+		Done:           done,
+	}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn(stateLockedOutput, errors.New("exit status 1"))
+
+	_, err := runner.Run(ctx, nil, "path", nil)
+	Equals(t, execution.ErrCancelled, err)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
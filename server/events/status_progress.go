@@ -0,0 +1,112 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// MinStatusProgressInterval is the shortest interval progress can be
+// reported at so that long commands don't use up the VCS host's rate limit.
+const MinStatusProgressInterval = 10 * time.Second
+
+// statusProgress periodically updates the pending commit statuses of a
+// command that runs projects with which project is running and for how long
+// so reviewers get feedback before the final comment. Statuses are updated
+// at most once per interval and not at all for commands that finish within
+// it.
+type statusProgress struct {
+	updater         CommitStatusUpdater
+	ctx             *CommandContext
+	cmdName         models.CommandName
+	total           int
+	projectStatuses bool
+
+	// mu is held while reporting so that a project's final status can't be
+	// set while its progress is being reported.
+	mu       sync.Mutex
+	started  time.Time
+	current  int
+	running  bool
+	pCmd     models.ProjectCommandContext
+	pStarted time.Time
+
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// startStatusProgress starts reporting progress every interval for running
+// cmdName on total projects. If interval is 0, no progress is reported.
+// Stop must be called before the command's final status is set.
+func startStatusProgress(updater CommitStatusUpdater, ctx *CommandContext, cmdName models.CommandName, total int, projectStatuses bool, interval time.Duration) *statusProgress {
+	p := &statusProgress{
+		updater:         updater,
+		ctx:             ctx,
+		cmdName:         cmdName,
+		total:           total,
+		projectStatuses: projectStatuses,
+		started:         time.Now(),
+		done:            make(chan struct{}),
+	}
+	if interval <= 0 {
+		return p
+	}
+	ticker := time.NewTicker(interval)
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.report()
+			}
+		}
+	}()
+	return p
+}
+
+// Next records that pCmd, the next project, has started running.
+func (p *statusProgress) Next(pCmd models.ProjectCommandContext) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current++
+	p.running = true
+	p.pCmd = pCmd
+	p.pStarted = time.Now()
+}
+
+// Finished records that the current project has finished. It must be called
+// before the project's final status is set.
+func (p *statusProgress) Finished() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+}
+
+// Stop stops reporting progress and waits for any update in flight so it
+// can't overwrite the command's final status.
+func (p *statusProgress) Stop() {
+	close(p.done)
+	p.stopped.Wait()
+}
+
+func (p *statusProgress) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == 0 {
+		return
+	}
+
+	if err := p.updater.UpdateCombinedProgress(p.ctx.BaseRepo, p.ctx.Pull, p.cmdName, p.current, p.total, time.Since(p.started)); err != nil {
+		p.ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+	if p.projectStatuses && p.running {
+		if err := p.updater.UpdateProjectProgress(p.pCmd, p.cmdName, time.Since(p.pStarted)); err != nil {
+			p.ctx.Log.Warn("unable to update commit status: %s", err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// progressRecorder records the progress updates it's sent.
+type progressRecorder struct {
+	MockCSU
+	mu       sync.Mutex
+	combined []int
+	projects []string
+}
+
+func (p *progressRecorder) UpdateCombinedProgress(repo models.Repo, pull models.PullRequest, command models.CommandName, current int, numTotal int, elapsed time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.combined = append(p.combined, current)
+	return nil
+}
+
+func (p *progressRecorder) UpdateProjectProgress(ctx models.ProjectCommandContext, cmdName models.CommandName, elapsed time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.projects = append(p.projects, ctx.RepoRelDir)
+	return nil
+}
+
+func (p *progressRecorder) calls() ([]int, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.combined...), append([]string(nil), p.projects...)
+}
+
+func TestStatusProgress(t *testing.T) {
+	recorder := &progressRecorder{}
+	ctx := &CommandContext{Log: logging.NewNoopLogger()}
+	progress := startStatusProgress(recorder, ctx, models.PlanCommand, 2, true, 5*time.Millisecond)
+
+	progress.Next(models.ProjectCommandContext{RepoRelDir: "project1"})
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if combined, _ := recorder.calls(); len(combined) > 0 {
+			break
+		}
+	}
+	progress.Finished()
+	progress.Stop()
+
+	combined, projects := recorder.calls()
+	Assert(t, len(combined) > 0, "expected progress to be reported")
+	for _, current := range combined {
+		Equals(t, 1, current)
+	}
+	for _, dir := range projects {
+		Equals(t, "project1", dir)
+	}
+
+	// Nothing is reported after stopping.
+	time.Sleep(20 * time.Millisecond)
+	afterStop, _ := recorder.calls()
+	Equals(t, len(combined), len(afterStop))
+}
+
+func TestStatusProgress_Disabled(t *testing.T) {
+	recorder := &progressRecorder{}
+	progress := startStatusProgress(recorder, &CommandContext{Log: logging.NewNoopLogger()}, models.ApplyCommand, 1, true, 0)
+	progress.Next(models.ProjectCommandContext{})
+	time.Sleep(10 * time.Millisecond)
+	progress.Finished()
+	progress.Stop()
+	combined, projects := recorder.calls()
+	Equals(t, 0, len(combined))
+	Equals(t, 0, len(projects))
+}
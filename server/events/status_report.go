@@ -0,0 +1,69 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// StatusReport is the state of each project in a pull request. It's what the
+// status command comments.
+type StatusReport struct {
+	// PullNum is the pull request the report is for. It's used to tell apart
+	// the locks held by the pull request from those held by others.
+	PullNum int
+	// Projects are the projects that plan would be run for followed by any
+	// others that were planned by earlier commands.
+	Projects []ProjectStatusReport
+	// DetectErr is set if the projects that plan would be run for couldn't
+	// be determined, ex. because another command is running on the pull
+	// request. Only projects from earlier commands are listed then.
+	DetectErr error
+}
+
+// ProjectStatusReport is the state of a project in a StatusReport.
+type ProjectStatusReport struct {
+	ProjectName string
+	RepoRelDir  string
+	Workspace   string
+	// Status is the project's status at the pull request's head commit or
+	// nil if it hasn't been planned at that commit.
+	Status *models.ProjectPlanStatus
+	// Lock is the lock held on the project's dir and workspace or nil if it
+	// isn't locked.
+	Lock *models.ProjectLock
+}
+
+// NeedPlan returns the number of projects that haven't been planned or whose
+// plans failed.
+func (s StatusReport) NeedPlan() int {
+	n := 0
+	for _, p := range s.Projects {
+		if p.Status == nil || *p.Status == models.ErroredPlanStatus {
+			n++
+		}
+	}
+	return n
+}
+
+// NeedApply returns the number of projects that have been planned but not
+// applied successfully.
+func (s StatusReport) NeedApply() int {
+	n := 0
+	for _, p := range s.Projects {
+		if p.Status != nil && (*p.Status == models.PlannedPlanStatus || *p.Status == models.ErroredApplyStatus) {
+			n++
+		}
+	}
+	return n
+}
+
+// WaitingOnLocks returns the number of projects that are locked by other pull
+// requests.
+func (s StatusReport) WaitingOnLocks() int {
+	n := 0
+	for _, p := range s.Projects {
+		if p.Lock != nil && p.Lock.Pull.Num != s.PullNum {
+			n++
+		}
+	}
+	return n
+}
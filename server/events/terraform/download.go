@@ -0,0 +1,161 @@
+package terraform
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+)
+
+// The distributions of Terraform that can be downloaded. See
+// DownloadConfig.Distribution.
+const (
+	TerraformDistribution = "terraform"
+	OpenTofuDistribution  = "opentofu"
+)
+
+// The URLs of the official releases of each distribution.
+const (
+	TerraformReleasesURL = "https://releases.hashicorp.com"
+	OpenTofuReleasesURL  = "https://github.com/opentofu/opentofu/releases/download"
+)
+
+// ReleasesURL returns the URL of the official releases of distribution.
+func ReleasesURL(distribution string) string {
+	if distribution == OpenTofuDistribution {
+		return OpenTofuReleasesURL
+	}
+	return TerraformReleasesURL
+}
+
+// The ways downloads can be checked. See DownloadConfig.Checksum.
+const (
+	// ChecksumVerify verifies downloads against the SHA256SUMS file
+	// published with each release.
+	ChecksumVerify = "verify"
+	// ChecksumSkip doesn't verify downloads. It's for mirrors that don't
+	// host the SHA256SUMS files.
+	ChecksumSkip = "skip"
+)
+
+// DownloadConfig configures how missing Terraform versions are found and
+// downloaded.
+type DownloadConfig struct {
+	// URL is the base URL of the releases, ex. https://releases.hashicorp.com.
+	// Its layout must match that of the distribution's official releases.
+	URL string
+	// Distribution is TerraformDistribution or OpenTofuDistribution. If
+	// empty, it's TerraformDistribution.
+	Distribution string
+	// Checksum is ChecksumVerify or ChecksumSkip. If empty, it's
+	// ChecksumVerify.
+	Checksum string
+	// LocalBinDir, if set, is a directory of binaries that's searched before
+	// downloading. Versions are found at <dir>/<bin><version>, ex.
+	// terraform0.12.0, or <dir>/<version>/<bin>.
+	LocalBinDir string
+}
+
+// BinName returns the name of the distribution's binary, ex. terraform.
+func (d DownloadConfig) BinName() string {
+	if d.Distribution == OpenTofuDistribution {
+		return "tofu"
+	}
+	return "terraform"
+}
+
+// localBin returns the path of version v in LocalBinDir or "" if it isn't
+// there.
+func (d DownloadConfig) localBin(v *version.Version) string {
+	if d.LocalBinDir == "" {
+		return ""
+	}
+	for _, path := range []string{
+		filepath.Join(d.LocalBinDir, d.BinName()+v.String()),
+		filepath.Join(d.LocalBinDir, v.String(), d.BinName()),
+	} {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// sourceURL returns the go-getter URL of the release archive of version v
+// for this OS and architecture.
+func (d DownloadConfig) sourceURL(v *version.Version) string {
+	var prefix string
+	if d.Distribution == OpenTofuDistribution {
+		prefix = fmt.Sprintf("%s/v%s/tofu_%s", d.URL, v.String(), v.String())
+	} else {
+		prefix = fmt.Sprintf("%s/terraform/%s/terraform_%s", d.URL, v.String(), v.String())
+	}
+	src := fmt.Sprintf("%s_%s_%s.zip", prefix, runtime.GOOS, runtime.GOARCH)
+	var params []string
+	if d.Distribution == OpenTofuDistribution {
+		// OpenTofu archives also contain the license and readme so we
+		// extract the binary ourselves.
+		params = append(params, "archive=false")
+	}
+	if d.Checksum != ChecksumSkip {
+		params = append(params, fmt.Sprintf("checksum=file:%s_SHA256SUMS", prefix))
+	}
+	for i, p := range params {
+		if i == 0 {
+			src += "?" + p
+		} else {
+			src += "&" + p
+		}
+	}
+	return src
+}
+
+// download downloads version v to dest with dl.
+func (d DownloadConfig) download(dl Downloader, v *version.Version, dest string) error {
+	src := d.sourceURL(v)
+	if d.Distribution != OpenTofuDistribution {
+		return errors.Wrapf(dl.GetFile(dest, src), "downloading terraform version %s at %q", v.String(), src)
+	}
+
+	archive := dest + ".zip"
+	defer os.Remove(archive) // nolint: errcheck
+	if err := dl.GetFile(archive, src); err != nil {
+		return errors.Wrapf(err, "downloading opentofu version %s at %q", v.String(), src)
+	}
+	return errors.Wrapf(extractBin(archive, d.BinName(), dest), "extracting opentofu version %s", v.String())
+}
+
+// extractBin writes the file named bin in the zip archive to dest.
+func extractBin(archive string, bin string, dest string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+	for _, f := range r.File {
+		if f.Name != bin {
+			continue
+		}
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close() // nolint: errcheck
+
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755) // nolint: gosec
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, src); err != nil { // nolint: gosec
+			out.Close() // nolint: errcheck
+			return err
+		}
+		return out.Close()
+	}
+	return fmt.Errorf("%s not found in %s", bin, archive)
+}
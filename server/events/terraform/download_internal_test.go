@@ -0,0 +1,105 @@
+package terraform
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/go-getter"
+	version "github.com/hashicorp/go-version"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDownloadConfig_SourceURL(t *testing.T) {
+	v := version.Must(version.NewVersion("1.6.0"))
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	cases := []struct {
+		config DownloadConfig
+		exp    string
+	}{
+		{
+			DownloadConfig{URL: "https://mirror.example.com"},
+			fmt.Sprintf("https://mirror.example.com/terraform/1.6.0/terraform_1.6.0_%s.zip?checksum=file:https://mirror.example.com/terraform/1.6.0/terraform_1.6.0_SHA256SUMS", platform),
+		},
+		{
+			DownloadConfig{URL: "https://mirror.example.com", Checksum: ChecksumSkip},
+			fmt.Sprintf("https://mirror.example.com/terraform/1.6.0/terraform_1.6.0_%s.zip", platform),
+		},
+		{
+			DownloadConfig{URL: "https://mirror.example.com", Distribution: OpenTofuDistribution},
+			fmt.Sprintf("https://mirror.example.com/v1.6.0/tofu_1.6.0_%s.zip?archive=false&checksum=file:https://mirror.example.com/v1.6.0/tofu_1.6.0_SHA256SUMS", platform),
+		},
+		{
+			DownloadConfig{URL: "https://mirror.example.com", Distribution: OpenTofuDistribution, Checksum: ChecksumSkip},
+			fmt.Sprintf("https://mirror.example.com/v1.6.0/tofu_1.6.0_%s.zip?archive=false", platform),
+		},
+	}
+	for _, c := range cases {
+		Equals(t, c.exp, c.config.sourceURL(v))
+	}
+}
+
+func TestDownloadConfig_LocalBin(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "terraform0.12.24"), nil, 0700))
+	Ok(t, os.MkdirAll(filepath.Join(tmp, "1.6.0"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "1.6.0", "tofu"), nil, 0700))
+
+	Equals(t, filepath.Join(tmp, "terraform0.12.24"), DownloadConfig{LocalBinDir: tmp}.localBin(version.Must(version.NewVersion("0.12.24"))))
+	Equals(t, "", DownloadConfig{LocalBinDir: tmp}.localBin(version.Must(version.NewVersion("1.6.0"))))
+	Equals(t, filepath.Join(tmp, "1.6.0", "tofu"), DownloadConfig{LocalBinDir: tmp, Distribution: OpenTofuDistribution}.localBin(version.Must(version.NewVersion("1.6.0"))))
+	Equals(t, "", DownloadConfig{}.localBin(version.Must(version.NewVersion("0.12.24"))))
+}
+
+// zipDownloader "downloads" a zip of files.
+type zipDownloader struct {
+	files map[string]string
+	src   string
+}
+
+func (z *zipDownloader) GetFile(dst, src string, opts ...getter.ClientOption) error {
+	z.src = src
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	w := zip.NewWriter(f)
+	for name, contents := range z.files {
+		fw, err := w.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write([]byte(contents)); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func TestDownloadConfig_DownloadOpenTofu(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	dl := &zipDownloader{files: map[string]string{"LICENSE": "license", "tofu": "binary"}}
+	config := DownloadConfig{URL: "https://mirror.example.com", Distribution: OpenTofuDistribution}
+	v := version.Must(version.NewVersion("1.6.0"))
+	dest := filepath.Join(tmp, "tofu1.6.0")
+
+	Ok(t, config.download(dl, v, dest))
+	Equals(t, config.sourceURL(v), dl.src)
+	contents, err := ioutil.ReadFile(dest)
+	Ok(t, err)
+	Equals(t, "binary", string(contents))
+	_, err = os.Stat(dest + ".zip")
+	Assert(t, os.IsNotExist(err), "expected archive to be deleted")
+
+	dl.files = map[string]string{"LICENSE": "license"}
+	ErrContains(t, "tofu not found in", config.download(dl, v, dest))
+}
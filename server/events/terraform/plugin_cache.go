@@ -0,0 +1,370 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PluginCacheCleanInterval is how often CleanEvery is called by the server.
+const PluginCacheCleanInterval = 24 * time.Hour
+
+// legacyPlatformRegex matches the dirs of the plugin cache layout used by
+// Terraform < 0.13, ex. linux_amd64. Since 0.13 the top level dirs are
+// registry hostnames.
+var legacyPlatformRegex = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// legacyPluginRegex matches the provider files inside the legacy platform
+// dirs, ex. terraform-provider-aws_v2.70.0_x4.
+var legacyPluginRegex = regexp.MustCompile(`^terraform-provider-([^_]+)_v([^_]+?)(_x\d+)?(\.exe)?$`)
+
+// PluginCache guards the TF_PLUGIN_CACHE_DIR that every terraform command
+// shares. Terraform doesn't lock the cache so two inits installing the same
+// provider at the same time can leave a corrupt binary in it. Inits lock the
+// providers they install so inits of projects that use different providers
+// still run concurrently.
+type PluginCache struct {
+	// Dir is the plugin cache dir.
+	Dir string
+	// KeepVersions is how many of the newest versions of each provider are
+	// kept by Clean. If 0, nothing is deleted.
+	KeepVersions int
+	// LinkDir is searched for symlinks into the cache by Clean. Terraform
+	// links the plugins of initialized dirs to the cache so versions that
+	// are linked to aren't deleted.
+	LinkDir string
+
+	// lock is held for reading by inits whose providers are known and for
+	// writing by Clean and inits whose providers aren't known.
+	lock      sync.RWMutex
+	mu        sync.Mutex
+	providers map[string]*sync.Mutex
+}
+
+// LockInit locks the providers that `terraform init` in path will install and
+// returns a func that unlocks them. If they can't be determined, ex. because
+// a module hasn't been downloaded yet, the whole cache is locked.
+func (p *PluginCache) LockInit(log *logging.SimpleLogger, path string) func() {
+	providers, err := initProviders(path)
+	if err != nil {
+		log.Debug("locking the whole plugin cache since the providers of %q can't be determined: %s", path, err)
+		p.lock.Lock()
+		return p.lock.Unlock
+	}
+
+	p.lock.RLock()
+	// Locks are always taken in the same order so inits can't deadlock.
+	sort.Strings(providers)
+	var locks []*sync.Mutex
+	for _, name := range providers {
+		m := p.providerLock(name)
+		m.Lock()
+		locks = append(locks, m)
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+		p.lock.RUnlock()
+	}
+}
+
+// CleanEvery calls Clean now and then every interval. It never returns.
+func (p *PluginCache) CleanEvery(log *logging.SimpleLogger, interval time.Duration) {
+	for {
+		if err := p.Clean(log); err != nil {
+			log.Err("cleaning up plugin cache: %s", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Clean deletes all but the KeepVersions newest versions of each provider
+// in the cache, except for versions that are linked to from LinkDir. It
+// waits for running inits to finish.
+func (p *PluginCache) Clean(log *logging.SimpleLogger) error {
+	if p.KeepVersions <= 0 {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	linked, err := p.linkedPaths()
+	if err != nil {
+		return errors.Wrapf(err, "searching %q for links to the plugin cache", p.LinkDir)
+	}
+	entries, err := ioutil.ReadDir(p.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(p.Dir, e.Name())
+		if legacyPlatformRegex.MatchString(e.Name()) {
+			err = p.cleanLegacyPlatform(log, dir, linked)
+		} else {
+			err = p.cleanHostname(log, dir, linked)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedVersion is a version of a provider in the cache and the paths of its
+// files.
+type cachedVersion struct {
+	version *version.Version
+	paths   []string
+}
+
+// cleanLegacyPlatform cleans a dir of the Terraform < 0.13 layout which
+// contains the files of all providers, ex.
+// linux_amd64/terraform-provider-aws_v2.70.0_x4.
+func (p *PluginCache) cleanLegacyPlatform(log *logging.SimpleLogger, dir string, linked []string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	versions := make(map[string]map[string]*cachedVersion)
+	for _, e := range entries {
+		match := legacyPluginRegex.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		v, err := version.NewVersion(match[2])
+		if err != nil {
+			continue
+		}
+		name := match[1]
+		if versions[name] == nil {
+			versions[name] = make(map[string]*cachedVersion)
+		}
+		cv, ok := versions[name][v.String()]
+		if !ok {
+			cv = &cachedVersion{version: v}
+			versions[name][v.String()] = cv
+		}
+		cv.paths = append(cv.paths, filepath.Join(dir, e.Name()))
+	}
+	for name, byVersion := range versions {
+		var cvs []cachedVersion
+		for _, cv := range byVersion {
+			cvs = append(cvs, *cv)
+		}
+		p.deleteOldVersions(log, fmt.Sprintf("%s/%s", filepath.Base(dir), name), cvs, linked)
+	}
+	return nil
+}
+
+// cleanHostname cleans a dir of the Terraform >= 0.13 layout which contains a
+// dir for each version of each provider, ex.
+// registry.terraform.io/hashicorp/aws/3.0.0/linux_amd64.
+func (p *PluginCache) cleanHostname(log *logging.SimpleLogger, dir string, linked []string) error {
+	typeDirs, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if err != nil {
+		return err
+	}
+	for _, typeDir := range typeDirs {
+		entries, err := ioutil.ReadDir(typeDir)
+		if err != nil {
+			continue
+		}
+		var cvs []cachedVersion
+		for _, e := range entries {
+			v, err := version.NewVersion(e.Name())
+			if !e.IsDir() || err != nil {
+				continue
+			}
+			cvs = append(cvs, cachedVersion{version: v, paths: []string{filepath.Join(typeDir, e.Name())}})
+		}
+		rel, _ := filepath.Rel(p.Dir, typeDir)
+		p.deleteOldVersions(log, filepath.ToSlash(rel), cvs, linked)
+	}
+	return nil
+}
+
+// deleteOldVersions deletes all but the KeepVersions newest of the versions
+// of provider unless they're linked to.
+func (p *PluginCache) deleteOldVersions(log *logging.SimpleLogger, provider string, versions []cachedVersion, linked []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].version.GreaterThan(versions[j].version)
+	})
+	for i := p.KeepVersions; i < len(versions); i++ {
+		v := versions[i]
+		if isLinked(v.paths, linked) {
+			log.Debug("not deleting %s %s from plugin cache since it's in use", provider, v.version)
+			continue
+		}
+		log.Info("deleting %s %s from plugin cache", provider, v.version)
+		for _, path := range v.paths {
+			if err := os.RemoveAll(path); err != nil {
+				log.Warn("unable to delete %q: %s", path, err)
+			}
+		}
+	}
+}
+
+// linkedPaths returns the targets of all the symlinks in LinkDir that point
+// into the cache.
+func (p *PluginCache) linkedPaths() ([]string, error) {
+	if p.LinkDir == "" {
+		return nil, nil
+	}
+	var linked []string
+	err := filepath.Walk(p.LinkDir, func(path string, info os.FileInfo, err error) error {
+		// Dirs can be deleted while we walk, ex. when pull requests are
+		// closed.
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		target = filepath.Clean(target)
+		if strings.HasPrefix(target, filepath.Clean(p.Dir)+string(filepath.Separator)) {
+			linked = append(linked, target)
+		}
+		return nil
+	})
+	return linked, err
+}
+
+// isLinked returns true if any of the linked targets is one of paths or
+// inside of them.
+func isLinked(paths []string, linked []string) bool {
+	for _, target := range linked {
+		for _, path := range paths {
+			if target == path || strings.HasPrefix(target, path+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *PluginCache) providerLock(name string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.providers == nil {
+		p.providers = make(map[string]*sync.Mutex)
+	}
+	m, ok := p.providers[name]
+	if !ok {
+		m = &sync.Mutex{}
+		p.providers[name] = m
+	}
+	return m
+}
+
+// moduleManifest is the .terraform/modules/modules.json file that init
+// writes with the dirs it downloaded modules into.
+type moduleManifest struct {
+	Modules []struct {
+		Key string
+		Dir string
+	}
+}
+
+// initProviders returns the names of the providers used by the module in
+// path and the modules it calls. Modules that aren't local are looked up in
+// the manifest of a previous init and if they aren't there, an error is
+// returned.
+func initProviders(path string) ([]string, error) {
+	moduleDirs := make(map[string]string)
+	contents, err := ioutil.ReadFile(filepath.Join(path, ".terraform", "modules", "modules.json")) // nolint: gosec
+	if err == nil {
+		var manifest moduleManifest
+		if err := json.Unmarshal(contents, &manifest); err != nil {
+			return nil, errors.Wrap(err, "parsing module manifest")
+		}
+		for _, m := range manifest.Modules {
+			dir := m.Dir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(path, dir)
+			}
+			moduleDirs[m.Key] = dir
+		}
+	}
+
+	names := make(map[string]bool)
+	loaded := make(map[string]bool)
+	var load func(key string, dir string) error
+	load = func(key string, dir string) error {
+		// Modules called more than once use the same providers each time.
+		if loaded[dir] {
+			return nil
+		}
+		loaded[dir] = true
+		module, diags := tfconfig.LoadModule(dir)
+		if diags.HasErrors() {
+			return diags.Err()
+		}
+		for name := range module.RequiredProviders {
+			names[name] = true
+		}
+		for _, r := range module.ManagedResources {
+			names[r.Provider.Name] = true
+		}
+		for _, r := range module.DataResources {
+			names[r.Provider.Name] = true
+		}
+		for name, call := range module.ModuleCalls {
+			childKey := name
+			if key != "" {
+				childKey = key + "." + name
+			}
+			childDir, ok := moduleDirs[childKey]
+			if !ok {
+				if !strings.HasPrefix(call.Source, "./") && !strings.HasPrefix(call.Source, "../") {
+					return fmt.Errorf("module %q hasn't been downloaded", childKey)
+				}
+				childDir = filepath.Join(dir, call.Source)
+			}
+			if err := load(childKey, childDir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := load("", path); err != nil {
+		return nil, err
+	}
+
+	var providers []string
+	for name := range names {
+		providers = append(providers, name)
+	}
+	return providers, nil
+}
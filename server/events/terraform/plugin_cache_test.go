@@ -0,0 +1,137 @@
+package terraform_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// writeFiles writes each of files' contents to its path under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for path, contents := range files {
+		path = filepath.Join(dir, path)
+		Ok(t, os.MkdirAll(filepath.Dir(path), 0700))
+		Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	}
+}
+
+// locked returns true if lockInit is still blocked after a short wait. Once
+// it's unblocked, its unlock func is called.
+func locked(lockInit func() func()) bool {
+	acquired := make(chan func(), 1)
+	go func() { acquired <- lockInit() }()
+	select {
+	case unlock := <-acquired:
+		unlock()
+		return false
+	case <-time.After(100 * time.Millisecond):
+		go func() { (<-acquired)() }()
+		return true
+	}
+}
+
+func TestPluginCache_LockInit(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFiles(t, tmp, map[string]string{
+		"aws/main.tf":    `resource "aws_instance" "a" {}`,
+		"aws2/main.tf":   `module "local" { source = "../modules/vpc" }`,
+		"google/main.tf": `data "google_project" "p" {}`,
+		// A module from the registry that was downloaded by a previous init.
+		"downloaded/main.tf":                         `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+		"downloaded/.terraform/modules/modules.json": `{"Modules":[{"Key":"","Dir":"."},{"Key":"vpc","Dir":".terraform/modules/vpc"}]}`,
+		"downloaded/.terraform/modules/vpc/main.tf":  `resource "aws_vpc" "v" {}`,
+		"remote/main.tf":                             `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+		"modules/vpc/main.tf":                        `resource "aws_vpc" "v" {}`,
+	})
+	log := logging.NewNoopLogger()
+	cache := &terraform.PluginCache{Dir: filepath.Join(tmp, "cache")}
+	lockInit := func(dir string) func() func() {
+		return func() func() { return cache.LockInit(log, filepath.Join(tmp, dir)) }
+	}
+
+	unlock := cache.LockInit(log, filepath.Join(tmp, "aws"))
+	Assert(t, !locked(lockInit("google")), "expected init with other providers not to wait")
+	Assert(t, locked(lockInit("aws2")), "expected init of local module with the same provider to wait")
+	Assert(t, locked(lockInit("downloaded")), "expected init of downloaded module with the same provider to wait")
+	// The providers of modules that haven't been downloaded aren't known
+	// so the init waits for all other inits.
+	Assert(t, locked(lockInit("remote")), "expected init with unknown providers to wait")
+	unlock()
+
+	unlock = cache.LockInit(log, filepath.Join(tmp, "remote"))
+	Assert(t, locked(lockInit("google")), "expected init to wait for init with unknown providers")
+	unlock()
+}
+
+func TestPluginCache_Clean(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	cacheDir := filepath.Join(tmp, "cache")
+	writeFiles(t, cacheDir, map[string]string{
+		// Terraform < 0.13 layout.
+		"linux_amd64/terraform-provider-aws_v2.9.0_x4":    "",
+		"linux_amd64/terraform-provider-aws_v2.10.0_x4":   "",
+		"linux_amd64/terraform-provider-aws_v2.70.0_x4":   "",
+		"linux_amd64/terraform-provider-null_v2.1.2_x4":   "",
+		"linux_amd64/terraform-provider-google_v3.0.0_x5": "",
+		"linux_amd64/terraform-provider-google_v3.1.0_x5": "",
+		"linux_amd64/terraform-provider-google_v3.2.0_x5": "",
+		// Terraform >= 0.13 layout.
+		"registry.terraform.io/hashicorp/aws/3.0.0/linux_amd64/terraform-provider-aws_v3.0.0_x5":   "",
+		"registry.terraform.io/hashicorp/aws/3.1.0/linux_amd64/terraform-provider-aws_v3.1.0_x5":   "",
+		"registry.terraform.io/hashicorp/aws/3.10.0/linux_amd64/terraform-provider-aws_v3.10.0_x5": "",
+	})
+	// A project that was initialized with an old version of google links to
+	// it.
+	linkDir := filepath.Join(tmp, "repos")
+	pluginsDir := filepath.Join(linkDir, "owner", "repo", "1", "default", ".terraform", "plugins", "linux_amd64")
+	Ok(t, os.MkdirAll(pluginsDir, 0700))
+	Ok(t, os.Symlink(filepath.Join(cacheDir, "linux_amd64", "terraform-provider-google_v3.0.0_x5"), filepath.Join(pluginsDir, "terraform-provider-google_v3.0.0_x5")))
+
+	cache := &terraform.PluginCache{
+		Dir:          cacheDir,
+		KeepVersions: 2,
+		LinkDir:      linkDir,
+	}
+	Ok(t, cache.Clean(logging.NewNoopLogger()))
+
+	var remaining []string
+	Ok(t, filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(cacheDir, path)
+			remaining = append(remaining, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	Equals(t, []string{
+		"linux_amd64/terraform-provider-aws_v2.10.0_x4",
+		"linux_amd64/terraform-provider-aws_v2.70.0_x4",
+		"linux_amd64/terraform-provider-google_v3.0.0_x5",
+		"linux_amd64/terraform-provider-google_v3.1.0_x5",
+		"linux_amd64/terraform-provider-google_v3.2.0_x5",
+		"linux_amd64/terraform-provider-null_v2.1.2_x4",
+		"registry.terraform.io/hashicorp/aws/3.1.0/linux_amd64/terraform-provider-aws_v3.1.0_x5",
+		"registry.terraform.io/hashicorp/aws/3.10.0/linux_amd64/terraform-provider-aws_v3.10.0_x5",
+	}, remaining)
+}
+
+// Test that nothing is deleted if no number of versions to keep is set.
+func TestPluginCache_CleanKeepsAll(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	writeFiles(t, tmp, map[string]string{
+		"linux_amd64/terraform-provider-aws_v2.9.0_x4":  "",
+		"linux_amd64/terraform-provider-aws_v2.10.0_x4": "",
+	})
+	cache := &terraform.PluginCache{Dir: tmp}
+	Ok(t, cache.Clean(logging.NewNoopLogger()))
+	_, err := os.Stat(filepath.Join(tmp, "linux_amd64", "terraform-provider-aws_v2.9.0_x4"))
+	Ok(t, err)
+}
@@ -19,11 +19,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"strings"
 	"sync"
 
@@ -31,6 +31,7 @@ import (
 	"github.com/hashicorp/go-version"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/execution"
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
@@ -56,8 +57,9 @@ type DefaultClient struct {
 	// with another binary, ex. echo.
 	overrideTF string
 	// downloader downloads terraform versions.
-	downloader      Downloader
-	downloadBaseURL string
+	downloader Downloader
+	// download configures where versions are found and downloaded from.
+	download DownloadConfig
 	// versions maps from the string representation of a tf version (ex. 0.11.10)
 	// to the absolute path of that binary on disk (if it exists).
 	// Use versionsLock to control access.
@@ -65,6 +67,18 @@ type DefaultClient struct {
 
 	// versionsLock is used to ensure versions isn't being concurrently written to.
 	versionsLock *sync.Mutex
+
+	// ExecutionBackend runs terraform commands. If nil, they're run on the
+	// server.
+	ExecutionBackend execution.Backend
+	// PluginCache, if set, is locked by init commands so they don't install
+	// the same provider into terraformPluginCacheDir at the same time.
+	PluginCache *PluginCache
+
+	// distributions are the clients ForDistribution has returned, keyed by
+	// distribution. Use distributionsLock to control access.
+	distributions     map[string]*DefaultClient
+	distributionsLock sync.Mutex
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_downloader.go Downloader
@@ -89,7 +103,7 @@ const (
 //
 //     Terraform v0.11.10
 //	   => 0.11.10
-var versionRegex = regexp.MustCompile("Terraform v(.*?)(\\s.*)?\n")
+var versionRegex = regexp.MustCompile("(?:Terraform|OpenTofu) v(.*?)(\\s.*)?\n")
 
 // NewClient constructs a terraform client.
 // tfeToken is an optional terraform enterprise token.
@@ -97,6 +111,8 @@ var versionRegex = regexp.MustCompile("Terraform v(.*?)(\\s.*)?\n")
 // a specific version is set.
 // defaultVersionFlagName is the name of the flag that sets the default terraform
 // version.
+// download configures where missing terraform versions are found and
+// downloaded from.
 // tfDownloader is used to download terraform versions.
 // Will asynchronously download the required version if it doesn't exist already.
 func NewClient(
@@ -106,16 +122,16 @@ func NewClient(
 	tfeHostname string,
 	defaultVersionStr string,
 	defaultVersionFlagName string,
-	tfDownloadURL string,
+	download DownloadConfig,
 	tfDownloader Downloader) (*DefaultClient, error) {
 	var finalDefaultVersion *version.Version
 	var localVersion *version.Version
 	versions := make(map[string]string)
 	var versionsLock sync.Mutex
 
-	localPath, err := exec.LookPath("terraform")
+	localPath, err := exec.LookPath(download.BinName())
 	if err != nil && defaultVersionStr == "" {
-		return nil, fmt.Errorf("terraform not found in $PATH. Set --%s or download terraform from https://www.terraform.io/downloads.html", defaultVersionFlagName)
+		return nil, fmt.Errorf("%s not found in $PATH. Set --%s or download terraform from https://www.terraform.io/downloads.html", download.BinName(), defaultVersionFlagName)
 	}
 	if err == nil {
 		localVersion, err = getVersion(localPath)
@@ -145,7 +161,7 @@ func NewClient(
 			// Since ensureVersion might end up downloading terraform,
 			// we call it asynchronously so as to not delay server startup.
 			versionsLock.Lock()
-			_, err := ensureVersion(log, tfDownloader, versions, defaultVersion, binDir, tfDownloadURL)
+			_, err := ensureVersion(log, tfDownloader, versions, defaultVersion, binDir, download)
 			versionsLock.Unlock()
 			if err != nil {
 				log.Err("could not download terraform %s: %s", defaultVersion.String(), err)
@@ -176,9 +192,10 @@ func NewClient(
 		terraformPluginCacheDir: cacheDir,
 		binDir:                  binDir,
 		downloader:              tfDownloader,
-		downloadBaseURL:         tfDownloadURL,
+		download:                download,
 		versionsLock:            &versionsLock,
 		versions:                versions,
+		PluginCache:             &PluginCache{Dir: cacheDir},
 	}, nil
 }
 
@@ -188,6 +205,47 @@ func (c *DefaultClient) DefaultVersion() *version.Version {
 	return c.defaultVersion
 }
 
+// ForDistribution returns a client that runs distribution, ex. OpenTofu,
+// instead of c's. Missing versions are downloaded from the distribution's
+// official releases into the same bin dir. Its default version is c's. If
+// distribution is empty or c's, c is returned.
+func (c *DefaultClient) ForDistribution(distribution string) *DefaultClient {
+	current := c.download.Distribution
+	if current == "" {
+		current = TerraformDistribution
+	}
+	if distribution == "" || distribution == current {
+		return c
+	}
+
+	c.distributionsLock.Lock()
+	defer c.distributionsLock.Unlock()
+	if client, ok := c.distributions[distribution]; ok {
+		return client
+	}
+	client := &DefaultClient{
+		defaultVersion:          c.defaultVersion,
+		terraformPluginCacheDir: c.terraformPluginCacheDir,
+		binDir:                  c.binDir,
+		overrideTF:              c.overrideTF,
+		downloader:              c.downloader,
+		download: DownloadConfig{
+			URL:          ReleasesURL(distribution),
+			Distribution: distribution,
+			LocalBinDir:  c.download.LocalBinDir,
+		},
+		versions:         make(map[string]string),
+		versionsLock:     c.versionsLock,
+		ExecutionBackend: c.ExecutionBackend,
+		PluginCache:      c.PluginCache,
+	}
+	if c.distributions == nil {
+		c.distributions = make(map[string]*DefaultClient)
+	}
+	c.distributions[distribution] = client
+	return client
+}
+
 // TerraformBinDir returns the directory where we download Terraform binaries.
 func (c *DefaultClient) TerraformBinDir() string {
 	return c.binDir
@@ -195,13 +253,14 @@ func (c *DefaultClient) TerraformBinDir() string {
 
 // See Client.EnsureVersion.
 func (c *DefaultClient) EnsureVersion(log *logging.SimpleLogger, v *version.Version) error {
+	log = log.ForSubsystem(logging.TerraformSubsystem)
 	if v == nil {
 		v = c.defaultVersion
 	}
 
 	var err error
 	c.versionsLock.Lock()
-	_, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.downloadBaseURL)
+	_, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.download)
 	c.versionsLock.Unlock()
 	if err != nil {
 		return err
@@ -212,6 +271,7 @@ func (c *DefaultClient) EnsureVersion(log *logging.SimpleLogger, v *version.Vers
 
 // See Client.RunCommandWithVersion.
 func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (string, error) {
+	log = log.ForSubsystem(logging.TerraformSubsystem)
 	tfCmd, cmd, err := c.prepCmd(log, v, workspace, path, args)
 	if err != nil {
 		return "", err
@@ -221,7 +281,11 @@ func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 		envVars = append(envVars, fmt.Sprintf("%s=%s", key, val))
 	}
 	cmd.Env = envVars
-	out, err := cmd.CombinedOutput()
+	if c.PluginCache != nil && len(args) > 0 && args[0] == "init" {
+		unlock := c.PluginCache.LockInit(log, path)
+		defer unlock()
+	}
+	out, err := execution.CombinedOutput(c.ExecutionBackend, log, cmd)
 	if err != nil {
 		err = errors.Wrapf(err, "running %q in %q", tfCmd, path)
 		log.Err(err.Error())
@@ -246,7 +310,7 @@ func (c *DefaultClient) prepCmd(log *logging.SimpleLogger, v *version.Version, w
 	} else {
 		var err error
 		c.versionsLock.Lock()
-		binPath, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.downloadBaseURL)
+		binPath, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.download)
 		c.versionsLock.Unlock()
 		if err != nil {
 			return "", nil, err
@@ -289,6 +353,7 @@ type Line struct {
 // If any error is passed on the out channel, there will be no
 // further output (so callers are free to exit).
 func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (chan<- string, <-chan Line) {
+	log = log.ForSubsystem(logging.TerraformSubsystem)
 	outCh := make(chan Line)
 	inCh := make(chan string)
 
@@ -316,6 +381,7 @@ func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string,
 			envVars = append(envVars, fmt.Sprintf("%s=%s", key, val))
 		}
 		cmd.Env = envVars
+		execution.PrepareStop(cmd)
 
 		log.Debug("starting %q in %q", tfCmd, path)
 		err = cmd.Start()
@@ -325,6 +391,7 @@ func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string,
 			outCh <- Line{Err: err}
 			return
 		}
+		stopCmd := execution.WatchStop(cmd)
 
 		// If we get anything on inCh, write it to stdin.
 		// This function will exit when inCh is closed which we do in our defer.
@@ -364,6 +431,9 @@ func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string,
 
 		// Wait for the command to complete.
 		err = cmd.Wait()
+		if stopErr := stopCmd(); stopErr != nil {
+			err = stopErr
+		}
 
 		// We're done now. Send an error if there was one.
 		if err != nil {
@@ -391,7 +461,7 @@ func MustConstraint(v string) version.Constraints {
 
 // ensureVersion returns the path to a terraform binary of version v.
 // It will download this version if we don't have it.
-func ensureVersion(log *logging.SimpleLogger, dl Downloader, versions map[string]string, v *version.Version, binDir string, downloadURL string) (string, error) {
+func ensureVersion(log *logging.SimpleLogger, dl Downloader, versions map[string]string, v *version.Version, binDir string, download DownloadConfig) (string, error) {
 	if binPath, ok := versions[v.String()]; ok {
 		return binPath, nil
 	}
@@ -399,12 +469,18 @@ func ensureVersion(log *logging.SimpleLogger, dl Downloader, versions map[string
 	// This tf version might not yet be in the versions map even though it
 	// exists on disk. This would happen if users have manually added
 	// terraform{version} binaries. In this case we don't want to re-download.
-	binFile := "terraform" + v.String()
+	binFile := download.BinName() + v.String()
 	if binPath, err := exec.LookPath(binFile); err == nil {
 		versions[v.String()] = binPath
 		return binPath, nil
 	}
 
+	// Or it might be in the pre-populated directory of binaries.
+	if binPath := download.localBin(v); binPath != "" {
+		versions[v.String()] = binPath
+		return binPath, nil
+	}
+
 	// The version might also not be in the versions map if it's in our bin dir.
 	// This could happen if Atlantis was restarted without losing its disk.
 	dest := filepath.Join(binDir, binFile)
@@ -412,13 +488,9 @@ func ensureVersion(log *logging.SimpleLogger, dl Downloader, versions map[string
 		versions[v.String()] = dest
 		return dest, nil
 	}
-	log.Info("could not find terraform version %s in PATH or %s, downloading from %s", v.String(), binDir, downloadURL)
-	urlPrefix := fmt.Sprintf("%s/terraform/%s/terraform_%s", downloadURL, v.String(), v.String())
-	binURL := fmt.Sprintf("%s_%s_%s.zip", urlPrefix, runtime.GOOS, runtime.GOARCH)
-	checksumURL := fmt.Sprintf("%s_SHA256SUMS", urlPrefix)
-	fullSrcURL := fmt.Sprintf("%s?checksum=file:%s", binURL, checksumURL)
-	if err := dl.GetFile(dest, fullSrcURL); err != nil {
-		return "", errors.Wrapf(err, "downloading terraform version %s at %q", v.String(), fullSrcURL)
+	log.Info("could not find terraform version %s in PATH or %s, downloading from %s", v.String(), binDir, download.URL)
+	if err := download.download(dl, v, dest); err != nil {
+		return "", err
 	}
 
 	log.Info("downloaded terraform %s to %s", v.String(), dest)
@@ -476,9 +548,31 @@ var rcFileContents = `credentials "%s" {
   token = %q
 }`
 
-type DefaultDownloader struct{}
+// DefaultDownloader downloads terraform versions with go-getter.
+type DefaultDownloader struct {
+	// HTTPClient, if set, is used for HTTP and HTTPS downloads instead of
+	// go-getter's default client.
+	HTTPClient *http.Client
+}
 
 // See go-getter.GetFile.
 func (d *DefaultDownloader) GetFile(dst, src string, opts ...getter.ClientOption) error {
+	if d.HTTPClient != nil {
+		opts = append(opts, d.useHTTPClient)
+	}
 	return getter.GetFile(dst, src, opts...)
 }
+
+// useHTTPClient configures c to download over HTTP and HTTPS with
+// d.HTTPClient.
+func (d *DefaultDownloader) useHTTPClient(c *getter.Client) error {
+	getters := make(map[string]getter.Getter, len(getter.Getters))
+	for scheme, g := range getter.Getters {
+		getters[scheme] = g
+	}
+	httpGetter := &getter.HttpGetter{Netrc: true, Client: d.HTTPClient}
+	getters["http"] = httpGetter
+	getters["https"] = httpGetter
+	c.Getters = getters
+	return nil
+}
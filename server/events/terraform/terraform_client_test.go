@@ -68,7 +68,7 @@ is 0.11.13. You can update by downloading from www.terraform.io/downloads.html
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(nil, tmp, "", "", "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	c, err := terraform.NewClient(nil, tmp, "", "", "", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -96,7 +96,7 @@ is 0.11.13. You can update by downloading from www.terraform.io/downloads.html
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -116,7 +116,7 @@ func TestNewClient_NoTF(t *testing.T) {
 	// Set PATH to only include our empty directory.
 	defer tempSetEnv(t, "PATH", tmp)()
 
-	_, err := terraform.NewClient(nil, tmp, "", "", "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	_, err := terraform.NewClient(nil, tmp, "", "", "", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	ErrEquals(t, "terraform not found in $PATH. Set --default-tf-version or download terraform from https://www.terraform.io/downloads.html", err)
 }
 
@@ -133,7 +133,7 @@ func TestNewClient_DefaultTFFlagInPath(t *testing.T) {
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -157,7 +157,7 @@ func TestNewClient_DefaultTFFlagInBinDir(t *testing.T) {
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(logging.NewNoopLogger(), tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	c, err := terraform.NewClient(logging.NewNoopLogger(), tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -183,7 +183,7 @@ func TestNewClient_DefaultTFFlagDownload(t *testing.T) {
 		err := ioutil.WriteFile(params[0].(string), []byte("#!/bin/sh\necho '\nTerraform v0.11.10\n'"), 0755)
 		return []pegomock.ReturnValue{err}
 	})
-	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, "https://my-mirror.releases.mycompany.com", mockDownloader)
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: "https://my-mirror.releases.mycompany.com"}, mockDownloader)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -207,7 +207,7 @@ func TestNewClient_DefaultTFFlagDownload(t *testing.T) {
 func TestNewClient_BadVersion(t *testing.T) {
 	tmp, cleanup := TempDir(t)
 	defer cleanup()
-	_, err := terraform.NewClient(nil, tmp, "", "", "malformed", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil)
+	_, err := terraform.NewClient(nil, tmp, "", "", "malformed", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, nil)
 	ErrEquals(t, "Malformed version: malformed", err)
 }
 
@@ -230,7 +230,7 @@ func TestRunCommandWithVersion_DLsTF(t *testing.T) {
 		return []pegomock.ReturnValue{err}
 	})
 
-	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, mockDownloader)
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, mockDownloader)
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
@@ -249,7 +249,7 @@ func TestEnsureVersion_downloaded(t *testing.T) {
 
 	mockDownloader := mocks.NewMockDownloader()
 
-	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, mockDownloader)
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, mockDownloader)
 	Ok(t, err)
 
 	Equals(t, "0.11.10", c.DefaultVersion().String())
@@ -270,6 +270,33 @@ func TestEnsureVersion_downloaded(t *testing.T) {
 	mockDownloader.VerifyWasCalledEventually(Once(), 2*time.Second).GetFile(filepath.Join(tmp, "bin", "terraform99.99.99"), expURL)
 }
 
+// Test that ForDistribution returns a client that runs another distribution's
+// binaries from the same bin dir.
+func TestDefaultClient_ForDistribution(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	binDir := filepath.Join(tmp, "bin")
+	Ok(t, os.MkdirAll(binDir, 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(binDir, "terraform0.11.10"), []byte("#!/bin/sh\necho 'Terraform v0.11.10'"), 0755))
+	Ok(t, ioutil.WriteFile(filepath.Join(binDir, "tofu1.6.0"), []byte("#!/bin/sh\necho 'OpenTofu v1.6.0'"), 0755))
+
+	c, err := terraform.NewClient(nil, tmp, "", "", "0.11.10", cmd.DefaultTFVersionFlag, terraform.DownloadConfig{URL: cmd.DefaultTFDownloadURL}, mocks.NewMockDownloader())
+	Ok(t, err)
+	Assert(t, c.ForDistribution("") == c, "exp the same client for no distribution")
+	Assert(t, c.ForDistribution(terraform.TerraformDistribution) == c, "exp the same client for the client's distribution")
+
+	tofu := c.ForDistribution(terraform.OpenTofuDistribution)
+	Assert(t, tofu != c, "exp a new client for another distribution")
+	Assert(t, c.ForDistribution(terraform.OpenTofuDistribution) == tofu, "exp the client to be reused")
+	output, err := tofu.RunCommandWithVersion(nil, tmp, nil, map[string]string{}, version.Must(version.NewVersion("1.6.0")), "")
+	Ok(t, err)
+	Equals(t, "OpenTofu v1.6.0\n", output)
+	output, err = c.RunCommandWithVersion(nil, tmp, nil, map[string]string{}, nil, "")
+	Ok(t, err)
+	Equals(t, "Terraform v0.11.10\n", output)
+}
+
 // tempSetEnv sets env var key to value. It returns a function that when called
 // will reset the env var to its original value.
 func tempSetEnv(t *testing.T, key string, value string) func() {
@@ -0,0 +1,327 @@
+// Package tfc runs plans and applies for projects whose workspaces are in
+// Terraform Cloud or Terraform Enterprise through the Terraform Cloud API.
+package tfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonAPIContentType is the content type the Terraform Cloud API expects.
+const jsonAPIContentType = "application/vnd.api+json"
+
+// The statuses of runs that won't change.
+const (
+	AppliedRunStatus            = "applied"
+	PlannedAndFinishedRunStatus = "planned_and_finished"
+	ErroredRunStatus            = "errored"
+	DiscardedRunStatus          = "discarded"
+	CanceledRunStatus           = "canceled"
+	ForceCanceledRunStatus      = "force_canceled"
+)
+
+// Client makes requests to the Terraform Cloud API.
+type Client struct {
+	HTTPClient *http.Client
+	// BaseURL is the URL of the Terraform Cloud or Enterprise host, ex.
+	// https://app.terraform.io.
+	BaseURL string
+	Token   string
+}
+
+// NewClient builds a client for the Terraform Cloud or Enterprise instance
+// at hostname, ex. app.terraform.io.
+func NewClient(httpClient *http.Client, hostname string, token string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		HTTPClient: httpClient,
+		BaseURL:    "https://" + hostname,
+		Token:      token,
+	}
+}
+
+// Workspace is a Terraform Cloud workspace.
+type Workspace struct {
+	ID   string
+	Name string
+	Tags []string
+}
+
+// ConfigurationVersion is a version of a workspace's configuration that runs
+// are made from.
+type ConfigurationVersion struct {
+	ID        string
+	Status    string
+	UploadURL string
+}
+
+// Run is a Terraform Cloud run.
+type Run struct {
+	ID     string
+	Status string
+	// IsConfirmable is true if the run is waiting to be confirmed before
+	// it's applied.
+	IsConfirmable bool
+}
+
+type jsonAPIResource struct {
+	ID            string                     `json:"id,omitempty"`
+	Type          string                     `json:"type"`
+	Attributes    map[string]interface{}     `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelation `json:"relationships,omitempty"`
+}
+
+type jsonAPIRelation struct {
+	Data jsonAPIResource `json:"data"`
+}
+
+type workspaceResource struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Name     string   `json:"name"`
+		TagNames []string `json:"tag-names"`
+	} `json:"attributes"`
+}
+
+type configurationVersionResource struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Status    string `json:"status"`
+		UploadURL string `json:"upload-url"`
+	} `json:"attributes"`
+}
+
+type runResource struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Status  string `json:"status"`
+		Actions struct {
+			IsConfirmable bool `json:"is-confirmable"`
+		} `json:"actions"`
+	} `json:"attributes"`
+}
+
+type logResource struct {
+	Attributes struct {
+		LogReadURL string `json:"log-read-url"`
+	} `json:"attributes"`
+}
+
+// ListWorkspaces returns the workspaces in organization that have all of tags.
+func (c *Client) ListWorkspaces(organization string, tags []string) ([]Workspace, error) {
+	var workspaces []Workspace
+	// We'll only loop 100 times as a safety measure.
+	maxPages := 100
+	for page := 1; page <= maxPages; page++ {
+		query := url.Values{}
+		query.Set("search[tags]", strings.Join(tags, ","))
+		query.Set("page[number]", fmt.Sprintf("%d", page))
+		query.Set("page[size]", "100")
+		var resp struct {
+			Data []workspaceResource `json:"data"`
+			Meta struct {
+				Pagination struct {
+					NextPage *int `json:"next-page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		path := fmt.Sprintf("/api/v2/organizations/%s/workspaces?%s", url.PathEscape(organization), query.Encode())
+		if err := c.makeRequest("GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+		for _, w := range resp.Data {
+			workspaces = append(workspaces, Workspace{ID: w.ID, Name: w.Attributes.Name, Tags: w.Attributes.TagNames})
+		}
+		if resp.Meta.Pagination.NextPage == nil {
+			break
+		}
+	}
+	return workspaces, nil
+}
+
+// CreateConfigurationVersion creates a configuration version in the
+// workspace with id workspaceID. Speculative configuration versions can only
+// be planned.
+func (c *Client) CreateConfigurationVersion(workspaceID string, speculative bool) (ConfigurationVersion, error) {
+	body := jsonAPIResource{
+		Type: "configuration-versions",
+		Attributes: map[string]interface{}{
+			"auto-queue-runs": false,
+			"speculative":     speculative,
+		},
+	}
+	var resp struct {
+		Data configurationVersionResource `json:"data"`
+	}
+	if err := c.makeRequest("POST", fmt.Sprintf("/api/v2/workspaces/%s/configuration-versions", workspaceID), body, &resp); err != nil {
+		return ConfigurationVersion{}, err
+	}
+	return ConfigurationVersion{ID: resp.Data.ID, Status: resp.Data.Attributes.Status, UploadURL: resp.Data.Attributes.UploadURL}, nil
+}
+
+// GetConfigurationVersion returns the configuration version with id.
+func (c *Client) GetConfigurationVersion(id string) (ConfigurationVersion, error) {
+	var resp struct {
+		Data configurationVersionResource `json:"data"`
+	}
+	if err := c.makeRequest("GET", fmt.Sprintf("/api/v2/configuration-versions/%s", id), nil, &resp); err != nil {
+		return ConfigurationVersion{}, err
+	}
+	return ConfigurationVersion{ID: resp.Data.ID, Status: resp.Data.Attributes.Status, UploadURL: resp.Data.Attributes.UploadURL}, nil
+}
+
+// UploadConfiguration uploads archive, a gzipped tarball of the
+// configuration, to uploadURL.
+func (c *Client) UploadConfiguration(uploadURL string, archive io.Reader) error {
+	// The upload URL is pre-signed so the token isn't sent.
+	req, err := http.NewRequest("PUT", uploadURL, archive)
+	if err != nil {
+		return errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("uploading configuration: unexpected status code: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// CreateRun queues a run in the workspace with id workspaceID for the
+// configuration version with id configVersionID.
+func (c *Client) CreateRun(workspaceID string, configVersionID string, message string) (Run, error) {
+	body := jsonAPIResource{
+		Type: "runs",
+		Attributes: map[string]interface{}{
+			"message": message,
+		},
+		Relationships: map[string]jsonAPIRelation{
+			"workspace":             {Data: jsonAPIResource{Type: "workspaces", ID: workspaceID}},
+			"configuration-version": {Data: jsonAPIResource{Type: "configuration-versions", ID: configVersionID}},
+		},
+	}
+	var resp struct {
+		Data runResource `json:"data"`
+	}
+	if err := c.makeRequest("POST", "/api/v2/runs", body, &resp); err != nil {
+		return Run{}, err
+	}
+	return toRun(resp.Data), nil
+}
+
+// GetRun returns the run with id.
+func (c *Client) GetRun(id string) (Run, error) {
+	var resp struct {
+		Data runResource `json:"data"`
+	}
+	if err := c.makeRequest("GET", fmt.Sprintf("/api/v2/runs/%s", id), nil, &resp); err != nil {
+		return Run{}, err
+	}
+	return toRun(resp.Data), nil
+}
+
+// ApplyRun confirms the run with id so it's applied.
+func (c *Client) ApplyRun(id string, comment string) error {
+	body := map[string]string{"comment": comment}
+	return c.makeRequest("POST", fmt.Sprintf("/api/v2/runs/%s/actions/apply", id), body, nil)
+}
+
+// GetPlanLog returns the log of the plan of the run with id.
+func (c *Client) GetPlanLog(runID string) (string, error) {
+	return c.getLog(fmt.Sprintf("/api/v2/runs/%s/plan", runID))
+}
+
+// GetApplyLog returns the log of the apply of the run with id.
+func (c *Client) GetApplyLog(runID string) (string, error) {
+	return c.getLog(fmt.Sprintf("/api/v2/runs/%s/apply", runID))
+}
+
+// RunURL returns the URL to view the run with id in the UI.
+func (c *Client) RunURL(organization string, workspace string, runID string) string {
+	return fmt.Sprintf("%s/app/%s/workspaces/%s/runs/%s", c.BaseURL, url.PathEscape(organization), url.PathEscape(workspace), runID)
+}
+
+func (c *Client) getLog(path string) (string, error) {
+	var resp struct {
+		Data logResource `json:"data"`
+	}
+	if err := c.makeRequest("GET", path, nil, &resp); err != nil {
+		return "", err
+	}
+	// The log URL is pre-signed so the token isn't sent.
+	logResp, err := c.HTTPClient.Get(resp.Data.Attributes.LogReadURL)
+	if err != nil {
+		return "", errors.Wrap(err, "reading log")
+	}
+	defer logResp.Body.Close() // nolint: errcheck
+	log, err := ioutil.ReadAll(logResp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading log")
+	}
+	if logResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reading log: unexpected status code: %d, body: %s", logResp.StatusCode, string(log))
+	}
+	// Logs are wrapped in STX and ETX control characters.
+	return strings.Trim(string(log), "\x02\x03\n"), nil
+}
+
+// makeRequest makes a request to path with body encoded as a JSON:API
+// resource, if it's set, and decodes the response into out, if it's set.
+func (c *Client) makeRequest(method string, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		if _, ok := body.(jsonAPIResource); ok {
+			body = map[string]interface{}{"data": body}
+		}
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "encoding request")
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", jsonAPIContentType)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	requestStr := fmt.Sprintf("%s %s", method, path)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading response from request %q", requestStr)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return errors.Wrapf(err, "parsing response from request %q", requestStr)
+	}
+	return nil
+}
+
+func toRun(r runResource) Run {
+	return Run{ID: r.ID, Status: r.Attributes.Status, IsConfirmable: r.Attributes.Actions.IsConfirmable}
+}
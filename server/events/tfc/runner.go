@@ -0,0 +1,327 @@
+package tfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// BranchTagPrefix is the prefix of workspace tags that map workspaces to the
+// base branch of pull requests, ex. branch:main.
+const BranchTagPrefix = "branch:"
+
+// planFileHeader starts the planfile we write after a plan so apply all
+// finds the project. The plan itself stays in Terraform Cloud.
+const planFileHeader = "Atlantis: this plan was created in Terraform Cloud run "
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultTimeout      = 2 * time.Hour
+)
+
+// Runner plans projects with speculative runs and applies them with
+// confirmed runs in the Terraform Cloud workspace selected by their tags.
+type Runner struct {
+	Client *Client
+	// PollInterval is how often runs are checked while we wait for them.
+	// Defaults to 5s.
+	PollInterval time.Duration
+	// Timeout is how long we wait for a run before giving up. Defaults to 2h.
+	Timeout time.Duration
+}
+
+// Plan creates a speculative run of the configuration at path in the
+// project's workspace and returns its plan log.
+func (r *Runner) Plan(ctx models.ProjectCommandContext, path string) (string, error) {
+	ws, err := r.SelectWorkspace(*ctx.TerraformCloud, ctx.Pull.BaseBranch)
+	if err != nil {
+		return "", err
+	}
+	ctx.Log.Info("planning in Terraform Cloud workspace %q", ws.Name)
+	run, err := r.startRun(ctx, ws, path, true)
+	if err != nil {
+		return "", err
+	}
+	runURL := r.Client.RunURL(ctx.TerraformCloud.Organization, ws.Name, run.ID)
+	run, err = r.waitForRun(run.ID, func(run Run) bool { return isFinal(run.Status) })
+	if err != nil {
+		return "", errors.Wrapf(err, "waiting for run %s", runURL)
+	}
+	out, err := r.Client.GetPlanLog(run.ID)
+	if err != nil {
+		return "", err
+	}
+	if run.Status != PlannedAndFinishedRunStatus {
+		return out, fmt.Errorf("run %s finished with status %q", runURL, run.Status)
+	}
+
+	planPath := filepath.Join(path, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if err := ioutil.WriteFile(planPath, []byte(planFileHeader+run.ID+"\n"), 0600); err != nil {
+		return "", errors.Wrap(err, "writing planfile")
+	}
+	return fmt.Sprintf("%s\n\nTerraform Cloud run: %s", out, runURL), nil
+}
+
+// Apply creates a run of the configuration at path in the project's
+// workspace, confirms it once it's planned and returns its apply log. If the
+// run doesn't have any changes, its plan log is returned instead.
+func (r *Runner) Apply(ctx models.ProjectCommandContext, path string) (string, error) {
+	planPath := filepath.Join(path, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if _, err := os.Stat(planPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("no plan found at path %q and workspace %q–did you run plan?", ctx.RepoRelDir, ctx.Workspace)
+	}
+
+	ws, err := r.SelectWorkspace(*ctx.TerraformCloud, ctx.Pull.BaseBranch)
+	if err != nil {
+		return "", err
+	}
+	ctx.Log.Info("applying in Terraform Cloud workspace %q", ws.Name)
+	run, err := r.startRun(ctx, ws, path, false)
+	if err != nil {
+		return "", err
+	}
+	runURL := r.Client.RunURL(ctx.TerraformCloud.Organization, ws.Name, run.ID)
+	run, err = r.waitForRun(run.ID, func(run Run) bool { return run.IsConfirmable || isFinal(run.Status) })
+	if err != nil {
+		return "", errors.Wrapf(err, "waiting for run %s", runURL)
+	}
+
+	var out string
+	switch {
+	case run.IsConfirmable:
+		if err = r.Client.ApplyRun(run.ID, fmt.Sprintf("Applied by Atlantis for %s#%d", ctx.BaseRepo.FullName, ctx.Pull.Num)); err != nil {
+			return "", errors.Wrapf(err, "confirming run %s", runURL)
+		}
+		run, err = r.waitForRun(run.ID, func(run Run) bool { return isFinal(run.Status) })
+		if err != nil {
+			return "", errors.Wrapf(err, "waiting for run %s", runURL)
+		}
+		if out, err = r.Client.GetApplyLog(run.ID); err != nil {
+			return "", err
+		}
+		if run.Status != AppliedRunStatus {
+			return out, fmt.Errorf("run %s finished with status %q", runURL, run.Status)
+		}
+	case run.Status == PlannedAndFinishedRunStatus:
+		// There were no changes to apply.
+		if out, err = r.Client.GetPlanLog(run.ID); err != nil {
+			return "", err
+		}
+	default:
+		out, _ = r.Client.GetPlanLog(run.ID) // nolint: errcheck
+		return out, fmt.Errorf("run %s finished with status %q", runURL, run.Status)
+	}
+
+	ctx.Log.Info("apply successful, deleting planfile")
+	if removeErr := os.Remove(planPath); removeErr != nil {
+		ctx.Log.Warn("failed to delete planfile after successful apply: %s", removeErr)
+	}
+	return fmt.Sprintf("%s\n\nTerraform Cloud run: %s", out, runURL), nil
+}
+
+// SelectWorkspace returns the workspace in cfg's organization that has all
+// of cfg's tags. If more than one does, the one tagged with baseBranch, ex.
+// branch:main, is selected, or else the one without any branch tags.
+func (r *Runner) SelectWorkspace(cfg valid.TerraformCloud, baseBranch string) (Workspace, error) {
+	if r.Client.Token == "" {
+		return Workspace{}, errors.New("using Terraform Cloud requires the server to be started with a Terraform Cloud token")
+	}
+	workspaces, err := r.Client.ListWorkspaces(cfg.Organization, cfg.Tags)
+	if err != nil {
+		return Workspace{}, errors.Wrap(err, "listing Terraform Cloud workspaces")
+	}
+	if len(workspaces) == 0 {
+		return Workspace{}, fmt.Errorf("no Terraform Cloud workspaces in organization %q have tags %s", cfg.Organization, strings.Join(cfg.Tags, ", "))
+	}
+
+	var branchWorkspaces, unbranchedWorkspaces []Workspace
+	for _, ws := range workspaces {
+		hasBranchTag := false
+		for _, tag := range ws.Tags {
+			if !strings.HasPrefix(tag, BranchTagPrefix) {
+				continue
+			}
+			hasBranchTag = true
+			if strings.TrimPrefix(tag, BranchTagPrefix) == baseBranch {
+				branchWorkspaces = append(branchWorkspaces, ws)
+				break
+			}
+		}
+		if !hasBranchTag {
+			unbranchedWorkspaces = append(unbranchedWorkspaces, ws)
+		}
+	}
+	candidates := branchWorkspaces
+	if len(candidates) == 0 {
+		candidates = unbranchedWorkspaces
+	}
+	switch len(candidates) {
+	case 0:
+		return Workspace{}, fmt.Errorf("no Terraform Cloud workspaces with tags %s are for branch %q: tag one with %q", strings.Join(cfg.Tags, ", "), baseBranch, BranchTagPrefix+baseBranch)
+	case 1:
+		return candidates[0], nil
+	default:
+		var names []string
+		for _, ws := range candidates {
+			names = append(names, ws.Name)
+		}
+		return Workspace{}, fmt.Errorf("more than one Terraform Cloud workspace matches tags %s and branch %q: %s", strings.Join(cfg.Tags, ", "), baseBranch, strings.Join(names, ", "))
+	}
+}
+
+// startRun uploads the repo containing the project at path as a new
+// configuration version of ws and queues a run of it.
+func (r *Runner) startRun(ctx models.ProjectCommandContext, ws Workspace, path string, speculative bool) (Run, error) {
+	cv, err := r.Client.CreateConfigurationVersion(ws.ID, speculative)
+	if err != nil {
+		return Run{}, errors.Wrap(err, "creating configuration version")
+	}
+	// We upload the whole repo, not just the project, so modules referenced
+	// with relative paths are included. The workspace's working directory
+	// must be set to the project's dir.
+	archive, err := archiveDir(repoRoot(path, ctx.RepoRelDir))
+	if err != nil {
+		return Run{}, err
+	}
+	if err = r.Client.UploadConfiguration(cv.UploadURL, archive); err != nil {
+		return Run{}, err
+	}
+	if err = r.waitForUpload(cv.ID); err != nil {
+		return Run{}, err
+	}
+	message := fmt.Sprintf("Queued by Atlantis for %s#%d", ctx.BaseRepo.FullName, ctx.Pull.Num)
+	run, err := r.Client.CreateRun(ws.ID, cv.ID, message)
+	return run, errors.Wrap(err, "creating run")
+}
+
+func (r *Runner) waitForUpload(configVersionID string) error {
+	deadline := time.Now().Add(r.timeout())
+	for time.Now().Before(deadline) {
+		cv, err := r.Client.GetConfigurationVersion(configVersionID)
+		if err != nil {
+			return err
+		}
+		switch cv.Status {
+		case "uploaded":
+			return nil
+		case "errored":
+			return fmt.Errorf("configuration version %s errored", configVersionID)
+		}
+		time.Sleep(r.pollInterval())
+	}
+	return fmt.Errorf("timed out waiting for configuration version %s to be uploaded", configVersionID)
+}
+
+// waitForRun polls the run with id until done returns true.
+func (r *Runner) waitForRun(id string, done func(Run) bool) (Run, error) {
+	deadline := time.Now().Add(r.timeout())
+	for time.Now().Before(deadline) {
+		run, err := r.Client.GetRun(id)
+		if err != nil {
+			return run, err
+		}
+		if done(run) {
+			return run, nil
+		}
+		time.Sleep(r.pollInterval())
+	}
+	return Run{}, fmt.Errorf("timed out after %s", r.timeout())
+}
+
+func (r *Runner) pollInterval() time.Duration {
+	if r.PollInterval == 0 {
+		return defaultPollInterval
+	}
+	return r.PollInterval
+}
+
+func (r *Runner) timeout() time.Duration {
+	if r.Timeout == 0 {
+		return defaultTimeout
+	}
+	return r.Timeout
+}
+
+func isFinal(status string) bool {
+	switch status {
+	case AppliedRunStatus, PlannedAndFinishedRunStatus, ErroredRunStatus, DiscardedRunStatus, CanceledRunStatus, ForceCanceledRunStatus:
+		return true
+	}
+	return false
+}
+
+// repoRoot returns the root of the repo that the project at absPath, which
+// is repoRelDir in the repo, is in.
+func repoRoot(absPath string, repoRelDir string) string {
+	root := filepath.Clean(absPath)
+	for dir := filepath.Clean(repoRelDir); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		root = filepath.Dir(root)
+	}
+	return root
+}
+
+// archiveDir returns a gzipped tarball of the files in dir, skipping the
+// .git and .terraform dirs and planfiles.
+func archiveDir(dir string) (io.Reader, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		if info.IsDir() && (info.Name() == ".git" || info.Name() == ".terraform") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && (!info.Mode().IsRegular() || strings.HasSuffix(info.Name(), ".tfplan")) {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint: errcheck
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "archiving %s", dir)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrapf(err, "archiving %s", dir)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.Wrapf(err, "archiving %s", dir)
+	}
+	return &buf, nil
+}
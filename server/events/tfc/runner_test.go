@@ -0,0 +1,335 @@
+package tfc_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/tfc"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeTFC is a fake of the parts of the Terraform Cloud API we use.
+type fakeTFC struct {
+	mu         sync.Mutex
+	url        string
+	workspaces []map[string]interface{}
+	// statuses are the statuses runs go through each time they're fetched.
+	statuses []string
+	// applyStatuses are the statuses runs go through after they're
+	// confirmed.
+	applyStatuses []string
+
+	// uploaded are the names of the files that were uploaded.
+	uploaded        []string
+	speculative     bool
+	runGets         int
+	confirmed       bool
+	workspaceSearch string
+}
+
+func (f *fakeTFC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/api/") && r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v2/organizations/acme/workspaces":
+		f.workspaceSearch = r.URL.Query().Get("search[tags]")
+		writeJSON(w, map[string]interface{}{"data": f.workspaces, "meta": map[string]interface{}{"pagination": map[string]interface{}{"next-page": nil}}})
+	case r.Method == "POST" && r.URL.Path == "/api/v2/workspaces/ws-1/configuration-versions":
+		var body struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body) // nolint: errcheck
+		f.speculative = body.Data.Attributes["speculative"].(bool)
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"id": "cv-1", "attributes": map[string]interface{}{"status": "pending", "upload-url": f.url + "/upload/cv-1"}}})
+	case r.Method == "PUT" && r.URL.Path == "/upload/cv-1":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tr := tar.NewReader(gz)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.uploaded = append(f.uploaded, h.Name)
+		}
+	case r.Method == "GET" && r.URL.Path == "/api/v2/configuration-versions/cv-1":
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"id": "cv-1", "attributes": map[string]interface{}{"status": "uploaded"}}})
+	case r.Method == "POST" && r.URL.Path == "/api/v2/runs":
+		writeJSON(w, f.run("pending"))
+	case r.Method == "GET" && r.URL.Path == "/api/v2/runs/run-1":
+		statuses := f.statuses
+		if f.confirmed {
+			statuses = f.applyStatuses
+		}
+		status := statuses[len(statuses)-1]
+		if f.runGets < len(statuses) {
+			status = statuses[f.runGets]
+		}
+		f.runGets++
+		writeJSON(w, f.run(status))
+	case r.Method == "POST" && r.URL.Path == "/api/v2/runs/run-1/actions/apply":
+		f.confirmed = true
+		f.runGets = 0
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "GET" && r.URL.Path == "/api/v2/runs/run-1/plan":
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"attributes": map[string]interface{}{"log-read-url": f.url + "/logs/plan"}}})
+	case r.Method == "GET" && r.URL.Path == "/api/v2/runs/run-1/apply":
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"attributes": map[string]interface{}{"log-read-url": f.url + "/logs/apply"}}})
+	case r.Method == "GET" && r.URL.Path == "/logs/plan":
+		fmt.Fprint(w, "\x02plan log\x03") // nolint: errcheck
+	case r.Method == "GET" && r.URL.Path == "/logs/apply":
+		fmt.Fprint(w, "\x02apply log\x03") // nolint: errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeTFC) run(status string) map[string]interface{} {
+	return map[string]interface{}{"data": map[string]interface{}{
+		"id": "run-1",
+		"attributes": map[string]interface{}{
+			"status":  status,
+			"actions": map[string]interface{}{"is-confirmable": status == "planned"},
+		},
+	}}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	json.NewEncoder(w).Encode(v) // nolint: errcheck
+}
+
+func workspace(id string, name string, tags ...string) map[string]interface{} {
+	return map[string]interface{}{"id": id, "attributes": map[string]interface{}{"name": name, "tag-names": tags}}
+}
+
+func setupRunner(t *testing.T, f *fakeTFC) (*tfc.Runner, func()) {
+	server := httptest.NewServer(f)
+	f.url = server.URL
+	client := tfc.NewClient(server.Client(), "unused", "token")
+	client.BaseURL = server.URL
+	return &tfc.Runner{Client: client, PollInterval: 1}, server.Close
+}
+
+func TestRunner_SelectWorkspace(t *testing.T) {
+	cases := []struct {
+		description string
+		workspaces  []map[string]interface{}
+		baseBranch  string
+		expName     string
+		expErr      string
+	}{
+		{
+			description: "single workspace",
+			workspaces:  []map[string]interface{}{workspace("ws-1", "net", "networking")},
+			baseBranch:  "main",
+			expName:     "net",
+		},
+		{
+			description: "branch tag",
+			workspaces: []map[string]interface{}{
+				workspace("ws-1", "net-prod", "networking", "branch:main"),
+				workspace("ws-2", "net-staging", "networking", "branch:staging"),
+			},
+			baseBranch: "staging",
+			expName:    "net-staging",
+		},
+		{
+			description: "falls back to workspace without branch tags",
+			workspaces: []map[string]interface{}{
+				workspace("ws-1", "net", "networking"),
+				workspace("ws-2", "net-staging", "networking", "branch:staging"),
+			},
+			baseBranch: "main",
+			expName:    "net",
+		},
+		{
+			description: "no workspaces",
+			baseBranch:  "main",
+			expErr:      `no Terraform Cloud workspaces in organization "acme" have tags networking`,
+		},
+		{
+			description: "no workspace for branch",
+			workspaces: []map[string]interface{}{
+				workspace("ws-2", "net-staging", "networking", "branch:staging"),
+			},
+			baseBranch: "main",
+			expErr:     `no Terraform Cloud workspaces with tags networking are for branch "main": tag one with "branch:main"`,
+		},
+		{
+			description: "ambiguous",
+			workspaces: []map[string]interface{}{
+				workspace("ws-1", "net-a", "networking"),
+				workspace("ws-2", "net-b", "networking"),
+			},
+			baseBranch: "main",
+			expErr:     `more than one Terraform Cloud workspace matches tags networking and branch "main": net-a, net-b`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			f := &fakeTFC{workspaces: c.workspaces}
+			runner, cleanup := setupRunner(t, f)
+			defer cleanup()
+
+			ws, err := runner.SelectWorkspace(valid.TerraformCloud{Organization: "acme", Tags: []string{"networking"}}, c.baseBranch)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expName, ws.Name)
+			Equals(t, "networking", f.workspaceSearch)
+		})
+	}
+}
+
+func TestRunner_SelectWorkspace_NoToken(t *testing.T) {
+	runner := &tfc.Runner{Client: tfc.NewClient(nil, "app.terraform.io", "")}
+	_, err := runner.SelectWorkspace(valid.TerraformCloud{Organization: "acme", Tags: []string{"networking"}}, "main")
+	ErrEquals(t, "using Terraform Cloud requires the server to be started with a Terraform Cloud token", err)
+}
+
+func setupRepo(t *testing.T) (string, func()) {
+	return DirStructure(t, map[string]interface{}{
+		".git": map[string]interface{}{
+			"HEAD": nil,
+		},
+		"modules": map[string]interface{}{
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+		"networking": map[string]interface{}{
+			"main.tf": nil,
+		},
+	})
+}
+
+func projectCtx() models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log:            logging.NewNoopLogger(),
+		BaseRepo:       models.Repo{FullName: "owner/repo"},
+		Pull:           models.PullRequest{Num: 1, BaseBranch: "main"},
+		RepoRelDir:     "networking",
+		Workspace:      "default",
+		TerraformCloud: &valid.TerraformCloud{Organization: "acme", Tags: []string{"networking"}},
+	}
+}
+
+func TestRunner_Plan(t *testing.T) {
+	f := &fakeTFC{
+		workspaces: []map[string]interface{}{workspace("ws-1", "net", "networking")},
+		statuses:   []string{"planning", "planned_and_finished"},
+	}
+	runner, cleanup := setupRunner(t, f)
+	defer cleanup()
+	repoDir, cleanupRepo := setupRepo(t)
+	defer cleanupRepo()
+	path := filepath.Join(repoDir, "networking")
+
+	out, err := runner.Plan(projectCtx(), path)
+	Ok(t, err)
+	Equals(t, "plan log\n\nTerraform Cloud run: "+f.url+"/app/acme/workspaces/net/runs/run-1", out)
+	Equals(t, true, f.speculative)
+	Equals(t, []string{"modules", "modules/vpc", "modules/vpc/main.tf", "networking", "networking/main.tf"}, f.uploaded)
+
+	// The planfile is written so apply all finds the project.
+	_, err = os.Stat(filepath.Join(path, "default.tfplan"))
+	Ok(t, err)
+}
+
+func TestRunner_PlanErrored(t *testing.T) {
+	f := &fakeTFC{
+		workspaces: []map[string]interface{}{workspace("ws-1", "net", "networking")},
+		statuses:   []string{"errored"},
+	}
+	runner, cleanup := setupRunner(t, f)
+	defer cleanup()
+	repoDir, cleanupRepo := setupRepo(t)
+	defer cleanupRepo()
+	path := filepath.Join(repoDir, "networking")
+
+	out, err := runner.Plan(projectCtx(), path)
+	ErrEquals(t, fmt.Sprintf("run %s/app/acme/workspaces/net/runs/run-1 finished with status \"errored\"", f.url), err)
+	Equals(t, "plan log", out)
+	_, err = os.Stat(filepath.Join(path, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "expected no planfile")
+}
+
+func TestRunner_Apply(t *testing.T) {
+	f := &fakeTFC{
+		workspaces:    []map[string]interface{}{workspace("ws-1", "net", "networking")},
+		statuses:      []string{"planning", "planned"},
+		applyStatuses: []string{"applying", "applied"},
+	}
+	runner, cleanup := setupRunner(t, f)
+	defer cleanup()
+	repoDir, cleanupRepo := setupRepo(t)
+	defer cleanupRepo()
+	path := filepath.Join(repoDir, "networking")
+	planPath := filepath.Join(path, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, nil, 0600))
+
+	out, err := runner.Apply(projectCtx(), path)
+	Ok(t, err)
+	Equals(t, "apply log\n\nTerraform Cloud run: "+f.url+"/app/acme/workspaces/net/runs/run-1", out)
+	Equals(t, false, f.speculative)
+	Equals(t, true, f.confirmed)
+	_, err = os.Stat(planPath)
+	Assert(t, os.IsNotExist(err), "expected planfile to be deleted")
+}
+
+func TestRunner_ApplyNoChanges(t *testing.T) {
+	f := &fakeTFC{
+		workspaces: []map[string]interface{}{workspace("ws-1", "net", "networking")},
+		statuses:   []string{"planned_and_finished"},
+	}
+	runner, cleanup := setupRunner(t, f)
+	defer cleanup()
+	repoDir, cleanupRepo := setupRepo(t)
+	defer cleanupRepo()
+	path := filepath.Join(repoDir, "networking")
+	Ok(t, ioutil.WriteFile(filepath.Join(path, "default.tfplan"), nil, 0600))
+
+	out, err := runner.Apply(projectCtx(), path)
+	Ok(t, err)
+	Equals(t, "plan log\n\nTerraform Cloud run: "+f.url+"/app/acme/workspaces/net/runs/run-1", out)
+	Equals(t, false, f.confirmed)
+}
+
+func TestRunner_ApplyNotPlanned(t *testing.T) {
+	runner := &tfc.Runner{Client: tfc.NewClient(nil, "app.terraform.io", "token")}
+	repoDir, cleanupRepo := setupRepo(t)
+	defer cleanupRepo()
+
+	_, err := runner.Apply(projectCtx(), filepath.Join(repoDir, "networking"))
+	ErrEquals(t, `no plan found at path "networking" and workspace "default"–did you run plan?`, err)
+}
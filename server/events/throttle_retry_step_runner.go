@@ -0,0 +1,154 @@
+package events
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// maxProviderRetryBackoff caps how long we'll wait between retries of a
+// throttled step or a step that found the state locked.
+const maxProviderRetryBackoff = 5 * time.Minute
+
+// ThrottleRetryStepRunner wraps a StepRunner and retries the step with
+// jittered exponential backoff if it fails with output that matches one of
+// the provider throttling patterns in Rules. Retries are reported in the
+// project's log rather than failing the command.
+type ThrottleRetryStepRunner struct {
+	StepRunner StepRunner
+	Rules      []valid.ProviderRetry
+}
+
+// NewRetryStepRunners wraps the runners of init, plan and apply steps so
+// they're retried when the Terraform state is locked and, for init and plan,
+// when a provider throttled them. The server and remote workers both use it
+// so they retry steps the same way.
+func NewRetryStepRunners(init StepRunner, plan StepRunner, apply StepRunner, providerRetries []valid.ProviderRetry) (StepRunner, StepRunner, StepRunner) {
+	// We only retry init and plan on provider throttling because retrying a
+	// partially completed apply could fail confusingly due to the plan now
+	// being stale. Apply is retried if the state is locked though since
+	// Terraform acquires the lock before changing anything.
+	return &ThrottleRetryStepRunner{StepRunner: &StateLockRetryStepRunner{StepRunner: init}, Rules: providerRetries},
+		&ThrottleRetryStepRunner{StepRunner: &StateLockRetryStepRunner{StepRunner: plan}, Rules: providerRetries},
+		&StateLockRetryStepRunner{StepRunner: apply}
+}
+
+// Run runs the wrapped step, retrying it if the provider throttled us.
+func (t *ThrottleRetryStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	retries := newStepRetries(ctx, envs)
+	attempt := 1
+	for {
+		out, err := t.StepRunner.Run(ctx, extraArgs, path, retries.envs())
+		if err == nil {
+			return out, nil
+		}
+		rule := t.matchingRule(out + err.Error())
+		if rule == nil || attempt >= rule.MaxAttempts {
+			return out, err
+		}
+		wait := retryBackoff(rule.Backoff, attempt)
+		ctx.Log.Warn("output matched provider throttling pattern %q, retrying in %s (attempt %d/%d)", rule.Pattern.String(), wait, attempt+1, rule.MaxAttempts)
+		if err := retries.wait(wait); err != nil {
+			return out, err
+		}
+		attempt++
+	}
+}
+
+// matchingRule returns the first rule whose pattern matches output or nil if
+// none match.
+func (t *ThrottleRetryStepRunner) matchingRule(output string) *valid.ProviderRetry {
+	for i, r := range t.Rules {
+		if r.Pattern.MatchString(output) {
+			return &t.Rules[i]
+		}
+	}
+	return nil
+}
+
+// retryBackoff returns how long to wait before the retry after attempt. The
+// wait doubles on every attempt and is randomized between 50% and 100% of
+// that value so that many projects failing at the same time don't all retry
+// at once.
+func retryBackoff(initial time.Duration, attempt int) time.Duration {
+	wait := initial
+	for i := 1; i < attempt && wait < maxProviderRetryBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxProviderRetryBackoff {
+		wait = maxProviderRetryBackoff
+	}
+	half := int64(wait / 2)
+	if half <= 0 {
+		return wait
+	}
+	// nolint: gosec
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// stepRetries tracks the time left for the attempts of a step. The step's
+// timeout covers all of its attempts and the waits between them, and the
+// waits are stopped early if the command is cancelled.
+type stepRetries struct {
+	done     <-chan struct{}
+	stepEnvs map[string]string
+	deadline time.Time
+}
+
+// newStepRetries starts tracking the attempts of a step run by ctx with envs.
+func newStepRetries(ctx models.ProjectCommandContext, envs map[string]string) *stepRetries {
+	r := &stepRetries{done: ctx.Done, stepEnvs: envs}
+	// Invalid timeouts are ignored like the backends ignore them since
+	// they're validated when the config is parsed.
+	if timeout, err := time.ParseDuration(envs[execution.TimeoutEnvVar]); err == nil && timeout > 0 {
+		r.deadline = time.Now().Add(timeout)
+	}
+	return r
+}
+
+// envs returns the envs for the next attempt, with the time that's left of
+// the step's timeout.
+func (r *stepRetries) envs() map[string]string {
+	if r.deadline.IsZero() {
+		return r.stepEnvs
+	}
+	envs := make(map[string]string, len(r.stepEnvs))
+	for k, v := range r.stepEnvs {
+		envs[k] = v
+	}
+	// The timeout can't be 0 since that means there's no timeout.
+	remaining := time.Until(r.deadline)
+	if remaining < time.Millisecond {
+		remaining = time.Millisecond
+	}
+	envs[execution.TimeoutEnvVar] = remaining.String()
+	return envs
+}
+
+// wait waits for d before the next attempt. It returns execution.ErrCancelled
+// if the command is cancelled first and a *execution.TimeoutError if the
+// step's timeout is reached first.
+func (r *stepRetries) wait(d time.Duration) error {
+	var timedOut <-chan time.Time
+	if !r.deadline.IsZero() {
+		deadline := time.NewTimer(time.Until(r.deadline))
+		defer deadline.Stop()
+		timedOut = deadline.C
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	// Nil channels never fire so steps without a timeout are only stopped if
+	// they're cancelled and the other way around.
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.done:
+		return execution.ErrCancelled
+	case <-timedOut:
+		timeout, _ := time.ParseDuration(r.stepEnvs[execution.TimeoutEnvVar])
+		return &execution.TimeoutError{Timeout: timeout}
+	}
+}
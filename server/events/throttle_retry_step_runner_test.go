@@ -0,0 +1,136 @@
+package events_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var throttleRules = []valid.ProviderRetry{
+	{
+		Pattern:     regexp.MustCompile("Throttling: Rate exceeded"),
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	},
+}
+
+func TestThrottleRetryStepRunner_SuccessNoRetry(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{StepRunner: mockStep, Rules: throttleRules}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("output", nil)
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	Ok(t, err)
+	Equals(t, "output", out)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_NonMatchingErrNotRetried(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{StepRunner: mockStep, Rules: throttleRules}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: invalid resource", errors.New("exit status 1"))
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	ErrEquals(t, "exit status 1", err)
+	Equals(t, "Error: invalid resource", out)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_RetriesUntilSuccess(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{StepRunner: mockStep, Rules: throttleRules}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Throttling: Rate exceeded", errors.New("exit status 1")).
+		ThenReturn("output", nil)
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	Ok(t, err)
+	Equals(t, "output", out)
+	mockStep.VerifyWasCalled(Times(2)).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_StopsAtMaxAttempts(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{StepRunner: mockStep, Rules: throttleRules}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Throttling: Rate exceeded", errors.New("exit status 1"))
+
+	out, err := runner.Run(ctx, nil, "path", nil)
+	ErrEquals(t, "exit status 1", err)
+	Equals(t, "Error: Throttling: Rate exceeded", out)
+	mockStep.VerifyWasCalled(Times(3)).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_BackoffCancelled(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{
+		StepRunner: mockStep,
+		Rules:      []valid.ProviderRetry{{Pattern: throttleRules[0].Pattern, MaxAttempts: 3, Backoff: time.Hour}},
+	}
+	done := make(chan struct{})
+	close(done)
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger(), Done: done}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Throttling: Rate exceeded", errors.New("exit status 1"))
+
+	_, err := runner.Run(ctx, nil, "path", nil)
+	Equals(t, execution.ErrCancelled, err)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_BackoffTimedOut(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{
+		StepRunner: mockStep,
+		Rules:      []valid.ProviderRetry{{Pattern: throttleRules[0].Pattern, MaxAttempts: 3, Backoff: time.Hour}},
+	}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Throttling: Rate exceeded", errors.New("exit status 1"))
+
+	_, err := runner.Run(ctx, nil, "path", map[string]string{execution.TimeoutEnvVar: "10ms"})
+	Equals(t, &execution.TimeoutError{Timeout: 10 * time.Millisecond}, err)
+	mockStep.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestThrottleRetryStepRunner_RetriesGetRemainingTimeout(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockStep := mocks.NewMockStepRunner()
+	runner := events.ThrottleRetryStepRunner{StepRunner: mockStep, Rules: throttleRules}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockStep.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn("Error: Throttling: Rate exceeded", errors.New("exit status 1")).
+		ThenReturn("output", nil)
+
+	_, err := runner.Run(ctx, nil, "path", map[string]string{execution.TimeoutEnvVar: "1h"})
+	Ok(t, err)
+	_, _, _, envs := mockStep.VerifyWasCalled(Times(2)).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString()).GetAllCapturedArguments()
+	first, err := time.ParseDuration(envs[0][execution.TimeoutEnvVar])
+	Ok(t, err)
+	second, err := time.ParseDuration(envs[1][execution.TimeoutEnvVar])
+	Ok(t, err)
+	Assert(t, first <= time.Hour && second < first && second > 59*time.Minute, "exp the retry to get the rest of the timeout, got %s then %s", first, second)
+}
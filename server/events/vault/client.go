@@ -0,0 +1,89 @@
+// Package vault fetches the secrets that env steps read from HashiCorp Vault.
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// Client reads secrets from Vault's HTTP API with a token. Both versions of
+// the KV secrets engine are supported. With version 2, refs must include the
+// data/ segment of the API path, ex. secret/data/db#password.
+type Client struct {
+	// Address is Vault's URL, ex. https://vault.example.com:8200.
+	Address string
+	// Token authenticates to Vault. Its policies must allow reading the
+	// secrets that workflows use.
+	Token string
+	// HTTPClient, if set, is used instead of a client with a 30s timeout.
+	HTTPClient *http.Client
+}
+
+// secretResponse is the body of Vault's response to reading a secret.
+type secretResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// Secret returns the value of key in the secret at path, where ref is
+// path#key.
+func (c *Client) Secret(ctx models.ProjectCommandContext, ref string) (string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i == -1 {
+		return "", fmt.Errorf("secret %q must be a path and key separated by #", ref)
+	}
+	path, key := strings.Trim(ref[:i], "/"), ref[i+1:]
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(c.Address, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "reading from vault")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading from vault")
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Vault's error bodies don't include secret values.
+		return "", fmt.Errorf("reading from vault: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var secret secretResponse
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", errors.Wrap(err, "parsing vault response")
+	}
+	data := secret.Data
+	// KV version 2 nests the secret's data next to its metadata.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret at %s has no key %q", path, key)
+	}
+	ctx.Log.Debug("read secret %s from vault", ref)
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	default:
+		// Non-string values are passed as JSON, ex. 5432 or true.
+		serialized, err := json.Marshal(v)
+		return string(serialized), err
+	}
+}
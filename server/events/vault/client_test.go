@@ -0,0 +1,89 @@
+package vault_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vault"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newTestVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/db":
+			fmt.Fprint(w, `{"data":{"password":"kv1-password","port":5432}}`)
+		case "/v1/secret/data/db":
+			fmt.Fprint(w, `{"data":{"data":{"password":"kv2-password"},"metadata":{"version":3}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+}
+
+func TestClient_Secret(t *testing.T) {
+	server := newTestVault(t)
+	defer server.Close()
+	client := &vault.Client{Address: server.URL + "/", Token: "token"}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+
+	cases := []struct {
+		ref    string
+		exp    string
+		expErr string
+	}{
+		{
+			ref: "kv/db#password",
+			exp: "kv1-password",
+		},
+		{
+			ref: "kv/db#port",
+			exp: "5432",
+		},
+		{
+			ref: "/secret/data/db#password",
+			exp: "kv2-password",
+		},
+		{
+			ref:    "secret/data/db#user",
+			expErr: "secret at secret/data/db has no key \"user\"",
+		},
+		{
+			ref:    "secret/data/other#password",
+			expErr: "reading from vault: 404 Not Found: {\"errors\":[]}",
+		},
+		{
+			ref:    "secret/data/db",
+			expErr: "secret \"secret/data/db\" must be a path and key separated by #",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.ref, func(t *testing.T) {
+			value, err := client.Secret(ctx, c.ref)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, value)
+		})
+	}
+}
+
+func TestClient_SecretPermissionDenied(t *testing.T) {
+	server := newTestVault(t)
+	defer server.Close()
+	client := &vault.Client{Address: server.URL, Token: "wrong"}
+	_, err := client.Secret(models.ProjectCommandContext{Log: logging.NewNoopLogger()}, "kv/db#password")
+	ErrEquals(t, "reading from vault: 403 Forbidden: {\"errors\":[\"permission denied\"]}", err)
+}
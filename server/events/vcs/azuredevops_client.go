@@ -21,15 +21,20 @@ type AzureDevopsClient struct {
 	ctx    context.Context
 }
 
-// NewAzureDevopsClient returns a valid Azure DevOps client.
-func NewAzureDevopsClient(hostname string, token string) (*AzureDevopsClient, error) {
+// NewAzureDevopsClient returns a valid Azure DevOps client. Its requests are
+// sent with httpClient's transport. If httpClient is nil,
+// http.DefaultTransport is used.
+func NewAzureDevopsClient(httpClient *http.Client, hostname string, token string) (*AzureDevopsClient, error) {
 	tp := azuredevops.BasicAuthTransport{
 		Username: "",
 		Password: strings.TrimSpace(token),
 	}
-	httpClient := tp.Client()
-	httpClient.Timeout = time.Second * 10
-	var adClient, err = azuredevops.NewClient(httpClient)
+	if httpClient != nil {
+		tp.Transport = httpClient.Transport
+	}
+	authClient := tp.Client()
+	authClient.Timeout = time.Second * 10
+	var adClient, err = azuredevops.NewClient(authClient)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +163,34 @@ func (g *AzureDevopsClient) PullIsApproved(repo models.Repo, pull models.PullReq
 	return false, nil
 }
 
+// GetPullApprovers returns the unique names of the reviewers other than the
+// author who voted to approve the pull request.
+func (g *AzureDevopsClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	owner, project, repoName := SplitAzureDevopsRepoFullName(repo.FullName)
+
+	opts := azuredevops.PullRequestGetOptions{
+		IncludeWorkItemRefs: true,
+	}
+	adPull, _, err := g.Client.PullRequests.GetWithRepo(g.ctx, owner, project, repoName, pull.Num, &opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting pull request")
+	}
+
+	var approvers []string
+	for _, review := range adPull.Reviewers {
+		if review == nil {
+			continue
+		}
+		if review.IdentityRef.GetUniqueName() == adPull.GetCreatedBy().GetUniqueName() {
+			continue
+		}
+		if review.GetVote() == azuredevops.VoteApproved || review.GetVote() == azuredevops.VoteApprovedWithSuggestions {
+			approvers = append(approvers, review.IdentityRef.GetUniqueName())
+		}
+	}
+	return approvers, nil
+}
+
 // PullIsMergeable returns true if the merge request can be merged.
 func (g *AzureDevopsClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	owner, project, repoName := SplitAzureDevopsRepoFullName(repo.FullName)
@@ -207,6 +240,28 @@ func (g *AzureDevopsClient) PullIsMergeable(repo models.Repo, pull models.PullRe
 	return true, nil
 }
 
+// PullIsUndiverged returns true if the pull request's head commit isn't behind
+// its target branch.
+func (g *AzureDevopsClient) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	owner, project, repoName := SplitAzureDevopsRepoFullName(repo.FullName)
+	// The client doesn't support the commit diffs API so the request is made
+	// directly.
+	// See https://docs.microsoft.com/en-us/rest/api/azure/devops/git/diffs/get?view=azure-devops-rest-5.1
+	u := fmt.Sprintf("%s/%s/_apis/git/repositories/%s/diffs/commits?baseVersion=%s&baseVersionType=branch&targetVersion=%s&targetVersionType=commit&$top=1&api-version=5.1",
+		owner, project, repoName, url.QueryEscape(pull.BaseBranch), url.QueryEscape(pull.HeadCommit))
+	req, err := g.Client.NewRequest("GET", u, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "creating request")
+	}
+	var diffs struct {
+		BehindCount int `json:"behindCount"`
+	}
+	if _, err := g.Client.Execute(g.ctx, req, &diffs); err != nil {
+		return false, errors.Wrap(err, "comparing head commit to target branch")
+	}
+	return diffs.BehindCount == 0, nil
+}
+
 // GetPullRequest returns the pull request.
 func (g *AzureDevopsClient) GetPullRequest(repo models.Repo, num int) (*azuredevops.GitPullRequest, error) {
 	opts := azuredevops.PullRequestGetOptions{
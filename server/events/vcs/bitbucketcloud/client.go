@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -129,6 +130,49 @@ func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool
 	return false, nil
 }
 
+// GetPullHeadRepo returns the full name and clone URL of the repo the pull
+// request's changes are from, which is a fork for pull requests from forks.
+func (b *Client) GetPullHeadRepo(repo models.Repo, pullNum int) (string, string, error) {
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, pullNum)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return "", "", err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return "", "", errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return "", "", errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	return *pullResp.Source.Repository.FullName, *pullResp.Source.Repository.Links.HTML.HREF, nil
+}
+
+// GetPullApprovers returns the nicknames of the participants other than the
+// author who approved the pull request.
+func (b *Client) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, pull.Num)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	authorUUID := *pullResp.Author.UUID
+	var approvers []string
+	for _, participant := range pullResp.Participants {
+		if *participant.Approved && *participant.User.UUID != authorUUID && participant.User.Nickname != nil {
+			approvers = append(approvers, *participant.User.Nickname)
+		}
+	}
+	return approvers, nil
+}
+
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
 func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	nextPageURL := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/diffstat", b.BaseURL, repo.FullName, pull.Num)
@@ -160,6 +204,23 @@ func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (boo
 	return true, nil
 }
 
+// PullIsUndiverged returns true if the pull request's branch has every commit
+// from the branch it's being merged into.
+func (b *Client) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	// Lists the commits on the base branch that aren't in the head commit.
+	path := fmt.Sprintf("%s/2.0/repositories/%s/commits/%s?exclude=%s&pagelen=1",
+		b.BaseURL, repo.FullName, url.PathEscape(pull.BaseBranch), url.QueryEscape(pull.HeadCommit))
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	var commits Commits
+	if err := json.Unmarshal(resp, &commits); err != nil {
+		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	return len(commits.Values) == 0, nil
+}
+
 // UpdateStatus updates the status of a commit.
 func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, src string, description string, url string) error {
 	bbState := "FAILED"
@@ -200,6 +261,14 @@ func (b *Client) MergePull(pull models.PullRequest) error {
 	return err
 }
 
+// CheckCredentials returns an error if our username and app password are
+// invalid. Bitbucket doesn't report an app password's permissions so they
+// aren't checked.
+func (b *Client) CheckCredentials() error {
+	_, err := b.makeRequest("GET", fmt.Sprintf("%s/2.0/user", b.BaseURL), nil)
+	return errors.Wrap(err, "getting authenticated user")
+}
+
 // MarkdownPullLink specifies the character used in a pull request comment.
 func (b *Client) MarkdownPullLink(pull models.PullRequest) (string, error) {
 	return fmt.Sprintf("#%d", pull.Num), nil
@@ -214,6 +214,33 @@ func TestClient_PullIsApproved(t *testing.T) {
 	}
 }
 
+func TestClient_GetPullHeadRepo(t *testing.T) {
+	json, err := ioutil.ReadFile(filepath.Join("testdata", "pull-approved.json"))
+	Ok(t, err)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/2.0/repositories/owner/repo/pullrequests/1":
+			w.Write(json) // nolint: errcheck
+			return
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client := bitbucketcloud.NewClient(http.DefaultClient, "user", "pass", "runatlantis.io")
+	client.BaseURL = testServer.URL
+
+	repo, err := models.NewRepo(models.BitbucketCloud, "owner/repo", "https://bitbucket.org/owner/repo.git", "user", "token")
+	Ok(t, err)
+	fullName, cloneURL, err := client.GetPullHeadRepo(repo, 1)
+	Ok(t, err)
+	Equals(t, "lkysow/atlantis-example", fullName)
+	Equals(t, "https://bitbucket.org/lkysow/atlantis-example", cloneURL)
+}
+
 func TestClient_PullIsMergeable(t *testing.T) {
 	cases := map[string]struct {
 		DiffStat     string
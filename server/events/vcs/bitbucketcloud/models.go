@@ -63,7 +63,8 @@ type Link struct {
 type Participant struct {
 	Approved *bool `json:"approved,omitempty" validate:"required"`
 	User     *struct {
-		UUID *string `json:"uuid,omitempty" validate:"required"`
+		UUID     *string `json:"uuid,omitempty" validate:"required"`
+		Nickname *string `json:"nickname,omitempty"`
 	} `json:"user,omitempty" validate:"required"`
 }
 type BranchMeta struct {
@@ -77,6 +78,11 @@ type Branch struct {
 type Commit struct {
 	Hash *string `json:"hash,omitempty" validate:"required"`
 }
+
+// Commits is a page of commits. Only how many there are is used.
+type Commits struct {
+	Values []Commit `json:"values,omitempty"`
+}
 type Comment struct {
 	Content *CommentContent `json:"content,omitempty" validate:"required"`
 }
@@ -22,6 +22,13 @@ import (
 // single comment.
 const maxCommentLength = 32768
 
+// The limits Bitbucket puts on Code Insights reports.
+const (
+	maxInsightsTitleLength   = 450
+	maxInsightsDetailsLength = 2000
+	maxInsightsData          = 6
+)
+
 type Client struct {
 	HTTPClient  *http.Client
 	Username    string
@@ -186,6 +193,60 @@ func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool
 	return false, nil
 }
 
+// GetPullHeadRepo returns the full name and clone URL of the repo the pull
+// request's changes are from, which is a fork for pull requests from forks.
+func (b *Client) GetPullHeadRepo(repo models.Repo, pullNum int) (string, string, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return "", "", err
+	}
+	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pullNum)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return "", "", err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return "", "", errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return "", "", errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	// Built the same way as the clone URLs of repos in webhooks.
+	headRepo := pullResp.FromRef.Repository
+	fullName := fmt.Sprintf("%s/%s", *headRepo.Project.Name, *headRepo.Slug)
+	cloneURL := fmt.Sprintf("%s/scm/%s/%s.git", b.BaseURL, strings.ToLower(*headRepo.Project.Key), *headRepo.Slug)
+	return fullName, cloneURL, nil
+}
+
+// GetPullApprovers returns the names of the reviewers who approved the pull
+// request.
+func (b *Client) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pull.Num)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	var approvers []string
+	for _, reviewer := range pullResp.Reviewers {
+		if *reviewer.Approved && reviewer.User != nil && reviewer.User.Name != nil {
+			approvers = append(approvers, *reviewer.User.Name)
+		}
+	}
+	return approvers, nil
+}
+
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
 func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
@@ -210,6 +271,27 @@ func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (boo
 	return false, nil
 }
 
+// PullIsUndiverged returns true if the pull request's branch has every commit
+// from the branch it's being merged into.
+func (b *Client) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return false, err
+	}
+	// Lists the commits on the base branch that aren't in the head commit.
+	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/commits?since=%s&until=%s&limit=1",
+		b.BaseURL, projectKey, repo.Name, url.QueryEscape(pull.HeadCommit), url.QueryEscape("refs/heads/"+pull.BaseBranch))
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	var commits Commits
+	if err := json.Unmarshal(resp, &commits); err != nil {
+		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	return len(commits.Values) == 0, nil
+}
+
 // UpdateStatus updates the status of a commit.
 func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, src string, description string, url string) error {
 	bbState := "FAILED"
@@ -243,6 +325,51 @@ func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status
 	return err
 }
 
+// CreateInsightsReport creates the Code Insights report with key on the head
+// commit of pull, replacing any existing report with the same key. Code
+// Insights requires Bitbucket Server 5.15 or later.
+func (b *Client) CreateInsightsReport(repo models.Repo, pull models.PullRequest, key string, report models.InsightsReport) error {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return err
+	}
+	result := "FAIL"
+	if report.Passed {
+		result = "PASS"
+	}
+	data := []InsightsData{}
+	for _, d := range report.Data {
+		if len(data) == maxInsightsData {
+			break
+		}
+		data = append(data, InsightsData{Title: d.Title, Type: "NUMBER", Value: d.Value})
+	}
+	bodyBytes, err := json.Marshal(InsightsReport{
+		Title:    truncate(report.Title, maxInsightsTitleLength),
+		Details:  truncate(report.Details, maxInsightsDetailsLength),
+		Result:   result,
+		Reporter: "Atlantis",
+		Link:     report.Link,
+		Data:     data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/rest/insights/1.0/projects/%s/repos/%s/commits/%s/reports/%s", b.BaseURL, projectKey, repo.Name, pull.HeadCommit, url.PathEscape(key))
+	_, err = b.makeRequest("PUT", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// truncate shortens s to at most max chars, ending it with an ellipsis if
+// it's shortened.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
 // MergePull merges the pull request.
 func (b *Client) MergePull(pull models.PullRequest) error {
 	projectKey, err := b.GetProjectKey(pull.BaseRepo.Name, pull.BaseRepo.SanitizedCloneURL)
@@ -268,6 +395,14 @@ func (b *Client) MergePull(pull models.PullRequest) error {
 	return err
 }
 
+// CheckCredentials returns an error if our username and password are
+// invalid. Bitbucket doesn't report a token's permissions so they aren't
+// checked.
+func (b *Client) CheckCredentials() error {
+	_, err := b.makeRequest("GET", fmt.Sprintf("%s/rest/api/1.0/users/%s", b.BaseURL, url.PathEscape(b.Username)), nil)
+	return errors.Wrap(err, "getting authenticated user")
+}
+
 // MarkdownPullLink specifies the character used in a pull request comment.
 func (b *Client) MarkdownPullLink(pull models.PullRequest) (string, error) {
 	return fmt.Sprintf("#%d", pull.Num), nil
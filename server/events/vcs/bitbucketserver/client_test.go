@@ -181,6 +181,77 @@ func TestClient_MergePull(t *testing.T) {
 	Ok(t, err)
 }
 
+func TestClient_GetPullHeadRepo(t *testing.T) {
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request.json"))
+	Ok(t, err)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write(pullRequest) // nolint: errcheck
+			return
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+
+	fullName, cloneURL, err := client.GetPullHeadRepo(models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+	}, 1)
+	Ok(t, err)
+	Equals(t, "atlantis/example", fullName)
+	Equals(t, fmt.Sprintf("%s/scm/at/example.git", testServer.URL), cloneURL)
+}
+
+func TestClient_CreateInsightsReport(t *testing.T) {
+	var gotBody string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/insights/1.0/projects/ow/repos/repo/commits/sha/reports/atlantis-plan-abc":
+			Equals(t, "PUT", r.Method)
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			gotBody = string(body)
+			w.Write([]byte("{}")) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	repo := models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+	}
+	var data []models.InsightsData
+	for i := 0; i < 8; i++ {
+		data = append(data, models.InsightsData{Title: fmt.Sprintf("data%d", i), Value: i})
+	}
+	err = client.CreateInsightsReport(repo, models.PullRequest{Num: 1, HeadCommit: "sha"}, "atlantis-plan-abc", models.InsightsReport{
+		Title:   "Atlantis plan: dir: . workspace: default",
+		Details: strings.Repeat("a", 2001),
+		Passed:  true,
+		Data:    data,
+	})
+	Ok(t, err)
+	Equals(t, `{"title":"Atlantis plan: dir: . workspace: default","details":"`+strings.Repeat("a", 1997)+`...","result":"PASS","reporter":"Atlantis","data":[`+
+		`{"title":"data0","type":"NUMBER","value":0},{"title":"data1","type":"NUMBER","value":1},{"title":"data2","type":"NUMBER","value":2},`+
+		`{"title":"data3","type":"NUMBER","value":3},{"title":"data4","type":"NUMBER","value":4},{"title":"data5","type":"NUMBER","value":5}]}`, gotBody)
+}
+
 func TestClient_MarkdownPullLink(t *testing.T) {
 	client, err := bitbucketserver.NewClient(nil, "u", "p", "https://base-url", "atlantis-url")
 	Ok(t, err)
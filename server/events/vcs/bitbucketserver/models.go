@@ -31,6 +31,9 @@ type PullRequest struct {
 	State     *string `json:"state,omitempty" validate:"required"`
 	Reviewers []struct {
 		Approved *bool `json:"approved,omitempty" validate:"required"`
+		User     *struct {
+			Name *string `json:"name,omitempty"`
+		} `json:"user,omitempty"`
 	} `json:"reviewers,omitempty" validate:"required"`
 }
 
@@ -75,3 +78,27 @@ type MergeStatus struct {
 	CanMerge   *bool `json:"canMerge,omitempty" validate:"required"`
 	Conflicted *bool `json:"conflicted,omitempty" validate:"required"`
 }
+
+// Commits is a page of commits. Only how many there are is used.
+type Commits struct {
+	Values []struct {
+		ID *string `json:"id,omitempty"`
+	} `json:"values,omitempty"`
+}
+
+// InsightsReport is a Code Insights report.
+type InsightsReport struct {
+	Title    string         `json:"title"`
+	Details  string         `json:"details,omitempty"`
+	Result   string         `json:"result"`
+	Reporter string         `json:"reporter"`
+	Link     string         `json:"link,omitempty"`
+	Data     []InsightsData `json:"data"`
+}
+
+// InsightsData is a value shown in a Code Insights report.
+type InsightsData struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
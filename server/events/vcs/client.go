@@ -27,7 +27,13 @@ type Client interface {
 	CreateComment(repo models.Repo, pullNum int, comment string) error
 	HidePrevPlanComments(repo models.Repo, pullNum int) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
+	// GetPullApprovers returns the usernames of the users other than the
+	// author whose approvals of pull are current.
+	GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error)
 	PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error)
+	// PullIsUndiverged returns true if pull's branch contains the latest
+	// commit of the branch it's being merged into, i.e. it isn't behind.
+	PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error)
 	// UpdateStatus updates the commit status to state for pull. src is the
 	// source of this status. This should be relatively static across runs,
 	// ex. atlantis/plan or atlantis/apply.
@@ -16,6 +16,7 @@ package vcs
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -40,13 +41,20 @@ type GithubClient struct {
 	ctx            context.Context
 }
 
-// NewGithubClient returns a valid GitHub client.
-func NewGithubClient(hostname string, user string, pass string) (*GithubClient, error) {
+// NewGithubClient returns a valid GitHub client. Its requests are sent with
+// httpClient's transport and timeout. If httpClient is nil,
+// http.DefaultClient is used.
+func NewGithubClient(httpClient *http.Client, hostname string, user string, pass string) (*GithubClient, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
 	tp := github.BasicAuthTransport{
-		Username: strings.TrimSpace(user),
-		Password: strings.TrimSpace(pass),
+		Username:  strings.TrimSpace(user),
+		Password:  strings.TrimSpace(pass),
+		Transport: httpClient.Transport,
 	}
-	client := github.NewClient(tp.Client())
+	authClient := &http.Client{Transport: &tp, Timeout: httpClient.Timeout}
+	client := github.NewClient(authClient)
 	graphqlURL := "https://api.github.com/graphql"
 
 	// If we're using github.com then we don't need to do any additional configuration
@@ -75,7 +83,7 @@ func NewGithubClient(hostname string, user string, pass string) (*GithubClient,
 	// shurcooL's libraries completely.
 	v4MutateClient := graphql.NewClient(
 		graphqlURL,
-		tp.Client(),
+		authClient,
 		graphql.WithHeader("Accept", "application/vnd.github.queen-beryl-preview+json"),
 	)
 
@@ -200,6 +208,21 @@ func (g *GithubClient) HidePrevPlanComments(repo models.Repo, pullNum int) error
 	return nil
 }
 
+// githubReactions are the reactions we add to comment commands. GitHub only
+// allows a few reactions so there's no check mark.
+var githubReactions = map[models.CommentReaction]string{
+	models.ReceivedReaction:  "eyes",
+	models.StartedReaction:   "rocket",
+	models.SucceededReaction: "+1",
+	models.FailedReaction:    "confused",
+}
+
+// ReactToComment adds the reaction to the comment with commentID.
+func (g *GithubClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction models.CommentReaction) error {
+	_, _, err := g.client.Reactions.CreateIssueCommentReaction(g.ctx, repo.Owner, repo.Name, commentID, githubReactions[reaction])
+	return err
+}
+
 // PullIsApproved returns true if the pull request was approved.
 func (g *GithubClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	nextPage := 0
@@ -227,6 +250,75 @@ func (g *GithubClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return false, nil
 }
 
+// GetPullApprovers returns the logins of the users whose latest review of the
+// pull request approved it. Approvals that were dismissed or followed by a
+// review requesting changes don't count.
+func (g *GithubClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var order []string
+	approved := make(map[string]bool)
+	nextPage := 0
+	for {
+		opts := github.ListOptions{
+			PerPage: 300,
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		pageReviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting reviews")
+		}
+		// Reviews are listed oldest first so later reviews override.
+		for _, review := range pageReviews {
+			login := review.GetUser().GetLogin()
+			if login == "" || strings.EqualFold(login, pull.Author) {
+				continue
+			}
+			switch review.GetState() {
+			case "APPROVED":
+				if _, ok := approved[login]; !ok {
+					order = append(order, login)
+				}
+				approved[login] = true
+			case "CHANGES_REQUESTED", "DISMISSED":
+				approved[login] = false
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	var approvers []string
+	for _, login := range order {
+		if approved[login] {
+			approvers = append(approvers, login)
+		}
+	}
+	return approvers, nil
+}
+
+// IsTeamMember returns true if user is an active member of team, of the form
+// org/team.
+func (g *GithubClient) IsTeamMember(repo models.Repo, team string, user string) (bool, error) {
+	i := strings.Index(team, "/")
+	if i == -1 {
+		return false, fmt.Errorf("team %q must be of the form org/team", team)
+	}
+	t, _, err := g.client.Teams.GetTeamBySlug(g.ctx, team[:i], team[i+1:])
+	if err != nil {
+		return false, errors.Wrapf(err, "getting team %s", team)
+	}
+	membership, resp, err := g.client.Teams.GetTeamMembership(g.ctx, t.GetID(), user)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting membership of %s in team %s", user, team)
+	}
+	return membership.GetState() == "active", nil
+}
+
 // PullIsMergeable returns true if the pull request is mergeable.
 func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	githubPR, err := g.GetPullRequest(repo, pull.Num)
@@ -249,6 +341,16 @@ func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	return true, nil
 }
 
+// PullIsUndiverged returns true if the pull request's head commit isn't behind
+// its base branch.
+func (g *GithubClient) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	comparison, _, err := g.client.Repositories.CompareCommits(g.ctx, repo.Owner, repo.Name, pull.BaseBranch, pull.HeadCommit)
+	if err != nil {
+		return false, errors.Wrap(err, "comparing head commit to base branch")
+	}
+	return comparison.GetBehindBy() == 0, nil
+}
+
 // GetPullRequest returns the pull request.
 func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRequest, error) {
 	pull, _, err := g.client.PullRequests.Get(g.ctx, repo.Owner, repo.Name, num)
@@ -320,6 +422,147 @@ func (g *GithubClient) MergePull(pull models.PullRequest) error {
 	return nil
 }
 
+// RequestReviewers requests reviews of pull from reviewers. Reviewers of the
+// form "org/team" are requested as teams.
+func (g *GithubClient) RequestReviewers(repo models.Repo, pull models.PullRequest, reviewers []string) error {
+	var req github.ReviewersRequest
+	for _, r := range reviewers {
+		if i := strings.Index(r, "/"); i != -1 {
+			req.TeamReviewers = append(req.TeamReviewers, r[i+1:])
+		} else {
+			req.Reviewers = append(req.Reviewers, r)
+		}
+	}
+	_, _, err := g.client.PullRequests.RequestReviewers(g.ctx, repo.Owner, repo.Name, pull.Num, req)
+	return errors.Wrap(err, "requesting reviewers")
+}
+
+// CreateGist creates a secret gist with a file called filename and returns
+// the URL to view it. Classic personal access tokens need the gist scope.
+func (g *GithubClient) CreateGist(description string, filename string, content string) (string, error) {
+	gist, _, err := g.client.Gists.Create(g.ctx, &github.Gist{
+		Description: github.String(description),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(filename): {Content: github.String(content)},
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "creating gist")
+	}
+	return gist.GetHTMLURL(), nil
+}
+
+// githubWebhookEvents are the events Atlantis's webhook subscribes to.
+var githubWebhookEvents = []string{"issue_comment", "pull_request", "pull_request_review", "push"}
+
+// CheckCredentials returns an error if our token is invalid or, for classic
+// personal access tokens, if it doesn't have the repo scope. Fine-grained
+// tokens don't report their permissions so they're only checked for
+// validity.
+func (g *GithubClient) CheckCredentials() error {
+	_, resp, err := g.client.Users.Get(g.ctx, "")
+	if err != nil {
+		return errors.Wrap(err, "getting authenticated user")
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil
+	}
+	var scopes []string
+	for _, h := range header {
+		for _, scope := range strings.Split(h, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	for _, scope := range scopes {
+		if scope == "repo" {
+			return nil
+		}
+	}
+	return fmt.Errorf("token for user %q needs the repo scope, it has: %s", g.user, formatScopes(scopes))
+}
+
+// CheckWebhookPermissions returns an error if our user can't manage the
+// webhooks of the repo.
+func (g *GithubClient) CheckWebhookPermissions(repoFullName string) error {
+	owner, name := models.SplitRepoFullName(repoFullName)
+	repo, _, err := g.client.Repositories.Get(g.ctx, owner, name)
+	if err != nil {
+		return errors.Wrap(err, "getting repo")
+	}
+	if repo.Permissions == nil || !(*repo.Permissions)["admin"] {
+		return fmt.Errorf("user %q needs admin access to %s to manage its webhooks", g.user, repoFullName)
+	}
+	return nil
+}
+
+// EnsureWebhook creates a webhook that sends Atlantis's events to hookURL
+// unless the repo already has one. It returns the ID of the webhook and
+// whether it was created.
+func (g *GithubClient) EnsureWebhook(repoFullName string, hookURL string, secret string) (string, bool, error) {
+	owner, name := models.SplitRepoFullName(repoFullName)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		hooks, resp, err := g.client.Repositories.ListHooks(g.ctx, owner, name, opts)
+		if err != nil {
+			return "", false, errors.Wrap(err, "listing webhooks")
+		}
+		for _, hook := range hooks {
+			if u, ok := hook.Config["url"].(string); ok && u == hookURL {
+				return fmt.Sprintf("%d", hook.GetID()), false, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	config := map[string]interface{}{
+		"url":          hookURL,
+		"content_type": "json",
+	}
+	if secret != "" {
+		config["secret"] = secret
+	}
+	hook, _, err := g.client.Repositories.CreateHook(g.ctx, owner, name, &github.Hook{
+		Config: config,
+		Events: githubWebhookEvents,
+		Active: github.Bool(true),
+	})
+	if err != nil {
+		return "", false, errors.Wrap(err, "creating webhook")
+	}
+	return fmt.Sprintf("%d", hook.GetID()), true, nil
+}
+
+// ListOrgRepos returns the full names of the repos in org that aren't
+// archived. If topic is set, only the repos with that topic are returned.
+func (g *GithubClient) ListOrgRepos(org string, topic string) ([]string, error) {
+	var names []string
+	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := g.client.Repositories.ListByOrg(g.ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if repo.GetArchived() || (topic != "" && !containsString(repo.Topics, topic)) {
+				continue
+			}
+			names = append(names, repo.GetFullName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return names, nil
+}
+
 // MarkdownPullLink specifies the string used in a pull request comment to reference another pull request.
 func (g *GithubClient) MarkdownPullLink(pull models.PullRequest) (string, error) {
 	return fmt.Sprintf("#%d", pull.Num), nil
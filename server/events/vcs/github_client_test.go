@@ -59,7 +59,7 @@ func TestGithubClient_GetModifiedFiles(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -114,7 +114,7 @@ func TestGithubClient_GetModifiedFilesMovedFile(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -208,7 +208,7 @@ func TestGithubClient_PaginatesComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -294,7 +294,7 @@ func TestGithubClient_HideOldComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -358,7 +358,7 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -380,6 +380,232 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestGithubClient_RequestReviewers(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/requested_reviewers":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				Equals(t, `{"reviewers":["alice"],"team_reviewers":["team"]}`+"\n", string(body))
+				defer r.Body.Close() // nolint: errcheck
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}")) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	err = client.RequestReviewers(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+		VCSHost: models.VCSHost{
+			Type:     models.Github,
+			Hostname: "github.com",
+		},
+	}, models.PullRequest{
+		Num: 1,
+	}, []string{"alice", "org/team"})
+	Ok(t, err)
+}
+
+func TestGithubClient_CreateGist(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/gists":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				Equals(t, `{"description":"Plan of project","public":false,"files":{"plan.txt":{"content":"output"}}}`+"\n", string(body))
+				defer r.Body.Close() // nolint: errcheck
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"html_url": "https://gist.github.com/abc123"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	gistURL, err := client.CreateGist("Plan of project", "plan.txt", "output")
+	Ok(t, err)
+	Equals(t, "https://gist.github.com/abc123", gistURL)
+}
+
+func TestGithubClient_ReactToComment(t *testing.T) {
+	var reactions []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/issues/comments/5/reactions":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				reactions = append(reactions, strings.TrimSpace(string(body)))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}")) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+	for _, reaction := range []models.CommentReaction{models.ReceivedReaction, models.StartedReaction, models.SucceededReaction, models.FailedReaction} {
+		Ok(t, client.ReactToComment(repo, 1, 5, reaction))
+	}
+	Equals(t, []string{`{"content":"eyes"}`, `{"content":"rocket"}`, `{"content":"+1"}`, `{"content":"confused"}`}, reactions)
+}
+
+func TestGithubClient_EnsureWebhook(t *testing.T) {
+	cases := []struct {
+		description string
+		existingURL string
+		expID       string
+		expCreated  bool
+	}{
+		{
+			"no webhook",
+			"https://other.com/events",
+			"2",
+			true,
+		},
+		{
+			"webhook exists",
+			"https://atlantis.com/events",
+			"1",
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch {
+					case r.Method == "GET" && r.URL.Path == "/api/v3/repos/owner/repo/hooks":
+						w.Write([]byte(fmt.Sprintf(`[{"id": 1, "config": {"url": %q}}]`, c.existingURL))) // nolint: errcheck
+					case r.Method == "POST" && r.URL.Path == "/api/v3/repos/owner/repo/hooks":
+						body, err := ioutil.ReadAll(r.Body)
+						Ok(t, err)
+						Equals(t, `{"name":"web","config":{"content_type":"json","secret":"secret","url":"https://atlantis.com/events"},"events":["issue_comment","pull_request","pull_request_review","push"],"active":true}`+"\n", string(body))
+						w.WriteHeader(http.StatusCreated)
+						w.Write([]byte(`{"id": 2}`)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			id, created, err := client.EnsureWebhook("owner/repo", "https://atlantis.com/events", "secret")
+			Ok(t, err)
+			Equals(t, c.expID, id)
+			Equals(t, c.expCreated, created)
+		})
+	}
+}
+
+func TestGithubClient_ListOrgRepos(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/api/v3/orgs/owner/repos" && r.URL.Query().Get("page") == "":
+				w.Header().Set("Link", fmt.Sprintf(`<https://%s/api/v3/orgs/owner/repos?page=2>; rel="next"`, r.Host))
+				w.Write([]byte(`[{"full_name": "owner/app", "topics": ["atlantis-enabled"]}, {"full_name": "owner/docs"}]`)) // nolint: errcheck
+			case r.URL.Path == "/api/v3/orgs/owner/repos" && r.URL.Query().Get("page") == "2":
+				w.Write([]byte(`[{"full_name": "owner/old", "archived": true, "topics": ["atlantis-enabled"]}, {"full_name": "owner/infra", "topics": ["go", "atlantis-enabled"]}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	repos, err := client.ListOrgRepos("owner", "")
+	Ok(t, err)
+	Equals(t, []string{"owner/app", "owner/docs", "owner/infra"}, repos)
+	repos, err = client.ListOrgRepos("owner", "atlantis-enabled")
+	Ok(t, err)
+	Equals(t, []string{"owner/app", "owner/infra"}, repos)
+}
+
+func TestGithubClient_CheckWebhookPermissions(t *testing.T) {
+	cases := []struct {
+		permissions string
+		expErr      string
+	}{
+		{
+			`{"admin": true, "push": true, "pull": true}`,
+			"",
+		},
+		{
+			`{"admin": false, "push": true, "pull": true}`,
+			`user "user" needs admin access to owner/repo to manage its webhooks`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.permissions, func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.RequestURI {
+					case "/api/v3/repos/owner/repo":
+						w.Write([]byte(fmt.Sprintf(`{"id": 1, "permissions": %s}`, c.permissions))) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			err = client.CheckWebhookPermissions("owner/repo")
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
 func TestGithubClient_PullIsApproved(t *testing.T) {
 	respTemplate := `[
 		{
@@ -444,7 +670,7 @@ func TestGithubClient_PullIsApproved(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -465,6 +691,52 @@ func TestGithubClient_PullIsApproved(t *testing.T) {
 	Equals(t, false, approved)
 }
 
+// Test that only users whose latest review approved the pull request are
+// approvers.
+func TestGithubClient_GetPullApprovers(t *testing.T) {
+	reviews := `[
+  {"user": {"login": "alice"}, "state": "APPROVED"},
+  {"user": {"login": "bob"}, "state": "APPROVED"},
+  {"user": {"login": "carol"}, "state": "APPROVED"},
+  {"user": {"login": "bob"}, "state": "COMMENTED"},
+  {"user": {"login": "carol"}, "state": "CHANGES_REQUESTED"},
+  {"user": {"login": "dave"}, "state": "DISMISSED"},
+  {"user": {"login": "author"}, "state": "APPROVED"}
+]`
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/reviews?per_page=300":
+				w.Write([]byte(reviews)) // nolint: errcheck
+				return
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	approvers, err := client.GetPullApprovers(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+		VCSHost: models.VCSHost{
+			Type:     models.Github,
+			Hostname: "github.com",
+		},
+	}, models.PullRequest{
+		Num:    1,
+		Author: "author",
+	})
+	Ok(t, err)
+	Equals(t, []string{"alice", "bob"}, approvers)
+}
+
 func TestGithubClient_PullIsMergeable(t *testing.T) {
 	cases := []struct {
 		state        string
@@ -535,7 +807,7 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -558,6 +830,55 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 	}
 }
 
+func TestGithubClient_PullIsUndiverged(t *testing.T) {
+	cases := []struct {
+		behindBy      int
+		expUndiverged bool
+	}{
+		{0, true},
+		{1, false},
+		{10, false},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("behind by %d", c.behindBy), func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.RequestURI {
+					case "/api/v3/repos/owner/repo/compare/master...abc123":
+						w.Write([]byte(fmt.Sprintf(`{"status": "diverged", "ahead_by": 1, "behind_by": %d}`, c.behindBy))) // nolint: errcheck
+						return
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+						return
+					}
+				}))
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			actUndiverged, err := client.PullIsUndiverged(models.Repo{
+				FullName: "owner/repo",
+				Owner:    "owner",
+				Name:     "repo",
+				VCSHost: models.VCSHost{
+					Type:     models.Github,
+					Hostname: "github.com",
+				},
+			}, models.PullRequest{
+				Num:        1,
+				BaseBranch: "master",
+				HeadCommit: "abc123",
+			})
+			Ok(t, err)
+			Equals(t, c.expUndiverged, actUndiverged)
+		})
+	}
+}
+
 func TestGithubClient_MergePullHandlesError(t *testing.T) {
 	cases := []struct {
 		code    int
@@ -617,7 +938,7 @@ func TestGithubClient_MergePullHandlesError(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -740,7 +1061,7 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -764,8 +1085,62 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 	}
 }
 
+func TestGithubClient_CheckCredentials(t *testing.T) {
+	cases := []struct {
+		description string
+		scopes      []string
+		expErr      string
+	}{
+		{
+			"repo scope",
+			[]string{"repo, read:org"},
+			"",
+		},
+		{
+			"missing repo scope",
+			[]string{"public_repo, read:org"},
+			`token for user "user" needs the repo scope, it has: public_repo, read:org`,
+		},
+		{
+			"no scopes",
+			[]string{""},
+			`token for user "user" needs the repo scope, it has: no scopes`,
+		},
+		{
+			"fine-grained token",
+			nil,
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Equals(t, "/api/v3/user", r.RequestURI)
+					for _, scopes := range c.scopes {
+						w.Header().Add("X-OAuth-Scopes", scopes)
+					}
+					w.Write([]byte(`{"login": "user"}`)) // nolint: errcheck
+				}))
+			defer testServer.Close()
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(nil, testServerURL.Host, "user", "pass")
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			err = client.CheckCredentials()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
 func TestGithubClient_MarkdownPullLink(t *testing.T) {
-	client, err := vcs.NewGithubClient("hostname", "user", "pass")
+	client, err := vcs.NewGithubClient(nil, "hostname", "user", "pass")
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
@@ -16,6 +16,7 @@ package vcs
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -33,6 +34,12 @@ type GitlabClient struct {
 	Client *gitlab.Client
 	// Version is set to the server version.
 	Version *version.Version
+	// User is the username of the Atlantis user. It's used to find the
+	// discussions Atlantis started.
+	User string
+	// PlanDiscussions is true if plans should be posted as resolvable
+	// discussion threads instead of comments.
+	PlanDiscussions bool
 }
 
 // commonMarkSupported is a version constraint that is true when this version of
@@ -43,10 +50,11 @@ var commonMarkSupported = MustConstraint(">=11.1")
 // gitlabClientUnderTest is true if we're running under go test.
 var gitlabClientUnderTest = false
 
-// NewGitlabClient returns a valid GitLab client.
-func NewGitlabClient(hostname string, token string, logger *logging.SimpleLogger) (*GitlabClient, error) {
+// NewGitlabClient returns a valid GitLab client that sends its requests with
+// httpClient. If httpClient is nil, http.DefaultClient is used.
+func NewGitlabClient(httpClient *http.Client, hostname string, token string, logger *logging.SimpleLogger) (*GitlabClient, error) {
 	client := &GitlabClient{
-		Client: gitlab.NewClient(nil, token),
+		Client: gitlab.NewClient(httpClient, token),
 	}
 
 	// If not using gitlab.com we need to set the URL to the API.
@@ -133,17 +141,87 @@ func (g *GitlabClient) GetModifiedFiles(repo models.Repo, pull models.PullReques
 	return files, nil
 }
 
-// CreateComment creates a comment on the merge request.
+// CreateComment creates a comment on the merge request. If PlanDiscussions
+// is set, plans are posted as resolvable discussion threads instead.
 func (g *GitlabClient) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	if g.PlanDiscussions && isPlanComment(comment) {
+		_, _, err := g.Client.Discussions.CreateMergeRequestDiscussion(repo.FullName, pullNum, &gitlab.CreateMergeRequestDiscussionOptions{Body: gitlab.String(comment)})
+		return err
+	}
 	_, _, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
 	return err
 }
 
+// HidePrevPlanComments resolves the discussion threads of previous plans so
+// GitLab collapses them. Plain comments can't be hidden so this does nothing
+// unless PlanDiscussions is set.
 func (g *GitlabClient) HidePrevPlanComments(repo models.Repo, pullNum int) error {
+	if !g.PlanDiscussions {
+		return nil
+	}
+	opts := &gitlab.ListMergeRequestDiscussionsOptions{PerPage: 100}
+	for {
+		discussions, resp, err := g.Client.Discussions.ListMergeRequestDiscussions(repo.FullName, pullNum, opts)
+		if err != nil {
+			return errors.Wrap(err, "listing discussions")
+		}
+		for _, d := range discussions {
+			if d.IndividualNote || len(d.Notes) == 0 {
+				continue
+			}
+			first := d.Notes[0]
+			// Using a case insensitive compare here because usernames aren't
+			// case sensitive.
+			if !first.Resolvable || first.Resolved || !strings.EqualFold(first.Author.Username, g.User) || !isPlanComment(first.Body) {
+				continue
+			}
+			if _, _, err := g.Client.Discussions.ResolveMergeRequestDiscussion(repo.FullName, pullNum, d.ID, &gitlab.ResolveMergeRequestDiscussionOptions{Resolved: gitlab.Bool(true)}); err != nil {
+				return errors.Wrapf(err, "resolving discussion %s", d.ID)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
 	return nil
 }
 
-// PullIsApproved returns true if the merge request was approved.
+// isPlanComment returns true if comment is the output of a plan. Like for
+// GitHub, it's a crude check: the comment templates include the command name
+// in the first line.
+func isPlanComment(comment string) bool {
+	firstLine := strings.ToLower(strings.SplitN(comment, "\n", 2)[0])
+	return strings.Contains(firstLine, models.PlanCommand.String())
+}
+
+// gitlabReactions are the award emoji we add to comment commands.
+var gitlabReactions = map[models.CommentReaction]string{
+	models.ReceivedReaction:  "eyes",
+	models.StartedReaction:   "rocket",
+	models.SucceededReaction: "white_check_mark",
+	models.FailedReaction:    "x",
+}
+
+// ReactToComment awards the emoji for reaction to the note with commentID.
+func (g *GitlabClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction models.CommentReaction) error {
+	_, _, err := g.Client.AwardEmoji.CreateMergeRequestAwardEmojiOnNote(repo.FullName, pullNum, int(commentID), &gitlab.CreateAwardEmojiOptions{Name: gitlabReactions[reaction]})
+	return err
+}
+
+// approvalState is the response of the approval_state API, which lists the
+// merge request's approval rules and whether each was satisfied. It's only
+// available on GitLab Premium.
+type approvalState struct {
+	Rules []struct {
+		Name     string `json:"name"`
+		Approved bool   `json:"approved"`
+	} `json:"rules"`
+}
+
+// PullIsApproved returns true if the merge request was approved. If the
+// project has approval rules, every rule must be satisfied, otherwise at
+// least one user must have approved it.
 func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
 	if err != nil {
@@ -152,7 +230,47 @@ func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	if approvals.ApprovalsLeft > 0 {
 		return false, nil
 	}
-	return true, nil
+
+	// Rules can require approvals from specific users or code owners, which
+	// approvals_left doesn't always account for.
+	req, err := g.Client.NewRequest("GET", fmt.Sprintf("projects/%s/merge_requests/%d/approval_state", url.QueryEscape(repo.FullName), pull.Num), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	state := new(approvalState)
+	resp, err := g.Client.Do(req, state)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		// GitLab Core doesn't have approval rules.
+	case err != nil:
+		return false, errors.Wrap(err, "getting approval rules")
+	case len(state.Rules) > 0:
+		for _, rule := range state.Rules {
+			if !rule.Approved {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return approvals.ApprovalsRequired > 0 || len(approvals.ApprovedBy) > 0, nil
+}
+
+// GetPullApprovers returns the usernames of the users who approved the merge
+// request. GitLab resets approvals itself when new commits are pushed if the
+// project is configured to.
+func (g *GitlabClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting approvals")
+	}
+	var approvers []string
+	for _, a := range approvals.ApprovedBy {
+		if a == nil || a.User == nil || strings.EqualFold(a.User.Username, pull.Author) {
+			continue
+		}
+		approvers = append(approvers, a.User.Username)
+	}
+	return approvers, nil
 }
 
 // PullIsMergeable returns true if the merge request can be merged.
@@ -179,6 +297,18 @@ func (g *GitlabClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	return false, nil
 }
 
+// PullIsUndiverged returns true if the merge request's branch doesn't have any
+// commits from its target branch missing.
+func (g *GitlabClient) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	mr, _, err := g.Client.MergeRequests.GetMergeRequest(repo.FullName, pull.Num, &gitlab.GetMergeRequestsOptions{
+		IncludeDivergedCommitsCount: gitlab.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+	return mr.DivergedCommitsCount == 0, nil
+}
+
 // UpdateStatus updates the build status of a commit.
 func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	gitlabState := gitlab.Failed
@@ -204,6 +334,21 @@ func (g *GitlabClient) GetMergeRequest(repoFullName string, pullNum int) (*gitla
 	return mr, err
 }
 
+// GetPullHeadRepo returns the full name and clone URL of the project the
+// merge request's changes are from, which is a fork for merge requests from
+// forks.
+func (g *GitlabClient) GetPullHeadRepo(repo models.Repo, pullNum int) (string, string, error) {
+	mr, err := g.GetMergeRequest(repo.FullName, pullNum)
+	if err != nil {
+		return "", "", err
+	}
+	project, _, err := g.Client.Projects.GetProject(mr.SourceProjectID, nil)
+	if err != nil {
+		return "", "", err
+	}
+	return project.PathWithNamespace, project.HTTPURLToRepo, nil
+}
+
 // MergePull merges the merge request.
 func (g *GitlabClient) MergePull(pull models.PullRequest) error {
 	commitMsg := common.AutomergeCommitMsg
@@ -216,6 +361,149 @@ func (g *GitlabClient) MergePull(pull models.PullRequest) error {
 	return errors.Wrap(err, "unable to merge merge request, it may not be in a mergeable state")
 }
 
+// CheckCredentials returns an error if our token is invalid or if it doesn't
+// have the api scope. GitLab before 15.5 doesn't report a token's scopes so
+// they're only checked for validity.
+func (g *GitlabClient) CheckCredentials() error {
+	if _, _, err := g.Client.Users.CurrentUser(); err != nil {
+		return errors.Wrap(err, "getting authenticated user")
+	}
+	req, err := g.Client.NewRequest("GET", "personal_access_tokens/self", nil, nil)
+	if err != nil {
+		return err
+	}
+	token := struct {
+		Scopes []string `json:"scopes"`
+	}{}
+	resp, err := g.Client.Do(req, &token)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "getting token")
+	}
+	for _, scope := range token.Scopes {
+		if scope == "api" {
+			return nil
+		}
+	}
+	return fmt.Errorf("token needs the api scope, it has: %s", formatScopes(token.Scopes))
+}
+
+// CheckWebhookPermissions returns an error if our user can't manage the
+// webhooks of the project.
+func (g *GitlabClient) CheckWebhookPermissions(repoFullName string) error {
+	project, _, err := g.Client.Projects.GetProject(repoFullName, nil)
+	if err != nil {
+		return errors.Wrap(err, "getting project")
+	}
+	if project.Permissions != nil {
+		if p := project.Permissions.ProjectAccess; p != nil && p.AccessLevel >= gitlab.MaintainerPermissions {
+			return nil
+		}
+		if p := project.Permissions.GroupAccess; p != nil && p.AccessLevel >= gitlab.MaintainerPermissions {
+			return nil
+		}
+	}
+	return fmt.Errorf("token needs maintainer access to %s to manage its webhooks", repoFullName)
+}
+
+// EnsureWebhook creates a webhook that sends Atlantis's events to hookURL
+// unless the project or one of its groups already has one. It returns the ID
+// of the webhook and whether it was created. Group webhooks' IDs are prefixed
+// with "group:".
+func (g *GitlabClient) EnsureWebhook(repoFullName string, hookURL string, secret string) (string, bool, error) {
+	if id, ok := g.findGroupWebhook(repoFullName, hookURL); ok {
+		return id, false, nil
+	}
+
+	opts := &gitlab.ListProjectHooksOptions{PerPage: 100}
+	for {
+		hooks, resp, err := g.Client.Projects.ListProjectHooks(repoFullName, opts)
+		if err != nil {
+			return "", false, errors.Wrap(err, "listing webhooks")
+		}
+		for _, hook := range hooks {
+			if hook.URL == hookURL {
+				return fmt.Sprintf("%d", hook.ID), false, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	hookOpts := &gitlab.AddProjectHookOptions{
+		URL:                 gitlab.String(hookURL),
+		MergeRequestsEvents: gitlab.Bool(true),
+		NoteEvents:          gitlab.Bool(true),
+		PushEvents:          gitlab.Bool(true),
+	}
+	if secret != "" {
+		hookOpts.Token = gitlab.String(secret)
+	}
+	hook, _, err := g.Client.Projects.AddProjectHook(repoFullName, hookOpts)
+	if err != nil {
+		return "", false, errors.Wrap(err, "creating webhook")
+	}
+	return fmt.Sprintf("%d", hook.ID), true, nil
+}
+
+// findGroupWebhook looks for a webhook to hookURL in the groups the project
+// is in, starting with its closest parent. Group webhooks are only available
+// on GitLab Premium and need owner access to list so any errors are ignored
+// and we fall back to a project webhook.
+func (g *GitlabClient) findGroupWebhook(repoFullName string, hookURL string) (string, bool) {
+	parts := strings.Split(repoFullName, "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		group := strings.Join(parts[:i], "/")
+		req, err := g.Client.NewRequest("GET", fmt.Sprintf("groups/%s/hooks", url.QueryEscape(group)), &gitlab.ListOptions{PerPage: 100}, nil)
+		if err != nil {
+			return "", false
+		}
+		var hooks []*gitlab.ProjectHook
+		if _, err := g.Client.Do(req, &hooks); err != nil {
+			continue
+		}
+		for _, hook := range hooks {
+			if hook.URL == hookURL {
+				return fmt.Sprintf("group:%d", hook.ID), true
+			}
+		}
+	}
+	return "", false
+}
+
+// ListOrgRepos returns the full names of the projects in group and its
+// subgroups that aren't archived. If topic is set, only the projects with
+// that topic are returned.
+func (g *GitlabClient) ListOrgRepos(group string, topic string) ([]string, error) {
+	var names []string
+	opts := &gitlab.ListGroupProjectsOptions{
+		ListOptions:      gitlab.ListOptions{PerPage: 100},
+		Archived:         gitlab.Bool(false),
+		IncludeSubgroups: gitlab.Bool(true),
+	}
+	for {
+		projects, resp, err := g.Client.Groups.ListGroupProjects(group, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			if project.Archived || (topic != "" && !containsString(project.TagList, topic)) {
+				continue
+			}
+			names = append(names, project.PathWithNamespace)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return names, nil
+}
+
 // MarkdownPullLink specifies the string used in a pull request comment to reference another pull request.
 func (g *GitlabClient) MarkdownPullLink(pull models.PullRequest) (string, error) {
 	return fmt.Sprintf("#%d", pull.Num), nil
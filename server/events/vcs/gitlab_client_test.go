@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	version "github.com/hashicorp/go-version"
@@ -54,7 +55,7 @@ func TestNewGitlabClient_BaseURL(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.Hostname, func(t *testing.T) {
-			client, err := NewGitlabClient(c.Hostname, "token", nil)
+			client, err := NewGitlabClient(nil, c.Hostname, "token", nil)
 			Ok(t, err)
 			Equals(t, c.ExpBaseURL, client.Client.BaseURL().String())
 		})
@@ -232,10 +233,429 @@ func TestGitlabClient_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestGitlabClient_CheckCredentials(t *testing.T) {
+	cases := []struct {
+		description string
+		// tokenResp is the response to GET personal_access_tokens/self. If
+		// it's empty, the endpoint doesn't exist.
+		tokenResp string
+		expErr    string
+	}{
+		{
+			"api scope",
+			`{"scopes": ["read_user", "api"]}`,
+			"",
+		},
+		{
+			"missing api scope",
+			`{"scopes": ["read_api"]}`,
+			"token needs the api scope, it has: read_api",
+		},
+		{
+			"gitlab before 15.5",
+			"",
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/api/v4/user":
+						w.Write([]byte(`{"username": "atlantis"}`)) // nolint: errcheck
+					case "/api/v4/personal_access_tokens/self":
+						if c.tokenResp == "" {
+							http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+							return
+						}
+						w.Write([]byte(c.tokenResp)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			internalClient := gitlab.NewClient(nil, "token")
+			Ok(t, internalClient.SetBaseURL(testServer.URL))
+			client := &GitlabClient{Client: internalClient}
+			err := client.CheckCredentials()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestGitlabClient_EnsureWebhook(t *testing.T) {
+	cases := []struct {
+		description      string
+		existingURL      string
+		existingGroupURL string
+		expID            string
+		expCreated       bool
+	}{
+		{
+			"no webhook",
+			"https://other.com/events",
+			"",
+			"2",
+			true,
+		},
+		{
+			"webhook exists",
+			"https://atlantis.com/events",
+			"",
+			"1",
+			false,
+		},
+		{
+			"group webhook exists",
+			"https://other.com/events",
+			"https://atlantis.com/events",
+			"group:3",
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch {
+					case r.Method == "GET" && r.URL.Path == "/api/v4/groups/owner/hooks":
+						if c.existingGroupURL == "" {
+							// Group webhooks need GitLab Premium.
+							http.Error(w, `{"message": "403 Forbidden"}`, http.StatusForbidden)
+							return
+						}
+						w.Write([]byte(fmt.Sprintf(`[{"id": 3, "url": %q}]`, c.existingGroupURL))) // nolint: errcheck
+					case r.Method == "GET" && r.URL.Path == "/api/v4/projects/owner/repo/hooks":
+						w.Write([]byte(fmt.Sprintf(`[{"id": 1, "url": %q}]`, c.existingURL))) // nolint: errcheck
+					case r.Method == "POST" && r.URL.Path == "/api/v4/projects/owner/repo/hooks":
+						body, err := ioutil.ReadAll(r.Body)
+						Ok(t, err)
+						Equals(t, `{"url":"https://atlantis.com/events","push_events":true,"merge_requests_events":true,"note_events":true,"token":"secret"}`, string(body))
+						w.Write([]byte(`{"id": 2}`)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			internalClient := gitlab.NewClient(nil, "token")
+			Ok(t, internalClient.SetBaseURL(testServer.URL))
+			client := &GitlabClient{Client: internalClient}
+			id, created, err := client.EnsureWebhook("owner/repo", "https://atlantis.com/events", "secret")
+			Ok(t, err)
+			Equals(t, c.expID, id)
+			Equals(t, c.expCreated, created)
+		})
+	}
+}
+
+// Test that we look for webhooks in every parent group of a project in a
+// subgroup, closest first.
+func TestGitlabClient_EnsureWebhook_Subgroups(t *testing.T) {
+	var requested []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = append(requested, r.Method+" "+r.URL.Path)
+			switch r.URL.Path {
+			case "/api/v4/groups/owner/sub/hooks":
+				w.Write([]byte(`[]`)) // nolint: errcheck
+			case "/api/v4/groups/owner/hooks":
+				w.Write([]byte(`[{"id": 3, "url": "https://atlantis.com/events"}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{Client: internalClient}
+	id, created, err := client.EnsureWebhook("owner/sub/repo", "https://atlantis.com/events", "secret")
+	Ok(t, err)
+	Equals(t, "group:3", id)
+	Equals(t, false, created)
+	Equals(t, []string{"GET /api/v4/groups/owner/sub/hooks", "GET /api/v4/groups/owner/hooks"}, requested)
+}
+
+func TestGitlabClient_ListOrgRepos(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/groups/owner/projects":
+				Equals(t, "false", r.URL.Query().Get("archived"))
+				Equals(t, "true", r.URL.Query().Get("include_subgroups"))
+				w.Write([]byte(`[{"path_with_namespace": "owner/app", "tag_list": ["atlantis-enabled"]}, {"path_with_namespace": "owner/sub/infra"}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{Client: internalClient}
+	repos, err := client.ListOrgRepos("owner", "")
+	Ok(t, err)
+	Equals(t, []string{"owner/app", "owner/sub/infra"}, repos)
+	repos, err = client.ListOrgRepos("owner", "atlantis-enabled")
+	Ok(t, err)
+	Equals(t, []string{"owner/app"}, repos)
+}
+
+func TestGitlabClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		description   string
+		approvals     string
+		approvalState string
+		exp           bool
+	}{
+		{
+			"approvals left",
+			`{"approvals_required": 2, "approvals_left": 1, "approved_by": [{"user": {"username": "a"}}]}`,
+			`{"rules": [{"name": "all", "approved": false}]}`,
+			false,
+		},
+		{
+			"no approvals required or given",
+			`{"approvals_required": 0, "approvals_left": 0, "approved_by": []}`,
+			"",
+			false,
+		},
+		{
+			"approved without rules",
+			`{"approvals_required": 0, "approvals_left": 0, "approved_by": [{"user": {"username": "a"}}]}`,
+			"",
+			true,
+		},
+		{
+			"unapproved code owner rule",
+			`{"approvals_required": 1, "approvals_left": 0, "approved_by": [{"user": {"username": "a"}}]}`,
+			`{"rules": [{"name": "All Members", "approved": true}, {"name": "infra/", "approved": false}]}`,
+			false,
+		},
+		{
+			"all rules approved",
+			`{"approvals_required": 1, "approvals_left": 0, "approved_by": [{"user": {"username": "a"}}]}`,
+			`{"rules": [{"name": "All Members", "approved": true}, {"name": "infra/", "approved": true}]}`,
+			true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/api/v4/projects/owner/repo/merge_requests/1/approvals":
+						w.Write([]byte(c.approvals)) // nolint: errcheck
+					case "/api/v4/projects/owner/repo/merge_requests/1/approval_state":
+						if c.approvalState == "" {
+							http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+							return
+						}
+						w.Write([]byte(c.approvalState)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			internalClient := gitlab.NewClient(nil, "token")
+			Ok(t, internalClient.SetBaseURL(testServer.URL))
+			client := &GitlabClient{Client: internalClient}
+			repo := models.Repo{FullName: "owner/repo"}
+			approved, err := client.PullIsApproved(repo, models.PullRequest{Num: 1})
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestGitlabClient_GetPullHeadRepo(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/projects/owner/repo/merge_requests/1":
+				w.Write([]byte(`{"iid": 1, "project_id": 1, "source_project_id": 2, "target_project_id": 1}`)) // nolint: errcheck
+			case "/api/v4/projects/2":
+				w.Write([]byte(`{"id": 2, "path_with_namespace": "fork/repo", "http_url_to_repo": "https://gitlab.com/fork/repo.git"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{Client: internalClient}
+	fullName, cloneURL, err := client.GetPullHeadRepo(models.Repo{FullName: "owner/repo"}, 1)
+	Ok(t, err)
+	Equals(t, "fork/repo", fullName)
+	Equals(t, "https://gitlab.com/fork/repo.git", cloneURL)
+}
+
+func TestGitlabClient_CreateComment_PlanDiscussions(t *testing.T) {
+	var requested []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = append(requested, r.Method+" "+r.URL.Path)
+			switch r.URL.Path {
+			case "/api/v4/projects/owner/repo/merge_requests/1/discussions":
+				w.Write([]byte(`{"id": "abc"}`)) // nolint: errcheck
+			case "/api/v4/projects/owner/repo/merge_requests/1/notes":
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{Client: internalClient, PlanDiscussions: true}
+	repo := models.Repo{FullName: "owner/repo"}
+	Ok(t, client.CreateComment(repo, 1, "Ran Plan for dir: `.` workspace: `default`"))
+	Ok(t, client.CreateComment(repo, 1, "Ran Apply for dir: `.` workspace: `default`"))
+	Equals(t, []string{
+		"POST /api/v4/projects/owner/repo/merge_requests/1/discussions",
+		"POST /api/v4/projects/owner/repo/merge_requests/1/notes",
+	}, requested)
+}
+
+func TestGitlabClient_ReactToComment(t *testing.T) {
+	var emoji []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/api/v4/projects/owner/repo/merge_requests/1/notes/5/award_emoji":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				emoji = append(emoji, string(body))
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{Client: internalClient}
+	repo := models.Repo{FullName: "owner/repo"}
+	for _, reaction := range []models.CommentReaction{models.ReceivedReaction, models.StartedReaction, models.SucceededReaction, models.FailedReaction} {
+		Ok(t, client.ReactToComment(repo, 1, 5, reaction))
+	}
+	Equals(t, []string{`{"name":"eyes"}`, `{"name":"rocket"}`, `{"name":"white_check_mark"}`, `{"name":"x"}`}, emoji)
+}
+
+// Test that only the unresolved plan discussions started by our user are
+// resolved.
+func TestGitlabClient_HidePrevPlanComments(t *testing.T) {
+	var resolved []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" && r.URL.Path == "/api/v4/projects/owner/repo/merge_requests/1/discussions":
+				w.Write([]byte(`[
+  {"id": "plan", "notes": [{"body": "Ran Plan for dir: ` + "`.`" + `", "author": {"username": "Atlantis"}, "resolvable": true}]},
+  {"id": "resolved", "notes": [{"body": "Ran Plan for dir: ` + "`.`" + `", "author": {"username": "atlantis"}, "resolvable": true, "resolved": true}]},
+  {"id": "apply", "notes": [{"body": "Ran Apply for dir: ` + "`.`" + `", "author": {"username": "atlantis"}, "resolvable": true}]},
+  {"id": "other-user", "notes": [{"body": "Ran Plan for dir: ` + "`.`" + `", "author": {"username": "someone"}, "resolvable": true}]},
+  {"id": "note", "individual_note": true, "notes": [{"body": "Ran Plan for dir: ` + "`.`" + `", "author": {"username": "atlantis"}}]}
+]`)) // nolint: errcheck
+			case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/api/v4/projects/owner/repo/merge_requests/1/discussions/"):
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				Equals(t, `{"resolved":true}`, string(body))
+				resolved = append(resolved, strings.TrimPrefix(r.URL.Path, "/api/v4/projects/owner/repo/merge_requests/1/discussions/"))
+				w.Write([]byte(`{}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	repo := models.Repo{FullName: "owner/repo"}
+
+	// Without discussions there's nothing to hide.
+	client := &GitlabClient{Client: internalClient, User: "atlantis"}
+	Ok(t, client.HidePrevPlanComments(repo, 1))
+	Equals(t, 0, len(resolved))
+
+	client.PlanDiscussions = true
+	Ok(t, client.HidePrevPlanComments(repo, 1))
+	Equals(t, []string{"plan"}, resolved)
+}
+
+func TestGitlabClient_CheckWebhookPermissions(t *testing.T) {
+	cases := []struct {
+		permissions string
+		expErr      string
+	}{
+		{
+			`{"project_access": {"access_level": 40}}`,
+			"",
+		},
+		{
+			`{"project_access": {"access_level": 30}, "group_access": {"access_level": 50}}`,
+			"",
+		},
+		{
+			`{"project_access": {"access_level": 30}}`,
+			"token needs maintainer access to owner/repo to manage its webhooks",
+		},
+		{
+			`null`,
+			"token needs maintainer access to owner/repo to manage its webhooks",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.permissions, func(t *testing.T) {
+			testServer := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/api/v4/projects/owner/repo":
+						w.Write([]byte(fmt.Sprintf(`{"id": 1, "permissions": %s}`, c.permissions))) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			defer testServer.Close()
+
+			internalClient := gitlab.NewClient(nil, "token")
+			Ok(t, internalClient.SetBaseURL(testServer.URL))
+			client := &GitlabClient{Client: internalClient}
+			err := client.CheckWebhookPermissions("owner/repo")
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
 func TestGitlabClient_MarkdownPullLink(t *testing.T) {
 	gitlabClientUnderTest = true
 	defer func() { gitlabClientUnderTest = false }()
-	client, err := NewGitlabClient("gitlab.com", "token", nil)
+	client, err := NewGitlabClient(nil, "gitlab.com", "token", nil)
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
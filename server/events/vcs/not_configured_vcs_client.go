@@ -38,9 +38,15 @@ func (a *NotConfiguredVCSClient) HidePrevPlanComments(repo models.Repo, pullNum
 func (a *NotConfiguredVCSClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
+func (a *NotConfiguredVCSClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, a.err()
+}
 func (a *NotConfiguredVCSClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
+func (a *NotConfiguredVCSClient) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	return false, a.err()
+}
 func (a *NotConfiguredVCSClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	return a.err()
 }
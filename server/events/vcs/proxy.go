@@ -15,6 +15,7 @@ package vcs
 
 import (
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/tracing"
 )
 
 // ClientProxy proxies calls to the correct VCS client depending on which
@@ -23,6 +24,9 @@ type ClientProxy struct {
 	// clients maps from the vcs host type to the client that implements the
 	// api for that host type, ex. github -> github client.
 	clients map[models.VCSHostType]Client
+	// Tracer, if set, records a span for each call made while a command is
+	// running on the pull request.
+	Tracer *tracing.Tracer
 }
 
 func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClient Client, bitbucketServerClient Client, azuredevopsClient Client) *ClientProxy {
@@ -53,33 +57,80 @@ func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClie
 }
 
 func (d *ClientProxy) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
-	return d.clients[repo.VCSHost.Type].GetModifiedFiles(repo, pull)
+	span := d.startSpan("GetModifiedFiles", repo, pull.Num)
+	files, err := d.clients[repo.VCSHost.Type].GetModifiedFiles(repo, pull)
+	endSpan(span, err)
+	return files, err
 }
 
 func (d *ClientProxy) CreateComment(repo models.Repo, pullNum int, comment string) error {
-	return d.clients[repo.VCSHost.Type].CreateComment(repo, pullNum, comment)
+	span := d.startSpan("CreateComment", repo, pullNum)
+	err := d.clients[repo.VCSHost.Type].CreateComment(repo, pullNum, comment)
+	endSpan(span, err)
+	return err
 }
 
 func (d *ClientProxy) HidePrevPlanComments(repo models.Repo, pullNum int) error {
-	return d.clients[repo.VCSHost.Type].HidePrevPlanComments(repo, pullNum)
+	span := d.startSpan("HidePrevPlanComments", repo, pullNum)
+	err := d.clients[repo.VCSHost.Type].HidePrevPlanComments(repo, pullNum)
+	endSpan(span, err)
+	return err
 }
 
 func (d *ClientProxy) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
-	return d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
+	span := d.startSpan("PullIsApproved", repo, pull.Num)
+	approved, err := d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
+	endSpan(span, err)
+	return approved, err
+}
+
+func (d *ClientProxy) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	span := d.startSpan("GetPullApprovers", repo, pull.Num)
+	approvers, err := d.clients[repo.VCSHost.Type].GetPullApprovers(repo, pull)
+	endSpan(span, err)
+	return approvers, err
 }
 
 func (d *ClientProxy) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
-	return d.clients[repo.VCSHost.Type].PullIsMergeable(repo, pull)
+	span := d.startSpan("PullIsMergeable", repo, pull.Num)
+	mergeable, err := d.clients[repo.VCSHost.Type].PullIsMergeable(repo, pull)
+	endSpan(span, err)
+	return mergeable, err
+}
+
+func (d *ClientProxy) PullIsUndiverged(repo models.Repo, pull models.PullRequest) (bool, error) {
+	span := d.startSpan("PullIsUndiverged", repo, pull.Num)
+	undiverged, err := d.clients[repo.VCSHost.Type].PullIsUndiverged(repo, pull)
+	endSpan(span, err)
+	return undiverged, err
 }
 
 func (d *ClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
-	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, src, description, url)
+	span := d.startSpan("UpdateStatus", repo, pull.Num)
+	err := d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, src, description, url)
+	endSpan(span, err)
+	return err
 }
 
 func (d *ClientProxy) MergePull(pull models.PullRequest) error {
-	return d.clients[pull.BaseRepo.VCSHost.Type].MergePull(pull)
+	span := d.startSpan("MergePull", pull.BaseRepo, pull.Num)
+	err := d.clients[pull.BaseRepo.VCSHost.Type].MergePull(pull)
+	endSpan(span, err)
+	return err
 }
 
 func (d *ClientProxy) MarkdownPullLink(pull models.PullRequest) (string, error) {
 	return d.clients[pull.BaseRepo.VCSHost.Type].MarkdownPullLink(pull)
 }
+
+// startSpan starts a span for calling method as part of the command running
+// on pull request pullNum. It returns nil if tracing is disabled or no
+// command is running.
+func (d *ClientProxy) startSpan(method string, repo models.Repo, pullNum int) *tracing.Span {
+	return d.Tracer.PullSpan(repo.FullName, pullNum).Start("vcs "+method, tracing.String("vcs", repo.VCSHost.Type.String()))
+}
+
+func endSpan(span *tracing.Span, err error) {
+	span.SetError(err)
+	span.End()
+}
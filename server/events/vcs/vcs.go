@@ -1 +1,21 @@
 package vcs
+
+import "strings"
+
+// formatScopes returns scopes for use in error messages.
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "no scopes"
+	}
+	return strings.Join(scopes, ", ")
+}
+
+// containsString returns true if s is in strs.
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
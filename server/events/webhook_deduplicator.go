@@ -0,0 +1,123 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// WebhookDeduplicator remembers the webhook deliveries and the events it has
+// recently seen so that redelivered or duplicate webhooks, ex. deliveries the
+// VCS host retries because they timed out, don't run commands twice. A nil
+// *WebhookDeduplicator never reports duplicates.
+type WebhookDeduplicator struct {
+	// Window is how long deliveries and events are remembered.
+	Window time.Duration
+	// Duplicates, if set, counts the duplicates that were seen by kind:
+	// delivery, autoplan or comment.
+	Duplicates *metrics.Counter
+	// Now is stubbed in tests.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// DeliveryDedupKey returns the key for the webhook delivery with id, ex. the
+// X-Github-Delivery header.
+func DeliveryDedupKey(id string) string {
+	return "delivery:" + id
+}
+
+// AutoplanDedupKey returns the key for autoplanning the head commit of pull.
+func AutoplanDedupKey(repo models.Repo, pull models.PullRequest) string {
+	return autoplanDedupPrefix(repo, pull.Num) + pull.HeadCommit
+}
+
+// CommentDedupKey returns the key for the comment with commentID on pull
+// request pullNum.
+func CommentDedupKey(repo models.Repo, pullNum int, commentID int64) string {
+	return fmt.Sprintf("comment:%s/%s#%d:%d", repo.VCSHost.Hostname, repo.FullName, pullNum, commentID)
+}
+
+func autoplanDedupPrefix(repo models.Repo, pullNum int) string {
+	return fmt.Sprintf("autoplan:%s/%s#%d@", repo.VCSHost.Hostname, repo.FullName, pullNum)
+}
+
+// Seen records key and returns true if it was already recorded in the last
+// Window.
+func (d *WebhookDeduplicator) Seen(key string) bool {
+	if d == nil || d.Window <= 0 {
+		return false
+	}
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = make(map[string]time.Time)
+	}
+	d.sweep(now)
+
+	if at, ok := d.seen[key]; ok && now.Sub(at) < d.Window {
+		if d.Duplicates != nil {
+			d.Duplicates.Inc(strings.SplitN(key, ":", 2)[0])
+		}
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// Forget forgets key so it isn't a duplicate the next time it's seen, ex.
+// because handling it failed and it should be handled if it's delivered
+// again.
+func (d *WebhookDeduplicator) Forget(key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, key)
+}
+
+// ForgetPull forgets the autoplans of pull request pullNum so that its head
+// commit is autoplanned again if it's reopened.
+func (d *WebhookDeduplicator) ForgetPull(repo models.Repo, pullNum int) {
+	if d == nil {
+		return
+	}
+	prefix := autoplanDedupPrefix(repo, pullNum)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key := range d.seen {
+		if strings.HasPrefix(key, prefix) {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// sweep forgets the keys that were seen more than Window ago so the map
+// doesn't grow forever. It runs at most once per window. d.mu must be held.
+func (d *WebhookDeduplicator) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.Window {
+		return
+	}
+	d.lastSweep = now
+	for key, at := range d.seen {
+		if now.Sub(at) >= d.Window {
+			delete(d.seen, key)
+		}
+	}
+}
+
+func (d *WebhookDeduplicator) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
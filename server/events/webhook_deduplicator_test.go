@@ -0,0 +1,62 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestWebhookDeduplicator_Seen(t *testing.T) {
+	now := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+	registry := metrics.NewRegistry()
+	dedup := &events.WebhookDeduplicator{
+		Window:     10 * time.Minute,
+		Duplicates: registry.NewCounter("atlantis_webhook_duplicates", "", "kind"),
+		Now:        func() time.Time { return now },
+	}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull := models.PullRequest{Num: 1, HeadCommit: "abc"}
+
+	Equals(t, false, dedup.Seen(events.DeliveryDedupKey("1")))
+	Equals(t, false, dedup.Seen(events.AutoplanDedupKey(repo, pull)))
+	Equals(t, false, dedup.Seen(events.CommentDedupKey(repo, 1, 100)))
+	now = now.Add(time.Minute)
+	Equals(t, true, dedup.Seen(events.DeliveryDedupKey("1")))
+	Equals(t, true, dedup.Seen(events.AutoplanDedupKey(repo, pull)))
+	Equals(t, true, dedup.Seen(events.CommentDedupKey(repo, 1, 100)))
+
+	// Other deliveries, commits and comments aren't duplicates.
+	Equals(t, false, dedup.Seen(events.DeliveryDedupKey("2")))
+	Equals(t, false, dedup.Seen(events.AutoplanDedupKey(repo, models.PullRequest{Num: 1, HeadCommit: "def"})))
+	Equals(t, false, dedup.Seen(events.CommentDedupKey(repo, 1, 101)))
+
+	// Forgotten keys aren't duplicates.
+	dedup.Forget(events.DeliveryDedupKey("1"))
+	Equals(t, false, dedup.Seen(events.DeliveryDedupKey("1")))
+	dedup.ForgetPull(repo, 1)
+	Equals(t, false, dedup.Seen(events.AutoplanDedupKey(repo, pull)))
+	Equals(t, true, dedup.Seen(events.CommentDedupKey(repo, 1, 100)))
+
+	// Keys are forgotten once they're outside the window.
+	now = now.Add(10 * time.Minute)
+	Equals(t, false, dedup.Seen(events.CommentDedupKey(repo, 1, 100)))
+
+	families, err := registry.Gather()
+	Ok(t, err)
+	Equals(t, []metrics.Sample{
+		{Labels: []string{"autoplan"}, Value: 1},
+		{Labels: []string{"comment"}, Value: 2},
+		{Labels: []string{"delivery"}, Value: 1},
+	}, families[0].Samples)
+}
+
+func TestWebhookDeduplicator_Disabled(t *testing.T) {
+	var dedup *events.WebhookDeduplicator
+	for i := 0; i < 3; i++ {
+		Equals(t, false, dedup.Seen(events.DeliveryDedupKey("1")))
+	}
+}
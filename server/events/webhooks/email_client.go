@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_email_client.go EmailClient
+
+// EmailClient sends emails about apply results.
+type EmailClient interface {
+	SendEmail(to []string, applyResult ApplyResult) error
+}
+
+// SMTPEmailClient sends emails through an SMTP server.
+type SMTPEmailClient struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	// From is the address emails are sent from.
+	From string
+	// Username and Password are used for PLAIN auth if Username is set.
+	Username string
+	Password string
+}
+
+func NewSMTPEmailClient(addr string, from string, username string, password string) *SMTPEmailClient {
+	return &SMTPEmailClient{
+		Addr:     addr,
+		From:     from,
+		Username: username,
+		Password: password,
+	}
+}
+
+// SendEmail sends an email about applyResult to each address in to.
+func (s *SMTPEmailClient) SendEmail(to []string, applyResult ApplyResult) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return errors.Wrapf(err, "parsing smtp address %q", s.Addr)
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	err := smtp.SendMail(s.Addr, auth, s.From, to, s.createMessage(to, applyResult))
+	return errors.Wrapf(err, "sending email to %s", strings.Join(to, ", "))
+}
+
+func (s *SMTPEmailClient) createMessage(to []string, applyResult ApplyResult) []byte {
+	successWord := "failed"
+	if applyResult.Success {
+		successWord = "succeeded"
+	}
+	directory := applyResult.Directory
+	// Since "." looks weird, replace it with "/" to make it clear this is the root.
+	if directory == "." {
+		directory = "/"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: Atlantis apply %s for %s#%d\r\n", successWord, applyResult.Repo.FullName, applyResult.Pull.Num)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "Apply %s for %s\r\n\r\n", successWord, applyResult.Pull.URL)
+	fmt.Fprintf(&b, "Workspace: %s\r\n", applyResult.Workspace)
+	fmt.Fprintf(&b, "User: %s\r\n", applyResult.User.Username)
+	fmt.Fprintf(&b, "Directory: %s\r\n", directory)
+	return b.Bytes()
+}
@@ -0,0 +1,101 @@
+package webhooks_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeSMTPServer accepts one SMTP session and sends the commands and message
+// data it received on the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close() // nolint: errcheck
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+		var session strings.Builder
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			session.WriteString(line)
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					fmt.Fprint(conn, "250 OK\r\n")
+				}
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 Go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 Bye\r\n")
+				received <- session.String()
+				return
+			default:
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+		}
+		received <- session.String()
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTPEmailClient_SendEmail(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	client := webhooks.NewSMTPEmailClient(addr, "atlantis@example.com", "", "")
+	err := client.SendEmail([]string{"net@example.com", "ops@example.com"}, webhooks.ApplyResult{
+		Workspace: "production",
+		Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+		Pull:      models.PullRequest{Num: 1, URL: "https://github.com/runatlantis/atlantis/pull/1"},
+		User:      models.User{Username: "lkysow"},
+		Success:   true,
+		Directory: ".",
+	})
+	Ok(t, err)
+
+	session := <-received
+	for _, exp := range []string{
+		"MAIL FROM:<atlantis@example.com>",
+		"RCPT TO:<net@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"To: net@example.com, ops@example.com\r\n",
+		"Subject: Atlantis apply succeeded for runatlantis/atlantis#1\r\n",
+		"Apply succeeded for https://github.com/runatlantis/atlantis/pull/1\r\n",
+		"Workspace: production\r\n",
+		"User: lkysow\r\n",
+		"Directory: /\r\n",
+	} {
+		Assert(t, strings.Contains(session, exp), "expected %q in SMTP session:\n%s", exp, session)
+	}
+}
+
+func TestSMTPEmailClient_SendEmailError(t *testing.T) {
+	// Listen and close immediately so nothing's listening on the address.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	addr := l.Addr().String()
+	Ok(t, l.Close())
+
+	client := webhooks.NewSMTPEmailClient(addr, "atlantis@example.com", "", "")
+	err = client.SendEmail([]string{"net@example.com"}, webhooks.ApplyResult{})
+	ErrContains(t, "sending email to net@example.com", err)
+}
@@ -89,6 +89,36 @@ func (mock *MockSlackClient) PostMessage(channel string, applyResult webhooks.Ap
 	return ret0
 }
 
+func (mock *MockSlackClient) PostText(channel string, text string) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockSlackClient().")
+	}
+	params := []pegomock.Param{channel, text}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("PostText", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockSlackClient) PostApprovalRequest(channel string, req webhooks.ApprovalRequest) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockSlackClient().")
+	}
+	params := []pegomock.Param{channel, req}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("PostApprovalRequest", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockSlackClient) VerifyWasCalledOnce() *VerifierMockSlackClient {
 	return &VerifierMockSlackClient{
 		mock:                   mock,
@@ -179,7 +209,7 @@ func (c *MockSlackClient_ChannelExists_OngoingVerification) GetCapturedArguments
 func (c *MockSlackClient_ChannelExists_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]string, len(params[0]))
+		_param0 = make([]string, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(string)
 		}
@@ -206,14 +236,76 @@ func (c *MockSlackClient_PostMessage_OngoingVerification) GetCapturedArguments()
 func (c *MockSlackClient_PostMessage_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []webhooks.ApplyResult) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]string, len(params[0]))
+		_param0 = make([]string, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(string)
 		}
-		_param1 = make([]webhooks.ApplyResult, len(params[1]))
+		_param1 = make([]webhooks.ApplyResult, len(c.methodInvocations))
 		for u, param := range params[1] {
 			_param1[u] = param.(webhooks.ApplyResult)
 		}
 	}
 	return
 }
+
+func (verifier *VerifierMockSlackClient) PostText(channel string, text string) *MockSlackClient_PostText_OngoingVerification {
+	params := []pegomock.Param{channel, text}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "PostText", params, verifier.timeout)
+	return &MockSlackClient_PostText_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockSlackClient_PostText_OngoingVerification struct {
+	mock              *MockSlackClient
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockSlackClient_PostText_OngoingVerification) GetCapturedArguments() (string, string) {
+	channel, text := c.GetAllCapturedArguments()
+	return channel[len(channel)-1], text[len(text)-1]
+}
+
+func (c *MockSlackClient_PostText_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]string, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(string)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockSlackClient) PostApprovalRequest(channel string, req webhooks.ApprovalRequest) *MockSlackClient_PostApprovalRequest_OngoingVerification {
+	params := []pegomock.Param{channel, req}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "PostApprovalRequest", params, verifier.timeout)
+	return &MockSlackClient_PostApprovalRequest_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockSlackClient_PostApprovalRequest_OngoingVerification struct {
+	mock              *MockSlackClient
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockSlackClient_PostApprovalRequest_OngoingVerification) GetCapturedArguments() (string, webhooks.ApprovalRequest) {
+	channel, req := c.GetAllCapturedArguments()
+	return channel[len(channel)-1], req[len(req)-1]
+}
+
+func (c *MockSlackClient_PostApprovalRequest_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []webhooks.ApprovalRequest) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]webhooks.ApprovalRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(webhooks.ApprovalRequest)
+		}
+	}
+	return
+}
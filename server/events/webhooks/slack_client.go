@@ -15,13 +15,23 @@ package webhooks
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/nlopes/slack"
+	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 const (
 	slackSuccessColour = "good"
 	slackFailureColour = "danger"
+	slackPendingColour = "warning"
+
+	// SlackApplyCallbackID is the callback ID of the attachments posted by
+	// PostApprovalRequest. Slack sends it back when their button is clicked.
+	SlackApplyCallbackID = "atlantis_apply"
+	// SlackApplyAction is the name of the "Approve & Apply" button.
+	SlackApplyAction = "approve_apply"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_slack_client.go SlackClient
@@ -32,6 +42,25 @@ type SlackClient interface {
 	TokenIsSet() bool
 	ChannelExists(channelName string) (bool, error)
 	PostMessage(channel string, applyResult ApplyResult) error
+	// PostText posts text, which can use Slack's markdown, to channel.
+	PostText(channel string, text string) error
+	// PostApprovalRequest posts the plans of a pull request to channel with
+	// an "Approve & Apply" button.
+	PostApprovalRequest(channel string, req ApprovalRequest) error
+}
+
+// ApprovalRequest is a pull request whose plans can be approved and applied
+// from Slack.
+type ApprovalRequest struct {
+	Repo models.Repo
+	Pull models.PullRequest
+	// User is who ran the plan.
+	User models.User
+	// Projects describe each plan, ex. its project and changes.
+	Projects []string
+	// Value is sent back by Slack when the button is clicked so the plans
+	// can be found.
+	Value string
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_underlying_slack_client.go UnderlyingSlackClient
@@ -49,7 +78,13 @@ type DefaultSlackClient struct {
 	Token string
 }
 
-func NewSlackClient(token string) SlackClient {
+// NewSlackClient returns a client that calls Slack with token. If httpClient
+// isn't nil, Slack is called with it. The Slack library only supports one
+// HTTP client for the whole process so this replaces it for all clients.
+func NewSlackClient(token string, httpClient *http.Client) SlackClient {
+	if httpClient != nil {
+		slack.SetHTTPClient(httpClient)
+	}
 	return &DefaultSlackClient{
 		Slack: slack.New(token),
 		Token: token,
@@ -87,6 +122,46 @@ func (d *DefaultSlackClient) PostMessage(channel string, applyResult ApplyResult
 	return err
 }
 
+func (d *DefaultSlackClient) PostText(channel string, text string) error {
+	params := slack.NewPostMessageParameters()
+	params.AsUser = true
+	params.EscapeText = false
+	_, _, err := d.Slack.PostMessage(channel, text, params)
+	return err
+}
+
+func (d *DefaultSlackClient) PostApprovalRequest(channel string, req ApprovalRequest) error {
+	params := slack.NewPostMessageParameters()
+	params.Attachments = d.createApprovalAttachments(req)
+	params.AsUser = true
+	params.EscapeText = false
+	_, _, err := d.Slack.PostMessage(channel, "", params)
+	return err
+}
+
+func (d *DefaultSlackClient) createApprovalAttachments(req ApprovalRequest) []slack.Attachment {
+	text := fmt.Sprintf("Plans of <%s|%s#%d> by %s are ready to apply:\n• %s", req.Pull.URL, req.Repo.FullName, req.Pull.Num, req.User.Username, strings.Join(req.Projects, "\n• "))
+	return []slack.Attachment{{
+		Color:      slackPendingColour,
+		Fallback:   fmt.Sprintf("Plans of %s#%d are ready to apply", req.Repo.FullName, req.Pull.Num),
+		CallbackID: SlackApplyCallbackID,
+		Text:       text,
+		Actions: []slack.AttachmentAction{{
+			Name:  SlackApplyAction,
+			Text:  "Approve & Apply",
+			Style: "primary",
+			Type:  "button",
+			Value: req.Value,
+			Confirm: &slack.ConfirmationField{
+				Title:       "Approve and apply?",
+				Text:        "This approves the pull request as your VCS user and applies its plans.",
+				OkText:      "Approve & Apply",
+				DismissText: "Cancel",
+			},
+		}},
+	}}
+}
+
 func (d *DefaultSlackClient) createAttachments(applyResult ApplyResult) []slack.Attachment {
 	var colour string
 	var successWord string
@@ -22,6 +22,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks/matchers"
 
 	. "github.com/petergtz/pegomock"
 	. "github.com/runatlantis/atlantis/testing"
@@ -170,6 +171,19 @@ func TestPostMessage_Error(t *testing.T) {
 	Assert(t, err != nil, "expected error")
 }
 
+func TestPostText_Success(t *testing.T) {
+	t.Log("Text should be posted without attachments")
+	setup(t)
+
+	expParams := slack.NewPostMessageParameters()
+	expParams.AsUser = true
+	expParams.EscapeText = false
+
+	err := client.PostText("somechannel", "*bold* text")
+	Ok(t, err)
+	underlying.VerifyWasCalledOnce().PostMessage("somechannel", "*bold* text", expParams)
+}
+
 func setup(t *testing.T) {
 	RegisterMockTestingT(t)
 	underlying = mocks.NewMockUnderlyingSlackClient()
@@ -192,3 +206,31 @@ func setup(t *testing.T) {
 		Success: true,
 	}
 }
+
+func TestPostApprovalRequest_Success(t *testing.T) {
+	t.Log("The plans should be posted with an Approve & Apply button")
+	setup(t)
+
+	req := webhooks.ApprovalRequest{
+		Repo:     result.Repo,
+		Pull:     result.Pull,
+		User:     models.User{Username: "alice"},
+		Projects: []string{"dir: . workspace: default: 1 to add, 0 to change, 0 to destroy.", "dir: db workspace: default: 0 to add, 1 to change, 0 to destroy."},
+		Value:    `{"repo":"runatlantis/atlantis","pull":1,"commit":"abc"}`,
+	}
+	err := client.PostApprovalRequest("approvals", req)
+	Ok(t, err)
+
+	_, _, params := underlying.VerifyWasCalledOnce().PostMessage(EqString("approvals"), EqString(""), matchers.AnySlackPostMessageParameters()).GetCapturedArguments()
+	Equals(t, 1, len(params.Attachments))
+	attachment := params.Attachments[0]
+	Equals(t, webhooks.SlackApplyCallbackID, attachment.CallbackID)
+	Equals(t, "Plans of <url|runatlantis/atlantis#1> by alice are ready to apply:\n"+
+		"• dir: . workspace: default: 1 to add, 0 to change, 0 to destroy.\n"+
+		"• dir: db workspace: default: 0 to add, 1 to change, 0 to destroy.", attachment.Text)
+	Equals(t, 1, len(attachment.Actions))
+	Equals(t, webhooks.SlackApplyAction, attachment.Actions[0].Name)
+	Equals(t, "button", attachment.Actions[0].Type)
+	Equals(t, req.Value, attachment.Actions[0].Value)
+	Assert(t, attachment.Actions[0].Confirm != nil, "exp the button to ask for confirmation")
+}
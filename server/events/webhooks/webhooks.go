@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
@@ -42,14 +43,30 @@ type ApplyResult struct {
 	User      models.User
 	Success   bool
 	Directory string
+	// Notify is where the project's atlantis.yaml says its events should be
+	// sent in addition to the unnamed webhooks.
+	Notify valid.Notify
 }
 
 // MultiWebhookSender sends multiple webhooks for each one it's configured for.
 type MultiWebhookSender struct {
+	// Webhooks are sent for every project.
 	Webhooks []Sender
+	// NamedWebhooks are only sent for projects that list their name in
+	// their notify targets.
+	NamedWebhooks map[string]Sender
+	// SlackClient is used to post to the Slack channels in projects' notify
+	// targets.
+	SlackClient SlackClient
+	// EmailClient, if set, is used to email the addresses in projects' notify
+	// targets.
+	EmailClient EmailClient
 }
 
 type Config struct {
+	// Name, if set, makes this webhook opt-in: it's only sent for projects
+	// that list it in their notify targets.
+	Name           string
 	Event          string
 	WorkspaceRegex string
 	Kind           string
@@ -58,7 +75,11 @@ type Config struct {
 
 func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookSender, error) {
 	var webhooks []Sender
+	namedWebhooks := make(map[string]Sender)
 	for _, c := range configs {
+		if _, ok := namedWebhooks[c.Name]; ok {
+			return nil, fmt.Errorf("webhook name %q is used more than once", c.Name)
+		}
 		r, err := regexp.Compile(c.WorkspaceRegex)
 		if err != nil {
 			return nil, err
@@ -81,23 +102,61 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 			if err != nil {
 				return nil, err
 			}
-			webhooks = append(webhooks, slack)
+			if c.Name != "" {
+				namedWebhooks[c.Name] = slack
+			} else {
+				webhooks = append(webhooks, slack)
+			}
 		default:
 			return nil, fmt.Errorf("\"kind: %s\" not supported. Only \"kind: %s\" is supported right now", c.Kind, SlackKind)
 		}
 	}
 
 	return &MultiWebhookSender{
-		Webhooks: webhooks,
+		Webhooks:      webhooks,
+		NamedWebhooks: namedWebhooks,
+		SlackClient:   client,
 	}, nil
 }
 
-// Send sends the webhook using its Webhooks.
+// Send sends the webhook using its Webhooks and then to the project's notify
+// targets.
 func (w *MultiWebhookSender) Send(log *logging.SimpleLogger, result ApplyResult) error {
 	for _, w := range w.Webhooks {
 		if err := w.Send(log, result); err != nil {
 			log.Warn("error sending slack webhook: %s", err)
 		}
 	}
+
+	for _, name := range result.Notify.Webhooks {
+		named, ok := w.NamedWebhooks[name]
+		if !ok {
+			log.Warn("project's notify targets include webhook %q but no webhook with that name is configured", name)
+			continue
+		}
+		if err := named.Send(log, result); err != nil {
+			log.Warn("error sending webhook %q: %s", name, err)
+		}
+	}
+
+	if len(result.Notify.SlackChannels) > 0 {
+		if w.SlackClient == nil || !w.SlackClient.TokenIsSet() {
+			log.Warn("project's notify targets include slack channels but no slack-token is configured")
+		} else {
+			for _, channel := range result.Notify.SlackChannels {
+				if err := w.SlackClient.PostMessage(channel, result); err != nil {
+					log.Warn("error posting to slack channel %q: %s", channel, err)
+				}
+			}
+		}
+	}
+
+	if len(result.Notify.Emails) > 0 {
+		if w.EmailClient == nil {
+			log.Warn("project's notify targets include email addresses but no smtp-addr is configured")
+		} else if err := w.EmailClient.SendEmail(result.Notify.Emails, result); err != nil {
+			log.Warn("error sending email: %s", err)
+		}
+	}
 	return nil
 }
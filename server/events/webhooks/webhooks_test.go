@@ -20,6 +20,8 @@ import (
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -111,7 +113,7 @@ func TestNewWebhooksManager_NoConfigSuccess(t *testing.T) {
 	t.Log("passing any client should succeed")
 	var emptyConfigs []webhooks.Config
 	emptyToken := ""
-	m, err := webhooks.NewMultiWebhookSender(emptyConfigs, webhooks.NewSlackClient(emptyToken))
+	m, err := webhooks.NewMultiWebhookSender(emptyConfigs, webhooks.NewSlackClient(emptyToken, nil))
 	Ok(t, err)
 	Assert(t, m != nil, "manager shouldn't be nil")
 	Equals(t, 0, len(m.Webhooks)) // nolint: staticcheck
@@ -154,6 +156,25 @@ func TestNewWebhooksManager_MultipleConfigSuccess(t *testing.T) {
 	Equals(t, nConfigs, len(m.Webhooks)) // nolint: staticcheck
 }
 
+func TestNewWebhooksManager_NamedConfig(t *testing.T) {
+	t.Log("Named webhooks should only be sent for projects that notify them")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	When(client.TokenIsSet()).ThenReturn(true)
+	When(client.ChannelExists(validChannel)).ThenReturn(true, nil)
+
+	named := validConfig
+	named.Name = "net-team"
+	m, err := webhooks.NewMultiWebhookSender([]webhooks.Config{validConfig, named}, client)
+	Ok(t, err)
+	Equals(t, 1, len(m.Webhooks))
+	_, ok := m.NamedWebhooks["net-team"]
+	Assert(t, ok, "expected named webhook")
+
+	_, err = webhooks.NewMultiWebhookSender([]webhooks.Config{named, named}, client)
+	ErrEquals(t, `webhook name "net-team" is used more than once`, err)
+}
+
 func TestSend_SingleSuccess(t *testing.T) {
 	t.Log("Sending one webhook should succeed")
 	RegisterMockTestingT(t)
@@ -186,3 +207,55 @@ func TestSend_MultipleSuccess(t *testing.T) {
 		s.VerifyWasCalledOnce().Send(logger, result)
 	}
 }
+
+func TestSend_Notify(t *testing.T) {
+	t.Log("Projects' notify targets should get the webhook in addition to the unnamed webhooks")
+	RegisterMockTestingT(t)
+	sender := mocks.NewMockSender()
+	namedSender := mocks.NewMockSender()
+	otherNamedSender := mocks.NewMockSender()
+	slackClient := mocks.NewMockSlackClient()
+	When(slackClient.TokenIsSet()).ThenReturn(true)
+	emailClient := mocks.NewMockEmailClient()
+	manager := webhooks.MultiWebhookSender{
+		Webhooks: []webhooks.Sender{sender},
+		NamedWebhooks: map[string]webhooks.Sender{
+			"net-team":   namedSender,
+			"other-team": otherNamedSender,
+		},
+		SlackClient: slackClient,
+		EmailClient: emailClient,
+	}
+	logger := logging.NewNoopLogger()
+	result := webhooks.ApplyResult{
+		Notify: valid.Notify{
+			SlackChannels: []string{"team-net"},
+			Webhooks:      []string{"net-team", "doesnt-exist"},
+			Emails:        []string{"net@example.com"},
+		},
+	}
+	Ok(t, manager.Send(logger, result))
+	sender.VerifyWasCalledOnce().Send(logger, result)
+	namedSender.VerifyWasCalledOnce().Send(logger, result)
+	otherNamedSender.VerifyWasCalled(Never()).Send(logger, result)
+	slackClient.VerifyWasCalledOnce().PostMessage("team-net", result)
+	emailClient.VerifyWasCalledOnce().SendEmail([]string{"net@example.com"}, result)
+}
+
+func TestSend_NotifyNotConfigured(t *testing.T) {
+	t.Log("Notify targets that aren't configured on the server should be skipped")
+	RegisterMockTestingT(t)
+	slackClient := mocks.NewMockSlackClient()
+	When(slackClient.TokenIsSet()).ThenReturn(false)
+	manager := webhooks.MultiWebhookSender{
+		SlackClient: slackClient,
+	}
+	result := webhooks.ApplyResult{
+		Notify: valid.Notify{
+			SlackChannels: []string{"team-net"},
+			Emails:        []string{"net@example.com"},
+		},
+	}
+	Ok(t, manager.Send(logging.NewNoopLogger(), result))
+	slackClient.VerifyWasCalled(Never()).PostMessage(AnyString(), matchers.AnyWebhooksApplyResult())
+}
@@ -0,0 +1,288 @@
+package workers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/artifacts"
+	"github.com/runatlantis/atlantis/server/events/cloudcreds"
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/vault"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// retryInterval is how long the agent waits before claiming another job after
+// it fails to reach the server.
+const retryInterval = 5 * time.Second
+
+// StepsRunner runs the steps of project commands.
+type StepsRunner interface {
+	// RunSteps runs ctx's steps for the project at absPath.
+	RunSteps(ctx models.ProjectCommandContext, absPath string) ([]string, error)
+}
+
+// AgentConfig configures a worker agent.
+type AgentConfig struct {
+	// ServerURL is the URL of the Atlantis server to claim jobs from.
+	ServerURL string
+	// Token is an API token with the workers scope.
+	Token string
+	// DataDir is where repos are cloned and terraform binaries are
+	// downloaded.
+	DataDir              string
+	DefaultTFVersion     string
+	DefaultTFVersionFlag string
+	TFDownload           terraform.DownloadConfig
+	TFEToken             string
+	TFEHostname          string
+	// ArtifactBucket, if set, is the S3 bucket upload_artifact steps upload
+	// to.
+	ArtifactBucket     string
+	ArtifactS3Endpoint string
+	ArtifactURLExpiry  time.Duration
+	// VaultAddr and VaultToken, if set, are used to read the secrets of env
+	// steps from Vault.
+	VaultAddr  string
+	VaultToken string
+}
+
+// Agent claims jobs from the server, runs them and reports their results. It's
+// stateless: repos are cloned for each job and deleted once it's done.
+type Agent struct {
+	Client  *Client
+	DataDir string
+	Runner  StepsRunner
+	Logger  *logging.SimpleLogger
+}
+
+// NewAgent builds an agent that runs terraform like the server does.
+func NewAgent(config AgentConfig, logger *logging.SimpleLogger) (*Agent, error) {
+	terraformClient, err := terraform.NewClient(
+		logger,
+		config.DataDir,
+		config.TFEToken,
+		config.TFEHostname,
+		config.DefaultTFVersion,
+		config.DefaultTFVersionFlag,
+		config.TFDownload,
+		&terraform.DefaultDownloader{})
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing terraform")
+	}
+	// Steps of workflows that set an image run in Docker containers.
+	executionBackend := &execution.DockerBackend{
+		DataDir:       config.DataDir,
+		TerraformPath: execution.LocalTerraformPath(),
+	}
+	terraformClient.ExecutionBackend = executionBackend
+	defaultTfVersion := terraformClient.DefaultVersion()
+	runStepRunner := &runtime.RunStepRunner{
+		TerraformExecutor: terraformClient,
+		DefaultTFVersion:  defaultTfVersion,
+		TerraformBinDir:   terraformClient.TerraformBinDir(),
+		ExecutionBackend:  executionBackend,
+	}
+	var artifactUploader runtime.ArtifactUploader
+	if config.ArtifactBucket != "" {
+		artifactUploader, err = artifacts.NewS3Store(config.ArtifactBucket, config.ArtifactS3Endpoint, config.ArtifactURLExpiry)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing artifact storage")
+		}
+	}
+	var secretsProvider events.SecretsProvider
+	if config.VaultAddr != "" {
+		secretsProvider = &vault.Client{
+			Address: config.VaultAddr,
+			Token:   config.VaultToken,
+		}
+	}
+	// Workers can't update commit statuses so the links to remote
+	// operations are only in the output.
+	statusUpdater := noopStatusUpdater{}
+	// Workers don't have the server-side repo config so throttled steps are
+	// retried with the default provider_retries.
+	initStepRunner, planStepRunner, applyStepRunner := events.NewRetryStepRunners(
+		&runtime.InitStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		&runtime.PlanStepRunner{
+			TerraformExecutor:   terraformClient,
+			DefaultTFVersion:    defaultTfVersion,
+			CommitStatusUpdater: statusUpdater,
+			AsyncTFExec:         terraformClient,
+		},
+		&runtime.ApplyStepRunner{
+			TerraformExecutor:   terraformClient,
+			DefaultTFVersion:    defaultTfVersion,
+			CommitStatusUpdater: statusUpdater,
+			AsyncTFExec:         terraformClient,
+		},
+		valid.DefaultProviderRetries,
+	)
+	return &Agent{
+		Client: &Client{
+			ServerURL: config.ServerURL,
+			Token:     config.Token,
+		},
+		DataDir: config.DataDir,
+		Runner: &events.DefaultProjectCommandRunner{
+			// Steps are retried here, where they run, so the job isn't
+			// dispatched again.
+			InitStepRunner:  initStepRunner,
+			PlanStepRunner:  planStepRunner,
+			ApplyStepRunner: applyStepRunner,
+			RunStepRunner:   runStepRunner,
+			EnvStepRunner: &runtime.EnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
+			MultiEnvStepRunner: &runtime.MultiEnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
+			ArtifactStepRunner: &runtime.UploadArtifactStepRunner{
+				Uploader: artifactUploader,
+			},
+			DiagramStepRunner: &runtime.DiagramStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+				Uploader:          artifactUploader,
+				DotPath:           runtime.FindDot(),
+			},
+			Redactor:               events.NewRedactor(nil, []string{config.Token, config.TFEToken, config.VaultToken}, nil),
+			CloudCredentialAssumer: &cloudcreds.Assumer{},
+			SecretsProvider:        secretsProvider,
+			SecurityScanStepRunner: &runtime.SecurityScanStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			FmtCheckStepRunner: &runtime.FmtCheckStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			ValidateStepRunner: &runtime.ValidateStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+		},
+		Logger: logger,
+	}, nil
+}
+
+// Start runs jobs until the agent is interrupted. The job that's running when
+// it's interrupted is finished first.
+func (a *Agent) Start() error {
+	stop := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		a.Logger.Warn("Received interrupt. Stopping once the current job is done")
+		close(stop)
+	}()
+	a.Logger.Info("Atlantis worker started, claiming jobs from %s", a.Client.ServerURL)
+	a.Run(stop)
+	return nil
+}
+
+// Run claims and runs jobs until stop is closed.
+func (a *Agent) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		job, err := a.Client.Claim()
+		if err != nil {
+			a.Logger.Err("failed to claim job: %s", err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+		if job == nil {
+			continue
+		}
+		res := a.Process(*job)
+		if err := a.Client.Complete(job.ID, res); err != nil {
+			a.Logger.Err("failed to report result of job %s: %s", job.ID, err)
+		}
+	}
+}
+
+// Process runs job and returns its result.
+func (a *Agent) Process(job Job) Result {
+	log := a.Logger.NewLogger(fmt.Sprintf("%s#%d", job.BaseRepo.FullName, job.Pull.Num), false, a.Logger.GetLevel())
+	log.Info("running %s job %s for dir %q and workspace %q", job.Command, job.ID, job.RepoRelDir, job.Workspace)
+	if job.Command != PlanJob && job.Command != ApplyJob {
+		return Result{Error: fmt.Sprintf("unknown job command %q", job.Command)}
+	}
+	ctx, err := job.ProjectCommandContext(log)
+	if err != nil {
+		return Result{Error: err.Error()}
+	}
+
+	workingDir := &events.FileWorkspace{
+		DataDir:       a.DataDir,
+		CheckoutMerge: job.CheckoutMerge,
+		CheckoutDepth: job.CheckoutDepth,
+		SparseCheckoutPatterns: func(string) []string {
+			return job.SparseCheckout
+		},
+		CloneOptions: func(string) valid.CloneOptions {
+			return valid.CloneOptions{Submodules: job.Submodules, LFS: job.LFS}
+		},
+	}
+	repoDir, _, err := workingDir.Clone(log, job.BaseRepo, job.HeadRepo, job.Pull, job.Workspace)
+	if err != nil {
+		return Result{Error: errors.Wrap(err, "cloning repo").Error()}
+	}
+	defer func() {
+		if err := workingDir.DeleteForWorkspace(job.BaseRepo, job.Pull, job.Workspace); err != nil {
+			log.Warn("failed to delete clone: %s", err)
+		}
+	}()
+	absPath := filepath.Join(repoDir, job.RepoRelDir)
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return Result{Error: events.DirNotExistErr{RepoRelDir: job.RepoRelDir}.Error()}
+	}
+	planPath := planPath(ctx, absPath)
+	if job.Command == ApplyJob {
+		if err := ioutil.WriteFile(planPath, job.PlanFile, 0600); err != nil {
+			return Result{Error: errors.Wrap(err, "writing planfile").Error()}
+		}
+	}
+
+	outputs, err := a.Runner.RunSteps(ctx, absPath)
+	if err != nil {
+		return Result{Outputs: outputs, Error: err.Error()}
+	}
+	res := Result{Outputs: outputs}
+	if job.Command == PlanJob {
+		res.PlanFile, err = ioutil.ReadFile(planPath)
+		if err != nil && !os.IsNotExist(err) {
+			return Result{Outputs: outputs, Error: errors.Wrap(err, "reading planfile").Error()}
+		}
+	}
+	return res
+}
+
+// noopStatusUpdater doesn't update commit statuses.
+type noopStatusUpdater struct{}
+
+func (noopStatusUpdater) UpdateProject(models.ProjectCommandContext, models.CommandName, models.CommitStatus, string) error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package workers_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeStepsRunner writes a planfile when it's run for a plan and records the
+// planfile it's run with for an apply.
+type fakeStepsRunner struct {
+	planFile string
+	err      error
+}
+
+func (f *fakeStepsRunner) RunSteps(ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	planPath := filepath.Join(absPath, ctx.Workspace+".tfplan")
+	if ctx.Steps[0].StepName == "plan" {
+		if err := ioutil.WriteFile(planPath, []byte("planfile"), 0600); err != nil {
+			return nil, err
+		}
+		return []string{"planned"}, f.err
+	}
+	planFile, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		return nil, err
+	}
+	f.planFile = string(planFile)
+	return []string{"applied"}, f.err
+}
+
+func runCmd(t *testing.T, dir string, name string, args ...string) string {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	Assert(t, err == nil, "err running %q: %s", strings.Join(append([]string{name}, args...), " "), out)
+	return strings.TrimSpace(string(out))
+}
+
+func initRepo(t *testing.T) (string, func()) {
+	repoDir, cleanup := TempDir(t)
+	runCmd(t, repoDir, "git", "init")
+	runCmd(t, repoDir, "mkdir", "dir")
+	runCmd(t, repoDir, "touch", "dir/main.tf")
+	runCmd(t, repoDir, "git", "add", "dir/main.tf")
+	runCmd(t, repoDir, "git", "config", "--local", "user.email", "atlantisbot@runatlantis.io")
+	runCmd(t, repoDir, "git", "config", "--local", "user.name", "atlantisbot")
+	runCmd(t, repoDir, "git", "commit", "-m", "initial commit")
+	runCmd(t, repoDir, "git", "branch", "branch")
+	return repoDir, cleanup
+}
+
+func setupAgent(t *testing.T, runner workers.StepsRunner) (*workers.Agent, workers.Job, func()) {
+	repoDir, cleanup := initRepo(t)
+	dataDir, cleanup2 := TempDir(t)
+	repo := models.Repo{FullName: "owner/repo", CloneURL: fmt.Sprintf("file://%s", repoDir)}
+	job := workers.Job{
+		ID:         "id",
+		BaseRepo:   repo,
+		HeadRepo:   repo,
+		Pull:       models.PullRequest{Num: 1, HeadBranch: "branch", HeadCommit: runCmd(t, repoDir, "git", "rev-parse", "HEAD")},
+		RepoRelDir: "dir",
+		Workspace:  "default",
+	}
+	agent := &workers.Agent{
+		DataDir: dataDir,
+		Runner:  runner,
+		Logger:  logging.NewNoopLogger(),
+	}
+	return agent, job, func() {
+		cleanup()
+		cleanup2()
+	}
+}
+
+func TestAgent_ProcessPlan(t *testing.T) {
+	agent, job, cleanup := setupAgent(t, &fakeStepsRunner{})
+	defer cleanup()
+	job.Command = workers.PlanJob
+	job.Steps = append(job.Steps, valid.Step{StepName: "plan"})
+
+	res := agent.Process(job)
+	Equals(t, workers.Result{Outputs: []string{"planned"}, PlanFile: []byte("planfile")}, res)
+	assertCloneDeleted(t, agent)
+}
+
+func TestAgent_ProcessApply(t *testing.T) {
+	runner := &fakeStepsRunner{}
+	agent, job, cleanup := setupAgent(t, runner)
+	defer cleanup()
+	job.Command = workers.ApplyJob
+	job.Steps = append(job.Steps, valid.Step{StepName: "apply"})
+	job.PlanFile = []byte("planfile from server")
+
+	res := agent.Process(job)
+	Equals(t, workers.Result{Outputs: []string{"applied"}}, res)
+	Equals(t, "planfile from server", runner.planFile)
+	assertCloneDeleted(t, agent)
+}
+
+func TestAgent_ProcessStepsError(t *testing.T) {
+	agent, job, cleanup := setupAgent(t, &fakeStepsRunner{err: fmt.Errorf("exit status 1")})
+	defer cleanup()
+	job.Command = workers.PlanJob
+	job.Steps = append(job.Steps, valid.Step{StepName: "plan"})
+
+	res := agent.Process(job)
+	Equals(t, workers.Result{Outputs: []string{"planned"}, Error: "exit status 1"}, res)
+}
+
+func TestAgent_ProcessDirNotExist(t *testing.T) {
+	agent, job, cleanup := setupAgent(t, &fakeStepsRunner{})
+	defer cleanup()
+	job.Command = workers.PlanJob
+	job.RepoRelDir = "missing"
+
+	res := agent.Process(job)
+	Equals(t, `dir "missing" does not exist`, res.Error)
+}
+
+func TestAgent_ProcessMultiEnv(t *testing.T) {
+	dataDir, cleanupDataDir := TempDir(t)
+	defer cleanupDataDir()
+	// The default version is set and already "downloaded" so terraform
+	// doesn't have to be installed.
+	Ok(t, os.MkdirAll(filepath.Join(dataDir, "bin"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(dataDir, "bin", "terraform0.12.0"), nil, 0700)) // nolint: gosec
+	agent, err := workers.NewAgent(workers.AgentConfig{DataDir: dataDir, DefaultTFVersion: "0.12.0"}, logging.NewNoopLogger())
+	Ok(t, err)
+	_, job, cleanup := setupAgent(t, agent.Runner)
+	defer cleanup()
+	job.Command = workers.PlanJob
+	job.Steps = []valid.Step{
+		{StepName: "multienv", RunCommand: "printf 'FOO=foo\\nBAR=bar\\n'"},
+		{StepName: "run", RunCommand: "echo $FOO $BAR"},
+	}
+
+	res := agent.Process(job)
+	Equals(t, workers.Result{Outputs: []string{"foo bar\n"}}, res)
+}
+
+func assertCloneDeleted(t *testing.T, agent *workers.Agent) {
+	t.Helper()
+	_, err := os.Stat(filepath.Join(agent.DataDir, "repos", "owner", "repo", "1", "default"))
+	Assert(t, os.IsNotExist(err), "expected clone to be deleted")
+}
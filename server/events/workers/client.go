@@ -0,0 +1,97 @@
+package workers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ClaimPath and CompletePath are the API routes workers use to claim jobs and
+// report their results.
+const (
+	ClaimPath    = "/api/workers/jobs/claim"
+	CompletePath = "/api/workers/jobs/%s/result"
+)
+
+// Client is used by workers to claim jobs from the server and report their
+// results.
+type Client struct {
+	HTTPClient *http.Client
+	// ServerURL is the URL of the Atlantis server, ex.
+	// https://atlantis.example.com.
+	ServerURL string
+	// Token is an API token with the workers scope.
+	Token string
+}
+
+// Claim claims the next job from the server. It returns nil if there wasn't
+// a job to run before the server stopped waiting for one.
+func (c *Client) Claim() (*Job, error) {
+	var job Job
+	found, err := c.makeRequest("POST", ClaimPath, nil, &job)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Complete reports the result of the job with id.
+func (c *Client) Complete(id string, res Result) error {
+	_, err := c.makeRequest("POST", fmt.Sprintf(CompletePath, url.PathEscape(id)), res, nil)
+	return err
+}
+
+// makeRequest makes a request to path with body encoded as JSON, if it's set,
+// and decodes the response into out, if it's set. It returns false if the
+// server responded that there was no content.
+func (c *Client) makeRequest(method string, path string, body interface{}, out interface{}) (bool, error) {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return false, errors.Wrap(err, "encoding request")
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.ServerURL, "/")+path, reqBody)
+	if err != nil {
+		return false, errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	requestStr := fmt.Sprintf("%s %s", method, path)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, errors.Wrapf(err, "reading response from request %q", requestStr)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return true, nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return false, errors.Wrapf(err, "parsing response from request %q", requestStr)
+	}
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package workers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Dispatcher runs the steps of project commands on workers. The server still
+// clones the repo, locks the project and checks apply requirements, and it
+// keeps the planfiles that workers generate so any worker can apply them.
+type Dispatcher struct {
+	Queue *Queue
+	// CheckoutMerge is true if workers should check out the base branch
+	// merged with the pull request's branch.
+	CheckoutMerge bool
+	// CheckoutDepth, SparseCheckoutPatterns and CloneOptions are passed to
+	// workers so they clone like the server. See events.FileWorkspace.
+	CheckoutDepth          int
+	SparseCheckoutPatterns func(repoID string) []string
+	CloneOptions           func(repoID string) valid.CloneOptions
+}
+
+// Plan runs ctx's plan steps on a worker and saves the generated planfile to
+// the project at path.
+func (d *Dispatcher) Plan(ctx models.ProjectCommandContext, path string) ([]string, error) {
+	res, err := d.Queue.Dispatch(d.newJob(PlanJob, ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return res.Outputs, errors.New(res.Error)
+	}
+	if res.PlanFile != nil {
+		if err := ioutil.WriteFile(planPath(ctx, path), res.PlanFile, 0600); err != nil {
+			return res.Outputs, errors.Wrap(err, "saving planfile")
+		}
+	}
+	return res.Outputs, nil
+}
+
+// Apply runs ctx's apply steps on a worker with the planfile saved in the
+// project at path. The planfile is deleted once it's applied.
+func (d *Dispatcher) Apply(ctx models.ProjectCommandContext, path string) ([]string, error) {
+	planFile, err := ioutil.ReadFile(planPath(ctx, path))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no plan found at path %q and workspace %q–did you run plan?", ctx.RepoRelDir, ctx.Workspace)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading planfile")
+	}
+	job := d.newJob(ApplyJob, ctx)
+	job.PlanFile = planFile
+	res, err := d.Queue.Dispatch(job)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return res.Outputs, errors.New(res.Error)
+	}
+	if err := os.Remove(planPath(ctx, path)); err != nil {
+		ctx.Log.Warn("failed to delete planfile after successful apply: %s", err)
+	}
+	return res.Outputs, nil
+}
+
+// newJob builds a job that runs command for ctx and clones the repo like the
+// server would.
+func (d *Dispatcher) newJob(command string, ctx models.ProjectCommandContext) Job {
+	job := NewJob(command, ctx, d.CheckoutMerge)
+	job.CheckoutDepth = d.CheckoutDepth
+	if d.SparseCheckoutPatterns != nil {
+		job.SparseCheckout = d.SparseCheckoutPatterns(ctx.BaseRepo.ID())
+	}
+	if d.CloneOptions != nil {
+		opts := d.CloneOptions(ctx.BaseRepo.ID())
+		job.Submodules = opts.Submodules
+		job.LFS = opts.LFS
+	}
+	return job
+}
+
+func planPath(ctx models.ProjectCommandContext, path string) string {
+	return filepath.Join(path, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+}
@@ -0,0 +1,118 @@
+package workers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// runWorker claims one job from q, checks it with check and completes it with
+// res.
+func runWorker(t *testing.T, q *workers.Queue, res workers.Result, check func(job workers.Job)) {
+	go func() {
+		job := q.Claim(time.Second)
+		if job == nil {
+			return
+		}
+		check(*job)
+		q.Complete(job.ID, res) // nolint: errcheck
+	}()
+}
+
+func TestDispatcher_Plan(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	q := workers.NewQueue(time.Minute)
+	d := &workers.Dispatcher{Queue: q, CheckoutMerge: true}
+	ctx := models.ProjectCommandContext{
+		Log:                   logging.NewNoopLogger(),
+		BaseRepo:              models.Repo{FullName: "owner/repo"},
+		Pull:                  models.PullRequest{Num: 1},
+		ProjectName:           "proj",
+		RepoRelDir:            "dir",
+		Workspace:             "default",
+		Steps:                 []valid.Step{{StepName: "init"}, {StepName: "plan", ExtraArgs: []string{"-var", "a=b"}}},
+		TerraformVersion:      version.Must(version.NewVersion("0.12.24")),
+		ContainerImage:        "alpine/terragrunt",
+		TerraformDistribution: "opentofu",
+		StateLockRetry:        &valid.StateLockRetry{MaxAttempts: 5, Backoff: time.Minute},
+	}
+
+	var job workers.Job
+	runWorker(t, q, workers.Result{Outputs: []string{"init", "plan"}, PlanFile: []byte("planfile")}, func(j workers.Job) {
+		job = j
+	})
+	outputs, err := d.Plan(ctx, tmp)
+	Ok(t, err)
+	Equals(t, []string{"init", "plan"}, outputs)
+
+	Equals(t, workers.PlanJob, job.Command)
+	Equals(t, true, job.CheckoutMerge)
+	Equals(t, "owner/repo", job.BaseRepo.FullName)
+	Equals(t, ctx.Steps, job.Steps)
+	Equals(t, "0.12.24", job.TerraformVersion)
+	jobCtx, err := job.ProjectCommandContext(logging.NewNoopLogger())
+	Ok(t, err)
+	Equals(t, ctx.TerraformVersion.String(), jobCtx.TerraformVersion.String())
+	Equals(t, "alpine/terragrunt", jobCtx.ContainerImage)
+	Equals(t, "opentofu", jobCtx.TerraformDistribution)
+	Equals(t, ctx.StateLockRetry, jobCtx.StateLockRetry)
+
+	planFile, err := ioutil.ReadFile(filepath.Join(tmp, "proj-default.tfplan"))
+	Ok(t, err)
+	Equals(t, "planfile", string(planFile))
+}
+
+func TestDispatcher_PlanError(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	q := workers.NewQueue(time.Minute)
+	d := &workers.Dispatcher{Queue: q}
+	runWorker(t, q, workers.Result{Outputs: []string{"init"}, Error: "exit status 1"}, func(workers.Job) {})
+
+	outputs, err := d.Plan(models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}, tmp)
+	ErrEquals(t, "exit status 1", err)
+	Equals(t, []string{"init"}, outputs)
+	_, err = os.Stat(filepath.Join(tmp, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "expected no planfile")
+}
+
+func TestDispatcher_Apply(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("planfile"), 0600))
+	q := workers.NewQueue(time.Minute)
+	d := &workers.Dispatcher{Queue: q}
+
+	var job workers.Job
+	runWorker(t, q, workers.Result{Outputs: []string{"applied"}}, func(j workers.Job) {
+		job = j
+	})
+	outputs, err := d.Apply(models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}, tmp)
+	Ok(t, err)
+	Equals(t, []string{"applied"}, outputs)
+	Equals(t, workers.ApplyJob, job.Command)
+	Equals(t, "planfile", string(job.PlanFile))
+
+	// The planfile is deleted once it's applied.
+	_, err = os.Stat(planPath)
+	Assert(t, os.IsNotExist(err), "expected planfile to be deleted")
+}
+
+func TestDispatcher_ApplyNoPlan(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	d := &workers.Dispatcher{Queue: workers.NewQueue(time.Minute)}
+	_, err := d.Apply(models.ProjectCommandContext{Log: logging.NewNoopLogger(), RepoRelDir: ".", Workspace: "default"}, tmp)
+	ErrEquals(t, `no plan found at path "." and workspace "default"–did you run plan?`, err)
+}
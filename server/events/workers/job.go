@@ -0,0 +1,148 @@
+// Package workers dispatches project commands from the server to remote
+// worker agents that clone, plan and apply projects, and reports their results
+// back to the server.
+package workers
+
+import (
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// The commands jobs run.
+const (
+	PlanJob  = "plan"
+	ApplyJob = "apply"
+)
+
+// Job is a project command that's run by a worker.
+type Job struct {
+	ID string `json:"id"`
+	// Command is PlanJob or ApplyJob.
+	Command string `json:"command"`
+	// CheckoutMerge is true if the worker should check out the base branch
+	// merged with the pull request's branch, like the server's
+	// --checkout-strategy=merge.
+	CheckoutMerge bool `json:"checkout_merge"`
+	// CheckoutDepth, SparseCheckout, Submodules and LFS control what the
+	// worker clones, like the server's --checkout-depth and the repo's
+	// sparse_checkout, submodules and lfs settings.
+	CheckoutDepth      int                `json:"checkout_depth,omitempty"`
+	SparseCheckout     []string           `json:"sparse_checkout,omitempty"`
+	Submodules         bool               `json:"submodules,omitempty"`
+	LFS                bool               `json:"lfs,omitempty"`
+	BaseRepo           models.Repo        `json:"base_repo"`
+	HeadRepo           models.Repo        `json:"head_repo"`
+	Pull               models.PullRequest `json:"pull"`
+	User               models.User        `json:"user"`
+	ProjectName        string             `json:"project_name"`
+	RepoRelDir         string             `json:"repo_rel_dir"`
+	Workspace          string             `json:"workspace"`
+	WorkspaceVarFile   bool               `json:"workspace_var_file,omitempty"`
+	VarFile            string             `json:"var_file,omitempty"`
+	RepoConfigVersion  int                `json:"repo_config_version"`
+	Steps              []valid.Step       `json:"steps"`
+	EscapedCommentArgs []string           `json:"escaped_comment_args"`
+	// TerraformVersion is empty if the worker's default version should be
+	// used.
+	TerraformVersion string `json:"terraform_version"`
+	// TerraformDistribution is empty if the worker's distribution should be
+	// used.
+	TerraformDistribution string `json:"terraform_distribution,omitempty"`
+	// ContainerImage is the image the steps run in, from the project's
+	// workflow.
+	ContainerImage string `json:"container_image,omitempty"`
+	// StateLockRetry is how the steps are retried when the Terraform state
+	// is locked, from the project's workflow.
+	StateLockRetry *valid.StateLockRetry `json:"state_lock_retry,omitempty"`
+	// CloudCredential is the cloud identity the worker assumes before
+	// running the steps, from the server-side repo config.
+	CloudCredential *valid.CloudCredential `json:"cloud_credential,omitempty"`
+	// EnvVars are set for the steps, from the server-side repo config.
+	// Values that are read from the environment are read from the worker's.
+	EnvVars []valid.EnvVar `json:"env_vars,omitempty"`
+	// GitCredentials are the credentials the steps fetch private module
+	// sources with. Secrets are read from the worker's environment and
+	// filesystem.
+	GitCredentials []valid.GitCredential `json:"git_credentials,omitempty"`
+	Verbose        bool                  `json:"verbose"`
+	// PlanFile is the planfile to apply. It's only set for apply jobs.
+	PlanFile []byte `json:"plan_file,omitempty"`
+}
+
+// Result is the result of running a job.
+type Result struct {
+	// Outputs are the outputs of the steps that were run.
+	Outputs []string `json:"outputs"`
+	// Error is set if the job failed.
+	Error string `json:"error,omitempty"`
+	// PlanFile is the planfile generated by a plan job.
+	PlanFile []byte `json:"plan_file,omitempty"`
+}
+
+// NewJob builds a job that runs command for the project described by ctx.
+func NewJob(command string, ctx models.ProjectCommandContext, checkoutMerge bool) Job {
+	job := Job{
+		Command:               command,
+		CheckoutMerge:         checkoutMerge,
+		BaseRepo:              ctx.BaseRepo,
+		HeadRepo:              ctx.HeadRepo,
+		Pull:                  ctx.Pull,
+		User:                  ctx.User,
+		ProjectName:           ctx.ProjectName,
+		RepoRelDir:            ctx.RepoRelDir,
+		Workspace:             ctx.Workspace,
+		WorkspaceVarFile:      ctx.WorkspaceVarFile,
+		VarFile:               ctx.VarFile,
+		RepoConfigVersion:     ctx.RepoConfigVersion,
+		Steps:                 ctx.Steps,
+		EscapedCommentArgs:    ctx.EscapedCommentArgs,
+		ContainerImage:        ctx.ContainerImage,
+		StateLockRetry:        ctx.StateLockRetry,
+		CloudCredential:       ctx.CloudCredential,
+		EnvVars:               ctx.EnvVars,
+		GitCredentials:        ctx.GitCredentials,
+		Verbose:               ctx.Verbose,
+		TerraformDistribution: ctx.TerraformDistribution,
+	}
+	if ctx.TerraformVersion != nil {
+		job.TerraformVersion = ctx.TerraformVersion.String()
+	}
+	return job
+}
+
+// ProjectCommandContext returns the context the job's steps are run with.
+func (j Job) ProjectCommandContext(log *logging.SimpleLogger) (models.ProjectCommandContext, error) {
+	ctx := models.ProjectCommandContext{
+		BaseRepo:              j.BaseRepo,
+		HeadRepo:              j.HeadRepo,
+		Pull:                  j.Pull,
+		User:                  j.User,
+		Log:                   log,
+		ProjectName:           j.ProjectName,
+		RepoRelDir:            j.RepoRelDir,
+		Workspace:             j.Workspace,
+		WorkspaceVarFile:      j.WorkspaceVarFile,
+		VarFile:               j.VarFile,
+		RepoConfigVersion:     j.RepoConfigVersion,
+		Steps:                 j.Steps,
+		EscapedCommentArgs:    j.EscapedCommentArgs,
+		ContainerImage:        j.ContainerImage,
+		StateLockRetry:        j.StateLockRetry,
+		CloudCredential:       j.CloudCredential,
+		EnvVars:               j.EnvVars,
+		GitCredentials:        j.GitCredentials,
+		Verbose:               j.Verbose,
+		TerraformDistribution: j.TerraformDistribution,
+	}
+	if j.TerraformVersion != "" {
+		v, err := version.NewVersion(j.TerraformVersion)
+		if err != nil {
+			return ctx, errors.Wrapf(err, "parsing terraform version %q", j.TerraformVersion)
+		}
+		ctx.TerraformVersion = v
+	}
+	return ctx, nil
+}
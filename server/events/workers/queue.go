@@ -0,0 +1,112 @@
+package workers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultJobTimeout is how long the server waits for a job to be run if the
+// queue's timeout isn't set.
+const DefaultJobTimeout = 2 * time.Hour
+
+// ErrJobNotFound is returned when completing a job that wasn't claimed or
+// that has timed out.
+var ErrJobNotFound = errors.New("job not found")
+
+// Queue holds the jobs waiting to be claimed by workers and the jobs that
+// workers are running. It's held in memory so jobs are lost if the server
+// restarts, like commands that are running on the server.
+type Queue struct {
+	// Timeout is how long a job can wait to be claimed and run before it
+	// fails. If a worker dies while running a job, the job fails once it
+	// times out.
+	Timeout time.Duration
+
+	jobs    chan *queuedJob
+	mu      sync.Mutex
+	claimed map[string]*queuedJob
+}
+
+type queuedJob struct {
+	job    Job
+	result chan Result
+}
+
+// NewQueue returns an empty queue whose jobs time out after timeout.
+func NewQueue(timeout time.Duration) *Queue {
+	if timeout == 0 {
+		timeout = DefaultJobTimeout
+	}
+	return &Queue{
+		Timeout: timeout,
+		jobs:    make(chan *queuedJob),
+		claimed: make(map[string]*queuedJob),
+	}
+}
+
+// Dispatch queues job and blocks until a worker has run it and reported its
+// result.
+func (q *Queue) Dispatch(job Job) (Result, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Result{}, err
+	}
+	job.ID = id
+	qj := &queuedJob{job: job, result: make(chan Result, 1)}
+	timeout := time.After(q.Timeout)
+
+	select {
+	case q.jobs <- qj:
+	case <-timeout:
+		return Result{}, fmt.Errorf("timed out after %s waiting for a worker to claim the job", q.Timeout)
+	}
+
+	select {
+	case res := <-qj.result:
+		return res, nil
+	case <-timeout:
+		q.mu.Lock()
+		delete(q.claimed, id)
+		q.mu.Unlock()
+		return Result{}, fmt.Errorf("timed out after %s waiting for the worker running the job to report its result", q.Timeout)
+	}
+}
+
+// Claim returns the next job to run or nil if there wasn't one within wait.
+func (q *Queue) Claim(wait time.Duration) *Job {
+	select {
+	case qj := <-q.jobs:
+		q.mu.Lock()
+		q.claimed[qj.job.ID] = qj
+		q.mu.Unlock()
+		return &qj.job
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Complete reports the result of the claimed job with id.
+func (q *Queue) Complete(id string, res Result) error {
+	q.mu.Lock()
+	qj, ok := q.claimed[id]
+	delete(q.claimed, id)
+	q.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+	qj.result <- res
+	return nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating job id")
+	}
+	return hex.EncodeToString(b), nil
+}
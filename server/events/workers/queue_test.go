@@ -0,0 +1,57 @@
+package workers_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/workers"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestQueue_DispatchClaimComplete(t *testing.T) {
+	q := workers.NewQueue(time.Minute)
+	results := make(chan workers.Result)
+	go func() {
+		res, err := q.Dispatch(workers.Job{Command: workers.PlanJob, Workspace: "default"})
+		Ok(t, err)
+		results <- res
+	}()
+
+	job := q.Claim(time.Second)
+	Assert(t, job != nil, "expected to claim job")
+	Equals(t, workers.PlanJob, job.Command)
+	Equals(t, "default", job.Workspace)
+	Assert(t, job.ID != "", "expected job to have an id")
+
+	Ok(t, q.Complete(job.ID, workers.Result{Outputs: []string{"output"}}))
+	Equals(t, workers.Result{Outputs: []string{"output"}}, <-results)
+
+	// Jobs can only be completed once.
+	Equals(t, workers.ErrJobNotFound, q.Complete(job.ID, workers.Result{}))
+}
+
+func TestQueue_ClaimNoJobs(t *testing.T) {
+	q := workers.NewQueue(time.Minute)
+	Assert(t, q.Claim(10*time.Millisecond) == nil, "expected no job")
+}
+
+func TestQueue_DispatchTimesOutUnclaimed(t *testing.T) {
+	q := workers.NewQueue(10 * time.Millisecond)
+	_, err := q.Dispatch(workers.Job{})
+	Assert(t, err != nil && strings.Contains(err.Error(), "waiting for a worker to claim the job"), "expected claim timeout, got %v", err)
+}
+
+func TestQueue_DispatchTimesOutClaimed(t *testing.T) {
+	q := workers.NewQueue(50 * time.Millisecond)
+	claimed := make(chan *workers.Job)
+	go func() {
+		claimed <- q.Claim(time.Second)
+	}()
+	_, err := q.Dispatch(workers.Job{})
+	Assert(t, err != nil && strings.Contains(err.Error(), "to report its result"), "expected result timeout, got %v", err)
+
+	// The worker's result is rejected once the job has timed out.
+	job := <-claimed
+	Equals(t, workers.ErrJobNotFound, q.Complete(job.ID, workers.Result{}))
+}
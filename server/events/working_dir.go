@@ -15,6 +15,7 @@ package events
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,10 +24,23 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
-const workingDirPrefix = "repos"
+// WorkingDirPrefix is the directory under the data dir that repos are cloned
+// into. Clones are at {WorkingDirPrefix}/{repo full name}/{pull num}/{workspace}.
+const WorkingDirPrefix = "repos"
+
+// ReposDir returns the directory that repos are cloned into: reposDir if it's
+// set, otherwise {dataDir}/{WorkingDirPrefix}.
+func ReposDir(dataDir string, reposDir string) string {
+	if reposDir != "" {
+		return reposDir
+	}
+	return filepath.Join(dataDir, WorkingDirPrefix)
+}
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_working_dir.go WorkingDir
 //go:generate pegomock generate -m --use-experimental-model-gen --package events WorkingDir
@@ -45,16 +59,67 @@ type WorkingDir interface {
 	// Delete deletes the workspace for this repo and pull.
 	Delete(r models.Repo, p models.PullRequest) error
 	DeleteForWorkspace(r models.Repo, p models.PullRequest, workspace string) error
+	// GetModifiedFilesSince returns the files, relative to the repo root,
+	// that were modified between commit and the pull request's head commit
+	// in the clone for workspace. commit must be in the clone, ex. because
+	// the clone was updated from it.
+	GetModifiedFilesSince(r models.Repo, p models.PullRequest, workspace string, commit string) ([]string, error)
+	// GetMergeBaseModifiedFiles returns the files, relative to the repo
+	// root, that the pull request modified since it branched off its base
+	// branch by diffing the clone for workspace against their merge base.
+	// Unlike the lists of VCS hosts' APIs, it's never truncated.
+	GetMergeBaseModifiedFiles(log *logging.SimpleLogger, baseRepo models.Repo, headRepo models.Repo, p models.PullRequest, workspace string) ([]string, error)
+	// GetMergedBaseCommit returns the commit of the base branch that's
+	// merged into the clone for workspace. It returns an empty string if the
+	// head branch is checked out without merging.
+	GetMergedBaseCommit(r models.Repo, p models.PullRequest, workspace string) (string, error)
+	// GetBaseBranchCommit fetches the base branch into the clone for
+	// workspace and returns the commit it's at now. It returns an empty
+	// string if the head branch is checked out without merging since the
+	// base branch doesn't affect the clone.
+	GetBaseBranchCommit(log *logging.SimpleLogger, baseRepo models.Repo, headRepo models.Repo, p models.PullRequest, workspace string) (string, error)
+	// GetBaseBranchModifiedFiles returns the files, relative to the repo
+	// root, that were modified on the base branch between commit and the
+	// commit GetBaseBranchCommit last fetched into the clone for workspace.
+	GetBaseBranchModifiedFiles(r models.Repo, p models.PullRequest, workspace string, commit string) ([]string, error)
 }
 
 // FileWorkspace implements WorkingDir with the file system.
 type FileWorkspace struct {
 	DataDir string
+	// ReposDir, if set, is where repos are cloned instead of under DataDir,
+	// ex. a tmpfs mount so planfiles are never written to disk.
+	ReposDir string
 	// CheckoutMerge is true if we should check out the branch that corresponds
 	// to what the base branch will look like *after* the pull request is merged.
 	// If this is false, then we will check out the head branch from the pull
 	// request.
 	CheckoutMerge bool
+	// IncrementalFetch is true if we should update an existing clone that's
+	// at the wrong commit by fetching and resetting to the new commit rather
+	// than deleting it and cloning from scratch.
+	IncrementalFetch bool
+	// KeepPlans is true if planfiles should be kept when an existing clone
+	// is updated to a new commit so the plans the new commits don't affect
+	// can still be applied. Clones are always updated by fetching, as if
+	// IncrementalFetch was set, because re-cloning would delete them.
+	KeepPlans bool
+	// CheckoutDepth, if set, is how many commits of history are cloned. If
+	// 0, the full history is cloned when merging and one commit otherwise.
+	CheckoutDepth int
+	// SparseCheckoutPatterns, if set, returns the sparse checkout patterns of
+	// the repo with the given ID. Only the paths matching the patterns are
+	// checked out, along with the repo's atlantis.yaml. If it returns nil,
+	// the whole repo is checked out.
+	SparseCheckoutPatterns func(repoID string) []string
+	// CloneOptions, if set, returns whether the submodules and Git LFS
+	// objects of the repo with the given ID are checked out. If nil, they
+	// aren't.
+	CloneOptions func(repoID string) valid.CloneOptions
+	// CloneCache, if set, keeps mirrors of the base repos that clones
+	// reference so they don't need to download and store all the objects
+	// again.
+	CloneCache *CloneCache
 	// TestingOverrideHeadCloneURL can be used during testing to override the
 	// URL of the head repo to be cloned. If it's empty then we clone normally.
 	TestingOverrideHeadCloneURL string
@@ -106,6 +171,13 @@ func (w *FileWorkspace) Clone(
 		}
 
 		log.Debug("repo was already cloned but is not at correct commit, wanted %q got %q", p.HeadCommit, currCommit)
+		if w.IncrementalFetch || w.KeepPlans {
+			err := w.fetchAndReset(log, cloneDir, headRepo, p)
+			if err == nil {
+				return cloneDir, false, nil
+			}
+			log.Warn("will re-clone repo, could not update existing clone: %s", err)
+		}
 		// We'll fall through to re-clone.
 	}
 
@@ -160,6 +232,25 @@ func (w *FileWorkspace) forceClone(log *logging.SimpleLogger,
 	headRepo models.Repo,
 	p models.PullRequest) error {
 
+	err := w.cloneWithDepth(log, cloneDir, headRepo, p, w.CheckoutDepth)
+	if err != nil && w.CheckoutMerge && w.CheckoutDepth > 0 {
+		// The merge fails if the commit the branch was created from isn't in
+		// the shallow history so we fall back to the full history.
+		log.Warn("will re-clone repo with full history, could not merge shallow clone: %s", err)
+		return w.cloneWithDepth(log, cloneDir, headRepo, p, 0)
+	}
+	return err
+}
+
+// cloneWithDepth clones the repo into cloneDir, deleting anything that's
+// already there. If depth is 0, the full history is cloned when merging and
+// one commit otherwise.
+func (w *FileWorkspace) cloneWithDepth(log *logging.SimpleLogger,
+	cloneDir string,
+	headRepo models.Repo,
+	p models.PullRequest,
+	depth int) error {
+
 	err := os.RemoveAll(cloneDir)
 	if err != nil {
 		return errors.Wrapf(err, "deleting dir %q before cloning", cloneDir)
@@ -181,22 +272,44 @@ func (w *FileWorkspace) forceClone(log *logging.SimpleLogger,
 		baseCloneURL = w.TestingOverrideBaseCloneURL
 	}
 
-	var cmds [][]string
+	var depthArgs []string
+	if depth > 0 {
+		depthArgs = []string{fmt.Sprintf("--depth=%d", depth)}
+	}
+	// If only some paths are checked out, we clone without checking out any
+	// files and check out the paths once sparse checkout is configured.
+	sparsePatterns := w.sparseCheckoutPatterns(p.BaseRepo)
+	var checkoutArgs []string
+	if sparsePatterns != nil {
+		checkoutArgs = []string{"--no-checkout"}
+	}
+
+	var referenceArgs []string
+	if w.CloneCache != nil {
+		reference, release, refErr := w.CloneCache.Reference(log, p.BaseRepo, baseCloneURL)
+		if refErr != nil {
+			log.Warn("cloning without the clone cache: %s", refErr)
+		} else {
+			defer release()
+			referenceArgs = []string{"--reference", reference}
+		}
+	}
+
+	var cloneCmds, checkoutCmds [][]string
 	if w.CheckoutMerge {
-		// NOTE: We can't do a shallow clone when we're merging because we'll
-		// get merge conflicts if our clone doesn't have the commits that the
-		// branch we're merging branched off at.
+		// NOTE: Unless --checkout-depth is set, we can't do a shallow clone
+		// when we're merging because we'll get merge conflicts if our clone
+		// doesn't have the commits that the branch we're merging branched
+		// off at.
 		// See https://groups.google.com/forum/#!topic/git-users/v3MkuuiDJ98.
-		cmds = [][]string{
-			{
-				"git", "clone", "--branch", p.BaseBranch, "--single-branch", baseCloneURL, cloneDir,
-			},
+		cloneCmds = [][]string{
+			concatArgs([]string{"git", "clone", "--branch", p.BaseBranch, "--single-branch"}, depthArgs, checkoutArgs, referenceArgs, []string{baseCloneURL, cloneDir}),
+		}
+		checkoutCmds = [][]string{
 			{
 				"git", "remote", "add", "head", headCloneURL,
 			},
-			{
-				"git", "fetch", "head", fmt.Sprintf("+refs/heads/%s:", p.HeadBranch),
-			},
+			concatArgs([]string{"git", "fetch"}, depthArgs, []string{"head", fmt.Sprintf("+refs/heads/%s:", p.HeadBranch)}),
 			// We use --no-ff because we always want there to be a merge commit.
 			// This way, our branch will look the same regardless if the merge
 			// could be fast forwarded. This is useful later when we run
@@ -208,12 +321,190 @@ func (w *FileWorkspace) forceClone(log *logging.SimpleLogger,
 			},
 		}
 	} else {
+		if depthArgs == nil {
+			depthArgs = []string{"--depth=1"}
+		}
+		cloneCmds = [][]string{
+			concatArgs([]string{"git", "clone", "--branch", p.HeadBranch}, depthArgs, checkoutArgs, referenceArgs, []string{"--single-branch", headCloneURL, cloneDir}),
+		}
+	}
+
+	if err := w.runGitCmds(log, cloneDir, headRepo, p, cloneCmds); err != nil {
+		return err
+	}
+	if sparsePatterns != nil {
+		if err := w.configureSparseCheckout(log, cloneDir, headRepo, p, sparsePatterns); err != nil {
+			return err
+		}
+	}
+	if err := w.runGitCmds(log, cloneDir, headRepo, p, checkoutCmds); err != nil {
+		return err
+	}
+	return w.runGitCmds(log, cloneDir, headRepo, p, w.cloneOptionCmds(p.BaseRepo))
+}
+
+// cloneOptionCmds returns the commands that check out the submodules and Git
+// LFS objects of repo if its clone options are set. They're run after every
+// checkout so they match the checked out commit.
+func (w *FileWorkspace) cloneOptionCmds(repo models.Repo) [][]string {
+	if w.CloneOptions == nil {
+		return nil
+	}
+	opts := w.CloneOptions(repo.ID())
+	var cmds [][]string
+	if opts.Submodules {
+		// Sync first in case the pull request changed the submodules' URLs.
+		cmds = append(cmds,
+			[]string{"git", "submodule", "sync", "--recursive"},
+			[]string{"git", "submodule", "update", "--init", "--recursive", "--force"},
+		)
+	}
+	if opts.LFS {
+		// Installing the LFS filters in the clone means git lfs doesn't
+		// need to be set up in the server's global git config.
+		cmds = append(cmds,
+			[]string{"git", "lfs", "install", "--local"},
+			[]string{"git", "lfs", "pull"},
+		)
+		if opts.Submodules {
+			cmds = append(cmds, []string{"git", "submodule", "foreach", "--recursive", "git lfs install --local && git lfs pull"})
+		}
+	}
+	return cmds
+}
+
+// sparseCheckoutPatterns returns the sparse checkout patterns for repo or nil
+// if the whole repo should be checked out.
+func (w *FileWorkspace) sparseCheckoutPatterns(repo models.Repo) []string {
+	if w.SparseCheckoutPatterns == nil {
+		return nil
+	}
+	patterns := w.SparseCheckoutPatterns(repo.ID())
+	if len(patterns) == 0 {
+		return nil
+	}
+	// The repo config is always needed to find the repo's projects.
+	return append([]string{"/" + yaml.AtlantisYAMLFilename}, patterns...)
+}
+
+// configureSparseCheckout enables sparse checkout of patterns in the clone in
+// cloneDir, which was cloned without checking out any files, and then checks
+// out the files that match. We write the sparse-checkout file ourselves rather
+// than use git sparse-checkout since its default mode differs between git
+// versions.
+func (w *FileWorkspace) configureSparseCheckout(log *logging.SimpleLogger,
+	cloneDir string,
+	headRepo models.Repo,
+	p models.PullRequest,
+	patterns []string) error {
+
+	if err := w.runGitCmds(log, cloneDir, headRepo, p, [][]string{{"git", "config", "core.sparseCheckout", "true"}}); err != nil {
+		return err
+	}
+	sparseFile := filepath.Join(cloneDir, ".git", "info", "sparse-checkout")
+	if err := os.MkdirAll(filepath.Dir(sparseFile), 0700); err != nil {
+		return errors.Wrap(err, "creating sparse-checkout file")
+	}
+	if err := ioutil.WriteFile(sparseFile, []byte(strings.Join(patterns, "\n")+"\n"), 0600); err != nil {
+		return errors.Wrap(err, "writing sparse-checkout file")
+	}
+	log.Debug("checking out paths matching %s", strings.Join(patterns, ", "))
+	return w.runGitCmds(log, cloneDir, headRepo, p, [][]string{{"git", "read-tree", "-mu", "HEAD"}})
+}
+
+// concatArgs returns the concatenation of args.
+func concatArgs(args ...[]string) []string {
+	var all []string
+	for _, a := range args {
+		all = append(all, a...)
+	}
+	return all
+}
+
+// fetchAndReset updates the existing clone in cloneDir to the pull request's
+// head commit by fetching only the new commits. Files that aren't tracked by
+// git, ex. old planfiles, are deleted except for .terraform directories so
+// that modules and providers don't need to be downloaded again, and
+// planfiles if KeepPlans is set. If the clone
+// doesn't end up at the expected commit an error is returned and the caller
+// should re-clone.
+func (w *FileWorkspace) fetchAndReset(log *logging.SimpleLogger,
+	cloneDir string,
+	headRepo models.Repo,
+	p models.PullRequest) error {
+
+	headCloneURL := headRepo.CloneURL
+	if w.TestingOverrideHeadCloneURL != "" {
+		headCloneURL = w.TestingOverrideHeadCloneURL
+	}
+	baseCloneURL := p.BaseRepo.CloneURL
+	if w.TestingOverrideBaseCloneURL != "" {
+		baseCloneURL = w.TestingOverrideBaseCloneURL
+	}
+
+	var depthArgs []string
+	if w.CheckoutDepth > 0 {
+		depthArgs = []string{fmt.Sprintf("--depth=%d", w.CheckoutDepth)}
+	}
+	cleanCmd := []string{"git", "clean", "-ffdx", "-e", ".terraform"}
+	if w.KeepPlans {
+		cleanCmd = append(cleanCmd, "-e", "*.tfplan")
+	}
+	var cmds [][]string
+	pullHead := "HEAD"
+	if w.CheckoutMerge {
+		pullHead = "HEAD^2"
+		baseRef := fmt.Sprintf("refs/remotes/origin/%s", p.BaseBranch)
+		cmds = [][]string{
+			concatArgs([]string{"git", "fetch"}, depthArgs, []string{baseCloneURL, fmt.Sprintf("+refs/heads/%s:%s", p.BaseBranch, baseRef)}),
+			{
+				"git", "reset", "-q", "--hard", baseRef,
+			},
+			cleanCmd,
+			concatArgs([]string{"git", "fetch"}, depthArgs, []string{headCloneURL, fmt.Sprintf("+refs/heads/%s:", p.HeadBranch)}),
+			{
+				"git", "merge", "-q", "--no-ff", "-m", "atlantis-merge", "FETCH_HEAD",
+			},
+		}
+	} else {
+		if depthArgs == nil {
+			depthArgs = []string{"--depth=1"}
+		}
 		cmds = [][]string{
+			concatArgs([]string{"git", "fetch"}, depthArgs, []string{headCloneURL, fmt.Sprintf("+refs/heads/%s:", p.HeadBranch)}),
 			{
-				"git", "clone", "--branch", p.HeadBranch, "--depth=1", "--single-branch", headCloneURL, cloneDir,
+				"git", "reset", "-q", "--hard", "FETCH_HEAD",
 			},
+			cleanCmd,
 		}
 	}
+	cmds = append(cmds, w.cloneOptionCmds(p.BaseRepo)...)
+	if err := w.runGitCmds(log, cloneDir, headRepo, p, cmds); err != nil {
+		return err
+	}
+
+	// Check that we ended up where we expected. The branch could have been
+	// pushed to again since this event was sent.
+	revParseCmd := exec.Command("git", "rev-parse", pullHead) // #nosec
+	revParseCmd.Dir = cloneDir
+	output, err := revParseCmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "running git rev-parse %s: %s", pullHead, string(output))
+	}
+	currCommit := strings.Trim(string(output), "\n")
+	if !strings.HasPrefix(currCommit, p.HeadCommit) {
+		return fmt.Errorf("expected to be at commit %q after fetching but was at %q", p.HeadCommit, currCommit)
+	}
+	log.Info("updated existing clone in %q to commit %q", cloneDir, p.HeadCommit)
+	return nil
+}
+
+// runGitCmds runs each of cmds in cloneDir, stopping at the first error.
+func (w *FileWorkspace) runGitCmds(log *logging.SimpleLogger,
+	cloneDir string,
+	headRepo models.Repo,
+	p models.PullRequest,
+	cmds [][]string) error {
 
 	for _, args := range cmds {
 		cmd := exec.Command(args[0], args[1:]...) // nolint: gosec
@@ -246,6 +537,177 @@ func (w *FileWorkspace) GetWorkingDir(r models.Repo, p models.PullRequest, works
 	return repoDir, nil
 }
 
+// GetModifiedFilesSince returns the files modified between commit and the
+// pull request's head commit in the clone for workspace.
+func (w *FileWorkspace) GetModifiedFilesSince(r models.Repo, p models.PullRequest, workspace string, commit string) ([]string, error) {
+	cloneDir, err := w.GetWorkingDir(r, p, workspace)
+	if err != nil {
+		return nil, err
+	}
+	// When merging, HEAD is our merge commit so the pull request's head
+	// commit is its second parent.
+	pullHead := "HEAD"
+	if w.CheckoutMerge {
+		pullHead = "HEAD^2"
+	}
+	diffCmd := exec.Command("git", "diff", "--name-only", commit, pullHead) // #nosec
+	diffCmd.Dir = cloneDir
+	output, err := diffCmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "running git diff --name-only %s %s: %s", commit, pullHead, string(output))
+	}
+	var files []string
+	for _, file := range strings.Split(string(output), "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// mergeBaseRef is the ref that the base branch is fetched into to find its
+// merge base with the pull request.
+const mergeBaseRef = "refs/atlantis/merge-base"
+
+// GetMergeBaseModifiedFiles returns the files modified between the merge base
+// of the pull request and its base branch and the pull request's head commit
+// in the clone for workspace. Deleted files and both the old and new paths of
+// renamed files are included, like they are by VCS hosts.
+func (w *FileWorkspace) GetMergeBaseModifiedFiles(log *logging.SimpleLogger, baseRepo models.Repo, headRepo models.Repo, p models.PullRequest, workspace string) ([]string, error) {
+	cloneDir, err := w.GetWorkingDir(baseRepo, p, workspace)
+	if err != nil {
+		return nil, err
+	}
+	headCloneURL := headRepo.CloneURL
+	if w.TestingOverrideHeadCloneURL != "" {
+		headCloneURL = w.TestingOverrideHeadCloneURL
+	}
+	baseCloneURL := p.BaseRepo.CloneURL
+	if w.TestingOverrideBaseCloneURL != "" {
+		baseCloneURL = w.TestingOverrideBaseCloneURL
+	}
+
+	// Shallow clones don't have the merge base so they're unshallowed
+	// first. Clones that merge have commits from both repos so each is
+	// unshallowed from the repo it's from.
+	fetches := [][]string{
+		{headCloneURL, fmt.Sprintf("+refs/heads/%s:", p.HeadBranch)},
+		{baseCloneURL, fmt.Sprintf("+refs/heads/%s:%s", p.BaseBranch, mergeBaseRef)},
+	}
+	for i, fetch := range fetches {
+		shallow, err := w.gitOutput(cloneDir, "rev-parse", "--is-shallow-repository") // nolint: vetshadow
+		if err != nil {
+			return nil, err
+		}
+		args := []string{"git", "fetch"}
+		if shallow == "true" {
+			args = append(args, "--unshallow")
+		} else if i == 0 {
+			// The base branch always needs to be fetched but the head
+			// branch is already there.
+			continue
+		}
+		if err := w.runGitCmds(log, cloneDir, headRepo, p, [][]string{concatArgs(args, fetch)}); err != nil { // nolint: vetshadow
+			return nil, err
+		}
+	}
+
+	// When merging, HEAD is our merge commit so the pull request's head
+	// commit is its second parent.
+	pullHead := "HEAD"
+	if w.CheckoutMerge {
+		pullHead = "HEAD^2"
+	}
+	mergeBase, err := w.gitOutput(cloneDir, "merge-base", mergeBaseRef, pullHead)
+	if err != nil {
+		return nil, err
+	}
+	// Without rename detection renamed files are listed as deleted from
+	// their old path and added at their new one.
+	output, err := w.gitOutput(cloneDir, "diff", "--name-only", "--no-renames", mergeBase, pullHead)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	log.Debug("git found %d files modified since merge base %s", len(files), mergeBase)
+	return files, nil
+}
+
+// GetMergedBaseCommit returns the commit of the base branch that's merged into
+// the clone for workspace, or an empty string if it's not merging.
+func (w *FileWorkspace) GetMergedBaseCommit(r models.Repo, p models.PullRequest, workspace string) (string, error) {
+	if !w.CheckoutMerge {
+		return "", nil
+	}
+	cloneDir, err := w.GetWorkingDir(r, p, workspace)
+	if err != nil {
+		return "", err
+	}
+	// HEAD is our merge commit so the base branch is its first parent.
+	return w.gitOutput(cloneDir, "rev-parse", "HEAD^1")
+}
+
+// baseBranchRef is the ref that GetBaseBranchCommit fetches the base branch
+// into.
+const baseBranchRef = "refs/atlantis/base"
+
+// GetBaseBranchCommit fetches the base branch into the clone for workspace and
+// returns the commit it's at, or an empty string if it's not merging.
+func (w *FileWorkspace) GetBaseBranchCommit(log *logging.SimpleLogger, baseRepo models.Repo, headRepo models.Repo, p models.PullRequest, workspace string) (string, error) {
+	if !w.CheckoutMerge {
+		return "", nil
+	}
+	cloneDir, err := w.GetWorkingDir(baseRepo, p, workspace)
+	if err != nil {
+		return "", err
+	}
+	baseCloneURL := p.BaseRepo.CloneURL
+	if w.TestingOverrideBaseCloneURL != "" {
+		baseCloneURL = w.TestingOverrideBaseCloneURL
+	}
+	fetch := []string{"git", "fetch", baseCloneURL, fmt.Sprintf("+refs/heads/%s:%s", p.BaseBranch, baseBranchRef)}
+	if err := w.runGitCmds(log, cloneDir, headRepo, p, [][]string{fetch}); err != nil {
+		return "", err
+	}
+	return w.gitOutput(cloneDir, "rev-parse", baseBranchRef)
+}
+
+// GetBaseBranchModifiedFiles returns the files modified on the base branch
+// between commit and the commit GetBaseBranchCommit last fetched.
+func (w *FileWorkspace) GetBaseBranchModifiedFiles(r models.Repo, p models.PullRequest, workspace string, commit string) ([]string, error) {
+	cloneDir, err := w.GetWorkingDir(r, p, workspace)
+	if err != nil {
+		return nil, err
+	}
+	output, err := w.gitOutput(cloneDir, "diff", "--name-only", commit, baseBranchRef)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// gitOutput runs git with args in cloneDir and returns its trimmed output.
+func (w *FileWorkspace) gitOutput(cloneDir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...) // #nosec
+	cmd.Dir = cloneDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "running git %s: %s", strings.Join(args, " "), string(output))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // GetPullDir returns the dir where the workspaces for this pull are cloned.
 // If the dir doesn't exist it will return an error.
 func (w *FileWorkspace) GetPullDir(r models.Repo, p models.PullRequest) (string, error) {
@@ -267,7 +729,7 @@ func (w *FileWorkspace) DeleteForWorkspace(r models.Repo, p models.PullRequest,
 }
 
 func (w *FileWorkspace) repoPullDir(r models.Repo, p models.PullRequest) string {
-	return filepath.Join(w.DataDir, workingDirPrefix, r.FullName, strconv.Itoa(p.Num))
+	return filepath.Join(ReposDir(w.DataDir, w.ReposDir), r.FullName, strconv.Itoa(p.Num))
 }
 
 func (w *FileWorkspace) cloneDir(r models.Repo, p models.PullRequest, workspace string) string {
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_working_dir_prewarmer.go WorkingDirPrewarmer
+
+// WorkingDirPrewarmer gets the working directories of a pull request ready
+// ahead of time so that later plan commands run faster.
+type WorkingDirPrewarmer interface {
+	// Prewarm clones the pull request and runs terraform init for its
+	// modified projects.
+	Prewarm(ctx *CommandContext)
+}
+
+// DefaultWorkingDirPrewarmer implements WorkingDirPrewarmer.
+type DefaultWorkingDirPrewarmer struct {
+	ProjectCommandBuilder ProjectCommandBuilder
+	WorkingDir            WorkingDir
+	WorkingDirLocker      WorkingDirLocker
+	InitStepRunner        StepRunner
+}
+
+// Prewarm initializes each modified project that wasn't autoplanned. Projects
+// that were autoplanned have already been initialized.
+func (p *DefaultWorkingDirPrewarmer) Prewarm(ctx *CommandContext) {
+	projCtxs, err := p.ProjectCommandBuilder.BuildPlanCommands(ctx, &CommentCommand{Name: models.PlanCommand})
+	if err != nil {
+		ctx.Log.Warn("unable to pre-warm working directories: %s", err)
+		return
+	}
+	for _, projCtx := range projCtxs {
+		if projCtx.AutoplanEnabled {
+			continue
+		}
+		p.prewarmProject(projCtx)
+	}
+}
+
+func (p *DefaultWorkingDirPrewarmer) prewarmProject(ctx models.ProjectCommandContext) {
+	var initStep *valid.Step
+	for i := range ctx.Steps {
+		if ctx.Steps[i].StepName == "init" {
+			initStep = &ctx.Steps[i]
+			break
+		}
+	}
+	if initStep == nil {
+		ctx.Log.Debug("not pre-warming project at dir %q, workspace %q because its workflow has no init step", ctx.RepoRelDir, ctx.Workspace)
+		return
+	}
+
+	// We don't wait for the lock because if it's held then a command is
+	// already running and will have to initialize the project itself.
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	if err != nil {
+		ctx.Log.Debug("not pre-warming project at dir %q, workspace %q: %s", ctx.RepoRelDir, ctx.Workspace, err)
+		return
+	}
+	defer unlockFn()
+
+	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	if err != nil {
+		ctx.Log.Warn("unable to pre-warm project at dir %q, workspace %q: %s", ctx.RepoRelDir, ctx.Workspace, err)
+		return
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err := os.Stat(projAbsPath); err != nil {
+		ctx.Log.Debug("not pre-warming project at dir %q: %s", ctx.RepoRelDir, err)
+		return
+	}
+
+	ctx.Log.Info("pre-warming project at dir %q, workspace %q", ctx.RepoRelDir, ctx.Workspace)
+	if out, err := p.InitStepRunner.Run(ctx, initStep.ExtraArgs, projAbsPath, map[string]string{}); err != nil {
+		ctx.Log.Warn("unable to pre-warm project at dir %q, workspace %q: %s: %s", ctx.RepoRelDir, ctx.Workspace, err, out)
+	}
+}
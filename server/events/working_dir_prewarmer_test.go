@@ -0,0 +1,75 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDefaultWorkingDirPrewarmer_Prewarm(t *testing.T) {
+	RegisterMockTestingT(t)
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	builder := mocks.NewMockProjectCommandBuilder()
+	workingDir := mocks.NewMockWorkingDir()
+	locker := mocks.NewMockWorkingDirLocker()
+	initRunner := mocks.NewMockStepRunner()
+	p := events.DefaultWorkingDirPrewarmer{
+		ProjectCommandBuilder: builder,
+		WorkingDir:            workingDir,
+		WorkingDirLocker:      locker,
+		InitStepRunner:        initRunner,
+	}
+
+	logger := logging.NewNoopLogger()
+	initSteps := []valid.Step{{StepName: "init", ExtraArgs: []string{"-upgrade"}}, {StepName: "plan"}}
+	autoplanned := models.ProjectCommandContext{Log: logger, RepoRelDir: ".", Workspace: "default", AutoplanEnabled: true, Steps: initSteps}
+	notAutoplanned := models.ProjectCommandContext{Log: logger, RepoRelDir: ".", Workspace: "staging", Steps: initSteps}
+	noInit := models.ProjectCommandContext{Log: logger, RepoRelDir: ".", Workspace: "prod", Steps: []valid.Step{{StepName: "plan"}}}
+	ctx := &events.CommandContext{Log: logger}
+	When(builder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{autoplanned, notAutoplanned, noInit}, nil)
+	When(locker.TryLock(AnyString(), AnyInt(), AnyString())).ThenReturn(func() {}, nil)
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).
+		ThenReturn(repoDir, nil)
+
+	p.Prewarm(ctx)
+
+	builder.VerifyWasCalledOnce().BuildPlanCommands(ctx, &events.CommentCommand{Name: models.PlanCommand})
+	initRunner.VerifyWasCalledOnce().Run(notAutoplanned, []string{"-upgrade"}, repoDir, map[string]string{})
+	workingDir.VerifyWasCalledOnce().Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())
+}
+
+func TestDefaultWorkingDirPrewarmer_SkipsLockedWorkspace(t *testing.T) {
+	RegisterMockTestingT(t)
+	builder := mocks.NewMockProjectCommandBuilder()
+	workingDir := mocks.NewMockWorkingDir()
+	locker := mocks.NewMockWorkingDirLocker()
+	initRunner := mocks.NewMockStepRunner()
+	p := events.DefaultWorkingDirPrewarmer{
+		ProjectCommandBuilder: builder,
+		WorkingDir:            workingDir,
+		WorkingDirLocker:      locker,
+		InitStepRunner:        initRunner,
+	}
+
+	logger := logging.NewNoopLogger()
+	projCtx := models.ProjectCommandContext{Log: logger, RepoRelDir: ".", Workspace: "default", Steps: []valid.Step{{StepName: "init"}}}
+	When(builder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(locker.TryLock(AnyString(), AnyInt(), AnyString())).ThenReturn(nil, errors.New("locked"))
+
+	p.Prewarm(&events.CommandContext{Log: logger})
+
+	workingDir.VerifyWasCalled(Never()).Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())
+	initRunner.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
@@ -2,12 +2,16 @@ package events_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -302,6 +306,156 @@ func TestClone_RecloneWrongCommit(t *testing.T) {
 	Equals(t, expCommit, actCommit)
 }
 
+// Test that if the repo is already cloned but is at the wrong commit and
+// incremental fetching is enabled, we update the existing clone rather than
+// re-cloning.
+func TestClone_IncrementalFetch(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               false,
+		IncrementalFetch:            true,
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+	}
+	cloneDir, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+	}, "default")
+	Ok(t, err)
+
+	// Create files that should be deleted and kept by the update.
+	runCmd(t, cloneDir, "touch", "default.tfplan")
+	runCmd(t, cloneDir, "mkdir", ".terraform")
+	runCmd(t, cloneDir, "touch", ".terraform/proof")
+
+	// Now add a commit to the repo, so the clone is out of date.
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "newfile")
+	runCmd(t, repoDir, "git", "add", "newfile")
+	runCmd(t, repoDir, "git", "commit", "-m", "newfile")
+	expCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+
+	cloneDir, hasDiverged, err := wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+		HeadCommit: expCommit,
+	}, "default")
+	Ok(t, err)
+	Equals(t, false, hasDiverged)
+
+	actCommit := runCmd(t, cloneDir, "git", "rev-parse", "HEAD")
+	Equals(t, expCommit, strings.TrimSpace(actCommit))
+	_, err = os.Stat(filepath.Join(cloneDir, "newfile"))
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(cloneDir, ".terraform", "proof"))
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(cloneDir, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "expected old planfile to be deleted")
+}
+
+// Test that with KeepPlans the clone is updated by fetching, planfiles are
+// kept and we can list the files modified by the new commits.
+func TestClone_KeepPlans(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               false,
+		KeepPlans:                   true,
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+	}
+	cloneDir, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+	}, "default")
+	Ok(t, err)
+	prevCommit := strings.TrimSpace(runCmd(t, cloneDir, "git", "rev-parse", "HEAD"))
+	runCmd(t, cloneDir, "touch", "default.tfplan")
+	runCmd(t, cloneDir, "touch", "untracked")
+
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "mkdir", "dir1")
+	runCmd(t, repoDir, "touch", "dir1/main.tf")
+	runCmd(t, repoDir, "git", "add", "dir1/main.tf")
+	runCmd(t, repoDir, "git", "commit", "-m", "dir1")
+	expCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+
+	pull := models.PullRequest{
+		HeadBranch: "branch",
+		HeadCommit: expCommit,
+	}
+	cloneDir, _, err = wd.Clone(nil, models.Repo{}, models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, expCommit, strings.TrimSpace(runCmd(t, cloneDir, "git", "rev-parse", "HEAD")))
+	_, err = os.Stat(filepath.Join(cloneDir, "default.tfplan"))
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(cloneDir, "untracked"))
+	Assert(t, os.IsNotExist(err), "expected other untracked files to be deleted")
+
+	files, err := wd.GetModifiedFilesSince(models.Repo{}, pull, "default", prevCommit)
+	Ok(t, err)
+	Equals(t, []string{"dir1/main.tf"}, files)
+}
+
+// Test that incremental fetching works with the merge checkout strategy.
+func TestClone_IncrementalFetchCheckoutMerge(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	branchCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		IncrementalFetch:            true,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	cloneDir, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+		HeadCommit: branchCommit,
+		BaseBranch: "master",
+	}, "default")
+	Ok(t, err)
+	runCmd(t, cloneDir, "mkdir", ".terraform")
+	runCmd(t, cloneDir, "touch", ".terraform/proof")
+
+	// Advance both branches.
+	runCmd(t, repoDir, "touch", "branch-file2")
+	runCmd(t, repoDir, "git", "add", "branch-file2")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit2")
+	branchCommit = strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	runCmd(t, repoDir, "git", "checkout", "master")
+	runCmd(t, repoDir, "touch", "master-file")
+	runCmd(t, repoDir, "git", "add", "master-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "master-commit")
+
+	cloneDir, _, err = wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+		HeadCommit: branchCommit,
+		BaseBranch: "master",
+	}, "default")
+	Ok(t, err)
+
+	actCommit := runCmd(t, cloneDir, "git", "rev-parse", "HEAD^2")
+	Equals(t, branchCommit, strings.TrimSpace(actCommit))
+	for _, f := range []string{"branch-file2", "master-file", ".terraform/proof"} {
+		_, err = os.Stat(filepath.Join(cloneDir, f))
+		Ok(t, err)
+	}
+}
+
 // Test that if the branch we're merging into has diverged and we're using
 // checkout-strategy=merge, we warn the user (see #804).
 func TestClone_MasterHasDiverged(t *testing.T) {
@@ -373,6 +527,99 @@ func TestClone_MasterHasDiverged(t *testing.T) {
 	Equals(t, hasDiverged, false)
 }
 
+// Test that the files modified since the merge base are listed, not the ones
+// modified on the base branch since, even in shallow clones.
+func TestFileWorkspace_GetMergeBaseModifiedFiles(t *testing.T) {
+	for _, checkoutMerge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("checkout merge %t", checkoutMerge), func(t *testing.T) {
+			repoDir, cleanup := initRepo(t)
+			defer cleanup()
+			baseBranch := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "--abbrev-ref", "HEAD"))
+			runCmd(t, repoDir, "touch", "base-file")
+			runCmd(t, repoDir, "git", "add", "base-file")
+			runCmd(t, repoDir, "git", "commit", "-m", "base")
+			runCmd(t, repoDir, "git", "checkout", "branch")
+			runCmd(t, repoDir, "mkdir", "dir1")
+			runCmd(t, repoDir, "touch", "dir1/main.tf")
+			runCmd(t, repoDir, "git", "add", "dir1/main.tf")
+			runCmd(t, repoDir, "git", "commit", "-m", "dir1")
+			runCmd(t, repoDir, "git", "mv", ".gitkeep", "renamed")
+			runCmd(t, repoDir, "git", "commit", "-m", "rename")
+
+			dataDir, cleanup2 := TempDir(t)
+			defer cleanup2()
+			wd := &events.FileWorkspace{
+				DataDir:                     dataDir,
+				CheckoutMerge:               checkoutMerge,
+				TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+				TestingOverrideBaseCloneURL: fmt.Sprintf("file://%s", repoDir),
+			}
+			pull := models.PullRequest{
+				HeadBranch: "branch",
+				BaseBranch: baseBranch,
+			}
+			_, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, pull, "default")
+			Ok(t, err)
+
+			files, err := wd.GetMergeBaseModifiedFiles(logging.NewNoopLogger(), models.Repo{}, models.Repo{}, pull, "default")
+			Ok(t, err)
+			Equals(t, []string{".gitkeep", "dir1/main.tf", "renamed"}, files)
+		})
+	}
+}
+
+func TestFileWorkspace_BaseCommits(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	baseBranch := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "--abbrev-ref", "HEAD"))
+	mergedBase := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+		TestingOverrideBaseCloneURL: fmt.Sprintf("file://%s", repoDir),
+	}
+	pull := models.PullRequest{
+		HeadBranch: "branch",
+		BaseBranch: baseBranch,
+	}
+	_, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, pull, "default")
+	Ok(t, err)
+
+	// The base branch moves after it was merged.
+	runCmd(t, repoDir, "git", "checkout", baseBranch)
+	runCmd(t, repoDir, "touch", "base-file")
+	runCmd(t, repoDir, "git", "add", "base-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "base")
+	currBase := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+
+	merged, err := wd.GetMergedBaseCommit(models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, mergedBase, merged)
+	curr, err := wd.GetBaseBranchCommit(logging.NewNoopLogger(), models.Repo{}, models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, currBase, curr)
+	files, err := wd.GetBaseBranchModifiedFiles(models.Repo{}, pull, "default", merged)
+	Ok(t, err)
+	Equals(t, []string{"base-file"}, files)
+
+	// Without merging, the base branch doesn't matter.
+	wd.CheckoutMerge = false
+	merged, err = wd.GetMergedBaseCommit(models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, "", merged)
+	curr, err = wd.GetBaseBranchCommit(logging.NewNoopLogger(), models.Repo{}, models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, "", curr)
+}
+
 func initRepo(t *testing.T) (string, func()) {
 	repoDir, cleanup := TempDir(t)
 	runCmd(t, repoDir, "git", "init")
@@ -384,3 +631,209 @@ func initRepo(t *testing.T) (string, func()) {
 	runCmd(t, repoDir, "git", "branch", "branch")
 	return repoDir, cleanup
 }
+
+// Test that only --checkout-depth commits are cloned.
+func TestClone_CheckoutDepth(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	for _, f := range []string{"file1", "file2"} {
+		runCmd(t, repoDir, "touch", f)
+		runCmd(t, repoDir, "git", "add", f)
+		runCmd(t, repoDir, "git", "commit", "-m", f)
+	}
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutDepth:               2,
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+	}
+	cloneDir, _, err := wd.Clone(nil, models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+	}, "default")
+	Ok(t, err)
+	Equals(t, "2\n", runCmd(t, cloneDir, "git", "rev-list", "--count", "HEAD"))
+}
+
+// Test that if the merge fails because the clone is too shallow to contain
+// the commit the branch was created from, we re-clone with the full history.
+func TestClone_CheckoutMergeShallowTooShallow(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	branchCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+	runCmd(t, repoDir, "git", "checkout", "master")
+	for _, f := range []string{"master-file1", "master-file2"} {
+		runCmd(t, repoDir, "touch", f)
+		runCmd(t, repoDir, "git", "add", f)
+		runCmd(t, repoDir, "git", "commit", "-m", f)
+	}
+	masterCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		CheckoutDepth:               1,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	cloneDir, _, err := wd.Clone(logging.NewNoopLogger(), models.Repo{}, models.Repo{}, models.PullRequest{
+		HeadBranch: "branch",
+		BaseBranch: "master",
+	}, "default")
+	Ok(t, err)
+	Equals(t, masterCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD~1"))
+	Equals(t, branchCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD^2"))
+}
+
+// Test that with sparse checkout patterns only the matching paths and the
+// repo config are checked out.
+func TestClone_SparseCheckout(t *testing.T) {
+	for _, checkoutMerge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("merge=%t", checkoutMerge), func(t *testing.T) {
+			repoDir, cleanup := initRepo(t)
+			defer cleanup()
+			runCmd(t, repoDir, "git", "checkout", "branch")
+			for _, dir := range []string{"project1", "project2", "modules"} {
+				runCmd(t, repoDir, "mkdir", dir)
+				runCmd(t, repoDir, "touch", filepath.Join(dir, "main.tf"))
+			}
+			runCmd(t, repoDir, "touch", "atlantis.yaml")
+			runCmd(t, repoDir, "git", "add", ".")
+			runCmd(t, repoDir, "git", "commit", "-m", "projects")
+			runCmd(t, repoDir, "git", "checkout", "master")
+
+			dataDir, cleanup2 := TempDir(t)
+			defer cleanup2()
+			overrideURL := fmt.Sprintf("file://%s", repoDir)
+			wd := &events.FileWorkspace{
+				DataDir:                     dataDir,
+				CheckoutMerge:               checkoutMerge,
+				TestingOverrideHeadCloneURL: overrideURL,
+				TestingOverrideBaseCloneURL: overrideURL,
+				SparseCheckoutPatterns: func(repoID string) []string {
+					Equals(t, "github.com/owner/repo", repoID)
+					return []string{"/project1/", "/modules/"}
+				},
+			}
+			repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+			cloneDir, _, err := wd.Clone(logging.NewNoopLogger(), repo, repo, models.PullRequest{
+				BaseRepo:   repo,
+				HeadBranch: "branch",
+				BaseBranch: "master",
+			}, "default")
+			Ok(t, err)
+			for path, exists := range map[string]bool{
+				"atlantis.yaml":    true,
+				"project1/main.tf": true,
+				"modules/main.tf":  true,
+				"project2":         false,
+			} {
+				_, err := os.Stat(filepath.Join(cloneDir, path))
+				Equals(t, exists, err == nil)
+			}
+		})
+	}
+}
+
+// Test that submodules are checked out recursively if the repo's clone
+// options are set.
+func TestClone_Submodules(t *testing.T) {
+	// Git doesn't clone submodules from local paths by default.
+	os.Setenv("GIT_CONFIG_COUNT", "1")                   // nolint: errcheck
+	os.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow") // nolint: errcheck
+	os.Setenv("GIT_CONFIG_VALUE_0", "always")            // nolint: errcheck
+	defer func() {
+		for _, name := range []string{"GIT_CONFIG_COUNT", "GIT_CONFIG_KEY_0", "GIT_CONFIG_VALUE_0"} {
+			os.Unsetenv(name) // nolint: errcheck
+		}
+	}()
+
+	for _, checkoutMerge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("merge=%t", checkoutMerge), func(t *testing.T) {
+			modulesDir, cleanup := initRepo(t)
+			defer cleanup()
+			runCmd(t, modulesDir, "touch", "main.tf")
+			runCmd(t, modulesDir, "git", "add", "main.tf")
+			runCmd(t, modulesDir, "git", "commit", "-m", "module")
+
+			repoDir, cleanup2 := initRepo(t)
+			defer cleanup2()
+			runCmd(t, repoDir, "git", "checkout", "branch")
+			runCmd(t, repoDir, "git", "submodule", "add", modulesDir, "modules")
+			runCmd(t, repoDir, "git", "commit", "-m", "add modules")
+			runCmd(t, repoDir, "git", "checkout", "master")
+
+			dataDir, cleanup3 := TempDir(t)
+			defer cleanup3()
+			overrideURL := fmt.Sprintf("file://%s", repoDir)
+			wd := &events.FileWorkspace{
+				DataDir:                     dataDir,
+				CheckoutMerge:               checkoutMerge,
+				TestingOverrideHeadCloneURL: overrideURL,
+				TestingOverrideBaseCloneURL: overrideURL,
+				CloneOptions: func(repoID string) valid.CloneOptions {
+					Equals(t, "github.com/owner/repo", repoID)
+					return valid.CloneOptions{Submodules: true}
+				},
+			}
+			repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+			cloneDir, _, err := wd.Clone(logging.NewNoopLogger(), repo, repo, models.PullRequest{
+				BaseRepo:   repo,
+				HeadBranch: "branch",
+				BaseBranch: "master",
+			}, "default")
+			Ok(t, err)
+			_, err = os.Stat(filepath.Join(cloneDir, "modules", "main.tf"))
+			Ok(t, err)
+		})
+	}
+}
+
+// Test that clones reference the clone cache's mirror.
+func TestClone_CloneCache(t *testing.T) {
+	for _, checkoutMerge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("merge=%t", checkoutMerge), func(t *testing.T) {
+			repoDir, cleanup := initRepo(t)
+			defer cleanup()
+			runCmd(t, repoDir, "git", "checkout", "branch")
+			runCmd(t, repoDir, "touch", "branch-file")
+			runCmd(t, repoDir, "git", "add", "branch-file")
+			runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+			runCmd(t, repoDir, "git", "checkout", "master")
+
+			dataDir, cleanup2 := TempDir(t)
+			defer cleanup2()
+			overrideURL := fmt.Sprintf("file://%s", repoDir)
+			wd := &events.FileWorkspace{
+				DataDir:                     dataDir,
+				CheckoutMerge:               checkoutMerge,
+				CloneCache:                  &events.CloneCache{DataDir: dataDir},
+				TestingOverrideHeadCloneURL: overrideURL,
+				TestingOverrideBaseCloneURL: overrideURL,
+			}
+			repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+			cloneDir, _, err := wd.Clone(logging.NewNoopLogger(), repo, repo, models.PullRequest{
+				BaseRepo:   repo,
+				HeadBranch: "branch",
+				BaseBranch: "master",
+			}, "default")
+			Ok(t, err)
+			_, err = os.Stat(filepath.Join(cloneDir, "branch-file"))
+			Ok(t, err)
+
+			alternates, err := ioutil.ReadFile(filepath.Join(cloneDir, ".git", "objects", "info", "alternates"))
+			Ok(t, err)
+			Assert(t, strings.HasPrefix(string(alternates), filepath.Join(dataDir, events.CloneCacheDir, "github.com", "owner", "repo")),
+				"expected clone to reference the mirror, got %q", string(alternates))
+		})
+	}
+}
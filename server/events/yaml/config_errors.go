@@ -0,0 +1,212 @@
+package yaml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigError is an error in a config file.
+type ConfigError struct {
+	// Line is the line of the invalid key or 0 if it isn't known, ex. for
+	// errors that involve more than one key.
+	Line int
+	// Key is the path to the invalid key, ex. projects[0].dir, or empty if
+	// it isn't known.
+	Key string
+	Msg string
+}
+
+func (c ConfigError) Error() string {
+	msg := c.Msg
+	if c.Key != "" {
+		msg = c.Key + ": " + msg
+	}
+	if c.Line > 0 {
+		return fmt.Sprintf("line %d: %s", c.Line, msg)
+	}
+	return msg
+}
+
+// yamlLineErrRegex matches the line numbers in errors from the yaml library.
+var yamlLineErrRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// ValidateRepoCfgFile parses and validates the atlantis.yaml file at path the
+// same way the server does for the repo with id repoID. It returns each error
+// in the file, with its line if it's known. The returned error is only set if
+// the file couldn't be read.
+func (p *ParserValidator) ValidateRepoCfgFile(path string, globalCfg valid.GlobalCfg, repoID string) ([]ConfigError, error) {
+	data, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", path)
+	}
+	_, err = p.ParseRepoCfgData(data, globalCfg, repoID)
+	if err == nil {
+		return nil, nil
+	}
+
+	switch e := err.(type) {
+	case *yaml.TypeError:
+		var cfgErrs []ConfigError
+		for _, msg := range e.Errors {
+			cfgErrs = append(cfgErrs, yamlConfigError(msg))
+		}
+		return cfgErrs, nil
+	case validation.Errors:
+		lines := newYAMLLines(data)
+		var cfgErrs []ConfigError
+		flattenValidationErrors(e, nil, func(path []string, msg string) {
+			cfgErrs = append(cfgErrs, ConfigError{
+				Line: lines.find(path),
+				Key:  keyPath(path),
+				Msg:  msg,
+			})
+		})
+		sort.SliceStable(cfgErrs, func(i, j int) bool {
+			return cfgErrs[i].Line < cfgErrs[j].Line
+		})
+		return cfgErrs, nil
+	default:
+		return []ConfigError{yamlConfigError(err.Error())}, nil
+	}
+}
+
+// yamlConfigError returns the error for msg, an error from the yaml library
+// that may start with its line.
+func yamlConfigError(msg string) ConfigError {
+	if match := yamlLineErrRegex.FindStringSubmatch(msg); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return ConfigError{Line: line, Msg: match[2]}
+	}
+	return ConfigError{Msg: msg}
+}
+
+// flattenValidationErrors calls f with the path to and message of each error
+// in errs. Nested keys are sorted so the errors are in the same order each
+// time.
+func flattenValidationErrors(errs validation.Errors, path []string, f func(path []string, msg string)) {
+	var keys []string
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyPath := append(append([]string{}, path...), key)
+		if nested, ok := errs[key].(validation.Errors); ok {
+			flattenValidationErrors(nested, keyPath, f)
+			continue
+		}
+		f(keyPath, errs[key].Error())
+	}
+}
+
+// keyPath renders path like projects[0].dir.
+func keyPath(path []string) string {
+	var b strings.Builder
+	for _, key := range path {
+		if _, err := strconv.Atoi(key); err == nil {
+			fmt.Fprintf(&b, "[%s]", key)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(key)
+	}
+	return b.String()
+}
+
+// yamlEntry is a key or list item in a YAML file.
+type yamlEntry struct {
+	line   int
+	indent int
+	// text is the entry with its indent and list item dash removed.
+	text   string
+	isDash bool
+}
+
+// yamlLines finds the lines of keys in YAML files. The yaml library doesn't
+// return the lines of values so we find them by indentation. It only
+// understands block style YAML, which is what config files are usually
+// written in.
+type yamlLines []yamlEntry
+
+func newYAMLLines(data []byte) yamlLines {
+	var entries yamlLines
+	for i, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		for {
+			if text == "" || strings.HasPrefix(text, "#") {
+				break
+			}
+			if text != "-" && !strings.HasPrefix(text, "- ") {
+				entries = append(entries, yamlEntry{line: i + 1, indent: indent, text: text})
+				break
+			}
+			// A list item can start with a key on the same line, ex.
+			// "- dir: .", so the key is its own entry indented past the
+			// dash.
+			entries = append(entries, yamlEntry{line: i + 1, indent: indent, isDash: true})
+			rest := strings.TrimLeft(strings.TrimPrefix(text, "-"), " ")
+			indent += len(text) - len(rest)
+			text = rest
+		}
+	}
+	return entries
+}
+
+// find returns the line of the key at path, where list items are their
+// index, ex. [projects 0 dir]. If the key isn't found it returns the line of
+// its closest parent that was found or 0.
+func (y yamlLines) find(path []string) int {
+	line := 0
+	pos := 0
+	parentIndent := -1
+	parentIsKey := false
+	for _, key := range path {
+		idx, err := strconv.Atoi(key)
+		isIndex := err == nil
+		childIndent := -1
+		n := 0
+		found := false
+		for ; pos < len(y); pos++ {
+			e := y[pos]
+			// Lists can be at the same indent as their key.
+			if e.indent < parentIndent || (e.indent == parentIndent && !(parentIsKey && e.isDash)) {
+				break
+			}
+			if childIndent == -1 {
+				childIndent = e.indent
+			}
+			if e.indent != childIndent {
+				continue
+			}
+			if isIndex && e.isDash {
+				found = n == idx
+				n++
+			} else if !isIndex && !e.isDash {
+				found = strings.HasPrefix(e.text, key+":")
+			}
+			if found {
+				line = e.line
+				parentIndent = e.indent
+				parentIsKey = !isIndex
+				pos++
+				break
+			}
+		}
+		if !found {
+			return line
+		}
+	}
+	return line
+}
@@ -0,0 +1,118 @@
+package yaml_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateRepoCfgFile(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		globalCfg   valid.GlobalCfg
+		exp         []yaml.ConfigError
+	}{
+		{
+			description: "valid",
+			input: `version: 3
+projects:
+- dir: .
+`,
+			globalCfg: globalCfg,
+			exp:       nil,
+		},
+		{
+			description: "syntax error",
+			input: `version: 3
+projects:
+- dir: .
+ workspace: default
+`,
+			globalCfg: globalCfg,
+			exp: []yaml.ConfigError{
+				{Line: 3, Msg: "did not find expected key"},
+			},
+		},
+		{
+			description: "unknown keys",
+			input: `version: 3
+projects:
+- dir: .
+  worksapce: default
+- dir: mod
+  autoplan:
+    whenmodified: ["*.tf"]
+`,
+			globalCfg: globalCfg,
+			exp: []yaml.ConfigError{
+				{Line: 4, Msg: "field worksapce not found in type raw.Project"},
+				{Line: 7, Msg: "field whenmodified not found in type raw.Autoplan"},
+			},
+		},
+		{
+			description: "invalid values",
+			input: `version: 3
+projects:
+- dir: .
+  workspace: default
+- name: mod
+  dir: ../mod
+  apply_requirements: [approved]
+- dir: other
+  apply_requirements: [reviewed]
+`,
+			globalCfg: globalCfg,
+			exp: []yaml.ConfigError{
+				{Line: 6, Key: "projects[1].dir", Msg: "cannot contain '..'"},
+				{Line: 9, Key: "projects[2].apply_requirements", Msg: `"reviewed" is not a valid apply_requirement, only "approved", "mergeable" and "undiverged" are supported`},
+			},
+		},
+		{
+			description: "server-side workflow that doesn't exist",
+			input: `version: 3
+projects:
+- dir: .
+  workflow: custom
+`,
+			globalCfg: globalCfg,
+			exp: []yaml.ConfigError{
+				{Msg: `workflow "custom" is not defined anywhere`},
+			},
+		},
+		{
+			description: "override not allowed by server-side config",
+			input: `version: 3
+projects:
+- dir: .
+  workflow: custom
+`,
+			globalCfg: valid.NewGlobalCfg(false, false, false),
+			exp: []yaml.ConfigError{
+				{Msg: "repo config not allowed to set 'workflow' key: server-side config needs 'allowed_overrides: [workflow]'"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			path := filepath.Join(tmp, "atlantis.yaml")
+			Ok(t, ioutil.WriteFile(path, []byte(c.input), 0600))
+
+			r := yaml.ParserValidator{}
+			errs, err := r.ValidateRepoCfgFile(path, c.globalCfg, "github.com/owner/repo")
+			Ok(t, err)
+			Equals(t, c.exp, errs)
+		})
+	}
+}
+
+func TestConfigError_Error(t *testing.T) {
+	Equals(t, "line 6: projects[1].dir: cannot contain '..'", yaml.ConfigError{Line: 6, Key: "projects[1].dir", Msg: "cannot contain '..'"}.Error())
+	Equals(t, "workflow \"custom\" is not defined anywhere", yaml.ConfigError{Msg: "workflow \"custom\" is not defined anywhere"}.Error())
+}
@@ -56,7 +56,12 @@ func (p *ParserValidator) ParseRepoCfg(absRepoDir string, globalCfg valid.Global
 		// able to detect if it's a NotExist err.
 		return valid.RepoCfg{}, err
 	}
+	return p.ParseRepoCfgData(configData, globalCfg, repoID)
+}
 
+// ParseRepoCfgData returns the parsed and validated atlantis.yaml config in
+// configData.
+func (p *ParserValidator) ParseRepoCfgData(configData []byte, globalCfg valid.GlobalCfg, repoID string) (valid.RepoCfg, error) {
 	var rawConfig raw.RepoCfg
 	if err := yaml.UnmarshalStrict(configData, &rawConfig); err != nil {
 		return valid.RepoCfg{}, err
@@ -83,7 +88,7 @@ func (p *ParserValidator) ParseRepoCfg(absRepoDir string, globalCfg valid.Global
 		}
 	}
 
-	err = globalCfg.ValidateRepoCfg(validConfig, repoID)
+	err := globalCfg.ValidateRepoCfg(validConfig, repoID)
 	return validConfig, err
 }
 
@@ -834,6 +834,76 @@ workflows:
 				},
 			},
 		},
+		{
+			description: "project generators",
+			input: `
+version: 3
+projects:
+- dir: modules
+project_generators:
+- matrix:
+    env: [staging, prod]
+    region: [us-east-1]
+  project:
+    name: ${env}-${region}
+    dir: envs/${env}
+    workspace: ${region}`,
+			exp: valid.RepoCfg{
+				Version: 3,
+				Projects: []valid.Project{
+					{
+						Dir:       "modules",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*", "**/terragrunt.hcl"},
+							Enabled:      true,
+						},
+					},
+					{
+						Name:      String("staging-us-east-1"),
+						Dir:       "envs/staging",
+						Workspace: "us-east-1",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*", "**/terragrunt.hcl"},
+							Enabled:      true,
+						},
+					},
+					{
+						Name:      String("prod-us-east-1"),
+						Dir:       "envs/prod",
+						Workspace: "us-east-1",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*", "**/terragrunt.hcl"},
+							Enabled:      true,
+						},
+					},
+				},
+				Workflows: map[string]valid.Workflow{},
+			},
+		},
+		{
+			description: "project generators generating duplicate names",
+			input: `
+version: 3
+project_generators:
+- matrix:
+    env: [staging, prod]
+  project:
+    name: infra
+    dir: envs/${env}`,
+			expErr: "found two or more projects with name \"infra\"; project names must be unique",
+		},
+		{
+			description: "invalid project generator",
+			input: `
+version: 3
+project_generators:
+- matrix:
+    env: [staging, prod]
+  project:
+    dir: envs/${environment}`,
+			expErr: "project_generators: (0: (project: ${environment} is not a variable in the matrix.).).",
+		},
 	}
 
 	tmpDir, cleanup := TempDir(t)
@@ -948,13 +1018,206 @@ func TestParseGlobalCfg(t *testing.T) {
 			input: `repos:
 - id: /.*/
   allowed_overrides: [invalid]`,
-			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"apply_requirements\" and \"workflow\" are supported.).).",
+			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"apply_requirements\", \"workflow\" and \"concurrency_group\" are supported.).).",
 		},
 		"invalid apply_requirement": {
 			input: `repos:
 - id: /.*/
   apply_requirements: [invalid]`,
-			expErr: "repos: (0: (apply_requirements: \"invalid\" is not a valid apply_requirement, only \"approved\" and \"mergeable\" are supported.).).",
+			expErr: "repos: (0: (apply_requirements: \"invalid\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.).).",
+		},
+		"approvals count less than 1": {
+			input: `repos:
+- id: /.*/
+  approvals:
+    count: 0`,
+			expErr: "repos: (0: (approvals: (count: must be at least 1.).).).",
+		},
+		"concurrency group doesn't exist": {
+			input: `repos:
+- id: /.*/
+  concurrency_group: notdefined`,
+			expErr: "concurrency group \"notdefined\" is not defined",
+		},
+		"concurrency group without max_concurrent": {
+			input: `concurrency_groups:
+  aws-prod: {}`,
+			expErr: "concurrency_groups: (aws-prod: (max_concurrent: cannot be blank.).).",
+		},
+		"concurrency group defined": {
+			input: `repos:
+- id: github.com/owner/repo
+  concurrency_group: aws-prod
+concurrency_groups:
+  aws-prod:
+    max_concurrent: 2`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					ID:               "github.com/owner/repo",
+					ConcurrencyGroup: String("aws-prod"),
+				}),
+				Workflows: defaultCfg.Workflows,
+				ConcurrencyGroups: map[string]valid.ConcurrencyGroup{
+					"aws-prod": {
+						Name:          "aws-prod",
+						MaxConcurrent: 2,
+					},
+				},
+			},
+		},
+		"custom commands": {
+			input: `commands:
+  lint:
+    description: Runs tflint
+    lock: true
+    steps:
+    - init
+    - run: tflint
+  docs:
+    steps:
+    - run: terraform-docs md .`,
+			exp: valid.GlobalCfg{
+				Repos:     defaultCfg.Repos,
+				Workflows: defaultCfg.Workflows,
+				Commands: map[string]valid.CustomCommand{
+					"lint": {
+						Name:        "lint",
+						Description: "Runs tflint",
+						Lock:        true,
+						Steps: []valid.Step{
+							{StepName: "init"},
+							{StepName: "run", RunCommand: "tflint"},
+						},
+					},
+					"docs": {
+						Name: "docs",
+						Steps: []valid.Step{
+							{StepName: "run", RunCommand: "terraform-docs md ."},
+						},
+					},
+				},
+			},
+		},
+		"custom command without steps": {
+			input: `commands:
+  lint:
+    description: Runs tflint`,
+			expErr: "commands: (lint: (steps: cannot be blank.).).",
+		},
+		"custom command with a built-in name": {
+			input: `commands:
+  plan:
+    steps:
+    - run: echo hi`,
+			expErr: "command name \"plan\" is reserved for a built-in command",
+		},
+		"custom command with an invalid name": {
+			input: `commands:
+  Lint:
+    steps:
+    - run: echo hi`,
+			expErr: "command name \"Lint\" must start with a lowercase letter and only contain lowercase letters, numbers, '-' and '_'",
+		},
+		"allowed_workflows and allow_custom_run_steps": {
+			input: `repos:
+- id: github.com/owner/repo
+  allowed_workflows: [default]
+  allow_custom_run_steps: false`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					ID:                  "github.com/owner/repo",
+					AllowedWorkflows:    []string{"default"},
+					AllowCustomRunSteps: Bool(false),
+				}),
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"empty allowed_workflows name": {
+			input: `repos:
+- id: /.*/
+  allowed_workflows: [""]`,
+			expErr: "repos: (0: (allowed_workflows: cannot contain empty workflow names.).).",
+		},
+		"branch": {
+			input: `repos:
+- id: github.com/owner/repo
+  branch: /^(main|release\/.*)$/`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					ID:          "github.com/owner/repo",
+					BranchRegex: regexp.MustCompile("^(main|release\\/.*)$"),
+				}),
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"branch without slashes": {
+			input: `repos:
+- id: /.*/
+  branch: main`,
+			expErr: "repos: (0: (branch: \"main\" must be a regex wrapped in slashes, ex. /^main$/.).).",
+		},
+		"invalid branch regex": {
+			input: `repos:
+- id: /.*/
+  branch: /?/`,
+			expErr: "repos: (0: (branch: parsing: /?/: error parsing regexp: missing argument to repetition operator: `?`.).).",
+		},
+		"sparse_checkout": {
+			input: `repos:
+- id: github.com/owner/repo
+  sparse_checkout: [/infra/, /modules/]`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					ID:             "github.com/owner/repo",
+					SparseCheckout: []string{"/infra/", "/modules/"},
+				}),
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"empty sparse_checkout pattern": {
+			input: `repos:
+- id: /.*/
+  sparse_checkout: [""]`,
+			expErr: "repos: (0: (sparse_checkout: cannot contain empty patterns.).).",
+		},
+		"default_terraform_version and terraform_distribution": {
+			input: `repos:
+- id: /github.com/owner/legacy-.*/
+  default_terraform_version: 0.13.7
+  terraform_distribution: opentofu`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					IDRegex:                 regexp.MustCompile("github.com/owner/legacy-.*"),
+					DefaultTerraformVersion: version.Must(version.NewVersion("0.13.7")),
+					TerraformDistribution:   "opentofu",
+				}),
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"invalid default_terraform_version": {
+			input: `repos:
+- id: /.*/
+  default_terraform_version: latest`,
+			expErr: "repos: (0: (default_terraform_version: version \"latest\" could not be parsed: Malformed version: latest.).).",
+		},
+		"invalid terraform_distribution": {
+			input: `repos:
+- id: /.*/
+  terraform_distribution: tf`,
+			expErr: "repos: (0: (terraform_distribution: must be a valid value.).).",
+		},
+		"invalid provider retry pattern": {
+			input: `provider_retries:
+- pattern: "?"`,
+			expErr: "provider_retries: (0: (pattern: parsing: ?: error parsing regexp: missing argument to repetition operator: `?`.).).",
+		},
+		"provider retries empty": {
+			input: `provider_retries: []`,
+			exp: valid.GlobalCfg{
+				Repos:           defaultCfg.Repos,
+				Workflows:       defaultCfg.Workflows,
+				ProviderRetries: []valid.ProviderRetry{},
+			},
 		},
 		"no workflows key": {
 			input: `repos: []`,
@@ -0,0 +1,31 @@
+package raw
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// ApplyAfterMerge is the raw schema for applying the projects of repos once
+// pull requests are merged in the server-side repo config.
+type ApplyAfterMerge struct {
+	// Enabled defaults to true so that setting apply_after_merge turns it on.
+	Enabled *bool   `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Notify  *Notify `yaml:"notify,omitempty" json:"notify,omitempty"`
+}
+
+func (a ApplyAfterMerge) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Notify),
+	)
+}
+
+func (a ApplyAfterMerge) ToValid() valid.ApplyAfterMerge {
+	v := valid.ApplyAfterMerge{Enabled: true}
+	if a.Enabled != nil {
+		v.Enabled = *a.Enabled
+	}
+	if a.Notify != nil {
+		v.Notify = a.Notify.ToValid()
+	}
+	return v
+}
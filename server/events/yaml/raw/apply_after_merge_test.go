@@ -0,0 +1,34 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestApplyAfterMerge_UnmarshalYAML(t *testing.T) {
+	var a raw.ApplyAfterMerge
+	err := yaml.UnmarshalStrict([]byte(`
+notify:
+  slack: ["#infra"]
+`), &a)
+	Ok(t, err)
+	Equals(t, raw.ApplyAfterMerge{Notify: &raw.Notify{Slack: []string{"#infra"}}}, a)
+}
+
+func TestApplyAfterMerge_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	Ok(t, raw.ApplyAfterMerge{}.Validate())
+	ErrEquals(t, "notify: (slack: cannot contain empty values.).", raw.ApplyAfterMerge{Notify: &raw.Notify{Slack: []string{"#"}}}.Validate())
+}
+
+func TestApplyAfterMerge_ToValid(t *testing.T) {
+	disabled := false
+	Equals(t, valid.ApplyAfterMerge{Enabled: true, Notify: valid.Notify{SlackChannels: []string{"infra"}}},
+		raw.ApplyAfterMerge{Notify: &raw.Notify{Slack: []string{"#infra"}}}.ToValid())
+	Equals(t, valid.ApplyAfterMerge{Enabled: false}, raw.ApplyAfterMerge{Enabled: &disabled}.ToValid())
+}
@@ -0,0 +1,111 @@
+package raw
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// ApplyWindows is the raw schema for when the projects of repos can be
+// applied in the server-side repo config.
+type ApplyWindows struct {
+	Windows    []ApplyWindow `yaml:"windows" json:"windows"`
+	Outside    *string       `yaml:"outside,omitempty" json:"outside,omitempty"`
+	BreakGlass []string      `yaml:"break_glass,omitempty" json:"break_glass,omitempty"`
+}
+
+// ApplyWindow is the raw schema for a single apply window.
+type ApplyWindow struct {
+	Schedule  string `yaml:"schedule" json:"schedule"`
+	Timezone  string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	Dir       string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace string `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Project   string `yaml:"project,omitempty" json:"project,omitempty"`
+}
+
+func (a ApplyWindows) Validate() error {
+	outsideValid := func(value interface{}) error {
+		outside := value.(*string)
+		if outside != nil && *outside != valid.RejectOutsideApplyWindows && *outside != valid.QueueOutsideApplyWindows {
+			return fmt.Errorf("%q is not one of %s or %s", *outside, valid.RejectOutsideApplyWindows, valid.QueueOutsideApplyWindows)
+		}
+		return nil
+	}
+	breakGlassValid := func(value interface{}) error {
+		for _, user := range value.([]string) {
+			if strings.TrimPrefix(user, "@") == "" {
+				return errors.New("cannot contain empty users")
+			}
+			if strings.ContainsAny(user, " \t\n,") {
+				return fmt.Errorf("%q is not a valid user: must be a username or team, ex. \"alice\" or \"org/team\"", user)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Windows, validation.Required),
+		validation.Field(&a.Outside, validation.By(outsideValid)),
+		validation.Field(&a.BreakGlass, validation.By(breakGlassValid)),
+	)
+}
+
+func (a ApplyWindows) ToValid() valid.ApplyWindows {
+	v := valid.ApplyWindows{Outside: valid.RejectOutsideApplyWindows}
+	for _, w := range a.Windows {
+		v.Windows = append(v.Windows, w.ToValid())
+	}
+	if a.Outside != nil {
+		v.Outside = *a.Outside
+	}
+	// Like owners, users can be written as mentions but are stored without
+	// the @.
+	for _, user := range a.BreakGlass {
+		v.BreakGlass = append(v.BreakGlass, strings.TrimPrefix(user, "@"))
+	}
+	return v
+}
+
+func (w ApplyWindow) Validate() error {
+	scheduleValid := func(value interface{}) error {
+		_, err := valid.ParseSchedule(value.(string))
+		return err
+	}
+	timezoneValid := func(value interface{}) error {
+		_, err := time.LoadLocation(value.(string))
+		return errors.Wrapf(err, "loading time zone %q", value.(string))
+	}
+	globValid := func(value interface{}) error {
+		// Match only returns an error if the glob is malformed.
+		_, err := path.Match(value.(string), "")
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	return validation.ValidateStruct(&w,
+		validation.Field(&w.Schedule, validation.Required, validation.By(scheduleValid)),
+		validation.Field(&w.Timezone, validation.By(timezoneValid)),
+		validation.Field(&w.Dir, validation.By(globValid)),
+		validation.Field(&w.Workspace, validation.By(globValid)),
+		validation.Field(&w.Project, validation.By(globValid)),
+	)
+}
+
+func (w ApplyWindow) ToValid() valid.ApplyWindow {
+	// Safe to ignore the errors because we test them in Validate(). An
+	// empty time zone is UTC.
+	schedule, _ := valid.ParseSchedule(w.Schedule)
+	location, _ := time.LoadLocation(w.Timezone)
+	v := valid.ApplyWindow{
+		Schedule:  schedule,
+		Location:  location,
+		Workspace: w.Workspace,
+		Project:   w.Project,
+	}
+	if w.Dir != "" {
+		v.Dir = path.Clean(w.Dir)
+	}
+	return v
+}
@@ -0,0 +1,133 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestApplyWindows_UnmarshalYAML(t *testing.T) {
+	var a raw.ApplyWindows
+	err := yaml.UnmarshalStrict([]byte(`
+windows:
+- schedule: "* 9-16 * * 1-5"
+  timezone: Europe/Amsterdam
+  dir: production/*
+outside: queue
+break_glass: [alice, org/sre]
+`), &a)
+	Ok(t, err)
+	Equals(t, raw.ApplyWindows{
+		Windows: []raw.ApplyWindow{
+			{Schedule: "* 9-16 * * 1-5", Timezone: "Europe/Amsterdam", Dir: "production/*"},
+		},
+		Outside:    String("queue"),
+		BreakGlass: []string{"alice", "org/sre"},
+	}, a)
+}
+
+func TestApplyWindows_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.ApplyWindows
+		expErr      string
+	}{
+		{
+			description: "valid",
+			input: raw.ApplyWindows{
+				Windows: []raw.ApplyWindow{
+					{Schedule: "*/30 9-16 * * 1-5", Timezone: "America/New_York", Workspace: "prod*"},
+					{Schedule: "0 0 1 1 *"},
+				},
+				Outside:    String("reject"),
+				BreakGlass: []string{"@alice", "org/sre"},
+			},
+		},
+		{
+			description: "no windows",
+			input:       raw.ApplyWindows{},
+			expErr:      "windows: cannot be blank.",
+		},
+		{
+			description: "no schedule",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Dir: "production"}}},
+			expErr:      "windows: (0: (schedule: cannot be blank.).).",
+		},
+		{
+			description: "invalid schedule",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* 9-17 * *"}}},
+			expErr:      `windows: (0: (schedule: "* 9-17 * *" must have 5 fields: minute hour day-of-month month day-of-week.).).`,
+		},
+		{
+			description: "unknown time zone",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* * * * *", Timezone: "Mars/Olympus_Mons"}}},
+			expErr:      `windows: (0: (timezone: loading time zone "Mars/Olympus_Mons": unknown time zone Mars/Olympus_Mons.).).`,
+		},
+		{
+			description: "invalid glob",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* * * * *", Dir: "production/["}}},
+			expErr:      "windows: (0: (dir: parsing: production/[: syntax error in pattern.).).",
+		},
+		{
+			description: "unknown outside",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* * * * *"}}, Outside: String("wait")},
+			expErr:      `outside: "wait" is not one of reject or queue.`,
+		},
+		{
+			description: "empty break glass user",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* * * * *"}}, BreakGlass: []string{"@"}},
+			expErr:      "break_glass: cannot contain empty users.",
+		},
+		{
+			description: "invalid break glass user",
+			input:       raw.ApplyWindows{Windows: []raw.ApplyWindow{{Schedule: "* * * * *"}}, BreakGlass: []string{"alice bob"}},
+			expErr:      `break_glass: "alice bob" is not a valid user: must be a username or team, ex. "alice" or "org/team".`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestApplyWindows_ToValid(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	Ok(t, err)
+	weekdays, err := valid.ParseSchedule("* 9-16 * * 1-5")
+	Ok(t, err)
+	anytime, err := valid.ParseSchedule("* * * * *")
+	Ok(t, err)
+
+	Equals(t, valid.ApplyWindows{
+		Windows: []valid.ApplyWindow{
+			{Schedule: weekdays, Location: amsterdam, Dir: "production", Project: "app-*"},
+			{Schedule: anytime, Location: time.UTC},
+		},
+		Outside:    valid.QueueOutsideApplyWindows,
+		BreakGlass: []string{"alice", "org/sre"},
+	}, raw.ApplyWindows{
+		Windows: []raw.ApplyWindow{
+			{Schedule: "* 9-16 * * 1-5", Timezone: "Europe/Amsterdam", Dir: "./production/", Project: "app-*"},
+			{Schedule: "* * * * *"},
+		},
+		Outside:    String("queue"),
+		BreakGlass: []string{"@alice", "org/sre"},
+	}.ToValid())
+
+	// Applies outside the windows are rejected by default.
+	Equals(t, valid.RejectOutsideApplyWindows, raw.ApplyWindows{
+		Windows: []raw.ApplyWindow{{Schedule: "* * * * *"}},
+	}.ToValid().Outside)
+}
@@ -0,0 +1,38 @@
+package raw
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Approvals is the raw schema for the rules of the approved apply requirement
+// in the server-side repo config.
+type Approvals struct {
+	Count      *int `yaml:"count,omitempty" json:"count,omitempty"`
+	Codeowners bool `yaml:"codeowners,omitempty" json:"codeowners,omitempty"`
+}
+
+func (a Approvals) Validate() error {
+	countValid := func(value interface{}) error {
+		count := value.(*int)
+		if count != nil && *count < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Count, validation.By(countValid)),
+	)
+}
+
+func (a Approvals) ToValid() valid.Approvals {
+	v := valid.Approvals{
+		Count:      valid.DefaultApprovalCount,
+		Codeowners: a.Codeowners,
+	}
+	if a.Count != nil {
+		v.Count = *a.Count
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+package raw
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/pkg/fileutils"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Autodiscover is the raw schema for how the projects of repos without an
+// atlantis.yaml are found in the server-side repo config.
+type Autodiscover struct {
+	Include        []string `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude        []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	ProjectMarkers []string `yaml:"project_markers,omitempty" json:"project_markers,omitempty"`
+}
+
+func (a Autodiscover) Validate() error {
+	dirGlobsValid := func(value interface{}) error {
+		for _, glob := range value.([]string) {
+			if glob == "" || strings.HasPrefix(glob, "!") {
+				return fmt.Errorf("%q is not a valid glob: must be a non-empty path relative to the repo root without a leading !", glob)
+			}
+			if _, err := fileutils.NewPatternMatcher([]string{glob}); err != nil {
+				return errors.Wrapf(err, "parsing: %s", glob)
+			}
+		}
+		return nil
+	}
+	markersValid := func(value interface{}) error {
+		for _, marker := range value.([]string) {
+			if marker == "" || strings.Contains(marker, "/") {
+				return fmt.Errorf("%q is not a valid file name glob: must be non-empty and can't contain /", marker)
+			}
+			// Match only returns an error if the glob is malformed.
+			if _, err := path.Match(marker, ""); err != nil {
+				return errors.Wrapf(err, "parsing: %s", marker)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Include, validation.By(dirGlobsValid)),
+		validation.Field(&a.Exclude, validation.By(dirGlobsValid)),
+		validation.Field(&a.ProjectMarkers, validation.By(markersValid)),
+	)
+}
+
+func (a Autodiscover) ToValid() valid.Autodiscover {
+	v := valid.Autodiscover{
+		Include:        a.Include,
+		Exclude:        valid.DefaultAutodiscoverExclude,
+		ProjectMarkers: valid.DefaultProjectMarkers,
+	}
+	// An empty exclude list is kept so nothing is excluded.
+	if a.Exclude != nil {
+		v.Exclude = a.Exclude
+	}
+	if len(a.ProjectMarkers) > 0 {
+		v.ProjectMarkers = a.ProjectMarkers
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAutodiscover_UnmarshalYAML(t *testing.T) {
+	var a raw.Autodiscover
+	err := yaml.UnmarshalStrict([]byte(`
+include: [live/**]
+exclude: ["**/modules", live/sandbox]
+project_markers: [backend.tf]
+`), &a)
+	Ok(t, err)
+	Equals(t, raw.Autodiscover{
+		Include:        []string{"live/**"},
+		Exclude:        []string{"**/modules", "live/sandbox"},
+		ProjectMarkers: []string{"backend.tf"},
+	}, a)
+}
+
+func TestAutodiscover_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Autodiscover
+		expErr      string
+	}{
+		{
+			description: "empty",
+			input:       raw.Autodiscover{},
+		},
+		{
+			description: "valid",
+			input: raw.Autodiscover{
+				Include:        []string{"live/*/*", "**/stacks"},
+				Exclude:        []string{"live/sandbox"},
+				ProjectMarkers: []string{"*.tf", "terragrunt.hcl"},
+			},
+		},
+		{
+			description: "empty include",
+			input:       raw.Autodiscover{Include: []string{""}},
+			expErr:      `include: "" is not a valid glob: must be a non-empty path relative to the repo root without a leading !.`,
+		},
+		{
+			description: "negated exclude",
+			input:       raw.Autodiscover{Exclude: []string{"!live"}},
+			expErr:      `exclude: "!live" is not a valid glob: must be a non-empty path relative to the repo root without a leading !.`,
+		},
+		{
+			description: "invalid glob",
+			input:       raw.Autodiscover{Include: []string{"live/["}},
+			expErr:      "include: parsing: live/[: syntax error in pattern.",
+		},
+		{
+			description: "marker with a dir",
+			input:       raw.Autodiscover{ProjectMarkers: []string{"env/backend.tf"}},
+			expErr:      `project_markers: "env/backend.tf" is not a valid file name glob: must be non-empty and can't contain /.`,
+		},
+		{
+			description: "invalid marker",
+			input:       raw.Autodiscover{ProjectMarkers: []string{"[.tf"}},
+			expErr:      "project_markers: parsing: [.tf: syntax error in pattern.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestAutodiscover_ToValid(t *testing.T) {
+	Equals(t, valid.Autodiscover{
+		Exclude:        valid.DefaultAutodiscoverExclude,
+		ProjectMarkers: valid.DefaultProjectMarkers,
+	}, raw.Autodiscover{}.ToValid())
+
+	Equals(t, valid.Autodiscover{
+		Include:        []string{"live/**"},
+		Exclude:        []string{},
+		ProjectMarkers: []string{"backend.tf"},
+	}, raw.Autodiscover{
+		Include:        []string{"live/**"},
+		Exclude:        []string{},
+		ProjectMarkers: []string{"backend.tf"},
+	}.ToValid())
+}
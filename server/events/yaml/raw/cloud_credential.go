@@ -0,0 +1,115 @@
+package raw
+
+import (
+	"path"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// awsRoleARNRegex matches the ARNs of IAM roles, ex.
+// arn:aws:iam::111111111111:role/atlantis. The partition can also be aws-cn or
+// aws-us-gov.
+var awsRoleARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+
+// gcpServiceAccountRegex matches the emails of Google Cloud service accounts.
+var gcpServiceAccountRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.gserviceaccount\.com$`)
+
+// Assumed credentials can't be valid for less than 15 minutes in AWS or more
+// than 12 hours in AWS or Google Cloud.
+const (
+	minCloudCredentialDuration = 15 * time.Minute
+	maxCloudCredentialDuration = 12 * time.Hour
+)
+
+// CloudCredential is the raw schema for the cloud credentials of projects in
+// the server-side repo config.
+type CloudCredential struct {
+	Dir               string  `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace         string  `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Project           string  `yaml:"project,omitempty" json:"project,omitempty"`
+	AWSRoleARN        string  `yaml:"aws_role_arn,omitempty" json:"aws_role_arn,omitempty"`
+	AWSExternalID     string  `yaml:"aws_external_id,omitempty" json:"aws_external_id,omitempty"`
+	GCPServiceAccount string  `yaml:"gcp_service_account,omitempty" json:"gcp_service_account,omitempty"`
+	Duration          *string `yaml:"duration,omitempty" json:"duration,omitempty"`
+}
+
+func (c CloudCredential) Validate() error {
+	globValid := func(value interface{}) error {
+		// Match only returns an error if the glob is malformed.
+		_, err := path.Match(value.(string), "")
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	identitySet := func(value interface{}) error {
+		if c.AWSRoleARN == "" && c.GCPServiceAccount == "" {
+			return errors.New("aws_role_arn or gcp_service_account must be set")
+		}
+		return nil
+	}
+	roleARNValid := func(value interface{}) error {
+		arn := value.(string)
+		if arn != "" && !awsRoleARNRegex.MatchString(arn) {
+			return errors.Errorf("%q is not an IAM role ARN, ex. arn:aws:iam::111111111111:role/atlantis", arn)
+		}
+		return nil
+	}
+	externalIDValid := func(value interface{}) error {
+		if value.(string) != "" && c.AWSRoleARN == "" {
+			return errors.New("can only be set with aws_role_arn")
+		}
+		return nil
+	}
+	serviceAccountValid := func(value interface{}) error {
+		email := value.(string)
+		if email != "" && !gcpServiceAccountRegex.MatchString(email) {
+			return errors.Errorf("%q is not a service account email, ex. atlantis@project.iam.gserviceaccount.com", email)
+		}
+		return nil
+	}
+	durationValid := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		d, err := time.ParseDuration(*strPtr)
+		if err != nil {
+			return errors.Wrapf(err, "parsing: %s", *strPtr)
+		}
+		if d < minCloudCredentialDuration || d > maxCloudCredentialDuration {
+			return errors.Errorf("must be between %s and %s", minCloudCredentialDuration, maxCloudCredentialDuration)
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Dir, validation.By(globValid)),
+		validation.Field(&c.Workspace, validation.By(globValid)),
+		validation.Field(&c.Project, validation.By(globValid)),
+		validation.Field(&c.AWSRoleARN, validation.By(identitySet), validation.By(roleARNValid)),
+		validation.Field(&c.AWSExternalID, validation.By(externalIDValid)),
+		validation.Field(&c.GCPServiceAccount, validation.By(serviceAccountValid)),
+		validation.Field(&c.Duration, validation.By(durationValid)),
+	)
+}
+
+func (c CloudCredential) ToValid() valid.CloudCredential {
+	v := valid.CloudCredential{
+		Dir:               c.Dir,
+		Workspace:         c.Workspace,
+		Project:           c.Project,
+		AWSRoleARN:        c.AWSRoleARN,
+		AWSExternalID:     c.AWSExternalID,
+		GCPServiceAccount: c.GCPServiceAccount,
+		Duration:          valid.DefaultCloudCredentialDuration,
+	}
+	if c.Dir != "" {
+		v.Dir = path.Clean(c.Dir)
+	}
+	if c.Duration != nil {
+		// Safe to ignore the error because we test it in Validate().
+		v.Duration, _ = time.ParseDuration(*c.Duration)
+	}
+	return v
+}
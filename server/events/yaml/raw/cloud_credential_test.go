@@ -0,0 +1,99 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCloudCredential_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	roleARN := "arn:aws:iam::111111111111:role/atlantis"
+	cases := []struct {
+		description string
+		input       raw.CloudCredential
+		expErr      string
+	}{
+		{
+			description: "no identity",
+			input:       raw.CloudCredential{Dir: "production"},
+			expErr:      "aws_role_arn: aws_role_arn or gcp_service_account must be set.",
+		},
+		{
+			description: "invalid role ARN",
+			input:       raw.CloudCredential{AWSRoleARN: "arn:aws:iam::111111111111:user/atlantis"},
+			expErr:      "aws_role_arn: \"arn:aws:iam::111111111111:user/atlantis\" is not an IAM role ARN, ex. arn:aws:iam::111111111111:role/atlantis.",
+		},
+		{
+			description: "external ID without role",
+			input:       raw.CloudCredential{GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com", AWSExternalID: "id"},
+			expErr:      "aws_external_id: can only be set with aws_role_arn.",
+		},
+		{
+			description: "invalid service account",
+			input:       raw.CloudCredential{GCPServiceAccount: "atlantis@example.com"},
+			expErr:      "gcp_service_account: \"atlantis@example.com\" is not a service account email, ex. atlantis@project.iam.gserviceaccount.com.",
+		},
+		{
+			description: "invalid glob",
+			input:       raw.CloudCredential{Dir: "[", AWSRoleARN: roleARN},
+			expErr:      "dir: parsing: [: syntax error in pattern.",
+		},
+		{
+			description: "duration too short",
+			input:       raw.CloudCredential{AWSRoleARN: roleARN, Duration: String("5m")},
+			expErr:      "duration: must be between 15m0s and 12h0m0s.",
+		},
+		{
+			description: "all set",
+			input: raw.CloudCredential{
+				Dir:               "production/*",
+				Workspace:         "default",
+				Project:           "prod-*",
+				AWSRoleARN:        "arn:aws-us-gov:iam::111111111111:role/path/atlantis",
+				AWSExternalID:     "id",
+				GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com",
+				Duration:          String("2h"),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestCloudCredential_ToValid(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		Equals(t, valid.CloudCredential{
+			AWSRoleARN: "arn:aws:iam::111111111111:role/atlantis",
+			Duration:   valid.DefaultCloudCredentialDuration,
+		}, raw.CloudCredential{AWSRoleARN: "arn:aws:iam::111111111111:role/atlantis"}.ToValid())
+	})
+
+	t.Run("all set", func(t *testing.T) {
+		Equals(t, valid.CloudCredential{
+			Dir:               "production/*",
+			Workspace:         "default",
+			Project:           "prod-*",
+			GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com",
+			Duration:          30 * time.Minute,
+		}, raw.CloudCredential{
+			Dir:               "./production/*/",
+			Workspace:         "default",
+			Project:           "prod-*",
+			GCPServiceAccount: "atlantis@project.iam.gserviceaccount.com",
+			Duration:          String("30m"),
+		}.ToValid())
+	})
+}
@@ -0,0 +1,29 @@
+package raw
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Concurrency is the raw schema for a repo's concurrency limits and priority
+// in the server-side repo config.
+type Concurrency struct {
+	MaxConcurrent           int `yaml:"max_concurrent,omitempty" json:"max_concurrent,omitempty"`
+	MaxConcurrentPerProject int `yaml:"max_concurrent_per_project,omitempty" json:"max_concurrent_per_project,omitempty"`
+	Priority                int `yaml:"priority,omitempty" json:"priority,omitempty"`
+}
+
+func (c Concurrency) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.MaxConcurrent, validation.Min(0)),
+		validation.Field(&c.MaxConcurrentPerProject, validation.Min(0)),
+	)
+}
+
+func (c Concurrency) ToValid() valid.Concurrency {
+	return valid.Concurrency{
+		MaxConcurrent:           c.MaxConcurrent,
+		MaxConcurrentPerProject: c.MaxConcurrentPerProject,
+		Priority:                c.Priority,
+	}
+}
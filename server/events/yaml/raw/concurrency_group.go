@@ -0,0 +1,25 @@
+package raw
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// ConcurrencyGroup is the raw schema for a concurrency group in the
+// server-side repo config.
+type ConcurrencyGroup struct {
+	MaxConcurrent int `yaml:"max_concurrent" json:"max_concurrent"`
+}
+
+func (c ConcurrencyGroup) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.MaxConcurrent, validation.Required, validation.Min(1)),
+	)
+}
+
+func (c ConcurrencyGroup) ToValid(name string) valid.ConcurrencyGroup {
+	return valid.ConcurrencyGroup{
+		Name:          name,
+		MaxConcurrent: c.MaxConcurrent,
+	}
+}
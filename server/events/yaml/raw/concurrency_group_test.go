@@ -0,0 +1,51 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestConcurrencyGroup_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.ConcurrencyGroup
+		expErr      string
+	}{
+		{
+			description: "max_concurrent not set",
+			input:       raw.ConcurrencyGroup{},
+			expErr:      "max_concurrent: cannot be blank.",
+		},
+		{
+			description: "max_concurrent negative",
+			input:       raw.ConcurrencyGroup{MaxConcurrent: -1},
+			expErr:      "max_concurrent: must be no less than 1.",
+		},
+		{
+			description: "max_concurrent set",
+			input:       raw.ConcurrencyGroup{MaxConcurrent: 1},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestConcurrencyGroup_ToValid(t *testing.T) {
+	Equals(t, valid.ConcurrencyGroup{
+		Name:          "aws-prod",
+		MaxConcurrent: 3,
+	}, raw.ConcurrencyGroup{MaxConcurrent: 3}.ToValid("aws-prod"))
+}
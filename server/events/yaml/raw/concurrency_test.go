@@ -0,0 +1,65 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestConcurrency_UnmarshalYAML(t *testing.T) {
+	var c raw.Concurrency
+	err := yaml.UnmarshalStrict([]byte(`
+max_concurrent: 10
+max_concurrent_per_project: 2
+priority: -5
+`), &c)
+	Ok(t, err)
+	Equals(t, raw.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 2, Priority: -5}, c)
+}
+
+func TestConcurrency_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Concurrency
+		expErr      string
+	}{
+		{
+			description: "empty",
+			input:       raw.Concurrency{},
+		},
+		{
+			description: "valid",
+			input:       raw.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 1, Priority: 100},
+		},
+		{
+			description: "negative max_concurrent",
+			input:       raw.Concurrency{MaxConcurrent: -1},
+			expErr:      "max_concurrent: must be no less than 0.",
+		},
+		{
+			description: "negative max_concurrent_per_project",
+			input:       raw.Concurrency{MaxConcurrentPerProject: -1},
+			expErr:      "max_concurrent_per_project: must be no less than 0.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestConcurrency_ToValid(t *testing.T) {
+	Equals(t, valid.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 2, Priority: -5},
+		raw.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 2, Priority: -5}.ToValid())
+}
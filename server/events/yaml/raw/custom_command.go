@@ -0,0 +1,60 @@
+package raw
+
+import (
+	"fmt"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// customCommandNameRegex matches valid custom command names. They're typed in
+// comments and used in commit status names so they're kept simple.
+var customCommandNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// reservedCommandNames are the built-in comment commands, which custom
+// commands can't replace.
+var reservedCommandNames = []string{"plan", "apply", "diff", "discard", "cancel", "help"}
+
+// CustomCommand is the raw schema for a custom comment command in the
+// server-side repo config.
+type CustomCommand struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Lock is true if the command locks the projects it runs on like plan
+	// does.
+	Lock  bool   `yaml:"lock,omitempty" json:"lock,omitempty"`
+	Steps []Step `yaml:"steps" json:"steps"`
+}
+
+func (c CustomCommand) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Steps, validation.Required),
+	)
+}
+
+func (c CustomCommand) ToValid(name string) valid.CustomCommand {
+	var steps []valid.Step
+	for _, s := range c.Steps {
+		steps = append(steps, s.ToValid())
+	}
+	return valid.CustomCommand{
+		Name:        name,
+		Description: c.Description,
+		Lock:        c.Lock,
+		Steps:       steps,
+	}
+}
+
+// validateCustomCommandName returns an error if name can't be used for a
+// custom command.
+func validateCustomCommandName(name string) error {
+	if !customCommandNameRegex.MatchString(name) {
+		return fmt.Errorf("command name %q must start with a lowercase letter and only contain lowercase letters, numbers, '-' and '_'", name)
+	}
+	for _, reserved := range reservedCommandNames {
+		if name == reserved {
+			return fmt.Errorf("command name %q is reserved for a built-in command", name)
+		}
+	}
+	return nil
+}
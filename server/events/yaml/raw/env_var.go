@@ -0,0 +1,80 @@
+package raw
+
+import (
+	"path"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// envVarNameRegex matches names that can be set in a shell.
+var envVarNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// EnvVar is the raw schema for the env vars injected into the steps of
+// projects in the server-side repo config.
+type EnvVar struct {
+	Name      string  `yaml:"name" json:"name"`
+	Value     *string `yaml:"value,omitempty" json:"value,omitempty"`
+	FromEnv   string  `yaml:"from_env,omitempty" json:"from_env,omitempty"`
+	Dir       string  `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace string  `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Project   string  `yaml:"project,omitempty" json:"project,omitempty"`
+}
+
+func (e EnvVar) Validate() error {
+	nameValid := func(value interface{}) error {
+		name := value.(string)
+		if !envVarNameRegex.MatchString(name) {
+			return errors.Errorf("%q is not a valid environment variable name", name)
+		}
+		return nil
+	}
+	valueSet := func(value interface{}) error {
+		if e.Value == nil && e.FromEnv == "" {
+			return errors.New("value or from_env must be set")
+		}
+		if e.Value != nil && e.FromEnv != "" {
+			return errors.New("only one of value or from_env can be set")
+		}
+		return nil
+	}
+	fromEnvValid := func(value interface{}) error {
+		name := value.(string)
+		if name != "" && !envVarNameRegex.MatchString(name) {
+			return errors.Errorf("%q is not a valid environment variable name", name)
+		}
+		return nil
+	}
+	globValid := func(value interface{}) error {
+		// Match only returns an error if the glob is malformed.
+		_, err := path.Match(value.(string), "")
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	return validation.ValidateStruct(&e,
+		validation.Field(&e.Name, validation.Required, validation.By(nameValid)),
+		validation.Field(&e.Value, validation.By(valueSet)),
+		validation.Field(&e.FromEnv, validation.By(fromEnvValid)),
+		validation.Field(&e.Dir, validation.By(globValid)),
+		validation.Field(&e.Workspace, validation.By(globValid)),
+		validation.Field(&e.Project, validation.By(globValid)),
+	)
+}
+
+func (e EnvVar) ToValid() valid.EnvVar {
+	v := valid.EnvVar{
+		Name:      e.Name,
+		FromEnv:   e.FromEnv,
+		Dir:       e.Dir,
+		Workspace: e.Workspace,
+		Project:   e.Project,
+	}
+	if e.Value != nil {
+		v.Value = *e.Value
+	}
+	if e.Dir != "" {
+		v.Dir = path.Clean(e.Dir)
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestEnvVar_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.EnvVar
+		expErr      string
+	}{
+		{
+			description: "no name",
+			input:       raw.EnvVar{Value: String("value")},
+			expErr:      "name: cannot be blank.",
+		},
+		{
+			description: "invalid name",
+			input:       raw.EnvVar{Name: "TF-VAR", Value: String("value")},
+			expErr:      "name: \"TF-VAR\" is not a valid environment variable name.",
+		},
+		{
+			description: "no value",
+			input:       raw.EnvVar{Name: "NAME"},
+			expErr:      "value: value or from_env must be set.",
+		},
+		{
+			description: "value and from_env",
+			input:       raw.EnvVar{Name: "NAME", Value: String("value"), FromEnv: "OTHER"},
+			expErr:      "value: only one of value or from_env can be set.",
+		},
+		{
+			description: "invalid from_env",
+			input:       raw.EnvVar{Name: "NAME", FromEnv: "$OTHER"},
+			expErr:      "from_env: \"$OTHER\" is not a valid environment variable name.",
+		},
+		{
+			description: "invalid glob",
+			input:       raw.EnvVar{Name: "NAME", Value: String(""), Workspace: "["},
+			expErr:      "workspace: parsing: [: syntax error in pattern.",
+		},
+		{
+			description: "empty value",
+			input:       raw.EnvVar{Name: "NAME", Value: String("")},
+		},
+		{
+			description: "all set",
+			input:       raw.EnvVar{Name: "NAME", FromEnv: "OTHER", Dir: "production/*", Workspace: "default", Project: "vpc-*"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestEnvVar_ToValid(t *testing.T) {
+	Equals(t, valid.EnvVar{
+		Name:  "NAME",
+		Value: "value",
+		Dir:   "production",
+	}, raw.EnvVar{Name: "NAME", Value: String("value"), Dir: "./production/"}.ToValid())
+
+	Equals(t, valid.EnvVar{
+		Name:      "NAME",
+		FromEnv:   "OTHER",
+		Workspace: "default",
+		Project:   "vpc-*",
+	}, raw.EnvVar{Name: "NAME", FromEnv: "OTHER", Workspace: "default", Project: "vpc-*"}.ToValid())
+}
@@ -0,0 +1,105 @@
+package raw
+
+import (
+	"path"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// gitHostRegex matches hostnames, optionally with a port.
+var gitHostRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?(:[0-9]+)?$`)
+
+// GitCredential is the raw schema for the credentials that Terraform uses
+// to fetch private module sources in the server-side repo config.
+type GitCredential struct {
+	Host          string `yaml:"host" json:"host"`
+	SSHKeyFromEnv string `yaml:"ssh_key_from_env,omitempty" json:"ssh_key_from_env,omitempty"`
+	SSHKeyFile    string `yaml:"ssh_key_file,omitempty" json:"ssh_key_file,omitempty"`
+	TokenFromEnv  string `yaml:"token_from_env,omitempty" json:"token_from_env,omitempty"`
+	Username      string `yaml:"username,omitempty" json:"username,omitempty"`
+	Dir           string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace     string `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Project       string `yaml:"project,omitempty" json:"project,omitempty"`
+}
+
+func (g GitCredential) Validate() error {
+	hostValid := func(value interface{}) error {
+		host := value.(string)
+		if host != "" && !gitHostRegex.MatchString(host) {
+			return errors.Errorf("%q is not a valid hostname: it must not include a scheme or path", host)
+		}
+		return nil
+	}
+	sourceSet := func(value interface{}) error {
+		set := 0
+		for _, s := range []string{g.SSHKeyFromEnv, g.SSHKeyFile, g.TokenFromEnv} {
+			if s != "" {
+				set++
+			}
+		}
+		if set == 0 {
+			return errors.New("one of ssh_key_from_env, ssh_key_file or token_from_env must be set")
+		}
+		if set > 1 {
+			return errors.New("only one of ssh_key_from_env, ssh_key_file or token_from_env can be set")
+		}
+		return nil
+	}
+	envNameValid := func(value interface{}) error {
+		name := value.(string)
+		if name != "" && !envVarNameRegex.MatchString(name) {
+			return errors.Errorf("%q is not a valid environment variable name", name)
+		}
+		return nil
+	}
+	usernameValid := func(value interface{}) error {
+		if value.(string) != "" && g.TokenFromEnv == "" {
+			return errors.New("can only be set with token_from_env")
+		}
+		return nil
+	}
+	keyFileValid := func(value interface{}) error {
+		file := value.(string)
+		if file != "" && !path.IsAbs(file) {
+			return errors.Errorf("%q must be an absolute path", file)
+		}
+		return nil
+	}
+	globValid := func(value interface{}) error {
+		// Match only returns an error if the glob is malformed.
+		_, err := path.Match(value.(string), "")
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	return validation.ValidateStruct(&g,
+		validation.Field(&g.Host, validation.Required, validation.By(hostValid)),
+		validation.Field(&g.SSHKeyFromEnv, validation.By(sourceSet), validation.By(envNameValid)),
+		validation.Field(&g.SSHKeyFile, validation.By(keyFileValid)),
+		validation.Field(&g.TokenFromEnv, validation.By(envNameValid)),
+		validation.Field(&g.Username, validation.By(usernameValid)),
+		validation.Field(&g.Dir, validation.By(globValid)),
+		validation.Field(&g.Workspace, validation.By(globValid)),
+		validation.Field(&g.Project, validation.By(globValid)),
+	)
+}
+
+func (g GitCredential) ToValid() valid.GitCredential {
+	v := valid.GitCredential{
+		Host:          g.Host,
+		SSHKeyFromEnv: g.SSHKeyFromEnv,
+		SSHKeyFile:    g.SSHKeyFile,
+		TokenFromEnv:  g.TokenFromEnv,
+		Username:      g.Username,
+		Workspace:     g.Workspace,
+		Project:       g.Project,
+	}
+	if g.TokenFromEnv != "" && g.Username == "" {
+		v.Username = valid.DefaultGitCredentialUsername
+	}
+	if g.Dir != "" {
+		v.Dir = path.Clean(g.Dir)
+	}
+	return v
+}
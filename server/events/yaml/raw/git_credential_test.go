@@ -0,0 +1,95 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGitCredential_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.GitCredential
+		expErr      string
+	}{
+		{
+			description: "no host",
+			input:       raw.GitCredential{SSHKeyFromEnv: "KEY"},
+			expErr:      "host: cannot be blank.",
+		},
+		{
+			description: "host with scheme",
+			input:       raw.GitCredential{Host: "https://github.com", SSHKeyFromEnv: "KEY"},
+			expErr:      "host: \"https://github.com\" is not a valid hostname: it must not include a scheme or path.",
+		},
+		{
+			description: "no credential",
+			input:       raw.GitCredential{Host: "github.com"},
+			expErr:      "ssh_key_from_env: one of ssh_key_from_env, ssh_key_file or token_from_env must be set.",
+		},
+		{
+			description: "key and token",
+			input:       raw.GitCredential{Host: "github.com", SSHKeyFile: "/keys/id_rsa", TokenFromEnv: "TOKEN"},
+			expErr:      "ssh_key_from_env: only one of ssh_key_from_env, ssh_key_file or token_from_env can be set.",
+		},
+		{
+			description: "invalid env var",
+			input:       raw.GitCredential{Host: "github.com", TokenFromEnv: "$TOKEN"},
+			expErr:      "token_from_env: \"$TOKEN\" is not a valid environment variable name.",
+		},
+		{
+			description: "relative key file",
+			input:       raw.GitCredential{Host: "github.com", SSHKeyFile: "keys/id_rsa"},
+			expErr:      "ssh_key_file: \"keys/id_rsa\" must be an absolute path.",
+		},
+		{
+			description: "username with ssh key",
+			input:       raw.GitCredential{Host: "github.com", SSHKeyFromEnv: "KEY", Username: "git"},
+			expErr:      "username: can only be set with token_from_env.",
+		},
+		{
+			description: "invalid glob",
+			input:       raw.GitCredential{Host: "github.com", SSHKeyFromEnv: "KEY", Dir: "["},
+			expErr:      "dir: parsing: [: syntax error in pattern.",
+		},
+		{
+			description: "ssh key",
+			input:       raw.GitCredential{Host: "gitlab.example.com:2222", SSHKeyFile: "/keys/id_rsa"},
+		},
+		{
+			description: "all set",
+			input:       raw.GitCredential{Host: "github.com", TokenFromEnv: "TOKEN", Username: "oauth2", Dir: "production/*", Workspace: "default", Project: "vpc-*"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestGitCredential_ToValid(t *testing.T) {
+	Equals(t, valid.GitCredential{
+		Host:          "github.com",
+		SSHKeyFromEnv: "KEY",
+		Dir:           "production",
+	}, raw.GitCredential{Host: "github.com", SSHKeyFromEnv: "KEY", Dir: "./production/"}.ToValid())
+
+	// Tokens are sent with the default username if it isn't set.
+	Equals(t, valid.GitCredential{
+		Host:         "github.com",
+		TokenFromEnv: "TOKEN",
+		Username:     valid.DefaultGitCredentialUsername,
+		Workspace:    "default",
+		Project:      "vpc-*",
+	}, raw.GitCredential{Host: "github.com", TokenFromEnv: "TOKEN", Workspace: "default", Project: "vpc-*"}.ToValid())
+}
@@ -3,17 +3,24 @@ package raw
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	version "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/terraform"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 // GlobalCfg is the raw schema for server-side repo config.
 type GlobalCfg struct {
-	Repos     []Repo              `yaml:"repos" json:"repos"`
-	Workflows map[string]Workflow `yaml:"workflows" json:"workflows"`
+	Repos             []Repo                      `yaml:"repos" json:"repos"`
+	Workflows         map[string]Workflow         `yaml:"workflows" json:"workflows"`
+	ConcurrencyGroups map[string]ConcurrencyGroup `yaml:"concurrency_groups" json:"concurrency_groups"`
+	ProviderRetries   []ProviderRetry             `yaml:"provider_retries" json:"provider_retries"`
+	Commands          map[string]CustomCommand    `yaml:"commands" json:"commands"`
+	Tenants           map[string]Tenant           `yaml:"tenants" json:"tenants"`
 }
 
 // Repo is the raw schema for repos in the server-side repo config.
@@ -23,22 +30,71 @@ type Repo struct {
 	Workflow             *string  `yaml:"workflow,omitempty" json:"workflow,omitempty"`
 	AllowedOverrides     []string `yaml:"allowed_overrides" json:"allowed_overrides"`
 	AllowCustomWorkflows *bool    `yaml:"allow_custom_workflows,omitempty" json:"allow_custom_workflows,omitempty"`
+	AllowedWorkflows     []string `yaml:"allowed_workflows,omitempty" json:"allowed_workflows,omitempty"`
+	AllowCustomRunSteps  *bool    `yaml:"allow_custom_run_steps,omitempty" json:"allow_custom_run_steps,omitempty"`
+	ConcurrencyGroup     *string  `yaml:"concurrency_group,omitempty" json:"concurrency_group,omitempty"`
+	Branch               *string  `yaml:"branch,omitempty" json:"branch,omitempty"`
+	SparseCheckout       []string `yaml:"sparse_checkout,omitempty" json:"sparse_checkout,omitempty"`
+	Submodules           *bool    `yaml:"submodules,omitempty" json:"submodules,omitempty"`
+	LFS                  *bool    `yaml:"lfs,omitempty" json:"lfs,omitempty"`
+	// CloudCredentials can only be set in the server-side config so repos
+	// can't choose which cloud identity they're run with.
+	CloudCredentials []CloudCredential `yaml:"cloud_credentials,omitempty" json:"cloud_credentials,omitempty"`
+	EnvVars          []EnvVar          `yaml:"env,omitempty" json:"env,omitempty"`
+	GitCredentials   []GitCredential   `yaml:"git_credentials,omitempty" json:"git_credentials,omitempty"`
+	Approvals        *Approvals        `yaml:"approvals,omitempty" json:"approvals,omitempty"`
+	SecurityScan     *SecurityScan     `yaml:"security_scan,omitempty" json:"security_scan,omitempty"`
+	ApplyWindows     *ApplyWindows     `yaml:"apply_windows,omitempty" json:"apply_windows,omitempty"`
+	ApplyAfterMerge  *ApplyAfterMerge  `yaml:"apply_after_merge,omitempty" json:"apply_after_merge,omitempty"`
+	Guardrails       *Guardrails       `yaml:"guardrails,omitempty" json:"guardrails,omitempty"`
+	Autodiscover     *Autodiscover     `yaml:"autodiscover,omitempty" json:"autodiscover,omitempty"`
+	Concurrency      *Concurrency      `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	Timeouts         *Timeouts         `yaml:"timeouts,omitempty" json:"timeouts,omitempty"`
+	HelpSections     []HelpSection     `yaml:"help_sections,omitempty" json:"help_sections,omitempty"`
+	// DefaultTerraformVersion and TerraformDistribution are used by projects
+	// that don't set a terraform_version in their atlantis.yaml.
+	DefaultTerraformVersion *string `yaml:"default_terraform_version,omitempty" json:"default_terraform_version,omitempty"`
+	TerraformDistribution   *string `yaml:"terraform_distribution,omitempty" json:"terraform_distribution,omitempty"`
 }
 
 func (g GlobalCfg) Validate() error {
 	err := validation.ValidateStruct(&g,
 		validation.Field(&g.Repos),
-		validation.Field(&g.Workflows))
+		validation.Field(&g.Workflows),
+		validation.Field(&g.ConcurrencyGroups),
+		validation.Field(&g.ProviderRetries),
+		validation.Field(&g.Commands),
+		validation.Field(&g.Tenants))
 	if err != nil {
 		return err
 	}
 
-	// Check that all workflows referenced by repos are actually defined.
+	for name := range g.Tenants {
+		if err := validateTenantName(name); err != nil {
+			return err
+		}
+	}
+
+	for name := range g.Commands {
+		if err := validateCustomCommandName(name); err != nil {
+			return err
+		}
+	}
+
+	// Check that all workflows referenced by repos and tenants are actually
+	// defined.
+	var workflowRefs []*string
 	for _, repo := range g.Repos {
-		if repo.Workflow == nil {
+		workflowRefs = append(workflowRefs, repo.Workflow)
+	}
+	for _, name := range g.tenantNames() {
+		workflowRefs = append(workflowRefs, g.Tenants[name].Workflow)
+	}
+	for _, ref := range workflowRefs {
+		if ref == nil {
 			continue
 		}
-		name := *repo.Workflow
+		name := *ref
 		if name == valid.DefaultWorkflowName {
 			// The 'default' workflow will always be defined.
 			continue
@@ -54,6 +110,16 @@ func (g GlobalCfg) Validate() error {
 			return fmt.Errorf("workflow %q is not defined", name)
 		}
 	}
+
+	// Check that all concurrency groups referenced by repos are defined.
+	for _, repo := range g.Repos {
+		if repo.ConcurrencyGroup == nil {
+			continue
+		}
+		if _, ok := g.ConcurrencyGroups[*repo.ConcurrencyGroup]; !ok {
+			return fmt.Errorf("concurrency group %q is not defined", *repo.ConcurrencyGroup)
+		}
+	}
 	return nil
 }
 
@@ -76,21 +142,78 @@ func (g GlobalCfg) ToValid(defaultCfg valid.GlobalCfg) valid.GlobalCfg {
 		}
 	}
 
+	// Tenant repo configs go between the defaults and the repos section so
+	// repos can override their tenant's defaults.
 	var repos []valid.Repo
+	var tenants map[string]valid.Tenant
+	for _, name := range g.tenantNames() {
+		if tenants == nil {
+			tenants = make(map[string]valid.Tenant)
+		}
+		t := g.Tenants[name]
+		tenants[name] = t.ToValid(name)
+		repos = append(repos, t.RepoCfgs(name, workflows)...)
+	}
 	for _, r := range g.Repos {
 		repos = append(repos, r.ToValid(workflows))
 	}
 	repos = append(defaultCfg.Repos, repos...)
+
+	var concurrencyGroups map[string]valid.ConcurrencyGroup
+	for k, v := range g.ConcurrencyGroups {
+		if concurrencyGroups == nil {
+			concurrencyGroups = make(map[string]valid.ConcurrencyGroup)
+		}
+		concurrencyGroups[k] = v.ToValid(k)
+	}
+
+	// We differentiate between provider_retries not being set, in which case
+	// we use the defaults, and it being set to an empty list which disables
+	// retries.
+	providerRetries := defaultCfg.ProviderRetries
+	if g.ProviderRetries != nil {
+		providerRetries = []valid.ProviderRetry{}
+		for _, r := range g.ProviderRetries {
+			providerRetries = append(providerRetries, r.ToValid())
+		}
+	}
+	var commands map[string]valid.CustomCommand
+	for k, v := range g.Commands {
+		if commands == nil {
+			commands = make(map[string]valid.CustomCommand)
+		}
+		commands[k] = v.ToValid(k)
+	}
 	return valid.GlobalCfg{
-		Repos:     repos,
-		Workflows: workflows,
+		Repos:             repos,
+		Workflows:         workflows,
+		ConcurrencyGroups: concurrencyGroups,
+		ProviderRetries:   providerRetries,
+		Commands:          commands,
+		Tenants:           tenants,
+	}
+}
+
+// tenantNames returns the names of the tenants in order so that if a repo
+// belongs to more than one, the last name wins.
+func (g GlobalCfg) tenantNames() []string {
+	var names []string
+	for name := range g.Tenants {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
 // HasRegexID returns true if r is configured with a regex id instead of an
 // exact match id.
 func (r Repo) HasRegexID() bool {
-	return strings.HasPrefix(r.ID, "/") && strings.HasSuffix(r.ID, "/")
+	return isSlashDelimited(r.ID)
+}
+
+// isSlashDelimited returns true if s is wrapped in slashes, ex. /regex/.
+func isSlashDelimited(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/")
 }
 
 func (r Repo) Validate() error {
@@ -106,13 +229,52 @@ func (r Repo) Validate() error {
 	overridesValid := func(value interface{}) error {
 		overrides := value.([]string)
 		for _, o := range overrides {
-			if o != valid.ApplyRequirementsKey && o != valid.WorkflowKey {
-				return fmt.Errorf("%q is not a valid override, only %q and %q are supported", o, valid.ApplyRequirementsKey, valid.WorkflowKey)
+			if o != valid.ApplyRequirementsKey && o != valid.WorkflowKey && o != valid.ConcurrencyGroupKey {
+				return fmt.Errorf("%q is not a valid override, only %q, %q and %q are supported", o, valid.ApplyRequirementsKey, valid.WorkflowKey, valid.ConcurrencyGroupKey)
+			}
+		}
+		return nil
+	}
+
+	allowedWorkflowsValid := func(value interface{}) error {
+		for _, w := range value.([]string) {
+			if w == "" {
+				return errors.New("cannot contain empty workflow names")
+			}
+		}
+		return nil
+	}
+
+	branchValid := func(value interface{}) error {
+		branch := value.(*string)
+		if branch == nil {
+			return nil
+		}
+		if !isSlashDelimited(*branch) {
+			return fmt.Errorf("%q must be a regex wrapped in slashes, ex. /^main$/", *branch)
+		}
+		_, err := regexp.Compile((*branch)[1 : len(*branch)-1])
+		return errors.Wrapf(err, "parsing: %s", *branch)
+	}
+
+	sparseCheckoutValid := func(value interface{}) error {
+		for _, pattern := range value.([]string) {
+			if strings.TrimSpace(pattern) == "" {
+				return errors.New("cannot contain empty patterns")
 			}
 		}
 		return nil
 	}
 
+	tfVersionValid := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		_, err := version.NewVersion(*strPtr)
+		return errors.Wrapf(err, "version %q could not be parsed", *strPtr)
+	}
+
 	workflowExists := func(value interface{}) error {
 		// We validate workflows in ParserValidator.validateRepoWorkflows
 		// because we need the list of workflows to validate.
@@ -124,6 +286,23 @@ func (r Repo) Validate() error {
 		validation.Field(&r.AllowedOverrides, validation.By(overridesValid)),
 		validation.Field(&r.ApplyRequirements, validation.By(validApplyReq)),
 		validation.Field(&r.Workflow, validation.By(workflowExists)),
+		validation.Field(&r.AllowedWorkflows, validation.By(allowedWorkflowsValid)),
+		validation.Field(&r.Branch, validation.By(branchValid)),
+		validation.Field(&r.SparseCheckout, validation.By(sparseCheckoutValid)),
+		validation.Field(&r.CloudCredentials),
+		validation.Field(&r.EnvVars),
+		validation.Field(&r.GitCredentials),
+		validation.Field(&r.Approvals),
+		validation.Field(&r.SecurityScan),
+		validation.Field(&r.ApplyWindows),
+		validation.Field(&r.ApplyAfterMerge),
+		validation.Field(&r.Guardrails),
+		validation.Field(&r.Autodiscover),
+		validation.Field(&r.Concurrency),
+		validation.Field(&r.Timeouts),
+		validation.Field(&r.HelpSections),
+		validation.Field(&r.DefaultTerraformVersion, validation.By(tfVersionValid)),
+		validation.Field(&r.TerraformDistribution, validation.In(terraform.TerraformDistribution, terraform.OpenTofuDistribution)),
 	)
 }
 
@@ -138,6 +317,99 @@ func (r Repo) ToValid(workflows map[string]valid.Workflow) valid.Repo {
 		id = r.ID
 	}
 
+	var branchRegex *regexp.Regexp
+	if r.Branch != nil {
+		// Safe to use MustCompile because we test it in Validate().
+		branchRegex = regexp.MustCompile((*r.Branch)[1 : len(*r.Branch)-1])
+	}
+
+	var cloudCredentials []valid.CloudCredential
+	for _, c := range r.CloudCredentials {
+		cloudCredentials = append(cloudCredentials, c.ToValid())
+	}
+
+	// An empty env unsets the env of earlier repo configs.
+	var envVars []valid.EnvVar
+	if r.EnvVars != nil {
+		envVars = []valid.EnvVar{}
+	}
+	for _, e := range r.EnvVars {
+		envVars = append(envVars, e.ToValid())
+	}
+
+	// Like env, empty git_credentials unset those of earlier repo configs.
+	var gitCredentials []valid.GitCredential
+	if r.GitCredentials != nil {
+		gitCredentials = []valid.GitCredential{}
+	}
+	for _, c := range r.GitCredentials {
+		gitCredentials = append(gitCredentials, c.ToValid())
+	}
+
+	var approvals *valid.Approvals
+	if r.Approvals != nil {
+		v := r.Approvals.ToValid()
+		approvals = &v
+	}
+
+	var securityScan *valid.SecurityScan
+	if r.SecurityScan != nil {
+		v := r.SecurityScan.ToValid()
+		securityScan = &v
+	}
+
+	var applyWindows *valid.ApplyWindows
+	if r.ApplyWindows != nil {
+		v := r.ApplyWindows.ToValid()
+		applyWindows = &v
+	}
+
+	var applyAfterMerge *valid.ApplyAfterMerge
+	if r.ApplyAfterMerge != nil {
+		v := r.ApplyAfterMerge.ToValid()
+		applyAfterMerge = &v
+	}
+
+	var guardrails *valid.Guardrails
+	if r.Guardrails != nil {
+		v := r.Guardrails.ToValid()
+		guardrails = &v
+	}
+
+	var autodiscover *valid.Autodiscover
+	if r.Autodiscover != nil {
+		v := r.Autodiscover.ToValid()
+		autodiscover = &v
+	}
+
+	var concurrency *valid.Concurrency
+	if r.Concurrency != nil {
+		v := r.Concurrency.ToValid()
+		concurrency = &v
+	}
+
+	var timeouts *valid.Timeouts
+	if r.Timeouts != nil {
+		v := r.Timeouts.ToValid()
+		timeouts = &v
+	}
+
+	var helpSections []valid.HelpSection
+	for _, h := range r.HelpSections {
+		helpSections = append(helpSections, h.ToValid())
+	}
+
+	var defaultTFVersion *version.Version
+	if r.DefaultTerraformVersion != nil {
+		// Safe to ignore the error because we test it in Validate().
+		defaultTFVersion, _ = version.NewVersion(*r.DefaultTerraformVersion)
+	}
+
+	var tfDistribution string
+	if r.TerraformDistribution != nil {
+		tfDistribution = *r.TerraformDistribution
+	}
+
 	var workflow *valid.Workflow
 	if r.Workflow != nil {
 		// This key is guaranteed to exist because we test for it in
@@ -153,5 +425,27 @@ func (r Repo) ToValid(workflows map[string]valid.Workflow) valid.Repo {
 		Workflow:             workflow,
 		AllowedOverrides:     r.AllowedOverrides,
 		AllowCustomWorkflows: r.AllowCustomWorkflows,
+		AllowedWorkflows:     r.AllowedWorkflows,
+		AllowCustomRunSteps:  r.AllowCustomRunSteps,
+		ConcurrencyGroup:     r.ConcurrencyGroup,
+		BranchRegex:          branchRegex,
+		SparseCheckout:       r.SparseCheckout,
+		Submodules:           r.Submodules,
+		LFS:                  r.LFS,
+		CloudCredentials:     cloudCredentials,
+		EnvVars:              envVars,
+		GitCredentials:       gitCredentials,
+		Approvals:            approvals,
+		SecurityScan:         securityScan,
+		ApplyWindows:         applyWindows,
+		ApplyAfterMerge:      applyAfterMerge,
+		Guardrails:           guardrails,
+		Autodiscover:         autodiscover,
+		Concurrency:          concurrency,
+		Timeouts:             timeouts,
+		HelpSections:         helpSections,
+
+		DefaultTerraformVersion: defaultTFVersion,
+		TerraformDistribution:   tfDistribution,
 	}
 }
@@ -0,0 +1,107 @@
+package raw
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Guardrails is the raw schema for the limits on what the plans of repos'
+// projects can change in the server-side repo config.
+type Guardrails struct {
+	Rules    []GuardrailRule `yaml:"rules" json:"rules"`
+	Override []string        `yaml:"override,omitempty" json:"override,omitempty"`
+}
+
+// GuardrailRule is the raw schema for a single guardrail rule.
+type GuardrailRule struct {
+	MaxDeletes *int     `yaml:"max_deletes,omitempty" json:"max_deletes,omitempty"`
+	MaxChanges *int     `yaml:"max_changes,omitempty" json:"max_changes,omitempty"`
+	Deny       []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+	Dir        string   `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace  string   `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Project    string   `yaml:"project,omitempty" json:"project,omitempty"`
+}
+
+func (g Guardrails) Validate() error {
+	overrideValid := func(value interface{}) error {
+		for _, user := range value.([]string) {
+			if strings.TrimPrefix(user, "@") == "" {
+				return errors.New("cannot contain empty users")
+			}
+			if strings.ContainsAny(user, " \t\n,") {
+				return fmt.Errorf("%q is not a valid user: must be a username or team, ex. \"alice\" or \"org/team\"", user)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&g,
+		validation.Field(&g.Rules, validation.Required),
+		validation.Field(&g.Override, validation.By(overrideValid)),
+	)
+}
+
+func (g Guardrails) ToValid() valid.Guardrails {
+	var v valid.Guardrails
+	for _, r := range g.Rules {
+		v.Rules = append(v.Rules, r.ToValid())
+	}
+	// Like owners, users can be written as mentions but are stored without
+	// the @.
+	for _, user := range g.Override {
+		v.Override = append(v.Override, strings.TrimPrefix(user, "@"))
+	}
+	return v
+}
+
+func (r GuardrailRule) Validate() error {
+	limitValid := func(value interface{}) error {
+		limit := value.(*int)
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf("%d can't be negative", *limit)
+		}
+		return nil
+	}
+	denyValid := func(value interface{}) error {
+		for _, pattern := range value.([]string) {
+			if strings.TrimSpace(pattern) == "" {
+				return errors.New("cannot contain empty patterns")
+			}
+		}
+		return nil
+	}
+	globValid := func(value interface{}) error {
+		// Match only returns an error if the glob is malformed.
+		_, err := path.Match(value.(string), "")
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	if r.MaxDeletes == nil && r.MaxChanges == nil && len(r.Deny) == 0 {
+		return errors.New("must set at least one of max_deletes, max_changes or deny")
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.MaxDeletes, validation.By(limitValid)),
+		validation.Field(&r.MaxChanges, validation.By(limitValid)),
+		validation.Field(&r.Deny, validation.By(denyValid)),
+		validation.Field(&r.Dir, validation.By(globValid)),
+		validation.Field(&r.Workspace, validation.By(globValid)),
+		validation.Field(&r.Project, validation.By(globValid)),
+	)
+}
+
+func (r GuardrailRule) ToValid() valid.GuardrailRule {
+	v := valid.GuardrailRule{
+		MaxDeletes: r.MaxDeletes,
+		MaxChanges: r.MaxChanges,
+		Deny:       r.Deny,
+		Workspace:  r.Workspace,
+		Project:    r.Project,
+	}
+	if r.Dir != "" {
+		v.Dir = path.Clean(r.Dir)
+	}
+	return v
+}
@@ -0,0 +1,106 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestGuardrails_UnmarshalYAML(t *testing.T) {
+	var g raw.Guardrails
+	err := yaml.UnmarshalStrict([]byte(`
+rules:
+- dir: production/*
+  max_deletes: 0
+  max_changes: 20
+  deny: [aws_route53_zone.*]
+override: [alice, org/sre]
+`), &g)
+	Ok(t, err)
+	Equals(t, raw.Guardrails{
+		Rules: []raw.GuardrailRule{
+			{Dir: "production/*", MaxDeletes: Int(0), MaxChanges: Int(20), Deny: []string{"aws_route53_zone.*"}},
+		},
+		Override: []string{"alice", "org/sre"},
+	}, g)
+}
+
+func TestGuardrails_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Guardrails
+		expErr      string
+	}{
+		{
+			description: "valid",
+			input: raw.Guardrails{
+				Rules: []raw.GuardrailRule{
+					{MaxDeletes: Int(0), Workspace: "prod*"},
+					{Deny: []string{"aws_route53_zone.*", "module.dns.*"}},
+				},
+				Override: []string{"@alice", "org/sre"},
+			},
+		},
+		{
+			description: "no rules",
+			input:       raw.Guardrails{},
+			expErr:      "rules: cannot be blank.",
+		},
+		{
+			description: "rule without limits",
+			input:       raw.Guardrails{Rules: []raw.GuardrailRule{{Dir: "production"}}},
+			expErr:      "rules: (0: must set at least one of max_deletes, max_changes or deny.).",
+		},
+		{
+			description: "negative limit",
+			input:       raw.Guardrails{Rules: []raw.GuardrailRule{{MaxChanges: Int(-1)}}},
+			expErr:      "rules: (0: (max_changes: -1 can't be negative.).).",
+		},
+		{
+			description: "empty deny pattern",
+			input:       raw.Guardrails{Rules: []raw.GuardrailRule{{Deny: []string{" "}}}},
+			expErr:      "rules: (0: (deny: cannot contain empty patterns.).).",
+		},
+		{
+			description: "invalid glob",
+			input:       raw.Guardrails{Rules: []raw.GuardrailRule{{MaxDeletes: Int(0), Dir: "production/["}}},
+			expErr:      "rules: (0: (dir: parsing: production/[: syntax error in pattern.).).",
+		},
+		{
+			description: "invalid override user",
+			input:       raw.Guardrails{Rules: []raw.GuardrailRule{{MaxDeletes: Int(0)}}, Override: []string{"alice bob"}},
+			expErr:      `override: "alice bob" is not a valid user: must be a username or team, ex. "alice" or "org/team".`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestGuardrails_ToValid(t *testing.T) {
+	Equals(t, valid.Guardrails{
+		Rules: []valid.GuardrailRule{
+			{MaxDeletes: Int(0), Dir: "production", Project: "app-*"},
+			{Deny: []string{"aws_route53_zone.*"}},
+		},
+		Override: []string{"alice", "org/sre"},
+	}, raw.Guardrails{
+		Rules: []raw.GuardrailRule{
+			{MaxDeletes: Int(0), Dir: "./production/", Project: "app-*"},
+			{Deny: []string{"aws_route53_zone.*"}},
+		},
+		Override: []string{"@alice", "org/sre"},
+	}.ToValid())
+}
@@ -0,0 +1,26 @@
+package raw
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// HelpSection is the raw schema for a section of the atlantis help comment
+// in the server-side repo config.
+type HelpSection struct {
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	Text  string `yaml:"text" json:"text"`
+}
+
+func (h HelpSection) Validate() error {
+	return validation.ValidateStruct(&h,
+		validation.Field(&h.Text, validation.Required),
+	)
+}
+
+func (h HelpSection) ToValid() valid.HelpSection {
+	return valid.HelpSection{
+		Title: h.Title,
+		Text:  h.Text,
+	}
+}
@@ -0,0 +1,32 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestHelpSection_UnmarshalYAML(t *testing.T) {
+	var h raw.HelpSection
+	err := yaml.UnmarshalStrict([]byte(`
+title: Escalation
+text: "Ask in #platform."
+`), &h)
+	Ok(t, err)
+	Equals(t, raw.HelpSection{Title: "Escalation", Text: "Ask in #platform."}, h)
+}
+
+func TestHelpSection_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	Ok(t, raw.HelpSection{Text: "Ask in #platform."}.Validate())
+	ErrEquals(t, "text: cannot be blank.", raw.HelpSection{Title: "Escalation"}.Validate())
+}
+
+func TestHelpSection_ToValid(t *testing.T) {
+	Equals(t, valid.HelpSection{Title: "Escalation", Text: "Ask in #platform."},
+		raw.HelpSection{Title: "Escalation", Text: "Ask in #platform."}.ToValid())
+}
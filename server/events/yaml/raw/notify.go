@@ -0,0 +1,59 @@
+package raw
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Notify is where a project's events are sent in addition to the server's
+// unnamed webhooks.
+type Notify struct {
+	// Slack is a list of Slack channels.
+	Slack []string `yaml:"slack,omitempty"`
+	// Webhooks is a list of names of webhooks in the server config.
+	Webhooks []string `yaml:"webhooks,omitempty"`
+	// Email is a list of email addresses.
+	Email []string `yaml:"email,omitempty"`
+}
+
+func (n Notify) Validate() error {
+	notEmpty := func(value interface{}) error {
+		for _, s := range value.([]string) {
+			if strings.TrimSpace(strings.TrimPrefix(s, "#")) == "" {
+				return errors.New("cannot contain empty values")
+			}
+		}
+		return nil
+	}
+	validEmails := func(value interface{}) error {
+		for _, e := range value.([]string) {
+			addr, err := mail.ParseAddress(e)
+			if err != nil || addr.Address != e {
+				return fmt.Errorf("%q is not a valid email address", e)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&n,
+		validation.Field(&n.Slack, validation.By(notEmpty)),
+		validation.Field(&n.Webhooks, validation.By(notEmpty)),
+		validation.Field(&n.Email, validation.By(validEmails)),
+	)
+}
+
+func (n Notify) ToValid() valid.Notify {
+	var v valid.Notify
+	// Channels are commonly written with a leading # but the Slack API
+	// expects them without it.
+	for _, c := range n.Slack {
+		v.SlackChannels = append(v.SlackChannels, strings.TrimPrefix(c, "#"))
+	}
+	v.Webhooks = n.Webhooks
+	v.Emails = n.Email
+	return v
+}
@@ -0,0 +1,90 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNotify_UnmarshalYAML(t *testing.T) {
+	var n raw.Notify
+	err := yaml.UnmarshalStrict([]byte(`
+slack: ["#team-net"]
+webhooks: [net-team]
+email: [net@example.com]
+`), &n)
+	Ok(t, err)
+	Equals(t, raw.Notify{
+		Slack:    []string{"#team-net"},
+		Webhooks: []string{"net-team"},
+		Email:    []string{"net@example.com"},
+	}, n)
+}
+
+func TestNotify_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Notify
+		expErr      string
+	}{
+		{
+			description: "empty",
+			input:       raw.Notify{},
+		},
+		{
+			description: "all set",
+			input: raw.Notify{
+				Slack:    []string{"#team-net", "alerts"},
+				Webhooks: []string{"net-team"},
+				Email:    []string{"net@example.com"},
+			},
+		},
+		{
+			description: "empty slack channel",
+			input:       raw.Notify{Slack: []string{"#"}},
+			expErr:      "slack: cannot contain empty values.",
+		},
+		{
+			description: "empty webhook",
+			input:       raw.Notify{Webhooks: []string{""}},
+			expErr:      "webhooks: cannot contain empty values.",
+		},
+		{
+			description: "invalid email",
+			input:       raw.Notify{Email: []string{"net"}},
+			expErr:      `email: "net" is not a valid email address.`,
+		},
+		{
+			description: "email with name",
+			input:       raw.Notify{Email: []string{"Net Team <net@example.com>"}},
+			expErr:      `email: "Net Team <net@example.com>" is not a valid email address.`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestNotify_ToValid(t *testing.T) {
+	Equals(t, valid.Notify{
+		SlackChannels: []string{"team-net", "alerts"},
+		Webhooks:      []string{"net-team"},
+		Emails:        []string{"net@example.com"},
+	}, raw.Notify{
+		Slack:    []string{"#team-net", "alerts"},
+		Webhooks: []string{"net-team"},
+		Email:    []string{"net@example.com"},
+	}.ToValid())
+}
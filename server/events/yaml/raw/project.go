@@ -13,19 +13,32 @@ import (
 )
 
 const (
-	DefaultWorkspace          = "default"
-	ApprovedApplyRequirement  = "approved"
-	MergeableApplyRequirement = "mergeable"
+	DefaultWorkspace           = "default"
+	ApprovedApplyRequirement   = "approved"
+	MergeableApplyRequirement  = "mergeable"
+	UndivergedApplyRequirement = "undiverged"
 )
 
 type Project struct {
-	Name              *string   `yaml:"name,omitempty"`
-	Dir               *string   `yaml:"dir,omitempty"`
-	Workspace         *string   `yaml:"workspace,omitempty"`
-	Workflow          *string   `yaml:"workflow,omitempty"`
-	TerraformVersion  *string   `yaml:"terraform_version,omitempty"`
-	Autoplan          *Autoplan `yaml:"autoplan,omitempty"`
-	ApplyRequirements []string  `yaml:"apply_requirements,omitempty"`
+	Name              *string         `yaml:"name,omitempty"`
+	Dir               *string         `yaml:"dir,omitempty"`
+	Workspace         *string         `yaml:"workspace,omitempty"`
+	Workflow          *string         `yaml:"workflow,omitempty"`
+	TerraformVersion  *string         `yaml:"terraform_version,omitempty"`
+	Autoplan          *Autoplan       `yaml:"autoplan,omitempty"`
+	ApplyRequirements []string        `yaml:"apply_requirements,omitempty"`
+	ConcurrencyGroup  *string         `yaml:"concurrency_group,omitempty"`
+	Owners            []string        `yaml:"owners,omitempty"`
+	Notify            *Notify         `yaml:"notify,omitempty"`
+	PlanOnly          *bool           `yaml:"plan_only,omitempty"`
+	TerraformCloud    *TerraformCloud `yaml:"terraform_cloud,omitempty"`
+	WorkspaceVarFile  *bool           `yaml:"workspace_var_file,omitempty"`
+	// DiscoverWorkspaces, if true, plans the project in each of its existing
+	// Terraform workspaces instead of in Workspace.
+	DiscoverWorkspaces *bool `yaml:"discover_workspaces,omitempty"`
+	// VarFiles are the .tfvars files, relative to Dir, that can be selected
+	// with atlantis plan --var-file.
+	VarFiles []string `yaml:"var_files,omitempty"`
 }
 
 func (p Project) Validate() error {
@@ -57,11 +70,45 @@ func (p Project) Validate() error {
 		}
 		return nil
 	}
+	validOwners := func(value interface{}) error {
+		for _, owner := range value.([]string) {
+			if strings.TrimPrefix(owner, "@") == "" {
+				return errors.New("cannot contain empty owners")
+			}
+			if strings.ContainsAny(owner, " \t\n,") {
+				return fmt.Errorf("%q is not a valid owner: must be a username or team, ex. \"alice\" or \"org/team\"", owner)
+			}
+		}
+		return nil
+	}
+	notWithDiscoverWorkspaces := func(value interface{}) error {
+		if p.DiscoverWorkspaces != nil && *p.DiscoverWorkspaces && !validation.IsEmpty(value) {
+			return errors.New("cannot be set if discover_workspaces is true")
+		}
+		return nil
+	}
+	validVarFiles := func(value interface{}) error {
+		for _, varFile := range value.([]string) {
+			if varFile == "" {
+				return errors.New("cannot contain empty files")
+			}
+			if filepath.IsAbs(varFile) || strings.Contains(varFile, "..") {
+				return fmt.Errorf("%q is not allowed: must be relative to dir and cannot contain '..'", varFile)
+			}
+		}
+		return nil
+	}
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Dir, validation.Required, validation.By(hasDotDot)),
+		validation.Field(&p.Workspace, validation.By(notWithDiscoverWorkspaces)),
 		validation.Field(&p.ApplyRequirements, validation.By(validApplyReq)),
 		validation.Field(&p.TerraformVersion, validation.By(validTFVersion)),
 		validation.Field(&p.Name, validation.By(validName)),
+		validation.Field(&p.ConcurrencyGroup, validation.NilOrNotEmpty),
+		validation.Field(&p.Owners, validation.By(validOwners)),
+		validation.Field(&p.Notify),
+		validation.Field(&p.TerraformCloud, validation.By(notWithDiscoverWorkspaces)),
+		validation.Field(&p.VarFiles, validation.By(validVarFiles)),
 	)
 }
 
@@ -93,6 +140,29 @@ func (p Project) ToValid() valid.Project {
 	v.ApplyRequirements = p.ApplyRequirements
 
 	v.Name = p.Name
+	v.ConcurrencyGroup = p.ConcurrencyGroup
+
+	// Owners can be written as mentions, ex. "@alice", but we store them
+	// without the @.
+	for _, owner := range p.Owners {
+		v.Owners = append(v.Owners, strings.TrimPrefix(owner, "@"))
+	}
+	if p.Notify != nil {
+		v.Notify = p.Notify.ToValid()
+	}
+	if p.PlanOnly != nil {
+		v.PlanOnly = *p.PlanOnly
+	}
+	if p.TerraformCloud != nil {
+		v.TerraformCloud = p.TerraformCloud.ToValid()
+	}
+	if p.WorkspaceVarFile != nil {
+		v.WorkspaceVarFile = *p.WorkspaceVarFile
+	}
+	if p.DiscoverWorkspaces != nil {
+		v.DiscoverWorkspaces = *p.DiscoverWorkspaces
+	}
+	v.VarFiles = p.VarFiles
 
 	return v
 }
@@ -109,8 +179,8 @@ func validProjectName(name string) bool {
 func validApplyReq(value interface{}) error {
 	reqs := value.([]string)
 	for _, r := range reqs {
-		if r != ApprovedApplyRequirement && r != MergeableApplyRequirement {
-			return fmt.Errorf("%q is not a valid apply_requirement, only %q and %q are supported", r, ApprovedApplyRequirement, MergeableApplyRequirement)
+		if r != ApprovedApplyRequirement && r != MergeableApplyRequirement && r != UndivergedApplyRequirement {
+			return fmt.Errorf("%q is not a valid apply_requirement, only %q, %q and %q are supported", r, ApprovedApplyRequirement, MergeableApplyRequirement, UndivergedApplyRequirement)
 		}
 	}
 	return nil
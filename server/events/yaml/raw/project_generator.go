@@ -0,0 +1,259 @@
+package raw
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MaxGeneratedProjects is the most projects that one project generator can
+// generate. It protects the server from matrices that are much bigger than
+// intended.
+const MaxGeneratedProjects = 1000
+
+// matrixVarRegex matches the names of matrix variables.
+var matrixVarRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// placeholderRegex matches the placeholders of matrix variables in project
+// templates, ex. ${env}. The first group is the variable's name.
+var placeholderRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// ProjectGenerator generates a project for each combination of the values of
+// its matrix variables, ex. one per environment and region, so that nearly
+// identical projects don't have to be repeated. Each ${var} in the string
+// values of Project is replaced with the value of the matrix variable var.
+type ProjectGenerator struct {
+	Matrix map[string][]string `yaml:"matrix,omitempty"`
+	// Exclude are the combinations that aren't generated. A combination is
+	// excluded if its variables have all the values of one of the entries.
+	Exclude []map[string]string `yaml:"exclude,omitempty"`
+	Project Project             `yaml:"project,omitempty"`
+}
+
+func (g ProjectGenerator) Validate() error {
+	validMatrix := func(value interface{}) error {
+		matrix := value.(map[string][]string)
+		for _, name := range g.varNames() {
+			if !matrixVarRegex.MatchString(name) {
+				return fmt.Errorf("%q is not a valid variable name: must contain only letters, numbers and _ and not start with a number", name)
+			}
+			if len(matrix[name]) == 0 {
+				return fmt.Errorf("variable %q must have at least one value", name)
+			}
+		}
+		return nil
+	}
+	validExclude := func(value interface{}) error {
+		for _, exclude := range value.([]map[string]string) {
+			if len(exclude) == 0 {
+				return errors.New("cannot contain empty entries")
+			}
+			for name := range exclude {
+				if _, ok := g.Matrix[name]; !ok {
+					return fmt.Errorf("variable %q is not in the matrix", name)
+				}
+			}
+		}
+		return nil
+	}
+	validProjects := func() error {
+		var unknown []string
+		walkStrings(g.projectTree(), func(s string) string {
+			for _, match := range placeholderRegex.FindAllStringSubmatch(s, -1) {
+				if _, ok := g.Matrix[match[1]]; !ok {
+					unknown = append(unknown, match[0])
+				}
+			}
+			return s
+		})
+		if len(unknown) > 0 {
+			return fmt.Errorf("%s is not a variable in the matrix", unknown[0])
+		}
+		if n := g.combinationCount(); n > MaxGeneratedProjects {
+			return fmt.Errorf("the matrix has %d combinations, more than the limit of %d", n, MaxGeneratedProjects)
+		}
+		for _, vars := range g.combinations() {
+			p, err := g.expand(vars)
+			if err != nil {
+				return errors.Wrapf(err, "generating project with %s", formatVars(vars))
+			}
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("project generated with %s: %s", formatVars(vars), err)
+			}
+		}
+		return nil
+	}
+	if err := validation.ValidateStruct(&g,
+		validation.Field(&g.Matrix, validation.Required, validation.By(validMatrix)),
+		validation.Field(&g.Exclude, validation.By(validExclude)),
+	); err != nil {
+		return err
+	}
+	// The template isn't validated itself since it can be invalid until its
+	// placeholders are replaced, ex. name: ${env}. The projects are only
+	// generated once the matrix is valid.
+	if err := validProjects(); err != nil {
+		return validation.Errors{"project": err}
+	}
+	return nil
+}
+
+// Projects returns the projects that g generates, in the order of its
+// combinations. g must be valid.
+func (g ProjectGenerator) Projects() []Project {
+	var projects []Project
+	for _, vars := range g.combinations() {
+		// The expansion can't fail since g is valid.
+		p, _ := g.expand(vars)
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// varNames returns the sorted names of the matrix variables.
+func (g ProjectGenerator) varNames() []string {
+	var names []string
+	for name := range g.Matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g ProjectGenerator) combinationCount() int {
+	n := 1
+	for _, values := range g.Matrix {
+		n *= len(values)
+		if n > MaxGeneratedProjects {
+			// Stop before the count can overflow.
+			return n
+		}
+	}
+	return n
+}
+
+// combinations returns the combinations of the values of the matrix
+// variables that aren't excluded. The variables are sorted by name, the
+// first one changes slowest and the values are in the order they're listed,
+// ex. env=staging,region=us then env=staging,region=eu then env=prod,region=us.
+func (g ProjectGenerator) combinations() []map[string]string {
+	names := g.varNames()
+	combos := []map[string]string{{}}
+	for _, name := range names {
+		var next []map[string]string
+		for _, combo := range combos {
+			for _, value := range g.Matrix[name] {
+				c := make(map[string]string, len(combo)+1)
+				for k, v := range combo {
+					c[k] = v
+				}
+				c[name] = value
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+
+	var included []map[string]string
+	for _, combo := range combos {
+		if !g.excluded(combo) {
+			included = append(included, combo)
+		}
+	}
+	return included
+}
+
+func (g ProjectGenerator) excluded(vars map[string]string) bool {
+	for _, exclude := range g.Exclude {
+		matches := true
+		for name, value := range exclude {
+			if vars[name] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// projectTree returns the project template as the generic values it's
+// unmarshaled from so its strings can be replaced whatever field they're in.
+func (g ProjectGenerator) projectTree() interface{} {
+	// Marshaling a Project can't fail.
+	data, _ := yaml.Marshal(g.Project)
+	var tree interface{}
+	_ = yaml.Unmarshal(data, &tree)
+	return tree
+}
+
+// expand returns the project template with the placeholders replaced with
+// the values in vars.
+func (g ProjectGenerator) expand(vars map[string]string) (Project, error) {
+	tree := walkStrings(g.projectTree(), func(s string) string {
+		return placeholderRegex.ReplaceAllStringFunc(s, func(placeholder string) string {
+			return vars[placeholderRegex.FindStringSubmatch(placeholder)[1]]
+		})
+	})
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return Project{}, err
+	}
+	var p Project
+	err = yaml.UnmarshalStrict(data, &p)
+	return p, err
+}
+
+// walkStrings returns tree, a value unmarshaled from YAML, with each string
+// value replaced with f(value). Keys aren't replaced.
+func walkStrings(tree interface{}, f func(string) string) interface{} {
+	switch t := tree.(type) {
+	case string:
+		return f(t)
+	case []interface{}:
+		for i := range t {
+			t[i] = walkStrings(t[i], f)
+		}
+		return t
+	case map[interface{}]interface{}:
+		for k, v := range t {
+			t[k] = walkStrings(v, f)
+		}
+		return t
+	default:
+		return tree
+	}
+}
+
+// formatVars returns vars for error messages, ex. env=prod, region=us.
+func formatVars(vars map[string]string) string {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, name+"="+vars[name])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// generatedProjects returns the valid projects that generators generate.
+func generatedProjects(generators []ProjectGenerator) []valid.Project {
+	var projects []valid.Project
+	for _, g := range generators {
+		for _, p := range g.Projects() {
+			projects = append(projects, p.ToValid())
+		}
+	}
+	return projects
+}
@@ -0,0 +1,149 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestProjectGenerator_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       string
+		expErr      string
+	}{
+		{
+			description: "no matrix",
+			input: `
+project:
+  dir: envs`,
+			expErr: "matrix: cannot be blank.",
+		},
+		{
+			description: "variable without values",
+			input: `
+matrix:
+  env: []
+project:
+  dir: envs/${env}`,
+			expErr: "matrix: variable \"env\" must have at least one value.",
+		},
+		{
+			description: "invalid variable name",
+			input: `
+matrix:
+  1env: [prod]
+project:
+  dir: envs/${1env}`,
+			expErr: "matrix: \"1env\" is not a valid variable name: must contain only letters, numbers and _ and not start with a number.",
+		},
+		{
+			description: "exclude of unknown variable",
+			input: `
+matrix:
+  env: [prod]
+exclude:
+- region: us
+project:
+  dir: envs/${env}`,
+			expErr: "exclude: variable \"region\" is not in the matrix.",
+		},
+		{
+			description: "unknown placeholder",
+			input: `
+matrix:
+  env: [prod]
+project:
+  dir: envs/${env}/${region}`,
+			expErr: "project: ${region} is not a variable in the matrix.",
+		},
+		{
+			description: "invalid generated project",
+			input: `
+matrix:
+  env: [prod, ..]
+project:
+  dir: envs/${env}`,
+			expErr: "project: project generated with env=..: dir: cannot contain '..'..",
+		},
+		{
+			description: "too many combinations",
+			input: `
+matrix:
+  a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+  b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+  c: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]
+project:
+  dir: ${a}/${b}/${c}`,
+			expErr: "project: the matrix has 1100 combinations, more than the limit of 1000.",
+		},
+		{
+			description: "valid",
+			input: `
+matrix:
+  env: [staging, prod]
+  region: [us-east-1, eu-west-1]
+exclude:
+- env: staging
+  region: eu-west-1
+project:
+  name: ${env}-${region}
+  dir: envs/${env}/${region}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			var g raw.ProjectGenerator
+			Ok(t, yaml.UnmarshalStrict([]byte(c.input), &g))
+			err := g.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestProjectGenerator_Projects(t *testing.T) {
+	var g raw.ProjectGenerator
+	Ok(t, yaml.UnmarshalStrict([]byte(`
+matrix:
+  region: [us-east-1, eu-west-1]
+  env: [staging, prod]
+exclude:
+- env: staging
+  region: eu-west-1
+project:
+  name: ${env}-${region}
+  dir: envs/${env}
+  workspace: ${region}
+  terraform_version: ${tf}
+  autoplan:
+    when_modified: ["*.tf", "../modules/${env}/**/*.tf"]
+    enabled: false
+`), &g))
+	g.Matrix["tf"] = []string{"0.12.0"}
+
+	projects := g.Projects()
+	var names []string
+	for _, p := range projects {
+		names = append(names, *p.Name)
+	}
+	// The variables are sorted by name, the first one changes slowest and the
+	// values are in the order they're listed.
+	Equals(t, []string{"staging-us-east-1", "prod-us-east-1", "prod-eu-west-1"}, names)
+	Equals(t, "envs/prod", *projects[2].Dir)
+	Equals(t, "eu-west-1", *projects[2].Workspace)
+	Equals(t, "0.12.0", *projects[2].TerraformVersion)
+	Equals(t, []string{"*.tf", "../modules/prod/**/*.tf"}, projects[2].Autoplan.WhenModified)
+	Equals(t, false, *projects[2].Autoplan.Enabled)
+	Equals(t, (*string)(nil), projects[2].Workflow)
+
+	// Generating doesn't change the template.
+	Equals(t, "envs/${env}", *g.Project.Dir)
+}
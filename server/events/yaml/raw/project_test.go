@@ -42,7 +42,12 @@ autoplan:
   when_modified: []
   enabled: false
 apply_requirements:
-- mergeable`,
+- mergeable
+owners:
+- alice
+- org/team
+plan_only: true
+workspace_var_file: true`,
 			exp: raw.Project{
 				Name:             String("myname"),
 				Dir:              String("mydir"),
@@ -54,6 +59,9 @@ apply_requirements:
 					Enabled:      Bool(false),
 				},
 				ApplyRequirements: []string{"mergeable"},
+				Owners:            []string{"alice", "org/team"},
+				PlanOnly:          Bool(true),
+				WorkspaceVarFile:  Bool(true),
 			},
 		},
 	}
@@ -101,7 +109,7 @@ func TestProject_Validate(t *testing.T) {
 				Dir:               String("."),
 				ApplyRequirements: []string{"unsupported"},
 			},
-			expErr: "apply_requirements: \"unsupported\" is not a valid apply_requirement, only \"approved\" and \"mergeable\" are supported.",
+			expErr: "apply_requirements: \"unsupported\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.",
 		},
 		{
 			description: "apply reqs with approved requirement",
@@ -199,6 +207,97 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: `name: "namewith\\" is not allowed: must contain only URL safe characters.`,
 		},
+		{
+			description: "owners",
+			input: raw.Project{
+				Dir:    String("."),
+				Owners: []string{"alice", "@bob", "org/team"},
+			},
+			expErr: "",
+		},
+		{
+			description: "empty owner",
+			input: raw.Project{
+				Dir:    String("."),
+				Owners: []string{"alice", "@"},
+			},
+			expErr: "owners: cannot contain empty owners.",
+		},
+		{
+			description: "owner with spaces",
+			input: raw.Project{
+				Dir:    String("."),
+				Owners: []string{"alice, bob"},
+			},
+			expErr: "owners: \"alice, bob\" is not a valid owner: must be a username or team, ex. \"alice\" or \"org/team\".",
+		},
+		{
+			description: "discover_workspaces",
+			input: raw.Project{
+				Dir:                String("."),
+				DiscoverWorkspaces: Bool(true),
+			},
+			expErr: "",
+		},
+		{
+			description: "discover_workspaces with workspace",
+			input: raw.Project{
+				Dir:                String("."),
+				Workspace:          String("staging"),
+				DiscoverWorkspaces: Bool(true),
+			},
+			expErr: "workspace: cannot be set if discover_workspaces is true.",
+		},
+		{
+			description: "discover_workspaces false with workspace",
+			input: raw.Project{
+				Dir:                String("."),
+				Workspace:          String("staging"),
+				DiscoverWorkspaces: Bool(false),
+			},
+			expErr: "",
+		},
+		{
+			description: "discover_workspaces with terraform_cloud",
+			input: raw.Project{
+				Dir:                String("."),
+				TerraformCloud:     &raw.TerraformCloud{Organization: String("acme")},
+				DiscoverWorkspaces: Bool(true),
+			},
+			expErr: "terraform_cloud: cannot be set if discover_workspaces is true.",
+		},
+		{
+			description: "var_files",
+			input: raw.Project{
+				Dir:      String("."),
+				VarFiles: []string{"staging.tfvars", "env/production.tfvars"},
+			},
+			expErr: "",
+		},
+		{
+			description: "var_files with ..",
+			input: raw.Project{
+				Dir:      String("."),
+				VarFiles: []string{"../secrets.tfvars"},
+			},
+			expErr: "var_files: \"../secrets.tfvars\" is not allowed: must be relative to dir and cannot contain '..'.",
+		},
+		{
+			description: "var_files with absolute path",
+			input: raw.Project{
+				Dir:      String("."),
+				VarFiles: []string{"/etc/secrets.tfvars"},
+			},
+			expErr: "var_files: \"/etc/secrets.tfvars\" is not allowed: must be relative to dir and cannot contain '..'.",
+		},
+		{
+			description: "var_files with empty file",
+			input: raw.Project{
+				Dir:      String("."),
+				VarFiles: []string{""},
+			},
+			expErr: "var_files: cannot contain empty files.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -251,6 +350,10 @@ func TestProject_ToValid(t *testing.T) {
 				},
 				ApplyRequirements: []string{"approved"},
 				Name:              String("myname"),
+				Owners:            []string{"alice", "@org/team"},
+				Notify:            &raw.Notify{Slack: []string{"#team"}},
+				PlanOnly:          Bool(true),
+				WorkspaceVarFile:  Bool(true),
 			},
 			exp: valid.Project{
 				Dir:              ".",
@@ -263,6 +366,10 @@ func TestProject_ToValid(t *testing.T) {
 				},
 				ApplyRequirements: []string{"approved"},
 				Name:              String("myname"),
+				Owners:            []string{"alice", "org/team"},
+				Notify:            valid.Notify{SlackChannels: []string{"team"}},
+				PlanOnly:          true,
+				WorkspaceVarFile:  true,
 			},
 		},
 		{
@@ -0,0 +1,68 @@
+package raw
+
+import (
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// ProviderRetry is the raw schema for a provider throttling retry rule in the
+// server-side repo config.
+type ProviderRetry struct {
+	Pattern     string  `yaml:"pattern" json:"pattern"`
+	MaxAttempts *int    `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	Backoff     *string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+}
+
+func (p ProviderRetry) Validate() error {
+	patternValid := func(value interface{}) error {
+		_, err := regexp.Compile(value.(string))
+		return errors.Wrapf(err, "parsing: %s", value.(string))
+	}
+	backoffValid := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		d, err := time.ParseDuration(*strPtr)
+		if err != nil {
+			return errors.Wrapf(err, "parsing: %s", *strPtr)
+		}
+		if d <= 0 {
+			return errors.New("must be greater than 0")
+		}
+		return nil
+	}
+	maxAttemptsValid := func(value interface{}) error {
+		intPtr := value.(*int)
+		if intPtr != nil && *intPtr < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Pattern, validation.Required, validation.By(patternValid)),
+		validation.Field(&p.MaxAttempts, validation.By(maxAttemptsValid)),
+		validation.Field(&p.Backoff, validation.By(backoffValid)),
+	)
+}
+
+func (p ProviderRetry) ToValid() valid.ProviderRetry {
+	v := valid.ProviderRetry{
+		// Safe to use MustCompile because we test it in Validate().
+		Pattern:     regexp.MustCompile(p.Pattern),
+		MaxAttempts: valid.DefaultProviderRetryMaxAttempts,
+		Backoff:     valid.DefaultProviderRetryBackoff,
+	}
+	if p.MaxAttempts != nil {
+		v.MaxAttempts = *p.MaxAttempts
+	}
+	if p.Backoff != nil {
+		// Safe to ignore the error because we test it in Validate().
+		v.Backoff, _ = time.ParseDuration(*p.Backoff)
+	}
+	return v
+}
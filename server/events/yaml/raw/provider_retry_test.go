@@ -0,0 +1,76 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestProviderRetry_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.ProviderRetry
+		expErr      string
+	}{
+		{
+			description: "pattern not set",
+			input:       raw.ProviderRetry{},
+			expErr:      "pattern: cannot be blank.",
+		},
+		{
+			description: "invalid pattern",
+			input:       raw.ProviderRetry{Pattern: "?"},
+			expErr:      "pattern: parsing: ?: error parsing regexp: missing argument to repetition operator: `?`.",
+		},
+		{
+			description: "invalid backoff",
+			input:       raw.ProviderRetry{Pattern: "Throttling", Backoff: String("notaduration")},
+			expErr:      "backoff: parsing: notaduration: time: invalid duration \"notaduration\".",
+		},
+		{
+			description: "negative backoff",
+			input:       raw.ProviderRetry{Pattern: "Throttling", Backoff: String("-1s")},
+			expErr:      "backoff: must be greater than 0.",
+		},
+		{
+			description: "max_attempts less than 1",
+			input:       raw.ProviderRetry{Pattern: "Throttling", MaxAttempts: Int(0)},
+			expErr:      "max_attempts: must be at least 1.",
+		},
+		{
+			description: "all set",
+			input:       raw.ProviderRetry{Pattern: "Throttling", MaxAttempts: Int(2), Backoff: String("1s")},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestProviderRetry_ToValid(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		act := raw.ProviderRetry{Pattern: "Throttling"}.ToValid()
+		Equals(t, "Throttling", act.Pattern.String())
+		Equals(t, valid.DefaultProviderRetryMaxAttempts, act.MaxAttempts)
+		Equals(t, valid.DefaultProviderRetryBackoff, act.Backoff)
+	})
+
+	t.Run("all set", func(t *testing.T) {
+		act := raw.ProviderRetry{Pattern: "Throttling", MaxAttempts: Int(5), Backoff: String("30s")}.ToValid()
+		Equals(t, "Throttling", act.Pattern.String())
+		Equals(t, 5, act.MaxAttempts)
+		Equals(t, 30*time.Second, act.Backoff)
+	})
+}
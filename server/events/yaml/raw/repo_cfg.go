@@ -2,6 +2,7 @@ package raw
 
 import (
 	"errors"
+	"fmt"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -16,6 +17,10 @@ type RepoCfg struct {
 	Projects  []Project           `yaml:"projects,omitempty"`
 	Workflows map[string]Workflow `yaml:"workflows,omitempty"`
 	Automerge *bool               `yaml:"automerge,omitempty"`
+	// ProjectGenerators generate projects in addition to Projects.
+	ProjectGenerators []ProjectGenerator `yaml:"project_generators,omitempty"`
+	// SilenceNoChanges overrides the server's --silence-no-changes setting.
+	SilenceNoChanges *string `yaml:"silence_no_changes,omitempty"`
 }
 
 func (r RepoCfg) Validate() error {
@@ -29,10 +34,23 @@ func (r RepoCfg) Validate() error {
 		}
 		return nil
 	}
+	silenceNoChangesValid := func(value interface{}) error {
+		mode := value.(*string)
+		if mode == nil {
+			return nil
+		}
+		switch *mode {
+		case valid.SilenceNoChangesOff, valid.SilenceNoChangesHide, valid.SilenceNoChangesSummarize:
+			return nil
+		}
+		return fmt.Errorf("%q is not a valid value, only %q, %q and %q are supported", *mode, valid.SilenceNoChangesOff, valid.SilenceNoChangesHide, valid.SilenceNoChangesSummarize)
+	}
 	return validation.ValidateStruct(&r,
 		validation.Field(&r.Version, validation.By(equals2)),
 		validation.Field(&r.Projects),
+		validation.Field(&r.ProjectGenerators),
 		validation.Field(&r.Workflows),
+		validation.Field(&r.SilenceNoChanges, validation.By(silenceNoChangesValid)),
 	)
 }
 
@@ -46,16 +64,25 @@ func (r RepoCfg) ToValid() valid.RepoCfg {
 	for _, p := range r.Projects {
 		validProjects = append(validProjects, p.ToValid())
 	}
+	// Generated projects go after the projects so adding a generator doesn't
+	// change the order of the existing projects.
+	validProjects = append(validProjects, generatedProjects(r.ProjectGenerators)...)
 
 	automerge := DefaultAutomerge
 	if r.Automerge != nil {
 		automerge = *r.Automerge
 	}
 
+	var silenceNoChanges string
+	if r.SilenceNoChanges != nil {
+		silenceNoChanges = *r.SilenceNoChanges
+	}
+
 	return valid.RepoCfg{
-		Version:   *r.Version,
-		Projects:  validProjects,
-		Workflows: validWorkflows,
-		Automerge: automerge,
+		Version:          *r.Version,
+		Projects:         validProjects,
+		Workflows:        validWorkflows,
+		Automerge:        automerge,
+		SilenceNoChanges: silenceNoChanges,
 	}
 }
@@ -194,6 +194,21 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expErr: "version: only versions 2 and 3 are supported.",
 		},
+		{
+			description: "silence_no_changes valid",
+			input: raw.RepoCfg{
+				Version:          Int(3),
+				SilenceNoChanges: String("summarize"),
+			},
+		},
+		{
+			description: "silence_no_changes invalid",
+			input: raw.RepoCfg{
+				Version:          Int(3),
+				SilenceNoChanges: String("quiet"),
+			},
+			expErr: "silence_no_changes: \"quiet\" is not a valid value, only \"off\", \"hide\" and \"summarize\" are supported.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -0,0 +1,71 @@
+package raw
+
+import (
+	"fmt"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// SecurityScan is the raw schema for how security_scan steps scan projects in
+// the server-side repo config.
+type SecurityScan struct {
+	Tool          *string `yaml:"tool,omitempty" json:"tool,omitempty"`
+	Target        *string `yaml:"target,omitempty" json:"target,omitempty"`
+	BlockSeverity *string `yaml:"block_severity,omitempty" json:"block_severity,omitempty"`
+}
+
+func (s SecurityScan) Validate() error {
+	toolValid := func(value interface{}) error {
+		tool := value.(*string)
+		if tool != nil && *tool != valid.TfsecSecurityScanTool && *tool != valid.CheckovSecurityScanTool {
+			return fmt.Errorf("%q is not one of %s or %s", *tool, valid.TfsecSecurityScanTool, valid.CheckovSecurityScanTool)
+		}
+		return nil
+	}
+	targetValid := func(value interface{}) error {
+		target := value.(*string)
+		if target == nil {
+			return nil
+		}
+		if *target != valid.DirSecurityScanTarget && *target != valid.PlanSecurityScanTarget {
+			return fmt.Errorf("%q is not one of %s or %s", *target, valid.DirSecurityScanTarget, valid.PlanSecurityScanTarget)
+		}
+		if *target == valid.PlanSecurityScanTarget && (s.Tool == nil || *s.Tool != valid.CheckovSecurityScanTool) {
+			return fmt.Errorf("only %s can scan the plan", valid.CheckovSecurityScanTool)
+		}
+		return nil
+	}
+	severityValid := func(value interface{}) error {
+		severity := value.(*string)
+		if severity == nil {
+			return nil
+		}
+		for _, s := range valid.SecuritySeverities {
+			if strings.EqualFold(s, *severity) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", *severity, strings.ToLower(strings.Join(valid.SecuritySeverities, ", ")))
+	}
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Tool, validation.By(toolValid)),
+		validation.Field(&s.Target, validation.By(targetValid)),
+		validation.Field(&s.BlockSeverity, validation.By(severityValid)),
+	)
+}
+
+func (s SecurityScan) ToValid() valid.SecurityScan {
+	v := valid.DefaultSecurityScan
+	if s.Tool != nil {
+		v.Tool = *s.Tool
+	}
+	if s.Target != nil {
+		v.Target = *s.Target
+	}
+	if s.BlockSeverity != nil {
+		v.BlockSeverity = strings.ToUpper(*s.BlockSeverity)
+	}
+	return v
+}
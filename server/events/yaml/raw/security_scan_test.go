@@ -0,0 +1,87 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestSecurityScan_UnmarshalYAML(t *testing.T) {
+	var s raw.SecurityScan
+	err := yaml.UnmarshalStrict([]byte(`
+tool: checkov
+target: plan
+block_severity: high
+`), &s)
+	Ok(t, err)
+	Equals(t, raw.SecurityScan{
+		Tool:          String("checkov"),
+		Target:        String("plan"),
+		BlockSeverity: String("high"),
+	}, s)
+}
+
+func TestSecurityScan_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.SecurityScan
+		expErr      string
+	}{
+		{
+			description: "empty",
+			input:       raw.SecurityScan{},
+		},
+		{
+			description: "checkov scanning the plan",
+			input: raw.SecurityScan{
+				Tool:          String("checkov"),
+				Target:        String("plan"),
+				BlockSeverity: String("CRITICAL"),
+			},
+		},
+		{
+			description: "unknown tool",
+			input:       raw.SecurityScan{Tool: String("terrascan")},
+			expErr:      `tool: "terrascan" is not one of tfsec or checkov.`,
+		},
+		{
+			description: "unknown target",
+			input:       raw.SecurityScan{Target: String("state")},
+			expErr:      `target: "state" is not one of dir or plan.`,
+		},
+		{
+			description: "tfsec scanning the plan",
+			input:       raw.SecurityScan{Tool: String("tfsec"), Target: String("plan")},
+			expErr:      "target: only checkov can scan the plan.",
+		},
+		{
+			description: "unknown severity",
+			input:       raw.SecurityScan{BlockSeverity: String("severe")},
+			expErr:      `block_severity: "severe" is not one of low, medium, high, critical.`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestSecurityScan_ToValid(t *testing.T) {
+	Equals(t, valid.DefaultSecurityScan, raw.SecurityScan{}.ToValid())
+	Equals(t, valid.SecurityScan{Tool: "checkov", Target: "plan", BlockSeverity: "HIGH"}, raw.SecurityScan{
+		Tool:          String("checkov"),
+		Target:        String("plan"),
+		BlockSeverity: String("high"),
+	}.ToValid())
+}
@@ -0,0 +1,59 @@
+package raw
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// StateLockRetry is the raw schema for retrying a workflow's steps when the
+// Terraform state is locked.
+type StateLockRetry struct {
+	MaxAttempts *int    `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	Backoff     *string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+}
+
+func (s StateLockRetry) Validate() error {
+	backoffValid := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		d, err := time.ParseDuration(*strPtr)
+		if err != nil {
+			return errors.Wrapf(err, "parsing: %s", *strPtr)
+		}
+		if d <= 0 {
+			return errors.New("must be greater than 0")
+		}
+		return nil
+	}
+	maxAttemptsValid := func(value interface{}) error {
+		intPtr := value.(*int)
+		if intPtr != nil && *intPtr < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.MaxAttempts, validation.By(maxAttemptsValid)),
+		validation.Field(&s.Backoff, validation.By(backoffValid)),
+	)
+}
+
+func (s StateLockRetry) ToValid() valid.StateLockRetry {
+	v := valid.StateLockRetry{
+		MaxAttempts: valid.DefaultStateLockRetryMaxAttempts,
+		Backoff:     valid.DefaultStateLockRetryBackoff,
+	}
+	if s.MaxAttempts != nil {
+		v.MaxAttempts = *s.MaxAttempts
+	}
+	if s.Backoff != nil {
+		// Safe to ignore the error because we test it in Validate().
+		v.Backoff, _ = time.ParseDuration(*s.Backoff)
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestStateLockRetry_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.StateLockRetry
+		expErr      string
+	}{
+		{
+			description: "nothing set",
+			input:       raw.StateLockRetry{},
+		},
+		{
+			description: "invalid backoff",
+			input:       raw.StateLockRetry{Backoff: String("notaduration")},
+			expErr:      "backoff: parsing: notaduration: time: invalid duration \"notaduration\".",
+		},
+		{
+			description: "negative backoff",
+			input:       raw.StateLockRetry{Backoff: String("-1s")},
+			expErr:      "backoff: must be greater than 0.",
+		},
+		{
+			description: "max_attempts less than 1",
+			input:       raw.StateLockRetry{MaxAttempts: Int(0)},
+			expErr:      "max_attempts: must be at least 1.",
+		},
+		{
+			description: "all set",
+			input:       raw.StateLockRetry{MaxAttempts: Int(5), Backoff: String("1m")},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestStateLockRetry_ToValid(t *testing.T) {
+	Equals(t, valid.StateLockRetry{
+		MaxAttempts: valid.DefaultStateLockRetryMaxAttempts,
+		Backoff:     valid.DefaultStateLockRetryBackoff,
+	}, raw.StateLockRetry{}.ToValid())
+	Equals(t, valid.StateLockRetry{
+		MaxAttempts: 5,
+		Backoff:     time.Minute,
+	}, raw.StateLockRetry{MaxAttempts: Int(5), Backoff: String("1m")}.ToValid())
+}
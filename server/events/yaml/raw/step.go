@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -16,27 +19,61 @@ const (
 	NameArgKey    = "name"
 	CommandArgKey = "command"
 	ValueArgKey   = "value"
+	VaultArgKey   = "vault"
+	PathArgKey    = "path"
+	ToolArgKey    = "tool"
+	TimeoutArgKey = "timeout"
 	RunStepName   = "run"
 	PlanStepName  = "plan"
 	ApplyStepName = "apply"
 	InitStepName  = "init"
 	EnvStepName   = "env"
+
+	UploadArtifactStepName = "upload_artifact"
+	DiagramStepName        = "diagram"
+	MultiEnvStepName       = "multienv"
+	SecurityScanStepName   = "security_scan"
+	FmtCheckStepName       = "fmt_check"
+	ValidateStepName       = "validate"
 )
 
+// artifactNameRegex matches valid artifact names. They're used in the keys of
+// uploaded artifacts so they're limited to characters that are safe there.
+var artifactNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
 // Step represents a single action/command to perform. In YAML, it can be set as
 // 1. A single string for a built-in command:
 //    - init
 //    - plan
-// 2. A map for an env step with name and command or value
+//    - diagram
+//    - security_scan
+//    - fmt_check
+//    - validate
+// 2. A map for an env step with name and command, value or vault, an
+//    upload_artifact step with name and path, or a diagram step with tool
 //    - env:
 //        name: test
 //        command: echo 312
 //        value: value
+//        vault: secret/data/db#password
+//    - upload_artifact:
+//        name: plan-json
+//        path: plan.json
+//    - diagram:
+//        tool: inframap
 // 3. A map for a built-in command and extra_args:
 //    - plan:
 //        extra_args: [-var-file=staging.tfvars]
-// 4. A map for a custom run command:
+// 4. A map for a custom run command or a multienv step's command:
 //    - run: my custom command
+//    - multienv: my command that prints NAME=value lines
+// 5. A map for an init, plan, apply or run step with a timeout:
+//    - plan:
+//        extra_args: [-var-file=staging.tfvars]
+//        timeout: 30m
+//    - run:
+//        command: my custom command
+//        timeout: 5m
 // Here we parse step in the most generic fashion possible. See fields for more
 // details.
 type Step struct {
@@ -49,6 +86,16 @@ type Step struct {
 	Map map[string]map[string][]string
 	// StringVal will be set in case #4 above.
 	StringVal map[string]string
+	// Timeout will be set in case #5 above.
+	Timeout map[string]TimeoutStepArgs
+}
+
+// TimeoutStepArgs are the args of a step with a timeout, case #5 in the Step
+// docs.
+type TimeoutStepArgs struct {
+	ExtraArgs []string `yaml:"extra_args,omitempty" json:"extra_args,omitempty"`
+	Command   *string  `yaml:"command,omitempty" json:"command,omitempty"`
+	Timeout   string   `yaml:"timeout" json:"timeout"`
 }
 
 func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -76,7 +123,8 @@ func (s *Step) MarshalJSON() ([]byte, error) {
 func (s Step) Validate() error {
 	validStep := func(value interface{}) error {
 		str := *value.(*string)
-		if str != InitStepName && str != PlanStepName && str != ApplyStepName && str != EnvStepName {
+		if str != InitStepName && str != PlanStepName && str != ApplyStepName && str != EnvStepName && str != DiagramStepName && str != SecurityScanStepName &&
+			str != FmtCheckStepName && str != ValidateStepName {
 			return fmt.Errorf("%q is not a valid step type, maybe you omitted the 'run' key", str)
 		}
 		return nil
@@ -96,7 +144,7 @@ func (s Step) Validate() error {
 				len(keys), strings.Join(keys, ","))
 		}
 		for stepName, args := range elem {
-			if stepName != InitStepName && stepName != PlanStepName && stepName != ApplyStepName {
+			if stepName != InitStepName && stepName != PlanStepName && stepName != ApplyStepName && stepName != FmtCheckStepName && stepName != ValidateStepName {
 				return fmt.Errorf("%q is not a valid step type", stepName)
 			}
 			var argKeys []string
@@ -134,6 +182,12 @@ func (s Step) Validate() error {
 				len(keys), strings.Join(keys, ","))
 		}
 		for stepName, args := range elem {
+			if stepName == UploadArtifactStepName {
+				return validateUploadArtifactStep(args)
+			}
+			if stepName == DiagramStepName {
+				return validateDiagramStep(args)
+			}
 			if stepName != EnvStepName {
 				return fmt.Errorf("%q is not a valid step type", stepName)
 			}
@@ -146,8 +200,8 @@ func (s Step) Validate() error {
 
 			foundNameKey := false
 			for _, k := range argKeys {
-				if k != NameArgKey && k != CommandArgKey && k != ValueArgKey {
-					return fmt.Errorf("env steps only support keys %q, %q and %q, found key %q", NameArgKey, ValueArgKey, CommandArgKey, k)
+				if k != NameArgKey && k != CommandArgKey && k != ValueArgKey && k != VaultArgKey {
+					return fmt.Errorf("env steps only support keys %q, %q, %q and %q, found key %q", NameArgKey, ValueArgKey, CommandArgKey, VaultArgKey, k)
 				}
 				if k == NameArgKey {
 					foundNameKey = true
@@ -156,10 +210,18 @@ func (s Step) Validate() error {
 			if !foundNameKey {
 				return fmt.Errorf("env steps must have a %q key set", NameArgKey)
 			}
-			// If we have 3 keys at this point then they've set both command and value.
-			if len(argKeys) != 2 {
-				return fmt.Errorf("env steps only support one of the %q or %q keys, found both",
-					ValueArgKey, CommandArgKey)
+			// If we have 3 or more keys at this point then they've set more
+			// than one of command, value and vault.
+			if len(argKeys) > 2 {
+				return fmt.Errorf("env steps only support one of the %q, %q or %q keys, found more than one",
+					ValueArgKey, CommandArgKey, VaultArgKey)
+			}
+			if len(argKeys) < 2 {
+				return fmt.Errorf("env steps must have one of the %q, %q or %q keys set",
+					ValueArgKey, CommandArgKey, VaultArgKey)
+			}
+			if ref, ok := args[VaultArgKey]; ok {
+				return validateVaultRef(ref)
 			}
 		}
 		return nil
@@ -179,13 +241,47 @@ func (s Step) Validate() error {
 				len(keys), strings.Join(keys, ","))
 		}
 		for stepName := range elem {
-			if stepName != RunStepName {
+			if stepName != RunStepName && stepName != MultiEnvStepName {
 				return fmt.Errorf("%q is not a valid step type", stepName)
 			}
 		}
 		return nil
 	}
 
+	timeoutStep := func(value interface{}) error {
+		elem := value.(map[string]TimeoutStepArgs)
+		var keys []string
+		for k := range elem {
+			keys = append(keys, k)
+		}
+		// Sort so tests can be deterministic.
+		sort.Strings(keys)
+
+		if len(keys) > 1 {
+			return fmt.Errorf("step element can only contain a single key, found %d: %s",
+				len(keys), strings.Join(keys, ","))
+		}
+		for stepName, args := range elem {
+			if stepName != InitStepName && stepName != PlanStepName && stepName != ApplyStepName && stepName != RunStepName {
+				return fmt.Errorf("%q steps don't support a %s key, only %s, %s, %s and %s steps do", stepName, TimeoutArgKey, InitStepName, PlanStepName, ApplyStepName, RunStepName)
+			}
+			if stepName == RunStepName {
+				if args.Command == nil || *args.Command == "" {
+					return fmt.Errorf("run steps with a %s key must have a %q key set", TimeoutArgKey, CommandArgKey)
+				}
+				if args.ExtraArgs != nil {
+					return fmt.Errorf("run steps don't support a %s key", ExtraArgsKey)
+				}
+			} else if args.Command != nil {
+				return fmt.Errorf("built-in steps don't support a %q key, found it in step %s", CommandArgKey, stepName)
+			}
+			if err := validateTimeout(args.Timeout); err != nil {
+				return fmt.Errorf("step %s %s: %s", stepName, TimeoutArgKey, err)
+			}
+		}
+		return nil
+	}
+
 	if s.Key != nil {
 		return validation.Validate(s.Key, validation.By(validStep))
 	}
@@ -198,9 +294,56 @@ func (s Step) Validate() error {
 	if len(s.StringVal) > 0 {
 		return validation.Validate(s.StringVal, validation.By(runStep))
 	}
+	if len(s.Timeout) > 0 {
+		return validation.Validate(s.Timeout, validation.By(timeoutStep))
+	}
 	return errors.New("step element is empty")
 }
 
+// validateUploadArtifactStep validates the args of an upload_artifact step.
+func validateUploadArtifactStep(args map[string]string) error {
+	for k := range args {
+		if k != NameArgKey && k != PathArgKey {
+			return fmt.Errorf("upload_artifact steps only support keys %q and %q, found key %q", NameArgKey, PathArgKey, k)
+		}
+	}
+	if args[NameArgKey] == "" || args[PathArgKey] == "" {
+		return fmt.Errorf("upload_artifact steps must have %q and %q keys set", NameArgKey, PathArgKey)
+	}
+	if !artifactNameRegex.MatchString(args[NameArgKey]) {
+		return fmt.Errorf("upload_artifact step name %q can only contain letters, numbers, '.', '-' and '_'", args[NameArgKey])
+	}
+	path := args[PathArgKey]
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(filepath.Clean(path), ".."+string(filepath.Separator)) {
+		return fmt.Errorf("upload_artifact step path %q must be a relative path inside the project's dir", path)
+	}
+	return nil
+}
+
+// validateVaultRef validates the vault key of an env step, which is the path
+// of a secret in Vault and the key to read from it separated by #, ex.
+// secret/data/db#password.
+func validateVaultRef(ref string) error {
+	parts := strings.Split(ref, "#")
+	if len(parts) != 2 || strings.Trim(parts[0], "/") == "" || parts[1] == "" {
+		return fmt.Errorf("env step vault %q must be a secret's path and key separated by #, ex. secret/data/db#password", ref)
+	}
+	return nil
+}
+
+// validateDiagramStep validates the args of a diagram step.
+func validateDiagramStep(args map[string]string) error {
+	for k := range args {
+		if k != ToolArgKey {
+			return fmt.Errorf("diagram steps only support a single %q key, found key %q", ToolArgKey, k)
+		}
+	}
+	if tool := args[ToolArgKey]; tool != "terraform" && tool != "inframap" {
+		return fmt.Errorf("diagram step tool %q is not one of terraform or inframap", tool)
+	}
+	return nil
+}
+
 func (s Step) ToValid() valid.Step {
 	// This will trigger in case #1 (see Step docs).
 	if s.Key != nil {
@@ -214,11 +357,25 @@ func (s Step) ToValid() valid.Step {
 		// After validation we assume there's only one key and it's a valid
 		// step name so we just use the first one.
 		for stepName, stepArgs := range s.Env {
+			if stepName == UploadArtifactStepName {
+				return valid.Step{
+					StepName:     stepName,
+					ArtifactName: stepArgs[NameArgKey],
+					ArtifactPath: stepArgs[PathArgKey],
+				}
+			}
+			if stepName == DiagramStepName {
+				return valid.Step{
+					StepName:    stepName,
+					DiagramTool: stepArgs[ToolArgKey],
+				}
+			}
 			return valid.Step{
-				StepName:    stepName,
-				EnvVarName:  stepArgs[NameArgKey],
-				RunCommand:  stepArgs[CommandArgKey],
-				EnvVarValue: stepArgs[ValueArgKey],
+				StepName:     stepName,
+				EnvVarName:   stepArgs[NameArgKey],
+				RunCommand:   stepArgs[CommandArgKey],
+				EnvVarValue:  stepArgs[ValueArgKey],
+				EnvVarSecret: stepArgs[VaultArgKey],
 			}
 		}
 	}
@@ -239,14 +396,33 @@ func (s Step) ToValid() valid.Step {
 	if len(s.StringVal) > 0 {
 		// After validation we assume there's only one key and it's a valid
 		// step name so we just use the first one.
-		for _, v := range s.StringVal {
+		for stepName, v := range s.StringVal {
 			return valid.Step{
-				StepName:   RunStepName,
+				StepName:   stepName,
 				RunCommand: v,
 			}
 		}
 	}
 
+	// This will trigger in case #5 (see Step docs).
+	if len(s.Timeout) > 0 {
+		// After validation we assume there's only one key and it's a valid
+		// step name so we just use the first one.
+		for stepName, stepArgs := range s.Timeout {
+			// Safe to ignore the error because we test it in Validate().
+			timeout, _ := time.ParseDuration(stepArgs.Timeout)
+			step := valid.Step{
+				StepName:  stepName,
+				ExtraArgs: stepArgs.ExtraArgs,
+				Timeout:   timeout,
+			}
+			if stepArgs.Command != nil {
+				step.RunCommand = *stepArgs.Command
+			}
+			return step
+		}
+	}
+
 	panic("step was not valid. This is a bug!")
 }
 
@@ -256,6 +432,7 @@ func (s Step) ToValid() valid.Step {
 // 1. a built-in step: " - init"
 // 2. a built-in step with extra_args: " - init: {extra_args: [arg1] }"
 // 3. a custom run step: " - run: my custom command"
+// 4. a step with a timeout: " - plan: {extra_args: [arg1], timeout: 30m}"
 // It takes a parameter unmarshal that is a function that tries to unmarshal
 // the current element into a given object.
 func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
@@ -284,11 +461,34 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 		return nil
 	}
 
+	// This represents an init, plan, apply or run step with a timeout, ex:
+	//   plan:
+	//     extra_args: [a, b]
+	//     timeout: 30m
+	//   run:
+	//     command: my command
+	//     timeout: 5m
+	// It's tried before env steps since those would also match run steps.
+	// Steps without a timeout key are one of the other forms.
+	var timeoutStep map[string]TimeoutStepArgs
+	err = unmarshal(&timeoutStep)
+	if err == nil && hasTimeout(timeoutStep) {
+		s.Timeout = timeoutStep
+		return nil
+	}
+
 	// This represents an env step, ex:
 	//   env:
 	//     name: k
 	//     value: hi //optional
 	//     command: exec
+	// or an upload_artifact step, ex:
+	//   upload_artifact:
+	//     name: plan-json
+	//     path: plan.json
+	// or a diagram step, ex:
+	//   diagram:
+	//     tool: inframap
 	var envStep map[string]map[string]string
 	err = unmarshal(&envStep)
 	if err == nil {
@@ -296,9 +496,10 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 		return nil
 	}
 
-	// Try to unmarshal as a custom run step, ex.
+	// Try to unmarshal as a custom run step or a multienv step, ex.
 	// steps:
 	// - run: my command
+	// - multienv: my command
 	// We validate if the key is run later.
 	var runStep map[string]string
 	err = unmarshal(&runStep)
@@ -310,8 +511,20 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 	return err
 }
 
+// hasTimeout returns true if any step in step has a timeout key.
+func hasTimeout(step map[string]TimeoutStepArgs) bool {
+	for _, args := range step {
+		if args.Timeout != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (s Step) marshalGeneric() (interface{}, error) {
-	if len(s.StringVal) != 0 {
+	if len(s.Timeout) != 0 {
+		return s.Timeout, nil
+	} else if len(s.StringVal) != 0 {
 		return s.StringVal, nil
 	} else if len(s.Map) != 0 {
 		return s.Map, nil
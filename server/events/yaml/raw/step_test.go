@@ -2,6 +2,7 @@ package raw_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -141,6 +142,32 @@ key: value`,
 			},
 		},
 
+		// Timeout style.
+		{
+			description: "built-in step with timeout",
+			input: `
+plan:
+  extra_args: [arg1]
+  timeout: 30m`,
+			exp: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"plan": {ExtraArgs: []string{"arg1"}, Timeout: "30m"},
+				},
+			},
+		},
+		{
+			description: "run step with timeout",
+			input: `
+run:
+  command: my command
+  timeout: 5m`,
+			exp: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"run": {Command: String("my command"), Timeout: "5m"},
+				},
+			},
+		},
+
 		// Errors
 		{
 			description: "extra args style no slice strings",
@@ -224,6 +251,24 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "fmt_check step",
+			input: raw.Step{
+				Key: String("fmt_check"),
+			},
+			expErr: "",
+		},
+		{
+			description: "validate extra_args",
+			input: raw.Step{
+				Map: MapType{
+					"validate": {
+						"extra_args": []string{"-json"},
+					},
+				},
+			},
+			expErr: "",
+		},
 		{
 			description: "env",
 			input: raw.Step{
@@ -256,6 +301,15 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "multienv step",
+			input: raw.Step{
+				StringVal: map[string]string{
+					"multienv": "my command",
+				},
+			},
+			expErr: "",
+		},
 
 		// Invalid inputs.
 		{
@@ -371,7 +425,7 @@ func TestStep_Validate(t *testing.T) {
 					},
 				},
 			},
-			expErr: "env steps only support keys \"name\", \"value\" and \"command\", found key \"abc\"",
+			expErr: "env steps only support keys \"name\", \"value\", \"command\" and \"vault\", found key \"abc\"",
 		},
 		{
 			description: "env step with both command and value set",
@@ -384,7 +438,222 @@ func TestStep_Validate(t *testing.T) {
 					},
 				},
 			},
-			expErr: "env steps only support one of the \"value\" or \"command\" keys, found both",
+			expErr: "env steps only support one of the \"value\", \"command\" or \"vault\" keys, found more than one",
+		},
+		{
+			description: "env step with only a name",
+			input: raw.Step{
+				Env: EnvType{
+					"env": {
+						"name": "name",
+					},
+				},
+			},
+			expErr: "env steps must have one of the \"value\", \"command\" or \"vault\" keys set",
+		},
+		{
+			description: "env step with vault",
+			input: raw.Step{
+				Env: EnvType{
+					"env": {
+						"name":  "PGPASSWORD",
+						"vault": "secret/data/db#password",
+					},
+				},
+			},
+		},
+		{
+			description: "env step with vault and value",
+			input: raw.Step{
+				Env: EnvType{
+					"env": {
+						"name":  "PGPASSWORD",
+						"value": "value",
+						"vault": "secret/data/db#password",
+					},
+				},
+			},
+			expErr: "env steps only support one of the \"value\", \"command\" or \"vault\" keys, found more than one",
+		},
+		{
+			description: "env step with vault without key",
+			input: raw.Step{
+				Env: EnvType{
+					"env": {
+						"name":  "PGPASSWORD",
+						"vault": "secret/data/db",
+					},
+				},
+			},
+			expErr: "env step vault \"secret/data/db\" must be a secret's path and key separated by #, ex. secret/data/db#password",
+		},
+		{
+			description: "upload_artifact step",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan-json",
+						"path": "out/plan.json",
+					},
+				},
+			},
+		},
+		{
+			description: "upload_artifact step without path",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan-json",
+					},
+				},
+			},
+			expErr: "upload_artifact steps must have \"name\" and \"path\" keys set",
+		},
+		{
+			description: "upload_artifact step with invalid key",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name":  "plan-json",
+						"path":  "plan.json",
+						"value": "value",
+					},
+				},
+			},
+			expErr: "upload_artifact steps only support keys \"name\" and \"path\", found key \"value\"",
+		},
+		{
+			description: "upload_artifact step with invalid name",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan/json",
+						"path": "plan.json",
+					},
+				},
+			},
+			expErr: "upload_artifact step name \"plan/json\" can only contain letters, numbers, '.', '-' and '_'",
+		},
+		{
+			description: "upload_artifact step with path outside project",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan-json",
+						"path": "../plan.json",
+					},
+				},
+			},
+			expErr: "upload_artifact step path \"../plan.json\" must be a relative path inside the project's dir",
+		},
+		{
+			description: "upload_artifact step with absolute path",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan-json",
+						"path": "/etc/passwd",
+					},
+				},
+			},
+			expErr: "upload_artifact step path \"/etc/passwd\" must be a relative path inside the project's dir",
+		},
+		{
+			description: "diagram step",
+			input: raw.Step{
+				Key: String("diagram"),
+			},
+		},
+		{
+			description: "security_scan step",
+			input: raw.Step{
+				Key: String("security_scan"),
+			},
+		},
+		{
+			description: "diagram step with tool",
+			input: raw.Step{
+				Env: EnvType{
+					"diagram": {
+						"tool": "inframap",
+					},
+				},
+			},
+		},
+		{
+			description: "diagram step with invalid tool",
+			input: raw.Step{
+				Env: EnvType{
+					"diagram": {
+						"tool": "graphviz",
+					},
+				},
+			},
+			expErr: "diagram step tool \"graphviz\" is not one of terraform or inframap",
+		},
+		{
+			description: "diagram step with invalid key",
+			input: raw.Step{
+				Env: EnvType{
+					"diagram": {
+						"tool": "terraform",
+						"path": "diagram.svg",
+					},
+				},
+			},
+			expErr: "diagram steps only support a single \"tool\" key, found key \"path\"",
+		},
+		{
+			description: "plan step with timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"plan": {ExtraArgs: []string{"arg1"}, Timeout: "30m"},
+				},
+			},
+		},
+		{
+			description: "run step with timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"run": {Command: String("my command"), Timeout: "5m"},
+				},
+			},
+		},
+		{
+			description: "run step with timeout and no command",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"run": {Timeout: "5m"},
+				},
+			},
+			expErr: "run steps with a timeout key must have a \"command\" key set",
+		},
+		{
+			description: "built-in step with timeout and command",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"init": {Command: String("my command"), Timeout: "5m"},
+				},
+			},
+			expErr: "built-in steps don't support a \"command\" key, found it in step init",
+		},
+		{
+			description: "env step with timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"env": {Command: String("my command"), Timeout: "5m"},
+				},
+			},
+			expErr: "\"env\" steps don't support a timeout key, only init, plan, apply and run steps do",
+		},
+		{
+			description: "step with invalid timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"apply": {Timeout: "-5m"},
+				},
+			},
+			expErr: "step apply timeout: must be greater than 0",
 		},
 		{
 			// For atlantis.yaml v2, this wouldn't parse, but now there should
@@ -458,6 +727,61 @@ func TestStep_ToValid(t *testing.T) {
 				EnvVarName: "test",
 			},
 		},
+		{
+			description: "env step with vault",
+			input: raw.Step{
+				Env: EnvType{
+					"env": {
+						"name":  "PGPASSWORD",
+						"vault": "secret/data/db#password",
+					},
+				},
+			},
+			exp: valid.Step{
+				StepName:     "env",
+				EnvVarName:   "PGPASSWORD",
+				EnvVarSecret: "secret/data/db#password",
+			},
+		},
+		{
+			description: "upload_artifact step",
+			input: raw.Step{
+				Env: EnvType{
+					"upload_artifact": {
+						"name": "plan-json",
+						"path": "plan.json",
+					},
+				},
+			},
+			exp: valid.Step{
+				StepName:     "upload_artifact",
+				ArtifactName: "plan-json",
+				ArtifactPath: "plan.json",
+			},
+		},
+		{
+			description: "diagram step",
+			input: raw.Step{
+				Key: String("diagram"),
+			},
+			exp: valid.Step{
+				StepName: "diagram",
+			},
+		},
+		{
+			description: "diagram step with tool",
+			input: raw.Step{
+				Env: EnvType{
+					"diagram": {
+						"tool": "inframap",
+					},
+				},
+			},
+			exp: valid.Step{
+				StepName:    "diagram",
+				DiagramTool: "inframap",
+			},
+		},
 		{
 			description: "init extra_args",
 			input: raw.Step{
@@ -512,6 +836,44 @@ func TestStep_ToValid(t *testing.T) {
 				RunCommand: "my 'run command'",
 			},
 		},
+		{
+			description: "multienv step",
+			input: raw.Step{
+				StringVal: map[string]string{
+					"multienv": "vault-creds --format=env",
+				},
+			},
+			exp: valid.Step{
+				StepName:   "multienv",
+				RunCommand: "vault-creds --format=env",
+			},
+		},
+		{
+			description: "plan step with timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"plan": {ExtraArgs: []string{"arg1"}, Timeout: "30m"},
+				},
+			},
+			exp: valid.Step{
+				StepName:  "plan",
+				ExtraArgs: []string{"arg1"},
+				Timeout:   30 * time.Minute,
+			},
+		},
+		{
+			description: "run step with timeout",
+			input: raw.Step{
+				Timeout: map[string]raw.TimeoutStepArgs{
+					"run": {Command: String("my command"), Timeout: "5m"},
+				},
+			},
+			exp: valid.Step{
+				StepName:   "run",
+				RunCommand: "my command",
+				Timeout:    5 * time.Minute,
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
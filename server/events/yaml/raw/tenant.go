@@ -0,0 +1,101 @@
+package raw
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// tenantNameRegex matches the names of tenants. They're used as metrics
+// labels so they're kept simple.
+var tenantNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Tenant is the raw schema for a tenant in the server-side repo config.
+type Tenant struct {
+	// Repos are the ids of the tenant's repos, ex.
+	// github.com/team-a/infra, or regexes wrapped in slashes.
+	Repos []string `yaml:"repos" json:"repos"`
+	// Users are globs, ex. *@team-a.example.com, matching the web UI and API
+	// users who belong to the tenant.
+	Users            []string          `yaml:"users,omitempty" json:"users,omitempty"`
+	Workflow         *string           `yaml:"workflow,omitempty" json:"workflow,omitempty"`
+	CloudCredentials []CloudCredential `yaml:"cloud_credentials,omitempty" json:"cloud_credentials,omitempty"`
+	EnvVars          []EnvVar          `yaml:"env,omitempty" json:"env,omitempty"`
+	GitCredentials   []GitCredential   `yaml:"git_credentials,omitempty" json:"git_credentials,omitempty"`
+}
+
+func (t Tenant) Validate() error {
+	reposValid := func(value interface{}) error {
+		for _, id := range value.([]string) {
+			if id == "" {
+				return errors.New("cannot contain empty ids")
+			}
+			if isSlashDelimited(id) {
+				if _, err := regexp.Compile(id[1 : len(id)-1]); err != nil {
+					return errors.Wrapf(err, "parsing: %s", id)
+				}
+			}
+		}
+		return nil
+	}
+	usersValid := func(value interface{}) error {
+		for _, glob := range value.([]string) {
+			if strings.TrimSpace(glob) == "" {
+				return errors.New("cannot contain empty users")
+			}
+			// Match only returns an error if the glob is malformed.
+			if _, err := path.Match(glob, ""); err != nil {
+				return errors.Wrapf(err, "parsing: %s", glob)
+			}
+		}
+		return nil
+	}
+
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Repos, validation.Required, validation.By(reposValid)),
+		validation.Field(&t.Users, validation.By(usersValid)),
+		validation.Field(&t.CloudCredentials),
+		validation.Field(&t.EnvVars),
+		validation.Field(&t.GitCredentials),
+	)
+}
+
+// validateTenantName returns an error if name can't be used as the name of a
+// tenant.
+func validateTenantName(name string) error {
+	if !tenantNameRegex.MatchString(name) {
+		return errors.Errorf("tenant name %q must only contain lowercase letters, numbers, - and _", name)
+	}
+	return nil
+}
+
+// ToValid returns the tenant called name.
+func (t Tenant) ToValid(name string) valid.Tenant {
+	return valid.Tenant{
+		Name:  name,
+		Users: t.Users,
+	}
+}
+
+// RepoCfgs returns a repo config for each of the tenant's repos that sets the
+// tenant's defaults. They're ordered before the repos section so repo
+// configs can override them.
+func (t Tenant) RepoCfgs(name string, workflows map[string]valid.Workflow) []valid.Repo {
+	var repos []valid.Repo
+	for _, id := range t.Repos {
+		r := Repo{
+			ID:               id,
+			Workflow:         t.Workflow,
+			CloudCredentials: t.CloudCredentials,
+			EnvVars:          t.EnvVars,
+			GitCredentials:   t.GitCredentials,
+		}.ToValid(workflows)
+		r.Tenant = name
+		repos = append(repos, r)
+	}
+	return repos
+}
@@ -0,0 +1,126 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTenant_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Tenant
+		expErr      string
+	}{
+		{
+			description: "no repos",
+			input:       raw.Tenant{},
+			expErr:      "repos: cannot be blank.",
+		},
+		{
+			description: "empty repo id",
+			input:       raw.Tenant{Repos: []string{""}},
+			expErr:      "repos: cannot contain empty ids.",
+		},
+		{
+			description: "invalid repo regex",
+			input:       raw.Tenant{Repos: []string{"/[/"}},
+			expErr:      "repos: parsing: /[/: error parsing regexp: missing closing ]: `[`.",
+		},
+		{
+			description: "empty user",
+			input:       raw.Tenant{Repos: []string{"github.com/team-a/infra"}, Users: []string{" "}},
+			expErr:      "users: cannot contain empty users.",
+		},
+		{
+			description: "invalid user glob",
+			input:       raw.Tenant{Repos: []string{"github.com/team-a/infra"}, Users: []string{"["}},
+			expErr:      "users: parsing: [: syntax error in pattern.",
+		},
+		{
+			description: "invalid env",
+			input: raw.Tenant{
+				Repos:   []string{"github.com/team-a/infra"},
+				EnvVars: []raw.EnvVar{{Name: "$TEAM", FromEnv: "TEAM"}},
+			},
+			expErr: "env: (0: (name: \"$TEAM\" is not a valid environment variable name.).).",
+		},
+		{
+			description: "valid",
+			input: raw.Tenant{
+				Repos: []string{"github.com/team-a/infra", "/github.com\\/team-a-.*/"},
+				Users: []string{"*@team-a.example.com", "api-token:team-a"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestGlobalCfg_ValidateTenants(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	workflow := "team-a"
+	tenant := raw.Tenant{Repos: []string{"github.com/team-a/infra"}, Workflow: &workflow}
+
+	err := raw.GlobalCfg{Tenants: map[string]raw.Tenant{"Team A": {Repos: []string{"github.com/team-a/infra"}}}}.Validate()
+	ErrEquals(t, "tenant name \"Team A\" must only contain lowercase letters, numbers, - and _", err)
+
+	err = raw.GlobalCfg{Tenants: map[string]raw.Tenant{"team-a": tenant}}.Validate()
+	ErrEquals(t, "workflow \"team-a\" is not defined", err)
+
+	err = raw.GlobalCfg{
+		Tenants:   map[string]raw.Tenant{"team-a": tenant},
+		Workflows: map[string]raw.Workflow{"team-a": {}},
+	}.Validate()
+	Ok(t, err)
+}
+
+// The tenants' repo configs go after the defaults and before the repos so
+// repos can override them.
+func TestGlobalCfg_ToValidTenants(t *testing.T) {
+	workflow := "team-a"
+	override := "default"
+	team := "a"
+	cfg := raw.GlobalCfg{
+		Repos: []raw.Repo{{ID: "github.com/team-a/special", Workflow: &override}},
+		Tenants: map[string]raw.Tenant{
+			"team-b": {Repos: []string{"github.com/team-b/infra"}},
+			"team-a": {
+				Repos:    []string{"github.com/team-a/infra", "github.com/team-a/special"},
+				Users:    []string{"*@team-a.example.com"},
+				Workflow: &workflow,
+				EnvVars:  []raw.EnvVar{{Name: "TEAM", Value: &team}},
+			},
+		},
+		Workflows: map[string]raw.Workflow{"team-a": {}},
+	}.ToValid(valid.NewGlobalCfg(false, false, false))
+
+	Equals(t, map[string]valid.Tenant{
+		"team-a": {Name: "team-a", Users: []string{"*@team-a.example.com"}},
+		"team-b": {Name: "team-b"},
+	}, cfg.Tenants)
+
+	var ids []string
+	var tenants []string
+	for _, r := range cfg.Repos {
+		ids = append(ids, r.IDString())
+		tenants = append(tenants, r.Tenant)
+	}
+	Equals(t, []string{"/.*/", "github.com/team-a/infra", "github.com/team-a/special", "github.com/team-b/infra", "github.com/team-a/special"}, ids)
+	Equals(t, []string{"", "team-a", "team-a", "team-b", ""}, tenants)
+	Equals(t, "team-a", cfg.Repos[1].Workflow.Name)
+	Equals(t, []valid.EnvVar{{Name: "TEAM", Value: "a"}}, cfg.Repos[1].EnvVars)
+	Equals(t, (*valid.Workflow)(nil), cfg.Repos[3].Workflow)
+}
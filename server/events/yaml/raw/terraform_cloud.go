@@ -0,0 +1,41 @@
+package raw
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// TerraformCloud selects the Terraform Cloud workspace a project is planned
+// and applied in by its tags.
+type TerraformCloud struct {
+	Organization *string  `yaml:"organization,omitempty"`
+	Tags         []string `yaml:"tags,omitempty"`
+}
+
+func (t TerraformCloud) Validate() error {
+	validTags := func(value interface{}) error {
+		for _, tag := range value.([]string) {
+			if strings.TrimSpace(tag) == "" {
+				return errors.New("cannot contain empty tags")
+			}
+			if strings.Contains(tag, ",") {
+				return errors.New("cannot contain commas")
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Organization, validation.Required),
+		validation.Field(&t.Tags, validation.Required, validation.By(validTags)),
+	)
+}
+
+func (t TerraformCloud) ToValid() *valid.TerraformCloud {
+	return &valid.TerraformCloud{
+		Organization: *t.Organization,
+		Tags:         t.Tags,
+	}
+}
@@ -0,0 +1,92 @@
+package raw_test
+
+import (
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestTerraformCloud_UnmarshalYAML(t *testing.T) {
+	var tc raw.TerraformCloud
+	err := yaml.UnmarshalStrict([]byte(`
+organization: acme
+tags: [networking, "env:prod"]
+`), &tc)
+	Ok(t, err)
+	Equals(t, raw.TerraformCloud{
+		Organization: String("acme"),
+		Tags:         []string{"networking", "env:prod"},
+	}, tc)
+}
+
+func TestTerraformCloud_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.TerraformCloud
+		expErr      string
+	}{
+		{
+			description: "all set",
+			input: raw.TerraformCloud{
+				Organization: String("acme"),
+				Tags:         []string{"networking"},
+			},
+		},
+		{
+			description: "no organization",
+			input: raw.TerraformCloud{
+				Tags: []string{"networking"},
+			},
+			expErr: "organization: cannot be blank.",
+		},
+		{
+			description: "no tags",
+			input: raw.TerraformCloud{
+				Organization: String("acme"),
+			},
+			expErr: "tags: cannot be blank.",
+		},
+		{
+			description: "empty tag",
+			input: raw.TerraformCloud{
+				Organization: String("acme"),
+				Tags:         []string{" "},
+			},
+			expErr: "tags: cannot contain empty tags.",
+		},
+		{
+			description: "tag with comma",
+			input: raw.TerraformCloud{
+				Organization: String("acme"),
+				Tags:         []string{"a,b"},
+			},
+			expErr: "tags: cannot contain commas.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestTerraformCloud_ToValid(t *testing.T) {
+	tc := raw.TerraformCloud{
+		Organization: String("acme"),
+		Tags:         []string{"networking"},
+	}
+	Equals(t, &valid.TerraformCloud{
+		Organization: "acme",
+		Tags:         []string{"networking"},
+	}, tc.ToValid())
+}
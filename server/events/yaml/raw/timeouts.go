@@ -0,0 +1,63 @@
+package raw
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Timeouts is the raw schema for how long the steps of a repo's projects can
+// run for in the server-side repo config.
+type Timeouts struct {
+	Init  *string `yaml:"init,omitempty" json:"init,omitempty"`
+	Plan  *string `yaml:"plan,omitempty" json:"plan,omitempty"`
+	Apply *string `yaml:"apply,omitempty" json:"apply,omitempty"`
+	Run   *string `yaml:"run,omitempty" json:"run,omitempty"`
+}
+
+func (t Timeouts) Validate() error {
+	timeoutValid := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		return validateTimeout(*strPtr)
+	}
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Init, validation.By(timeoutValid)),
+		validation.Field(&t.Plan, validation.By(timeoutValid)),
+		validation.Field(&t.Apply, validation.By(timeoutValid)),
+		validation.Field(&t.Run, validation.By(timeoutValid)),
+	)
+}
+
+func (t Timeouts) ToValid() valid.Timeouts {
+	// Safe to ignore the errors because we test them in Validate().
+	parse := func(s *string) time.Duration {
+		if s == nil {
+			return 0
+		}
+		d, _ := time.ParseDuration(*s)
+		return d
+	}
+	return valid.Timeouts{
+		Init:  parse(t.Init),
+		Plan:  parse(t.Plan),
+		Apply: parse(t.Apply),
+		Run:   parse(t.Run),
+	}
+}
+
+// validateTimeout validates a step timeout, ex. 30m.
+func validateTimeout(timeout string) error {
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return errors.Wrapf(err, "parsing: %s", timeout)
+	}
+	if d <= 0 {
+		return errors.New("must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTimeouts_Validate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+	cases := []struct {
+		description string
+		input       raw.Timeouts
+		expErr      string
+	}{
+		{
+			description: "nothing set",
+			input:       raw.Timeouts{},
+		},
+		{
+			description: "all set",
+			input:       raw.Timeouts{Init: String("5m"), Plan: String("30m"), Apply: String("1h"), Run: String("10m")},
+		},
+		{
+			description: "invalid timeout",
+			input:       raw.Timeouts{Plan: String("notaduration")},
+			expErr:      "plan: parsing: notaduration: time: invalid duration \"notaduration\".",
+		},
+		{
+			description: "zero timeout",
+			input:       raw.Timeouts{Run: String("0s")},
+			expErr:      "run: must be greater than 0.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
+func TestTimeouts_ToValid(t *testing.T) {
+	Equals(t, valid.Timeouts{}, raw.Timeouts{}.ToValid())
+	Equals(t, valid.Timeouts{
+		Plan:  30 * time.Minute,
+		Apply: time.Hour,
+	}, raw.Timeouts{Plan: String("30m"), Apply: String("1h")}.ToValid())
+}
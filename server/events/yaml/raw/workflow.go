@@ -8,18 +8,25 @@ import (
 type Workflow struct {
 	Apply *Stage `yaml:"apply,omitempty" json:"apply,omitempty"`
 	Plan  *Stage `yaml:"plan,omitempty" json:"plan,omitempty"`
+	// Image, if set, is the container image the workflow's steps run in.
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+	// StateLockRetry, if set, retries the init, plan and apply steps when
+	// the Terraform state is locked by another run.
+	StateLockRetry *StateLockRetry `yaml:"state_lock_retry,omitempty" json:"state_lock_retry,omitempty"`
 }
 
 func (w Workflow) Validate() error {
 	return validation.ValidateStruct(&w,
 		validation.Field(&w.Apply),
 		validation.Field(&w.Plan),
+		validation.Field(&w.StateLockRetry),
 	)
 }
 
 func (w Workflow) ToValid(name string) valid.Workflow {
 	v := valid.Workflow{
-		Name: name,
+		Name:  name,
+		Image: w.Image,
 	}
 	if w.Apply == nil || w.Apply.Steps == nil {
 		v.Apply = valid.DefaultApplyStage
@@ -31,5 +38,9 @@ func (w Workflow) ToValid(name string) valid.Workflow {
 	} else {
 		v.Plan = w.Plan.ToValid()
 	}
+	if w.StateLockRetry != nil {
+		stateLockRetry := w.StateLockRetry.ToValid()
+		v.StateLockRetry = &stateLockRetry
+	}
 	return v
 }
@@ -60,6 +60,19 @@ apply:
 				},
 			},
 		},
+		{
+			description: "image set",
+			input: `
+image: alpine/terragrunt:0.12.24
+plan:
+  steps: [init]`,
+			exp: raw.Workflow{
+				Plan: &raw.Stage{
+					Steps: []raw.Step{{Key: String("init")}},
+				},
+				Image: "alpine/terragrunt:0.12.24",
+			},
+		},
 		{
 			description: "steps set to empty slice",
 			input: `
@@ -159,6 +172,28 @@ func TestWorkflow_ToValid(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "image set",
+			input: raw.Workflow{
+				Image: "alpine/terragrunt",
+			},
+			exp: valid.Workflow{
+				Apply: valid.DefaultApplyStage,
+				Plan:  valid.DefaultPlanStage,
+				Image: "alpine/terragrunt",
+			},
+		},
+		{
+			description: "state_lock_retry set",
+			input: raw.Workflow{
+				StateLockRetry: &raw.StateLockRetry{MaxAttempts: Int(5)},
+			},
+			exp: valid.Workflow{
+				Apply:          valid.DefaultApplyStage,
+				Plan:           valid.DefaultPlanStage,
+				StateLockRetry: &valid.StateLockRetry{MaxAttempts: 5, Backoff: valid.DefaultStateLockRetryBackoff},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
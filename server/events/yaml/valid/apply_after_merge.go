@@ -0,0 +1,46 @@
+package valid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ApplyAfterMerge is how the projects of a repo are applied once pull
+// requests are merged into its default branch instead of from pull requests,
+// from the server-side repo config.
+type ApplyAfterMerge struct {
+	// Enabled is true if projects can't be applied from pull requests.
+	Enabled bool
+	// Notify is where the results of applies are sent in addition to the
+	// merged pull request.
+	Notify Notify
+}
+
+// String describes the config like it's written in config files, ex.
+// "enabled: true, notify: [#infra]".
+func (a ApplyAfterMerge) String() string {
+	var targets []string
+	for _, c := range a.Notify.SlackChannels {
+		targets = append(targets, "#"+c)
+	}
+	targets = append(targets, a.Notify.Webhooks...)
+	targets = append(targets, a.Notify.Emails...)
+	return fmt.Sprintf("enabled: %t, notify: [%s]", a.Enabled, strings.Join(targets, ", "))
+}
+
+// ApplyAfterMerge returns how the repo with id repoID is applied after pull
+// requests are merged. The config of the last matching repo config that sets
+// it is used. It returns nil if the repo's projects are applied from pull
+// requests.
+func (g GlobalCfg) ApplyAfterMerge(repoID string) *ApplyAfterMerge {
+	var cfg *ApplyAfterMerge
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.ApplyAfterMerge != nil {
+			cfg = repo.ApplyAfterMerge
+		}
+	}
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	return cfg
+}
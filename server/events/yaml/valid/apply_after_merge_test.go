@@ -0,0 +1,32 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_ApplyAfterMerge(t *testing.T) {
+	infra := valid.ApplyAfterMerge{Enabled: true, Notify: valid.Notify{SlackChannels: []string{"infra"}}}
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:         regexp.MustCompile(".*"),
+			ApplyAfterMerge: &infra,
+		},
+		valid.Repo{
+			ID:              "github.com/owner/sandbox",
+			ApplyAfterMerge: &valid.ApplyAfterMerge{Enabled: false},
+		},
+		valid.Repo{
+			ID: "github.com/owner/sandbox",
+		},
+	)
+
+	Equals(t, &infra, global.ApplyAfterMerge("github.com/owner/repo"))
+	Assert(t, global.ApplyAfterMerge("github.com/owner/sandbox") == nil, "exp repos that disable it to be applied from pull requests")
+	Assert(t, valid.NewGlobalCfg(false, false, false).ApplyAfterMerge("github.com/owner/repo") == nil, "exp disabled by default")
+	Equals(t, "enabled: true, notify: [#infra]", infra.String())
+}
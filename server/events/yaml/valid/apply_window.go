@@ -0,0 +1,132 @@
+package valid
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// RejectOutsideApplyWindows rejects applies outside the apply windows.
+	RejectOutsideApplyWindows = "reject"
+	// QueueOutsideApplyWindows queues applies outside the apply windows
+	// until the next window opens.
+	QueueOutsideApplyWindows = "queue"
+)
+
+// ApplyWindows are when the projects of a repo can be applied, from the
+// server-side repo config.
+type ApplyWindows struct {
+	Windows []ApplyWindow
+	// Outside is what happens to applies outside the windows, one of the
+	// *OutsideApplyWindows constants.
+	Outside string
+	// BreakGlass are the users and teams, of the form org/team, that can
+	// apply outside the windows with atlantis apply --break-glass.
+	BreakGlass []string
+}
+
+// ApplyWindow is a schedule during which the projects it matches can be
+// applied.
+type ApplyWindow struct {
+	// Schedule matches the minutes the window is open.
+	Schedule Schedule
+	// Location is the time zone Schedule is in.
+	Location *time.Location
+	// Dir, Workspace and Project are globs, ex. production/*, that the
+	// project's dir, workspace and name must match. Empty globs match
+	// everything.
+	Dir       string
+	Workspace string
+	Project   string
+}
+
+// Matches returns true if the window applies to the project in repoRelDir
+// and workspace, named projectName.
+func (w ApplyWindow) Matches(repoRelDir string, workspace string, projectName string) bool {
+	return projectGlobsMatch(w.Dir, w.Workspace, w.Project, repoRelDir, workspace, projectName)
+}
+
+// String describes the window for people, ex.
+// "* 9-16 * * 1-5 Europe/Amsterdam, dir: production/*".
+func (w ApplyWindow) String() string {
+	parts := []string{w.Schedule.String() + " " + w.Location.String()}
+	for _, kv := range [][2]string{
+		{"dir", w.Dir},
+		{"workspace", w.Workspace},
+		{"project", w.Project},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+": "+kv[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ForProject returns the windows that apply to the project in repoRelDir and
+// workspace, named projectName, or nil if none do and it can be applied at
+// any time.
+func (a ApplyWindows) ForProject(repoRelDir string, workspace string, projectName string) *ApplyWindows {
+	var windows []ApplyWindow
+	for _, w := range a.Windows {
+		if w.Matches(repoRelDir, workspace, projectName) {
+			windows = append(windows, w)
+		}
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+	a.Windows = windows
+	return &a
+}
+
+// IsOpen returns true if one of the windows is open at t.
+func (a ApplyWindows) IsOpen(t time.Time) bool {
+	for _, w := range a.Windows {
+		if w.Schedule.Matches(t.In(w.Location)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns when the first of the windows opens after t. It returns
+// false if none opens in the next year.
+func (a ApplyWindows) NextOpen(t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, w := range a.Windows {
+		n, ok := w.Schedule.Next(t.In(w.Location))
+		if ok && (!found || n.Before(next)) {
+			next, found = n, true
+		}
+	}
+	return next, found
+}
+
+// String describes the windows for people like they're written in config
+// files, ex. "windows: [* 9-16 * * 1-5 UTC], outside: queue, break_glass:
+// [org/sre]".
+func (a ApplyWindows) String() string {
+	var windows []string
+	for _, w := range a.Windows {
+		windows = append(windows, w.String())
+	}
+	return "windows: [" + strings.Join(windows, "; ") + "], outside: " + a.Outside + ", break_glass: [" + strings.Join(a.BreakGlass, ", ") + "]"
+}
+
+// ApplyWindows returns the apply windows of the project in repoRelDir and
+// workspace, named projectName, in the repo with id repoID. The windows of
+// the last matching repo config that sets them are used. It returns nil if
+// none of them apply to the project.
+func (g GlobalCfg) ApplyWindows(repoID string, repoRelDir string, workspace string, projectName string) *ApplyWindows {
+	var windows *ApplyWindows
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.ApplyWindows != nil {
+			windows = repo.ApplyWindows
+		}
+	}
+	if windows == nil {
+		return nil
+	}
+	return windows.ForProject(repoRelDir, workspace, projectName)
+}
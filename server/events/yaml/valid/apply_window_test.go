@@ -0,0 +1,102 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestApplyWindows_IsOpenNextOpen(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	Ok(t, err)
+	windows := valid.ApplyWindows{
+		Windows: []valid.ApplyWindow{
+			{Schedule: mustParseSchedule(t, "* 9-16 * * 1-5"), Location: newYork},
+			{Schedule: mustParseSchedule(t, "* 22-23 * * 6"), Location: time.UTC},
+		},
+	}
+
+	// 2020-06-05 is a Friday.
+	fridayMorning := time.Date(2020, 6, 5, 12, 0, 0, 0, time.UTC)
+	Equals(t, false, windows.IsOpen(fridayMorning))
+	next, ok := windows.NextOpen(fridayMorning)
+	Equals(t, true, ok)
+	Assert(t, next.Equal(time.Date(2020, 6, 5, 9, 0, 0, 0, newYork)), "got %s", next)
+	Equals(t, true, windows.IsOpen(next))
+
+	fridayNight := time.Date(2020, 6, 5, 21, 0, 0, 0, time.UTC)
+	Equals(t, false, windows.IsOpen(fridayNight))
+	next, ok = windows.NextOpen(fridayNight)
+	Equals(t, true, ok)
+	Assert(t, next.Equal(time.Date(2020, 6, 6, 22, 0, 0, 0, time.UTC)), "got %s", next)
+}
+
+func TestGlobalCfg_ApplyWindows(t *testing.T) {
+	anytime := mustParseSchedule(t, "* * * * *")
+	production := valid.ApplyWindow{Schedule: anytime, Location: time.UTC, Dir: "production/*"}
+	prodWorkspace := valid.ApplyWindow{Schedule: anytime, Location: time.UTC, Workspace: "prod"}
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:      regexp.MustCompile(".*"),
+			ApplyWindows: &valid.ApplyWindows{Windows: []valid.ApplyWindow{prodWorkspace}, Outside: valid.RejectOutsideApplyWindows},
+		},
+		valid.Repo{
+			ID:           "github.com/owner/repo",
+			ApplyWindows: &valid.ApplyWindows{Windows: []valid.ApplyWindow{production, prodWorkspace}, Outside: valid.QueueOutsideApplyWindows},
+		},
+		valid.Repo{
+			ID: "github.com/owner/repo",
+		},
+	)
+
+	cases := []struct {
+		description string
+		repoID      string
+		dir         string
+		workspace   string
+		exp         *valid.ApplyWindows
+	}{
+		{
+			description: "no matching windows",
+			repoID:      "github.com/owner/repo",
+			dir:         "staging",
+			workspace:   "default",
+		},
+		{
+			description: "matching dir",
+			repoID:      "github.com/owner/repo",
+			dir:         "production/eu",
+			workspace:   "default",
+			exp:         &valid.ApplyWindows{Windows: []valid.ApplyWindow{production}, Outside: valid.QueueOutsideApplyWindows},
+		},
+		{
+			description: "matching dir and workspace",
+			repoID:      "github.com/owner/repo",
+			dir:         "production/eu",
+			workspace:   "prod",
+			exp:         &valid.ApplyWindows{Windows: []valid.ApplyWindow{production, prodWorkspace}, Outside: valid.QueueOutsideApplyWindows},
+		},
+		{
+			description: "other repo",
+			repoID:      "github.com/owner/other",
+			dir:         "production/eu",
+			workspace:   "prod",
+			exp:         &valid.ApplyWindows{Windows: []valid.ApplyWindow{prodWorkspace}, Outside: valid.RejectOutsideApplyWindows},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, global.ApplyWindows(c.repoID, c.dir, c.workspace, ""))
+		})
+	}
+}
+
+func mustParseSchedule(t *testing.T, expr string) valid.Schedule {
+	s, err := valid.ParseSchedule(expr)
+	Ok(t, err)
+	return s
+}
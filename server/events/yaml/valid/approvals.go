@@ -0,0 +1,45 @@
+package valid
+
+import (
+	"fmt"
+)
+
+// DefaultApprovalCount is the number of approvals the approved apply
+// requirement needs if it isn't configured.
+const DefaultApprovalCount = 1
+
+// Approvals are the rules of the approved apply requirement, from the
+// server-side repo config.
+type Approvals struct {
+	// Count is how many users other than the author must have approved the
+	// pull request.
+	Count int
+	// Codeowners is true if every modified file that has owners in the
+	// repo's CODEOWNERS file must be approved by one of its owners.
+	Codeowners bool
+}
+
+// IsDefault returns true if the rules are met by any single approval, in
+// which case the VCS host decides if the pull request is approved.
+func (a Approvals) IsDefault() bool {
+	return a.Count <= DefaultApprovalCount && !a.Codeowners
+}
+
+// String describes the rules like they're written in config files, ex.
+// "count: 2, codeowners: true".
+func (a Approvals) String() string {
+	return fmt.Sprintf("count: %d, codeowners: %t", a.Count, a.Codeowners)
+}
+
+// Approvals returns the approval rules for the repo with id repoID. The rules
+// of the last matching repo config that sets them are used. If none do, it
+// returns nil and the default rules apply.
+func (g GlobalCfg) Approvals(repoID string) *Approvals {
+	var approvals *Approvals
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Approvals != nil {
+			approvals = repo.Approvals
+		}
+	}
+	return approvals
+}
@@ -0,0 +1,51 @@
+package valid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultProjectMarkers are the files that make a directory a project if a
+// repo's autodiscover config doesn't set project_markers.
+var DefaultProjectMarkers = []string{"*.tf", "terragrunt.hcl"}
+
+// DefaultAutodiscoverExclude are the directories that aren't projects if a
+// repo's autodiscover config doesn't set exclude. Modules are planned by the
+// projects that use them.
+var DefaultAutodiscoverExclude = []string{"**/modules"}
+
+// Autodiscover is how the projects of repos without an atlantis.yaml are
+// found, from the server-side repo config.
+type Autodiscover struct {
+	// Include are globs, ex. environments/**, of the directories that can
+	// be projects. A directory matches if it or one of its parents does. If
+	// empty, every directory can be.
+	Include []string
+	// Exclude are globs of the directories that can't be projects. A
+	// directory matches if it or one of its parents does.
+	Exclude []string
+	// ProjectMarkers are globs, ex. backend.tf, of the files that make the
+	// directory they're in a project.
+	ProjectMarkers []string
+}
+
+// String describes the config like it's written in config files, ex.
+// "include: [environments], exclude: [**/modules], project_markers: [*.tf]".
+func (a Autodiscover) String() string {
+	return fmt.Sprintf("include: [%s], exclude: [%s], project_markers: [%s]",
+		strings.Join(a.Include, ", "), strings.Join(a.Exclude, ", "), strings.Join(a.ProjectMarkers, ", "))
+}
+
+// Autodiscover returns how the projects of the repo with id repoID are found
+// if it doesn't have an atlantis.yaml. The config of the last matching repo
+// that sets it is used. If none do, it returns nil and projects are found by
+// the default rules.
+func (g GlobalCfg) Autodiscover(repoID string) *Autodiscover {
+	var autodiscover *Autodiscover
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Autodiscover != nil {
+			autodiscover = repo.Autodiscover
+		}
+	}
+	return autodiscover
+}
@@ -0,0 +1,33 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_Autodiscover(t *testing.T) {
+	all := &valid.Autodiscover{Exclude: valid.DefaultAutodiscoverExclude, ProjectMarkers: valid.DefaultProjectMarkers}
+	live := &valid.Autodiscover{Include: []string{"live/**"}, ProjectMarkers: []string{"terragrunt.hcl"}}
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:      regexp.MustCompile("^github.com/owner/.*"),
+			Autodiscover: all,
+		},
+		valid.Repo{
+			ID:           "github.com/owner/monorepo",
+			Autodiscover: live,
+		},
+		valid.Repo{
+			ID: "github.com/owner/monorepo",
+		},
+	)
+
+	Equals(t, live, global.Autodiscover("github.com/owner/monorepo"))
+	Equals(t, all, global.Autodiscover("github.com/owner/other"))
+	Equals(t, (*valid.Autodiscover)(nil), global.Autodiscover("github.com/other/repo"))
+	Equals(t, "include: [live/**], exclude: [], project_markers: [terragrunt.hcl]", live.String())
+}
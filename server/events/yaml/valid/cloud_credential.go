@@ -0,0 +1,94 @@
+package valid
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultCloudCredentialDuration is how long assumed credentials are valid for
+// if duration isn't set. Commands that run for longer fail once they expire.
+const DefaultCloudCredentialDuration = time.Hour
+
+// CloudCredential is the cloud identity that Atlantis assumes before running
+// the steps of the projects it matches so each project only gets the
+// short-lived credentials it needs instead of the server's own.
+type CloudCredential struct {
+	// Dir, Workspace and Project are globs, ex. production/*, that the
+	// project's dir, workspace and name must match. Empty globs match
+	// everything.
+	Dir       string
+	Workspace string
+	Project   string
+	// AWSRoleARN, if set, is the AWS IAM role to assume.
+	AWSRoleARN string
+	// AWSExternalID is the external ID the role requires, if any.
+	AWSExternalID string
+	// GCPServiceAccount, if set, is the email of the Google Cloud service
+	// account to impersonate.
+	GCPServiceAccount string
+	// Duration is how long the credentials are valid for.
+	Duration time.Duration
+}
+
+// Matches returns true if the project in repoRelDir and workspace, named
+// projectName, gets these credentials.
+func (c CloudCredential) Matches(repoRelDir string, workspace string, projectName string) bool {
+	return projectGlobsMatch(c.Dir, c.Workspace, c.Project, repoRelDir, workspace, projectName)
+}
+
+// projectGlobsMatch returns true if the project in repoRelDir and workspace,
+// named projectName, matches the dir, workspace and project globs. Empty
+// globs match everything.
+func projectGlobsMatch(dirGlob string, workspaceGlob string, projectGlob string, repoRelDir string, workspace string, projectName string) bool {
+	globMatches := func(glob string, value string) bool {
+		if glob == "" {
+			return true
+		}
+		// The glob is validated when it's parsed so there's no error.
+		matched, _ := path.Match(glob, value)
+		return matched
+	}
+	return globMatches(dirGlob, path.Clean(repoRelDir)) &&
+		globMatches(workspaceGlob, workspace) &&
+		globMatches(projectGlob, projectName)
+}
+
+// String describes the credentials for people, ex.
+// "dir: production/*, aws_role_arn: arn:aws:iam::111111111111:role/production".
+func (c CloudCredential) String() string {
+	var parts []string
+	for _, kv := range [][2]string{
+		{"dir", c.Dir},
+		{"workspace", c.Workspace},
+		{"project", c.Project},
+		{"aws_role_arn", c.AWSRoleARN},
+		{"gcp_service_account", c.GCPServiceAccount},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+": "+kv[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CloudCredential returns the credentials that the project in repoRelDir and
+// workspace, named projectName, in the repo with id repoID gets or nil if it
+// runs with the server's own credentials. The cloud_credentials of the last
+// matching repo config that sets them are used and within them, the first
+// that matches the project.
+func (g GlobalCfg) CloudCredential(repoID string, repoRelDir string, workspace string, projectName string) *CloudCredential {
+	var creds []CloudCredential
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.CloudCredentials != nil {
+			creds = repo.CloudCredentials
+		}
+	}
+	for _, c := range creds {
+		if c.Matches(repoRelDir, workspace, projectName) {
+			c := c
+			return &c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCloudCredential_Matches(t *testing.T) {
+	cases := []struct {
+		cred       valid.CloudCredential
+		dir        string
+		workspace  string
+		name       string
+		expMatches bool
+	}{
+		{valid.CloudCredential{}, "any", "default", "", true},
+		{valid.CloudCredential{Dir: "production/*"}, "production/vpc", "default", "", true},
+		{valid.CloudCredential{Dir: "production/*"}, "./production/vpc/", "default", "", true},
+		{valid.CloudCredential{Dir: "production/*"}, "production/vpc/sub", "default", "", false},
+		{valid.CloudCredential{Dir: "production/*"}, "staging/vpc", "default", "", false},
+		{valid.CloudCredential{Workspace: "prod*"}, ".", "production", "", true},
+		{valid.CloudCredential{Workspace: "prod*"}, ".", "staging", "", false},
+		{valid.CloudCredential{Project: "vpc-*"}, ".", "default", "vpc-prod", true},
+		{valid.CloudCredential{Project: "vpc-*"}, ".", "default", "", false},
+		{valid.CloudCredential{Dir: ".", Workspace: "default"}, ".", "staging", "", false},
+	}
+	for _, c := range cases {
+		Equals(t, c.expMatches, c.cred.Matches(c.dir, c.workspace, c.name))
+	}
+}
+
+func TestGlobalCfg_CloudCredential(t *testing.T) {
+	prod := valid.CloudCredential{Dir: "production", AWSRoleARN: "arn:aws:iam::111111111111:role/production"}
+	fallback := valid.CloudCredential{AWSRoleARN: "arn:aws:iam::111111111111:role/readonly"}
+	other := valid.CloudCredential{GCPServiceAccount: "atlantis@other.iam.gserviceaccount.com"}
+	globalCfg := valid.NewGlobalCfg(false, false, false)
+	globalCfg.Repos = append(globalCfg.Repos,
+		valid.Repo{IDRegex: regexp.MustCompile(".*"), CloudCredentials: []valid.CloudCredential{prod, fallback}},
+		valid.Repo{ID: "github.com/owner/other", CloudCredentials: []valid.CloudCredential{other}},
+		// Repos that don't set cloud_credentials don't override them.
+		valid.Repo{ID: "github.com/owner/repo"},
+	)
+
+	// The first matching credentials of the last repo that sets them are
+	// used.
+	Equals(t, &prod, globalCfg.CloudCredential("github.com/owner/repo", "production", "default", ""))
+	Equals(t, &fallback, globalCfg.CloudCredential("github.com/owner/repo", "staging", "default", ""))
+	Equals(t, &other, globalCfg.CloudCredential("github.com/owner/other", "production", "default", ""))
+
+	// They're set in the project's merged config.
+	Equals(t, &prod, globalCfg.DefaultProjCfg(logging.NewNoopLogger(), "github.com/owner/repo", "production", "default").CloudCredential)
+	proj := valid.Project{Dir: "staging", Workspace: "default"}
+	Equals(t, &fallback, globalCfg.MergeProjectCfg(logging.NewNoopLogger(), "github.com/owner/repo", proj, valid.RepoCfg{}).CloudCredential)
+
+	// No credentials are assumed if none match.
+	globalCfg.Repos[1].CloudCredentials = []valid.CloudCredential{prod}
+	Assert(t, globalCfg.CloudCredential("github.com/owner/repo", "staging", "default", "") == nil, "exp no credentials")
+}
@@ -0,0 +1,40 @@
+package valid
+
+import "fmt"
+
+// Concurrency limits how many Terraform operations can run at once for the
+// projects of a repo and orders them against other repos' when the server is
+// busy, from the server-side repo config.
+type Concurrency struct {
+	// MaxConcurrent is the most plans or applies that can run at once
+	// across all of the repo's projects and pull requests. 0 means there's
+	// no limit.
+	MaxConcurrent int
+	// MaxConcurrentPerProject is the most plans or applies that can run at
+	// once for each of the repo's projects across its pull requests. 0
+	// means there's no limit.
+	MaxConcurrentPerProject int
+	// Priority orders the repo's queued events and waiting operations
+	// against those of other repos. Higher priorities go first and equal
+	// priorities go in the order they arrived. Defaults to 0.
+	Priority int
+}
+
+// String describes the config like it's written in config files, ex.
+// "max_concurrent: 10, max_concurrent_per_project: 2, priority: 0".
+func (c Concurrency) String() string {
+	return fmt.Sprintf("max_concurrent: %d, max_concurrent_per_project: %d, priority: %d", c.MaxConcurrent, c.MaxConcurrentPerProject, c.Priority)
+}
+
+// Concurrency returns the concurrency config of the repo with id repoID. The
+// config of the last matching repo that sets it is used. If none do, there
+// are no limits and the priority is 0.
+func (g GlobalCfg) Concurrency(repoID string) Concurrency {
+	var concurrency Concurrency
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Concurrency != nil {
+			concurrency = *repo.Concurrency
+		}
+	}
+	return concurrency
+}
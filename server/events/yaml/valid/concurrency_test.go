@@ -0,0 +1,31 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_Concurrency(t *testing.T) {
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:     regexp.MustCompile(".*"),
+			Concurrency: &valid.Concurrency{MaxConcurrent: 20},
+		},
+		valid.Repo{
+			ID:          "github.com/owner/monorepo",
+			Concurrency: &valid.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 2, Priority: -1},
+		},
+		valid.Repo{
+			ID: "github.com/owner/monorepo",
+		},
+	)
+
+	Equals(t, valid.Concurrency{MaxConcurrent: 10, MaxConcurrentPerProject: 2, Priority: -1}, global.Concurrency("github.com/owner/monorepo"))
+	Equals(t, valid.Concurrency{MaxConcurrent: 20}, global.Concurrency("github.com/owner/other"))
+	Equals(t, valid.Concurrency{}, valid.NewGlobalCfg(false, false, false).Concurrency("github.com/owner/other"))
+	Equals(t, "max_concurrent: 10, max_concurrent_per_project: 2, priority: -1", global.Concurrency("github.com/owner/monorepo").String())
+}
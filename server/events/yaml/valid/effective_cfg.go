@@ -0,0 +1,337 @@
+package valid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultCfgSource is the source of settings that no repos config in the
+// server-side repo config file overrides, i.e. the defaults that come from
+// the server's flags.
+const DefaultCfgSource = "server defaults"
+
+// EffectiveCfg is the server-side repo config that applies to a repo after
+// the repos configs that match it are merged. It's used to debug which
+// settings a repo gets, ex. why a workflow isn't used.
+type EffectiveCfg struct {
+	RepoID string `json:"repo"`
+	// MatchingRepos are the sources of the repos configs that match the repo,
+	// in the order they're merged. Later ones override earlier ones.
+	MatchingRepos        []string         `json:"matching_repos"`
+	ApplyRequirements    EffectiveSetting `json:"apply_requirements"`
+	Workflow             EffectiveSetting `json:"workflow"`
+	AllowedOverrides     EffectiveSetting `json:"allowed_overrides"`
+	AllowCustomWorkflows EffectiveSetting `json:"allow_custom_workflows"`
+	// AllowedWorkflows is nil if projects can use any workflow.
+	AllowedWorkflows    EffectiveSetting `json:"allowed_workflows"`
+	AllowCustomRunSteps EffectiveSetting `json:"allow_custom_run_steps"`
+	ConcurrencyGroup    EffectiveSetting `json:"concurrency_group"`
+	Branch              EffectiveSetting `json:"branch"`
+	SparseCheckout      EffectiveSetting `json:"sparse_checkout"`
+	Submodules          EffectiveSetting `json:"submodules"`
+	LFS                 EffectiveSetting `json:"lfs"`
+	// CloudCredentials are the descriptions of the rules that pick the
+	// cloud identity of the repo's projects.
+	CloudCredentials EffectiveSetting `json:"cloud_credentials"`
+	// EnvVars are the descriptions of the environment variables that are
+	// set for the repo's projects. Values read from the server's
+	// environment aren't included.
+	EnvVars EffectiveSetting `json:"env"`
+	// GitCredentials are the descriptions of the credentials that private
+	// module sources are fetched with. Secrets aren't included.
+	GitCredentials EffectiveSetting `json:"git_credentials"`
+	// Approvals describes the rules of the approved apply requirement.
+	Approvals EffectiveSetting `json:"approvals"`
+	// SecurityScan describes how security_scan steps scan the repo's
+	// projects.
+	SecurityScan EffectiveSetting `json:"security_scan"`
+	// ApplyWindows describes when the repo's projects can be applied.
+	ApplyWindows EffectiveSetting `json:"apply_windows"`
+	// ApplyAfterMerge describes whether the repo's projects are applied
+	// once pull requests are merged.
+	ApplyAfterMerge EffectiveSetting `json:"apply_after_merge"`
+	// Guardrails describes what the plans of the repo's projects can
+	// change.
+	Guardrails EffectiveSetting `json:"guardrails"`
+	// Autodiscover describes how the repo's projects are found if it
+	// doesn't have an atlantis.yaml.
+	Autodiscover EffectiveSetting `json:"autodiscover"`
+	// Concurrency describes the repo's concurrency limits and priority.
+	Concurrency EffectiveSetting `json:"concurrency"`
+	// Timeouts describes how long the steps of the repo's projects can run
+	// for.
+	Timeouts EffectiveSetting `json:"timeouts"`
+	// HelpSections are the titles of the sections added to the repo's
+	// atlantis help comment.
+	HelpSections EffectiveSetting `json:"help_sections"`
+	// DefaultTerraformVersion is the version projects run with if they
+	// don't choose one, or not set if it's the server's default version.
+	DefaultTerraformVersion EffectiveSetting `json:"default_terraform_version"`
+	// TerraformDistribution is the distribution projects run with, or not
+	// set if it's the server's distribution.
+	TerraformDistribution EffectiveSetting `json:"terraform_distribution"`
+	// Tenant is the name of the tenant the repo belongs to, or not set if
+	// it doesn't belong to one.
+	Tenant EffectiveSetting `json:"tenant"`
+	// Workflows are the server-side workflows that the repo's projects can
+	// use, sorted by name. Workflows defined in the repo's atlantis.yaml
+	// aren't included since the repo isn't cloned.
+	Workflows []EffectiveWorkflow `json:"workflows"`
+}
+
+// EffectiveSetting is the value of a server-side repo config key for a repo
+// and where it was set.
+type EffectiveSetting struct {
+	Value interface{} `json:"value"`
+	// Source is where Value was set, ex. repos[1] (id: /.*/),
+	// tenants.team-a (id: /.*/) or DefaultCfgSource.
+	Source string `json:"source"`
+}
+
+// String returns the value for people, ex. "[mergeable, approved]".
+func (e EffectiveSetting) String() string {
+	switch v := e.Value.(type) {
+	case nil:
+		return "not set"
+	case string:
+		if v == "" {
+			return "not set"
+		}
+		return v
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// EffectiveWorkflow is a workflow with its steps described for people.
+type EffectiveWorkflow struct {
+	Name  string   `json:"name"`
+	Image string   `json:"image,omitempty"`
+	Plan  []string `json:"plan"`
+	Apply []string `json:"apply"`
+}
+
+// EffectiveCfg returns the config that applies to the repo with id repoID.
+func (g GlobalCfg) EffectiveCfg(repoID string) EffectiveCfg {
+	cfg := EffectiveCfg{
+		RepoID:                  repoID,
+		MatchingRepos:           []string{},
+		AllowCustomRunSteps:     EffectiveSetting{Value: true, Source: DefaultCfgSource},
+		ConcurrencyGroup:        EffectiveSetting{Value: "", Source: DefaultCfgSource},
+		Branch:                  EffectiveSetting{Source: DefaultCfgSource},
+		SparseCheckout:          EffectiveSetting{Source: DefaultCfgSource},
+		Submodules:              EffectiveSetting{Value: false, Source: DefaultCfgSource},
+		LFS:                     EffectiveSetting{Value: false, Source: DefaultCfgSource},
+		CloudCredentials:        EffectiveSetting{Source: DefaultCfgSource},
+		EnvVars:                 EffectiveSetting{Source: DefaultCfgSource},
+		GitCredentials:          EffectiveSetting{Source: DefaultCfgSource},
+		Approvals:               EffectiveSetting{Value: Approvals{Count: DefaultApprovalCount}.String(), Source: DefaultCfgSource},
+		SecurityScan:            EffectiveSetting{Value: DefaultSecurityScan.String(), Source: DefaultCfgSource},
+		ApplyWindows:            EffectiveSetting{Source: DefaultCfgSource},
+		ApplyAfterMerge:         EffectiveSetting{Source: DefaultCfgSource},
+		Guardrails:              EffectiveSetting{Source: DefaultCfgSource},
+		Autodiscover:            EffectiveSetting{Source: DefaultCfgSource},
+		Concurrency:             EffectiveSetting{Value: Concurrency{}.String(), Source: DefaultCfgSource},
+		Timeouts:                EffectiveSetting{Source: DefaultCfgSource},
+		HelpSections:            EffectiveSetting{Source: DefaultCfgSource},
+		DefaultTerraformVersion: EffectiveSetting{Value: "", Source: DefaultCfgSource},
+		TerraformDistribution:   EffectiveSetting{Value: "", Source: DefaultCfgSource},
+		AllowedWorkflows:        EffectiveSetting{Source: DefaultCfgSource},
+		Tenant:                  EffectiveSetting{Value: "", Source: DefaultCfgSource},
+	}
+	var workflow *Workflow
+	var allowedWorkflows []string
+	tenantRepos := 0
+	for i, repo := range g.Repos {
+		// The first repo is always the defaults from the server's flags,
+		// followed by the repos generated from the tenants and then the
+		// repos in the config file.
+		source := DefaultCfgSource
+		if repo.Tenant != "" {
+			source = fmt.Sprintf("tenants.%s (id: %s)", repo.Tenant, repo.IDString())
+			tenantRepos++
+		} else if i > 0 {
+			source = fmt.Sprintf("repos[%d] (id: %s)", i-1-tenantRepos, repo.IDString())
+		}
+		if !repo.IDMatches(repoID) {
+			continue
+		}
+		cfg.MatchingRepos = append(cfg.MatchingRepos, source)
+		if repo.Tenant != "" {
+			cfg.Tenant = EffectiveSetting{Value: repo.Tenant, Source: source}
+		}
+		if repo.ApplyRequirements != nil {
+			cfg.ApplyRequirements = EffectiveSetting{Value: repo.ApplyRequirements, Source: source}
+		}
+		if repo.Workflow != nil {
+			workflow = repo.Workflow
+			cfg.Workflow = EffectiveSetting{Value: repo.Workflow.Name, Source: source}
+		}
+		if repo.AllowedOverrides != nil {
+			cfg.AllowedOverrides = EffectiveSetting{Value: repo.AllowedOverrides, Source: source}
+		}
+		if repo.AllowCustomWorkflows != nil {
+			cfg.AllowCustomWorkflows = EffectiveSetting{Value: *repo.AllowCustomWorkflows, Source: source}
+		}
+		if repo.AllowedWorkflows != nil {
+			allowedWorkflows = repo.AllowedWorkflows
+			cfg.AllowedWorkflows = EffectiveSetting{Value: repo.AllowedWorkflows, Source: source}
+		}
+		if repo.AllowCustomRunSteps != nil {
+			cfg.AllowCustomRunSteps = EffectiveSetting{Value: *repo.AllowCustomRunSteps, Source: source}
+		}
+		if repo.ConcurrencyGroup != nil {
+			cfg.ConcurrencyGroup = EffectiveSetting{Value: *repo.ConcurrencyGroup, Source: source}
+		}
+		if repo.BranchRegex != nil {
+			cfg.Branch = EffectiveSetting{Value: repo.BranchRegex.String(), Source: source}
+		}
+		if len(repo.SparseCheckout) > 0 {
+			cfg.SparseCheckout = EffectiveSetting{Value: repo.SparseCheckout, Source: source}
+		}
+		if repo.Submodules != nil {
+			cfg.Submodules = EffectiveSetting{Value: *repo.Submodules, Source: source}
+		}
+		if repo.LFS != nil {
+			cfg.LFS = EffectiveSetting{Value: *repo.LFS, Source: source}
+		}
+		if repo.CloudCredentials != nil {
+			var creds []string
+			for _, c := range repo.CloudCredentials {
+				creds = append(creds, c.String())
+			}
+			cfg.CloudCredentials = EffectiveSetting{Value: creds, Source: source}
+		}
+		if repo.EnvVars != nil {
+			vars := []string{}
+			for _, e := range repo.EnvVars {
+				vars = append(vars, e.String())
+			}
+			cfg.EnvVars = EffectiveSetting{Value: vars, Source: source}
+		}
+		if repo.GitCredentials != nil {
+			creds := []string{}
+			for _, c := range repo.GitCredentials {
+				creds = append(creds, c.String())
+			}
+			cfg.GitCredentials = EffectiveSetting{Value: creds, Source: source}
+		}
+		if repo.Approvals != nil {
+			cfg.Approvals = EffectiveSetting{Value: repo.Approvals.String(), Source: source}
+		}
+		if repo.SecurityScan != nil {
+			cfg.SecurityScan = EffectiveSetting{Value: repo.SecurityScan.String(), Source: source}
+		}
+		if repo.ApplyWindows != nil {
+			cfg.ApplyWindows = EffectiveSetting{Value: repo.ApplyWindows.String(), Source: source}
+		}
+		if repo.ApplyAfterMerge != nil {
+			cfg.ApplyAfterMerge = EffectiveSetting{Value: repo.ApplyAfterMerge.String(), Source: source}
+		}
+		if repo.Guardrails != nil {
+			cfg.Guardrails = EffectiveSetting{Value: repo.Guardrails.String(), Source: source}
+		}
+		if repo.Autodiscover != nil {
+			cfg.Autodiscover = EffectiveSetting{Value: repo.Autodiscover.String(), Source: source}
+		}
+		if repo.Concurrency != nil {
+			cfg.Concurrency = EffectiveSetting{Value: repo.Concurrency.String(), Source: source}
+		}
+		if repo.Timeouts != nil {
+			cfg.Timeouts = EffectiveSetting{Value: repo.Timeouts.String(), Source: source}
+		}
+		if repo.HelpSections != nil {
+			titles := []string{}
+			for _, h := range repo.HelpSections {
+				title := h.Title
+				if title == "" {
+					title = "untitled"
+				}
+				titles = append(titles, title)
+			}
+			cfg.HelpSections = EffectiveSetting{Value: titles, Source: source}
+		}
+		if repo.DefaultTerraformVersion != nil {
+			cfg.DefaultTerraformVersion = EffectiveSetting{Value: repo.DefaultTerraformVersion.String(), Source: source}
+		}
+		if repo.TerraformDistribution != "" {
+			cfg.TerraformDistribution = EffectiveSetting{Value: repo.TerraformDistribution, Source: source}
+		}
+	}
+
+	// Projects that don't set a workflow use the repo's workflow, even if
+	// it isn't in the allowed workflows.
+	names := make(map[string]bool)
+	for name := range g.Workflows {
+		if allowedWorkflows == nil || containsString(allowedWorkflows, name) {
+			names[name] = true
+		}
+	}
+	cfg.Workflows = []EffectiveWorkflow{}
+	if workflow != nil && !names[workflow.Name] {
+		cfg.Workflows = append(cfg.Workflows, newEffectiveWorkflow(*workflow))
+	}
+	for name := range names {
+		cfg.Workflows = append(cfg.Workflows, newEffectiveWorkflow(g.Workflows[name]))
+	}
+	sort.Slice(cfg.Workflows, func(i, j int) bool {
+		return cfg.Workflows[i].Name < cfg.Workflows[j].Name
+	})
+	return cfg
+}
+
+func newEffectiveWorkflow(w Workflow) EffectiveWorkflow {
+	describe := func(stage Stage) []string {
+		steps := []string{}
+		for _, step := range stage.Steps {
+			steps = append(steps, step.String())
+		}
+		return steps
+	}
+	return EffectiveWorkflow{
+		Name:  w.Name,
+		Image: w.Image,
+		Plan:  describe(w.Plan),
+		Apply: describe(w.Apply),
+	}
+}
+
+// String describes the step like it's written in config files, ex.
+// "plan -lock=false" or "run: make plan".
+func (s Step) String() string {
+	switch s.StepName {
+	case "run":
+		return "run: " + s.RunCommand
+	case "multienv":
+		return "multienv: " + s.RunCommand
+	case "env":
+		if s.RunCommand != "" {
+			return fmt.Sprintf("env: %s=$(%s)", s.EnvVarName, s.RunCommand)
+		}
+		if s.EnvVarSecret != "" {
+			return fmt.Sprintf("env: %s=vault(%s)", s.EnvVarName, s.EnvVarSecret)
+		}
+		return fmt.Sprintf("env: %s=%s", s.EnvVarName, s.EnvVarValue)
+	case "upload_artifact":
+		return fmt.Sprintf("upload_artifact: %s (%s)", s.ArtifactName, s.ArtifactPath)
+	case "diagram":
+		if s.DiagramTool != "" {
+			return "diagram: " + s.DiagramTool
+		}
+		return "diagram"
+	default:
+		return strings.Join(append([]string{s.StepName}, s.ExtraArgs...), " ")
+	}
+}
+
+func containsString(slc []string, str string) bool {
+	for _, s := range slc {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
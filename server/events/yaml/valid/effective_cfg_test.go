@@ -0,0 +1,229 @@
+package valid_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_EffectiveCfg(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(`
+repos:
+- id: /.*/
+  apply_requirements: [approved]
+  allowed_workflows: [default, custom]
+  approvals:
+    count: 2
+    codeowners: true
+  default_terraform_version: 0.13.7
+- id: github.com/owner/repo
+  workflow: custom
+  allowed_overrides: [workflow]
+  branch: /main/
+  submodules: true
+  cloud_credentials:
+  - dir: production
+    aws_role_arn: arn:aws:iam::111111111111:role/production
+  - gcp_service_account: atlantis@staging.iam.gserviceaccount.com
+  env:
+  - name: TF_VAR_env
+    value: production
+    dir: production
+  - name: GITHUB_TOKEN
+    from_env: ATLANTIS_GH_TOKEN
+  git_credentials:
+  - host: github.com
+    ssh_key_from_env: ATLANTIS_MODULES_KEY
+  apply_windows:
+    windows:
+    - schedule: "* 9-16 * * 1-5"
+      timezone: Europe/Amsterdam
+      dir: production
+    outside: queue
+    break_glass: ["@alice", org/sre]
+  apply_after_merge:
+    notify:
+      slack: ["#infra-applies"]
+      email: [infra@example.com]
+  guardrails:
+    rules:
+    - max_deletes: 0
+      deny: [aws_route53_zone.*]
+    override: [org/sre]
+  autodiscover:
+    include: [live/**]
+  concurrency:
+    max_concurrent: 10
+    priority: -1
+  timeouts:
+    plan: 30m
+    apply: 1h
+  help_sections:
+  - title: Escalation
+    text: "Ask in #platform."
+  - text: See the runbook.
+- id: github.com/owner/other
+  apply_requirements: [mergeable]
+workflows:
+  custom:
+    plan:
+      steps:
+      - init
+      - plan:
+          extra_args: [-lock=false]
+      - run: echo hi
+      - env:
+          name: NAME
+          command: echo value
+    apply:
+      steps: [apply]
+  unused:
+    plan:
+      steps: [plan]
+`), 0600))
+	globalCfg, err := (&yaml.ParserValidator{}).ParseGlobalCfg(path, valid.NewGlobalCfg(false, true, false))
+	Ok(t, err)
+
+	Equals(t, valid.EffectiveCfg{
+		RepoID:               "github.com/owner/repo",
+		MatchingRepos:        []string{"server defaults", "repos[0] (id: /.*/)", "repos[1] (id: github.com/owner/repo)"},
+		ApplyRequirements:    valid.EffectiveSetting{Value: []string{"approved"}, Source: "repos[0] (id: /.*/)"},
+		Workflow:             valid.EffectiveSetting{Value: "custom", Source: "repos[1] (id: github.com/owner/repo)"},
+		AllowedOverrides:     valid.EffectiveSetting{Value: []string{"workflow"}, Source: "repos[1] (id: github.com/owner/repo)"},
+		AllowCustomWorkflows: valid.EffectiveSetting{Value: false, Source: "server defaults"},
+		AllowedWorkflows:     valid.EffectiveSetting{Value: []string{"default", "custom"}, Source: "repos[0] (id: /.*/)"},
+		AllowCustomRunSteps:  valid.EffectiveSetting{Value: true, Source: "server defaults"},
+		ConcurrencyGroup:     valid.EffectiveSetting{Value: "", Source: "server defaults"},
+		Branch:               valid.EffectiveSetting{Value: "main", Source: "repos[1] (id: github.com/owner/repo)"},
+		SparseCheckout:       valid.EffectiveSetting{Source: "server defaults"},
+		Submodules:           valid.EffectiveSetting{Value: true, Source: "repos[1] (id: github.com/owner/repo)"},
+		LFS:                  valid.EffectiveSetting{Value: false, Source: "server defaults"},
+		CloudCredentials: valid.EffectiveSetting{
+			Value: []string{
+				"dir: production, aws_role_arn: arn:aws:iam::111111111111:role/production",
+				"gcp_service_account: atlantis@staging.iam.gserviceaccount.com",
+			},
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		EnvVars: valid.EffectiveSetting{
+			Value: []string{
+				"TF_VAR_env=production, dir: production",
+				"GITHUB_TOKEN=$ATLANTIS_GH_TOKEN",
+			},
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		GitCredentials: valid.EffectiveSetting{
+			Value:  []string{"github.com: ssh key $ATLANTIS_MODULES_KEY"},
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		Approvals: valid.EffectiveSetting{
+			Value:  "count: 2, codeowners: true",
+			Source: "repos[0] (id: /.*/)",
+		},
+		SecurityScan: valid.EffectiveSetting{Value: "tool: tfsec, target: dir", Source: "server defaults"},
+		ApplyWindows: valid.EffectiveSetting{
+			Value:  "windows: [* 9-16 * * 1-5 Europe/Amsterdam, dir: production], outside: queue, break_glass: [alice, org/sre]",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		ApplyAfterMerge: valid.EffectiveSetting{
+			Value:  "enabled: true, notify: [#infra-applies, infra@example.com]",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		Guardrails: valid.EffectiveSetting{
+			Value:  "rules: [max_deletes: 0, deny: [aws_route53_zone.*]], override: [org/sre]",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		Autodiscover: valid.EffectiveSetting{
+			Value:  "include: [live/**], exclude: [**/modules], project_markers: [*.tf, terragrunt.hcl]",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		Concurrency: valid.EffectiveSetting{
+			Value:  "max_concurrent: 10, max_concurrent_per_project: 0, priority: -1",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		Timeouts: valid.EffectiveSetting{
+			Value:  "plan: 30m0s, apply: 1h0m0s",
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		HelpSections: valid.EffectiveSetting{
+			Value:  []string{"Escalation", "untitled"},
+			Source: "repos[1] (id: github.com/owner/repo)",
+		},
+		DefaultTerraformVersion: valid.EffectiveSetting{
+			Value:  "0.13.7",
+			Source: "repos[0] (id: /.*/)",
+		},
+		TerraformDistribution: valid.EffectiveSetting{Value: "", Source: "server defaults"},
+		Tenant:                valid.EffectiveSetting{Value: "", Source: "server defaults"},
+		Workflows: []valid.EffectiveWorkflow{
+			{
+				Name:  "custom",
+				Plan:  []string{"init", "plan -lock=false", "run: echo hi", "env: NAME=$(echo value)"},
+				Apply: []string{"apply"},
+			},
+			{
+				Name:  "default",
+				Plan:  []string{"init", "plan"},
+				Apply: []string{"apply"},
+			},
+		},
+	}, globalCfg.EffectiveCfg("github.com/owner/repo"))
+}
+
+func TestGlobalCfg_EffectiveCfgDefaults(t *testing.T) {
+	cfg := valid.NewGlobalCfg(true, true, false).EffectiveCfg("github.com/owner/repo")
+	Equals(t, []string{"server defaults"}, cfg.MatchingRepos)
+	Equals(t, "[mergeable]", cfg.ApplyRequirements.String())
+	Equals(t, "[apply_requirements, workflow, concurrency_group]", cfg.AllowedOverrides.String())
+	Equals(t, "true", cfg.AllowCustomWorkflows.String())
+	Equals(t, "not set", cfg.AllowedWorkflows.String())
+	Equals(t, "not set", cfg.ConcurrencyGroup.String())
+	Equals(t, "not set", cfg.EnvVars.String())
+	Equals(t, "not set", cfg.GitCredentials.String())
+	Equals(t, "not set", cfg.DefaultTerraformVersion.String())
+	Equals(t, "not set", cfg.TerraformDistribution.String())
+	Equals(t, "not set", cfg.Tenant.String())
+	Equals(t, 1, len(cfg.Workflows))
+}
+
+// Repos configs are numbered as they are in the config file even though the
+// tenants' repo configs come before them.
+func TestGlobalCfg_EffectiveCfgTenants(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmp, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(`
+tenants:
+  team-a:
+    repos: [/github.com\/team-a\/.*/]
+    workflow: team-a
+repos:
+- id: /.*/
+  apply_requirements: [approved]
+- id: github.com/team-a/special
+  workflow: default
+workflows:
+  team-a:
+    plan:
+      steps: [init, plan]
+`), 0600))
+	globalCfg, err := (&yaml.ParserValidator{}).ParseGlobalCfg(path, valid.NewGlobalCfg(false, true, false))
+	Ok(t, err)
+
+	cfg := globalCfg.EffectiveCfg("github.com/team-a/infra")
+	Equals(t, []string{"server defaults", "tenants.team-a (id: /github.com\\/team-a\\/.*/)", "repos[0] (id: /.*/)"}, cfg.MatchingRepos)
+	Equals(t, valid.EffectiveSetting{Value: "team-a", Source: "tenants.team-a (id: /github.com\\/team-a\\/.*/)"}, cfg.Tenant)
+	Equals(t, valid.EffectiveSetting{Value: "team-a", Source: "tenants.team-a (id: /github.com\\/team-a\\/.*/)"}, cfg.Workflow)
+	Equals(t, "repos[0] (id: /.*/)", cfg.ApplyRequirements.Source)
+
+	cfg = globalCfg.EffectiveCfg("github.com/team-a/special")
+	Equals(t, valid.EffectiveSetting{Value: "default", Source: "repos[1] (id: github.com/team-a/special)"}, cfg.Workflow)
+	Equals(t, "team-a", cfg.Tenant.String())
+}
@@ -0,0 +1,75 @@
+package valid
+
+import (
+	"strings"
+)
+
+// EnvVar is an environment variable that's set for every step of the
+// projects it matches, from the server-side repo config.
+type EnvVar struct {
+	Name string
+	// Value is the variable's value if FromEnv isn't set.
+	Value string
+	// FromEnv, if set, is the name of the environment variable of the
+	// Atlantis process that the value is read from when the steps are run.
+	FromEnv string
+	// Dir, Workspace and Project are globs, ex. production/*, that the
+	// project's dir, workspace and name must match. Empty globs match
+	// everything.
+	Dir       string
+	Workspace string
+	Project   string
+}
+
+// Matches returns true if the project in repoRelDir and workspace, named
+// projectName, gets this variable.
+func (e EnvVar) Matches(repoRelDir string, workspace string, projectName string) bool {
+	return projectGlobsMatch(e.Dir, e.Workspace, e.Project, repoRelDir, workspace, projectName)
+}
+
+// String describes the variable for people, ex. "TF_VAR_env=production" or
+// "GITHUB_TOKEN=$ATLANTIS_GH_TOKEN, dir: production/*".
+func (e EnvVar) String() string {
+	parts := []string{e.Name + "=" + e.Value}
+	if e.FromEnv != "" {
+		parts[0] = e.Name + "=$" + e.FromEnv
+	}
+	for _, kv := range [][2]string{
+		{"dir", e.Dir},
+		{"workspace", e.Workspace},
+		{"project", e.Project},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+": "+kv[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// EnvVars returns the variables that are set for the steps of the project in
+// repoRelDir and workspace, named projectName, in the repo with id repoID.
+// The env of the last matching repo config that sets it is used and within
+// it, every variable that matches the project. If a name is matched more than
+// once, the last one wins.
+func (g GlobalCfg) EnvVars(repoID string, repoRelDir string, workspace string, projectName string) []EnvVar {
+	var vars []EnvVar
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.EnvVars != nil {
+			vars = repo.EnvVars
+		}
+	}
+	var matched []EnvVar
+	indexes := make(map[string]int)
+	for _, e := range vars {
+		if !e.Matches(repoRelDir, workspace, projectName) {
+			continue
+		}
+		if i, ok := indexes[e.Name]; ok {
+			matched[i] = e
+			continue
+		}
+		indexes[e.Name] = len(matched)
+		matched = append(matched, e)
+	}
+	return matched
+}
@@ -0,0 +1,45 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestEnvVar_String(t *testing.T) {
+	Equals(t, "TF_VAR_env=production", valid.EnvVar{Name: "TF_VAR_env", Value: "production"}.String())
+	Equals(t, "TOKEN=$ATLANTIS_TOKEN, dir: production/*, workspace: default",
+		valid.EnvVar{Name: "TOKEN", FromEnv: "ATLANTIS_TOKEN", Dir: "production/*", Workspace: "default"}.String())
+}
+
+func TestGlobalCfg_EnvVars(t *testing.T) {
+	region := valid.EnvVar{Name: "AWS_REGION", Value: "us-east-1"}
+	prodRegion := valid.EnvVar{Name: "AWS_REGION", Value: "eu-west-1", Dir: "production"}
+	token := valid.EnvVar{Name: "TOKEN", FromEnv: "ATLANTIS_TOKEN", Project: "vpc-*"}
+	other := valid.EnvVar{Name: "OTHER", Value: "other"}
+	globalCfg := valid.NewGlobalCfg(false, false, false)
+	globalCfg.Repos = append(globalCfg.Repos,
+		valid.Repo{IDRegex: regexp.MustCompile(".*"), EnvVars: []valid.EnvVar{region, token, prodRegion}},
+		valid.Repo{ID: "github.com/owner/other", EnvVars: []valid.EnvVar{other}},
+		// Repos that don't set env don't override it.
+		valid.Repo{ID: "github.com/owner/repo"},
+	)
+
+	// Every matching variable of the last repo that sets env is used and
+	// later ones override earlier ones with the same name.
+	Equals(t, []valid.EnvVar{region}, globalCfg.EnvVars("github.com/owner/repo", "staging", "default", ""))
+	Equals(t, []valid.EnvVar{prodRegion, token}, globalCfg.EnvVars("github.com/owner/repo", "production", "default", "vpc-prod"))
+	Equals(t, []valid.EnvVar{other}, globalCfg.EnvVars("github.com/owner/other", "production", "default", ""))
+
+	// They're set in the project's merged config.
+	Equals(t, []valid.EnvVar{prodRegion}, globalCfg.DefaultProjCfg(logging.NewNoopLogger(), "github.com/owner/repo", "production", "default").EnvVars)
+	proj := valid.Project{Dir: "staging", Workspace: "default", Name: String("vpc-staging")}
+	Equals(t, []valid.EnvVar{region, token}, globalCfg.MergeProjectCfg(logging.NewNoopLogger(), "github.com/owner/repo", proj, valid.RepoCfg{}).EnvVars)
+
+	// An empty env unsets them.
+	globalCfg.Repos[3].EnvVars = []valid.EnvVar{}
+	Equals(t, 0, len(globalCfg.EnvVars("github.com/owner/repo", "production", "default", "")))
+}
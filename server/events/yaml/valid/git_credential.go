@@ -0,0 +1,92 @@
+package valid
+
+import (
+	"strings"
+)
+
+// DefaultGitCredentialUsername is the username that tokens are sent with if
+// username isn't set. GitHub and GitLab accept tokens with any username.
+const DefaultGitCredentialUsername = "x-access-token"
+
+// GitCredential is the SSH key or HTTPS token that Terraform uses to fetch
+// private module sources from Host when running the steps of the projects
+// it matches, from the server-side repo config.
+type GitCredential struct {
+	// Host is the hostname of the module sources, ex. github.com.
+	Host string
+	// SSHKeyFromEnv, if set, is the name of the environment variable of the
+	// Atlantis process that the private SSH key is read from.
+	SSHKeyFromEnv string
+	// SSHKeyFile, if set, is the path of the private SSH key.
+	SSHKeyFile string
+	// TokenFromEnv, if set, is the name of the environment variable of the
+	// Atlantis process that the HTTPS token is read from.
+	TokenFromEnv string
+	// Username is the username the token is sent with.
+	Username string
+	// Dir, Workspace and Project are globs, ex. production/*, that the
+	// project's dir, workspace and name must match. Empty globs match
+	// everything.
+	Dir       string
+	Workspace string
+	Project   string
+}
+
+// Matches returns true if the project in repoRelDir and workspace, named
+// projectName, gets this credential.
+func (g GitCredential) Matches(repoRelDir string, workspace string, projectName string) bool {
+	return projectGlobsMatch(g.Dir, g.Workspace, g.Project, repoRelDir, workspace, projectName)
+}
+
+// String describes the credential for people without its secret, ex.
+// "github.com: ssh key $ATLANTIS_MODULES_KEY, dir: production/*".
+func (g GitCredential) String() string {
+	var source string
+	switch {
+	case g.SSHKeyFromEnv != "":
+		source = "ssh key $" + g.SSHKeyFromEnv
+	case g.SSHKeyFile != "":
+		source = "ssh key " + g.SSHKeyFile
+	default:
+		source = "token $" + g.TokenFromEnv
+	}
+	parts := []string{g.Host + ": " + source}
+	for _, kv := range [][2]string{
+		{"dir", g.Dir},
+		{"workspace", g.Workspace},
+		{"project", g.Project},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+": "+kv[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GitCredentials returns the credentials that the steps of the project in
+// repoRelDir and workspace, named projectName, in the repo with id repoID
+// are run with. The git_credentials of the last matching repo config that
+// sets them are used and within them, every one that matches the project.
+// If a host is matched more than once, the last one wins.
+func (g GlobalCfg) GitCredentials(repoID string, repoRelDir string, workspace string, projectName string) []GitCredential {
+	var creds []GitCredential
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.GitCredentials != nil {
+			creds = repo.GitCredentials
+		}
+	}
+	var matched []GitCredential
+	indexes := make(map[string]int)
+	for _, c := range creds {
+		if !c.Matches(repoRelDir, workspace, projectName) {
+			continue
+		}
+		if i, ok := indexes[c.Host]; ok {
+			matched[i] = c
+			continue
+		}
+		indexes[c.Host] = len(matched)
+		matched = append(matched, c)
+	}
+	return matched
+}
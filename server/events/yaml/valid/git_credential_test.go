@@ -0,0 +1,46 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGitCredential_String(t *testing.T) {
+	Equals(t, "github.com: ssh key $MODULES_KEY", valid.GitCredential{Host: "github.com", SSHKeyFromEnv: "MODULES_KEY"}.String())
+	Equals(t, "github.com: ssh key /keys/id_rsa, workspace: default", valid.GitCredential{Host: "github.com", SSHKeyFile: "/keys/id_rsa", Workspace: "default"}.String())
+	Equals(t, "gitlab.com: token $GITLAB_TOKEN, dir: production/*, project: vpc-*",
+		valid.GitCredential{Host: "gitlab.com", TokenFromEnv: "GITLAB_TOKEN", Username: "oauth2", Dir: "production/*", Project: "vpc-*"}.String())
+}
+
+func TestGlobalCfg_GitCredentials(t *testing.T) {
+	github := valid.GitCredential{Host: "github.com", SSHKeyFromEnv: "KEY"}
+	prodGithub := valid.GitCredential{Host: "github.com", SSHKeyFromEnv: "PROD_KEY", Dir: "production"}
+	gitlab := valid.GitCredential{Host: "gitlab.com", TokenFromEnv: "TOKEN", Username: "oauth2", Project: "vpc-*"}
+	other := valid.GitCredential{Host: "github.com", SSHKeyFile: "/keys/other"}
+	globalCfg := valid.NewGlobalCfg(false, false, false)
+	globalCfg.Repos = append(globalCfg.Repos,
+		valid.Repo{IDRegex: regexp.MustCompile(".*"), GitCredentials: []valid.GitCredential{github, gitlab, prodGithub}},
+		valid.Repo{ID: "github.com/owner/other", GitCredentials: []valid.GitCredential{other}},
+		// Repos that don't set git_credentials don't override them.
+		valid.Repo{ID: "github.com/owner/repo"},
+	)
+
+	// Every matching credential of the last repo that sets them is used and
+	// later ones override earlier ones for the same host.
+	Equals(t, []valid.GitCredential{github}, globalCfg.GitCredentials("github.com/owner/repo", "staging", "default", ""))
+	Equals(t, []valid.GitCredential{prodGithub, gitlab}, globalCfg.GitCredentials("github.com/owner/repo", "production", "default", "vpc-prod"))
+	Equals(t, []valid.GitCredential{other}, globalCfg.GitCredentials("github.com/owner/other", "production", "default", ""))
+
+	// They're set in the project's merged config.
+	Equals(t, []valid.GitCredential{prodGithub}, globalCfg.DefaultProjCfg(logging.NewNoopLogger(), "github.com/owner/repo", "production", "default").GitCredentials)
+	proj := valid.Project{Dir: "staging", Workspace: "default", Name: String("vpc-staging")}
+	Equals(t, []valid.GitCredential{github, gitlab}, globalCfg.MergeProjectCfg(logging.NewNoopLogger(), "github.com/owner/repo", proj, valid.RepoCfg{}).GitCredentials)
+
+	// Empty git_credentials unset them.
+	globalCfg.Repos[3].GitCredentials = []valid.GitCredential{}
+	Equals(t, 0, len(globalCfg.GitCredentials("github.com/owner/repo", "production", "default", "")))
+}
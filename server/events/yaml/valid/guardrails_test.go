@@ -0,0 +1,60 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAddressPatternMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		address string
+		exp     bool
+	}{
+		{"aws_route53_zone.*", "aws_route53_zone.main", true},
+		{"aws_route53_zone.*", `aws_route53_zone.zones["example.com"]`, true},
+		{"aws_route53_zone.*", "module.dns.aws_route53_zone.main", false},
+		{"*.aws_route53_zone.*", "module.dns.aws_route53_zone.main", true},
+		{"aws_route53_zone.main", "aws_route53_zone.main", true},
+		{"aws_route53_zone.main", "aws_route53_zone.main2", false},
+		{"aws_db_instance.prod[0]", "aws_db_instance.prod[0]", true},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern+" "+c.address, func(t *testing.T) {
+			Equals(t, c.exp, valid.AddressPatternMatches(c.pattern, c.address))
+		})
+	}
+}
+
+func TestGlobalCfg_Guardrails(t *testing.T) {
+	noDeletes := valid.GuardrailRule{MaxDeletes: Int(0), Dir: "production/*"}
+	noZones := valid.GuardrailRule{Deny: []string{"aws_route53_zone.*"}}
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:    regexp.MustCompile(".*"),
+			Guardrails: &valid.Guardrails{Rules: []valid.GuardrailRule{noZones}},
+		},
+		valid.Repo{
+			ID:         "github.com/owner/repo",
+			Guardrails: &valid.Guardrails{Rules: []valid.GuardrailRule{noDeletes}, Override: []string{"org/sre"}},
+		},
+	)
+
+	Equals(t, (*valid.Guardrails)(nil), global.Guardrails("github.com/owner/repo", "staging", "default", ""))
+	Equals(t, &valid.Guardrails{Rules: []valid.GuardrailRule{noDeletes}, Override: []string{"org/sre"}}, global.Guardrails("github.com/owner/repo", "production/eu", "default", ""))
+	Equals(t, &valid.Guardrails{Rules: []valid.GuardrailRule{noZones}}, global.Guardrails("github.com/owner/other", "staging", "default", ""))
+}
+
+func TestGuardrails_String(t *testing.T) {
+	Equals(t, "rules: [max_deletes: 0, dir: production/*; deny: [aws_route53_zone.*]], override: [org/sre]", valid.Guardrails{
+		Rules: []valid.GuardrailRule{
+			{MaxDeletes: Int(0), Dir: "production/*"},
+			{Deny: []string{"aws_route53_zone.*"}},
+		},
+		Override: []string{"org/sre"},
+	}.String())
+}
@@ -0,0 +1,23 @@
+package valid
+
+// HelpSection is a section that's added to the atlantis help comment of
+// matching repos, ex. who to contact when a plan fails, from the server-side
+// repo config.
+type HelpSection struct {
+	// Title is the section's heading. It's optional.
+	Title string
+	// Text is the section's markdown.
+	Text string
+}
+
+// HelpSections returns the help sections of the repo with id repoID. The
+// sections of the last matching repo that sets them are used.
+func (g GlobalCfg) HelpSections(repoID string) []HelpSection {
+	var sections []HelpSection
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.HelpSections != nil {
+			sections = repo.HelpSections
+		}
+	}
+	return sections
+}
@@ -0,0 +1,30 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_HelpSections(t *testing.T) {
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:      regexp.MustCompile(".*"),
+			HelpSections: []valid.HelpSection{{Title: "Support", Text: "Ask in #atlantis."}},
+		},
+		valid.Repo{
+			ID:           "github.com/owner/payments",
+			HelpSections: []valid.HelpSection{{Title: "Escalation", Text: "Page the payments on-call."}},
+		},
+		valid.Repo{
+			ID: "github.com/owner/payments",
+		},
+	)
+
+	Equals(t, []valid.HelpSection{{Title: "Escalation", Text: "Page the payments on-call."}}, global.HelpSections("github.com/owner/payments"))
+	Equals(t, []valid.HelpSection{{Title: "Support", Text: "Ask in #atlantis."}}, global.HelpSections("github.com/owner/other"))
+	Equals(t, []valid.HelpSection(nil), valid.NewGlobalCfg(false, false, false).HelpSections("github.com/owner/other"))
+}
@@ -0,0 +1,51 @@
+package valid
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultProviderRetryMaxAttempts is the number of times a step is attempted
+// if its output matches a throttling pattern and max_attempts isn't set.
+const DefaultProviderRetryMaxAttempts = 3
+
+// DefaultProviderRetryBackoff is the initial wait before retrying a throttled
+// step if backoff isn't set.
+const DefaultProviderRetryBackoff = 10 * time.Second
+
+// ProviderRetry describes when and how to retry a Terraform step that failed
+// because a provider's API throttled us.
+type ProviderRetry struct {
+	// Pattern is matched against the output of the failed step.
+	Pattern *regexp.Regexp
+	// MaxAttempts is the total number of times the step will be run,
+	// including the first attempt.
+	MaxAttempts int
+	// Backoff is the initial wait before retrying. It doubles on each
+	// subsequent retry and has jitter applied.
+	Backoff time.Duration
+}
+
+// DefaultProviderRetries are the rules used if the server-side config doesn't
+// define provider_retries. They match the throttling errors returned by the
+// most common providers.
+var DefaultProviderRetries = []ProviderRetry{
+	{
+		// AWS.
+		Pattern:     regexp.MustCompile(`Throttling: Rate exceeded|RequestLimitExceeded|ThrottlingException|TooManyRequestsException`),
+		MaxAttempts: DefaultProviderRetryMaxAttempts,
+		Backoff:     DefaultProviderRetryBackoff,
+	},
+	{
+		// Azure.
+		Pattern:     regexp.MustCompile(`StatusCode=429|TooManyRequests`),
+		MaxAttempts: DefaultProviderRetryMaxAttempts,
+		Backoff:     DefaultProviderRetryBackoff,
+	},
+	{
+		// Google Cloud.
+		Pattern:     regexp.MustCompile(`rateLimitExceeded|userRateLimitExceeded|Error 429`),
+		MaxAttempts: DefaultProviderRetryMaxAttempts,
+		Backoff:     DefaultProviderRetryBackoff,
+	},
+}
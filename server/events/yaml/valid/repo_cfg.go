@@ -2,7 +2,23 @@
 // after it's been parsed and validated.
 package valid
 
-import version "github.com/hashicorp/go-version"
+import (
+	"time"
+
+	version "github.com/hashicorp/go-version"
+)
+
+const (
+	// SilenceNoChangesOff posts the results of plans without changes like
+	// those of any other plan.
+	SilenceNoChangesOff = "off"
+	// SilenceNoChangesHide doesn't post the results or commit statuses of
+	// plans without changes.
+	SilenceNoChangesHide = "hide"
+	// SilenceNoChangesSummarize lists the projects whose plans have no
+	// changes on a single line instead of posting their results.
+	SilenceNoChangesSummarize = "summarize"
+)
 
 // RepoCfg is the atlantis.yaml config after it's been parsed and validated.
 type RepoCfg struct {
@@ -11,15 +27,32 @@ type RepoCfg struct {
 	Projects  []Project
 	Workflows map[string]Workflow
 	Automerge bool
+	// SilenceNoChanges is how the results of plans without changes are
+	// posted, one of the SilenceNoChanges* constants. If empty, the server's
+	// setting is used.
+	SilenceNoChanges string
 }
 
+// FindProjectsByDirWorkspace returns all projects in repoRelDir and workspace.
+// If none are configured with workspace, projects in repoRelDir that discover
+// their workspaces are returned with their Workspace set to workspace.
 func (r RepoCfg) FindProjectsByDirWorkspace(repoRelDir string, workspace string) []Project {
 	var ps []Project
+	var discovered []Project
 	for _, p := range r.Projects {
-		if p.Dir == repoRelDir && p.Workspace == workspace {
+		if p.Dir != repoRelDir {
+			continue
+		}
+		if p.Workspace == workspace {
 			ps = append(ps, p)
+		} else if p.DiscoverWorkspaces {
+			p.Workspace = workspace
+			discovered = append(discovered, p)
 		}
 	}
+	if len(ps) == 0 {
+		return discovered
+	}
 	return ps
 }
 
@@ -51,6 +84,27 @@ type Project struct {
 	TerraformVersion  *version.Version
 	Autoplan          Autoplan
 	ApplyRequirements []string
+	ConcurrencyGroup  *string
+	// Owners are the VCS users or teams that own this project. They're
+	// suggested as reviewers when a pull request modifies it.
+	Owners []string
+	// Notify is where this project's events are sent.
+	Notify Notify
+	// PlanOnly is true if Atlantis should only plan this project and refuse
+	// to apply it, ex. because another system owns its applies.
+	PlanOnly bool
+	// TerraformCloud, if set, means this project is planned and applied in a
+	// Terraform Cloud workspace instead of by running its workflow.
+	TerraformCloud *TerraformCloud
+	// WorkspaceVarFile is true if the project's <workspace>.tfvars file must
+	// exist and is passed to Terraform with -var-file.
+	WorkspaceVarFile bool
+	// DiscoverWorkspaces is true if the project is planned in each of its
+	// existing Terraform workspaces instead of only in Workspace.
+	DiscoverWorkspaces bool
+	// VarFiles are the .tfvars files, relative to Dir, that can be selected
+	// with atlantis plan --var-file.
+	VarFiles []string
 }
 
 // GetName returns the name of the project or an empty string if there is no
@@ -62,6 +116,30 @@ func (p Project) GetName() string {
 	return ""
 }
 
+// TerraformCloud selects the Terraform Cloud workspace a project is planned
+// and applied in.
+type TerraformCloud struct {
+	Organization string
+	// Tags are the tags a workspace must have to be selected.
+	Tags []string
+}
+
+// Notify is where a project's events are sent in addition to the server's
+// unnamed webhooks.
+type Notify struct {
+	// SlackChannels are Slack channel names without the leading #.
+	SlackChannels []string
+	// Webhooks are names of webhooks in the server config.
+	Webhooks []string
+	// Emails are email addresses.
+	Emails []string
+}
+
+// IsEmpty returns true if there are no notification targets.
+func (n Notify) IsEmpty() bool {
+	return len(n.SlackChannels) == 0 && len(n.Webhooks) == 0 && len(n.Emails) == 0
+}
+
 type Autoplan struct {
 	WhenModified []string
 	Enabled      bool
@@ -74,18 +152,59 @@ type Stage struct {
 type Step struct {
 	StepName  string
 	ExtraArgs []string
-	// RunCommand is either a custom run step or the command to run
-	// during an env step to populate the environment variable dynamically.
+	// RunCommand is either a custom run step, the command to run during an
+	// env step to populate the environment variable dynamically or the
+	// command a multienv step runs to print its environment variables.
 	RunCommand string
 	// EnvVarName is the name of the
 	// environment variable that should be set by this step.
 	EnvVarName string
 	// EnvVarValue is the value to set EnvVarName to.
 	EnvVarValue string
+	// EnvVarSecret is the secret to set EnvVarName to, ex.
+	// secret/data/db#password. It's fetched from the secrets provider when
+	// the step runs.
+	EnvVarSecret string
+	// ArtifactName is the name of the artifact uploaded by an upload_artifact
+	// step.
+	ArtifactName string
+	// ArtifactPath is the path, relative to the project's dir, of the file
+	// uploaded by an upload_artifact step.
+	ArtifactPath string
+	// DiagramTool is the tool a diagram step generates its diagram with. It's
+	// empty for the default, terraform.
+	DiagramTool string
+	// Timeout is how long an init, plan, apply or run step can run for. 0
+	// means the step uses the timeout of its repo or the server.
+	Timeout time.Duration
 }
 
 type Workflow struct {
 	Name  string
 	Apply Stage
 	Plan  Stage
+	// Image is the container image the workflow's steps run in. If empty,
+	// they run wherever the execution backend runs commands.
+	Image string
+	// StateLockRetry, if set, retries the workflow's init, plan and apply
+	// steps when another run holds the Terraform state lock. If nil, they
+	// fail on the first attempt.
+	StateLockRetry *StateLockRetry
+}
+
+// RunsCommands returns true if any of the workflow's steps run arbitrary
+// commands, i.e. run steps or env steps with a command, or if the workflow
+// runs its steps in a container image, since the image can run anything.
+func (w Workflow) RunsCommands() bool {
+	if w.Image != "" {
+		return true
+	}
+	for _, stage := range []Stage{w.Plan, w.Apply} {
+		for _, step := range stage.Steps {
+			if step.RunCommand != "" {
+				return true
+			}
+		}
+	}
+	return false
 }
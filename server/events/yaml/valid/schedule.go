@@ -0,0 +1,166 @@
+package valid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxScheduleSearch is how far ahead Schedule.Next looks for a matching
+// minute. Schedules that match less than once a leap year, ex. only on
+// February 29th that's a Monday, are treated as never matching.
+const maxScheduleSearch = 366 * 24 * time.Hour
+
+// Schedule is a cron schedule of the form "minute hour day-of-month month
+// day-of-week", ex. "* 9-16 * * 1-5" for every minute from 09:00 to 16:59
+// on weekdays. Each field is *, a number, a range a-b or a comma-separated
+// list of them, each optionally followed by a step, ex. */15. Days of the
+// week are 0-6 starting on Sunday, or 7 for Sunday. Like cron, if both the
+// day of the month and the day of the week are restricted, a time matches if
+// either does.
+type Schedule struct {
+	expr     string
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// anyDay and anyWeekday are true if the day of the month or week
+	// fields are *.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// scheduleField is the name and range of a field of a schedule.
+type scheduleField struct {
+	name string
+	min  int
+	max  int
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseSchedule parses expr, a cron schedule.
+func ParseSchedule(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(scheduleFields) {
+		return Schedule{}, fmt.Errorf("%q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseScheduleField(field, scheduleFields[i])
+		if err != nil {
+			return Schedule{}, err
+		}
+	}
+	// 7 is another way to write Sunday.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return Schedule{
+		expr:       strings.Join(fields, " "),
+		minutes:    bits[0],
+		hours:      bits[1],
+		days:       bits[2],
+		months:     bits[3],
+		weekdays:   bits[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// parseScheduleField returns the values field matches as a bit set.
+func parseScheduleField(field string, f scheduleField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, part)
+			}
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, part)
+				}
+			} else if step > 1 {
+				// Like cron, a/step means from a to the max.
+				hi = f.max
+			}
+			if lo < f.min || hi > f.max || lo > hi {
+				return 0, fmt.Errorf("%s %q is out of range %d-%d", f.name, part, f.min, f.max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches returns true if the minute of t matches the schedule. t is matched
+// in its own location.
+func (s Schedule) Matches(t time.Time) bool {
+	return s.minutes&(1<<uint(t.Minute())) != 0 &&
+		s.hours&(1<<uint(t.Hour())) != 0 &&
+		s.months&(1<<uint(t.Month())) != 0 &&
+		s.dayMatches(t)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the start of the first minute at or after t that matches the
+// schedule. It returns false if there's none in the next year.
+func (s Schedule) Next(t time.Time) (time.Time, bool) {
+	end := t.Add(maxScheduleSearch)
+	next := t.Truncate(time.Minute)
+	if next.Before(t) {
+		next = next.Add(time.Minute)
+	}
+	for next.Before(end) {
+		switch {
+		case s.months&(1<<uint(next.Month())) == 0 || !s.dayMatches(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case s.hours&(1<<uint(next.Hour())) == 0:
+			// Truncate isn't used since it ignores the location and some
+			// locations are offset by half hours.
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case s.minutes&(1<<uint(next.Minute())) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// String returns the schedule's expression.
+func (s Schedule) String() string {
+	return s.expr
+}
@@ -0,0 +1,142 @@
+package valid_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseSchedule_Errors(t *testing.T) {
+	cases := []struct {
+		expr   string
+		expErr string
+	}{
+		{"", `"" must have 5 fields: minute hour day-of-month month day-of-week`},
+		{"* * * * * *", `"* * * * * *" must have 5 fields: minute hour day-of-month month day-of-week`},
+		{"*/0 * * * *", `invalid step in minute "*/0"`},
+		{"*/x * * * *", `invalid step in minute "*/x"`},
+		{"* nine * * *", `invalid hour "nine"`},
+		{"* 9-x * * *", `invalid hour "9-x"`},
+		{"* 24 * * *", `hour "24" is out of range 0-23`},
+		{"* * 0 * *", `day of month "0" is out of range 1-31`},
+		{"* * * 12-1 *", `month "12-1" is out of range 1-12`},
+		{"* * * * 8", `day of week "8" is out of range 0-7`},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			_, err := valid.ParseSchedule(c.expr)
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	// 2020-06-01 is a Monday.
+	monday := time.Date(2020, 6, 1, 9, 30, 0, 0, time.UTC)
+	cases := []struct {
+		expr string
+		t    time.Time
+		exp  bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 9 * * *", monday, true},
+		{"31 9 * * *", monday, false},
+		{"*/15 9-16 * * 1-5", monday, true},
+		{"*/15 9-16 * * 1-5", monday.Add(time.Minute), false},
+		{"* 9-16 * * 1-5", monday.Add(-24 * time.Hour), false},
+		{"0,30 9,17 * * *", monday, true},
+		{"* 10/2 * * *", monday.Add(2 * time.Hour), false},
+		{"* 10/2 * * *", monday.Add(3 * time.Hour), true},
+		// 7 is Sunday, which is the day before.
+		{"* * * * 7", monday.Add(-24 * time.Hour), true},
+		{"* * * 7 *", monday, false},
+		// If both the day of the month and week are restricted, either can
+		// match.
+		{"* * 15 * 1", monday, true},
+		{"* * 1 * 5", monday, true},
+		{"* * 15 * 5", monday, false},
+		{"* * 1 * *", monday, true},
+		{"* * * * 5", monday, false},
+	}
+	for _, c := range cases {
+		t.Run(c.expr+" "+c.t.Format(time.RFC3339), func(t *testing.T) {
+			s, err := valid.ParseSchedule(c.expr)
+			Ok(t, err)
+			Equals(t, c.exp, s.Matches(c.t))
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	Ok(t, err)
+	// 2020-06-05 is a Friday.
+	friday := time.Date(2020, 6, 5, 17, 0, 30, 0, amsterdam)
+	cases := []struct {
+		description string
+		expr        string
+		t           time.Time
+		exp         time.Time
+		expOK       bool
+	}{
+		{
+			description: "now",
+			expr:        "* * * * *",
+			t:           time.Date(2020, 6, 5, 17, 0, 0, 0, amsterdam),
+			exp:         time.Date(2020, 6, 5, 17, 0, 0, 0, amsterdam),
+			expOK:       true,
+		},
+		{
+			description: "rounds up to the next minute",
+			expr:        "* * * * *",
+			t:           friday,
+			exp:         time.Date(2020, 6, 5, 17, 1, 0, 0, amsterdam),
+			expOK:       true,
+		},
+		{
+			description: "after the weekend",
+			expr:        "* 9-16 * * 1-5",
+			t:           friday,
+			exp:         time.Date(2020, 6, 8, 9, 0, 0, 0, amsterdam),
+			expOK:       true,
+		},
+		{
+			description: "later the same day",
+			expr:        "45 20 * * *",
+			t:           friday,
+			exp:         time.Date(2020, 6, 5, 20, 45, 0, 0, amsterdam),
+			expOK:       true,
+		},
+		{
+			description: "next year",
+			expr:        "0 0 1 1 *",
+			t:           friday,
+			exp:         time.Date(2021, 1, 1, 0, 0, 0, 0, amsterdam),
+			expOK:       true,
+		},
+		{
+			description: "in the location of t",
+			expr:        "0 9 * * *",
+			t:           friday.In(time.UTC),
+			exp:         time.Date(2020, 6, 6, 9, 0, 0, 0, time.UTC),
+			expOK:       true,
+		},
+		{
+			description: "never",
+			expr:        "* * 31 2 *",
+			t:           friday,
+			expOK:       false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			s, err := valid.ParseSchedule(c.expr)
+			Ok(t, err)
+			next, ok := s.Next(c.t)
+			Equals(t, c.expOK, ok)
+			Assert(t, next.Equal(c.exp), "exp %s got %s", c.exp, next)
+		})
+	}
+}
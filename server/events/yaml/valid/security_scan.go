@@ -0,0 +1,84 @@
+package valid
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// TfsecSecurityScanTool scans with tfsec (https://github.com/aquasecurity/tfsec).
+	TfsecSecurityScanTool = "tfsec"
+	// CheckovSecurityScanTool scans with Checkov (https://www.checkov.io).
+	CheckovSecurityScanTool = "checkov"
+
+	// DirSecurityScanTarget scans the project's Terraform files.
+	DirSecurityScanTarget = "dir"
+	// PlanSecurityScanTarget scans the project's plan as JSON. Only Checkov
+	// supports it.
+	PlanSecurityScanTarget = "plan"
+)
+
+// SecuritySeverities are the severities of security scan findings from lowest
+// to highest.
+var SecuritySeverities = []string{"LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// SecurityScan is how security_scan steps scan projects, from the server-side
+// repo config.
+type SecurityScan struct {
+	// Tool is the scanner, one of the *SecurityScanTool constants.
+	Tool string
+	// Target is what's scanned, one of the *SecurityScanTarget constants.
+	Target string
+	// BlockSeverity, if set, is the lowest severity, one of
+	// SecuritySeverities, whose findings block apply.
+	BlockSeverity string
+}
+
+// DefaultSecurityScan is used for repos that don't configure security_scan.
+var DefaultSecurityScan = SecurityScan{
+	Tool:   TfsecSecurityScanTool,
+	Target: DirSecurityScanTarget,
+}
+
+// Blocks returns true if findings of severity block apply.
+func (s SecurityScan) Blocks(severity string) bool {
+	if s.BlockSeverity == "" {
+		return false
+	}
+	return SeverityRank(severity) >= SeverityRank(s.BlockSeverity)
+}
+
+// String describes the scan like it's written in config files, ex.
+// "tool: checkov, target: plan, block_severity: HIGH".
+func (s SecurityScan) String() string {
+	str := fmt.Sprintf("tool: %s, target: %s", s.Tool, s.Target)
+	if s.BlockSeverity != "" {
+		str += ", block_severity: " + s.BlockSeverity
+	}
+	return str
+}
+
+// SeverityRank returns the position of severity in SecuritySeverities.
+// Unknown severities, ex. from Checkov without a Bridgecrew API key, rank as
+// LOW.
+func SeverityRank(severity string) int {
+	for i, s := range SecuritySeverities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+	return 0
+}
+
+// SecurityScan returns how security_scan steps scan projects in the repo with
+// id repoID. The settings of the last matching repo config that sets them are
+// used. If none do, it returns nil and DefaultSecurityScan is used.
+func (g GlobalCfg) SecurityScan(repoID string) *SecurityScan {
+	var scan *SecurityScan
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.SecurityScan != nil {
+			scan = repo.SecurityScan
+		}
+	}
+	return scan
+}
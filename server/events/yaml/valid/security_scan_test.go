@@ -0,0 +1,41 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestSecurityScan_Blocks(t *testing.T) {
+	Assert(t, !valid.DefaultSecurityScan.Blocks("CRITICAL"), "exp findings not to block without block_severity")
+
+	scan := valid.SecurityScan{Tool: "tfsec", Target: "dir", BlockSeverity: "HIGH"}
+	Assert(t, scan.Blocks("CRITICAL"), "exp CRITICAL to block")
+	Assert(t, scan.Blocks("high"), "exp high to block")
+	Assert(t, !scan.Blocks("MEDIUM"), "exp MEDIUM not to block")
+	Assert(t, !scan.Blocks(""), "exp unknown severities not to block")
+}
+
+func TestSecurityScan_String(t *testing.T) {
+	Equals(t, "tool: tfsec, target: dir", valid.DefaultSecurityScan.String())
+	Equals(t, "tool: checkov, target: plan, block_severity: HIGH", valid.SecurityScan{Tool: "checkov", Target: "plan", BlockSeverity: "HIGH"}.String())
+}
+
+func TestGlobalCfg_SecurityScan(t *testing.T) {
+	checkov := &valid.SecurityScan{Tool: "checkov", Target: "dir"}
+	blocking := &valid.SecurityScan{Tool: "tfsec", Target: "dir", BlockSeverity: "CRITICAL"}
+	global := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), SecurityScan: checkov},
+			{ID: "github.com/owner/prod", SecurityScan: blocking},
+			{ID: "github.com/owner/other"},
+		},
+	}
+	Equals(t, checkov, global.SecurityScan("github.com/owner/repo"))
+	// The last matching repo that sets it wins.
+	Equals(t, blocking, global.SecurityScan("github.com/owner/prod"))
+	Equals(t, checkov, global.SecurityScan("github.com/owner/other"))
+	Equals(t, (*valid.SecurityScan)(nil), valid.NewGlobalCfg(false, false, false).SecurityScan("github.com/owner/repo"))
+}
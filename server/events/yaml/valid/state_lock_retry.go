@@ -0,0 +1,22 @@
+package valid
+
+import "time"
+
+// DefaultStateLockRetryMaxAttempts is the number of times a step is attempted
+// if the Terraform state is locked and max_attempts isn't set.
+const DefaultStateLockRetryMaxAttempts = 3
+
+// DefaultStateLockRetryBackoff is the initial wait before retrying a step
+// that failed because the Terraform state was locked if backoff isn't set.
+const DefaultStateLockRetryBackoff = 30 * time.Second
+
+// StateLockRetry describes how to retry the init, plan and apply steps of a
+// workflow when they fail because another run holds the Terraform state lock.
+type StateLockRetry struct {
+	// MaxAttempts is the total number of times the step will be run,
+	// including the first attempt.
+	MaxAttempts int
+	// Backoff is the initial wait before retrying. It doubles on each
+	// subsequent retry and has jitter applied.
+	Backoff time.Duration
+}
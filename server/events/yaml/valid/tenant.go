@@ -0,0 +1,71 @@
+package valid
+
+import (
+	"path"
+	"sort"
+)
+
+// Tenant partitions the repos of one Atlantis deployment, ex. for a team.
+// Its repos are allowed in addition to the repo whitelist, get its default
+// workflow and credentials and are only shown to its users in the web UI
+// and API.
+type Tenant struct {
+	Name string
+	// Users are globs, ex. *@team-a.example.com, matching the web UI and API
+	// users who belong to the tenant.
+	Users []string
+}
+
+// HasUser returns true if user, as returned by server.RequestUser, belongs to
+// the tenant.
+func (t Tenant) HasUser(user string) bool {
+	for _, glob := range t.Users {
+		// The glob is validated when it's parsed so there's no error.
+		if matched, _ := path.Match(glob, user); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// TenantFor returns the name of the tenant that the repo with id repoID
+// belongs to or an empty string if it doesn't belong to any.
+func (g GlobalCfg) TenantFor(repoID string) string {
+	var tenant string
+	for _, repo := range g.Repos {
+		if repo.Tenant != "" && repo.IDMatches(repoID) {
+			tenant = repo.Tenant
+		}
+	}
+	return tenant
+}
+
+// UserTenants returns the sorted names of the tenants that user belongs to.
+func (g GlobalCfg) UserTenants(user string) []string {
+	var names []string
+	for name, t := range g.Tenants {
+		if t.HasUser(user) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RepoVisibleTo returns true if user can see the repo with id repoID in the
+// web UI and API. Users who belong to tenants can only see their tenants'
+// repos. Users who don't belong to any, ex. the operators of the server, can
+// see every repo.
+func (g GlobalCfg) RepoVisibleTo(user string, repoID string) bool {
+	tenants := g.UserTenants(user)
+	if len(tenants) == 0 {
+		return true
+	}
+	repoTenant := g.TenantFor(repoID)
+	for _, name := range tenants {
+		if name == repoTenant {
+			return true
+		}
+	}
+	return false
+}
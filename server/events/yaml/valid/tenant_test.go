@@ -0,0 +1,62 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func tenantsCfg() valid.GlobalCfg {
+	return valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile(`^github\.com/team-a/`), Tenant: "team-a"},
+			{ID: "github.com/team-b/infra", Tenant: "team-b"},
+			{ID: "github.com/team-a/shared", Tenant: "team-b"},
+			{ID: "github.com/team-b/infra"},
+		},
+		Tenants: map[string]valid.Tenant{
+			"team-a": {Name: "team-a", Users: []string{"*@team-a.example.com", "api-token:team-a"}},
+			"team-b": {Name: "team-b", Users: []string{"bob@example.com", "*@team-a.example.com"}},
+		},
+	}
+}
+
+func TestTenant_HasUser(t *testing.T) {
+	tenant := valid.Tenant{Users: []string{"*@team-a.example.com", "api-token:team-a"}}
+	Equals(t, true, tenant.HasUser("alice@team-a.example.com"))
+	Equals(t, true, tenant.HasUser("api-token:team-a"))
+	Equals(t, false, tenant.HasUser("api-token:team-b"))
+	Equals(t, false, tenant.HasUser("alice@team-b.example.com"))
+	Equals(t, false, valid.Tenant{}.HasUser(""))
+}
+
+func TestGlobalCfg_TenantFor(t *testing.T) {
+	cfg := tenantsCfg()
+	Equals(t, "team-a", cfg.TenantFor("github.com/team-a/infra"))
+	// The last matching tenant wins and repos without a tenant don't unset
+	// it.
+	Equals(t, "team-b", cfg.TenantFor("github.com/team-a/shared"))
+	Equals(t, "team-b", cfg.TenantFor("github.com/team-b/infra"))
+	Equals(t, "", cfg.TenantFor("github.com/other/infra"))
+}
+
+func TestGlobalCfg_UserTenants(t *testing.T) {
+	cfg := tenantsCfg()
+	Equals(t, []string{"team-a", "team-b"}, cfg.UserTenants("alice@team-a.example.com"))
+	Equals(t, []string{"team-b"}, cfg.UserTenants("bob@example.com"))
+	Equals(t, []string(nil), cfg.UserTenants("admin"))
+}
+
+func TestGlobalCfg_RepoVisibleTo(t *testing.T) {
+	cfg := tenantsCfg()
+	Equals(t, true, cfg.RepoVisibleTo("api-token:team-a", "github.com/team-a/infra"))
+	Equals(t, false, cfg.RepoVisibleTo("api-token:team-a", "github.com/team-a/shared"))
+	Equals(t, false, cfg.RepoVisibleTo("api-token:team-a", "github.com/other/infra"))
+	Equals(t, true, cfg.RepoVisibleTo("bob@example.com", "github.com/team-a/shared"))
+	// Users who don't belong to a tenant see every repo.
+	Equals(t, true, cfg.RepoVisibleTo("admin", "github.com/other/infra"))
+	Equals(t, true, cfg.RepoVisibleTo("", "github.com/team-a/infra"))
+}
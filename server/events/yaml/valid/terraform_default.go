@@ -0,0 +1,33 @@
+package valid
+
+import version "github.com/hashicorp/go-version"
+
+// TerraformDefault is the Terraform version and distribution that the
+// projects of matching repos run with if they don't choose a version, from
+// the server-side repo config.
+type TerraformDefault struct {
+	// Version is nil if the server's default version is used.
+	Version *version.Version
+	// Distribution, ex. opentofu, is empty if the server's distribution is
+	// used.
+	Distribution string
+}
+
+// TerraformDefault returns the default Terraform version and distribution of
+// the repo with id repoID. The version and distribution of the last matching
+// repos that set them are used.
+func (g GlobalCfg) TerraformDefault(repoID string) TerraformDefault {
+	var d TerraformDefault
+	for _, repo := range g.Repos {
+		if !repo.IDMatches(repoID) {
+			continue
+		}
+		if repo.DefaultTerraformVersion != nil {
+			d.Version = repo.DefaultTerraformVersion
+		}
+		if repo.TerraformDistribution != "" {
+			d.Distribution = repo.TerraformDistribution
+		}
+	}
+	return d
+}
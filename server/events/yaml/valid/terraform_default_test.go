@@ -0,0 +1,51 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_TerraformDefault(t *testing.T) {
+	v0137, _ := version.NewVersion("0.13.7")
+	v160, _ := version.NewVersion("1.6.0")
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:                 regexp.MustCompile(".*"),
+			TerraformDistribution:   "opentofu",
+			DefaultTerraformVersion: v160,
+		},
+		valid.Repo{
+			IDRegex:                 regexp.MustCompile("github.com/owner/legacy-.*"),
+			DefaultTerraformVersion: v0137,
+			TerraformDistribution:   "terraform",
+		},
+		valid.Repo{
+			ID: "github.com/owner/legacy-network",
+		},
+	)
+
+	Equals(t, valid.TerraformDefault{Version: v0137, Distribution: "terraform"}, global.TerraformDefault("github.com/owner/legacy-network"))
+	Equals(t, valid.TerraformDefault{Version: v160, Distribution: "opentofu"}, global.TerraformDefault("github.com/owner/other"))
+	Equals(t, valid.TerraformDefault{}, valid.NewGlobalCfg(false, false, false).TerraformDefault("github.com/owner/other"))
+}
+
+func TestGlobalCfg_MergeProjectCfgTerraformDefault(t *testing.T) {
+	v0137, _ := version.NewVersion("0.13.7")
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos, valid.Repo{
+		IDRegex:                 regexp.MustCompile("github.com/owner/legacy-.*"),
+		DefaultTerraformVersion: v0137,
+		TerraformDistribution:   "opentofu",
+	})
+
+	merged := global.DefaultProjCfg(logging.NewNoopLogger(), "github.com/owner/legacy-network", ".", "default")
+	Equals(t, v0137, merged.DefaultTerraformVersion)
+	Equals(t, "opentofu", merged.TerraformDistribution)
+	Assert(t, merged.TerraformVersion == nil, "exp the repo default not to override the project's version")
+}
@@ -0,0 +1,59 @@
+package valid
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timeouts are how long the steps of a repo's projects can run for before
+// they're stopped, from the server-side repo config. 0 means the step's
+// timeout isn't set by the repo.
+type Timeouts struct {
+	Init  time.Duration
+	Plan  time.Duration
+	Apply time.Duration
+	// Run is the timeout of custom run steps.
+	Run time.Duration
+}
+
+// For returns the timeout of steps named stepName, or 0 if it isn't set or
+// steps with that name can't have a timeout.
+func (t Timeouts) For(stepName string) time.Duration {
+	switch stepName {
+	case "init":
+		return t.Init
+	case "plan":
+		return t.Plan
+	case "apply":
+		return t.Apply
+	case "run":
+		return t.Run
+	}
+	return 0
+}
+
+// String describes the config like it's written in config files, ex.
+// "plan: 30m0s, apply: 1h0m0s".
+func (t Timeouts) String() string {
+	var timeouts []string
+	for _, stepName := range []string{"init", "plan", "apply", "run"} {
+		if d := t.For(stepName); d > 0 {
+			timeouts = append(timeouts, fmt.Sprintf("%s: %s", stepName, d))
+		}
+	}
+	return strings.Join(timeouts, ", ")
+}
+
+// Timeouts returns the step timeouts of the repo with id repoID. The config
+// of the last matching repo that sets them is used. If none do, no timeouts
+// are set.
+func (g GlobalCfg) Timeouts(repoID string) Timeouts {
+	var timeouts Timeouts
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Timeouts != nil {
+			timeouts = *repo.Timeouts
+		}
+	}
+	return timeouts
+}
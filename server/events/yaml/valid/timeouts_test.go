@@ -0,0 +1,38 @@
+package valid_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfg_Timeouts(t *testing.T) {
+	all := valid.Timeouts{Plan: 30 * time.Minute, Apply: time.Hour}
+	slow := valid.Timeouts{Init: 10 * time.Minute, Plan: 2 * time.Hour}
+	global := valid.NewGlobalCfg(false, false, false)
+	global.Repos = append(global.Repos,
+		valid.Repo{
+			IDRegex:  regexp.MustCompile(".*"),
+			Timeouts: &all,
+		},
+		valid.Repo{
+			ID:       "github.com/owner/slow",
+			Timeouts: &slow,
+		},
+		valid.Repo{
+			ID: "github.com/owner/slow",
+		},
+	)
+
+	Equals(t, all, global.Timeouts("github.com/owner/repo"))
+	Equals(t, slow, global.Timeouts("github.com/owner/slow"))
+	Equals(t, valid.Timeouts{}, valid.NewGlobalCfg(false, false, false).Timeouts("github.com/owner/repo"))
+
+	Equals(t, 10*time.Minute, slow.For("init"))
+	Equals(t, time.Duration(0), slow.For("run"))
+	Equals(t, time.Duration(0), slow.For("env"))
+	Equals(t, "init: 10m0s, plan: 2h0m0s", slow.String())
+}
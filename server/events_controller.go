@@ -14,21 +14,27 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v28/github"
 	"github.com/mcdafydd/go-azuredevops/azuredevops"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/archive"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
+	"github.com/runatlantis/atlantis/server/tracing"
 	gitlab "github.com/xanzy/go-gitlab"
 )
 
@@ -50,17 +56,23 @@ type EventsController struct {
 	Logger        *logging.SimpleLogger
 	Parser        events.EventParsing
 	CommentParser events.CommentParsing
-	// GithubWebhookSecret is the secret added to this webhook via the GitHub
-	// UI that identifies this call as coming from GitHub. If empty, no
-	// request validation is done.
-	GithubWebhookSecret          []byte
+	// CommentTemplates renders the comment for repos that aren't
+	// whitelisted. If nil, the default comment is used.
+	CommentTemplates *events.CommentTemplates
+	// GithubWebhookSecrets are the secrets added to this webhook via the GitHub
+	// UI that identify this call as coming from GitHub. Requests signed by any
+	// of them are valid. If empty, no request validation is done.
+	GithubWebhookSecrets         [][]byte
 	GithubRequestValidator       GithubRequestValidator
 	GitlabRequestParserValidator GitlabRequestParserValidator
-	// GitlabWebhookSecret is the secret added to this webhook via the GitLab
-	// UI that identifies this call as coming from GitLab. If empty, no
-	// request validation is done.
-	GitlabWebhookSecret  []byte
+	// GitlabWebhookSecrets are the secrets added to this webhook via the GitLab
+	// UI that identify this call as coming from GitLab. Requests with any of
+	// them are valid. If empty, no request validation is done.
+	GitlabWebhookSecrets [][]byte
 	RepoWhitelistChecker *events.RepoWhitelistChecker
+	// RepoAutoRegistrar, if set, registers repos that aren't whitelisted
+	// when their webhooks arrive if they match its rules.
+	RepoAutoRegistrar *events.RepoAutoRegistrar
 	// SilenceWhitelistErrors controls whether we write an error comment on
 	// pull requests from non-whitelisted repos.
 	SilenceWhitelistErrors bool
@@ -69,10 +81,10 @@ type EventsController struct {
 	SupportedVCSHosts []models.VCSHostType
 	VCSClient         vcs.Client
 	TestingMode       bool
-	// BitbucketWebhookSecret is the secret added to this webhook via the Bitbucket
-	// UI that identifies this call as coming from Bitbucket. If empty, no
-	// request validation is done.
-	BitbucketWebhookSecret []byte
+	// BitbucketWebhookSecrets are the secrets added to this webhook via the
+	// Bitbucket UI that identify this call as coming from Bitbucket. Requests
+	// signed by any of them are valid. If empty, no request validation is done.
+	BitbucketWebhookSecrets [][]byte
 	// AzureDevopsWebhookUser is the Basic authentication username added to this
 	// webhook via the Azure DevOps UI that identifies this call as coming from your
 	// Azure DevOps Team Project. If empty, no request validation is done.
@@ -83,13 +95,111 @@ type EventsController struct {
 	// Azure DevOps Team Project. If empty, no request validation is done.
 	AzureDevopsWebhookBasicPassword []byte
 	AzureDevopsRequestValidator     AzureDevopsRequestValidator
+	// SignatureFailures, if set, counts the webhook requests that weren't
+	// signed by any of the webhook secrets, labelled by VCS host.
+	SignatureFailures *metrics.Counter
+	// PayloadArchiver, if set, archives the raw payload of every webhook
+	// request, including ones that are rejected.
+	PayloadArchiver *archive.Archiver
+	// EventQueue, if set, queues commands to be run by its workers instead
+	// of running them in a new goroutine. If it's full, the webhook is
+	// responded to with a 503 so it can be delivered again later.
+	EventQueue *events.EventQueue
+	// Deduplicator, if set, ignores webhook deliveries and events that were
+	// already seen, ex. because the VCS host redelivered them.
+	Deduplicator *events.WebhookDeduplicator
+	// AutoplanDebouncer, if set, is told about every push so it can cancel
+	// the autoplans of the commits they supersede.
+	AutoplanDebouncer *events.AutoplanDebouncer
+	// RateLimiter, if set, rejects webhooks from IPs and repos that exceed
+	// their rate limits with a 429.
+	RateLimiter *events.EventRateLimiter
+	// TrustedProxies are the networks of proxies in front of Atlantis whose
+	// X-Forwarded-For header is used to find the IP a webhook was sent from.
+	TrustedProxies []*net.IPNet
+	// Tracer, if set, records a span for handling each webhook. Commands
+	// the webhooks trigger are traced separately since they run after the
+	// response is sent.
+	Tracer *tracing.Tracer
+	// MergeQueue, if set, handles GitHub merge_group webhooks.
+	MergeQueue *events.MergeQueue
+	// AfterMergeApplier, if set, applies pull requests once they're merged
+	// in repos that are applied after merge and in the apply-after-merge
+	// merge queue mode.
+	AfterMergeApplier *events.AfterMergeApplier
+	// FailedEvents, if set, stores the webhook requests that fail to be
+	// handled so they can be replayed with the API.
+	FailedEvents *events.FailedEvents
 }
 
 // Post handles POST webhook requests.
 func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
+	if e.Tracer != nil {
+		span := e.Tracer.Start("POST /events", tracing.String("delivery", webhookDeliveryID(r)))
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		w = sw
+		defer func() {
+			span.SetAttributes(tracing.Int("http.status_code", sw.status))
+			span.End()
+		}()
+	}
+	// Rate limited requests are rejected before they're read or archived so
+	// floods of them are cheap.
+	if ip := e.remoteIP(r); !e.RateLimiter.AllowIP(ip) {
+		respond(w, e.Logger, logging.Debug, http.StatusTooManyRequests, "Rate limit exceeded for %s", ip)
+		return
+	}
+	var body []byte
+	if e.PayloadArchiver != nil || e.FailedEvents != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			respond(w, e.Logger, logging.Warn, http.StatusBadRequest, "Could not read body: %s", err)
+			return
+		}
+		// The handlers read the body again.
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if e.PayloadArchiver != nil {
+		if key := e.PayloadArchiver.Archive(r, body); key != "" {
+			e.Logger.Debug("archiving payload to %s", key)
+		}
+	}
+	if e.FailedEvents != nil && isWebhook(r) {
+		cw := &capturingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		w = cw
+		defer func() {
+			if cw.status >= 400 {
+				e.recordFailedEvent(r, body, cw.status, cw.body.String())
+			}
+		}()
+	}
+	e.handle(w, r)
+}
+
+// handle handles the webhook request r. It's called for each request and for
+// each replay of a request that failed.
+func (e *EventsController) handle(w http.ResponseWriter, r *http.Request) {
+	if deliveryID := webhookDeliveryID(r); deliveryID != "" && e.Deduplicator != nil {
+		key := events.DeliveryDedupKey(deliveryID)
+		if e.Deduplicator.Seen(key) {
+			respond(w, e.Logger, logging.Info, http.StatusOK, "Ignoring duplicate delivery %s", deliveryID)
+			return
+		}
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		w = sw
+		// Deliveries that we failed to handle, ex. because their signature
+		// was invalid or the event queue was full, should be handled if
+		// they're delivered again.
+		defer func() {
+			if sw.status >= 300 {
+				e.Deduplicator.Forget(key)
+			}
+		}()
+	}
 	if r.Header.Get(githubHeader) != "" {
 		if !e.supportsHost(models.Github) {
-			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
+			respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
 			return
 		}
 		e.Logger.Debug("handling GitHub post")
@@ -97,7 +207,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	} else if r.Header.Get(gitlabHeader) != "" {
 		if !e.supportsHost(models.Gitlab) {
-			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitLab")
+			respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitLab")
 			return
 		}
 		e.Logger.Debug("handling GitLab post")
@@ -108,7 +218,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		// use different request ID headers.
 		if r.Header.Get(bitbucketCloudRequestIDHeader) != "" {
 			if !e.supportsHost(models.BitbucketCloud) {
-				e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Cloud")
+				respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Cloud")
 				return
 			}
 			e.Logger.Debug("handling Bitbucket Cloud post")
@@ -116,7 +226,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		} else if r.Header.Get(bitbucketServerRequestIDHeader) != "" {
 			if !e.supportsHost(models.BitbucketServer) {
-				e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Server")
+				respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Server")
 				return
 			}
 			e.Logger.Debug("handling Bitbucket Server post")
@@ -125,26 +235,34 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		}
 	} else if r.Header.Get(azuredevopsHeader) != "" {
 		if !e.supportsHost(models.AzureDevops) {
-			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support AzureDevops")
+			respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support AzureDevops")
 			return
 		}
 		e.Logger.Debug("handling AzureDevops post")
 		e.handleAzureDevopsPost(w, r)
 		return
 	}
-	e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request")
+	respond(w, e.Logger, logging.Debug, http.StatusBadRequest, "Ignoring request")
 }
 
 func (e *EventsController) handleGithubPost(w http.ResponseWriter, r *http.Request) {
 	// Validate the request against the optional webhook secret.
-	payload, err := e.GithubRequestValidator.Validate(r, e.GithubWebhookSecret)
+	payload, err := e.GithubRequestValidator.Validate(r, e.GithubWebhookSecrets)
 	if err != nil {
-		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
+		e.recordSignatureFailure(models.Github, err)
+		respond(w, e.Logger, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
 	e.Logger.Debug("request valid")
 
 	githubReqID := "X-Github-Delivery=" + r.Header.Get("X-Github-Delivery")
+	// The GitHub client doesn't support merge_group webhooks so they're
+	// parsed separately.
+	if github.WebHookType(r) == "merge_group" {
+		e.Logger.Debug("handling as merge group event")
+		e.HandleGithubMergeGroupEvent(w, payload, githubReqID)
+		return
+	}
 	event, _ := github.ParseWebHook(github.WebHookType(r), payload)
 	switch event := event.(type) {
 	case *github.IssueCommentEvent:
@@ -154,7 +272,7 @@ func (e *EventsController) handleGithubPost(w http.ResponseWriter, r *http.Reque
 		e.Logger.Debug("handling as pull request event")
 		e.HandleGithubPullRequestEvent(w, event, githubReqID)
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event %s", githubReqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring unsupported event %s", githubReqID)
 	}
 }
 
@@ -164,7 +282,7 @@ func (e *EventsController) handleBitbucketCloudPost(w http.ResponseWriter, r *ht
 	defer r.Body.Close() // nolint: errcheck
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
 	switch eventType {
@@ -177,7 +295,7 @@ func (e *EventsController) handleBitbucketCloudPost(w http.ResponseWriter, r *ht
 		e.HandleBitbucketCloudCommentEvent(w, body, reqID)
 		return
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketCloudRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketCloudRequestIDHeader, reqID)
 	}
 }
 
@@ -188,18 +306,19 @@ func (e *EventsController) handleBitbucketServerPost(w http.ResponseWriter, r *h
 	defer r.Body.Close() // nolint: errcheck
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketServerRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketServerRequestIDHeader, reqID)
 		return
 	}
 	if eventType == bitbucketserver.DiagnosticsPingHeader {
 		// Specially handle the diagnostics:ping event because Bitbucket Server
 		// doesn't send the signature with this event for some reason.
-		e.respond(w, logging.Info, http.StatusOK, "Successfully received %s event %s=%s", eventType, bitbucketServerRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Info, http.StatusOK, "Successfully received %s event %s=%s", eventType, bitbucketServerRequestIDHeader, reqID)
 		return
 	}
-	if len(e.BitbucketWebhookSecret) > 0 {
-		if err := bitbucketserver.ValidateSignature(body, sig, e.BitbucketWebhookSecret); err != nil {
-			e.respond(w, logging.Warn, http.StatusBadRequest, errors.Wrap(err, "request did not pass validation").Error())
+	if len(e.BitbucketWebhookSecrets) > 0 {
+		if err := e.validateBitbucketServerSignature(body, sig); err != nil {
+			e.recordSignatureFailure(models.BitbucketServer, err)
+			respond(w, e.Logger, logging.Warn, http.StatusBadRequest, errors.Wrap(err, "request did not pass validation").Error())
 			return
 		}
 	}
@@ -213,7 +332,30 @@ func (e *EventsController) handleBitbucketServerPost(w http.ResponseWriter, r *h
 		e.HandleBitbucketServerCommentEvent(w, body, reqID)
 		return
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketServerRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketServerRequestIDHeader, reqID)
+	}
+}
+
+// validateBitbucketServerSignature returns an error if body wasn't signed by
+// any of the Bitbucket webhook secrets. Secrets are tried in order.
+func (e *EventsController) validateBitbucketServerSignature(body []byte, sig string) error {
+	var err error
+	for _, secret := range e.BitbucketWebhookSecrets {
+		if err = bitbucketserver.ValidateSignature(body, sig, secret); err == nil {
+			return nil
+		}
+	}
+	return &WebhookSignatureError{Err: err}
+}
+
+// recordSignatureFailure increments the signature failures metric for
+// vcsHost if err is because the request wasn't signed by a webhook secret.
+func (e *EventsController) recordSignatureFailure(vcsHost models.VCSHostType, err error) {
+	if e.SignatureFailures == nil {
+		return
+	}
+	if _, ok := err.(*WebhookSignatureError); ok {
+		e.SignatureFailures.Inc(vcsHost.String())
 	}
 }
 
@@ -221,7 +363,7 @@ func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.
 	// Validate the request against the optional basic auth username and password.
 	payload, err := e.AzureDevopsRequestValidator.Validate(r, e.AzureDevopsWebhookBasicUser, e.AzureDevopsWebhookBasicPassword)
 	if err != nil {
-		e.respond(w, logging.Warn, http.StatusUnauthorized, err.Error())
+		respond(w, e.Logger, logging.Warn, http.StatusUnauthorized, err.Error())
 		return
 	}
 	e.Logger.Debug("request valid")
@@ -229,7 +371,7 @@ func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.
 	azuredevopsReqID := "Request-Id=" + r.Header.Get("Request-Id")
 	event, err := azuredevops.ParseWebHook(payload)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing webhook: %v %s", err, azuredevopsReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Failed parsing webhook: %v %s", err, azuredevopsReqID)
 		return
 	}
 	switch event.PayloadType {
@@ -240,7 +382,7 @@ func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.
 		e.Logger.Debug("handling as pull request event")
 		e.HandleAzureDevopsPullRequestEvent(w, event, azuredevopsReqID)
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event: %v %s", event.PayloadType, azuredevopsReqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring unsupported event: %v %s", event.PayloadType, azuredevopsReqID)
 	}
 }
 
@@ -248,45 +390,45 @@ func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.
 // commands can come from. It's exported to make testing easier.
 func (e *EventsController) HandleGithubCommentEvent(w http.ResponseWriter, event *github.IssueCommentEvent, githubReqID string) {
 	if event.GetAction() != "created" {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s", githubReqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s", githubReqID)
 		return
 	}
 
 	baseRepo, user, pullNum, err := e.Parser.ParseGithubIssueCommentEvent(event)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
 		return
 	}
 
 	// We pass in nil for maybeHeadRepo because the head repo data isn't
 	// available in the GithubIssueComment event.
-	e.handleCommentEvent(w, baseRepo, nil, nil, user, pullNum, event.Comment.GetBody(), models.Github)
+	e.handleCommentEvent(w, baseRepo, nil, nil, user, pullNum, event.Comment.GetBody(), event.Comment.GetID(), models.Github)
 }
 
 // HandleBitbucketCloudCommentEvent handles comment events from Bitbucket.
 func (e *EventsController) HandleBitbucketCloudCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketCloudPullCommentEvent(body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, 0, models.BitbucketCloud)
 }
 
 // HandleBitbucketServerCommentEvent handles comment events from Bitbucket.
 func (e *EventsController) HandleBitbucketServerCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketServerPullCommentEvent(body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, 0, models.BitbucketCloud)
 }
 
 func (e *EventsController) handleBitbucketCloudPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, err := e.Parser.ParseBitbucketCloudPullEvent(body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
 	pullEventType := e.Parser.GetBitbucketCloudPullEventType(eventType)
@@ -297,7 +439,7 @@ func (e *EventsController) handleBitbucketCloudPullRequestEvent(w http.ResponseW
 func (e *EventsController) handleBitbucketServerPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, err := e.Parser.ParseBitbucketServerPullEvent(body)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketServerRequestIDHeader, reqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketServerRequestIDHeader, reqID)
 		return
 	}
 	pullEventType := e.Parser.GetBitbucketServerPullEventType(eventType)
@@ -311,15 +453,81 @@ func (e *EventsController) handleBitbucketServerPullRequestEvent(w http.Response
 func (e *EventsController) HandleGithubPullRequestEvent(w http.ResponseWriter, pullEvent *github.PullRequestEvent, githubReqID string) {
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGithubPullEvent(pullEvent)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, githubReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, githubReqID)
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
+	if !e.appliesAfterMerge(pullEvent, pullEventType, baseRepo) || !e.isWhitelisted(baseRepo) {
+		e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+		return
+	}
+
+	// The projects the pull request planned are looked up before it's
+	// cleaned up so they can be applied now that it's merged.
+	projects, err := e.AfterMergeApplier.MergedProjects(pull)
+	if err != nil {
+		respond(w, e.Logger, logging.Error, http.StatusInternalServerError, "Error getting planned projects: %s", err)
+		return
+	}
 	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	mergeCommit := pullEvent.GetPullRequest().GetMergeCommitSHA()
+	e.Logger.Info("applying merged pull request")
+	if !e.TestingMode {
+		go e.AfterMergeApplier.ApplyMerged(baseRepo, pull, mergeCommit, user, projects)
+	} else {
+		// When testing we want to wait for everything to complete.
+		e.AfterMergeApplier.ApplyMerged(baseRepo, pull, mergeCommit, user, projects)
+	}
+}
+
+// appliesAfterMerge returns true if the pull request of pullEvent was merged
+// into the default branch of baseRepo and should be applied now.
+func (e *EventsController) appliesAfterMerge(pullEvent *github.PullRequestEvent, pullEventType models.PullRequestEventType, baseRepo models.Repo) bool {
+	if e.AfterMergeApplier == nil || pullEventType != models.ClosedPullEvent {
+		return false
+	}
+	if !pullEvent.GetPullRequest().GetMerged() || pullEvent.GetPullRequest().GetBase().GetRef() != pullEvent.GetRepo().GetDefaultBranch() {
+		return false
+	}
+	return (e.MergeQueue != nil && e.MergeQueue.Mode == events.ApplyAfterMergeMode) || e.AfterMergeApplier.Enabled(baseRepo)
+}
+
+// HandleGithubMergeGroupEvent handles merge_group events from GitHub, which
+// are sent when pull requests enter merge queues. It's exported to make
+// testing easier.
+func (e *EventsController) HandleGithubMergeGroupEvent(w http.ResponseWriter, payload []byte, githubReqID string) {
+	if e.MergeQueue == nil {
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring merge group event since merge queues aren't enabled %s", githubReqID)
+		return
+	}
+	action, group, baseRepo, user, err := events.ParseGithubMergeGroupEvent(e.Parser, payload)
+	if err != nil {
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing merge group data: %s %s", err, githubReqID)
+		return
+	}
+	if !e.isWhitelisted(baseRepo) {
+		respond(w, e.Logger, logging.Debug, http.StatusForbidden,
+			"Ignoring merge group event from non-whitelisted repo \"%s/%s\"",
+			baseRepo.VCSHost.Hostname, baseRepo.FullName)
+		return
+	}
+	if action != "checks_requested" {
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring non-actionable merge group event %s", githubReqID)
+		return
+	}
+
+	fmt.Fprintln(w, "Processing...")
+	e.Logger.Info("checking merge group %s", group.HeadRef)
+	if !e.TestingMode {
+		go e.MergeQueue.ChecksRequested(baseRepo, group, user)
+	} else {
+		// When testing we want to wait for everything to complete.
+		e.MergeQueue.ChecksRequested(baseRepo, group, user)
+	}
 }
 
 func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User, eventType models.PullRequestEventType) {
-	if !e.RepoWhitelistChecker.IsWhitelisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+	if !e.isWhitelisted(baseRepo) {
 		// If the repo isn't whitelisted and we receive an opened pull request
 		// event we comment back on the pull request that the repo isn't
 		// whitelisted. This is because the user might be expecting Atlantis to
@@ -327,7 +535,7 @@ func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRep
 		if eventType == models.OpenedPullEvent {
 			e.commentNotWhitelisted(baseRepo, pull.Num)
 		}
-		e.respond(w, logging.Debug, http.StatusForbidden,
+		respond(w, e.Logger, logging.Debug, http.StatusForbidden,
 			"Ignoring pull request event from non-whitelisted repo \"%s/%s\"",
 			baseRepo.VCSHost.Hostname, baseRepo.FullName)
 		return
@@ -337,6 +545,31 @@ func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRep
 	case models.OpenedPullEvent, models.UpdatedPullEvent:
 		// If the pull request was opened or updated, we will try to autoplan.
 
+		if !e.RateLimiter.AllowRepo(baseRepo.FullName) {
+			respond(w, e.Logger, logging.Debug, http.StatusTooManyRequests, "Rate limit exceeded for repo %s", baseRepo.FullName)
+			return
+		}
+
+		// The same commit can be autoplanned by duplicate webhooks, ex. GitLab
+		// sends one when the merge request's description is edited.
+		dedupKey := events.AutoplanDedupKey(baseRepo, pull)
+		if pull.HeadCommit != "" && e.Deduplicator.Seen(dedupKey) {
+			respond(w, e.Logger, logging.Info, http.StatusOK, "Ignoring duplicate autoplan of commit %s", pull.HeadCommit)
+			return
+		}
+
+		e.AutoplanDebouncer.Push(baseRepo, pull)
+
+		if e.EventQueue != nil {
+			if err := e.EventQueue.QueueAutoplan(baseRepo, headRepo, pull, user); err != nil {
+				e.Deduplicator.Forget(dedupKey)
+				respond(w, e.Logger, logging.Warn, http.StatusServiceUnavailable, "Unable to queue autoplan: %s", err)
+				return
+			}
+			respond(w, e.Logger, logging.Info, http.StatusOK, "Queued autoplan")
+			return
+		}
+
 		// Respond with success and then actually execute the command asynchronously.
 		// We use a goroutine so that this function returns and the connection is
 		// closed.
@@ -353,23 +586,27 @@ func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRep
 	case models.ClosedPullEvent:
 		// If the pull request was closed, we delete locks.
 		if err := e.PullCleaner.CleanUpPull(baseRepo, pull); err != nil {
-			e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
+			respond(w, e.Logger, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
 			return
 		}
+		// If the pull request is reopened, its head commit should be
+		// autoplanned again.
+		e.Deduplicator.ForgetPull(baseRepo, pull.Num)
 		e.Logger.Info("deleted locks and workspace for repo %s, pull %d", baseRepo.FullName, pull.Num)
 		fmt.Fprintln(w, "Pull request cleaned successfully")
 		return
 	case models.OtherPullEvent:
 		// Else we ignore the event.
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring non-actionable pull request event")
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring non-actionable pull request event")
 		return
 	}
 }
 
 func (e *EventsController) handleGitlabPost(w http.ResponseWriter, r *http.Request) {
-	event, err := e.GitlabRequestParserValidator.ParseAndValidate(r, e.GitlabWebhookSecret)
+	event, err := e.GitlabRequestParserValidator.ParseAndValidate(r, e.GitlabWebhookSecrets)
 	if err != nil {
-		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
+		e.recordSignatureFailure(models.Gitlab, err)
+		respond(w, e.Logger, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
 	e.Logger.Debug("request valid")
@@ -383,9 +620,9 @@ func (e *EventsController) handleGitlabPost(w http.ResponseWriter, r *http.Reque
 		e.HandleGitlabMergeRequestEvent(w, event)
 	case gitlab.CommitCommentEvent:
 		e.Logger.Debug("comments on commits are not supported, only comments on merge requests")
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment on commit event")
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring comment on commit event")
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event")
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring unsupported event")
 	}
 
 }
@@ -396,13 +633,13 @@ func (e *EventsController) HandleGitlabCommentEvent(w http.ResponseWriter, event
 	// todo: can gitlab return the pull request here too?
 	baseRepo, headRepo, user, err := e.Parser.ParseGitlabMergeRequestCommentEvent(event)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, nil, user, event.MergeRequest.IID, event.ObjectAttributes.Note, models.Gitlab)
+	e.handleCommentEvent(w, baseRepo, &headRepo, nil, user, event.MergeRequest.IID, event.ObjectAttributes.Note, int64(event.ObjectAttributes.ID), models.Gitlab)
 }
 
-func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, comment string, vcsHost models.VCSHostType) {
+func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, comment string, commentID int64, vcsHost models.VCSHostType) {
 	parseResult := e.CommentParser.Parse(comment, vcsHost)
 	if parseResult.Ignore {
 		truncated := comment
@@ -410,16 +647,16 @@ func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo mo
 		if len(truncated) > truncateLen {
 			truncated = comment[:truncateLen] + "..."
 		}
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring non-command comment: %q", truncated)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring non-command comment: %q", truncated)
 		return
 	}
 	e.Logger.Info("parsed comment as %s", parseResult.Command)
 
 	// At this point we know it's a command we're not supposed to ignore, so now
 	// we check if this repo is allowed to run commands in the first place.
-	if !e.RepoWhitelistChecker.IsWhitelisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+	if !e.isWhitelisted(baseRepo) {
 		e.commentNotWhitelisted(baseRepo, pullNum)
-		e.respond(w, logging.Warn, http.StatusForbidden, "Repo not whitelisted")
+		respond(w, e.Logger, logging.Warn, http.StatusForbidden, "Repo not whitelisted")
 		return
 	}
 
@@ -428,14 +665,41 @@ func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo mo
 	// We do this here rather than earlier because we need access to the pull
 	// variable to comment back on the pull request.
 	if parseResult.CommentResponse != "" {
-		if err := e.VCSClient.CreateComment(baseRepo, pullNum, parseResult.CommentResponse); err != nil {
+		response := parseResult.CommentResponse
+		if parseResult.Help {
+			response = e.CommentParser.HelpComment(baseRepo.ID())
+		}
+		if err := e.VCSClient.CreateComment(baseRepo, pullNum, response); err != nil {
 			e.Logger.Err("unable to comment on pull request: %s", err)
 		}
-		e.respond(w, logging.Info, http.StatusOK, "Commenting back on pull request")
+		respond(w, e.Logger, logging.Info, http.StatusOK, "Commenting back on pull request")
+		return
+	}
+
+	if !e.RateLimiter.AllowRepo(baseRepo.FullName) {
+		respond(w, e.Logger, logging.Debug, http.StatusTooManyRequests, "Rate limit exceeded for repo %s", baseRepo.FullName)
+		return
+	}
+
+	dedupKey := events.CommentDedupKey(baseRepo, pullNum, commentID)
+	if commentID != 0 && e.Deduplicator.Seen(dedupKey) {
+		respond(w, e.Logger, logging.Info, http.StatusOK, "Ignoring duplicate comment %d", commentID)
 		return
 	}
 
 	e.Logger.Debug("executing command")
+	if parseResult.Command != nil {
+		parseResult.Command.CommentID = commentID
+	}
+	if e.EventQueue != nil {
+		if err := e.EventQueue.QueueCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command); err != nil {
+			e.Deduplicator.Forget(dedupKey)
+			respond(w, e.Logger, logging.Warn, http.StatusServiceUnavailable, "Unable to queue command: %s", err)
+			return
+		}
+		respond(w, e.Logger, logging.Info, http.StatusOK, "Queued command")
+		return
+	}
 	fmt.Fprintln(w, "Processing...")
 	if !e.TestingMode {
 		// Respond with success and then actually execute the command asynchronously.
@@ -454,7 +718,7 @@ func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo mo
 func (e *EventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter, event gitlab.MergeEvent) {
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGitlabMergeRequestEvent(event)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
@@ -468,18 +732,18 @@ func (e *EventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter,
 func (e *EventsController) HandleAzureDevopsPullRequestCommentedEvent(w http.ResponseWriter, event *azuredevops.Event, azuredevopsReqID string) {
 	resource, ok := event.Resource.(*azuredevops.GitPullRequestWithComment)
 	if !ok || event.PayloadType != azuredevops.PullRequestCommentedEvent {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Event.Resource is nil or received bad event type %v; %s", event.Resource, azuredevopsReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Event.Resource is nil or received bad event type %v; %s", event.Resource, azuredevopsReqID)
 		return
 	}
 
 	if resource.Comment == nil {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since no comment is linked to payload; %s", azuredevopsReqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring comment event since no comment is linked to payload; %s", azuredevopsReqID)
 		return
 	}
 	strippedComment := bluemonday.StrictPolicy().SanitizeBytes([]byte(*resource.Comment.Content))
 
 	if resource.PullRequest == nil {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since no pull request is linked to payload; %s", azuredevopsReqID)
+		respond(w, e.Logger, logging.Debug, http.StatusOK, "Ignoring comment event since no pull request is linked to payload; %s", azuredevopsReqID)
 		return
 	}
 
@@ -487,10 +751,10 @@ func (e *EventsController) HandleAzureDevopsPullRequestCommentedEvent(w http.Res
 	user := models.User{Username: createdBy.GetUniqueName()}
 	baseRepo, err := e.Parser.ParseAzureDevopsRepo(resource.PullRequest.GetRepository())
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull request repository field: %s; %s", err, azuredevopsReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull request repository field: %s; %s", err, azuredevopsReqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, nil, nil, user, resource.PullRequest.GetPullRequestID(), string(strippedComment), models.AzureDevops)
+	e.handleCommentEvent(w, baseRepo, nil, nil, user, resource.PullRequest.GetPullRequestID(), string(strippedComment), 0, models.AzureDevops)
 }
 
 // HandleAzureDevopsPullRequestEvent will delete any locks associated with the pull
@@ -509,14 +773,14 @@ func (e *EventsController) HandleAzureDevopsPullRequestEvent(w http.ResponseWrit
 	for _, s := range ignoreEvents {
 		if strings.Contains(prText, s) {
 			msg := fmt.Sprintf("pull request updated event is not a supported type [%s]", s)
-			e.respond(w, logging.Debug, http.StatusOK, "%s: %s", msg, azuredevopsReqID)
+			respond(w, e.Logger, logging.Debug, http.StatusOK, "%s: %s", msg, azuredevopsReqID)
 			return
 		}
 	}
 
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseAzureDevopsPullEvent(*event)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, azuredevopsReqID)
+		respond(w, e.Logger, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, azuredevopsReqID)
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
@@ -533,11 +797,137 @@ func (e *EventsController) supportsHost(h models.VCSHostType) bool {
 	return false
 }
 
-func (e *EventsController) respond(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
-	response := fmt.Sprintf(format, args...)
-	e.Logger.Log(lvl, response)
-	w.WriteHeader(code)
-	fmt.Fprintln(w, response)
+// webhookDeliveryID returns the ID of the webhook delivery of r or an empty
+// string if the VCS host didn't set one. Redeliveries of a webhook keep its
+// ID.
+func webhookDeliveryID(r *http.Request) string {
+	for _, header := range archive.DeliveryIDHeaders {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// remoteIP returns the IP r was sent from. If r was sent by one of
+// TrustedProxies, it's the last IP in X-Forwarded-For that isn't a trusted
+// proxy since the IPs before it were set by the client and can't be trusted.
+// Otherwise it's the IP of the connection, which assumes Atlantis is exposed
+// directly.
+func (e *EventsController) remoteIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if !e.isTrustedProxy(ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !e.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// isTrustedProxy returns true if ip is in one of TrustedProxies.
+func (e *EventsController) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range e.TrustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusResponseWriter records the status code of the response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records code and then writes it.
+func (s *statusResponseWriter) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// maxFailedEventResponse is how much of the response to a failed webhook
+// request is stored with it.
+const maxFailedEventResponse = 1024
+
+// capturingResponseWriter records the status code and the start of the body
+// written to it so failed webhook requests can be stored with their response.
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records code and then writes it.
+func (c *capturingResponseWriter) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the start of b and then writes it.
+func (c *capturingResponseWriter) Write(b []byte) (int, error) {
+	if n := maxFailedEventResponse - c.body.Len(); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		c.body.Write(b[:n])
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// isWebhook returns true if r has the headers of a webhook from one of the
+// VCS hosts. Other requests, ex. from scanners, aren't stored when they fail.
+func isWebhook(r *http.Request) bool {
+	return r.Header.Get(githubHeader) != "" ||
+		r.Header.Get(gitlabHeader) != "" ||
+		r.Header.Get(bitbucketEventTypeHeader) != "" ||
+		r.Header.Get(azuredevopsHeader) != ""
+}
+
+// recordFailedEvent stores the webhook request r that was responded to with
+// status and response. Cookies are dropped since webhooks don't need them.
+func (e *EventsController) recordFailedEvent(r *http.Request, body []byte, status int, response string) {
+	header := r.Header.Clone()
+	header.Del("Cookie")
+	event, err := e.FailedEvents.Record(models.FailedEvent{
+		ReceivedAt: time.Now().UTC(),
+		RemoteAddr: r.RemoteAddr,
+		Header:     header,
+		Body:       body,
+		Status:     status,
+		Response:   strings.TrimSpace(response),
+	})
+	if err != nil {
+		e.Logger.Err("storing failed webhook: %s", err)
+		return
+	}
+	e.Logger.Info("stored failed webhook as event %d so it can be replayed", event.ID)
+}
+
+// isWhitelisted returns true if Atlantis should process events for repo. Repos
+// that aren't whitelisted yet are auto-registered if they match the
+// auto-register rules.
+func (e *EventsController) isWhitelisted(repo models.Repo) bool {
+	if e.RepoWhitelistChecker.IsWhitelisted(repo.FullName, repo.VCSHost.Hostname) {
+		return true
+	}
+	return e.RepoAutoRegistrar != nil && e.RepoAutoRegistrar.Register(repo.FullName, repo.VCSHost.Hostname)
 }
 
 // commentNotWhitelisted comments on the pull request that the repo is not
@@ -547,8 +937,7 @@ func (e *EventsController) commentNotWhitelisted(baseRepo models.Repo, pullNum i
 		return
 	}
 
-	errMsg := "```\nError: This repo is not whitelisted for Atlantis.\n```"
-	if err := e.VCSClient.CreateComment(baseRepo, pullNum, errMsg); err != nil {
+	if err := e.VCSClient.CreateComment(baseRepo, pullNum, e.CommentTemplates.NotWhitelisted()); err != nil {
 		e.Logger.Err("unable to comment on pull request: %s", err)
 	}
 }
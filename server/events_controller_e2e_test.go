@@ -400,7 +400,7 @@ func setupE2E(t *testing.T, repoDir string) (server.EventsController, *vcsmocks.
 		GithubUser: "github-user",
 		GitlabUser: "gitlab-user",
 	}
-	terraformClient, err := terraform.NewClient(logger, dataDir, "", "", "", "default-tf-version", "https://releases.hashicorp.com", &NoopTFDownloader{})
+	terraformClient, err := terraform.NewClient(logger, dataDir, "", "", "", "default-tf-version", terraform.DownloadConfig{URL: "https://releases.hashicorp.com"}, &NoopTFDownloader{})
 	Ok(t, err)
 	boltdb, err := db.New(dataDir)
 	Ok(t, err)
@@ -487,10 +487,10 @@ func setupE2E(t *testing.T, repoDir string) (server.EventsController, *vcsmocks.
 		Logger:                       logger,
 		Parser:                       eventParser,
 		CommentParser:                commentParser,
-		GithubWebhookSecret:          nil,
+		GithubWebhookSecrets:         nil,
 		GithubRequestValidator:       &server.DefaultGithubRequestValidator{},
 		GitlabRequestParserValidator: &server.DefaultGitlabRequestParserValidator{},
-		GitlabWebhookSecret:          nil,
+		GitlabWebhookSecrets:         nil,
 		RepoWhitelistChecker:         repoWhitelistChecker,
 		SupportedVCSHosts:            []models.VCSHostType{models.Gitlab, models.Github, models.BitbucketCloud},
 		VCSClient:                    e2eVCSClient,
@@ -633,6 +633,11 @@ func assertCommentEquals(t *testing.T, expFile string, act string, repoDir strin
 	resourceRegex := regexp.MustCompile(`null_resource\.simple(\[\d])?\d?:.*`)
 	act = resourceRegex.ReplaceAllString(act, "null_resource.simple:")
 
+	// The comment metadata is tested separately so strip it to keep the
+	// expected comments readable.
+	metadataRegex := regexp.MustCompile(`\n\n<!-- atlantis-metadata: .* -->$`)
+	act = metadataRegex.ReplaceAllString(act, "")
+
 	expStr := string(exp)
 	// My editor adds a newline to all the files, so if the actual comment
 	// doesn't end with a newline then strip the last newline from the file's
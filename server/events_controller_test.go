@@ -15,23 +15,32 @@ package server_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/archive"
+	"github.com/runatlantis/atlantis/server/events/db"
 	emocks "github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 	"github.com/runatlantis/atlantis/server/mocks"
 	. "github.com/runatlantis/atlantis/testing"
 	gitlab "github.com/xanzy/go-gitlab"
@@ -42,6 +51,7 @@ const gitlabHeader = "X-Gitlab-Event"
 const azuredevopsHeader = "Request-Id"
 
 var secret = []byte("secret")
+var secrets = [][]byte{secret}
 
 func TestPost_NotGithubOrGitlab(t *testing.T) {
 	t.Log("when the request is not for gitlab or github a 400 is returned")
@@ -80,7 +90,7 @@ func TestPost_InvalidGithubSecret(t *testing.T) {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(githubHeader, "value")
-	When(v.Validate(req, secret)).ThenReturn(nil, errors.New("err"))
+	When(v.Validate(req, secrets)).ThenReturn(nil, errors.New("err"))
 	e.Post(w, req)
 	responseContains(t, w, http.StatusBadRequest, "err")
 }
@@ -91,11 +101,209 @@ func TestPost_InvalidGitlabSecret(t *testing.T) {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(nil, errors.New("err"))
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(nil, errors.New("err"))
 	e.Post(w, req)
 	responseContains(t, w, http.StatusBadRequest, "err")
 }
 
+func TestPost_SignatureFailuresMetric(t *testing.T) {
+	t.Log("requests that weren't signed by a webhook secret are counted")
+	e, v, gl, _, _, _, _, _ := setup(t)
+	registry := metrics.NewRegistry()
+	e.SignatureFailures = registry.NewCounter("atlantis_webhook_signature_failures", "Failures.", "vcs")
+
+	githubReq, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	githubReq.Header.Set(githubHeader, "value")
+	When(v.Validate(githubReq, secrets)).ThenReturn(nil, &server.WebhookSignatureError{Err: errors.New("payload signature check failed")})
+	e.Post(httptest.NewRecorder(), githubReq)
+	e.Post(httptest.NewRecorder(), githubReq)
+
+	gitlabReq, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	gitlabReq.Header.Set(gitlabHeader, "value")
+	When(gl.ParseAndValidate(gitlabReq, secrets)).ThenReturn(nil, &server.WebhookSignatureError{Err: errors.New("did not match")})
+	e.Post(httptest.NewRecorder(), gitlabReq)
+
+	// Errors that aren't signature failures aren't counted.
+	otherReq, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	otherReq.Header.Set(githubHeader, "other")
+	When(v.Validate(otherReq, secrets)).ThenReturn(nil, errors.New("unsupported Content-Type"))
+	e.Post(httptest.NewRecorder(), otherReq)
+
+	families, err := registry.Gather()
+	Ok(t, err)
+	Equals(t, []metrics.Sample{
+		{Labels: []string{"Github"}, Value: 2},
+		{Labels: []string{"Gitlab"}, Value: 1},
+	}, families[0].Samples)
+}
+
+func TestPost_BBServerSecretRotation(t *testing.T) {
+	body := []byte(`{"eventKey":"repo:refs_changed"}`)
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body) // nolint: errcheck
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	cases := []struct {
+		description string
+		sig         string
+		expCode     int
+		expBody     string
+		expFailures int
+	}{
+		{
+			"signed by first secret",
+			sign("new"),
+			http.StatusOK,
+			"Ignoring unsupported event type repo:refs_changed",
+			0,
+		},
+		{
+			"signed by second secret",
+			sign("old"),
+			http.StatusOK,
+			"Ignoring unsupported event type repo:refs_changed",
+			0,
+		},
+		{
+			"signed by unknown secret",
+			sign("other"),
+			http.StatusBadRequest,
+			"request did not pass validation: payload signature check failed",
+			1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			registry := metrics.NewRegistry()
+			e := &server.EventsController{
+				Logger:                  logging.NewNoopLogger(),
+				SupportedVCSHosts:       []models.VCSHostType{models.BitbucketServer},
+				BitbucketWebhookSecrets: [][]byte{[]byte("new"), []byte("old")},
+				SignatureFailures:       registry.NewCounter("atlantis_webhook_signature_failures", "Failures.", "vcs"),
+			}
+			req, err := http.NewRequest("POST", "/events", bytes.NewBuffer(body))
+			Ok(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Event-Key", "repo:refs_changed")
+			req.Header.Set("X-Request-ID", "request-id")
+			req.Header.Set("X-Hub-Signature", c.sig)
+
+			w := httptest.NewRecorder()
+			e.Post(w, req)
+			responseContains(t, w, c.expCode, c.expBody)
+
+			families, err := registry.Gather()
+			Ok(t, err)
+			var failures float64
+			for _, s := range families[0].Samples {
+				failures += s.Value
+			}
+			Equals(t, float64(c.expFailures), failures)
+		})
+	}
+}
+
+func TestPost_ArchivesPayload(t *testing.T) {
+	t.Log("payloads are archived, even if they're rejected, and can still be validated")
+	body := []byte(`{"eventKey":"repo:refs_changed"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body) // nolint: errcheck
+	store := &archiveStore{objects: make(map[string][]byte)}
+	archiver := archive.NewArchiver(store, "webhooks", 0, logging.NewNoopLogger())
+	archiver.Start()
+	e := &server.EventsController{
+		Logger:                  logging.NewNoopLogger(),
+		SupportedVCSHosts:       []models.VCSHostType{models.BitbucketServer},
+		BitbucketWebhookSecrets: [][]byte{[]byte("secret")},
+		PayloadArchiver:         archiver,
+	}
+	for i, sig := range []string{"sha256=" + hex.EncodeToString(mac.Sum(nil)), "sha256=00"} {
+		req, err := http.NewRequest("POST", "/events", bytes.NewBuffer(body))
+		Ok(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event-Key", "repo:refs_changed")
+		req.Header.Set("X-Request-ID", fmt.Sprintf("request-%d", i))
+		req.Header.Set("X-Hub-Signature", sig)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		if i == 0 {
+			responseContains(t, w, http.StatusOK, "Ignoring unsupported event type repo:refs_changed")
+		} else {
+			responseContains(t, w, http.StatusBadRequest, "payload signature check failed")
+		}
+	}
+
+	for i := 0; i < 100 && store.len() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	Equals(t, 2, store.len())
+}
+
+func TestPost_RateLimitedNotArchived(t *testing.T) {
+	t.Log("requests from IPs that exceed their rate limit are rejected before they're archived")
+	store := &archiveStore{objects: make(map[string][]byte)}
+	archiver := archive.NewArchiver(store, "webhooks", 0, logging.NewNoopLogger())
+	archiver.Start()
+	e := &server.EventsController{
+		Logger:          logging.NewNoopLogger(),
+		PayloadArchiver: archiver,
+		RateLimiter:     &events.EventRateLimiter{PerIP: 1, Logger: logging.NewNoopLogger()},
+	}
+	for i, c := range []struct {
+		remoteAddr string
+		code       int
+	}{
+		{"10.0.0.1:1234", http.StatusBadRequest},
+		{"10.0.0.1:1234", http.StatusTooManyRequests},
+		{"10.0.0.2:1234", http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest("POST", "/events", bytes.NewBufferString("{}"))
+		Ok(t, err)
+		req.RemoteAddr = c.remoteAddr
+		req.Header.Set("X-Request-ID", fmt.Sprintf("request-%d", i))
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		Equals(t, c.code, w.Code)
+	}
+
+	// Payloads are uploaded in order so once the last one is uploaded the
+	// rate limited one would have been too.
+	for i := 0; i < 100 && store.len() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	Equals(t, 2, store.len())
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key := range store.objects {
+		Assert(t, !strings.Contains(key, "request-1"), "rate limited request was archived to %s", key)
+	}
+}
+
+// archiveStore is an archive.Store that keeps objects in memory.
+type archiveStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (a *archiveStore) Put(key string, body []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.objects[key] = body
+	return nil
+}
+
+func (a *archiveStore) Walk(string, func(string) bool) error { return nil }
+
+func (a *archiveStore) Delete(string) error { return nil }
+
+func (a *archiveStore) len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.objects)
+}
+
 func TestPost_UnsupportedGithubEvent(t *testing.T) {
 	t.Log("when the event type is an unsupported github event we ignore it")
 	e, v, _, _, _, _, _, _ := setup(t)
@@ -113,7 +321,7 @@ func TestPost_UnsupportedGitlabEvent(t *testing.T) {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn([]byte(`{"not an event": ""}`), nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn([]byte(`{"not an event": ""}`), nil)
 	e.Post(w, req)
 	responseContains(t, w, http.StatusOK, "Ignoring unsupported event")
 }
@@ -125,7 +333,7 @@ func TestPost_GitlabCommentOnCommit(t *testing.T) {
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	w := httptest.NewRecorder()
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.CommitCommentEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.CommitCommentEvent{}, nil)
 	e.Post(w, req)
 	responseContains(t, w, http.StatusOK, "Ignoring comment on commit event")
 }
@@ -137,7 +345,7 @@ func TestPost_GithubCommentNotCreated(t *testing.T) {
 	req.Header.Set(githubHeader, "issue_comment")
 	// comment action is deleted, not created
 	event := `{"action": "deleted"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	w := httptest.NewRecorder()
 	e.Post(w, req)
 	responseContains(t, w, http.StatusOK, "Ignoring comment event since action was not created")
@@ -149,7 +357,7 @@ func TestPost_GithubInvalidComment(t *testing.T) {
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(githubHeader, "issue_comment")
 	event := `{"action": "created"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(models.Repo{}, models.User{}, 1, errors.New("err"))
 	w := httptest.NewRecorder()
 	e.Post(w, req)
@@ -161,7 +369,7 @@ func TestPost_GitlabCommentInvalidCommand(t *testing.T) {
 	e, _, gl, _, _, _, _, cp := setup(t)
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
 	When(cp.Parse("", models.Gitlab)).ThenReturn(events.CommentParseResult{Ignore: true})
 	w := httptest.NewRecorder()
 	e.Post(w, req)
@@ -174,7 +382,7 @@ func TestPost_GithubCommentInvalidCommand(t *testing.T) {
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(githubHeader, "issue_comment")
 	event := `{"action": "created"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(models.Repo{}, models.User{}, 1, nil)
 	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Ignore: true})
 	w := httptest.NewRecorder()
@@ -302,7 +510,7 @@ func TestPost_GitlabCommentResponse(t *testing.T) {
 	e, _, gl, _, _, _, vcsClient, cp := setup(t)
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
 	When(cp.Parse("", models.Gitlab)).ThenReturn(events.CommentParseResult{CommentResponse: "a comment"})
 	w := httptest.NewRecorder()
 	e.Post(w, req)
@@ -316,7 +524,7 @@ func TestPost_GithubCommentResponse(t *testing.T) {
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(githubHeader, "issue_comment")
 	event := `{"action": "created"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	baseRepo := models.Repo{}
 	user := models.User{}
 	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, user, 1, nil)
@@ -328,12 +536,30 @@ func TestPost_GithubCommentResponse(t *testing.T) {
 	responseContains(t, w, http.StatusOK, "Commenting back on pull request")
 }
 
+func TestPost_GithubCommentHelpResponse(t *testing.T) {
+	t.Log("when the event is a github comment asking for help we comment back with the repo's help")
+	e, v, _, p, _, _, vcsClient, cp := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "issue_comment")
+	event := `{"action": "created"}`
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
+	baseRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, models.User{}, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{CommentResponse: "generic help", Help: true})
+	When(cp.HelpComment("github.com/owner/repo")).ThenReturn("repo help")
+	w := httptest.NewRecorder()
+
+	e.Post(w, req)
+	vcsClient.VerifyWasCalledOnce().CreateComment(baseRepo, 1, "repo help")
+	responseContains(t, w, http.StatusOK, "Commenting back on pull request")
+}
+
 func TestPost_GitlabCommentSuccess(t *testing.T) {
 	t.Log("when the event is a gitlab comment with a valid command we call the command handler")
 	e, _, gl, _, cr, _, _, _ := setup(t)
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeCommentEvent{}, nil)
 	w := httptest.NewRecorder()
 	e.Post(w, req)
 	responseContains(t, w, http.StatusOK, "Processing...")
@@ -347,7 +573,7 @@ func TestPost_GithubCommentSuccess(t *testing.T) {
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(githubHeader, "issue_comment")
 	event := `{"action": "created"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	baseRepo := models.Repo{}
 	user := models.User{}
 	cmd := events.CommentCommand{}
@@ -367,7 +593,7 @@ func TestPost_GithubPullRequestInvalid(t *testing.T) {
 	req.Header.Set(githubHeader, "pull_request")
 
 	event := `{"action": "closed"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(models.PullRequest{}, models.OpenedPullEvent, models.Repo{}, models.Repo{}, models.User{}, errors.New("err"))
 	w := httptest.NewRecorder()
 	e.Post(w, req)
@@ -379,7 +605,7 @@ func TestPost_GitlabMergeRequestInvalid(t *testing.T) {
 	e, _, gl, p, _, _, _, _ := setup(t)
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeEvent{}, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGitlabMergeRequestEvent(gitlab.MergeEvent{})).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, errors.New("err"))
@@ -398,7 +624,7 @@ func TestPost_GithubPullRequestNotWhitelisted(t *testing.T) {
 	req.Header.Set(githubHeader, "pull_request")
 
 	event := `{"action": "closed"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	w := httptest.NewRecorder()
 	e.Post(w, req)
 	responseContains(t, w, http.StatusForbidden, "Ignoring pull request event from non-whitelisted repo")
@@ -413,7 +639,7 @@ func TestPost_GitlabMergeRequestNotWhitelisted(t *testing.T) {
 	var err error
 	e.RepoWhitelistChecker, err = events.NewRepoWhitelistChecker("github.com/nevermatch")
 	Ok(t, err)
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeEvent{}, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGitlabMergeRequestEvent(gitlab.MergeEvent{})).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -423,6 +649,45 @@ func TestPost_GitlabMergeRequestNotWhitelisted(t *testing.T) {
 	responseContains(t, w, http.StatusForbidden, "Ignoring pull request event from non-whitelisted repo")
 }
 
+func TestPost_GitlabMergeRequestAutoRegistered(t *testing.T) {
+	t.Log("when the event is a gitlab merge request to a non-whitelisted repo that matches the auto-register rules we register the repo and autoplan")
+	e, _, gl, p, cr, _, _, _ := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(gitlabHeader, "value")
+
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	e.RepoWhitelistChecker, err = events.NewRepoWhitelistChecker("gitlab.com/nevermatch")
+	Ok(t, err)
+	e.RepoWhitelistChecker.OnboardedRepos = boltDB
+	e.RepoAutoRegistrar, err = events.NewRepoAutoRegistrar("gitlab.com/owner/*,!gitlab.com/owner/sandbox", boltDB, logging.NewNoopLogger())
+	Ok(t, err)
+
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeEvent{}, nil)
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "gitlab.com", Type: models.Gitlab}}
+	pullRequest := models.PullRequest{State: models.OpenPullState}
+	When(p.ParseGitlabMergeRequestEvent(gitlab.MergeEvent{})).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, nil)
+
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	responseContains(t, w, http.StatusOK, "Processing...")
+	cr.VerifyWasCalledOnce().RunAutoplanCommand(repo, repo, pullRequest, models.User{})
+	repos, err := boltDB.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, "gitlab.com/owner/repo", repos[0].ID)
+	Equals(t, events.AutoRegisteredBy, repos[0].OnboardedBy)
+
+	// Excluded repos aren't registered.
+	excluded := models.Repo{FullName: "owner/sandbox", VCSHost: models.VCSHost{Hostname: "gitlab.com", Type: models.Gitlab}}
+	When(p.ParseGitlabMergeRequestEvent(gitlab.MergeEvent{})).ThenReturn(pullRequest, models.ClosedPullEvent, excluded, excluded, models.User{}, nil)
+	w = httptest.NewRecorder()
+	e.Post(w, req)
+	responseContains(t, w, http.StatusForbidden, "Ignoring pull request event from non-whitelisted repo")
+}
+
 func TestPost_GithubPullRequestUnsupportedAction(t *testing.T) {
 	t.Skip("relies too much on mocks, should use real event parser")
 	e, v, _, _, _, _, _, _ := setup(t)
@@ -430,7 +695,7 @@ func TestPost_GithubPullRequestUnsupportedAction(t *testing.T) {
 	req.Header.Set(githubHeader, "pull_request")
 
 	event := `{"action": "unsupported"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	w := httptest.NewRecorder()
 	e.Parser = &events.EventParser{}
 	e.Post(w, req)
@@ -445,7 +710,7 @@ func TestPost_GitlabMergeRequestUnsupportedAction(t *testing.T) {
 	req.Header.Set(gitlabHeader, "value")
 	var event gitlab.MergeEvent
 	event.ObjectAttributes.Action = "unsupported"
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(event, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(event, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGitlabMergeRequestEvent(event)).ThenReturn(pullRequest, repo, repo, models.User{}, nil)
@@ -541,7 +806,7 @@ func TestPost_GithubPullRequestClosedErrCleaningPull(t *testing.T) {
 	req.Header.Set(githubHeader, "pull_request")
 
 	event := `{"action": "closed"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	repo := models.Repo{}
 	pull := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(pull, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -559,7 +824,7 @@ func TestPost_GitlabMergeRequestClosedErrCleaningPull(t *testing.T) {
 	req.Header.Set(gitlabHeader, "value")
 	var event gitlab.MergeEvent
 	event.ObjectAttributes.Action = "close"
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(event, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(event, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGitlabMergeRequestEvent(event)).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -577,7 +842,7 @@ func TestPost_GithubClosedPullRequestSuccess(t *testing.T) {
 	req.Header.Set(githubHeader, "pull_request")
 
 	event := `{"action": "closed"}`
-	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 	repo := models.Repo{}
 	pull := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(pull, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -593,7 +858,7 @@ func TestPost_GitlabMergeRequestSuccess(t *testing.T) {
 	e, _, gl, p, _, _, _, _ := setup(t)
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	req.Header.Set(gitlabHeader, "value")
-	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.MergeEvent{}, nil)
+	When(gl.ParseAndValidate(req, secrets)).ThenReturn(gitlab.MergeEvent{}, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.ClosedPullState}
 	When(p.ParseGitlabMergeRequestEvent(gitlab.MergeEvent{})).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -716,14 +981,14 @@ func TestPost_PullOpenedOrUpdated(t *testing.T) {
 				req.Header.Set(gitlabHeader, "value")
 				var event gitlab.MergeEvent
 				event.ObjectAttributes.Action = c.Action
-				When(gl.ParseAndValidate(req, secret)).ThenReturn(event, nil)
+				When(gl.ParseAndValidate(req, secrets)).ThenReturn(event, nil)
 				repo := models.Repo{}
 				pullRequest := models.PullRequest{State: models.ClosedPullState}
 				When(p.ParseGitlabMergeRequestEvent(event)).ThenReturn(pullRequest, models.OpenedPullEvent, repo, repo, models.User{}, nil)
 			case models.Github:
 				req.Header.Set(githubHeader, "pull_request")
 				event := fmt.Sprintf(`{"action": "%s"}`, c.Action)
-				When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+				When(v.Validate(req, secrets)).ThenReturn([]byte(event), nil)
 				repo := models.Repo{}
 				pull := models.PullRequest{State: models.ClosedPullState}
 				When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(pull, models.OpenedPullEvent, repo, repo, models.User{}, nil)
@@ -736,6 +1001,130 @@ func TestPost_PullOpenedOrUpdated(t *testing.T) {
 	}
 }
 
+func TestPost_PullOpenedQueuedUntilFull(t *testing.T) {
+	t.Log("when there's an event queue autoplans should be queued until it's full")
+	e, v, _, p, cr, _, _, _ := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	// The queue has no workers so the events stay queued.
+	e.EventQueue = &events.EventQueue{
+		Runner:   cr,
+		Store:    boltDB,
+		MaxDepth: 1,
+		Logger:   logging.NewNoopLogger(),
+	}
+	Ok(t, e.EventQueue.Start())
+	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(models.PullRequest{Num: 1}, models.OpenedPullEvent, models.Repo{}, models.Repo{}, models.User{}, nil)
+
+	for _, exp := range []struct {
+		code int
+		body string
+	}{
+		{http.StatusOK, "Queued autoplan"},
+		{http.StatusServiceUnavailable, "Unable to queue autoplan: event queue is full"},
+	} {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "pull_request")
+		When(v.Validate(req, secrets)).ThenReturn([]byte(`{"action": "opened"}`), nil)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		responseContains(t, w, exp.code, exp.body)
+	}
+	Equals(t, 1, e.EventQueue.Len())
+	cr.VerifyWasCalled(Never()).RunAutoplanCommand(matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyModelsUser())
+}
+
+func TestPost_DuplicateDeliveriesIgnored(t *testing.T) {
+	t.Log("when a webhook is delivered twice or the same commit is autoplanned twice the duplicate should be ignored")
+	e, v, _, p, cr, _, _, _ := setup(t)
+	e.Deduplicator = &events.WebhookDeduplicator{Window: 10 * time.Minute}
+	pull := models.PullRequest{Num: 1, HeadCommit: "abc"}
+	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(pull, models.OpenedPullEvent, models.Repo{}, models.Repo{}, models.User{}, nil)
+
+	for _, c := range []struct {
+		deliveryID string
+		validErr   error
+		code       int
+		body       string
+	}{
+		{"1", nil, http.StatusOK, "Processing..."},
+		{"1", nil, http.StatusOK, "Ignoring duplicate delivery 1"},
+		{"2", nil, http.StatusOK, "Ignoring duplicate autoplan of commit abc"},
+		// Deliveries that fail can be delivered again.
+		{"3", errors.New("err"), http.StatusBadRequest, "err"},
+		{"3", nil, http.StatusOK, "Ignoring duplicate autoplan of commit abc"},
+	} {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "pull_request")
+		req.Header.Set("X-Github-Delivery", c.deliveryID)
+		When(v.Validate(req, secrets)).ThenReturn([]byte(`{"action": "opened"}`), c.validErr)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		responseContains(t, w, c.code, c.body)
+	}
+	cr.VerifyWasCalledOnce().RunAutoplanCommand(models.Repo{}, models.Repo{}, pull, models.User{})
+}
+
+func TestPost_RateLimited(t *testing.T) {
+	t.Log("when an IP or repo exceeds its rate limit webhooks should be rejected with a 429")
+	e, v, _, p, cr, _, _, _ := setup(t)
+	e.RateLimiter = &events.EventRateLimiter{PerIP: 2, PerRepo: 1, Logger: logging.NewNoopLogger()}
+	repo := models.Repo{FullName: "owner/repo"}
+	When(p.ParseGithubPullEvent(matchers.AnyPtrToGithubPullRequestEvent())).ThenReturn(models.PullRequest{Num: 1}, models.OpenedPullEvent, repo, repo, models.User{}, nil)
+
+	for _, c := range []struct {
+		code int
+		body string
+	}{
+		{http.StatusOK, "Processing..."},
+		{http.StatusTooManyRequests, "Rate limit exceeded for repo owner/repo"},
+		{http.StatusTooManyRequests, "Rate limit exceeded for 10.0.0.1"},
+	} {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(githubHeader, "pull_request")
+		When(v.Validate(req, secrets)).ThenReturn([]byte(`{"action": "opened"}`), nil)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		responseContains(t, w, c.code, c.body)
+	}
+	cr.VerifyWasCalledOnce().RunAutoplanCommand(repo, repo, models.PullRequest{Num: 1}, models.User{})
+}
+
+func TestPost_RateLimitedBehindTrustedProxy(t *testing.T) {
+	t.Log("when a webhook is sent by a trusted proxy it should be rate limited by the IP it forwarded")
+	e, _, _, _, _, _, _, _ := setup(t)
+	e.RateLimiter = &events.EventRateLimiter{PerIP: 1, Logger: logging.NewNoopLogger()}
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	Ok(t, err)
+	e.TrustedProxies = []*net.IPNet{proxies}
+
+	for _, c := range []struct {
+		remoteAddr string
+		forwarded  string
+		code       int
+		body       string
+	}{
+		{"10.0.0.1:1234", "1.1.1.1, 2.2.2.2, 10.0.0.2", http.StatusBadRequest, "Ignoring request"},
+		{"10.0.0.3:1234", "2.2.2.2", http.StatusTooManyRequests, "Rate limit exceeded for 2.2.2.2"},
+		// The client can set X-Forwarded-For to anything so only the last
+		// untrusted IP is used.
+		{"10.0.0.1:1234", "3.3.3.3, 2.2.2.2", http.StatusTooManyRequests, "Rate limit exceeded for 2.2.2.2"},
+		// Untrusted IPs can't set X-Forwarded-For.
+		{"2.2.2.2:1234", "3.3.3.3", http.StatusTooManyRequests, "Rate limit exceeded for 2.2.2.2"},
+		{"4.4.4.4:1234", "2.2.2.2", http.StatusBadRequest, "Ignoring request"},
+	} {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.RemoteAddr = c.remoteAddr
+		req.Header.Set("X-Forwarded-For", c.forwarded)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		responseContains(t, w, c.code, c.body)
+	}
+}
+
 func setup(t *testing.T) (server.EventsController, *mocks.MockGithubRequestValidator, *mocks.MockGitlabRequestParserValidator, *emocks.MockEventParsing, *emocks.MockCommandRunner, *emocks.MockPullCleaner, *vcsmocks.MockClient, *emocks.MockCommentParsing) {
 	RegisterMockTestingT(t)
 	v := mocks.NewMockGithubRequestValidator()
@@ -755,9 +1144,9 @@ func setup(t *testing.T) (server.EventsController, *mocks.MockGithubRequestValid
 		CommentParser:                cp,
 		CommandRunner:                cr,
 		PullCleaner:                  c,
-		GithubWebhookSecret:          secret,
+		GithubWebhookSecrets:         secrets,
 		SupportedVCSHosts:            []models.VCSHostType{models.Github, models.Gitlab},
-		GitlabWebhookSecret:          secret,
+		GitlabWebhookSecrets:         secrets,
 		GitlabRequestParserValidator: gl,
 		RepoWhitelistChecker:         repoWhitelistChecker,
 		VCSClient:                    vcsmock,
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// failedEventRedactedHeaders are the headers whose values aren't returned by
+// the API since they contain webhook secrets or credentials.
+var failedEventRedactedHeaders = []string{
+	"Authorization",
+	"X-Gitlab-Token",
+}
+
+// FailedEventsController lists the webhook requests that failed to be handled
+// and replays them, ex. after a misconfigured webhook secret is fixed.
+type FailedEventsController struct {
+	FailedEvents *events.FailedEvents
+	// Events handles the replayed requests like it handles the ones from
+	// the VCS hosts.
+	Events *EventsController
+	Logger *logging.SimpleLogger
+}
+
+// FailedEventResponse is a failed webhook request in the responses of the
+// failed events routes.
+type FailedEventResponse struct {
+	ID         uint64              `json:"id"`
+	ReceivedAt time.Time           `json:"received_at"`
+	RemoteAddr string              `json:"remote_addr"`
+	Header     map[string][]string `json:"header"`
+	Body       string              `json:"body"`
+	Status     int                 `json:"status"`
+	Response   string              `json:"response"`
+	Replays    int                 `json:"replays"`
+}
+
+// ListFailedEventsResponse is the response of the GET /api/events/failed
+// route.
+type ListFailedEventsResponse struct {
+	Events []FailedEventResponse `json:"events"`
+}
+
+// ReplayFailedEventResponse is the response of the
+// POST /api/events/{id}/replay route.
+type ReplayFailedEventResponse struct {
+	ID uint64 `json:"id"`
+	// Status and Response are the status code and body of the response to
+	// the replayed request.
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+	// Deleted is true if the replay succeeded and the event was deleted.
+	Deleted bool `json:"deleted"`
+}
+
+// ListFailedEvents is the GET /api/events/failed route. It returns the stored
+// failed webhook requests as JSON, oldest first.
+func (f *FailedEventsController) ListFailedEvents(w http.ResponseWriter, r *http.Request) {
+	stored, err := f.FailedEvents.List()
+	if err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Error listing failed events: %s", err)
+		return
+	}
+	resp := ListFailedEventsResponse{Events: []FailedEventResponse{}}
+	for _, event := range stored {
+		resp.Events = append(resp.Events, newFailedEventResponse(event))
+	}
+	f.writeJSON(w, resp)
+}
+
+// ReplayFailedEvent is the POST /api/events/{id}/replay route. It handles the
+// stored webhook request with id again. Its signature is validated again so
+// the replay only succeeds once the problem that made it fail is fixed. If it
+// succeeds the event is deleted, otherwise it's kept with the new response.
+func (f *FailedEventsController) ReplayFailedEvent(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		respond(w, f.Logger, logging.Warn, http.StatusBadRequest, "Invalid event id %q", idStr)
+		return
+	}
+	event, err := f.FailedEvents.Get(id)
+	if err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Error getting failed event %d: %s", id, err)
+		return
+	}
+	if event == nil {
+		respond(w, f.Logger, logging.Info, http.StatusNotFound, "No failed event with id %d", id)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/events", bytes.NewReader(event.Body))
+	if err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Error building request for failed event %d: %s", id, err)
+		return
+	}
+	req.Header = http.Header(event.Header).Clone()
+	req.RemoteAddr = event.RemoteAddr
+	// The request is handled without Post so that it isn't archived or rate
+	// limited again and isn't stored as a new failed event if it fails.
+	rec := httptest.NewRecorder()
+	f.Events.handle(rec, req)
+
+	response := strings.TrimSpace(rec.Body.String())
+	if len(response) > maxFailedEventResponse {
+		response = response[:maxFailedEventResponse]
+	}
+	if err := f.FailedEvents.Replayed(*event, rec.Code, response); err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Error updating failed event %d: %s", id, err)
+		return
+	}
+	deleted := rec.Code < 400
+	f.Logger.Info("replayed failed event %d, response was %d", id, rec.Code)
+	f.writeJSON(w, ReplayFailedEventResponse{
+		ID:       id,
+		Status:   rec.Code,
+		Response: response,
+		Deleted:  deleted,
+	})
+}
+
+func newFailedEventResponse(event models.FailedEvent) FailedEventResponse {
+	header := http.Header(event.Header).Clone()
+	for _, h := range failedEventRedactedHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, "<redacted>")
+		}
+	}
+	return FailedEventResponse{
+		ID:         event.ID,
+		ReceivedAt: event.ReceivedAt,
+		RemoteAddr: event.RemoteAddr,
+		Header:     header,
+		Body:       string(event.Body),
+		Status:     event.Status,
+		Response:   event.Response,
+		Replays:    event.Replays,
+	}
+}
+
+func (f *FailedEventsController) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.Logger.Err("writing response: %s", err)
+	}
+}
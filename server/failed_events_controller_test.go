@@ -0,0 +1,116 @@
+package server_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFailedEvents_RecordListReplay(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+	failedEvents := &events.FailedEvents{Store: store, Max: events.DefaultMaxFailedEvents, Logger: logging.NewNoopLogger()}
+	// The webhook is signed with a secret that the server doesn't have yet.
+	e := &server.EventsController{
+		Logger:                  logging.NewNoopLogger(),
+		SupportedVCSHosts:       []models.VCSHostType{models.BitbucketServer},
+		BitbucketWebhookSecrets: [][]byte{[]byte("old-secret")},
+		FailedEvents:            failedEvents,
+	}
+	fc := &server.FailedEventsController{
+		FailedEvents: failedEvents,
+		Events:       e,
+		Logger:       logging.NewNoopLogger(),
+	}
+
+	body := []byte(`{"eventKey":"repo:refs_changed"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body) // nolint: errcheck
+	req, err := http.NewRequest("POST", "/events", bytes.NewBuffer(body))
+	Ok(t, err)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Event-Key", "repo:refs_changed")
+	req.Header.Set("X-Request-ID", "request-1")
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	req.Header.Set("Cookie", "session=abc")
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	responseContains(t, w, http.StatusBadRequest, "payload signature check failed")
+
+	// Requests that aren't webhooks aren't recorded.
+	req, err = http.NewRequest("POST", "/events", bytes.NewBuffer(nil))
+	Ok(t, err)
+	w = httptest.NewRecorder()
+	e.Post(w, req)
+	responseContains(t, w, http.StatusBadRequest, "Ignoring request")
+
+	w = httptest.NewRecorder()
+	fc.ListFailedEvents(w, httptest.NewRequest("GET", "/api/events/failed", nil))
+	Equals(t, http.StatusOK, w.Code)
+	var list server.ListFailedEventsResponse
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &list))
+	Equals(t, 1, len(list.Events))
+	listed := list.Events[0]
+	Equals(t, uint64(1), listed.ID)
+	Equals(t, "10.0.0.1:1234", listed.RemoteAddr)
+	Equals(t, string(body), listed.Body)
+	Equals(t, http.StatusBadRequest, listed.Status)
+	Assert(t, listed.Response != "", "exp response to be stored")
+	Equals(t, []string{"<redacted>"}, listed.Header["Authorization"])
+	Equals(t, []string{"repo:refs_changed"}, listed.Header["X-Event-Key"])
+	_, ok := listed.Header["Cookie"]
+	Assert(t, !ok, "exp cookie not to be stored")
+
+	replay := func(id string) (int, server.ReplayFailedEventResponse) {
+		req := httptest.NewRequest("POST", "/api/events/"+id+"/replay", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		fc.ReplayFailedEvent(w, req)
+		var resp server.ReplayFailedEventResponse
+		if w.Code == http.StatusOK {
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	// Replaying before the secret is fixed fails again and keeps the event.
+	code, resp := replay("1")
+	Equals(t, http.StatusOK, code)
+	Equals(t, http.StatusBadRequest, resp.Status)
+	Equals(t, false, resp.Deleted)
+	stored, err := store.GetFailedEvent(1)
+	Ok(t, err)
+	Equals(t, 1, stored.Replays)
+
+	// Replaying after the secret is fixed succeeds and deletes the event.
+	e.BitbucketWebhookSecrets = [][]byte{[]byte("secret")}
+	code, resp = replay("1")
+	Equals(t, http.StatusOK, code)
+	Equals(t, http.StatusOK, resp.Status)
+	Equals(t, "Ignoring unsupported event type repo:refs_changed X-Request-ID=request-1", resp.Response)
+	Equals(t, true, resp.Deleted)
+	stored, err = store.GetFailedEvent(1)
+	Ok(t, err)
+	Assert(t, stored == nil, "exp replayed event to be deleted")
+
+	code, _ = replay("1")
+	Equals(t, http.StatusNotFound, code)
+	code, _ = replay("abc")
+	Equals(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/featureflags"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// FeatureFlagsController handles requests to manage feature flags.
+type FeatureFlagsController struct {
+	Store  *featureflags.Store
+	Logger *logging.SimpleLogger
+}
+
+// FeatureFlagData is the API representation of a feature flag.
+type FeatureFlagData struct {
+	Name       string   `json:"name"`
+	Enabled    bool     `json:"enabled"`
+	Repos      []string `json:"repos"`
+	Percentage int      `json:"percentage"`
+	// Source is where the flag was set, either "file" or "api".
+	Source string `json:"source,omitempty"`
+	// EnabledForRepo is whether the flag is enabled for the repo in the
+	// ?repo= query param, if it was passed.
+	EnabledForRepo *bool `json:"enabled_for_repo,omitempty"`
+}
+
+// ListFlags is the GET /api/feature-flags route. If the repo query param is
+// set, ex. ?repo=github.com/owner/repo, the response includes whether each
+// flag is enabled for that repo.
+func (f *FeatureFlagsController) ListFlags(w http.ResponseWriter, r *http.Request) {
+	repoID := r.URL.Query().Get("repo")
+	flags := []FeatureFlagData{}
+	for _, s := range f.Store.List() {
+		data := FeatureFlagData{
+			Name:       s.Name,
+			Enabled:    s.Enabled,
+			Repos:      s.Repos,
+			Percentage: s.Percentage,
+			Source:     s.Source,
+		}
+		if data.Repos == nil {
+			data.Repos = []string{}
+		}
+		if repoID != "" {
+			enabled := f.Store.Enabled(s.Name, repoID)
+			data.EnabledForRepo = &enabled
+		}
+		flags = append(flags, data)
+	}
+	f.respondJSON(w, http.StatusOK, flags)
+}
+
+// SetFlag is the PUT /api/feature-flags/{name} route. It creates or replaces
+// the flag, overriding any flag with the same name in the flags file.
+func (f *FeatureFlagsController) SetFlag(w http.ResponseWriter, r *http.Request) {
+	var req FeatureFlagData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, f.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+		return
+	}
+	flag := models.FeatureFlag{
+		Name:       mux.Vars(r)["name"],
+		Enabled:    req.Enabled,
+		Repos:      req.Repos,
+		Percentage: req.Percentage,
+	}
+	if err := featureflags.Validate(flag); err != nil {
+		respond(w, f.Logger, logging.Warn, http.StatusBadRequest, "Invalid feature flag: %s", err)
+		return
+	}
+	if err := f.Store.Set(flag); err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Failed saving feature flag: %s", err)
+		return
+	}
+	f.Logger.Info("set feature flag %q to enabled: %t, repos: %v, percentage: %d", flag.Name, flag.Enabled, flag.Repos, flag.Percentage)
+	respond(w, f.Logger, logging.Info, http.StatusOK, "Set feature flag %q", flag.Name)
+}
+
+// DeleteFlag is the DELETE /api/feature-flags/{name} route. It deletes a flag
+// that was set via the API. If the flags file has a flag with the same name,
+// it's used again.
+func (f *FeatureFlagsController) DeleteFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	deleted, err := f.Store.Delete(name)
+	if err != nil {
+		respond(w, f.Logger, logging.Error, http.StatusInternalServerError, "Failed deleting feature flag: %s", err)
+		return
+	}
+	if !deleted {
+		respond(w, f.Logger, logging.Info, http.StatusNotFound, "No feature flag named %q was set via the API", name)
+		return
+	}
+	respond(w, f.Logger, logging.Info, http.StatusOK, "Deleted feature flag %q", name)
+}
+
+// respondJSON writes data as JSON.
+func (f *FeatureFlagsController) respondJSON(w http.ResponseWriter, responseCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(responseCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		f.Logger.Err("writing response: %s", err)
+	}
+}
@@ -0,0 +1,86 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/featureflags"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newFeatureFlagsController(t *testing.T) (*server.FeatureFlagsController, func()) {
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	store, err := featureflags.NewStore("", boltDB)
+	Ok(t, err)
+	return &server.FeatureFlagsController{
+		Store:  store,
+		Logger: logging.NewNoopLogger(),
+	}, cleanup
+}
+
+func setFlag(c *server.FeatureFlagsController, name string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PUT", "/api/feature-flags/"+name, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{"name": name})
+	w := httptest.NewRecorder()
+	c.SetFlag(w, req)
+	return w
+}
+
+func listFlags(t *testing.T, c *server.FeatureFlagsController, url string) []server.FeatureFlagData {
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	c.ListFlags(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var flags []server.FeatureFlagData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &flags))
+	return flags
+}
+
+func TestFeatureFlagsController_SetListDelete(t *testing.T) {
+	c, cleanup := newFeatureFlagsController(t)
+	defer cleanup()
+	Equals(t, []server.FeatureFlagData{}, listFlags(t, c, "/api/feature-flags"))
+
+	w := setFlag(c, "parallel-apply", `{"repos": ["github.com/owner/repo"]}`)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, []server.FeatureFlagData{
+		{Name: "parallel-apply", Repos: []string{"github.com/owner/repo"}, Source: featureflags.APISource},
+	}, listFlags(t, c, "/api/feature-flags"))
+
+	enabled, disabled := true, false
+	Equals(t, &enabled, listFlags(t, c, "/api/feature-flags?repo=github.com/owner/repo")[0].EnabledForRepo)
+	Equals(t, &disabled, listFlags(t, c, "/api/feature-flags?repo=github.com/owner/other")[0].EnabledForRepo)
+
+	req, _ := http.NewRequest("DELETE", "/api/feature-flags/parallel-apply", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "parallel-apply"})
+	w = httptest.NewRecorder()
+	c.DeleteFlag(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, []server.FeatureFlagData{}, listFlags(t, c, "/api/feature-flags"))
+
+	w = httptest.NewRecorder()
+	c.DeleteFlag(w, req)
+	Equals(t, http.StatusNotFound, w.Code)
+}
+
+func TestFeatureFlagsController_SetInvalid(t *testing.T) {
+	c, cleanup := newFeatureFlagsController(t)
+	defer cleanup()
+
+	w := setFlag(c, "parallel-apply", `{"percentage": 150}`)
+	Equals(t, http.StatusBadRequest, w.Code)
+	Equals(t, "Invalid feature flag: flag \"parallel-apply\": percentage must be between 0 and 100, got 150\n", w.Body.String())
+
+	w = setFlag(c, "parallel-apply", `not json`)
+	Equals(t, http.StatusBadRequest, w.Code)
+	Equals(t, 0, len(listFlags(t, c, "/api/feature-flags")))
+}
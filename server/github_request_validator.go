@@ -14,6 +14,7 @@
 package server
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -25,36 +26,51 @@ import (
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_request_validator.go GithubRequestValidator
 
 // GithubRequestValidator handles checking if GitHub requests are signed
-// properly by the secret.
+// properly by one of the secrets.
 type GithubRequestValidator interface {
 	// Validate returns the JSON payload of the request.
-	// If secret is not empty, it checks that the request was signed
-	// by secret and returns an error if it was not.
-	// If secret is empty, it does not check if the request was signed.
-	Validate(r *http.Request, secret []byte) ([]byte, error)
+	// If secrets is not empty, it checks that the request was signed
+	// by one of secrets, trying them in order, and returns a
+	// *WebhookSignatureError if it was not.
+	// If secrets is empty, it does not check if the request was signed.
+	Validate(r *http.Request, secrets [][]byte) ([]byte, error)
 }
 
 // DefaultGithubRequestValidator handles checking if GitHub requests are signed
-// properly by the secret.
+// properly by one of the secrets.
 type DefaultGithubRequestValidator struct{}
 
 // Validate returns the JSON payload of the request.
-// If secret is not empty, it checks that the request was signed
-// by secret and returns an error if it was not.
-// If secret is empty, it does not check if the request was signed.
-func (d *DefaultGithubRequestValidator) Validate(r *http.Request, secret []byte) ([]byte, error) {
-	if len(secret) != 0 {
-		return d.validateAgainstSecret(r, secret)
+// See GithubRequestValidator.Validate().
+func (d *DefaultGithubRequestValidator) Validate(r *http.Request, secrets [][]byte) ([]byte, error) {
+	if len(secrets) != 0 {
+		return d.validateAgainstSecrets(r, secrets)
 	}
 	return d.validateWithoutSecret(r)
 }
 
-func (d *DefaultGithubRequestValidator) validateAgainstSecret(r *http.Request, secret []byte) ([]byte, error) {
-	payload, err := github.ValidatePayload(r, secret)
+func (d *DefaultGithubRequestValidator) validateAgainstSecrets(r *http.Request, secrets [][]byte) ([]byte, error) {
+	switch ct := r.Header.Get("Content-Type"); ct {
+	case "application/json", "application/x-www-form-urlencoded":
+	default:
+		return nil, fmt.Errorf("webhook request has unsupported Content-Type %q", ct)
+	}
+	// The body can only be read once so we buffer it in order to check it
+	// against each secret.
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not read body: %s", err)
+	}
+	var lastErr error
+	for _, secret := range secrets {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		payload, err := github.ValidatePayload(r, secret)
+		if err == nil {
+			return payload, nil
+		}
+		lastErr = err
 	}
-	return payload, nil
+	return nil, &WebhookSignatureError{Err: lastErr}
 }
 
 func (d *DefaultGithubRequestValidator) validateWithoutSecret(r *http.Request) ([]byte, error) {
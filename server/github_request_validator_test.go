@@ -34,7 +34,7 @@ func TestValidate_WithSecretErr(t *testing.T) {
 	req.Header.Set("X-Hub-Signature", "sha1=126f2c800419c60137ce748d7672e77b65cf16d6")
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = g.Validate(req, []byte("secret"))
+	_, err = g.Validate(req, [][]byte{[]byte("secret")})
 	Assert(t, err != nil, "error should not be nil")
 	Equals(t, "payload signature check failed", err.Error())
 }
@@ -49,11 +49,57 @@ func TestValidate_WithSecret(t *testing.T) {
 	req.Header.Set("X-Hub-Signature", "sha1=126f2c800419c60137ce748d7672e77b65cf16d6")
 	req.Header.Set("Content-Type", "application/json")
 
-	bs, err := g.Validate(req, []byte("0123456789abcdef"))
+	bs, err := g.Validate(req, [][]byte{[]byte("0123456789abcdef")})
 	Ok(t, err)
 	Equals(t, `{"yo":true}`, string(bs))
 }
 
+func TestValidate_WithSecretRotation(t *testing.T) {
+	t.Log("if the request is valid against any of the secrets the payload is returned")
+	RegisterMockTestingT(t)
+	g := server.DefaultGithubRequestValidator{}
+	buf := bytes.NewBufferString(`{"yo":true}`)
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("X-Hub-Signature", "sha1=126f2c800419c60137ce748d7672e77b65cf16d6")
+	req.Header.Set("Content-Type", "application/json")
+
+	bs, err := g.Validate(req, [][]byte{[]byte("new-secret"), []byte("0123456789abcdef")})
+	Ok(t, err)
+	Equals(t, `{"yo":true}`, string(bs))
+}
+
+func TestValidate_WithSecretsErr(t *testing.T) {
+	t.Log("if the request is not valid against any of the secrets there is a signature error")
+	RegisterMockTestingT(t)
+	g := server.DefaultGithubRequestValidator{}
+	buf := bytes.NewBufferString(`{"yo":true}`)
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("X-Hub-Signature", "sha1=126f2c800419c60137ce748d7672e77b65cf16d6")
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = g.Validate(req, [][]byte{[]byte("new-secret"), []byte("old-secret")})
+	Equals(t, "payload signature check failed", err.Error())
+	_, ok := err.(*server.WebhookSignatureError)
+	Assert(t, ok, "expected *server.WebhookSignatureError, got %T", err)
+}
+
+func TestValidate_WithSecretInvalidContentType(t *testing.T) {
+	t.Log("if the request has an invalid content type an error that isn't a signature error is returned")
+	RegisterMockTestingT(t)
+	g := server.DefaultGithubRequestValidator{}
+	buf := bytes.NewBufferString("")
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("Content-Type", "invalid")
+
+	_, err = g.Validate(req, [][]byte{[]byte("secret")})
+	Equals(t, "webhook request has unsupported Content-Type \"invalid\"", err.Error())
+	_, ok := err.(*server.WebhookSignatureError)
+	Assert(t, !ok, "expected non signature error")
+}
+
 func TestValidate_WithoutSecretInvalidContentType(t *testing.T) {
 	t.Log("if the request has an invalid content type an error is returned")
 	RegisterMockTestingT(t)
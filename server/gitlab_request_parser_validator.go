@@ -28,14 +28,14 @@ const secretHeader = "X-Gitlab-Token" // #nosec
 
 // GitlabRequestParserValidator parses and validates GitLab requests.
 type GitlabRequestParserValidator interface {
-	// ParseAndValidate validates that the request has a token header matching secret.
-	// If the secret does not match it returns an error.
-	// If secret is empty it does not check the token header.
+	// ParseAndValidate validates that the request has a token header matching
+	// one of secrets. If none of them match it returns a *WebhookSignatureError.
+	// If secrets is empty it does not check the token header.
 	// It then parses the request as a GitLab object depending on the header
 	// provided by GitLab identifying the webhook type. If the webhook type
 	// is not recognized it will return nil but will not return an error.
 	// Usage:
-	//	event, err := GitlabRequestParserValidator.ParseAndValidate(r, secret)
+	//	event, err := GitlabRequestParserValidator.ParseAndValidate(r, secrets)
 	//	if err != nil {
 	//		return
 	//	}
@@ -47,7 +47,7 @@ type GitlabRequestParserValidator interface {
 	//	default:
 	//		// unsupported event
 	//	}
-	ParseAndValidate(r *http.Request, secret []byte) (interface{}, error)
+	ParseAndValidate(r *http.Request, secrets [][]byte) (interface{}, error)
 }
 
 // DefaultGitlabRequestParserValidator parses and validates GitLab requests.
@@ -55,15 +55,23 @@ type DefaultGitlabRequestParserValidator struct{}
 
 // ParseAndValidate returns the JSON payload of the request.
 // See GitlabRequestParserValidator.ParseAndValidate().
-func (d *DefaultGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secret []byte) (interface{}, error) {
+func (d *DefaultGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secrets [][]byte) (interface{}, error) {
 	const mergeEventHeader = "Merge Request Hook"
 	const noteEventHeader = "Note Hook"
 
 	// Validate secret if specified.
-	headerSecret := r.Header.Get(secretHeader)
-	secretStr := string(secret)
-	if len(secret) != 0 && headerSecret != secretStr {
-		return nil, fmt.Errorf("header %s=%s did not match expected secret", secretHeader, headerSecret)
+	if len(secrets) != 0 {
+		headerSecret := r.Header.Get(secretHeader)
+		matched := false
+		for _, secret := range secrets {
+			if headerSecret == string(secret) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, &WebhookSignatureError{Err: fmt.Errorf("header %s=%s did not match expected secret", secretHeader, headerSecret)}
+		}
 	}
 
 	// Parse request into a gitlab object based on the object type specified
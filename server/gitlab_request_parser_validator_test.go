@@ -34,7 +34,7 @@ func TestValidate_InvalidSecret(t *testing.T) {
 	req, err := http.NewRequest("POST", "http://localhost/event", buf)
 	Ok(t, err)
 	req.Header.Set("X-Gitlab-Token", "does-not-match")
-	_, err = parser.ParseAndValidate(req, []byte("secret"))
+	_, err = parser.ParseAndValidate(req, [][]byte{[]byte("secret")})
 	Assert(t, err != nil, "should be an error")
 	Equals(t, "header X-Gitlab-Token=does-not-match did not match expected secret", err.Error())
 }
@@ -47,7 +47,20 @@ func TestValidate_ValidSecret(t *testing.T) {
 	Ok(t, err)
 	req.Header.Set("X-Gitlab-Token", "secret")
 	req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
-	b, err := parser.ParseAndValidate(req, []byte("secret"))
+	b, err := parser.ParseAndValidate(req, [][]byte{[]byte("secret")})
+	Ok(t, err)
+	Equals(t, "atlantis-example", b.(gitlab.MergeEvent).Project.Name)
+}
+
+func TestValidate_ValidSecretRotation(t *testing.T) {
+	t.Log("If the secret header matches any of the secrets then the event is returned")
+	RegisterMockTestingT(t)
+	buf := bytes.NewBufferString(mergeEventJSON)
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("X-Gitlab-Token", "old")
+	req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+	b, err := parser.ParseAndValidate(req, [][]byte{[]byte("new"), []byte("old")})
 	Ok(t, err)
 	Equals(t, "atlantis-example", b.(gitlab.MergeEvent).Project.Name)
 }
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/runatlantis/atlantis/server/apipb"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcMethodScopes maps from the full name of each gRPC method to the API
+// scope that's needed to call it.
+var grpcMethodScopes = map[string]string{
+	"/atlantis.v1.Atlantis/ListLocks":     LocksReadScope,
+	"/atlantis.v1.Atlantis/Plan":          PlanTriggerScope,
+	"/atlantis.v1.Atlantis/Apply":         ApplyTriggerScope,
+	"/atlantis.v1.Atlantis/StreamJobLogs": PlansReadScope,
+}
+
+// grpcTokenRequiredMethods are the methods that always need an API token,
+// even if no tokens have been configured, because they run plans and
+// applies.
+var grpcTokenRequiredMethods = map[string]bool{
+	"/atlantis.v1.Atlantis/Plan":  true,
+	"/atlantis.v1.Atlantis/Apply": true,
+}
+
+// GRPCServer implements the gRPC API. It mirrors the REST API so platforms
+// can use generated clients instead of parsing JSON.
+type GRPCServer struct {
+	Authenticator *APIAuthenticator
+	Locker        locking.Locker
+	DB            db.PullStatusStore
+	CommandRunner events.CommandRunner
+	JobLogs       *events.JobLogs
+	Logger        *logging.SimpleLogger
+}
+
+// grpcUserKey is the context key of the user that made a gRPC call.
+type grpcUserKey struct{}
+
+// NewGRPCServer returns a gRPC server that serves s and authenticates calls
+// with s.Authenticator. opts are passed to grpc.NewServer.
+func NewGRPCServer(s *GRPCServer, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := s.authenticate(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+		}),
+	)
+	server := grpc.NewServer(opts...)
+	apipb.RegisterAtlantisServer(server, s)
+	return server
+}
+
+// authenticatedStream is a stream whose context has the user that made the
+// call.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authenticatedStream) Context() context.Context {
+	return a.ctx
+}
+
+// authenticate checks that the API token in ctx's authorization metadata has
+// the scope of method. It returns ctx with the token's user. Like the REST
+// API, calls without a token are allowed if no tokens have been configured,
+// unless the web UI requires users to log in. Plans and applies always need a
+// token, otherwise enabling the gRPC API would let anyone who can reach its
+// port apply.
+func (s *GRPCServer) authenticate(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := grpcMethodScopes[method]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	tokens, err := s.Authenticator.tokens()
+	if err != nil {
+		s.Logger.Err("getting API tokens: %s", err)
+		return nil, status.Error(codes.Internal, "getting API tokens")
+	}
+
+	var raw string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		const prefix = "bearer "
+		if len(values[0]) > len(prefix) && strings.EqualFold(values[0][:len(prefix)], prefix) {
+			raw = strings.TrimSpace(values[0][len(prefix):])
+		}
+	}
+	if raw == "" {
+		if len(tokens) > 0 || s.Authenticator.WebAuthenticator != nil || grpcTokenRequiredMethods[method] {
+			return nil, status.Error(codes.Unauthenticated, "an API token is required")
+		}
+		return context.WithValue(ctx, grpcUserKey{}, "api"), nil
+	}
+	token := findAPIToken(tokens, raw)
+	if token == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid API token")
+	}
+	if !token.HasScope(scope) {
+		s.Logger.Warn("rejecting call to %s from API token %q because it doesn't have scope %q", method, token.Name, scope)
+		return nil, status.Errorf(codes.PermissionDenied, "API token %q does not have scope %q", token.Name, scope)
+	}
+	return context.WithValue(ctx, grpcUserKey{}, "api-token:"+token.Name), nil
+}
+
+// ListLocks returns the locks that pass the request's filter.
+func (s *GRPCServer) ListLocks(ctx context.Context, req *apipb.ListLocksRequest) (*apipb.ListLocksResponse, error) {
+	query := url.Values{}
+	for k, v := range map[string]string{"repo": req.Repo, "user": req.User, "older_than": req.OlderThan, "q": req.Search, "sort": req.Sort} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	if req.PullNum != 0 {
+		query.Set("pull", strconv.Itoa(int(req.PullNum)))
+	}
+	filter, err := ParseLockFilter(query)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %s", err)
+	}
+	locks, err := s.Locker.List()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "listing locks: %s", err)
+	}
+	resp := &apipb.ListLocksResponse{}
+	for _, id := range filter.Apply(locks, time.Now()) {
+		lock := locks[id]
+		lockTime, err := ptypes.TimestampProto(lock.Time)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "converting time of lock %s: %s", id, err)
+		}
+		resp.Locks = append(resp.Locks, &apipb.Lock{
+			Id:        id,
+			ProjectId: lock.ProjectID(),
+			Repo:      lock.Project.RepoFullName,
+			Path:      lock.Project.Path,
+			Workspace: lock.Workspace,
+			PullNum:   int32(lock.Pull.Num),
+			PullUrl:   lock.Pull.URL,
+			User:      lock.User.Username,
+			Time:      lockTime,
+		})
+	}
+	return resp, nil
+}
+
+// Plan runs plan on the request's pull request.
+func (s *GRPCServer) Plan(ctx context.Context, req *apipb.PlanRequest) (*apipb.CommandResponse, error) {
+	if err := validateRepoRelDir(req.Dir); err != nil {
+		return nil, err
+	}
+	cmd := events.NewCommentCommand(req.Dir, req.ExtraArgs, models.PlanCommand, req.Verbose, req.Workspace, req.Project)
+	cmd.Destroy = req.Destroy
+	cmd.VarFile = req.VarFile
+	return s.run(ctx, req.Repo, int(req.PullNum), cmd)
+}
+
+// Apply runs apply on the request's pull request.
+func (s *GRPCServer) Apply(ctx context.Context, req *apipb.ApplyRequest) (*apipb.CommandResponse, error) {
+	if err := validateRepoRelDir(req.Dir); err != nil {
+		return nil, err
+	}
+	cmd := events.NewCommentCommand(req.Dir, nil, models.ApplyCommand, req.Verbose, req.Workspace, req.Project)
+	cmd.ConfirmDestroy = req.ConfirmDestroy
+	cmd.BreakGlass = req.BreakGlass
+	return s.run(ctx, req.Repo, int(req.PullNum), cmd)
+}
+
+// StreamJobLogs streams the logs of the request's project's job.
+func (s *GRPCServer) StreamJobLogs(req *apipb.StreamJobLogsRequest, stream apipb.Atlantis_StreamJobLogsServer) error {
+	if req.Repo == "" || req.PullNum <= 0 {
+		return status.Error(codes.InvalidArgument, "repo and pull_num are required")
+	}
+	if err := validateRepoRelDir(req.Dir); err != nil {
+		return err
+	}
+	dir := events.NewCommentCommand(req.Dir, nil, models.PlanCommand, false, "", "").RepoRelDir
+	if dir == "" {
+		dir = "."
+	}
+	workspace := req.Workspace
+	if workspace == "" {
+		workspace = events.DefaultWorkspace
+	}
+	// Jobs are keyed by the repo's name as the VCS host spells it.
+	repo := req.Repo
+	if pull, err := s.findPull(req.Repo, int(req.PullNum)); err == nil {
+		repo = pull.BaseRepo.FullName
+	}
+
+	key := events.JobKey(repo, int(req.PullNum), dir, workspace)
+	err := s.JobLogs.Follow(stream.Context(), key, req.Next, func(line events.JobLogLine) error {
+		lineTime, err := ptypes.TimestampProto(line.Time)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&apipb.JobLogLine{Step: line.Step, Output: line.Output, Time: lineTime})
+	})
+	if err != nil && stream.Context().Err() != nil {
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	return err
+}
+
+// run runs cmd on the pull request and returns the status of its projects
+// once it completes.
+func (s *GRPCServer) run(ctx context.Context, repo string, pullNum int, cmd *events.CommentCommand) (*apipb.CommandResponse, error) {
+	if repo == "" || pullNum <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "repo and pull_num are required")
+	}
+	pull, err := s.findPull(repo, pullNum)
+	if err != nil {
+		return nil, err
+	}
+	user, _ := ctx.Value(grpcUserKey{}).(string)
+	s.Logger.Info("running %s on %s#%d for %s", cmd.Name.String(), pull.BaseRepo.FullName, pull.Num, user)
+	// The head repo isn't stored with the pull request so, like for GitHub
+	// comments, the command runner looks it up from the VCS host. It's a
+	// fork's for pull requests from forks.
+	s.CommandRunner.RunCommentCommand(pull.BaseRepo, nil, &pull, models.User{Username: user}, pull.Num, cmd)
+
+	pullStatus, err := s.DB.GetPullStatus(pull)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "getting status of pull request: %s", err)
+	}
+	resp := &apipb.CommandResponse{PullUrl: pull.URL}
+	if pullStatus != nil {
+		for _, p := range pullStatus.Projects {
+			resp.Projects = append(resp.Projects, &apipb.ProjectStatus{
+				ProjectId:   models.ProjectID(pull.BaseRepo.FullName, p.RepoRelDir, p.Workspace),
+				ProjectName: p.ProjectName,
+				Path:        p.RepoRelDir,
+				Workspace:   p.Workspace,
+				Status:      p.Status.String(),
+				NoChanges:   p.NoChanges,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// findPull returns the pull request pullNum in repo. Atlantis must already
+// know it, i.e. it must have a status or hold locks, since that's where the
+// repo's VCS host and clone URL are stored.
+func (s *GRPCServer) findPull(repo string, pullNum int) (models.PullRequest, error) {
+	pull, ok, err := findKnownPull(s.DB, s.Locker, repo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return models.PullRequest{}, status.Errorf(codes.NotFound, "pull request %s#%d isn't known to Atlantis, it must have been planned first", repo, pullNum)
+	}
+	return pull, nil
+}
+
+// validateRepoRelDir returns an InvalidArgument error if dir isn't relative
+// to the repo root like it must be in comments.
+func validateRepoRelDir(dir string) error {
+	if strings.HasPrefix(dir, "/") || strings.Contains(dir, "..") {
+		return status.Errorf(codes.InvalidArgument, "invalid dir %q, must be relative to the repo root and can't contain ..", dir)
+	}
+	return nil
+}
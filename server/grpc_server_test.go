@@ -0,0 +1,267 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/apipb"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking/mocks"
+	eventmocks "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+	gitlab "github.com/xanzy/go-gitlab"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeCommandRunner records the comment commands it runs and stores a plan
+// result for each of them.
+type fakeCommandRunner struct {
+	store *db.BoltDB
+	users []string
+	cmds  []*events.CommentCommand
+}
+
+func (f *fakeCommandRunner) RunCommentCommand(_ models.Repo, _ *models.Repo, maybePull *models.PullRequest, user models.User, _ int, cmd *events.CommentCommand) {
+	f.users = append(f.users, user.Username)
+	f.cmds = append(f.cmds, cmd)
+	f.store.UpdatePullWithResults(*maybePull, []models.ProjectResult{ // nolint: errcheck
+		{Command: models.PlanCommand, RepoRelDir: cmd.RepoRelDir, Workspace: cmd.Workspace, PlanSuccess: &models.PlanSuccess{}},
+	})
+}
+
+func (f *fakeCommandRunner) RunAutoplanCommand(models.Repo, models.Repo, models.PullRequest, models.User) {
+}
+
+func TestGRPCServer(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+
+	repo := models.Repo{FullName: "Owner/Repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	pull := models.PullRequest{Num: 1, URL: "https://github.com/Owner/Repo/pull/1", HeadCommit: "sha", BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", Failure: "failure"},
+	})
+	Ok(t, err)
+	lockTime := time.Date(2020, 6, 5, 12, 0, 0, 0, time.UTC)
+	locker := mocks.NewMockLocker()
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"Owner/Repo/staging/default": {
+			Project:   models.NewProject("Owner/Repo", "staging"),
+			Workspace: "default",
+			Pull:      pull,
+			User:      models.User{Username: "alice"},
+			Time:      lockTime,
+		},
+	}, nil)
+
+	runner := &fakeCommandRunner{store: store}
+	jobLogs := &events.JobLogs{}
+	grpcServer := server.NewGRPCServer(&server.GRPCServer{
+		Authenticator: &server.APIAuthenticator{
+			StaticTokens: []models.APIToken{
+				{Name: "ci", Hash: server.HashAPIToken("ci-token"), Scopes: []string{server.LocksReadScope, server.PlanTriggerScope, server.PlansReadScope}},
+			},
+			Logger: logging.NewNoopLogger(),
+		},
+		Locker:        locker,
+		DB:            store,
+		CommandRunner: runner,
+		JobLogs:       jobLogs,
+		Logger:        logging.NewNoopLogger(),
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	go grpcServer.Serve(lis) // nolint: errcheck
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	client := apipb.NewAtlantisClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer ci-token")
+
+	t.Run("requires a token", func(t *testing.T) {
+		_, err := client.ListLocks(context.Background(), &apipb.ListLocksRequest{})
+		Equals(t, codes.Unauthenticated, status.Code(err))
+		badCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+		_, err = client.ListLocks(badCtx, &apipb.ListLocksRequest{})
+		Equals(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("requires the method's scope", func(t *testing.T) {
+		_, err := client.Apply(ctx, &apipb.ApplyRequest{Repo: "owner/repo", PullNum: 1})
+		Equals(t, codes.PermissionDenied, status.Code(err))
+		Equals(t, `API token "ci" does not have scope "apply:trigger"`, status.Convert(err).Message())
+	})
+
+	t.Run("lists locks", func(t *testing.T) {
+		resp, err := client.ListLocks(ctx, &apipb.ListLocksRequest{Repo: "owner/repo", PullNum: 1})
+		Ok(t, err)
+		Equals(t, 1, len(resp.Locks))
+		lock := resp.Locks[0]
+		Equals(t, "Owner/Repo/staging/default", lock.Id)
+		Equals(t, "staging", lock.Path)
+		Equals(t, "alice", lock.User)
+		Equals(t, lockTime.Unix(), lock.Time.Seconds)
+
+		_, err = client.ListLocks(ctx, &apipb.ListLocksRequest{Sort: "size"})
+		Equals(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("plans", func(t *testing.T) {
+		resp, err := client.Plan(ctx, &apipb.PlanRequest{Repo: "owner/repo", PullNum: 1, Dir: "staging/", Workspace: "default", ExtraArgs: []string{"-target=null_resource.a"}, Destroy: true})
+		Ok(t, err)
+		Equals(t, pull.URL, resp.PullUrl)
+		Equals(t, 1, len(resp.Projects))
+		Equals(t, "planned", resp.Projects[0].Status)
+		Equals(t, "staging", resp.Projects[0].Path)
+
+		Equals(t, []string{"api-token:ci"}, runner.users)
+		cmd := runner.cmds[0]
+		Equals(t, models.PlanCommand, cmd.Name)
+		Equals(t, "staging", cmd.RepoRelDir)
+		Equals(t, []string{"-target=null_resource.a"}, cmd.Flags)
+		Equals(t, true, cmd.Destroy)
+	})
+
+	t.Run("rejects unknown pull requests and invalid dirs", func(t *testing.T) {
+		_, err := client.Plan(ctx, &apipb.PlanRequest{Repo: "owner/repo", PullNum: 2})
+		Equals(t, codes.NotFound, status.Code(err))
+		_, err = client.Plan(ctx, &apipb.PlanRequest{Repo: "owner/repo", PullNum: 1, Dir: "../other"})
+		Equals(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("streams job logs", func(t *testing.T) {
+		key := events.JobKey("Owner/Repo", 1, "staging", "default")
+		jobLogs.Start(key)
+		jobLogs.Append(key, events.JobLogLine{Step: "init", Output: "initialized", Time: lockTime})
+
+		stream, err := client.StreamJobLogs(ctx, &apipb.StreamJobLogsRequest{Repo: "owner/repo", PullNum: 1, Dir: "staging"})
+		Ok(t, err)
+		line, err := stream.Recv()
+		Ok(t, err)
+		Equals(t, "init", line.Step)
+		Equals(t, "initialized", line.Output)
+
+		jobLogs.Append(key, events.JobLogLine{Step: "plan", Output: "planned", Time: lockTime})
+		jobLogs.Finish(key)
+		line, err = stream.Recv()
+		Ok(t, err)
+		Equals(t, "plan", line.Step)
+		_, err = stream.Recv()
+		Equals(t, io.EOF, err)
+	})
+}
+
+func TestGRPCServer_NoTokens(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker := mocks.NewMockLocker()
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{}, nil)
+	runner := &fakeCommandRunner{}
+	grpcServer := server.NewGRPCServer(&server.GRPCServer{
+		Authenticator: &server.APIAuthenticator{Logger: logging.NewNoopLogger()},
+		Locker:        locker,
+		CommandRunner: runner,
+		JobLogs:       &events.JobLogs{},
+		Logger:        logging.NewNoopLogger(),
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	go grpcServer.Serve(lis) // nolint: errcheck
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	client := apipb.NewAtlantisClient(conn)
+
+	// Reading is allowed without a token like the REST API.
+	_, err = client.ListLocks(context.Background(), &apipb.ListLocksRequest{})
+	Ok(t, err)
+
+	// Plans and applies always need a token.
+	_, err = client.Plan(context.Background(), &apipb.PlanRequest{Repo: "owner/repo", PullNum: 1})
+	Equals(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Apply(context.Background(), &apipb.ApplyRequest{Repo: "owner/repo", PullNum: 1})
+	Equals(t, codes.Unauthenticated, status.Code(err))
+	Equals(t, 0, len(runner.cmds))
+}
+
+func TestGRPCServer_ForkPull(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+
+	repo, err := models.NewRepo(models.Gitlab, "owner/repo", "https://gitlab.com/owner/repo.git", "user", "token")
+	Ok(t, err)
+	pull := models.PullRequest{Num: 1, URL: "https://gitlab.com/owner/repo/merge_requests/1", BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", Failure: "failure"},
+	})
+	Ok(t, err)
+	locker := mocks.NewMockLocker()
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{}, nil)
+
+	// The merge request is from a fork, which only the VCS host knows.
+	mr := &gitlab.MergeRequest{IID: 1, State: "opened", WebURL: pull.URL, Author: &gitlab.BasicUser{Username: "author"}}
+	mrGetter := eventmocks.NewMockGitlabMergeRequestGetter()
+	When(mrGetter.GetMergeRequest("owner/repo", 1)).ThenReturn(mr, nil)
+	headRepoGetter := eventmocks.NewMockPullHeadRepoGetter()
+	When(headRepoGetter.GetPullHeadRepo(repo, 1)).ThenReturn("fork/repo", "https://gitlab.com/fork/repo.git", nil)
+	vcsClient := vcsmocks.NewMockClient()
+	runner := &events.DefaultCommandRunner{
+		VCSClient:                vcsClient,
+		GitlabMergeRequestGetter: mrGetter,
+		PullHeadRepoGetters:      map[models.VCSHostType]events.PullHeadRepoGetter{models.Gitlab: headRepoGetter},
+		EventParser:              &events.EventParser{},
+		Logger:                   logging.NewNoopLogger(),
+		AllowForkPRsFlag:         "allow-fork-prs",
+	}
+	grpcServer := server.NewGRPCServer(&server.GRPCServer{
+		Authenticator: &server.APIAuthenticator{
+			StaticTokens: []models.APIToken{
+				{Name: "ci", Hash: server.HashAPIToken("ci-token"), Scopes: []string{server.PlanTriggerScope}},
+			},
+			Logger: logging.NewNoopLogger(),
+		},
+		Locker:        locker,
+		DB:            store,
+		CommandRunner: runner,
+		JobLogs:       &events.JobLogs{},
+		Logger:        logging.NewNoopLogger(),
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	go grpcServer.Serve(lis) // nolint: errcheck
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	client := apipb.NewAtlantisClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer ci-token")
+
+	// Fork pull requests aren't allowed so the plan is rejected like it
+	// would be from a comment.
+	_, err = client.Plan(ctx, &apipb.PlanRequest{Repo: "owner/repo", PullNum: 1})
+	Ok(t, err)
+	headRepoGetter.VerifyWasCalledOnce().GetPullHeadRepo(repo, 1)
+	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "can't be run on fork pull requests"), "comment should reject the fork pull request but was %q", comment)
+}
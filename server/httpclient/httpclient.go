@@ -0,0 +1,75 @@
+// Package httpclient builds the HTTP clients Atlantis makes outbound calls
+// with, ex. to the VCS hosts, Slack and to download Terraform, so a proxy,
+// custom CAs and a client certificate apply to all of them.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config configures outbound HTTP calls. The proxy is always read from the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+type Config struct {
+	// CABundleFile, if set, is a file of PEM encoded CA certificates that
+	// are trusted in addition to the system's.
+	CABundleFile string
+	// ClientCertFile and ClientKeyFile, if set, are the PEM encoded
+	// certificate and key presented to servers that require mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// Factory creates HTTP clients that share one transport so connections are
+// reused across them.
+type Factory struct {
+	transport *http.Transport
+}
+
+// NewFactory returns a factory whose clients are configured by cfg.
+func NewFactory(cfg Config) (*Factory, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CABundleFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(cfg.CABundleFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading CA bundle %s", cfg.CABundleFile)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no PEM encoded certificates found in CA bundle %s", cfg.CABundleFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading client certificate %s and key %s", cfg.ClientCertFile, cfg.ClientKeyFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &Factory{transport: transport}, nil
+}
+
+// Transport returns the shared transport, ex. to wrap it in one that adds
+// authentication.
+func (f *Factory) Transport() http.RoundTripper {
+	return f.transport
+}
+
+// Client returns a client that times out requests after timeout. A timeout
+// of 0 never times out.
+func (f *Factory) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: f.transport, Timeout: timeout}
+}
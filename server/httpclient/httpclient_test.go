@@ -0,0 +1,49 @@
+package httpclient_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/httpclient"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFactory_CABundle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	// Without the bundle the test server's self-signed cert isn't trusted.
+	f, err := httpclient.NewFactory(httpclient.Config{})
+	Ok(t, err)
+	_, err = f.Client(0).Get(ts.URL)
+	Assert(t, err != nil, "expected certificate error")
+
+	bundle := filepath.Join(tmp, "ca.pem")
+	Ok(t, ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600))
+	f, err = httpclient.NewFactory(httpclient.Config{CABundleFile: bundle})
+	Ok(t, err)
+	resp, err := f.Client(0).Get(ts.URL)
+	Ok(t, err)
+	Equals(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close() // nolint: errcheck
+}
+
+func TestNewFactory_Errors(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	notPEM := filepath.Join(tmp, "not-pem")
+	Ok(t, ioutil.WriteFile(notPEM, []byte("hello"), 0600))
+
+	_, err := httpclient.NewFactory(httpclient.Config{CABundleFile: notPEM})
+	ErrEquals(t, "no PEM encoded certificates found in CA bundle "+notPEM, err)
+	_, err = httpclient.NewFactory(httpclient.Config{CABundleFile: filepath.Join(tmp, "missing")})
+	ErrContains(t, "reading CA bundle", err)
+	_, err = httpclient.NewFactory(httpclient.Config{ClientCertFile: notPEM, ClientKeyFile: notPEM})
+	ErrContains(t, "loading client certificate", err)
+}
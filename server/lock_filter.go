@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// The orders locks can be sorted in. See LockFilter.Sort.
+const (
+	LockSortID     = "id"
+	LockSortNewest = "newest"
+	LockSortOldest = "oldest"
+	LockSortRepo   = "repo"
+	LockSortPull   = "pull"
+	LockSortUser   = "user"
+)
+
+// LockSorts are the valid values of LockFilter.Sort.
+var LockSorts = []string{LockSortID, LockSortNewest, LockSortOldest, LockSortRepo, LockSortPull, LockSortUser}
+
+// LockFilter selects and orders the locks in the locks index and the locks
+// API. It's parsed from the repo, pull, user, older_than, q and sort query
+// params.
+type LockFilter struct {
+	// Repo, if set, is the full name of the repo the locks must be in, ex.
+	// owner/repo.
+	Repo string
+	// Pull, if set, is the number of the pull request that holds the locks.
+	Pull int
+	// User, if set, is the username of the user that created the locks.
+	User string
+	// OlderThan, if set, is how long ago the locks must have been created.
+	OlderThan time.Duration
+	// Search, if set, must be in the lock's repo, path, workspace or user.
+	// It's case insensitive.
+	Search string
+	// Sort is one of LockSorts. If empty, locks are sorted by ID.
+	Sort string
+}
+
+// ParseLockFilter parses the filter in query.
+func ParseLockFilter(query url.Values) (LockFilter, error) {
+	f := LockFilter{
+		Repo:   query.Get("repo"),
+		User:   query.Get("user"),
+		Search: query.Get("q"),
+		Sort:   query.Get("sort"),
+	}
+	if pull := query.Get("pull"); pull != "" {
+		num, err := strconv.Atoi(pull)
+		if err != nil || num <= 0 {
+			return f, fmt.Errorf("invalid pull %q, must be a pull request number", pull)
+		}
+		f.Pull = num
+	}
+	if olderThan := query.Get("older_than"); olderThan != "" {
+		d, err := time.ParseDuration(olderThan)
+		if err != nil || d < 0 {
+			return f, fmt.Errorf("invalid older_than %q, must be a duration, ex. 24h", olderThan)
+		}
+		f.OlderThan = d
+	}
+	if f.Sort != "" && !containsLockSort(f.Sort) {
+		return f, fmt.Errorf("invalid sort %q, must be one of %s", f.Sort, strings.Join(LockSorts, ", "))
+	}
+	return f, nil
+}
+
+// Selects returns true if the filter selects a subset of the locks rather
+// than all of them.
+func (f LockFilter) Selects() bool {
+	return f.Repo != "" || f.Pull != 0 || f.User != "" || f.OlderThan != 0 || f.Search != ""
+}
+
+// Matches returns true if lock passes the filter at now.
+func (f LockFilter) Matches(lock models.ProjectLock, now time.Time) bool {
+	if f.Repo != "" && !strings.EqualFold(f.Repo, lock.Project.RepoFullName) {
+		return false
+	}
+	if f.Pull != 0 && f.Pull != lock.Pull.Num {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(f.User, lock.User.Username) {
+		return false
+	}
+	if f.OlderThan != 0 && now.Sub(lock.Time) < f.OlderThan {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		found := false
+		for _, field := range []string{lock.Project.RepoFullName, lock.Project.Path, lock.Workspace, lock.User.Username} {
+			if strings.Contains(strings.ToLower(field), search) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the IDs of the locks that pass the filter at now, in the
+// filter's order.
+func (f LockFilter) Apply(locks map[string]models.ProjectLock, now time.Time) []string {
+	var ids []string
+	for id, lock := range locks {
+		if f.Matches(lock, now) {
+			ids = append(ids, id)
+		}
+	}
+	// Ties are broken by ID so the order is stable.
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := locks[ids[i]], locks[ids[j]]
+		switch f.Sort {
+		case LockSortNewest:
+			if !a.Time.Equal(b.Time) {
+				return a.Time.After(b.Time)
+			}
+		case LockSortOldest:
+			if !a.Time.Equal(b.Time) {
+				return a.Time.Before(b.Time)
+			}
+		case LockSortRepo:
+			if a.Project.RepoFullName != b.Project.RepoFullName {
+				return a.Project.RepoFullName < b.Project.RepoFullName
+			}
+		case LockSortPull:
+			if a.Project.RepoFullName != b.Project.RepoFullName {
+				return a.Project.RepoFullName < b.Project.RepoFullName
+			}
+			if a.Pull.Num != b.Pull.Num {
+				return a.Pull.Num < b.Pull.Num
+			}
+		case LockSortUser:
+			if a.User.Username != b.User.Username {
+				return a.User.Username < b.User.Username
+			}
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func containsLockSort(s string) bool {
+	for _, sort := range LockSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
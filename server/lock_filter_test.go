@@ -0,0 +1,119 @@
+package server_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseLockFilter(t *testing.T) {
+	filter, err := server.ParseLockFilter(url.Values{
+		"repo":       {"owner/repo"},
+		"pull":       {"2"},
+		"user":       {"lkysow"},
+		"older_than": {"24h"},
+		"q":          {"prod"},
+		"sort":       {"oldest"},
+	})
+	Ok(t, err)
+	Equals(t, server.LockFilter{
+		Repo:      "owner/repo",
+		Pull:      2,
+		User:      "lkysow",
+		OlderThan: 24 * time.Hour,
+		Search:    "prod",
+		Sort:      server.LockSortOldest,
+	}, filter)
+	Equals(t, true, filter.Selects())
+
+	filter, err = server.ParseLockFilter(url.Values{"sort": {"repo"}})
+	Ok(t, err)
+	Equals(t, false, filter.Selects())
+
+	for query, expErr := range map[string]string{
+		"pull=abc":       `invalid pull "abc", must be a pull request number`,
+		"older_than=1d":  `invalid older_than "1d", must be a duration, ex. 24h`,
+		"sort=something": `invalid sort "something", must be one of id, newest, oldest, repo, pull, user`,
+	} {
+		values, err := url.ParseQuery(query)
+		Ok(t, err)
+		_, err = server.ParseLockFilter(values)
+		ErrEquals(t, expErr, err)
+	}
+}
+
+func TestLockFilter_Apply(t *testing.T) {
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	locks := map[string]models.ProjectLock{
+		"owner/repo/prod/default": {
+			Project:   models.NewProject("owner/repo", "prod"),
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 2},
+			User:      models.User{Username: "alice"},
+			Time:      now.Add(-48 * time.Hour),
+		},
+		"owner/repo/staging/default": {
+			Project:   models.NewProject("owner/repo", "staging"),
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1},
+			User:      models.User{Username: "bob"},
+			Time:      now.Add(-time.Hour),
+		},
+		"owner/other/./production": {
+			Project:   models.NewProject("owner/other", "."),
+			Workspace: "production",
+			Pull:      models.PullRequest{Num: 3},
+			User:      models.User{Username: "Bob"},
+			Time:      now.Add(-72 * time.Hour),
+		},
+	}
+
+	cases := []struct {
+		filter server.LockFilter
+		exp    []string
+	}{
+		{
+			server.LockFilter{},
+			[]string{"owner/other/./production", "owner/repo/prod/default", "owner/repo/staging/default"},
+		},
+		{
+			server.LockFilter{Sort: server.LockSortNewest},
+			[]string{"owner/repo/staging/default", "owner/repo/prod/default", "owner/other/./production"},
+		},
+		{
+			server.LockFilter{Sort: server.LockSortPull},
+			[]string{"owner/other/./production", "owner/repo/staging/default", "owner/repo/prod/default"},
+		},
+		{
+			server.LockFilter{Repo: "Owner/Repo", Sort: server.LockSortOldest},
+			[]string{"owner/repo/prod/default", "owner/repo/staging/default"},
+		},
+		{
+			server.LockFilter{User: "bob"},
+			[]string{"owner/other/./production", "owner/repo/staging/default"},
+		},
+		{
+			server.LockFilter{Pull: 2},
+			[]string{"owner/repo/prod/default"},
+		},
+		{
+			server.LockFilter{OlderThan: 24 * time.Hour, Sort: server.LockSortUser},
+			[]string{"owner/other/./production", "owner/repo/prod/default"},
+		},
+		{
+			server.LockFilter{Search: "PROD"},
+			[]string{"owner/other/./production", "owner/repo/prod/default"},
+		},
+		{
+			server.LockFilter{Search: "missing"},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		Equals(t, c.exp, c.filter.Apply(locks, now))
+	}
+}
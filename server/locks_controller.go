@@ -1,13 +1,17 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/db"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -25,29 +29,155 @@ type LocksController struct {
 	LockDetailTemplate TemplateWriter
 	WorkingDir         events.WorkingDir
 	WorkingDirLocker   events.WorkingDirLocker
-	DB                 *db.BoltDB
+	DB                 db.PullStatusStore
+	// AuditLogger, if set, records each lock that's deleted.
+	AuditLogger events.AuditLogger
+	// CommitStatusUpdater, if set, fails the plan status of the pull requests
+	// whose locks are deleted.
+	CommitStatusUpdater events.CommitStatusUpdater
+	// GlobalCfgStore, if set, holds the server-side repo config. Users who
+	// belong to its tenants can only see and delete the locks of their
+	// tenants' repos.
+	GlobalCfgStore *events.GlobalCfgStore
+}
+
+// maxDiscardReasonLen is the longest reason for deleting a lock that's
+// posted on the pull request. Longer reasons are truncated.
+const maxDiscardReasonLen = 1000
+
+// LockData is the API representation of a lock.
+type LockData struct {
+	ID           string    `json:"id"`
+	ProjectID    string    `json:"project_id"`
+	RepoFullName string    `json:"repo_full_name"`
+	Path         string    `json:"path"`
+	Workspace    string    `json:"workspace"`
+	PullNum      int       `json:"pull_num"`
+	PullURL      string    `json:"pull_url"`
+	User         string    `json:"user"`
+	Time         time.Time `json:"time"`
+}
+
+// DeleteLocksData is the response of DELETE /api/locks.
+type DeleteLocksData struct {
+	// Deleted are the IDs of the locks that were deleted.
+	Deleted []string `json:"deleted"`
+	// Errors are the errors of the locks that couldn't be deleted or whose
+	// pull requests couldn't be commented on, by lock ID.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ListLocks is the GET /api/locks route. It returns the locks that pass the
+// LockFilter in the query params as JSON, sorted by ID unless sort is set.
+func (l *LocksController) ListLocks(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseLockFilter(r.URL.Query())
+	if err != nil {
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid query: %s", err)
+		return
+	}
+	locks, err := l.Locker.List()
+	if err != nil {
+		respond(w, l.Logger, logging.Error, http.StatusInternalServerError, "Failed listing locks: %s", err)
+		return
+	}
+	visible := repoScope(l.GlobalCfgStore, r)
+	data := []LockData{}
+	for _, id := range filter.Apply(locks, time.Now()) {
+		lock := locks[id]
+		if visible != nil && !visible(lockRepoID(lock)) {
+			continue
+		}
+		data = append(data, LockData{
+			ID:           id,
+			ProjectID:    lock.ProjectID(),
+			RepoFullName: lock.Project.RepoFullName,
+			Path:         lock.Project.Path,
+			Workspace:    lock.Workspace,
+			PullNum:      lock.Pull.Num,
+			PullURL:      lock.Pull.URL,
+			User:         lock.User.Username,
+			Time:         lock.Time,
+		})
+	}
+	l.respondJSON(w, http.StatusOK, data)
+}
+
+// DeleteLocks is the DELETE /api/locks route. It deletes the locks whose IDs
+// are in the id query params or, if there are none, the locks that pass the
+// LockFilter in the query params, like DeleteLock does for one lock. So all
+// locks aren't deleted by accident, either ids or a filter must be set.
+func (l *LocksController) DeleteLocks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ParseLockFilter(query)
+	if err != nil {
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid query: %s", err)
+		return
+	}
+	ids := query["id"]
+	if len(ids) == 0 {
+		if !filter.Selects() {
+			respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid query: id or a filter must be set")
+			return
+		}
+		locks, err := l.Locker.List()
+		if err != nil {
+			respond(w, l.Logger, logging.Error, http.StatusInternalServerError, "Failed listing locks: %s", err)
+			return
+		}
+		ids = filter.Apply(locks, time.Now())
+	}
+	visible := repoScope(l.GlobalCfgStore, r)
+
+	data := DeleteLocksData{Deleted: []string{}}
+	addErr := func(id string, format string, args ...interface{}) {
+		if data.Errors == nil {
+			data.Errors = make(map[string]string)
+		}
+		data.Errors[id] = fmt.Sprintf(format, args...)
+	}
+	for _, id := range ids {
+		if visible != nil && !l.lockVisible(id, visible) {
+			addErr(id, "no lock found")
+			continue
+		}
+		lock, err := l.Locker.Unlock(id)
+		if err != nil {
+			addErr(id, "deleting lock failed with: %s", err)
+			continue
+		}
+		if lock == nil {
+			addErr(id, "no lock found")
+			continue
+		}
+		data.Deleted = append(data.Deleted, id)
+		if err := l.cleanUpLock(r, *lock); err != nil {
+			addErr(id, "%s", err)
+		}
+	}
+	l.Logger.Info("deleted %d locks in bulk, %d errors", len(data.Deleted), len(data.Errors))
+	l.respondJSON(w, http.StatusOK, data)
 }
 
 // GetLock is the GET /locks/{id} route. It renders the lock detail view.
 func (l *LocksController) GetLock(w http.ResponseWriter, r *http.Request) {
 	id, ok := mux.Vars(r)["id"]
 	if !ok {
-		l.respond(w, logging.Warn, http.StatusBadRequest, "No lock id in request")
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "No lock id in request")
 		return
 	}
 
 	idUnencoded, err := url.QueryUnescape(id)
 	if err != nil {
-		l.respond(w, logging.Warn, http.StatusBadRequest, "Invalid lock id: %s", err)
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid lock id: %s", err)
 		return
 	}
 	lock, err := l.Locker.GetLock(idUnencoded)
 	if err != nil {
-		l.respond(w, logging.Error, http.StatusInternalServerError, "Failed getting lock: %s", err)
+		respond(w, l.Logger, logging.Error, http.StatusInternalServerError, "Failed getting lock: %s", err)
 		return
 	}
-	if lock == nil {
-		l.respond(w, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
+	if lock == nil || !l.canSee(r, *lock) {
+		respond(w, l.Logger, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
 		return
 	}
 
@@ -75,24 +205,69 @@ func (l *LocksController) GetLock(w http.ResponseWriter, r *http.Request) {
 func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 	id, ok := mux.Vars(r)["id"]
 	if !ok || id == "" {
-		l.respond(w, logging.Warn, http.StatusBadRequest, "No lock id in request")
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "No lock id in request")
 		return
 	}
 
 	idUnencoded, err := url.PathUnescape(id)
 	if err != nil {
-		l.respond(w, logging.Warn, http.StatusBadRequest, "Invalid lock id %q. Failed with error: %s", id, err)
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid lock id %q. Failed with error: %s", id, err)
+		return
+	}
+	if visible := repoScope(l.GlobalCfgStore, r); visible != nil && !l.lockVisible(idUnencoded, visible) {
+		respond(w, l.Logger, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
 		return
 	}
 	lock, err := l.Locker.Unlock(idUnencoded)
 	if err != nil {
-		l.respond(w, logging.Error, http.StatusInternalServerError, "deleting lock failed with: %s", err)
+		respond(w, l.Logger, logging.Error, http.StatusInternalServerError, "deleting lock failed with: %s", err)
 		return
 	}
 	if lock == nil {
-		l.respond(w, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
+		respond(w, l.Logger, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
 		return
 	}
+	if err := l.cleanUpLock(r, *lock); err != nil {
+		respond(w, l.Logger, logging.Error, http.StatusInternalServerError, "Failed %s", err)
+		return
+	}
+	respond(w, l.Logger, logging.Info, http.StatusOK, "Deleted lock id %q", id)
+}
+
+// canSee returns true if the user who made r can see lock.
+func (l *LocksController) canSee(r *http.Request, lock models.ProjectLock) bool {
+	visible := repoScope(l.GlobalCfgStore, r)
+	return visible == nil || visible(lockRepoID(lock))
+}
+
+// lockVisible returns true if the lock at id exists and is held in a repo
+// that visible returns true for.
+func (l *LocksController) lockVisible(id string, visible func(repoID string) bool) bool {
+	lock, err := l.Locker.GetLock(id)
+	if err != nil || lock == nil {
+		return false
+	}
+	return visible(lockRepoID(*lock))
+}
+
+// cleanUpLock is called once lock was deleted from the UI or API. It records
+// the deletion, deletes the lock's plan, fails its plan status and comments
+// on its pull request with who deleted it and the optional reason query
+// param.
+func (l *LocksController) cleanUpLock(r *http.Request, lock models.ProjectLock) error {
+	user := RequestUser(r)
+	if l.AuditLogger != nil {
+		l.AuditLogger.Record(models.AuditEvent{
+			Action:       models.DeleteLockAuditAction,
+			User:         user,
+			RepoFullName: lock.Project.RepoFullName,
+			PullNum:      lock.Pull.Num,
+			RepoRelDir:   lock.Project.Path,
+			Workspace:    lock.Workspace,
+			Result:       models.SuccessAuditResult,
+			ProjectID:    lock.ProjectID(),
+		})
+	}
 
 	// NOTE: Because BaseRepo was added to the PullRequest model later, previous
 	// installations of Atlantis will have locks in their DB that do not have
@@ -112,25 +287,50 @@ func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 			l.Logger.Err("unable to delete project status: %s", err)
 		}
 
+		if l.CommitStatusUpdater != nil {
+			if err := l.CommitStatusUpdater.UpdateDiscarded(lock); err != nil {
+				l.Logger.Warn("unable to update commit status: %s", err)
+			}
+		}
+
 		// Once the lock has been deleted, comment back on the pull request.
-		comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via the Atlantis UI.\n\n"+
-			"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace)
-		err = l.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment)
-		if err != nil {
-			l.respond(w, logging.Error, http.StatusInternalServerError, "Failed commenting on pull request: %s", err)
-			return
+		comment := discardComment(lock, user, r.URL.Query().Get("reason"))
+		if err := l.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment); err != nil {
+			return errors.Wrap(err, "commenting on pull request")
 		}
 	} else {
 		l.Logger.Debug("skipping commenting on pull request and deleting workspace because BaseRepo field is empty")
 	}
-	l.respond(w, logging.Info, http.StatusOK, "Deleted lock id %q", id)
+	return nil
 }
 
-// respond is a helper function to respond and log the response. lvl is the log
-// level to log at, code is the HTTP response code.
-func (l *LocksController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
-	response := fmt.Sprintf(format, args...)
-	l.Logger.Log(lvl, response)
+// discardComment returns the comment posted on the pull request of lock once
+// user deleted it for reason. user and reason can be empty.
+func discardComment(lock models.ProjectLock, user string, reason string) string {
+	via := "the Atlantis UI"
+	if strings.HasPrefix(user, "api-token:") {
+		via = "the Atlantis API"
+	}
+	comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via %s", lock.Project.Path, lock.Workspace, via)
+	if user != "" {
+		comment += fmt.Sprintf(" by `%s`", user)
+	}
+	comment += ".\n\n"
+	if reason = strings.TrimSpace(reason); reason != "" {
+		if runes := []rune(reason); len(runes) > maxDiscardReasonLen {
+			reason = string(runes[:maxDiscardReasonLen]) + "..."
+		}
+		// Each line is quoted so the reason can't end the quote early.
+		comment += "Reason:\n> " + strings.Replace(reason, "\n", "\n> ", -1) + "\n\n"
+	}
+	return comment + "To `apply` this plan you must run `plan` again."
+}
+
+// respondJSON writes data as JSON.
+func (l *LocksController) respondJSON(w http.ResponseWriter, responseCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(responseCode)
-	fmt.Fprintln(w, response)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		l.Logger.Err("writing response: %s", err)
+	}
 }
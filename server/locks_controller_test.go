@@ -2,12 +2,14 @@ package server_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/db"
 
@@ -19,6 +21,7 @@ import (
 	mocks2 "github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	sMocks "github.com/runatlantis/atlantis/server/mocks"
 	. "github.com/runatlantis/atlantis/testing"
@@ -266,3 +269,280 @@ func TestDeleteLock_CommentSuccess(t *testing.T) {
 			"To `apply` this plan you must run `plan` again.")
 	workingDir.VerifyWasCalledOnce().DeleteForWorkspace(pull.BaseRepo, pull, "workspace")
 }
+
+func TestDeleteLock_CommentUserAndReason(t *testing.T) {
+	t.Log("The comment should say who deleted the lock and why and the plan status should fail")
+	RegisterMockTestingT(t)
+
+	cp := vcsmocks.NewMockClient()
+	l := mocks.NewMockLocker()
+	csu := mocks2.NewMockCommitStatusUpdater()
+	pull := models.PullRequest{
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+	}
+	lock := models.ProjectLock{
+		Pull:      pull,
+		Workspace: "workspace",
+		Project: models.Project{
+			Path:         "path",
+			RepoFullName: "owner/repo",
+		},
+	}
+	When(l.Unlock("id")).ThenReturn(&lock, nil)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := db.New(tmp)
+	Ok(t, err)
+	lc := server.LocksController{
+		Locker:              l,
+		Logger:              logging.NewNoopLogger(),
+		VCSClient:           cp,
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+		WorkingDir:          mocks2.NewMockWorkingDir(),
+		DB:                  db,
+		CommitStatusUpdater: csu,
+	}
+	auth := &server.BasicWebAuthenticator{Username: "admin", Password: "pass"}
+	req, _ := http.NewRequest("DELETE", "/locks?id=id&reason="+url.QueryEscape("stuck since friday\nreplanning"), bytes.NewBuffer(nil))
+	req.SetBasicAuth("admin", "pass")
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	auth.Wrap(lc.DeleteLock)(w, req)
+	responseContains(t, w, http.StatusOK, "Deleted lock id \"id\"")
+	cp.VerifyWasCalled(Once()).CreateComment(pull.BaseRepo, pull.Num,
+		"**Warning**: The plan for dir: `path` workspace: `workspace` was **discarded** via the Atlantis UI by `admin`.\n\n"+
+			"Reason:\n> stuck since friday\n> replanning\n\n"+
+			"To `apply` this plan you must run `plan` again.")
+	csu.VerifyWasCalledOnce().UpdateDiscarded(lock)
+}
+
+func TestListLocks(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	lockTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/path/default": {
+			Project:   models.NewProject("owner/repo", "path"),
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1, URL: "url"},
+			User:      models.User{Username: "lkysow"},
+			Time:      lockTime,
+		},
+	}, nil)
+	lc := server.LocksController{
+		Logger: logging.NewNoopLogger(),
+		Locker: l,
+	}
+	req, _ := http.NewRequest("GET", "/api/locks", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	lc.ListLocks(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "application/json", w.Header().Get("Content-Type"))
+	var locks []server.LockData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &locks))
+	Equals(t, []server.LockData{
+		{
+			ID:           "owner/repo/path/default",
+			ProjectID:    "445faa5827ef29e9",
+			RepoFullName: "owner/repo",
+			Path:         "path",
+			Workspace:    "default",
+			PullNum:      1,
+			PullURL:      "url",
+			User:         "lkysow",
+			Time:         lockTime,
+		},
+	}, locks)
+}
+
+func TestListLocks_Filter(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/path/default":  {Project: models.NewProject("owner/repo", "path"), Workspace: "default"},
+		"owner/other/path/default": {Project: models.NewProject("owner/other", "path"), Workspace: "default"},
+	}, nil)
+	lc := server.LocksController{
+		Logger: logging.NewNoopLogger(),
+		Locker: l,
+	}
+	req, _ := http.NewRequest("GET", "/api/locks?repo=owner/other", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	lc.ListLocks(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var locks []server.LockData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &locks))
+	Equals(t, 1, len(locks))
+	Equals(t, "owner/other/path/default", locks[0].ID)
+
+	req, _ = http.NewRequest("GET", "/api/locks?sort=size", bytes.NewBuffer(nil))
+	w = httptest.NewRecorder()
+	lc.ListLocks(w, req)
+	responseContains(t, w, http.StatusBadRequest, "Invalid query: invalid sort \"size\"")
+}
+
+func TestListLocks_Tenants(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	teamA := models.Repo{FullName: "team-a/infra", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	teamB := models.Repo{FullName: "team-b/infra", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{
+		"team-a/infra/path/default": {Project: models.NewProject("team-a/infra", "path"), Workspace: "default", Pull: models.PullRequest{BaseRepo: teamA}},
+		"team-b/infra/path/default": {Project: models.NewProject("team-b/infra", "path"), Workspace: "default", Pull: models.PullRequest{BaseRepo: teamB}},
+		// Locks from before BaseRepo was stored don't know their VCS host.
+		"team-a/old/path/default": {Project: models.NewProject("team-a/old", "path"), Workspace: "default"},
+	}, nil)
+	lc := server.LocksController{
+		Logger: logging.NewNoopLogger(),
+		Locker: l,
+		GlobalCfgStore: events.NewGlobalCfgStore(valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{ID: "github.com/team-a/infra", Tenant: "team-a"},
+				{ID: "github.com/team-b/infra", Tenant: "team-b"},
+			},
+			Tenants: map[string]valid.Tenant{
+				"team-a": {Name: "team-a", Users: []string{"alice"}},
+			},
+		}),
+	}
+	listLocks := func(user string) []string {
+		req, _ := http.NewRequest("GET", "/api/locks", bytes.NewBuffer(nil))
+		req.SetBasicAuth(user, "password")
+		w := httptest.NewRecorder()
+		(&server.BasicWebAuthenticator{Username: user, Password: "password"}).Wrap(lc.ListLocks)(w, req)
+		Equals(t, http.StatusOK, w.Code)
+		var locks []server.LockData
+		Ok(t, json.Unmarshal(w.Body.Bytes(), &locks))
+		var ids []string
+		for _, lock := range locks {
+			ids = append(ids, lock.ID)
+		}
+		return ids
+	}
+
+	Equals(t, []string{"team-a/infra/path/default"}, listLocks("alice"))
+	// Users who don't belong to a tenant see every lock.
+	Equals(t, 3, len(listLocks("admin")))
+}
+
+func TestDeleteLocks(t *testing.T) {
+	RegisterMockTestingT(t)
+	cp := vcsmocks.NewMockClient()
+	l := mocks.NewMockLocker()
+	workingDir := mocks2.NewMockWorkingDir()
+	pull := models.PullRequest{
+		Num:      1,
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+	}
+	lock := func(path string) models.ProjectLock {
+		return models.ProjectLock{
+			Pull:      pull,
+			Workspace: "default",
+			Project:   models.NewProject("owner/repo", path),
+			Time:      time.Now().Add(-48 * time.Hour),
+		}
+	}
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/prod/default":    lock("prod"),
+		"owner/repo/staging/default": lock("staging"),
+	}, nil)
+	prod, staging := lock("prod"), lock("staging")
+	When(l.Unlock("owner/repo/prod/default")).ThenReturn(&prod, nil)
+	When(l.Unlock("owner/repo/staging/default")).ThenReturn(&staging, nil)
+	When(l.Unlock("missing")).ThenReturn(nil, nil)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := db.New(tmp)
+	Ok(t, err)
+	lc := server.LocksController{
+		Locker:           l,
+		Logger:           logging.NewNoopLogger(),
+		VCSClient:        cp,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		DB:               db,
+	}
+	deleteLocks := func(query string) (int, server.DeleteLocksData) {
+		req, _ := http.NewRequest("DELETE", "/api/locks?"+query, bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		lc.DeleteLocks(w, req)
+		var data server.DeleteLocksData
+		if w.Code == http.StatusOK {
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &data))
+		}
+		return w.Code, data
+	}
+
+	// So all locks aren't deleted by accident, ids or a filter must be set.
+	code, _ := deleteLocks("sort=repo")
+	Equals(t, http.StatusBadRequest, code)
+
+	// Locks are deleted by id.
+	code, data := deleteLocks("id=" + url.QueryEscape("owner/repo/prod/default") + "&id=missing")
+	Equals(t, http.StatusOK, code)
+	Equals(t, server.DeleteLocksData{
+		Deleted: []string{"owner/repo/prod/default"},
+		Errors:  map[string]string{"missing": "no lock found"},
+	}, data)
+	cp.VerifyWasCalledOnce().CreateComment(pull.BaseRepo, pull.Num,
+		"**Warning**: The plan for dir: `prod` workspace: `default` was **discarded** via the Atlantis UI.\n\n"+
+			"To `apply` this plan you must run `plan` again.")
+
+	// Or by filter.
+	code, data = deleteLocks("repo=owner/repo&older_than=24h")
+	Equals(t, http.StatusOK, code)
+	Equals(t, []string{"owner/repo/prod/default", "owner/repo/staging/default"}, data.Deleted)
+	// The plans of every deleted lock are discarded.
+	workingDir.VerifyWasCalled(Times(3)).DeleteForWorkspace(pull.BaseRepo, pull, "default")
+}
+
+func TestDeleteLock_AuditLog(t *testing.T) {
+	t.Log("deleting a lock should be recorded in the audit log with the authenticated user")
+	RegisterMockTestingT(t)
+
+	cp := vcsmocks.NewMockClient()
+	l := mocks.NewMockLocker()
+	pull := models.PullRequest{
+		Num:      2,
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+	}
+	When(l.Unlock("id")).ThenReturn(&models.ProjectLock{
+		Pull:      pull,
+		Workspace: "workspace",
+		Project: models.Project{
+			Path:         "path",
+			RepoFullName: "owner/repo",
+		},
+	}, nil)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := db.New(tmp)
+	Ok(t, err)
+	lc := server.LocksController{
+		Locker:           l,
+		Logger:           logging.NewNoopLogger(),
+		VCSClient:        cp,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       mocks2.NewMockWorkingDir(),
+		DB:               db,
+		AuditLogger:      &events.DefaultAuditLogger{DB: db, Logger: logging.NewNoopLogger()},
+	}
+	webAuth := &server.BasicWebAuthenticator{Username: "admin", Password: "pass"}
+	req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	req.SetBasicAuth("admin", "pass")
+	w := httptest.NewRecorder()
+	webAuth.Wrap(lc.DeleteLock)(w, req)
+	responseContains(t, w, http.StatusOK, "Deleted lock id \"id\"")
+
+	auditEvents, err := db.ListAuditEvents(time.Time{})
+	Ok(t, err)
+	Equals(t, 1, len(auditEvents))
+	Equals(t, models.DeleteLockAuditAction, auditEvents[0].Action)
+	Equals(t, "admin", auditEvents[0].User)
+	Equals(t, "owner/repo", auditEvents[0].RepoFullName)
+	Equals(t, 2, auditEvents[0].PullNum)
+	Equals(t, "path", auditEvents[0].RepoRelDir)
+	Equals(t, "workspace", auditEvents[0].Workspace)
+	Equals(t, models.SuccessAuditResult, auditEvents[0].Result)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// LogLevelController handles requests to view and change the server's log
+// levels without restarting it.
+type LogLevelController struct {
+	Levels *logging.Levels
+	Logger *logging.SimpleLogger
+}
+
+// LogLevelData is the API representation of the server's log levels.
+type LogLevelData struct {
+	// Level is the level the server logs at.
+	Level string `json:"level"`
+	// Subsystems are the levels of the subsystems that don't log at the
+	// server's level, ex. {"vcs": "debug"}.
+	Subsystems map[string]string `json:"subsystems"`
+}
+
+// SetLogLevelRequest is the body of PUT /api/loglevel.
+type SetLogLevelRequest struct {
+	// Level is the level to log at, ex. "debug". If it's empty and Subsystem
+	// is set, the subsystem logs at the server's level again.
+	Level string `json:"level"`
+	// Subsystem is the subsystem to set the level of, ex. "vcs". If it's
+	// empty, the server's level is set.
+	Subsystem string `json:"subsystem"`
+}
+
+// GetLevels is the GET /api/loglevel route.
+func (l *LogLevelController) GetLevels(w http.ResponseWriter, r *http.Request) {
+	l.respondJSON(w, http.StatusOK, l.levels())
+}
+
+// SetLevel is the PUT /api/loglevel route. It responds with the levels after
+// the change.
+func (l *LogLevelController) SetLevel(w http.ResponseWriter, r *http.Request) {
+	var req SetLogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+		return
+	}
+	if req.Level == "" && req.Subsystem == "" {
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: level must be set")
+		return
+	}
+
+	if req.Level == "" {
+		if err := l.Levels.ResetSubsystem(req.Subsystem); err != nil {
+			respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+			return
+		}
+		l.Logger.Info("reset log level of subsystem %q to the server's level", req.Subsystem)
+		l.respondJSON(w, http.StatusOK, l.levels())
+		return
+	}
+
+	level, err := logging.ParseLogLevel(req.Level)
+	if err != nil {
+		respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+		return
+	}
+	if req.Subsystem == "" {
+		l.Levels.Set(level)
+		l.Logger.Info("set log level to %s", level)
+	} else {
+		if err := l.Levels.SetSubsystem(req.Subsystem, level); err != nil {
+			respond(w, l.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+			return
+		}
+		l.Logger.Info("set log level of subsystem %q to %s", req.Subsystem, level)
+	}
+	l.respondJSON(w, http.StatusOK, l.levels())
+}
+
+func (l *LogLevelController) levels() LogLevelData {
+	level, subsystems := l.Levels.Get()
+	data := LogLevelData{
+		Level:      level.String(),
+		Subsystems: make(map[string]string, len(subsystems)),
+	}
+	for name, lvl := range subsystems {
+		data.Subsystems[name] = lvl.String()
+	}
+	return data
+}
+
+// respondJSON writes data as JSON.
+func (l *LogLevelController) respondJSON(w http.ResponseWriter, responseCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(responseCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		l.Logger.Err("writing response: %s", err)
+	}
+}
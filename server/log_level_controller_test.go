@@ -0,0 +1,72 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func setLogLevel(c *server.LogLevelController, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PUT", "/api/loglevel", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	c.SetLevel(w, req)
+	return w
+}
+
+func TestLogLevelController_SetLevel(t *testing.T) {
+	levels := logging.NewLevels(logging.Info)
+	c := &server.LogLevelController{Levels: levels, Logger: logging.NewNoopLogger()}
+
+	w := setLogLevel(c, `{"level": "debug", "subsystem": "vcs"}`)
+	Equals(t, http.StatusOK, w.Code)
+	var data server.LogLevelData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &data))
+	Equals(t, server.LogLevelData{Level: "info", Subsystems: map[string]string{"vcs": "debug"}}, data)
+	Equals(t, logging.Debug, levels.Level(logging.VCSSubsystem))
+	Equals(t, logging.Info, levels.Level(logging.TerraformSubsystem))
+
+	w = setLogLevel(c, `{"level": "warn"}`)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, logging.Warn, levels.Level(""))
+	Equals(t, logging.Warn, levels.Level(logging.LockingSubsystem))
+	Equals(t, logging.Debug, levels.Level(logging.VCSSubsystem))
+
+	// Resetting the subsystem makes it follow the server's level again.
+	w = setLogLevel(c, `{"subsystem": "vcs"}`)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, logging.Warn, levels.Level(logging.VCSSubsystem))
+
+	req, _ := http.NewRequest("GET", "/api/loglevel", nil)
+	w = httptest.NewRecorder()
+	c.GetLevels(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	data = server.LogLevelData{}
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &data))
+	Equals(t, server.LogLevelData{Level: "warn", Subsystems: map[string]string{}}, data)
+}
+
+func TestLogLevelController_SetLevelInvalid(t *testing.T) {
+	levels := logging.NewLevels(logging.Info)
+	c := &server.LogLevelController{Levels: levels, Logger: logging.NewNoopLogger()}
+	for _, body := range []string{
+		`not json`,
+		`{}`,
+		`{"level": "verbose"}`,
+		`{"level": "debug", "subsystem": "github"}`,
+		`{"subsystem": "github"}`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			w := setLogLevel(c, body)
+			Equals(t, http.StatusBadRequest, w.Code)
+			level, subsystems := levels.Get()
+			Equals(t, logging.Info, level)
+			Equals(t, 0, len(subsystems))
+		})
+	}
+}
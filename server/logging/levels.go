@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// The subsystems whose log level can be set separately from the server's.
+const (
+	LockingSubsystem   = "locking"
+	TerraformSubsystem = "terraform"
+	VCSSubsystem       = "vcs"
+)
+
+// ValidSubsystems are the subsystems whose log level can be set.
+var ValidSubsystems = []string{LockingSubsystem, TerraformSubsystem, VCSSubsystem}
+
+// Levels are the log levels of the server and its subsystems. Loggers that
+// have Levels set check them on every log entry so the levels can be changed
+// while the server is running, ex. to turn on debug logging while
+// reproducing an issue.
+type Levels struct {
+	mu         sync.RWMutex
+	level      LogLevel
+	subsystems map[string]LogLevel
+}
+
+// NewLevels returns levels where the server and every subsystem log at
+// level.
+func NewLevels(level LogLevel) *Levels {
+	return &Levels{level: level, subsystems: make(map[string]LogLevel)}
+}
+
+// Level returns the level subsystem logs at. If subsystem is empty or its
+// level isn't set, it's the server's level.
+func (l *Levels) Level(subsystem string) LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.subsystems[subsystem]; ok {
+		return level
+	}
+	return l.level
+}
+
+// Set sets the server's level. Subsystems whose level is set keep it.
+func (l *Levels) Set(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// SetSubsystem sets subsystem's level.
+func (l *Levels) SetSubsystem(subsystem string, level LogLevel) error {
+	if !validSubsystem(subsystem) {
+		return fmt.Errorf("invalid subsystem %q: must be one of %v", subsystem, ValidSubsystems)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subsystems[subsystem] = level
+	return nil
+}
+
+// ResetSubsystem makes subsystem log at the server's level again.
+func (l *Levels) ResetSubsystem(subsystem string) error {
+	if !validSubsystem(subsystem) {
+		return fmt.Errorf("invalid subsystem %q: must be one of %v", subsystem, ValidSubsystems)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subsystems, subsystem)
+	return nil
+}
+
+// Get returns the server's level and the levels of the subsystems whose
+// level is set.
+func (l *Levels) Get() (LogLevel, map[string]LogLevel) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	subsystems := make(map[string]LogLevel, len(l.subsystems))
+	for name, level := range l.subsystems {
+		subsystems[name] = level
+	}
+	return l.level, subsystems
+}
+
+func validSubsystem(subsystem string) bool {
+	i := sort.SearchStrings(ValidSubsystems, subsystem)
+	return i < len(ValidSubsystems) && ValidSubsystems[i] == subsystem
+}
+
+// ParseLogLevel parses a log level name, ex. "debug". See LogLevel.String.
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch s {
+	case "debug":
+		return Debug, nil
+	case "info":
+		return Info, nil
+	case "warn":
+		return Warn, nil
+	case "error":
+		return Error, nil
+	}
+	return Info, fmt.Errorf("invalid log level %q: must be one of debug, info, warn or error", s)
+}
+
+// String returns the level's name, ex. "debug".
+func (l LogLevel) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
@@ -0,0 +1,41 @@
+package logging_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestSimpleLogger_Levels(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.NewSimpleLogger("server", false, logging.Info)
+	logger.Logger = log.New(&out, "", 0)
+	logger.Levels = logging.NewLevels(logging.Info)
+	vcsLogger := logger.ForSubsystem(logging.VCSSubsystem)
+	pullLogger := logger.NewLogger("owner/repo#1", true, logging.Info).ForSubsystem(logging.VCSSubsystem)
+
+	vcsLogger.Debug("before")
+	Ok(t, logger.Levels.SetSubsystem(logging.VCSSubsystem, logging.Debug))
+	logger.Debug("server")
+	vcsLogger.Debug("vcs")
+	pullLogger.Debug("pull")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	Equals(t, 2, len(lines))
+	Assert(t, strings.HasSuffix(lines[0], "server: Vcs"), "got %q", lines[0])
+	Assert(t, strings.HasSuffix(lines[1], "owner/repo#1: Pull"), "got %q", lines[1])
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		level, err := logging.ParseLogLevel(name)
+		Ok(t, err)
+		Equals(t, name, level.String())
+	}
+	_, err := logging.ParseLogLevel("verbose")
+	ErrEquals(t, `invalid log level "verbose": must be one of debug, info, warn or error`, err)
+}
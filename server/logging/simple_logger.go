@@ -57,6 +57,24 @@ type SimpleLogger struct {
 	Logger      *log.Logger
 	KeepHistory bool
 	Level       LogLevel
+	// Redactor, if set, scrubs secrets from each log entry before it's
+	// written or stored in History.
+	Redactor Redactor
+	// Levels, if set, are checked for the level to write at instead of
+	// Level so it can be changed while the server is running.
+	Levels *Levels
+	// Subsystem is the subsystem whose level in Levels this logger writes
+	// at, ex. vcs, or empty for the server's level.
+	Subsystem string
+
+	// historyOwner, if set, is the logger whose History this logger's
+	// entries are stored in. See ForSubsystem.
+	historyOwner *SimpleLogger
+}
+
+// Redactor scrubs secrets from log entries.
+type Redactor interface {
+	Redact(s string) string
 }
 
 type LogLevel int
@@ -97,7 +115,8 @@ func NewNoopLogger() *SimpleLogger {
 	}
 }
 
-// NewLogger returns a new logger that reuses the underlying logger.
+// NewLogger returns a new logger that reuses the underlying logger. If l has
+// Levels, the new logger follows them instead of writing at lvl.
 func (l *SimpleLogger) NewLogger(source string, keepHistory bool, lvl LogLevel) *SimpleLogger {
 	if l == nil {
 		return nil
@@ -107,6 +126,32 @@ func (l *SimpleLogger) NewLogger(source string, keepHistory bool, lvl LogLevel)
 		Level:       lvl,
 		Logger:      l.Underlying(),
 		KeepHistory: keepHistory,
+		Redactor:    l.Redactor,
+		Levels:      l.Levels,
+		Subsystem:   l.Subsystem,
+	}
+}
+
+// ForSubsystem returns a logger that writes at subsystem's level in Levels,
+// ex. so that only the VCS clients log at debug level. Its entries are
+// stored in l's History.
+func (l *SimpleLogger) ForSubsystem(subsystem string) *SimpleLogger {
+	if l == nil {
+		return nil
+	}
+	owner := l
+	if l.historyOwner != nil {
+		owner = l.historyOwner
+	}
+	return &SimpleLogger{
+		Source:       l.Source,
+		Level:        l.Level,
+		Logger:       l.Underlying(),
+		KeepHistory:  l.KeepHistory,
+		Redactor:     l.Redactor,
+		Levels:       l.Levels,
+		Subsystem:    subsystem,
+		historyOwner: owner,
 	}
 }
 
@@ -150,12 +195,15 @@ func (l *SimpleLogger) Log(level LogLevel, format string, a ...interface{}) {
 	if l != nil {
 		levelStr := l.levelToString(level)
 		msg := l.capitalizeFirstLetter(fmt.Sprintf(format, a...))
+		if l.Redactor != nil {
+			msg = l.Redactor.Redact(msg)
+		}
 
 		// Only log this message if configured to log at this level.
-		if l.Level <= level {
+		if current := l.GetLevel(); current <= level {
 			datetime := time.Now().Format("2006/01/02 15:04:05-0700")
 			var caller string
-			if l.Level <= Debug {
+			if current <= Debug {
 				file, line := l.callSite(3)
 				caller = fmt.Sprintf(" %s:%d", file, line)
 			}
@@ -176,10 +224,17 @@ func (l *SimpleLogger) Underlying() *log.Logger {
 
 // GetLevel returns the current log level of the logger.
 func (l *SimpleLogger) GetLevel() LogLevel {
+	if l.Levels != nil {
+		return l.Levels.Level(l.Subsystem)
+	}
 	return l.Level
 }
 
 func (l *SimpleLogger) saveToHistory(level string, msg string) {
+	if l.historyOwner != nil {
+		l.historyOwner.saveToHistory(level, msg)
+		return
+	}
 	l.History.WriteString(fmt.Sprintf("[%s] %s\n", level, msg))
 }
 
@@ -0,0 +1,356 @@
+// Package metrics implements a small metrics registry that's exposed in the
+// OpenMetrics text format so it can be scraped by Prometheus.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Type is the type of a metric family.
+type Type string
+
+const (
+	CounterType Type = "counter"
+	GaugeType   Type = "gauge"
+)
+
+// Sample is a single value of a metric family.
+type Sample struct {
+	// Labels are the label names and values of this sample. They must be
+	// the same length as the family's LabelNames.
+	Labels []string
+	Value  float64
+}
+
+// Family is a named metric and all its samples.
+type Family struct {
+	Name       string
+	Help       string
+	Type       Type
+	LabelNames []string
+	Samples    []Sample
+}
+
+// Collector returns metric families whose values are computed when the
+// metrics are gathered, ex. the number of locks.
+type Collector interface {
+	Collect() ([]Family, error)
+}
+
+// CollectorFunc is a function that implements Collector.
+type CollectorFunc func() ([]Family, error)
+
+// Collect implements Collector.
+func (c CollectorFunc) Collect() ([]Family, error) {
+	return c()
+}
+
+// Registry holds counters and gauges that are updated as events happen and
+// collectors that are called when the metrics are gathered.
+type Registry struct {
+	mu         sync.Mutex
+	vecs       map[string]*vec
+	collectors []Collector
+	sink       Sink
+}
+
+// Sink is sent every update to a registry's counters, gauges and timers as
+// it happens, ex. to forward them to statsd. Tags are the label names and
+// values of the update as "name:value".
+type Sink interface {
+	Count(name string, delta float64, tags []string)
+	Gauge(name string, value float64, tags []string)
+	Timing(name string, d time.Duration, tags []string)
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		vecs: make(map[string]*vec),
+	}
+}
+
+// Counter is a value that only goes up, ex. the number of requests. Counters
+// are exposed with a "_total" suffix.
+type Counter struct {
+	vec *vec
+}
+
+// NewCounter registers and returns a counter. name should not have a
+// "_total" suffix. labelNames are the names of the labels whose values are
+// passed to Add and Inc.
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	return &Counter{vec: r.newVec(name, help, CounterType, labelNames)}
+}
+
+// SetSink sets the sink that updates are sent to in addition to being
+// gathered.
+func (r *Registry) SetSink(sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sink = sink
+}
+
+// Inc increments the counter with labelValues by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the counter with
+// labelValues.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.vec.update(labelValues, func(v float64) float64 { return v + delta })
+	if sink := c.vec.registry.getSink(); sink != nil {
+		sink.Count(c.vec.name, delta, c.vec.tags(labelValues))
+	}
+}
+
+// Gauge is a value that can go up and down, ex. the number of commands
+// running.
+type Gauge struct {
+	vec *vec
+}
+
+// NewGauge registers and returns a gauge.
+func (r *Registry) NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return &Gauge{vec: r.newVec(name, help, GaugeType, labelNames)}
+}
+
+// Set sets the gauge with labelValues to value.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.send(g.vec.update(labelValues, func(float64) float64 { return value }), labelValues)
+}
+
+// Add adds delta, which can be negative, to the gauge with labelValues.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.send(g.vec.update(labelValues, func(v float64) float64 { return v + delta }), labelValues)
+}
+
+func (g *Gauge) send(value float64, labelValues []string) {
+	if sink := g.vec.registry.getSink(); sink != nil {
+		sink.Gauge(g.vec.name, value, g.vec.tags(labelValues))
+	}
+}
+
+// Timer records how long something took, ex. running a command. It's
+// exposed as a counter of the total seconds with a "_seconds_total" suffix
+// and sent to the sink as a timing.
+type Timer struct {
+	name string
+	vec  *vec
+}
+
+// NewTimer registers and returns a timer. name should not have a
+// "_seconds" suffix.
+func (r *Registry) NewTimer(name string, help string, labelNames ...string) *Timer {
+	return &Timer{name: name, vec: r.newVec(name+"_seconds", help, CounterType, labelNames)}
+}
+
+// Observe records that something with labelValues took d.
+func (t *Timer) Observe(d time.Duration, labelValues ...string) {
+	t.vec.update(labelValues, func(v float64) float64 { return v + d.Seconds() })
+	if sink := t.vec.registry.getSink(); sink != nil {
+		sink.Timing(t.name, d, t.vec.tags(labelValues))
+	}
+}
+
+// Register adds a collector that's called each time the metrics are
+// gathered.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Gather returns all the metric families sorted by name. Collectors that
+// error are skipped and their errors are returned along with the families
+// that could be gathered.
+func (r *Registry) Gather() ([]Family, error) {
+	r.mu.Lock()
+	var families []Family
+	for _, v := range r.vecs {
+		families = append(families, v.family())
+	}
+	collectors := make([]Collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	var errs []string
+	for _, c := range collectors {
+		collected, err := c.Collect()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		families = append(families, collected...)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	if len(errs) > 0 {
+		return families, fmt.Errorf("collecting metrics: %s", strings.Join(errs, "; "))
+	}
+	return families, nil
+}
+
+// ServeHTTP serves the metrics in the OpenMetrics text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	families, err := r.Gather()
+	if err != nil && len(families) == 0 {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	WriteOpenMetrics(w, families) // nolint: errcheck
+}
+
+// WriteOpenMetrics writes families to w in the OpenMetrics text format.
+func WriteOpenMetrics(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escape(f.Help, false))
+		sampleName := f.Name
+		if f.Type == CounterType {
+			sampleName += "_total"
+		}
+		for _, s := range f.Samples {
+			b.WriteString(sampleName)
+			if len(f.LabelNames) > 0 {
+				b.WriteString("{")
+				for i, name := range f.LabelNames {
+					if i > 0 {
+						b.WriteString(",")
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", name, escape(s.Labels[i], true))
+				}
+				b.WriteString("}")
+			}
+			b.WriteString(" ")
+			b.WriteString(formatValue(s.Value))
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r *Registry) newVec(name string, help string, typ Type, labelNames []string) *vec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.vecs[name]; ok {
+		panic(fmt.Sprintf("metrics: %q is already registered", name))
+	}
+	v := &vec{
+		registry:   r,
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		values:     make(map[string]*Sample),
+	}
+	r.vecs[name] = v
+	return v
+}
+
+// vec holds the values of a counter or gauge for each set of label values.
+type vec struct {
+	mu         sync.Mutex
+	registry   *Registry
+	name       string
+	help       string
+	typ        Type
+	labelNames []string
+	values     map[string]*Sample
+}
+
+// update sets the value with labelValues to f of its current value and
+// returns the new value.
+func (v *vec) update(labelValues []string, f func(float64) float64) float64 {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %q has %d labels but got %d values", v.name, len(v.labelNames), len(labelValues)))
+	}
+	// Label values can't contain a null byte so it's safe to use as a
+	// separator.
+	key := strings.Join(labelValues, "\x00")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.values[key]
+	if !ok {
+		s = &Sample{Labels: append([]string(nil), labelValues...)}
+		v.values[key] = s
+	}
+	s.Value = f(s.Value)
+	return s.Value
+}
+
+// tags returns labelValues as "name:value" tags.
+func (v *vec) tags(labelValues []string) []string {
+	var tags []string
+	for i, name := range v.labelNames {
+		tags = append(tags, name+":"+labelValues[i])
+	}
+	return tags
+}
+
+func (v *vec) family() Family {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f := Family{
+		Name:       v.name,
+		Help:       v.help,
+		Type:       v.typ,
+		LabelNames: v.labelNames,
+	}
+	var keys []string
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.Samples = append(f.Samples, *v.values[k])
+	}
+	return f
+}
+
+func (r *Registry) getSink() Sink {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sink
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quotes {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,98 @@
+package metrics_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := metrics.NewRegistry()
+	requests := r.NewCounter("atlantis_requests", "Number of requests.", "path")
+	requests.Inc("/events")
+	requests.Inc("/events")
+	requests.Add(3, `/with"quote`)
+	running := r.NewGauge("atlantis_running", "Commands running.")
+	running.Add(2)
+	running.Add(-1)
+	r.NewTimer("atlantis_duration", "Time spent.").Observe(1500 * time.Millisecond)
+	r.Register(metrics.CollectorFunc(func() ([]metrics.Family, error) {
+		return []metrics.Family{
+			{
+				Name:       "atlantis_collected",
+				Help:       "Collected\nat scrape time.",
+				Type:       metrics.GaugeType,
+				LabelNames: []string{"a", "b"},
+				Samples: []metrics.Sample{
+					{Labels: []string{"1", "2"}, Value: 1.5},
+					{Labels: []string{"3", "4"}, Value: math.Inf(1)},
+				},
+			},
+		}, nil
+	}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	Equals(t, 200, w.Code)
+	Equals(t, metrics.ContentType, w.Header().Get("Content-Type"))
+	body, err := ioutil.ReadAll(w.Body)
+	Ok(t, err)
+	Equals(t, `# TYPE atlantis_collected gauge
+# HELP atlantis_collected Collected\nat scrape time.
+atlantis_collected{a="1",b="2"} 1.5
+atlantis_collected{a="3",b="4"} +Inf
+# TYPE atlantis_duration_seconds counter
+# HELP atlantis_duration_seconds Time spent.
+atlantis_duration_seconds_total 1.5
+# TYPE atlantis_requests counter
+# HELP atlantis_requests Number of requests.
+atlantis_requests_total{path="/events"} 2
+atlantis_requests_total{path="/with\"quote"} 3
+# TYPE atlantis_running gauge
+# HELP atlantis_running Commands running.
+atlantis_running 1
+# EOF
+`, string(body))
+}
+
+func TestRegistry_GatherCollectorError(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.NewGauge("atlantis_gauge", "A gauge.").Set(1)
+	r.Register(metrics.CollectorFunc(func() ([]metrics.Family, error) {
+		return nil, errors.New("oops")
+	}))
+	families, err := r.Gather()
+	ErrEquals(t, "collecting metrics: oops", err)
+	Equals(t, 1, len(families))
+
+	// We still serve what we could gather.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	Equals(t, 200, w.Code)
+	Assert(t, strings.Contains(w.Body.String(), "atlantis_gauge 1\n"), "expected gauge in %q", w.Body.String())
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.NewGauge("atlantis_gauge", "A gauge.")
+	defer func() {
+		Assert(t, recover() != nil, "expected panic")
+	}()
+	r.NewCounter("atlantis_gauge", "A counter.")
+}
+
+func TestCounter_WrongLabels(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.NewCounter("atlantis_counter", "A counter.", "repo")
+	defer func() {
+		Assert(t, recover() != nil, "expected panic")
+	}()
+	c.Inc()
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatsdSink sends every update to a registry's metrics to a statsd server,
+// ex. the Datadog agent, over UDP. Labels are sent as DogStatsD tags.
+type StatsdSink struct {
+	conn net.Conn
+	// prefix replaces the "atlantis_" prefix of metric names.
+	prefix string
+}
+
+// NewStatsdSink returns a sink that sends metrics to the statsd server at
+// addr, ex. "localhost:8125". Metric names have their "atlantis_" prefix
+// replaced by prefix, ex. atlantis_command_failures is sent as
+// atlantis.command_failures if prefix is "atlantis.".
+func NewStatsdSink(addr string, prefix string) (*StatsdSink, error) {
+	// Since it's UDP this doesn't connect to the server so it only fails if
+	// the address is invalid.
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to statsd at %q", addr)
+	}
+	return &StatsdSink{conn: conn, prefix: prefix}, nil
+}
+
+// Count sends a counter increment.
+func (s *StatsdSink) Count(name string, delta float64, tags []string) {
+	s.send(name, formatValue(delta), "c", tags)
+}
+
+// Gauge sends a gauge's new value.
+func (s *StatsdSink) Gauge(name string, value float64, tags []string) {
+	s.send(name, formatValue(value), "g", tags)
+}
+
+// Timing sends a duration in milliseconds.
+func (s *StatsdSink) Timing(name string, d time.Duration, tags []string) {
+	s.send(name, strconv.FormatInt(int64(d/time.Millisecond), 10), "ms", tags)
+}
+
+// send writes the metric in the statsd line format, ex.
+// atlantis.command_failures:1|c|#repo:owner/repo. Metrics are dropped if
+// they can't be sent since the server may not be listening.
+func (s *StatsdSink) send(name string, value string, typ string, tags []string) {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	b.WriteString(strings.TrimPrefix(name, "atlantis_"))
+	b.WriteString(":")
+	b.WriteString(value)
+	b.WriteString("|")
+	b.WriteString(typ)
+	if len(tags) > 0 {
+		b.WriteString("|#")
+		for i, tag := range tags {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(statsdTagReplacer.Replace(tag))
+		}
+	}
+	s.conn.Write([]byte(b.String())) // nolint: errcheck
+}
+
+// Close closes the connection to the statsd server.
+func (s *StatsdSink) Close() error {
+	return s.conn.Close()
+}
+
+// statsdTagReplacer replaces the characters that separate the parts of a
+// statsd line so they can't appear in tags.
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
@@ -0,0 +1,39 @@
+package metrics_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	sink, err := metrics.NewStatsdSink(conn.LocalAddr().String(), "atlantis.")
+	Ok(t, err)
+	defer sink.Close() // nolint: errcheck
+
+	r := metrics.NewRegistry()
+	r.SetSink(sink)
+	r.NewCounter("atlantis_commands", "", "repo", "command").Add(2, "owner/repo", "plan")
+	r.NewGauge("atlantis_queue_depth", "").Set(3)
+	r.NewTimer("atlantis_command_duration", "", "command").Observe(1500*time.Millisecond, "a|b,c")
+
+	var lines []string
+	buf := make([]byte, 1024)
+	for i := 0; i < 3; i++ {
+		Ok(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		Ok(t, err)
+		lines = append(lines, string(buf[:n]))
+	}
+	Equals(t, []string{
+		"atlantis.commands:2|c|#repo:owner/repo,command:plan",
+		"atlantis.queue_depth:3|g",
+		"atlantis.command_duration:1500|ms|#command:a_b_c",
+	}, lines)
+}
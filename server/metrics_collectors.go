@@ -0,0 +1,249 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// DefaultStalePlanAge is how old a plan has to be before it's counted as
+// stale if --stale-plan-age isn't set.
+const DefaultStalePlanAge = 24 * time.Hour
+
+// workingDirStatsTTL is how long the working dir metrics are cached for.
+// Computing them requires walking every clone so we don't want to do it on
+// every scrape.
+const workingDirStatsTTL = time.Minute
+
+// LockLister lists all the project locks. It's implemented by the locking
+// backends.
+type LockLister interface {
+	List() ([]models.ProjectLock, error)
+}
+
+// LocksCollector collects metrics about the current project locks.
+type LocksCollector struct {
+	Locks LockLister
+	Now   func() time.Time
+}
+
+// Collect implements metrics.Collector.
+func (l *LocksCollector) Collect() ([]metrics.Family, error) {
+	locks, err := l.Locks.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing locks")
+	}
+	now := l.now()
+	counts := make(map[string]float64)
+	oldest := make(map[string]float64)
+	ages := metrics.Family{
+		Name:       "atlantis_lock_age_seconds",
+		Help:       "How long each project lock has been held for.",
+		Type:       metrics.GaugeType,
+		LabelNames: []string{"repo", "path", "workspace"},
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		a, b := locks[i], locks[j]
+		if a.Project.RepoFullName != b.Project.RepoFullName {
+			return a.Project.RepoFullName < b.Project.RepoFullName
+		}
+		if a.Project.Path != b.Project.Path {
+			return a.Project.Path < b.Project.Path
+		}
+		return a.Workspace < b.Workspace
+	})
+	for _, lock := range locks {
+		repo := lock.Project.RepoFullName
+		age := now.Sub(lock.Time).Seconds()
+		counts[repo]++
+		if age > oldest[repo] {
+			oldest[repo] = age
+		}
+		ages.Samples = append(ages.Samples, metrics.Sample{
+			Labels: []string{repo, lock.Project.Path, lock.Workspace},
+			Value:  age,
+		})
+	}
+
+	lockCounts := metrics.Family{
+		Name:       "atlantis_locks",
+		Help:       "Number of project locks currently held.",
+		Type:       metrics.GaugeType,
+		LabelNames: []string{"repo"},
+	}
+	oldestAges := metrics.Family{
+		Name:       "atlantis_oldest_lock_age_seconds",
+		Help:       "Age of the oldest project lock.",
+		Type:       metrics.GaugeType,
+		LabelNames: []string{"repo"},
+	}
+	for _, repo := range sortedKeys(counts) {
+		lockCounts.Samples = append(lockCounts.Samples, metrics.Sample{Labels: []string{repo}, Value: counts[repo]})
+		oldestAges.Samples = append(oldestAges.Samples, metrics.Sample{Labels: []string{repo}, Value: oldest[repo]})
+	}
+	return []metrics.Family{lockCounts, oldestAges, ages}, nil
+}
+
+func (l *LocksCollector) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// LockingHealthCollector collects metrics about the health of the locking
+// backend.
+type LockingHealthCollector struct {
+	Health *locking.HealthChecker
+}
+
+// Collect implements metrics.Collector.
+func (l *LockingHealthCollector) Collect() ([]metrics.Family, error) {
+	status := l.Health.Status()
+	var up float64
+	if status.Healthy {
+		up = 1
+	}
+	return []metrics.Family{
+		{
+			Name:    "atlantis_locking_backend_up",
+			Help:    "Whether the last health check of the locking backend succeeded.",
+			Type:    metrics.GaugeType,
+			Samples: []metrics.Sample{{Value: up}},
+		},
+		{
+			Name:    "atlantis_locking_backend_failed_checks",
+			Help:    "Number of health checks of the locking backend that failed.",
+			Type:    metrics.CounterType,
+			Samples: []metrics.Sample{{Value: float64(status.FailedChecks)}},
+		},
+	}, nil
+}
+
+// WorkingDirCollector collects metrics about the repos cloned into the data
+// dir, or ReposDir if it's set.
+type WorkingDirCollector struct {
+	DataDir  string
+	ReposDir string
+	// StalePlanAge is how old a plan must be to be counted as stale.
+	StalePlanAge time.Duration
+	Now          func() time.Time
+
+	mu       sync.Mutex
+	cached   []metrics.Family
+	cachedAt time.Time
+}
+
+// Collect implements metrics.Collector.
+func (w *WorkingDirCollector) Collect() ([]metrics.Family, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if w.cached != nil && now.Sub(w.cachedAt) < workingDirStatsTTL {
+		return w.cached, nil
+	}
+
+	sizes := make(map[string]float64)
+	stalePlans := make(map[string]float64)
+	reposDir := events.ReposDir(w.DataDir, w.ReposDir)
+	err := filepath.Walk(reposDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+		// This is a clone at {repo full name}/{pull num}/{workspace}.
+		rel, err := filepath.Rel(reposDir, path)
+		if err != nil {
+			return err
+		}
+		repo := filepath.ToSlash(filepath.Dir(filepath.Dir(rel)))
+		if err := w.walkClone(path, repo, now, sizes, stalePlans); err != nil {
+			return err
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking working dirs")
+	}
+
+	diskUsage := metrics.Family{
+		Name:       "atlantis_working_dir_bytes",
+		Help:       "Disk space used by the repo's clones.",
+		Type:       metrics.GaugeType,
+		LabelNames: []string{"repo"},
+	}
+	for _, repo := range sortedKeys(sizes) {
+		diskUsage.Samples = append(diskUsage.Samples, metrics.Sample{Labels: []string{repo}, Value: sizes[repo]})
+	}
+	stale := metrics.Family{
+		Name:       "atlantis_stale_plans",
+		Help:       "Number of plans that haven't been applied or discarded after the stale plan age.",
+		Type:       metrics.GaugeType,
+		LabelNames: []string{"repo"},
+	}
+	for _, repo := range sortedKeys(sizes) {
+		stale.Samples = append(stale.Samples, metrics.Sample{Labels: []string{repo}, Value: stalePlans[repo]})
+	}
+
+	w.cached = []metrics.Family{stale, diskUsage}
+	w.cachedAt = now
+	return w.cached, nil
+}
+
+// walkClone adds the size and stale plans of the clone at dir to the totals
+// for repo.
+func (w *WorkingDirCollector) walkClone(dir string, repo string, now time.Time, sizes map[string]float64, stalePlans map[string]float64) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files can be deleted while we're walking, ex. if a pull
+			// request is closed.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		sizes[repo] += float64(info.Size())
+		// Ignore .terragrunt-cache dirs like the PendingPlanFinder.
+		if filepath.Ext(path) == ".tfplan" && !strings.Contains(path, ".terragrunt-cache"+string(filepath.Separator)) &&
+			now.Sub(info.ModTime()) > w.StalePlanAge {
+			stalePlans[repo]++
+		}
+		return nil
+	})
+}
+
+func (w *WorkingDirCollector) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+func sortedKeys(m map[string]float64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
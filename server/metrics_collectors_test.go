@@ -0,0 +1,178 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type staticLocks []models.ProjectLock
+
+func (s staticLocks) List() ([]models.ProjectLock, error) {
+	return s, nil
+}
+
+func TestLocksCollector_Collect(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	c := &server.LocksCollector{
+		Locks: staticLocks{
+			{
+				Project:   models.NewProject("owner/repo", "prod"),
+				Workspace: "default",
+				Time:      now.Add(-5 * 24 * time.Hour),
+			},
+			{
+				Project:   models.NewProject("owner/repo", "staging"),
+				Workspace: "default",
+				Time:      now.Add(-time.Hour),
+			},
+			{
+				Project:   models.NewProject("owner/other", "."),
+				Workspace: "default",
+				Time:      now.Add(-time.Minute),
+			},
+		},
+		Now: func() time.Time { return now },
+	}
+	families, err := c.Collect()
+	Ok(t, err)
+	Equals(t, []metrics.Family{
+		{
+			Name:       "atlantis_locks",
+			Help:       "Number of project locks currently held.",
+			Type:       metrics.GaugeType,
+			LabelNames: []string{"repo"},
+			Samples: []metrics.Sample{
+				{Labels: []string{"owner/other"}, Value: 1},
+				{Labels: []string{"owner/repo"}, Value: 2},
+			},
+		},
+		{
+			Name:       "atlantis_oldest_lock_age_seconds",
+			Help:       "Age of the oldest project lock.",
+			Type:       metrics.GaugeType,
+			LabelNames: []string{"repo"},
+			Samples: []metrics.Sample{
+				{Labels: []string{"owner/other"}, Value: 60},
+				{Labels: []string{"owner/repo"}, Value: 5 * 24 * 60 * 60},
+			},
+		},
+		{
+			Name:       "atlantis_lock_age_seconds",
+			Help:       "How long each project lock has been held for.",
+			Type:       metrics.GaugeType,
+			LabelNames: []string{"repo", "path", "workspace"},
+			Samples: []metrics.Sample{
+				{Labels: []string{"owner/other", ".", "default"}, Value: 60},
+				{Labels: []string{"owner/repo", "prod", "default"}, Value: 5 * 24 * 60 * 60},
+				{Labels: []string{"owner/repo", "staging", "default"}, Value: 60 * 60},
+			},
+		},
+	}, families)
+}
+
+func TestWorkingDirCollector_Collect(t *testing.T) {
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	writeFile := func(path string, contents string, modTime time.Time) {
+		abs := filepath.Join(dataDir, "repos", path)
+		Ok(t, os.MkdirAll(filepath.Dir(abs), 0700))
+		Ok(t, ioutil.WriteFile(abs, []byte(contents), 0600))
+		Ok(t, os.Chtimes(abs, modTime, modTime))
+	}
+	// A repo with one stale and one fresh plan.
+	writeFile("owner/repo/1/default/.git/HEAD", "12345", now)
+	writeFile("owner/repo/1/default/prod/default.tfplan", "1234567890", old)
+	writeFile("owner/repo/1/default/staging/default.tfplan", "12345", now)
+	writeFile("owner/repo/1/default/.terragrunt-cache/x/default.tfplan", "", old)
+	// A second pull for the same repo in another workspace.
+	writeFile("owner/repo/2/staging/.git/HEAD", "12345", now)
+	// A GitLab subgroup repo.
+	writeFile("group/subgroup/repo/3/default/.git/HEAD", "1", now)
+	writeFile("group/subgroup/repo/3/default/default.tfplan", "1", old)
+
+	c := &server.WorkingDirCollector{
+		DataDir:      dataDir,
+		StalePlanAge: 24 * time.Hour,
+	}
+	families, err := c.Collect()
+	Ok(t, err)
+	Equals(t, []metrics.Family{
+		{
+			Name:       "atlantis_stale_plans",
+			Help:       "Number of plans that haven't been applied or discarded after the stale plan age.",
+			Type:       metrics.GaugeType,
+			LabelNames: []string{"repo"},
+			Samples: []metrics.Sample{
+				{Labels: []string{"group/subgroup/repo"}, Value: 1},
+				{Labels: []string{"owner/repo"}, Value: 1},
+			},
+		},
+		{
+			Name:       "atlantis_working_dir_bytes",
+			Help:       "Disk space used by the repo's clones.",
+			Type:       metrics.GaugeType,
+			LabelNames: []string{"repo"},
+			Samples: []metrics.Sample{
+				{Labels: []string{"group/subgroup/repo"}, Value: 2},
+				{Labels: []string{"owner/repo"}, Value: 25},
+			},
+		},
+	}, families)
+
+	// The results are cached so new files aren't picked up right away.
+	writeFile("owner/new/1/default/.git/HEAD", "1", now)
+	cached, err := c.Collect()
+	Ok(t, err)
+	Equals(t, families, cached)
+}
+
+func TestWorkingDirCollector_NoReposDir(t *testing.T) {
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	c := &server.WorkingDirCollector{
+		DataDir:      dataDir,
+		StalePlanAge: time.Hour,
+	}
+	families, err := c.Collect()
+	Ok(t, err)
+	Equals(t, 2, len(families))
+	Equals(t, 0, len(families[0].Samples))
+}
+
+func TestLockingHealthCollector_Collect(t *testing.T) {
+	health := locking.NewHealthChecker(unavailableBackend{})
+	c := &server.LockingHealthCollector{Health: health}
+	families, err := c.Collect()
+	Ok(t, err)
+	Equals(t, 1.0, families[0].Samples[0].Value)
+	Equals(t, 0.0, families[1].Samples[0].Value)
+
+	health.Check() // nolint: errcheck
+	families, err = c.Collect()
+	Ok(t, err)
+	Equals(t, []metrics.Family{
+		{
+			Name:    "atlantis_locking_backend_up",
+			Help:    "Whether the last health check of the locking backend succeeded.",
+			Type:    metrics.GaugeType,
+			Samples: []metrics.Sample{{Value: 0}},
+		},
+		{
+			Name:    "atlantis_locking_backend_failed_checks",
+			Help:    "Number of health checks of the locking backend that failed.",
+			Type:    metrics.CounterType,
+			Samples: []metrics.Sample{{Value: 1}},
+		},
+	}, families)
+}
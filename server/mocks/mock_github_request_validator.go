@@ -25,11 +25,11 @@ func NewMockGithubRequestValidator(options ...pegomock.Option) *MockGithubReques
 func (mock *MockGithubRequestValidator) SetFailHandler(fh pegomock.FailHandler) { mock.fail = fh }
 func (mock *MockGithubRequestValidator) FailHandler() pegomock.FailHandler      { return mock.fail }
 
-func (mock *MockGithubRequestValidator) Validate(r *http.Request, secret []byte) ([]byte, error) {
+func (mock *MockGithubRequestValidator) Validate(r *http.Request, secrets [][]byte) ([]byte, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockGithubRequestValidator().")
 	}
-	params := []pegomock.Param{r, secret}
+	params := []pegomock.Param{r, secrets}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("Validate", params, []reflect.Type{reflect.TypeOf((*[]byte)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 []byte
 	var ret1 error
@@ -81,8 +81,8 @@ type VerifierMockGithubRequestValidator struct {
 	timeout                time.Duration
 }
 
-func (verifier *VerifierMockGithubRequestValidator) Validate(r *http.Request, secret []byte) *MockGithubRequestValidator_Validate_OngoingVerification {
-	params := []pegomock.Param{r, secret}
+func (verifier *VerifierMockGithubRequestValidator) Validate(r *http.Request, secrets [][]byte) *MockGithubRequestValidator_Validate_OngoingVerification {
+	params := []pegomock.Param{r, secrets}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Validate", params, verifier.timeout)
 	return &MockGithubRequestValidator_Validate_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -92,21 +92,21 @@ type MockGithubRequestValidator_Validate_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockGithubRequestValidator_Validate_OngoingVerification) GetCapturedArguments() (*http.Request, []byte) {
-	r, secret := c.GetAllCapturedArguments()
-	return r[len(r)-1], secret[len(secret)-1]
+func (c *MockGithubRequestValidator_Validate_OngoingVerification) GetCapturedArguments() (*http.Request, [][]byte) {
+	r, secrets := c.GetAllCapturedArguments()
+	return r[len(r)-1], secrets[len(secrets)-1]
 }
 
-func (c *MockGithubRequestValidator_Validate_OngoingVerification) GetAllCapturedArguments() (_param0 []*http.Request, _param1 [][]byte) {
+func (c *MockGithubRequestValidator_Validate_OngoingVerification) GetAllCapturedArguments() (_param0 []*http.Request, _param1 [][][]byte) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]*http.Request, len(params[0]))
+		_param0 = make([]*http.Request, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(*http.Request)
 		}
-		_param1 = make([][]byte, len(params[1]))
+		_param1 = make([][][]byte, len(c.methodInvocations))
 		for u, param := range params[1] {
-			_param1[u] = param.([]byte)
+			_param1[u] = param.([][]byte)
 		}
 	}
 	return
@@ -25,11 +25,11 @@ func NewMockGitlabRequestParserValidator(options ...pegomock.Option) *MockGitlab
 func (mock *MockGitlabRequestParserValidator) SetFailHandler(fh pegomock.FailHandler) { mock.fail = fh }
 func (mock *MockGitlabRequestParserValidator) FailHandler() pegomock.FailHandler      { return mock.fail }
 
-func (mock *MockGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secret []byte) (interface{}, error) {
+func (mock *MockGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secrets [][]byte) (interface{}, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockGitlabRequestParserValidator().")
 	}
-	params := []pegomock.Param{r, secret}
+	params := []pegomock.Param{r, secrets}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("ParseAndValidate", params, []reflect.Type{reflect.TypeOf((*interface{})(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 interface{}
 	var ret1 error
@@ -81,8 +81,8 @@ type VerifierMockGitlabRequestParserValidator struct {
 	timeout                time.Duration
 }
 
-func (verifier *VerifierMockGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secret []byte) *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification {
-	params := []pegomock.Param{r, secret}
+func (verifier *VerifierMockGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secrets [][]byte) *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification {
+	params := []pegomock.Param{r, secrets}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "ParseAndValidate", params, verifier.timeout)
 	return &MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -92,21 +92,21 @@ type MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification struc
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification) GetCapturedArguments() (*http.Request, []byte) {
-	r, secret := c.GetAllCapturedArguments()
-	return r[len(r)-1], secret[len(secret)-1]
+func (c *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification) GetCapturedArguments() (*http.Request, [][]byte) {
+	r, secrets := c.GetAllCapturedArguments()
+	return r[len(r)-1], secrets[len(secrets)-1]
 }
 
-func (c *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification) GetAllCapturedArguments() (_param0 []*http.Request, _param1 [][]byte) {
+func (c *MockGitlabRequestParserValidator_ParseAndValidate_OngoingVerification) GetAllCapturedArguments() (_param0 []*http.Request, _param1 [][][]byte) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]*http.Request, len(params[0]))
+		_param0 = make([]*http.Request, len(c.methodInvocations))
 		for u, param := range params[0] {
 			_param0[u] = param.(*http.Request)
 		}
-		_param1 = make([][]byte, len(params[1]))
+		_param1 = make([][][]byte, len(c.methodInvocations))
 		for u, param := range params[1] {
-			_param1[u] = param.([]byte)
+			_param1[u] = param.([][]byte)
 		}
 	}
 	return
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PlanOutputGetter gets the full output of truncated plans.
+type PlanOutputGetter interface {
+	// GetPlanOutput returns the plan output with id or nil if there isn't
+	// one.
+	GetPlanOutput(id string) (*models.PlanOutput, error)
+}
+
+// PlanOutputsController handles requests to view the full output of plans
+// that were truncated in their comment.
+type PlanOutputsController struct {
+	AtlantisVersion    string
+	AtlantisURL        *url.URL
+	Store              PlanOutputGetter
+	PlanOutputTemplate TemplateWriter
+	Logger             *logging.SimpleLogger
+}
+
+// GetPlanOutput is the GET /plan-output?id={id} route. It renders the full
+// output of a plan.
+func (p *PlanOutputsController) GetPlanOutput(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok || id == "" {
+		respond(w, p.Logger, logging.Warn, http.StatusBadRequest, "No plan output id in request")
+		return
+	}
+	idUnencoded, err := url.QueryUnescape(id)
+	if err != nil {
+		respond(w, p.Logger, logging.Warn, http.StatusBadRequest, "Invalid plan output id: %s", err)
+		return
+	}
+	output, err := p.Store.GetPlanOutput(idUnencoded)
+	if err != nil {
+		respond(w, p.Logger, logging.Error, http.StatusInternalServerError, "Failed getting plan output: %s", err)
+		return
+	}
+	if output == nil {
+		respond(w, p.Logger, logging.Info, http.StatusNotFound, "No plan output found at id %q", idUnencoded)
+		return
+	}
+
+	err = p.PlanOutputTemplate.Execute(w, PlanOutputData{
+		RepoFullName:    output.Pull.BaseRepo.FullName,
+		PullNum:         output.Pull.Num,
+		PullRequestLink: output.Pull.URL,
+		HeadCommit:      output.Pull.HeadCommit,
+		ProjectName:     output.ProjectName,
+		RepoRelDir:      output.RepoRelDir,
+		Workspace:       output.Workspace,
+		Output:          output.Output,
+		Apply:           output.Apply,
+		TimeFormatted:   output.CreatedAt.Format("02-01-2006 15:04:05"),
+		AtlantisVersion: p.AtlantisVersion,
+		CleanedBasePath: p.AtlantisURL.Path,
+	})
+	if err != nil {
+		p.Logger.Err(err.Error())
+	}
+}
@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGetPlanOutput(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	id, err := boltDB.PutPlanOutput(models.PlanOutput{
+		Pull: models.PullRequest{
+			Num:        1,
+			URL:        "url",
+			HeadCommit: "abc123",
+			BaseRepo:   models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}},
+		},
+		RepoRelDir: "dir",
+		Workspace:  "default",
+		Output:     "full output",
+		CreatedAt:  createdAt,
+	})
+	Ok(t, err)
+	tmpl := sMocks.NewMockTemplateWriter()
+	atlantisURL, err := url.Parse("https://example.com/basepath")
+	Ok(t, err)
+	c := server.PlanOutputsController{
+		AtlantisVersion:    "1300135",
+		AtlantisURL:        atlantisURL,
+		Store:              boltDB,
+		PlanOutputTemplate: tmpl,
+		Logger:             logging.NewNoopLogger(),
+	}
+
+	req, _ := http.NewRequest("GET", "", nil)
+	w := httptest.NewRecorder()
+	c.GetPlanOutput(w, req)
+	responseContains(t, w, http.StatusBadRequest, "No plan output id in request")
+
+	req = mux.SetURLVars(req, map[string]string{"id": url.QueryEscape("missing")})
+	w = httptest.NewRecorder()
+	c.GetPlanOutput(w, req)
+	responseContains(t, w, http.StatusNotFound, `No plan output found at id "missing"`)
+
+	req = mux.SetURLVars(req, map[string]string{"id": url.QueryEscape(id)})
+	w = httptest.NewRecorder()
+	c.GetPlanOutput(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.PlanOutputData{
+		RepoFullName:    "owner/repo",
+		PullNum:         1,
+		PullRequestLink: "url",
+		HeadCommit:      "abc123",
+		RepoRelDir:      "dir",
+		Workspace:       "default",
+		Output:          "full output",
+		TimeFormatted:   "02-01-2020 03:04:05",
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/basepath",
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PlansController discards the plans of pull requests from the API.
+type PlansController struct {
+	Discarder *events.PlanDiscarder
+	DB        db.PullStatusStore
+	Locker    locking.Locker
+	VCSClient vcs.Client
+	Logger    *logging.SimpleLogger
+}
+
+// DiscardPlansResponse is the response of the DELETE /api/plans route.
+type DiscardPlansResponse struct {
+	Discarded []events.DiscardedPlan `json:"discarded"`
+}
+
+// DiscardPlans is the DELETE /api/plans route. It discards the plans of the
+// pull request in the repo and pull query params, ex. repo=owner/repo&pull=1,
+// and releases their locks like the discard comment command. The dir,
+// workspace and project query params select the plans like the -d, -w and -p
+// flags. It comments on the pull request with the discarded plans and returns
+// them as JSON.
+func (p *PlansController) DiscardPlans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	repo := query.Get("repo")
+	if repo == "" {
+		respond(w, p.Logger, logging.Warn, http.StatusBadRequest, "repo query param must be set, ex. owner/repo")
+		return
+	}
+	pullNum, err := strconv.Atoi(query.Get("pull"))
+	if err != nil || pullNum <= 0 {
+		respond(w, p.Logger, logging.Warn, http.StatusBadRequest, "pull query param must be a pull request number, got %q", query.Get("pull"))
+		return
+	}
+	sel := events.DiscardSelector{
+		RepoRelDir:  query.Get("dir"),
+		Workspace:   query.Get("workspace"),
+		ProjectName: query.Get("project"),
+	}
+	if strings.HasPrefix(sel.RepoRelDir, "/") || strings.Contains(sel.RepoRelDir, "..") {
+		respond(w, p.Logger, logging.Warn, http.StatusBadRequest, "invalid dir %q, must be relative to the repo root and can't contain ..", sel.RepoRelDir)
+		return
+	}
+
+	pull, ok, err := findKnownPull(p.DB, p.Locker, repo, pullNum)
+	if err != nil {
+		respond(w, p.Logger, logging.Error, http.StatusInternalServerError, "Failed finding pull request: %s", err)
+		return
+	}
+	if !ok {
+		respond(w, p.Logger, logging.Info, http.StatusNotFound, "Pull request %s#%d isn't known to Atlantis", repo, pullNum)
+		return
+	}
+
+	user := RequestUser(r)
+	plans, err := p.Discarder.Discard(p.Logger, pull, user, sel)
+	if err != nil {
+		respond(w, p.Logger, logging.Error, http.StatusInternalServerError, "Failed discarding plans: %s", err)
+		return
+	}
+	if len(plans) > 0 {
+		if err := p.VCSClient.CreateComment(pull.BaseRepo, pull.Num, events.DiscardComment(plans, user, "the Atlantis API")); err != nil {
+			p.Logger.Err("unable to comment on pull request: %s", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(DiscardPlansResponse{Discarded: plans}); err != nil {
+		p.Logger.Err("writing response: %s", err)
+	}
+}
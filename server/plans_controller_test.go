@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDiscardPlans(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	pull := models.PullRequest{Num: 1, BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, ProjectName: "app", RepoRelDir: "app", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		{Command: models.PlanCommand, ProjectName: "db", RepoRelDir: "db", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmp, nil)
+	locker := lockmocks.NewMockLocker()
+	vcsClient := vcsmocks.NewMockClient()
+	pc := server.PlansController{
+		Discarder: &events.PlanDiscarder{
+			WorkingDir:       workingDir,
+			WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+			DB:               store,
+			Locker:           locker,
+		},
+		DB:        store,
+		Locker:    locker,
+		VCSClient: vcsClient,
+		Logger:    logging.NewNoopLogger(),
+	}
+
+	for query, expCode := range map[string]int{
+		"":                                http.StatusBadRequest,
+		"?repo=owner/repo&pull=abc":       http.StatusBadRequest,
+		"?repo=owner/repo&pull=1&dir=../": http.StatusBadRequest,
+		"?repo=owner/repo&pull=2":         http.StatusNotFound,
+	} {
+		req, _ := http.NewRequest("DELETE", "/api/plans"+query, nil)
+		w := httptest.NewRecorder()
+		pc.DiscardPlans(w, req)
+		Equals(t, expCode, w.Code)
+	}
+
+	req, _ := http.NewRequest("DELETE", "/api/plans?repo=OWNER/repo&pull=1&project=app", nil)
+	w := httptest.NewRecorder()
+	pc.DiscardPlans(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var resp server.DiscardPlansResponse
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	Equals(t, []events.DiscardedPlan{{ProjectName: "app", RepoRelDir: "app", Workspace: "default"}}, resp.Discarded)
+	vcsClient.VerifyWasCalledOnce().CreateComment(repo, 1, events.DiscardComment(resp.Discarded, "", "the Atlantis API"))
+
+	status, err := store.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, "db", status.Projects[0].ProjectName)
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PullsController shows the open pull requests Atlantis knows about with the
+// last plan and apply status of their projects, the locks they hold and the
+// commands running on them.
+type PullsController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	DB              db.PullStatusStore
+	Locker          locking.Locker
+	Router          *Router
+	// RunningCommands, if set, lists the commands running on each pull
+	// request.
+	RunningCommands *events.RunningCommands
+	PullsTemplate   TemplateWriter
+	Logger          *logging.SimpleLogger
+	// GlobalCfgStore, if set, holds the server-side repo config. Users who
+	// belong to its tenants can only see their tenants' pull requests.
+	GlobalCfgStore *events.GlobalCfgStore
+}
+
+// PullData is the API representation of a pull request's status.
+type PullData struct {
+	RepoFullName string `json:"repo_full_name"`
+	PullNum      int    `json:"pull_num"`
+	PullURL      string `json:"pull_url"`
+	Author       string `json:"author"`
+	HeadCommit   string `json:"head_commit"`
+	// Projects are the pull request's planned projects and the projects it
+	// holds locks for.
+	Projects []PullProjectData `json:"projects"`
+	// Running are the commands running on the pull request, oldest first.
+	Running []events.RunningCommand `json:"running,omitempty"`
+}
+
+// PullProjectData is the status of a project in PullData.
+type PullProjectData struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name,omitempty"`
+	Path        string `json:"path"`
+	Workspace   string `json:"workspace"`
+	// Status is the result of the project's last plan or apply, ex. planned
+	// or apply_errored. It's empty if the project is only locked, ex. because
+	// its status was deleted when its plan was discarded.
+	Status    string `json:"status,omitempty"`
+	NoChanges bool   `json:"no_changes"`
+	// LockID and LockURL are set if the pull request holds the project's lock.
+	LockID  string `json:"lock_id,omitempty"`
+	LockURL string `json:"lock_url,omitempty"`
+}
+
+// ListPulls is the GET /api/pulls route. It returns the pull requests as JSON,
+// sorted by repo and pull request number. If the repo query param is set,
+// only pull requests in that repo, ex. owner/repo, are returned.
+func (p *PullsController) ListPulls(w http.ResponseWriter, r *http.Request) {
+	pulls, err := p.pulls(r.URL.Query().Get("repo"), repoScope(p.GlobalCfgStore, r))
+	if err != nil {
+		respond(w, p.Logger, logging.Error, http.StatusInternalServerError, "Failed listing pull requests: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pulls); err != nil {
+		p.Logger.Err("writing response: %s", err)
+	}
+}
+
+// GetPullsPage is the GET /pulls route. It renders the pull requests, filtered
+// like ListPulls.
+func (p *PullsController) GetPullsPage(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("repo")
+	pulls, err := p.pulls(repo, repoScope(p.GlobalCfgStore, r))
+	if err != nil {
+		respond(w, p.Logger, logging.Error, http.StatusServiceUnavailable, "Could not retrieve pull requests: %s", err)
+		return
+	}
+	err = p.PullsTemplate.Execute(w, PullsData{
+		Pulls:           pulls,
+		Repo:            repo,
+		AtlantisVersion: p.AtlantisVersion,
+		CleanedBasePath: p.AtlantisURL.Path,
+	})
+	if err != nil {
+		p.Logger.Err(err.Error())
+	}
+}
+
+// pulls joins the pull statuses with the locks. Pull requests that only hold
+// locks are included too. If repo is set, only its pull requests are
+// returned. If visible is set, only pull requests in the repos it returns
+// true for are returned.
+func (p *PullsController) pulls(repo string, visible func(repoID string) bool) ([]PullData, error) {
+	statuses, err := p.DB.ListPullStatuses()
+	if err != nil {
+		return nil, err
+	}
+	locks, err := p.Locker.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Locks don't store the VCS host so pull requests are keyed by repo and
+	// number.
+	var pulls []*PullData
+	byKey := make(map[string]*PullData)
+	get := func(pull models.PullRequest) *PullData {
+		key := fmt.Sprintf("%s#%d", pull.BaseRepo.FullName, pull.Num)
+		if d, ok := byKey[key]; ok {
+			return d
+		}
+		d := &PullData{
+			RepoFullName: pull.BaseRepo.FullName,
+			PullNum:      pull.Num,
+			PullURL:      pull.URL,
+			Author:       pull.Author,
+			HeadCommit:   pull.HeadCommit,
+		}
+		byKey[key] = d
+		pulls = append(pulls, d)
+		return d
+	}
+	for _, s := range statuses {
+		if repo != "" && !strings.EqualFold(repo, s.Pull.BaseRepo.FullName) {
+			continue
+		}
+		if visible != nil && !visible(s.Pull.BaseRepo.ID()) {
+			continue
+		}
+		d := get(s.Pull)
+		for _, proj := range s.Projects {
+			d.Projects = append(d.Projects, PullProjectData{
+				ProjectID:   models.ProjectID(s.Pull.BaseRepo.FullName, proj.RepoRelDir, proj.Workspace),
+				ProjectName: proj.ProjectName,
+				Path:        proj.RepoRelDir,
+				Workspace:   proj.Workspace,
+				Status:      proj.Status.String(),
+				NoChanges:   proj.NoChanges,
+			})
+		}
+	}
+	for id, lock := range locks {
+		if repo != "" && !strings.EqualFold(repo, lock.Project.RepoFullName) {
+			continue
+		}
+		if visible != nil && !visible(lockRepoID(lock)) {
+			continue
+		}
+		// Locks from before BaseRepo was stored on pull requests only have
+		// the project's repo.
+		pull := lock.Pull
+		pull.BaseRepo.FullName = lock.Project.RepoFullName
+		d := get(pull)
+		lockURL := p.Router.GenerateLockURL(id)
+		found := false
+		for i := range d.Projects {
+			proj := &d.Projects[i]
+			if proj.ProjectID == lock.ProjectID() {
+				proj.LockID = id
+				proj.LockURL = lockURL
+				found = true
+			}
+		}
+		if !found {
+			d.Projects = append(d.Projects, PullProjectData{
+				ProjectID: lock.ProjectID(),
+				Path:      lock.Project.Path,
+				Workspace: lock.Workspace,
+				LockID:    id,
+				LockURL:   lockURL,
+			})
+		}
+	}
+
+	for _, cmd := range p.RunningCommands.List() {
+		if repo != "" && !strings.EqualFold(repo, cmd.Repo) {
+			continue
+		}
+		if visible != nil && !visible(cmd.RepoID()) {
+			continue
+		}
+		// Pull requests are only known once their first command has
+		// finished or locked a project.
+		d := get(models.PullRequest{Num: cmd.Pull, BaseRepo: models.Repo{FullName: cmd.Repo}})
+		d.Running = append(d.Running, cmd)
+	}
+
+	sort.Slice(pulls, func(i, j int) bool {
+		if pulls[i].RepoFullName != pulls[j].RepoFullName {
+			return pulls[i].RepoFullName < pulls[j].RepoFullName
+		}
+		return pulls[i].PullNum < pulls[j].PullNum
+	})
+	data := make([]PullData, 0, len(pulls))
+	for _, d := range pulls {
+		sort.SliceStable(d.Projects, func(i, j int) bool {
+			a, b := d.Projects[i], d.Projects[j]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.Workspace < b.Workspace
+		})
+		data = append(data, *d)
+	}
+	return data, nil
+}
+
+// findKnownPull returns the pull request pullNum in repo, ex. owner/repo, from
+// its status or the locks it holds since that's where the repo's VCS host and
+// clone URL are stored. It returns false if Atlantis doesn't know the pull
+// request.
+func findKnownPull(store db.PullStatusStore, locker locking.Locker, repo string, pullNum int) (models.PullRequest, bool, error) {
+	statuses, err := store.ListPullStatuses()
+	if err != nil {
+		return models.PullRequest{}, false, fmt.Errorf("listing pull requests: %s", err)
+	}
+	for _, st := range statuses {
+		if st.Pull.Num == pullNum && strings.EqualFold(st.Pull.BaseRepo.FullName, repo) {
+			return st.Pull, true, nil
+		}
+	}
+	locks, err := locker.List()
+	if err != nil {
+		return models.PullRequest{}, false, fmt.Errorf("listing locks: %s", err)
+	}
+	for _, lock := range locks {
+		// Locks from before BaseRepo was stored on pull requests can't be
+		// used to find the pull request's repo.
+		if lock.Pull.Num == pullNum && lock.Pull.BaseRepo.VCSHost.Hostname != "" && strings.EqualFold(lock.Pull.BaseRepo.FullName, repo) {
+			return lock.Pull, true, nil
+		}
+	}
+	return models.PullRequest{}, false, nil
+}
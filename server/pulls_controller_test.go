@@ -0,0 +1,167 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestListPulls(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	otherRepo := models.Repo{FullName: "owner/other", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	pull := models.PullRequest{Num: 1, URL: "url", Author: "lkysow", HeadCommit: "sha", BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "staging", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		{Command: models.PlanCommand, RepoRelDir: "prod", Workspace: "default", ProjectName: "prod", Failure: "failure"},
+	})
+	Ok(t, err)
+
+	l := mocks.NewMockLocker()
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/staging/default": {
+			Project:   models.NewProject("owner/repo", "staging"),
+			Workspace: "default",
+			Pull:      pull,
+		},
+		// The plan of this lock was discarded so it has no status.
+		"owner/other/./default": {
+			Project:   models.NewProject("owner/other", "."),
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 2, BaseRepo: otherRepo},
+		},
+	}, nil)
+
+	r := mux.NewRouter()
+	r.NewRoute().Path("/lock").Queries("id", "{id}").Name(server.LockViewRouteName)
+	u, err := url.Parse("https://example.com")
+	Ok(t, err)
+	pc := server.PullsController{
+		DB:     store,
+		Locker: l,
+		Router: &server.Router{
+			Underlying:                r,
+			LockViewRouteName:         server.LockViewRouteName,
+			LockViewRouteIDQueryParam: server.LockViewRouteIDQueryParam,
+			AtlantisURL:               u,
+		},
+		Logger: logging.NewNoopLogger(),
+	}
+
+	req, _ := http.NewRequest("GET", "/api/pulls", nil)
+	w := httptest.NewRecorder()
+	pc.ListPulls(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var pulls []server.PullData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &pulls))
+	Equals(t, []server.PullData{
+		{
+			RepoFullName: "owner/other",
+			PullNum:      2,
+			Projects: []server.PullProjectData{
+				{
+					ProjectID: models.ProjectID("owner/other", ".", "default"),
+					Path:      ".",
+					Workspace: "default",
+					LockID:    "owner/other/./default",
+					LockURL:   "https://example.com/lock?id=owner%252Fother%252F.%252Fdefault",
+				},
+			},
+		},
+		{
+			RepoFullName: "owner/repo",
+			PullNum:      1,
+			PullURL:      "url",
+			Author:       "lkysow",
+			HeadCommit:   "sha",
+			Projects: []server.PullProjectData{
+				{
+					ProjectID:   models.ProjectID("owner/repo", "prod", "default"),
+					ProjectName: "prod",
+					Path:        "prod",
+					Workspace:   "default",
+					Status:      "plan_errored",
+				},
+				{
+					ProjectID: models.ProjectID("owner/repo", "staging", "default"),
+					Path:      "staging",
+					Workspace: "default",
+					Status:    "planned",
+					LockID:    "owner/repo/staging/default",
+					LockURL:   "https://example.com/lock?id=owner%252Frepo%252Fstaging%252Fdefault",
+				},
+			},
+		},
+	}, pulls)
+
+	// Filtering by repo.
+	req, _ = http.NewRequest("GET", "/api/pulls?repo=owner/repo", nil)
+	w = httptest.NewRecorder()
+	pc.ListPulls(w, req)
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &pulls))
+	Equals(t, 1, len(pulls))
+	Equals(t, "owner/repo", pulls[0].RepoFullName)
+}
+
+func TestListPulls_Tenants(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+	for _, fullName := range []string{"team-a/infra", "team-b/infra"} {
+		pull := models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: fullName, VCSHost: models.VCSHost{Hostname: "github.com"}}}
+		_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+			{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		})
+		Ok(t, err)
+	}
+	l := mocks.NewMockLocker()
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{}, nil)
+	pc := server.PullsController{
+		DB:     store,
+		Locker: l,
+		Logger: logging.NewNoopLogger(),
+		GlobalCfgStore: events.NewGlobalCfgStore(valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{ID: "github.com/team-a/infra", Tenant: "team-a"},
+				{ID: "github.com/team-b/infra", Tenant: "team-b"},
+			},
+			Tenants: map[string]valid.Tenant{
+				"team-b": {Name: "team-b", Users: []string{"api-token:team-b"}},
+			},
+		}),
+	}
+	auth := server.APIAuthenticator{
+		StaticTokens: []models.APIToken{{Name: "team-b", Hash: server.HashAPIToken("secret"), Scopes: []string{server.PullsReadScope}}},
+		Logger:       logging.NewNoopLogger(),
+	}
+
+	req, _ := http.NewRequest("GET", "/api/pulls", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	auth.Wrap(server.PullsReadScope, pc.ListPulls)(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var pulls []server.PullData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &pulls))
+	Equals(t, 1, len(pulls))
+	Equals(t, "team-b/infra", pulls[0].RepoFullName)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// RepoConfigWatchInterval is how often the --repo-config file is checked for
+// changes if it's watched.
+const RepoConfigWatchInterval = 10 * time.Second
+
+// RepoConfigController reloads the server-side repo config file so changes
+// don't require restarting the server, which would drop the commands that
+// are running.
+type RepoConfigController struct {
+	// Path is the path to the --repo-config file.
+	Path string
+	// Defaults returns the config that the file is merged into, ex. with the
+	// --require-approval flag applied. It's called for each reload since
+	// parsing modifies the defaults.
+	Defaults        func() valid.GlobalCfg
+	ParserValidator *yaml.ParserValidator
+	Store           *events.GlobalCfgStore
+	Logger          *logging.SimpleLogger
+	// Shadow is true if the file is the --repo-config-shadow candidate, which
+	// is only compared to the active config.
+	Shadow bool
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// Reload is the POST /api/reload-config route. It reloads the config file. If
+// it's invalid, the current config is kept.
+func (rc *RepoConfigController) Reload(w http.ResponseWriter, _ *http.Request) {
+	if err := rc.reload(); err != nil {
+		respond(w, rc.Logger, logging.Warn, http.StatusBadRequest, "Failed reloading %s, still using the previous config: %s", rc.Path, err)
+		return
+	}
+	respond(w, rc.Logger, logging.Info, http.StatusOK, "Reloaded %s", rc.Path)
+}
+
+// Watch reloads the config file whenever its modification time changes. It
+// checks the file every interval and never returns.
+func (rc *RepoConfigController) Watch(interval time.Duration) {
+	rc.mu.Lock()
+	if info, err := os.Stat(rc.Path); err == nil {
+		rc.modTime = info.ModTime()
+	}
+	rc.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		// Stat follows symlinks so files mounted from Kubernetes config maps,
+		// which are updated by swapping a symlink, are reloaded too.
+		info, err := os.Stat(rc.Path)
+		if err != nil {
+			rc.Logger.Warn("unable to check %s for changes: %s", rc.Path, err)
+			continue
+		}
+		rc.mu.Lock()
+		changed := !info.ModTime().Equal(rc.modTime)
+		rc.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if err := rc.reload(); err != nil {
+			rc.Logger.Err("failed reloading %s, still using the previous config: %s", rc.Path, err)
+			// We don't retry until the file changes again since it would
+			// fail the same way.
+			rc.mu.Lock()
+			rc.modTime = info.ModTime()
+			rc.mu.Unlock()
+			continue
+		}
+		rc.Logger.Info("reloaded %s", rc.Path)
+	}
+}
+
+// reload parses the config file and replaces the config in Store with it.
+func (rc *RepoConfigController) reload() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	info, statErr := os.Stat(rc.Path)
+	cfg, err := rc.ParserValidator.ParseGlobalCfg(rc.Path, rc.Defaults())
+	if err != nil {
+		return err
+	}
+
+	// These keys are only read on startup.
+	prev := rc.Store.Get()
+	if !rc.Shadow && !reflect.DeepEqual(prev.ConcurrencyGroups, cfg.ConcurrencyGroups) {
+		rc.Logger.Warn("changes to the %s key won't take effect until Atlantis is restarted", "concurrency_groups")
+	}
+	if !rc.Shadow && !reflect.DeepEqual(prev.ProviderRetries, cfg.ProviderRetries) {
+		rc.Logger.Warn("changes to the %s key won't take effect until Atlantis is restarted", "provider_retries")
+	}
+
+	rc.Store.Set(cfg)
+	if statErr == nil {
+		rc.modTime = info.ModTime()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newRepoConfigController(t *testing.T, contents string) (*server.RepoConfigController, func()) {
+	tmp, cleanup := TempDir(t)
+	path := filepath.Join(tmp, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	defaults := func() valid.GlobalCfg { return valid.NewGlobalCfg(false, false, false) }
+	validator := &yaml.ParserValidator{}
+	cfg, err := validator.ParseGlobalCfg(path, defaults())
+	Ok(t, err)
+	return &server.RepoConfigController{
+		Path:            path,
+		Defaults:        defaults,
+		ParserValidator: validator,
+		Store:           events.NewGlobalCfgStore(cfg),
+		Logger:          logging.NewNoopLogger(),
+	}, cleanup
+}
+
+func reloadRepoConfig(rc *server.RepoConfigController) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/reload-config", nil)
+	w := httptest.NewRecorder()
+	rc.Reload(w, req)
+	return w
+}
+
+func TestReloadRepoConfig(t *testing.T) {
+	rc, cleanup := newRepoConfigController(t, "repos:\n- id: /.*/\n")
+	defer cleanup()
+	Equals(t, []string(nil), rc.Store.Get().Repos[1].ApplyRequirements)
+
+	Ok(t, ioutil.WriteFile(rc.Path, []byte("repos:\n- id: /.*/\n  apply_requirements: [approved]\n"), 0600))
+	w := reloadRepoConfig(rc)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "Reloaded "+rc.Path+"\n", w.Body.String())
+	Equals(t, []string{"approved"}, rc.Store.Get().Repos[1].ApplyRequirements)
+
+	// The defaults shouldn't be modified by earlier reloads.
+	Equals(t, 2, len(rc.Store.Get().Repos))
+}
+
+func TestReloadRepoConfig_InvalidKeepsPrevious(t *testing.T) {
+	rc, cleanup := newRepoConfigController(t, "repos:\n- id: /.*/\n  apply_requirements: [approved]\n")
+	defer cleanup()
+
+	Ok(t, ioutil.WriteFile(rc.Path, []byte("repos:\n- id: /.*/\n  apply_requirements: [invalid]\n"), 0600))
+	w := reloadRepoConfig(rc)
+	Equals(t, http.StatusBadRequest, w.Code)
+	Assert(t, len(w.Body.String()) > 0, "expected error in response")
+	Equals(t, []string{"approved"}, rc.Store.Get().Repos[1].ApplyRequirements)
+}
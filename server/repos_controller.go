@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_repo_onboarder.go RepoOnboarder
+
+// RepoOnboarder creates Atlantis's webhook on a VCS host's repos. It's
+// implemented by the VCS clients that support onboarding.
+type RepoOnboarder interface {
+	// CheckWebhookPermissions returns an error if Atlantis's VCS user can't
+	// manage the webhooks of the repo.
+	CheckWebhookPermissions(repoFullName string) error
+	// EnsureWebhook creates a webhook that sends events to hookURL, signed
+	// with secret, unless the repo already has one. It returns the ID of the
+	// webhook and whether it was created.
+	EnsureWebhook(repoFullName string, hookURL string, secret string) (string, bool, error)
+}
+
+// OnboardedRepoStore stores the repos onboarded via the API.
+type OnboardedRepoStore interface {
+	PutOnboardedRepo(repo models.OnboardedRepo) error
+	ListOnboardedRepos() ([]models.OnboardedRepo, error)
+	DeleteOnboardedRepo(id string) (bool, error)
+}
+
+// ReposController handles requests to onboard repos.
+type ReposController struct {
+	// Onboarders are the onboarders for each VCS hostname, ex. github.com.
+	// Repos on other hosts can't be onboarded.
+	Onboarders map[string]RepoOnboarder
+	// WebhookSecrets are the secrets to sign each VCS hostname's webhooks
+	// with. Hosts without a secret get unsigned webhooks.
+	WebhookSecrets map[string]string
+	// WebhookURL is the URL webhooks are sent to, ex.
+	// https://atlantis.example.com/events.
+	WebhookURL string
+	Store      OnboardedRepoStore
+	Logger     *logging.SimpleLogger
+}
+
+// OnboardedRepoData is the API representation of an onboarded repo.
+type OnboardedRepoData struct {
+	Repo        string    `json:"repo"`
+	WebhookID   string    `json:"webhook_id"`
+	OnboardedBy string    `json:"onboarded_by,omitempty"`
+	OnboardedAt time.Time `json:"onboarded_at"`
+	// WebhookCreated is true if Atlantis created the webhook, false if the
+	// repo already had one. It's only set in the onboard response.
+	WebhookCreated *bool `json:"webhook_created,omitempty"`
+}
+
+// ListRepos is the GET /api/repos route. It lists the repos onboarded via the
+// API.
+func (rc *ReposController) ListRepos(w http.ResponseWriter, _ *http.Request) {
+	repos, err := rc.Store.ListOnboardedRepos()
+	if err != nil {
+		respond(w, rc.Logger, logging.Error, http.StatusInternalServerError, "Failed listing onboarded repos: %s", err)
+		return
+	}
+	data := []OnboardedRepoData{}
+	for _, r := range repos {
+		data = append(data, OnboardedRepoData{
+			Repo:        r.ID,
+			WebhookID:   r.WebhookID,
+			OnboardedBy: r.OnboardedBy,
+			OnboardedAt: r.OnboardedAt,
+		})
+	}
+	rc.respondJSON(w, http.StatusOK, data)
+}
+
+// OnboardRepo is the POST /api/repos route. It checks that Atlantis can manage
+// the repo's webhooks, creates its webhook if it doesn't have one yet and
+// whitelists the repo. Onboarding a repo again is a no-op apart from updating
+// its onboarded time.
+func (rc *ReposController) OnboardRepo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Repo string `json:"repo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, rc.Logger, logging.Warn, http.StatusBadRequest, "Invalid request body: %s", err)
+		return
+	}
+	hostname, fullName, err := splitRepoID(req.Repo)
+	if err != nil {
+		respond(w, rc.Logger, logging.Warn, http.StatusBadRequest, "Invalid repo: %s", err)
+		return
+	}
+	onboarder, ok := rc.Onboarders[hostname]
+	if !ok {
+		respond(w, rc.Logger, logging.Warn, http.StatusBadRequest, "Invalid repo: onboarding isn't supported for %q", hostname)
+		return
+	}
+
+	if err := onboarder.CheckWebhookPermissions(fullName); err != nil {
+		respond(w, rc.Logger, logging.Warn, http.StatusForbidden, "Can't onboard %s: %s", req.Repo, err)
+		return
+	}
+	webhookID, created, err := onboarder.EnsureWebhook(fullName, rc.WebhookURL, rc.WebhookSecrets[hostname])
+	if err != nil {
+		respond(w, rc.Logger, logging.Error, http.StatusBadGateway, "Failed creating webhook for %s: %s", req.Repo, err)
+		return
+	}
+	repo := models.OnboardedRepo{
+		ID:          fmt.Sprintf("%s/%s", hostname, fullName),
+		WebhookID:   webhookID,
+		OnboardedBy: RequestUser(r),
+		OnboardedAt: time.Now().UTC(),
+	}
+	if err := rc.Store.PutOnboardedRepo(repo); err != nil {
+		respond(w, rc.Logger, logging.Error, http.StatusInternalServerError, "Failed saving onboarded repo: %s", err)
+		return
+	}
+	rc.Logger.Info("onboarded %s with webhook %s (created: %t)", repo.ID, webhookID, created)
+	rc.respondJSON(w, http.StatusOK, OnboardedRepoData{
+		Repo:           repo.ID,
+		WebhookID:      repo.WebhookID,
+		OnboardedBy:    repo.OnboardedBy,
+		OnboardedAt:    repo.OnboardedAt,
+		WebhookCreated: &created,
+	})
+}
+
+// OffboardRepo is the DELETE /api/repos/{repo} route. It removes the repo from
+// the onboarded repos so it's no longer whitelisted, unless it's matched by
+// the repo whitelist. The repo's webhook isn't deleted.
+func (rc *ReposController) OffboardRepo(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["repo"]
+	deleted, err := rc.Store.DeleteOnboardedRepo(id)
+	if err != nil {
+		respond(w, rc.Logger, logging.Error, http.StatusInternalServerError, "Failed deleting onboarded repo: %s", err)
+		return
+	}
+	if !deleted {
+		respond(w, rc.Logger, logging.Info, http.StatusNotFound, "Repo %s isn't onboarded", id)
+		return
+	}
+	respond(w, rc.Logger, logging.Info, http.StatusOK, "Offboarded repo %s", id)
+}
+
+// splitRepoID splits a repo ID, ex. github.com/owner/repo, into its hostname
+// and full name.
+func splitRepoID(id string) (string, string, error) {
+	if strings.Contains(id, "://") {
+		return "", "", fmt.Errorf("%q must not contain a scheme, ex. github.com/owner/repo", id)
+	}
+	parts := strings.SplitN(strings.Trim(id, "/"), "/", 2)
+	if len(parts) != 2 || !strings.Contains(parts[1], "/") {
+		return "", "", fmt.Errorf("%q must be in the format {hostname}/{owner}/{repo}", id)
+	}
+	for _, p := range strings.Split(parts[1], "/") {
+		if p == "" || p == "." || p == ".." {
+			return "", "", fmt.Errorf("%q must be in the format {hostname}/{owner}/{repo}", id)
+		}
+	}
+	return parts[0], parts[1], nil
+}
+
+// vcsHostname returns the hostname from a VCS hostname flag, which may
+// include a scheme and port, ex. https://gitlab.example.com:8443. Repos are
+// identified by the hostname alone.
+func vcsHostname(hostname string) string {
+	if !strings.Contains(hostname, "://") {
+		hostname = "https://" + hostname
+	}
+	parsed, err := url.Parse(hostname)
+	if err != nil {
+		return hostname
+	}
+	return parsed.Hostname()
+}
+
+// respondJSON writes data as JSON.
+func (rc *ReposController) respondJSON(w http.ResponseWriter, responseCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(responseCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		rc.Logger.Err("writing response: %s", err)
+	}
+}
@@ -0,0 +1,163 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func newReposController(t *testing.T) (*server.ReposController, *mocks.MockRepoOnboarder, func()) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	onboarder := mocks.NewMockRepoOnboarder()
+	return &server.ReposController{
+		Onboarders:     map[string]server.RepoOnboarder{"github.com": onboarder},
+		WebhookSecrets: map[string]string{"github.com": "secret"},
+		WebhookURL:     "https://atlantis.example.com/events",
+		Store:          boltDB,
+		Logger:         logging.NewNoopLogger(),
+	}, onboarder, cleanup
+}
+
+// onboard calls the onboard route as the API token "ci".
+func onboard(c *server.ReposController, body string) *httptest.ResponseRecorder {
+	a := &server.APIAuthenticator{
+		StaticTokens: []models.APIToken{
+			{Name: "ci", Hash: server.HashAPIToken("ci-secret"), Scopes: []string{server.ReposManageScope}},
+		},
+		Logger: logging.NewNoopLogger(),
+	}
+	req, _ := http.NewRequest("POST", "/api/repos", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer ci-secret")
+	w := httptest.NewRecorder()
+	a.Wrap(server.ReposManageScope, c.OnboardRepo)(w, req)
+	return w
+}
+
+func TestReposController_OnboardAndList(t *testing.T) {
+	c, onboarder, cleanup := newReposController(t)
+	defer cleanup()
+	When(onboarder.EnsureWebhook("owner/repo", "https://atlantis.example.com/events", "secret")).
+		ThenReturn("123", true, nil)
+
+	w := onboard(c, `{"repo": "github.com/owner/repo"}`)
+	Equals(t, http.StatusOK, w.Code)
+	var onboarded server.OnboardedRepoData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &onboarded))
+	Equals(t, "github.com/owner/repo", onboarded.Repo)
+	Equals(t, "123", onboarded.WebhookID)
+	Equals(t, "api-token:ci", onboarded.OnboardedBy)
+	Equals(t, true, *onboarded.WebhookCreated)
+	onboarder.VerifyWasCalledOnce().CheckWebhookPermissions("owner/repo")
+
+	// The repo should now be whitelisted.
+	repos, err := c.Store.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, "github.com/owner/repo", repos[0].ID)
+
+	req, _ := http.NewRequest("GET", "/api/repos", nil)
+	w = httptest.NewRecorder()
+	c.ListRepos(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	var listed []server.OnboardedRepoData
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	Equals(t, 1, len(listed))
+	Equals(t, "github.com/owner/repo", listed[0].Repo)
+	Equals(t, (*bool)(nil), listed[0].WebhookCreated)
+
+	// Offboard.
+	req, _ = http.NewRequest("DELETE", "/api/repos/github.com/owner/repo", nil)
+	req = mux.SetURLVars(req, map[string]string{"repo": "github.com/owner/repo"})
+	w = httptest.NewRecorder()
+	c.OffboardRepo(w, req)
+	responseContains(t, w, http.StatusOK, "Offboarded repo github.com/owner/repo")
+	repos, err = c.Store.ListOnboardedRepos()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+
+	w = httptest.NewRecorder()
+	c.OffboardRepo(w, req)
+	responseContains(t, w, http.StatusNotFound, "Repo github.com/owner/repo isn't onboarded")
+}
+
+func TestReposController_OnboardErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		body        string
+		permErr     error
+		webhookErr  error
+		expCode     int
+		expBody     string
+	}{
+		{
+			description: "invalid body",
+			body:        `{`,
+			expCode:     http.StatusBadRequest,
+			expBody:     "Invalid request body",
+		},
+		{
+			description: "missing owner",
+			body:        `{"repo": "github.com/repo"}`,
+			expCode:     http.StatusBadRequest,
+			expBody:     `Invalid repo: "github.com/repo" must be in the format {hostname}/{owner}/{repo}`,
+		},
+		{
+			description: "scheme",
+			body:        `{"repo": "https://github.com/owner/repo"}`,
+			expCode:     http.StatusBadRequest,
+			expBody:     "must not contain a scheme",
+		},
+		{
+			description: "unsupported host",
+			body:        `{"repo": "bitbucket.org/owner/repo"}`,
+			expCode:     http.StatusBadRequest,
+			expBody:     `onboarding isn't supported for "bitbucket.org"`,
+		},
+		{
+			description: "no permissions",
+			body:        `{"repo": "github.com/owner/repo"}`,
+			permErr:     errors.New("needs admin access"),
+			expCode:     http.StatusForbidden,
+			expBody:     "Can't onboard github.com/owner/repo: needs admin access",
+		},
+		{
+			description: "webhook error",
+			body:        `{"repo": "github.com/owner/repo"}`,
+			webhookErr:  errors.New("rate limited"),
+			expCode:     http.StatusBadGateway,
+			expBody:     "Failed creating webhook for github.com/owner/repo: rate limited",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			rc, onboarder, cleanup := newReposController(t)
+			defer cleanup()
+			When(onboarder.CheckWebhookPermissions("owner/repo")).ThenReturn(c.permErr)
+			When(onboarder.EnsureWebhook("owner/repo", "https://atlantis.example.com/events", "secret")).
+				ThenReturn("", false, c.webhookErr)
+
+			w := onboard(rc, c.body)
+			responseContains(t, w, c.expCode, c.expBody)
+
+			// Nothing should be whitelisted.
+			repos, err := rc.Store.ListOnboardedRepos()
+			Ok(t, err)
+			Equals(t, 0, len(repos))
+		})
+	}
+}
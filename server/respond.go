@@ -0,0 +1,17 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// respond logs the message made from format and args at lvl and writes it
+// as a plain text response with responseCode.
+func respond(w http.ResponseWriter, logger logging.SimpleLogging, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	logger.Log(lvl, response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
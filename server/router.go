@@ -18,6 +18,9 @@ type Router struct {
 	// LockViewRouteIDQueryParam is the query parameter needed to construct the
 	// lock view: underlying.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id").
 	LockViewRouteIDQueryParam string
+	// PlanOutputViewRouteName is the named route for the full output of a
+	// truncated plan. It takes the same ID query parameter as the lock view.
+	PlanOutputViewRouteName string
 	// AtlantisURL is the fully qualified URL that Atlantis is
 	// accessible from externally.
 	AtlantisURL *url.URL
@@ -33,3 +36,10 @@ func (r *Router) GenerateLockURL(lockID string) string {
 	// golang likes to double escape the lockURL path when using url.Parse().
 	return r.AtlantisURL.String() + lockURL.String()
 }
+
+// GeneratePlanOutputURL returns a fully qualified URL to view the full output
+// of the plan with id.
+func (r *Router) GeneratePlanOutputURL(id string) string {
+	outputURL, _ := r.Underlying.Get(r.PlanOutputViewRouteName).URL(r.LockViewRouteIDQueryParam, url.QueryEscape(id))
+	return r.AtlantisURL.String() + outputURL.String()
+}
@@ -60,3 +60,18 @@ func TestRouter_GenerateLockURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRouter_GeneratePlanOutputURL(t *testing.T) {
+	underlyingRouter := mux.NewRouter()
+	underlyingRouter.HandleFunc("/plan-output", func(_ http.ResponseWriter, _ *http.Request) {}).Methods("GET").Queries("id", "{id}").Name("plan-output")
+	atlantisURL, err := server.ParseAtlantisURL("https://example.com/basepath")
+	Ok(t, err)
+
+	router := &server.Router{
+		AtlantisURL:               atlantisURL,
+		LockViewRouteIDQueryParam: "id",
+		PlanOutputViewRouteName:   "plan-output",
+		Underlying:                underlyingRouter,
+	}
+	Equals(t, "https://example.com/basepath/plan-output?id=github.com%253A%253Aowner%252Frepo%253A%253A1%253A%253Adefault%253A%253A.", router.GeneratePlanOutputURL("github.com::owner/repo::1::default::."))
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// CredentialChecker checks the credentials of a VCS client. It's implemented
+// by the VCS clients whose hosts report whether a token is valid.
+type CredentialChecker interface {
+	// CheckCredentials returns an error describing what's wrong if the
+	// client's token is invalid or is missing permissions Atlantis needs.
+	CheckCredentials() error
+}
+
+// The statuses of a self-check.
+const (
+	SelfCheckOK      = "ok"
+	SelfCheckWarning = "warning"
+	SelfCheckFailed  = "failed"
+	SelfCheckSkipped = "skipped"
+)
+
+// SelfCheck is the result of one check of the server's configuration.
+type SelfCheck struct {
+	// Name is what was checked, ex. github/credentials.
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// SelfCheckResult is the response of the /api/selfcheck route.
+type SelfCheckResult struct {
+	// Status is failed if any check failed, warning if any check warned and
+	// ok otherwise.
+	Status string      `json:"status"`
+	Checks []SelfCheck `json:"checks"`
+}
+
+// VCSSelfCheck is what's checked for a VCS host.
+type VCSSelfCheck struct {
+	Host models.VCSHostType
+	// Credentials checks the host's token. If nil, the token isn't checked.
+	Credentials CredentialChecker
+	// WebhookSecretSet is true if webhooks from the host are validated.
+	WebhookSecretSet bool
+	// WebhookSecretUnsupported is true if the host doesn't sign webhooks,
+	// ex. Bitbucket Cloud.
+	WebhookSecretUnsupported bool
+}
+
+// SelfChecker checks the server's configuration so misconfigurations like
+// a token without the right scopes are found at startup instead of during
+// the first plan.
+type SelfChecker struct {
+	VCSHosts []VCSSelfCheck
+	// AtlantisURL is requested to check that it's reachable if
+	// CheckAtlantisURL is true.
+	AtlantisURL      string
+	CheckAtlantisURL bool
+	HTTPClient       *http.Client
+	// TerraformClient runs terraform version to check the default version
+	// runs. If nil, it isn't checked.
+	TerraformClient terraform.Client
+	// DataDir is the directory terraform version is run in.
+	DataDir string
+	Logger  *logging.SimpleLogger
+}
+
+// Run runs all the checks.
+func (s *SelfChecker) Run() SelfCheckResult {
+	var checks []SelfCheck
+	for _, host := range s.VCSHosts {
+		checks = append(checks, s.checkCredentials(host), s.checkWebhookSecret(host))
+	}
+	checks = append(checks, s.checkAtlantisURL(), s.checkTerraform())
+
+	result := SelfCheckResult{Status: SelfCheckOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status == SelfCheckFailed {
+			result.Status = SelfCheckFailed
+			break
+		}
+		if check.Status == SelfCheckWarning {
+			result.Status = SelfCheckWarning
+		}
+	}
+	return result
+}
+
+// RunAndLog runs all the checks and logs the checks that didn't pass.
+func (s *SelfChecker) RunAndLog() {
+	result := s.Run()
+	for _, check := range result.Checks {
+		switch check.Status {
+		case SelfCheckFailed:
+			s.Logger.Err("self-check %s failed: %s", check.Name, check.Message)
+		case SelfCheckWarning:
+			s.Logger.Warn("self-check %s: %s", check.Name, check.Message)
+		}
+	}
+	s.Logger.Info("self-check finished with status %s", result.Status)
+}
+
+func (s *SelfChecker) checkCredentials(host VCSSelfCheck) SelfCheck {
+	check := SelfCheck{Name: fmt.Sprintf("%s/credentials", vcsCheckName(host.Host)), Status: SelfCheckOK}
+	if host.Credentials == nil {
+		check.Status = SelfCheckSkipped
+		check.Message = fmt.Sprintf("%s tokens can't be checked", host.Host.String())
+		return check
+	}
+	if err := host.Credentials.CheckCredentials(); err != nil {
+		check.Status = SelfCheckFailed
+		check.Message = err.Error()
+	}
+	return check
+}
+
+func (s *SelfChecker) checkWebhookSecret(host VCSSelfCheck) SelfCheck {
+	check := SelfCheck{Name: fmt.Sprintf("%s/webhook-secret", vcsCheckName(host.Host)), Status: SelfCheckOK}
+	switch {
+	case host.WebhookSecretUnsupported:
+		check.Status = SelfCheckSkipped
+		check.Message = fmt.Sprintf("%s doesn't support webhook secrets", host.Host.String())
+	case !host.WebhookSecretSet:
+		check.Status = SelfCheckWarning
+		check.Message = "no webhook secret is set so webhooks can't be validated and could be spoofed"
+	}
+	return check
+}
+
+func (s *SelfChecker) checkAtlantisURL() SelfCheck {
+	check := SelfCheck{Name: "atlantis-url", Status: SelfCheckOK}
+	if !s.CheckAtlantisURL {
+		check.Status = SelfCheckSkipped
+		check.Message = "not enabled"
+		return check
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	healthURL := strings.TrimRight(s.AtlantisURL, "/") + "/healthz"
+	resp, err := client.Get(healthURL)
+	if err != nil {
+		check.Status = SelfCheckFailed
+		check.Message = fmt.Sprintf("requesting %s: %s", healthURL, err)
+		return check
+	}
+	resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		check.Status = SelfCheckFailed
+		check.Message = fmt.Sprintf("requesting %s: unexpected status code %d", healthURL, resp.StatusCode)
+	}
+	return check
+}
+
+func (s *SelfChecker) checkTerraform() SelfCheck {
+	check := SelfCheck{Name: "terraform", Status: SelfCheckOK}
+	if s.TerraformClient == nil {
+		check.Status = SelfCheckSkipped
+		check.Message = "terraform isn't configured"
+		return check
+	}
+	out, err := s.TerraformClient.RunCommandWithVersion(s.Logger, s.DataDir, []string{"version"}, nil, nil, events.DefaultWorkspace)
+	if err != nil {
+		check.Status = SelfCheckFailed
+		check.Message = fmt.Sprintf("%s: %s", err, strings.TrimSpace(out))
+		return check
+	}
+	// Only keep the first line, ex. Terraform v0.12.24.
+	check.Message = strings.SplitN(strings.TrimSpace(out), "\n", 2)[0]
+	return check
+}
+
+// vcsCheckName returns host's name for use in check names, ex. github.
+func vcsCheckName(host models.VCSHostType) string {
+	return strings.ToLower(host.String())
+}
+
+// SelfCheckController runs the self-checks on demand.
+type SelfCheckController struct {
+	Checker *SelfChecker
+	Logger  *logging.SimpleLogger
+}
+
+// GetSelfCheck is the GET /api/selfcheck route. It runs the self-checks and
+// returns their results as JSON. It responds with a 503 if any check failed.
+func (c *SelfCheckController) GetSelfCheck(w http.ResponseWriter, _ *http.Request) {
+	result := c.Checker.Run()
+	w.Header().Set("Content-Type", "application/json")
+	if result.Status == SelfCheckFailed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		c.Logger.Err("writing response: %s", err)
+	}
+}
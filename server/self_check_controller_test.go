@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/models"
+	tfmocks "github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	tfmatchers "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type fakeCredentialChecker struct {
+	err error
+}
+
+func (f fakeCredentialChecker) CheckCredentials() error {
+	return f.err
+}
+
+func TestSelfChecker_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	atlantis := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/healthz", r.URL.Path)
+	}))
+	defer atlantis.Close()
+	tf := tfmocks.NewMockClient()
+	When(tf.RunCommandWithVersion(tfmatchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), tfmatchers.AnyMapOfStringToString(), tfmatchers.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("Terraform v0.12.24\nYour version of Terraform is out of date!\n", nil)
+
+	checker := &server.SelfChecker{
+		VCSHosts: []server.VCSSelfCheck{
+			{Host: models.Github, Credentials: fakeCredentialChecker{}, WebhookSecretSet: true},
+			{Host: models.BitbucketCloud, Credentials: fakeCredentialChecker{}, WebhookSecretUnsupported: true},
+			{Host: models.AzureDevops},
+		},
+		AtlantisURL:      atlantis.URL + "/",
+		CheckAtlantisURL: true,
+		TerraformClient:  tf,
+		DataDir:          "/data",
+		Logger:           logging.NewNoopLogger(),
+	}
+	Equals(t, server.SelfCheckResult{
+		Status: server.SelfCheckWarning,
+		Checks: []server.SelfCheck{
+			{Name: "github/credentials", Status: server.SelfCheckOK},
+			{Name: "github/webhook-secret", Status: server.SelfCheckOK},
+			{Name: "bitbucketcloud/credentials", Status: server.SelfCheckOK},
+			{Name: "bitbucketcloud/webhook-secret", Status: server.SelfCheckSkipped, Message: "BitbucketCloud doesn't support webhook secrets"},
+			{Name: "azuredevops/credentials", Status: server.SelfCheckSkipped, Message: "AzureDevops tokens can't be checked"},
+			{Name: "azuredevops/webhook-secret", Status: server.SelfCheckWarning, Message: "no webhook secret is set so webhooks can't be validated and could be spoofed"},
+			{Name: "atlantis-url", Status: server.SelfCheckOK},
+			{Name: "terraform", Status: server.SelfCheckOK, Message: "Terraform v0.12.24"},
+		},
+	}, checker.Run())
+	tf.VerifyWasCalledOnce().RunCommandWithVersion(checker.Logger, "/data", []string{"version"}, map[string]string(nil), nil, "default")
+}
+
+func TestSelfChecker_RunFailures(t *testing.T) {
+	RegisterMockTestingT(t)
+	atlantis := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer atlantis.Close()
+	tf := tfmocks.NewMockClient()
+	When(tf.RunCommandWithVersion(tfmatchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), tfmatchers.AnyMapOfStringToString(), tfmatchers.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("cannot execute binary file\n", errors.New("exit status 126"))
+
+	checker := &server.SelfChecker{
+		VCSHosts: []server.VCSSelfCheck{
+			{Host: models.Gitlab, Credentials: fakeCredentialChecker{errors.New("token needs the api scope, it has: read_api")}, WebhookSecretSet: true},
+		},
+		AtlantisURL:      atlantis.URL,
+		CheckAtlantisURL: true,
+		TerraformClient:  tf,
+		Logger:           logging.NewNoopLogger(),
+	}
+	Equals(t, server.SelfCheckResult{
+		Status: server.SelfCheckFailed,
+		Checks: []server.SelfCheck{
+			{Name: "gitlab/credentials", Status: server.SelfCheckFailed, Message: "token needs the api scope, it has: read_api"},
+			{Name: "gitlab/webhook-secret", Status: server.SelfCheckOK},
+			{Name: "atlantis-url", Status: server.SelfCheckFailed, Message: "requesting " + atlantis.URL + "/healthz: unexpected status code 502"},
+			{Name: "terraform", Status: server.SelfCheckFailed, Message: "exit status 126: cannot execute binary file"},
+		},
+	}, checker.Run())
+}
+
+func TestSelfCheckController_GetSelfCheck(t *testing.T) {
+	cases := []struct {
+		description string
+		credErr     error
+		expCode     int
+		expStatus   string
+	}{
+		{"all checks pass", nil, http.StatusOK, server.SelfCheckOK},
+		{"a check fails", errors.New("bad credentials"), http.StatusServiceUnavailable, server.SelfCheckFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			controller := &server.SelfCheckController{
+				Checker: &server.SelfChecker{
+					VCSHosts: []server.VCSSelfCheck{
+						{Host: models.Github, Credentials: fakeCredentialChecker{c.credErr}, WebhookSecretSet: true},
+					},
+					Logger: logging.NewNoopLogger(),
+				},
+				Logger: logging.NewNoopLogger(),
+			}
+			req, _ := http.NewRequest("GET", "/api/selfcheck", nil)
+			w := httptest.NewRecorder()
+			controller.GetSelfCheck(w, req)
+			Equals(t, c.expCode, w.Code)
+			Equals(t, "application/json", w.Header().Get("Content-Type"))
+			var result server.SelfCheckResult
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &result))
+			Equals(t, c.expStatus, result.Status)
+			Equals(t, 4, len(result.Checks))
+		})
+	}
+}
@@ -20,37 +20,55 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
-	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/mitchellh/go-homedir"
+	"github.com/runatlantis/atlantis/server/events/cloudcreds"
 	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/eventbus"
+	"github.com/runatlantis/atlantis/server/events/execution"
+	"github.com/runatlantis/atlantis/server/events/featureflags"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/datadir"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/archive"
+	"github.com/runatlantis/atlantis/server/events/artifacts"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/tfc"
+	"github.com/runatlantis/atlantis/server/events/vault"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/events/workers"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/httpclient"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 	"github.com/runatlantis/atlantis/server/static"
+	"github.com/runatlantis/atlantis/server/tracing"
 	"github.com/urfave/cli"
 	"github.com/urfave/negroni"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -62,6 +80,9 @@ const (
 	// route. ex:
 	//   mux.Router.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id")
 	LockViewRouteIDQueryParam = "id"
+	// PlanOutputViewRouteName is the named route in mux.Router for the view
+	// of the full output of a truncated plan.
+	PlanOutputViewRouteName = "plan-output"
 )
 
 // Server runs the Atlantis web server.
@@ -74,21 +95,109 @@ type Server struct {
 	Logger             *logging.SimpleLogger
 	Locker             locking.Locker
 	EventsController   *EventsController
+	Tracer             *tracing.Tracer
 	LocksController    *LocksController
+	GlobalCfgStore     *events.GlobalCfgStore
 	IndexTemplate      TemplateWriter
 	LockDetailTemplate TemplateWriter
 	SSLCertFile        string
 	SSLKeyFile         string
+	// EventsPort, if set, is the port the /events route is served on instead
+	// of Port. Only the /events and /healthz routes are served on it so it
+	// can be exposed to VCS providers while the UI and API stay internal.
+	EventsPort        int
+	EventsSSLCertFile string
+	EventsSSLKeyFile  string
+	// GRPCPort is the port GRPCServer is served on. GRPCServer is nil if
+	// the gRPC API isn't enabled.
+	GRPCPort   int
+	GRPCServer *grpc.Server
+	// WebAuthenticator authenticates requests to the web UI. If nil, the web
+	// UI doesn't require authentication.
+	WebAuthenticator WebAuthenticator
+	// APIAuthenticator enforces API token scopes on all routes except for
+	// the webhook and health routes.
+	APIAuthenticator    *APIAuthenticator
+	APITokensController *APITokensController
+	// AuditController is nil if the audit log isn't enabled.
+	AuditController       *AuditController
+	ReposController       *ReposController
+	PlanOutputsController *PlanOutputsController
+	// WorkersController is nil if remote workers aren't enabled.
+	WorkersController *WorkersController
+	// RepoConfigController is nil if there's no --repo-config file.
+	RepoConfigController *RepoConfigController
+	// ShadowConfigController is nil if there's no --repo-config-shadow file.
+	ShadowConfigController *ShadowConfigController
+	// SlackController is nil if Slack approvals aren't enabled.
+	SlackController *SlackController
+	// EffectiveConfigController shows the server-side repo config that
+	// applies to repos.
+	EffectiveConfigController *EffectiveConfigController
+	// ServerConfigController shows the settings the server was started with.
+	ServerConfigController *ServerConfigController
+	// PullsController shows the open pull requests and their projects.
+	PullsController *PullsController
+	// PlansController discards the plans of pull requests.
+	PlansController *PlansController
+	// CommandsController cancels the commands running on pull requests.
+	CommandsController *CommandsController
+	// FailedEventsController is nil if failed webhooks aren't stored.
+	FailedEventsController *FailedEventsController
+	// SelfChecker checks the server's VCS credentials, webhook secrets and
+	// terraform binary at startup and on demand.
+	SelfChecker         *SelfChecker
+	SelfCheckController *SelfCheckController
+	// FeatureFlags enables new behaviors for some repos before they're
+	// enabled for all of them.
+	FeatureFlags           *featureflags.Store
+	FeatureFlagsController *FeatureFlagsController
+	// LogLevelController changes the server's log levels without a restart.
+	LogLevelController *LogLevelController
+	// MetricsRegistry holds the metrics served at /metrics.
+	MetricsRegistry *metrics.Registry
+	// LoadShedder reports whether the server is degraded at /status. It's
+	// nil if load shedding isn't enabled.
+	LoadShedder *events.LoadShedder
+	// LockingHealth reports whether the locking backend can be reached at
+	// /healthz.
+	LockingHealth *locking.HealthChecker
+	// RunningCommands lists the commands in progress at /status.
+	RunningCommands *events.RunningCommands
+	// EventQueue, if set, is the queue whose depth is shown at /status.
+	EventQueue *events.EventQueue
+	// DataDir is checked to be writable by /readyz.
+	DataDir string
+	// DrainTimeout is how long the server waits for running commands to
+	// finish after it's told to stop.
+	DrainTimeout time.Duration
+
+	// draining is 1 once the server has been told to stop.
+	draining int32
 }
 
 // Config holds config for server that isn't passed in by the user.
 type Config struct {
 	AllowForkPRsFlag        string
+	APITokensFlag           string
+	AutoRegisterReposFlag   string
 	AtlantisURLFlag         string
 	AtlantisVersion         string
 	DefaultTFVersionFlag    string
+	EventsURLFlag           string
+	RedactPatternsFlag      string
 	RepoConfigJSONFlag      string
 	SilenceForkPRErrorsFlag string
+	WebhookSecretsFlag      string
+	// ConfigFile is the path of the --config file, if any.
+	ConfigFile string
+	// Settings are the server's settings with their secrets redacted. They're
+	// served at /api/server/config.
+	Settings []Setting
+	// Store, if set, stores locks and pull statuses instead of the
+	// --db-backend so programs that run the server can use their own
+	// storage.
+	Store db.Store
 }
 
 // WebhookConfig is nested within UserConfig. It's used to configure webhooks.
@@ -104,6 +213,9 @@ type WebhookConfig struct {
 	// Channel is the channel to send this webhook to. It only applies to
 	// slack webhooks. Should be without '#'.
 	Channel string `mapstructure:"channel"`
+	// Name, if set, means this webhook is only sent for projects that list
+	// it in the notify key of their atlantis.yaml.
+	Name string `mapstructure:"name"`
 }
 
 // NewServer returns a new server. If there are issues starting the server or
@@ -111,6 +223,45 @@ type WebhookConfig struct {
 // for the server CLI command because it injects all the dependencies.
 func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	logger := logging.NewSimpleLogger("server", false, userConfig.ToLogLevel())
+	logger.Levels = logging.NewLevels(userConfig.ToLogLevel())
+	// All outbound HTTP calls use clients from here so they go through the
+	// same proxy and trust the same CAs.
+	httpClients, err := httpclient.NewFactory(httpclient.Config{
+		CABundleFile:   userConfig.OutboundCABundleFile,
+		ClientCertFile: userConfig.OutboundClientCertFile,
+		ClientKeyFile:  userConfig.OutboundClientKeyFile,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing outbound HTTP clients")
+	}
+	redactPatterns, err := events.ParseRedactPatterns(userConfig.RedactPatterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing --%s", config.RedactPatternsFlag)
+	}
+	var redactEnvVars []string
+	for _, name := range strings.Split(userConfig.RedactEnvVars, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			redactEnvVars = append(redactEnvVars, name)
+		}
+	}
+	// Atlantis's own credentials are always redacted.
+	redactor := events.NewRedactor(redactEnvVars, []string{
+		userConfig.GithubToken,
+		userConfig.GithubWebhookSecret,
+		userConfig.GitlabToken,
+		userConfig.GitlabWebhookSecret,
+		userConfig.BitbucketToken,
+		userConfig.BitbucketWebhookSecret,
+		userConfig.AzureDevopsToken,
+		userConfig.AzureDevopsWebhookPassword,
+		userConfig.TFEToken,
+		userConfig.VaultToken,
+		userConfig.SlackToken,
+		userConfig.SMTPPassword,
+		userConfig.WebPassword,
+		userConfig.WebOIDCClientSecret,
+	}, redactPatterns)
+	logger.Redactor = redactor
 	var supportedVCSHosts []models.VCSHostType
 	var githubClient *vcs.GithubClient
 	var gitlabClient *vcs.GitlabClient
@@ -120,7 +271,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if userConfig.GithubUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.Github)
 		var err error
-		githubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, userConfig.GithubUser, userConfig.GithubToken)
+		githubClient, err = vcs.NewGithubClient(httpClients.Client(0), userConfig.GithubHostname, userConfig.GithubUser, userConfig.GithubToken)
 		if err != nil {
 			return nil, err
 		}
@@ -128,16 +279,18 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if userConfig.GitlabUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.Gitlab)
 		var err error
-		gitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabToken, logger)
+		gitlabClient, err = vcs.NewGitlabClient(httpClients.Client(0), userConfig.GitlabHostname, userConfig.GitlabToken, logger.ForSubsystem(logging.VCSSubsystem))
 		if err != nil {
 			return nil, err
 		}
+		gitlabClient.User = userConfig.GitlabUser
+		gitlabClient.PlanDiscussions = userConfig.GitlabDiscussions
 	}
 	if userConfig.BitbucketUser != "" {
 		if userConfig.BitbucketBaseURL == bitbucketcloud.BaseURL {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketCloud)
 			bitbucketCloudClient = bitbucketcloud.NewClient(
-				http.DefaultClient,
+				httpClients.Client(0),
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.AtlantisURL)
@@ -145,7 +298,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketServer)
 			var err error
 			bitbucketServerClient, err = bitbucketserver.NewClient(
-				http.DefaultClient,
+				httpClients.Client(0),
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.BitbucketBaseURL,
@@ -158,7 +311,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if userConfig.AzureDevopsUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.AzureDevops)
 		var err error
-		azuredevopsClient, err = vcs.NewAzureDevopsClient("dev.azure.com", userConfig.AzureDevopsToken)
+		azuredevopsClient, err = vcs.NewAzureDevopsClient(httpClients.Client(0), userConfig.AzureDevopsHostname, userConfig.AzureDevopsToken)
 		if err != nil {
 			return nil, err
 		}
@@ -191,7 +344,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			}
 		}
 		if userConfig.AzureDevopsUser != "" {
-			if err := events.WriteGitCreds(userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, "https://dev.azure.com/", home, logger); err != nil {
+			if err := events.WriteGitCreds(userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, vcsHostname(userConfig.AzureDevopsHostname), home, logger); err != nil {
 				return nil, err
 			}
 		}
@@ -203,31 +356,83 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			Channel:        c.Channel,
 			Event:          c.Event,
 			Kind:           c.Kind,
+			Name:           c.Name,
 			WorkspaceRegex: c.WorkspaceRegex,
 		}
 		webhooksConfig = append(webhooksConfig, config)
 	}
-	webhooksManager, err := webhooks.NewMultiWebhookSender(webhooksConfig, webhooks.NewSlackClient(userConfig.SlackToken))
+	webhooksManager, err := webhooks.NewMultiWebhookSender(webhooksConfig, webhooks.NewSlackClient(userConfig.SlackToken, httpClients.Client(0)))
 	if err != nil {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
+	if userConfig.SMTPAddr != "" {
+		webhooksManager.EmailClient = webhooks.NewSMTPEmailClient(userConfig.SMTPAddr, userConfig.SMTPFrom, userConfig.SMTPUsername, userConfig.SMTPPassword)
+	}
+	var tracer *tracing.Tracer
+	if userConfig.TracingOTLPEndpoint != "" {
+		tracer = tracing.NewTracer(tracing.NewOTLPExporter(userConfig.TracingOTLPEndpoint, userConfig.TracingServiceName), tracing.DefaultFlushInterval, logger)
+	}
 	vcsClient := vcs.NewClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient, azuredevopsClient)
-	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient, StatusName: userConfig.VCSStatusName}
+	vcsClient.Tracer = tracer
+	commitStatusUpdater := &events.DefaultCommitStatusUpdater{
+		Client:       vcsClient,
+		StatusName:   userConfig.VCSStatusName,
+		SkipCombined: userConfig.VCSStatusMode == events.ProjectCommitStatusMode,
+	}
+	reposDir := events.ReposDir(userConfig.DataDir, userConfig.ReposDir)
+	// Migrate the data dir before anything reads from it.
+	if err := datadir.NewMigrator(userConfig.DataDir, logger).Run(); err != nil {
+		return nil, errors.Wrap(err, "migrating data dir")
+	}
 	terraformClient, err := terraform.NewClient(
-		logger,
+		logger.ForSubsystem(logging.TerraformSubsystem),
 		userConfig.DataDir,
 		userConfig.TFEToken,
 		userConfig.TFEHostname,
 		userConfig.DefaultTFVersion,
 		config.DefaultTFVersionFlag,
-		userConfig.TFDownloadURL,
-		&terraform.DefaultDownloader{})
+		terraform.DownloadConfig{
+			URL:          userConfig.TFDownloadURL,
+			Distribution: userConfig.TFDistribution,
+			Checksum:     userConfig.TFDownloadChecksum,
+			LocalBinDir:  userConfig.TFLocalBinDir,
+		},
+		&terraform.DefaultDownloader{HTTPClient: httpClients.Client(0)})
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
 	// installed on our CI system where the unit tests run.
 	if err != nil && flag.Lookup("test.v") == nil {
 		return nil, errors.Wrap(err, "initializing terraform")
 	}
+	if terraformClient != nil && userConfig.TFPluginCacheVersions > 0 {
+		terraformClient.PluginCache.KeepVersions = userConfig.TFPluginCacheVersions
+		terraformClient.PluginCache.LinkDir = reposDir
+		go terraformClient.PluginCache.CleanEvery(logger.ForSubsystem(logging.TerraformSubsystem), terraform.PluginCacheCleanInterval)
+	}
+	var executionBackend execution.Backend = &execution.LocalBackend{}
+	if userConfig.ExecutionBackend == execution.KubernetesJobBackendName {
+		k8sBackend, err := execution.NewInClusterKubernetesJobBackend(userConfig.K8sJobNamespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kubernetes job execution backend")
+		}
+		k8sBackend.Image = userConfig.K8sJobImage
+		k8sBackend.ServiceAccount = userConfig.K8sJobServiceAccount
+		k8sBackend.CPU = userConfig.K8sJobCPU
+		k8sBackend.Memory = userConfig.K8sJobMemory
+		k8sBackend.DataDir = userConfig.DataDir
+		k8sBackend.DataVolumeClaim = userConfig.K8sJobDataVolumeClaim
+		executionBackend = k8sBackend
+	} else {
+		// Steps of workflows that set an image run in Docker containers.
+		executionBackend = &execution.DockerBackend{
+			Backend:       executionBackend,
+			DataDir:       userConfig.DataDir,
+			TerraformPath: execution.LocalTerraformPath(),
+		}
+	}
+	if terraformClient != nil {
+		terraformClient.ExecutionBackend = executionBackend
+	}
 	markdownRenderer := &events.MarkdownRenderer{
 		GitlabSupportsCommonMark: gitlabClient.SupportsCommonMark(),
 		DisableApplyAll:          userConfig.DisableApplyAll,
@@ -238,12 +443,123 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	lockingClient := locking.NewClient(boltdb)
-	workingDirLocker := events.NewDefaultWorkingDirLocker()
-	workingDir := &events.FileWorkspace{
-		DataDir:       userConfig.DataDir,
-		CheckoutMerge: userConfig.CheckoutStrategy == "merge",
+	featureFlags, err := featureflags.NewStore(userConfig.FeatureFlagsFile, boltdb)
+	if err != nil {
+		return nil, err
+	}
+	// Locks and pull statuses are stored in BoltDB unless another backend or
+	// store is configured. API tokens and the audit log are always stored in BoltDB.
+	var lockingBackend locking.Backend = boltdb
+	var pullStatusStore db.PullStatusStore = boltdb
+	switch userConfig.DBBackend {
+	case db.PostgresBackend:
+		postgresDB, err := db.NewPostgres(userConfig.PostgresURL)
+		if err != nil {
+			return nil, err
+		}
+		lockingBackend = postgresDB
+		pullStatusStore = postgresDB
+	case db.SQLiteBackend:
+		sqliteDB, err := db.NewSQLite(userConfig.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		lockingBackend = sqliteDB
+		pullStatusStore = sqliteDB
+	}
+	if config.Store != nil {
+		lockingBackend = config.Store
+		pullStatusStore = config.Store
+	}
+	var lockingClient locking.Locker = locking.NewClient(lockingBackend)
+	var eventPublisher *eventbus.Publisher
+	if userConfig.EventBusURL != "" {
+		broker, err := eventbus.NewBroker(userConfig.EventBusURL, userConfig.EventBusTopic)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing event bus")
+		}
+		eventPublisher = eventbus.NewPublisher(broker, logger)
+		go eventPublisher.Start()
+		lockingClient = &eventbus.Locker{Locker: lockingClient, Publisher: eventPublisher}
+	}
+	lockingHealth := locking.NewHealthChecker(lockingBackend)
+	go lockingHealth.Start(logger.ForSubsystem(logging.LockingSubsystem))
+	var lockingOutageWait time.Duration
+	if userConfig.LockingOutageMode == events.QueueLockingOutageMode {
+		lockingOutageWait, err = time.ParseDuration(userConfig.LockingOutageWait)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing locking outage wait %q", userConfig.LockingOutageWait)
+		}
 	}
+
+	var statusProgressInterval time.Duration
+	if userConfig.StatusProgressInterval != "" {
+		statusProgressInterval, err = time.ParseDuration(userConfig.StatusProgressInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing status progress interval %q", userConfig.StatusProgressInterval)
+		}
+	}
+
+	stalePlanAge := DefaultStalePlanAge
+	if userConfig.StalePlanAge != "" {
+		stalePlanAge, err = time.ParseDuration(userConfig.StalePlanAge)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing stale plan age %q", userConfig.StalePlanAge)
+		}
+	}
+	var defaultTimeouts valid.Timeouts
+	for _, t := range []struct {
+		value   string
+		timeout *time.Duration
+	}{
+		{userConfig.InitTimeout, &defaultTimeouts.Init},
+		{userConfig.PlanTimeout, &defaultTimeouts.Plan},
+		{userConfig.ApplyTimeout, &defaultTimeouts.Apply},
+		{userConfig.RunTimeout, &defaultTimeouts.Run},
+	} {
+		if t.value == "" {
+			continue
+		}
+		if *t.timeout, err = time.ParseDuration(t.value); err != nil {
+			return nil, errors.Wrapf(err, "parsing step timeout %q", t.value)
+		}
+	}
+	metricsRegistry := metrics.NewRegistry()
+	if userConfig.StatsdHost != "" {
+		statsdSink, err := metrics.NewStatsdSink(net.JoinHostPort(userConfig.StatsdHost, strconv.Itoa(userConfig.StatsdPort)), userConfig.StatsdPrefix)
+		if err != nil {
+			return nil, err
+		}
+		metricsRegistry.SetSink(statsdSink)
+	}
+	metricsRegistry.Register(&LocksCollector{Locks: lockingBackend})
+	metricsRegistry.Register(&LockingHealthCollector{Health: lockingHealth})
+	metricsRegistry.Register(&WorkingDirCollector{DataDir: userConfig.DataDir, ReposDir: userConfig.ReposDir, StalePlanAge: stalePlanAge})
+	failureTracker := &events.FailureTracker{
+		Failures: metricsRegistry.NewCounter("atlantis_command_failures", "Number of errors of commands by repo, command and class: config, terraform, provider_auth or infra.", "repo", "command", "class"),
+	}
+	if userConfig.FailureReportChannel != "" {
+		reportInterval, err := time.ParseDuration(userConfig.FailureReportInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing failure report interval %q", userConfig.FailureReportInterval)
+		}
+		failureTracker.SlackClient = webhooksManager.SlackClient
+		failureTracker.SlackChannel = userConfig.FailureReportChannel
+		go failureTracker.ReportEvery(logger, reportInterval)
+	}
+	var commentRateLimiter *events.CommentRateLimiter
+	if userConfig.CommentRateLimit > 0 {
+		rateLimitWindow, err := time.ParseDuration(userConfig.CommentRateLimitWindow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing comment rate limit window %q", userConfig.CommentRateLimitWindow)
+		}
+		commentRateLimiter = &events.CommentRateLimiter{
+			MaxCommands: userConfig.CommentRateLimit,
+			Window:      rateLimitWindow,
+			Limited:     metricsRegistry.NewCounter("atlantis_comment_commands_rate_limited", "Number of comment commands that were ignored because the user exceeded --comment-rate-limit.", "repo"),
+		}
+	}
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
 	projectLocker := &events.DefaultProjectLocker{
 		Locker:    lockingClient,
 		VCSClient: vcsClient,
@@ -255,7 +571,10 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	}
 	validator := &yaml.ParserValidator{}
 
-	globalCfg := valid.NewGlobalCfg(userConfig.AllowRepoConfig, userConfig.RequireMergeable, userConfig.RequireApproval)
+	defaultGlobalCfg := func() valid.GlobalCfg {
+		return valid.NewGlobalCfg(userConfig.AllowRepoConfig, userConfig.RequireMergeable, userConfig.RequireApproval)
+	}
+	globalCfg := defaultGlobalCfg()
 	if userConfig.RepoConfig != "" {
 		globalCfg, err = validator.ParseGlobalCfg(userConfig.RepoConfig, globalCfg)
 		if err != nil {
@@ -267,20 +586,90 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			return nil, errors.Wrapf(err, "parsing --%s", config.RepoConfigJSONFlag)
 		}
 	}
+	// The config is read from the store so the --repo-config file can be
+	// reloaded.
+	globalCfgStore := events.NewGlobalCfgStore(globalCfg)
+	sparseCheckoutPatterns := func(repoID string) []string {
+		return globalCfgStore.Get().SparseCheckoutPatterns(repoID)
+	}
+	cloneOptions := func(repoID string) valid.CloneOptions {
+		return globalCfgStore.Get().CloneOptions(repoID)
+	}
+	var repoConfigController *RepoConfigController
+	if userConfig.RepoConfig != "" {
+		repoConfigController = &RepoConfigController{
+			Path:            userConfig.RepoConfig,
+			Defaults:        defaultGlobalCfg,
+			ParserValidator: validator,
+			Store:           globalCfgStore,
+			Logger:          logger,
+		}
+		if userConfig.RepoConfigWatch {
+			go repoConfigController.Watch(RepoConfigWatchInterval)
+		}
+	}
+	workingDir := &events.FileWorkspace{
+		DataDir:                userConfig.DataDir,
+		ReposDir:               userConfig.ReposDir,
+		CheckoutMerge:          userConfig.CheckoutStrategy == "merge",
+		IncrementalFetch:       userConfig.IncrementalFetch,
+		KeepPlans:              userConfig.InvalidateOnPush == events.InvalidateModifiedOnPush,
+		CheckoutDepth:          userConfig.CheckoutDepth,
+		SparseCheckoutPatterns: sparseCheckoutPatterns,
+		CloneOptions:           cloneOptions,
+	}
+	if userConfig.CloneCache {
+		fetchInterval, err := time.ParseDuration(userConfig.CloneCacheFetchInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing clone cache fetch interval %q", userConfig.CloneCacheFetchInterval)
+		}
+		maxAge, err := time.ParseDuration(userConfig.CloneCacheMaxAge)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing clone cache max age %q", userConfig.CloneCacheMaxAge)
+		}
+		workingDir.CloneCache = &events.CloneCache{
+			DataDir:       userConfig.DataDir,
+			ReposDir:      userConfig.ReposDir,
+			FetchInterval: fetchInterval,
+			MaxAge:        maxAge,
+			Requests:      metricsRegistry.NewCounter("atlantis_clone_cache_requests", "Number of times a repo's mirror was used by pull request clones by result: hit, fetch, create or error.", "repo", "result"),
+		}
+	}
 
 	underlyingRouter := mux.NewRouter()
 	router := &Router{
 		AtlantisURL:               parsedURL,
 		LockViewRouteIDQueryParam: LockViewRouteIDQueryParam,
 		LockViewRouteName:         LockViewRouteName,
+		PlanOutputViewRouteName:   PlanOutputViewRouteName,
 		Underlying:                underlyingRouter,
 	}
+	var auditLogger events.AuditLogger
+	if userConfig.AuditLog {
+		defaultAuditLogger := &events.DefaultAuditLogger{
+			DB:     boltdb,
+			Logger: logger,
+		}
+		if userConfig.AuditSyslogAddr != "" {
+			syslogSink, err := events.NewSyslogAuditSink(userConfig.AuditSyslogAddr)
+			if err != nil {
+				return nil, errors.Wrap(err, "initializing audit syslog sink")
+			}
+			defaultAuditLogger.Sinks = append(defaultAuditLogger.Sinks, syslogSink)
+		}
+		if userConfig.AuditWebhookURL != "" {
+			defaultAuditLogger.Sinks = append(defaultAuditLogger.Sinks, &events.WebhookAuditSink{URL: userConfig.AuditWebhookURL, Client: httpClients.Client(10 * time.Second)})
+		}
+		auditLogger = defaultAuditLogger
+	}
 	pullClosedExecutor := &events.PullClosedExecutor{
-		VCSClient:  vcsClient,
-		Locker:     lockingClient,
-		WorkingDir: workingDir,
-		Logger:     logger,
-		DB:         boltdb,
+		VCSClient:       vcsClient,
+		Locker:          lockingClient,
+		WorkingDir:      workingDir,
+		Logger:          logger,
+		DB:              pullStatusStore,
+		AuditLogger:     auditLogger,
+		PlanOutputStore: boltdb,
 	}
 	eventParser := &events.EventParser{
 		GithubUser:         userConfig.GithubUser,
@@ -292,12 +681,24 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		BitbucketServerURL: userConfig.BitbucketBaseURL,
 		AzureDevopsUser:    userConfig.AzureDevopsUser,
 		AzureDevopsToken:   userConfig.AzureDevopsToken,
+		AllowDraftPRs:      userConfig.AllowDraftPRs,
+	}
+	commentTemplates := events.DefaultCommentTemplates()
+	if userConfig.CommentTemplatesDir != "" {
+		commentTemplates, err = events.LoadCommentTemplates(userConfig.CommentTemplatesDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading comment templates")
+		}
 	}
 	commentParser := &events.CommentParser{
-		GithubUser:      userConfig.GithubUser,
-		GitlabUser:      userConfig.GitlabUser,
-		BitbucketUser:   userConfig.BitbucketUser,
-		AzureDevopsUser: userConfig.AzureDevopsUser,
+		GithubUser:       userConfig.GithubUser,
+		GitlabUser:       userConfig.GitlabUser,
+		BitbucketUser:    userConfig.BitbucketUser,
+		AzureDevopsUser:  userConfig.AzureDevopsUser,
+		GlobalCfgStore:   globalCfgStore,
+		AllowedExtraArgs: splitExtraArgs(userConfig.AllowedExtraArgs),
+		DeniedExtraArgs:  splitExtraArgs(userConfig.DeniedExtraArgs),
+		Templates:        commentTemplates,
 	}
 	defaultTfVersion := terraformClient.DefaultVersion()
 	pendingPlanFinder := &events.DefaultPendingPlanFinder{}
@@ -305,12 +706,219 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		TerraformExecutor: terraformClient,
 		DefaultTFVersion:  defaultTfVersion,
 		TerraformBinDir:   terraformClient.TerraformBinDir(),
+		ExecutionBackend:  executionBackend,
+	}
+	// upload_artifact steps fail if no bucket is configured.
+	var artifactUploader runtime.ArtifactUploader
+	if userConfig.ArtifactBucket != "" {
+		artifactURLExpiry, err := time.ParseDuration(userConfig.ArtifactURLExpiry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing artifact URL expiry %q", userConfig.ArtifactURLExpiry)
+		}
+		artifactUploader, err = artifacts.NewS3Store(userConfig.ArtifactBucket, userConfig.ArtifactS3Endpoint, artifactURLExpiry)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing artifact storage")
+		}
+	}
+	// If not set, the full output of truncated plans is stored to view in
+	// the UI.
+	var planOutputUploader events.PlanOutputUploader
+	switch userConfig.PlanOutputDestination {
+	case events.PlanOutputDestinationS3:
+		if artifactUploader == nil {
+			return nil, errors.New("uploading plan output to S3 requires an artifact bucket")
+		}
+		planOutputUploader = &events.ArtifactPlanOutputUploader{Uploader: artifactUploader}
+	case events.PlanOutputDestinationGist:
+		if githubClient == nil {
+			return nil, errors.New("uploading plan output to gists requires GitHub to be configured")
+		}
+		planOutputUploader = &events.GistPlanOutputUploader{Client: githubClient}
+	}
+	providerRetries := globalCfg.ProviderRetries
+	if providerRetries == nil {
+		providerRetries = valid.DefaultProviderRetries
+	}
+	initStepRunner, planStepRunner, applyStepRunner := events.NewRetryStepRunners(
+		&runtime.InitStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		&runtime.PlanStepRunner{
+			TerraformExecutor:   terraformClient,
+			DefaultTFVersion:    defaultTfVersion,
+			CommitStatusUpdater: commitStatusUpdater,
+			AsyncTFExec:         terraformClient,
+		},
+		&runtime.ApplyStepRunner{
+			TerraformExecutor:   terraformClient,
+			DefaultTFVersion:    defaultTfVersion,
+			CommitStatusUpdater: commitStatusUpdater,
+			AsyncTFExec:         terraformClient,
+		},
+		providerRetries,
+	)
+	projectCommandBuilder := &events.DefaultProjectCommandBuilder{
+		ParserValidator:     validator,
+		ProjectFinder:       &events.DefaultProjectFinder{},
+		VCSClient:           vcsClient,
+		WorkingDir:          workingDir,
+		WorkingDirLocker:    workingDirLocker,
+		GlobalCfgStore:      globalCfgStore,
+		PendingPlanFinder:   pendingPlanFinder,
+		CommentBuilder:      commentParser,
+		AutoplanModules:     userConfig.AutoplanModules,
+		PrePlanChecks:       userConfig.PrePlanChecks,
+		ModifiedFilesSource: userConfig.ModifiedFilesSource,
+		WorkspaceLister: &runtime.WorkspaceLister{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+	}
+	var cmdBuilder events.ProjectCommandBuilder = projectCommandBuilder
+	var shadowConfigController *ShadowConfigController
+	if userConfig.RepoConfigShadow != "" {
+		shadowCfg, err := validator.ParseGlobalCfg(userConfig.RepoConfigShadow, defaultGlobalCfg())
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s file", userConfig.RepoConfigShadow)
+		}
+		shadowCfgStore := events.NewGlobalCfgStore(shadowCfg)
+		shadowBuilder := *projectCommandBuilder
+		shadowBuilder.GlobalCfgStore = shadowCfgStore
+		shadowDiffs := &events.ShadowDiffStore{}
+		cmdBuilder = &events.ShadowProjectCommandBuilder{
+			ProjectCommandBuilder: projectCommandBuilder,
+			Shadow:                &shadowBuilder,
+			Diffs:                 shadowDiffs,
+		}
+		shadowConfigController = &ShadowConfigController{
+			Path:   userConfig.RepoConfigShadow,
+			Diffs:  shadowDiffs,
+			Logger: logger,
+		}
+		shadowWatcher := &RepoConfigController{
+			Path:            userConfig.RepoConfigShadow,
+			Defaults:        defaultGlobalCfg,
+			ParserValidator: validator,
+			Store:           shadowCfgStore,
+			Logger:          logger,
+			Shadow:          true,
+		}
+		go shadowWatcher.Watch(RepoConfigWatchInterval)
+	}
+	var planSummarizer events.PlanSummarizer
+	if userConfig.StructuredPlanDiff {
+		planSummarizer = &runtime.PlanSummarizer{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		}
+	}
+	var loadShedder *events.LoadShedder
+	if userConfig.LoadShedMinFreeDiskMB > 0 || userConfig.LoadShedMinFreeMemoryMB > 0 || userConfig.LoadShedMaxQueueDepth > 0 {
+		loadShedder = &events.LoadShedder{
+			DataDir:            userConfig.DataDir,
+			MinFreeDiskBytes:   uint64(userConfig.LoadShedMinFreeDiskMB) * 1024 * 1024,
+			MinFreeMemoryBytes: uint64(userConfig.LoadShedMinFreeMemoryMB) * 1024 * 1024,
+			MaxQueueDepth:      userConfig.LoadShedMaxQueueDepth,
+		}
+	}
+	runningCommands := &events.RunningCommands{}
+	var drainTimeout time.Duration
+	if userConfig.DrainTimeout != "" {
+		drainTimeout, err = time.ParseDuration(userConfig.DrainTimeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing drain timeout %q", userConfig.DrainTimeout)
+		}
+	}
+	// The steps of projects are run on remote workers, which claim them from
+	// the queue via the API, if they're enabled.
+	var workerDispatcher events.WorkerDispatcher
+	var workersController *WorkersController
+	if userConfig.RemoteWorkers {
+		workerQueue := workers.NewQueue(workers.DefaultJobTimeout)
+		workerDispatcher = &workers.Dispatcher{
+			Queue:                  workerQueue,
+			CheckoutMerge:          userConfig.CheckoutStrategy == "merge",
+			CheckoutDepth:          userConfig.CheckoutDepth,
+			SparseCheckoutPatterns: sparseCheckoutPatterns,
+			CloneOptions:           cloneOptions,
+		}
+		workersController = &WorkersController{
+			Queue:     workerQueue,
+			ClaimWait: DefaultWorkerClaimWait,
+			Logger:    logger,
+		}
+	}
+	// env steps can only read secrets from Vault if it's configured.
+	var secretsProvider events.SecretsProvider
+	if userConfig.VaultAddr != "" {
+		secretsProvider = &vault.Client{
+			Address:    userConfig.VaultAddr,
+			Token:      userConfig.VaultToken,
+			HTTPClient: httpClients.Client(30 * time.Second),
+		}
+	}
+	// Only GitHub teams can be code owners that approvers are members of.
+	var teamMembershipChecker runtime.TeamMembershipChecker
+	if githubClient != nil {
+		teamMembershipChecker = githubClient
+	}
+	// Job logs are only kept if they can be streamed over the gRPC API.
+	var jobLogs *events.JobLogs
+	if userConfig.GRPCPort != 0 {
+		jobLogs = &events.JobLogs{}
+	}
+	var planEncrypter *events.PlanEncrypter
+	if userConfig.PlanEncryptionKey != "" {
+		planEncrypter, err = events.NewPlanEncrypter(userConfig.PlanEncryptionKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing plan encryption")
+		}
+	}
+	// The full output of truncated plans is saved to the database unless
+	// sensitive output can't be written to disk, and the plans' JSON that
+	// security scans write is kept in the repos dir.
+	var planOutputStore events.PlanOutputStore = boltdb
+	var securityScanTempDir string
+	if userConfig.NoSensitiveDiskWrites {
+		planOutputStore = nil
+		securityScanTempDir = reposDir
+		if err := os.MkdirAll(reposDir, 0700); err != nil {
+			return nil, errors.Wrap(err, "creating repos dir")
+		}
+	}
+	var commandAuthorizer *events.CommandAuthorizer
+	if userConfig.AuthorizerURL != "" {
+		commandAuthorizer = &events.CommandAuthorizer{
+			URL:    userConfig.AuthorizerURL,
+			Token:  userConfig.AuthorizerToken,
+			Client: httpClients.Client(10 * time.Second),
+		}
+	}
+	var autoplanDebouncer *events.AutoplanDebouncer
+	if userConfig.AutoplanDebounceWindow != "" {
+		debounceWindow, err := time.ParseDuration(userConfig.AutoplanDebounceWindow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing autoplan debounce window %q", userConfig.AutoplanDebounceWindow)
+		}
+		autoplanDebouncer = &events.AutoplanDebouncer{Window: debounceWindow}
+	}
+	pullHeadRepoGetters := make(map[models.VCSHostType]events.PullHeadRepoGetter)
+	if gitlabClient != nil {
+		pullHeadRepoGetters[models.Gitlab] = gitlabClient
+	}
+	if bitbucketCloudClient != nil {
+		pullHeadRepoGetters[models.BitbucketCloud] = bitbucketCloudClient
+	}
+	if bitbucketServerClient != nil {
+		pullHeadRepoGetters[models.BitbucketServer] = bitbucketServerClient
 	}
 	commandRunner := &events.DefaultCommandRunner{
 		VCSClient:                vcsClient,
 		GithubPullGetter:         githubClient,
 		GitlabMergeRequestGetter: gitlabClient,
 		AzureDevopsPullGetter:    azuredevopsClient,
+		PullHeadRepoGetters:      pullHeadRepoGetters,
 		CommitStatusUpdater:      commitStatusUpdater,
 		EventParser:              eventParser,
 		MarkdownRenderer:         markdownRenderer,
@@ -321,82 +929,478 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		SilenceForkPRErrors:      userConfig.SilenceForkPRErrors,
 		SilenceForkPRErrorsFlag:  config.SilenceForkPRErrorsFlag,
 		SilenceVCSStatusNoPlans:  userConfig.SilenceVCSStatusNoPlans,
+		SilenceNoChanges:         userConfig.SilenceNoChanges,
 		DisableApplyAll:          userConfig.DisableApplyAll,
-		ProjectCommandBuilder: &events.DefaultProjectCommandBuilder{
-			ParserValidator:   validator,
-			ProjectFinder:     &events.DefaultProjectFinder{},
-			VCSClient:         vcsClient,
-			WorkingDir:        workingDir,
-			WorkingDirLocker:  workingDirLocker,
-			GlobalCfg:         globalCfg,
-			PendingPlanFinder: pendingPlanFinder,
-			CommentBuilder:    commentParser,
-		},
+		ProjectCommandBuilder:    cmdBuilder,
 		ProjectCommandRunner: &events.DefaultProjectCommandRunner{
 			Locker:           projectLocker,
 			LockURLGenerator: router,
-			InitStepRunner: &runtime.InitStepRunner{
+			InitStepRunner:   initStepRunner,
+			PlanStepRunner:   planStepRunner,
+			ApplyStepRunner:  applyStepRunner,
+			RunStepRunner:    runStepRunner,
+			EnvStepRunner: &runtime.EnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
+			MultiEnvStepRunner: &runtime.MultiEnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
+			ArtifactStepRunner: &runtime.UploadArtifactStepRunner{
+				Uploader: artifactUploader,
+			},
+			DiagramStepRunner: &runtime.DiagramStepRunner{
 				TerraformExecutor: terraformClient,
 				DefaultTFVersion:  defaultTfVersion,
+				Uploader:          artifactUploader,
+				DotPath:           runtime.FindDot(),
 			},
-			PlanStepRunner: &runtime.PlanStepRunner{
-				TerraformExecutor:   terraformClient,
-				DefaultTFVersion:    defaultTfVersion,
-				CommitStatusUpdater: commitStatusUpdater,
-				AsyncTFExec:         terraformClient,
+			PullApprovedChecker:    vcsClient,
+			PullUndivergedChecker:  vcsClient,
+			TeamMembershipChecker:  teamMembershipChecker,
+			WorkingDir:             workingDir,
+			Webhooks:               webhooksManager,
+			WorkingDirLocker:       workingDirLocker,
+			ConcurrencyLimiter:     events.NewConcurrencyLimiter(globalCfg.ConcurrencyGroups),
+			JobLogs:                jobLogs,
+			PlanSummarizer:         planSummarizer,
+			Redactor:               redactor,
+			LockOnApply:            userConfig.LockingMode == events.LockOnApplyMode,
+			PlanOutputMaxBytes:     userConfig.PlanOutputMaxBytes,
+			PlanOutputStore:        planOutputStore,
+			PlanOutputURLGenerator: router,
+			PlanOutputUploader:     planOutputUploader,
+			TerraformCloudRunner: &tfc.Runner{
+				Client: tfc.NewClient(httpClients.Client(0), userConfig.TFEHostname, userConfig.TFEToken),
 			},
-			ApplyStepRunner: &runtime.ApplyStepRunner{
-				TerraformExecutor:   terraformClient,
-				CommitStatusUpdater: commitStatusUpdater,
-				AsyncTFExec:         terraformClient,
+			WorkerDispatcher:       workerDispatcher,
+			CloudCredentialAssumer: &cloudcreds.Assumer{},
+			SecretsProvider:        secretsProvider,
+			SecurityScanStepRunner: &runtime.SecurityScanStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+				TempDir:           securityScanTempDir,
 			},
-			RunStepRunner: runStepRunner,
-			EnvStepRunner: &runtime.EnvStepRunner{
-				RunStepRunner: runStepRunner,
+			FmtCheckStepRunner: &runtime.FmtCheckStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			ValidateStepRunner: &runtime.ValidateStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
 			},
-			PullApprovedChecker: vcsClient,
-			WorkingDir:          workingDir,
-			Webhooks:            webhooksManager,
-			WorkingDirLocker:    workingDirLocker,
+			PlanEncrypter:   planEncrypter,
+			Authorizer:      commandAuthorizer,
+			DefaultTimeouts: defaultTimeouts,
 		},
-		WorkingDir:        workingDir,
-		PendingPlanFinder: pendingPlanFinder,
-		DB:                boltdb,
-		GlobalAutomerge:   userConfig.Automerge,
+		WorkingDir:             workingDir,
+		PendingPlanFinder:      pendingPlanFinder,
+		DB:                     pullStatusStore,
+		GlobalAutomerge:        userConfig.Automerge,
+		AuditLogger:            auditLogger,
+		GlobalCfgStore:         globalCfgStore,
+		LoadShedder:            loadShedder,
+		RunningCommands:        runningCommands,
+		AutoplanDebouncer:      autoplanDebouncer,
+		LockingHealth:          lockingHealth,
+		LockingOutageWait:      lockingOutageWait,
+		SummaryComment:         userConfig.SummaryComment,
+		ProjectCommitStatuses:  userConfig.VCSStatusMode == events.ProjectCommitStatusMode || userConfig.VCSStatusMode == events.BothCommitStatusMode,
+		StatusProgressInterval: statusProgressInterval,
+		FailureTracker:         failureTracker,
+		ProjectCommands:        metricsRegistry.NewCounter("atlantis_project_commands", "Number of projects planned or applied by repo, tenant, command and result: success, failure or error.", "repo", "tenant", "command", "result"),
+		ProjectCommandDuration: metricsRegistry.NewTimer("atlantis_project_command_duration", "Time spent planning or applying projects by repo, tenant, command and result.", "repo", "tenant", "command", "result"),
+		CommentRateLimiter:     commentRateLimiter,
+		Tracer:                 tracer,
+		Locker:                 lockingClient,
+		PullStatusCheck:        userConfig.PullStatusCheck,
+		ReusePlans:             userConfig.ReusePlans,
+		EventPublisher:         eventPublisher,
+		QueuedApplies:          &events.QueuedApplies{},
+	}
+	if userConfig.ScanSecrets {
+		if userConfig.SecretScannerCommand != "" {
+			commandRunner.SecretScanner = &events.CommandSecretScanner{Command: userConfig.SecretScannerCommand}
+		} else {
+			commandRunner.SecretScanner = events.NewPatternSecretScanner()
+		}
+	}
+	if userConfig.RequestReviewers && githubClient != nil {
+		commandRunner.ReviewerRequester = githubClient
+	}
+	if userConfig.InvalidateOnPush == events.InvalidateModifiedOnPush {
+		commandRunner.PlanInvalidator = &events.PlanInvalidator{
+			WorkingDir:      workingDir,
+			ParserValidator: validator,
+			ProjectFinder:   &events.DefaultProjectFinder{},
+			GlobalCfgStore:  globalCfgStore,
+			Locker:          lockingClient,
+		}
+	}
+	if userConfig.BitbucketInsights && bitbucketServerClient != nil {
+		commandRunner.InsightsReporter = bitbucketServerClient
+	}
+	if userConfig.CommentReactions {
+		commandRunner.CommentReactors = make(map[models.VCSHostType]events.CommentReactor)
+		if githubClient != nil {
+			commandRunner.CommentReactors[models.Github] = githubClient
+		}
+		if gitlabClient != nil {
+			commandRunner.CommentReactors[models.Gitlab] = gitlabClient
+		}
+	}
+	if userConfig.PrewarmWorkingDir {
+		commandRunner.WorkingDirPrewarmer = &events.DefaultWorkingDirPrewarmer{
+			ProjectCommandBuilder: projectCommandBuilder,
+			WorkingDir:            workingDir,
+			WorkingDirLocker:      workingDirLocker,
+			InitStepRunner:        initStepRunner,
+		}
+	}
+	planDiscarder := &events.PlanDiscarder{
+		WorkingDir:          workingDir,
+		WorkingDirLocker:    workingDirLocker,
+		DB:                  pullStatusStore,
+		Locker:              lockingClient,
+		CommitStatusUpdater: commitStatusUpdater,
+		AuditLogger:         auditLogger,
+	}
+	commandRunner.PlanDiscarder = planDiscarder
+	if userConfig.ResultsExportDir != "" || userConfig.ResultsExportURL != "" {
+		commandRunner.ResultsExporter = &events.ResultsExporter{
+			Format:          userConfig.ResultsExportFormat,
+			Dir:             userConfig.ResultsExportDir,
+			URL:             userConfig.ResultsExportURL,
+			Client:          httpClients.Client(30 * time.Second),
+			AtlantisVersion: config.AtlantisVersion,
+		}
+	}
+	var slackController *SlackController
+	if userConfig.SlackApprovalsChannel != "" {
+		slackUsers, err := events.ParseSlackUsers(userConfig.SlackUsers)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing slack users")
+		}
+		slackApprovals := &events.SlackApprovals{
+			Client:  webhooksManager.SlackClient,
+			Channel: userConfig.SlackApprovalsChannel,
+			Users:   slackUsers,
+		}
+		commandRunner.SlackApprovals = slackApprovals
+		slackController = &SlackController{
+			SigningSecret: []byte(userConfig.SlackSigningSecret),
+			Approvals:     slackApprovals,
+			CommandRunner: commandRunner,
+			DB:            pullStatusStore,
+			Locker:        lockingClient,
+			Logger:        logger,
+			Now:           time.Now,
+		}
 	}
 	repoWhitelist, err := events.NewRepoWhitelistChecker(userConfig.RepoWhitelist)
 	if err != nil {
 		return nil, err
 	}
+	repoWhitelist.Tenants = globalCfgStore
+	if userConfig.RepoWhitelistOrgs != "" {
+		listers := make(map[string]events.OrgRepoLister)
+		if githubClient != nil {
+			listers[vcsHostname(userConfig.GithubHostname)] = githubClient
+		}
+		if gitlabClient != nil {
+			listers[vcsHostname(userConfig.GitlabHostname)] = gitlabClient
+		}
+		orgRepoSyncer, err := events.NewOrgRepoSyncer(userConfig.RepoWhitelistOrgs, userConfig.RepoWhitelistOrgsTopic, listers, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid repo whitelist orgs")
+		}
+		syncInterval, err := time.ParseDuration(userConfig.RepoWhitelistOrgsSyncInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing repo whitelist orgs sync interval %q", userConfig.RepoWhitelistOrgsSyncInterval)
+		}
+		repoWhitelist.OrgRepos = orgRepoSyncer
+		go orgRepoSyncer.SyncEvery(syncInterval)
+	}
+	if userConfig.StalePlanCheckSchedule != "" {
+		schedule, err := valid.ParseSchedule(userConfig.StalePlanCheckSchedule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing stale plan check schedule %q", userConfig.StalePlanCheckSchedule)
+		}
+		stalePlanChecker := &events.StalePlanChecker{
+			StalePlanAge:     stalePlanAge,
+			DB:               pullStatusStore,
+			WorkingDir:       workingDir,
+			WorkingDirLocker: workingDirLocker,
+			VCSClient:        vcsClient,
+			Logger:           logger,
+		}
+		go stalePlanChecker.CheckOn(schedule)
+	}
 	locksController := &LocksController{
-		AtlantisVersion:    config.AtlantisVersion,
-		AtlantisURL:        parsedURL,
-		Locker:             lockingClient,
-		Logger:             logger,
-		VCSClient:          vcsClient,
-		LockDetailTemplate: lockTemplate,
-		WorkingDir:         workingDir,
-		WorkingDirLocker:   workingDirLocker,
-		DB:                 boltdb,
+		AtlantisVersion:     config.AtlantisVersion,
+		AtlantisURL:         parsedURL,
+		Locker:              lockingClient,
+		Logger:              logger,
+		VCSClient:           vcsClient,
+		LockDetailTemplate:  lockTemplate,
+		WorkingDir:          workingDir,
+		WorkingDirLocker:    workingDirLocker,
+		DB:                  pullStatusStore,
+		AuditLogger:         auditLogger,
+		CommitStatusUpdater: commitStatusUpdater,
+		GlobalCfgStore:      globalCfgStore,
+	}
+	webhookSecrets, err := ParseWebhookSecrets(userConfig.WebhookSecrets)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing --%s", config.WebhookSecretsFlag)
 	}
+	// The secrets from the per-VCS flags are tried first.
+	webhookSecrets.Prepend(models.Github, userConfig.GithubWebhookSecret)
+	webhookSecrets.Prepend(models.Gitlab, userConfig.GitlabWebhookSecret)
+	webhookSecrets.Prepend(models.BitbucketServer, userConfig.BitbucketWebhookSecret)
 	eventsController := &EventsController{
 		CommandRunner:                   commandRunner,
 		PullCleaner:                     pullClosedExecutor,
+		AutoplanDebouncer:               autoplanDebouncer,
 		Parser:                          eventParser,
 		CommentParser:                   commentParser,
+		CommentTemplates:                commentTemplates,
 		Logger:                          logger,
-		GithubWebhookSecret:             []byte(userConfig.GithubWebhookSecret),
+		GithubWebhookSecrets:            webhookSecrets[models.Github],
 		GithubRequestValidator:          &DefaultGithubRequestValidator{},
 		GitlabRequestParserValidator:    &DefaultGitlabRequestParserValidator{},
-		GitlabWebhookSecret:             []byte(userConfig.GitlabWebhookSecret),
+		GitlabWebhookSecrets:            webhookSecrets[models.Gitlab],
 		RepoWhitelistChecker:            repoWhitelist,
 		SilenceWhitelistErrors:          userConfig.SilenceWhitelistErrors,
 		SupportedVCSHosts:               supportedVCSHosts,
 		VCSClient:                       vcsClient,
-		BitbucketWebhookSecret:          []byte(userConfig.BitbucketWebhookSecret),
+		BitbucketWebhookSecrets:         webhookSecrets[models.BitbucketServer],
 		AzureDevopsWebhookBasicUser:     []byte(userConfig.AzureDevopsWebhookUser),
 		AzureDevopsWebhookBasicPassword: []byte(userConfig.AzureDevopsWebhookPassword),
 		AzureDevopsRequestValidator:     &DefaultAzureDevopsRequestValidator{},
+		SignatureFailures:               metricsRegistry.NewCounter("atlantis_webhook_signature_failures", "Number of webhook requests that weren't signed by any of the webhook secrets.", "vcs"),
+		Tracer:                          tracer,
+	}
+	if userConfig.WebhookArchiveURL != "" {
+		store, prefix, err := archive.NewStore(userConfig.WebhookArchiveURL, userConfig.WebhookArchiveS3Endpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing webhook archive storage")
+		}
+		eventsController.PayloadArchiver = archive.NewArchiver(store, prefix, userConfig.WebhookArchiveRetentionDays, logger)
+		go eventsController.PayloadArchiver.Start()
+	}
+	if userConfig.EventsRateLimitPerIP > 0 || userConfig.EventsRateLimitPerRepo > 0 {
+		eventsController.RateLimiter = &events.EventRateLimiter{
+			PerIP:   userConfig.EventsRateLimitPerIP,
+			PerRepo: userConfig.EventsRateLimitPerRepo,
+			Limited: metricsRegistry.NewCounter("atlantis_events_rate_limited", "Number of webhooks that were rejected because their IP or repo exceeded its rate limit or the repo's circuit breaker was open.", "kind"),
+			Logger:  logger,
+		}
+		if userConfig.EventsBreakerCooldown != "" {
+			eventsController.RateLimiter.BreakerCooldown, err = time.ParseDuration(userConfig.EventsBreakerCooldown)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing circuit breaker cooldown %q", userConfig.EventsBreakerCooldown)
+			}
+		}
+	}
+	for _, cidr := range strings.Split(userConfig.EventsTrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing trusted proxy %q", cidr)
+		}
+		eventsController.TrustedProxies = append(eventsController.TrustedProxies, network)
+	}
+	if userConfig.WebhookDedupWindow != "" {
+		dedupWindow, err := time.ParseDuration(userConfig.WebhookDedupWindow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing webhook dedup window %q", userConfig.WebhookDedupWindow)
+		}
+		eventsController.Deduplicator = &events.WebhookDeduplicator{
+			Window:     dedupWindow,
+			Duplicates: metricsRegistry.NewCounter("atlantis_webhook_duplicates", "Number of webhook deliveries, autoplans and comment commands that were ignored because they were duplicates.", "kind"),
+		}
+	}
+	// Webhooks that fail to be handled are stored so they can be replayed,
+	// unless sensitive output can't be written to disk since the payloads
+	// can contain it, ex. in comments.
+	var failedEventsController *FailedEventsController
+	if !userConfig.NoSensitiveDiskWrites {
+		eventsController.FailedEvents = &events.FailedEvents{
+			Store:  boltdb,
+			Max:    events.DefaultMaxFailedEvents,
+			Logger: logger,
+		}
+		failedEventsController = &FailedEventsController{
+			FailedEvents: eventsController.FailedEvents,
+			Events:       eventsController,
+			Logger:       logger,
+		}
+	}
+	// Only pull requests merged on GitHub are applied after merge since
+	// the other VCS hosts' webhooks don't say if they were merged.
+	if githubClient != nil {
+		eventsController.AfterMergeApplier = &events.AfterMergeApplier{
+			GlobalCfgStore:        globalCfgStore,
+			VCSClient:             vcsClient,
+			DB:                    pullStatusStore,
+			ProjectCommandBuilder: projectCommandBuilder,
+			ProjectCommandRunner:  commandRunner.ProjectCommandRunner,
+			MarkdownRenderer:      markdownRenderer,
+			Locker:                lockingClient,
+			WorkingDir:            workingDir,
+			Logger:                logger,
+		}
+	}
+	if userConfig.MergeQueueMode != "" && githubClient != nil {
+		eventsController.MergeQueue = &events.MergeQueue{
+			Mode:                  userConfig.MergeQueueMode,
+			VerifyPlan:            userConfig.MergeQueueVerifyPlan,
+			StatusName:            userConfig.VCSStatusName,
+			VCSClient:             vcsClient,
+			GithubPullGetter:      githubClient,
+			EventParser:           eventParser,
+			DB:                    pullStatusStore,
+			ProjectCommandBuilder: projectCommandBuilder,
+			ProjectCommandRunner:  commandRunner.ProjectCommandRunner,
+			Logger:                logger,
+			AfterMergeApplier:     eventsController.AfterMergeApplier,
+		}
+	}
+	if userConfig.EventQueueWorkers > 0 {
+		eventsController.EventQueue = &events.EventQueue{
+			Runner:      commandRunner,
+			Store:       boltdb,
+			Workers:     userConfig.EventQueueWorkers,
+			MaxDepth:    userConfig.EventQueueMaxDepth,
+			MaxAttempts: events.DefaultEventQueueMaxAttempts,
+			Logger:      logger,
+			Depth:       metricsRegistry.NewGauge("atlantis_event_queue_depth", "Number of webhook events that are queued or being handled."),
+			Priority: func(repo models.Repo) int {
+				return globalCfgStore.Get().Concurrency(repo.ID()).Priority
+			},
+		}
+		if err := eventsController.EventQueue.Start(); err != nil {
+			return nil, errors.Wrap(err, "starting event queue")
+		}
+	}
+	repoWhitelist.OnboardedRepos = boltdb
+	if userConfig.AutoRegisterRepos != "" {
+		eventsController.RepoAutoRegistrar, err = events.NewRepoAutoRegistrar(userConfig.AutoRegisterRepos, boltdb, logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s", config.AutoRegisterReposFlag)
+		}
+	}
+	webhookURL := parsedURL.String() + "/events"
+	if userConfig.EventsURL != "" {
+		parsedEventsURL, err := ParseAtlantisURL(userConfig.EventsURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s flag %q", config.EventsURLFlag, userConfig.EventsURL)
+		}
+		webhookURL = parsedEventsURL.String() + "/events"
+	}
+	reposController := &ReposController{
+		Onboarders:     make(map[string]RepoOnboarder),
+		WebhookSecrets: make(map[string]string),
+		WebhookURL:     webhookURL,
+		Store:          boltdb,
+		Logger:         logger,
+	}
+	if githubClient != nil {
+		hostname := vcsHostname(userConfig.GithubHostname)
+		reposController.Onboarders[hostname] = githubClient
+		if secrets := webhookSecrets[models.Github]; len(secrets) > 0 {
+			reposController.WebhookSecrets[hostname] = string(secrets[0])
+		}
+	}
+	if gitlabClient != nil {
+		hostname := vcsHostname(userConfig.GitlabHostname)
+		reposController.Onboarders[hostname] = gitlabClient
+		if secrets := webhookSecrets[models.Gitlab]; len(secrets) > 0 {
+			reposController.WebhookSecrets[hostname] = string(secrets[0])
+		}
+	}
+	selfChecker := &SelfChecker{
+		AtlantisURL:      userConfig.AtlantisURL,
+		CheckAtlantisURL: userConfig.SelfCheckAtlantisURL,
+		HTTPClient:       httpClients.Client(10 * time.Second),
+		DataDir:          userConfig.DataDir,
+		Logger:           logger,
+	}
+	if terraformClient != nil {
+		selfChecker.TerraformClient = terraformClient
+	}
+	if githubClient != nil {
+		selfChecker.VCSHosts = append(selfChecker.VCSHosts, VCSSelfCheck{Host: models.Github, Credentials: githubClient, WebhookSecretSet: len(webhookSecrets[models.Github]) > 0})
+	}
+	if gitlabClient != nil {
+		selfChecker.VCSHosts = append(selfChecker.VCSHosts, VCSSelfCheck{Host: models.Gitlab, Credentials: gitlabClient, WebhookSecretSet: len(webhookSecrets[models.Gitlab]) > 0})
+	}
+	if bitbucketCloudClient != nil {
+		selfChecker.VCSHosts = append(selfChecker.VCSHosts, VCSSelfCheck{Host: models.BitbucketCloud, Credentials: bitbucketCloudClient, WebhookSecretUnsupported: true})
+	}
+	if bitbucketServerClient != nil {
+		selfChecker.VCSHosts = append(selfChecker.VCSHosts, VCSSelfCheck{Host: models.BitbucketServer, Credentials: bitbucketServerClient, WebhookSecretSet: len(webhookSecrets[models.BitbucketServer]) > 0})
+	}
+	if azuredevopsClient != nil {
+		// Azure DevOps webhooks are validated with basic auth instead of a
+		// secret.
+		selfChecker.VCSHosts = append(selfChecker.VCSHosts, VCSSelfCheck{Host: models.AzureDevops, WebhookSecretSet: userConfig.AzureDevopsWebhookUser != "" && userConfig.AzureDevopsWebhookPassword != ""})
+	}
+	var webAuthenticator WebAuthenticator
+	if userConfig.WebBasicAuth {
+		webAuthenticator = &BasicWebAuthenticator{
+			Username: userConfig.WebUsername,
+			Password: userConfig.WebPassword,
+		}
+	} else if userConfig.WebOIDCIssuerURL != "" {
+		var allowedDomains []string
+		for _, d := range strings.Split(userConfig.WebOIDCAllowedDomains, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				allowedDomains = append(allowedDomains, d)
+			}
+		}
+		webAuthenticator, err = NewOIDCWebAuthenticator(httpClients.Client(30*time.Second), userConfig.WebOIDCIssuerURL, userConfig.WebOIDCClientID, userConfig.WebOIDCClientSecret, allowedDomains, parsedURL, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing OIDC web authentication")
+		}
+	}
+	var auditController *AuditController
+	if userConfig.AuditLog {
+		auditController = &AuditController{
+			DB:     boltdb,
+			Logger: logger,
+		}
+	}
+	apiTokens, err := ParseAPITokens(userConfig.APITokens)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing --%s", config.APITokensFlag)
+	}
+	apiAuthenticator := &APIAuthenticator{
+		StaticTokens:     apiTokens,
+		DB:               boltdb,
+		WebAuthenticator: webAuthenticator,
+		Logger:           logger,
+	}
+	var grpcServer *grpc.Server
+	if userConfig.GRPCPort != 0 {
+		var opts []grpc.ServerOption
+		if userConfig.SSLCertFile != "" && userConfig.SSLKeyFile != "" {
+			creds, err := credentials.NewServerTLSFromFile(userConfig.SSLCertFile, userConfig.SSLKeyFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "loading TLS certificate for the gRPC API")
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		grpcServer = NewGRPCServer(&GRPCServer{
+			Authenticator: apiAuthenticator,
+			Locker:        lockingClient,
+			DB:            boltdb,
+			CommandRunner: commandRunner,
+			JobLogs:       jobLogs,
+			Logger:        logger,
+		}, opts...)
 	}
 	return &Server{
 		AtlantisVersion:    config.AtlantisVersion,
@@ -407,45 +1411,250 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Logger:             logger,
 		Locker:             lockingClient,
 		EventsController:   eventsController,
+		Tracer:             tracer,
 		LocksController:    locksController,
+		GlobalCfgStore:     globalCfgStore,
 		IndexTemplate:      indexTemplate,
 		LockDetailTemplate: lockTemplate,
 		SSLKeyFile:         userConfig.SSLKeyFile,
 		SSLCertFile:        userConfig.SSLCertFile,
+		EventsPort:         userConfig.EventsPort,
+		EventsSSLCertFile:  userConfig.EventsSSLCertFile,
+		EventsSSLKeyFile:   userConfig.EventsSSLKeyFile,
+		GRPCPort:           userConfig.GRPCPort,
+		GRPCServer:         grpcServer,
+		WebAuthenticator:   webAuthenticator,
+		APIAuthenticator:   apiAuthenticator,
+		APITokensController: &APITokensController{
+			StaticTokens: apiTokens,
+			DB:           boltdb,
+			Logger:       logger,
+		},
+		AuditController:        auditController,
+		ReposController:        reposController,
+		RepoConfigController:   repoConfigController,
+		ShadowConfigController: shadowConfigController,
+		SlackController:        slackController,
+		EffectiveConfigController: &EffectiveConfigController{
+			AtlantisVersion:         config.AtlantisVersion,
+			AtlantisURL:             parsedURL,
+			Store:                   globalCfgStore,
+			EffectiveConfigTemplate: effectiveConfigTemplate,
+			Logger:                  logger,
+		},
+		ServerConfigController: &ServerConfigController{
+			ConfigFile: config.ConfigFile,
+			Settings:   config.Settings,
+			Logger:     logger,
+		},
+		PullsController: &PullsController{
+			AtlantisVersion: config.AtlantisVersion,
+			AtlantisURL:     parsedURL,
+			DB:              pullStatusStore,
+			Locker:          lockingClient,
+			Router:          router,
+			RunningCommands: runningCommands,
+			PullsTemplate:   pullsTemplate,
+			Logger:          logger,
+			GlobalCfgStore:  globalCfgStore,
+		},
+		PlansController: &PlansController{
+			Discarder: planDiscarder,
+			DB:        pullStatusStore,
+			Locker:    lockingClient,
+			VCSClient: vcsClient,
+			Logger:    logger,
+		},
+		FailedEventsController: failedEventsController,
+		CommandsController: &CommandsController{
+			RunningCommands: runningCommands,
+			DB:              pullStatusStore,
+			Locker:          lockingClient,
+			VCSClient:       vcsClient,
+			Logger:          logger,
+			GlobalCfgStore:  globalCfgStore,
+		},
+		SelfChecker: selfChecker,
+		SelfCheckController: &SelfCheckController{
+			Checker: selfChecker,
+			Logger:  logger,
+		},
+		FeatureFlags: featureFlags,
+		FeatureFlagsController: &FeatureFlagsController{
+			Store:  featureFlags,
+			Logger: logger,
+		},
+		LogLevelController: &LogLevelController{
+			Levels: logger.Levels,
+			Logger: logger,
+		},
+		PlanOutputsController: &PlanOutputsController{
+			AtlantisVersion:    config.AtlantisVersion,
+			AtlantisURL:        parsedURL,
+			Store:              boltdb,
+			PlanOutputTemplate: planOutputTemplate,
+			Logger:             logger,
+		},
+		WorkersController: workersController,
+		MetricsRegistry:   metricsRegistry,
+		LoadShedder:       loadShedder,
+		LockingHealth:     lockingHealth,
+		RunningCommands:   runningCommands,
+		EventQueue:        eventsController.EventQueue,
+		DataDir:           userConfig.DataDir,
+		DrainTimeout:      drainTimeout,
 	}, nil
 }
 
 // Start creates the routes and starts serving traffic.
 func (s *Server) Start() error {
-	s.Router.HandleFunc("/", s.Index).Methods("GET").MatcherFunc(func(r *http.Request, rm *mux.RouteMatch) bool {
+	// auth wraps every route except for the webhook and health routes
+	// because they're called by VCS providers and load balancers.
+	auth := s.APIAuthenticator.Wrap
+	if s.WebAuthenticator != nil {
+		s.WebAuthenticator.RegisterRoutes(s.Router)
+	}
+	s.Router.HandleFunc("/", auth(LocksReadScope, s.Index)).Methods("GET").MatcherFunc(func(r *http.Request, rm *mux.RouteMatch) bool {
 		return r.URL.Path == "/" || r.URL.Path == "/index.html"
 	})
 	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
+	s.Router.HandleFunc("/readyz", s.Readyz).Methods("GET")
+	s.Router.HandleFunc("/status", s.Status).Methods("GET")
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(&assetfs.AssetFS{Asset: static.Asset, AssetDir: static.AssetDir, AssetInfo: static.AssetInfo}))
-	s.Router.HandleFunc("/events", s.EventsController.Post).Methods("POST")
-	s.Router.HandleFunc("/locks", s.LocksController.DeleteLock).Methods("DELETE").Queries("id", "{id:.*}")
-	s.Router.HandleFunc("/lock", s.LocksController.GetLock).Methods("GET").
+	if s.EventsPort == 0 {
+		s.Router.HandleFunc("/events", s.EventsController.Post).Methods("POST")
+		if s.SlackController != nil {
+			s.Router.HandleFunc("/slack/interactions", s.SlackController.PostInteraction).Methods("POST")
+		}
+	}
+	s.Router.HandleFunc("/api/locks", auth(LocksReadScope, s.LocksController.ListLocks)).Methods("GET")
+	s.Router.HandleFunc("/api/locks", auth(LocksDeleteScope, s.LocksController.DeleteLocks)).Methods("DELETE")
+	s.Router.HandleFunc("/api/tokens", auth(TokensManageScope, s.APITokensController.ListTokens)).Methods("GET")
+	s.Router.HandleFunc("/api/tokens", auth(TokensManageScope, s.APITokensController.CreateToken)).Methods("POST")
+	s.Router.HandleFunc("/api/tokens/{name}", auth(TokensManageScope, s.APITokensController.DeleteToken)).Methods("DELETE")
+	if s.AuditController != nil {
+		s.Router.HandleFunc("/api/audit", auth(AuditReadScope, s.AuditController.ExportAuditLog)).Methods("GET")
+	}
+	s.Router.HandleFunc("/api/repos", auth(ReposManageScope, s.ReposController.ListRepos)).Methods("GET")
+	s.Router.HandleFunc("/api/repos", auth(ReposManageScope, s.ReposController.OnboardRepo)).Methods("POST")
+	s.Router.HandleFunc("/api/repos/{repo:.+}/config", auth(ConfigReadScope, s.EffectiveConfigController.GetConfig)).Methods("GET")
+	s.Router.HandleFunc("/api/repos/{repo:.+}", auth(ReposManageScope, s.ReposController.OffboardRepo)).Methods("DELETE")
+	s.Router.HandleFunc("/api/pulls", auth(PullsReadScope, s.PullsController.ListPulls)).Methods("GET")
+	s.Router.HandleFunc("/api/plans", auth(LocksDeleteScope, s.PlansController.DiscardPlans)).Methods("DELETE")
+	s.Router.HandleFunc("/api/commands", auth(CommandsCancelScope, s.CommandsController.CancelCommands)).Methods("DELETE")
+	if s.FailedEventsController != nil {
+		s.Router.HandleFunc("/api/events/failed", auth(EventsManageScope, s.FailedEventsController.ListFailedEvents)).Methods("GET")
+		s.Router.HandleFunc("/api/events/{id}/replay", auth(EventsManageScope, s.FailedEventsController.ReplayFailedEvent)).Methods("POST")
+	}
+	s.Router.HandleFunc("/api/server/config", auth(ConfigReadScope, s.ServerConfigController.GetConfig)).Methods("GET")
+	s.Router.HandleFunc("/api/selfcheck", auth(ConfigReadScope, s.SelfCheckController.GetSelfCheck)).Methods("GET")
+	s.Router.HandleFunc("/api/feature-flags", auth(FeatureFlagsManageScope, s.FeatureFlagsController.ListFlags)).Methods("GET")
+	s.Router.HandleFunc("/api/feature-flags/{name}", auth(FeatureFlagsManageScope, s.FeatureFlagsController.SetFlag)).Methods("PUT")
+	s.Router.HandleFunc("/api/feature-flags/{name}", auth(FeatureFlagsManageScope, s.FeatureFlagsController.DeleteFlag)).Methods("DELETE")
+	s.Router.HandleFunc("/api/loglevel", auth(LogLevelManageScope, s.LogLevelController.GetLevels)).Methods("GET")
+	s.Router.HandleFunc("/api/loglevel", auth(LogLevelManageScope, s.LogLevelController.SetLevel)).Methods("PUT")
+	if s.RepoConfigController != nil {
+		s.Router.HandleFunc("/api/reload-config", auth(ConfigReloadScope, s.RepoConfigController.Reload)).Methods("POST")
+	}
+	if s.ShadowConfigController != nil {
+		s.Router.HandleFunc("/api/repo-config/shadow-diff", auth(ConfigReadScope, s.ShadowConfigController.GetDiffs)).Methods("GET")
+	}
+	if s.WorkersController != nil {
+		// Workers always need a token since jobs include credentials and
+		// their results include the planfiles that are applied.
+		workerAuth := s.APIAuthenticator.RequireToken
+		s.Router.HandleFunc(workers.ClaimPath, workerAuth(WorkersRunScope, s.WorkersController.ClaimJob)).Methods("POST")
+		s.Router.HandleFunc(fmt.Sprintf(workers.CompletePath, "{id}"), workerAuth(WorkersRunScope, s.WorkersController.CompleteJob)).Methods("POST")
+	}
+	s.Router.HandleFunc("/metrics", auth(MetricsReadScope, s.MetricsRegistry.ServeHTTP)).Methods("GET")
+	s.Router.HandleFunc("/plan-output", auth(PlansReadScope, s.PlanOutputsController.GetPlanOutput)).Methods("GET").
+		Queries(LockViewRouteIDQueryParam, fmt.Sprintf("{%s}", LockViewRouteIDQueryParam)).Name(PlanOutputViewRouteName)
+	s.Router.HandleFunc("/pulls", auth(PullsReadScope, s.PullsController.GetPullsPage)).Methods("GET")
+	s.Router.HandleFunc("/repo-config", auth(ConfigReadScope, s.EffectiveConfigController.GetConfigPage)).Methods("GET")
+	s.Router.HandleFunc("/locks", auth(LocksDeleteScope, s.LocksController.DeleteLock)).Methods("DELETE").Queries("id", "{id:.*}")
+	s.Router.HandleFunc("/lock", auth(LocksReadScope, s.LocksController.GetLock)).Methods("GET").
 		Queries(LockViewRouteIDQueryParam, fmt.Sprintf("{%s}", LockViewRouteIDQueryParam)).Name(LockViewRouteName)
+
+	// Ensure server gracefully drains connections when stopped.
+	stop := make(chan os.Signal, 1)
+	// Stop on SIGINTs and SIGTERMs.
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	servers := []*http.Server{
+		s.listenAndServe("", s.Port, s.SSLCertFile, s.SSLKeyFile, s.Router),
+	}
+	if s.EventsPort != 0 {
+		servers = append(servers, s.listenAndServe("events ", s.EventsPort, s.EventsSSLCertFile, s.EventsSSLKeyFile, s.EventsRouter()))
+	}
+	if s.GRPCServer != nil {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.GRPCPort))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("listening on gRPC port: %s", err), 1)
+		}
+		go func() {
+			s.Logger.Info("Atlantis started - gRPC API listening on port %v", s.GRPCPort)
+			if err := s.GRPCServer.Serve(lis); err != nil {
+				s.Logger.Err(err.Error())
+			}
+		}()
+	}
+	// The checks are run once the servers are listening so the Atlantis URL
+	// check can reach this server.
+	go s.SelfChecker.RunAndLog()
+	<-stop
+
+	s.Logger.Warn("Received interrupt. Safely shutting down")
+	s.Drain()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			return cli.NewExitError(fmt.Sprintf("while shutting down: %s", err), 1)
+		}
+	}
+	if s.GRPCServer != nil {
+		// Streams of job logs can be open for a long time so they're
+		// closed once the other servers have shut down.
+		s.GRPCServer.Stop()
+	}
+	s.Tracer.Shutdown()
+	return nil
+}
+
+// EventsRouter returns the router for the events port. It only serves the
+// /events, /slack/interactions, /healthz and /readyz routes. Webhooks and
+// Slack's requests are authenticated by their secrets so the routes don't use
+// the web UI or API authentication.
+func (s *Server) EventsRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/healthz", s.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", s.Readyz).Methods("GET")
+	router.HandleFunc("/events", s.EventsController.Post).Methods("POST")
+	if s.SlackController != nil {
+		router.HandleFunc("/slack/interactions", s.SlackController.PostInteraction).Methods("POST")
+	}
+	return router
+}
+
+// listenAndServe serves handler on port in the background, over TLS if
+// certFile and keyFile are set. name is prefixed to the log message so the
+// UI and events servers can be told apart.
+func (s *Server) listenAndServe(name string, port int, certFile string, keyFile string, handler http.Handler) *http.Server {
 	n := negroni.New(&negroni.Recovery{
 		Logger:     log.New(os.Stdout, "", log.LstdFlags),
 		PrintStack: false,
 		StackAll:   false,
 		StackSize:  1024 * 8,
 	}, NewRequestLogger(s.Logger))
-	n.UseHandler(s.Router)
-
-	// Ensure server gracefully drains connections when stopped.
-	stop := make(chan os.Signal, 1)
-	// Stop on SIGINTs and SIGTERMs.
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	n.UseHandler(handler)
 
-	server := &http.Server{Addr: fmt.Sprintf(":%d", s.Port), Handler: n}
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: n}
 	go func() {
-		s.Logger.Info("Atlantis started - listening on port %v", s.Port)
+		s.Logger.Info("Atlantis started - %slistening on port %v", name, port)
 
 		var err error
-		if s.SSLCertFile != "" && s.SSLKeyFile != "" {
-			err = server.ListenAndServeTLS(s.SSLCertFile, s.SSLKeyFile)
+		if certFile != "" && keyFile != "" {
+			err = server.ListenAndServeTLS(certFile, keyFile)
 		} else {
 			err = server.ListenAndServe()
 		}
@@ -454,18 +1663,21 @@ func (s *Server) Start() error {
 			s.Logger.Err(err.Error())
 		}
 	}()
-	<-stop
-
-	s.Logger.Warn("Received interrupt. Safely shutting down")
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second) // nolint: vet
-	if err := server.Shutdown(ctx); err != nil {
-		return cli.NewExitError(fmt.Sprintf("while shutting down: %s", err), 1)
-	}
-	return nil
+	return server
 }
 
 // Index is the / route.
-func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
+func (s *Server) Index(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseLockFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid query: %s", err)
+		return
+	}
+	// Sort by date - newest to oldest - by default.
+	if filter.Sort == "" {
+		filter.Sort = LockSortNewest
+	}
 	locks, err := s.Locker.List()
 	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -473,27 +1685,33 @@ func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
+	visible := repoScope(s.GlobalCfgStore, r)
 	var lockResults []LockIndexData
-	for id, v := range locks {
+	for _, id := range filter.Apply(locks, time.Now()) {
+		v := locks[id]
+		if visible != nil && !visible(lockRepoID(v)) {
+			continue
+		}
 		lockURL, _ := s.Router.Get(LockViewRouteName).URL("id", url.QueryEscape(id))
 		lockResults = append(lockResults, LockIndexData{
 			// NOTE: must use .String() instead of .Path because we need the
 			// query params as part of the lock URL.
 			LockPath:      lockURL.String(),
+			LockID:        id,
 			RepoFullName:  v.Project.RepoFullName,
 			PullNum:       v.Pull.Num,
 			Path:          v.Project.Path,
 			Workspace:     v.Workspace,
+			User:          v.User.Username,
 			Time:          v.Time,
 			TimeFormatted: v.Time.Format("02-01-2006 15:04:05"),
 		})
 	}
 
-	//Sort by date - newest to oldest.
-	sort.SliceStable(lockResults, func(i, j int) bool { return lockResults[i].Time.After(lockResults[j].Time) })
-
 	err = s.IndexTemplate.Execute(w, IndexData{
 		Locks:           lockResults,
+		TotalLocks:      len(locks),
+		Filter:          filter,
 		AtlantisVersion: s.AtlantisVersion,
 		CleanedBasePath: s.AtlantisURL.Path,
 	})
@@ -502,12 +1720,144 @@ func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-// Healthz returns the health check response. It always returns a 200 currently.
+// Healthz returns the health check response. It always returns a 200 so
+// that a locking backend outage doesn't fail liveness probes and restart
+// every replica. The backend's state is included for information and
+// reported by Readyz and the atlantis_locking_backend_up metric.
 func (s *Server) Healthz(w http.ResponseWriter, _ *http.Request) {
+	var lockingBackend *locking.HealthStatus
+	if s.LockingHealth != nil {
+		health := s.LockingHealth.Status()
+		lockingBackend = &health
+	}
+	data, err := json.MarshalIndent(&struct {
+		Status         string                `json:"status"`
+		LockingBackend *locking.HealthStatus `json:"locking_backend,omitempty"`
+	}{
+		Status:         "ok",
+		LockingBackend: lockingBackend,
+	}, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error creating status json response: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data) // nolint: errcheck
+}
+
+// Drain marks the server as not ready so load balancers stop sending it
+// traffic and waits up to DrainTimeout for the running commands to finish.
+func (s *Server) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+	deadline := time.Now().Add(s.DrainTimeout)
+	for s.RunningCommands.Len() > 0 && time.Now().Before(deadline) {
+		s.Logger.Info("waiting for %d running commands to finish before shutting down", s.RunningCommands.Len())
+		time.Sleep(drainPollInterval)
+	}
+	if n := s.RunningCommands.Len(); n > 0 {
+		s.Logger.Warn("shutting down with %d commands still running", n)
+	}
+}
+
+// drainPollInterval is how often Drain checks whether the running commands
+// have finished.
+const drainPollInterval = 5 * time.Second
+
+// Readyz returns the readiness check response. Unlike /healthz, it returns
+// a 503 while the server is draining before it shuts down so rolling deploys
+// stop sending it webhooks, and if the data dir or the locking backend is
+// unavailable.
+func (s *Server) Readyz(w http.ResponseWriter, _ *http.Request) {
+	var reasons []string
+	if atomic.LoadInt32(&s.draining) == 1 {
+		reasons = append(reasons, "server is draining")
+	}
+	if s.DataDir != "" {
+		if err := checkWritable(s.DataDir); err != nil {
+			reasons = append(reasons, fmt.Sprintf("data dir is unavailable: %s", err))
+		}
+	}
+	if s.LockingHealth != nil {
+		if health := s.LockingHealth.Status(); !health.Healthy {
+			reasons = append(reasons, fmt.Sprintf("locking backend is unavailable: %s", health.Error))
+		}
+	}
+	status := "ready"
+	if len(reasons) > 0 {
+		status = "not ready"
+	}
+	data, err := json.MarshalIndent(&struct {
+		Status  string   `json:"status"`
+		Reasons []string `json:"reasons,omitempty"`
+	}{
+		Status:  status,
+		Reasons: reasons,
+	}, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error creating status json response: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(reasons) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(data) // nolint: errcheck
+}
+
+// checkWritable returns an error if a file can't be created in dir.
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".readyz")
+	if err != nil {
+		return err
+	}
+	f.Close() // nolint: errcheck
+	return os.Remove(f.Name())
+}
+
+// splitExtraArgs splits the comma-separated flags of --allowed-extra-args or
+// --denied-extra-args.
+func splitExtraArgs(flags string) []string {
+	var split []string
+	for _, flag := range strings.Split(flags, ",") {
+		if flag = strings.TrimSpace(flag); flag != "" {
+			split = append(split, flag)
+		}
+	}
+	return split
+}
+
+// Status returns whether the server is ok or degraded and why, and the
+// number of commands in progress. When it's degraded, new autoplans are
+// rejected. It always returns a 200 so it isn't mistaken for a failed health
+// check. The route isn't authenticated so it doesn't list the commands
+// themselves, which are listed by the pulls API instead.
+func (s *Server) Status(w http.ResponseWriter, _ *http.Request) {
+	status := "ok"
+	reasons := s.LoadShedder.Check()
+	if len(reasons) > 0 {
+		status = "degraded"
+	}
+	inFlight := s.LoadShedder.InFlight()
+	if s.RunningCommands != nil {
+		inFlight = s.RunningCommands.Len()
+	}
+	var queuedEvents *int
+	if s.EventQueue != nil {
+		n := s.EventQueue.Len()
+		queuedEvents = &n
+	}
 	data, err := json.MarshalIndent(&struct {
-		Status string `json:"status"`
+		Status           string   `json:"status"`
+		Reasons          []string `json:"reasons,omitempty"`
+		InFlightCommands int      `json:"in_flight_commands"`
+		QueuedEvents     *int     `json:"queued_events,omitempty"`
 	}{
-		Status: "ok",
+		Status:           status,
+		Reasons:          reasons,
+		InFlightCommands: inFlight,
+		QueuedEvents:     queuedEvents,
 	}, "", "  ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
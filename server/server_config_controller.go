@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Setting is one of the settings the server was started with.
+type Setting struct {
+	// Name is the setting's flag, ex. gh-user.
+	Name string `json:"name"`
+	// Value is the setting's value after defaults are applied. Secrets are
+	// redacted.
+	Value interface{} `json:"value"`
+	// Source is where the setting was set: flag, env, config-file or
+	// default.
+	Source string `json:"source"`
+}
+
+// ServerConfig is the response of the /api/server/config route.
+type ServerConfig struct {
+	ConfigFile string    `json:"config_file"`
+	Settings   []Setting `json:"settings"`
+}
+
+// ServerConfigController shows the settings the server was started with so
+// large deployments can be checked without reading their flags, env vars
+// and config file.
+type ServerConfigController struct {
+	ConfigFile string
+	Settings   []Setting
+	Logger     *logging.SimpleLogger
+}
+
+// GetConfig is the GET /api/server/config route. It returns the server's
+// settings as JSON.
+func (s *ServerConfigController) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	cfg := ServerConfig{
+		ConfigFile: s.ConfigFile,
+		Settings:   s.Settings,
+	}
+	if cfg.Settings == nil {
+		cfg.Settings = []Setting{}
+	}
+	enc := json.NewEncoder(w)
+	// Don't escape the <redacted> values.
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(cfg); err != nil {
+		s.Logger.Err("writing response: %s", err)
+	}
+}
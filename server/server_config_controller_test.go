@@ -0,0 +1,33 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestServerConfigController_GetConfig(t *testing.T) {
+	c := &server.ServerConfigController{
+		ConfigFile: "/etc/atlantis/server.yaml",
+		Settings: []server.Setting{
+			{Name: "gh-token", Value: "<redacted>", Source: "env"},
+			{Name: "port", Value: 4141, Source: "default"},
+		},
+		Logger: logging.NewNoopLogger(),
+	}
+	req, _ := http.NewRequest("GET", "/api/server/config", nil)
+	w := httptest.NewRecorder()
+	c.GetConfig(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "application/json", w.Header().Get("Content-Type"))
+	Equals(t, `{"config_file":"/etc/atlantis/server.yaml","settings":[{"name":"gh-token","value":"<redacted>","source":"env"},{"name":"port","value":4141,"source":"default"}]}`+"\n", w.Body.String())
+
+	c = &server.ServerConfigController{Logger: logging.NewNoopLogger()}
+	w = httptest.NewRecorder()
+	c.GetConfig(w, req)
+	Equals(t, `{"config_file":"","settings":[]}`+"\n", w.Body.String())
+}
@@ -15,6 +15,7 @@ package server_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -31,6 +32,7 @@ import (
 	"github.com/gorilla/mux"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
 	sMocks "github.com/runatlantis/atlantis/server/mocks"
@@ -145,12 +147,15 @@ func TestIndex_Success(t *testing.T) {
 		Locks: []server.LockIndexData{
 			{
 				LockPath:      "/lock?id=lkysow%252Fatlantis-example%252F.%252Fdefault",
+				LockID:        "lkysow/atlantis-example/./default",
 				RepoFullName:  "lkysow/atlantis-example",
 				PullNum:       9,
 				Time:          now,
 				TimeFormatted: now.Format("02-01-2006 15:04:05"),
 			},
 		},
+		TotalLocks:      1,
+		Filter:          server.LockFilter{Sort: server.LockSortNewest},
 		AtlantisVersion: atlantisVersion,
 	})
 	responseContains(t, w, http.StatusOK, "")
@@ -170,6 +175,172 @@ func TestHealthz(t *testing.T) {
 }`, string(body))
 }
 
+func TestHealthz_LockingBackendUnavailable(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	health := locking.NewHealthChecker(unavailableBackend{})
+	health.Now = func() time.Time { return now }
+	health.Check() // nolint: errcheck
+	s := server.Server{LockingHealth: health}
+	req, _ := http.NewRequest("GET", "/healthz", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Healthz(w, req)
+	// Restarting won't fix the backend so we're still healthy.
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "ok",
+  "locking_backend": {
+    "healthy": false,
+    "error": "connection refused",
+    "since": "2020-01-10T00:00:00Z",
+    "failed_checks": 1
+  }
+}`, string(body))
+}
+
+// unavailableBackend is a locking backend that can't be reached.
+type unavailableBackend struct {
+	locking.Backend
+}
+
+func (unavailableBackend) Ping() error {
+	return errors.New("connection refused")
+}
+
+func TestStatus(t *testing.T) {
+	s := server.Server{}
+	req, _ := http.NewRequest("GET", "/status", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Status(w, req)
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "ok",
+  "in_flight_commands": 0
+}`, string(body))
+
+	s.LoadShedder = &events.LoadShedder{MaxQueueDepth: 1}
+	defer s.LoadShedder.Track()()
+	w = httptest.NewRecorder()
+	s.Status(w, req)
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+	body, _ = ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "degraded",
+  "reasons": [
+    "1 commands are in flight, the most allowed is 1"
+  ],
+  "in_flight_commands": 1
+}`, string(body))
+}
+
+func TestStatus_RunningCommands(t *testing.T) {
+	running := &events.RunningCommands{}
+	ctx := &events.CommandContext{
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+		Pull:     models.PullRequest{Num: 1},
+		User:     models.User{Username: "user"},
+	}
+	defer running.Start(ctx, "plan")()
+	running.SetProject(ctx, models.ProjectCommandContext{RepoRelDir: "infra", Workspace: "default"})
+	s := server.Server{RunningCommands: running}
+	req, _ := http.NewRequest("GET", "/status", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Status(w, req)
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+
+	// /status isn't authenticated so it only has the count, not who's
+	// running what.
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "ok",
+  "in_flight_commands": 1
+}`, string(body))
+}
+
+func TestReadyz(t *testing.T) {
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	s := server.Server{DataDir: dataDir}
+	req, _ := http.NewRequest("GET", "/readyz", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Readyz(w, req)
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "ready"
+}`, string(body))
+
+	// Draining makes the server not ready but it's still healthy.
+	s.Drain()
+	w = httptest.NewRecorder()
+	s.Readyz(w, req)
+	Equals(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+	body, _ = ioutil.ReadAll(w.Result().Body)
+	Equals(t,
+		`{
+  "status": "not ready",
+  "reasons": [
+    "server is draining"
+  ]
+}`, string(body))
+	w = httptest.NewRecorder()
+	s.Healthz(w, req)
+	Equals(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestReadyz_Unavailable(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	health := locking.NewHealthChecker(unavailableBackend{})
+	health.Now = func() time.Time { return now }
+	health.Check() // nolint: errcheck
+	s := server.Server{
+		DataDir:       "/does/not/exist",
+		LockingHealth: health,
+	}
+	req, _ := http.NewRequest("GET", "/readyz", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Readyz(w, req)
+	Equals(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+	var readyz struct {
+		Status  string
+		Reasons []string
+	}
+	Ok(t, json.NewDecoder(w.Result().Body).Decode(&readyz))
+	Equals(t, "not ready", readyz.Status)
+	Equals(t, 2, len(readyz.Reasons))
+	Assert(t, strings.HasPrefix(readyz.Reasons[0], "data dir is unavailable: "), "got %q", readyz.Reasons[0])
+	Equals(t, "locking backend is unavailable: connection refused", readyz.Reasons[1])
+}
+
+func TestEventsRouter(t *testing.T) {
+	s := server.Server{}
+	router := s.EventsRouter()
+	cases := []struct {
+		method string
+		path   string
+		exp    bool
+	}{
+		{"POST", "/events", true},
+		{"GET", "/healthz", true},
+		{"GET", "/readyz", true},
+		{"GET", "/", false},
+		{"GET", "/api/locks", false},
+		{"GET", "/status", false},
+	}
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req, _ := http.NewRequest(c.method, c.path, nil)
+			Equals(t, c.exp, router.Match(req, &mux.RouteMatch{}))
+		})
+	}
+}
+
 func TestParseAtlantisURL(t *testing.T) {
 	cases := []struct {
 		In     string
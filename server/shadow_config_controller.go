@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// ShadowConfigController shows how the project commands built with the
+// --repo-config-shadow file differ from those built with the active
+// server-side repo config.
+type ShadowConfigController struct {
+	// Path is the path to the --repo-config-shadow file.
+	Path   string
+	Diffs  *events.ShadowDiffStore
+	Logger *logging.SimpleLogger
+}
+
+// ShadowDiffsResponse is the response of the GET /api/repo-config/shadow-diff
+// route.
+type ShadowDiffsResponse struct {
+	// ShadowConfig is the path to the candidate config.
+	ShadowConfig string              `json:"shadow_config"`
+	Diffs        []events.ShadowDiff `json:"diffs"`
+}
+
+// GetDiffs is the GET /api/repo-config/shadow-diff route. It returns the
+// comparisons for recent pull requests, newest first. The repo query param,
+// ex. repo=owner/repo, only returns the comparisons of its pull requests and
+// changed=true leaves out the ones where both configs build the same
+// commands.
+func (s *ShadowConfigController) GetDiffs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	resp := ShadowDiffsResponse{
+		ShadowConfig: s.Path,
+		Diffs:        s.Diffs.List(query.Get("repo"), query.Get("changed") == "true"),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.Logger.Err("writing response: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestShadowConfigController_GetDiffs(t *testing.T) {
+	diffs := &events.ShadowDiffStore{}
+	diffs.Add(events.ShadowDiff{Repo: "owner/repo", Pull: 1, Command: "autoplan", Identical: true})
+	diffs.Add(events.ShadowDiff{Repo: "owner/repo", Pull: 2, Command: "plan", Removed: []events.ShadowProject{{RepoRelDir: "app", Workspace: "default"}}})
+	diffs.Add(events.ShadowDiff{Repo: "owner/other", Pull: 3, Command: "plan", Identical: true})
+	c := server.ShadowConfigController{
+		Path:   "repos.candidate.yaml",
+		Diffs:  diffs,
+		Logger: logging.NewNoopLogger(),
+	}
+
+	for query, expPulls := range map[string][]int{
+		"":                              {3, 2, 1},
+		"?repo=owner/repo":              {2, 1},
+		"?repo=owner/repo&changed=true": {2},
+	} {
+		req, _ := http.NewRequest("GET", "/api/repo-config/shadow-diff"+query, nil)
+		w := httptest.NewRecorder()
+		c.GetDiffs(w, req)
+		Equals(t, http.StatusOK, w.Code)
+		Equals(t, "application/json", w.Header().Get("Content-Type"))
+		var resp server.ShadowDiffsResponse
+		Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		Equals(t, "repos.candidate.yaml", resp.ShadowConfig)
+		var pulls []int
+		for _, d := range resp.Diffs {
+			pulls = append(pulls, d.Pull)
+		}
+		Equals(t, expPulls, pulls)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// slackRequestMaxAge is how old a request from Slack can be. Older requests
+// are rejected so captured requests can't be replayed.
+const slackRequestMaxAge = 5 * time.Minute
+
+// SlackController handles clicks of the "Approve & Apply" buttons posted by
+// events.SlackApprovals. The Slack app's interactivity request URL must be
+// set to its route.
+type SlackController struct {
+	// SigningSecret is the Slack app's signing secret. It's used to verify
+	// that requests come from Slack.
+	SigningSecret []byte
+	Approvals     *events.SlackApprovals
+	CommandRunner events.CommandRunner
+	DB            db.PullStatusStore
+	Locker        locking.Locker
+	Logger        *logging.SimpleLogger
+	Now           func() time.Time
+	// TestingMode is true if the apply should finish before responding.
+	TestingMode bool
+}
+
+// slackResponse is the response to an interaction. It replaces the message
+// with the button unless it's ephemeral, in which case only the user who
+// clicked it sees it.
+type slackResponse struct {
+	ResponseType    string `json:"response_type,omitempty"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	Text            string `json:"text"`
+}
+
+// PostInteraction is the POST /slack/interactions route. It approves and
+// applies the plans of the pull request whose button was clicked as the
+// clicker's VCS user.
+func (s *SlackController) PostInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(w, s.Logger, logging.Warn, http.StatusBadRequest, "Failed reading request body: %s", err)
+		return
+	}
+	if err := s.verify(r, body); err != nil {
+		respond(w, s.Logger, logging.Warn, http.StatusUnauthorized, "Request isn't from Slack: %s", err)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		respond(w, s.Logger, logging.Warn, http.StatusBadRequest, "Failed parsing request body: %s", err)
+		return
+	}
+	var callback slack.AttachmentActionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		respond(w, s.Logger, logging.Warn, http.StatusBadRequest, "Failed parsing payload: %s", err)
+		return
+	}
+	if callback.CallbackID != webhooks.SlackApplyCallbackID || len(callback.Actions) != 1 || callback.Actions[0].Name != webhooks.SlackApplyAction {
+		respond(w, s.Logger, logging.Warn, http.StatusBadRequest, "Unsupported interaction %q", callback.CallbackID)
+		return
+	}
+	approval, err := events.ParseSlackApproval(callback.Actions[0].Value)
+	if err != nil {
+		respond(w, s.Logger, logging.Warn, http.StatusBadRequest, "Invalid approval: %s", err)
+		return
+	}
+
+	user, ok := s.Approvals.VCSUser(callback.User.ID)
+	if !ok {
+		s.Logger.Warn("slack user %s (%s) tried to approve %s#%d but isn't mapped to a VCS user", callback.User.ID, callback.User.Name, approval.Repo, approval.Pull)
+		s.reply(w, slackResponse{ResponseType: "ephemeral", Text: "You can't approve applies because your Slack user isn't mapped to a VCS user. Ask an Atlantis admin to add you."})
+		return
+	}
+	pull, ok, err := findKnownPull(s.DB, s.Locker, approval.Repo, approval.Pull)
+	if err != nil {
+		respond(w, s.Logger, logging.Error, http.StatusInternalServerError, "Failed finding pull request: %s", err)
+		return
+	}
+	if !ok {
+		s.reply(w, slackResponse{ResponseType: "ephemeral", Text: fmt.Sprintf("Pull request %s#%d isn't known to Atlantis anymore.", approval.Repo, approval.Pull)})
+		return
+	}
+	if pull.HeadCommit != approval.Commit {
+		s.reply(w, slackResponse{ResponseType: "ephemeral", Text: fmt.Sprintf("%s#%d was planned again since this message was posted. Approve its latest plans instead.", approval.Repo, approval.Pull)})
+		return
+	}
+
+	s.Logger.Info("%s (slack user %s) approved applying %s#%d", user, callback.User.ID, pull.BaseRepo.FullName, pull.Num)
+	s.reply(w, slackResponse{
+		ReplaceOriginal: true,
+		Text:            fmt.Sprintf("<@%s> approved the plans of <%s|%s#%d> as %s. Applying…", callback.User.ID, pull.URL, pull.BaseRepo.FullName, pull.Num, user),
+	})
+	if s.TestingMode {
+		s.apply(pull, user)
+	} else {
+		go s.apply(pull, user)
+	}
+}
+
+// apply applies the plans of pull as user and posts the outcome to the
+// approvals channel.
+func (s *SlackController) apply(pull models.PullRequest, user string) {
+	cmd := events.NewCommentCommand("", nil, models.ApplyCommand, false, "", "")
+	cmd.SlackApprover = user
+	// The head repo isn't stored with the pull request so the command runner
+	// looks it up from the VCS host.
+	s.CommandRunner.RunCommentCommand(pull.BaseRepo, nil, &pull, models.User{Username: user}, pull.Num, cmd)
+
+	status, err := s.DB.GetPullStatus(pull)
+	if err != nil {
+		s.Logger.Err("getting status of %s#%d: %s", pull.BaseRepo.FullName, pull.Num, err)
+		return
+	}
+	var applied, errored int
+	if status != nil {
+		for _, p := range status.Projects {
+			switch p.Status {
+			case models.AppliedPlanStatus:
+				applied++
+			case models.ErroredApplyStatus:
+				errored++
+			}
+		}
+	}
+	outcome := "nothing was applied, see the pull request for why"
+	if applied > 0 || errored > 0 {
+		outcome = fmt.Sprintf("%d applied, %d failed", applied, errored)
+	}
+	text := fmt.Sprintf("Apply of <%s|%s#%d> approved by %s finished: %s.", pull.URL, pull.BaseRepo.FullName, pull.Num, user, outcome)
+	if err := s.Approvals.Client.PostText(s.Approvals.Channel, text); err != nil {
+		s.Logger.Warn("unable to post apply outcome to slack: %s", err)
+	}
+}
+
+// verify returns an error unless r, whose body is body, was signed with the
+// signing secret recently.
+func (s *SlackController) verify(r *http.Request, body []byte) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Slack-Request-Timestamp %q", ts)
+	}
+	if age := s.Now().Sub(time.Unix(secs, 0)); age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return fmt.Errorf("X-Slack-Request-Timestamp %s is more than %s old", ts, slackRequestMaxAge)
+	}
+	mac := hmac.New(sha256.New, s.SigningSecret)
+	mac.Write([]byte("v0:" + ts + ":")) // nolint: errcheck
+	mac.Write(body)                     // nolint: errcheck
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid X-Slack-Signature")
+	}
+	return nil
+}
+
+func (s *SlackController) reply(w http.ResponseWriter, resp slackResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.Logger.Err("writing response: %s", err)
+	}
+}
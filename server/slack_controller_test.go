@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	webhookmocks "github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var slackNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// slackRequest returns a request like Slack sends when a button is clicked,
+// signed with secret at ts.
+func slackRequest(secret string, ts time.Time, userID string, value string) *http.Request {
+	payload := fmt.Sprintf(`{"callback_id":"atlantis_apply","user":{"id":%q,"name":"slackuser"},"actions":[{"name":"approve_apply","type":"button","value":%q}]}`, userID, value)
+	body := url.Values{"payload": {payload}}.Encode()
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body)) // nolint: errcheck
+	req, _ := http.NewRequest("POST", "/slack/interactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackController_PostInteraction(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	store, err := db.New(tmp)
+	Ok(t, err)
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	pull := models.PullRequest{Num: 7, HeadCommit: "abc123", URL: "https://github.com/owner/repo/pull/7", BaseRepo: repo}
+	_, err = store.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "app", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+
+	slackClient := webhookmocks.NewMockSlackClient()
+	commandRunner := mocks.NewMockCommandRunner()
+	sc := server.SlackController{
+		SigningSecret: []byte("secret"),
+		Approvals: &events.SlackApprovals{
+			Client:  slackClient,
+			Channel: "approvals",
+			Users:   map[string]string{"U012AB3CD": "alice"},
+		},
+		CommandRunner: commandRunner,
+		DB:            store,
+		Locker:        lockmocks.NewMockLocker(),
+		Logger:        logging.NewNoopLogger(),
+		Now:           func() time.Time { return slackNow },
+		TestingMode:   true,
+	}
+	value := `{"repo":"owner/repo","pull":7,"commit":"abc123"}`
+
+	for description, c := range map[string]struct {
+		req     *http.Request
+		expCode int
+		expText string
+	}{
+		"wrong secret": {
+			req:     slackRequest("wrong", slackNow, "U012AB3CD", value),
+			expCode: http.StatusUnauthorized,
+		},
+		"replayed request": {
+			req:     slackRequest("secret", slackNow.Add(-10*time.Minute), "U012AB3CD", value),
+			expCode: http.StatusUnauthorized,
+		},
+		"unmapped user": {
+			req:     slackRequest("secret", slackNow, "U999", value),
+			expCode: http.StatusOK,
+			expText: "You can't approve applies because your Slack user isn't mapped to a VCS user. Ask an Atlantis admin to add you.",
+		},
+		"unknown pull request": {
+			req:     slackRequest("secret", slackNow, "U012AB3CD", `{"repo":"owner/repo","pull":8,"commit":"abc123"}`),
+			expCode: http.StatusOK,
+			expText: "Pull request owner/repo#8 isn't known to Atlantis anymore.",
+		},
+		"planned again": {
+			req:     slackRequest("secret", slackNow, "U012AB3CD", `{"repo":"owner/repo","pull":7,"commit":"old"}`),
+			expCode: http.StatusOK,
+			expText: "owner/repo#7 was planned again since this message was posted. Approve its latest plans instead.",
+		},
+	} {
+		t.Run(description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			sc.PostInteraction(w, c.req)
+			Equals(t, c.expCode, w.Code)
+			if c.expText != "" {
+				var resp struct {
+					ResponseType string `json:"response_type"`
+					Text         string `json:"text"`
+				}
+				Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				Equals(t, "ephemeral", resp.ResponseType)
+				Equals(t, c.expText, resp.Text)
+			}
+		})
+	}
+	commandRunner.VerifyWasCalled(Never()).RunCommentCommand(matchers.AnyModelsRepo(), matchers.AnyPtrToModelsRepo(), matchers.AnyPtrToModelsPullRequest(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+
+	w := httptest.NewRecorder()
+	sc.PostInteraction(w, slackRequest("secret", slackNow, "U012AB3CD", value))
+	Equals(t, http.StatusOK, w.Code)
+	var resp struct {
+		ReplaceOriginal bool   `json:"replace_original"`
+		Text            string `json:"text"`
+	}
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	Assert(t, resp.ReplaceOriginal, "exp the message to be replaced")
+	Equals(t, "<@U012AB3CD> approved the plans of <https://github.com/owner/repo/pull/7|owner/repo#7> as alice. Applying…", resp.Text)
+
+	_, headRepo, _, user, pullNum, cmd := commandRunner.VerifyWasCalledOnce().RunCommentCommand(matchers.AnyModelsRepo(), matchers.AnyPtrToModelsRepo(), matchers.AnyPtrToModelsPullRequest(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Assert(t, headRepo == nil, "the head repo should be left for the command runner to look up but was %v", headRepo)
+	Equals(t, models.User{Username: "alice"}, user)
+	Equals(t, 7, pullNum)
+	Equals(t, models.ApplyCommand, cmd.Name)
+	Equals(t, "alice", cmd.SlackApprover)
+	slackClient.VerifyWasCalledOnce().PostText("approvals", "Apply of <https://github.com/owner/repo/pull/7|owner/repo#7> approved by alice finished: nothing was applied, see the pull request for why.")
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// repoScope returns a func that returns true if the user who made r can see
+// the repo with id repoID, ex. github.com/owner/repo, in the web UI and API.
+// Users who belong to tenants in the server-side repo config can only see
+// their tenants' repos. It returns nil if the user can see every repo, ex.
+// because store is nil or the user doesn't belong to a tenant.
+func repoScope(store *events.GlobalCfgStore, r *http.Request) func(repoID string) bool {
+	if store == nil {
+		return nil
+	}
+	cfg := store.Get()
+	user := RequestUser(r)
+	if len(cfg.UserTenants(user)) == 0 {
+		return nil
+	}
+	return func(repoID string) bool {
+		return repoID != "" && cfg.RepoVisibleTo(user, repoID)
+	}
+}
+
+// lockRepoID returns the id of the repo that lock is held in. Locks from
+// before BaseRepo was stored on pull requests don't know their repo's VCS
+// host so their id is empty and they're only shown to users who can see
+// every repo.
+func lockRepoID(lock models.ProjectLock) string {
+	if lock.Pull.BaseRepo.VCSHost.Hostname == "" {
+		return ""
+	}
+	return lock.Pull.BaseRepo.ID()
+}
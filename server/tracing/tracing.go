@@ -0,0 +1,281 @@
+// Package tracing records spans of the work done to handle webhooks and run
+// commands, ex. cloning, running Terraform and posting comments, and exports
+// them to an OpenTelemetry collector so slow commands can be broken down.
+package tracing
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultFlushInterval is how often finished spans are exported.
+const DefaultFlushInterval = 5 * time.Second
+
+// maxBufferedSpans is how many finished spans are buffered before they're
+// exported without waiting for the flush interval. If exporting is slower
+// than spans are finished, further spans are dropped.
+const maxBufferedSpans = 2048
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer starts spans and exports them once they're finished. A nil *Tracer
+// starts nil spans, which record nothing, so tracing can be disabled by not
+// creating one.
+type Tracer struct {
+	exporter Exporter
+	logger   logging.SimpleLogging
+
+	mu       sync.Mutex
+	buffered []*Span
+	dropped  int
+	// pulls are the spans of the commands running on each pull request. See
+	// PullSpan.
+	pulls map[string]*Span
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewTracer returns a tracer that exports finished spans with exporter every
+// flushInterval.
+func NewTracer(exporter Exporter, flushInterval time.Duration, logger logging.SimpleLogging) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		logger:   logger,
+		pulls:    make(map[string]*Span),
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run(flushInterval)
+	return t
+}
+
+// Start starts a span that's the root of a new trace.
+func (t *Tracer) Start(name string, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{
+		tracer:     t,
+		TraceID:    newID(16),
+		SpanID:     newID(8),
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: attrs,
+	}
+	return s
+}
+
+// StartPull starts a span that's the root of a new trace for a command on
+// pull request pullNum. Until the span ends, it's the parent of the spans
+// of calls that only know the pull request, ex. VCS API calls. See PullSpan.
+func (t *Tracer) StartPull(name string, repoFullName string, pullNum int, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	attrs = append([]Attribute{String("repo", repoFullName), Int("pull", pullNum)}, attrs...)
+	s := t.Start(name, attrs...)
+	s.pullKey = pullKey(repoFullName, pullNum)
+	t.mu.Lock()
+	t.pulls[s.pullKey] = s
+	t.mu.Unlock()
+	return s
+}
+
+// PullSpan returns the span of the command running on pull request pullNum
+// or nil if no command is running.
+func (t *Tracer) PullSpan(repoFullName string, pullNum int) *Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pulls[pullKey(repoFullName, pullNum)]
+}
+
+// Shutdown exports the spans that are buffered and stops exporting.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+}
+
+// finish buffers s to be exported.
+func (t *Tracer) finish(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s.pullKey != "" && t.pulls[s.pullKey] == s {
+		delete(t.pulls, s.pullKey)
+	}
+	if len(t.buffered) >= maxBufferedSpans {
+		t.dropped++
+		return
+	}
+	t.buffered = append(t.buffered, s)
+	if len(t.buffered) >= maxBufferedSpans/2 {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run(flushInterval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.export()
+		case <-t.flush:
+			t.export()
+		case <-t.stop:
+			t.export()
+			return
+		}
+	}
+}
+
+// export exports the buffered spans. Spans that fail to export are dropped
+// so an unavailable collector doesn't use up the server's memory.
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans := t.buffered
+	dropped := t.dropped
+	t.buffered = nil
+	t.dropped = 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warn("dropped %d spans because they were finished faster than they could be exported", dropped)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.exporter.Export(spans); err != nil {
+		t.logger.Warn("unable to export %d spans: %s", len(spans), err)
+	}
+}
+
+// Span is a timed operation, ex. running terraform plan. A nil *Span records
+// nothing so spans can be started from a parent that may be nil.
+type Span struct {
+	TraceID []byte
+	SpanID  []byte
+	// ParentSpanID is empty if the span is the root of its trace.
+	ParentSpanID []byte
+	Name         string
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   []Attribute
+	// Err is the error the operation failed with, if any.
+	Err string
+
+	tracer  *Tracer
+	pullKey string
+	mu      sync.Mutex
+	ended   bool
+}
+
+// Start starts a span that's a child of s.
+func (s *Span) Start(name string, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		tracer:       s.tracer,
+		TraceID:      s.TraceID,
+		SpanID:       newID(8),
+		ParentSpanID: s.SpanID,
+		Name:         name,
+		StartTime:    time.Now(),
+		Attributes:   attrs,
+	}
+}
+
+// SetAttributes adds attrs to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes = append(s.Attributes, attrs...)
+}
+
+// SetError marks the span as failed with err if err isn't nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Err = err.Error()
+}
+
+// End ends the span. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	s.tracer.finish(s)
+}
+
+// Attribute is a key/value pair describing a span, ex. the repo a command
+// was run on.
+type Attribute struct {
+	Key string
+	// Value is a string, int64 or bool.
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func pullKey(repoFullName string, pullNum int) string {
+	return fmt.Sprintf("%s#%d", repoFullName, pullNum)
+}
+
+// newID returns a random ID of n bytes. IDs only need to be unique so if
+// the random source fails, the current time is used instead.
+func newID(n int) []byte {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		now := time.Now().UnixNano()
+		for i := range id {
+			id[i] = byte(now >> (8 * uint(i%8)))
+		}
+	}
+	return id
+}
@@ -0,0 +1,85 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTracer_ExportsToOTLP(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/v1/traces", r.URL.Path)
+		Equals(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		Ok(t, err)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	tracer := tracing.NewTracer(tracing.NewOTLPExporter(collector.URL, "atlantis-test"), time.Hour, logging.NewNoopLogger())
+	root := tracer.StartPull("autoplan", "owner/repo", 1)
+	Equals(t, root, tracer.PullSpan("owner/repo", 1))
+	child := tracer.PullSpan("owner/repo", 1).Start("clone", tracing.Bool("cached", true))
+	child.SetError(errors.New("clone failed"))
+	child.End()
+	root.End()
+	Assert(t, tracer.PullSpan("owner/repo", 1) == nil, "expected no span once the command ended")
+	tracer.Shutdown()
+
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]interface{}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string
+					SpanID       string
+					ParentSpanID string
+					Name         string
+					Attributes   []map[string]interface{}
+					Status       map[string]interface{}
+				}
+			}
+		}
+	}
+	Ok(t, json.Unmarshal(<-bodies, &req))
+	Equals(t, "atlantis-test", req.ResourceSpans[0].Resource.Attributes[0]["value"].(map[string]interface{})["stringValue"])
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	Equals(t, 2, len(spans))
+	Equals(t, "clone", spans[0].Name)
+	Equals(t, "autoplan", spans[1].Name)
+	Equals(t, spans[1].TraceID, spans[0].TraceID)
+	Equals(t, spans[1].SpanID, spans[0].ParentSpanID)
+	Equals(t, "", spans[1].ParentSpanID)
+	Equals(t, 32, len(spans[1].TraceID))
+	Equals(t, map[string]interface{}{"code": float64(2), "message": "clone failed"}, spans[0].Status)
+	Equals(t, []map[string]interface{}{
+		{"key": "cached", "value": map[string]interface{}{"boolValue": true}},
+	}, spans[0].Attributes)
+	Equals(t, []map[string]interface{}{
+		{"key": "repo", "value": map[string]interface{}{"stringValue": "owner/repo"}},
+		{"key": "pull", "value": map[string]interface{}{"intValue": "1"}},
+	}, spans[1].Attributes)
+}
+
+func TestTracer_Disabled(t *testing.T) {
+	var tracer *tracing.Tracer
+	span := tracer.StartPull("autoplan", "owner/repo", 1)
+	Assert(t, span == nil, "expected nil span")
+	child := tracer.PullSpan("owner/repo", 1).Start("clone")
+	child.SetAttributes(tracing.String("key", "value"))
+	child.SetError(errors.New("err"))
+	child.End()
+	span.End()
+	tracer.Shutdown()
+}
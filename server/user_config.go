@@ -8,57 +8,194 @@ import (
 // The mapstructure tags correspond to flags in cmd/server.go and are used when
 // the config is parsed from a YAML file.
 type UserConfig struct {
+	AllowDraftPRs              bool   `mapstructure:"allow-draft-prs"`
 	AllowForkPRs               bool   `mapstructure:"allow-fork-prs"`
 	AllowRepoConfig            bool   `mapstructure:"allow-repo-config"`
+	AllowedExtraArgs           string `mapstructure:"allowed-extra-args"`
+	APITokens                  string `mapstructure:"api-tokens"`
+	ApplyTimeout               string `mapstructure:"apply-timeout"`
+	ArtifactBucket             string `mapstructure:"artifact-bucket"`
+	ArtifactS3Endpoint         string `mapstructure:"artifact-s3-endpoint"`
+	ArtifactURLExpiry          string `mapstructure:"artifact-url-expiry"`
 	AtlantisURL                string `mapstructure:"atlantis-url"`
+	AuditLog                   bool   `mapstructure:"audit-log"`
+	AuditSyslogAddr            string `mapstructure:"audit-syslog-addr"`
+	AuditWebhookURL            string `mapstructure:"audit-webhook-url"`
+	AuthorizerToken            string `mapstructure:"authorizer-token"`
+	AuthorizerURL              string `mapstructure:"authorizer-url"`
 	Automerge                  bool   `mapstructure:"automerge"`
+	AutoplanDebounceWindow     string `mapstructure:"autoplan-debounce-window"`
+	AutoplanModules            bool   `mapstructure:"autoplan-modules"`
+	AutoRegisterRepos          string `mapstructure:"auto-register-repos"`
+	AzureDevopsHostname        string `mapstructure:"azuredevops-hostname"`
 	AzureDevopsToken           string `mapstructure:"azuredevops-token"`
 	AzureDevopsUser            string `mapstructure:"azuredevops-user"`
 	AzureDevopsWebhookPassword string `mapstructure:"azuredevops-webhook-password"`
 	AzureDevopsWebhookUser     string `mapstructure:"azuredevops-webhook-user"`
 	BitbucketBaseURL           string `mapstructure:"bitbucket-base-url"`
+	BitbucketInsights          bool   `mapstructure:"bitbucket-insights"`
 	BitbucketToken             string `mapstructure:"bitbucket-token"`
 	BitbucketUser              string `mapstructure:"bitbucket-user"`
 	BitbucketWebhookSecret     string `mapstructure:"bitbucket-webhook-secret"`
+	CheckoutDepth              int    `mapstructure:"checkout-depth"`
 	CheckoutStrategy           string `mapstructure:"checkout-strategy"`
+	CloneCache                 bool   `mapstructure:"clone-cache"`
+	CloneCacheFetchInterval    string `mapstructure:"clone-cache-fetch-interval"`
+	CloneCacheMaxAge           string `mapstructure:"clone-cache-max-age"`
+	CommentReactions           bool   `mapstructure:"comment-reactions"`
+	CommentRateLimit           int    `mapstructure:"comment-rate-limit"`
+	CommentRateLimitWindow     string `mapstructure:"comment-rate-limit-window"`
+	CommentTemplatesDir        string `mapstructure:"comment-templates-dir"`
 	DataDir                    string `mapstructure:"data-dir"`
+	DBBackend                  string `mapstructure:"db-backend"`
+	DeniedExtraArgs            string `mapstructure:"denied-extra-args"`
 	DisableApplyAll            bool   `mapstructure:"disable-apply-all"`
 	DisableMarkdownFolding     bool   `mapstructure:"disable-markdown-folding"`
+	DrainTimeout               string `mapstructure:"drain-timeout"`
+	EventBusTopic              string `mapstructure:"event-bus-topic"`
+	EventBusURL                string `mapstructure:"event-bus-url"`
+	EventQueueMaxDepth         int    `mapstructure:"event-queue-max-depth"`
+	EventQueueWorkers          int    `mapstructure:"event-queue-workers"`
+	EventsBreakerCooldown      string `mapstructure:"events-circuit-breaker-cooldown"`
+	EventsPort                 int    `mapstructure:"events-port"`
+	EventsRateLimitPerIP       int    `mapstructure:"events-rate-limit-per-ip"`
+	EventsRateLimitPerRepo     int    `mapstructure:"events-rate-limit-per-repo"`
+	EventsSSLCertFile          string `mapstructure:"events-ssl-cert-file"`
+	EventsSSLKeyFile           string `mapstructure:"events-ssl-key-file"`
+	EventsTrustedProxies       string `mapstructure:"events-trusted-proxies"`
+	EventsURL                  string `mapstructure:"events-url"`
+	ExecutionBackend           string `mapstructure:"execution-backend"`
+	FailureReportChannel       string `mapstructure:"failure-report-channel"`
+	FailureReportInterval      string `mapstructure:"failure-report-interval"`
+	FeatureFlagsFile           string `mapstructure:"feature-flags-file"`
 	GithubHostname             string `mapstructure:"gh-hostname"`
 	GithubToken                string `mapstructure:"gh-token"`
 	GithubUser                 string `mapstructure:"gh-user"`
 	GithubWebhookSecret        string `mapstructure:"gh-webhook-secret"`
+	GitlabDiscussions          bool   `mapstructure:"gitlab-discussions"`
 	GitlabHostname             string `mapstructure:"gitlab-hostname"`
 	GitlabToken                string `mapstructure:"gitlab-token"`
 	GitlabUser                 string `mapstructure:"gitlab-user"`
 	GitlabWebhookSecret        string `mapstructure:"gitlab-webhook-secret"`
+	GRPCPort                   int    `mapstructure:"grpc-port"`
 	HidePrevPlanComments       bool   `mapstructure:"hide-prev-plan-comments"`
+	IncrementalFetch           bool   `mapstructure:"incremental-fetch"`
+	InitTimeout                string `mapstructure:"init-timeout"`
+	InvalidateOnPush           string `mapstructure:"invalidate-on-push"`
+	K8sJobCPU                  string `mapstructure:"k8s-job-cpu"`
+	K8sJobDataVolumeClaim      string `mapstructure:"k8s-job-data-volume-claim"`
+	K8sJobImage                string `mapstructure:"k8s-job-image"`
+	K8sJobMemory               string `mapstructure:"k8s-job-memory"`
+	K8sJobNamespace            string `mapstructure:"k8s-job-namespace"`
+	K8sJobServiceAccount       string `mapstructure:"k8s-job-service-account"`
+	LoadShedMaxQueueDepth      int    `mapstructure:"load-shed-max-queue-depth"`
+	LoadShedMinFreeDiskMB      int    `mapstructure:"load-shed-min-free-disk-mb"`
+	LoadShedMinFreeMemoryMB    int    `mapstructure:"load-shed-min-free-memory-mb"`
+	LockingMode                string `mapstructure:"locking-mode"`
+	LockingOutageMode          string `mapstructure:"locking-outage-mode"`
+	LockingOutageWait          string `mapstructure:"locking-outage-wait"`
 	LogLevel                   string `mapstructure:"log-level"`
+	MergeQueueMode             string `mapstructure:"merge-queue-mode"`
+	MergeQueueVerifyPlan       bool   `mapstructure:"merge-queue-verify-plan"`
+	ModifiedFilesSource        string `mapstructure:"modified-files-source"`
+	NoSensitiveDiskWrites      bool   `mapstructure:"no-sensitive-disk-writes"`
+	OutboundCABundleFile       string `mapstructure:"outbound-ca-bundle-file"`
+	OutboundClientCertFile     string `mapstructure:"outbound-client-cert-file"`
+	OutboundClientKeyFile      string `mapstructure:"outbound-client-key-file"`
+	PlanEncryptionKey          string `mapstructure:"plan-encryption-key"`
+	PlanOutputDestination      string `mapstructure:"plan-output-destination"`
+	PlanOutputMaxBytes         int    `mapstructure:"plan-output-max-bytes"`
+	PlanTimeout                string `mapstructure:"plan-timeout"`
 	Port                       int    `mapstructure:"port"`
+	PostgresURL                string `mapstructure:"postgres-url"`
+	PrePlanChecks              bool   `mapstructure:"pre-plan-checks"`
+	PrewarmWorkingDir          bool   `mapstructure:"prewarm-working-dir"`
+	PullStatusCheck            bool   `mapstructure:"pull-status-check"`
+	RedactEnvVars              string `mapstructure:"redact-env-vars"`
+	RedactPatterns             string `mapstructure:"redact-patterns"`
+	RemoteWorkers              bool   `mapstructure:"remote-workers"`
 	RepoConfig                 string `mapstructure:"repo-config"`
+	RepoConfigWatch            bool   `mapstructure:"repo-config-watch"`
 	RepoConfigJSON             string `mapstructure:"repo-config-json"`
+	RepoConfigShadow           string `mapstructure:"repo-config-shadow"`
 	RepoWhitelist              string `mapstructure:"repo-whitelist"`
+	// RepoWhitelistOrgs are the GitHub organizations or GitLab groups, ex.
+	// github.com/myorg, whose repos are whitelisted.
+	RepoWhitelistOrgs             string `mapstructure:"repo-whitelist-orgs"`
+	RepoWhitelistOrgsSyncInterval string `mapstructure:"repo-whitelist-orgs-sync-interval"`
+	RepoWhitelistOrgsTopic        string `mapstructure:"repo-whitelist-orgs-topic"`
+	ReposDir                      string `mapstructure:"repos-dir"`
+	RequestReviewers              bool   `mapstructure:"request-reviewers"`
+	ResultsExportDir              string `mapstructure:"results-export-dir"`
+	ResultsExportFormat           string `mapstructure:"results-export-format"`
+	ResultsExportURL              string `mapstructure:"results-export-url"`
 	// RequireApproval is whether to require pull request approval before
 	// allowing terraform apply's to be run.
 	RequireApproval bool `mapstructure:"require-approval"`
 	// RequireMergeable is whether to require pull requests to be mergeable before
 	// allowing terraform apply's to run.
-	RequireMergeable    bool `mapstructure:"require-mergeable"`
-	SilenceForkPRErrors bool `mapstructure:"silence-fork-pr-errors"`
+	RequireMergeable     bool   `mapstructure:"require-mergeable"`
+	ReusePlans           bool   `mapstructure:"reuse-plans"`
+	RunTimeout           string `mapstructure:"run-timeout"`
+	ScanSecrets          bool   `mapstructure:"scan-secrets"`
+	SecretScannerCommand string `mapstructure:"secret-scanner-command"`
+	SelfCheckAtlantisURL bool   `mapstructure:"self-check-atlantis-url"`
+	SilenceForkPRErrors  bool   `mapstructure:"silence-fork-pr-errors"`
+	// SilenceNoChanges is how the results of plans without changes are
+	// posted.
+	SilenceNoChanges string `mapstructure:"silence-no-changes"`
 	// SilenceVCSStatusNoPlans is whether autoplan should set commit status if no plans
 	// are found.
 	SilenceVCSStatusNoPlans bool            `mapstructure:"silence-vcs-status-no-plans"`
 	SilenceWhitelistErrors  bool            `mapstructure:"silence-whitelist-errors"`
+	SlackApprovalsChannel   string          `mapstructure:"slack-approvals-channel"`
+	SlackSigningSecret      string          `mapstructure:"slack-signing-secret"`
 	SlackToken              string          `mapstructure:"slack-token"`
+	SlackUsers              string          `mapstructure:"slack-users"`
+	SMTPAddr                string          `mapstructure:"smtp-addr"`
+	SMTPFrom                string          `mapstructure:"smtp-from"`
+	SMTPPassword            string          `mapstructure:"smtp-password"`
+	SMTPUsername            string          `mapstructure:"smtp-username"`
 	SSLCertFile             string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile              string          `mapstructure:"ssl-key-file"`
+	StalePlanAge            string          `mapstructure:"stale-plan-age"`
+	StalePlanCheckSchedule  string          `mapstructure:"stale-plan-check-schedule"`
+	StatsdHost              string          `mapstructure:"statsd-host"`
+	StatsdPort              int             `mapstructure:"statsd-port"`
+	StatsdPrefix            string          `mapstructure:"statsd-prefix"`
+	StatusProgressInterval  string          `mapstructure:"status-progress-interval"`
+	StructuredPlanDiff      bool            `mapstructure:"structured-plan-diff"`
+	SummaryComment          bool            `mapstructure:"summary-comment"`
+	TFDistribution          string          `mapstructure:"tf-distribution"`
+	TFDownloadChecksum      string          `mapstructure:"tf-download-checksum"`
 	TFDownloadURL           string          `mapstructure:"tf-download-url"`
+	TFLocalBinDir           string          `mapstructure:"tf-local-bin-dir"`
+	TFPluginCacheVersions   int             `mapstructure:"tf-plugin-cache-versions"`
 	TFEHostname             string          `mapstructure:"tfe-hostname"`
 	TFEToken                string          `mapstructure:"tfe-token"`
+	TracingOTLPEndpoint     string          `mapstructure:"tracing-otlp-endpoint"`
+	TracingServiceName      string          `mapstructure:"tracing-service-name"`
+	VaultAddr               string          `mapstructure:"vault-addr"`
+	VaultToken              string          `mapstructure:"vault-token"`
+	VCSStatusMode           string          `mapstructure:"vcs-status-mode"`
 	VCSStatusName           string          `mapstructure:"vcs-status-name"`
+	WebBasicAuth            bool            `mapstructure:"web-basic-auth"`
+	WebOIDCAllowedDomains   string          `mapstructure:"web-oidc-allowed-domains"`
+	WebOIDCClientID         string          `mapstructure:"web-oidc-client-id"`
+	WebOIDCClientSecret     string          `mapstructure:"web-oidc-client-secret"`
+	WebOIDCIssuerURL        string          `mapstructure:"web-oidc-issuer-url"`
+	WebPassword             string          `mapstructure:"web-password"`
+	WebUsername             string          `mapstructure:"web-username"`
 	DefaultTFVersion        string          `mapstructure:"default-tf-version"`
 	Webhooks                []WebhookConfig `mapstructure:"webhooks"`
-	WriteGitCreds           bool            `mapstructure:"write-git-creds"`
+	// WebhookArchiveRetentionDays is how many days archived webhook
+	// payloads are kept for. 0 keeps them forever.
+	WebhookArchiveRetentionDays int    `mapstructure:"webhook-archive-retention-days"`
+	WebhookArchiveS3Endpoint    string `mapstructure:"webhook-archive-s3-endpoint"`
+	WebhookArchiveURL           string `mapstructure:"webhook-archive-url"`
+	WebhookDedupWindow          string `mapstructure:"webhook-dedup-window"`
+	WebhookSecrets              string `mapstructure:"webhook-secrets"`
+	WriteGitCreds               bool   `mapstructure:"write-git-creds"`
 }
 
 // ToLogLevel returns the LogLevel object corresponding to the user-passed
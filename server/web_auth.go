@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// OIDCLoginPath is the route that starts an OIDC login.
+	OIDCLoginPath = "/auth/login"
+	// OIDCCallbackPath is the route that the OIDC provider redirects back to
+	// after a login. It must be registered with the provider.
+	OIDCCallbackPath = "/auth/callback"
+
+	sessionCookieName   = "atlantis_session"
+	oidcStateCookieName = "atlantis_oidc_state"
+	sessionDuration     = 12 * time.Hour
+	oidcStateDuration   = 10 * time.Minute
+)
+
+// WebAuthenticator authenticates requests to the web UI and its API routes.
+type WebAuthenticator interface {
+	// Wrap returns a handler that calls next only if the request is
+	// authenticated.
+	Wrap(next http.HandlerFunc) http.HandlerFunc
+	// RegisterRoutes registers any routes the authenticator needs, ex. to
+	// handle logins.
+	RegisterRoutes(router *mux.Router)
+}
+
+// requestUserKey is the context key for the authenticated user.
+type requestUserKey struct{}
+
+// withRequestUser returns r with user saved as the authenticated user.
+func withRequestUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestUserKey{}, user))
+}
+
+// RequestUser returns who r was authenticated as, ex. the username or email of
+// a web user or "api-token:<name>" for API tokens. It returns an empty string
+// if the request wasn't authenticated.
+func RequestUser(r *http.Request) string {
+	user, _ := r.Context().Value(requestUserKey{}).(string)
+	return user
+}
+
+// BasicWebAuthenticator authenticates requests using HTTP basic auth.
+type BasicWebAuthenticator struct {
+	Username string
+	Password string
+}
+
+// Wrap implements WebAuthenticator.Wrap.
+func (b *BasicWebAuthenticator) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		// Compare both so we don't leak which one was wrong via timing.
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(b.Username)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(b.Password)) == 1
+		if !ok || !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="atlantis"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, withRequestUser(r, user))
+	}
+}
+
+// RegisterRoutes implements WebAuthenticator.RegisterRoutes. Basic auth
+// doesn't need any routes.
+func (b *BasicWebAuthenticator) RegisterRoutes(_ *mux.Router) {}
+
+// OIDCWebAuthenticator authenticates requests using an OpenID Connect
+// provider. Users are sent to the provider to log in and on success are given
+// a signed session cookie.
+type OIDCWebAuthenticator struct {
+	Issuer       string
+	OAuthConfig  *oauth2.Config
+	HTTPClient   *http.Client
+	Logger       *logging.SimpleLogger
+	SecureCookie bool
+	// AllowedDomains is the list of email domains that are allowed to log in.
+	// If empty, any user that can log in to the provider is allowed.
+	AllowedDomains []string
+	// SessionKey is used to sign session cookies.
+	SessionKey []byte
+	// BasePath is the path Atlantis is hosted under from --atlantis-url, ex.
+	// "/basepath", or empty if it's hosted at the root. Our routes don't
+	// include it but the URLs we redirect browsers to must.
+	BasePath string
+}
+
+// oidcDiscovery is the subset of the provider's discovery document that we
+// use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// oidcClaims are the ID token claims that we use.
+type oidcClaims struct {
+	Issuer        string          `json:"iss"`
+	Audience      json.RawMessage `json:"aud"`
+	Expiry        int64           `json:"exp"`
+	Email         string          `json:"email"`
+	EmailVerified *bool           `json:"email_verified"`
+}
+
+// NewOIDCWebAuthenticator looks up the provider's endpoints from issuerURL's
+// discovery document and returns an authenticator that uses them.
+// atlantisURL is used to construct the callback URL. The provider is called
+// with httpClient or, if it's nil, a client with a 30s timeout.
+func NewOIDCWebAuthenticator(httpClient *http.Client, issuerURL string, clientID string, clientSecret string, allowedDomains []string, atlantisURL *url.URL, logger *logging.SimpleLogger) (*OIDCWebAuthenticator, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	issuer := strings.TrimSuffix(issuerURL, "/")
+	resp, err := httpClient.Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching OIDC discovery document")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching OIDC discovery document: got status %d", resp.StatusCode)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, errors.Wrap(err, "parsing OIDC discovery document")
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery document is for issuer %q, expected %q", discovery.Issuer, issuer)
+	}
+
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, errors.Wrap(err, "generating session key")
+	}
+
+	callbackURL := *atlantisURL
+	callbackURL.Path = strings.TrimSuffix(callbackURL.Path, "/") + OIDCCallbackPath
+	return &OIDCWebAuthenticator{
+		Issuer: issuer,
+		OAuthConfig: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  discovery.AuthorizationEndpoint,
+				TokenURL: discovery.TokenEndpoint,
+			},
+			RedirectURL: callbackURL.String(),
+			Scopes:      []string{"openid", "email"},
+		},
+		HTTPClient:     httpClient,
+		Logger:         logger,
+		SecureCookie:   atlantisURL.Scheme == "https",
+		AllowedDomains: allowedDomains,
+		SessionKey:     sessionKey,
+		BasePath:       strings.TrimSuffix(atlantisURL.Path, "/"),
+	}, nil
+}
+
+// Wrap implements WebAuthenticator.Wrap. Unauthenticated GET requests are
+// redirected to log in. Other requests are rejected.
+func (o *OIDCWebAuthenticator) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			if email, ok := o.verifySession(cookie.Value); ok {
+				next(w, withRequestUser(r, email))
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, o.BasePath+OIDCLoginPath+"?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+	}
+}
+
+// RegisterRoutes implements WebAuthenticator.RegisterRoutes.
+func (o *OIDCWebAuthenticator) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(OIDCLoginPath, o.Login).Methods("GET")
+	router.HandleFunc(OIDCCallbackPath, o.Callback).Methods("GET")
+}
+
+// Login is the GET /auth/login route. It redirects to the provider.
+func (o *OIDCWebAuthenticator) Login(w http.ResponseWriter, r *http.Request) {
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		o.Logger.Err("generating OIDC state: %s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	redirect := r.URL.Query().Get("redirect")
+	if !isLocalRedirect(redirect) {
+		redirect = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state + "." + base64.RawURLEncoding.EncodeToString([]byte(redirect)),
+		Path:     o.BasePath + "/auth",
+		MaxAge:   int(oidcStateDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   o.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, o.OAuthConfig.AuthCodeURL(state), http.StatusFound)
+}
+
+// Callback is the GET /auth/callback route. The provider redirects here
+// after a login.
+func (o *OIDCWebAuthenticator) Callback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil {
+		http.Error(w, "Missing login state, try logging in again", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(stateCookie.Value, ".", 2)
+	if len(parts) != 2 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(r.URL.Query().Get("state"))) != 1 {
+		http.Error(w, "Invalid login state, try logging in again", http.StatusBadRequest)
+		return
+	}
+	redirect, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !isLocalRedirect(string(redirect)) {
+		redirect = []byte("/")
+	}
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		http.Error(w, fmt.Sprintf("Login failed: %s", errParam), http.StatusUnauthorized)
+		return
+	}
+
+	email, err := o.exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		o.Logger.Warn("OIDC login failed: %s", err)
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	if !o.domainAllowed(email) {
+		o.Logger.Warn("rejecting OIDC login from %q because its domain isn't allowed", email)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	o.Logger.Info("user %q logged in to the web UI", email)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Path:     o.BasePath + "/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   o.SecureCookie,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    o.signSession(email, time.Now().Add(sessionDuration)),
+		Path:     o.BasePath + "/",
+		MaxAge:   int(sessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   o.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, o.BasePath+string(redirect), http.StatusFound)
+}
+
+// exchange exchanges code for tokens and returns the email from the
+// validated ID token. The ID token's signature isn't checked against the
+// provider's JWKS. Instead we rely on the token coming directly from the
+// provider's token endpoint, which the OIDC spec allows for the authorization
+// code flow as long as that connection uses TLS, so the issuer URL and the
+// token endpoint from its discovery document should be https.
+func (o *OIDCWebAuthenticator) exchange(ctx context.Context, code string) (string, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, o.HTTPClient)
+	token, err := o.OAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		return "", errors.Wrap(err, "exchanging code")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", errors.New("no id_token in token response")
+	}
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed id_token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Wrap(err, "decoding id_token")
+	}
+	var claims oidcClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errors.Wrap(err, "parsing id_token")
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != o.Issuer {
+		return "", fmt.Errorf("id_token issuer %q doesn't match %q", claims.Issuer, o.Issuer)
+	}
+	if !audienceContains(claims.Audience, o.OAuthConfig.ClientID) {
+		return "", errors.New("id_token audience doesn't include our client id")
+	}
+	if time.Now().Unix() >= claims.Expiry {
+		return "", errors.New("id_token is expired")
+	}
+	if claims.Email == "" {
+		return "", errors.New("id_token has no email claim")
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return "", fmt.Errorf("email %q is not verified", claims.Email)
+	}
+	return claims.Email, nil
+}
+
+// isLocalRedirect returns true if redirect is a path on this server, so that
+// logging in can't be used as an open redirect. Backslashes are rejected
+// because browsers treat them as slashes, ex. "/\evil.com" as "//evil.com".
+func isLocalRedirect(redirect string) bool {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.Contains(redirect, "\\") {
+		return false
+	}
+	u, err := url.Parse(redirect)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// domainAllowed returns true if email is in one of the allowed domains.
+func (o *OIDCWebAuthenticator) domainAllowed(email string) bool {
+	if len(o.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range o.AllowedDomains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// signSession returns a session cookie value for email that's valid until
+// expiry.
+func (o *OIDCWebAuthenticator) signSession(email string, expiry time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + strconv.FormatInt(expiry.Unix(), 10)
+	return payload + "." + o.sessionMAC(payload)
+}
+
+// verifySession returns the email from the session cookie value and true if
+// it's valid.
+func (o *OIDCWebAuthenticator) verifySession(value string) (string, bool) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return "", false
+	}
+	payload, mac := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(o.sessionMAC(payload))) {
+		return "", false
+	}
+	parts := strings.SplitN(payload, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiry {
+		return "", false
+	}
+	email, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(email), true
+}
+
+func (o *OIDCWebAuthenticator) sessionMAC(payload string) string {
+	h := hmac.New(sha256.New, o.SessionKey)
+	h.Write([]byte(payload)) // nolint: errcheck
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// audienceContains returns true if the aud claim, which can be a string or
+// a list of strings, contains clientID.
+func audienceContains(aud json.RawMessage, clientID string) bool {
+	var single string
+	if err := json.Unmarshal(aud, &single); err == nil {
+		return single == clientID
+	}
+	var multiple []string
+	if err := json.Unmarshal(aud, &multiple); err != nil {
+		return false
+	}
+	for _, a := range multiple {
+		if a == clientID {
+			return true
+		}
+	}
+	return false
+}
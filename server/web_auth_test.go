@@ -0,0 +1,250 @@
+package server_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestBasicWebAuthenticator(t *testing.T) {
+	auth := &server.BasicWebAuthenticator{Username: "user", Password: "pass"}
+	handler := auth.Wrap(okHandler)
+
+	cases := []struct {
+		description string
+		user        string
+		pass        string
+		setAuth     bool
+		expCode     int
+	}{
+		{"no credentials", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "user", "wrong", true, http.StatusUnauthorized},
+		{"wrong user", "wrong", "pass", true, http.StatusUnauthorized},
+		{"correct credentials", "user", "pass", true, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			if c.setAuth {
+				req.SetBasicAuth(c.user, c.pass)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			Equals(t, c.expCode, w.Code)
+			if c.expCode == http.StatusUnauthorized {
+				Equals(t, `Basic realm="atlantis"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// fakeOIDCProvider starts a server that implements enough of an OIDC provider
+// to log in. The ID token it returns has the given email.
+func fakeOIDCProvider(t *testing.T, email string) *httptest.Server {
+	var issuer string
+	handler := http.NewServeMux()
+	handler.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"issuer": %q, "authorization_endpoint": %q, "token_endpoint": %q}`, issuer, issuer+"/authorize", issuer+"/token")
+	})
+	handler.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		Ok(t, r.ParseForm())
+		Equals(t, "the-code", r.PostForm.Get("code"))
+		claims, _ := json.Marshal(map[string]interface{}{
+			"iss":   issuer,
+			"aud":   []string{"client-id"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"email": email,
+		})
+		idToken := "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token", "token_type": "Bearer", "id_token": %q}`, idToken)
+	})
+	s := httptest.NewServer(handler)
+	issuer = s.URL
+	return s
+}
+
+// oidcLogin runs through the login flow and returns the callback response.
+func oidcLogin(t *testing.T, router *mux.Router) *httptest.ResponseRecorder {
+	// Accessing a page should send us to log in.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lock?id=abc", nil)
+	router.ServeHTTP(w, req)
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "/auth/login?redirect=%2Flock%3Fid%3Dabc", w.Header().Get("Location"))
+
+	// Logging in should send us to the provider.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/login?redirect=%2Flock%3Fid%3Dabc", nil)
+	router.ServeHTTP(w, req)
+	Equals(t, http.StatusFound, w.Code)
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	Equals(t, "/authorize", authURL.Path)
+	Equals(t, "client-id", authURL.Query().Get("client_id"))
+	Equals(t, "https://atlantis.example.com/auth/callback", authURL.Query().Get("redirect_uri"))
+	stateCookie := w.Result().Cookies()[0]
+
+	// The provider redirects back to us.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/callback?code=the-code&state="+authURL.Query().Get("state"), nil)
+	req.AddCookie(stateCookie)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func newTestOIDCAuthenticator(t *testing.T, providerURL string, allowedDomains []string) *server.OIDCWebAuthenticator {
+	atlantisURL, _ := url.Parse("https://atlantis.example.com")
+	auth, err := server.NewOIDCWebAuthenticator(nil, providerURL, "client-id", "client-secret", allowedDomains, atlantisURL, logging.NewNoopLogger())
+	Ok(t, err)
+	return auth
+}
+
+func TestOIDCWebAuthenticator_Login(t *testing.T) {
+	provider := fakeOIDCProvider(t, "user@example.com")
+	defer provider.Close()
+	auth := newTestOIDCAuthenticator(t, provider.URL, []string{"example.com"})
+	router := mux.NewRouter()
+	auth.RegisterRoutes(router)
+	router.HandleFunc("/lock", auth.Wrap(okHandler))
+
+	w := oidcLogin(t, router)
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "/lock?id=abc", w.Header().Get("Location"))
+	var sessionCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "atlantis_session" {
+			sessionCookie = c
+		}
+	}
+	Assert(t, sessionCookie != nil, "expected session cookie to be set")
+	Equals(t, true, sessionCookie.Secure)
+
+	// Now we should be able to access the page.
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lock?id=abc", nil)
+	req.AddCookie(sessionCookie)
+	router.ServeHTTP(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	// A tampered cookie shouldn't work.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/lock?id=abc", nil)
+	req.AddCookie(&http.Cookie{Name: "atlantis_session", Value: "YXR0YWNrZXJAZXZpbC5jb20.9999999999." + sessionCookie.Value[len(sessionCookie.Value)-64:]})
+	router.ServeHTTP(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCWebAuthenticator_DomainNotAllowed(t *testing.T) {
+	provider := fakeOIDCProvider(t, "user@evil.com")
+	defer provider.Close()
+	auth := newTestOIDCAuthenticator(t, provider.URL, []string{"example.com"})
+	router := mux.NewRouter()
+	auth.RegisterRoutes(router)
+	router.HandleFunc("/lock", auth.Wrap(okHandler))
+
+	w := oidcLogin(t, router)
+	Equals(t, http.StatusForbidden, w.Code)
+}
+
+func TestOIDCWebAuthenticator_InvalidState(t *testing.T) {
+	provider := fakeOIDCProvider(t, "user@example.com")
+	defer provider.Close()
+	auth := newTestOIDCAuthenticator(t, provider.URL, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/auth/callback?code=the-code&state=abc", nil)
+	req.AddCookie(&http.Cookie{Name: "atlantis_oidc_state", Value: "def.Lw"})
+	auth.Callback(w, req)
+	Equals(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCWebAuthenticator_OpenRedirect(t *testing.T) {
+	provider := fakeOIDCProvider(t, "user@example.com")
+	defer provider.Close()
+	auth := newTestOIDCAuthenticator(t, provider.URL, nil)
+
+	for _, redirect := range []string{"//evil.com", "/\\evil.com", "https://evil.com", "evil.com", "/\n/evil.com"} {
+		t.Run(redirect, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/auth/login?redirect="+url.QueryEscape(redirect), nil)
+			auth.Login(w, req)
+			cookie := w.Result().Cookies()[0]
+			// The redirect should have been replaced by "/", which is "Lw"
+			// encoded.
+			Equals(t, "Lw", cookie.Value[len(cookie.Value)-2:])
+		})
+	}
+}
+
+func TestOIDCWebAuthenticator_BasePath(t *testing.T) {
+	provider := fakeOIDCProvider(t, "user@example.com")
+	defer provider.Close()
+	atlantisURL, _ := url.Parse("https://example.com/atlantis/")
+	auth, err := server.NewOIDCWebAuthenticator(nil, provider.URL, "client-id", "client-secret", nil, atlantisURL, logging.NewNoopLogger())
+	Ok(t, err)
+	router := mux.NewRouter()
+	auth.RegisterRoutes(router)
+	router.HandleFunc("/lock", auth.Wrap(okHandler))
+
+	// Our routes don't include the base path but the browser's URLs do.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lock?id=abc", nil)
+	router.ServeHTTP(w, req)
+	Equals(t, "/atlantis/auth/login?redirect=%2Flock%3Fid%3Dabc", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/login?redirect=%2Flock%3Fid%3Dabc", nil)
+	router.ServeHTTP(w, req)
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	Equals(t, "https://example.com/atlantis/auth/callback", authURL.Query().Get("redirect_uri"))
+	stateCookie := w.Result().Cookies()[0]
+	Equals(t, "/atlantis/auth", stateCookie.Path)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/callback?code=the-code&state="+authURL.Query().Get("state"), nil)
+	req.AddCookie(stateCookie)
+	router.ServeHTTP(w, req)
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "/atlantis/lock?id=abc", w.Header().Get("Location"))
+}
+
+func TestRequestUser(t *testing.T) {
+	var user string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		user = server.RequestUser(r)
+	}
+
+	auth := &server.BasicWebAuthenticator{Username: "admin", Password: "pass"}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "pass")
+	auth.Wrap(handler)(httptest.NewRecorder(), req)
+	Equals(t, "admin", user)
+
+	apiAuth := &server.APIAuthenticator{
+		StaticTokens: []models.APIToken{
+			{Name: "ci", Hash: server.HashAPIToken("secret"), Scopes: []string{server.LocksReadScope}},
+		},
+		Logger: logging.NewNoopLogger(),
+	}
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	apiAuth.Wrap(server.LocksReadScope, handler)(httptest.NewRecorder(), req)
+	Equals(t, "api-token:ci", user)
+}
@@ -17,6 +17,8 @@ import (
 	"html/template"
 	"io"
 	"time"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_template_writer.go TemplateWriter
@@ -32,17 +34,24 @@ type TemplateWriter interface {
 // LockIndexData holds the fields needed to display the index view for locks.
 type LockIndexData struct {
 	LockPath      string
+	LockID        string
 	RepoFullName  string
 	PullNum       int
 	Path          string
 	Workspace     string
+	User          string
 	Time          time.Time
 	TimeFormatted string
 }
 
 // IndexData holds the data for rendering the index page
 type IndexData struct {
-	Locks           []LockIndexData
+	// Locks are the locks that pass Filter.
+	Locks []LockIndexData
+	// TotalLocks is the number of locks, including those that don't pass
+	// Filter.
+	TotalLocks      int
+	Filter          LockFilter
 	AtlantisVersion string
 	// CleanedBasePath is the path Atlantis is accessible at externally. If
 	// not using a path-based proxy, this will be an empty string. Never ends
@@ -67,6 +76,68 @@ var indexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
     setTimeout(function() {
         $("p.js-discard-success").fadeOut('slow');
     }, 5000); // <-- time in milliseconds
+
+    $(document).ready(function () {
+      $("input.js-select-all-locks").change(function() {
+        $("input.js-select-lock").prop("checked", this.checked);
+      });
+      $("button.js-delete-locks").click(function() {
+        var ids = $("input.js-select-lock:checked").map(function() {
+          return "id=" + encodeURIComponent($(this).val());
+        }).get();
+        if (!ids.length) {
+          return;
+        }
+        // The reason is optional and is posted on the locks' pull requests.
+        var reason = prompt("Discard the plans of and delete " + ids.length + " locks? Optionally, enter why:", "");
+        if (reason === null) {
+          return;
+        }
+        if (reason) {
+          ids.push("reason=" + encodeURIComponent(reason));
+        }
+        $.ajax({
+          url: "{{ .CleanedBasePath }}/api/locks?" + ids.join("&"),
+          type: "DELETE",
+          dataType: "json",
+          success: function(result) {
+            if (result.errors) {
+              alert("Some locks couldn't be deleted:\n" + Object.keys(result.errors).map(function(id) {
+                return id + ": " + result.errors[id];
+              }).join("\n"));
+            }
+            window.location.replace("{{ .CleanedBasePath }}/?discard=true");
+          },
+          error: function(xhr) {
+            alert(xhr.responseText);
+          }
+        });
+      });
+    });
+
+    function showLogLevels(levels) {
+      var subsystems = Object.keys(levels.subsystems).sort().map(function(name) {
+        return name + ": " + levels.subsystems[name];
+      });
+      $("span.js-log-levels").text(levels.level + (subsystems.length ? " (" + subsystems.join(", ") + ")" : ""));
+    }
+    $(document).ready(function () {
+      $.getJSON("{{ .CleanedBasePath }}/api/loglevel", showLogLevels);
+      $("form.js-log-level").submit(function(event) {
+        event.preventDefault();
+        $.ajax({
+          url: "{{ .CleanedBasePath }}/api/loglevel",
+          type: "PUT",
+          contentType: "application/json",
+          data: JSON.stringify({level: $("#log-level").val(), subsystem: $("#log-subsystem").val()}),
+          dataType: "json",
+          success: showLogLevels,
+          error: function(xhr) {
+            $("span.js-log-levels").text(xhr.responseText);
+          }
+        });
+      });
+    });
   </script>
   <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
   <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
@@ -88,12 +159,38 @@ var indexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
   <br>
   <section>
     <p class="title-heading small"><strong>Locks</strong></p>
+    {{ with .Filter }}
+    <form action="{{ $.CleanedBasePath }}/" method="get">
+      <div class="row">
+        <input class="four columns" type="text" name="q" placeholder="Search" value="{{ .Search }}">
+        <input class="three columns" type="text" name="repo" placeholder="owner/repo" value="{{ .Repo }}">
+        <input class="two columns" type="text" name="pull" placeholder="Pull" value="{{ if .Pull }}{{ .Pull }}{{ end }}">
+        <input class="three columns" type="text" name="user" placeholder="User" value="{{ .User }}">
+      </div>
+      <div class="row">
+        <input class="four columns" type="text" name="older_than" placeholder="Older than, ex. 24h" value="{{ if .OlderThan }}{{ .OlderThan }}{{ end }}">
+        <select class="four columns" name="sort">
+          <option value="newest" {{ if eq .Sort "newest" }}selected{{ end }}>Newest first</option>
+          <option value="oldest" {{ if eq .Sort "oldest" }}selected{{ end }}>Oldest first</option>
+          <option value="repo" {{ if eq .Sort "repo" }}selected{{ end }}>Repo</option>
+          <option value="pull" {{ if eq .Sort "pull" }}selected{{ end }}>Pull request</option>
+          <option value="user" {{ if eq .Sort "user" }}selected{{ end }}>User</option>
+        </select>
+        <input class="button-primary four columns" type="submit" value="Filter Locks">
+      </div>
+    </form>
+    {{ end }}
     {{ if .Locks }}
     {{ $basePath := .CleanedBasePath }}
+    <p>
+      <label><input type="checkbox" class="js-select-all-locks"> Showing {{ len .Locks }} of {{ .TotalLocks }} locks</label>
+      <button class="js-delete-locks">Delete Selected Locks</button>
+    </p>
     {{ range .Locks }}
+      <input type="checkbox" class="js-select-lock" value="{{ .LockID }}">
       <a href="{{ $basePath }}{{.LockPath}}">
         <div class="twelve columns button content lock-row">
-        <div class="list-title">{{.RepoFullName}} <span class="heading-font-size">#{{.PullNum}}</span> <code>{{.Path}}</code> <code>{{.Workspace}}</code></div>
+        <div class="list-title">{{.RepoFullName}} <span class="heading-font-size">#{{.PullNum}}</span> <code>{{.Path}}</code> <code>{{.Workspace}}</code>{{ if .User }} <span class="heading-font-size">{{.User}}</span>{{ end }}</div>
         <div class="list-status"><code>Locked</code></div>
         <div class="list-timestamp"><span class="heading-font-size">{{.TimeFormatted}}</span></div>
         </div>
@@ -103,6 +200,37 @@ var indexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
     <p class="placeholder">No locks found.</p>
     {{ end }}
   </section>
+  <section>
+    <p class="title-heading small"><strong>Pull Requests</strong></p>
+    <a class="button" href="{{ .CleanedBasePath }}/pulls">View Pull Requests</a>
+  </section>
+  <section>
+    <p class="title-heading small"><strong>Repo Config</strong></p>
+    <form action="{{ .CleanedBasePath }}/repo-config" method="get">
+      <input class="eight columns" type="text" name="repo" placeholder="github.com/owner/repo">
+      <input class="button-primary four columns" type="submit" value="View Effective Config">
+    </form>
+  </section>
+  <section>
+    <p class="title-heading small"><strong>Log Level</strong></p>
+    <p>Current: <code><span class="js-log-levels"></span></code></p>
+    <form class="js-log-level">
+      <select class="four columns" id="log-subsystem">
+        <option value="">All</option>
+        <option value="locking">locking</option>
+        <option value="terraform">terraform</option>
+        <option value="vcs">vcs</option>
+      </select>
+      <select class="four columns" id="log-level">
+        <option value="debug">debug</option>
+        <option value="info">info</option>
+        <option value="warn">warn</option>
+        <option value="error">error</option>
+        <option value="">server's level</option>
+      </select>
+      <input class="button-primary four columns" type="submit" value="Set Log Level">
+    </form>
+  </section>
 </div>
 <footer>
 v{{ .AtlantisVersion }}
@@ -174,6 +302,7 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
       </div>
       <div class="modal-body">
         <p><strong>Are you sure you want to discard the plan and unlock?</strong></p>
+        <textarea class="u-full-width" id="discardReason" placeholder="Reason (optional, posted on the pull request)"></textarea>
         <input class="button-primary" id="discardYes" type="submit" value="Yes" data="{{.LockKeyEncoded}}">
         <input type="button" class="cancel" value="Cancel">
       </div>
@@ -211,7 +340,7 @@ v{{ .AtlantisVersion }}
 
   btnDiscard.click(function() {
     $.ajax({
-        url: '{{ .CleanedBasePath }}/locks?id='+lockId,
+        url: '{{ .CleanedBasePath }}/locks?id='+lockId+'&reason='+encodeURIComponent($("#discardReason").val()),
         type: 'DELETE',
         success: function(result) {
           window.location.replace("{{ .CleanedBasePath }}/?discard=true");
@@ -229,3 +358,265 @@ v{{ .AtlantisVersion }}
 </body>
 </html>
 `))
+
+// PlanOutputData holds the fields needed to display the full output of a
+// plan.
+type PlanOutputData struct {
+	RepoFullName    string
+	PullNum         int
+	PullRequestLink string
+	HeadCommit      string
+	ProjectName     string
+	RepoRelDir      string
+	Workspace       string
+	Output          string
+	// Apply is true if Output is the output of an apply.
+	Apply           bool
+	TimeFormatted   string
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var planOutputTemplate = template.Must(template.New("plan-output.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{ .RepoFullName }} #{{ .PullNum }}</strong> <code>{{ if .Apply }}Apply{{ else }}Plan{{ end }} Output</code></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <h6><code>Pull Request Link</code>: <a href="{{ .PullRequestLink }}" target="_blank"><strong>{{ .PullRequestLink }}</strong></a></h6>
+      {{ if .ProjectName }}<h6><code>Project</code>: <strong>{{ .ProjectName }}</strong></h6>{{ end }}
+      <h6><code>Dir</code>: <strong>{{ .RepoRelDir }}</strong></h6>
+      <h6><code>Workspace</code>: <strong>{{ .Workspace }}</strong></h6>
+      <h6><code>Commit</code>: <strong>{{ .HeadCommit }}</strong></h6>
+      <h6><code>{{ if .Apply }}Applied{{ else }}Planned{{ end }} At</code>: <strong>{{ .TimeFormatted }}</strong></h6>
+      <pre><code>{{ .Output }}</code></pre>
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
+
+// EffectiveConfigData holds the fields needed to display a repo's effective
+// server-side repo config.
+type EffectiveConfigData struct {
+	// RepoID is the repo that was asked for or empty if none was.
+	RepoID string
+	// Error is why RepoID is invalid.
+	Error string
+	// Config is nil if no valid repo was asked for.
+	Config          *valid.EffectiveCfg
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var effectiveConfigTemplate = template.Must(template.New("effective-config.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{ if .RepoID }}{{ .RepoID }}{{ else }}Repo{{ end }}</strong> <code>Effective Config</code></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <form action="{{ .CleanedBasePath }}/repo-config" method="get">
+        <input class="eight columns" type="text" name="repo" value="{{ .RepoID }}" placeholder="github.com/owner/repo">
+        <input class="button-primary four columns" type="submit" value="View Effective Config">
+      </form>
+    </section>
+    {{ if .Error }}
+    <section>
+      <p class="placeholder">Invalid repo: {{ .Error }}</p>
+    </section>
+    {{ end }}
+    {{ with .Config }}
+    <section>
+      <h6><code>Matching Repos</code>: <strong>{{ range $i, $r := .MatchingRepos }}{{ if $i }}, {{ end }}{{ $r }}{{ end }}</strong></h6>
+      <table class="u-full-width">
+        <thead>
+          <tr><th>Key</th><th>Value</th><th>Set By</th></tr>
+        </thead>
+        <tbody>
+          <tr><td><code>apply_requirements</code></td><td>{{ .ApplyRequirements }}</td><td>{{ .ApplyRequirements.Source }}</td></tr>
+          <tr><td><code>workflow</code></td><td>{{ .Workflow }}</td><td>{{ .Workflow.Source }}</td></tr>
+          <tr><td><code>allowed_overrides</code></td><td>{{ .AllowedOverrides }}</td><td>{{ .AllowedOverrides.Source }}</td></tr>
+          <tr><td><code>allow_custom_workflows</code></td><td>{{ .AllowCustomWorkflows }}</td><td>{{ .AllowCustomWorkflows.Source }}</td></tr>
+          <tr><td><code>allowed_workflows</code></td><td>{{ .AllowedWorkflows }}</td><td>{{ .AllowedWorkflows.Source }}</td></tr>
+          <tr><td><code>allow_custom_run_steps</code></td><td>{{ .AllowCustomRunSteps }}</td><td>{{ .AllowCustomRunSteps.Source }}</td></tr>
+          <tr><td><code>concurrency_group</code></td><td>{{ .ConcurrencyGroup }}</td><td>{{ .ConcurrencyGroup.Source }}</td></tr>
+          <tr><td><code>branch</code></td><td>{{ .Branch }}</td><td>{{ .Branch.Source }}</td></tr>
+          <tr><td><code>sparse_checkout</code></td><td>{{ .SparseCheckout }}</td><td>{{ .SparseCheckout.Source }}</td></tr>
+          <tr><td><code>submodules</code></td><td>{{ .Submodules }}</td><td>{{ .Submodules.Source }}</td></tr>
+          <tr><td><code>lfs</code></td><td>{{ .LFS }}</td><td>{{ .LFS.Source }}</td></tr>
+          <tr><td><code>cloud_credentials</code></td><td>{{ .CloudCredentials }}</td><td>{{ .CloudCredentials.Source }}</td></tr>
+          <tr><td><code>env</code></td><td>{{ .EnvVars }}</td><td>{{ .EnvVars.Source }}</td></tr>
+          <tr><td><code>git_credentials</code></td><td>{{ .GitCredentials }}</td><td>{{ .GitCredentials.Source }}</td></tr>
+          <tr><td><code>approvals</code></td><td>{{ .Approvals }}</td><td>{{ .Approvals.Source }}</td></tr>
+          <tr><td><code>help_sections</code></td><td>{{ .HelpSections }}</td><td>{{ .HelpSections.Source }}</td></tr>
+          <tr><td><code>default_terraform_version</code></td><td>{{ .DefaultTerraformVersion }}</td><td>{{ .DefaultTerraformVersion.Source }}</td></tr>
+          <tr><td><code>terraform_distribution</code></td><td>{{ .TerraformDistribution }}</td><td>{{ .TerraformDistribution.Source }}</td></tr>
+          <tr><td><code>tenant</code></td><td>{{ .Tenant }}</td><td>{{ .Tenant.Source }}</td></tr>
+        </tbody>
+      </table>
+    </section>
+    <section>
+      <p class="title-heading small"><strong>Workflows</strong></p>
+      {{ range .Workflows }}
+      <h6><code>{{ .Name }}</code>{{ if .Image }} in <code>{{ .Image }}</code>{{ end }}</h6>
+      <pre><code>plan:
+{{ range .Plan }}  - {{ . }}
+{{ end }}apply:
+{{ range .Apply }}  - {{ . }}
+{{ end }}</code></pre>
+      {{ end }}
+    </section>
+    {{ end }}
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
+
+// PullsData holds the data for rendering the pull requests page.
+type PullsData struct {
+	Pulls []PullData
+	// Repo is the full name of the repo the pull requests are filtered by, if
+	// any.
+	Repo            string
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var pullsTemplate = template.Must(template.New("pulls.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{ if .Repo }}{{ .Repo }}{{ else }}All Repos{{ end }}</strong> <code>Pull Requests</code></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <form action="{{ .CleanedBasePath }}/pulls" method="get">
+        <input class="eight columns" type="text" name="repo" value="{{ .Repo }}" placeholder="owner/repo">
+        <input class="button-primary four columns" type="submit" value="Filter Pull Requests">
+      </form>
+    </section>
+    <section>
+    {{ range .Pulls }}
+      <h6><a href="{{ .PullURL }}">{{ .RepoFullName }} #{{ .PullNum }}</a>{{ if .Author }} by <strong>{{ .Author }}</strong>{{ end }}{{ if .HeadCommit }} at <code>{{ .HeadCommit }}</code>{{ end }}</h6>
+      {{ if .Running }}
+      <p>
+        Running:
+        {{ range .Running }}<code>{{ .Command }}</code> by <strong>{{ .User }}</strong>{{ if .Project }} on {{ .Project }}{{ end }}{{ if .CancelledBy }} (cancelled by {{ .CancelledBy }}){{ end }}. {{ end }}
+        <a class="button js-cancel" data-repo="{{ .RepoFullName }}" data-pull="{{ .PullNum }}">Cancel</a>
+      </p>
+      {{ end }}
+      {{ if .Projects }}
+      <table class="u-full-width">
+        <thead>
+          <tr><th>Project</th><th>Dir</th><th>Workspace</th><th>Status</th><th>Lock</th></tr>
+        </thead>
+        <tbody>
+          {{ range .Projects }}
+          <tr>
+            <td>{{ if .ProjectName }}{{ .ProjectName }}{{ else }}<code>{{ .ProjectID }}</code>{{ end }}</td>
+            <td><code>{{ .Path }}</code></td>
+            <td><code>{{ .Workspace }}</code></td>
+            <td>{{ if .Status }}<code>{{ .Status }}</code>{{ if .NoChanges }} (no changes){{ end }}{{ else }}-{{ end }}</td>
+            <td>{{ if .LockURL }}<a href="{{ .LockURL }}">Locked</a>{{ else }}-{{ end }}</td>
+          </tr>
+          {{ end }}
+        </tbody>
+      </table>
+      {{ end }}
+    {{ else }}
+      <p class="placeholder">No pull requests found.</p>
+    {{ end }}
+    </section>
+  </div>
+<script>
+  document.querySelectorAll(".js-cancel").forEach(function(btn) {
+    btn.addEventListener("click", function() {
+      if (!confirm("Cancel the commands running on " + btn.dataset.repo + " #" + btn.dataset.pull + "?")) {
+        return;
+      }
+      var req = new XMLHttpRequest();
+      req.open("DELETE", "{{ .CleanedBasePath }}/api/commands?repo=" + encodeURIComponent(btn.dataset.repo) + "&pull=" + btn.dataset.pull);
+      req.onload = function() {
+        if (req.status === 200) {
+          window.location.reload();
+        } else {
+          alert("Failed cancelling commands: " + req.responseText);
+        }
+      };
+      req.send();
+    });
+  });
+</script>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// webhookSecretVCSHosts maps the vcs key used in the --webhook-secrets flag
+// to the VCS host it applies to. Bitbucket Cloud and Azure DevOps don't
+// support webhook secrets.
+var webhookSecretVCSHosts = map[string]models.VCSHostType{
+	"bitbucket": models.BitbucketServer,
+	"github":    models.Github,
+	"gitlab":    models.Gitlab,
+}
+
+// WebhookSecrets holds the secrets used to validate webhooks from each VCS
+// host. Each request is valid if it was signed by any of its host's secrets
+// which allows secrets to be rotated without rejecting webhooks.
+type WebhookSecrets map[models.VCSHostType][][]byte
+
+// webhookSecretConfig is the format of each secret in the --webhook-secrets
+// flag.
+type webhookSecretConfig struct {
+	VCS    string `json:"vcs"`
+	Secret string `json:"secret"`
+}
+
+// ParseWebhookSecrets parses the JSON value of the --webhook-secrets flag, ex.
+// [{"vcs": "github", "secret": "new"}, {"vcs": "github", "secret": "old"}].
+// The secrets for each VCS host are returned in the order they were listed.
+func ParseWebhookSecrets(secretsJSON string) (WebhookSecrets, error) {
+	secrets := make(WebhookSecrets)
+	if secretsJSON == "" {
+		return secrets, nil
+	}
+	var configs []webhookSecretConfig
+	if err := json.Unmarshal([]byte(secretsJSON), &configs); err != nil {
+		return nil, errors.Wrap(err, "parsing webhook secrets")
+	}
+	for i, c := range configs {
+		host, ok := webhookSecretVCSHosts[c.VCS]
+		if !ok {
+			return nil, fmt.Errorf("webhook secret %d: invalid vcs %q, must be one of %s", i, c.VCS, strings.Join(webhookSecretVCSNames(), ", "))
+		}
+		if c.Secret == "" {
+			return nil, fmt.Errorf("webhook secret %d: secret is required", i)
+		}
+		secrets[host] = append(secrets[host], []byte(c.Secret))
+	}
+	return secrets, nil
+}
+
+// Prepend adds secret as the first secret tried for host. It's used for the
+// secrets from the per-VCS flags, ex. --gh-webhook-secret. It's a no-op if
+// secret is empty.
+func (s WebhookSecrets) Prepend(host models.VCSHostType, secret string) {
+	if secret != "" {
+		s[host] = append([][]byte{[]byte(secret)}, s[host]...)
+	}
+}
+
+func webhookSecretVCSNames() []string {
+	var names []string
+	for name := range webhookSecretVCSHosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WebhookSignatureError is returned when a webhook request wasn't signed by
+// any of the webhook secrets.
+type WebhookSignatureError struct {
+	// Err is the error from validating against the last secret.
+	Err error
+}
+
+// Error implements error.
+func (w *WebhookSignatureError) Error() string {
+	return w.Err.Error()
+}
@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseWebhookSecrets(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		exp         server.WebhookSecrets
+		expErr      string
+	}{
+		{
+			"empty",
+			"",
+			server.WebhookSecrets{},
+			"",
+		},
+		{
+			"valid",
+			`[{"vcs": "github", "secret": "new"}, {"vcs": "gitlab", "secret": "gl"}, {"vcs": "github", "secret": "old"}, {"vcs": "bitbucket", "secret": "bb"}]`,
+			server.WebhookSecrets{
+				models.Github:          {[]byte("new"), []byte("old")},
+				models.Gitlab:          {[]byte("gl")},
+				models.BitbucketServer: {[]byte("bb")},
+			},
+			"",
+		},
+		{
+			"invalid json",
+			`{`,
+			nil,
+			"parsing webhook secrets: unexpected end of JSON input",
+		},
+		{
+			"invalid vcs",
+			`[{"vcs": "azuredevops", "secret": "s"}]`,
+			nil,
+			`webhook secret 0: invalid vcs "azuredevops", must be one of bitbucket, github, gitlab`,
+		},
+		{
+			"missing secret",
+			`[{"vcs": "github", "secret": "s"}, {"vcs": "github"}]`,
+			nil,
+			"webhook secret 1: secret is required",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			secrets, err := server.ParseWebhookSecrets(c.input)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, secrets)
+		})
+	}
+}
+
+func TestWebhookSecrets_Prepend(t *testing.T) {
+	secrets, err := server.ParseWebhookSecrets(`[{"vcs": "github", "secret": "old"}]`)
+	Ok(t, err)
+	secrets.Prepend(models.Github, "flag")
+	secrets.Prepend(models.Gitlab, "gl-flag")
+	secrets.Prepend(models.BitbucketServer, "")
+	Equals(t, server.WebhookSecrets{
+		models.Github: {[]byte("flag"), []byte("old")},
+		models.Gitlab: {[]byte("gl-flag")},
+	}, secrets)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultWorkerClaimWait is how long claim requests wait for a job before
+// responding that there isn't one.
+const DefaultWorkerClaimWait = 30 * time.Second
+
+// WorkersController handles requests from remote workers to claim jobs and
+// report their results.
+type WorkersController struct {
+	Queue *workers.Queue
+	// ClaimWait is how long claim requests wait for a job.
+	ClaimWait time.Duration
+	Logger    *logging.SimpleLogger
+}
+
+// ClaimJob is the POST /api/workers/jobs/claim route. It responds with the
+// next job to run or with no content if there wasn't one before ClaimWait.
+func (wc *WorkersController) ClaimJob(w http.ResponseWriter, _ *http.Request) {
+	job := wc.Queue.Claim(wc.ClaimWait)
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	wc.Logger.Info("job %s claimed by worker", job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		wc.Logger.Err("writing response: %s", err)
+	}
+}
+
+// CompleteJob is the POST /api/workers/jobs/{id}/result route. It reports the
+// result of a job.
+func (wc *WorkersController) CompleteJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok || id == "" {
+		respond(w, wc.Logger, logging.Warn, http.StatusBadRequest, "No job id in request")
+		return
+	}
+	var res workers.Result
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		respond(w, wc.Logger, logging.Warn, http.StatusBadRequest, "Failed parsing request: %s", err)
+		return
+	}
+	if err := wc.Queue.Complete(id, res); err == workers.ErrJobNotFound {
+		respond(w, wc.Logger, logging.Warn, http.StatusNotFound, "No running job with id %q", id)
+		return
+	}
+	respond(w, wc.Logger, logging.Info, http.StatusOK, "Job %s completed", id)
+}
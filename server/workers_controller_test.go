@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/workers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func setupWorkersController(claimWait time.Duration) (*workers.Queue, *workers.Client, func()) {
+	queue := workers.NewQueue(time.Minute)
+	c := &server.WorkersController{
+		Queue:     queue,
+		ClaimWait: claimWait,
+		Logger:    logging.NewNoopLogger(),
+	}
+	router := mux.NewRouter()
+	router.HandleFunc(workers.ClaimPath, c.ClaimJob).Methods("POST")
+	router.HandleFunc(fmt.Sprintf(workers.CompletePath, "{id}"), c.CompleteJob).Methods("POST")
+	s := httptest.NewServer(router)
+	return queue, &workers.Client{HTTPClient: s.Client(), ServerURL: s.URL + "/", Token: "token"}, s.Close
+}
+
+func TestWorkersController_ClaimNoJobs(t *testing.T) {
+	_, client, cleanup := setupWorkersController(10 * time.Millisecond)
+	defer cleanup()
+	job, err := client.Claim()
+	Ok(t, err)
+	Assert(t, job == nil, "expected no job, got %v", job)
+}
+
+func TestWorkersController_ClaimAndComplete(t *testing.T) {
+	queue, client, cleanup := setupWorkersController(time.Second)
+	defer cleanup()
+	results := make(chan workers.Result)
+	go func() {
+		res, err := queue.Dispatch(workers.Job{
+			Command:   workers.ApplyJob,
+			Workspace: "default",
+			PlanFile:  []byte("planfile"),
+		})
+		Ok(t, err)
+		results <- res
+	}()
+
+	job, err := client.Claim()
+	Ok(t, err)
+	Assert(t, job != nil, "expected job")
+	Equals(t, workers.ApplyJob, job.Command)
+	Equals(t, "default", job.Workspace)
+	Equals(t, "planfile", string(job.PlanFile))
+
+	Ok(t, client.Complete(job.ID, workers.Result{Outputs: []string{"applied"}}))
+	Equals(t, workers.Result{Outputs: []string{"applied"}}, <-results)
+
+	// The job can't be completed again.
+	err = client.Complete(job.ID, workers.Result{})
+	Assert(t, err != nil && strings.Contains(err.Error(), "unexpected status code: 404"), "expected not found, got %v", err)
+}
+
+func TestWorkersController_CompleteInvalidBody(t *testing.T) {
+	c := &server.WorkersController{
+		Queue:  workers.NewQueue(time.Minute),
+		Logger: logging.NewNoopLogger(),
+	}
+	req, _ := http.NewRequest("POST", "", strings.NewReader("not json"))
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	c.CompleteJob(w, req)
+	responseContains(t, w, http.StatusBadRequest, "Failed parsing request")
+}